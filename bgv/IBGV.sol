@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: MIT
+// Copyright (C) 2025, Lux Industries, Inc. All rights reserved.
+
+pragma solidity ^0.8.24;
+
+/**
+ * @title BGV
+ * @notice Library for the BGV exact-arithmetic FHE precompile
+ * @dev C-Chain: 0x4243000000000000000000000000000000000000
+ *      Z-Chain: 0x4643000000000000000000000000000000000000
+ *
+ * A ciphertext holds 8192 integers modulo 65537, arranged as 2 rows of
+ * 4096 slots, and every operation applies to all slots at once. Ciphertexts
+ * are passed and returned as bytes; the precompile keeps no state.
+ *
+ * Gas costs:
+ *   - encrypt: 20000 + 10 per value
+ *   - add, sub: 5000
+ *   - mul: 140000 (tensor, relinearize, rescale)
+ *   - tensor: 50000
+ *   - relinearize, rotateRows: 80000
+ *   - rotateColumns: 80000 per set bit of k mod 4096
+ *   - addPlain: 10000 + 10 per value
+ *   - mulPlain: 25000 + 10 per value
+ */
+library BGV {
+    address constant PRECOMPILE = 0x4243000000000000000000000000000000000000;
+
+    uint8 constant OP_ENCRYPT = 0x01;
+    uint8 constant OP_ADD = 0x02;
+    uint8 constant OP_SUB = 0x03;
+    uint8 constant OP_MUL = 0x04;
+    uint8 constant OP_TENSOR = 0x05;
+    uint8 constant OP_RELINEARIZE = 0x06;
+    uint8 constant OP_ADD_PLAIN = 0x07;
+    uint8 constant OP_MUL_PLAIN = 0x08;
+    uint8 constant OP_ROTATE_COLUMNS = 0x10;
+    uint8 constant OP_ROTATE_ROWS = 0x11;
+    uint8 constant OP_PUBLIC_KEY = 0x20;
+    uint8 constant OP_PARAMS = 0x21;
+
+    error BGVCallFailed(uint8 op);
+
+    function _call(bytes memory input) private view returns (bytes memory result) {
+        bool success;
+        (success, result) = PRECOMPILE.staticcall(input);
+        if (!success) revert BGVCallFailed(uint8(input[0]));
+    }
+
+    function _values(uint64[] memory values) private pure returns (bytes memory packed) {
+        for (uint256 i = 0; i < values.length; i++) {
+            packed = abi.encodePacked(packed, values[i]);
+        }
+    }
+
+    /// @notice Encrypt public values into the first slots; the rest are zero
+    function encrypt(uint64[] memory values) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_ENCRYPT, _values(values)));
+    }
+
+    function add(bytes memory a, bytes memory b) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_ADD, uint32(a.length), a, b));
+    }
+
+    function sub(bytes memory a, bytes memory b) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_SUB, uint32(a.length), a, b));
+    }
+
+    /// @notice Multiply slot-wise; consumes one level
+    function mul(bytes memory a, bytes memory b) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_MUL, uint32(a.length), a, b));
+    }
+
+    /// @notice Multiply without relinearizing; sum the products, then relinearize once
+    function tensor(bytes memory a, bytes memory b) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_TENSOR, uint32(a.length), a, b));
+    }
+
+    function relinearize(bytes memory ct) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_RELINEARIZE, ct));
+    }
+
+    function addPlain(bytes memory ct, uint64[] memory values) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_ADD_PLAIN, uint32(ct.length), ct, _values(values)));
+    }
+
+    function mulPlain(bytes memory ct, uint64[] memory values) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_MUL_PLAIN, uint32(ct.length), ct, _values(values)));
+    }
+
+    /// @notice Rotate each row left by k slots; negative k rotates right
+    function rotateColumns(bytes memory ct, int32 k) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_ROTATE_COLUMNS, k, ct));
+    }
+
+    /// @notice Swap the two rows of slots
+    function rotateRows(bytes memory ct) internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_ROTATE_ROWS, ct));
+    }
+
+    /// @notice Network public key clients encrypt private values under
+    function publicKey() internal view returns (bytes memory) {
+        return _call(abi.encodePacked(OP_PUBLIC_KEY));
+    }
+
+    /// @notice LogN, slot count, plaintext modulus and top level
+    function params() internal view returns (uint256 logN, uint256 slots, uint256 plaintextModulus, uint256 maxLevel) {
+        return abi.decode(_call(abi.encodePacked(OP_PARAMS)), (uint256, uint256, uint256, uint256));
+    }
+}
@@ -0,0 +1,638 @@
+// Copyright (C) 2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package bgv implements the BGV exact-arithmetic FHE precompile for the Lux EVM.
+// Address: 0x4243000000000000000000000000000000000000 (C-Chain),
+// 0x4643000000000000000000000000000000000000 (Z-Chain)
+//
+// BGV encrypts a vector of integers modulo the plaintext modulus into one
+// ciphertext, one value per slot, and evaluates additions and
+// multiplications on every slot at once (SIMD). It suits workloads that
+// need exact modular arithmetic over many values, such as tallies, sums of
+// balances and inner products, where bitwise TFHE would need one ciphertext
+// and one bootstrapped circuit per value.
+//
+// Operations:
+// - Encrypt: Trivially encrypt public slot values
+// - Add, Sub: Slot-wise addition and subtraction
+// - Mul: Slot-wise multiplication, relinearized and rescaled
+// - Tensor, Relinearize: Multiplication without relinearization, and the
+// relinearization that follows a sum of tensored products
+// - AddPlain, MulPlain: Slot-wise operations with a plaintext vector
+// - RotateColumns, RotateRows: Slot rotations
+// - PublicKey, Params: Network key and parameters
+//
+// Ciphertexts are passed and returned in their binary encoding. The
+// precompile keeps no state and never decrypts; values leave the encrypted
+// domain only through the network's threshold decryption.
+package bgv
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"sync"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/lattice/v7/core/rlwe"
+	"github.com/luxfi/lattice/v7/schemes/bgv"
+	"github.com/luxfi/precompile/contract"
+)
+
+var (
+	// ContractAddress is the address of the BGV precompile on the C-Chain
+	ContractAddress = common.HexToAddress("0x4243000000000000000000000000000000000000")
+
+	// ZChainAddress is the address of the BGV precompile on the Z-Chain
+	ZChainAddress = common.HexToAddress("0x4643000000000000000000000000000000000000")
+
+	// Singleton instance
+	BGVPrecompile = &bgvPrecompile{}
+
+	_ contract.StatefulPrecompiledContract = &bgvPrecompile{}
+
+	ErrInvalidInput      = errors.New("invalid bgv input")
+	ErrInvalidOperation  = errors.New("invalid operation selector")
+	ErrInvalidCiphertext = errors.New("invalid bgv ciphertext")
+	ErrInvalidSlot       = errors.New("slot value exceeds plaintext modulus")
+	ErrTooManySlots      = errors.New("too many slot values")
+	ErrDepthExhausted    = errors.New("ciphertext has no level left to rescale")
+	ErrNoNetworkKeys     = errors.New("bgv network keys not installed")
+	ErrEvaluation        = errors.New("bgv evaluation failed")
+)
+
+// Operation selectors (first byte of input)
+const (
+	OpEncrypt       = 0x01 // Encrypt slot values
+	OpAdd           = 0x02 // ct + ct
+	OpSub           = 0x03 // ct - ct
+	OpMul           = 0x04 // ct * ct, relinearized and rescaled
+	OpTensor        = 0x05 // ct * ct, degree 2
+	OpRelinearize   = 0x06 // Degree 2 to degree 1
+	OpAddPlain      = 0x07 // ct + plaintext vector
+	OpMulPlain      = 0x08 // ct * plaintext vector
+	OpRotateColumns = 0x10 // Rotate the slots of each row
+	OpRotateRows    = 0x11 // Swap the two rows
+	OpPublicKey     = 0x20 // Network public key
+	OpParams        = 0x21 // Scheme parameters
+)
+
+// Gas costs
+const (
+	GasEncrypt          = 20_000 // Encode into a trivial encryption
+	GasAdd              = 5_000  // Addition or subtraction
+	GasPlainAdd         = 10_000 // Encode and add
+	GasPlainMul         = 25_000 // Encode and multiply
+	GasTensor           = 50_000 // Tensor product
+	GasKeySwitch        = 80_000 // Relinearization or one rotation
+	GasRescale          = 10_000 // Modulus switch
+	GasParams           = 100    // Parameter query
+	GasPerSlotValue     = 10     // Per plaintext slot value
+	GasPublicKeyPerWord = 3      // Per 32-byte word of the public key
+	GasCiphertextWord   = 4      // Per 32-byte word of input ciphertext
+	GasMul              = GasTensor + GasKeySwitch + GasRescale
+)
+
+// Scheme parameters. N = 8192 slots are arranged as a 2 x 4096 matrix:
+// RotateColumns rotates both rows left by k, RotateRows swaps them. The
+// plaintext modulus 65537 is prime and 1 mod 2N, which batching requires.
+var ParametersLiteral = bgv.ParametersLiteral{
+	LogN:             13,
+	LogQ:             []int{54, 54, 54},
+	LogP:             []int{55},
+	PlaintextModulus: 0x10001,
+}
+
+// Parameters are the scheme parameters every network key is generated for
+var Parameters bgv.Parameters
+
+func init() {
+	var err error
+	if Parameters, err = bgv.NewParametersFromLiteral(ParametersLiteral); err != nil {
+		panic(err)
+	}
+}
+
+// Slots returns the number of slots of a ciphertext
+func Slots() int {
+	return Parameters.MaxSlots()
+}
+
+// GaloisElements returns the Galois elements the network's rotation keys
+// must cover: column rotations by every power of two and the row swap
+func GaloisElements() []uint64 {
+	cols := Slots() / 2
+	els := make([]uint64, 0, bits.Len(uint(cols)))
+	for k := 1; k < cols; k <<= 1 {
+		els = append(els, Parameters.GaloisElement(k))
+	}
+	return append(els, Parameters.GaloisElementOrderTwoOrthogonalSubgroup())
+}
+
+// networkKeys are the keys of the network: the public key clients encrypt
+// under and the relinearization and rotation keys
+type networkKeys struct {
+	pk      *rlwe.PublicKey
+	pkBytes []byte
+	eval    *bgv.Evaluator
+	encoder *bgv.Encoder
+}
+
+var (
+	keysMu sync.RWMutex
+	keys   *networkKeys
+)
+
+// SetNetworkKeys installs the network public key and evaluation keys. The
+// evaluation keys must hold the relinearization key and a rotation key for
+// each of GaloisElements. Every validator must install the same keys, as
+// results are part of consensus; Configure installs the keys the precompile
+// config names.
+func SetNetworkKeys(pk *rlwe.PublicKey, evk *rlwe.MemEvaluationKeySet) error {
+	if pk == nil || evk == nil || evk.RelinearizationKey == nil {
+		return ErrInvalidInput
+	}
+	for _, el := range GaloisElements() {
+		if _, err := evk.GetGaloisKey(el); err != nil {
+			return fmt.Errorf("%w: missing rotation key %d", ErrInvalidInput, el)
+		}
+	}
+	pkBytes, err := pk.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keys = &networkKeys{
+		pk:      pk,
+		pkBytes: pkBytes,
+		eval:    bgv.NewEvaluator(Parameters, evk),
+		encoder: bgv.NewEncoder(Parameters),
+	}
+	return nil
+}
+
+// ClearNetworkKeys removes the installed network keys
+func ClearNetworkKeys() {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keys = nil
+}
+
+// networkKeySet returns the installed keys
+func networkKeySet() (*networkKeys, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	if keys == nil {
+		return nil, ErrNoNetworkKeys
+	}
+	return keys, nil
+}
+
+type bgvPrecompile struct{}
+
+// Address returns the C-Chain address of the BGV precompile
+func (p *bgvPrecompile) Address() common.Address {
+	return ContractAddress
+}
+
+// RequiredGas calculates gas for BGV operations
+func (p *bgvPrecompile) RequiredGas(input []byte) uint64 {
+	if len(input) < 1 {
+		return 0
+	}
+
+	op := input[0]
+	data := input[1:]
+
+	switch op {
+	case OpEncrypt:
+		return GasEncrypt + GasPerSlotValue*uint64(len(data)/8)
+	case OpAdd, OpSub:
+		return GasAdd + ciphertextGas(len(data)-4)
+	case OpMul:
+		return GasMul + ciphertextGas(len(data)-4)
+	case OpTensor:
+		return GasTensor + ciphertextGas(len(data)-4)
+	case OpRelinearize, OpRotateRows:
+		return GasKeySwitch + ciphertextGas(len(data))
+	case OpAddPlain, OpMulPlain:
+		base := uint64(GasPlainAdd)
+		if op == OpMulPlain {
+			base = GasPlainMul
+		}
+		if ct, _, ok := splitCiphertext(data); ok {
+			return base + ciphertextGas(len(ct)) + GasPerSlotValue*uint64((len(data)-4-len(ct))/8)
+		}
+		return base
+	case OpRotateColumns:
+		if len(data) < 4 {
+			return GasKeySwitch
+		}
+		steps := len(rotationSteps(int32(binary.BigEndian.Uint32(data))))
+		return (GasKeySwitch + ciphertextGas(len(data)-4)) * uint64(max(steps, 1))
+	case OpPublicKey:
+		k, err := networkKeySet()
+		if err != nil {
+			return GasParams
+		}
+		return GasParams + GasPublicKeyPerWord*uint64((len(k.pkBytes)+31)/32)
+	case OpParams:
+		return GasParams
+	default:
+		return 0
+	}
+}
+
+// ciphertextGas returns the size-dependent gas of evaluating size bytes of
+// ciphertext: evaluation work grows with the degree and level a ciphertext
+// carries, and so with its encoding
+func ciphertextGas(size int) uint64 {
+	if size <= 0 {
+		return 0
+	}
+	return GasCiphertextWord * uint64((size+31)/32)
+}
+
+// Run executes the BGV precompile
+func (p *bgvPrecompile) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	requiredGas := p.RequiredGas(input)
+	if suppliedGas < requiredGas {
+		return nil, 0, contract.ErrOutOfGas
+	}
+	remainingGas = suppliedGas - requiredGas
+
+	if len(input) < 1 {
+		return nil, remainingGas, ErrInvalidInput
+	}
+
+	op := input[0]
+	data := input[1:]
+
+	switch op {
+	case OpParams:
+		return params(), remainingGas, nil
+	case OpPublicKey:
+		k, err := networkKeySet()
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return k.pkBytes, remainingGas, nil
+	}
+
+	k, err := networkKeySet()
+	if err != nil {
+		return nil, remainingGas, err
+	}
+	switch op {
+	case OpEncrypt:
+		ret, err = encrypt(k, data)
+	case OpAdd, OpSub, OpMul, OpTensor:
+		ret, err = binaryOp(k, op, data)
+	case OpAddPlain, OpMulPlain:
+		ret, err = plainOp(k, op, data)
+	case OpRelinearize:
+		ret, err = relinearize(k, data)
+	case OpRotateColumns:
+		ret, err = rotateColumns(k, data)
+	case OpRotateRows:
+		ret, err = rotateRows(k, data)
+	default:
+		return nil, remainingGas, ErrInvalidOperation
+	}
+	return ret, remainingGas, err
+}
+
+// params returns LogN, the slot count, the plaintext modulus and the top
+// level as 32-byte words
+func params() []byte {
+	out := make([]byte, 128)
+	binary.BigEndian.PutUint64(out[24:], uint64(Parameters.LogN()))
+	binary.BigEndian.PutUint64(out[56:], uint64(Slots()))
+	binary.BigEndian.PutUint64(out[88:], Parameters.PlaintextModulus())
+	binary.BigEndian.PutUint64(out[120:], uint64(Parameters.MaxLevel()))
+	return out
+}
+
+// decodeSlots decodes big-endian uint64 slot values
+func decodeSlots(data []byte) ([]uint64, error) {
+	if len(data)%8 != 0 {
+		return nil, ErrInvalidInput
+	}
+	n := len(data) / 8
+	if n > Slots() {
+		return nil, ErrTooManySlots
+	}
+	values := make([]uint64, n)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint64(data[i*8:])
+		if values[i] >= Parameters.PlaintextModulus() {
+			return nil, ErrInvalidSlot
+		}
+	}
+	return values, nil
+}
+
+// decodeCiphertext decodes a ciphertext and checks it belongs to the
+// scheme parameters: every polynomial has the ring degree and the same
+// level, every coefficient is reduced modulo its prime, and the metadata is
+// what the evaluator produces. The evaluator assumes all of this, so a
+// ciphertext that passes cannot make it panic.
+func decodeCiphertext(data []byte) (*rlwe.Ciphertext, error) {
+	if err := checkCiphertextLayout(data); err != nil {
+		return nil, err
+	}
+	ct := new(rlwe.Ciphertext)
+	if err := ct.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCiphertext, err)
+	}
+	if ct.Degree() < 1 || ct.Degree() > 2 || ct.MetaData == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	level := ct.Value[0].Level()
+	if level < 0 || level > Parameters.MaxLevel() {
+		return nil, ErrInvalidCiphertext
+	}
+	moduli := Parameters.RingQ().ModuliChain()
+	for _, poly := range ct.Value {
+		if poly.Level() != level {
+			return nil, ErrInvalidCiphertext
+		}
+		for i, coeffs := range poly.Coeffs {
+			if len(coeffs) != Parameters.N() {
+				return nil, ErrInvalidCiphertext
+			}
+			for _, c := range coeffs {
+				if c >= moduli[i] {
+					return nil, ErrInvalidCiphertext
+				}
+			}
+		}
+	}
+	if !ct.IsNTT || ct.IsMontgomery || !ct.IsBatched || ct.LogDimensions != Parameters.LogMaxDimensions() {
+		return nil, ErrInvalidCiphertext
+	}
+	scale := &ct.Scale
+	if scale.Mod == nil || !scale.Mod.IsUint64() || scale.Mod.Uint64() != Parameters.PlaintextModulus() ||
+		!scale.Value.IsInt() || scale.Value.Sign() < 0 || scale.Uint64() >= Parameters.PlaintextModulus() {
+		return nil, ErrInvalidCiphertext
+	}
+	return ct, nil
+}
+
+// checkCiphertextLayout checks the binary encoding of a ciphertext before
+// it is decoded: the metadata's scale parses, and every length prefix fits
+// the scheme parameters and the input. The lattice decoder trusts both, so
+// it must not see an encoding that fails here.
+func checkCiphertextLayout(data []byte) error {
+	size := rlwe.MetaData{}.BinarySize()
+	if len(data) < 1+size || data[0] != 1 {
+		return ErrInvalidCiphertext
+	}
+	var meta struct {
+		PlaintextMetaData struct {
+			Scale struct{ Value, Mod string }
+		}
+	}
+	if err := json.Unmarshal(data[1:1+size], &meta); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCiphertext, err)
+	}
+	scale := meta.PlaintextMetaData.Scale
+	for _, s := range []string{scale.Value, scale.Mod} {
+		if _, ok := new(big.Float).SetString(s); !ok {
+			return ErrInvalidCiphertext
+		}
+	}
+
+	rest := data[1+size:]
+	next := func() (uint64, bool) {
+		if len(rest) < 8 {
+			return 0, false
+		}
+		v := binary.LittleEndian.Uint64(rest)
+		rest = rest[8:]
+		return v, true
+	}
+	polys, ok := next()
+	if !ok || polys < 2 || polys > 3 {
+		return ErrInvalidCiphertext
+	}
+	for range polys {
+		rows, ok := next()
+		if !ok || rows < 1 || rows > uint64(Parameters.MaxLevel()+1) {
+			return ErrInvalidCiphertext
+		}
+		for range rows {
+			n, ok := next()
+			if !ok || n != uint64(Parameters.N()) || uint64(len(rest)) < 8*n {
+				return ErrInvalidCiphertext
+			}
+			rest = rest[8*n:]
+		}
+	}
+	if len(rest) != 0 {
+		return ErrInvalidCiphertext
+	}
+	return nil
+}
+
+// encodeCiphertext returns the binary encoding of ct
+func encodeCiphertext(ct *rlwe.Ciphertext) ([]byte, error) {
+	out, err := ct.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+	return out, nil
+}
+
+// splitCiphertext splits length (4) || ciphertext || rest
+func splitCiphertext(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+	size := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(size) {
+		return nil, nil, false
+	}
+	return data[4 : 4+size], data[4+size:], true
+}
+
+// encrypt encrypts public slot values. Input is 8-byte big-endian values;
+// unset slots are zero. The values are public in the calldata, so the
+// ciphertext is the noiseless trivial encryption (encoding, 0), which every
+// validator computes alike. Private values are encrypted by the client
+// under the network public key.
+func encrypt(k *networkKeys, data []byte) ([]byte, error) {
+	values, err := decodeSlots(data)
+	if err != nil {
+		return nil, err
+	}
+	pt := bgv.NewPlaintext(Parameters, Parameters.MaxLevel())
+	if err := k.encoder.ShallowCopy().Encode(values, pt); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+
+	ct := rlwe.NewCiphertext(Parameters, 1, pt.Level())
+	ct.Value[0].Copy(pt.Value)
+	*ct.MetaData = *pt.MetaData
+	return encodeCiphertext(ct)
+}
+
+// binaryOp evaluates an operation on two ciphertexts. Input is
+// length (4) || a || b.
+func binaryOp(k *networkKeys, op byte, data []byte) ([]byte, error) {
+	rawA, rawB, ok := splitCiphertext(data)
+	if !ok {
+		return nil, ErrInvalidInput
+	}
+	a, err := decodeCiphertext(rawA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeCiphertext(rawB)
+	if err != nil {
+		return nil, err
+	}
+	if op == OpMul || op == OpTensor {
+		if a.Degree() != 1 || b.Degree() != 1 {
+			return nil, ErrInvalidCiphertext
+		}
+	}
+
+	eval := k.eval.ShallowCopy()
+	var ct *rlwe.Ciphertext
+	switch op {
+	case OpAdd:
+		ct, err = eval.AddNew(a, b)
+	case OpSub:
+		ct, err = eval.SubNew(a, b)
+	case OpTensor:
+		ct, err = eval.MulNew(a, b)
+	case OpMul:
+		if min(a.Level(), b.Level()) == 0 {
+			return nil, ErrDepthExhausted
+		}
+		if ct, err = eval.MulRelinNew(a, b); err == nil {
+			err = eval.Rescale(ct, ct)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+	return encodeCiphertext(ct)
+}
+
+// plainOp evaluates an operation on a ciphertext and a plaintext vector.
+// Input is length (4) || ciphertext || 8-byte big-endian values.
+func plainOp(k *networkKeys, op byte, data []byte) ([]byte, error) {
+	raw, rest, ok := splitCiphertext(data)
+	if !ok {
+		return nil, ErrInvalidInput
+	}
+	ct, err := decodeCiphertext(raw)
+	if err != nil {
+		return nil, err
+	}
+	values, err := decodeSlots(rest)
+	if err != nil {
+		return nil, err
+	}
+	padded := make([]uint64, Slots())
+	copy(padded, values)
+
+	eval := k.eval.ShallowCopy()
+	var out *rlwe.Ciphertext
+	if op == OpAddPlain {
+		out, err = eval.AddNew(ct, padded)
+	} else {
+		out, err = eval.MulNew(ct, padded)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+	return encodeCiphertext(out)
+}
+
+// relinearize reduces a degree-2 ciphertext to degree 1. Input is the
+// ciphertext.
+func relinearize(k *networkKeys, data []byte) ([]byte, error) {
+	ct, err := decodeCiphertext(data)
+	if err != nil {
+		return nil, err
+	}
+	if ct.Degree() != 2 {
+		return nil, ErrInvalidCiphertext
+	}
+	out, err := k.eval.ShallowCopy().RelinearizeNew(ct)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+	return encodeCiphertext(out)
+}
+
+// rotationSteps decomposes a left rotation by k columns into rotations by
+// powers of two, the rotations the network holds keys for
+func rotationSteps(k int32) []int {
+	cols := Slots() / 2
+	r := int(k) % cols
+	if r < 0 {
+		r += cols
+	}
+	var steps []int
+	for step := 1; step < cols; step <<= 1 {
+		if r&step != 0 {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// rotateColumns rotates each row left by k slots; a negative k rotates
+// right. Input is k (4, signed) || ciphertext.
+func rotateColumns(k *networkKeys, data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, ErrInvalidInput
+	}
+	ct, err := decodeCiphertext(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	if ct.Degree() != 1 {
+		return nil, ErrInvalidCiphertext
+	}
+
+	eval := k.eval.ShallowCopy()
+	for _, step := range rotationSteps(int32(binary.BigEndian.Uint32(data))) {
+		if ct, err = eval.RotateColumnsNew(ct, step); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+		}
+	}
+	return encodeCiphertext(ct)
+}
+
+// rotateRows swaps the two rows of slots. Input is the ciphertext.
+func rotateRows(k *networkKeys, data []byte) ([]byte, error) {
+	ct, err := decodeCiphertext(data)
+	if err != nil {
+		return nil, err
+	}
+	if ct.Degree() != 1 {
+		return nil, ErrInvalidCiphertext
+	}
+	out, err := k.eval.ShallowCopy().RotateRowsNew(ct)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+	return encodeCiphertext(out)
+}
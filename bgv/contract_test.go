@@ -0,0 +1,205 @@
+// Copyright (C) 2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package bgv
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luxfi/lattice/v7/core/rlwe"
+	"github.com/luxfi/lattice/v7/schemes/bgv"
+	"github.com/stretchr/testify/require"
+)
+
+// installTestKeys generates network keys, installs them and returns the
+// secret key
+func installTestKeys(t *testing.T) *rlwe.SecretKey {
+	t.Helper()
+	kgen := rlwe.NewKeyGenerator(Parameters)
+	sk, pk := kgen.GenKeyPairNew()
+	evk := rlwe.NewMemEvaluationKeySet(kgen.GenRelinearizationKeyNew(sk), kgen.GenGaloisKeysNew(GaloisElements(), sk)...)
+	require.NoError(t, SetNetworkKeys(pk, evk))
+	t.Cleanup(ClearNetworkKeys)
+	return sk
+}
+
+func run(t *testing.T, input []byte) []byte {
+	t.Helper()
+	out, _, err := BGVPrecompile.Run(nil, [20]byte{}, ContractAddress, input, 10_000_000, false)
+	require.NoError(t, err)
+	return out
+}
+
+func slotInput(op byte, values ...uint64) []byte {
+	input := []byte{op}
+	for _, v := range values {
+		input = binary.BigEndian.AppendUint64(input, v)
+	}
+	return input
+}
+
+func pairInput(op byte, a, b []byte) []byte {
+	input := binary.BigEndian.AppendUint32([]byte{op}, uint32(len(a)))
+	return append(append(input, a...), b...)
+}
+
+func decrypt(t *testing.T, sk *rlwe.SecretKey, raw []byte) []uint64 {
+	t.Helper()
+	ct, err := decodeCiphertext(raw)
+	require.NoError(t, err)
+	values := make([]uint64, Slots())
+	pt := rlwe.NewDecryptor(Parameters, sk).DecryptNew(ct)
+	require.NoError(t, bgv.NewEncoder(Parameters).Decode(pt, values))
+	return values
+}
+
+func TestBGVAddress(t *testing.T) {
+	require.Equal(t, "0x4243000000000000000000000000000000000000", ContractAddress.Hex())
+	require.Equal(t, "0x4643000000000000000000000000000000000000", ZChainAddress.Hex())
+}
+
+func TestRequiredGas(t *testing.T) {
+	p := BGVPrecompile
+	require.Equal(t, uint64(0), p.RequiredGas(nil))
+	require.Equal(t, uint64(GasEncrypt+3*GasPerSlotValue), p.RequiredGas(slotInput(OpEncrypt, 1, 2, 3)))
+	require.Equal(t, uint64(GasMul), p.RequiredGas([]byte{OpMul}))
+
+	// 7 = 4 + 2 + 1 takes three keyed rotations, -1 wraps to 4095 = 12 steps
+	rotate := func(k int32) []byte {
+		return binary.BigEndian.AppendUint32([]byte{OpRotateColumns}, uint32(k))
+	}
+	require.Equal(t, uint64(3*GasKeySwitch), p.RequiredGas(rotate(7)))
+	require.Equal(t, uint64(12*GasKeySwitch), p.RequiredGas(rotate(-1)))
+	require.Equal(t, uint64(GasKeySwitch), p.RequiredGas(rotate(0)))
+
+	// Ciphertext operands are charged by size
+	ct := make([]byte, 64)
+	require.Equal(t, uint64(GasMul+2*GasCiphertextWord), p.RequiredGas(pairInput(OpMul, ct[:32], ct[32:])))
+	require.Equal(t, uint64(GasKeySwitch+2*GasCiphertextWord), p.RequiredGas(append([]byte{OpRelinearize}, ct...)))
+	require.Equal(t, uint64(3*(GasKeySwitch+2*GasCiphertextWord)), p.RequiredGas(append(rotate(7), ct...)))
+}
+
+func TestConfigure(t *testing.T) {
+	require.ErrorIs(t, (&Config{}).Verify(nil), ErrNoKeyPaths)
+
+	kgen := rlwe.NewKeyGenerator(Parameters)
+	sk, pk := kgen.GenKeyPairNew()
+	evk := rlwe.NewMemEvaluationKeySet(kgen.GenRelinearizationKeyNew(sk), kgen.GenGaloisKeysNew(GaloisElements(), sk)...)
+	pkBytes, err := pk.MarshalBinary()
+	require.NoError(t, err)
+	evkBytes, err := evk.MarshalBinary()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	config := &Config{PublicKeyPath: filepath.Join(dir, "pk"), EvaluationKeysPath: filepath.Join(dir, "evk")}
+	require.NoError(t, config.Verify(nil))
+	require.Error(t, Module.Configurator.Configure(nil, config, nil, nil))
+	require.NoError(t, os.WriteFile(config.PublicKeyPath, pkBytes, 0o600))
+	require.NoError(t, os.WriteFile(config.EvaluationKeysPath, evkBytes, 0o600))
+	require.NoError(t, Module.Configurator.Configure(nil, config, nil, nil))
+	t.Cleanup(ClearNetworkKeys)
+
+	require.Equal(t, pkBytes, run(t, []byte{OpPublicKey}))
+	sum := decrypt(t, sk, run(t, pairInput(OpAdd, run(t, slotInput(OpEncrypt, 1)), run(t, slotInput(OpEncrypt, 2)))))
+	require.Equal(t, uint64(3), sum[0])
+}
+
+func TestParams(t *testing.T) {
+	out := run(t, []byte{OpParams})
+	require.Len(t, out, 128)
+	require.Equal(t, uint64(13), binary.BigEndian.Uint64(out[24:]))
+	require.Equal(t, uint64(8192), binary.BigEndian.Uint64(out[56:]))
+	require.Equal(t, uint64(0x10001), binary.BigEndian.Uint64(out[88:]))
+}
+
+func TestNoNetworkKeys(t *testing.T) {
+	ClearNetworkKeys()
+	_, _, err := BGVPrecompile.Run(nil, [20]byte{}, ContractAddress, slotInput(OpEncrypt, 1), 1_000_000, false)
+	require.ErrorIs(t, err, ErrNoNetworkKeys)
+}
+
+func TestInvalidInput(t *testing.T) {
+	installTestKeys(t)
+
+	_, _, err := BGVPrecompile.Run(nil, [20]byte{}, ContractAddress, slotInput(OpEncrypt, 0x10001), 1_000_000, false)
+	require.ErrorIs(t, err, ErrInvalidSlot)
+
+	_, _, err = BGVPrecompile.Run(nil, [20]byte{}, ContractAddress, pairInput(OpAdd, []byte{1, 2, 3}, []byte{4}), 1_000_000, false)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, _, err = BGVPrecompile.Run(nil, [20]byte{}, ContractAddress, []byte{0xff}, 1_000_000, false)
+	require.ErrorIs(t, err, ErrInvalidOperation)
+
+	// Length prefixes beyond the parameters are rejected before decoding
+	ct := run(t, slotInput(OpEncrypt, 1))
+	size := rlwe.MetaData{}.BinarySize()
+	for _, offset := range []int{1 + size, 1 + size + 8, 1 + size + 16} {
+		bad := append([]byte(nil), ct...)
+		binary.LittleEndian.PutUint64(bad[offset:], 1<<62)
+		_, _, err = BGVPrecompile.Run(nil, [20]byte{}, ContractAddress, append([]byte{OpRelinearize}, bad...), 10_000_000, false)
+		require.ErrorIs(t, err, ErrInvalidCiphertext)
+	}
+
+	// So are unreduced coefficients
+	bad := append([]byte(nil), ct...)
+	binary.LittleEndian.PutUint64(bad[1+size+24:], ^uint64(0))
+	_, _, err = BGVPrecompile.Run(nil, [20]byte{}, ContractAddress, append([]byte{OpRotateRows}, bad...), 10_000_000, false)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestSlotArithmetic(t *testing.T) {
+	sk := installTestKeys(t)
+	tmod := Parameters.PlaintextModulus()
+
+	a := run(t, slotInput(OpEncrypt, 1, 2, 3, tmod-1))
+	b := run(t, slotInput(OpEncrypt, 10, 20, 30, 2))
+
+	sum := decrypt(t, sk, run(t, pairInput(OpAdd, a, b)))
+	require.Equal(t, []uint64{11, 22, 33, 1}, sum[:4])
+
+	diff := decrypt(t, sk, run(t, pairInput(OpSub, a, b)))
+	require.Equal(t, []uint64{tmod - 9, tmod - 18, tmod - 27, tmod - 3}, diff[:4])
+
+	prod := decrypt(t, sk, run(t, pairInput(OpMul, a, b)))
+	require.Equal(t, []uint64{10, 40, 90, tmod - 2}, prod[:4])
+
+	// Two tensored products summed, then relinearized once
+	ab := run(t, pairInput(OpTensor, a, b))
+	aa := run(t, pairInput(OpTensor, a, a))
+	relin := decrypt(t, sk, run(t, append([]byte{OpRelinearize}, run(t, pairInput(OpAdd, ab, aa))...)))
+	require.Equal(t, []uint64{11, 44, 99}, relin[:3])
+
+	scaled := decrypt(t, sk, run(t, append(pairInput(OpMulPlain, a, nil), slotInput(0, 5, 5, 5)[1:]...)))
+	require.Equal(t, []uint64{5, 10, 15, 0}, scaled[:4])
+
+	shifted := decrypt(t, sk, run(t, append(pairInput(OpAddPlain, a, nil), slotInput(0, 100)[1:]...)))
+	require.Equal(t, []uint64{101, 2, 3}, shifted[:3])
+}
+
+func TestRotations(t *testing.T) {
+	sk := installTestKeys(t)
+	cols := Slots() / 2
+
+	values := make([]uint64, Slots())
+	for i := range values {
+		values[i] = uint64(i)
+	}
+	ct := run(t, slotInput(OpEncrypt, values...))
+
+	for _, k := range []int32{1, 7, -3} {
+		input := binary.BigEndian.AppendUint32([]byte{OpRotateColumns}, uint32(k))
+		got := decrypt(t, sk, run(t, append(input, ct...)))
+		for i := 0; i < cols; i++ {
+			j := ((i+int(k))%cols + cols) % cols
+			require.Equal(t, values[j], got[i], "k=%d slot %d", k, i)
+			require.Equal(t, values[cols+j], got[cols+i], "k=%d slot %d", k, cols+i)
+		}
+	}
+
+	swapped := decrypt(t, sk, run(t, append([]byte{OpRotateRows}, ct...)))
+	require.Equal(t, values[cols:], swapped[:cols])
+	require.Equal(t, values[:cols], swapped[cols:])
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package bgv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/luxfi/lattice/v7/core/rlwe"
+	"github.com/luxfi/precompile/contract"
+	"github.com/luxfi/precompile/modules"
+	"github.com/luxfi/precompile/precompileconfig"
+)
+
+var _ contract.Configurator = (*configurator)(nil)
+
+// ConfigKey is the key used in json config files to specify this precompile config.
+const ConfigKey = "bgvConfig"
+
+// ZChainConfigKey is the json config key of the Z-Chain BGV precompile
+const ZChainConfigKey = "bgvZChainConfig"
+
+// Module is the C-Chain precompile module
+var Module = modules.Module{
+	ConfigKey:    ConfigKey,
+	Address:      ContractAddress,
+	Contract:     BGVPrecompile,
+	Configurator: &configurator{key: ConfigKey},
+}
+
+// ZChainModule is the Z-Chain precompile module. It runs the same contract.
+var ZChainModule = modules.Module{
+	ConfigKey:    ZChainConfigKey,
+	Address:      ZChainAddress,
+	Contract:     BGVPrecompile,
+	Configurator: &configurator{key: ZChainConfigKey},
+}
+
+type configurator struct {
+	key string
+}
+
+func init() {
+	for _, m := range []modules.Module{Module, ZChainModule} {
+		if err := modules.RegisterModule(m); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (c *configurator) MakeConfig() precompileconfig.Config {
+	return &Config{key: c.key}
+}
+
+// Configure installs the network keys the config names
+func (*configurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	config, ok := cfg.(*Config)
+	if !ok {
+		return fmt.Errorf("expected config type %T, got %T: %v", &Config{}, cfg, cfg)
+	}
+
+	raw, err := os.ReadFile(config.PublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading bgv public key: %w", err)
+	}
+	pk := new(rlwe.PublicKey)
+	if err := pk.UnmarshalBinary(raw); err != nil {
+		return fmt.Errorf("decoding bgv public key: %w", err)
+	}
+	if raw, err = os.ReadFile(config.EvaluationKeysPath); err != nil {
+		return fmt.Errorf("reading bgv evaluation keys: %w", err)
+	}
+	evk := new(rlwe.MemEvaluationKeySet)
+	if err := evk.UnmarshalBinary(raw); err != nil {
+		return fmt.Errorf("decoding bgv evaluation keys: %w", err)
+	}
+	return SetNetworkKeys(pk, evk)
+}
+
+// ErrNoKeyPaths is returned by Verify for an enabled config without network
+// key files
+var ErrNoKeyPaths = errors.New("bgv config requires publicKeyPath and evaluationKeysPath")
+
+// Config implements the precompileconfig.Config interface
+type Config struct {
+	Upgrade precompileconfig.Upgrade `json:"upgrade,omitempty"`
+
+	// Files holding the binary encodings of the network public key and of
+	// the evaluation keys (relinearization and rotation keys)
+	PublicKeyPath      string `json:"publicKeyPath,omitempty"`
+	EvaluationKeysPath string `json:"evaluationKeysPath,omitempty"`
+
+	key string
+}
+
+func (c *Config) Key() string {
+	if c.key == "" {
+		return ConfigKey
+	}
+	return c.key
+}
+
+func (c *Config) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *Config) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *Config) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*Config)
+	if !ok {
+		return false
+	}
+	return c.Key() == other.Key() && c.Upgrade.Equal(&other.Upgrade) &&
+		c.PublicKeyPath == other.PublicKeyPath && c.EvaluationKeysPath == other.EvaluationKeysPath
+}
+
+func (c *Config) Verify(chainConfig precompileconfig.ChainConfig) error {
+	if !c.IsDisabled() && (c.PublicKeyPath == "" || c.EvaluationKeysPath == "") {
+		return ErrNoKeyPaths
+	}
+	return nil
+}
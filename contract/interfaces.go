@@ -27,6 +27,9 @@ type StateDB interface {
 	GetState(common.Address, common.Hash) common.Hash
 	SetState(common.Address, common.Hash, common.Hash) common.Hash
 
+	GetTransientState(common.Address, common.Hash) common.Hash
+	SetTransientState(common.Address, common.Hash, common.Hash)
+
 	SetNonce(common.Address, uint64, tracing.NonceChangeReason)
 	GetNonce(common.Address) uint64
 
@@ -59,6 +62,22 @@ type PrecompileEnvironment interface {
 	ReadOnly() bool
 }
 
+// CallerEnvironment is implemented by precompile environments that can call
+// other contracts, such as hooks, from within a precompile. The call runs
+// with at most gas and returns the gas it left unused.
+type CallerEnvironment interface {
+	PrecompileEnvironment
+	Call(addr common.Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, gasLeft uint64, err error)
+}
+
+// ValueEnvironment is implemented by precompile environments that expose
+// the value sent with the call. The EVM has already moved it to the
+// precompile's address when Run is called.
+type ValueEnvironment interface {
+	PrecompileEnvironment
+	Value() *uint256.Int
+}
+
 // AccessibleState defines the interface exposed to stateful precompile contracts
 type AccessibleState interface {
 	GetStateDB() StateDB
@@ -0,0 +1,464 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package statetest provides a deterministic, journaling StateDB for
+// precompile tests. It records every storage write, balance and nonce
+// change and emitted log in order, supports snapshots, approximates storage
+// gas, and renders the net effect of a transaction as a canonical diff that
+// tests compare against golden files.
+package statetest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/geth/core/tracing"
+	ethtypes "github.com/luxfi/geth/core/types"
+	"github.com/luxfi/precompile/contract"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite golden state diffs in testdata")
+
+// Storage gas, following EIP-2929 access costs and EIP-2200 SSTORE pricing
+// (refunds are not modelled)
+const (
+	ColdSloadGas   = 2100
+	WarmAccessGas  = 100
+	SstoreSetGas   = 20000
+	SstoreResetGas = 2900
+)
+
+// ChangeKind identifies what a Change modified
+type ChangeKind uint8
+
+const (
+	ChangeAccount   ChangeKind = iota // Account created
+	ChangeBalance                     // Native balance
+	ChangeCoin                        // Multi-coin balance, Key is the coin ID
+	ChangeNonce                       // Account nonce
+	ChangeStorage                     // Storage slot, Key is the slot
+	ChangeLog                         // Emitted log
+	ChangeTransient                   // Transient storage slot, Key is the slot
+)
+
+// Change is one recorded state write. Balances and nonces are stored as
+// big-endian words in Prev and Value.
+type Change struct {
+	Kind    ChangeKind
+	Address common.Address
+	Key     common.Hash
+	Prev    common.Hash
+	Value   common.Hash
+	Log     *ethtypes.Log
+}
+
+type slotKey struct {
+	addr common.Address
+	key  common.Hash
+}
+
+// StateDB is an in-memory contract.StateDB that journals every write
+type StateDB struct {
+	storage  map[common.Address]map[common.Hash]common.Hash
+	balances map[common.Address]*uint256.Int
+	coins    map[common.Address]map[common.Hash]*big.Int
+	nonces   map[common.Address]uint64
+	accounts map[common.Address]bool
+	logs     []*ethtypes.Log
+
+	// transient is EIP-1153 storage, cleared when the transaction commits
+	transient map[slotKey]common.Hash
+
+	// journal holds every write; the current transaction starts at base
+	journal   []Change
+	base      int
+	snapshots []int
+
+	// Storage gas accounting for the current transaction
+	accessed  map[slotKey]bool
+	originals map[slotKey]common.Hash
+	gasUsed   uint64
+
+	blockNumber uint64
+	txHash      common.Hash
+}
+
+var _ contract.StateDB = (*StateDB)(nil)
+
+// New creates an empty StateDB at block 1
+func New() *StateDB {
+	return &StateDB{
+		storage:     make(map[common.Address]map[common.Hash]common.Hash),
+		balances:    make(map[common.Address]*uint256.Int),
+		coins:       make(map[common.Address]map[common.Hash]*big.Int),
+		nonces:      make(map[common.Address]uint64),
+		accounts:    make(map[common.Address]bool),
+		transient:   make(map[slotKey]common.Hash),
+		accessed:    make(map[slotKey]bool),
+		originals:   make(map[slotKey]common.Hash),
+		blockNumber: 1,
+	}
+}
+
+// GetBlockNumber returns the current block number
+func (s *StateDB) GetBlockNumber() uint64 { return s.blockNumber }
+
+// SetBlockNumber sets the current block number
+func (s *StateDB) SetBlockNumber(block uint64) { s.blockNumber = block }
+
+// SetTxHash sets the hash TxHash reports
+func (s *StateDB) SetTxHash(hash common.Hash) { s.txHash = hash }
+
+// TxHash returns the current transaction hash
+func (s *StateDB) TxHash() common.Hash { return s.txHash }
+
+// GetState returns a storage slot, charging an SLOAD
+func (s *StateDB) GetState(addr common.Address, key common.Hash) common.Hash {
+	s.gasUsed += s.access(addr, key)
+	return s.storage[addr][key]
+}
+
+// SetState writes a storage slot, charging an SSTORE, and returns the
+// previous value
+func (s *StateDB) SetState(addr common.Address, key common.Hash, value common.Hash) common.Hash {
+	gas := s.access(addr, key)
+	prev := s.storage[addr][key]
+	switch original := s.originals[slotKey{addr, key}]; {
+	case prev == value || original != prev:
+		gas += WarmAccessGas
+	case original == (common.Hash{}):
+		gas += SstoreSetGas
+	default:
+		gas += SstoreResetGas
+	}
+	s.gasUsed += gas
+
+	s.journal = append(s.journal, Change{Kind: ChangeStorage, Address: addr, Key: key, Prev: prev, Value: value})
+	s.setSlot(addr, key, value)
+	return prev
+}
+
+// GetTransientState returns a transient storage slot, charging a TLOAD
+func (s *StateDB) GetTransientState(addr common.Address, key common.Hash) common.Hash {
+	s.gasUsed += WarmAccessGas
+	return s.transient[slotKey{addr, key}]
+}
+
+// SetTransientState writes a transient storage slot, charging a TSTORE
+func (s *StateDB) SetTransientState(addr common.Address, key common.Hash, value common.Hash) {
+	s.gasUsed += WarmAccessGas
+	slot := slotKey{addr, key}
+	s.journal = append(s.journal, Change{Kind: ChangeTransient, Address: addr, Key: key, Prev: s.transient[slot], Value: value})
+	s.setTransient(slot, value)
+}
+
+func (s *StateDB) setTransient(slot slotKey, value common.Hash) {
+	if value == (common.Hash{}) {
+		delete(s.transient, slot)
+		return
+	}
+	s.transient[slot] = value
+}
+
+// access marks a slot accessed and returns its access cost
+func (s *StateDB) access(addr common.Address, key common.Hash) uint64 {
+	slot := slotKey{addr, key}
+	if s.accessed[slot] {
+		return WarmAccessGas
+	}
+	s.accessed[slot] = true
+	s.originals[slot] = s.storage[addr][key]
+	return ColdSloadGas
+}
+
+func (s *StateDB) setSlot(addr common.Address, key common.Hash, value common.Hash) {
+	if value == (common.Hash{}) {
+		delete(s.storage[addr], key)
+		return
+	}
+	if s.storage[addr] == nil {
+		s.storage[addr] = make(map[common.Hash]common.Hash)
+	}
+	s.storage[addr][key] = value
+}
+
+// GetNonce returns an account nonce
+func (s *StateDB) GetNonce(addr common.Address) uint64 { return s.nonces[addr] }
+
+// SetNonce sets an account nonce
+func (s *StateDB) SetNonce(addr common.Address, nonce uint64, _ tracing.NonceChangeReason) {
+	s.journal = append(s.journal, Change{
+		Kind:    ChangeNonce,
+		Address: addr,
+		Prev:    uint64Word(s.nonces[addr]),
+		Value:   uint64Word(nonce),
+	})
+	s.nonces[addr] = nonce
+}
+
+// GetBalance returns a native balance
+func (s *StateDB) GetBalance(addr common.Address) *uint256.Int {
+	if balance := s.balances[addr]; balance != nil {
+		return new(uint256.Int).Set(balance)
+	}
+	return uint256.NewInt(0)
+}
+
+// AddBalance credits a native balance and returns the previous balance
+func (s *StateDB) AddBalance(addr common.Address, amount *uint256.Int, _ tracing.BalanceChangeReason) uint256.Int {
+	prev := s.GetBalance(addr)
+	s.setBalance(addr, new(uint256.Int).Add(prev, amount))
+	return *prev
+}
+
+// SubBalance debits a native balance and returns the previous balance
+func (s *StateDB) SubBalance(addr common.Address, amount *uint256.Int, _ tracing.BalanceChangeReason) uint256.Int {
+	prev := s.GetBalance(addr)
+	s.setBalance(addr, new(uint256.Int).Sub(prev, amount))
+	return *prev
+}
+
+func (s *StateDB) setBalance(addr common.Address, balance *uint256.Int) {
+	s.journal = append(s.journal, Change{
+		Kind:    ChangeBalance,
+		Address: addr,
+		Prev:    s.GetBalance(addr).Bytes32(),
+		Value:   balance.Bytes32(),
+	})
+	s.balances[addr] = balance
+}
+
+// GetBalanceMultiCoin returns a multi-coin balance
+func (s *StateDB) GetBalanceMultiCoin(addr common.Address, coinID common.Hash) *big.Int {
+	if balance := s.coins[addr][coinID]; balance != nil {
+		return new(big.Int).Set(balance)
+	}
+	return new(big.Int)
+}
+
+// AddBalanceMultiCoin credits a multi-coin balance
+func (s *StateDB) AddBalanceMultiCoin(addr common.Address, coinID common.Hash, amount *big.Int) {
+	s.setCoin(addr, coinID, new(big.Int).Add(s.GetBalanceMultiCoin(addr, coinID), amount))
+}
+
+// SubBalanceMultiCoin debits a multi-coin balance
+func (s *StateDB) SubBalanceMultiCoin(addr common.Address, coinID common.Hash, amount *big.Int) {
+	s.setCoin(addr, coinID, new(big.Int).Sub(s.GetBalanceMultiCoin(addr, coinID), amount))
+}
+
+func (s *StateDB) setCoin(addr common.Address, coinID common.Hash, balance *big.Int) {
+	s.journal = append(s.journal, Change{
+		Kind:    ChangeCoin,
+		Address: addr,
+		Key:     coinID,
+		Prev:    common.BigToHash(s.GetBalanceMultiCoin(addr, coinID)),
+		Value:   common.BigToHash(balance),
+	})
+	if s.coins[addr] == nil {
+		s.coins[addr] = make(map[common.Hash]*big.Int)
+	}
+	s.coins[addr][coinID] = balance
+}
+
+// CreateAccount marks an account as existing
+func (s *StateDB) CreateAccount(addr common.Address) {
+	if s.accounts[addr] {
+		return
+	}
+	s.journal = append(s.journal, Change{Kind: ChangeAccount, Address: addr})
+	s.accounts[addr] = true
+}
+
+// Exist reports whether an account was created
+func (s *StateDB) Exist(addr common.Address) bool { return s.accounts[addr] }
+
+// AddLog records an emitted log
+func (s *StateDB) AddLog(log *ethtypes.Log) {
+	log.BlockNumber = s.blockNumber
+	log.TxHash = s.txHash
+	log.Index = uint(len(s.logs))
+	s.journal = append(s.journal, Change{Kind: ChangeLog, Address: log.Address, Log: log})
+	s.logs = append(s.logs, log)
+}
+
+// Logs returns every log emitted so far
+func (s *StateDB) Logs() []*ethtypes.Log { return s.logs }
+
+// GetPredicateStorageSlots reports no predicates
+func (s *StateDB) GetPredicateStorageSlots(common.Address, int) ([]byte, bool) { return nil, false }
+
+// Snapshot returns an identifier for the current state
+func (s *StateDB) Snapshot() int {
+	s.snapshots = append(s.snapshots, len(s.journal))
+	return len(s.snapshots) - 1
+}
+
+// RevertToSnapshot undoes every write made since the snapshot was taken
+func (s *StateDB) RevertToSnapshot(id int) {
+	if id < 0 || id >= len(s.snapshots) {
+		panic(fmt.Sprintf("statetest: invalid snapshot %d", id))
+	}
+	mark := s.snapshots[id]
+	for i := len(s.journal) - 1; i >= mark; i-- {
+		c := s.journal[i]
+		switch c.Kind {
+		case ChangeAccount:
+			delete(s.accounts, c.Address)
+		case ChangeBalance:
+			s.balances[c.Address] = new(uint256.Int).SetBytes32(c.Prev[:])
+		case ChangeCoin:
+			s.coins[c.Address][c.Key] = c.Prev.Big()
+		case ChangeNonce:
+			s.nonces[c.Address] = c.Prev.Big().Uint64()
+		case ChangeStorage:
+			s.setSlot(c.Address, c.Key, c.Prev)
+		case ChangeLog:
+			s.logs = s.logs[:len(s.logs)-1]
+		case ChangeTransient:
+			s.setTransient(slotKey{c.Address, c.Key}, c.Prev)
+		}
+	}
+	s.journal = s.journal[:mark]
+	s.snapshots = s.snapshots[:id]
+	if s.base > mark {
+		s.base = mark
+	}
+}
+
+// Commit ends the current transaction: later diffs, changes and gas start
+// from the current state, earlier snapshots are discarded and transient
+// storage is cleared
+func (s *StateDB) Commit() {
+	s.base = len(s.journal)
+	s.snapshots = nil
+	s.transient = make(map[slotKey]common.Hash)
+	s.accessed = make(map[slotKey]bool)
+	s.originals = make(map[slotKey]common.Hash)
+	s.gasUsed = 0
+}
+
+// GasUsed returns the storage gas charged in the current transaction
+func (s *StateDB) GasUsed() uint64 { return s.gasUsed }
+
+// Changes returns the writes of the current transaction in order
+func (s *StateDB) Changes() []Change {
+	return append([]Change(nil), s.journal[s.base:]...)
+}
+
+// Diff renders the net effect of the current transaction, one line per
+// changed value, sorted by kind, address and key, followed by the emitted
+// logs in order. Values written back to their starting value and transient
+// storage are omitted.
+func (s *StateDB) Diff() string {
+	type entry struct {
+		first, last Change
+	}
+	type entryKey struct {
+		kind ChangeKind
+		addr common.Address
+		key  common.Hash
+	}
+	entries := make(map[entryKey]*entry)
+	var logs []string
+	for _, c := range s.journal[s.base:] {
+		if c.Kind == ChangeLog {
+			logs = append(logs, formatLog(c.Log))
+			continue
+		}
+		if c.Kind == ChangeTransient {
+			continue
+		}
+		k := entryKey{c.Kind, c.Address, c.Key}
+		if e := entries[k]; e != nil {
+			e.last = c
+		} else {
+			entries[k] = &entry{first: c, last: c}
+		}
+	}
+
+	keys := make([]entryKey, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if c := bytes.Compare(a.addr[:], b.addr[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(a.key[:], b.key[:]) < 0
+	})
+
+	var lines []string
+	for _, k := range keys {
+		e := entries[k]
+		prev, value := e.first.Prev, e.last.Value
+		if k.kind != ChangeAccount && prev == value {
+			continue
+		}
+		switch k.kind {
+		case ChangeAccount:
+			lines = append(lines, fmt.Sprintf("account %s: created", k.addr.Hex()))
+		case ChangeBalance:
+			lines = append(lines, fmt.Sprintf("balance %s: %s -> %s", k.addr.Hex(), prev.Big(), value.Big()))
+		case ChangeCoin:
+			lines = append(lines, fmt.Sprintf("coin %s %s: %s -> %s", k.addr.Hex(), k.key.Hex(), prev.Big(), value.Big()))
+		case ChangeNonce:
+			lines = append(lines, fmt.Sprintf("nonce %s: %s -> %s", k.addr.Hex(), prev.Big(), value.Big()))
+		case ChangeStorage:
+			lines = append(lines, fmt.Sprintf("storage %s %s: %s -> %s", k.addr.Hex(), k.key.Hex(), prev.Hex(), value.Hex()))
+		}
+	}
+	lines = append(lines, logs...)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func formatLog(log *ethtypes.Log) string {
+	topics := make([]string, len(log.Topics))
+	for i, topic := range log.Topics {
+		topics[i] = topic.Hex()
+	}
+	return fmt.Sprintf("log %s [%s] 0x%x", log.Address.Hex(), strings.Join(topics, " "), log.Data)
+}
+
+func uint64Word(v uint64) common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(v))
+}
+
+// CheckGolden compares a diff with testdata/<name>.golden. Running the
+// tests with -update-golden rewrites the file instead.
+func CheckGolden(t testing.TB, name, diff string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatalf("create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(diff), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v (run with -update-golden to create it)", path, err)
+	}
+	if string(want) != diff {
+		t.Errorf("state diff mismatch for %s\n--- want\n%s--- got\n%s", name, want, diff)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package statetest
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/luxfi/geth/common"
+	ethtypes "github.com/luxfi/geth/core/types"
+)
+
+func TestJournalDiff(t *testing.T) {
+	s := New()
+	a := common.HexToAddress("0x01")
+	b := common.HexToAddress("0x02")
+	slot := common.HexToHash("0x01")
+
+	s.SetState(a, slot, common.HexToHash("0x2a"))
+	s.GetState(a, slot)
+	s.SetState(a, slot, common.HexToHash("0x2b"))
+	s.AddBalance(b, uint256.NewInt(100), 0)
+
+	// Reverted writes leave no trace in state or diff
+	snap := s.Snapshot()
+	s.SetState(a, common.HexToHash("0x02"), common.HexToHash("0x2a"))
+	s.SubBalance(b, uint256.NewInt(40), 0)
+	s.AddLog(&ethtypes.Log{Address: b})
+	s.RevertToSnapshot(snap)
+
+	if got := s.GetBalance(b); got.Uint64() != 100 {
+		t.Errorf("Expected balance 100 after revert, got %d", got.Uint64())
+	}
+	if len(s.Logs()) != 0 {
+		t.Errorf("Expected reverted log to be dropped, got %d logs", len(s.Logs()))
+	}
+
+	s.SetNonce(b, 1, 0)
+	s.AddLog(&ethtypes.Log{Address: a, Topics: []common.Hash{common.HexToHash("0xaa")}, Data: []byte{1, 2}})
+
+	if n := len(s.Changes()); n != 5 {
+		t.Errorf("Expected 5 recorded changes, got %d", n)
+	}
+
+	// Cold set, warm read, warm dirty write, and the reverted cold set
+	if want := uint64(ColdSloadGas + SstoreSetGas + 2*WarmAccessGas + WarmAccessGas + ColdSloadGas + SstoreSetGas); s.GasUsed() != want {
+		t.Errorf("Expected gas %d, got %d", want, s.GasUsed())
+	}
+
+	CheckGolden(t, "journal", s.Diff())
+}
+
+func TestCommit(t *testing.T) {
+	s := New()
+	a := common.HexToAddress("0x01")
+	slot := common.HexToHash("0x01")
+
+	s.SetState(a, slot, common.HexToHash("0x2a"))
+	s.Commit()
+	if diff := s.Diff(); diff != "" {
+		t.Errorf("Expected empty diff after commit, got %q", diff)
+	}
+
+	// A write restored within the transaction is not a change
+	s.SetState(a, slot, common.HexToHash("0x2b"))
+	s.SetState(a, slot, common.HexToHash("0x2a"))
+	if diff := s.Diff(); diff != "" {
+		t.Errorf("Expected empty diff for restored slot, got %q", diff)
+	}
+	if want := uint64(ColdSloadGas + SstoreResetGas + 2*WarmAccessGas); s.GasUsed() != want {
+		t.Errorf("Expected gas %d, got %d", want, s.GasUsed())
+	}
+}
+
+func TestTransientState(t *testing.T) {
+	s := New()
+	a := common.HexToAddress("0x01")
+	slot := common.HexToHash("0x01")
+
+	s.SetTransientState(a, slot, common.HexToHash("0x2a"))
+	snap := s.Snapshot()
+	s.SetTransientState(a, slot, common.HexToHash("0x2b"))
+	s.RevertToSnapshot(snap)
+	if got := s.GetTransientState(a, slot); got != common.HexToHash("0x2a") {
+		t.Errorf("Expected reverted transient slot 0x2a, got %s", got.Hex())
+	}
+	if diff := s.Diff(); diff != "" {
+		t.Errorf("Expected transient writes to stay out of the diff, got %q", diff)
+	}
+	if want := uint64(3 * WarmAccessGas); s.GasUsed() != want {
+		t.Errorf("Expected gas %d, got %d", want, s.GasUsed())
+	}
+
+	s.Commit()
+	if got := s.GetTransientState(a, slot); got != (common.Hash{}) {
+		t.Errorf("Expected transient slot cleared by commit, got %s", got.Hex())
+	}
+}
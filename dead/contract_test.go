@@ -16,18 +16,20 @@ import (
 
 // MockStateDB implements contract.StateDB interface for testing
 type MockStateDB struct {
-	storage  map[common.Address]map[common.Hash]common.Hash
-	balances map[common.Address]*uint256.Int
-	nonces   map[common.Address]uint64
-	logs     []*ethtypes.Log
+	storage   map[common.Address]map[common.Hash]common.Hash
+	transient map[common.Address]map[common.Hash]common.Hash
+	balances  map[common.Address]*uint256.Int
+	nonces    map[common.Address]uint64
+	logs      []*ethtypes.Log
 }
 
 func NewMockStateDB() *MockStateDB {
 	return &MockStateDB{
-		storage:  make(map[common.Address]map[common.Hash]common.Hash),
-		balances: make(map[common.Address]*uint256.Int),
-		nonces:   make(map[common.Address]uint64),
-		logs:     make([]*ethtypes.Log, 0),
+		storage:   make(map[common.Address]map[common.Hash]common.Hash),
+		transient: make(map[common.Address]map[common.Hash]common.Hash),
+		balances:  make(map[common.Address]*uint256.Int),
+		nonces:    make(map[common.Address]uint64),
+		logs:      make([]*ethtypes.Log, 0),
 	}
 }
 
@@ -47,6 +49,17 @@ func (m *MockStateDB) SetState(addr common.Address, key, value common.Hash) comm
 	return prev
 }
 
+func (m *MockStateDB) GetTransientState(addr common.Address, key common.Hash) common.Hash {
+	return m.transient[addr][key]
+}
+
+func (m *MockStateDB) SetTransientState(addr common.Address, key, value common.Hash) {
+	if m.transient[addr] == nil {
+		m.transient[addr] = make(map[common.Hash]common.Hash)
+	}
+	m.transient[addr][key] = value
+}
+
 func (m *MockStateDB) GetBalance(addr common.Address) *uint256.Int {
 	if bal, ok := m.balances[addr]; ok {
 		return bal.Clone()
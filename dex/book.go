@@ -0,0 +1,1185 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	ethtypes "github.com/luxfi/geth/core/types"
+	"github.com/luxfi/precompile/contract"
+	"github.com/zeebo/blake3"
+)
+
+// =========================================================================
+// Central Limit Order Book (LXBook)
+// =========================================================================
+//
+// LXBook matches limit orders on markets of a base currency priced in a
+// quote currency. Prices are quote per whole base unit, scaled by
+// BookPriceScale, and quantities are in base units. The book lives at the
+// LXBook address. Each side of a market links its price levels from the
+// best price down, with a FIFO queue of orders per level. The best bid and
+// ask are read in O(1), and emptied levels are unlinked in O(1); a new
+// level is linked in by walking from the best price.
+//
+// Matching is price-time priority: an incoming order fills against the best
+// opposing level, oldest order first, at the resting order's price, until it
+// is filled or no longer crosses. The remainder then rests (GTC), is
+// cancelled (IOC), or, for a post-only order that would cross, the order is
+// rejected. Self-trades follow the taker's STP policy, and placements are
+// rate limited per block (see book_controls.go).
+//
+// Orders are funded through flash accounting. Placing an order charges its
+// owner, the current locker, for the most it can pay: the quantity for a
+// sell, or its value at the limit price for a buy. The taker's proceeds and
+// unused funds are credited to its delta. Resting orders are paid in
+// ERC-6909 claims, since their owners are not the locker. Fees are a share
+// of what each side receives and accrue to the book until the protocol fee
+// controller collects them.
+
+const (
+	// MaxBookFeeBps caps maker and taker fees (1%)
+	MaxBookFeeBps uint32 = 100
+
+	// MaxOrderFills bounds the matches of one placement, whatever its gas
+	MaxOrderFills uint64 = 256
+)
+
+// BookPriceScale is the fixed-point scale of order prices (1e18)
+var BookPriceScale = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// OrderSide is the side of a limit order
+type OrderSide uint8
+
+const (
+	OrderBuy  OrderSide = iota // Bid: pays quote for base
+	OrderSell                  // Ask: pays base for quote
+)
+
+// TimeInForce selects what happens to an order's unfilled remainder
+type TimeInForce uint8
+
+const (
+	TimeInForceGTC      TimeInForce = iota // Rest until filled or cancelled
+	TimeInForceIOC                         // Cancel the remainder after matching
+	TimeInForcePostOnly                    // Rest without matching; rejected if it would cross
+)
+
+// Order is a limit order
+type Order struct {
+	ID          uint64
+	Owner       common.Address
+	Base        Currency
+	Quote       Currency
+	Side        OrderSide
+	Price       *big.Int // Quote per base unit, scaled by BookPriceScale
+	Quantity    *big.Int // Quantity when placed or last modified
+	Remaining   *big.Int // Unfilled quantity
+	Locked      *big.Int // Funds held for the remainder: base for sells, quote for buys
+	TimeInForce TimeInForce
+}
+
+// copy returns a copy of the order
+func (o *Order) copy() *Order {
+	c := *o
+	c.Price = new(big.Int).Set(o.Price)
+	c.Quantity = new(big.Int).Set(o.Quantity)
+	c.Remaining = new(big.Int).Set(o.Remaining)
+	c.Locked = new(big.Int).Set(o.Locked)
+	return &c
+}
+
+// lockCurrency returns the currency the order pays with
+func (o *Order) lockCurrency() Currency {
+	if o.Side == OrderBuy {
+		return o.Quote
+	}
+	return o.Base
+}
+
+// required returns the funds the order needs to pay for remaining
+func (o *Order) required(remaining *big.Int) *big.Int {
+	if o.Side == OrderBuy {
+		return quoteAmount(remaining, o.Price)
+	}
+	return new(big.Int).Set(remaining)
+}
+
+// crosses reports whether the order would trade at a resting price
+func (o *Order) crosses(price *big.Int) bool {
+	if o.Side == OrderBuy {
+		return o.Price.Cmp(price) >= 0
+	}
+	return o.Price.Cmp(price) <= 0
+}
+
+// quoteAmount returns the quote value of quantity at price, rounded down
+func quoteAmount(quantity, price *big.Int) *big.Int {
+	amount := new(big.Int).Mul(quantity, price)
+	return amount.Div(amount, BookPriceScale)
+}
+
+// feeAmount returns feeBps of amount, rounded down
+func feeAmount(amount *big.Int, feeBps uint32) *big.Int {
+	fee := new(big.Int).Mul(amount, big.NewInt(int64(feeBps)))
+	return fee.Div(fee, big.NewInt(10_000))
+}
+
+// MarketID computes the identifier of the market of base priced in quote
+func MarketID(base, quote Currency) [32]byte {
+	h := blake3.New()
+	h.Write(base.ToBytes())
+	h.Write(quote.ToBytes())
+	var id [32]byte
+	h.Digest().Read(id[:])
+	return id
+}
+
+// Fill is one match between a resting (maker) and an incoming (taker) order
+type Fill struct {
+	MakerOrder  uint64
+	TakerOrder  uint64
+	Maker       common.Address
+	Taker       common.Address
+	Price       *big.Int // The maker's price
+	Quantity    *big.Int // Base traded
+	QuoteAmount *big.Int // Quote traded
+	MakerFee    *big.Int // In the currency the maker receives
+	TakerFee    *big.Int // In the currency the taker receives
+}
+
+// BookReceipt reports what placing or modifying an order did
+type BookReceipt struct {
+	Order     *Order   // The order after matching
+	Fills     []Fill   // In match order
+	Cancelled []*Order // Makers removed by STP, and the order itself if its remainder was cancelled
+	Rested    bool     // The remainder rests on the book
+}
+
+// bookCredit is an amount of currency owed to an account
+type bookCredit struct {
+	owner    common.Address
+	currency Currency
+	amount   *big.Int
+}
+
+// bookResult is a receipt with the payments it implies
+type bookResult struct {
+	receipt      BookReceipt
+	takerCredits []bookCredit // Credited to the taker's delta
+	makerCredits []bookCredit // Credited to makers as claims
+}
+
+// credit adds an amount owed to the taker or a maker
+func (r *bookResult) credit(taker bool, owner common.Address, currency Currency, amount *big.Int) {
+	if amount.Sign() <= 0 {
+		return
+	}
+	c := bookCredit{owner: owner, currency: currency, amount: new(big.Int).Set(amount)}
+	if taker {
+		r.takerCredits = append(r.takerCredits, c)
+	} else {
+		r.makerCredits = append(r.makerCredits, c)
+	}
+}
+
+// Storage key prefixes for LXBook, stored at the LXBook address. Sides are
+// 0 for asks and 1 for bids, and prices are 32-byte words:
+//
+//	book/last                              -> ID of the last order placed
+//	book/fee                               -> maker bps (bytes 24..28) | taker bps (bytes 28..32)
+//	book/acc || currency                   -> accrued fees
+//	book/ord || id || field                -> "own", "base", "quote", "px", "qty", "rem", "lck",
+//	                                          "meta" (side byte 30, time in force byte 31),
+//	                                          "prev", "next" (neighbours in the level queue)
+//	book/side || market || side            -> best price, zero when the side is empty
+//	book/lvl || market || side || price || field
+//	                                       -> "prev", "next" (better and worse prices),
+//	                                          "head", "tail" (oldest and newest order), "tot"
+var (
+	bookLastPrefix  = []byte("book/last")
+	bookFeePrefix   = []byte("book/fee")
+	bookAccPrefix   = []byte("book/acc")
+	bookOrderPrefix = []byte("book/ord")
+	bookSidePrefix  = []byte("book/side")
+	bookLevelPrefix = []byte("book/lvl")
+)
+
+// bookOrderKey returns the storage key of an order field
+func bookOrderKey(id uint64, field string) common.Hash {
+	return makeStorageKey(bookOrderPrefix, append(encodeUint64(id)[24:], field...))
+}
+
+// orderLink loads an order's neighbour in its level queue
+func orderLink(stateDB StateDB, id uint64, field string) uint64 {
+	return decodeUint64Word(stateDB.GetState(lxBookAddr, bookOrderKey(id, field)).Bytes())
+}
+
+// setOrderLink stores an order's neighbour in its level queue
+func setOrderLink(stateDB StateDB, id uint64, field string, neighbour uint64) {
+	stateDB.SetState(lxBookAddr, bookOrderKey(id, field), common.BytesToHash(encodeUint64(neighbour)))
+}
+
+// bookSide is one side of a market in storage: price levels linked from
+// the best price down, each a FIFO queue of linked orders
+type bookSide struct {
+	stateDB StateDB
+	market  [32]byte
+	bids    bool
+}
+
+// sideID returns the market and side bytes that lead the side's keys
+func (s *bookSide) sideID() []byte {
+	id := append([]byte(nil), s.market[:]...)
+	if s.bids {
+		return append(id, 1)
+	}
+	return append(id, 0)
+}
+
+// levelKey returns the storage key of a price level field
+func (s *bookSide) levelKey(price *big.Int, field string) common.Hash {
+	id := append(s.sideID(), common.BigToHash(price).Bytes()...)
+	return makeStorageKey(bookLevelPrefix, append(id, field...))
+}
+
+// levelPrice loads a price field of a level, or nil if it is unset
+func (s *bookSide) levelPrice(price *big.Int, field string) *big.Int {
+	if v := s.stateDB.GetState(lxBookAddr, s.levelKey(price, field)).Big(); v.Sign() != 0 {
+		return v
+	}
+	return nil
+}
+
+// setLevelPrice stores a price field of a level; nil clears it
+func (s *bookSide) setLevelPrice(price *big.Int, field string, v *big.Int) {
+	var word common.Hash
+	if v != nil {
+		word = common.BigToHash(v)
+	}
+	s.stateDB.SetState(lxBookAddr, s.levelKey(price, field), word)
+}
+
+// levelOrder loads the head or tail order of a level, or zero
+func (s *bookSide) levelOrder(price *big.Int, field string) uint64 {
+	return decodeUint64Word(s.stateDB.GetState(lxBookAddr, s.levelKey(price, field)).Bytes())
+}
+
+// setLevelOrder stores the head or tail order of a level
+func (s *bookSide) setLevelOrder(price *big.Int, field string, id uint64) {
+	s.stateDB.SetState(lxBookAddr, s.levelKey(price, field), common.BytesToHash(encodeUint64(id)))
+}
+
+// best returns the best price, or nil if the side is empty
+func (s *bookSide) best() *big.Int {
+	if v := s.stateDB.GetState(lxBookAddr, makeStorageKey(bookSidePrefix, s.sideID())).Big(); v.Sign() != 0 {
+		return v
+	}
+	return nil
+}
+
+// setBest stores the best price; nil marks the side empty
+func (s *bookSide) setBest(price *big.Int) {
+	var word common.Hash
+	if price != nil {
+		word = common.BigToHash(price)
+	}
+	s.stateDB.SetState(lxBookAddr, makeStorageKey(bookSidePrefix, s.sideID()), word)
+}
+
+// better reports whether price a has priority over price b
+func (s *bookSide) better(a, b *big.Int) bool {
+	if s.bids {
+		return a.Cmp(b) > 0
+	}
+	return a.Cmp(b) < 0
+}
+
+// total returns the quantity resting at a price
+func (s *bookSide) total(price *big.Int) *big.Int {
+	return s.stateDB.GetState(lxBookAddr, s.levelKey(price, "tot")).Big()
+}
+
+// addTotal adds delta, which may be negative, to the quantity resting at a
+// price
+func (s *bookSide) addTotal(price, delta *big.Int) {
+	total := s.total(price)
+	s.stateDB.SetState(lxBookAddr, s.levelKey(price, "tot"), common.BigToHash(total.Add(total, delta)))
+}
+
+// add queues an order at the back of its price level, linking the level in
+// price order if it is new
+func (s *bookSide) add(order *Order) {
+	price := order.Price
+	if tail := s.levelOrder(price, "tail"); tail != 0 {
+		setOrderLink(s.stateDB, tail, "next", order.ID)
+		setOrderLink(s.stateDB, order.ID, "prev", tail)
+	} else {
+		// Walk from the best price to the first level this one beats
+		var prev *big.Int
+		next := s.best()
+		for next != nil && !s.better(price, next) {
+			prev, next = next, s.levelPrice(next, "next")
+		}
+		s.setLevelPrice(price, "prev", prev)
+		s.setLevelPrice(price, "next", next)
+		if prev == nil {
+			s.setBest(price)
+		} else {
+			s.setLevelPrice(prev, "next", price)
+		}
+		if next != nil {
+			s.setLevelPrice(next, "prev", price)
+		}
+		s.setLevelOrder(price, "head", order.ID)
+	}
+	s.setLevelOrder(price, "tail", order.ID)
+	s.addTotal(price, order.Remaining)
+}
+
+// remove takes an order off its level, unlinking the level once empty
+func (s *bookSide) remove(order *Order) {
+	price := order.Price
+	prev := orderLink(s.stateDB, order.ID, "prev")
+	next := orderLink(s.stateDB, order.ID, "next")
+	if prev != 0 {
+		setOrderLink(s.stateDB, prev, "next", next)
+	} else {
+		s.setLevelOrder(price, "head", next)
+	}
+	if next != 0 {
+		setOrderLink(s.stateDB, next, "prev", prev)
+	} else {
+		s.setLevelOrder(price, "tail", prev)
+	}
+	s.addTotal(price, new(big.Int).Neg(order.Remaining))
+	if prev != 0 || next != 0 {
+		return
+	}
+
+	better := s.levelPrice(price, "prev")
+	worse := s.levelPrice(price, "next")
+	if better != nil {
+		s.setLevelPrice(better, "next", worse)
+	} else {
+		s.setBest(worse)
+	}
+	if worse != nil {
+		s.setLevelPrice(worse, "prev", better)
+	}
+	s.setLevelPrice(price, "prev", nil)
+	s.setLevelPrice(price, "next", nil)
+	s.setLevelPrice(price, "tot", nil)
+}
+
+// OrderBook matches limit orders of all markets. Orders, price levels and
+// fees live at the LXBook address.
+type OrderBook struct {
+	// controls holds STP policies and order rate limits
+	controls *BookControls
+}
+
+// NewOrderBook creates an order book using controls for STP and rate limits
+func NewOrderBook(controls *BookControls) *OrderBook {
+	return &OrderBook{controls: controls}
+}
+
+// Controls returns the book's STP policies and rate limits
+func (b *OrderBook) Controls() *BookControls {
+	return b.controls
+}
+
+// side returns one side of a market
+func (b *OrderBook) side(stateDB StateDB, base, quote Currency, bids bool) *bookSide {
+	return &bookSide{stateDB: stateDB, market: MarketID(base, quote), bids: bids}
+}
+
+// SetFees sets the maker and taker fees in basis points
+func (b *OrderBook) SetFees(stateDB StateDB, makerFeeBps, takerFeeBps uint32) error {
+	if makerFeeBps > MaxBookFeeBps || takerFeeBps > MaxBookFeeBps {
+		return ErrInvalidBookFee
+	}
+
+	var word common.Hash
+	binary.BigEndian.PutUint32(word[24:28], makerFeeBps)
+	binary.BigEndian.PutUint32(word[28:32], takerFeeBps)
+	stateDB.SetState(lxBookAddr, makeStorageKey(bookFeePrefix, nil), word)
+	return nil
+}
+
+// Fees returns the maker and taker fees in basis points
+func (b *OrderBook) Fees(stateDB StateDB) (uint32, uint32) {
+	word := stateDB.GetState(lxBookAddr, makeStorageKey(bookFeePrefix, nil))
+	return binary.BigEndian.Uint32(word[24:28]), binary.BigEndian.Uint32(word[28:32])
+}
+
+// AccruedFees returns the fees accrued in a currency
+func (b *OrderBook) AccruedFees(stateDB StateDB, currency Currency) *big.Int {
+	return stateDB.GetState(lxBookAddr, makeStorageKey(bookAccPrefix, currency.Address.Bytes())).Big()
+}
+
+// Order returns a resting order
+func (b *OrderBook) Order(stateDB StateDB, id uint64) (*Order, error) {
+	get := func(field string) common.Hash {
+		return stateDB.GetState(lxBookAddr, bookOrderKey(id, field))
+	}
+
+	owner := common.BytesToAddress(get("own").Bytes())
+	if owner == (common.Address{}) {
+		return nil, ErrOrderNotFound
+	}
+	meta := get("meta")
+	return &Order{
+		ID:          id,
+		Owner:       owner,
+		Base:        Currency{Address: common.BytesToAddress(get("base").Bytes())},
+		Quote:       Currency{Address: common.BytesToAddress(get("quote").Bytes())},
+		Side:        OrderSide(meta[30]),
+		Price:       get("px").Big(),
+		Quantity:    get("qty").Big(),
+		Remaining:   get("rem").Big(),
+		Locked:      get("lck").Big(),
+		TimeInForce: TimeInForce(meta[31]),
+	}, nil
+}
+
+// saveOrder stores a resting order's fields; its queue links are kept by
+// its side
+func (b *OrderBook) saveOrder(stateDB StateDB, order *Order) {
+	set := func(field string, value common.Hash) {
+		stateDB.SetState(lxBookAddr, bookOrderKey(order.ID, field), value)
+	}
+
+	var meta common.Hash
+	meta[30] = byte(order.Side)
+	meta[31] = byte(order.TimeInForce)
+	set("own", common.BytesToHash(order.Owner.Bytes()))
+	set("base", common.BytesToHash(order.Base.Address.Bytes()))
+	set("quote", common.BytesToHash(order.Quote.Address.Bytes()))
+	set("meta", meta)
+	set("px", common.BigToHash(order.Price))
+	set("qty", common.BigToHash(order.Quantity))
+	set("rem", common.BigToHash(order.Remaining))
+	set("lck", common.BigToHash(order.Locked))
+}
+
+// deleteOrder clears an order that has left the book
+func (b *OrderBook) deleteOrder(stateDB StateDB, id uint64) {
+	for _, field := range []string{"own", "base", "quote", "meta", "px", "qty", "rem", "lck", "prev", "next"} {
+		stateDB.SetState(lxBookAddr, bookOrderKey(id, field), common.Hash{})
+	}
+}
+
+// BestBid returns the highest bid of a market and the quantity resting at
+// it, or ErrOrderNotFound if there are no bids
+func (b *OrderBook) BestBid(stateDB StateDB, base, quote Currency) (*big.Int, *big.Int, error) {
+	return b.best(stateDB, base, quote, true)
+}
+
+// BestAsk returns the lowest ask of a market and the quantity resting at
+// it, or ErrOrderNotFound if there are no asks
+func (b *OrderBook) BestAsk(stateDB StateDB, base, quote Currency) (*big.Int, *big.Int, error) {
+	return b.best(stateDB, base, quote, false)
+}
+
+func (b *OrderBook) best(stateDB StateDB, base, quote Currency, bids bool) (*big.Int, *big.Int, error) {
+	side := b.side(stateDB, base, quote, bids)
+	price := side.best()
+	if price == nil {
+		return nil, nil, ErrOrderNotFound
+	}
+	return price, side.total(price), nil
+}
+
+// ImpactPrice returns the average price of trading notional, in quote,
+// against the bids or asks of a market, best level first. It returns
+// ErrInsufficientLiquidity if the side is too thin to absorb notional.
+func (b *OrderBook) ImpactPrice(stateDB StateDB, base, quote Currency, bids bool, notional *big.Int) (*big.Int, error) {
+	if notional == nil || notional.Sign() <= 0 {
+		return nil, ErrInvalidParameter
+	}
+
+	side := b.side(stateDB, base, quote, bids)
+	remaining := new(big.Int).Set(notional)
+	quantity := new(big.Int)
+	for price := side.best(); price != nil; price = side.levelPrice(price, "next") {
+		total := side.total(price)
+		value := quoteAmount(total, price)
+		if value.Cmp(remaining) >= 0 {
+			partial := new(big.Int).Mul(remaining, BookPriceScale)
+			quantity.Add(quantity, partial.Div(partial, price))
+			remaining.SetInt64(0)
+			break
+		}
+		quantity.Add(quantity, total)
+		remaining.Sub(remaining, value)
+	}
+	if remaining.Sign() > 0 || quantity.Sign() == 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+
+	price := new(big.Int).Mul(notional, BookPriceScale)
+	return price.Div(price, quantity), nil
+}
+
+// place matches an order funded with order.Locked against the book and
+// rests or cancels its remainder. Matching stops after maxFills matches,
+// as if the book no longer crossed.
+func (b *OrderBook) place(stateDB StateDB, order *Order, maxFills int) (*bookResult, error) {
+	own := b.side(stateDB, order.Base, order.Quote, order.Side == OrderBuy)
+	opposite := b.side(stateDB, order.Base, order.Quote, order.Side == OrderSell)
+	if order.TimeInForce == TimeInForcePostOnly {
+		if price := opposite.best(); price != nil && order.crosses(price) {
+			return nil, ErrOrderWouldCross
+		}
+	}
+	if err := b.controls.AdmitOrder(stateDB, order.Owner); err != nil {
+		return nil, err
+	}
+
+	if order.ID == 0 {
+		lastKey := makeStorageKey(bookLastPrefix, nil)
+		order.ID = decodeUint64Word(stateDB.GetState(lxBookAddr, lastKey).Bytes()) + 1
+		stateDB.SetState(lxBookAddr, lastKey, common.BytesToHash(encodeUint64(order.ID)))
+	}
+
+	result := &bookResult{}
+	for matches := 0; order.Remaining.Sign() > 0 && matches < maxFills; matches++ {
+		price := opposite.best()
+		if price == nil || !order.crosses(price) {
+			break
+		}
+		maker, err := b.Order(stateDB, opposite.levelOrder(price, "head"))
+		if err != nil {
+			return nil, err
+		}
+
+		if b.controls.IsSelfTrade(stateDB, maker.Owner, order.Owner) {
+			out := b.controls.ResolveSelfTrade(stateDB, order.Owner, maker.Remaining, order.Remaining)
+			b.reduceMaker(stateDB, result, opposite, maker, out.MakerRemaining, true)
+			if out.CancelTaker && b.controls.GetSTPMode(stateDB, order.Owner) == STPCancelNewest {
+				result.receipt.Cancelled = append(result.receipt.Cancelled, order.copy())
+			}
+			order.Remaining = out.TakerRemaining
+			if out.CancelTaker {
+				break
+			}
+			continue
+		}
+		b.match(stateDB, result, opposite, maker, order)
+	}
+
+	// The remainder rests with the funds it needs, or is cancelled. An order
+	// that ran out of matches while still crossing cannot rest.
+	rest := order.Remaining.Sign() > 0 && order.TimeInForce != TimeInForceIOC
+	if price := opposite.best(); rest && price != nil && order.crosses(price) {
+		rest = false
+	}
+	if rest {
+		required := order.required(order.Remaining)
+		result.credit(true, order.Owner, order.lockCurrency(), new(big.Int).Sub(order.Locked, required))
+		order.Locked = required
+		b.saveOrder(stateDB, order)
+		own.add(order)
+		result.receipt.Rested = true
+	} else {
+		if order.Remaining.Sign() > 0 {
+			result.receipt.Cancelled = append(result.receipt.Cancelled, order.copy())
+		}
+		result.credit(true, order.Owner, order.lockCurrency(), order.Locked)
+		order.Locked = new(big.Int)
+	}
+	result.receipt.Order = order.copy()
+	return result, nil
+}
+
+// match fills the taker against a maker at the front of its level
+func (b *OrderBook) match(stateDB StateDB, result *bookResult, side *bookSide, maker, taker *Order) {
+	quantity := new(big.Int).Set(maker.Remaining)
+	if taker.Remaining.Cmp(quantity) < 0 {
+		quantity.Set(taker.Remaining)
+	}
+	quote := quoteAmount(quantity, maker.Price)
+
+	// Each side receives what the other pays, less its fee
+	takerReceives, makerReceives := quantity, quote
+	takerCurrency, makerCurrency := taker.Base, taker.Quote
+	if taker.Side == OrderSell {
+		takerReceives, makerReceives = quote, quantity
+		takerCurrency, makerCurrency = taker.Quote, taker.Base
+	}
+	makerFeeBps, takerFeeBps := b.Fees(stateDB)
+	takerFee := feeAmount(takerReceives, takerFeeBps)
+	makerFee := feeAmount(makerReceives, makerFeeBps)
+	b.accrue(stateDB, takerCurrency, takerFee)
+	b.accrue(stateDB, makerCurrency, makerFee)
+
+	result.credit(true, taker.Owner, takerCurrency, new(big.Int).Sub(takerReceives, takerFee))
+	result.credit(false, maker.Owner, makerCurrency, new(big.Int).Sub(makerReceives, makerFee))
+
+	// Each side pays from its funds what the other receives. A buying taker
+	// pays the maker's price, and keeps the rest of its limit funds.
+	taker.Locked.Sub(taker.Locked, makerReceives)
+	maker.Locked.Sub(maker.Locked, takerReceives)
+
+	result.receipt.Fills = append(result.receipt.Fills, Fill{
+		MakerOrder:  maker.ID,
+		TakerOrder:  taker.ID,
+		Maker:       maker.Owner,
+		Taker:       taker.Owner,
+		Price:       new(big.Int).Set(maker.Price),
+		Quantity:    quantity,
+		QuoteAmount: quote,
+		MakerFee:    makerFee,
+		TakerFee:    takerFee,
+	})
+	taker.Remaining = new(big.Int).Sub(taker.Remaining, quantity)
+	b.reduceMaker(stateDB, result, side, maker, new(big.Int).Sub(maker.Remaining, quantity), false)
+}
+
+// reduceMaker sets a resting order's remaining quantity, paying out the
+// funds it no longer needs. An order with nothing left leaves the book.
+func (b *OrderBook) reduceMaker(stateDB StateDB, result *bookResult, side *bookSide, maker *Order, remaining *big.Int, cancelled bool) {
+	side.addTotal(maker.Price, new(big.Int).Sub(remaining, maker.Remaining))
+	maker.Remaining = new(big.Int).Set(remaining)
+
+	required := maker.required(remaining)
+	if remaining.Sign() == 0 {
+		required.SetInt64(0)
+	}
+	result.credit(false, maker.Owner, maker.lockCurrency(), new(big.Int).Sub(maker.Locked, required))
+	maker.Locked = required
+
+	if remaining.Sign() > 0 {
+		b.saveOrder(stateDB, maker)
+		return
+	}
+	side.remove(maker)
+	b.deleteOrder(stateDB, maker.ID)
+	if cancelled {
+		result.receipt.Cancelled = append(result.receipt.Cancelled, maker.copy())
+	}
+}
+
+// accrue adds to the fees accrued in a currency
+func (b *OrderBook) accrue(stateDB StateDB, currency Currency, amount *big.Int) {
+	if amount.Sign() == 0 {
+		return
+	}
+	fees := b.AccruedFees(stateDB, currency)
+	stateDB.SetState(lxBookAddr, makeStorageKey(bookAccPrefix, currency.Address.Bytes()), common.BigToHash(fees.Add(fees, amount)))
+}
+
+// cancel removes an owner's resting order, returning it with the funds it
+// held
+func (b *OrderBook) cancel(stateDB StateDB, id uint64, owner common.Address) (*Order, error) {
+	order, err := b.Order(stateDB, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Owner != owner {
+		return nil, ErrUnauthorized
+	}
+
+	b.side(stateDB, order.Base, order.Quote, order.Side == OrderBuy).remove(order)
+	b.deleteOrder(stateDB, id)
+	return order, nil
+}
+
+// reduce lowers an owner's resting order to remaining in place, keeping
+// its priority, and returns the funds it no longer needs
+func (b *OrderBook) reduce(stateDB StateDB, id uint64, owner common.Address, remaining *big.Int) (*Order, *big.Int, error) {
+	order, err := b.Order(stateDB, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.Owner != owner {
+		return nil, nil, ErrUnauthorized
+	}
+
+	side := b.side(stateDB, order.Base, order.Quote, order.Side == OrderBuy)
+	side.addTotal(order.Price, new(big.Int).Sub(remaining, order.Remaining))
+	order.Remaining = new(big.Int).Set(remaining)
+	order.Quantity = new(big.Int).Set(remaining)
+	required := order.required(remaining)
+	released := new(big.Int).Sub(order.Locked, required)
+	order.Locked = required
+	b.saveOrder(stateDB, order)
+	return order.copy(), released, nil
+}
+
+// takeFees removes up to amount of a currency's accrued fees, or all of
+// them if amount is nil
+func (b *OrderBook) takeFees(stateDB StateDB, currency Currency, amount *big.Int) *big.Int {
+	accrued := b.AccruedFees(stateDB, currency)
+	taken := protocolFeeAmount(accrued, amount)
+	stateDB.SetState(lxBookAddr, makeStorageKey(bookAccPrefix, currency.Address.Bytes()), common.BigToHash(accrued.Sub(accrued, taken)))
+	return taken
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// Book returns the pool manager's order book
+func (pm *PoolManager) Book() *OrderBook {
+	return pm.book
+}
+
+// PlaceOrder places a limit order for the current locker, which owes the
+// funds the order may pay. Matching stops after maxFills matches.
+func (pm *PoolManager) PlaceOrder(
+	stateDB StateDB,
+	base, quote Currency,
+	side OrderSide,
+	price, quantity *big.Int,
+	tif TimeInForce,
+	maxFills int,
+) (*BookReceipt, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+	if base == quote || side > OrderSell || tif > TimeInForcePostOnly ||
+		price == nil || price.Sign() <= 0 || quantity == nil || quantity.Sign() <= 0 {
+		return nil, ErrInvalidOrder
+	}
+
+	order := &Order{
+		Owner:       locker,
+		Base:        base,
+		Quote:       quote,
+		Side:        side,
+		Price:       new(big.Int).Set(price),
+		Quantity:    new(big.Int).Set(quantity),
+		Remaining:   new(big.Int).Set(quantity),
+		TimeInForce: tif,
+	}
+	return pm.placeOrder(stateDB, locker, order, maxFills)
+}
+
+// placeOrder funds an order from the locker's delta and places it
+func (pm *PoolManager) placeOrder(stateDB StateDB, locker common.Address, order *Order, maxFills int) (*BookReceipt, error) {
+	funds := order.required(order.Quantity)
+	if funds.Sign() == 0 {
+		return nil, ErrInvalidOrder
+	}
+	order.Locked = new(big.Int).Set(funds)
+
+	result, err := pm.book.place(stateDB, order, maxFills)
+	if err != nil {
+		return nil, err
+	}
+
+	// Positive delta: the locker owes the order's funds
+	pm.updateDelta(locker, order.lockCurrency(), funds)
+	pm.payBookCredits(stateDB, locker, result)
+	return &result.receipt, nil
+}
+
+// payBookCredits pays the taker through its delta and makers in claims
+func (pm *PoolManager) payBookCredits(stateDB StateDB, locker common.Address, result *bookResult) {
+	for _, c := range result.takerCredits {
+		pm.updateDelta(locker, c.currency, new(big.Int).Neg(c.amount))
+	}
+	for _, c := range result.makerCredits {
+		balance := pm.getClaims(stateDB, c.owner, c.currency)
+		pm.setClaims(stateDB, c.owner, c.currency, balance.Add(balance, c.amount))
+	}
+}
+
+// CancelOrder cancels one of the current locker's resting orders and
+// credits the funds it held to the locker's delta
+func (pm *PoolManager) CancelOrder(stateDB StateDB, id uint64) (*Order, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+
+	order, err := pm.book.cancel(stateDB, id, locker)
+	if err != nil {
+		return nil, err
+	}
+
+	// Negative delta: the pool owes the locker
+	pm.updateDelta(locker, order.lockCurrency(), new(big.Int).Neg(order.Locked))
+	return order, nil
+}
+
+// ModifyOrder changes the price and remaining quantity of one of the
+// current locker's resting orders. Reducing the quantity at the same price
+// keeps the order's place in the queue; any other change cancels it and
+// places it again under the same ID, as a GTC order that may match.
+func (pm *PoolManager) ModifyOrder(stateDB StateDB, id uint64, price, quantity *big.Int, maxFills int) (*BookReceipt, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+	if price == nil || price.Sign() <= 0 || quantity == nil || quantity.Sign() <= 0 {
+		return nil, ErrInvalidOrder
+	}
+
+	current, err := pm.book.Order(stateDB, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Owner != locker {
+		return nil, ErrUnauthorized
+	}
+
+	if price.Cmp(current.Price) == 0 && quantity.Cmp(current.Remaining) <= 0 {
+		order, released, err := pm.book.reduce(stateDB, id, locker, quantity)
+		if err != nil {
+			return nil, err
+		}
+		pm.updateDelta(locker, order.lockCurrency(), new(big.Int).Neg(released))
+		return &BookReceipt{Order: order, Rested: true}, nil
+	}
+
+	cancelled, err := pm.CancelOrder(stateDB, id)
+	if err != nil {
+		return nil, err
+	}
+	order := &Order{
+		ID:          id,
+		Owner:       locker,
+		Base:        cancelled.Base,
+		Quote:       cancelled.Quote,
+		Side:        cancelled.Side,
+		Price:       new(big.Int).Set(price),
+		Quantity:    new(big.Int).Set(quantity),
+		Remaining:   new(big.Int).Set(quantity),
+		TimeInForce: TimeInForceGTC,
+	}
+	return pm.placeOrder(stateDB, locker, order, maxFills)
+}
+
+// SetBookFees sets the order book's maker and taker fees (protocol fee
+// controller only)
+func (pm *PoolManager) SetBookFees(stateDB StateDB, caller common.Address, makerFeeBps, takerFeeBps uint32) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	return pm.book.SetFees(stateDB, makerFeeBps, takerFeeBps)
+}
+
+// CollectBookFees transfers up to amount of the book's accrued fees in a
+// currency to recipient (protocol fee controller only). A nil amount, or
+// one above what has accrued, collects everything accrued.
+func (pm *PoolManager) CollectBookFees(
+	stateDB StateDB,
+	caller common.Address,
+	currency Currency,
+	recipient common.Address,
+	amount *big.Int,
+) (*big.Int, error) {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return nil, ErrUnauthorized
+	}
+	if recipient == (common.Address{}) {
+		return nil, ErrInvalidParameter
+	}
+
+	collected := pm.book.takeFees(stateDB, currency, amount)
+	if err := pm.transferOut(stateDB, currency, recipient, collected); err != nil {
+		return nil, err
+	}
+	return collected, nil
+}
+
+// =========================================================================
+// LXBook precompile
+// =========================================================================
+
+// Method selectors for LXBook
+const (
+	SelectorPlaceOrder     uint32 = 0x01000000 // placeOrder(Currency,Currency,uint8,uint256,uint256,uint8)
+	SelectorCancelOrder    uint32 = 0x02000000 // cancelOrder(uint64)
+	SelectorModifyOrder    uint32 = 0x03000000 // modifyOrder(uint64,uint256,uint256)
+	SelectorGetOrder       uint32 = 0x04000000 // getOrder(uint64)
+	SelectorBestBid        uint32 = 0x05000000 // bestBid(Currency,Currency)
+	SelectorBestAsk        uint32 = 0x06000000 // bestAsk(Currency,Currency)
+	SelectorSetSTPMode     uint32 = 0x07000000 // setSTPMode(uint8)
+	SelectorCollectBookFee uint32 = 0x08000000 // collectFees(Currency,address,uint256)
+)
+
+// Order book events, logged from the LXBook address
+var (
+	// OrderPlaced(uint64 indexed id, address indexed owner, bytes32 indexed market, uint8 side, uint256 price, uint256 quantity)
+	OrderPlacedTopic = common.BytesToHash(crypto.Keccak256([]byte("OrderPlaced(uint64,address,bytes32,uint8,uint256,uint256)")))
+
+	// OrderFilled(uint64 indexed makerId, uint64 indexed takerId, uint256 price, uint256 quantity, uint256 quoteAmount, uint256 makerFee, uint256 takerFee)
+	OrderFilledTopic = common.BytesToHash(crypto.Keccak256([]byte("OrderFilled(uint64,uint64,uint256,uint256,uint256,uint256,uint256)")))
+
+	// OrderCancelled(uint64 indexed id, uint256 remaining)
+	OrderCancelledTopic = common.BytesToHash(crypto.Keccak256([]byte("OrderCancelled(uint64,uint256)")))
+
+	// OrderModified(uint64 indexed id, uint256 price, uint256 quantity)
+	OrderModifiedTopic = common.BytesToHash(crypto.Keccak256([]byte("OrderModified(uint64,uint256,uint256)")))
+)
+
+// BookContract implements the LXBook precompile over the pool manager
+// shared with LXPool
+type BookContract struct {
+	poolManager *PoolManager
+}
+
+// Run executes the precompile
+func (c *BookContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+
+	selector := binary.BigEndian.Uint32(input[:4])
+	data := input[4:]
+
+	gas := c.RequiredGas(input)
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+	remainingGas = suppliedGas - gas
+
+	pm := c.poolManager
+	stateAdapter := newPoolStateAdapter(accessibleState)
+	switch selector {
+	case SelectorGetOrder:
+		// id (32)
+		if len(data) < 32 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		order, err := pm.book.Order(stateAdapter, decodeUint64Word(data[:32]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return EncodeOrder(order), remainingGas, nil
+
+	case SelectorBestBid, SelectorBestAsk:
+		// base (32) + quote (32)
+		if len(data) < 64 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		base := Currency{Address: common.BytesToAddress(data[12:32])}
+		quote := Currency{Address: common.BytesToAddress(data[44:64])}
+		best := pm.book.BestAsk
+		if selector == SelectorBestBid {
+			best = pm.book.BestBid
+		}
+		price, quantity, err := best(stateAdapter, base, quote)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		result := make([]byte, 64)
+		price.FillBytes(result[:32])
+		quantity.FillBytes(result[32:64])
+		return result, remainingGas, nil
+
+	case SelectorPlaceOrder, SelectorCancelOrder, SelectorModifyOrder, SelectorSetSTPMode, SelectorCollectBookFee:
+	default:
+		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	}
+
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	var receipt *BookReceipt
+	switch selector {
+	case SelectorPlaceOrder:
+		// base (32) + quote (32) + side (32) + price (32) + quantity (32) + tif (32)
+		if len(data) < 192 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		base := Currency{Address: common.BytesToAddress(data[12:32])}
+		quote := Currency{Address: common.BytesToAddress(data[44:64])}
+		receipt, err = pm.PlaceOrder(stateAdapter, base, quote, OrderSide(data[95]),
+			new(big.Int).SetBytes(data[96:128]), new(big.Int).SetBytes(data[128:160]),
+			TimeInForce(data[191]), maxOrderFills(remainingGas))
+
+	case SelectorModifyOrder:
+		// id (32) + price (32) + quantity (32)
+		if len(data) < 96 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		receipt, err = pm.ModifyOrder(stateAdapter, decodeUint64Word(data[:32]),
+			new(big.Int).SetBytes(data[32:64]), new(big.Int).SetBytes(data[64:96]),
+			maxOrderFills(remainingGas))
+
+	case SelectorCancelOrder:
+		// id (32)
+		if len(data) < 32 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		order, err := pm.CancelOrder(stateAdapter, decodeUint64Word(data[:32]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		emitBookLogs(accessibleState, &BookReceipt{Cancelled: []*Order{order}}, false)
+		result := make([]byte, 32)
+		order.Locked.FillBytes(result)
+		return result, remainingGas, nil
+
+	case SelectorSetSTPMode:
+		// mode (32)
+		if len(data) < 32 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		if err := pm.book.controls.SetSTPMode(stateAdapter, caller, STPMode(data[31])); err != nil {
+			return nil, remainingGas, err
+		}
+		return nil, remainingGas, nil
+
+	default: // SelectorCollectBookFee
+		// currency (32) + recipient (32) + amount (32)
+		if len(data) < 96 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		currency := Currency{Address: common.BytesToAddress(data[12:32])}
+		recipient := common.BytesToAddress(data[44:64])
+		var amount *big.Int
+		if requested := new(big.Int).SetBytes(data[64:96]); requested.Sign() > 0 {
+			amount = requested
+		}
+		collected, err := pm.CollectBookFees(stateAdapter, caller, currency, recipient, amount)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		result := make([]byte, 32)
+		collected.FillBytes(result)
+		return result, remainingGas, nil
+	}
+	if err != nil {
+		return nil, remainingGas, err
+	}
+
+	// Each match is charged after the fact; maxOrderFills kept it in budget
+	remainingGas -= uint64(len(receipt.Fills)) * GasOrderFill
+	if selector == SelectorModifyOrder {
+		order := receipt.Order
+		data := make([]byte, 64)
+		order.Price.FillBytes(data[:32])
+		order.Quantity.FillBytes(data[32:64])
+		accessibleState.GetStateDB().AddLog(&ethtypes.Log{
+			Address: lxBookAddr,
+			Topics:  []common.Hash{OrderModifiedTopic, uint64Topic(order.ID)},
+			Data:    data,
+		})
+	}
+	emitBookLogs(accessibleState, receipt, selector == SelectorPlaceOrder)
+	return EncodeBookReceipt(receipt), remainingGas, nil
+}
+
+// maxOrderFills returns how many matches gas pays for
+func maxOrderFills(gas uint64) int {
+	return int(min(gas/GasOrderFill, MaxOrderFills))
+}
+
+// RequiredGas returns the gas required for the precompile input
+func (c *BookContract) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return GasPoolLookup
+	}
+
+	switch binary.BigEndian.Uint32(input[:4]) {
+	case SelectorPlaceOrder, SelectorModifyOrder:
+		return GasPlaceOrder
+	case SelectorCancelOrder:
+		return GasCancelOrder
+	case SelectorSetSTPMode:
+		return GasSetSTPMode
+	case SelectorCollectBookFee:
+		return GasCollectBookFees
+	default:
+		return GasPoolLookup
+	}
+}
+
+// emitBookLogs logs the placement of a receipt's order, if placed, then its
+// fills and cancellations
+func emitBookLogs(state contract.AccessibleState, receipt *BookReceipt, placed bool) {
+	db := state.GetStateDB()
+	if order := receipt.Order; placed {
+		data := make([]byte, 96)
+		data[31] = byte(order.Side)
+		order.Price.FillBytes(data[32:64])
+		order.Quantity.FillBytes(data[64:96])
+		market := MarketID(order.Base, order.Quote)
+		db.AddLog(&ethtypes.Log{
+			Address: lxBookAddr,
+			Topics:  []common.Hash{OrderPlacedTopic, uint64Topic(order.ID), common.BytesToHash(order.Owner.Bytes()), market},
+			Data:    data,
+		})
+	}
+	for _, fill := range receipt.Fills {
+		data := make([]byte, 160)
+		fill.Price.FillBytes(data[0:32])
+		fill.Quantity.FillBytes(data[32:64])
+		fill.QuoteAmount.FillBytes(data[64:96])
+		fill.MakerFee.FillBytes(data[96:128])
+		fill.TakerFee.FillBytes(data[128:160])
+		db.AddLog(&ethtypes.Log{
+			Address: lxBookAddr,
+			Topics:  []common.Hash{OrderFilledTopic, uint64Topic(fill.MakerOrder), uint64Topic(fill.TakerOrder)},
+			Data:    data,
+		})
+	}
+	for _, order := range receipt.Cancelled {
+		db.AddLog(&ethtypes.Log{
+			Address: lxBookAddr,
+			Topics:  []common.Hash{OrderCancelledTopic, uint64Topic(order.ID)},
+			Data:    common.LeftPadBytes(order.Remaining.Bytes(), 32),
+		})
+	}
+}
+
+// uint64Topic encodes v as an indexed log topic
+func uint64Topic(v uint64) common.Hash {
+	return common.BytesToHash(encodeUint64(v))
+}
+
+// EncodeOrder encodes an order: id (32) + owner (32) + base (32) +
+// quote (32) + side (32) + price (32) + quantity (32) + remaining (32) +
+// locked (32) + timeInForce (32)
+func EncodeOrder(order *Order) []byte {
+	result := make([]byte, 320)
+	binary.BigEndian.PutUint64(result[24:32], order.ID)
+	copy(result[44:64], order.Owner.Bytes())
+	copy(result[76:96], order.Base.Address.Bytes())
+	copy(result[108:128], order.Quote.Address.Bytes())
+	result[159] = byte(order.Side)
+	order.Price.FillBytes(result[160:192])
+	order.Quantity.FillBytes(result[192:224])
+	order.Remaining.FillBytes(result[224:256])
+	order.Locked.FillBytes(result[256:288])
+	result[319] = byte(order.TimeInForce)
+	return result
+}
+
+// EncodeBookReceipt encodes the outcome of placing or modifying an order:
+// id (32) + filled (32) + remaining (32) + rested (32)
+func EncodeBookReceipt(receipt *BookReceipt) []byte {
+	filled := new(big.Int)
+	for _, fill := range receipt.Fills {
+		filled.Add(filled, fill.Quantity)
+	}
+
+	result := make([]byte, 128)
+	binary.BigEndian.PutUint64(result[24:32], receipt.Order.ID)
+	filled.FillBytes(result[32:64])
+	receipt.Order.Remaining.FillBytes(result[64:96])
+	if receipt.Rested {
+		result[127] = 1
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+)
+
+// =========================================================================
+// Order Book Controls (LXBook)
+// =========================================================================
+//
+// Self-trade prevention (STP) and per-trader order rate limits consumed by
+// the LXBook matching engine. STP is evaluated whenever an incoming (taker)
+// order would cross a resting (maker) order from the same account; the
+// taker's policy decides the outcome.
+//
+// Controls are stored at the LXBook address:
+//
+//	book/stp || account  -> STP mode (byte 31)
+//	book/cap             -> global per-trader cap per block
+//	book/cap || trader   -> per-trader cap override (0 = none)
+//	book/cnt || trader   -> block number (bytes 16..24) | orders (bytes 24..32)
+//
+// A trader's counter only covers the block it records; any other block
+// starts from zero.
+
+// Storage key prefixes for book controls
+var (
+	bookSTPPrefix   = []byte("book/stp")
+	bookCapPrefix   = []byte("book/cap")
+	bookCountPrefix = []byte("book/cnt")
+)
+
+// STPMode selects how a self-trade is resolved
+type STPMode uint8
+
+const (
+	STPNone         STPMode = iota // Allow self-trades
+	STPCancelNewest                // Cancel the incoming (taker) order
+	STPCancelOldest                // Cancel the resting (maker) order, keep matching
+	STPDecrement                   // Reduce both by the overlap without trading
+)
+
+// Valid returns true if the mode is a known STP policy
+func (m STPMode) Valid() bool {
+	return m <= STPDecrement
+}
+
+// STPOutcome is the resolution of a single maker/taker self-match
+type STPOutcome struct {
+	MakerRemaining *big.Int // Maker quantity left after resolution
+	TakerRemaining *big.Int // Taker quantity left after resolution
+	CancelMaker    bool     // Resting order must be removed from the book
+	CancelTaker    bool     // Incoming order must stop matching
+}
+
+// BookControls holds per-account STP policies and order rate limits. All
+// state lives at the LXBook address.
+type BookControls struct{}
+
+// NewBookControls creates book controls
+func NewBookControls() *BookControls {
+	return &BookControls{}
+}
+
+// SetSTPMode sets the self-trade prevention policy for an account
+func (bc *BookControls) SetSTPMode(stateDB StateDB, account common.Address, mode STPMode) error {
+	if !mode.Valid() {
+		return ErrInvalidSTPMode
+	}
+
+	var word common.Hash
+	word[31] = byte(mode)
+	stateDB.SetState(lxBookAddr, makeStorageKey(bookSTPPrefix, account.Bytes()), word)
+	return nil
+}
+
+// GetSTPMode returns the self-trade prevention policy of an account
+func (bc *BookControls) GetSTPMode(stateDB StateDB, account common.Address) STPMode {
+	return STPMode(stateDB.GetState(lxBookAddr, makeStorageKey(bookSTPPrefix, account.Bytes()))[31])
+}
+
+// SetMaxOrdersPerBlock sets the global per-trader order cap (0 disables)
+func (bc *BookControls) SetMaxOrdersPerBlock(stateDB StateDB, limit uint64) {
+	stateDB.SetState(lxBookAddr, makeStorageKey(bookCapPrefix, nil), common.BytesToHash(encodeUint64(limit)))
+}
+
+// MaxOrdersPerBlock returns the global per-trader order cap
+func (bc *BookControls) MaxOrdersPerBlock(stateDB StateDB) uint64 {
+	return decodeUint64Word(stateDB.GetState(lxBookAddr, makeStorageKey(bookCapPrefix, nil)).Bytes())
+}
+
+// SetTraderOrderCap overrides the per-block cap for a single trader.
+// A cap of 0 removes the override.
+func (bc *BookControls) SetTraderOrderCap(stateDB StateDB, trader common.Address, limit uint64) {
+	stateDB.SetState(lxBookAddr, makeStorageKey(bookCapPrefix, trader.Bytes()), common.BytesToHash(encodeUint64(limit)))
+}
+
+// AdmitOrder records an order placement by trader in the current block and
+// returns ErrOrderRateLimited if it would exceed the trader's cap
+func (bc *BookControls) AdmitOrder(stateDB StateDB, trader common.Address) error {
+	limit := decodeUint64Word(stateDB.GetState(lxBookAddr, makeStorageKey(bookCapPrefix, trader.Bytes())).Bytes())
+	if limit == 0 {
+		limit = bc.MaxOrdersPerBlock(stateDB)
+	}
+	if limit == 0 {
+		return nil
+	}
+
+	blockNumber := stateDB.GetBlockNumber()
+	count := bc.OrdersInBlock(stateDB, trader)
+	if count >= limit {
+		return ErrOrderRateLimited
+	}
+
+	var word common.Hash
+	binary.BigEndian.PutUint64(word[16:24], blockNumber)
+	binary.BigEndian.PutUint64(word[24:32], count+1)
+	stateDB.SetState(lxBookAddr, makeStorageKey(bookCountPrefix, trader.Bytes()), word)
+	return nil
+}
+
+// OrdersInBlock returns the number of orders admitted for trader in the
+// current block
+func (bc *BookControls) OrdersInBlock(stateDB StateDB, trader common.Address) uint64 {
+	word := stateDB.GetState(lxBookAddr, makeStorageKey(bookCountPrefix, trader.Bytes()))
+	if binary.BigEndian.Uint64(word[16:24]) != stateDB.GetBlockNumber() {
+		return 0
+	}
+	return binary.BigEndian.Uint64(word[24:32])
+}
+
+// IsSelfTrade returns true if the maker and taker orders belong to the same
+// account and the taker's policy forbids the match
+func (bc *BookControls) IsSelfTrade(stateDB StateDB, maker, taker common.Address) bool {
+	if maker != taker {
+		return false
+	}
+	return bc.GetSTPMode(stateDB, taker) != STPNone
+}
+
+// ResolveSelfTrade applies the taker's STP policy to a crossing maker order.
+// Quantities are not modified in place.
+func (bc *BookControls) ResolveSelfTrade(stateDB StateDB, taker common.Address, makerRemaining, takerRemaining *big.Int) STPOutcome {
+	return ApplySTP(bc.GetSTPMode(stateDB, taker), makerRemaining, takerRemaining)
+}
+
+// ApplySTP resolves a self-match between a resting maker quantity and an
+// incoming taker quantity under the given policy
+func ApplySTP(mode STPMode, makerRemaining, takerRemaining *big.Int) STPOutcome {
+	out := STPOutcome{
+		MakerRemaining: new(big.Int).Set(makerRemaining),
+		TakerRemaining: new(big.Int).Set(takerRemaining),
+	}
+
+	switch mode {
+	case STPCancelNewest:
+		out.TakerRemaining.SetInt64(0)
+		out.CancelTaker = true
+
+	case STPCancelOldest:
+		out.MakerRemaining.SetInt64(0)
+		out.CancelMaker = true
+
+	case STPDecrement:
+		overlap := makerRemaining
+		if takerRemaining.Cmp(overlap) < 0 {
+			overlap = takerRemaining
+		}
+		out.MakerRemaining.Sub(out.MakerRemaining, overlap)
+		out.TakerRemaining.Sub(out.TakerRemaining, overlap)
+		out.CancelMaker = out.MakerRemaining.Sign() == 0
+		out.CancelTaker = out.TakerRemaining.Sign() == 0
+	}
+
+	return out
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var (
+	testTraderA = common.HexToAddress("0xa1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1")
+	testTraderB = common.HexToAddress("0xb2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2")
+)
+
+func TestApplySTP(t *testing.T) {
+	maker := big.NewInt(100)
+	taker := big.NewInt(40)
+
+	tests := []struct {
+		mode        STPMode
+		makerLeft   int64
+		takerLeft   int64
+		cancelMaker bool
+		cancelTaker bool
+	}{
+		{STPNone, 100, 40, false, false},
+		{STPCancelNewest, 100, 0, false, true},
+		{STPCancelOldest, 0, 40, true, false},
+		{STPDecrement, 60, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		out := ApplySTP(tt.mode, maker, taker)
+		if out.MakerRemaining.Int64() != tt.makerLeft || out.TakerRemaining.Int64() != tt.takerLeft {
+			t.Errorf("mode %d: expected remaining %d/%d, got %s/%s",
+				tt.mode, tt.makerLeft, tt.takerLeft, out.MakerRemaining, out.TakerRemaining)
+		}
+		if out.CancelMaker != tt.cancelMaker || out.CancelTaker != tt.cancelTaker {
+			t.Errorf("mode %d: expected cancel %v/%v, got %v/%v",
+				tt.mode, tt.cancelMaker, tt.cancelTaker, out.CancelMaker, out.CancelTaker)
+		}
+	}
+
+	// Inputs are never modified in place
+	if maker.Int64() != 100 || taker.Int64() != 40 {
+		t.Error("ApplySTP must not mutate its inputs")
+	}
+}
+
+func TestBookControlsSTPPolicy(t *testing.T) {
+	bc := NewBookControls()
+	stateDB := NewMockStateDB()
+
+	if err := bc.SetSTPMode(stateDB, testTraderA, STPMode(9)); err != ErrInvalidSTPMode {
+		t.Errorf("expected ErrInvalidSTPMode, got %v", err)
+	}
+	if bc.IsSelfTrade(stateDB, testTraderA, testTraderA) {
+		t.Error("self-trade should be allowed without a policy")
+	}
+
+	if err := bc.SetSTPMode(stateDB, testTraderA, STPDecrement); err != nil {
+		t.Fatalf("SetSTPMode failed: %v", err)
+	}
+	if !bc.IsSelfTrade(stateDB, testTraderA, testTraderA) {
+		t.Error("expected self-trade to be detected")
+	}
+	if bc.IsSelfTrade(stateDB, testTraderB, testTraderA) {
+		t.Error("different accounts are never a self-trade")
+	}
+
+	// The policy lives in state, not in the controls
+	if mode := NewBookControls().GetSTPMode(stateDB, testTraderA); mode != STPDecrement {
+		t.Errorf("expected STPDecrement from state, got %d", mode)
+	}
+
+	out := bc.ResolveSelfTrade(stateDB, testTraderA, big.NewInt(30), big.NewInt(50))
+	if !out.CancelMaker || out.TakerRemaining.Int64() != 20 {
+		t.Errorf("expected maker cancelled and 20 taker left, got %+v", out)
+	}
+}
+
+func TestBookControlsRateLimit(t *testing.T) {
+	bc := NewBookControls()
+	stateDB := NewMockStateDB()
+	stateDB.SetBlockNumber(100)
+	bc.SetMaxOrdersPerBlock(stateDB, 2)
+
+	for i := 0; i < 2; i++ {
+		if err := bc.AdmitOrder(stateDB, testTraderA); err != nil {
+			t.Fatalf("order %d rejected: %v", i, err)
+		}
+	}
+	if err := bc.AdmitOrder(stateDB, testTraderA); err != ErrOrderRateLimited {
+		t.Errorf("expected ErrOrderRateLimited, got %v", err)
+	}
+
+	// Other traders have their own budget
+	if err := bc.AdmitOrder(stateDB, testTraderB); err != nil {
+		t.Errorf("trader B rejected: %v", err)
+	}
+
+	// Counters reset in the next block
+	stateDB.SetBlockNumber(101)
+	if err := bc.AdmitOrder(stateDB, testTraderA); err != nil {
+		t.Errorf("expected reset in new block, got %v", err)
+	}
+	if got := bc.OrdersInBlock(stateDB, testTraderA); got != 1 {
+		t.Errorf("expected 1 order in block 101, got %d", got)
+	}
+
+	// Per-trader override lifts the cap for market makers
+	bc.SetTraderOrderCap(stateDB, testTraderA, 5)
+	for i := 0; i < 4; i++ {
+		if err := bc.AdmitOrder(stateDB, testTraderA); err != nil {
+			t.Fatalf("override order %d rejected: %v", i, err)
+		}
+	}
+	if err := bc.AdmitOrder(stateDB, testTraderA); err != ErrOrderRateLimited {
+		t.Errorf("expected ErrOrderRateLimited at override cap, got %v", err)
+	}
+}
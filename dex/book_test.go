@@ -0,0 +1,153 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// TestOrderBook tests price-time priority matching, partial fills, fees,
+// and cancelling and modifying resting orders
+func TestOrderBook(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	base := Currency{Address: common.HexToAddress("0x000000000000000000000000000000000000BA5E")}
+	quote := Currency{Address: common.HexToAddress("0x0000000000000000000000000000000000000C0C")}
+	taker := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	if err := pm.book.SetFees(stateDB, 10, 20); err != nil {
+		t.Fatalf("SetFees failed: %v", err)
+	}
+	price := func(p int64) *big.Int { return new(big.Int).Mul(big.NewInt(p), BookPriceScale) }
+	place := func(owner common.Address, side OrderSide, p, quantity int64, tif TimeInForce) (*BookReceipt, error) {
+		t.Helper()
+		openLock(pm, owner)
+		return pm.PlaceOrder(stateDB, base, quote, side, price(p), big.NewInt(quantity), tif, 16)
+	}
+
+	// Two asks at 2 in time order, and one at 3
+	for _, ask := range []struct {
+		owner    common.Address
+		p, q     int64
+		expected uint64
+	}{{testTraderA, 2, 10_000, 1}, {testTraderB, 2, 10_000, 2}, {testTraderA, 3, 5_000, 3}} {
+		receipt, err := place(ask.owner, OrderSell, ask.p, ask.q, TimeInForceGTC)
+		if err != nil {
+			t.Fatalf("PlaceOrder failed: %v", err)
+		}
+		if receipt.Order.ID != ask.expected || !receipt.Rested {
+			t.Errorf("Expected order %d to rest, got %d (rested %v)", ask.expected, receipt.Order.ID, receipt.Rested)
+		}
+	}
+	if p, q, err := pm.book.BestAsk(stateDB, base, quote); err != nil || p.Cmp(price(2)) != 0 || q.Int64() != 20_000 {
+		t.Errorf("Expected best ask 20000 at 2, got %v at %v: %v", q, p, err)
+	}
+
+	// A buy at 3 fills the oldest ask first, at the makers' price
+	receipt, err := place(taker, OrderBuy, 3, 15_000, TimeInForceGTC)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(receipt.Fills) != 2 || receipt.Fills[0].MakerOrder != 1 || receipt.Fills[1].MakerOrder != 2 {
+		t.Fatalf("Expected fills against orders 1 then 2, got %+v", receipt.Fills)
+	}
+	if fill := receipt.Fills[1]; fill.Quantity.Int64() != 5_000 || fill.QuoteAmount.Int64() != 10_000 {
+		t.Errorf("Expected a partial fill of 5000 for 10000, got %s for %s", fill.Quantity, fill.QuoteAmount)
+	}
+	if receipt.Rested || receipt.Order.Remaining.Sign() != 0 {
+		t.Errorf("Expected the buy to fill completely, got %s remaining", receipt.Order.Remaining)
+	}
+
+	// The taker receives base less its fee and pays only what it spent
+	if delta := pm.GetDelta(taker, base); delta.Int64() != -14_970 {
+		t.Errorf("Expected taker base delta -14970, got %s", delta)
+	}
+	if delta := pm.GetDelta(taker, quote); delta.Int64() != 30_000 {
+		t.Errorf("Expected taker quote delta 30000, got %s", delta)
+	}
+
+	// Makers are paid in claims, less their fee
+	if claims := pm.ClaimBalanceOf(stateDB, testTraderA, quote); claims.Int64() != 19_980 {
+		t.Errorf("Expected maker A claims 19980, got %s", claims)
+	}
+	if claims := pm.ClaimBalanceOf(stateDB, testTraderB, quote); claims.Int64() != 9_990 {
+		t.Errorf("Expected maker B claims 9990, got %s", claims)
+	}
+	if fees := pm.book.AccruedFees(stateDB, base); fees.Int64() != 30 {
+		t.Errorf("Expected base fees 30, got %s", fees)
+	}
+	if fees := pm.book.AccruedFees(stateDB, quote); fees.Int64() != 30 {
+		t.Errorf("Expected quote fees 30, got %s", fees)
+	}
+
+	// Only the owner cancels, and gets back what the order held
+	if _, err := pm.CancelOrder(stateDB, 2); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got: %v", err)
+	}
+	openLock(pm, testTraderB)
+	if order, err := pm.CancelOrder(stateDB, 2); err != nil || order.Locked.Int64() != 5_000 {
+		t.Fatalf("Expected 5000 refunded, got %v: %v", order, err)
+	}
+	if delta := pm.GetDelta(testTraderB, base); delta.Int64() != 5_000 {
+		t.Errorf("Expected maker B base delta 5000, got %s", delta)
+	}
+	if _, err := pm.book.Order(stateDB, 2); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound, got: %v", err)
+	}
+
+	// Post-only orders never take
+	if _, err := place(taker, OrderBuy, 3, 1_000, TimeInForcePostOnly); err != ErrOrderWouldCross {
+		t.Errorf("Expected ErrOrderWouldCross, got: %v", err)
+	}
+	receipt, err = place(taker, OrderBuy, 1, 4_000, TimeInForcePostOnly)
+	if err != nil || !receipt.Rested {
+		t.Fatalf("Expected the post-only bid to rest: %v", err)
+	}
+	bid := receipt.Order.ID
+	if p, q, _ := pm.book.BestBid(stateDB, base, quote); p.Cmp(price(1)) != 0 || q.Int64() != 4_000 {
+		t.Errorf("Expected best bid 4000 at 1, got %s at %s", q, p)
+	}
+
+	// Reducing keeps the order in place; repricing through the ask matches
+	before := pm.GetDelta(taker, quote)
+	if _, err := pm.ModifyOrder(stateDB, bid, price(1), big.NewInt(1_000), 16); err != nil {
+		t.Fatalf("ModifyOrder failed: %v", err)
+	}
+	if refund := new(big.Int).Sub(before, pm.GetDelta(taker, quote)); refund.Int64() != 3_000 {
+		t.Errorf("Expected 3000 refunded on reduce, got %s", refund)
+	}
+	receipt, err = pm.ModifyOrder(stateDB, bid, price(3), big.NewInt(2_000), 16)
+	if err != nil {
+		t.Fatalf("ModifyOrder failed: %v", err)
+	}
+	if receipt.Order.ID != bid || len(receipt.Fills) != 1 || receipt.Fills[0].MakerOrder != 3 {
+		t.Errorf("Expected order %d to fill against order 3, got %+v", bid, receipt)
+	}
+	if order, _ := pm.book.Order(stateDB, 3); order.Remaining.Int64() != 3_000 {
+		t.Errorf("Expected 3000 left of order 3, got %s", order.Remaining)
+	}
+
+	// IOC remainders are cancelled instead of resting
+	receipt, err = place(taker, OrderBuy, 3, 4_000, TimeInForceIOC)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if receipt.Rested || len(receipt.Cancelled) != 1 || receipt.Cancelled[0].Remaining.Int64() != 1_000 {
+		t.Errorf("Expected 1000 of the IOC order cancelled, got %+v", receipt)
+	}
+	if _, _, err := pm.book.BestAsk(stateDB, base, quote); err != ErrOrderNotFound {
+		t.Errorf("Expected an empty ask side, got: %v", err)
+	}
+
+	// Fees and accruals live in LXBook storage, not in the book
+	fresh := NewOrderBook(NewBookControls())
+	if makerBps, takerBps := fresh.Fees(stateDB); makerBps != 10 || takerBps != 20 {
+		t.Errorf("Expected stored fees 10/20, got %d/%d", makerBps, takerBps)
+	}
+	if fees := fresh.AccruedFees(stateDB, base); fees.Cmp(pm.book.AccruedFees(stateDB, base)) != 0 || fees.Sign() == 0 {
+		t.Errorf("Expected stored base fees, got %s", fees)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+)
+
+// ERC-6909 claims
+//
+// Lockers may leave currency owed to them inside the pool manager as claims
+// instead of taking it, as in v4. A claim is an ERC-6909 balance whose id
+// is the currency's address. Minting claims is accounted like Take and
+// burning them like Settle, so claims are reused in later operations
+// without any token transfer. Claims of non-standard currencies are in the
+// same units as their deltas (shares for rebasing currencies).
+//
+// Claim balances are stored under claimPrefix:
+//
+//	owner || currency -> balance
+
+// claimStorageKey returns the storage key of an owner's claims of a currency
+func claimStorageKey(owner common.Address, currency Currency) common.Hash {
+	id := make([]byte, 0, 2*common.AddressLength)
+	id = append(id, owner.Bytes()...)
+	id = append(id, currency.Address.Bytes()...)
+	return makeStorageKey(claimPrefix, id)
+}
+
+// getClaims returns an owner's claims of a currency
+func (pm *PoolManager) getClaims(stateDB StateDB, owner common.Address, currency Currency) *big.Int {
+	value := stateDB.GetState(poolManagerAddr, claimStorageKey(owner, currency))
+	return new(big.Int).SetBytes(value[:])
+}
+
+// setClaims saves an owner's claims of a currency
+func (pm *PoolManager) setClaims(stateDB StateDB, owner common.Address, currency Currency, balance *big.Int) {
+	var value common.Hash
+	balance.FillBytes(value[:])
+	stateDB.SetState(poolManagerAddr, claimStorageKey(owner, currency), value)
+}
+
+// ClaimBalanceOf returns an owner's claims of a currency
+func (pm *PoolManager) ClaimBalanceOf(stateDB StateDB, owner common.Address, currency Currency) *big.Int {
+	return pm.getClaims(stateDB, owner, currency)
+}
+
+// Mint mints claims of a currency to an address, charging them to the
+// current locker's delta as if the locker took the currency
+func (pm *PoolManager) Mint(
+	stateDB StateDB,
+	to common.Address,
+	currency Currency,
+	amount *big.Int,
+) error {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return ErrUnauthorized
+	}
+	if to == (common.Address{}) {
+		return ErrInvalidParameter
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+
+	balance := pm.getClaims(stateDB, to, currency)
+	pm.setClaims(stateDB, to, currency, balance.Add(balance, amount))
+	pm.updateDelta(locker, currency, amount)
+	return nil
+}
+
+// Burn burns the current locker's claims of a currency, crediting its
+// delta as if the locker settled the currency
+func (pm *PoolManager) Burn(
+	stateDB StateDB,
+	currency Currency,
+	amount *big.Int,
+) error {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return ErrUnauthorized
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+
+	balance := pm.getClaims(stateDB, locker, currency)
+	if balance.Cmp(amount) < 0 {
+		return ErrInsufficientClaims
+	}
+	pm.setClaims(stateDB, locker, currency, balance.Sub(balance, amount))
+	pm.updateDelta(locker, currency, new(big.Int).Neg(amount))
+	return nil
+}
+
+// TransferClaim moves claims of a currency from sender to receiver
+func (pm *PoolManager) TransferClaim(
+	stateDB StateDB,
+	sender common.Address,
+	receiver common.Address,
+	currency Currency,
+	amount *big.Int,
+) error {
+	if receiver == (common.Address{}) {
+		return ErrInvalidParameter
+	}
+	if amount == nil || amount.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+
+	from := pm.getClaims(stateDB, sender, currency)
+	if from.Cmp(amount) < 0 {
+		return ErrInsufficientClaims
+	}
+	pm.setClaims(stateDB, sender, currency, from.Sub(from, amount))
+
+	to := pm.getClaims(stateDB, receiver, currency)
+	pm.setClaims(stateDB, receiver, currency, to.Add(to, amount))
+	return nil
+}
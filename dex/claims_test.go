@@ -0,0 +1,68 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// TestClaims tests that lockers can leave currency owed to them as claims,
+// transfer them, and burn them to pay later deltas
+func TestClaims(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	currency := newTestPoolKey().Currency1
+	trader := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	other := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	if err := pm.Mint(stateDB, trader, currency, big.NewInt(1_000)); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized outside a lock, got: %v", err)
+	}
+
+	// Minting claims to what the pool owes settles the delta
+	openLock(pm, trader)
+	pm.updateDelta(trader, currency, big.NewInt(-1_000))
+	if err := pm.Mint(stateDB, trader, currency, big.NewInt(0)); err != ErrInvalidAmount {
+		t.Errorf("Expected ErrInvalidAmount, got: %v", err)
+	}
+	if err := pm.Mint(stateDB, trader, currency, big.NewInt(1_000)); err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	if err := pm.verifySettlement(trader); err != nil {
+		t.Errorf("Expected settled deltas after mint, got: %v", err)
+	}
+
+	if err := pm.TransferClaim(stateDB, trader, other, currency, big.NewInt(400)); err != nil {
+		t.Fatalf("TransferClaim failed: %v", err)
+	}
+	if err := pm.TransferClaim(stateDB, other, trader, currency, big.NewInt(401)); err != ErrInsufficientClaims {
+		t.Errorf("Expected ErrInsufficientClaims, got: %v", err)
+	}
+	if balance := pm.ClaimBalanceOf(stateDB, trader, currency); balance.Cmp(big.NewInt(600)) != 0 {
+		t.Errorf("Expected trader claims 600, got %s", balance)
+	}
+	if balance := pm.ClaimBalanceOf(stateDB, other, currency); balance.Cmp(big.NewInt(400)) != 0 {
+		t.Errorf("Expected other claims 400, got %s", balance)
+	}
+
+	// Burning claims pays what the locker owes
+	pm.updateDelta(trader, currency, big.NewInt(500))
+	if err := pm.Burn(stateDB, currency, big.NewInt(500)); err != nil {
+		t.Fatalf("Burn failed: %v", err)
+	}
+	if err := pm.verifySettlement(trader); err != nil {
+		t.Errorf("Expected settled deltas after burn, got: %v", err)
+	}
+	if err := pm.Burn(stateDB, currency, big.NewInt(101)); err != ErrInsufficientClaims {
+		t.Errorf("Expected ErrInsufficientClaims, got: %v", err)
+	}
+
+	// Claims persist
+	if balance := NewPoolManager().ClaimBalanceOf(stateDB, trader, currency); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("Expected reloaded trader claims 100, got %s", balance)
+	}
+}
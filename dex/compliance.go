@@ -0,0 +1,382 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// =========================================================================
+// Compliance: identity registry and KYC hook
+// =========================================================================
+//
+// The identity registry records attestations that an address passed an
+// issuer's identity checks. The registry admin adds and removes issuers.
+// Issuers attest addresses with an expiry and can revoke them again. An
+// attestation only counts while its issuer is still active, so removing an
+// issuer voids everything it attested.
+//
+// Pools restricted to verified participants, such as RWA pools, set their
+// hooks address to ComplianceHookAddress. The compliance hook then rejects
+// swaps and liquidity additions from lockers without a valid attestation.
+// Removing liquidity stays open, so a revoked LP can still exit.
+
+// ComplianceHookAddress is the built-in compliance hook. Its leading bytes
+// encode beforeSwap and beforeAddLiquidity.
+var ComplianceHookAddress = common.HexToAddress("0x0044000000000000000000000000000000009092")
+
+// NativeHook is a hook implemented in the precompile rather than in an EVM
+// contract
+type NativeHook interface {
+	// Call runs the hook for flag on behalf of sender
+	Call(stateDB StateDB, flag HookFlags, sender common.Address, key PoolKey) error
+}
+
+// Attestation records that an issuer verified an address
+type Attestation struct {
+	Subject  common.Address
+	Issuer   common.Address
+	IssuedAt uint64
+	Expiry   uint64 // Timestamp after which the attestation lapses
+	Revoked  bool
+}
+
+// Storage key prefixes for the identity registry, stored at the LXIdentity
+// address:
+//
+//	idnt/admin             -> registry admin
+//	idnt/issr || issuer    -> active flag
+//	idnt/att || subject    -> issuer; "time" -> revoked (byte 0) |
+//	                          issuedAt (bytes 16..24) | expiry (bytes 24..32)
+var (
+	identityAdminPrefix  = []byte("idnt/admin")
+	identityIssuerPrefix = []byte("idnt/issr")
+	identityAttestPrefix = []byte("idnt/att")
+)
+
+// IdentityRegistry holds issuer-attested identities. All state lives at
+// the LXIdentity address; attestations are checked against block time.
+type IdentityRegistry struct{}
+
+// NewIdentityRegistry creates an identity registry
+func NewIdentityRegistry() *IdentityRegistry {
+	return &IdentityRegistry{}
+}
+
+// identityAttestKey returns the storage key of an attestation field
+func identityAttestKey(subject common.Address, field string) common.Hash {
+	return makeStorageKey(identityAttestPrefix, append(subject.Bytes(), field...))
+}
+
+// SetAdmin replaces the registry admin
+func (r *IdentityRegistry) SetAdmin(stateDB StateDB, admin common.Address) {
+	stateDB.SetState(lxIdentityAddr, makeStorageKey(identityAdminPrefix, nil), common.BytesToHash(admin.Bytes()))
+}
+
+// Admin returns the registry admin
+func (r *IdentityRegistry) Admin(stateDB StateDB) common.Address {
+	return common.BytesToAddress(stateDB.GetState(lxIdentityAddr, makeStorageKey(identityAdminPrefix, nil)).Bytes())
+}
+
+// setIssuer stores an issuer's active flag
+func (r *IdentityRegistry) setIssuer(stateDB StateDB, issuer common.Address, active bool) {
+	stateDB.SetState(lxIdentityAddr, makeStorageKey(identityIssuerPrefix, issuer.Bytes()), common.BytesToHash(encodeBool(active)))
+}
+
+// AddIssuer activates an issuer (admin only)
+func (r *IdentityRegistry) AddIssuer(stateDB StateDB, caller, issuer common.Address) error {
+	if caller != r.Admin(stateDB) {
+		return ErrUnauthorized
+	}
+	if issuer == (common.Address{}) {
+		return ErrInvalidIssuer
+	}
+	r.setIssuer(stateDB, issuer, true)
+	return nil
+}
+
+// RemoveIssuer deactivates an issuer, voiding its attestations (admin only)
+func (r *IdentityRegistry) RemoveIssuer(stateDB StateDB, caller, issuer common.Address) error {
+	if caller != r.Admin(stateDB) {
+		return ErrUnauthorized
+	}
+	if !r.IsIssuer(stateDB, issuer) {
+		return ErrInvalidIssuer
+	}
+	r.setIssuer(stateDB, issuer, false)
+	return nil
+}
+
+// IsIssuer reports whether issuer is active
+func (r *IdentityRegistry) IsIssuer(stateDB StateDB, issuer common.Address) bool {
+	return stateDB.GetState(lxIdentityAddr, makeStorageKey(identityIssuerPrefix, issuer.Bytes()))[31] != 0
+}
+
+// loadAttestation loads subject's attestation, or nil if there is none
+func (r *IdentityRegistry) loadAttestation(stateDB StateDB, subject common.Address) *Attestation {
+	issuer := common.BytesToAddress(stateDB.GetState(lxIdentityAddr, identityAttestKey(subject, "iss")).Bytes())
+	if issuer == (common.Address{}) {
+		return nil
+	}
+	word := stateDB.GetState(lxIdentityAddr, identityAttestKey(subject, "time"))
+	return &Attestation{
+		Subject:  subject,
+		Issuer:   issuer,
+		IssuedAt: binary.BigEndian.Uint64(word[16:24]),
+		Expiry:   binary.BigEndian.Uint64(word[24:32]),
+		Revoked:  word[0] != 0,
+	}
+}
+
+// saveAttestation stores an attestation
+func (r *IdentityRegistry) saveAttestation(stateDB StateDB, att *Attestation) {
+	var word common.Hash
+	if att.Revoked {
+		word[0] = 1
+	}
+	binary.BigEndian.PutUint64(word[16:24], att.IssuedAt)
+	binary.BigEndian.PutUint64(word[24:32], att.Expiry)
+	stateDB.SetState(lxIdentityAddr, identityAttestKey(att.Subject, "iss"), common.BytesToHash(att.Issuer.Bytes()))
+	stateDB.SetState(lxIdentityAddr, identityAttestKey(att.Subject, "time"), word)
+}
+
+// Attest records that caller, an active issuer, verified subject until expiry
+func (r *IdentityRegistry) Attest(stateDB StateDB, caller, subject common.Address, expiry uint64) error {
+	if !r.IsIssuer(stateDB, caller) {
+		return ErrInvalidIssuer
+	}
+	if subject == (common.Address{}) {
+		return ErrInvalidAttestation
+	}
+	now := stateDB.GetBlockTimestamp()
+	if expiry <= now {
+		return ErrInvalidAttestation
+	}
+
+	r.saveAttestation(stateDB, &Attestation{
+		Subject:  subject,
+		Issuer:   caller,
+		IssuedAt: now,
+		Expiry:   expiry,
+	})
+	return nil
+}
+
+// Revoke revokes subject's attestation. The attesting issuer and the admin
+// may revoke.
+func (r *IdentityRegistry) Revoke(stateDB StateDB, caller, subject common.Address) error {
+	att := r.loadAttestation(stateDB, subject)
+	if att == nil {
+		return ErrAttestationNotFound
+	}
+	if caller != att.Issuer && caller != r.Admin(stateDB) {
+		return ErrUnauthorized
+	}
+	att.Revoked = true
+	r.saveAttestation(stateDB, att)
+	return nil
+}
+
+// IsVerified reports whether subject holds a live attestation from an
+// active issuer
+func (r *IdentityRegistry) IsVerified(stateDB StateDB, subject common.Address) bool {
+	att := r.loadAttestation(stateDB, subject)
+	if att == nil || att.Revoked || !r.IsIssuer(stateDB, att.Issuer) {
+		return false
+	}
+	return stateDB.GetBlockTimestamp() < att.Expiry
+}
+
+// GetAttestation returns subject's attestation
+func (r *IdentityRegistry) GetAttestation(stateDB StateDB, subject common.Address) (*Attestation, error) {
+	att := r.loadAttestation(stateDB, subject)
+	if att == nil {
+		return nil, ErrAttestationNotFound
+	}
+	return att, nil
+}
+
+// ComplianceHook gates swaps and liquidity additions on the identity
+// registry
+type ComplianceHook struct {
+	registry *IdentityRegistry
+}
+
+// NewComplianceHook creates a compliance hook over registry
+func NewComplianceHook(registry *IdentityRegistry) *ComplianceHook {
+	return &ComplianceHook{registry: registry}
+}
+
+// Call rejects unverified senders in beforeSwap and beforeAddLiquidity
+func (h *ComplianceHook) Call(stateDB StateDB, flag HookFlags, sender common.Address, key PoolKey) error {
+	switch flag {
+	case HookBeforeSwap, HookBeforeAddLiquidity:
+		if !h.registry.IsVerified(stateDB, sender) {
+			return ErrNotVerified
+		}
+	}
+	return nil
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// Identity returns the identity registry behind the compliance hook
+func (pm *PoolManager) Identity() *IdentityRegistry {
+	return pm.identity
+}
+
+// RegisterNativeHook installs a native hook at addr (protocol fee controller
+// only). Its calls are gated by the permissions encoded in addr, like those
+// of any other hook.
+func (pm *PoolManager) RegisterNativeHook(stateDB StateDB, caller, addr common.Address, hook NativeHook) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	if addr == (common.Address{}) {
+		return ErrHookInvalidAddress
+	}
+	pm.nativeHooks[addr] = hook
+	return nil
+}
+
+// =========================================================================
+// LXIdentity precompile
+// =========================================================================
+
+// Method selectors for LXIdentity
+const (
+	SelectorAddIssuer      uint32 = 0x01000000 // addIssuer(address)
+	SelectorRemoveIssuer   uint32 = 0x02000000 // removeIssuer(address)
+	SelectorAttest         uint32 = 0x03000000 // attest(address,uint64)
+	SelectorRevoke         uint32 = 0x04000000 // revoke(address)
+	SelectorIsVerified     uint32 = 0x05000000 // isVerified(address)
+	SelectorGetAttestation uint32 = 0x06000000 // getAttestation(address)
+	SelectorIsIssuer       uint32 = 0x07000000 // isIssuer(address)
+)
+
+// IdentityContract implements the LXIdentity precompile over the registry
+// shared with LXPool's compliance hook
+type IdentityContract struct {
+	poolManager *PoolManager
+}
+
+// Run executes the precompile
+func (c *IdentityContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+
+	selector := binary.BigEndian.Uint32(input[:4])
+	data := input[4:]
+
+	gas := c.RequiredGas(input)
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+	remainingGas = suppliedGas - gas
+
+	// Every method leads with an address word
+	if len(data) < 32 {
+		return nil, remainingGas, fmt.Errorf("input too short")
+	}
+	subject := common.BytesToAddress(data[12:32])
+	registry := c.poolManager.identity
+	stateAdapter := newPoolStateAdapter(accessibleState)
+
+	switch selector {
+	case SelectorIsVerified:
+		return encodeBool(registry.IsVerified(stateAdapter, subject)), remainingGas, nil
+
+	case SelectorIsIssuer:
+		return encodeBool(registry.IsIssuer(stateAdapter, subject)), remainingGas, nil
+
+	case SelectorGetAttestation:
+		att, err := registry.GetAttestation(stateAdapter, subject)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return EncodeAttestation(att), remainingGas, nil
+
+	case SelectorAddIssuer, SelectorRemoveIssuer, SelectorAttest, SelectorRevoke:
+	default:
+		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	}
+
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	switch selector {
+	case SelectorAddIssuer:
+		err = registry.AddIssuer(stateAdapter, caller, subject)
+	case SelectorRemoveIssuer:
+		err = registry.RemoveIssuer(stateAdapter, caller, subject)
+	case SelectorAttest:
+		// expiry (32)
+		if len(data) < 64 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		err = registry.Attest(stateAdapter, caller, subject, decodeUint64Word(data[32:64]))
+	default: // SelectorRevoke
+		err = registry.Revoke(stateAdapter, caller, subject)
+	}
+	if err != nil {
+		return nil, remainingGas, err
+	}
+	return nil, remainingGas, nil
+}
+
+// RequiredGas returns the gas required for the precompile input
+func (c *IdentityContract) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return GasPoolLookup
+	}
+
+	switch binary.BigEndian.Uint32(input[:4]) {
+	case SelectorAddIssuer, SelectorRemoveIssuer:
+		return GasIdentityIssuer
+	case SelectorAttest:
+		return GasIdentityAttest
+	case SelectorRevoke:
+		return GasIdentityRevoke
+	default:
+		return GasPoolLookup
+	}
+}
+
+// EncodeAttestation encodes an attestation: subject (32) + issuer (32) +
+// issuedAt (32) + expiry (32) + revoked (32)
+func EncodeAttestation(att *Attestation) []byte {
+	result := make([]byte, 160)
+	copy(result[12:32], att.Subject.Bytes())
+	copy(result[44:64], att.Issuer.Bytes())
+	binary.BigEndian.PutUint64(result[88:96], att.IssuedAt)
+	binary.BigEndian.PutUint64(result[120:128], att.Expiry)
+	if att.Revoked {
+		result[159] = 1
+	}
+	return result
+}
+
+// encodeBool encodes b as a 32-byte word
+func encodeBool(b bool) []byte {
+	result := make([]byte, 32)
+	if b {
+		result[31] = 1
+	}
+	return result
+}
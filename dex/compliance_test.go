@@ -0,0 +1,133 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var (
+	testIdentityAdmin = common.HexToAddress("0x7777777777777777777777777777777777777777")
+	testIssuer        = common.HexToAddress("0x8888888888888888888888888888888888888888")
+)
+
+func TestIdentityRegistryIssuers(t *testing.T) {
+	stateDB := NewMockStateDB()
+	stateDB.SetBlockTimestamp(1000)
+	r := NewIdentityRegistry()
+	r.SetAdmin(stateDB, testIdentityAdmin)
+
+	if err := r.AddIssuer(stateDB, testIssuer, testIssuer); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if err := r.Attest(stateDB, testIssuer, testTrader, 2000); err != ErrInvalidIssuer {
+		t.Errorf("Expected ErrInvalidIssuer before activation, got %v", err)
+	}
+	if err := r.AddIssuer(stateDB, testIdentityAdmin, testIssuer); err != nil {
+		t.Fatalf("AddIssuer failed: %v", err)
+	}
+	if err := r.Attest(stateDB, testIssuer, testTrader, 1000); err != ErrInvalidAttestation {
+		t.Errorf("Expected ErrInvalidAttestation for past expiry, got %v", err)
+	}
+	if err := r.Attest(stateDB, testIssuer, testTrader, 2000); err != nil {
+		t.Fatalf("Attest failed: %v", err)
+	}
+	if !r.IsVerified(stateDB, testTrader) {
+		t.Error("Expected trader to be verified")
+	}
+
+	// Attestations live in state, not in the registry
+	if !NewIdentityRegistry().IsVerified(stateDB, testTrader) {
+		t.Error("Expected attestation to be read from state")
+	}
+
+	// Attestations lapse at expiry
+	stateDB.SetBlockTimestamp(2000)
+	if r.IsVerified(stateDB, testTrader) {
+		t.Error("Expected attestation to lapse at expiry")
+	}
+
+	// Removing the issuer voids its attestations
+	stateDB.SetBlockTimestamp(1500)
+	if err := r.RemoveIssuer(stateDB, testIdentityAdmin, testIssuer); err != nil {
+		t.Fatalf("RemoveIssuer failed: %v", err)
+	}
+	if r.IsVerified(stateDB, testTrader) {
+		t.Error("Expected attestation of removed issuer to be void")
+	}
+
+	if err := r.Revoke(stateDB, testTrader, testTrader); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if err := r.Revoke(stateDB, testIdentityAdmin, testTrader); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if att, _ := r.GetAttestation(stateDB, testTrader); !att.Revoked || att.IssuedAt != 1000 || att.Expiry != 2000 {
+		t.Errorf("Expected revoked attestation issued at 1000 until 2000, got %+v", att)
+	}
+}
+
+func TestComplianceHookGatesPool(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+	key.Hooks = ComplianceHookAddress
+
+	registry := pm.Identity()
+	registry.SetAdmin(stateDB, testIdentityAdmin)
+	if err := registry.AddIssuer(stateDB, testIdentityAdmin, testIssuer); err != nil {
+		t.Fatalf("AddIssuer failed: %v", err)
+	}
+
+	sqrtPriceX96 := new(big.Int).Lsh(big.NewInt(1), 96)
+	if _, err := pm.Initialize(stateDB, key, sqrtPriceX96, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	pm.pools[key.ID()].Liquidity = big.NewInt(1_000_000_000)
+	openLock(pm, testTrader)
+
+	swap := SwapParams{
+		ZeroForOne:        true,
+		AmountSpecified:   big.NewInt(1_000),
+		SqrtPriceLimitX96: MinSqrtRatio,
+	}
+	add := ModifyLiquidityParams{
+		TickLower:      -1000,
+		TickUpper:      1000,
+		LiquidityDelta: big.NewInt(1000),
+	}
+
+	if _, err := pm.Swap(stateDB, key, swap, nil); err != ErrNotVerified {
+		t.Errorf("Expected ErrNotVerified for swap, got %v", err)
+	}
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, add, nil); err != ErrNotVerified {
+		t.Errorf("Expected ErrNotVerified for add liquidity, got %v", err)
+	}
+
+	if err := registry.Attest(stateDB, testIssuer, testTrader, ^uint64(0)); err != nil {
+		t.Fatalf("Attest failed: %v", err)
+	}
+	if _, err := pm.Swap(stateDB, key, swap, nil); err != nil {
+		t.Errorf("Swap failed for verified trader: %v", err)
+	}
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, add, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed for verified trader: %v", err)
+	}
+
+	// A revoked LP can still withdraw
+	if err := registry.Revoke(stateDB, testIssuer, testTrader); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	remove := add
+	remove.LiquidityDelta = big.NewInt(-1000)
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, remove, nil); err != nil {
+		t.Errorf("Expected revoked LP to remove liquidity, got %v", err)
+	}
+	if _, err := pm.Swap(stateDB, key, swap, nil); err != ErrNotVerified {
+		t.Errorf("Expected ErrNotVerified after revocation, got %v", err)
+	}
+}
@@ -0,0 +1,789 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// =========================================================================
+// Vesting Escrow (LP-9090 LXEscrow)
+// =========================================================================
+//
+// Projects launching pools lock team tokens and LP positions in the escrow
+// and release them to a beneficiary on a cliff/linear schedule. Locks settle
+// through flash accounting: creating a token lock debits the creator's
+// delta, and claiming credits the beneficiary's delta, to be taken before
+// the lock settles.
+//
+// A locked LP position never leaves the pool. Its liquidity is moved to a
+// position owned by the escrow over the same tick range, so it stays in
+// range, keeps earning swap fees and keeps accruing gauge rewards for the
+// beneficiary. Claiming moves the vested liquidity back to a position owned
+// by the beneficiary.
+
+// LockKind is the asset held by a vesting lock
+type LockKind uint8
+
+const (
+	LockTokens   LockKind = iota // An amount of a currency
+	LockPosition                 // Liquidity of a pool position
+)
+
+// VestingSchedule releases a lock linearly from Start to End. Nothing can
+// be claimed before Cliff; Cliff == End makes a plain time lock.
+type VestingSchedule struct {
+	Start uint64
+	Cliff uint64
+	End   uint64
+}
+
+// valid reports whether Start <= Cliff <= End
+func (s VestingSchedule) valid() bool {
+	return s.Start <= s.Cliff && s.Cliff <= s.End
+}
+
+// vested returns the part of total released at timestamp at
+func (s VestingSchedule) vested(total *big.Int, at uint64) *big.Int {
+	switch {
+	case at >= s.End:
+		return new(big.Int).Set(total)
+	case at < s.Cliff:
+		return big.NewInt(0)
+	}
+	vested := new(big.Int).Mul(total, new(big.Int).SetUint64(at-s.Start))
+	return vested.Div(vested, new(big.Int).SetUint64(s.End-s.Start))
+}
+
+// VestingLock is a single escrowed grant
+type VestingLock struct {
+	ID           uint64
+	Kind         LockKind
+	Creator      common.Address
+	Beneficiary  common.Address
+	Transferable bool // Beneficiary may reassign the lock
+	Schedule     VestingSchedule
+
+	Currency Currency // Locked currency (LockTokens)
+
+	PoolID    [32]byte // Pool of the locked position (LockPosition)
+	TickLower int24
+	TickUpper int24
+	Salt      [32]byte // Salt of the beneficiary's position on release
+
+	Total   *big.Int // Tokens or liquidity locked
+	Claimed *big.Int // Tokens or liquidity released so far
+}
+
+// Storage key prefixes for LXEscrow, stored at the LXEscrow address:
+//
+//	escw/last                      -> ID of the last lock created
+//	escw/lock || id || "ben"       -> beneficiary (set for every lock)
+//	escw/lock || id || "meta"      -> kind (byte 0) | transferable (byte 1) |
+//	                                  tickLower (bytes 4..8) | tickUpper (bytes 8..12)
+//	escw/lock || id || "sched"     -> start | cliff | end (bytes 8..32)
+//	escw/lock || id || "crt", "cur", "pool", "salt", "tot", "clm"
+//	escw/ben || beneficiary        -> number of locks held
+//	escw/ben || beneficiary || i   -> lock ID
+var (
+	escrowLastPrefix   = []byte("escw/last")
+	escrowLockPrefix   = []byte("escw/lock")
+	escrowHolderPrefix = []byte("escw/ben")
+)
+
+// EscrowManager holds vesting locks. All state lives at the LXEscrow
+// address, and locks vest by block timestamp.
+type EscrowManager struct{}
+
+// NewEscrowManager creates an escrow with no locks
+func NewEscrowManager() *EscrowManager {
+	return &EscrowManager{}
+}
+
+// escrowLockKey returns the storage key of a lock field
+func escrowLockKey(id uint64, field string) common.Hash {
+	return makeStorageKey(escrowLockPrefix, append(encodeUint64(id)[24:], field...))
+}
+
+// escrowHolderKey returns the storage key of a beneficiary's lock count, or
+// of one of its lock IDs
+func escrowHolderKey(beneficiary common.Address, index ...uint64) common.Hash {
+	id := beneficiary.Bytes()
+	for _, i := range index {
+		id = append(id, encodeUint64(i)[24:]...)
+	}
+	return makeStorageKey(escrowHolderPrefix, id)
+}
+
+// loadLock loads a lock, or returns ErrLockNotFound
+func (em *EscrowManager) loadLock(stateDB StateDB, id uint64) (*VestingLock, error) {
+	get := func(field string) common.Hash {
+		return stateDB.GetState(lxEscrowAddr, escrowLockKey(id, field))
+	}
+	beneficiary := common.BytesToAddress(get("ben").Bytes())
+	if beneficiary == (common.Address{}) {
+		return nil, ErrLockNotFound
+	}
+
+	meta, sched := get("meta"), get("sched")
+	return &VestingLock{
+		ID:           id,
+		Kind:         LockKind(meta[0]),
+		Creator:      common.BytesToAddress(get("crt").Bytes()),
+		Beneficiary:  beneficiary,
+		Transferable: meta[1] != 0,
+		Schedule: VestingSchedule{
+			Start: binary.BigEndian.Uint64(sched[8:16]),
+			Cliff: binary.BigEndian.Uint64(sched[16:24]),
+			End:   binary.BigEndian.Uint64(sched[24:32]),
+		},
+		Currency:  Currency{Address: common.BytesToAddress(get("cur").Bytes())},
+		PoolID:    get("pool"),
+		TickLower: int24(int32(binary.BigEndian.Uint32(meta[4:8]))),
+		TickUpper: int24(int32(binary.BigEndian.Uint32(meta[8:12]))),
+		Salt:      get("salt"),
+		Total:     get("tot").Big(),
+		Claimed:   get("clm").Big(),
+	}, nil
+}
+
+// saveLock stores a lock
+func (em *EscrowManager) saveLock(stateDB StateDB, lock *VestingLock) {
+	set := func(field string, value common.Hash) {
+		stateDB.SetState(lxEscrowAddr, escrowLockKey(lock.ID, field), value)
+	}
+
+	var meta, sched common.Hash
+	meta[0] = byte(lock.Kind)
+	if lock.Transferable {
+		meta[1] = 1
+	}
+	binary.BigEndian.PutUint32(meta[4:8], uint32(int32(lock.TickLower)))
+	binary.BigEndian.PutUint32(meta[8:12], uint32(int32(lock.TickUpper)))
+	binary.BigEndian.PutUint64(sched[8:16], lock.Schedule.Start)
+	binary.BigEndian.PutUint64(sched[16:24], lock.Schedule.Cliff)
+	binary.BigEndian.PutUint64(sched[24:32], lock.Schedule.End)
+
+	set("ben", common.BytesToHash(lock.Beneficiary.Bytes()))
+	set("meta", meta)
+	set("sched", sched)
+	set("crt", common.BytesToHash(lock.Creator.Bytes()))
+	set("cur", common.BytesToHash(lock.Currency.Address.Bytes()))
+	set("pool", lock.PoolID)
+	set("salt", lock.Salt)
+	set("tot", common.BigToHash(lock.Total))
+	set("clm", common.BigToHash(lock.Claimed))
+}
+
+// addHolding appends a lock ID to a beneficiary's locks
+func (em *EscrowManager) addHolding(stateDB StateDB, beneficiary common.Address, id uint64) {
+	count := decodeUint64Word(stateDB.GetState(lxEscrowAddr, escrowHolderKey(beneficiary)).Bytes())
+	stateDB.SetState(lxEscrowAddr, escrowHolderKey(beneficiary, count), common.BytesToHash(encodeUint64(id)))
+	stateDB.SetState(lxEscrowAddr, escrowHolderKey(beneficiary), common.BytesToHash(encodeUint64(count+1)))
+}
+
+// removeHolding removes a lock ID from a beneficiary's locks, moving the
+// last entry into its slot
+func (em *EscrowManager) removeHolding(stateDB StateDB, beneficiary common.Address, id uint64) {
+	ids := em.LocksOf(stateDB, beneficiary)
+	last := uint64(len(ids)) - 1
+	for i, lockID := range ids {
+		if lockID == id {
+			stateDB.SetState(lxEscrowAddr, escrowHolderKey(beneficiary, uint64(i)), common.BytesToHash(encodeUint64(ids[last])))
+			stateDB.SetState(lxEscrowAddr, escrowHolderKey(beneficiary, last), common.Hash{})
+			stateDB.SetState(lxEscrowAddr, escrowHolderKey(beneficiary), common.BytesToHash(encodeUint64(last)))
+			return
+		}
+	}
+}
+
+// add validates and records a new lock and returns its ID
+func (em *EscrowManager) add(stateDB StateDB, lock *VestingLock) (uint64, error) {
+	if lock.Beneficiary == (common.Address{}) || !lock.Schedule.valid() {
+		return 0, ErrInvalidSchedule
+	}
+
+	lastKey := makeStorageKey(escrowLastPrefix, nil)
+	lock.ID = decodeUint64Word(stateDB.GetState(lxEscrowAddr, lastKey).Bytes()) + 1
+	stateDB.SetState(lxEscrowAddr, lastKey, common.BytesToHash(encodeUint64(lock.ID)))
+
+	lock.Claimed = big.NewInt(0)
+	em.saveLock(stateDB, lock)
+	em.addHolding(stateDB, lock.Beneficiary, lock.ID)
+	return lock.ID, nil
+}
+
+// GetLock returns a lock
+func (em *EscrowManager) GetLock(stateDB StateDB, id uint64) (*VestingLock, error) {
+	return em.loadLock(stateDB, id)
+}
+
+// LocksOf returns the IDs of the locks held by a beneficiary
+func (em *EscrowManager) LocksOf(stateDB StateDB, beneficiary common.Address) []uint64 {
+	count := decodeUint64Word(stateDB.GetState(lxEscrowAddr, escrowHolderKey(beneficiary)).Bytes())
+	ids := make([]uint64, 0, count)
+	for i := uint64(0); i < count; i++ {
+		ids = append(ids, decodeUint64Word(stateDB.GetState(lxEscrowAddr, escrowHolderKey(beneficiary, i)).Bytes()))
+	}
+	return ids
+}
+
+// VestedAt returns the total amount of a lock released by timestamp at,
+// including amounts already claimed
+func (em *EscrowManager) VestedAt(stateDB StateDB, id uint64, at uint64) (*big.Int, error) {
+	lock, err := em.loadLock(stateDB, id)
+	if err != nil {
+		return nil, err
+	}
+	return lock.Schedule.vested(lock.Total, at), nil
+}
+
+// Claimable returns the amount of a lock that can be claimed in the
+// current block
+func (em *EscrowManager) Claimable(stateDB StateDB, id uint64) (*big.Int, error) {
+	lock, err := em.loadLock(stateDB, id)
+	if err != nil {
+		return nil, err
+	}
+	return em.claimable(stateDB, lock), nil
+}
+
+// claimable returns the vested but unclaimed amount of a lock
+func (em *EscrowManager) claimable(stateDB StateDB, lock *VestingLock) *big.Int {
+	vested := lock.Schedule.vested(lock.Total, stateDB.GetBlockTimestamp())
+	return vested.Sub(vested, lock.Claimed)
+}
+
+// claim releases everything vested to the beneficiary and returns a copy
+// of the lock as it was before the claim, with the amount released
+func (em *EscrowManager) claim(stateDB StateDB, id uint64, caller common.Address) (VestingLock, *big.Int, error) {
+	lock, err := em.loadLock(stateDB, id)
+	if err != nil {
+		return VestingLock{}, nil, err
+	}
+	if lock.Beneficiary != caller {
+		return VestingLock{}, nil, ErrUnauthorized
+	}
+	amount := em.claimable(stateDB, lock)
+	if amount.Sign() == 0 {
+		return VestingLock{}, nil, ErrNothingVested
+	}
+
+	before := *lock
+	before.Claimed = new(big.Int).Set(lock.Claimed)
+	lock.Claimed.Add(lock.Claimed, amount)
+	em.saveLock(stateDB, lock)
+	return before, amount, nil
+}
+
+// transfer reassigns a lock to a new beneficiary and returns a copy of the
+// lock as it was before the transfer
+func (em *EscrowManager) transfer(stateDB StateDB, id uint64, caller, to common.Address) (VestingLock, error) {
+	if to == (common.Address{}) {
+		return VestingLock{}, ErrInvalidParameter
+	}
+
+	lock, err := em.loadLock(stateDB, id)
+	if err != nil {
+		return VestingLock{}, err
+	}
+	if lock.Beneficiary != caller {
+		return VestingLock{}, ErrUnauthorized
+	}
+	if !lock.Transferable {
+		return VestingLock{}, ErrLockNotTransferable
+	}
+
+	before := *lock
+	em.removeHolding(stateDB, caller, id)
+	em.addHolding(stateDB, to, id)
+	lock.Beneficiary = to
+	em.saveLock(stateDB, lock)
+	return before, nil
+}
+
+// escrowPositionKey is the key of the escrow-owned position holding a
+// position lock's liquidity. It depends on the beneficiary, so gauge
+// rewards accrued under one beneficiary stay with them after a transfer.
+func escrowPositionKey(lock *VestingLock) [32]byte {
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], lock.ID)
+	salt := makeStorageKey(escrowPrefix, append(id[:], lock.Beneficiary.Bytes()...))
+	return PositionKey(lxEscrowAddr, lock.TickLower, lock.TickUpper, salt)
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// Escrow returns the pool manager's vesting escrow
+func (pm *PoolManager) Escrow() *EscrowManager {
+	return pm.escrow
+}
+
+// CreateTokenLock locks amount of currency from the current locker for a
+// beneficiary. The locker owes the amount in its flash-accounting delta.
+func (pm *PoolManager) CreateTokenLock(
+	stateDB StateDB,
+	currency Currency,
+	amount *big.Int,
+	beneficiary common.Address,
+	schedule VestingSchedule,
+	transferable bool,
+) (uint64, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return 0, ErrUnauthorized
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	id, err := pm.escrow.add(stateDB, &VestingLock{
+		Kind:         LockTokens,
+		Creator:      locker,
+		Beneficiary:  beneficiary,
+		Transferable: transferable,
+		Schedule:     schedule,
+		Currency:     currency,
+		Total:        new(big.Int).Set(amount),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Positive delta: the locker owes the pool
+	pm.updateDelta(locker, currency, amount)
+	return id, nil
+}
+
+// CreatePositionLock locks liquidity of one of the current locker's
+// positions for a beneficiary. The liquidity stays in the pool.
+func (pm *PoolManager) CreatePositionLock(
+	stateDB StateDB,
+	key PoolKey,
+	tickLower, tickUpper int24,
+	salt [32]byte,
+	liquidity *big.Int,
+	beneficiary common.Address,
+	schedule VestingSchedule,
+	transferable bool,
+) (uint64, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return 0, ErrUnauthorized
+	}
+	if liquidity == nil || liquidity.Sign() <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	poolId := key.ID()
+	if !pm.getPool(stateDB, poolId).IsInitialized() {
+		return 0, ErrPoolNotInitialized
+	}
+	sourceKey := PositionKey(locker, tickLower, tickUpper, salt)
+	if pm.getPosition(stateDB, sourceKey).Liquidity.Cmp(liquidity) < 0 {
+		return 0, ErrInsufficientLiquidity
+	}
+
+	lock := &VestingLock{
+		Kind:         LockPosition,
+		Creator:      locker,
+		Beneficiary:  beneficiary,
+		Transferable: transferable,
+		Schedule:     schedule,
+		PoolID:       poolId,
+		TickLower:    tickLower,
+		TickUpper:    tickUpper,
+		Salt:         salt,
+		Total:        new(big.Int).Set(liquidity),
+	}
+	id, err := pm.escrow.add(stateDB, lock)
+	if err != nil {
+		return 0, err
+	}
+
+	pm.moveLiquidity(stateDB, lock,
+		positionRef{key: sourceKey, owner: locker, earner: locker},
+		positionRef{key: escrowPositionKey(lock), owner: lxEscrowAddr, earner: beneficiary},
+		liquidity,
+	)
+	return id, nil
+}
+
+// ClaimVested releases everything vested in a lock to its beneficiary, who
+// must be the current locker. Tokens are credited to the locker's delta;
+// liquidity moves to the beneficiary's position over the lock's range.
+func (pm *PoolManager) ClaimVested(stateDB StateDB, id uint64) (*big.Int, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+
+	lock, amount, err := pm.escrow.claim(stateDB, id, locker)
+	if err != nil {
+		return nil, err
+	}
+
+	if lock.Kind == LockTokens {
+		// Negative delta: the pool owes the locker
+		pm.updateDelta(locker, lock.Currency, new(big.Int).Neg(amount))
+		return amount, nil
+	}
+
+	pm.moveLiquidity(stateDB, &lock,
+		positionRef{key: escrowPositionKey(&lock), owner: lxEscrowAddr, earner: locker},
+		positionRef{key: PositionKey(locker, lock.TickLower, lock.TickUpper, lock.Salt), owner: locker, earner: locker},
+		amount,
+	)
+	return amount, nil
+}
+
+// CollectLockFees credits the swap fees owed to a locked position to its
+// beneficiary's delta. The beneficiary must be the current locker.
+func (pm *PoolManager) CollectLockFees(stateDB StateDB, key PoolKey, id uint64) (BalanceDelta, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return ZeroBalanceDelta(), ErrUnauthorized
+	}
+
+	lock, err := pm.escrow.GetLock(stateDB, id)
+	if err != nil {
+		return ZeroBalanceDelta(), err
+	}
+	if lock.Kind != LockPosition || lock.PoolID != key.ID() {
+		return ZeroBalanceDelta(), ErrInvalidParameter
+	}
+	if lock.Beneficiary != locker {
+		return ZeroBalanceDelta(), ErrUnauthorized
+	}
+
+	position := pm.getPosition(stateDB, escrowPositionKey(lock))
+	if position.Liquidity.Sign() > 0 {
+		pm.pokePosition(stateDB, lock.PoolID, pm.getPool(stateDB, lock.PoolID), position, lock.TickLower, lock.TickUpper)
+	}
+	owed0, owed1 := position.TokensOwed0, position.TokensOwed1
+	position.TokensOwed0, position.TokensOwed1 = big.NewInt(0), big.NewInt(0)
+	pm.setPosition(stateDB, escrowPositionKey(lock), position)
+
+	pm.updateDelta(locker, key.Currency0, new(big.Int).Neg(owed0))
+	pm.updateDelta(locker, key.Currency1, new(big.Int).Neg(owed1))
+	return NewBalanceDelta(owed0, owed1), nil
+}
+
+// TransferLock reassigns a transferable lock to a new beneficiary. The
+// locked liquidity of a position lock moves with it.
+func (pm *PoolManager) TransferLock(stateDB StateDB, caller common.Address, id uint64, to common.Address) error {
+	before, err := pm.escrow.transfer(stateDB, id, caller, to)
+	if err != nil {
+		return err
+	}
+	if before.Kind != LockPosition {
+		return nil
+	}
+
+	after := before
+	after.Beneficiary = to
+	remaining := new(big.Int).Sub(before.Total, before.Claimed)
+	if remaining.Sign() > 0 {
+		pm.moveLiquidity(stateDB, &before,
+			positionRef{key: escrowPositionKey(&before), owner: lxEscrowAddr, earner: caller},
+			positionRef{key: escrowPositionKey(&after), owner: lxEscrowAddr, earner: to},
+			remaining,
+		)
+	}
+	return nil
+}
+
+// positionRef identifies a position and the account its gauge rewards
+// accrue to
+type positionRef struct {
+	key    [32]byte
+	owner  common.Address
+	earner common.Address
+}
+
+// moveLiquidity transfers liquidity between two positions over a lock's
+// tick range. Pool liquidity is unchanged, so the liquidity keeps earning
+// fees throughout; both positions accrue fees earned before the move.
+func (pm *PoolManager) moveLiquidity(stateDB StateDB, lock *VestingLock, from, to positionRef, amount *big.Int) {
+	pool := pm.getPool(stateDB, lock.PoolID)
+
+	source := pm.getPosition(stateDB, from.key)
+	pm.pokePosition(stateDB, lock.PoolID, pool, source, lock.TickLower, lock.TickUpper)
+	source.Liquidity = new(big.Int).Sub(source.Liquidity, amount)
+	pm.setPosition(stateDB, from.key, source)
+
+	dest := pm.getPosition(stateDB, to.key)
+	pm.pokePosition(stateDB, lock.PoolID, pool, dest, lock.TickLower, lock.TickUpper)
+	dest.Liquidity = new(big.Int).Add(dest.Liquidity, amount)
+	dest.Owner = to.owner
+	dest.TickLower = lock.TickLower
+	dest.TickUpper = lock.TickUpper
+	pm.setPosition(stateDB, to.key, dest)
+
+	// Checkpoint liquidity mining rewards for both positions
+	if pm.gauges != nil {
+		pm.gauges.OnPositionModified(stateDB, lock.PoolID, from.key, from.earner, source.Liquidity)
+		pm.gauges.OnPositionModified(stateDB, lock.PoolID, to.key, to.earner, dest.Liquidity)
+	}
+}
+
+// =========================================================================
+// LXEscrow precompile
+// =========================================================================
+
+// Method selectors for LXEscrow
+const (
+	SelectorCreateTokenLock    uint32 = 0x01000000 // createTokenLock(Currency,uint256,address,uint64,uint64,uint64,bool)
+	SelectorCreatePositionLock uint32 = 0x02000000 // createPositionLock(PoolKey,int24,int24,bytes32,uint256,address,uint64,uint64,uint64,bool)
+	SelectorClaimVested        uint32 = 0x03000000 // claim(uint256)
+	SelectorCollectLockFees    uint32 = 0x04000000 // collectFees(PoolKey,uint256)
+	SelectorTransferLock       uint32 = 0x05000000 // transferLock(uint256,address)
+	SelectorGetLock            uint32 = 0x06000000 // getLock(uint256)
+	SelectorVestedAt           uint32 = 0x07000000 // vestedAt(uint256,uint64)
+	SelectorLocksOf            uint32 = 0x08000000 // locksOf(address)
+)
+
+// EscrowContract implements the LXEscrow precompile over the pool manager
+// shared with LXPool
+type EscrowContract struct {
+	poolManager *PoolManager
+}
+
+// Run executes the precompile
+func (c *EscrowContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+
+	selector := binary.BigEndian.Uint32(input[:4])
+	data := input[4:]
+
+	gas := c.RequiredGas(input)
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+	remainingGas = suppliedGas - gas
+
+	stateAdapter := newPoolStateAdapter(accessibleState)
+	switch selector {
+	case SelectorGetLock, SelectorVestedAt, SelectorLocksOf:
+		ret, err = c.runView(stateAdapter, selector, data)
+		return ret, remainingGas, err
+	case SelectorCreateTokenLock, SelectorCreatePositionLock, SelectorClaimVested,
+		SelectorCollectLockFees, SelectorTransferLock:
+	default:
+		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	}
+
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	switch selector {
+	case SelectorCreateTokenLock:
+		// currency (32) + amount (32) + terms (160)
+		if len(data) < 224 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		currency := Currency{Address: common.BytesToAddress(data[12:32])}
+		beneficiary, schedule, transferable := decodeVestingTerms(data[64:224])
+		id, err := c.poolManager.CreateTokenLock(stateAdapter, currency, new(big.Int).SetBytes(data[32:64]), beneficiary, schedule, transferable)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return encodeUint64(id), remainingGas, nil
+
+	case SelectorCreatePositionLock:
+		// PoolKey (128) + tickLower (32) + tickUpper (32) + salt (32) + liquidity (32) + terms (160)
+		if len(data) < 416 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		key, err := DecodePoolKey(data[:128])
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		var salt [32]byte
+		copy(salt[:], data[192:224])
+		beneficiary, schedule, transferable := decodeVestingTerms(data[256:416])
+		id, err := c.poolManager.CreatePositionLock(
+			stateAdapter, key,
+			decodeInt24Word(data[128:160]), decodeInt24Word(data[160:192]),
+			salt, new(big.Int).SetBytes(data[224:256]),
+			beneficiary, schedule, transferable,
+		)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return encodeUint64(id), remainingGas, nil
+
+	case SelectorClaimVested:
+		if len(data) < 32 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		amount, err := c.poolManager.ClaimVested(stateAdapter, decodeUint64Word(data[:32]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		result := make([]byte, 32)
+		amount.FillBytes(result)
+		return result, remainingGas, nil
+
+	case SelectorCollectLockFees:
+		// PoolKey (128) + lockId (32)
+		if len(data) < 160 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		key, err := DecodePoolKey(data[:128])
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		fees, err := c.poolManager.CollectLockFees(stateAdapter, key, decodeUint64Word(data[128:160]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		result := make([]byte, 64)
+		fees.Amount0.FillBytes(result[0:32])
+		fees.Amount1.FillBytes(result[32:64])
+		return result, remainingGas, nil
+
+	default: // SelectorTransferLock
+		// lockId (32) + to (32)
+		if len(data) < 64 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		to := common.BytesToAddress(data[44:64])
+		if err := c.poolManager.TransferLock(stateAdapter, caller, decodeUint64Word(data[:32]), to); err != nil {
+			return nil, remainingGas, err
+		}
+		return nil, remainingGas, nil
+	}
+}
+
+// runView serves the read-only escrow queries
+func (c *EscrowContract) runView(stateDB StateDB, selector uint32, data []byte) ([]byte, error) {
+	escrow := c.poolManager.escrow
+
+	switch selector {
+	case SelectorGetLock:
+		if len(data) < 32 {
+			return nil, fmt.Errorf("input too short")
+		}
+		id := decodeUint64Word(data[:32])
+		lock, err := escrow.GetLock(stateDB, id)
+		if err != nil {
+			return nil, err
+		}
+		claimable, err := escrow.Claimable(stateDB, id)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeVestingLock(lock, claimable), nil
+
+	case SelectorVestedAt:
+		// lockId (32) + timestamp (32)
+		if len(data) < 64 {
+			return nil, fmt.Errorf("input too short")
+		}
+		vested, err := escrow.VestedAt(stateDB, decodeUint64Word(data[:32]), decodeUint64Word(data[32:64]))
+		if err != nil {
+			return nil, err
+		}
+		result := make([]byte, 32)
+		vested.FillBytes(result)
+		return result, nil
+
+	default: // SelectorLocksOf
+		if len(data) < 32 {
+			return nil, fmt.Errorf("input too short")
+		}
+		ids := escrow.LocksOf(stateDB, common.BytesToAddress(data[12:32]))
+		result := make([]byte, 32*(len(ids)+1))
+		binary.BigEndian.PutUint64(result[24:32], uint64(len(ids)))
+		for i, id := range ids {
+			binary.BigEndian.PutUint64(result[32*(i+1)+24:32*(i+2)], id)
+		}
+		return result, nil
+	}
+}
+
+// RequiredGas returns the gas required for the precompile input
+func (c *EscrowContract) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return GasPoolLookup
+	}
+
+	switch binary.BigEndian.Uint32(input[:4]) {
+	case SelectorCreateTokenLock, SelectorCreatePositionLock:
+		return GasEscrowCreate
+	case SelectorClaimVested, SelectorCollectLockFees:
+		return GasEscrowClaim
+	case SelectorTransferLock:
+		return GasEscrowTransfer
+	default:
+		return GasPoolLookup
+	}
+}
+
+// decodeVestingTerms decodes beneficiary (32) + start (32) + cliff (32) +
+// end (32) + transferable (32)
+func decodeVestingTerms(data []byte) (common.Address, VestingSchedule, bool) {
+	beneficiary := common.BytesToAddress(data[12:32])
+	schedule := VestingSchedule{
+		Start: decodeUint64Word(data[32:64]),
+		Cliff: decodeUint64Word(data[64:96]),
+		End:   decodeUint64Word(data[96:128]),
+	}
+	return beneficiary, schedule, data[159] != 0
+}
+
+// decodeUint64Word decodes the low 8 bytes of a 32-byte word
+func decodeUint64Word(word []byte) uint64 {
+	return binary.BigEndian.Uint64(word[24:32])
+}
+
+// decodeInt24Word decodes a sign-extended int24 from a 32-byte word
+func decodeInt24Word(word []byte) int24 {
+	return int24(binary.BigEndian.Uint32(word[28:32]))
+}
+
+// encodeUint64 encodes v as a 32-byte word
+func encodeUint64(v uint64) []byte {
+	result := make([]byte, 32)
+	binary.BigEndian.PutUint64(result[24:32], v)
+	return result
+}
+
+// EncodeVestingLock encodes a lock: id (32) + kind (32) + beneficiary (32) +
+// transferable (32) + start (32) + cliff (32) + end (32) + total (32) +
+// claimed (32) + claimable (32)
+func EncodeVestingLock(lock *VestingLock, claimable *big.Int) []byte {
+	result := make([]byte, 320)
+	binary.BigEndian.PutUint64(result[24:32], lock.ID)
+	result[63] = byte(lock.Kind)
+	copy(result[76:96], lock.Beneficiary.Bytes())
+	if lock.Transferable {
+		result[127] = 1
+	}
+	binary.BigEndian.PutUint64(result[152:160], lock.Schedule.Start)
+	binary.BigEndian.PutUint64(result[184:192], lock.Schedule.Cliff)
+	binary.BigEndian.PutUint64(result[216:224], lock.Schedule.End)
+	lock.Total.FillBytes(result[224:256])
+	lock.Claimed.FillBytes(result[256:288])
+	claimable.FillBytes(result[288:320])
+	return result
+}
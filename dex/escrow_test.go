@@ -0,0 +1,179 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var testBeneficiary = common.HexToAddress("0x5555555555555555555555555555555555555555")
+
+// openLock pushes account onto the locker stack of pm
+func openLock(pm *PoolManager, account common.Address) {
+	pm.lockers = append(pm.lockers, account)
+	if pm.currentDeltas[account] == nil {
+		pm.currentDeltas[account] = make(map[Currency]*big.Int)
+	}
+}
+
+func TestTokenLockVesting(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	stateDB.SetBlockTimestamp(1000)
+	token := newTestPoolKey().Currency1
+	schedule := VestingSchedule{Start: 1000, Cliff: 1250, End: 2000}
+
+	if _, err := pm.CreateTokenLock(stateDB, token, big.NewInt(1000), testBeneficiary, schedule, false); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized outside a lock, got %v", err)
+	}
+
+	openLock(pm, testTrader)
+	bad := VestingSchedule{Start: 1000, Cliff: 2500, End: 2000}
+	if _, err := pm.CreateTokenLock(stateDB, token, big.NewInt(1000), testBeneficiary, bad, false); err != ErrInvalidSchedule {
+		t.Errorf("Expected ErrInvalidSchedule, got %v", err)
+	}
+
+	id, err := pm.CreateTokenLock(stateDB, token, big.NewInt(1000), testBeneficiary, schedule, false)
+	if err != nil {
+		t.Fatalf("CreateTokenLock failed: %v", err)
+	}
+	if delta := pm.GetDelta(testTrader, token); delta.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected creator delta 1000, got %s", delta)
+	}
+
+	// Only the beneficiary claims, and nothing before the cliff
+	stateDB.SetBlockTimestamp(1200)
+	if _, err := pm.ClaimVested(stateDB, id); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized for creator, got %v", err)
+	}
+	openLock(pm, testBeneficiary)
+	if _, err := pm.ClaimVested(stateDB, id); err != ErrNothingVested {
+		t.Errorf("Expected ErrNothingVested before cliff, got %v", err)
+	}
+
+	// Past the cliff, vesting is linear from the start
+	stateDB.SetBlockTimestamp(1500)
+	amount, err := pm.ClaimVested(stateDB, id)
+	if err != nil {
+		t.Fatalf("ClaimVested failed: %v", err)
+	}
+	if amount.Cmp(big.NewInt(500)) != 0 {
+		t.Errorf("Expected 500 claimed, got %s", amount)
+	}
+	if delta := pm.GetDelta(testBeneficiary, token); delta.Cmp(big.NewInt(-500)) != 0 {
+		t.Errorf("Expected beneficiary delta -500, got %s", delta)
+	}
+
+	if vested, _ := pm.Escrow().VestedAt(stateDB, id, 1750); vested.Cmp(big.NewInt(750)) != 0 {
+		t.Errorf("Expected 750 vested at 1750, got %s", vested)
+	}
+	stateDB.SetBlockTimestamp(1750)
+	if claimable, _ := pm.Escrow().Claimable(stateDB, id); claimable.Cmp(big.NewInt(250)) != 0 {
+		t.Errorf("Expected 250 claimable, got %s", claimable)
+	}
+
+	// Locks live in state, not in the manager
+	if lock, err := NewEscrowManager().GetLock(stateDB, id); err != nil || lock.Claimed.Cmp(big.NewInt(500)) != 0 || lock.Schedule != schedule {
+		t.Errorf("Expected lock with 500 claimed from state, got %+v (%v)", lock, err)
+	}
+
+	if err := pm.TransferLock(stateDB, testBeneficiary, id, testTrader); err != ErrLockNotTransferable {
+		t.Errorf("Expected ErrLockNotTransferable, got %v", err)
+	}
+}
+
+func TestPositionLockKeepsEarning(t *testing.T) {
+	stateDB := NewMockStateDB()
+	stateDB.SetBlockTimestamp(1000)
+	gc := NewGaugeController()
+	pm := newTestPoolManager()
+	pm.SetGaugeController(gc)
+	key := newTestPoolKey()
+
+	sqrtPriceX96 := new(big.Int).Lsh(big.NewInt(1), 96)
+	if _, err := pm.Initialize(stateDB, key, sqrtPriceX96, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if _, err := gc.Fund(stateDB, testGaugeFunder, key.ID(), testRewardToken, big.NewInt(1000), big.NewInt(1)); err != nil {
+		t.Fatalf("Fund failed: %v", err)
+	}
+
+	openLock(pm, testGaugeLP1)
+	params := ModifyLiquidityParams{
+		TickLower:      -1000,
+		TickUpper:      1000,
+		LiquidityDelta: big.NewInt(1000),
+	}
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, params, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+
+	schedule := VestingSchedule{Start: 1000, Cliff: 1000, End: 1100}
+	if _, err := pm.CreatePositionLock(stateDB, key, -1000, 1000, params.Salt, big.NewInt(1001), testGaugeLP2, schedule, true); err != ErrInsufficientLiquidity {
+		t.Errorf("Expected ErrInsufficientLiquidity, got %v", err)
+	}
+	id, err := pm.CreatePositionLock(stateDB, key, -1000, 1000, params.Salt, big.NewInt(1000), testGaugeLP2, schedule, true)
+	if err != nil {
+		t.Fatalf("CreatePositionLock failed: %v", err)
+	}
+
+	// The liquidity stays in the pool under an escrow-owned position
+	if liq := pm.pools[key.ID()].Liquidity; liq.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected pool liquidity 1000, got %s", liq)
+	}
+	lock, _ := pm.Escrow().GetLock(stateDB, id)
+	escrowKey := escrowPositionKey(lock)
+	if liq := pm.getPosition(stateDB, escrowKey).Liquidity; liq.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected escrow position liquidity 1000, got %s", liq)
+	}
+	lpKey := PositionKey(testGaugeLP1, -1000, 1000, params.Salt)
+	if liq := pm.getPosition(stateDB, lpKey).Liquidity; liq.Sign() != 0 {
+		t.Errorf("Expected creator position emptied, got %s", liq)
+	}
+
+	// Gauge rewards accrue to the beneficiary while locked
+	stateDB.timestamp += 10
+	if got, err := gc.Claim(stateDB, testGaugeLP2, key.ID(), testRewardToken, escrowKey); err != nil || got.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("Expected beneficiary to claim 10 rewards, got %v (%v)", got, err)
+	}
+
+	// Half vested: claiming moves liquidity to the beneficiary's own position
+	stateDB.SetBlockTimestamp(1050)
+	openLock(pm, testGaugeLP2)
+	amount, err := pm.ClaimVested(stateDB, id)
+	if err != nil {
+		t.Fatalf("ClaimVested failed: %v", err)
+	}
+	if amount.Cmp(big.NewInt(500)) != 0 {
+		t.Errorf("Expected 500 liquidity claimed, got %s", amount)
+	}
+	ownKey := PositionKey(testGaugeLP2, -1000, 1000, params.Salt)
+	if liq := pm.getPosition(stateDB, ownKey).Liquidity; liq.Cmp(big.NewInt(500)) != 0 {
+		t.Errorf("Expected beneficiary position liquidity 500, got %s", liq)
+	}
+
+	// Transfers move the unvested liquidity with the lock
+	if err := pm.TransferLock(stateDB, testGaugeLP1, id, testTrader); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if err := pm.TransferLock(stateDB, testGaugeLP2, id, testTrader); err != nil {
+		t.Fatalf("TransferLock failed: %v", err)
+	}
+	lock, _ = pm.Escrow().GetLock(stateDB, id)
+	if liq := pm.getPosition(stateDB, escrowPositionKey(lock)).Liquidity; liq.Cmp(big.NewInt(500)) != 0 {
+		t.Errorf("Expected transferred escrow liquidity 500, got %s", liq)
+	}
+	if liq := pm.getPosition(stateDB, escrowKey).Liquidity; liq.Sign() != 0 {
+		t.Errorf("Expected old escrow position emptied, got %s", liq)
+	}
+	if ids := pm.Escrow().LocksOf(stateDB, testTrader); len(ids) != 1 || ids[0] != id {
+		t.Errorf("Expected lock %d held by new beneficiary, got %v", id, ids)
+	}
+	if ids := pm.Escrow().LocksOf(stateDB, testGaugeLP2); len(ids) != 0 {
+		t.Errorf("Expected no locks for old beneficiary, got %v", ids)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"sort"
+
+	"github.com/luxfi/geth/common"
+)
+
+// Fee tier governance
+//
+// Pools may only be initialized with an enabled fee tier, using the tick
+// spacing enabled for it. The standard tiers are enabled by default; the
+// protocol fee controller enables new tiers (e.g. 0.02% for liquid staking
+// pairs) and disables tiers for new pools. Disabling a tier never affects
+// pools already initialized with it.
+//
+// Governance changes are stored under feeTierPrefix in the pool manager's
+// storage:
+//
+//	fee (3 bytes)        -> status (byte 0) | tick spacing (bytes 29..32)
+//	"count"              -> number of fees governance has changed
+//	"list" || index (8)  -> fee governance has changed
+//
+// A fee governance never touched falls back to DefaultFeeTiers.
+
+// MaxTickSpacing bounds the tick spacing of a fee tier
+const MaxTickSpacing int24 = 16384
+
+// FeeTier is an enabled fee and its tick spacing
+type FeeTier struct {
+	Fee         uint24 `json:"fee"`
+	TickSpacing int24  `json:"tickSpacing"`
+}
+
+// DefaultFeeTiers are the tiers enabled at genesis
+var DefaultFeeTiers = []FeeTier{
+	{Fee: Fee001, TickSpacing: TickSpacing001},
+	{Fee: Fee005, TickSpacing: TickSpacing005},
+	{Fee: Fee030, TickSpacing: TickSpacing030},
+	{Fee: Fee100, TickSpacing: TickSpacing100},
+}
+
+// Fee tier status stored in byte 0 of a fee's word
+const (
+	feeTierDefault  byte = 0 // never changed; DefaultFeeTiers applies
+	feeTierEnabled  byte = 1
+	feeTierDisabled byte = 2
+)
+
+// FeeTierRegistry holds the fee tiers new pools may use. All state lives
+// in the pool manager's storage.
+type FeeTierRegistry struct{}
+
+// NewFeeTierRegistry creates a registry with the default tiers enabled
+func NewFeeTierRegistry() *FeeTierRegistry {
+	return &FeeTierRegistry{}
+}
+
+// feeTierKey returns the storage key for a fee's status and tick spacing
+func feeTierKey(fee uint24) common.Hash {
+	return makeStorageKey(feeTierPrefix, []byte{byte(fee >> 16), byte(fee >> 8), byte(fee)})
+}
+
+// feeTierCountKey stores the number of fees governance has changed
+var feeTierCountKey = makeStorageKey(feeTierPrefix, []byte("count"))
+
+// feeTierListKey returns the storage key for an entry of the changed-fee list
+func feeTierListKey(index uint64) common.Hash {
+	return makeStorageKey(feeTierPrefix, append([]byte("list"), encodeUint64(index)[24:]...))
+}
+
+// defaultTickSpacing returns the tick spacing of a default tier
+func defaultTickSpacing(fee uint24) (int24, bool) {
+	for _, tier := range DefaultFeeTiers {
+		if tier.Fee == fee {
+			return tier.TickSpacing, true
+		}
+	}
+	return 0, false
+}
+
+// setTier stores a fee's status, recording fees outside the defaults the
+// first time they are changed so Tiers can enumerate them
+func (r *FeeTierRegistry) setTier(stateDB StateDB, fee uint24, status byte, tickSpacing int24) {
+	key := feeTierKey(fee)
+	if stateDB.GetState(poolManagerAddr, key)[0] == feeTierDefault {
+		if _, ok := defaultTickSpacing(fee); !ok {
+			count := decodeUint64Word(stateDB.GetState(poolManagerAddr, feeTierCountKey).Bytes())
+			stateDB.SetState(poolManagerAddr, feeTierListKey(count), common.BytesToHash(encodeUint64(uint64(fee))))
+			stateDB.SetState(poolManagerAddr, feeTierCountKey, common.BytesToHash(encodeUint64(count+1)))
+		}
+	}
+
+	var word common.Hash
+	word[0] = status
+	copy(word[29:], int24ToBytes(tickSpacing))
+	stateDB.SetState(poolManagerAddr, key, word)
+}
+
+// validate checks a tier's fee and tick spacing bounds
+func (t FeeTier) validate() error {
+	if t.Fee > FeeMax || t.TickSpacing <= 0 || t.TickSpacing > MaxTickSpacing {
+		return ErrInvalidFeeTier
+	}
+	return nil
+}
+
+// Enable enables a fee tier. A fee keeps the tick spacing it was first
+// enabled with until it is disabled; enabling it again with the same
+// spacing is a no-op.
+func (r *FeeTierRegistry) Enable(stateDB StateDB, fee uint24, tickSpacing int24) error {
+	tier := FeeTier{Fee: fee, TickSpacing: tickSpacing}
+	if err := tier.validate(); err != nil {
+		return err
+	}
+
+	if existing, ok := r.TickSpacing(stateDB, fee); ok && existing != tickSpacing {
+		return ErrInvalidFeeTier
+	}
+	r.setTier(stateDB, fee, feeTierEnabled, tickSpacing)
+	return nil
+}
+
+// Disable stops new pools from using a fee tier
+func (r *FeeTierRegistry) Disable(stateDB StateDB, fee uint24) error {
+	if _, ok := r.TickSpacing(stateDB, fee); !ok {
+		return ErrFeeTierNotEnabled
+	}
+	r.setTier(stateDB, fee, feeTierDisabled, 0)
+	return nil
+}
+
+// TickSpacing returns the tick spacing of an enabled fee tier
+func (r *FeeTierRegistry) TickSpacing(stateDB StateDB, fee uint24) (int24, bool) {
+	word := stateDB.GetState(poolManagerAddr, feeTierKey(fee))
+	switch word[0] {
+	case feeTierEnabled:
+		return decodeInt24Word(word[:]), true
+	case feeTierDisabled:
+		return 0, false
+	}
+	return defaultTickSpacing(fee)
+}
+
+// Tiers returns the enabled tiers ordered by fee
+func (r *FeeTierRegistry) Tiers(stateDB StateDB) []FeeTier {
+	fees := make([]uint24, 0, len(DefaultFeeTiers))
+	for _, tier := range DefaultFeeTiers {
+		fees = append(fees, tier.Fee)
+	}
+	count := decodeUint64Word(stateDB.GetState(poolManagerAddr, feeTierCountKey).Bytes())
+	for i := uint64(0); i < count; i++ {
+		fees = append(fees, uint24(decodeUint64Word(stateDB.GetState(poolManagerAddr, feeTierListKey(i)).Bytes())))
+	}
+
+	tiers := make([]FeeTier, 0, len(fees))
+	for _, fee := range fees {
+		if spacing, ok := r.TickSpacing(stateDB, fee); ok {
+			tiers = append(tiers, FeeTier{Fee: fee, TickSpacing: spacing})
+		}
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].Fee < tiers[j].Fee })
+	return tiers
+}
+
+// checkFeeTier reports whether a pool key uses an enabled fee tier
+func (r *FeeTierRegistry) checkFeeTier(stateDB StateDB, key PoolKey) error {
+	if spacing, ok := r.TickSpacing(stateDB, key.Fee); !ok || spacing != key.TickSpacing {
+		return ErrFeeTierNotEnabled
+	}
+	return nil
+}
+
+// FeeTiers returns the pool manager's fee tier registry
+func (pm *PoolManager) FeeTiers() *FeeTierRegistry {
+	return pm.feeTiers
+}
+
+// EnableFeeTier enables a fee tier for new pools (protocol fee controller only)
+func (pm *PoolManager) EnableFeeTier(stateDB StateDB, caller common.Address, fee uint24, tickSpacing int24) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	return pm.feeTiers.Enable(stateDB, fee, tickSpacing)
+}
+
+// DisableFeeTier disables a fee tier for new pools (protocol fee controller only)
+func (pm *PoolManager) DisableFeeTier(stateDB StateDB, caller common.Address, fee uint24) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	return pm.feeTiers.Disable(stateDB, fee)
+}
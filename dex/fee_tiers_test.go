@@ -0,0 +1,87 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+func TestFeeTierGovernance(t *testing.T) {
+	pm := newTestPoolManager()
+	controller := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	stateDB := NewMockStateDB()
+	pm.setProtocolFeeController(stateDB, controller)
+	sqrtPriceX96 := new(big.Int).Lsh(big.NewInt(1), 96)
+
+	// 0.02% for liquid staking pairs is not a default tier
+	lst := newTestPoolKey()
+	lst.Fee, lst.TickSpacing = 200, 4
+	if _, err := pm.Initialize(stateDB, lst, sqrtPriceX96, nil); err != ErrFeeTierNotEnabled {
+		t.Errorf("Expected ErrFeeTierNotEnabled, got %v", err)
+	}
+
+	if err := pm.EnableFeeTier(stateDB, testTrader, 200, 4); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if err := pm.EnableFeeTier(stateDB, controller, 200, 0); err != ErrInvalidFeeTier {
+		t.Errorf("Expected ErrInvalidFeeTier for zero spacing, got %v", err)
+	}
+	if err := pm.EnableFeeTier(stateDB, controller, 200, 4); err != nil {
+		t.Fatalf("EnableFeeTier failed: %v", err)
+	}
+	if err := pm.EnableFeeTier(stateDB, controller, 200, 8); err != ErrInvalidFeeTier {
+		t.Errorf("Expected ErrInvalidFeeTier for changed spacing, got %v", err)
+	}
+
+	// The tier is bound to its tick spacing
+	wrongSpacing := lst
+	wrongSpacing.TickSpacing = 10
+	if _, err := pm.Initialize(stateDB, wrongSpacing, sqrtPriceX96, nil); err != ErrFeeTierNotEnabled {
+		t.Errorf("Expected ErrFeeTierNotEnabled for wrong spacing, got %v", err)
+	}
+	if _, err := pm.Initialize(stateDB, lst, sqrtPriceX96, nil); err != nil {
+		t.Fatalf("Initialize failed for enabled tier: %v", err)
+	}
+
+	// Disabling only affects new pools
+	if _, err := pm.Initialize(stateDB, newTestPoolKey(), sqrtPriceX96, nil); err != nil {
+		t.Fatalf("Initialize failed for default tier: %v", err)
+	}
+	if err := pm.DisableFeeTier(stateDB, controller, Fee030); err != nil {
+		t.Fatalf("DisableFeeTier failed: %v", err)
+	}
+	other := newTestPoolKey()
+	other.Currency1 = Currency{Address: common.HexToAddress("0x2234567890123456789012345678901234567890")}
+	if _, err := pm.Initialize(stateDB, other, sqrtPriceX96, nil); err != ErrFeeTierNotEnabled {
+		t.Errorf("Expected ErrFeeTierNotEnabled after disable, got %v", err)
+	}
+	if err := pm.DisableFeeTier(stateDB, controller, Fee030); err != ErrFeeTierNotEnabled {
+		t.Errorf("Expected ErrFeeTierNotEnabled for second disable, got %v", err)
+	}
+	if !pm.getPool(stateDB, newTestPoolKey().ID()).IsInitialized() {
+		t.Error("Expected existing pool to survive disabling its tier")
+	}
+
+	// Governance changes live in state, not in the registry
+	if spacing, ok := NewFeeTierRegistry().TickSpacing(stateDB, 200); !ok || spacing != 4 {
+		t.Errorf("Expected enabled tier to be read from state, got %d %v", spacing, ok)
+	}
+	if _, ok := NewFeeTierRegistry().TickSpacing(stateDB, Fee030); ok {
+		t.Error("Expected disabled tier to be read from state")
+	}
+
+	tiers := pm.FeeTiers().Tiers(stateDB)
+	want := []FeeTier{{Fee001, TickSpacing001}, {200, 4}, {Fee005, TickSpacing005}, {Fee100, TickSpacing100}}
+	if len(tiers) != len(want) {
+		t.Fatalf("Expected %d tiers, got %d", len(want), len(tiers))
+	}
+	for i := range want {
+		if tiers[i] != want[i] {
+			t.Errorf("Tier %d: expected %+v, got %+v", i, want[i], tiers[i])
+		}
+	}
+}
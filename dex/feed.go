@@ -0,0 +1,434 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// =========================================================================
+// Computed mark and index prices (LXFeed)
+// =========================================================================
+//
+// LXFeed derives the prices the perp engine marks positions at. The index
+// price is the LXOracle aggregate of the market's base in its quote. The
+// book price is the LXBook mid of the impact bid and impact ask: the average
+// prices of selling and buying ImpactNotional of quote against the book, or
+// the top of book when ImpactNotional is zero. Without a two-sided book the
+// index stands in for the book price.
+//
+// The book price is smoothed by a time-weighted EMA: an update dt seconds
+// of block time after the last moves the average min(dt, EMAWindow)/
+// EMAWindow of the way to the new book price. Updates within one block
+// share its timestamp, so a burst of trades in a block cannot move it.
+// The mark price is the smoothed price clamped to within MaxDivergenceBps of
+// the index. The clamp is the circuit breaker: a thin or manipulated book
+// cannot push the mark, and so PnL, liquidations and funding, further than
+// that band from the oracle.
+//
+// Prices are scaled by OraclePriceScale like their sources. PerpPrices
+// converts them to the Q96 prices of the perp engine.
+
+// FeedMarketConfig holds the derivation parameters of a feed market
+type FeedMarketConfig struct {
+	ImpactNotional   *big.Int // Quote notional of impact prices; zero uses the top of book
+	EMAWindow        uint64   // Smoothing window in seconds; zero disables smoothing
+	MaxDivergenceBps uint32   // Largest distance of mark from index; zero disables the clamp
+}
+
+// FeedMarket is the derived price state of one market
+type FeedMarket struct {
+	Base   Currency
+	Quote  Currency
+	Config FeedMarketConfig
+
+	IndexPrice    *big.Int // Oracle price at the last update
+	BookPrice     *big.Int // Raw impact mid at the last update; nil without a two-sided book
+	SmoothedPrice *big.Int // EMA of the book price
+	MarkPrice     *big.Int // Smoothed price clamped around the index
+	Clamped       bool     // Whether the clamp bound the last mark
+	LastUpdate    uint64
+}
+
+// PerpPriceSource supplies the mark and index prices (Q96) of a perp market
+type PerpPriceSource interface {
+	PerpPrices(stateDB StateDB, now uint64, base, quote Currency) (mark, index *big.Int, err error)
+}
+
+var _ PerpPriceSource = (*PriceFeed)(nil)
+
+// Storage key prefix for LXFeed markets, stored at the LXFeed address. A
+// market is keyed by MarketID(base, quote):
+//
+//	feed/mkt || id || "cfg"   -> configured flag (byte 0) | EMA window (bytes 16..24) |
+//	                             max divergence bps (bytes 28..32)
+//	feed/mkt || id || "imp"   -> impact notional
+//	feed/mkt || id || "stat"  -> clamped (byte 0) | last update (bytes 24..32)
+//	feed/mkt || id || "base", "quote", "idx", "book", "ema", "mark"
+//
+// A zero book or smoothed price reads back as nil.
+var feedMarketPrefix = []byte("feed/mkt")
+
+// PriceFeed derives mark and index prices from the order book and oracle.
+// Markets and their derived prices live at the LXFeed address.
+type PriceFeed struct {
+	book   *OrderBook
+	oracle PriceSource
+}
+
+// NewPriceFeed creates a feed over book and oracle
+func NewPriceFeed(book *OrderBook, oracle PriceSource) *PriceFeed {
+	return &PriceFeed{
+		book:   book,
+		oracle: oracle,
+	}
+}
+
+// feedMarketKey returns the storage key of a market field
+func feedMarketKey(id [32]byte, field string) common.Hash {
+	return makeStorageKey(feedMarketPrefix, append(id[:], field...))
+}
+
+// loadMarket loads a market, or returns ErrFeedMarketNotFound
+func (f *PriceFeed) loadMarket(stateDB StateDB, base, quote Currency) (*FeedMarket, error) {
+	id := MarketID(base, quote)
+	get := func(field string) common.Hash {
+		return stateDB.GetState(lxFeedAddr, feedMarketKey(id, field))
+	}
+	optional := func(field string) *big.Int {
+		if v := get(field).Big(); v.Sign() != 0 {
+			return v
+		}
+		return nil
+	}
+
+	cfg := get("cfg")
+	if cfg[0] == 0 {
+		return nil, ErrFeedMarketNotFound
+	}
+	stat := get("stat")
+	return &FeedMarket{
+		Base:  base,
+		Quote: quote,
+		Config: FeedMarketConfig{
+			ImpactNotional:   optional("imp"),
+			EMAWindow:        binary.BigEndian.Uint64(cfg[16:24]),
+			MaxDivergenceBps: binary.BigEndian.Uint32(cfg[28:32]),
+		},
+		IndexPrice:    optional("idx"),
+		BookPrice:     optional("book"),
+		SmoothedPrice: optional("ema"),
+		MarkPrice:     optional("mark"),
+		Clamped:       stat[0] != 0,
+		LastUpdate:    binary.BigEndian.Uint64(stat[24:32]),
+	}, nil
+}
+
+// saveMarket stores a market's configuration and derived prices
+func (f *PriceFeed) saveMarket(stateDB StateDB, market *FeedMarket) {
+	id := MarketID(market.Base, market.Quote)
+	set := func(field string, value common.Hash) {
+		stateDB.SetState(lxFeedAddr, feedMarketKey(id, field), value)
+	}
+	setBig := func(field string, v *big.Int) {
+		if v == nil {
+			v = new(big.Int)
+		}
+		set(field, common.BigToHash(v))
+	}
+
+	var cfg common.Hash
+	cfg[0] = 1
+	binary.BigEndian.PutUint64(cfg[16:24], market.Config.EMAWindow)
+	binary.BigEndian.PutUint32(cfg[28:32], market.Config.MaxDivergenceBps)
+	set("cfg", cfg)
+	set("base", common.BytesToHash(market.Base.Address.Bytes()))
+	set("quote", common.BytesToHash(market.Quote.Address.Bytes()))
+	setBig("imp", market.Config.ImpactNotional)
+	setBig("idx", market.IndexPrice)
+	setBig("book", market.BookPrice)
+	setBig("ema", market.SmoothedPrice)
+	setBig("mark", market.MarkPrice)
+
+	var stat common.Hash
+	if market.Clamped {
+		stat[0] = 1
+	}
+	binary.BigEndian.PutUint64(stat[24:32], market.LastUpdate)
+	set("stat", stat)
+}
+
+// ConfigureMarket adds a market or replaces its parameters. Derived prices
+// are kept and follow the new parameters from the next update.
+func (f *PriceFeed) ConfigureMarket(stateDB StateDB, base, quote Currency, config FeedMarketConfig) error {
+	if base == quote || config.MaxDivergenceBps > 10000 {
+		return ErrInvalidParameter
+	}
+	if config.ImpactNotional != nil && config.ImpactNotional.Sign() < 0 {
+		return ErrInvalidParameter
+	}
+
+	market, err := f.loadMarket(stateDB, base, quote)
+	if err != nil {
+		market = &FeedMarket{Base: base, Quote: quote}
+	}
+	market.Config = config
+	f.saveMarket(stateDB, market)
+	return nil
+}
+
+// Update reads the oracle and book and derives new prices for a market at
+// block time now. It fails without an oracle price, leaving the last prices
+// in place.
+func (f *PriceFeed) Update(stateDB StateDB, now uint64, base, quote Currency) (*FeedMarket, error) {
+	market, err := f.loadMarket(stateDB, base, quote)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.update(stateDB, now, market); err != nil {
+		return nil, err
+	}
+	f.saveMarket(stateDB, market)
+	return market, nil
+}
+
+// Market returns a market's last derived prices
+func (f *PriceFeed) Market(stateDB StateDB, base, quote Currency) (*FeedMarket, error) {
+	return f.loadMarket(stateDB, base, quote)
+}
+
+// PerpPrices updates a market at block time now and returns its mark and
+// index prices in Q96
+func (f *PriceFeed) PerpPrices(stateDB StateDB, now uint64, base, quote Currency) (*big.Int, *big.Int, error) {
+	market, err := f.Update(stateDB, now, base, quote)
+	if err != nil {
+		return nil, nil, err
+	}
+	return feedPriceToQ96(market.MarkPrice), feedPriceToQ96(market.IndexPrice), nil
+}
+
+// update derives a market's prices at block time now
+func (f *PriceFeed) update(stateDB StateDB, now uint64, market *FeedMarket) error {
+	index, err := f.oracle.GetPrice(stateDB, now, market.Base.Address, market.Quote.Address)
+	if err != nil {
+		return err
+	}
+
+	market.BookPrice = f.bookPrice(stateDB, market)
+	target := index
+	if market.BookPrice != nil {
+		target = market.BookPrice
+	}
+
+	// Time-weighted EMA; the first update seeds it
+	window := market.Config.EMAWindow
+	if market.SmoothedPrice == nil || window == 0 {
+		market.SmoothedPrice = new(big.Int).Set(target)
+	} else if now > market.LastUpdate {
+		weight := min(now-market.LastUpdate, window)
+		step := new(big.Int).Sub(target, market.SmoothedPrice)
+		step.Mul(step, new(big.Int).SetUint64(weight))
+		step.Quo(step, new(big.Int).SetUint64(window))
+		market.SmoothedPrice.Add(market.SmoothedPrice, step)
+	}
+
+	// Circuit breaker: clamp the mark to the band around the index
+	mark := new(big.Int).Set(market.SmoothedPrice)
+	market.Clamped = false
+	if bps := market.Config.MaxDivergenceBps; bps > 0 {
+		band := new(big.Int).Mul(index, big.NewInt(int64(bps)))
+		band.Div(band, big.NewInt(10000))
+		lower := new(big.Int).Sub(index, band)
+		upper := new(big.Int).Add(index, band)
+		if mark.Cmp(lower) < 0 {
+			mark.Set(lower)
+			market.Clamped = true
+		} else if mark.Cmp(upper) > 0 {
+			mark.Set(upper)
+			market.Clamped = true
+		}
+	}
+
+	market.IndexPrice = new(big.Int).Set(index)
+	market.MarkPrice = mark
+	market.LastUpdate = now
+	return nil
+}
+
+// bookPrice returns the mid of a market's impact bid and ask, or nil
+// without a two-sided book deep enough for the impact notional
+func (f *PriceFeed) bookPrice(stateDB StateDB, market *FeedMarket) *big.Int {
+	var bid, ask *big.Int
+	var err error
+	if notional := market.Config.ImpactNotional; notional != nil && notional.Sign() > 0 {
+		if bid, err = f.book.ImpactPrice(stateDB, market.Base, market.Quote, true, notional); err != nil {
+			return nil
+		}
+		if ask, err = f.book.ImpactPrice(stateDB, market.Base, market.Quote, false, notional); err != nil {
+			return nil
+		}
+	} else {
+		if bid, _, err = f.book.BestBid(stateDB, market.Base, market.Quote); err != nil {
+			return nil
+		}
+		if ask, _, err = f.book.BestAsk(stateDB, market.Base, market.Quote); err != nil {
+			return nil
+		}
+	}
+
+	mid := new(big.Int).Add(bid, ask)
+	return mid.Rsh(mid, 1)
+}
+
+// feedPriceToQ96 converts a price scaled by OraclePriceScale to Q96
+func feedPriceToQ96(price *big.Int) *big.Int {
+	q96 := new(big.Int).Mul(price, Q96)
+	return q96.Div(q96, OraclePriceScale)
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// Feed returns the mark and index price feed behind LXFeed
+func (pm *PoolManager) Feed() *PriceFeed {
+	return pm.feed
+}
+
+// ConfigureFeed adds or reconfigures a feed market (protocol fee controller
+// only)
+func (pm *PoolManager) ConfigureFeed(stateDB StateDB, caller common.Address, base, quote Currency, config FeedMarketConfig) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	return pm.feed.ConfigureMarket(stateDB, base, quote, config)
+}
+
+// =========================================================================
+// LXFeed precompile
+// =========================================================================
+
+// Method selectors for LXFeed
+const (
+	SelectorConfigureFeed uint32 = 0x01000000 // configureFeed(Currency,Currency,uint256,uint64,uint32)
+	SelectorUpdateFeed    uint32 = 0x02000000 // updateFeed(Currency,Currency)
+	SelectorGetFeed       uint32 = 0x03000000 // getFeed(Currency,Currency)
+)
+
+// FeedContract implements the LXFeed precompile over the feed shared with
+// LXPool
+type FeedContract struct {
+	poolManager *PoolManager
+}
+
+// Run executes the precompile
+func (c *FeedContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+
+	selector := binary.BigEndian.Uint32(input[:4])
+	data := input[4:]
+
+	gas := c.RequiredGas(input)
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+	remainingGas = suppliedGas - gas
+
+	// Every method leads with the market: base (32) + quote (32)
+	if len(data) < 64 {
+		return nil, remainingGas, fmt.Errorf("input too short")
+	}
+	base := Currency{Address: common.BytesToAddress(data[12:32])}
+	quote := Currency{Address: common.BytesToAddress(data[44:64])}
+	pm := c.poolManager
+	stateAdapter := newPoolStateAdapter(accessibleState)
+
+	switch selector {
+	case SelectorGetFeed:
+		market, err := pm.feed.Market(stateAdapter, base, quote)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return EncodeFeedMarket(market), remainingGas, nil
+
+	case SelectorConfigureFeed, SelectorUpdateFeed:
+	default:
+		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	}
+
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	switch selector {
+	case SelectorConfigureFeed:
+		// impactNotional (32) + emaWindow (32) + maxDivergenceBps (32)
+		if len(data) < 160 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		config := FeedMarketConfig{
+			ImpactNotional:   new(big.Int).SetBytes(data[64:96]),
+			EMAWindow:        decodeUint64Word(data[96:128]),
+			MaxDivergenceBps: uint32(decodeUint64Word(data[128:160])),
+		}
+		if err := pm.ConfigureFeed(stateAdapter, caller, base, quote, config); err != nil {
+			return nil, remainingGas, err
+		}
+		return nil, remainingGas, nil
+
+	default: // SelectorUpdateFeed
+		market, err := pm.feed.Update(stateAdapter, accessibleState.GetBlockContext().Timestamp(), base, quote)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return EncodeFeedMarket(market), remainingGas, nil
+	}
+}
+
+// RequiredGas returns the gas required for the precompile input
+func (c *FeedContract) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return GasPoolLookup
+	}
+
+	switch binary.BigEndian.Uint32(input[:4]) {
+	case SelectorConfigureFeed:
+		return GasFeedConfigure
+	case SelectorUpdateFeed:
+		return GasFeedUpdate
+	default:
+		return GasPoolLookup
+	}
+}
+
+// EncodeFeedMarket encodes a market's derived prices: mark (32) +
+// index (32) + book (32) + smoothed (32) + clamped (32) + lastUpdate (32).
+// A missing book price encodes as zero.
+func EncodeFeedMarket(market *FeedMarket) []byte {
+	result := make([]byte, 192)
+	for i, price := range []*big.Int{market.MarkPrice, market.IndexPrice, market.BookPrice, market.SmoothedPrice} {
+		if price != nil {
+			price.FillBytes(result[i*32 : (i+1)*32])
+		}
+	}
+	if market.Clamped {
+		result[159] = 1
+	}
+	binary.BigEndian.PutUint64(result[184:192], market.LastUpdate)
+	return result
+}
@@ -0,0 +1,165 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// stubPriceSource returns fixed prices by OraclePairID
+type stubPriceSource map[[32]byte]*big.Int
+
+func (s stubPriceSource) GetPrice(stateDB StateDB, now uint64, base, quote common.Address) (*big.Int, error) {
+	price, ok := s[OraclePairID(base, quote)]
+	if !ok {
+		return nil, ErrOracleFeedNotFound
+	}
+	return new(big.Int).Set(price), nil
+}
+
+// TestPriceFeedBookMark tests mark prices from the top of book and from
+// impact prices, and syncing them into a perp market
+func TestPriceFeedBookMark(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	base := Currency{Address: common.HexToAddress("0x000000000000000000000000000000000000BA5E")}
+	quote := Currency{Address: common.HexToAddress("0x0000000000000000000000000000000000000C0C")}
+	price := func(p int64) *big.Int { return new(big.Int).Mul(big.NewInt(p), OraclePriceScale) }
+
+	for _, order := range []struct {
+		owner common.Address
+		side  OrderSide
+		p     int64
+	}{{testTraderA, OrderBuy, 99}, {testTraderA, OrderBuy, 98}, {testTraderB, OrderSell, 101}, {testTraderB, OrderSell, 103}} {
+		openLock(pm, order.owner)
+		if _, err := pm.PlaceOrder(stateDB, base, quote, order.side, price(order.p), big.NewInt(1000), TimeInForceGTC, 16); err != nil {
+			t.Fatalf("PlaceOrder failed: %v", err)
+		}
+	}
+
+	oracle := stubPriceSource{OraclePairID(base.Address, quote.Address): price(100)}
+	feed := NewPriceFeed(pm.book, oracle)
+
+	if _, err := feed.Update(stateDB, perpTestTime, base, quote); !errors.Is(err, ErrFeedMarketNotFound) {
+		t.Errorf("Expected ErrFeedMarketNotFound, got: %v", err)
+	}
+	if err := feed.ConfigureMarket(stateDB, base, quote, FeedMarketConfig{}); err != nil {
+		t.Fatalf("ConfigureMarket failed: %v", err)
+	}
+	market, err := feed.Update(stateDB, perpTestTime, base, quote)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if market.MarkPrice.Cmp(price(100)) != 0 || market.BookPrice.Cmp(price(100)) != 0 {
+		t.Errorf("Expected top of book mark 100, got: %s", market.MarkPrice)
+	}
+
+	// Impact prices walk past the top level: 150000 of quote sells 1000 at
+	// 99 and 520 at 98, and buys 1000 at 101 and 475 at 103
+	notional := big.NewInt(150_000)
+	bid, err := pm.book.ImpactPrice(stateDB, base, quote, true, notional)
+	if err != nil {
+		t.Fatalf("ImpactPrice failed: %v", err)
+	}
+	ask, _ := pm.book.ImpactPrice(stateDB, base, quote, false, notional)
+	expectedBid := new(big.Int).Div(new(big.Int).Mul(notional, OraclePriceScale), big.NewInt(1520))
+	expectedAsk := new(big.Int).Div(new(big.Int).Mul(notional, OraclePriceScale), big.NewInt(1475))
+	if bid.Cmp(expectedBid) != 0 || ask.Cmp(expectedAsk) != 0 {
+		t.Errorf("Expected impact bid %s and ask %s, got: %s and %s", expectedBid, expectedAsk, bid, ask)
+	}
+	if _, err := pm.book.ImpactPrice(stateDB, base, quote, true, big.NewInt(1_000_000)); !errors.Is(err, ErrInsufficientLiquidity) {
+		t.Errorf("Expected ErrInsufficientLiquidity, got: %v", err)
+	}
+
+	if err := feed.ConfigureMarket(stateDB, base, quote, FeedMarketConfig{ImpactNotional: notional}); err != nil {
+		t.Fatalf("ConfigureMarket failed: %v", err)
+	}
+	market, _ = feed.Update(stateDB, perpTestTime, base, quote)
+	expectedMark := new(big.Int).Add(expectedBid, expectedAsk)
+	expectedMark.Rsh(expectedMark, 1)
+	if market.MarkPrice.Cmp(expectedMark) != 0 {
+		t.Errorf("Expected impact mid %s, got: %s", expectedMark, market.MarkPrice)
+	}
+
+	// The perp engine marks at the feed's prices in Q96
+	pe := NewPerpetualEngine()
+	pe.Funding.now = func() int64 { return perpTestTime }
+	marketID, err := pe.CreateMarket(base, quote, new(big.Int).Mul(big.NewInt(90), Q96), 100, big.NewInt(5e16))
+	if err != nil {
+		t.Fatalf("CreateMarket failed: %v", err)
+	}
+	if err := pe.SyncPrices(stateDB, perpTestTime, marketID); !errors.Is(err, ErrNoPriceFeed) {
+		t.Errorf("Expected ErrNoPriceFeed, got: %v", err)
+	}
+	pe.PriceFeed = feed
+	if err := pe.SyncPrices(stateDB, perpTestTime, marketID); err != nil {
+		t.Fatalf("SyncPrices failed: %v", err)
+	}
+	perp := pe.Markets[marketID]
+	if perp.IndexPrice.Cmp(new(big.Int).Mul(big.NewInt(100), Q96)) != 0 {
+		t.Errorf("Expected index 100 in Q96, got: %s", perp.IndexPrice)
+	}
+	if perp.MarkPrice.Cmp(feedPriceToQ96(expectedMark)) != 0 {
+		t.Errorf("Expected mark %s in Q96, got: %s", feedPriceToQ96(expectedMark), perp.MarkPrice)
+	}
+}
+
+// TestPriceFeedSmoothingAndClamp tests the EMA of the mark and the clamp
+// around the index
+func TestPriceFeedSmoothingAndClamp(t *testing.T) {
+	base := Currency{Address: common.HexToAddress("0x000000000000000000000000000000000000BA5E")}
+	quote := Currency{Address: common.HexToAddress("0x0000000000000000000000000000000000000C0C")}
+	price := func(p int64) *big.Int { return new(big.Int).Mul(big.NewInt(p), OraclePriceScale) }
+
+	// Without a book the mark tracks the index through the EMA
+	stateDB := NewMockStateDB()
+	now := uint64(perpTestTime)
+	oracle := stubPriceSource{OraclePairID(base.Address, quote.Address): price(100)}
+	feed := NewPriceFeed(NewOrderBook(NewBookControls()), oracle)
+	config := FeedMarketConfig{EMAWindow: 100, MaxDivergenceBps: 1000}
+	if err := feed.ConfigureMarket(stateDB, base, quote, config); err != nil {
+		t.Fatalf("ConfigureMarket failed: %v", err)
+	}
+	if market, err := feed.Update(stateDB, now, base, quote); err != nil || market.MarkPrice.Cmp(price(100)) != 0 {
+		t.Fatalf("Expected seeded mark 100, got: %v (%v)", market, err)
+	}
+
+	// A quarter window moves the average a quarter of the way, and the
+	// clamp holds the mark within 10% of the new index
+	now += 25
+	oracle[OraclePairID(base.Address, quote.Address)] = price(200)
+	market, _ := feed.Update(stateDB, now, base, quote)
+	if market.SmoothedPrice.Cmp(price(125)) != 0 {
+		t.Errorf("Expected smoothed price 125, got: %s", market.SmoothedPrice)
+	}
+	if market.MarkPrice.Cmp(price(180)) != 0 || !market.Clamped {
+		t.Errorf("Expected mark clamped to 180, got: %s (clamped %v)", market.MarkPrice, market.Clamped)
+	}
+
+	// A full window catches up
+	now += 100
+	market, _ = feed.Update(stateDB, now, base, quote)
+	if market.MarkPrice.Cmp(price(200)) != 0 || market.Clamped {
+		t.Errorf("Expected unclamped mark 200, got: %s (clamped %v)", market.MarkPrice, market.Clamped)
+	}
+
+	// Without an oracle price the last prices stand
+	delete(oracle, OraclePairID(base.Address, quote.Address))
+	if _, err := feed.Update(stateDB, now, base, quote); !errors.Is(err, ErrOracleFeedNotFound) {
+		t.Errorf("Expected ErrOracleFeedNotFound, got: %v", err)
+	}
+	if market, _ := feed.Market(stateDB, base, quote); market.MarkPrice.Cmp(price(200)) != 0 {
+		t.Errorf("Expected last mark 200 to stand, got: %s", market.MarkPrice)
+	}
+
+	// Derived prices live in LXFeed storage, not in the feed
+	market, err := NewPriceFeed(nil, oracle).Market(stateDB, base, quote)
+	if err != nil || market.SmoothedPrice.Cmp(price(200)) != 0 || market.Config.EMAWindow != 100 || market.LastUpdate != now {
+		t.Errorf("Expected stored market, got: %+v (%v)", market, err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/luxfi/geth/common"
+)
+
+// =========================================================================
+// Perpetual Funding
+// =========================================================================
+//
+// Funding accrues continuously rather than in discrete 8h steps. Each market
+// keeps a time-weighted EMA of its premium, (mark - index) / index, whose
+// smoothing window is the market's TWAPWindow: a sample held for dt seconds
+// moves the EMA by min(dt, window) / window of the way towards it. The
+// funding rate is the premium EMA plus a fixed interest component, clamped,
+// and is quoted per window. Between two updates the rate in force accrues
+// into the cumulative funding index at rate * markPrice * dt / window.
+//
+// Nothing is settled on a timer. A market is brought up to date whenever it
+// is touched (a trade, a margin change, a liquidation or a price update),
+// and a position settles what it owes against the index the next time it
+// is touched itself.
+
+// Funding parameters
+const (
+	// FundingPrecision is the precision of funding rates (1e6 = 100%)
+	FundingPrecision = 1_000_000
+
+	// DefaultFundingWindow is the default TWAP window and funding interval
+	DefaultFundingWindow = 8 * 3600 // 8 hours
+
+	// DefaultFundingInterest is the interest component per funding interval
+	DefaultFundingInterest = 100 // 0.01%
+
+	// MaxFundingRate caps the funding rate per interval in either direction
+	MaxFundingRate = 7500 // 0.75%
+)
+
+// premiumPrecision is the precision of premium samples and the premium EMA
+var premiumPrecision = big.NewInt(1e18)
+
+// FundingEngine computes funding rates and the cumulative funding index of
+// perpetual markets
+type FundingEngine struct {
+	InterestRate *big.Int // Interest component per interval (1e6 precision)
+	MaxRate      *big.Int // Absolute rate cap per interval (1e6 precision)
+
+	// now returns the current timestamp in seconds
+	now func() int64
+}
+
+// NewFundingEngine creates a funding engine with the default parameters
+func NewFundingEngine() *FundingEngine {
+	return &FundingEngine{
+		InterestRate: big.NewInt(DefaultFundingInterest),
+		MaxRate:      big.NewInt(MaxFundingRate),
+		now:          func() int64 { return time.Now().Unix() },
+	}
+}
+
+// fundingUpdate is the state of a market's funding as of a timestamp
+type fundingUpdate struct {
+	cumulative *big.Int
+	premiumEMA *big.Int
+	rate       *big.Int
+	at         int64
+}
+
+// Premium returns the current premium of mark over index (18 decimals)
+func (fe *FundingEngine) Premium(market *PerpMarket) *big.Int {
+	if market.IndexPrice == nil || market.IndexPrice.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	premium := new(big.Int).Sub(market.MarkPrice, market.IndexPrice)
+	premium.Mul(premium, premiumPrecision)
+	return premium.Quo(premium, market.IndexPrice)
+}
+
+// advance computes a market's funding state as of now without applying it
+func (fe *FundingEngine) advance(market *PerpMarket, state *FundingState) *fundingUpdate {
+	now := fe.now()
+	update := &fundingUpdate{
+		cumulative: new(big.Int).Set(state.CumulativeFunding),
+		premiumEMA: new(big.Int).Set(state.PremiumEMA),
+		rate:       new(big.Int).Set(market.FundingRate),
+		at:         now,
+	}
+	elapsed := now - state.LastUpdateTime
+	if elapsed <= 0 {
+		update.at = state.LastUpdateTime
+		return update
+	}
+
+	window := int64(state.TWAPWindow)
+	if window <= 0 {
+		window = DefaultFundingWindow
+	}
+
+	// The rate in force since the last update accrues into the index
+	accrued := new(big.Int).Mul(market.FundingRate, market.MarkPrice)
+	accrued.Mul(accrued, big.NewInt(elapsed))
+	accrued.Quo(accrued, big.NewInt(FundingPrecision*window))
+	update.cumulative.Add(update.cumulative, accrued)
+
+	// The premium held since the last update moves the EMA towards it
+	weight := elapsed
+	if weight > window {
+		weight = window
+	}
+	step := new(big.Int).Sub(fe.Premium(market), update.premiumEMA)
+	step.Mul(step, big.NewInt(weight))
+	step.Quo(step, big.NewInt(window))
+	update.premiumEMA.Add(update.premiumEMA, step)
+
+	// Rate = premium EMA + interest, clamped to the cap
+	rate := new(big.Int).Quo(update.premiumEMA, big.NewInt(1e18/FundingPrecision))
+	rate.Add(rate, fe.InterestRate)
+	if rate.Cmp(fe.MaxRate) > 0 {
+		rate.Set(fe.MaxRate)
+	} else if rate.CmpAbs(fe.MaxRate) > 0 {
+		rate.Neg(fe.MaxRate)
+	}
+	update.rate = rate
+
+	return update
+}
+
+// accrue brings a market's funding state up to now
+func (fe *FundingEngine) accrue(market *PerpMarket, state *FundingState) {
+	update := fe.advance(market, state)
+	state.CumulativeFunding = update.cumulative
+	state.PremiumEMA = update.premiumEMA
+	state.LastUpdateTime = update.at
+	market.FundingRate = update.rate
+	market.LastFundingTime = update.at
+}
+
+// accrueFunding brings a market's funding up to date and returns its state.
+// Caller must hold pe.mu.
+func (pe *PerpetualEngine) accrueFunding(marketID [32]byte, market *PerpMarket) *FundingState {
+	state := pe.FundingStates[marketID]
+	pe.Funding.accrue(market, state)
+	return state
+}
+
+// UpdateFunding brings a market's funding rate and cumulative index up to
+// date. Markets also update whenever they are touched, so calling this is
+// only needed to refresh the published rate of an idle market.
+func (pe *PerpetualEngine) UpdateFunding(marketID [32]byte) error {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	market, exists := pe.Markets[marketID]
+	if !exists {
+		return ErrPoolNotFound
+	}
+
+	pe.accrueFunding(marketID, market)
+	return nil
+}
+
+// GetFundingRate returns the funding rate a market would publish now
+// (signed, per funding interval, 1e6 precision)
+func (pe *PerpetualEngine) GetFundingRate(marketID [32]byte) (*big.Int, error) {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	market, exists := pe.Markets[marketID]
+	if !exists {
+		return nil, ErrPoolNotFound
+	}
+
+	return pe.Funding.advance(market, pe.FundingStates[marketID]).rate, nil
+}
+
+// NextFundingPayment returns the funding a position would settle if it were
+// touched now: what it has accrued since it last settled, including time
+// the market has not yet been updated for. Positive means it receives.
+func (pe *PerpetualEngine) NextFundingPayment(owner common.Address, marketID [32]byte) (*big.Int, error) {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	market, exists := pe.Markets[marketID]
+	if !exists {
+		return nil, ErrPoolNotFound
+	}
+
+	position := pe.Positions[owner][marketID]
+	if position == nil {
+		return nil, ErrPositionNotFound
+	}
+
+	update := pe.Funding.advance(market, pe.FundingStates[marketID])
+	return fundingPayment(position, &FundingState{CumulativeFunding: update.cumulative}), nil
+}
+
+// settleFundingForPosition settles a position against its market's funding
+// index and returns the payment. Caller must have accrued the market.
+func (pe *PerpetualEngine) settleFundingForPosition(position *PerpPosition, state *FundingState) *big.Int {
+	payment := fundingPayment(position, state)
+	position.LastFundingIndex = new(big.Int).Set(state.CumulativeFunding)
+	return payment
+}
+
+// fundingPayment returns the funding a position has accrued since it was
+// last settled, without settling it
+func fundingPayment(position *PerpPosition, state *FundingState) *big.Int {
+	// Funding payment = -size * (currentFundingIndex - lastFundingIndex) / Q96
+	// Longs pay shorts when funding > 0, so longs get negative funding and
+	// shorts positive. Rounding down means payers never pay less than
+	// receivers get.
+	fundingDiff := new(big.Int).Sub(state.CumulativeFunding, position.LastFundingIndex)
+	payment := new(big.Int).Mul(position.Size, fundingDiff)
+	payment.Neg(payment)
+	return payment.Div(payment, Q96)
+}
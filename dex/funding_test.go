@@ -0,0 +1,56 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+)
+
+// TestFundingAccrual tests the premium EMA, the rate cap and lazy
+// settlement of cumulative funding
+func TestFundingAccrual(t *testing.T) {
+	pe, marketID := setupPerpMarket(t)
+	now := int64(perpTestTime)
+	pe.Funding.now = func() int64 { return now }
+
+	// A 1% premium held for a full window moves the EMA all the way to it
+	setPerpPrice(t, pe, marketID, 101)
+	now += DefaultFundingWindow
+	if err := pe.UpdateFunding(marketID); err != nil {
+		t.Fatalf("UpdateFunding failed: %v", err)
+	}
+	if ema := pe.FundingStates[marketID].PremiumEMA; ema.Cmp(big.NewInt(1e16)) != 0 {
+		t.Errorf("Expected premium EMA 1e16, got %s", ema)
+	}
+
+	// 1% + 0.01% interest is clamped to 0.75%
+	if rate, _ := pe.GetFundingRate(marketID); rate.Int64() != MaxFundingRate {
+		t.Errorf("Expected rate %d, got %s", MaxFundingRate, rate)
+	}
+
+	// A window later each unit owes 0.75% of 101 without the market being
+	// touched; payers round up and receivers down
+	now += DefaultFundingWindow
+	if payment, err := pe.NextFundingPayment(perpLong, marketID); err != nil || payment.Int64() != -8 {
+		t.Errorf("Expected long payment -8, got %v: %v", payment, err)
+	}
+	if payment, _ := pe.NextFundingPayment(perpShortB, marketID); payment.Int64() != 4 {
+		t.Errorf("Expected short payment 4, got %s", payment)
+	}
+	if cumulative := pe.FundingStates[marketID].CumulativeFunding; cumulative.Sign() != 0 {
+		t.Errorf("Expected funding to accrue lazily, got index %s", cumulative)
+	}
+
+	// Touching the position settles it into the margin
+	if payment, err := pe.SettleFunding(perpLong, marketID); err != nil || payment.Int64() != -8 {
+		t.Errorf("Expected settled payment -8, got %v: %v", payment, err)
+	}
+	if position, _ := pe.GetPosition(perpLong, marketID); position.Margin.Int64() != 92 {
+		t.Errorf("Expected margin 92, got %s", position.Margin)
+	}
+	if payment, _ := pe.NextFundingPayment(perpLong, marketID); payment.Sign() != 0 {
+		t.Errorf("Expected nothing left to settle, got %s", payment)
+	}
+}
@@ -0,0 +1,580 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+	"github.com/zeebo/blake3"
+)
+
+// Liquidity mining gauges
+//
+// Gauge state lives in the pool manager's storage:
+//
+//	gaugePrefix:         gaugeKey || field                 -> gauge
+//	gaugePositionPrefix: gaugePositionKey || field         -> gauge position
+//	gaugePoolPrefix:     poolId || "count"                 -> number of gauges
+//	                     poolId || i || "gauge"            -> i-th gauge key
+//	                     poolId || "total"                 -> tracked liquidity
+//	                     poolId || positionKey || field    -> tracked position
+//
+// Every position of a pool is tracked, so a gauge created later can pay
+// the LPs already in the pool. Such a position has no gauge entry until it
+// is next modified or claimed, and is read from its tracked liquidity as
+// if it had joined the gauge when the gauge started.
+
+// Gauge streams a reward token to the liquidity providers of a single pool.
+// Rewards are emitted at a constant rate while the gauge is funded and are
+// split pro-rata by liquidity-seconds: a position earns
+// liquidity * elapsed / totalLiquidity of every second's emission.
+type Gauge struct {
+	PoolID         [32]byte
+	RewardToken    Currency
+	Funder         common.Address
+	EmissionRate   *big.Int // Reward tokens emitted per second
+	StartTime      uint64   // Timestamp the gauge was created
+	PeriodFinish   uint64   // Timestamp when funded emissions run out
+	LastUpdate     uint64   // Timestamp of last accumulator update
+	TotalLiquidity *big.Int // Sum of liquidity of all tracked positions
+	// RewardPerLiquidityX128 accumulates reward per unit of liquidity (Q128)
+	RewardPerLiquidityX128 *big.Int
+	TotalFunded            *big.Int // Total rewards deposited
+	TotalClaimed           *big.Int // Total rewards paid out
+}
+
+// GaugePosition tracks reward accrual for a single liquidity position
+type GaugePosition struct {
+	Owner     common.Address
+	Liquidity *big.Int
+	// RewardPerLiquidityLastX128 is the gauge accumulator at last checkpoint
+	RewardPerLiquidityLastX128 *big.Int
+	// LiquiditySeconds is the cumulative liquidity * seconds provided
+	LiquiditySeconds *big.Int
+	RewardsOwed      *big.Int // Accrued but unclaimed rewards
+	LastCheckpoint   uint64   // Timestamp of last accrual
+}
+
+// GaugeController manages incentive gauges across pools.
+// It is notified by the PoolManager whenever a position's liquidity changes
+// so that rewards are always checkpointed at the old liquidity. Gauges and
+// positions live in state and accrue by the block timestamp.
+type GaugeController struct{}
+
+// NewGaugeController creates a new gauge controller
+func NewGaugeController() *GaugeController {
+	return &GaugeController{}
+}
+
+// gaugeKey computes the identifier of a pool's gauge for a reward token
+func gaugeKey(poolId [32]byte, rewardToken Currency) [32]byte {
+	h := blake3.New()
+	h.Write(poolId[:])
+	h.Write(rewardToken.ToBytes())
+	var key [32]byte
+	h.Digest().Read(key[:])
+	return key
+}
+
+// gaugePositionKey computes the identifier of a position within a gauge
+func gaugePositionKey(gKey [32]byte, positionKey [32]byte) [32]byte {
+	h := blake3.New()
+	h.Write(gKey[:])
+	h.Write(positionKey[:])
+	var key [32]byte
+	h.Digest().Read(key[:])
+	return key
+}
+
+// =========================================================================
+// Funding
+// =========================================================================
+
+// Fund deposits rewards into the gauge for (poolId, rewardToken), creating it
+// if needed. The emission rate is updated and the funded period is extended
+// so that all undistributed rewards stream out at the new rate.
+func (gc *GaugeController) Fund(
+	stateDB StateDB,
+	funder common.Address,
+	poolId [32]byte,
+	rewardToken Currency,
+	amount *big.Int,
+	emissionRate *big.Int,
+) (*Gauge, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if emissionRate == nil || emissionRate.Sign() <= 0 {
+		return nil, ErrInvalidEmissionRate
+	}
+
+	now := stateDB.GetBlockTimestamp()
+	key := gaugeKey(poolId, rewardToken)
+	gauge, exists := gc.loadGauge(stateDB, key)
+	if !exists {
+		// Start with the liquidity of the positions already in the pool
+		total, _ := gc.tracked(stateDB, poolId, nil)
+		gauge = &Gauge{
+			PoolID:                 poolId,
+			RewardToken:            rewardToken,
+			Funder:                 funder,
+			EmissionRate:           big.NewInt(0),
+			StartTime:              now,
+			LastUpdate:             now,
+			PeriodFinish:           now,
+			TotalLiquidity:         total,
+			RewardPerLiquidityX128: big.NewInt(0),
+			TotalFunded:            big.NewInt(0),
+			TotalClaimed:           big.NewInt(0),
+		}
+	} else if gauge.Funder != funder {
+		return nil, ErrUnauthorized
+	}
+
+	gc.updateGauge(gauge, now)
+
+	// Carry over undistributed rewards into the new period
+	undistributed := new(big.Int).Set(amount)
+	if gauge.PeriodFinish > now {
+		remaining := new(big.Int).SetUint64(gauge.PeriodFinish - now)
+		undistributed.Add(undistributed, remaining.Mul(remaining, gauge.EmissionRate))
+	}
+
+	duration := new(big.Int).Div(undistributed, emissionRate)
+	if duration.Sign() == 0 {
+		return nil, ErrInvalidEmissionRate
+	}
+
+	gauge.EmissionRate = new(big.Int).Set(emissionRate)
+	gauge.PeriodFinish = now + duration.Uint64()
+	gauge.TotalFunded.Add(gauge.TotalFunded, amount)
+
+	if !exists {
+		gc.addPoolGauge(stateDB, poolId, key)
+	}
+	gc.saveGauge(stateDB, key, gauge)
+	return gauge, nil
+}
+
+// =========================================================================
+// Position Hooks
+// =========================================================================
+
+// OnPositionModified checkpoints rewards for a position across every gauge
+// of the pool and records its new liquidity. It must be called whenever a
+// position's liquidity changes.
+func (gc *GaugeController) OnPositionModified(
+	stateDB StateDB,
+	poolId [32]byte,
+	positionKey [32]byte,
+	owner common.Address,
+	newLiquidity *big.Int,
+) {
+	now := stateDB.GetBlockTimestamp()
+
+	// Checkpoint at the old liquidity, which is still the tracked one
+	for _, gKey := range gc.poolGauges(stateDB, poolId) {
+		gauge, _ := gc.loadGauge(stateDB, gKey)
+		gc.updateGauge(gauge, now)
+
+		pos, _ := gc.loadPosition(stateDB, gauge, gKey, positionKey, owner, now)
+		gc.accruePosition(gauge, pos, now)
+
+		gauge.TotalLiquidity.Sub(gauge.TotalLiquidity, pos.Liquidity)
+		gauge.TotalLiquidity.Add(gauge.TotalLiquidity, newLiquidity)
+		pos.Liquidity = new(big.Int).Set(newLiquidity)
+
+		gc.saveGauge(stateDB, gKey, gauge)
+		gc.savePosition(stateDB, gaugePositionKey(gKey, positionKey), pos)
+	}
+
+	gc.track(stateDB, poolId, positionKey, owner, newLiquidity)
+}
+
+// Claim pays out all rewards owed to a position by the gauge for
+// (poolId, rewardToken). Only the position owner may claim.
+func (gc *GaugeController) Claim(
+	stateDB StateDB,
+	caller common.Address,
+	poolId [32]byte,
+	rewardToken Currency,
+	positionKey [32]byte,
+) (*big.Int, error) {
+	gKey := gaugeKey(poolId, rewardToken)
+	gauge, ok := gc.loadGauge(stateDB, gKey)
+	if !ok {
+		return nil, ErrGaugeNotFound
+	}
+
+	now := stateDB.GetBlockTimestamp()
+	pos, ok := gc.loadPosition(stateDB, gauge, gKey, positionKey, caller, now)
+	if !ok {
+		return big.NewInt(0), nil
+	}
+	if pos.Owner != caller {
+		return nil, ErrUnauthorized
+	}
+
+	gc.updateGauge(gauge, now)
+	gc.accruePosition(gauge, pos, now)
+
+	amount := pos.RewardsOwed
+	pos.RewardsOwed = big.NewInt(0)
+	gauge.TotalClaimed.Add(gauge.TotalClaimed, amount)
+
+	gc.saveGauge(stateDB, gKey, gauge)
+	gc.savePosition(stateDB, gaugePositionKey(gKey, positionKey), pos)
+	return amount, nil
+}
+
+// =========================================================================
+// View Functions
+// =========================================================================
+
+// GetGauge returns the gauge for (poolId, rewardToken)
+func (gc *GaugeController) GetGauge(stateDB StateDB, poolId [32]byte, rewardToken Currency) (*Gauge, error) {
+	gauge, ok := gc.loadGauge(stateDB, gaugeKey(poolId, rewardToken))
+	if !ok {
+		return nil, ErrGaugeNotFound
+	}
+	return gauge, nil
+}
+
+// PendingRewards returns the rewards a position could claim right now
+func (gc *GaugeController) PendingRewards(stateDB StateDB, poolId [32]byte, rewardToken Currency, positionKey [32]byte) *big.Int {
+	gKey := gaugeKey(poolId, rewardToken)
+	gauge, ok := gc.loadGauge(stateDB, gKey)
+	if !ok {
+		return big.NewInt(0)
+	}
+	now := stateDB.GetBlockTimestamp()
+	pos, ok := gc.loadPosition(stateDB, gauge, gKey, positionKey, common.Address{}, now)
+	if !ok {
+		return big.NewInt(0)
+	}
+
+	accumulator := gc.rewardPerLiquidity(gauge, now)
+	pending := new(big.Int).Sub(accumulator, pos.RewardPerLiquidityLastX128)
+	pending.Mul(pending, pos.Liquidity)
+	pending.Div(pending, Q128)
+	return pending.Add(pending, pos.RewardsOwed)
+}
+
+// =========================================================================
+// Pool Manager Entry Points
+// =========================================================================
+
+// FundGauge funds the gauge of a pool for a reward token from the current
+// locker. The rewards are charged to the locker's delta, so the locker
+// must settle them before its lock ends.
+func (pm *PoolManager) FundGauge(
+	stateDB StateDB,
+	key PoolKey,
+	rewardToken Currency,
+	amount *big.Int,
+	emissionRate *big.Int,
+) (*Gauge, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+	if pm.gauges == nil {
+		return nil, ErrGaugeNotFound
+	}
+	poolId := key.ID()
+	if !pm.getPool(stateDB, poolId).IsInitialized() {
+		return nil, ErrPoolNotInitialized
+	}
+
+	gauge, err := pm.gauges.Fund(stateDB, locker, poolId, rewardToken, amount, emissionRate)
+	if err != nil {
+		return nil, err
+	}
+	pm.updateDelta(locker, rewardToken, amount)
+	return gauge, nil
+}
+
+// ClaimGaugeRewards claims the rewards a pool's gauge owes a position of the
+// current locker, crediting them to the locker's delta to take
+func (pm *PoolManager) ClaimGaugeRewards(
+	stateDB StateDB,
+	key PoolKey,
+	rewardToken Currency,
+	positionKey [32]byte,
+) (*big.Int, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+	if pm.gauges == nil {
+		return nil, ErrGaugeNotFound
+	}
+
+	amount, err := pm.gauges.Claim(stateDB, locker, key.ID(), rewardToken, positionKey)
+	if err != nil {
+		return nil, err
+	}
+	pm.updateDelta(locker, rewardToken, new(big.Int).Neg(amount))
+	return amount, nil
+}
+
+// PendingGaugeRewards returns the rewards a pool's gauge owes a position
+func (pm *PoolManager) PendingGaugeRewards(stateDB StateDB, key PoolKey, rewardToken Currency, positionKey [32]byte) *big.Int {
+	if pm.gauges == nil {
+		return big.NewInt(0)
+	}
+	return pm.gauges.PendingRewards(stateDB, key.ID(), rewardToken, positionKey)
+}
+
+// =========================================================================
+// Internal Accounting
+// =========================================================================
+
+// rewardPerLiquidity returns the accumulator value at time now
+func (gc *GaugeController) rewardPerLiquidity(gauge *Gauge, now uint64) *big.Int {
+	end := now
+	if end > gauge.PeriodFinish {
+		end = gauge.PeriodFinish
+	}
+	if end <= gauge.LastUpdate || gauge.TotalLiquidity.Sign() == 0 {
+		return new(big.Int).Set(gauge.RewardPerLiquidityX128)
+	}
+
+	elapsed := new(big.Int).SetUint64(end - gauge.LastUpdate)
+	growth := new(big.Int).Mul(elapsed, gauge.EmissionRate)
+	growth.Mul(growth, Q128)
+	growth.Div(growth, gauge.TotalLiquidity)
+	return growth.Add(growth, gauge.RewardPerLiquidityX128)
+}
+
+// updateGauge advances the gauge accumulator to now
+func (gc *GaugeController) updateGauge(gauge *Gauge, now uint64) {
+	gauge.RewardPerLiquidityX128 = gc.rewardPerLiquidity(gauge, now)
+	gauge.LastUpdate = now
+}
+
+// accruePosition credits a position with rewards earned since its last
+// checkpoint. The gauge must already be updated to now.
+func (gc *GaugeController) accruePosition(gauge *Gauge, pos *GaugePosition, now uint64) {
+	earned := new(big.Int).Sub(gauge.RewardPerLiquidityX128, pos.RewardPerLiquidityLastX128)
+	earned.Mul(earned, pos.Liquidity)
+	earned.Div(earned, Q128)
+	pos.RewardsOwed.Add(pos.RewardsOwed, earned)
+	pos.RewardPerLiquidityLastX128 = new(big.Int).Set(gauge.RewardPerLiquidityX128)
+
+	if now > pos.LastCheckpoint {
+		elapsed := new(big.Int).SetUint64(now - pos.LastCheckpoint)
+		pos.LiquiditySeconds.Add(pos.LiquiditySeconds, elapsed.Mul(elapsed, pos.Liquidity))
+	}
+	pos.LastCheckpoint = now
+}
+
+// =========================================================================
+// Storage
+// =========================================================================
+
+// gaugeStorageKey returns the storage key of a field of a gauge record
+func gaugeStorageKey(prefix []byte, id []byte, field string) common.Hash {
+	return makeStorageKey(prefix, append(append([]byte{}, id...), field...))
+}
+
+// amountFields returns a gauge's amounts with their storage field names
+func (g *Gauge) amountFields() []positionField {
+	return []positionField{
+		{"rate", g.EmissionRate},
+		{"tliq", g.TotalLiquidity},
+		{"rpl", g.RewardPerLiquidityX128},
+		{"fund", g.TotalFunded},
+		{"clmd", g.TotalClaimed},
+	}
+}
+
+// timeFields returns a gauge's timestamps with their storage field names
+func (g *Gauge) timeFields() map[string]*uint64 {
+	return map[string]*uint64{
+		"start":  &g.StartTime,
+		"finish": &g.PeriodFinish,
+		"update": &g.LastUpdate,
+	}
+}
+
+// amountFields returns a gauge position's amounts with their storage field
+// names
+func (p *GaugePosition) amountFields() []positionField {
+	return []positionField{
+		{"liq", p.Liquidity},
+		{"rpl", p.RewardPerLiquidityLastX128},
+		{"lsec", p.LiquiditySeconds},
+		{"owed", p.RewardsOwed},
+	}
+}
+
+// loadGauge reads a gauge from state. A gauge exists once it has a funder.
+func (gc *GaugeController) loadGauge(stateDB StateDB, gKey [32]byte) (*Gauge, bool) {
+	funder := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], "funder"))
+	if funder == (common.Hash{}) {
+		return nil, false
+	}
+
+	pool := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], "pool"))
+	token := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], "token"))
+	gauge := &Gauge{
+		PoolID:                 pool,
+		RewardToken:            Currency{Address: common.BytesToAddress(token[:])},
+		Funder:                 common.BytesToAddress(funder[:]),
+		EmissionRate:           new(big.Int),
+		TotalLiquidity:         new(big.Int),
+		RewardPerLiquidityX128: new(big.Int),
+		TotalFunded:            new(big.Int),
+		TotalClaimed:           new(big.Int),
+	}
+	for _, field := range gauge.amountFields() {
+		hash := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], field.name))
+		field.value.SetBytes(hash[:])
+	}
+	for name, value := range gauge.timeFields() {
+		hash := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], name))
+		*value = decodeUint64Word(hash[:])
+	}
+	return gauge, true
+}
+
+// saveGauge writes a gauge to state
+func (gc *GaugeController) saveGauge(stateDB StateDB, gKey [32]byte, gauge *Gauge) {
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], "pool"), common.Hash(gauge.PoolID))
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], "token"),
+		common.BytesToHash(gauge.RewardToken.Address.Bytes()))
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], "funder"),
+		common.BytesToHash(gauge.Funder.Bytes()))
+	for _, field := range gauge.amountFields() {
+		var hash common.Hash
+		field.value.FillBytes(hash[:])
+		stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], field.name), hash)
+	}
+	for name, value := range gauge.timeFields() {
+		stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePrefix, gKey[:], name),
+			common.BytesToHash(encodeUint64(*value)))
+	}
+}
+
+// loadPosition reads a position's entry in a gauge. A position without
+// one that is tracked with liquidity predates the gauge and is read as
+// joining it at the start; any other starts empty at the gauge's current
+// accumulator, owned by owner. The flag reports whether the position has
+// any standing in the gauge.
+func (gc *GaugeController) loadPosition(
+	stateDB StateDB,
+	gauge *Gauge,
+	gKey [32]byte,
+	positionKey [32]byte,
+	owner common.Address,
+	now uint64,
+) (*GaugePosition, bool) {
+	key := gaugePositionKey(gKey, positionKey)
+	stored := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePositionPrefix, key[:], "owner"))
+	if stored != (common.Hash{}) {
+		pos := &GaugePosition{
+			Owner:                      common.BytesToAddress(stored[:]),
+			Liquidity:                  new(big.Int),
+			RewardPerLiquidityLastX128: new(big.Int),
+			LiquiditySeconds:           new(big.Int),
+			RewardsOwed:                new(big.Int),
+		}
+		for _, field := range pos.amountFields() {
+			hash := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePositionPrefix, key[:], field.name))
+			field.value.SetBytes(hash[:])
+		}
+		checkpoint := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePositionPrefix, key[:], "chk"))
+		pos.LastCheckpoint = decodeUint64Word(checkpoint[:])
+		return pos, true
+	}
+
+	if liquidity, trackedOwner := gc.tracked(stateDB, gauge.PoolID, positionKey[:]); liquidity.Sign() > 0 {
+		return &GaugePosition{
+			Owner:                      trackedOwner,
+			Liquidity:                  liquidity,
+			RewardPerLiquidityLastX128: big.NewInt(0),
+			LiquiditySeconds:           big.NewInt(0),
+			RewardsOwed:                big.NewInt(0),
+			LastCheckpoint:             gauge.StartTime,
+		}, true
+	}
+
+	return &GaugePosition{
+		Owner:                      owner,
+		Liquidity:                  big.NewInt(0),
+		RewardPerLiquidityLastX128: new(big.Int).Set(gauge.RewardPerLiquidityX128),
+		LiquiditySeconds:           big.NewInt(0),
+		RewardsOwed:                big.NewInt(0),
+		LastCheckpoint:             now,
+	}, false
+}
+
+// savePosition writes a position's entry in a gauge
+func (gc *GaugeController) savePosition(stateDB StateDB, key [32]byte, pos *GaugePosition) {
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePositionPrefix, key[:], "owner"),
+		common.BytesToHash(pos.Owner.Bytes()))
+	for _, field := range pos.amountFields() {
+		var hash common.Hash
+		field.value.FillBytes(hash[:])
+		stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePositionPrefix, key[:], field.name), hash)
+	}
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePositionPrefix, key[:], "chk"),
+		common.BytesToHash(encodeUint64(pos.LastCheckpoint)))
+}
+
+// poolGauges returns the keys of a pool's gauges
+func (gc *GaugeController) poolGauges(stateDB StateDB, poolId [32]byte) [][32]byte {
+	count := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, poolId[:], "count"))
+	n := decodeUint64Word(count[:])
+	keys := make([][32]byte, 0, n)
+	for i := uint64(0); i < n; i++ {
+		id := append(append([]byte{}, poolId[:]...), encodeUint64(i)...)
+		keys = append(keys, stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, id, "gauge")))
+	}
+	return keys
+}
+
+// addPoolGauge appends a gauge to its pool's list
+func (gc *GaugeController) addPoolGauge(stateDB StateDB, poolId [32]byte, gKey [32]byte) {
+	countKey := gaugeStorageKey(gaugePoolPrefix, poolId[:], "count")
+	count := stateDB.GetState(poolManagerAddr, countKey)
+	n := decodeUint64Word(count[:])
+	id := append(append([]byte{}, poolId[:]...), encodeUint64(n)...)
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, id, "gauge"), common.Hash(gKey))
+	stateDB.SetState(poolManagerAddr, countKey, common.BytesToHash(encodeUint64(n+1)))
+}
+
+// tracked returns the liquidity and owner a position of a pool was last
+// recorded with, or with no position the total tracked liquidity of the
+// pool
+func (gc *GaugeController) tracked(stateDB StateDB, poolId [32]byte, positionKey []byte) (*big.Int, common.Address) {
+	if positionKey == nil {
+		total := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, poolId[:], "total"))
+		return new(big.Int).SetBytes(total[:]), common.Address{}
+	}
+	id := append(append([]byte{}, poolId[:]...), positionKey...)
+	liquidity := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, id, "liq"))
+	owner := stateDB.GetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, id, "owner"))
+	return new(big.Int).SetBytes(liquidity[:]), common.BytesToAddress(owner[:])
+}
+
+// track records a position's liquidity and owner and keeps the pool's
+// total tracked liquidity in step
+func (gc *GaugeController) track(stateDB StateDB, poolId [32]byte, positionKey [32]byte, owner common.Address, liquidity *big.Int) {
+	old, _ := gc.tracked(stateDB, poolId, positionKey[:])
+	total, _ := gc.tracked(stateDB, poolId, nil)
+	total.Sub(total, old).Add(total, liquidity)
+
+	var hash common.Hash
+	total.FillBytes(hash[:])
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, poolId[:], "total"), hash)
+
+	id := append(append([]byte{}, poolId[:]...), positionKey[:]...)
+	hash = common.Hash{}
+	liquidity.FillBytes(hash[:])
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, id, "liq"), hash)
+	stateDB.SetState(poolManagerAddr, gaugeStorageKey(gaugePoolPrefix, id, "owner"), common.BytesToHash(owner.Bytes()))
+}
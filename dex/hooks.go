@@ -8,6 +8,7 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/luxfi/crypto"
 	"github.com/luxfi/geth/common"
 	"github.com/zeebo/blake3"
 )
@@ -50,20 +51,26 @@ type HookCallResult struct {
 	DeltaChange *BalanceDelta // Optional delta modification by hook
 }
 
-// Hook function signatures (4-byte selectors)
+// abiSelector returns the 4-byte function selector of an ABI signature
+func abiSelector(signature string) []byte {
+	return crypto.Keccak256([]byte(signature))[:4]
+}
+
+// Hook function selectors, of the callbacks as packHookCall encodes them.
+// PoolKey is the tuple (currency0, currency1, fee, tickSpacing, hooks).
 var (
-	SigBeforeInitialize      = []byte{0x01, 0x00, 0x00, 0x01}
-	SigAfterInitialize       = []byte{0x01, 0x00, 0x00, 0x02}
-	SigBeforeAddLiquidity    = []byte{0x02, 0x00, 0x00, 0x01}
-	SigAfterAddLiquidity     = []byte{0x02, 0x00, 0x00, 0x02}
-	SigBeforeRemoveLiquidity = []byte{0x02, 0x00, 0x00, 0x03}
-	SigAfterRemoveLiquidity  = []byte{0x02, 0x00, 0x00, 0x04}
-	SigBeforeSwap            = []byte{0x03, 0x00, 0x00, 0x01}
-	SigAfterSwap             = []byte{0x03, 0x00, 0x00, 0x02}
-	SigBeforeDonate          = []byte{0x04, 0x00, 0x00, 0x01}
-	SigAfterDonate           = []byte{0x04, 0x00, 0x00, 0x02}
-	SigBeforeFlash           = []byte{0x05, 0x00, 0x00, 0x01}
-	SigAfterFlash            = []byte{0x05, 0x00, 0x00, 0x02}
+	SigBeforeInitialize      = abiSelector("beforeInitialize(address,(address,address,uint24,int24,address),uint160,bytes)")
+	SigAfterInitialize       = abiSelector("afterInitialize(address,(address,address,uint24,int24,address),uint160,bytes)")
+	SigBeforeAddLiquidity    = abiSelector("beforeAddLiquidity(address,(address,address,uint24,int24,address),(int24,int24,int256,bytes32),bytes)")
+	SigAfterAddLiquidity     = abiSelector("afterAddLiquidity(address,(address,address,uint24,int24,address),(int24,int24,int256,bytes32),(int256,int256),(int256,int256),bytes)")
+	SigBeforeRemoveLiquidity = abiSelector("beforeRemoveLiquidity(address,(address,address,uint24,int24,address),(int24,int24,int256,bytes32),bytes)")
+	SigAfterRemoveLiquidity  = abiSelector("afterRemoveLiquidity(address,(address,address,uint24,int24,address),(int24,int24,int256,bytes32),(int256,int256),(int256,int256),bytes)")
+	SigBeforeSwap            = abiSelector("beforeSwap(address,(address,address,uint24,int24,address),(bool,int256,uint160),bytes)")
+	SigAfterSwap             = abiSelector("afterSwap(address,(address,address,uint24,int24,address),(bool,int256,uint160),(int256,int256),bytes)")
+	SigBeforeDonate          = abiSelector("beforeDonate(address,(address,address,uint24,int24,address),uint256,uint256,bytes)")
+	SigAfterDonate           = abiSelector("afterDonate(address,(address,address,uint24,int24,address),uint256,uint256,(int256,int256),bytes)")
+	SigBeforeFlash           = abiSelector("beforeFlash(address,(address,address,uint24,int24,address),(uint256,uint256,address),bytes)")
+	SigAfterFlash            = abiSelector("afterFlash(address,(address,address,uint24,int24,address),(uint256,uint256,address),(int256,int256),bytes)")
 )
 
 // Hook errors
@@ -75,6 +82,44 @@ var (
 	ErrHookUnauthorizedDelta = errors.New("hook not authorized to modify delta")
 )
 
+// HookCaller is implemented by state that can call hook and lock callback
+// contracts in the EVM. Calls run with at most gas and return the
+// contract's return data, or an error if it reverted or ran out of gas.
+type HookCaller interface {
+	CallHook(hook common.Address, input []byte, gas uint64) ([]byte, error)
+}
+
+// hookSelector returns the function selector of a hook callback
+func hookSelector(flag HookFlags) []byte {
+	switch flag {
+	case HookBeforeInitialize:
+		return SigBeforeInitialize
+	case HookAfterInitialize:
+		return SigAfterInitialize
+	case HookBeforeAddLiquidity:
+		return SigBeforeAddLiquidity
+	case HookAfterAddLiquidity:
+		return SigAfterAddLiquidity
+	case HookBeforeRemoveLiquidity:
+		return SigBeforeRemoveLiquidity
+	case HookAfterRemoveLiquidity:
+		return SigAfterRemoveLiquidity
+	case HookBeforeSwap:
+		return SigBeforeSwap
+	case HookAfterSwap:
+		return SigAfterSwap
+	case HookBeforeDonate:
+		return SigBeforeDonate
+	case HookAfterDonate:
+		return SigAfterDonate
+	case HookBeforeFlash:
+		return SigBeforeFlash
+	case HookAfterFlash:
+		return SigAfterFlash
+	}
+	return nil
+}
+
 // ValidateHookAddress validates that a hook address encodes the claimed permissions
 // Following Uniswap v4, the leading bits of the address encode hook capabilities
 func ValidateHookAddress(addr common.Address, permissions HookPermissions) error {
@@ -305,64 +350,86 @@ func GenerateHookAddress(deployer common.Address, salt [32]byte, permissions Hoo
 
 // PackBeforeSwapParams packs parameters for beforeSwap hook call
 func PackBeforeSwapParams(sender common.Address, key PoolKey, params SwapParams, hookData []byte) []byte {
-	// Simplified packing - real implementation would use ABI encoding
-	data := make([]byte, 0, 256)
-	data = append(data, SigBeforeSwap...)
-	data = append(data, sender.Bytes()...)
-	data = append(data, key.ToBytes()...)
-
-	// Pack swap params
-	if params.ZeroForOne {
-		data = append(data, 1)
-	} else {
-		data = append(data, 0)
-	}
-
-	amountBytes := make([]byte, 32)
-	params.AmountSpecified.FillBytes(amountBytes)
-	data = append(data, amountBytes...)
-
-	priceLimitBytes := make([]byte, 32)
-	params.SqrtPriceLimitX96.FillBytes(priceLimitBytes)
-	data = append(data, priceLimitBytes...)
-
-	data = append(data, hookData...)
-
-	return data
+	return packHookCall(SigBeforeSwap, sender, key, params, hookData)
 }
 
 // PackAfterSwapParams packs parameters for afterSwap hook call
 func PackAfterSwapParams(sender common.Address, key PoolKey, params SwapParams, delta BalanceDelta, hookData []byte) []byte {
-	data := make([]byte, 0, 320)
-	data = append(data, SigAfterSwap...)
-	data = append(data, sender.Bytes()...)
-	data = append(data, key.ToBytes()...)
-
-	// Pack swap params
-	if params.ZeroForOne {
-		data = append(data, 1)
-	} else {
-		data = append(data, 0)
-	}
-
-	amountBytes := make([]byte, 32)
-	params.AmountSpecified.FillBytes(amountBytes)
-	data = append(data, amountBytes...)
-
-	// Pack delta
-	delta0Bytes := make([]byte, 32)
-	delta.Amount0.FillBytes(delta0Bytes)
-	data = append(data, delta0Bytes...)
-
-	delta1Bytes := make([]byte, 32)
-	delta.Amount1.FillBytes(delta1Bytes)
-	data = append(data, delta1Bytes...)
+	return packHookCall(SigAfterSwap, sender, key, params, delta, hookData)
+}
 
-	data = append(data, hookData...)
+// packHookCall ABI-encodes a hook callback: the selector, then sender and
+// args as in the v4 IHooks functions. Structs are encoded as static tuples
+// (PoolKey as currency0, currency1, fee, tickSpacing, hooks), deltas as two
+// int256 words, and hook data as dynamic bytes.
+func packHookCall(sig []byte, sender common.Address, args ...interface{}) []byte {
+	head := [][]byte{common.LeftPadBytes(sender.Bytes(), 32)}
+	var dynamic [][]byte
+	var dynamicAt []int
+
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case PoolKey:
+			head = append(head,
+				common.LeftPadBytes(v.Currency0.Address.Bytes(), 32),
+				common.LeftPadBytes(v.Currency1.Address.Bytes(), 32),
+				hookWord(big.NewInt(int64(v.Fee))),
+				hookWord(big.NewInt(int64(v.TickSpacing))),
+				common.LeftPadBytes(v.Hooks.Bytes(), 32),
+			)
+		case SwapParams:
+			var zeroForOne int64
+			if v.ZeroForOne {
+				zeroForOne = 1
+			}
+			head = append(head, hookWord(big.NewInt(zeroForOne)), hookWord(v.AmountSpecified), hookWord(v.SqrtPriceLimitX96))
+		case ModifyLiquidityParams:
+			head = append(head,
+				hookWord(big.NewInt(int64(v.TickLower))),
+				hookWord(big.NewInt(int64(v.TickUpper))),
+				hookWord(v.LiquidityDelta),
+				common.CopyBytes(v.Salt[:]),
+			)
+		case FlashParams:
+			head = append(head, hookWord(v.Amount0), hookWord(v.Amount1), common.LeftPadBytes(v.Recipient.Bytes(), 32))
+		case BalanceDelta:
+			head = append(head, hookWord(v.Amount0), hookWord(v.Amount1))
+		case *big.Int:
+			head = append(head, hookWord(v))
+		case []byte:
+			// Offsets are filled in once the head's length is known
+			dynamicAt = append(dynamicAt, len(head))
+			dynamic = append(dynamic, v)
+			head = append(head, nil)
+		}
+	}
 
+	data := make([]byte, 0, 4+32*len(head))
+	data = append(data, sig...)
+	offset := 32 * len(head)
+	for i, at := range dynamicAt {
+		head[at] = hookWord(big.NewInt(int64(offset)))
+		offset += 32 + 32*((len(dynamic[i])+31)/32)
+	}
+	for _, word := range head {
+		data = append(data, word...)
+	}
+	for _, b := range dynamic {
+		data = append(data, hookWord(big.NewInt(int64(len(b))))...)
+		data = append(data, common.RightPadBytes(b, 32*((len(b)+31)/32))...)
+	}
 	return data
 }
 
+// hookWord encodes a signed value as a two's complement word, nil as zero
+func hookWord(v *big.Int) []byte {
+	if v == nil {
+		return make([]byte, 32)
+	}
+	h := signedToHash(v)
+	return h[:]
+}
+
 // UnpackHookDeltaReturn unpacks a hook's delta return value
 // Hooks can optionally return a modified delta
 func UnpackHookDeltaReturn(data []byte) (*BalanceDelta, error) {
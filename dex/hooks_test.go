@@ -4,7 +4,9 @@
 package dex
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"math/big"
 	"testing"
 
@@ -386,6 +388,24 @@ func TestPackBeforeSwapParams(t *testing.T) {
 	}
 }
 
+// TestHookSelectors checks that hook callbacks use keccak function selectors
+func TestHookSelectors(t *testing.T) {
+	if !bytes.Equal(SigBeforeSwap, []byte{0x57, 0x5e, 0x24, 0xb4}) || !bytes.Equal(SigLockAcquired, []byte{0x15, 0xc7, 0xaf, 0xb4}) {
+		t.Errorf("Unexpected selectors %x and %x", SigBeforeSwap, SigLockAcquired)
+	}
+	seen := make(map[string]bool)
+	for _, sig := range [][]byte{
+		SigBeforeInitialize, SigAfterInitialize, SigBeforeAddLiquidity, SigAfterAddLiquidity,
+		SigBeforeRemoveLiquidity, SigAfterRemoveLiquidity, SigBeforeSwap, SigAfterSwap,
+		SigBeforeDonate, SigAfterDonate, SigBeforeFlash, SigAfterFlash, SigLockAcquired,
+	} {
+		if seen[string(sig)] {
+			t.Errorf("Duplicate selector %x", sig)
+		}
+		seen[string(sig)] = true
+	}
+}
+
 func TestPackAfterSwapParams(t *testing.T) {
 	sender := common.HexToAddress("0x1111111111111111111111111111111111111111")
 	key := newTestPoolKey()
@@ -444,6 +464,113 @@ func TestUnpackHookDeltaReturn(t *testing.T) {
 	}
 }
 
+// hookCallerStateDB calls hook contracts by recording their calldata and
+// returning the callback's selector, or ret or err when set
+type hookCallerStateDB struct {
+	*MockStateDB
+	calls [][]byte
+	ret   []byte
+	err   error
+}
+
+func (s *hookCallerStateDB) CallHook(hook common.Address, input []byte, gas uint64) ([]byte, error) {
+	s.calls = append(s.calls, input)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.ret != nil {
+		return s.ret, nil
+	}
+	return common.RightPadBytes(input[:4], 32), nil
+}
+
+// TestEVMHookCalls tests that hook contracts are called with ABI-encoded
+// arguments for the callbacks their address permits, and that reverts and
+// invalid responses fail the operation
+func TestEVMHookCalls(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := &hookCallerStateDB{MockStateDB: NewMockStateDB()}
+	caller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	hookAddr := common.HexToAddress("0x00000000000000000000000000000000000000AA")
+	binary.BigEndian.PutUint16(hookAddr[0:2], uint16(HookBeforeSwap|HookAfterSwap))
+	key := newTestPoolKey()
+	key.Hooks = hookAddr
+
+	// Callbacks without permission are skipped
+	if _, err := pm.Initialize(stateDB, key, encodePriceSqrt(1, 1), nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	openLock(pm, caller)
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, ModifyLiquidityParams{TickLower: -60, TickUpper: 60, LiquidityDelta: expandTo18Decimals(1)}, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+	if len(stateDB.calls) != 0 {
+		t.Fatalf("Expected no hook calls, got %d", len(stateDB.calls))
+	}
+
+	params := SwapParams{ZeroForOne: true, AmountSpecified: big.NewInt(1_000_000)}
+	delta, err := pm.Swap(stateDB, key, params, []byte("data"))
+	if err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	if len(stateDB.calls) != 2 {
+		t.Fatalf("Expected beforeSwap and afterSwap calls, got %d", len(stateDB.calls))
+	}
+
+	// beforeSwap(sender, key, params, hookData)
+	before := stateDB.calls[0]
+	word := func(data []byte, i int) []byte { return data[4+32*i : 4+32*(i+1)] }
+	if !bytes.Equal(before[:4], SigBeforeSwap) || len(before) != 4+32*12 {
+		t.Fatalf("Expected beforeSwap calldata of %d bytes, got %x", 4+32*12, before)
+	}
+	if common.BytesToAddress(word(before, 0)) != caller || common.BytesToAddress(word(before, 5)) != hookAddr {
+		t.Errorf("Expected sender %s and hooks %s, got %x and %x", caller, hookAddr, word(before, 0), word(before, 5))
+	}
+	if word(before, 6)[31] != 1 || new(big.Int).SetBytes(word(before, 7)).Cmp(params.AmountSpecified) != 0 {
+		t.Errorf("Expected zeroForOne and amount %s, got %x and %x", params.AmountSpecified, word(before, 6), word(before, 7))
+	}
+	if offset := new(big.Int).SetBytes(word(before, 9)).Int64(); offset != 320 {
+		t.Errorf("Expected hook data at offset 320, got %d", offset)
+	}
+	if length := new(big.Int).SetBytes(word(before, 10)).Int64(); length != 4 || !bytes.HasPrefix(word(before, 11), []byte("data")) {
+		t.Errorf("Expected hook data \"data\", got %x", before[4+32*10:])
+	}
+
+	// afterSwap(sender, key, params, delta, hookData)
+	after := stateDB.calls[1]
+	if !bytes.Equal(after[:4], SigAfterSwap) {
+		t.Fatalf("Expected afterSwap selector, got %x", after[:4])
+	}
+	amount0 := hashToSigned(common.BytesToHash(word(after, 9)))
+	amount1 := hashToSigned(common.BytesToHash(word(after, 10)))
+	if amount0.Cmp(delta.Amount0) != 0 || amount1.Cmp(delta.Amount1) != 0 {
+		t.Errorf("Expected delta (%s, %s), got (%s, %s)", delta.Amount0, delta.Amount1, amount0, amount1)
+	}
+
+	// A revert fails the swap
+	stateDB.err = errors.New("execution reverted")
+	if _, err := pm.Swap(stateDB, key, params, nil); !errors.Is(err, ErrHookCallFailed) {
+		t.Errorf("Expected ErrHookCallFailed, got: %v", err)
+	}
+	stateDB.err = nil
+
+	// So does a hook that does not return the callback's selector
+	stateDB.ret = make([]byte, 32)
+	if _, err := pm.Swap(stateDB, key, params, nil); err != ErrInvalidHookResponse {
+		t.Errorf("Expected ErrInvalidHookResponse, got: %v", err)
+	}
+
+	// Without an EVM the hook is not called
+	calls := len(stateDB.calls)
+	if _, err := pm.Swap(stateDB.MockStateDB, key, params, nil); err != nil {
+		t.Errorf("Expected the swap to succeed without an EVM, got: %v", err)
+	}
+	if len(stateDB.calls) != calls {
+		t.Errorf("Expected no hook calls without an EVM")
+	}
+}
+
 // =========================================================================
 // Benchmark Tests
 // =========================================================================
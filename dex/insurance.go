@@ -0,0 +1,242 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/luxfi/geth/common"
+)
+
+// =========================================================================
+// Perpetual Insurance Fund and Auto-Deleveraging
+// =========================================================================
+//
+// Each market keeps an insurance fund. Liquidations pay a penalty into it
+// out of the liquidated margin, and it covers the deficit when a position
+// is liquidated past its bankruptcy price. When the fund cannot cover a
+// deficit, it is drained and the rest of the loss is absorbed by
+// auto-deleveraging (ADL): the liquidated position is closed against the
+// most profitable positions on the opposite side, at the price where the
+// liquidated equity (plus whatever the fund paid) reaches zero.
+//
+// The ADL queue ranks positions by PnL ratio times effective leverage,
+// highest first, with ties broken by owner address so every node picks the
+// same counterparties.
+
+// PerpLiquidation describes how a perpetual liquidation was settled
+type PerpLiquidation struct {
+	Reward                *big.Int   // Paid to the liquidator
+	Refund                *big.Int   // Equity left over for the position owner
+	InsuranceContribution *big.Int   // Penalty paid into the insurance fund
+	InsurancePayout       *big.Int   // Drawn from the insurance fund
+	BankruptcyPrice       *big.Int   // ADL fill price (Q96), nil without ADL
+	ADLFills              []*ADLFill // Counterparties deleveraged
+	BadDebt               *big.Int   // Loss neither the fund nor ADL absorbed
+}
+
+// ADLFill is one counterparty position reduced by auto-deleveraging. The
+// caller pays RealizedPnL and ReleasedMargin to the owner.
+type ADLFill struct {
+	Owner          common.Address
+	Size           *big.Int // Size closed (absolute)
+	RealizedPnL    *big.Int // PnL at the bankruptcy price, including funding
+	ReleasedMargin *big.Int // Margin released by the reduction
+}
+
+// ADLRank is an entry of a market's ADL queue
+type ADLRank struct {
+	Owner common.Address
+	Score *big.Int // PnL ratio x effective leverage (18 decimals)
+}
+
+// DepositInsurance adds funds to a market's insurance fund
+func (pe *PerpetualEngine) DepositInsurance(marketID [32]byte, amount *big.Int) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	market, exists := pe.Markets[marketID]
+	if !exists {
+		return ErrPoolNotFound
+	}
+
+	market.InsuranceFund.Add(market.InsuranceFund, amount)
+	return nil
+}
+
+// GetInsuranceFund returns a market's insurance fund balance
+func (pe *PerpetualEngine) GetInsuranceFund(marketID [32]byte) (*big.Int, error) {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	market, exists := pe.Markets[marketID]
+	if !exists {
+		return nil, ErrPoolNotFound
+	}
+
+	return new(big.Int).Set(market.InsuranceFund), nil
+}
+
+// GetADLQueue returns the profitable positions on one side of a market in
+// the order they would be deleveraged
+func (pe *PerpetualEngine) GetADLQueue(marketID [32]byte, long bool) ([]*ADLRank, error) {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	market, exists := pe.Markets[marketID]
+	if !exists {
+		return nil, ErrPoolNotFound
+	}
+
+	queue := pe.adlQueue(marketID, market, long)
+	ranks := make([]*ADLRank, len(queue))
+	for i, entry := range queue {
+		ranks[i] = &ADLRank{Owner: entry.position.Owner, Score: entry.score}
+	}
+	return ranks, nil
+}
+
+type adlEntry struct {
+	position *PerpPosition
+	score    *big.Int
+}
+
+// adlQueue ranks the profitable positions on one side of a market.
+// Caller must hold pe.mu.
+func (pe *PerpetualEngine) adlQueue(marketID [32]byte, market *PerpMarket, long bool) []adlEntry {
+	queue := make([]adlEntry, 0)
+	for _, userPositions := range pe.Positions {
+		position := userPositions[marketID]
+		if position == nil || (position.Size.Sign() > 0) != long {
+			continue
+		}
+		if score := adlScore(position, market); score != nil {
+			queue = append(queue, adlEntry{position: position, score: score})
+		}
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		if c := queue[i].score.Cmp(queue[j].score); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(queue[i].position.Owner[:], queue[j].position.Owner[:]) < 0
+	})
+	return queue
+}
+
+// adlScore returns pnl/margin * notional/equity at the mark price, or nil
+// for positions that are not in profit
+func adlScore(position *PerpPosition, market *PerpMarket) *big.Int {
+	if position.Margin.Sign() <= 0 {
+		return nil
+	}
+
+	priceDiff := new(big.Int).Sub(market.MarkPrice, position.EntryPrice)
+	pnl := new(big.Int).Mul(position.Size, priceDiff)
+	pnl.Div(pnl, Q96)
+	if pnl.Sign() <= 0 {
+		return nil
+	}
+
+	notional := new(big.Int).Abs(position.Size)
+	notional.Mul(notional, market.MarkPrice)
+	notional.Div(notional, Q96)
+	equity := new(big.Int).Add(position.Margin, pnl)
+
+	score := new(big.Int).Mul(pnl, notional)
+	score.Mul(score, big.NewInt(1e18))
+	return score.Div(score, new(big.Int).Mul(position.Margin, equity))
+}
+
+// bankruptcyPrice returns the price (Q96) at which a position's equity,
+// counting margin as its collateral, is zero
+func bankruptcyPrice(position *PerpPosition, margin *big.Int) *big.Int {
+	offset := new(big.Int).Mul(margin, Q96)
+	offset.Div(offset, new(big.Int).Abs(position.Size))
+
+	if position.Size.Sign() > 0 {
+		return new(big.Int).Sub(position.EntryPrice, offset)
+	}
+	return new(big.Int).Add(position.EntryPrice, offset)
+}
+
+// autoDeleverage closes size of the liquidated side against the ADL queue
+// of the opposite side at price and returns the fills and the size that
+// found no counterparty. Caller must hold pe.mu.
+func (pe *PerpetualEngine) autoDeleverage(
+	marketID [32]byte,
+	market *PerpMarket,
+	liquidatedLong bool,
+	size *big.Int,
+	price *big.Int,
+) ([]*ADLFill, *big.Int) {
+	remaining := new(big.Int).Set(size)
+	fills := make([]*ADLFill, 0)
+	fundingState := pe.FundingStates[marketID]
+
+	for _, entry := range pe.adlQueue(marketID, market, !liquidatedLong) {
+		if remaining.Sign() == 0 {
+			break
+		}
+		position := entry.position
+		positionSize := new(big.Int).Abs(position.Size)
+
+		fillSize := new(big.Int).Set(positionSize)
+		if fillSize.Cmp(remaining) > 0 {
+			fillSize.Set(remaining)
+		}
+
+		pnl := new(big.Int).Sub(price, position.EntryPrice)
+		pnl.Mul(pnl, fillSize)
+		pnl.Div(pnl, Q96)
+		if position.Size.Sign() < 0 {
+			pnl.Neg(pnl)
+		}
+		pnl.Add(pnl, pe.settleFundingForPosition(position, fundingState))
+
+		released := new(big.Int).Mul(position.Margin, fillSize)
+		released.Div(released, positionSize)
+
+		if fillSize.Cmp(positionSize) == 0 {
+			delete(pe.Positions[position.Owner], marketID)
+		} else {
+			position.Margin.Sub(position.Margin, released)
+			if position.Size.Sign() > 0 {
+				position.Size.Sub(position.Size, fillSize)
+			} else {
+				position.Size.Add(position.Size, fillSize)
+			}
+		}
+
+		if liquidatedLong {
+			market.OpenInterestShort.Sub(market.OpenInterestShort, fillSize)
+		} else {
+			market.OpenInterestLong.Sub(market.OpenInterestLong, fillSize)
+		}
+
+		fills = append(fills, &ADLFill{
+			Owner:          position.Owner,
+			Size:           fillSize,
+			RealizedPnL:    pnl,
+			ReleasedMargin: released,
+		})
+		remaining.Sub(remaining, fillSize)
+	}
+
+	return fills, remaining
+}
+
+// bigMin returns a copy of the smaller of a and b
+func bigMin(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
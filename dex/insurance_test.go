@@ -0,0 +1,183 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var (
+	perpLong   = common.HexToAddress("0xA000000000000000000000000000000000000001")
+	perpShortB = common.HexToAddress("0xB000000000000000000000000000000000000002")
+	perpShortC = common.HexToAddress("0xC000000000000000000000000000000000000003")
+)
+
+// perpTestTime is the clock perp tests start at
+const perpTestTime = 1_700_000_000
+
+// setupPerpMarket creates a market at price 100 with 10% maintenance margin,
+// a 10x long of 10 and two shorts of 6 with margin 100 and 300
+func setupPerpMarket(t *testing.T) (*PerpetualEngine, [32]byte) {
+	t.Helper()
+
+	pe := NewPerpetualEngine()
+	pe.Funding.now = func() int64 { return perpTestTime }
+	base := Currency{Address: common.HexToAddress("0x1111111111111111111111111111111111111111")}
+	quote := Currency{Address: common.HexToAddress("0x2222222222222222222222222222222222222222")}
+	price := new(big.Int).Mul(big.NewInt(100), Q96)
+
+	marketID, err := pe.CreateMarket(base, quote, price, 0, big.NewInt(1e17))
+	if err != nil {
+		t.Fatalf("CreateMarket failed: %v", err)
+	}
+
+	for _, p := range []struct {
+		owner        common.Address
+		size, margin int64
+	}{
+		{perpLong, 10, 100},
+		{perpShortB, -6, 100},
+		{perpShortC, -6, 300},
+	} {
+		if _, err := pe.OpenPosition(p.owner, marketID, big.NewInt(p.size), big.NewInt(p.margin), true); err != nil {
+			t.Fatalf("OpenPosition failed: %v", err)
+		}
+	}
+	return pe, marketID
+}
+
+func setPerpPrice(t *testing.T, pe *PerpetualEngine, marketID [32]byte, price int64) {
+	t.Helper()
+	if err := pe.UpdateMarkPrice(marketID, new(big.Int).Mul(big.NewInt(price), Q96)); err != nil {
+		t.Fatalf("UpdateMarkPrice failed: %v", err)
+	}
+}
+
+func TestLiquidationFundsInsurance(t *testing.T) {
+	pe, marketID := setupPerpMarket(t)
+
+	// At 95 the long has 50 equity against a 95 maintenance requirement
+	setPerpPrice(t, pe, marketID, 95)
+	result, err := pe.LiquidatePosition(common.Address{}, perpLong, marketID)
+	if err != nil {
+		t.Fatalf("LiquidatePosition failed: %v", err)
+	}
+
+	// Reward 2.5% of 950 is paid first; the 5% penalty is capped by what is left
+	if result.Reward.Cmp(big.NewInt(23)) != 0 || result.InsuranceContribution.Cmp(big.NewInt(27)) != 0 {
+		t.Errorf("Expected reward 23 and contribution 27, got %s and %s", result.Reward, result.InsuranceContribution)
+	}
+	if result.Refund.Sign() != 0 {
+		t.Errorf("Expected no refund, got %s", result.Refund)
+	}
+	if fund, _ := pe.GetInsuranceFund(marketID); fund.Cmp(big.NewInt(27)) != 0 {
+		t.Errorf("Expected fund 27, got %s", fund)
+	}
+	if len(result.ADLFills) != 0 {
+		t.Error("Solvent liquidation should not deleverage")
+	}
+}
+
+func TestBankruptcyDrawsInsurance(t *testing.T) {
+	pe, marketID := setupPerpMarket(t)
+	if err := pe.DepositInsurance(marketID, big.NewInt(500)); err != nil {
+		t.Fatalf("DepositInsurance failed: %v", err)
+	}
+
+	// At 80 the long is 100 below bankruptcy
+	setPerpPrice(t, pe, marketID, 80)
+	result, err := pe.LiquidatePosition(common.Address{}, perpLong, marketID)
+	if err != nil {
+		t.Fatalf("LiquidatePosition failed: %v", err)
+	}
+
+	// Deficit 100 plus reward 2.5% of 800
+	if result.Reward.Cmp(big.NewInt(20)) != 0 || result.InsurancePayout.Cmp(big.NewInt(120)) != 0 {
+		t.Errorf("Expected reward 20 and payout 120, got %s and %s", result.Reward, result.InsurancePayout)
+	}
+	if fund, _ := pe.GetInsuranceFund(marketID); fund.Cmp(big.NewInt(380)) != 0 {
+		t.Errorf("Expected fund 380, got %s", fund)
+	}
+	if _, err := pe.GetPosition(perpShortB, marketID); err != nil {
+		t.Error("Covered bankruptcy should not deleverage")
+	}
+}
+
+func TestAutoDeleveraging(t *testing.T) {
+	pe, marketID := setupPerpMarket(t)
+	setPerpPrice(t, pe, marketID, 80)
+
+	// Both shorts earn 120; B is more leveraged and ranks first
+	queue, err := pe.GetADLQueue(marketID, false)
+	if err != nil {
+		t.Fatalf("GetADLQueue failed: %v", err)
+	}
+	if len(queue) != 2 || queue[0].Owner != perpShortB || queue[1].Owner != perpShortC {
+		t.Fatalf("Unexpected ADL queue order")
+	}
+
+	result, err := pe.LiquidatePosition(common.Address{}, perpLong, marketID)
+	if err != nil {
+		t.Fatalf("LiquidatePosition failed: %v", err)
+	}
+
+	// The long's margin of 100 is worth a bankruptcy price of 90
+	if want := new(big.Int).Mul(big.NewInt(90), Q96); result.BankruptcyPrice.Cmp(want) != 0 {
+		t.Errorf("Expected bankruptcy price 90, got %s", result.BankruptcyPrice)
+	}
+	if result.Reward.Sign() != 0 || result.BadDebt.Sign() != 0 {
+		t.Errorf("Expected no reward and no bad debt, got %s and %s", result.Reward, result.BadDebt)
+	}
+	if len(result.ADLFills) != 2 {
+		t.Fatalf("Expected 2 ADL fills, got %d", len(result.ADLFills))
+	}
+
+	b, c := result.ADLFills[0], result.ADLFills[1]
+	if b.Owner != perpShortB || b.Size.Cmp(big.NewInt(6)) != 0 || b.RealizedPnL.Cmp(big.NewInt(60)) != 0 {
+		t.Errorf("Unexpected first fill: size %s pnl %s", b.Size, b.RealizedPnL)
+	}
+	if c.Owner != perpShortC || c.Size.Cmp(big.NewInt(4)) != 0 || c.RealizedPnL.Cmp(big.NewInt(40)) != 0 {
+		t.Errorf("Unexpected second fill: size %s pnl %s", c.Size, c.RealizedPnL)
+	}
+	if c.ReleasedMargin.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("Expected 200 margin released, got %s", c.ReleasedMargin)
+	}
+
+	// B is closed; C keeps 2 of its short
+	if _, err := pe.GetPosition(perpShortB, marketID); err != ErrPositionNotFound {
+		t.Errorf("Expected B closed, got %v", err)
+	}
+	position, err := pe.GetPosition(perpShortC, marketID)
+	if err != nil {
+		t.Fatalf("GetPosition failed: %v", err)
+	}
+	if position.Size.Cmp(big.NewInt(-2)) != 0 || position.Margin.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("Expected C short 2 with margin 100, got %s and %s", position.Size, position.Margin)
+	}
+
+	market := pe.Markets[marketID]
+	if market.OpenInterestLong.Sign() != 0 || market.OpenInterestShort.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("Unexpected open interest: long %s short %s", market.OpenInterestLong, market.OpenInterestShort)
+	}
+}
+
+func TestADLBadDebt(t *testing.T) {
+	pe, marketID := setupPerpMarket(t)
+	if _, err := pe.ClosePosition(perpShortC, marketID, big.NewInt(6)); err != nil {
+		t.Fatalf("ClosePosition failed: %v", err)
+	}
+	setPerpPrice(t, pe, marketID, 80)
+
+	// Only 6 of the 10 long finds a counterparty
+	result, err := pe.LiquidatePosition(common.Address{}, perpLong, marketID)
+	if err != nil {
+		t.Fatalf("LiquidatePosition failed: %v", err)
+	}
+	if len(result.ADLFills) != 1 || result.BadDebt.Cmp(big.NewInt(40)) != 0 {
+		t.Errorf("Expected 1 fill and bad debt 40, got %d and %s", len(result.ADLFills), result.BadDebt)
+	}
+}
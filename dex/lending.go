@@ -15,7 +15,12 @@ import (
 // Precompile address (LP-9050 LXLend)
 var lendingPoolAddr = common.HexToAddress(LXLendAddress)
 
-// Storage key prefixes for Lending state
+// Storage key prefixes for Lending state. Each value is a full 32-byte
+// word keyed by the reserve asset or position key plus a field name:
+// reserve totals under lend/pool, the interest checkpoint (borrow index,
+// supply index and block of last accrual) and the reserve configuration
+// (collateral parameters, caps, flags and rate model) under lend/resv, and
+// positions under lend/user.
 var (
 	lendPoolPrefix    = []byte("lend/pool")
 	lendUserPrefix    = []byte("lend/user")
@@ -31,13 +36,13 @@ var (
 type LendingPool struct {
 	mu sync.RWMutex
 
-	// Reserve configuration per asset
+	// Reserves per asset, cached from state
 	reserves map[common.Address]*Reserve
 
 	// User positions (keyed by user + asset)
 	positions map[[32]byte]*LendingPosition
 
-	// Interest rate models per asset
+	// Interest rate models per asset, cached from state
 	rateModels map[common.Address]*InterestRateModel
 
 	// Reference to pool manager for flash accounting
@@ -111,7 +116,7 @@ func (lp *LendingPool) InitializeReserve(
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 
-	if _, exists := lp.reserves[asset]; exists {
+	if lp.getReserve(stateDB, asset) != nil {
 		return ErrReserveAlreadyExists
 	}
 
@@ -137,9 +142,8 @@ func (lp *LendingPool) InitializeReserve(
 		SupplyIndex:      new(big.Int).Set(RAY),
 	}
 
-	lp.reserves[asset] = reserve
 	lp.rateModels[asset] = rateModel
-
+	lp.saveRateModel(stateDB, asset, rateModel)
 	lp.saveReserve(stateDB, reserve)
 
 	return nil
@@ -150,11 +154,12 @@ func (lp *LendingPool) SetReserveActive(stateDB StateDB, asset common.Address, a
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return ErrReserveNotFound
 	}
 
+	lp.accrueInterest(stateDB, reserve)
 	reserve.IsActive = active
 	lp.saveReserve(stateDB, reserve)
 
@@ -166,11 +171,12 @@ func (lp *LendingPool) SetBorrowCap(stateDB StateDB, asset common.Address, cap *
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return ErrReserveNotFound
 	}
 
+	lp.accrueInterest(stateDB, reserve)
 	reserve.BorrowCap = new(big.Int).Set(cap)
 	lp.saveReserve(stateDB, reserve)
 
@@ -192,8 +198,8 @@ func (lp *LendingPool) Supply(
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return nil, ErrReserveNotFound
 	}
 
@@ -205,6 +211,9 @@ func (lp *LendingPool) Supply(
 		return nil, ErrInvalidAmount
 	}
 
+	// Accrue interest first
+	lp.accrueInterest(stateDB, reserve)
+
 	// Check supply cap
 	if reserve.SupplyCap.Sign() > 0 {
 		newTotal := new(big.Int).Add(reserve.TotalSupply, amount)
@@ -213,9 +222,6 @@ func (lp *LendingPool) Supply(
 		}
 	}
 
-	// Accrue interest first
-	lp.accrueInterest(stateDB, reserve)
-
 	// Calculate supply tokens to mint
 	// supplyTokens = amount * RAY / exchangeRate
 	supplyTokens := new(big.Int).Mul(amount, RAY)
@@ -263,8 +269,8 @@ func (lp *LendingPool) Withdraw(
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return nil, ErrReserveNotFound
 	}
 
@@ -272,15 +278,15 @@ func (lp *LendingPool) Withdraw(
 		return nil, ErrReserveFrozen
 	}
 
+	// Accrue interest
+	lp.accrueInterest(stateDB, reserve)
+
 	key := positionKey(user, asset)
 	position := lp.getPosition(stateDB, key)
 	if position == nil || position.SupplyShares.Sign() == 0 {
 		return nil, ErrInsufficientBalance
 	}
 
-	// Accrue interest
-	lp.accrueInterest(stateDB, reserve)
-
 	// Cap withdrawal to available shares
 	withdrawShares := shareAmount
 	if withdrawShares.Cmp(position.SupplyShares) > 0 {
@@ -337,8 +343,8 @@ func (lp *LendingPool) Borrow(
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return ErrReserveNotFound
 	}
 
@@ -350,6 +356,9 @@ func (lp *LendingPool) Borrow(
 		return ErrInvalidAmount
 	}
 
+	// Accrue interest
+	lp.accrueInterest(stateDB, reserve)
+
 	// Check borrow cap
 	if reserve.BorrowCap.Sign() > 0 {
 		newTotal := new(big.Int).Add(reserve.TotalBorrows, amount)
@@ -358,9 +367,6 @@ func (lp *LendingPool) Borrow(
 		}
 	}
 
-	// Accrue interest
-	lp.accrueInterest(stateDB, reserve)
-
 	// Get position
 	key := positionKey(user, asset)
 	position := lp.getPosition(stateDB, key)
@@ -419,20 +425,20 @@ func (lp *LendingPool) Repay(
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return nil, ErrReserveNotFound
 	}
 
+	// Accrue interest
+	lp.accrueInterest(stateDB, reserve)
+
 	key := positionKey(user, asset)
 	position := lp.getPosition(stateDB, key)
 	if position == nil || position.BorrowAmount.Sign() == 0 {
 		return nil, ErrNoDebtToRepay
 	}
 
-	// Accrue interest
-	lp.accrueInterest(stateDB, reserve)
-
 	// Update user's borrow with accrued interest
 	lp.updateUserBorrow(position, reserve)
 
@@ -464,11 +470,28 @@ func (lp *LendingPool) Repay(
 // View Functions
 // =========================================================================
 
-// GetReserve returns reserve information
-func (lp *LendingPool) GetReserve(asset common.Address) *Reserve {
+// GetReserve returns reserve information, or nil if the asset has no
+// reserve
+func (lp *LendingPool) GetReserve(stateDB StateDB, asset common.Address) *Reserve {
 	lp.mu.RLock()
 	defer lp.mu.RUnlock()
-	return lp.reserves[asset]
+	return lp.getReserve(stateDB, asset)
+}
+
+// AccrueInterest checkpoints a reserve's interest to the current block and
+// returns the reserve, or nil if the asset has no reserve
+func (lp *LendingPool) AccrueInterest(stateDB StateDB, asset common.Address) *Reserve {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
+		return nil
+	}
+
+	lp.accrueInterest(stateDB, reserve)
+	lp.saveReserve(stateDB, reserve)
+	return reserve
 }
 
 // GetPosition returns a user's position
@@ -487,8 +510,8 @@ func (lp *LendingPool) GetHealthFactor(stateDB StateDB, user common.Address, ass
 	lp.mu.RLock()
 	defer lp.mu.RUnlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return new(big.Int).Set(RAY) // Max health factor if no reserve
 	}
 
@@ -513,8 +536,8 @@ func (lp *LendingPool) GetUserAccountData(
 	lp.mu.RLock()
 	defer lp.mu.RUnlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return big.NewInt(0), big.NewInt(0), big.NewInt(0), new(big.Int).Set(RAY)
 	}
 
@@ -553,12 +576,12 @@ func (lp *LendingPool) GetUserAccountData(
 }
 
 // GetSupplyAPY returns the current supply APY
-func (lp *LendingPool) GetSupplyAPY(asset common.Address) *big.Int {
+func (lp *LendingPool) GetSupplyAPY(stateDB StateDB, asset common.Address) *big.Int {
 	lp.mu.RLock()
 	defer lp.mu.RUnlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return big.NewInt(0)
 	}
 
@@ -572,12 +595,12 @@ func (lp *LendingPool) GetSupplyAPY(asset common.Address) *big.Int {
 }
 
 // GetBorrowAPY returns the current borrow APY
-func (lp *LendingPool) GetBorrowAPY(asset common.Address) *big.Int {
+func (lp *LendingPool) GetBorrowAPY(stateDB StateDB, asset common.Address) *big.Int {
 	lp.mu.RLock()
 	defer lp.mu.RUnlock()
 
-	reserve, exists := lp.reserves[asset]
-	if !exists {
+	reserve := lp.getReserve(stateDB, asset)
+	if reserve == nil {
 		return big.NewInt(0)
 	}
 
@@ -594,8 +617,14 @@ func (lp *LendingPool) GetBorrowAPY(asset common.Address) *big.Int {
 // Internal Functions
 // =========================================================================
 
-// accrueInterest updates interest for a reserve
+// accrueInterest brings a reserve's checkpoint up to the current block.
+// The checkpoint is reloaded from state first, so interest depends only on
+// block numbers and stored state, never on what this node has cached: a
+// restarted node or a reverted transaction accrues exactly as every other
+// validator does. Callers save the reserve afterwards.
 func (lp *LendingPool) accrueInterest(stateDB StateDB, reserve *Reserve) {
+	lp.loadReserve(stateDB, reserve)
+
 	currentBlock := stateDB.GetBlockNumber()
 	if currentBlock <= reserve.LastUpdateBlock {
 		return
@@ -636,17 +665,14 @@ func (lp *LendingPool) accrueInterest(stateDB StateDB, reserve *Reserve) {
 	reserveAmount := model.GetReserveAmount(interestAccrued)
 	reserve.TotalReserves = new(big.Int).Add(reserve.TotalReserves, reserveAmount)
 
-	// Update exchange rate
-	// newRate = (totalSupply + totalBorrows - reserves) / totalSupply
+	// Update supply index; suppliers earn the interest net of reserves
+	// pro rata, and supply tokens redeem at the supply index
 	if reserve.TotalSupply.Sign() > 0 {
-		totalValue := new(big.Int).Add(reserve.TotalSupply, reserve.TotalBorrows)
-		totalValue.Sub(totalValue, reserve.TotalReserves)
-		// Actually: exchangeRate = totalValue * RAY / supplyShares
-		// For simplicity, increase exchange rate proportionally
 		supplyInterest := new(big.Int).Sub(interestAccrued, reserveAmount)
-		rateIncrease := new(big.Int).Mul(supplyInterest, RAY)
-		rateIncrease.Div(rateIncrease, reserve.TotalSupply)
-		reserve.ExchangeRate = new(big.Int).Add(reserve.ExchangeRate, rateIncrease)
+		indexIncrease := new(big.Int).Mul(supplyInterest, RAY)
+		indexIncrease.Div(indexIncrease, reserve.TotalSupply)
+		reserve.SupplyIndex = new(big.Int).Add(reserve.SupplyIndex, indexIncrease)
+		reserve.ExchangeRate = new(big.Int).Set(reserve.SupplyIndex)
 	}
 
 	reserve.LastUpdateBlock = currentBlock
@@ -690,49 +716,242 @@ func (lp *LendingPool) calculateHealthFactor(collateralValue, debtValue *big.Int
 // Storage Management
 // =========================================================================
 
-func (lp *LendingPool) getPosition(stateDB StateDB, key [32]byte) *LendingPosition {
-	if position, ok := lp.positions[key]; ok {
-		return position
+// lendStorageKey returns the storage key of a named field of a reserve or
+// position
+func lendStorageKey(prefix []byte, id []byte, field string) common.Hash {
+	return makeStorageKey(prefix, append(append([]byte{}, id...), field...))
+}
+
+// totalFields returns a reserve's totals with their storage field names
+func (r *Reserve) totalFields() []positionField {
+	return []positionField{
+		{"supply", r.TotalSupply},
+		{"borrows", r.TotalBorrows},
+		{"reserves", r.TotalReserves},
+	}
+}
+
+// indexFields returns a reserve's interest indexes with their storage
+// field names
+func (r *Reserve) indexFields() []positionField {
+	return []positionField{
+		{"bidx", r.BorrowIndex},
+		{"sidx", r.SupplyIndex},
+	}
+}
+
+// configFields returns a reserve's collateral parameters and caps with
+// their storage field names
+func (r *Reserve) configFields() []positionField {
+	return []positionField{
+		{"cfac", r.CollateralFactor},
+		{"lbon", r.LiquidationBonus},
+		{"bcap", r.BorrowCap},
+		{"scap", r.SupplyCap},
+	}
+}
+
+// rateFields returns a rate model's parameters with their storage field
+// names
+func (m *InterestRateModel) rateFields() []positionField {
+	return []positionField{
+		{"rbase", m.BaseRate},
+		{"rslope1", m.Slope1},
+		{"rslope2", m.Slope2},
+		{"rkink", m.OptimalUtilization},
+		{"rfactor", m.ReserveFactor},
+	}
+}
+
+// Reserve flag bits, stored in the "flags" word of the reserve
+const (
+	reserveFlagActive = 1 << iota
+	reserveFlagFrozen
+	reserveFlagBorrowEnabled
+	reserveFlagRateModel
+)
+
+// positionFields returns a lending position's amounts and borrow index
+// with their storage field names
+func (p *LendingPosition) positionFields() []positionField {
+	return []positionField{
+		{"supply", p.SupplyShares},
+		{"borrow", p.BorrowAmount},
+		{"bidx", p.BorrowIndex},
 	}
+}
 
-	// Load from state
-	storageKey := makeStorageKey(lendUserPrefix, key[:])
-	data := stateDB.GetState(lendingPoolAddr, storageKey)
-	if data == (common.Hash{}) {
+// getReserve loads a reserve from state, refreshing the cached copy. The
+// interest checkpoint marks that a reserve exists.
+func (lp *LendingPool) getReserve(stateDB StateDB, asset common.Address) *Reserve {
+	if stateDB.GetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset.Bytes(), "bidx")) == (common.Hash{}) {
 		return nil
 	}
 
-	position := &LendingPosition{
-		SupplyShares: big.NewInt(0).SetBytes(data[:16]),
-		BorrowAmount: big.NewInt(0).SetBytes(data[16:]),
-		BorrowIndex:  new(big.Int).Set(RAY),
+	reserve, ok := lp.reserves[asset]
+	if !ok {
+		reserve = &Reserve{Asset: asset}
 	}
-	lp.positions[key] = position
-	return position
+	lp.loadReserve(stateDB, reserve)
+	lp.reserves[asset] = reserve
+	return reserve
 }
 
-func (lp *LendingPool) savePosition(stateDB StateDB, key [32]byte, position *LendingPosition) {
-	lp.positions[key] = position
+// loadReserve replaces a reserve's totals, interest checkpoint and
+// configuration, and its cached rate model, with the ones in state. A
+// reserve with no checkpoint yet is left as it is.
+func (lp *LendingPool) loadReserve(stateDB StateDB, reserve *Reserve) {
+	asset := reserve.Asset.Bytes()
+	if stateDB.GetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, "bidx")) == (common.Hash{}) {
+		return
+	}
+
+	reserve.TotalSupply = new(big.Int)
+	reserve.TotalBorrows = new(big.Int)
+	reserve.TotalReserves = new(big.Int)
+	for _, field := range reserve.totalFields() {
+		hash := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendPoolPrefix, asset, field.name))
+		field.value.SetBytes(hash[:])
+	}
+
+	reserve.BorrowIndex = new(big.Int)
+	reserve.SupplyIndex = new(big.Int)
+	for _, field := range reserve.indexFields() {
+		hash := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, field.name))
+		field.value.SetBytes(hash[:])
+	}
+	reserve.ExchangeRate = new(big.Int).Set(reserve.SupplyIndex)
+
+	block := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, "block"))
+	reserve.LastUpdateBlock = decodeUint64Word(block[:])
 
-	storageKey := makeStorageKey(lendUserPrefix, key[:])
-	var data common.Hash
-	supplyBytes := position.SupplyShares.Bytes()
-	borrowBytes := position.BorrowAmount.Bytes()
-	copy(data[:16], supplyBytes)
-	copy(data[16:], borrowBytes)
-	stateDB.SetState(lendingPoolAddr, storageKey, data)
+	reserve.CollateralFactor = new(big.Int)
+	reserve.LiquidationBonus = new(big.Int)
+	reserve.BorrowCap = new(big.Int)
+	reserve.SupplyCap = new(big.Int)
+	for _, field := range reserve.configFields() {
+		hash := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, field.name))
+		field.value.SetBytes(hash[:])
+	}
+
+	flags := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, "flags"))[31]
+	reserve.IsActive = flags&reserveFlagActive != 0
+	reserve.IsFrozen = flags&reserveFlagFrozen != 0
+	reserve.IsBorrowEnabled = flags&reserveFlagBorrowEnabled != 0
+
+	if flags&reserveFlagRateModel == 0 {
+		delete(lp.rateModels, reserve.Asset)
+		return
+	}
+	model := &InterestRateModel{
+		BaseRate:           new(big.Int),
+		Slope1:             new(big.Int),
+		Slope2:             new(big.Int),
+		OptimalUtilization: new(big.Int),
+		ReserveFactor:      new(big.Int),
+	}
+	for _, field := range model.rateFields() {
+		hash := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, field.name))
+		field.value.SetBytes(hash[:])
+	}
+	lp.rateModels[reserve.Asset] = model
 }
 
 func (lp *LendingPool) saveReserve(stateDB StateDB, reserve *Reserve) {
 	lp.reserves[reserve.Asset] = reserve
 
-	storageKey := makeStorageKey(lendPoolPrefix, reserve.Asset.Bytes())
-	var data common.Hash
-	supplyBytes := reserve.TotalSupply.Bytes()
-	borrowBytes := reserve.TotalBorrows.Bytes()
-	copy(data[:16], supplyBytes)
-	copy(data[16:], borrowBytes)
-	stateDB.SetState(lendingPoolAddr, storageKey, data)
+	asset := reserve.Asset.Bytes()
+	for _, field := range reserve.totalFields() {
+		var hash common.Hash
+		field.value.FillBytes(hash[:])
+		stateDB.SetState(lendingPoolAddr, lendStorageKey(lendPoolPrefix, asset, field.name), hash)
+	}
+	for _, field := range reserve.indexFields() {
+		var hash common.Hash
+		field.value.FillBytes(hash[:])
+		stateDB.SetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, field.name), hash)
+	}
+	stateDB.SetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, "block"),
+		common.BytesToHash(encodeUint64(reserve.LastUpdateBlock)))
+
+	for _, field := range reserve.configFields() {
+		var hash common.Hash
+		field.value.FillBytes(hash[:])
+		stateDB.SetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, field.name), hash)
+	}
+
+	var flags common.Hash
+	if reserve.IsActive {
+		flags[31] |= reserveFlagActive
+	}
+	if reserve.IsFrozen {
+		flags[31] |= reserveFlagFrozen
+	}
+	if reserve.IsBorrowEnabled {
+		flags[31] |= reserveFlagBorrowEnabled
+	}
+	if lp.rateModels[reserve.Asset] != nil {
+		flags[31] |= reserveFlagRateModel
+	}
+	stateDB.SetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset, "flags"), flags)
+}
+
+// saveRateModel writes an asset's rate model next to its reserve. The
+// reserve's flags record whether it has one.
+func (lp *LendingPool) saveRateModel(stateDB StateDB, asset common.Address, model *InterestRateModel) {
+	if model == nil {
+		return
+	}
+	for _, field := range model.rateFields() {
+		var hash common.Hash
+		field.value.FillBytes(hash[:])
+		stateDB.SetState(lendingPoolAddr, lendStorageKey(lendReservePrefix, asset.Bytes(), field.name), hash)
+	}
+}
+
+// getPosition loads a position from state, refreshing the cached copy.
+// The owner field marks that a position exists.
+func (lp *LendingPool) getPosition(stateDB StateDB, key [32]byte) *LendingPosition {
+	owner := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendUserPrefix, key[:], "owner"))
+	if owner == (common.Hash{}) {
+		return nil
+	}
+
+	position, ok := lp.positions[key]
+	if !ok {
+		position = &LendingPosition{}
+	}
+	position.Owner = common.BytesToAddress(owner[:])
+	asset := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendUserPrefix, key[:], "asset"))
+	position.Asset = common.BytesToAddress(asset[:])
+
+	position.SupplyShares = new(big.Int)
+	position.BorrowAmount = new(big.Int)
+	position.BorrowIndex = new(big.Int)
+	for _, field := range position.positionFields() {
+		hash := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendUserPrefix, key[:], field.name))
+		field.value.SetBytes(hash[:])
+	}
+
+	block := stateDB.GetState(lendingPoolAddr, lendStorageKey(lendUserPrefix, key[:], "block"))
+	position.LastUpdateBlock = decodeUint64Word(block[:])
+
+	lp.positions[key] = position
+	return position
+}
+
+func (lp *LendingPool) savePosition(stateDB StateDB, key [32]byte, position *LendingPosition) {
+	lp.positions[key] = position
+
+	stateDB.SetState(lendingPoolAddr, lendStorageKey(lendUserPrefix, key[:], "owner"), common.BytesToHash(position.Owner.Bytes()))
+	stateDB.SetState(lendingPoolAddr, lendStorageKey(lendUserPrefix, key[:], "asset"), common.BytesToHash(position.Asset.Bytes()))
+	for _, field := range position.positionFields() {
+		var hash common.Hash
+		field.value.FillBytes(hash[:])
+		stateDB.SetState(lendingPoolAddr, lendStorageKey(lendUserPrefix, key[:], field.name), hash)
+	}
+	stateDB.SetState(lendingPoolAddr, lendStorageKey(lendUserPrefix, key[:], "block"),
+		common.BytesToHash(encodeUint64(position.LastUpdateBlock)))
 }
 
 // transferAsset handles asset transfers
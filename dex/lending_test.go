@@ -163,7 +163,7 @@ func TestLendingPool_InitializeReserve(t *testing.T) {
 	}
 
 	// Check reserve was created
-	reserve := lp.GetReserve(testLendingAsset)
+	reserve := lp.GetReserve(stateDB, testLendingAsset)
 	if reserve == nil {
 		t.Fatal("reserve not found after initialization")
 	}
@@ -230,7 +230,7 @@ func TestLendingPool_Supply(t *testing.T) {
 	}
 
 	// Check reserve total
-	reserve := lp.GetReserve(testLendingAsset)
+	reserve := lp.GetReserve(stateDB, testLendingAsset)
 	if reserve.TotalSupply.Cmp(supplyAmount) != 0 {
 		t.Errorf("wrong total supply: got %v, want %v", reserve.TotalSupply, supplyAmount)
 	}
@@ -265,7 +265,7 @@ func TestLendingPool_Borrow(t *testing.T) {
 	}
 
 	// Check reserve
-	reserve := lp.GetReserve(testLendingAsset)
+	reserve := lp.GetReserve(stateDB, testLendingAsset)
 	if reserve.TotalBorrows.Cmp(borrowAmount) != 0 {
 		t.Errorf("wrong total borrows: got %v, want %v", reserve.TotalBorrows, borrowAmount)
 	}
@@ -582,7 +582,7 @@ func TestLendingPool_FullFlow(t *testing.T) {
 	}
 
 	// Check total supply
-	reserve := lp.GetReserve(testLendingAsset)
+	reserve := lp.GetReserve(stateDB, testLendingAsset)
 	expectedTotalSupply := new(big.Int).Add(supply1, supply2)
 	if reserve.TotalSupply.Cmp(expectedTotalSupply) != 0 {
 		t.Errorf("wrong total supply: got %v, want %v", reserve.TotalSupply, expectedTotalSupply)
@@ -613,3 +613,96 @@ func TestLendingPool_FullFlow(t *testing.T) {
 	t.Logf("  User 1 repaid: %v", borrow1)
 	t.Logf("  Total supply remaining: %v", reserve.TotalSupply)
 }
+
+func TestLendingPool_IndexCheckpoint(t *testing.T) {
+	pm := NewPoolManager()
+	lp := NewLendingPool(pm)
+	stateDB := NewMockStateDB()
+	model := DefaultInterestRateModel()
+
+	collateralFactor := new(big.Int).Div(new(big.Int).Mul(big.NewInt(75), RAY), big.NewInt(100))
+	liquidationBonus := new(big.Int).Div(new(big.Int).Mul(big.NewInt(5), RAY), big.NewInt(100))
+	lp.InitializeReserve(stateDB, testLendingAsset, collateralFactor, liquidationBonus, model)
+
+	setBalance(stateDB, testLendingUser1, bigInt("10000000000000000000000"))
+	supplyAmount := bigInt("1000000000000000000000")
+	borrowAmount := bigInt("500000000000000000000")
+	lp.Supply(stateDB, testLendingUser1, testLendingAsset, supplyAmount)
+	if err := lp.Borrow(stateDB, testLendingUser1, testLendingAsset, borrowAmount); err != nil {
+		t.Fatalf("Borrow failed: %v", err)
+	}
+
+	// 100 blocks of interest on the borrows
+	stateDB.SetBlockNumber(101)
+	cash := new(big.Int).Sub(supplyAmount, borrowAmount)
+	interest := model.AccrueInterest(borrowAmount, cash, borrowAmount, big.NewInt(0), 100)
+	expectedIndex := new(big.Int).Mul(interest, RAY)
+	expectedIndex.Div(expectedIndex, borrowAmount)
+	expectedIndex.Add(expectedIndex, RAY)
+
+	reserve := lp.AccrueInterest(stateDB, testLendingAsset)
+	if reserve.BorrowIndex.Cmp(expectedIndex) != 0 || reserve.LastUpdateBlock != 101 {
+		t.Fatalf("wrong checkpoint: got index %v at block %d, want %v at 101", reserve.BorrowIndex, reserve.LastUpdateBlock, expectedIndex)
+	}
+	if reserve.SupplyIndex.Cmp(RAY) <= 0 || reserve.ExchangeRate.Cmp(reserve.SupplyIndex) != 0 {
+		t.Errorf("supply index should grow and set the exchange rate, got %v and %v", reserve.SupplyIndex, reserve.ExchangeRate)
+	}
+
+	// A restarted pool finds the reserve and resumes from the checkpoint in
+	// state
+	restarted := NewLendingPool(pm)
+	if err := restarted.InitializeReserve(stateDB, testLendingAsset, collateralFactor, liquidationBonus, model); err != ErrReserveAlreadyExists {
+		t.Errorf("expected ErrReserveAlreadyExists from a restarted pool, got %v", err)
+	}
+	resumed := restarted.GetReserve(stateDB, testLendingAsset)
+	if resumed == nil {
+		t.Fatal("restarted pool should load the reserve from state")
+	}
+	if resumed.BorrowIndex.Cmp(reserve.BorrowIndex) != 0 || resumed.SupplyIndex.Cmp(reserve.SupplyIndex) != 0 ||
+		resumed.TotalBorrows.Cmp(reserve.TotalBorrows) != 0 || resumed.LastUpdateBlock != reserve.LastUpdateBlock {
+		t.Errorf("restarted reserve should match the checkpoint, got index %v at block %d", resumed.BorrowIndex, resumed.LastUpdateBlock)
+	}
+	if resumed.CollateralFactor.Cmp(collateralFactor) != 0 || !resumed.IsActive || !resumed.IsBorrowEnabled {
+		t.Errorf("restarted reserve should keep its configuration, got %+v", resumed)
+	}
+	if restarted.GetBorrowAPY(stateDB, testLendingAsset).Cmp(lp.GetBorrowAPY(stateDB, testLendingAsset)) != 0 {
+		t.Error("restarted pool should load the rate model")
+	}
+	position := restarted.GetPosition(stateDB, testLendingUser1, testLendingAsset)
+	if position == nil || position.Owner != testLendingUser1 || position.BorrowAmount.Cmp(borrowAmount) != 0 ||
+		position.BorrowIndex.Cmp(RAY) != 0 || position.LastUpdateBlock != 1 {
+		t.Fatalf("restarted pool should load the position, got %+v", position)
+	}
+
+	// Accrual by either pool depends only on the block number and state
+	stateDB.SetBlockNumber(201)
+	accrued := new(big.Int).Set(restarted.AccrueInterest(stateDB, testLendingAsset).BorrowIndex)
+	if accrued.Cmp(reserve.BorrowIndex) <= 0 {
+		t.Errorf("borrow index should grow from %v, got %v", reserve.BorrowIndex, accrued)
+	}
+	if index := lp.AccrueInterest(stateDB, testLendingAsset).BorrowIndex; index.Cmp(accrued) != 0 {
+		t.Errorf("original pool should not accrue twice: got %v, want %v", index, accrued)
+	}
+
+	// Repaying through the restarted pool charges the accrued interest
+	debt := new(big.Int).Mul(borrowAmount, accrued)
+	debt.Div(debt, RAY)
+	repaid, err := restarted.Repay(stateDB, testLendingUser1, testLendingAsset, debt)
+	if err != nil || repaid.Cmp(debt) != 0 {
+		t.Errorf("wrong repayment: got %v (%v), want %v", repaid, err, debt)
+	}
+
+	// Caps and deactivation set through one pool bind the other
+	if err := lp.SetBorrowCap(stateDB, testLendingAsset, big.NewInt(1)); err != nil {
+		t.Fatalf("SetBorrowCap failed: %v", err)
+	}
+	if err := restarted.Borrow(stateDB, testLendingUser1, testLendingAsset, big.NewInt(2)); err != ErrBorrowCapExceeded {
+		t.Errorf("expected ErrBorrowCapExceeded, got %v", err)
+	}
+	if err := lp.SetReserveActive(stateDB, testLendingAsset, false); err != nil {
+		t.Fatalf("SetReserveActive failed: %v", err)
+	}
+	if _, err := restarted.Supply(stateDB, testLendingUser1, testLendingAsset, big.NewInt(1)); err != ErrReserveFrozen {
+		t.Errorf("expected ErrReserveFrozen from an inactive reserve, got %v", err)
+	}
+}
@@ -44,6 +44,10 @@ type Liquid struct {
 	// User accounts (keyed by owner + yieldToken)
 	accounts map[[32]byte]*LiquidAccount
 
+	// Underlying collected from harvested yield and repayments, per liquid
+	// token, waiting to be topped up into the transmuter
+	collected map[common.Address]*big.Int
+
 	// Reference to pool manager for LP token valuations
 	poolManager *PoolManager
 }
@@ -54,6 +58,7 @@ func NewLiquid(pm *PoolManager) *Liquid {
 		yieldTokens:  make(map[common.Address]*YieldToken),
 		liquidTokens: make(map[common.Address]*LiquidToken),
 		accounts:     make(map[[32]byte]*LiquidAccount),
+		collected:    make(map[common.Address]*big.Int),
 		poolManager:  pm,
 	}
 }
@@ -148,7 +153,7 @@ func (a *Liquid) Deposit(
 		return ErrInvalidYieldToken
 	}
 
-	if amount.Sign() <= 0 {
+	if amount == nil || amount.Sign() <= 0 {
 		return ErrInsufficientCollateral
 	}
 
@@ -221,7 +226,7 @@ func (a *Liquid) Withdraw(
 	// Ensure position remains healthy (debt <= 90% of collateral)
 	// Since this is Alchemix-style, debt can never exceed collateral
 	if account.Debt.Sign() > 0 {
-		maxDebt := a.calculateMaxDebt(newCollateral)
+		maxDebt := a.calculateMaxDebt(a.getCollateralValue(stateDB, newCollateral, yt))
 		if account.Debt.Cmp(maxDebt) > 0 {
 			return ErrMaxLTVExceeded
 		}
@@ -244,7 +249,9 @@ func (a *Liquid) Withdraw(
 }
 
 // Mint mints liquid tokens against deposited collateral
-// Maximum 90% LTV (vs Alchemix's 50%)
+// Maximum 90% LTV (vs Alchemix's 50%), within the liquid token's debt ceiling.
+// An account owes debt in one liquid token at a time, which must track the
+// same underlying as its collateral.
 func (a *Liquid) Mint(
 	stateDB StateDB,
 	owner common.Address,
@@ -265,11 +272,12 @@ func (a *Liquid) Mint(
 	if !exists {
 		return ErrLiquidTokenNotRegistered
 	}
+	if st.UnderlyingAsset != yt.UnderlyingAsset {
+		return ErrLiquidTokenMismatch
+	}
 
-	// Check debt ceiling
-	newTotalMinted := new(big.Int).Add(st.TotalMinted, amount)
-	if newTotalMinted.Cmp(st.DebtCeiling) > 0 {
-		return ErrDebtCeiling
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
 	}
 
 	// Get account
@@ -279,9 +287,19 @@ func (a *Liquid) Mint(
 		return ErrInsufficientCollateral
 	}
 
-	// Harvest yield first
+	// Harvest yield first; repaid debt frees room under the ceiling
 	a.harvestYieldInternal(stateDB, account, yt)
 
+	if account.Debt.Sign() > 0 && account.LiquidToken != syntheticToken {
+		return ErrLiquidTokenMismatch
+	}
+
+	// Check debt ceiling
+	newTotalMinted := new(big.Int).Add(st.TotalMinted, amount)
+	if newTotalMinted.Cmp(st.DebtCeiling) > 0 {
+		return ErrDebtCeiling
+	}
+
 	// Calculate max mintable (90% of collateral value minus existing debt)
 	collateralValue := a.getCollateralValue(stateDB, account.Collateral, yt)
 	maxDebt := a.calculateMaxDebt(collateralValue)
@@ -297,6 +315,7 @@ func (a *Liquid) Mint(
 
 	// Update account debt
 	account.Debt = new(big.Int).Add(account.Debt, amount)
+	account.LiquidToken = syntheticToken
 
 	// Update synthetic total minted
 	st.TotalMinted = newTotalMinted
@@ -328,16 +347,28 @@ func (a *Liquid) Burn(
 		return ErrLiquidTokenNotRegistered
 	}
 
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+
 	key := accountKey(owner, yieldToken)
 	account := a.getAccount(stateDB, key)
 	if account == nil || account.Debt.Sign() == 0 {
 		return ErrNoDebtToRepay
 	}
+	if account.LiquidToken != syntheticToken {
+		return ErrLiquidTokenMismatch
+	}
 
 	yt := a.yieldTokens[yieldToken]
 	if yt != nil {
 		a.harvestYieldInternal(stateDB, account, yt)
 	}
+	if account.Debt.Sign() == 0 {
+		// Yield already repaid everything
+		a.saveAccount(stateDB, key, account)
+		return nil
+	}
 
 	// Cap burn amount to outstanding debt
 	burnAmount := amount
@@ -353,21 +384,59 @@ func (a *Liquid) Burn(
 	a.burnSynthetic(stateDB, syntheticToken, owner, burnAmount)
 
 	// Reduce debt
-	account.Debt = new(big.Int).Sub(account.Debt, debtReduction)
-	if account.Debt.Sign() < 0 {
-		account.Debt = big.NewInt(0)
-	}
-
-	// Update synthetic total
-	st.TotalMinted = new(big.Int).Sub(st.TotalMinted, burnAmount)
+	a.reduceDebt(stateDB, account, debtReduction)
 
 	// Save state
 	a.saveAccount(stateDB, key, account)
-	a.saveLiquidToken(stateDB, st)
 
 	return nil
 }
 
+// Repay repays debt with the underlying asset instead of liquid tokens.
+// The underlying is held by Liquid for the transmuter. Returns the amount
+// repaid, capped at the outstanding debt.
+func (a *Liquid) Repay(
+	stateDB StateDB,
+	owner common.Address,
+	yieldToken common.Address,
+	amount *big.Int,
+) (*big.Int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	yt, exists := a.yieldTokens[yieldToken]
+	if !exists {
+		return nil, ErrInvalidYieldToken
+	}
+
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	key := accountKey(owner, yieldToken)
+	account := a.getAccount(stateDB, key)
+	if account == nil || account.Debt.Sign() == 0 {
+		return nil, ErrNoDebtToRepay
+	}
+
+	a.harvestYieldInternal(stateDB, account, yt)
+
+	repaid := new(big.Int).Set(amount)
+	if repaid.Cmp(account.Debt) > 0 {
+		repaid.Set(account.Debt)
+	}
+
+	if repaid.Sign() > 0 {
+		a.transferFrom(stateDB, yt.UnderlyingAsset.Address, owner, liquidAddr, repaid)
+		a.collect(account.LiquidToken, repaid)
+		a.reduceDebt(stateDB, account, repaid)
+	}
+
+	a.saveAccount(stateDB, key, account)
+
+	return repaid, nil
+}
+
 // Harvest harvests accrued yield and applies it to debt repayment
 // This is the "self-repaying" mechanism
 func (a *Liquid) Harvest(
@@ -430,15 +499,14 @@ func (a *Liquid) harvestYieldInternal(
 
 	// Apply accrued yield to debt repayment
 	if account.Debt.Sign() > 0 && account.AccruedYield.Sign() > 0 {
-		if account.AccruedYield.Cmp(account.Debt) >= 0 {
+		repaid := new(big.Int).Set(account.AccruedYield)
+		if repaid.Cmp(account.Debt) > 0 {
 			// Yield covers all debt
-			account.AccruedYield = new(big.Int).Sub(account.AccruedYield, account.Debt)
-			account.Debt = big.NewInt(0)
-		} else {
-			// Partial debt repayment
-			account.Debt = new(big.Int).Sub(account.Debt, account.AccruedYield)
-			account.AccruedYield = big.NewInt(0)
+			repaid.Set(account.Debt)
 		}
+		account.AccruedYield = new(big.Int).Sub(account.AccruedYield, repaid)
+		a.collect(account.LiquidToken, repaid)
+		a.reduceDebt(stateDB, account, repaid)
 	}
 
 	account.LastHarvestBlock = currentBlock
@@ -446,6 +514,43 @@ func (a *Liquid) harvestYieldInternal(
 	return yieldAmount
 }
 
+// reduceDebt lowers an account's debt, releasing the same amount of its
+// liquid token's debt ceiling. amount must not exceed the debt.
+func (a *Liquid) reduceDebt(stateDB StateDB, account *LiquidAccount, amount *big.Int) {
+	account.Debt = new(big.Int).Sub(account.Debt, amount)
+
+	if st := a.liquidTokens[account.LiquidToken]; st != nil {
+		st.TotalMinted = new(big.Int).Sub(st.TotalMinted, amount)
+		a.saveLiquidToken(stateDB, st)
+	}
+	if account.Debt.Sign() == 0 {
+		account.LiquidToken = common.Address{}
+	}
+}
+
+// collect records underlying that backs repaid debt of a liquid token
+func (a *Liquid) collect(liquidToken common.Address, amount *big.Int) {
+	total := a.collected[liquidToken]
+	if total == nil {
+		total = big.NewInt(0)
+	}
+	a.collected[liquidToken] = total.Add(total, amount)
+}
+
+// takeCollected returns and resets the underlying collected for a liquid
+// token, for the transmuter to top up its buffer with
+func (a *Liquid) takeCollected(liquidToken common.Address) *big.Int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	amount := a.collected[liquidToken]
+	delete(a.collected, liquidToken)
+	if amount == nil {
+		return big.NewInt(0)
+	}
+	return amount
+}
+
 // =========================================================================
 // View Functions
 // =========================================================================
@@ -600,14 +705,7 @@ func (a *Liquid) getCollateralValue(stateDB StateDB, amount *big.Int, yt *YieldT
 
 // getCurrentBlock returns the current block number
 func (a *Liquid) getCurrentBlock(stateDB StateDB) uint64 {
-	// In production, would read from block context
-	// For testing, use state-based tracking
-	blockKey := makeStorageKey(liquidGlobalPrefix, []byte("block"))
-	blockHash := stateDB.GetState(liquidAddr, blockKey)
-	if blockHash == (common.Hash{}) {
-		return 1
-	}
-	return uint256.NewInt(0).SetBytes(blockHash[:]).Uint64()
+	return stateDB.GetBlockNumber()
 }
 
 // =========================================================================
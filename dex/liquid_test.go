@@ -344,6 +344,73 @@ func TestLiquid_GetTimeToRepayment(t *testing.T) {
 	}
 }
 
+func TestLiquid_HarvestAndRepay(t *testing.T) {
+	pm := NewPoolManager()
+	alchemist := NewLiquid(pm)
+	stateDB := NewMockStateDB()
+
+	yieldPerBlock := bigInt("1000000000000000") // 0.001 per block per unit
+	debtCeiling := bigInt("100000000000000000000")
+	alchemist.AddYieldToken(stateDB, testYieldToken, testUnderlying, yieldPerBlock)
+	alchemist.AddLiquidToken(stateDB, testLiquidToken, testUnderlying, debtCeiling)
+
+	// Deposit 100 and mint 90
+	setBalance(stateDB, testUser1, bigInt("1000000000000000000000"))
+	alchemist.Deposit(stateDB, testUser1, testYieldToken, bigInt("100000000000000000000"))
+	if err := alchemist.Mint(stateDB, testUser1, testYieldToken, testLiquidToken, bigInt("90000000000000000000")); err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+
+	// The ceiling is shared across accounts
+	setBalance(stateDB, testUser2, bigInt("1000000000000000000000"))
+	alchemist.Deposit(stateDB, testUser2, testYieldToken, bigInt("100000000000000000000"))
+	err := alchemist.Mint(stateDB, testUser2, testYieldToken, testLiquidToken, bigInt("20000000000000000000"))
+	if err != ErrDebtCeiling {
+		t.Fatalf("expected ErrDebtCeiling, got %v", err)
+	}
+
+	// 100 blocks of yield repays 10 and frees 10 of the ceiling
+	stateDB.SetBlockNumber(101)
+	harvested, err := alchemist.Harvest(stateDB, testUser1, testYieldToken)
+	if err != nil {
+		t.Fatalf("Harvest failed: %v", err)
+	}
+	if harvested.Cmp(bigInt("10000000000000000000")) != 0 {
+		t.Fatalf("harvested mismatch: got %s", harvested)
+	}
+	account := alchemist.GetAccount(stateDB, testUser1, testYieldToken)
+	if account.Debt.Cmp(bigInt("80000000000000000000")) != 0 {
+		t.Fatalf("debt after harvest: got %s", account.Debt)
+	}
+	if err := alchemist.Mint(stateDB, testUser2, testYieldToken, testLiquidToken, bigInt("20000000000000000000")); err != nil {
+		t.Fatalf("Mint after harvest failed: %v", err)
+	}
+
+	// Repaying in underlying is capped at the debt and clears the account
+	repaid, err := alchemist.Repay(stateDB, testUser1, testYieldToken, bigInt("100000000000000000000"))
+	if err != nil {
+		t.Fatalf("Repay failed: %v", err)
+	}
+	if repaid.Cmp(bigInt("80000000000000000000")) != 0 {
+		t.Fatalf("repaid mismatch: got %s", repaid)
+	}
+	account = alchemist.GetAccount(stateDB, testUser1, testYieldToken)
+	if account.Debt.Sign() != 0 || account.LiquidToken != (common.Address{}) {
+		t.Fatalf("expected a cleared account, got debt %s in %s", account.Debt, account.LiquidToken)
+	}
+	if minted := alchemist.liquidTokens[testLiquidToken].TotalMinted; minted.Cmp(bigInt("20000000000000000000")) != 0 {
+		t.Fatalf("total minted mismatch: got %s", minted)
+	}
+
+	// Debt must be minted in a liquid token of the collateral's underlying
+	otherLiquid := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	alchemist.AddLiquidToken(stateDB, otherLiquid, Currency{Address: otherLiquid}, debtCeiling)
+	err = alchemist.Mint(stateDB, testUser1, testYieldToken, otherLiquid, big.NewInt(1))
+	if err != ErrLiquidTokenMismatch {
+		t.Fatalf("expected ErrLiquidTokenMismatch, got %v", err)
+	}
+}
+
 // =========================================================================
 // Transmuter Tests
 // =========================================================================
@@ -535,6 +602,75 @@ func TestTransmuter_MultipleStakers(t *testing.T) {
 // Integration Tests
 // =========================================================================
 
+func TestTransmuter_FlowAndTopUp(t *testing.T) {
+	pm := NewPoolManager()
+	alchemist := NewLiquid(pm)
+	transmuter := NewTransmuter(alchemist)
+	stateDB := NewMockStateDB()
+
+	yieldPerBlock := bigInt("1000000000000000")
+	debtCeiling := bigInt("1000000000000000000000000")
+	alchemist.AddYieldToken(stateDB, testYieldToken, testUnderlying, yieldPerBlock)
+	alchemist.AddLiquidToken(stateDB, testLiquidToken, testUnderlying, debtCeiling)
+	transmuter.InitializeTransmuter(stateDB, testLiquidToken, testUnderlying)
+	if err := transmuter.SetFlowRate(stateDB, testLiquidToken, bigInt("1000000000000000000")); err != nil {
+		t.Fatalf("SetFlowRate failed: %v", err)
+	}
+
+	// A borrower with 90 debt; two stakers with 60 and 40
+	setBalance(stateDB, testUser1, bigInt("1000000000000000000000"))
+	setBalance(stateDB, testUser2, bigInt("1000000000000000000000"))
+	alchemist.Deposit(stateDB, testUser1, testYieldToken, bigInt("100000000000000000000"))
+	alchemist.Mint(stateDB, testUser1, testYieldToken, testLiquidToken, bigInt("90000000000000000000"))
+	transmuter.Stake(stateDB, testUser2, testLiquidToken, bigInt("60000000000000000000"))
+	transmuter.Stake(stateDB, testUser1, testLiquidToken, bigInt("40000000000000000000"))
+
+	// 100 blocks of yield repays 10 of debt, which the keeper tops up
+	stateDB.SetBlockNumber(101)
+	added, err := transmuter.HarvestAndTopUp(stateDB, testUser1, testYieldToken)
+	if err != nil {
+		t.Fatalf("HarvestAndTopUp failed: %v", err)
+	}
+	if added.Cmp(bigInt("10000000000000000000")) != 0 {
+		t.Fatalf("top-up mismatch: got %s", added)
+	}
+	if claimable := transmuter.GetClaimable(stateDB, testUser2, testLiquidToken); claimable.Sign() != 0 {
+		t.Fatalf("nothing should have streamed yet, got %s", claimable)
+	}
+
+	// The buffer streams 1 per block, split pro-rata
+	stateDB.SetBlockNumber(104)
+	if claimable := transmuter.GetClaimable(stateDB, testUser2, testLiquidToken); claimable.Cmp(bigInt("1800000000000000000")) != 0 {
+		t.Fatalf("user2 claimable after 3 blocks: got %s", claimable)
+	}
+
+	// Once all 10 have streamed, user2 has converted 6 of its 60
+	stateDB.SetBlockNumber(200)
+	claimed, err := transmuter.Claim(stateDB, testUser2, testLiquidToken)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if claimed.Cmp(bigInt("6000000000000000000")) != 0 {
+		t.Fatalf("user2 claimed mismatch: got %s", claimed)
+	}
+	if stake := transmuter.GetStake(stateDB, testUser2, testLiquidToken); stake.StakedAmount.Cmp(bigInt("54000000000000000000")) != 0 {
+		t.Fatalf("user2 remaining stake mismatch: got %s", stake.StakedAmount)
+	}
+
+	// A deposit larger than everything staked converts every stake in full
+	transmuter.SetFlowRate(stateDB, testLiquidToken, big.NewInt(0))
+	transmuter.Deposit(stateDB, testLiquidToken, bigInt("100000000000000000000"))
+	if claimable := transmuter.GetClaimable(stateDB, testUser1, testLiquidToken); claimable.Cmp(bigInt("40000000000000000000")) != 0 {
+		t.Fatalf("user1 claimable after full conversion: got %s", claimable)
+	}
+	if claimable := transmuter.GetClaimable(stateDB, testUser2, testLiquidToken); claimable.Cmp(bigInt("54000000000000000000")) != 0 {
+		t.Fatalf("user2 claimable after full conversion: got %s", claimable)
+	}
+	if state := transmuter.GetLiquidFXState(testLiquidToken); state.TotalStaked.Sign() != 0 || state.Unexchanged.Cmp(bigInt("10000000000000000000")) != 0 {
+		t.Fatalf("expected nothing staked and 10 unexchanged, got %s and %s", state.TotalStaked, state.Unexchanged)
+	}
+}
+
 func TestLiquid_FullFlow(t *testing.T) {
 	pm := NewPoolManager()
 	alchemist := NewLiquid(pm)
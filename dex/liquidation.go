@@ -138,8 +138,8 @@ func (l *Liquidator) Liquidate(
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	// Get lending pool (thread-safe, but we already have the lock)
-	reserve := l.lendingPool.GetReserve(asset)
+	// Accrue the reserve to this block before pricing the position
+	reserve := l.lendingPool.AccrueInterest(stateDB, asset)
 	if reserve == nil {
 		return nil, nil, ErrReserveNotFound
 	}
@@ -252,7 +252,7 @@ func (l *Liquidator) GetLiquidatableAmount(
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	reserve := l.lendingPool.GetReserve(asset)
+	reserve := l.lendingPool.GetReserve(stateDB, asset)
 	if reserve == nil {
 		return big.NewInt(0)
 	}
@@ -280,7 +280,7 @@ func (l *Liquidator) GetLiquidationBonus(
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	reserve := l.lendingPool.GetReserve(asset)
+	reserve := l.lendingPool.GetReserve(stateDB, asset)
 	if reserve == nil {
 		return big.NewInt(0)
 	}
@@ -369,7 +369,7 @@ func (l *Liquidator) executeLiquidation(
 
 	// Get borrower position
 	borrowerKey := positionKey(borrower, asset)
-	borrowerPosition := l.lendingPool.positions[borrowerKey]
+	borrowerPosition := l.lendingPool.getPosition(stateDB, borrowerKey)
 	if borrowerPosition == nil {
 		return ErrPositionNotFound
 	}
@@ -470,7 +470,7 @@ func (l *Liquidator) FindLiquidatablePositions(
 	targets := make([]BatchLiquidationTarget, 0)
 
 	for _, asset := range assets {
-		reserve := l.lendingPool.GetReserve(asset)
+		reserve := l.lendingPool.GetReserve(stateDB, asset)
 		if reserve == nil {
 			continue
 		}
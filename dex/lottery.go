@@ -0,0 +1,658 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+	"github.com/zeebo/blake3"
+)
+
+// =========================================================================
+// Swap Incentive Lottery
+// =========================================================================
+//
+// Enrolled pools run one lottery per epoch. In the afterSwap phase, every
+// swap in the pool gives its locker one ticket, and a slice of the swap fee,
+// taken in the input currency, goes into the epoch's prize pot. Sponsors can
+// add to a pot directly. The lottery never touches the swap math.
+//
+// Once an epoch has ended, anyone can draw it. The winning ticket is the
+// beacon seed modulo the ticket count. The seed is computed over a transcript
+// of the pool, epoch and tickets, so every node picks the same winner. The
+// winner claims the pot into its flash-accounting delta. When an epoch has no
+// tickets, its pot rolls over into the next undrawn epoch.
+
+const (
+	// DefaultLotteryEpoch is the default epoch length in seconds (one day)
+	DefaultLotteryEpoch uint64 = 86_400
+
+	// DefaultLotteryShareBps is the default slice of the swap fee paid into the pot (5%)
+	DefaultLotteryShareBps uint32 = 500
+
+	// MaxLotteryShareBps caps the slice of the swap fee paid into the pot (20%)
+	MaxLotteryShareBps uint32 = 2_000
+)
+
+// lotteryAlphaDomain domain-separates the draw transcript
+const lotteryAlphaDomain = "lux.dex.lottery.draw.v1"
+
+// LotteryBeacon supplies the randomness for lottery draws
+type LotteryBeacon interface {
+	// Seed returns the random seed for a draw over alpha, checking proof
+	// when the source is verifiable
+	Seed(alpha [32]byte, proof []byte) ([32]byte, error)
+}
+
+// BlockHashBeacon seeds draws with a recent block hash. It needs no oracle,
+// but the block proposer can bias the outcome by withholding blocks.
+type BlockHashBeacon struct {
+	BlockHash func() common.Hash // Hash of the most recent block
+}
+
+// Seed mixes alpha with the most recent block hash. proof is ignored.
+func (b *BlockHashBeacon) Seed(alpha [32]byte, proof []byte) ([32]byte, error) {
+	hash := b.BlockHash()
+	if hash == (common.Hash{}) {
+		return [32]byte{}, ErrInvalidRandomness
+	}
+
+	h := blake3.New()
+	h.Write(alpha[:])
+	h.Write(hash.Bytes())
+	var seed [32]byte
+	h.Digest().Read(seed[:])
+	return seed, nil
+}
+
+// VRFBeacon seeds draws with the output of a VRF over alpha. The proof is
+// checked against the oracle's public key, so nobody can bias the seed.
+type VRFBeacon struct {
+	Verify func(alpha, proof []byte) ([32]byte, bool) // Returns the VRF output for a valid proof
+}
+
+// Seed returns the VRF output proven by proof
+func (b *VRFBeacon) Seed(alpha [32]byte, proof []byte) ([32]byte, error) {
+	output, ok := b.Verify(alpha[:], proof)
+	if !ok {
+		return [32]byte{}, ErrInvalidRandomness
+	}
+	return output, nil
+}
+
+// LotteryEpoch is one pool's lottery for one epoch
+type LotteryEpoch struct {
+	PoolID  [32]byte
+	Epoch   uint64
+	Tickets []common.Address      // One per swap, in swap order
+	Prizes  map[Currency]*big.Int // Prize pot per currency
+	Drawn   bool
+	Seed    [32]byte
+	Winner  common.Address // Zero when the epoch had no tickets
+	Claimed bool
+}
+
+// Storage key prefixes for LXLottery, stored at the LXLottery address. An
+// epoch is keyed by BLAKE3(poolId || epoch):
+//
+//	lott/share                     -> fee slice (set flag byte 0, bps)
+//	lott/pool || poolId            -> enrolled flag
+//	lott/ep || epoch || "tix"      -> number of tickets
+//	lott/ep || epoch || "tix" || i -> ticket holder
+//	lott/ep || epoch || "cur"      -> number of pot currencies
+//	lott/ep || epoch || "cur" || i -> pot currency
+//	lott/ep || epoch || currency   -> pot amount in currency
+//	lott/ep || epoch || "draw"     -> drawn (byte 0) | claimed (byte 1)
+//	lott/ep || epoch || "seed", "win"
+var (
+	lotterySharePrefix = []byte("lott/share")
+	lotteryPoolPrefix  = []byte("lott/pool")
+	lotteryEpochPrefix = []byte("lott/ep")
+)
+
+// Lottery runs the per-epoch swap lotteries of enrolled pools. Pools, epochs,
+// tickets and pots live at the LXLottery address, and epochs are numbered by
+// block timestamp.
+type Lottery struct {
+	mu sync.RWMutex
+
+	// epochLength is the epoch length in seconds
+	epochLength uint64
+
+	// shareBps is the slice of the swap fee paid into the pot until
+	// governance stores another
+	shareBps uint32
+
+	// beacon supplies draw randomness; draws fail until one is set
+	beacon LotteryBeacon
+}
+
+// NewLottery creates a lottery with the given epoch length and fee slice
+func NewLottery(epochLength uint64, shareBps uint32) *Lottery {
+	return &Lottery{
+		epochLength: epochLength,
+		shareBps:    shareBps,
+	}
+}
+
+// lotteryEpochKey computes the identifier of a pool's lottery for an epoch
+func lotteryEpochKey(poolId [32]byte, epoch uint64) [32]byte {
+	var id [40]byte
+	copy(id[:32], poolId[:])
+	binary.BigEndian.PutUint64(id[32:], epoch)
+	return blake3.Sum256(id[:])
+}
+
+// lotteryFieldKey returns the storage key of an epoch field, or of one of
+// its list entries
+func lotteryFieldKey(epochKey [32]byte, field []byte, index ...uint64) common.Hash {
+	id := append(append([]byte(nil), epochKey[:]...), field...)
+	for _, i := range index {
+		id = append(id, encodeUint64(i)[24:]...)
+	}
+	return makeStorageKey(lotteryEpochPrefix, id)
+}
+
+// SetShare sets the slice of the swap fee paid into the pot in basis points
+func (l *Lottery) SetShare(stateDB StateDB, shareBps uint32) error {
+	if shareBps > MaxLotteryShareBps {
+		return ErrInvalidLotteryShare
+	}
+
+	var word common.Hash
+	word[0] = 1
+	binary.BigEndian.PutUint32(word[28:32], shareBps)
+	stateDB.SetState(lxLotteryAddr, makeStorageKey(lotterySharePrefix, nil), word)
+	return nil
+}
+
+// Share returns the slice of the swap fee paid into the pot in basis points
+func (l *Lottery) Share(stateDB StateDB) uint32 {
+	if word := stateDB.GetState(lxLotteryAddr, makeStorageKey(lotterySharePrefix, nil)); word[0] != 0 {
+		return binary.BigEndian.Uint32(word[28:32])
+	}
+	return l.shareBps
+}
+
+// SetEpochLength sets the epoch length in seconds. Changing it renumbers
+// epochs, so it is only meant to be set before any pool is enrolled.
+func (l *Lottery) SetEpochLength(epochLength uint64) error {
+	if epochLength == 0 {
+		return ErrInvalidParameter
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.epochLength = epochLength
+	return nil
+}
+
+// SetBeacon sets the randomness source for draws
+func (l *Lottery) SetBeacon(beacon LotteryBeacon) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.beacon = beacon
+}
+
+// SetEnabled enrolls a pool in the lottery or removes it. Removing a pool
+// stops new tickets; existing epochs can still be drawn and claimed.
+func (l *Lottery) SetEnabled(stateDB StateDB, poolId [32]byte, enabled bool) {
+	stateDB.SetState(lxLotteryAddr, makeStorageKey(lotteryPoolPrefix, poolId[:]), common.BytesToHash(encodeBool(enabled)))
+}
+
+// Enabled reports whether a pool is enrolled in the lottery
+func (l *Lottery) Enabled(stateDB StateDB, poolId [32]byte) bool {
+	return stateDB.GetState(lxLotteryAddr, makeStorageKey(lotteryPoolPrefix, poolId[:]))[31] != 0
+}
+
+// CurrentEpoch returns the epoch open for tickets at the current block
+func (l *Lottery) CurrentEpoch(stateDB StateDB) uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return stateDB.GetBlockTimestamp() / l.epochLength
+}
+
+// Epoch returns a pool's lottery for an epoch
+func (l *Lottery) Epoch(stateDB StateDB, poolId [32]byte, epoch uint64) *LotteryEpoch {
+	key := lotteryEpochKey(poolId, epoch)
+	get := func(field string) common.Hash {
+		return stateDB.GetState(lxLotteryAddr, lotteryFieldKey(key, []byte(field)))
+	}
+
+	flags := get("draw")
+	return &LotteryEpoch{
+		PoolID:  poolId,
+		Epoch:   epoch,
+		Tickets: l.tickets(stateDB, key),
+		Prizes:  l.prizes(stateDB, key),
+		Drawn:   flags[0] != 0,
+		Seed:    get("seed"),
+		Winner:  common.BytesToAddress(get("win").Bytes()),
+		Claimed: flags[1] != 0,
+	}
+}
+
+// tickets loads an epoch's ticket holders in swap order
+func (l *Lottery) tickets(stateDB StateDB, key [32]byte) []common.Address {
+	count := decodeUint64Word(stateDB.GetState(lxLotteryAddr, lotteryFieldKey(key, []byte("tix"))).Bytes())
+	tickets := make([]common.Address, 0, count)
+	for i := uint64(0); i < count; i++ {
+		tickets = append(tickets, common.BytesToAddress(stateDB.GetState(lxLotteryAddr, lotteryFieldKey(key, []byte("tix"), i)).Bytes()))
+	}
+	return tickets
+}
+
+// prizes loads an epoch's prize pot
+func (l *Lottery) prizes(stateDB StateDB, key [32]byte) map[Currency]*big.Int {
+	prizes := make(map[Currency]*big.Int)
+	count := decodeUint64Word(stateDB.GetState(lxLotteryAddr, lotteryFieldKey(key, []byte("cur"))).Bytes())
+	for i := uint64(0); i < count; i++ {
+		currency := Currency{Address: common.BytesToAddress(stateDB.GetState(lxLotteryAddr, lotteryFieldKey(key, []byte("cur"), i)).Bytes())}
+		prizes[currency] = stateDB.GetState(lxLotteryAddr, lotteryFieldKey(key, currency.Address.Bytes())).Big()
+	}
+	return prizes
+}
+
+// addPrize adds amount of currency to an epoch's pot
+func (l *Lottery) addPrize(stateDB StateDB, key [32]byte, currency Currency, amount *big.Int) {
+	amountKey := lotteryFieldKey(key, currency.Address.Bytes())
+	pot := stateDB.GetState(lxLotteryAddr, amountKey).Big()
+	if pot.Sign() == 0 {
+		countKey := lotteryFieldKey(key, []byte("cur"))
+		count := decodeUint64Word(stateDB.GetState(lxLotteryAddr, countKey).Bytes())
+		stateDB.SetState(lxLotteryAddr, lotteryFieldKey(key, []byte("cur"), count), common.BytesToHash(currency.Address.Bytes()))
+		stateDB.SetState(lxLotteryAddr, countKey, common.BytesToHash(encodeUint64(count+1)))
+	}
+	stateDB.SetState(lxLotteryAddr, amountKey, common.BigToHash(pot.Add(pot, amount)))
+}
+
+// clearPrizes empties an epoch's pot
+func (l *Lottery) clearPrizes(stateDB StateDB, key [32]byte) {
+	for currency := range l.prizes(stateDB, key) {
+		stateDB.SetState(lxLotteryAddr, lotteryFieldKey(key, currency.Address.Bytes()), common.Hash{})
+	}
+	stateDB.SetState(lxLotteryAddr, lotteryFieldKey(key, []byte("cur")), common.Hash{})
+}
+
+// flags loads an epoch's drawn and claimed flags
+func (l *Lottery) flags(stateDB StateDB, key [32]byte) (drawn, claimed bool) {
+	word := stateDB.GetState(lxLotteryAddr, lotteryFieldKey(key, []byte("draw")))
+	return word[0] != 0, word[1] != 0
+}
+
+// setFlags stores an epoch's drawn and claimed flags
+func (l *Lottery) setFlags(stateDB StateDB, key [32]byte, drawn, claimed bool) {
+	var word common.Hash
+	if drawn {
+		word[0] = 1
+	}
+	if claimed {
+		word[1] = 1
+	}
+	stateDB.SetState(lxLotteryAddr, lotteryFieldKey(key, []byte("draw")), word)
+}
+
+// Alpha returns the draw transcript of a pool's lottery for an epoch: a hash
+// of the pool, epoch and tickets
+func (l *Lottery) Alpha(stateDB StateDB, poolId [32]byte, epoch uint64) [32]byte {
+	tickets := l.tickets(stateDB, lotteryEpochKey(poolId, epoch))
+
+	h := blake3.New()
+	h.Write([]byte(lotteryAlphaDomain))
+	h.Write(poolId[:])
+	h.Write(binary.BigEndian.AppendUint64(nil, epoch))
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(tickets))))
+	for _, player := range tickets {
+		h.Write(player.Bytes())
+	}
+	var alpha [32]byte
+	h.Digest().Read(alpha[:])
+	return alpha
+}
+
+// enter gives player a ticket in the current epoch of an enrolled pool and
+// adds the lottery's slice of fee to the pot. It returns the amount added.
+func (l *Lottery) enter(stateDB StateDB, poolId [32]byte, player common.Address, currency Currency, fee *big.Int) *big.Int {
+	if !l.Enabled(stateDB, poolId) {
+		return big.NewInt(0)
+	}
+
+	cut := new(big.Int).Mul(fee, big.NewInt(int64(l.Share(stateDB))))
+	cut.Div(cut, big.NewInt(10_000))
+
+	key := lotteryEpochKey(poolId, l.CurrentEpoch(stateDB))
+	countKey := lotteryFieldKey(key, []byte("tix"))
+	count := decodeUint64Word(stateDB.GetState(lxLotteryAddr, countKey).Bytes())
+	stateDB.SetState(lxLotteryAddr, lotteryFieldKey(key, []byte("tix"), count), common.BytesToHash(player.Bytes()))
+	stateDB.SetState(lxLotteryAddr, countKey, common.BytesToHash(encodeUint64(count+1)))
+	if cut.Sign() > 0 {
+		l.addPrize(stateDB, key, currency, cut)
+	}
+	return cut
+}
+
+// fund adds amount to the pot of a current or future epoch
+func (l *Lottery) fund(stateDB StateDB, poolId [32]byte, epoch uint64, currency Currency, amount *big.Int) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+	if epoch < l.CurrentEpoch(stateDB) {
+		return ErrLotteryEpochEnded
+	}
+	l.addPrize(stateDB, lotteryEpochKey(poolId, epoch), currency, amount)
+	return nil
+}
+
+// draw picks the winner of an ended epoch
+func (l *Lottery) draw(stateDB StateDB, poolId [32]byte, epoch uint64, proof []byte) (common.Address, error) {
+	if epoch >= l.CurrentEpoch(stateDB) {
+		return common.Address{}, ErrLotteryEpochOpen
+	}
+	key := lotteryEpochKey(poolId, epoch)
+	if drawn, _ := l.flags(stateDB, key); drawn {
+		return common.Address{}, ErrLotteryDrawn
+	}
+
+	tickets := l.tickets(stateDB, key)
+	if len(tickets) == 0 {
+		// Roll the pot into the next epoch that can still be won
+		next := epoch + 1
+		for {
+			if drawn, _ := l.flags(stateDB, lotteryEpochKey(poolId, next)); !drawn {
+				break
+			}
+			next++
+		}
+		nextKey := lotteryEpochKey(poolId, next)
+		for c, v := range l.prizes(stateDB, key) {
+			l.addPrize(stateDB, nextKey, c, v)
+		}
+		l.clearPrizes(stateDB, key)
+		l.setFlags(stateDB, key, true, false)
+		return common.Address{}, nil
+	}
+
+	l.mu.RLock()
+	beacon := l.beacon
+	l.mu.RUnlock()
+	if beacon == nil {
+		return common.Address{}, ErrNoLotteryBeacon
+	}
+	seed, err := beacon.Seed(l.Alpha(stateDB, poolId, epoch), proof)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	// The modulo bias of a 256-bit seed is negligible for any ticket count
+	index := new(big.Int).SetBytes(seed[:])
+	index.Mod(index, big.NewInt(int64(len(tickets))))
+	winner := tickets[index.Int64()]
+
+	l.setFlags(stateDB, key, true, false)
+	stateDB.SetState(lxLotteryAddr, lotteryFieldKey(key, []byte("seed")), seed)
+	stateDB.SetState(lxLotteryAddr, lotteryFieldKey(key, []byte("win")), common.BytesToHash(winner.Bytes()))
+	return winner, nil
+}
+
+// claim marks a drawn epoch's pot as paid to its winner and returns it
+func (l *Lottery) claim(stateDB StateDB, poolId [32]byte, epoch uint64, caller common.Address) (map[Currency]*big.Int, error) {
+	key := lotteryEpochKey(poolId, epoch)
+	drawn, claimed := l.flags(stateDB, key)
+	if !drawn {
+		return nil, ErrLotteryNotDrawn
+	}
+	winner := common.BytesToAddress(stateDB.GetState(lxLotteryAddr, lotteryFieldKey(key, []byte("win"))).Bytes())
+	if winner == (common.Address{}) || winner != caller {
+		return nil, ErrUnauthorized
+	}
+	if claimed {
+		return nil, ErrLotteryPrizeClaimed
+	}
+
+	l.setFlags(stateDB, key, true, true)
+	return l.prizes(stateDB, key), nil
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// Lottery returns the pool manager's swap lottery
+func (pm *PoolManager) Lottery() *Lottery {
+	return pm.lottery
+}
+
+// SetLotteryBeacon sets the randomness source for lottery draws
+func (pm *PoolManager) SetLotteryBeacon(beacon LotteryBeacon) {
+	pm.lottery.SetBeacon(beacon)
+}
+
+// SetLotteryEnabled enrolls a pool in the lottery or removes it (protocol
+// fee controller only)
+func (pm *PoolManager) SetLotteryEnabled(stateDB StateDB, caller common.Address, poolId [32]byte, enabled bool) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	pm.lottery.SetEnabled(stateDB, poolId, enabled)
+	return nil
+}
+
+// SetLotteryShare sets the slice of swap fees paid into lottery pots
+// (protocol fee controller only)
+func (pm *PoolManager) SetLotteryShare(stateDB StateDB, caller common.Address, shareBps uint32) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	return pm.lottery.SetShare(stateDB, shareBps)
+}
+
+// enterLottery runs in the afterSwap phase: the locker gets a ticket and the
+// lottery's slice of the fee goes into the pot
+func (pm *PoolManager) enterLottery(stateDB StateDB, locker common.Address, key PoolKey, params SwapParams, delta BalanceDelta) {
+	currencyIn, amountIn := key.Currency1, delta.Amount1
+	if params.ZeroForOne {
+		currencyIn, amountIn = key.Currency0, delta.Amount0
+	}
+
+	fee := pm.calculateSwapFee(new(big.Int).Abs(amountIn), big.NewInt(0), key.Fee)
+	pm.lottery.enter(stateDB, key.ID(), locker, currencyIn, fee)
+}
+
+// FundLottery adds amount of currency from the current locker to the pot of
+// a pool's current or future epoch. The locker owes the amount in its delta.
+func (pm *PoolManager) FundLottery(stateDB StateDB, poolId [32]byte, epoch uint64, currency Currency, amount *big.Int) error {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return ErrUnauthorized
+	}
+	if err := pm.lottery.fund(stateDB, poolId, epoch, currency, amount); err != nil {
+		return err
+	}
+
+	// Positive delta: the locker owes the pool
+	pm.updateDelta(locker, currency, amount)
+	return nil
+}
+
+// DrawLottery picks the winner of a pool's ended epoch. It returns the zero
+// address when the epoch had no tickets and its pot rolled over.
+func (pm *PoolManager) DrawLottery(stateDB StateDB, poolId [32]byte, epoch uint64, proof []byte) (common.Address, error) {
+	return pm.lottery.draw(stateDB, poolId, epoch, proof)
+}
+
+// ClaimLotteryPrize credits a drawn epoch's pot to its winner, who must be
+// the current locker
+func (pm *PoolManager) ClaimLotteryPrize(stateDB StateDB, poolId [32]byte, epoch uint64) (map[Currency]*big.Int, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+
+	prizes, err := pm.lottery.claim(stateDB, poolId, epoch, locker)
+	if err != nil {
+		return nil, err
+	}
+
+	// Negative delta: the pool owes the locker
+	for currency, amount := range prizes {
+		pm.updateDelta(locker, currency, new(big.Int).Neg(amount))
+	}
+	return prizes, nil
+}
+
+// =========================================================================
+// LXLottery precompile
+// =========================================================================
+
+// Method selectors for LXLottery
+const (
+	SelectorFundLottery     uint32 = 0x01000000 // fund(bytes32,uint64,Currency,uint256)
+	SelectorDrawLottery     uint32 = 0x02000000 // draw(bytes32,uint64,bytes)
+	SelectorClaimLottery    uint32 = 0x03000000 // claim(bytes32,uint64,Currency[])
+	SelectorGetLotteryEpoch uint32 = 0x04000000 // getEpoch(bytes32,uint64,Currency)
+	SelectorCurrentEpoch    uint32 = 0x05000000 // currentEpoch()
+)
+
+// LotteryContract implements the LXLottery precompile over the pool manager
+// shared with LXPool
+type LotteryContract struct {
+	poolManager *PoolManager
+}
+
+// Run executes the precompile
+func (c *LotteryContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+
+	selector := binary.BigEndian.Uint32(input[:4])
+	data := input[4:]
+
+	gas := c.RequiredGas(input)
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+	remainingGas = suppliedGas - gas
+
+	lottery := c.poolManager.lottery
+	stateAdapter := newPoolStateAdapter(accessibleState)
+
+	switch selector {
+	case SelectorCurrentEpoch:
+		return encodeUint64(lottery.CurrentEpoch(stateAdapter)), remainingGas, nil
+
+	case SelectorGetLotteryEpoch:
+		// poolId (32) + epoch (32) + currency (32)
+		if len(data) < 96 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		var poolId [32]byte
+		copy(poolId[:], data[:32])
+		currency := Currency{Address: common.BytesToAddress(data[76:96])}
+		return EncodeLotteryEpoch(lottery.Epoch(stateAdapter, poolId, decodeUint64Word(data[32:64])), currency), remainingGas, nil
+
+	case SelectorFundLottery, SelectorDrawLottery, SelectorClaimLottery:
+	default:
+		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	}
+
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	// poolId (32) + epoch (32) lead every write
+	if len(data) < 64 {
+		return nil, remainingGas, fmt.Errorf("input too short")
+	}
+	var poolId [32]byte
+	copy(poolId[:], data[:32])
+	epoch := decodeUint64Word(data[32:64])
+
+	switch selector {
+	case SelectorFundLottery:
+		// currency (32) + amount (32)
+		if len(data) < 128 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		currency := Currency{Address: common.BytesToAddress(data[76:96])}
+		if err := c.poolManager.FundLottery(stateAdapter, poolId, epoch, currency, new(big.Int).SetBytes(data[96:128])); err != nil {
+			return nil, remainingGas, err
+		}
+		return nil, remainingGas, nil
+
+	case SelectorDrawLottery:
+		// proof is the remaining input
+		winner, err := c.poolManager.DrawLottery(stateAdapter, poolId, epoch, data[64:])
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return common.LeftPadBytes(winner.Bytes(), 32), remainingGas, nil
+
+	default: // SelectorClaimLottery
+		// currencies (32 each) select the amounts to return
+		prizes, err := c.poolManager.ClaimLotteryPrize(stateAdapter, poolId, epoch)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		currencies := data[64:]
+		result := make([]byte, len(currencies)/32*32)
+		for i := 0; i+32 <= len(currencies); i += 32 {
+			currency := Currency{Address: common.BytesToAddress(currencies[i+12 : i+32])}
+			if amount, ok := prizes[currency]; ok {
+				amount.FillBytes(result[i : i+32])
+			}
+		}
+		return result, remainingGas, nil
+	}
+}
+
+// RequiredGas returns the gas required for the precompile input
+func (c *LotteryContract) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return GasPoolLookup
+	}
+
+	switch binary.BigEndian.Uint32(input[:4]) {
+	case SelectorFundLottery:
+		return GasLotteryFund
+	case SelectorDrawLottery:
+		return GasLotteryDraw
+	case SelectorClaimLottery:
+		return GasLotteryClaim
+	default:
+		return GasPoolLookup
+	}
+}
+
+// EncodeLotteryEpoch encodes an epoch: epoch (32) + tickets (32) + drawn (32) +
+// winner (32) + claimed (32) + seed (32) + prize in currency (32)
+func EncodeLotteryEpoch(ep *LotteryEpoch, currency Currency) []byte {
+	result := make([]byte, 224)
+	binary.BigEndian.PutUint64(result[24:32], ep.Epoch)
+	binary.BigEndian.PutUint64(result[56:64], uint64(len(ep.Tickets)))
+	if ep.Drawn {
+		result[95] = 1
+	}
+	copy(result[108:128], ep.Winner.Bytes())
+	if ep.Claimed {
+		result[159] = 1
+	}
+	copy(result[160:192], ep.Seed[:])
+	if prize, ok := ep.Prizes[currency]; ok {
+		prize.FillBytes(result[192:224])
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var testSecondTrader = common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+// fixedBeacon returns the same seed for every draw
+type fixedBeacon struct {
+	seed  [32]byte
+	alpha [32]byte // Last alpha seen
+}
+
+func (b *fixedBeacon) Seed(alpha [32]byte, proof []byte) ([32]byte, error) {
+	b.alpha = alpha
+	return b.seed, nil
+}
+
+func TestLotteryDrawAndClaim(t *testing.T) {
+	pm, stateDB, key := setupReferralSwap(t)
+	stateDB.SetBlockTimestamp(10 * DefaultLotteryEpoch)
+	poolId := key.ID()
+
+	params := SwapParams{
+		ZeroForOne:        true,
+		AmountSpecified:   big.NewInt(1_000_000),
+		SqrtPriceLimitX96: MinSqrtRatio,
+	}
+
+	// Pools not enrolled get no tickets
+	if _, err := pm.Swap(stateDB, key, params, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	if ep := pm.Lottery().Epoch(stateDB, poolId, 10); len(ep.Tickets) != 0 {
+		t.Errorf("Expected no tickets before enrollment, got %d", len(ep.Tickets))
+	}
+
+	controller := common.HexToAddress("0x9999999999999999999999999999999999999999")
+	pm.setProtocolFeeController(stateDB, controller)
+	if err := pm.SetLotteryEnabled(stateDB, testTrader, poolId, true); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if err := pm.SetLotteryEnabled(stateDB, controller, poolId, true); err != nil {
+		t.Fatalf("SetLotteryEnabled failed: %v", err)
+	}
+
+	if _, err := pm.Swap(stateDB, key, params, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	openLock(pm, testSecondTrader)
+	if _, err := pm.Swap(stateDB, key, params, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+
+	// Fee = 1_000_000 * 0.30% = 3000; default lottery share 5% = 150 per swap
+	ep := pm.Lottery().Epoch(stateDB, poolId, 10)
+	if len(ep.Tickets) != 2 || ep.Tickets[0] != testTrader || ep.Tickets[1] != testSecondTrader {
+		t.Errorf("Expected tickets for both traders, got %v", ep.Tickets)
+	}
+	if prize := ep.Prizes[key.Currency0]; prize == nil || prize.Cmp(big.NewInt(300)) != 0 {
+		t.Errorf("Expected prize pot 300, got %v", prize)
+	}
+
+	if _, err := pm.DrawLottery(stateDB, poolId, 10, nil); err != ErrLotteryEpochOpen {
+		t.Errorf("Expected ErrLotteryEpochOpen, got %v", err)
+	}
+	stateDB.timestamp += DefaultLotteryEpoch
+	if _, err := pm.DrawLottery(stateDB, poolId, 10, nil); err != ErrNoLotteryBeacon {
+		t.Errorf("Expected ErrNoLotteryBeacon, got %v", err)
+	}
+
+	// An odd seed picks the second ticket
+	beacon := &fixedBeacon{seed: [32]byte{31: 0x03}}
+	pm.SetLotteryBeacon(beacon)
+	winner, err := pm.DrawLottery(stateDB, poolId, 10, nil)
+	if err != nil {
+		t.Fatalf("DrawLottery failed: %v", err)
+	}
+	if winner != testSecondTrader {
+		t.Errorf("Expected second trader to win, got %s", winner.Hex())
+	}
+	if beacon.alpha != pm.Lottery().Alpha(stateDB, poolId, 10) {
+		t.Error("Expected the beacon to be seeded with the epoch transcript")
+	}
+	if _, err := pm.DrawLottery(stateDB, poolId, 10, nil); err != ErrLotteryDrawn {
+		t.Errorf("Expected ErrLotteryDrawn, got %v", err)
+	}
+
+	// Only the winner claims, once, into its delta
+	pm.lockers = pm.lockers[:len(pm.lockers)-1]
+	if _, err := pm.ClaimLotteryPrize(stateDB, poolId, 10); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized for loser, got %v", err)
+	}
+	openLock(pm, testSecondTrader)
+	before := pm.GetDelta(testSecondTrader, key.Currency0)
+	if _, err := pm.ClaimLotteryPrize(stateDB, poolId, 10); err != nil {
+		t.Fatalf("ClaimLotteryPrize failed: %v", err)
+	}
+	if change := new(big.Int).Sub(pm.GetDelta(testSecondTrader, key.Currency0), before); change.Cmp(big.NewInt(-300)) != 0 {
+		t.Errorf("Expected delta change -300, got %s", change)
+	}
+	if _, err := pm.ClaimLotteryPrize(stateDB, poolId, 10); err != ErrLotteryPrizeClaimed {
+		t.Errorf("Expected ErrLotteryPrizeClaimed, got %v", err)
+	}
+
+	// The drawn epoch lives in LXLottery storage, not in the pool manager
+	ep = NewLottery(DefaultLotteryEpoch, DefaultLotteryShareBps).Epoch(stateDB, poolId, 10)
+	if !ep.Drawn || !ep.Claimed || ep.Winner != testSecondTrader || len(ep.Tickets) != 2 {
+		t.Errorf("Expected drawn and claimed epoch in state, got %+v", ep)
+	}
+}
+
+func TestLotteryRollover(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	stateDB.SetBlockTimestamp(5 * DefaultLotteryEpoch)
+	poolId := newTestPoolKey().ID()
+	token := newTestPoolKey().Currency1
+
+	if err := pm.FundLottery(stateDB, poolId, 5, token, big.NewInt(1000)); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized outside a lock, got %v", err)
+	}
+	openLock(pm, testTrader)
+	if err := pm.FundLottery(stateDB, poolId, 4, token, big.NewInt(1000)); err != ErrLotteryEpochEnded {
+		t.Errorf("Expected ErrLotteryEpochEnded, got %v", err)
+	}
+	if err := pm.FundLottery(stateDB, poolId, 5, token, big.NewInt(1000)); err != nil {
+		t.Fatalf("FundLottery failed: %v", err)
+	}
+	if delta := pm.GetDelta(testTrader, token); delta.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected sponsor delta 1000, got %s", delta)
+	}
+
+	// An epoch without tickets rolls its pot into the next one
+	stateDB.timestamp += DefaultLotteryEpoch
+	winner, err := pm.DrawLottery(stateDB, poolId, 5, nil)
+	if err != nil {
+		t.Fatalf("DrawLottery failed: %v", err)
+	}
+	if winner != (common.Address{}) {
+		t.Errorf("Expected no winner, got %s", winner.Hex())
+	}
+	if prize := pm.Lottery().Epoch(stateDB, poolId, 5).Prizes[token]; prize != nil {
+		t.Errorf("Expected empty pot after rollover, got %s", prize)
+	}
+	if prize := pm.Lottery().Epoch(stateDB, poolId, 6).Prizes[token]; prize == nil || prize.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected rolled pot 1000, got %v", prize)
+	}
+	if _, err := pm.ClaimLotteryPrize(stateDB, poolId, 5); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized for an epoch without winner, got %v", err)
+	}
+}
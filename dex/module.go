@@ -17,6 +17,18 @@ import (
 
 var _ contract.Configurator = (*configurator)(nil)
 var _ contract.StatefulPrecompiledContract = (*DEXContract)(nil)
+var _ contract.StatefulPrecompiledContract = (*EscrowContract)(nil)
+var _ contract.Configurator = (*escrowConfigurator)(nil)
+var _ contract.StatefulPrecompiledContract = (*LotteryContract)(nil)
+var _ contract.Configurator = (*lotteryConfigurator)(nil)
+var _ contract.StatefulPrecompiledContract = (*IdentityContract)(nil)
+var _ contract.Configurator = (*identityConfigurator)(nil)
+var _ contract.StatefulPrecompiledContract = (*BookContract)(nil)
+var _ contract.Configurator = (*bookConfigurator)(nil)
+var _ contract.StatefulPrecompiledContract = (*OracleContract)(nil)
+var _ contract.Configurator = (*oracleConfigurator)(nil)
+var _ contract.StatefulPrecompiledContract = (*FeedContract)(nil)
+var _ contract.Configurator = (*feedConfigurator)(nil)
 
 // ConfigKey is the key used in json config files to specify this precompile config.
 const ConfigKey = "dexConfig"
@@ -32,11 +44,15 @@ var (
 	lxFlashAddr  = common.HexToAddress(LXFlashAddress)  // LP-9014 LXFlash
 
 	// Trading & DeFi Extensions
-	lxBookAddr   = common.HexToAddress(LXBookAddress)   // LP-9020 LXBook (CLOB matcher)
-	lxVaultAddr  = common.HexToAddress(LXVaultAddress)  // LP-9030 LXVault
-	lxFeedAddr   = common.HexToAddress(LXFeedAddress)   // LP-9040 LXFeed
-	lxLendAddr   = common.HexToAddress(LXLendAddress)   // LP-9050 LXLend (lending pool)
-	lxLiquidAddr = common.HexToAddress(LXLiquidAddress) // LP-9060 LXLiquid (self-repaying loans)
+	lxBookAddr     = common.HexToAddress(LXBookAddress)     // LP-9020 LXBook (CLOB matcher)
+	lxVaultAddr    = common.HexToAddress(LXVaultAddress)    // LP-9030 LXVault
+	lxFeedAddr     = common.HexToAddress(LXFeedAddress)     // LP-9040 LXFeed
+	lxLendAddr     = common.HexToAddress(LXLendAddress)     // LP-9050 LXLend (lending pool)
+	lxLiquidAddr   = common.HexToAddress(LXLiquidAddress)   // LP-9060 LXLiquid (self-repaying loans)
+	lxEscrowAddr   = common.HexToAddress(LXEscrowAddress)   // LP-9090 LXEscrow (vesting escrow)
+	lxLotteryAddr  = common.HexToAddress(LXLotteryAddress)  // LP-9091 LXLottery (swap incentive lottery)
+	lxIdentityAddr = common.HexToAddress(LXIdentityAddress) // LP-9092 LXIdentity (compliance registry)
+	lxBondAddr     = common.HexToAddress(LXBondAddress)     // LP-9093 LXBond (protocol-owned liquidity)
 )
 
 // DEXPrecompile is the singleton instance
@@ -59,18 +75,210 @@ const (
 	SelectorModifyLiquidity uint32 = 0x03000000 // modifyLiquidity(PoolKey,ModifyLiqParams,bytes)
 	SelectorDonate          uint32 = 0x04000000 // donate(PoolKey,uint256,uint256)
 	SelectorTake            uint32 = 0x05000000 // take(Currency,address,uint256)
-	SelectorSettle          uint32 = 0x06000000 // settle()
+	SelectorSettle          uint32 = 0x06000000 // settle(Currency,uint256), payable
 	SelectorLock            uint32 = 0x07000000 // lock(bytes)
 	SelectorGetPool         uint32 = 0x08000000 // getPool(PoolKey)
 	SelectorGetPosition     uint32 = 0x09000000 // getPosition(PoolKey,address,int24,int24,bytes32)
+
+	// Referral fees
+	SelectorSwapWithReferral uint32 = 0x0A000000 // swapWithReferral(PoolKey,SwapParams,address,bytes)
+	SelectorClaimReferral    uint32 = 0x0B000000 // claimReferral(Currency,uint256)
+	SelectorWithdrawReferral uint32 = 0x0C000000 // withdrawReferral(Currency,address,uint256)
+	SelectorGetReferrerStats uint32 = 0x0D000000 // getReferrerStats(address,Currency)
+
+	// Non-standard tokens
+	SelectorInitializeWithTokenFlags uint32 = 0x0E000000 // initializeWithTokenFlags(PoolKey,uint160,uint8,bytes)
+
+	// Fee tier governance
+	SelectorEnableFeeTier      uint32 = 0x0F000000 // enableFeeTier(uint24,int24)
+	SelectorDisableFeeTier     uint32 = 0x10000000 // disableFeeTier(uint24)
+	SelectorFeeTierTickSpacing uint32 = 0x11000000 // feeTierTickSpacing(uint24)
+
+	// Aggregate queries (see multicall.go)
+	SelectorAggregate uint32 = 0x12000000 // aggregate((address,bytes)[])
+
+	// TWAP oracle (see observations.go)
+	SelectorObserve                        uint32 = 0x13000000 // observe(PoolKey,uint32[])
+	SelectorObservations                   uint32 = 0x14000000 // observations(PoolKey,uint16)
+	SelectorIncreaseObservationCardinality uint32 = 0x15000000 // increaseObservationCardinalityNext(PoolKey,uint16)
+
+	// Protocol fees (see protocol_fees.go)
+	SelectorSetProtocolFee           uint32 = 0x16000000 // setProtocolFee(PoolKey,uint32)
+	SelectorCollectProtocolFees      uint32 = 0x17000000 // collectProtocolFees(PoolKey,address,uint256,uint256)
+	SelectorSetProtocolFeeController uint32 = 0x18000000 // setProtocolFeeController(address)
+
+	// Currency reserves (see reserves.go)
+	SelectorSync       uint32 = 0x19000000 // sync(Currency)
+	SelectorReservesOf uint32 = 0x1A000000 // reservesOf(Currency)
+
+	// ERC-6909 claims (see claims.go)
+	SelectorMint          uint32 = 0x1B000000 // mint(address,Currency,uint256)
+	SelectorBurn          uint32 = 0x1C000000 // burn(Currency,uint256)
+	SelectorTransferClaim uint32 = 0x1D000000 // transferClaim(address,Currency,uint256)
+	SelectorBalanceOf     uint32 = 0x1E000000 // balanceOf(address,Currency)
+
+	// Position fees (see positions.go)
+	SelectorCollect uint32 = 0x1F000000 // collect(PoolKey,int24,int24,bytes32,uint256,uint256)
+
+	// Liquidity mining gauges (see gauges.go)
+	SelectorFundGauge           uint32 = 0x20000000 // fundGauge(PoolKey,Currency,uint256,uint256)
+	SelectorClaimGaugeRewards   uint32 = 0x21000000 // claimGaugeRewards(PoolKey,Currency,bytes32)
+	SelectorPendingGaugeRewards uint32 = 0x22000000 // pendingGaugeRewards(PoolKey,Currency,bytes32)
 )
 
+// EscrowConfigKey is the json config key of the LXEscrow precompile
+const EscrowConfigKey = "dexEscrowConfig"
+
+// EscrowPrecompile is the LXEscrow instance, sharing LXPool's pool manager
+var EscrowPrecompile = &EscrowContract{
+	poolManager: DEXPrecompile.poolManager,
+}
+
+// EscrowModule is the vesting escrow precompile module (LXEscrow at LP-9090)
+var EscrowModule = modules.Module{
+	ConfigKey:    EscrowConfigKey,
+	Address:      lxEscrowAddr,
+	Contract:     EscrowPrecompile,
+	Configurator: &escrowConfigurator{},
+}
+
+// LotteryConfigKey is the json config key of the LXLottery precompile
+const LotteryConfigKey = "dexLotteryConfig"
+
+// LotteryPrecompile is the LXLottery instance, sharing LXPool's pool manager
+var LotteryPrecompile = &LotteryContract{
+	poolManager: DEXPrecompile.poolManager,
+}
+
+// LotteryModule is the swap lottery precompile module (LXLottery at LP-9091)
+var LotteryModule = modules.Module{
+	ConfigKey:    LotteryConfigKey,
+	Address:      lxLotteryAddr,
+	Contract:     LotteryPrecompile,
+	Configurator: &lotteryConfigurator{},
+}
+
+// IdentityConfigKey is the json config key of the LXIdentity precompile
+const IdentityConfigKey = "dexIdentityConfig"
+
+// IdentityPrecompile is the LXIdentity instance, sharing LXPool's pool manager
+var IdentityPrecompile = &IdentityContract{
+	poolManager: DEXPrecompile.poolManager,
+}
+
+// IdentityModule is the compliance registry precompile module (LXIdentity at LP-9092)
+var IdentityModule = modules.Module{
+	ConfigKey:    IdentityConfigKey,
+	Address:      lxIdentityAddr,
+	Contract:     IdentityPrecompile,
+	Configurator: &identityConfigurator{},
+}
+
+// BondConfigKey is the json config key of the LXBond precompile
+const BondConfigKey = "dexBondConfig"
+
+// BondPrecompile is the LXBond instance, sharing LXPool's pool manager
+var BondPrecompile = &BondContract{
+	poolManager: DEXPrecompile.poolManager,
+}
+
+// BondModule is the protocol-owned liquidity precompile module (LXBond at LP-9093)
+var BondModule = modules.Module{
+	ConfigKey:    BondConfigKey,
+	Address:      lxBondAddr,
+	Contract:     BondPrecompile,
+	Configurator: &bondConfigurator{},
+}
+
+// BookConfigKey is the json config key of the LXBook precompile
+const BookConfigKey = "dexBookConfig"
+
+// BookPrecompile is the LXBook instance, sharing LXPool's pool manager
+var BookPrecompile = &BookContract{
+	poolManager: DEXPrecompile.poolManager,
+}
+
+// BookModule is the order book precompile module (LXBook at LP-9020)
+var BookModule = modules.Module{
+	ConfigKey:    BookConfigKey,
+	Address:      lxBookAddr,
+	Contract:     BookPrecompile,
+	Configurator: &bookConfigurator{},
+}
+
+// OracleConfigKey is the json config key of the LXOracle precompile
+const OracleConfigKey = "dexOracleConfig"
+
+// OraclePrecompile is the LXOracle instance, sharing LXPool's pool manager
+var OraclePrecompile = &OracleContract{
+	poolManager: DEXPrecompile.poolManager,
+}
+
+// OracleModule is the price aggregation precompile module (LXOracle at LP-9011)
+var OracleModule = modules.Module{
+	ConfigKey:    OracleConfigKey,
+	Address:      lxOracleAddr,
+	Contract:     OraclePrecompile,
+	Configurator: &oracleConfigurator{},
+}
+
+// FeedConfigKey is the json config key of the LXFeed precompile
+const FeedConfigKey = "dexFeedConfig"
+
+// FeedPrecompile is the LXFeed instance, sharing LXPool's pool manager
+var FeedPrecompile = &FeedContract{
+	poolManager: DEXPrecompile.poolManager,
+}
+
+// FeedModule is the mark and index price precompile module (LXFeed at LP-9040)
+var FeedModule = modules.Module{
+	ConfigKey:    FeedConfigKey,
+	Address:      lxFeedAddr,
+	Contract:     FeedPrecompile,
+	Configurator: &feedConfigurator{},
+}
+
 type configurator struct{}
 
+type escrowConfigurator struct{}
+
+type lotteryConfigurator struct{}
+
+type identityConfigurator struct{}
+
+type bondConfigurator struct{}
+
+type bookConfigurator struct{}
+
+type oracleConfigurator struct{}
+
+type feedConfigurator struct{}
+
 func init() {
 	if err := modules.RegisterModule(Module); err != nil {
 		panic(err)
 	}
+	if err := modules.RegisterModule(EscrowModule); err != nil {
+		panic(err)
+	}
+	if err := modules.RegisterModule(LotteryModule); err != nil {
+		panic(err)
+	}
+	if err := modules.RegisterModule(IdentityModule); err != nil {
+		panic(err)
+	}
+	if err := modules.RegisterModule(BondModule); err != nil {
+		panic(err)
+	}
+	if err := modules.RegisterModule(BookModule); err != nil {
+		panic(err)
+	}
+	if err := modules.RegisterModule(OracleModule); err != nil {
+		panic(err)
+	}
+	if err := modules.RegisterModule(FeedModule); err != nil {
+		panic(err)
+	}
 }
 
 func (*configurator) MakeConfig() precompileconfig.Config {
@@ -87,10 +295,25 @@ func (*configurator) Configure(
 	if !ok {
 		return fmt.Errorf("expected config type %T, got %T: %v", &Config{}, cfg, cfg)
 	}
+	stateAdapter := &poolStateAdapter{stateDB: state, block: blockContext}
 
 	// Set protocol fee controller if specified
 	if config.ProtocolFeeController != (common.Address{}) {
-		DEXPrecompile.poolManager.protocolFeeController = config.ProtocolFeeController
+		DEXPrecompile.poolManager.setProtocolFeeController(stateAdapter, config.ProtocolFeeController)
+	}
+
+	// Set default referral share if specified
+	if config.ReferralShareBps != 0 {
+		if err := DEXPrecompile.poolManager.referrals.SetShare(stateAdapter, config.ReferralShareBps); err != nil {
+			return err
+		}
+	}
+
+	// Enable additional fee tiers
+	for _, tier := range config.FeeTiers {
+		if err := DEXPrecompile.poolManager.feeTiers.Enable(stateAdapter, tier.Fee, tier.TickSpacing); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -103,6 +326,8 @@ type Config struct {
 	MaxPools                 uint64         `json:"maxPools,omitempty"`
 	EnableFlashLoans         bool           `json:"enableFlashLoans,omitempty"`
 	EnableHooks              bool           `json:"enableHooks,omitempty"`
+	ReferralShareBps         uint32         `json:"referralShareBps,omitempty"`
+	FeeTiers                 []FeeTier      `json:"feeTiers,omitempty"`
 }
 
 func (c *Config) Key() string {
@@ -122,64 +347,1147 @@ func (c *Config) Equal(cfg precompileconfig.Config) bool {
 	if !ok {
 		return false
 	}
+	if len(c.FeeTiers) != len(other.FeeTiers) {
+		return false
+	}
+	for i := range c.FeeTiers {
+		if c.FeeTiers[i] != other.FeeTiers[i] {
+			return false
+		}
+	}
 	return c.Upgrade.Equal(&other.Upgrade) &&
 		c.ProtocolFeeController == other.ProtocolFeeController &&
 		c.MaxPools == other.MaxPools &&
 		c.EnableFlashLoans == other.EnableFlashLoans &&
-		c.EnableHooks == other.EnableHooks
+		c.EnableHooks == other.EnableHooks &&
+		c.ReferralShareBps == other.ReferralShareBps
 }
 
 func (c *Config) Verify(chainConfig precompileconfig.ChainConfig) error {
+	if c.ReferralShareBps > MaxReferralShareBps {
+		return ErrInvalidReferralShare
+	}
+	for _, tier := range c.FeeTiers {
+		if err := tier.validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// DEXContract implements the DEX precompile
-type DEXContract struct {
-	poolManager *PoolManager
+func (*escrowConfigurator) MakeConfig() precompileconfig.Config {
+	return new(EscrowConfig)
+}
+
+func (*escrowConfigurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	// Locks live in the pool manager shared with LXPool
+	return nil
+}
+
+// EscrowConfig implements the precompileconfig.Config interface for LXEscrow
+type EscrowConfig struct {
+	precompileconfig.Upgrade // Embedded for flat JSON structure
+}
+
+func (c *EscrowConfig) Key() string {
+	return EscrowConfigKey
+}
+
+func (c *EscrowConfig) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *EscrowConfig) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *EscrowConfig) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*EscrowConfig)
+	if !ok {
+		return false
+	}
+	return c.Upgrade.Equal(&other.Upgrade)
+}
+
+func (c *EscrowConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	return nil
+}
+
+func (*lotteryConfigurator) MakeConfig() precompileconfig.Config {
+	return new(LotteryConfig)
+}
+
+func (*lotteryConfigurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	config, ok := cfg.(*LotteryConfig)
+	if !ok {
+		return fmt.Errorf("expected config type %T, got %T: %v", &LotteryConfig{}, cfg, cfg)
+	}
+
+	// Lotteries live in the pool manager shared with LXPool. The randomness
+	// beacon is wired by the host through PoolManager.SetLotteryBeacon.
+	lottery := LotteryPrecompile.poolManager.lottery
+	if config.EpochLength != 0 {
+		if err := lottery.SetEpochLength(config.EpochLength); err != nil {
+			return err
+		}
+	}
+	if config.ShareBps != 0 {
+		if err := lottery.SetShare(&poolStateAdapter{stateDB: state, block: blockContext}, config.ShareBps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LotteryConfig implements the precompileconfig.Config interface for LXLottery
+type LotteryConfig struct {
+	precompileconfig.Upgrade        // Embedded for flat JSON structure
+	EpochLength              uint64 `json:"epochLength,omitempty"`
+	ShareBps                 uint32 `json:"shareBps,omitempty"`
+}
+
+func (c *LotteryConfig) Key() string {
+	return LotteryConfigKey
+}
+
+func (c *LotteryConfig) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *LotteryConfig) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *LotteryConfig) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*LotteryConfig)
+	if !ok {
+		return false
+	}
+	return c.Upgrade.Equal(&other.Upgrade) &&
+		c.EpochLength == other.EpochLength &&
+		c.ShareBps == other.ShareBps
+}
+
+func (c *LotteryConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	if c.ShareBps > MaxLotteryShareBps {
+		return ErrInvalidLotteryShare
+	}
+	return nil
+}
+
+func (*identityConfigurator) MakeConfig() precompileconfig.Config {
+	return new(IdentityConfig)
+}
+
+func (*identityConfigurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	config, ok := cfg.(*IdentityConfig)
+	if !ok {
+		return fmt.Errorf("expected config type %T, got %T: %v", &IdentityConfig{}, cfg, cfg)
+	}
+
+	// The registry lives in the pool manager shared with LXPool, behind the
+	// built-in compliance hook
+	registry := IdentityPrecompile.poolManager.identity
+	stateAdapter := &poolStateAdapter{stateDB: state, block: blockContext}
+	registry.SetAdmin(stateAdapter, config.Admin)
+	for _, issuer := range config.Issuers {
+		if err := registry.AddIssuer(stateAdapter, config.Admin, issuer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IdentityConfig implements the precompileconfig.Config interface for LXIdentity
+type IdentityConfig struct {
+	precompileconfig.Upgrade                  // Embedded for flat JSON structure
+	Admin                    common.Address   `json:"admin"`
+	Issuers                  []common.Address `json:"issuers,omitempty"`
+}
+
+func (c *IdentityConfig) Key() string {
+	return IdentityConfigKey
+}
+
+func (c *IdentityConfig) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *IdentityConfig) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *IdentityConfig) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*IdentityConfig)
+	if !ok {
+		return false
+	}
+	if !c.Upgrade.Equal(&other.Upgrade) || c.Admin != other.Admin || len(c.Issuers) != len(other.Issuers) {
+		return false
+	}
+	for i := range c.Issuers {
+		if c.Issuers[i] != other.Issuers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *IdentityConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	if c.Admin == (common.Address{}) {
+		return ErrInvalidIssuer
+	}
+	for _, issuer := range c.Issuers {
+		if issuer == (common.Address{}) {
+			return ErrInvalidIssuer
+		}
+	}
+	return nil
+}
+
+func (*bondConfigurator) MakeConfig() precompileconfig.Config {
+	return new(BondConfig)
+}
+
+func (*bondConfigurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	// Bond markets live in the pool manager shared with LXPool. The TWAP
+	// oracle is wired by the host through PoolManager.SetPOLOracle.
+	return nil
+}
+
+// BondConfig implements the precompileconfig.Config interface for LXBond
+type BondConfig struct {
+	precompileconfig.Upgrade // Embedded for flat JSON structure
+}
+
+func (c *BondConfig) Key() string {
+	return BondConfigKey
+}
+
+func (c *BondConfig) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *BondConfig) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *BondConfig) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*BondConfig)
+	if !ok {
+		return false
+	}
+	return c.Upgrade.Equal(&other.Upgrade)
+}
+
+func (c *BondConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	return nil
+}
+
+func (*bookConfigurator) MakeConfig() precompileconfig.Config {
+	return new(BookConfig)
+}
+
+func (*bookConfigurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	config, ok := cfg.(*BookConfig)
+	if !ok {
+		return fmt.Errorf("expected config type %T, got %T: %v", &BookConfig{}, cfg, cfg)
+	}
+
+	// The book lives in the pool manager shared with LXPool
+	book := BookPrecompile.poolManager.book
+	stateAdapter := &poolStateAdapter{stateDB: state, block: blockContext}
+	if err := book.SetFees(stateAdapter, config.MakerFeeBps, config.TakerFeeBps); err != nil {
+		return err
+	}
+	book.controls.SetMaxOrdersPerBlock(stateAdapter, config.MaxOrdersPerBlock)
+	return nil
+}
+
+// BookConfig implements the precompileconfig.Config interface for LXBook
+type BookConfig struct {
+	precompileconfig.Upgrade        // Embedded for flat JSON structure
+	MakerFeeBps              uint32 `json:"makerFeeBps,omitempty"`
+	TakerFeeBps              uint32 `json:"takerFeeBps,omitempty"`
+	MaxOrdersPerBlock        uint64 `json:"maxOrdersPerBlock,omitempty"`
+}
+
+func (c *BookConfig) Key() string {
+	return BookConfigKey
+}
+
+func (c *BookConfig) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *BookConfig) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *BookConfig) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*BookConfig)
+	if !ok {
+		return false
+	}
+	return c.Upgrade.Equal(&other.Upgrade) &&
+		c.MakerFeeBps == other.MakerFeeBps &&
+		c.TakerFeeBps == other.TakerFeeBps &&
+		c.MaxOrdersPerBlock == other.MaxOrdersPerBlock
+}
+
+func (c *BookConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	if c.MakerFeeBps > MaxBookFeeBps || c.TakerFeeBps > MaxBookFeeBps {
+		return ErrInvalidBookFee
+	}
+	return nil
+}
+
+func (*oracleConfigurator) MakeConfig() precompileconfig.Config {
+	return new(OracleConfig)
+}
+
+func (*oracleConfigurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	config, ok := cfg.(*OracleConfig)
+	if !ok {
+		return fmt.Errorf("expected config type %T, got %T: %v", &OracleConfig{}, cfg, cfg)
+	}
+
+	// The admin is kept in LXOracle storage; the oracle itself lives in the
+	// pool manager shared with LXPool
+	OraclePrecompile.poolManager.oracle.SetAdmin(&poolStateAdapter{stateDB: state, block: blockContext}, config.Admin)
+	return nil
+}
+
+// OracleConfig implements the precompileconfig.Config interface for LXOracle
+type OracleConfig struct {
+	precompileconfig.Upgrade                // Embedded for flat JSON structure
+	Admin                    common.Address `json:"admin"`
+}
+
+func (c *OracleConfig) Key() string {
+	return OracleConfigKey
+}
+
+func (c *OracleConfig) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *OracleConfig) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *OracleConfig) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*OracleConfig)
+	if !ok {
+		return false
+	}
+	return c.Upgrade.Equal(&other.Upgrade) && c.Admin == other.Admin
+}
+
+func (c *OracleConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	if c.Admin == (common.Address{}) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func (*feedConfigurator) MakeConfig() precompileconfig.Config {
+	return new(FeedConfig)
+}
+
+func (*feedConfigurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	// Feed markets live in the pool manager shared with LXPool and are
+	// configured by the protocol fee controller through configureFeed
+	return nil
+}
+
+// FeedConfig implements the precompileconfig.Config interface for LXFeed
+type FeedConfig struct {
+	precompileconfig.Upgrade // Embedded for flat JSON structure
+}
+
+func (c *FeedConfig) Key() string {
+	return FeedConfigKey
+}
+
+func (c *FeedConfig) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *FeedConfig) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *FeedConfig) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*FeedConfig)
+	if !ok {
+		return false
+	}
+	return c.Upgrade.Equal(&other.Upgrade)
+}
+
+func (c *FeedConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	return nil
+}
+
+// DEXContract implements the DEX precompile
+type DEXContract struct {
+	poolManager *PoolManager
+}
+
+// Run executes the precompile
+func (c *DEXContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+
+	selector := binary.BigEndian.Uint32(input[:4])
+	data := input[4:]
+
+	// Only settle accepts value; anything else would strand it
+	if selector != SelectorSettle && callValue(accessibleState).Sign() > 0 {
+		return nil, suppliedGas, fmt.Errorf("non-payable function")
+	}
+
+	switch selector {
+	case SelectorInitialize:
+		return c.runInitialize(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorSwap:
+		return c.runSwap(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorModifyLiquidity:
+		return c.runModifyLiquidity(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorDonate:
+		return c.runDonate(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorTake:
+		return c.runTake(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorSettle:
+		return c.runSettle(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorLock:
+		return c.runLock(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorGetPool:
+		return c.runGetPool(accessibleState, data, suppliedGas)
+	case SelectorGetPosition:
+		return c.runGetPosition(accessibleState, data, suppliedGas)
+	case SelectorSwapWithReferral:
+		return c.runSwapWithReferral(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorClaimReferral:
+		return c.runClaimReferral(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorWithdrawReferral:
+		return c.runWithdrawReferral(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorGetReferrerStats:
+		return c.runGetReferrerStats(accessibleState, data, suppliedGas)
+	case SelectorInitializeWithTokenFlags:
+		return c.runInitializeWithTokenFlags(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorEnableFeeTier, SelectorDisableFeeTier:
+		return c.runUpdateFeeTier(accessibleState, selector, caller, data, suppliedGas, readOnly)
+	case SelectorFeeTierTickSpacing:
+		return c.runFeeTierTickSpacing(accessibleState, data, suppliedGas)
+	case SelectorAggregate:
+		return c.runAggregate(accessibleState, caller, data, suppliedGas)
+	case SelectorObserve:
+		return c.runObserve(accessibleState, data, suppliedGas)
+	case SelectorObservations:
+		return c.runObservations(accessibleState, data, suppliedGas)
+	case SelectorIncreaseObservationCardinality:
+		return c.runIncreaseObservationCardinality(accessibleState, data, suppliedGas, readOnly)
+	case SelectorSetProtocolFee:
+		return c.runSetProtocolFee(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorCollectProtocolFees:
+		return c.runCollectProtocolFees(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorSetProtocolFeeController:
+		return c.runSetProtocolFeeController(accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorSync:
+		return c.runSync(accessibleState, data, suppliedGas, readOnly)
+	case SelectorReservesOf:
+		return c.runReservesOf(accessibleState, data, suppliedGas)
+	case SelectorMint, SelectorBurn, SelectorTransferClaim:
+		return c.runUpdateClaims(selector, accessibleState, caller, data, suppliedGas, readOnly)
+	case SelectorBalanceOf:
+		return c.runBalanceOf(accessibleState, data, suppliedGas)
+	case SelectorCollect:
+		return c.runCollect(accessibleState, data, suppliedGas, readOnly)
+	case SelectorFundGauge:
+		return c.runFundGauge(accessibleState, data, suppliedGas, readOnly)
+	case SelectorClaimGaugeRewards:
+		return c.runClaimGaugeRewards(accessibleState, data, suppliedGas, readOnly)
+	case SelectorPendingGaugeRewards:
+		return c.runPendingGaugeRewards(accessibleState, data, suppliedGas)
+	default:
+		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	}
+}
+
+func (c *DEXContract) runInitialize(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasPoolCreate {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Parse PoolKey and sqrtPriceX96 from input
+	// Expected format: PoolKey (128 bytes) + sqrtPriceX96 (32 bytes) + hookData
+	if len(input) < 160 {
+		return nil, suppliedGas - GasPoolCreate, fmt.Errorf("input too short")
+	}
+
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasPoolCreate, err
+	}
+
+	sqrtPriceX96 := new(big.Int).SetBytes(input[128:160])
+	hookData := input[160:]
+
+	return c.initialize(state, key, sqrtPriceX96, 0, hookData, suppliedGas-GasPoolCreate)
+}
+
+func (c *DEXContract) runInitializeWithTokenFlags(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasPoolCreate {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: PoolKey (128 bytes) + sqrtPriceX96 (32 bytes) + flags (32 bytes) + hookData
+	if len(input) < 192 {
+		return nil, suppliedGas - GasPoolCreate, fmt.Errorf("input too short")
+	}
+
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasPoolCreate, err
+	}
+
+	sqrtPriceX96 := new(big.Int).SetBytes(input[128:160])
+	flags := TokenFlags(input[191])
+	hookData := input[192:]
+
+	return c.initialize(state, key, sqrtPriceX96, flags, hookData, suppliedGas-GasPoolCreate)
+}
+
+// initialize creates the pool and encodes the starting tick
+func (c *DEXContract) initialize(
+	state contract.AccessibleState,
+	key PoolKey,
+	sqrtPriceX96 *big.Int,
+	flags TokenFlags,
+	hookData []byte,
+	remainingGas uint64,
+) ([]byte, uint64, error) {
+	stateAdapter := newHookStateAdapter(state, remainingGas)
+	tick, err := c.poolManager.InitializeWithTokenFlags(stateAdapter, key, sqrtPriceX96, flags, hookData)
+	if err != nil {
+		return nil, stateAdapter.gas, err
+	}
+
+	// Return tick as int24 (3 bytes, padded to 32)
+	result := make([]byte, 32)
+	tickBytes := int24ToBytes(tick)
+	copy(result[29:], tickBytes)
+	return result, stateAdapter.gas, nil
+}
+
+func (c *DEXContract) runSwap(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasSwap {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Parse PoolKey and SwapParams from input
+	key, params, hookData, err := DecodeSwapInput(input)
+	if err != nil {
+		return nil, suppliedGas - GasSwap, err
+	}
+
+	stateAdapter := newHookStateAdapter(state, suppliedGas-GasSwap)
+	delta, err := c.poolManager.Swap(stateAdapter, key, params, hookData)
+	if err != nil {
+		return nil, stateAdapter.gas, err
+	}
+
+	// Return BalanceDelta as two int256 values
+	result := make([]byte, 64)
+	copy(result[0:32], delta.Amount0.Bytes())
+	copy(result[32:64], delta.Amount1.Bytes())
+	return result, stateAdapter.gas, nil
+}
+
+func (c *DEXContract) runModifyLiquidity(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasAddLiquidity {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	key, params, hookData, err := DecodeModifyLiquidityInput(input)
+	if err != nil {
+		return nil, suppliedGas - GasAddLiquidity, err
+	}
+
+	stateAdapter := newHookStateAdapter(state, suppliedGas-GasAddLiquidity)
+	delta, feeDelta, err := c.poolManager.ModifyLiquidity(stateAdapter, key, params, hookData)
+	if err != nil {
+		return nil, stateAdapter.gas, err
+	}
+
+	// Return BalanceDelta and FeeDelta
+	result := make([]byte, 128)
+	copy(result[0:32], delta.Amount0.Bytes())
+	copy(result[32:64], delta.Amount1.Bytes())
+	copy(result[64:96], feeDelta.Amount0.Bytes())
+	copy(result[96:128], feeDelta.Amount1.Bytes())
+	return result, stateAdapter.gas, nil
+}
+
+func (c *DEXContract) runDonate(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasBalanceUpdate {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: PoolKey (128 bytes) + amount0 (32) + amount1 (32) + hookData
+	if len(input) < 192 {
+		return nil, suppliedGas - GasBalanceUpdate, fmt.Errorf("input too short")
+	}
+
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasBalanceUpdate, err
+	}
+	amount0 := new(big.Int).SetBytes(input[128:160])
+	amount1 := new(big.Int).SetBytes(input[160:192])
+	hookData := input[192:]
+
+	stateAdapter := newHookStateAdapter(state, suppliedGas-GasBalanceUpdate)
+	delta, err := c.poolManager.Donate(stateAdapter, key, amount0, amount1, hookData)
+	if err != nil {
+		return nil, stateAdapter.gas, err
+	}
+
+	// Return BalanceDelta as two int256 values
+	result := make([]byte, 64)
+	copy(result[0:32], delta.Amount0.Bytes())
+	copy(result[32:64], delta.Amount1.Bytes())
+	return result, stateAdapter.gas, nil
+}
+
+func (c *DEXContract) runTake(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasBalanceUpdate {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: currency (32) + to (32) + amount (32)
+	if len(input) < 96 {
+		return nil, suppliedGas - GasBalanceUpdate, fmt.Errorf("input too short")
+	}
+	currency := Currency{Address: common.BytesToAddress(input[12:32])}
+	to := common.BytesToAddress(input[44:64])
+	amount := new(big.Int).SetBytes(input[64:96])
+
+	// Take is only valid within a lock callback
+	stateAdapter := newPoolStateAdapter(state)
+	if err := c.poolManager.Take(stateAdapter, currency, to, amount); err != nil {
+		return nil, suppliedGas - GasBalanceUpdate, err
+	}
+	return nil, suppliedGas - GasBalanceUpdate, nil
+}
+
+func (c *DEXContract) runSettle(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasSettlement {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: currency (32) + amount (32)
+	if len(input) < 64 {
+		return nil, suppliedGas - GasSettlement, fmt.Errorf("input too short")
+	}
+	currency := Currency{Address: common.BytesToAddress(input[12:32])}
+	amount := new(big.Int).SetBytes(input[32:64])
+
+	// Settle is only valid within a lock callback
+	stateAdapter := newPoolStateAdapter(state)
+	value := callValue(state)
+	if !currency.IsNative() {
+		if value.Sign() > 0 {
+			return nil, suppliedGas - GasSettlement, fmt.Errorf("value sent for non-native currency")
+		}
+		if err := c.poolManager.Settle(stateAdapter, currency, amount); err != nil {
+			return nil, suppliedGas - GasSettlement, err
+		}
+		return nil, suppliedGas - GasSettlement, nil
+	}
+
+	// Native LUX is paid with the call's value rather than pulled from the
+	// caller; amount is ignored. Returns the amount credited.
+	credited, err := c.poolManager.SettleValue(stateAdapter, value, caller)
+	if err != nil {
+		return nil, suppliedGas - GasSettlement, err
+	}
+	result := make([]byte, 32)
+	credited.FillBytes(result)
+	return result, suppliedGas - GasSettlement, nil
+}
+
+func (c *DEXContract) runLock(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	// Lock initiates flash accounting session
+	// The callback data contains the operations to execute
+	if suppliedGas < GasFlashLoan {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// The caller's lockAcquired callback runs with the remaining gas and
+	// makes its swaps, settles and takes as nested calls
+	stateAdapter := newHookStateAdapter(state, suppliedGas-GasFlashLoan)
+	result, err := c.poolManager.Lock(stateAdapter, caller, input)
+	if err != nil {
+		return nil, stateAdapter.gas, err
+	}
+	return result, stateAdapter.gas, nil
+}
+
+func (c *DEXContract) runGetPool(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+) ([]byte, uint64, error) {
+	if suppliedGas < GasPoolLookup {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	key, err := DecodePoolKey(input)
+	if err != nil {
+		return nil, suppliedGas - GasPoolLookup, err
+	}
+
+	poolId := key.ID()
+	pool, exists := c.poolManager.pools[poolId]
+	if !exists {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("pool not found")
+	}
+
+	// Encode pool state
+	result := EncodePoolState(pool)
+	return result, suppliedGas - GasPoolLookup, nil
+}
+
+func (c *DEXContract) runGetPosition(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+) ([]byte, uint64, error) {
+	if suppliedGas < GasPoolLookup {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: PoolKey (128) + owner (32) + tickLower (32) + tickUpper (32) + salt (32)
+	if len(input) < 256 {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasPoolLookup, err
+	}
+	var salt [32]byte
+	copy(salt[:], input[224:256])
+
+	stateAdapter := newPoolStateAdapter(state)
+	pos, err := c.poolManager.GetPosition(stateAdapter, key, common.BytesToAddress(input[140:160]),
+		decodeInt24Word(input[160:192]), decodeInt24Word(input[192:224]), salt)
+	if err != nil {
+		return nil, suppliedGas - GasPoolLookup, err
+	}
+
+	// Position not found returns zeroes
+	// liquidity (32) + feeGrowthInside0 (32) + feeGrowthInside1 (32) + tokensOwed0 (32) + tokensOwed1 (32)
+	result := make([]byte, 160)
+	pos.Liquidity.FillBytes(result[0:32])
+	pos.FeeGrowthInside0LastX128.FillBytes(result[32:64])
+	pos.FeeGrowthInside1LastX128.FillBytes(result[64:96])
+	pos.TokensOwed0.FillBytes(result[96:128])
+	pos.TokensOwed1.FillBytes(result[128:160])
+	return result, suppliedGas - GasPoolLookup, nil
+}
+
+func (c *DEXContract) runCollect(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasCollectFees {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: PoolKey (128) + tickLower (32) + tickUpper (32) + salt (32) + amount0 (32) + amount1 (32)
+	if len(input) < 288 {
+		return nil, suppliedGas - GasCollectFees, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasCollectFees, err
+	}
+	var salt [32]byte
+	copy(salt[:], input[192:224])
+	amount0 := new(big.Int).SetBytes(input[224:256])
+	amount1 := new(big.Int).SetBytes(input[256:288])
+
+	stateAdapter := newPoolStateAdapter(state)
+	collected0, collected1, err := c.poolManager.Collect(stateAdapter, key,
+		decodeInt24Word(input[128:160]), decodeInt24Word(input[160:192]), salt, amount0, amount1)
+	if err != nil {
+		return nil, suppliedGas - GasCollectFees, err
+	}
+
+	// Return collected0 (32) + collected1 (32)
+	result := make([]byte, 64)
+	collected0.FillBytes(result[0:32])
+	collected1.FillBytes(result[32:64])
+	return result, suppliedGas - GasCollectFees, nil
+}
+
+func (c *DEXContract) runFundGauge(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasGaugeFund {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: PoolKey (128) + rewardToken (32) + amount (32) + emissionRate (32)
+	if len(input) < 224 {
+		return nil, suppliedGas - GasGaugeFund, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasGaugeFund, err
+	}
+	rewardToken := Currency{Address: common.BytesToAddress(input[140:160])}
+	amount := new(big.Int).SetBytes(input[160:192])
+	emissionRate := new(big.Int).SetBytes(input[192:224])
+
+	stateAdapter := newPoolStateAdapter(state)
+	gauge, err := c.poolManager.FundGauge(stateAdapter, key, rewardToken, amount, emissionRate)
+	if err != nil {
+		return nil, suppliedGas - GasGaugeFund, err
+	}
+
+	// Return periodFinish (32)
+	return encodeUint64(gauge.PeriodFinish), suppliedGas - GasGaugeFund, nil
+}
+
+func (c *DEXContract) runClaimGaugeRewards(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasGaugeClaim {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: PoolKey (128) + rewardToken (32) + positionKey (32)
+	if len(input) < 192 {
+		return nil, suppliedGas - GasGaugeClaim, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasGaugeClaim, err
+	}
+	rewardToken := Currency{Address: common.BytesToAddress(input[140:160])}
+	var positionKey [32]byte
+	copy(positionKey[:], input[160:192])
+
+	stateAdapter := newPoolStateAdapter(state)
+	claimed, err := c.poolManager.ClaimGaugeRewards(stateAdapter, key, rewardToken, positionKey)
+	if err != nil {
+		return nil, suppliedGas - GasGaugeClaim, err
+	}
+
+	// Return claimed (32)
+	result := make([]byte, 32)
+	claimed.FillBytes(result)
+	return result, suppliedGas - GasGaugeClaim, nil
+}
+
+func (c *DEXContract) runPendingGaugeRewards(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+) ([]byte, uint64, error) {
+	if suppliedGas < GasPoolLookup {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: PoolKey (128) + rewardToken (32) + positionKey (32)
+	if len(input) < 192 {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasPoolLookup, err
+	}
+	rewardToken := Currency{Address: common.BytesToAddress(input[140:160])}
+	var positionKey [32]byte
+	copy(positionKey[:], input[160:192])
+
+	stateAdapter := newPoolStateAdapter(state)
+	pending := c.poolManager.PendingGaugeRewards(stateAdapter, key, rewardToken, positionKey)
+
+	// Return pending (32)
+	result := make([]byte, 32)
+	pending.FillBytes(result)
+	return result, suppliedGas - GasPoolLookup, nil
+}
+
+func (c *DEXContract) runSwapWithReferral(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	gas := GasSwap + GasBalanceUpdate
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	key, params, referrer, hookData, err := DecodeSwapWithReferralInput(input)
+	if err != nil {
+		return nil, suppliedGas - gas, err
+	}
+
+	stateAdapter := newHookStateAdapter(state, suppliedGas-gas)
+	delta, err := c.poolManager.SwapWithReferral(stateAdapter, key, params, referrer, hookData)
+	if err != nil {
+		return nil, stateAdapter.gas, err
+	}
+
+	// Return BalanceDelta as two int256 values
+	result := make([]byte, 64)
+	copy(result[0:32], delta.Amount0.Bytes())
+	copy(result[32:64], delta.Amount1.Bytes())
+	return result, stateAdapter.gas, nil
+}
+
+func (c *DEXContract) runClaimReferral(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	if suppliedGas < GasClaimReferral {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: currency (32) + amount (32)
+	if len(input) < 64 {
+		return nil, suppliedGas - GasClaimReferral, fmt.Errorf("input too short")
+	}
+
+	currency := Currency{Address: common.BytesToAddress(input[12:32])}
+	amount := new(big.Int).SetBytes(input[32:64])
+
+	if err := c.poolManager.ClaimReferralFees(newPoolStateAdapter(state), currency, amount); err != nil {
+		return nil, suppliedGas - GasClaimReferral, err
+	}
+	return nil, suppliedGas - GasClaimReferral, nil
 }
 
-// Run executes the precompile
-func (c *DEXContract) Run(
-	accessibleState contract.AccessibleState,
+func (c *DEXContract) runWithdrawReferral(
+	state contract.AccessibleState,
 	caller common.Address,
-	addr common.Address,
 	input []byte,
 	suppliedGas uint64,
 	readOnly bool,
-) (ret []byte, remainingGas uint64, err error) {
-	if len(input) < 4 {
-		return nil, suppliedGas, fmt.Errorf("input too short")
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
 	}
 
-	selector := binary.BigEndian.Uint32(input[:4])
-	data := input[4:]
+	if suppliedGas < GasWithdrawReferral {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
 
-	switch selector {
-	case SelectorInitialize:
-		return c.runInitialize(accessibleState, caller, data, suppliedGas, readOnly)
-	case SelectorSwap:
-		return c.runSwap(accessibleState, caller, data, suppliedGas, readOnly)
-	case SelectorModifyLiquidity:
-		return c.runModifyLiquidity(accessibleState, caller, data, suppliedGas, readOnly)
-	case SelectorDonate:
-		return c.runDonate(accessibleState, caller, data, suppliedGas, readOnly)
-	case SelectorTake:
-		return c.runTake(accessibleState, caller, data, suppliedGas, readOnly)
-	case SelectorSettle:
-		return c.runSettle(accessibleState, caller, data, suppliedGas, readOnly)
-	case SelectorLock:
-		return c.runLock(accessibleState, caller, data, suppliedGas, readOnly)
-	case SelectorGetPool:
-		return c.runGetPool(accessibleState, data, suppliedGas)
-	case SelectorGetPosition:
-		return c.runGetPosition(accessibleState, data, suppliedGas)
-	default:
-		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	// Expected format: currency (32) + to (32) + amount (32)
+	if len(input) < 96 {
+		return nil, suppliedGas - GasWithdrawReferral, fmt.Errorf("input too short")
+	}
+
+	currency := Currency{Address: common.BytesToAddress(input[12:32])}
+	to := common.BytesToAddress(input[44:64])
+	amount := new(big.Int).SetBytes(input[64:96])
+
+	stateAdapter := newPoolStateAdapter(state)
+	if err := c.poolManager.WithdrawReferralFees(stateAdapter, caller, currency, to, amount); err != nil {
+		return nil, suppliedGas - GasWithdrawReferral, err
 	}
+	return nil, suppliedGas - GasWithdrawReferral, nil
 }
 
-func (c *DEXContract) runInitialize(
+func (c *DEXContract) runGetReferrerStats(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+) ([]byte, uint64, error) {
+	if suppliedGas < GasPoolLookup {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: referrer (32) + currency (32)
+	if len(input) < 64 {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("input too short")
+	}
+
+	referrer := common.BytesToAddress(input[12:32])
+	currency := Currency{Address: common.BytesToAddress(input[44:64])}
+
+	stateAdapter := newPoolStateAdapter(state)
+	return EncodeReferrerStats(
+		c.poolManager.referrals.Stats(stateAdapter, referrer, currency),
+		c.poolManager.referrals.Balance(stateAdapter, referrer, currency),
+	), suppliedGas - GasPoolLookup, nil
+}
+
+func (c *DEXContract) runUpdateFeeTier(
 	state contract.AccessibleState,
+	selector uint32,
 	caller common.Address,
 	input []byte,
 	suppliedGas uint64,
@@ -189,75 +1497,122 @@ func (c *DEXContract) runInitialize(
 		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
 	}
 
-	if suppliedGas < GasPoolCreate {
+	if suppliedGas < GasFeeTierUpdate {
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	// Parse PoolKey and sqrtPriceX96 from input
-	// Expected format: PoolKey (128 bytes) + sqrtPriceX96 (32 bytes) + hookData
-	if len(input) < 160 {
-		return nil, suppliedGas - GasPoolCreate, fmt.Errorf("input too short")
+	// Expected format: fee (32) [+ tickSpacing (32) for enable]
+	if len(input) < 32 || (selector == SelectorEnableFeeTier && len(input) < 64) {
+		return nil, suppliedGas - GasFeeTierUpdate, fmt.Errorf("input too short")
 	}
 
-	key, err := DecodePoolKey(input[:128])
+	stateAdapter := newPoolStateAdapter(state)
+	fee := uint24(binary.BigEndian.Uint32(input[28:32]))
+	var err error
+	if selector == SelectorEnableFeeTier {
+		err = c.poolManager.EnableFeeTier(stateAdapter, caller, fee, decodeInt24Word(input[32:64]))
+	} else {
+		err = c.poolManager.DisableFeeTier(stateAdapter, caller, fee)
+	}
 	if err != nil {
-		return nil, suppliedGas - GasPoolCreate, err
+		return nil, suppliedGas - GasFeeTierUpdate, err
 	}
+	return nil, suppliedGas - GasFeeTierUpdate, nil
+}
 
-	sqrtPriceX96 := new(big.Int).SetBytes(input[128:160])
-	hookData := input[160:]
+func (c *DEXContract) runFeeTierTickSpacing(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+) ([]byte, uint64, error) {
+	if suppliedGas < GasPoolLookup {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
 
-	// Initialize pool
-	stateAdapter := &poolStateAdapter{state.GetStateDB()}
-	tick, err := c.poolManager.Initialize(stateAdapter, key, sqrtPriceX96, hookData)
-	if err != nil {
-		return nil, suppliedGas - GasPoolCreate, err
+	// Expected format: fee (32)
+	if len(input) < 32 {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("input too short")
 	}
 
-	// Return tick as int24 (3 bytes, padded to 32)
+	// A disabled fee tier reports a tick spacing of zero
+	spacing, _ := c.poolManager.feeTiers.TickSpacing(newPoolStateAdapter(state), uint24(binary.BigEndian.Uint32(input[28:32])))
 	result := make([]byte, 32)
-	tickBytes := int24ToBytes(tick)
-	copy(result[29:], tickBytes)
-	return result, suppliedGas - GasPoolCreate, nil
+	copy(result[29:], int24ToBytes(spacing))
+	return result, suppliedGas - GasPoolLookup, nil
 }
 
-func (c *DEXContract) runSwap(
+func (c *DEXContract) runObserve(
 	state contract.AccessibleState,
-	caller common.Address,
 	input []byte,
 	suppliedGas uint64,
-	readOnly bool,
 ) ([]byte, uint64, error) {
-	if readOnly {
-		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	// Expected format: PoolKey (128) + count (32) + secondsAgo (32) each
+	if len(input) < 160 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
 	}
-
-	if suppliedGas < GasSwap {
+	count := binary.BigEndian.Uint64(input[152:160])
+	if uint64(len(input)-160)/32 < count {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+	gas := GasPoolLookup + GasObserve*count
+	if suppliedGas < gas {
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	// Parse PoolKey and SwapParams from input
-	key, params, hookData, err := DecodeSwapInput(input)
+	key, err := DecodePoolKey(input[:128])
 	if err != nil {
-		return nil, suppliedGas - GasSwap, err
+		return nil, suppliedGas - gas, err
+	}
+	secondsAgos := make([]uint32, count)
+	for i := range secondsAgos {
+		offset := 160 + 32*i
+		secondsAgos[i] = binary.BigEndian.Uint32(input[offset+28 : offset+32])
 	}
 
-	stateAdapter := &poolStateAdapter{state.GetStateDB()}
-	delta, err := c.poolManager.Swap(stateAdapter, key, params, hookData)
+	stateAdapter := newPoolStateAdapter(state)
+	tickCumulatives, secondsPerLiquidity, err := c.poolManager.Observe(stateAdapter, key, secondsAgos)
 	if err != nil {
-		return nil, suppliedGas - GasSwap, err
+		return nil, suppliedGas - gas, err
 	}
 
-	// Return BalanceDelta as two int256 values
-	result := make([]byte, 64)
-	copy(result[0:32], delta.Amount0.Bytes())
-	copy(result[32:64], delta.Amount1.Bytes())
-	return result, suppliedGas - GasSwap, nil
+	// Return tickCumulative (int256) + secondsPerLiquidityX128 (32) per point
+	result := make([]byte, 64*count)
+	for i := range secondsAgos {
+		tickHash := signedToHash(tickCumulatives[i])
+		copy(result[64*i:64*i+32], tickHash[:])
+		secondsPerLiquidity[i].FillBytes(result[64*i+32 : 64*i+64])
+	}
+	return result, suppliedGas - gas, nil
 }
 
-func (c *DEXContract) runModifyLiquidity(
+func (c *DEXContract) runObservations(
+	state contract.AccessibleState,
+	input []byte,
+	suppliedGas uint64,
+) ([]byte, uint64, error) {
+	if suppliedGas < GasPoolLookup {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	// Expected format: PoolKey (128) + index (32)
+	if len(input) < 160 {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasPoolLookup, err
+	}
+
+	stateAdapter := newPoolStateAdapter(state)
+	obs, err := c.poolManager.Observations(stateAdapter, key, binary.BigEndian.Uint16(input[158:160]))
+	if err != nil {
+		return nil, suppliedGas - GasPoolLookup, err
+	}
+	return EncodeObservation(obs), suppliedGas - GasPoolLookup, nil
+}
+
+func (c *DEXContract) runIncreaseObservationCardinality(
 	state contract.AccessibleState,
-	caller common.Address,
 	input []byte,
 	suppliedGas uint64,
 	readOnly bool,
@@ -266,31 +1621,48 @@ func (c *DEXContract) runModifyLiquidity(
 		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
 	}
 
-	if suppliedGas < GasAddLiquidity {
+	if suppliedGas < GasPoolLookup {
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	key, params, hookData, err := DecodeModifyLiquidityInput(input)
+	// Expected format: PoolKey (128) + next (32)
+	if len(input) < 160 {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
 	if err != nil {
-		return nil, suppliedGas - GasAddLiquidity, err
+		return nil, suppliedGas - GasPoolLookup, err
 	}
 
-	stateAdapter := &poolStateAdapter{state.GetStateDB()}
-	delta, feeDelta, err := c.poolManager.ModifyLiquidity(stateAdapter, key, params, hookData)
+	stateAdapter := newPoolStateAdapter(state)
+	pool, err := c.poolManager.GetPool(stateAdapter, key)
 	if err != nil {
-		return nil, suppliedGas - GasAddLiquidity, err
+		return nil, suppliedGas - GasPoolLookup, err
 	}
 
-	// Return BalanceDelta and FeeDelta
-	result := make([]byte, 128)
-	copy(result[0:32], delta.Amount0.Bytes())
-	copy(result[32:64], delta.Amount1.Bytes())
-	copy(result[64:96], feeDelta.Amount0.Bytes())
-	copy(result[96:128], feeDelta.Amount1.Bytes())
-	return result, suppliedGas - GasAddLiquidity, nil
+	// Each added slot is paid for up front
+	next := binary.BigEndian.Uint16(input[158:160])
+	gas := GasPoolLookup
+	if next > pool.ObservationCardinalityNext {
+		gas += GasObservationSlot * uint64(next-pool.ObservationCardinalityNext)
+	}
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+
+	previous, next, err := c.poolManager.IncreaseObservationCardinality(stateAdapter, key, next)
+	if err != nil {
+		return nil, suppliedGas - gas, err
+	}
+
+	// Return previous (32) + next (32)
+	result := make([]byte, 64)
+	binary.BigEndian.PutUint16(result[30:32], previous)
+	binary.BigEndian.PutUint16(result[62:64], next)
+	return result, suppliedGas - gas, nil
 }
 
-func (c *DEXContract) runDonate(
+func (c *DEXContract) runSetProtocolFee(
 	state contract.AccessibleState,
 	caller common.Address,
 	input []byte,
@@ -301,15 +1673,27 @@ func (c *DEXContract) runDonate(
 		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
 	}
 
-	if suppliedGas < GasBalanceUpdate {
+	if suppliedGas < GasProtocolFeeUpdate {
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	// Donate is not yet implemented
-	return nil, suppliedGas - GasBalanceUpdate, fmt.Errorf("donate not implemented")
+	// Expected format: PoolKey (128) + feeBps (32)
+	if len(input) < 160 {
+		return nil, suppliedGas - GasProtocolFeeUpdate, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasProtocolFeeUpdate, err
+	}
+
+	stateAdapter := newPoolStateAdapter(state)
+	if err := c.poolManager.SetProtocolFee(stateAdapter, caller, key, binary.BigEndian.Uint32(input[156:160])); err != nil {
+		return nil, suppliedGas - GasProtocolFeeUpdate, err
+	}
+	return nil, suppliedGas - GasProtocolFeeUpdate, nil
 }
 
-func (c *DEXContract) runTake(
+func (c *DEXContract) runCollectProtocolFees(
 	state contract.AccessibleState,
 	caller common.Address,
 	input []byte,
@@ -320,15 +1704,36 @@ func (c *DEXContract) runTake(
 		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
 	}
 
-	if suppliedGas < GasBalanceUpdate {
+	if suppliedGas < GasCollectProtocolFees {
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	// Take is handled as part of flash accounting
-	return nil, suppliedGas - GasBalanceUpdate, fmt.Errorf("take must be called within lock callback")
+	// Expected format: PoolKey (128) + recipient (32) + amount0 (32) + amount1 (32)
+	if len(input) < 224 {
+		return nil, suppliedGas - GasCollectProtocolFees, fmt.Errorf("input too short")
+	}
+	key, err := DecodePoolKey(input[:128])
+	if err != nil {
+		return nil, suppliedGas - GasCollectProtocolFees, err
+	}
+	recipient := common.BytesToAddress(input[140:160])
+	amount0 := new(big.Int).SetBytes(input[160:192])
+	amount1 := new(big.Int).SetBytes(input[192:224])
+
+	stateAdapter := newPoolStateAdapter(state)
+	collected0, collected1, err := c.poolManager.CollectProtocolFees(stateAdapter, caller, key, recipient, amount0, amount1)
+	if err != nil {
+		return nil, suppliedGas - GasCollectProtocolFees, err
+	}
+
+	// Return collected0 (32) + collected1 (32)
+	result := make([]byte, 64)
+	collected0.FillBytes(result[0:32])
+	collected1.FillBytes(result[32:64])
+	return result, suppliedGas - GasCollectProtocolFees, nil
 }
 
-func (c *DEXContract) runSettle(
+func (c *DEXContract) runSetProtocolFeeController(
 	state contract.AccessibleState,
 	caller common.Address,
 	input []byte,
@@ -339,17 +1744,23 @@ func (c *DEXContract) runSettle(
 		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
 	}
 
-	if suppliedGas < GasSettlement {
+	if suppliedGas < GasProtocolFeeUpdate {
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	// Settle is handled as part of flash accounting
-	return nil, suppliedGas - GasSettlement, fmt.Errorf("settle must be called within lock callback")
+	// Expected format: controller (32)
+	if len(input) < 32 {
+		return nil, suppliedGas - GasProtocolFeeUpdate, fmt.Errorf("input too short")
+	}
+	stateAdapter := newPoolStateAdapter(state)
+	if err := c.poolManager.SetProtocolFeeController(stateAdapter, caller, common.BytesToAddress(input[12:32])); err != nil {
+		return nil, suppliedGas - GasProtocolFeeUpdate, err
+	}
+	return nil, suppliedGas - GasProtocolFeeUpdate, nil
 }
 
-func (c *DEXContract) runLock(
+func (c *DEXContract) runSync(
 	state contract.AccessibleState,
-	caller common.Address,
 	input []byte,
 	suppliedGas uint64,
 	readOnly bool,
@@ -358,18 +1769,24 @@ func (c *DEXContract) runLock(
 		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
 	}
 
-	// Lock initiates flash accounting session
-	// The callback data contains the operations to execute
-	if suppliedGas < GasFlashLoan {
+	if suppliedGas < GasSync {
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	// Lock implementation would normally call back to the caller contract
-	// For precompile, we return success and expect nested calls
-	return nil, suppliedGas - GasFlashLoan, nil
+	// Expected format: currency (32)
+	if len(input) < 32 {
+		return nil, suppliedGas - GasSync, fmt.Errorf("input too short")
+	}
+	currency := Currency{Address: common.BytesToAddress(input[12:32])}
+
+	stateAdapter := newPoolStateAdapter(state)
+	if err := c.poolManager.Sync(stateAdapter, currency); err != nil {
+		return nil, suppliedGas - GasSync, err
+	}
+	return nil, suppliedGas - GasSync, nil
 }
 
-func (c *DEXContract) runGetPool(
+func (c *DEXContract) runReservesOf(
 	state contract.AccessibleState,
 	input []byte,
 	suppliedGas uint64,
@@ -378,23 +1795,67 @@ func (c *DEXContract) runGetPool(
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	key, err := DecodePoolKey(input)
-	if err != nil {
-		return nil, suppliedGas - GasPoolLookup, err
+	// Expected format: currency (32)
+	if len(input) < 32 {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("input too short")
 	}
+	currency := Currency{Address: common.BytesToAddress(input[12:32])}
 
-	poolId := key.ID()
-	pool, exists := c.poolManager.pools[poolId]
-	if !exists {
-		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("pool not found")
+	stateAdapter := newPoolStateAdapter(state)
+	result := make([]byte, 32)
+	c.poolManager.ReservesOf(stateAdapter, currency).FillBytes(result)
+	return result, suppliedGas - GasPoolLookup, nil
+}
+
+// runUpdateClaims runs mint, burn and transferClaim
+func (c *DEXContract) runUpdateClaims(
+	selector uint32,
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
 	}
 
-	// Encode pool state
-	result := EncodePoolState(pool)
-	return result, suppliedGas - GasPoolLookup, nil
+	if suppliedGas < GasClaimUpdate {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+	remainingGas := suppliedGas - GasClaimUpdate
+
+	stateAdapter := newPoolStateAdapter(state)
+	var err error
+	switch selector {
+	case SelectorBurn:
+		// Expected format: currency (32) + amount (32)
+		if len(input) < 64 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		currency := Currency{Address: common.BytesToAddress(input[12:32])}
+		err = c.poolManager.Burn(stateAdapter, currency, new(big.Int).SetBytes(input[32:64]))
+	default:
+		// Expected format: address (32) + currency (32) + amount (32)
+		if len(input) < 96 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		to := common.BytesToAddress(input[12:32])
+		currency := Currency{Address: common.BytesToAddress(input[44:64])}
+		amount := new(big.Int).SetBytes(input[64:96])
+		if selector == SelectorMint {
+			err = c.poolManager.Mint(stateAdapter, to, currency, amount)
+		} else {
+			err = c.poolManager.TransferClaim(stateAdapter, caller, to, currency, amount)
+		}
+	}
+	if err != nil {
+		return nil, remainingGas, err
+	}
+	return nil, remainingGas, nil
 }
 
-func (c *DEXContract) runGetPosition(
+func (c *DEXContract) runBalanceOf(
 	state contract.AccessibleState,
 	input []byte,
 	suppliedGas uint64,
@@ -403,9 +1864,16 @@ func (c *DEXContract) runGetPosition(
 		return nil, 0, fmt.Errorf("out of gas")
 	}
 
-	// Parse position key from input
-	// Position not found returns zeroes
-	result := make([]byte, 96) // liquidity (32) + feeGrowthInside0 (32) + feeGrowthInside1 (32)
+	// Expected format: owner (32) + currency (32)
+	if len(input) < 64 {
+		return nil, suppliedGas - GasPoolLookup, fmt.Errorf("input too short")
+	}
+	owner := common.BytesToAddress(input[12:32])
+	currency := Currency{Address: common.BytesToAddress(input[44:64])}
+
+	stateAdapter := newPoolStateAdapter(state)
+	result := make([]byte, 32)
+	c.poolManager.ClaimBalanceOf(stateAdapter, owner, currency).FillBytes(result)
 	return result, suppliedGas - GasPoolLookup, nil
 }
 
@@ -417,7 +1885,7 @@ func (c *DEXContract) RequiredGas(input []byte) uint64 {
 
 	selector := binary.BigEndian.Uint32(input[:4])
 	switch selector {
-	case SelectorInitialize:
+	case SelectorInitialize, SelectorInitializeWithTokenFlags:
 		return GasPoolCreate
 	case SelectorSwap:
 		return GasSwap
@@ -431,16 +1899,105 @@ func (c *DEXContract) RequiredGas(input []byte) uint64 {
 		return GasSettlement
 	case SelectorLock:
 		return GasFlashLoan
-	case SelectorGetPool, SelectorGetPosition:
+	case SelectorGetPool, SelectorGetPosition, SelectorGetReferrerStats, SelectorFeeTierTickSpacing:
+		return GasPoolLookup
+	case SelectorSwapWithReferral:
+		return GasSwap + GasBalanceUpdate
+	case SelectorClaimReferral:
+		return GasClaimReferral
+	case SelectorWithdrawReferral:
+		return GasWithdrawReferral
+	case SelectorEnableFeeTier, SelectorDisableFeeTier:
+		return GasFeeTierUpdate
+	case SelectorAggregate:
+		return c.aggregateGas(input[4:])
+	case SelectorObserve:
+		// One lookup per secondsAgo supplied: selector (4) + PoolKey (128) + count (32)
+		if len(input) < 164 {
+			return GasPoolLookup
+		}
+		return GasPoolLookup + GasObserve*uint64((len(input)-164)/32)
+	case SelectorObservations, SelectorIncreaseObservationCardinality:
+		return GasPoolLookup
+	case SelectorSetProtocolFee, SelectorSetProtocolFeeController:
+		return GasProtocolFeeUpdate
+	case SelectorCollectProtocolFees:
+		return GasCollectProtocolFees
+	case SelectorSync:
+		return GasSync
+	case SelectorReservesOf, SelectorBalanceOf:
+		return GasPoolLookup
+	case SelectorMint, SelectorBurn, SelectorTransferClaim:
+		return GasClaimUpdate
+	case SelectorCollect:
+		return GasCollectFees
+	case SelectorFundGauge:
+		return GasGaugeFund
+	case SelectorClaimGaugeRewards:
+		return GasGaugeClaim
+	case SelectorPendingGaugeRewards:
 		return GasPoolLookup
 	default:
 		return GasSwap
 	}
 }
 
-// poolStateAdapter adapts contract.StateDB to dex.StateDB
+// poolStateAdapter adapts contract.StateDB to dex.StateDB, reading the
+// block number and timestamp from the block being executed. With an
+// environment and gas budget it also calls hook contracts, charging their
+// gas to the precompile call.
 type poolStateAdapter struct {
 	stateDB contract.StateDB
+	block   contract.ConfigurationBlockContext
+	env     contract.PrecompileEnvironment
+	gas     uint64
+}
+
+// newPoolStateAdapter returns an adapter over the state and block of a
+// precompile call
+func newPoolStateAdapter(state contract.AccessibleState) *poolStateAdapter {
+	return &poolStateAdapter{
+		stateDB: state.GetStateDB(),
+		block:   state.GetBlockContext(),
+	}
+}
+
+// newHookStateAdapter returns an adapter that can call hooks with gas
+func newHookStateAdapter(state contract.AccessibleState, gas uint64) *poolStateAdapter {
+	adapter := newPoolStateAdapter(state)
+	adapter.env = state.GetPrecompileEnv()
+	adapter.gas = gas
+	return adapter
+}
+
+// callValue returns the value sent with the precompile call, or zero if the
+// environment does not expose it
+func callValue(state contract.AccessibleState) *big.Int {
+	env, ok := state.GetPrecompileEnv().(contract.ValueEnvironment)
+	if !ok || env.Value() == nil {
+		return new(big.Int)
+	}
+	return env.Value().ToBig()
+}
+
+// CallHook calls a hook contract with up to gas, capped at what remains of
+// the precompile call's gas
+func (a *poolStateAdapter) CallHook(hook common.Address, input []byte, gas uint64) ([]byte, error) {
+	env, ok := a.env.(contract.CallerEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("hook calls not supported")
+	}
+	if a.gas < GasHookCall {
+		return nil, fmt.Errorf("out of gas")
+	}
+	a.gas -= GasHookCall
+	if gas > a.gas {
+		gas = a.gas
+	}
+
+	ret, gasLeft, err := env.Call(hook, input, gas, new(uint256.Int))
+	a.gas -= gas - gasLeft
+	return ret, err
 }
 
 func (a *poolStateAdapter) GetState(addr common.Address, key common.Hash) common.Hash {
@@ -472,7 +2029,17 @@ func (a *poolStateAdapter) CreateAccount(addr common.Address) {
 }
 
 func (a *poolStateAdapter) GetBlockNumber() uint64 {
-	return 0 // Would need block context
+	if a.block == nil || a.block.Number() == nil {
+		return 0
+	}
+	return a.block.Number().Uint64()
+}
+
+func (a *poolStateAdapter) GetBlockTimestamp() uint64 {
+	if a.block == nil {
+		return 0
+	}
+	return a.block.Timestamp()
 }
 
 // Helper functions for encoding/decoding
@@ -522,6 +2089,23 @@ func DecodeSwapInput(input []byte) (PoolKey, SwapParams, []byte, error) {
 	return key, params, hookData, nil
 }
 
+// DecodeSwapWithReferralInput decodes swapWithReferral input: the swap
+// input layout with a 32-byte referrer word before hookData
+func DecodeSwapWithReferralInput(input []byte) (PoolKey, SwapParams, common.Address, []byte, error) {
+	if len(input) < 225 {
+		return PoolKey{}, SwapParams{}, common.Address{}, nil, fmt.Errorf("input too short for swapWithReferral")
+	}
+
+	key, params, _, err := DecodeSwapInput(input[:193])
+	if err != nil {
+		return PoolKey{}, SwapParams{}, common.Address{}, nil, err
+	}
+
+	referrer := common.BytesToAddress(input[205:225])
+	hookData := input[225:]
+	return key, params, referrer, hookData, nil
+}
+
 // DecodeModifyLiquidityInput decodes modifyLiquidity input
 func DecodeModifyLiquidityInput(input []byte) (PoolKey, ModifyLiquidityParams, []byte, error) {
 	if len(input) < 192 {
@@ -553,3 +2137,29 @@ func EncodePoolState(pool *Pool) []byte {
 	copy(result[128:160], pool.FeeGrowth1X128.Bytes())
 	return result
 }
+
+// EncodeObservation encodes an observation: timestamp (32) +
+// tickCumulative (int256) + secondsPerLiquidityX128 (32) + initialized (32)
+func EncodeObservation(obs *TWAPObservation) []byte {
+	result := make([]byte, 128)
+	binary.BigEndian.PutUint64(result[24:32], obs.Timestamp)
+	tickHash := signedToHash(obs.TickCumulative)
+	copy(result[32:64], tickHash[:])
+	obs.SecondsPerLiquidity.FillBytes(result[64:96])
+	if obs.Initialized {
+		result[127] = 1
+	}
+	return result
+}
+
+// EncodeReferrerStats encodes a referrer's statistics for one currency:
+// swaps (32) + volume (32) + earned (32) + claimed (32) + balance (32)
+func EncodeReferrerStats(stats ReferrerStats, balance *big.Int) []byte {
+	result := make([]byte, 160)
+	binary.BigEndian.PutUint64(result[24:32], stats.Swaps)
+	stats.Volume.FillBytes(result[32:64])
+	stats.Earned.FillBytes(result[64:96])
+	stats.Claimed.FillBytes(result[96:128])
+	balance.FillBytes(result[128:160])
+	return result
+}
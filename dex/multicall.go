@@ -0,0 +1,223 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Aggregate queries
+//
+// aggregate runs a list of view calls against the DEX precompiles (LXPool,
+// LXEscrow, LXLottery, LXIdentity, LXBond, LXBook, LXOracle and LXFeed) in
+// one call, so a frontend can fetch pools, locks, markets, prices and stats
+// without an eth_call per item. Every call runs read-only, and a failing call is reported in its own result
+// instead of reverting the batch. Each call is charged the gas its target
+// consumed plus GasAggregateCall.
+//
+// Input:  count (32) + count * (target (32) + length (32) + calldata)
+// Output: count (32) + count * (success (32) + length (32) + returndata)
+//
+// A failed call's returndata is its error message.
+
+// MaxAggregateCalls bounds the number of calls in one aggregate query
+const MaxAggregateCalls = 64
+
+// AggregateCall is one view call of an aggregate query
+type AggregateCall struct {
+	Target   common.Address
+	Calldata []byte
+}
+
+// AggregateResult is the outcome of one aggregated call
+type AggregateResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// queryContract is a DEX precompile an aggregate query can call
+type queryContract interface {
+	contract.StatefulPrecompiledContract
+	RequiredGas(input []byte) uint64
+}
+
+// aggregateTarget returns the DEX precompile at addr, bound to this
+// contract's pool manager
+func (c *DEXContract) aggregateTarget(addr common.Address) queryContract {
+	switch addr {
+	case lxPoolAddr:
+		return c
+	case lxEscrowAddr:
+		return &EscrowContract{poolManager: c.poolManager}
+	case lxLotteryAddr:
+		return &LotteryContract{poolManager: c.poolManager}
+	case lxIdentityAddr:
+		return &IdentityContract{poolManager: c.poolManager}
+	case lxBondAddr:
+		return &BondContract{poolManager: c.poolManager}
+	case lxBookAddr:
+		return &BookContract{poolManager: c.poolManager}
+	case lxOracleAddr:
+		return &OracleContract{poolManager: c.poolManager}
+	case lxFeedAddr:
+		return &FeedContract{poolManager: c.poolManager}
+	default:
+		return nil
+	}
+}
+
+func (c *DEXContract) runAggregate(
+	state contract.AccessibleState,
+	caller common.Address,
+	input []byte,
+	suppliedGas uint64,
+) ([]byte, uint64, error) {
+	calls, err := DecodeAggregateCalls(input)
+	if err != nil {
+		return nil, suppliedGas, err
+	}
+
+	remaining := suppliedGas
+	results := make([]AggregateResult, len(calls))
+	for i, call := range calls {
+		if remaining < GasAggregateCall {
+			return nil, 0, fmt.Errorf("out of gas")
+		}
+		remaining -= GasAggregateCall
+
+		var ret []byte
+		var left uint64
+		target := c.aggregateTarget(call.Target)
+		switch {
+		case target == nil:
+			err, left = ErrInvalidAggregateTarget, remaining
+		case call.Target == lxPoolAddr && isAggregateCall(call.Calldata):
+			err, left = ErrNestedAggregate, remaining
+		default:
+			ret, left, err = target.Run(state, caller, call.Target, call.Calldata, remaining, true)
+		}
+
+		if err != nil {
+			results[i] = AggregateResult{ReturnData: []byte(err.Error())}
+		} else {
+			results[i] = AggregateResult{Success: true, ReturnData: ret}
+		}
+		if left < remaining {
+			remaining = left
+		}
+	}
+	return EncodeAggregateResults(results), remaining, nil
+}
+
+// isAggregateCall reports whether LXPool calldata is itself an aggregate query
+func isAggregateCall(calldata []byte) bool {
+	return len(calldata) >= 4 && binary.BigEndian.Uint32(calldata[:4]) == SelectorAggregate
+}
+
+// aggregateGas is the upfront gas of an aggregate query: the per-call
+// overhead plus each target's required gas
+func (c *DEXContract) aggregateGas(input []byte) uint64 {
+	calls, err := DecodeAggregateCalls(input)
+	if err != nil {
+		return GasPoolLookup
+	}
+	gas := uint64(0)
+	for _, call := range calls {
+		gas += GasAggregateCall
+		if call.Target == lxPoolAddr && isAggregateCall(call.Calldata) {
+			continue
+		}
+		if target := c.aggregateTarget(call.Target); target != nil {
+			gas += target.RequiredGas(call.Calldata)
+		}
+	}
+	return gas
+}
+
+// DecodeAggregateCalls decodes the calls of an aggregate query
+func DecodeAggregateCalls(input []byte) ([]AggregateCall, error) {
+	if len(input) < 32 {
+		return nil, ErrInvalidAggregate
+	}
+	count := decodeUint64Word(input[:32])
+	if count == 0 || count > MaxAggregateCalls {
+		return nil, ErrInvalidAggregate
+	}
+
+	calls := make([]AggregateCall, count)
+	offset := 32
+	for i := range calls {
+		if len(input)-offset < 64 {
+			return nil, ErrInvalidAggregate
+		}
+		length := decodeUint64Word(input[offset+32 : offset+64])
+		if length > uint64(len(input)-offset-64) {
+			return nil, ErrInvalidAggregate
+		}
+		calls[i] = AggregateCall{
+			Target:   common.BytesToAddress(input[offset+12 : offset+32]),
+			Calldata: input[offset+64 : offset+64+int(length)],
+		}
+		offset += 64 + int(length)
+	}
+	if offset != len(input) {
+		return nil, ErrInvalidAggregate
+	}
+	return calls, nil
+}
+
+// EncodeAggregateCalls encodes the calls of an aggregate query
+func EncodeAggregateCalls(calls []AggregateCall) []byte {
+	result := encodeUint64(uint64(len(calls)))
+	for _, call := range calls {
+		result = append(result, common.BytesToHash(call.Target.Bytes()).Bytes()...)
+		result = append(result, encodeUint64(uint64(len(call.Calldata)))...)
+		result = append(result, call.Calldata...)
+	}
+	return result
+}
+
+// EncodeAggregateResults encodes the results of an aggregate query
+func EncodeAggregateResults(results []AggregateResult) []byte {
+	result := encodeUint64(uint64(len(results)))
+	for _, r := range results {
+		result = append(result, encodeBool(r.Success)...)
+		result = append(result, encodeUint64(uint64(len(r.ReturnData)))...)
+		result = append(result, r.ReturnData...)
+	}
+	return result
+}
+
+// DecodeAggregateResults decodes the results of an aggregate query
+func DecodeAggregateResults(output []byte) ([]AggregateResult, error) {
+	if len(output) < 32 {
+		return nil, ErrInvalidAggregate
+	}
+	count := decodeUint64Word(output[:32])
+	if count > MaxAggregateCalls {
+		return nil, ErrInvalidAggregate
+	}
+
+	results := make([]AggregateResult, count)
+	offset := 32
+	for i := range results {
+		if len(output)-offset < 64 {
+			return nil, ErrInvalidAggregate
+		}
+		length := decodeUint64Word(output[offset+32 : offset+64])
+		if length > uint64(len(output)-offset-64) {
+			return nil, ErrInvalidAggregate
+		}
+		results[i] = AggregateResult{
+			Success:    output[offset+31] != 0,
+			ReturnData: output[offset+64 : offset+64+int(length)],
+		}
+		offset += 64 + int(length)
+	}
+	return results, nil
+}
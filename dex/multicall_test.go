@@ -0,0 +1,123 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+	"github.com/luxfi/precompile/contract/statetest"
+	"github.com/luxfi/precompile/precompileconfig"
+)
+
+// testState is an AccessibleState over a test StateDB at a block time
+type testState struct {
+	db   *statetest.StateDB
+	time uint64
+}
+
+func newTestState() *testState {
+	return &testState{db: statetest.New()}
+}
+
+func (s *testState) GetStateDB() contract.StateDB                     { return s.db }
+func (s *testState) GetBlockContext() contract.BlockContext           { return testBlockContext(s.time) }
+func (s *testState) GetConsensusContext() context.Context             { return context.Background() }
+func (s *testState) GetChainConfig() precompileconfig.ChainConfig     { return nil }
+func (s *testState) GetPrecompileEnv() contract.PrecompileEnvironment { return nil }
+
+// testBlockContext is a block context at a timestamp
+type testBlockContext uint64
+
+func (b testBlockContext) Number() *big.Int                                       { return big.NewInt(1) }
+func (b testBlockContext) Timestamp() uint64                                      { return uint64(b) }
+func (b testBlockContext) GetPredicateResults(common.Hash, common.Address) []byte { return nil }
+
+// selectorCall builds calldata from a selector and 32-byte words
+func selectorCall(selector uint32, words ...[]byte) []byte {
+	calldata := binary.BigEndian.AppendUint32(nil, selector)
+	for _, word := range words {
+		calldata = append(calldata, word...)
+	}
+	return calldata
+}
+
+// TestAggregateQuery tests batching and per-call error isolation
+func TestAggregateQuery(t *testing.T) {
+	c := &DEXContract{poolManager: newTestPoolManager()}
+	controller := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	state := newTestState()
+	c.poolManager.setProtocolFeeController(newPoolStateAdapter(state), controller)
+
+	nested := EncodeAggregateCalls([]AggregateCall{{Target: lxPoolAddr, Calldata: selectorCall(SelectorFeeTierTickSpacing, encodeUint64(uint64(Fee030)))}})
+	calls := []AggregateCall{
+		{Target: lxPoolAddr, Calldata: selectorCall(SelectorFeeTierTickSpacing, encodeUint64(uint64(Fee030)))},
+		{Target: lxPoolAddr, Calldata: selectorCall(SelectorEnableFeeTier, encodeUint64(200), encodeUint64(4))},
+		{Target: common.HexToAddress("0xdead"), Calldata: selectorCall(SelectorGetPool)},
+		{Target: lxBondAddr, Calldata: selectorCall(SelectorGetBondMarket)},
+		{Target: lxPoolAddr, Calldata: selectorCall(SelectorAggregate, nested)},
+		{Target: lxPoolAddr, Calldata: selectorCall(SelectorGetReferrerStats, encodeUint64(1), encodeUint64(0))},
+	}
+	input := selectorCall(SelectorAggregate, EncodeAggregateCalls(calls))
+
+	// Unknown targets and nested queries cost only the per-call overhead
+	if gas, want := c.RequiredGas(input), 6*GasAggregateCall+3*GasPoolLookup+GasFeeTierUpdate; gas != want {
+		t.Errorf("Expected gas %d, got %d", want, gas)
+	}
+
+	out, remaining, err := c.Run(state, controller, lxPoolAddr, input, 1_000_000, false)
+	if err != nil {
+		t.Fatalf("aggregate failed: %v", err)
+	}
+	if remaining >= 1_000_000 {
+		t.Error("Expected aggregate to consume gas")
+	}
+	results, err := DecodeAggregateResults(out)
+	if err != nil {
+		t.Fatalf("DecodeAggregateResults failed: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("Expected %d results, got %d", len(calls), len(results))
+	}
+
+	want := []struct {
+		success bool
+		err     string
+	}{
+		{true, ""},
+		{false, "cannot write in read-only mode"},
+		{false, ErrInvalidAggregateTarget.Error()},
+		{false, "input too short"},
+		{false, ErrNestedAggregate.Error()},
+		{true, ""},
+	}
+	for i, w := range want {
+		if results[i].Success != w.success {
+			t.Errorf("Call %d: expected success %v, got %v (%s)", i, w.success, results[i].Success, results[i].ReturnData)
+			continue
+		}
+		if !w.success && string(results[i].ReturnData) != w.err {
+			t.Errorf("Call %d: expected error %q, got %q", i, w.err, results[i].ReturnData)
+		}
+	}
+	if spacing := decodeInt24Word(results[0].ReturnData); spacing != TickSpacing030 {
+		t.Errorf("Expected tick spacing %d, got %d", TickSpacing030, spacing)
+	}
+
+	// The read-only write did not take effect
+	if _, ok := c.poolManager.feeTiers.TickSpacing(newPoolStateAdapter(state), 200); ok {
+		t.Error("Expected aggregated write to be rejected")
+	}
+
+	if _, _, err := c.Run(state, controller, lxPoolAddr, selectorCall(SelectorAggregate, encodeUint64(0)), 1_000_000, false); err != ErrInvalidAggregate {
+		t.Errorf("Expected ErrInvalidAggregate for empty query, got %v", err)
+	}
+	if _, _, err := c.Run(state, controller, lxPoolAddr, input[:len(input)-1], 1_000_000, false); err != ErrInvalidAggregate {
+		t.Errorf("Expected ErrInvalidAggregate for truncated query, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+)
+
+// TWAP oracle observations
+//
+// Each pool keeps a ring buffer of observations, as UniswapV3Pool does.
+// An observation accumulates the pool's tick and 2^128 / liquidity over
+// time, so the average tick (and price) or in-range liquidity between any
+// two stored timestamps is the difference of their cumulatives divided by
+// the seconds between them. At most one observation is written per
+// timestamp, on the first swap that moves the tick or liquidity change in
+// range, recording the values in effect before it. Averages over a window
+// therefore cannot be moved by trading within a single block.
+//
+// The buffer starts with one slot, which holds only the latest value.
+// Anyone may grow it with IncreaseObservationCardinality; new slots are
+// used once the write index wraps around to them.
+//
+// Observations are read and written through StateDB, with no cache in
+// front, so a reverted call leaves nothing behind. They are stored under
+// observationPrefix:
+//
+//	poolId || index (2 bytes) || field -> observation field
+
+// observationStorageKey returns the storage key of a field of an observation
+func observationStorageKey(poolId [32]byte, index uint16, field string) common.Hash {
+	id := make([]byte, 0, 32+2+len(field))
+	id = append(id, poolId[:]...)
+	id = binary.BigEndian.AppendUint16(id, index)
+	id = append(id, field...)
+	return makeStorageKey(observationPrefix, id)
+}
+
+// getObservation retrieves an observation of a pool, uninitialized if absent
+func (pm *PoolManager) getObservation(stateDB StateDB, poolId [32]byte, index uint16) *TWAPObservation {
+	timeHash := stateDB.GetState(poolManagerAddr, observationStorageKey(poolId, index, "time"))
+	splHash := stateDB.GetState(poolManagerAddr, observationStorageKey(poolId, index, "spl"))
+	return &TWAPObservation{
+		Timestamp:           binary.BigEndian.Uint64(timeHash[23:31]),
+		TickCumulative:      hashToSigned(stateDB.GetState(poolManagerAddr, observationStorageKey(poolId, index, "tick"))),
+		SecondsPerLiquidity: new(big.Int).SetBytes(splHash[:]),
+		Initialized:         timeHash[31] == 1,
+	}
+}
+
+// setObservation saves an observation of a pool
+func (pm *PoolManager) setObservation(stateDB StateDB, poolId [32]byte, index uint16, obs *TWAPObservation) {
+	var timeHash, splHash common.Hash
+	binary.BigEndian.PutUint64(timeHash[23:31], obs.Timestamp)
+	if obs.Initialized {
+		timeHash[31] = 1
+	}
+	obs.SecondsPerLiquidity.FillBytes(splHash[:])
+	stateDB.SetState(poolManagerAddr, observationStorageKey(poolId, index, "time"), timeHash)
+	stateDB.SetState(poolManagerAddr, observationStorageKey(poolId, index, "tick"), signedToHash(obs.TickCumulative))
+	stateDB.SetState(poolManagerAddr, observationStorageKey(poolId, index, "spl"), splHash)
+}
+
+// transformObservation advances last to timestamp, with tick and liquidity
+// in effect since last was recorded
+func transformObservation(last *TWAPObservation, timestamp uint64, tick int24, liquidity *big.Int) *TWAPObservation {
+	elapsed := new(big.Int).SetUint64(timestamp - last.Timestamp)
+
+	tickCumulative := new(big.Int).Mul(big.NewInt(int64(tick)), elapsed)
+	tickCumulative.Add(tickCumulative, last.TickCumulative)
+
+	// With no liquidity in range, seconds accrue as if there were one unit
+	divisor := liquidity
+	if divisor.Sign() <= 0 {
+		divisor = big.NewInt(1)
+	}
+	secondsPerLiquidity := new(big.Int).Lsh(elapsed, 128)
+	secondsPerLiquidity.Div(secondsPerLiquidity, divisor)
+	secondsPerLiquidity.Add(secondsPerLiquidity, last.SecondsPerLiquidity)
+
+	return &TWAPObservation{
+		Timestamp:           timestamp,
+		TickCumulative:      tickCumulative,
+		SecondsPerLiquidity: secondsPerLiquidity,
+		Initialized:         true,
+	}
+}
+
+// initializeObservations starts the pool's buffer with one observation at
+// the block timestamp
+func (pm *PoolManager) initializeObservations(stateDB StateDB, poolId [32]byte, pool *Pool) {
+	pm.setObservation(stateDB, poolId, 0, &TWAPObservation{
+		Timestamp:           stateDB.GetBlockTimestamp(),
+		TickCumulative:      big.NewInt(0),
+		SecondsPerLiquidity: big.NewInt(0),
+		Initialized:         true,
+	})
+	pool.ObservationIndex = 0
+	pool.ObservationCardinality = 1
+	pool.ObservationCardinalityNext = 1
+}
+
+// writeObservation records tick and liquidity, in effect since the last
+// observation, at the block timestamp. The buffer grows into its next
+// cardinality once the write index reaches its current end.
+func (pm *PoolManager) writeObservation(stateDB StateDB, poolId [32]byte, pool *Pool, tick int24, liquidity *big.Int) {
+	// Pools created before observations start their buffer now
+	if pool.ObservationCardinality == 0 {
+		pm.initializeObservations(stateDB, poolId, pool)
+		return
+	}
+
+	last := pm.getObservation(stateDB, poolId, pool.ObservationIndex)
+	now := stateDB.GetBlockTimestamp()
+	if now <= last.Timestamp {
+		return
+	}
+
+	cardinality := pool.ObservationCardinality
+	if pool.ObservationCardinalityNext > cardinality && pool.ObservationIndex == cardinality-1 {
+		cardinality = pool.ObservationCardinalityNext
+	}
+	index := (pool.ObservationIndex + 1) % cardinality
+
+	pm.setObservation(stateDB, poolId, index, transformObservation(last, now, tick, liquidity))
+	pool.ObservationIndex = index
+	pool.ObservationCardinality = cardinality
+}
+
+// observeSingle returns the pool's cumulatives secondsAgo seconds before
+// now, interpolating between the observations around that time
+func (pm *PoolManager) observeSingle(stateDB StateDB, poolId [32]byte, pool *Pool, now uint64, secondsAgo uint32) (*TWAPObservation, error) {
+	if uint64(secondsAgo) > now {
+		return nil, ErrObservationTooOld
+	}
+	target := now - uint64(secondsAgo)
+
+	// At or after the latest observation, extrapolate from the pool's state
+	last := pm.getObservation(stateDB, poolId, pool.ObservationIndex)
+	if target >= last.Timestamp {
+		if target == last.Timestamp {
+			return last, nil
+		}
+		return transformObservation(last, target, pool.Tick, pool.Liquidity), nil
+	}
+
+	// The oldest observation follows the latest, unless the buffer has not
+	// wrapped yet
+	cardinality := pool.ObservationCardinality
+	oldest := pm.getObservation(stateDB, poolId, (pool.ObservationIndex+1)%cardinality)
+	if !oldest.Initialized {
+		oldest = pm.getObservation(stateDB, poolId, 0)
+	}
+	if target < oldest.Timestamp {
+		return nil, ErrObservationTooOld
+	}
+
+	// Binary search the ring, oldest to latest, for the observations
+	// around target
+	l := int(pool.ObservationIndex) + 1
+	r := l + int(cardinality) - 1
+	for {
+		i := (l + r) / 2
+		before := pm.getObservation(stateDB, poolId, uint16(i%int(cardinality)))
+		if !before.Initialized {
+			// Grown slots not yet written
+			l = i + 1
+			continue
+		}
+		after := pm.getObservation(stateDB, poolId, uint16((i+1)%int(cardinality)))
+
+		if before.Timestamp > target {
+			r = i - 1
+			continue
+		}
+		if target > after.Timestamp {
+			l = i + 1
+			continue
+		}
+
+		switch target {
+		case before.Timestamp:
+			return before, nil
+		case after.Timestamp:
+			return after, nil
+		}
+		return interpolateObservation(before, after, target), nil
+	}
+}
+
+// interpolateObservation returns the cumulatives at target, between the
+// observations before and after it
+func interpolateObservation(before, after *TWAPObservation, target uint64) *TWAPObservation {
+	observationDelta := new(big.Int).SetUint64(after.Timestamp - before.Timestamp)
+	targetDelta := new(big.Int).SetUint64(target - before.Timestamp)
+
+	// Average tick over the interval, truncated toward zero as in v3
+	tickCumulative := new(big.Int).Sub(after.TickCumulative, before.TickCumulative)
+	tickCumulative.Quo(tickCumulative, observationDelta)
+	tickCumulative.Mul(tickCumulative, targetDelta)
+	tickCumulative.Add(tickCumulative, before.TickCumulative)
+
+	secondsPerLiquidity := new(big.Int).Sub(after.SecondsPerLiquidity, before.SecondsPerLiquidity)
+	secondsPerLiquidity.Mul(secondsPerLiquidity, targetDelta)
+	secondsPerLiquidity.Div(secondsPerLiquidity, observationDelta)
+	secondsPerLiquidity.Add(secondsPerLiquidity, before.SecondsPerLiquidity)
+
+	return &TWAPObservation{
+		Timestamp:           target,
+		TickCumulative:      tickCumulative,
+		SecondsPerLiquidity: secondsPerLiquidity,
+		Initialized:         true,
+	}
+}
+
+// Observe returns the pool's tick and seconds-per-liquidity cumulatives as
+// of each secondsAgos before now. The average tick over a window is the
+// difference of two tick cumulatives divided by the window's length.
+func (pm *PoolManager) Observe(
+	stateDB StateDB,
+	key PoolKey,
+	secondsAgos []uint32,
+) (tickCumulatives []*big.Int, secondsPerLiquidityCumulativeX128s []*big.Int, err error) {
+	poolId := key.ID()
+	pool := pm.getPool(stateDB, poolId)
+	if !pool.IsInitialized() || pool.ObservationCardinality == 0 {
+		return nil, nil, ErrPoolNotInitialized
+	}
+
+	now := stateDB.GetBlockTimestamp()
+	tickCumulatives = make([]*big.Int, len(secondsAgos))
+	secondsPerLiquidityCumulativeX128s = make([]*big.Int, len(secondsAgos))
+	for i, secondsAgo := range secondsAgos {
+		obs, err := pm.observeSingle(stateDB, poolId, pool, now, secondsAgo)
+		if err != nil {
+			return nil, nil, err
+		}
+		tickCumulatives[i] = new(big.Int).Set(obs.TickCumulative)
+		secondsPerLiquidityCumulativeX128s[i] = new(big.Int).Set(obs.SecondsPerLiquidity)
+	}
+	return tickCumulatives, secondsPerLiquidityCumulativeX128s, nil
+}
+
+// Observations returns a copy of the observation at index in the pool's
+// buffer. Slots not yet written are uninitialized.
+func (pm *PoolManager) Observations(stateDB StateDB, key PoolKey, index uint16) (*TWAPObservation, error) {
+	poolId := key.ID()
+	pool := pm.getPool(stateDB, poolId)
+	if !pool.IsInitialized() {
+		return nil, ErrPoolNotInitialized
+	}
+
+	obs := pm.getObservation(stateDB, poolId, index)
+	return &TWAPObservation{
+		Timestamp:           obs.Timestamp,
+		TickCumulative:      new(big.Int).Set(obs.TickCumulative),
+		SecondsPerLiquidity: new(big.Int).Set(obs.SecondsPerLiquidity),
+		Initialized:         obs.Initialized,
+	}, nil
+}
+
+// IncreaseObservationCardinality grows the pool's observation buffer to
+// hold next observations, returning the previous and new size. Smaller
+// sizes leave it unchanged.
+func (pm *PoolManager) IncreaseObservationCardinality(stateDB StateDB, key PoolKey, next uint16) (uint16, uint16, error) {
+	poolId := key.ID()
+	pool := pm.getPool(stateDB, poolId)
+	if !pool.IsInitialized() {
+		return 0, 0, ErrPoolNotInitialized
+	}
+	if pool.ObservationCardinality == 0 {
+		pm.initializeObservations(stateDB, poolId, pool)
+	}
+
+	previous := pool.ObservationCardinalityNext
+	if next > previous {
+		pool.ObservationCardinalityNext = next
+	}
+	pm.setPool(stateDB, poolId, pool)
+	return previous, pool.ObservationCardinalityNext, nil
+}
@@ -0,0 +1,128 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// TestObservations tests that swaps write one observation per timestamp,
+// that observe interpolates between them, and that the buffer wraps and
+// reloads from state
+func TestObservations(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+	poolId := key.ID()
+	caller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	stateDB.SetBlockTimestamp(1000)
+
+	if _, err := pm.Initialize(stateDB, key, encodePriceSqrt(1, 1), nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	obs, err := pm.Observations(stateDB, key, 0)
+	if err != nil {
+		t.Fatalf("Observations failed: %v", err)
+	}
+	if obs.Timestamp != 1000 || !obs.Initialized || obs.TickCumulative.Sign() != 0 {
+		t.Errorf("Expected initial observation at 1000, got %+v", obs)
+	}
+
+	openLock(pm, caller)
+	la := expandTo18Decimals(1)
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, ModifyLiquidityParams{TickLower: -60, TickUpper: 60, LiquidityDelta: la}, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+
+	if previous, next, err := pm.IncreaseObservationCardinality(stateDB, key, 3); err != nil || previous != 1 || next != 3 {
+		t.Fatalf("Expected cardinality to grow from 1 to 3, got %d to %d: %v", previous, next, err)
+	}
+	if previous, next, _ := pm.IncreaseObservationCardinality(stateDB, key, 2); previous != 3 || next != 3 {
+		t.Errorf("Expected a smaller cardinality to be ignored, got %d to %d", previous, next)
+	}
+
+	swap := func(zeroForOne bool, amount int64) int24 {
+		t.Helper()
+		if _, err := pm.Swap(stateDB, key, SwapParams{ZeroForOne: zeroForOne, AmountSpecified: big.NewInt(amount)}, nil); err != nil {
+			t.Fatalf("Swap failed: %v", err)
+		}
+		return pm.pools[poolId].Tick
+	}
+
+	// Tick 0 until 1010, tick1 until 1020, then tick2
+	stateDB.SetBlockTimestamp(1010)
+	tick1 := swap(true, 1_000_000_000_000_000)
+	stateDB.SetBlockTimestamp(1020)
+	tick2 := swap(false, 2_000_000_000_000_000)
+	if tick1 >= 0 || tick2 <= 0 {
+		t.Fatalf("Expected swaps to move the tick down then up, got %d and %d", tick1, tick2)
+	}
+	pool := pm.pools[poolId]
+	if pool.ObservationIndex != 2 || pool.ObservationCardinality != 3 {
+		t.Errorf("Expected observation 2 of 3, got %d of %d", pool.ObservationIndex, pool.ObservationCardinality)
+	}
+
+	// A second swap in the same second writes nothing
+	tick2 = swap(true, 1_000_000)
+	if pool = pm.pools[poolId]; pool.ObservationIndex != 2 {
+		t.Errorf("Expected one observation per timestamp, got index %d", pool.ObservationIndex)
+	}
+
+	stateDB.SetBlockTimestamp(1030)
+	t1, t2 := int64(tick1), int64(tick2)
+	secondsAgos := []uint32{0, 5, 10, 15, 20, 30}
+	want := []int64{t1*10 + t2*10, t1*10 + t2*5, t1 * 10, t1 * 5, 0, 0}
+	check := func(pm *PoolManager) {
+		t.Helper()
+		tickCumulatives, secondsPerLiquidity, err := pm.Observe(stateDB, key, secondsAgos)
+		if err != nil {
+			t.Fatalf("Observe failed: %v", err)
+		}
+		for i := range secondsAgos {
+			if tickCumulatives[i].Int64() != want[i] {
+				t.Errorf("%d seconds ago: tick cumulative %s, want %d", secondsAgos[i], tickCumulatives[i], want[i])
+			}
+		}
+		// Liquidity la was in range from 1000 to 1010
+		spl := new(big.Int).Div(new(big.Int).Lsh(big.NewInt(10), 128), la)
+		if secondsPerLiquidity[4].Cmp(spl) != 0 || secondsPerLiquidity[5].Sign() != 0 {
+			t.Errorf("Expected seconds per liquidity 0 then %s, got %s and %s", spl, secondsPerLiquidity[5], secondsPerLiquidity[4])
+		}
+	}
+	check(pm)
+
+	// The average tick over the last 10 seconds is the current tick
+	tickCumulatives, _, _ := pm.Observe(stateDB, key, []uint32{10, 0})
+	if twap := new(big.Int).Sub(tickCumulatives[1], tickCumulatives[0]).Int64() / 10; twap != t2 {
+		t.Errorf("Expected TWAP tick %d, got %d", t2, twap)
+	}
+
+	// Observations persist across restarts
+	restarted := NewPoolManager()
+	check(restarted)
+
+	if _, _, err := pm.Observe(stateDB, key, []uint32{31}); err != ErrObservationTooOld {
+		t.Errorf("Expected ErrObservationTooOld, got: %v", err)
+	}
+
+	// The buffer wraps, overwriting the oldest observation
+	stateDB.SetBlockTimestamp(1040)
+	swap(true, 1_000_000_000_000_000)
+	if pool = pm.pools[poolId]; pool.ObservationIndex != 0 {
+		t.Errorf("Expected the buffer to wrap to index 0, got %d", pool.ObservationIndex)
+	}
+	if _, _, err := pm.Observe(stateDB, key, []uint32{40}); err != ErrObservationTooOld {
+		t.Errorf("Expected ErrObservationTooOld after wrapping, got: %v", err)
+	}
+	if _, _, err := pm.Observe(stateDB, key, []uint32{30}); err != nil {
+		t.Errorf("Observe of the oldest observation failed: %v", err)
+	}
+
+	if _, _, err := pm.Observe(stateDB, PoolKey{}, []uint32{0}); err != ErrPoolNotInitialized {
+		t.Errorf("Expected ErrPoolNotInitialized, got: %v", err)
+	}
+}
@@ -0,0 +1,477 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+	"github.com/zeebo/blake3"
+)
+
+// =========================================================================
+// Omnichain Swaps
+// =========================================================================
+//
+// An omnichain swap composes three legs into a single OmnichainRoute:
+//
+//	source swaps -> teleport -> destination swaps
+//
+// Either swap leg may be empty. Every hop carries its own MinAmountOut, so a
+// price move on any leg stops the route rather than letting slippage
+// compound. A hop that fails before the teleport is burned refunds the
+// sender on the source chain in whatever token they held at that point; a
+// hop that fails after arrival refunds the recipient on the destination
+// chain in the bridged (or partially swapped) token.
+
+// HopSwapper quotes and executes single-pool swap hops
+type HopSwapper interface {
+	// QuoteExactIn returns the expected output of swapping hop.AmountIn
+	QuoteExactIn(hop RouteHop) (*big.Int, error)
+
+	// SwapExactIn swaps hop.AmountIn and returns the realized output
+	SwapExactIn(hop RouteHop) (*big.Int, error)
+}
+
+// RouteEscrow custodies the funds of an in-flight route and pays refunds
+type RouteEscrow interface {
+	// Refund transfers amount of token to recipient on chainID
+	Refund(chainID uint32, token, recipient common.Address, amount *big.Int) error
+}
+
+// OmnichainSwapParams describes a requested cross-chain swap
+type OmnichainSwapParams struct {
+	Sender       common.Address
+	Recipient    common.Address
+	SourceChain  uint32
+	DestChain    uint32
+	TokenIn      common.Address // Token spent on the source chain
+	AmountIn     *big.Int
+	MinAmountOut *big.Int   // Minimum final output (nil = per-hop slippage only)
+	SlippageBps  uint32     // Per-hop tolerance below the quoted output
+	SourcePath   []RouteHop // Swaps before teleporting (PoolID, TokenIn, TokenOut)
+	DestPath     []RouteHop // Swaps after arrival (PoolID, TokenIn, TokenOut)
+}
+
+// RouteExecution tracks the progress of an omnichain route
+type RouteExecution struct {
+	Route      *OmnichainRoute
+	Sender     common.Address
+	Recipient  common.Address
+	Status     RouteStatus
+	NextHop    int        // Index of the next hop to execute
+	Outputs    []*big.Int // Realized output of each executed hop
+	TeleportID [32]byte   // Set once the teleport hop is initiated
+
+	// Unsigned Warp message emitted by the teleport hop, to be signed by
+	// source chain validators and passed to CompleteOmnichainSwap
+	WarpMessage *WarpMessage
+
+	// Refund details, set when Status is RouteRefunded or RouteRefundFailed
+	FailedHop    int
+	RefundTo     common.Address
+	RefundChain  uint32
+	RefundToken  common.Address
+	RefundAmount *big.Int
+}
+
+// SetSwapper sets the executor used for local swap hops
+func (or *OmnichainRouter) SetSwapper(swapper HopSwapper) {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	or.Swapper = swapper
+}
+
+// SetEscrow sets the custodian that pays refunds of failed routes
+func (or *OmnichainRouter) SetEscrow(escrow RouteEscrow) {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	or.Escrow = escrow
+}
+
+// SetDestBridge sets the bridge that completes teleports arriving on its
+// chain
+func (or *OmnichainRouter) SetDestBridge(bridge *TeleportBridge) {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	or.DestBridges[bridge.ChainID] = bridge
+}
+
+// PlanOmnichainSwap quotes every hop of a cross-chain swap, assigns per-hop
+// minimum outputs and records the route for execution
+func (or *OmnichainRouter) PlanOmnichainSwap(params OmnichainSwapParams) (*OmnichainRoute, error) {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+
+	if params.AmountIn == nil || params.AmountIn.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if params.SlippageBps > 10000 || params.SourceChain == params.DestChain {
+		return nil, ErrInvalidRoute
+	}
+	if (len(params.SourcePath) > 0 || len(params.DestPath) > 0) && or.Swapper == nil {
+		return nil, ErrNoSwapper
+	}
+	if or.Escrow == nil {
+		return nil, ErrNoRouteEscrow
+	}
+
+	route := or.getRoute(params.SourceChain, params.DestChain)
+	if route == nil || !route.IsActive {
+		return nil, ErrNoRouteFound
+	}
+
+	plan := &OmnichainRoute{
+		SourceChain: params.SourceChain,
+		DestChain:   params.DestChain,
+		Hops:        make([]RouteHop, 0, len(params.SourcePath)+len(params.DestPath)+1),
+	}
+
+	token := params.TokenIn
+	amount := new(big.Int).Set(params.AmountIn)
+
+	// Source chain swaps
+	for _, leg := range params.SourcePath {
+		hop, quote, err := or.planSwapHop(params.SourceChain, leg, token, amount, params.SlippageBps)
+		if err != nil {
+			return nil, err
+		}
+		plan.Hops = append(plan.Hops, hop)
+		plan.TotalGas += GasSwap
+		token, amount = hop.TokenOut, quote
+	}
+
+	// Teleport hop
+	remote, err := or.Bridge.RemoteToken(params.SourceChain, token)
+	if err != nil {
+		return nil, err
+	}
+	if remaining := new(big.Int).Sub(route.MaxCapacity, route.UsedToday); remaining.Cmp(amount) < 0 {
+		return nil, ErrNoRouteFound
+	}
+	teleported := or.Bridge.QuoteTeleport(subRouteFee(amount, route.Fee))
+	plan.Hops = append(plan.Hops, RouteHop{
+		Kind:         HopTeleport,
+		ChainID:      params.SourceChain,
+		TokenIn:      token,
+		TokenOut:     remote,
+		AmountIn:     amount,
+		MinAmountOut: applySlippage(teleported, params.SlippageBps),
+	})
+	plan.TotalGas += GasTeleportInit + GasTeleportComplete
+	token, amount = remote, teleported
+
+	// Destination chain swaps
+	for _, leg := range params.DestPath {
+		hop, quote, err := or.planSwapHop(params.DestChain, leg, token, amount, params.SlippageBps)
+		if err != nil {
+			return nil, err
+		}
+		plan.Hops = append(plan.Hops, hop)
+		plan.TotalGas += GasSwap
+		token, amount = hop.TokenOut, quote
+	}
+
+	// The caller's floor applies to the final hop
+	if params.MinAmountOut != nil {
+		if amount.Cmp(params.MinAmountOut) < 0 {
+			return nil, ErrHopSlippage
+		}
+		last := &plan.Hops[len(plan.Hops)-1]
+		if last.MinAmountOut.Cmp(params.MinAmountOut) < 0 {
+			last.MinAmountOut = new(big.Int).Set(params.MinAmountOut)
+		}
+	}
+	plan.TotalEstimate = amount
+
+	or.routeNonce++
+	plan.RouteID = or.generateRouteID(params, or.routeNonce)
+
+	or.Executions[plan.RouteID] = &RouteExecution{
+		Route:     plan,
+		Sender:    params.Sender,
+		Recipient: params.Recipient,
+		Status:    RoutePlanned,
+		Outputs:   make([]*big.Int, 0, len(plan.Hops)),
+		FailedHop: -1,
+	}
+
+	return plan, nil
+}
+
+// ExecuteOmnichainSwap runs the source chain swaps and burns the teleport.
+// If any hop misses its minimum the route is refunded on the source chain
+// and the hop's error is returned, joined with the refund's error if the
+// refund transfer failed.
+func (or *OmnichainRouter) ExecuteOmnichainSwap(routeID [32]byte) (*RouteExecution, error) {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+
+	exec := or.Executions[routeID]
+	if exec == nil {
+		return nil, ErrRouteNotFound
+	}
+	if exec.Status != RoutePlanned {
+		return nil, ErrInvalidRouteState
+	}
+
+	token := exec.Route.Hops[0].TokenIn
+	amount := new(big.Int).Set(exec.Route.Hops[0].AmountIn)
+
+	for exec.NextHop < len(exec.Route.Hops) {
+		hop := exec.Route.Hops[exec.NextHop]
+		if hop.Kind == HopTeleport {
+			break
+		}
+
+		out, err := or.executeSwapHop(hop, amount)
+		if err != nil {
+			return exec, errors.Join(err, or.refund(exec, exec.Sender, exec.Route.SourceChain, token, amount))
+		}
+		exec.Outputs = append(exec.Outputs, out)
+		exec.NextHop++
+		token, amount = hop.TokenOut, out
+	}
+
+	if err := or.executeTeleportHop(exec, amount); err != nil {
+		return exec, errors.Join(err, or.refund(exec, exec.Sender, exec.Route.SourceChain, token, amount))
+	}
+
+	return exec, nil
+}
+
+// CompleteOmnichainSwap finalizes the teleport with its Warp attestation on
+// the destination chain's bridge and state, and runs the destination chain
+// swaps. If a destination hop misses its minimum the recipient is refunded
+// on the destination chain.
+func (or *OmnichainRouter) CompleteOmnichainSwap(stateDB StateDB, routeID [32]byte, message *WarpMessage) (*RouteExecution, error) {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+
+	exec := or.Executions[routeID]
+	if exec == nil {
+		return nil, ErrRouteNotFound
+	}
+	if exec.Status != RouteInTransit {
+		return nil, ErrInvalidRouteState
+	}
+
+	// The attestation must be for this route's teleport
+	if message == nil {
+		return nil, ErrInvalidWarpMessage
+	}
+	request, err := DecodeTeleportPayload(message.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if request.TeleportID != exec.TeleportID {
+		return nil, ErrInvalidWarpMessage
+	}
+	bridge := or.DestBridges[exec.Route.DestChain]
+	if bridge == nil {
+		return nil, ErrInvalidChainID
+	}
+	if _, err := bridge.CompleteTeleport(stateDB, message); err != nil {
+		return nil, err
+	}
+
+	teleportHop := exec.Route.Hops[exec.NextHop-1]
+	token := teleportHop.TokenOut
+	amount := exec.Outputs[len(exec.Outputs)-1]
+
+	for exec.NextHop < len(exec.Route.Hops) {
+		hop := exec.Route.Hops[exec.NextHop]
+
+		out, err := or.executeSwapHop(hop, amount)
+		if err != nil {
+			return exec, errors.Join(err, or.refund(exec, exec.Recipient, exec.Route.DestChain, token, amount))
+		}
+		exec.Outputs = append(exec.Outputs, out)
+		exec.NextHop++
+		token, amount = hop.TokenOut, out
+	}
+
+	exec.Status = RouteCompleted
+	return exec, nil
+}
+
+// GetRouteStatus returns the status of an omnichain route
+func (or *OmnichainRouter) GetRouteStatus(routeID [32]byte) (RouteStatus, error) {
+	or.mu.RLock()
+	defer or.mu.RUnlock()
+
+	exec := or.Executions[routeID]
+	if exec == nil {
+		return 0, ErrRouteNotFound
+	}
+	return exec.Status, nil
+}
+
+// GetRouteExecution returns the execution state of an omnichain route
+func (or *OmnichainRouter) GetRouteExecution(routeID [32]byte) (*RouteExecution, error) {
+	or.mu.RLock()
+	defer or.mu.RUnlock()
+
+	exec := or.Executions[routeID]
+	if exec == nil {
+		return nil, ErrRouteNotFound
+	}
+	return exec, nil
+}
+
+// Helper functions
+
+// planSwapHop quotes a swap leg and returns the hop with its minimum output
+// along with the quoted output
+func (or *OmnichainRouter) planSwapHop(
+	chainID uint32,
+	leg RouteHop,
+	tokenIn common.Address,
+	amountIn *big.Int,
+	slippageBps uint32,
+) (RouteHop, *big.Int, error) {
+	if leg.TokenIn != tokenIn || leg.TokenOut == tokenIn {
+		return RouteHop{}, nil, ErrInvalidRoute
+	}
+
+	hop := RouteHop{
+		Kind:     HopSwap,
+		ChainID:  chainID,
+		PoolID:   leg.PoolID,
+		TokenIn:  leg.TokenIn,
+		TokenOut: leg.TokenOut,
+		AmountIn: new(big.Int).Set(amountIn),
+	}
+
+	quote, err := or.Swapper.QuoteExactIn(hop)
+	if err != nil {
+		return RouteHop{}, nil, err
+	}
+	if quote.Sign() <= 0 {
+		return RouteHop{}, nil, ErrInsufficientLiquidity
+	}
+
+	hop.MinAmountOut = applySlippage(quote, slippageBps)
+	return hop, quote, nil
+}
+
+func (or *OmnichainRouter) executeSwapHop(hop RouteHop, amountIn *big.Int) (*big.Int, error) {
+	if or.Swapper == nil {
+		return nil, ErrNoSwapper
+	}
+
+	hop.AmountIn = new(big.Int).Set(amountIn)
+	out, err := or.Swapper.SwapExactIn(hop)
+	if err != nil {
+		return nil, err
+	}
+	if out.Cmp(hop.MinAmountOut) < 0 {
+		return nil, ErrHopSlippage
+	}
+	return out, nil
+}
+
+// executeTeleportHop initiates and burns the teleport for amount. Nothing is
+// left pending on the bridge if it fails; if the pending teleport cannot be
+// cancelled either, both errors are returned.
+func (or *OmnichainRouter) executeTeleportHop(exec *RouteExecution, amount *big.Int) error {
+	hop := exec.Route.Hops[exec.NextHop]
+
+	route := or.getRoute(exec.Route.SourceChain, exec.Route.DestChain)
+	if route == nil || !route.IsActive {
+		return ErrNoRouteFound
+	}
+	if remaining := new(big.Int).Sub(route.MaxCapacity, route.UsedToday); remaining.Cmp(amount) < 0 {
+		return ErrNoRouteFound
+	}
+
+	// Check the hop floor before touching the bridge
+	netAmount := subRouteFee(amount, route.Fee)
+	if or.Bridge.QuoteTeleport(netAmount).Cmp(hop.MinAmountOut) < 0 {
+		return ErrHopSlippage
+	}
+
+	request, err := or.Bridge.InitiateTeleport(
+		exec.Sender, exec.Route.DestChain, exec.Recipient, hop.TokenIn, netAmount, exec.Route.SourceChain,
+	)
+	if err != nil {
+		return err
+	}
+	message, err := or.Bridge.BurnForTeleport(request.TeleportID)
+	if err != nil {
+		return errors.Join(err, or.Bridge.CancelTeleport(exec.Sender, request.TeleportID))
+	}
+
+	route.UsedToday.Add(route.UsedToday, amount)
+
+	exec.TeleportID = request.TeleportID
+	exec.WarpMessage = message
+	exec.Outputs = append(exec.Outputs, new(big.Int).Set(request.Amount))
+	exec.NextHop++
+	exec.Status = RouteInTransit
+	return nil
+}
+
+// refund pays amount of token back to to through the escrow and records the
+// refund on the execution. A failed transfer leaves the route in
+// RouteRefundFailed with the owed refund recorded.
+func (or *OmnichainRouter) refund(
+	exec *RouteExecution,
+	to common.Address,
+	chainID uint32,
+	token common.Address,
+	amount *big.Int,
+) error {
+	exec.FailedHop = exec.NextHop
+	exec.RefundTo = to
+	exec.RefundChain = chainID
+	exec.RefundToken = token
+	exec.RefundAmount = new(big.Int).Set(amount)
+
+	if or.Escrow == nil {
+		exec.Status = RouteRefundFailed
+		return ErrNoRouteEscrow
+	}
+	if err := or.Escrow.Refund(chainID, token, to, amount); err != nil {
+		exec.Status = RouteRefundFailed
+		return err
+	}
+	exec.Status = RouteRefunded
+	return nil
+}
+
+func (or *OmnichainRouter) generateRouteID(params OmnichainSwapParams, nonce uint64) [32]byte {
+	var buf [8]byte
+
+	hasher := blake3.New()
+	hasher.Write(params.Sender[:])
+	hasher.Write(params.Recipient[:])
+	binary.BigEndian.PutUint32(buf[:4], params.SourceChain)
+	hasher.Write(buf[:4])
+	binary.BigEndian.PutUint32(buf[:4], params.DestChain)
+	hasher.Write(buf[:4])
+	hasher.Write(params.TokenIn[:])
+	hasher.Write(params.AmountIn.Bytes())
+	binary.BigEndian.PutUint64(buf[:], nonce)
+	hasher.Write(buf[:])
+
+	var id [32]byte
+	copy(id[:], hasher.Sum(nil))
+	return id
+}
+
+// subRouteFee deducts a routing fee in basis points
+func subRouteFee(amount *big.Int, feeBps uint32) *big.Int {
+	fee := new(big.Int).Mul(amount, big.NewInt(int64(feeBps)))
+	fee.Div(fee, big.NewInt(10000))
+	return new(big.Int).Sub(amount, fee)
+}
+
+// applySlippage returns amount reduced by slippageBps
+func applySlippage(amount *big.Int, slippageBps uint32) *big.Int {
+	out := new(big.Int).Mul(amount, big.NewInt(int64(10000-slippageBps)))
+	return out.Div(out, big.NewInt(10000))
+}
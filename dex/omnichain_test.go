@@ -0,0 +1,311 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var (
+	omniSender    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	omniRecipient = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	omniWLUX      = common.HexToAddress("0x0000000000000000000000000000000000000a01")
+	omniUSDC      = common.HexToAddress("0x0000000000000000000000000000000000000a02")
+	omniRemoteUSD = common.HexToAddress("0x0000000000000000000000000000000000000b02")
+	omniWETH      = common.HexToAddress("0x0000000000000000000000000000000000000b03")
+	omniSrcPool   = [32]byte{0x01}
+	omniDstPool   = [32]byte{0x02}
+)
+
+// mockHopSwapper prices each pool at a fixed num/den rate. execBps scales
+// the realized output relative to the quote to simulate price moves.
+type mockHopSwapper struct {
+	rates   map[[32]byte][2]int64
+	execBps map[[32]byte]int64
+}
+
+func (m *mockHopSwapper) QuoteExactIn(hop RouteHop) (*big.Int, error) {
+	rate, ok := m.rates[hop.PoolID]
+	if !ok {
+		return nil, ErrPoolNotFound
+	}
+	out := new(big.Int).Mul(hop.AmountIn, big.NewInt(rate[0]))
+	return out.Div(out, big.NewInt(rate[1])), nil
+}
+
+func (m *mockHopSwapper) SwapExactIn(hop RouteHop) (*big.Int, error) {
+	out, err := m.QuoteExactIn(hop)
+	if err != nil {
+		return nil, err
+	}
+	if bps, ok := m.execBps[hop.PoolID]; ok {
+		out.Mul(out, big.NewInt(bps))
+		out.Div(out, big.NewInt(10000))
+	}
+	return out, nil
+}
+
+// mockRouteEscrow records refund transfers; err makes every refund fail
+type mockRouteEscrow struct {
+	refunds []*big.Int
+	err     error
+}
+
+func (m *mockRouteEscrow) Refund(chainID uint32, token, recipient common.Address, amount *big.Int) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.refunds = append(m.refunds, new(big.Int).Set(amount))
+	return nil
+}
+
+// newTestOmnichainRouter returns a router from Lux to ETH and the state of
+// the ETH bridge that completes its teleports
+func newTestOmnichainRouter(t *testing.T) (*OmnichainRouter, *mockHopSwapper, *MockStateDB) {
+	t.Helper()
+
+	bridge := NewTeleportBridge(testNetworkID, ChainLux, 1)
+	dest := NewTeleportBridge(testNetworkID, ChainETH, 1)
+	limit := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e9))
+	if err := bridge.AddSupportedToken(ChainLux, omniUSDC, omniRemoteUSD, 18, limit, limit, big.NewInt(1)); err != nil {
+		t.Fatalf("AddSupportedToken failed: %v", err)
+	}
+	if err := dest.AddSupportedToken(ChainETH, omniRemoteUSD, omniUSDC, 18, limit, limit, big.NewInt(1)); err != nil {
+		t.Fatalf("AddSupportedToken failed: %v", err)
+	}
+	destState := NewMockStateDB()
+	setTestWarpValidators(t, destState, dest, ChainLux, 3)
+
+	router := NewOmnichainRouter(bridge)
+	router.SetDestBridge(dest)
+	if err := router.AddRoute(ChainLux, ChainETH, 10, limit); err != nil {
+		t.Fatalf("AddRoute failed: %v", err)
+	}
+
+	swapper := &mockHopSwapper{
+		rates: map[[32]byte][2]int64{
+			omniSrcPool: {2, 1}, // 1 WLUX = 2 USDC
+			omniDstPool: {1, 2}, // 2 USD = 1 WETH
+		},
+		execBps: make(map[[32]byte]int64),
+	}
+	router.SetSwapper(swapper)
+	router.SetEscrow(&mockRouteEscrow{})
+	return router, swapper, destState
+}
+
+func newTestOmnichainParams() OmnichainSwapParams {
+	return OmnichainSwapParams{
+		Sender:      omniSender,
+		Recipient:   omniRecipient,
+		SourceChain: ChainLux,
+		DestChain:   ChainETH,
+		TokenIn:     omniWLUX,
+		AmountIn:    new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18)),
+		SlippageBps: 50,
+		SourcePath:  []RouteHop{{PoolID: omniSrcPool, TokenIn: omniWLUX, TokenOut: omniUSDC}},
+		DestPath:    []RouteHop{{PoolID: omniDstPool, TokenIn: omniRemoteUSD, TokenOut: omniWETH}},
+	}
+}
+
+func TestPlanOmnichainSwap(t *testing.T) {
+	router, _, _ := newTestOmnichainRouter(t)
+
+	plan, err := router.PlanOmnichainSwap(newTestOmnichainParams())
+	if err != nil {
+		t.Fatalf("PlanOmnichainSwap failed: %v", err)
+	}
+
+	if len(plan.Hops) != 3 {
+		t.Fatalf("Expected 3 hops, got %d", len(plan.Hops))
+	}
+	kinds := []HopKind{HopSwap, HopTeleport, HopSwap}
+	chains := []uint32{ChainLux, ChainLux, ChainETH}
+	for i, hop := range plan.Hops {
+		if hop.Kind != kinds[i] || hop.ChainID != chains[i] {
+			t.Errorf("Hop %d: got kind %d chain %d", i, hop.Kind, hop.ChainID)
+		}
+		if hop.MinAmountOut == nil || hop.MinAmountOut.Sign() <= 0 {
+			t.Errorf("Hop %d has no minimum output", i)
+		}
+	}
+
+	// 1000 WLUX -> 2000 USDC, less 0.1% route fee and 0.3% bridge fee
+	teleportIn := new(big.Int).Mul(big.NewInt(2000), big.NewInt(1e18))
+	if plan.Hops[1].AmountIn.Cmp(teleportIn) != 0 {
+		t.Errorf("Expected teleport input %s, got %s", teleportIn, plan.Hops[1].AmountIn)
+	}
+	if plan.Hops[1].TokenOut != omniRemoteUSD {
+		t.Errorf("Teleport hop should deliver the remote token")
+	}
+	if plan.TotalEstimate.Cmp(new(big.Int).Div(teleportIn, big.NewInt(2))) >= 0 {
+		t.Errorf("Estimate should include route and bridge fees, got %s", plan.TotalEstimate)
+	}
+	if plan.TotalGas != 2*GasSwap+GasTeleportInit+GasTeleportComplete {
+		t.Errorf("Unexpected gas estimate %d", plan.TotalGas)
+	}
+
+	status, err := router.GetRouteStatus(plan.RouteID)
+	if err != nil || status != RoutePlanned {
+		t.Errorf("Expected RoutePlanned, got %d (%v)", status, err)
+	}
+}
+
+func TestPlanOmnichainSwapValidation(t *testing.T) {
+	router, _, _ := newTestOmnichainRouter(t)
+
+	params := newTestOmnichainParams()
+	params.SourcePath[0].TokenIn = omniUSDC
+	if _, err := router.PlanOmnichainSwap(params); !errors.Is(err, ErrInvalidRoute) {
+		t.Errorf("Expected ErrInvalidRoute for broken path, got %v", err)
+	}
+
+	params = newTestOmnichainParams()
+	params.DestChain = ChainArb
+	if _, err := router.PlanOmnichainSwap(params); !errors.Is(err, ErrNoRouteFound) {
+		t.Errorf("Expected ErrNoRouteFound, got %v", err)
+	}
+
+	params = newTestOmnichainParams()
+	params.MinAmountOut = new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+	if _, err := router.PlanOmnichainSwap(params); !errors.Is(err, ErrHopSlippage) {
+		t.Errorf("Expected ErrHopSlippage for unreachable floor, got %v", err)
+	}
+
+	if _, err := router.GetRouteStatus([32]byte{0xff}); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("Expected ErrRouteNotFound, got %v", err)
+	}
+}
+
+func TestOmnichainSwapCompletes(t *testing.T) {
+	router, _, destState := newTestOmnichainRouter(t)
+
+	plan, err := router.PlanOmnichainSwap(newTestOmnichainParams())
+	if err != nil {
+		t.Fatalf("PlanOmnichainSwap failed: %v", err)
+	}
+
+	exec, err := router.ExecuteOmnichainSwap(plan.RouteID)
+	if err != nil {
+		t.Fatalf("ExecuteOmnichainSwap failed: %v", err)
+	}
+	if exec.Status != RouteInTransit {
+		t.Fatalf("Expected RouteInTransit, got %d", exec.Status)
+	}
+	if pending := router.Bridge.PendingTeleports[exec.TeleportID]; pending == nil || pending.Status != TeleportBurned {
+		t.Errorf("Expected teleport to be burned")
+	}
+
+	if _, err := router.ExecuteOmnichainSwap(plan.RouteID); !errors.Is(err, ErrInvalidRouteState) {
+		t.Errorf("Expected ErrInvalidRouteState on re-execution, got %v", err)
+	}
+
+	if _, err := router.CompleteOmnichainSwap(destState, plan.RouteID, signTestWarp(t, exec.WarpMessage, 0)); !errors.Is(err, ErrInsufficientSignatures) {
+		t.Errorf("Expected ErrInsufficientSignatures without quorum, got %v", err)
+	}
+
+	exec, err = router.CompleteOmnichainSwap(destState, plan.RouteID, signTestWarp(t, exec.WarpMessage, 0, 1, 2))
+	if err != nil {
+		t.Fatalf("CompleteOmnichainSwap failed: %v", err)
+	}
+	if exec.Status != RouteCompleted {
+		t.Errorf("Expected RouteCompleted, got %d", exec.Status)
+	}
+	if len(exec.Outputs) != 3 {
+		t.Fatalf("Expected 3 hop outputs, got %d", len(exec.Outputs))
+	}
+	if exec.Outputs[2].Cmp(plan.TotalEstimate) != 0 {
+		t.Errorf("Expected final output %s, got %s", plan.TotalEstimate, exec.Outputs[2])
+	}
+}
+
+func TestOmnichainSwapSourceSlippageRefunds(t *testing.T) {
+	router, swapper, _ := newTestOmnichainRouter(t)
+
+	params := newTestOmnichainParams()
+	plan, err := router.PlanOmnichainSwap(params)
+	if err != nil {
+		t.Fatalf("PlanOmnichainSwap failed: %v", err)
+	}
+
+	// Price moves 1% against the sender, beyond the 0.5% tolerance
+	swapper.execBps[omniSrcPool] = 9900
+
+	exec, err := router.ExecuteOmnichainSwap(plan.RouteID)
+	if !errors.Is(err, ErrHopSlippage) {
+		t.Fatalf("Expected ErrHopSlippage, got %v", err)
+	}
+	if exec.Status != RouteRefunded || exec.FailedHop != 0 {
+		t.Errorf("Expected refund at hop 0, got status %d hop %d", exec.Status, exec.FailedHop)
+	}
+	if exec.RefundTo != omniSender || exec.RefundChain != ChainLux || exec.RefundToken != omniWLUX {
+		t.Errorf("Refund should return WLUX to the sender on the source chain")
+	}
+	if exec.RefundAmount.Cmp(params.AmountIn) != 0 {
+		t.Errorf("Expected full refund %s, got %s", params.AmountIn, exec.RefundAmount)
+	}
+	if refunds := router.Escrow.(*mockRouteEscrow).refunds; len(refunds) != 1 || refunds[0].Cmp(params.AmountIn) != 0 {
+		t.Errorf("Expected the escrow to pay the refund, got %v", refunds)
+	}
+	if len(router.Bridge.PendingTeleports) != 0 {
+		t.Errorf("No teleport should be pending after a source refund")
+	}
+}
+
+func TestOmnichainSwapRefundTransferFails(t *testing.T) {
+	router, swapper, _ := newTestOmnichainRouter(t)
+
+	plan, err := router.PlanOmnichainSwap(newTestOmnichainParams())
+	if err != nil {
+		t.Fatalf("PlanOmnichainSwap failed: %v", err)
+	}
+
+	errEscrow := errors.New("escrow empty")
+	router.Escrow.(*mockRouteEscrow).err = errEscrow
+	swapper.execBps[omniSrcPool] = 9900
+
+	exec, err := router.ExecuteOmnichainSwap(plan.RouteID)
+	if !errors.Is(err, ErrHopSlippage) || !errors.Is(err, errEscrow) {
+		t.Fatalf("Expected both the hop and refund errors, got %v", err)
+	}
+	if exec.Status != RouteRefundFailed {
+		t.Errorf("Expected RouteRefundFailed, got %d", exec.Status)
+	}
+}
+
+func TestOmnichainSwapDestSlippageRefunds(t *testing.T) {
+	router, swapper, destState := newTestOmnichainRouter(t)
+
+	plan, err := router.PlanOmnichainSwap(newTestOmnichainParams())
+	if err != nil {
+		t.Fatalf("PlanOmnichainSwap failed: %v", err)
+	}
+	exec, err := router.ExecuteOmnichainSwap(plan.RouteID)
+	if err != nil {
+		t.Fatalf("ExecuteOmnichainSwap failed: %v", err)
+	}
+
+	swapper.execBps[omniDstPool] = 9000
+
+	exec, err = router.CompleteOmnichainSwap(destState, plan.RouteID, signTestWarp(t, exec.WarpMessage, 0, 1, 2))
+	if !errors.Is(err, ErrHopSlippage) {
+		t.Fatalf("Expected ErrHopSlippage, got %v", err)
+	}
+	if exec.Status != RouteRefunded || exec.FailedHop != 2 {
+		t.Errorf("Expected refund at hop 2, got status %d hop %d", exec.Status, exec.FailedHop)
+	}
+	if exec.RefundTo != omniRecipient || exec.RefundChain != ChainETH || exec.RefundToken != omniRemoteUSD {
+		t.Errorf("Refund should return the bridged token to the recipient on the destination chain")
+	}
+	if exec.RefundAmount.Cmp(exec.Outputs[1]) != 0 {
+		t.Errorf("Expected refund of teleported amount %s, got %s", exec.Outputs[1], exec.RefundAmount)
+	}
+	if refunds := router.Escrow.(*mockRouteEscrow).refunds; len(refunds) != 1 || refunds[0].Cmp(exec.Outputs[1]) != 0 {
+		t.Errorf("Expected the escrow to pay the refund, got %v", refunds)
+	}
+}
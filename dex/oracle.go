@@ -0,0 +1,708 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// =========================================================================
+// Price oracle (LXOracle)
+// =========================================================================
+//
+// LXOracle aggregates the price of a base asset in a quote asset from a set
+// of reporters. The oracle admin registers a feed per pair with its reporters
+// and thresholds. Reporters sign reports of (pair, price, timestamp) off
+// chain and anyone may relay them, so a keeper can push many reports in one
+// transaction. A report replaces its reporter's previous one only if it is
+// newer.
+//
+// The feed price is the median of the fresh reports, those no older than
+// MaxStaleness. Reports deviating from that median by more than
+// MaxDeviationBps are dropped and the median is taken again over the rest.
+// With fewer than MinReporters reports left the feed has no price, and
+// lending, perps and liquidations must not act on it.
+//
+// Every accepted report records the new aggregate with its cumulative
+// price-seconds, so GetTWAP returns the time-weighted average price over a
+// window, like the pool observations in observations.go.
+//
+// Feeds, reports, the aggregate history and the admin live in the storage
+// of the LXOracle account, and staleness is judged against the block
+// timestamp, so every node aggregates the same reports to the same price.
+
+const (
+	// MaxOracleReporters bounds the reporter set of a feed
+	MaxOracleReporters = 32
+
+	// MaxOracleObservations bounds the aggregate history kept per feed
+	MaxOracleObservations = 256
+
+	// oracleReportDomain separates report digests from other signed messages
+	oracleReportDomain = "LXOracle.report"
+)
+
+// Storage key prefixes for LXOracle state. Feed words are keyed by the pair
+// ID plus a field name: the thresholds and reporter count (cfg) and the
+// history ring head (hist) under orcl/feed, the reporter list under
+// orcl/rptr, and each reporter's latest report under orcl/rpt. History
+// slots sit under orcl/obs and the admin under orcl/admin.
+var (
+	oracleAdminPrefix    = []byte("orcl/admin")
+	oracleFeedPrefix     = []byte("orcl/feed")
+	oracleReporterPrefix = []byte("orcl/rptr")
+	oracleReportPrefix   = []byte("orcl/rpt")
+	oracleObsPrefix      = []byte("orcl/obs")
+)
+
+// OraclePriceScale is the fixed-point scale of oracle prices (1e18)
+var OraclePriceScale = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// PriceSource supplies aggregated prices of base in quote at block time
+// now, scaled by OraclePriceScale, to lending, perps and liquidations
+type PriceSource interface {
+	GetPrice(stateDB StateDB, now uint64, base, quote common.Address) (*big.Int, error)
+}
+
+var _ PriceSource = (*PriceOracle)(nil)
+
+// OracleFeedConfig holds the aggregation thresholds of a feed
+type OracleFeedConfig struct {
+	MinReporters    uint32 // Fresh, non-deviating reports needed for a price
+	MaxStaleness    uint64 // Seconds after which a report is ignored
+	MaxDeviationBps uint32 // Largest accepted distance from the median; 0 disables the filter
+}
+
+// OracleReport is a reporter's latest price
+type OracleReport struct {
+	Reporter  common.Address
+	Price     *big.Int // Quote per base unit, scaled by OraclePriceScale
+	Timestamp uint64
+}
+
+// oracleObservation records the aggregate in force from Timestamp and the
+// price-seconds accumulated before it
+type oracleObservation struct {
+	Timestamp  uint64
+	Price      *big.Int
+	Cumulative *big.Int
+}
+
+// OracleFeed is the reporter set and reports of one pair, as loaded from
+// state
+type OracleFeed struct {
+	Base      common.Address
+	Quote     common.Address
+	Config    OracleFeedConfig
+	Reporters map[common.Address]bool
+	Reports   map[common.Address]*OracleReport
+
+	id [32]byte
+}
+
+// oracleHistory is the ring of a feed's recorded aggregates in state
+type oracleHistory struct {
+	stateDB StateDB
+	id      [32]byte
+	start   int // Slot of the oldest observation
+	count   int
+}
+
+// PriceOracle aggregates signed price reports per pair. It holds no state
+// of its own; feeds and the admin are read from StateDB on every call.
+type PriceOracle struct{}
+
+// NewPriceOracle creates an oracle over the LXOracle account's storage
+func NewPriceOracle() *PriceOracle {
+	return &PriceOracle{}
+}
+
+// OraclePairID returns the feed key of base priced in quote
+func OraclePairID(base, quote common.Address) [32]byte {
+	return MarketID(Currency{Address: base}, Currency{Address: quote})
+}
+
+// OracleReportDigest returns the digest a reporter signs to report price for
+// base in quote at timestamp
+func OracleReportDigest(base, quote common.Address, price *big.Int, timestamp uint64) common.Hash {
+	return crypto.Keccak256Hash(
+		[]byte(oracleReportDomain),
+		base.Bytes(),
+		quote.Bytes(),
+		common.BigToHash(price).Bytes(),
+		encodeUint64(timestamp),
+	)
+}
+
+// SetAdmin replaces the oracle admin
+func (o *PriceOracle) SetAdmin(stateDB StateDB, admin common.Address) {
+	stateDB.SetState(lxOracleAddr, makeStorageKey(oracleAdminPrefix, nil), common.BytesToHash(admin.Bytes()))
+}
+
+// Admin returns the oracle admin
+func (o *PriceOracle) Admin(stateDB StateDB) common.Address {
+	word := stateDB.GetState(lxOracleAddr, makeStorageKey(oracleAdminPrefix, nil))
+	return common.BytesToAddress(word[12:])
+}
+
+// RegisterFeed registers a feed for base priced in quote, or replaces the
+// reporters and thresholds of an existing one (admin only). Reports of
+// reporters still in the set are kept.
+func (o *PriceOracle) RegisterFeed(
+	stateDB StateDB,
+	caller common.Address,
+	base, quote common.Address,
+	reporters []common.Address,
+	config OracleFeedConfig,
+) error {
+	if caller == (common.Address{}) || caller != o.Admin(stateDB) {
+		return ErrUnauthorized
+	}
+	if base == quote || len(reporters) == 0 || len(reporters) > MaxOracleReporters {
+		return ErrInvalidOracleFeed
+	}
+	if config.MinReporters == 0 || int(config.MinReporters) > len(reporters) ||
+		config.MaxStaleness == 0 || config.MaxDeviationBps > 10000 {
+		return ErrInvalidOracleFeed
+	}
+
+	set := make(map[common.Address]bool, len(reporters))
+	for _, reporter := range reporters {
+		if reporter == (common.Address{}) || set[reporter] {
+			return ErrInvalidOracleFeed
+		}
+		set[reporter] = true
+	}
+
+	id := OraclePairID(base, quote)
+	oldCount := 0
+	if feed := loadOracleFeed(stateDB, base, quote); feed != nil {
+		oldCount = len(feed.Reporters)
+		for reporter := range feed.Reporters {
+			if !set[reporter] {
+				clearOracleReport(stateDB, id, reporter)
+			}
+		}
+	}
+
+	for i, reporter := range reporters {
+		stateDB.SetState(lxOracleAddr, oracleReporterKey(id, i), common.BytesToHash(reporter.Bytes()))
+	}
+	for i := len(reporters); i < oldCount; i++ {
+		stateDB.SetState(lxOracleAddr, oracleReporterKey(id, i), common.Hash{})
+	}
+
+	var word common.Hash
+	binary.BigEndian.PutUint32(word[0:4], config.MinReporters)
+	binary.BigEndian.PutUint64(word[4:12], config.MaxStaleness)
+	binary.BigEndian.PutUint32(word[12:16], config.MaxDeviationBps)
+	word[16] = byte(len(reporters))
+	stateDB.SetState(lxOracleAddr, oracleStorageKey(oracleFeedPrefix, id[:], "cfg"), word)
+	return nil
+}
+
+// SubmitReport records a report signed by one of the feed's reporters. The
+// report must be fresh at block time now and newer than the reporter's
+// previous one.
+func (o *PriceOracle) SubmitReport(
+	stateDB StateDB,
+	now uint64,
+	base, quote common.Address,
+	price *big.Int,
+	timestamp uint64,
+	signature []byte,
+) (common.Address, error) {
+	feed := loadOracleFeed(stateDB, base, quote)
+	if feed == nil {
+		return common.Address{}, ErrOracleFeedNotFound
+	}
+	if price == nil || price.Sign() <= 0 || price.BitLen() > 256 {
+		return common.Address{}, ErrInvalidReport
+	}
+
+	reporter, err := recoverReporter(OracleReportDigest(base, quote, price, timestamp), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !feed.Reporters[reporter] {
+		return common.Address{}, ErrUnknownReporter
+	}
+
+	if timestamp > now {
+		return common.Address{}, ErrInvalidReport
+	}
+	if now-timestamp > feed.Config.MaxStaleness {
+		return common.Address{}, ErrStaleReport
+	}
+	if prev := feed.Reports[reporter]; prev != nil && prev.Timestamp >= timestamp {
+		return common.Address{}, ErrStaleReport
+	}
+
+	report := &OracleReport{
+		Reporter:  reporter,
+		Price:     new(big.Int).Set(price),
+		Timestamp: timestamp,
+	}
+	feed.Reports[reporter] = report
+	setOracleReport(stateDB, feed.id, report)
+
+	// Record the new aggregate for TWAPs; a feed without a price yet keeps
+	// its history unchanged
+	if aggregate, err := feed.aggregate(now); err == nil {
+		loadOracleHistory(stateDB, feed.id).record(aggregate, now)
+	}
+	return reporter, nil
+}
+
+// GetPrice returns the aggregated price of base in quote at block time now.
+// A pair with only the reverse feed is priced by inverting it.
+func (o *PriceOracle) GetPrice(stateDB StateDB, now uint64, base, quote common.Address) (*big.Int, error) {
+	if feed := loadOracleFeed(stateDB, base, quote); feed != nil {
+		return feed.aggregate(now)
+	}
+	if feed := loadOracleFeed(stateDB, quote, base); feed != nil {
+		price, err := feed.aggregate(now)
+		if err != nil {
+			return nil, err
+		}
+		inverse := new(big.Int).Mul(OraclePriceScale, OraclePriceScale)
+		return inverse.Div(inverse, price), nil
+	}
+	return nil, ErrOracleFeedNotFound
+}
+
+// GetTWAP returns the time-weighted average aggregate of base in quote over
+// the window seconds before block time now. The latest aggregate must still
+// be fresh.
+func (o *PriceOracle) GetTWAP(stateDB StateDB, now uint64, base, quote common.Address, window uint64) (*big.Int, error) {
+	feed := loadOracleFeed(stateDB, base, quote)
+	if feed == nil {
+		return nil, ErrOracleFeedNotFound
+	}
+	if window == 0 {
+		return nil, ErrInvalidParameter
+	}
+
+	history := loadOracleHistory(stateDB, feed.id)
+	if history.count == 0 || window > now {
+		return nil, ErrTWAPUnavailable
+	}
+	if last := history.at(history.count - 1); now-last.Timestamp > feed.Config.MaxStaleness {
+		return nil, ErrStaleReport
+	}
+	if history.at(0).Timestamp > now-window {
+		return nil, ErrTWAPUnavailable
+	}
+
+	twap := new(big.Int).Sub(history.cumulativeAt(now), history.cumulativeAt(now-window))
+	return twap.Div(twap, new(big.Int).SetUint64(window)), nil
+}
+
+// GetReport returns reporter's latest report for the pair
+func (o *PriceOracle) GetReport(stateDB StateDB, base, quote, reporter common.Address) (*OracleReport, error) {
+	feed := loadOracleFeed(stateDB, base, quote)
+	if feed == nil {
+		return nil, ErrOracleFeedNotFound
+	}
+	report := feed.Reports[reporter]
+	if report == nil {
+		return nil, ErrInvalidReport
+	}
+	return report, nil
+}
+
+// oracleStorageKey returns the storage key of field of id under prefix
+func oracleStorageKey(prefix []byte, id []byte, field string) common.Hash {
+	return makeStorageKey(prefix, append(append([]byte{}, id...), field...))
+}
+
+// oracleReporterKey returns the slot of a feed's i-th reporter
+func oracleReporterKey(id [32]byte, i int) common.Hash {
+	return makeStorageKey(oracleReporterPrefix, append(id[:], byte(i)))
+}
+
+// loadOracleFeed reads the feed of base in quote from state, or returns nil
+// if none is registered. A registered feed has at least one reporter.
+func loadOracleFeed(stateDB StateDB, base, quote common.Address) *OracleFeed {
+	id := OraclePairID(base, quote)
+	word := stateDB.GetState(lxOracleAddr, oracleStorageKey(oracleFeedPrefix, id[:], "cfg"))
+	count := int(word[16])
+	if count == 0 {
+		return nil
+	}
+
+	feed := &OracleFeed{
+		Base:  base,
+		Quote: quote,
+		Config: OracleFeedConfig{
+			MinReporters:    binary.BigEndian.Uint32(word[0:4]),
+			MaxStaleness:    binary.BigEndian.Uint64(word[4:12]),
+			MaxDeviationBps: binary.BigEndian.Uint32(word[12:16]),
+		},
+		Reporters: make(map[common.Address]bool, count),
+		Reports:   make(map[common.Address]*OracleReport),
+		id:        id,
+	}
+	for i := 0; i < count; i++ {
+		slot := stateDB.GetState(lxOracleAddr, oracleReporterKey(id, i))
+		reporter := common.BytesToAddress(slot[12:])
+		feed.Reporters[reporter] = true
+		if report := loadOracleReport(stateDB, id, reporter); report != nil {
+			feed.Reports[reporter] = report
+		}
+	}
+	return feed
+}
+
+// loadOracleReport reads reporter's latest report for a feed, or returns
+// nil if it has none. Accepted prices are positive, so a zero price means
+// no report.
+func loadOracleReport(stateDB StateDB, id [32]byte, reporter common.Address) *OracleReport {
+	key := append(id[:], reporter.Bytes()...)
+	price := stateDB.GetState(lxOracleAddr, oracleStorageKey(oracleReportPrefix, key, "price"))
+	if price == (common.Hash{}) {
+		return nil
+	}
+	timestamp := stateDB.GetState(lxOracleAddr, oracleStorageKey(oracleReportPrefix, key, "time"))
+	return &OracleReport{
+		Reporter:  reporter,
+		Price:     new(big.Int).SetBytes(price[:]),
+		Timestamp: decodeUint64Word(timestamp[:]),
+	}
+}
+
+// setOracleReport writes a reporter's latest report for a feed
+func setOracleReport(stateDB StateDB, id [32]byte, report *OracleReport) {
+	key := append(id[:], report.Reporter.Bytes()...)
+	stateDB.SetState(lxOracleAddr, oracleStorageKey(oracleReportPrefix, key, "price"), common.BigToHash(report.Price))
+	stateDB.SetState(lxOracleAddr, oracleStorageKey(oracleReportPrefix, key, "time"),
+		common.BytesToHash(encodeUint64(report.Timestamp)))
+}
+
+// clearOracleReport removes a reporter's report for a feed
+func clearOracleReport(stateDB StateDB, id [32]byte, reporter common.Address) {
+	key := append(id[:], reporter.Bytes()...)
+	stateDB.SetState(lxOracleAddr, oracleStorageKey(oracleReportPrefix, key, "price"), common.Hash{})
+	stateDB.SetState(lxOracleAddr, oracleStorageKey(oracleReportPrefix, key, "time"), common.Hash{})
+}
+
+// aggregate returns the median of the fresh reports at now after dropping
+// those beyond the deviation threshold
+func (f *OracleFeed) aggregate(now uint64) (*big.Int, error) {
+	prices := make([]*big.Int, 0, len(f.Reports))
+	for _, report := range f.Reports {
+		if report.Timestamp <= now && now-report.Timestamp <= f.Config.MaxStaleness {
+			prices = append(prices, report.Price)
+		}
+	}
+	if len(prices) < int(f.Config.MinReporters) {
+		return nil, ErrInsufficientReports
+	}
+
+	median := medianPrice(prices)
+	if f.Config.MaxDeviationBps == 0 {
+		return median, nil
+	}
+
+	kept := prices[:0]
+	for _, price := range prices {
+		diff := new(big.Int).Sub(price, median)
+		diff.Abs(diff).Mul(diff, big.NewInt(10000))
+		if diff.Cmp(new(big.Int).Mul(median, big.NewInt(int64(f.Config.MaxDeviationBps)))) <= 0 {
+			kept = append(kept, price)
+		}
+	}
+	if len(kept) < int(f.Config.MinReporters) {
+		return nil, ErrPriceDeviation
+	}
+	return medianPrice(kept), nil
+}
+
+// loadOracleHistory reads the head of a feed's history ring
+func loadOracleHistory(stateDB StateDB, id [32]byte) *oracleHistory {
+	word := stateDB.GetState(lxOracleAddr, oracleStorageKey(oracleFeedPrefix, id[:], "hist"))
+	return &oracleHistory{
+		stateDB: stateDB,
+		id:      id,
+		start:   int(binary.BigEndian.Uint16(word[0:2])),
+		count:   int(binary.BigEndian.Uint16(word[2:4])),
+	}
+}
+
+// slotKey returns the storage key of field of the observation i places
+// after the oldest
+func (h *oracleHistory) slotKey(i int, field string) common.Hash {
+	slot := (h.start + i) % MaxOracleObservations
+	return oracleStorageKey(oracleObsPrefix, binary.BigEndian.AppendUint16(h.id[:], uint16(slot)), field)
+}
+
+// at returns the observation i places after the oldest
+func (h *oracleHistory) at(i int) *oracleObservation {
+	timestamp := h.stateDB.GetState(lxOracleAddr, h.slotKey(i, "time"))
+	price := h.stateDB.GetState(lxOracleAddr, h.slotKey(i, "price"))
+	cumulative := h.stateDB.GetState(lxOracleAddr, h.slotKey(i, "cum"))
+	return &oracleObservation{
+		Timestamp:  decodeUint64Word(timestamp[:]),
+		Price:      new(big.Int).SetBytes(price[:]),
+		Cumulative: new(big.Int).SetBytes(cumulative[:]),
+	}
+}
+
+// put writes the observation i places after the oldest
+func (h *oracleHistory) put(i int, obs *oracleObservation) {
+	h.stateDB.SetState(lxOracleAddr, h.slotKey(i, "time"), common.BytesToHash(encodeUint64(obs.Timestamp)))
+	h.stateDB.SetState(lxOracleAddr, h.slotKey(i, "price"), common.BigToHash(obs.Price))
+	h.stateDB.SetState(lxOracleAddr, h.slotKey(i, "cum"), common.BigToHash(obs.Cumulative))
+}
+
+// record appends the aggregate in force from now to the history, dropping
+// the oldest observation once the ring is full. A second aggregate in the
+// same second replaces the first.
+func (h *oracleHistory) record(price *big.Int, now uint64) {
+	if h.count > 0 {
+		if last := h.at(h.count - 1); last.Timestamp == now {
+			last.Price = price
+			h.put(h.count-1, last)
+			return
+		}
+	}
+
+	h.put(h.count, &oracleObservation{
+		Timestamp:  now,
+		Price:      price,
+		Cumulative: h.cumulativeAt(now),
+	})
+	if h.count < MaxOracleObservations {
+		h.count++
+	} else {
+		h.start = (h.start + 1) % MaxOracleObservations
+	}
+
+	var word common.Hash
+	binary.BigEndian.PutUint16(word[0:2], uint16(h.start))
+	binary.BigEndian.PutUint16(word[2:4], uint16(h.count))
+	h.stateDB.SetState(lxOracleAddr, oracleStorageKey(oracleFeedPrefix, h.id[:], "hist"), word)
+}
+
+// cumulativeAt returns the price-seconds accumulated up to t. Times before
+// the oldest observation read as its cumulative.
+func (h *oracleHistory) cumulativeAt(t uint64) *big.Int {
+	if h.count == 0 {
+		return new(big.Int)
+	}
+
+	// Latest observation at or before t
+	i := sort.Search(h.count, func(i int) bool { return h.at(i).Timestamp > t }) - 1
+	if i < 0 {
+		return h.at(0).Cumulative
+	}
+	obs := h.at(i)
+	elapsed := new(big.Int).SetUint64(t - obs.Timestamp)
+	return elapsed.Mul(elapsed, obs.Price).Add(elapsed, obs.Cumulative)
+}
+
+// medianPrice returns the median of prices, averaging the middle pair of an
+// even count. prices is not modified.
+func medianPrice(prices []*big.Int) *big.Int {
+	sorted := append([]*big.Int(nil), prices...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return new(big.Int).Set(sorted[mid])
+	}
+	median := new(big.Int).Add(sorted[mid-1], sorted[mid])
+	return median.Rsh(median, 1)
+}
+
+// recoverReporter returns the signer of digest. signature is 65 bytes,
+// r || s || v, with v either 0/1 or 27/28.
+func recoverReporter(digest common.Hash, signature []byte) (common.Address, error) {
+	if len(signature) != 65 {
+		return common.Address{}, ErrInvalidReport
+	}
+	normalized := append([]byte(nil), signature...)
+	if normalized[64] >= 27 {
+		normalized[64] -= 27
+	}
+	pub, err := crypto.Ecrecover(digest.Bytes(), normalized)
+	if err != nil || len(pub) != 65 {
+		return common.Address{}, ErrInvalidReport
+	}
+	return common.BytesToAddress(crypto.Keccak256(pub[1:])[12:]), nil
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// Oracle returns the price oracle behind LXOracle
+func (pm *PoolManager) Oracle() *PriceOracle {
+	return pm.oracle
+}
+
+// =========================================================================
+// LXOracle precompile
+// =========================================================================
+
+// Method selectors for LXOracle
+const (
+	SelectorRegisterFeed uint32 = 0x01000000 // registerFeed(address,address,uint32,uint64,uint32,address[])
+	SelectorSubmitReport uint32 = 0x02000000 // submitReport(address,address,uint256,uint64,bytes)
+	SelectorGetPrice     uint32 = 0x03000000 // getPrice(address,address)
+	SelectorGetTWAP      uint32 = 0x04000000 // getTWAP(address,address,uint64)
+	SelectorGetReport    uint32 = 0x05000000 // getReport(address,address,address)
+)
+
+// OracleContract implements the LXOracle precompile over the oracle shared
+// with LXPool
+type OracleContract struct {
+	poolManager *PoolManager
+}
+
+// Run executes the precompile
+func (c *OracleContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+
+	selector := binary.BigEndian.Uint32(input[:4])
+	data := input[4:]
+
+	gas := c.RequiredGas(input)
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+	remainingGas = suppliedGas - gas
+
+	// Every method leads with the pair: base (32) + quote (32)
+	if len(data) < 64 {
+		return nil, remainingGas, fmt.Errorf("input too short")
+	}
+	base := common.BytesToAddress(data[12:32])
+	quote := common.BytesToAddress(data[44:64])
+	oracle := c.poolManager.oracle
+	stateAdapter := newPoolStateAdapter(accessibleState)
+	now := accessibleState.GetBlockContext().Timestamp()
+
+	switch selector {
+	case SelectorGetPrice:
+		price, err := oracle.GetPrice(stateAdapter, now, base, quote)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return common.LeftPadBytes(price.Bytes(), 32), remainingGas, nil
+
+	case SelectorGetTWAP:
+		// window (32)
+		if len(data) < 96 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		twap, err := oracle.GetTWAP(stateAdapter, now, base, quote, decodeUint64Word(data[64:96]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return common.LeftPadBytes(twap.Bytes(), 32), remainingGas, nil
+
+	case SelectorGetReport:
+		// reporter (32)
+		if len(data) < 96 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		report, err := oracle.GetReport(stateAdapter, base, quote, common.BytesToAddress(data[76:96]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return EncodeOracleReport(report), remainingGas, nil
+
+	case SelectorRegisterFeed, SelectorSubmitReport:
+	default:
+		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	}
+
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	switch selector {
+	case SelectorRegisterFeed:
+		// minReporters (32) + maxStaleness (32) + maxDeviationBps (32) +
+		// count (32) + count * reporter (32)
+		if len(data) < 192 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		count := decodeUint64Word(data[160:192])
+		if count > MaxOracleReporters || uint64(len(data)) < 192+count*32 {
+			return nil, remainingGas, ErrInvalidOracleFeed
+		}
+		reporters := make([]common.Address, count)
+		for i := range reporters {
+			offset := 192 + i*32
+			reporters[i] = common.BytesToAddress(data[offset+12 : offset+32])
+		}
+		config := OracleFeedConfig{
+			MinReporters:    uint32(decodeUint64Word(data[64:96])),
+			MaxStaleness:    decodeUint64Word(data[96:128]),
+			MaxDeviationBps: uint32(decodeUint64Word(data[128:160])),
+		}
+		if err := oracle.RegisterFeed(stateAdapter, caller, base, quote, reporters, config); err != nil {
+			return nil, remainingGas, err
+		}
+		return nil, remainingGas, nil
+
+	default: // SelectorSubmitReport
+		// price (32) + timestamp (32) + signature (65)
+		if len(data) < 193 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		reporter, err := oracle.SubmitReport(stateAdapter, now, base, quote, new(big.Int).SetBytes(data[64:96]),
+			decodeUint64Word(data[96:128]), data[128:193])
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return common.LeftPadBytes(reporter.Bytes(), 32), remainingGas, nil
+	}
+}
+
+// RequiredGas returns the gas required for the precompile input
+func (c *OracleContract) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return GasPoolLookup
+	}
+
+	switch binary.BigEndian.Uint32(input[:4]) {
+	case SelectorRegisterFeed:
+		return GasOracleRegisterFeed
+	case SelectorSubmitReport:
+		return GasOracleReport
+	case SelectorGetPrice, SelectorGetTWAP:
+		return GasOracleRead
+	default:
+		return GasPoolLookup
+	}
+}
+
+// EncodeOracleReport encodes a report: reporter (32) + price (32) +
+// timestamp (32)
+func EncodeOracleReport(report *OracleReport) []byte {
+	result := make([]byte, 96)
+	copy(result[12:32], report.Reporter.Bytes())
+	report.Price.FillBytes(result[32:64])
+	binary.BigEndian.PutUint64(result[88:96], report.Timestamp)
+	return result
+}
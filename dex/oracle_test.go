@@ -0,0 +1,201 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+)
+
+var (
+	oracleAdmin = common.HexToAddress("0x00000000000000000000000000000000000000ad")
+	oracleBase  = common.HexToAddress("0x0000000000000000000000000000000000000c01")
+	oracleQuote = common.HexToAddress("0x0000000000000000000000000000000000000c02")
+)
+
+const oracleTestTime uint64 = 1_700_000_000
+
+// newTestReporters returns n reporter keys and their addresses
+func newTestReporters(t *testing.T, n int) ([]*ecdsa.PrivateKey, []common.Address) {
+	t.Helper()
+
+	keys := make([]*ecdsa.PrivateKey, n)
+	addrs := make([]common.Address, n)
+	for i := range keys {
+		key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		keys[i] = key
+		addrs[i] = common.BytesToAddress(crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey)[1:])[12:])
+	}
+	return keys, addrs
+}
+
+// submitTestReport signs and submits a report of price (in whole quote
+// units) at block time now
+func submitTestReport(o *PriceOracle, stateDB StateDB, now uint64, key *ecdsa.PrivateKey, price int64, timestamp uint64) error {
+	scaled := new(big.Int).Mul(big.NewInt(price), OraclePriceScale)
+	sig, err := crypto.Sign(OracleReportDigest(oracleBase, oracleQuote, scaled, timestamp).Bytes(), key)
+	if err != nil {
+		return err
+	}
+	_, err = o.SubmitReport(stateDB, now, oracleBase, oracleQuote, scaled, timestamp, sig)
+	return err
+}
+
+func TestOracleMedianAggregation(t *testing.T) {
+	now := oracleTestTime
+	stateDB := NewMockStateDB()
+	oracle := NewPriceOracle()
+	oracle.SetAdmin(stateDB, oracleAdmin)
+
+	keys, reporters := newTestReporters(t, 4)
+	config := OracleFeedConfig{MinReporters: 3, MaxStaleness: 60, MaxDeviationBps: 500}
+	if err := oracle.RegisterFeed(stateDB, reporters[0], oracleBase, oracleQuote, reporters, config); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got: %v", err)
+	}
+	if err := oracle.RegisterFeed(stateDB, oracleAdmin, oracleBase, oracleQuote, reporters, OracleFeedConfig{MinReporters: 5, MaxStaleness: 60}); !errors.Is(err, ErrInvalidOracleFeed) {
+		t.Errorf("Expected ErrInvalidOracleFeed, got: %v", err)
+	}
+	if err := oracle.RegisterFeed(stateDB, oracleAdmin, oracleBase, oracleQuote, reporters, config); err != nil {
+		t.Fatalf("RegisterFeed failed: %v", err)
+	}
+
+	for i, price := range []int64{100, 101} {
+		if err := submitTestReport(oracle, stateDB, now, keys[i], price, now); err != nil {
+			t.Fatalf("SubmitReport failed: %v", err)
+		}
+	}
+	if _, err := oracle.GetPrice(stateDB, now, oracleBase, oracleQuote); !errors.Is(err, ErrInsufficientReports) {
+		t.Errorf("Expected ErrInsufficientReports, got: %v", err)
+	}
+
+	if err := submitTestReport(oracle, stateDB, now, keys[2], 102, now); err != nil {
+		t.Fatalf("SubmitReport failed: %v", err)
+	}
+	price, err := oracle.GetPrice(stateDB, now, oracleBase, oracleQuote)
+	if err != nil || price.Cmp(new(big.Int).Mul(big.NewInt(101), OraclePriceScale)) != 0 {
+		t.Errorf("Expected median 101, got: %v (%v)", price, err)
+	}
+
+	// Outsiders and replays are rejected
+	outsider, _ := newTestReporters(t, 1)
+	if err := submitTestReport(oracle, stateDB, now, outsider[0], 100, now); !errors.Is(err, ErrUnknownReporter) {
+		t.Errorf("Expected ErrUnknownReporter, got: %v", err)
+	}
+	if err := submitTestReport(oracle, stateDB, now, keys[0], 100, now); !errors.Is(err, ErrStaleReport) {
+		t.Errorf("Expected ErrStaleReport on replay, got: %v", err)
+	}
+
+	// An outlier beyond 5% of the median is dropped
+	if err := submitTestReport(oracle, stateDB, now, keys[3], 150, now); err != nil {
+		t.Fatalf("SubmitReport failed: %v", err)
+	}
+	price, _ = oracle.GetPrice(stateDB, now, oracleBase, oracleQuote)
+	if price.Cmp(new(big.Int).Mul(big.NewInt(101), OraclePriceScale)) != 0 {
+		t.Errorf("Expected outlier to be dropped, got: %s", price)
+	}
+
+	// Feeds and reports live in state, not in the oracle
+	restarted := NewPriceOracle()
+	price, err = restarted.GetPrice(stateDB, now, oracleBase, oracleQuote)
+	if err != nil || price.Cmp(new(big.Int).Mul(big.NewInt(101), OraclePriceScale)) != 0 {
+		t.Errorf("Expected median 101 from state, got: %v (%v)", price, err)
+	}
+	if report, err := restarted.GetReport(stateDB, oracleBase, oracleQuote, reporters[3]); err != nil || report.Timestamp != now {
+		t.Errorf("Expected stored report, got: %v (%v)", report, err)
+	}
+
+	// Dropping a reporter drops its report
+	if err := oracle.RegisterFeed(stateDB, oracleAdmin, oracleBase, oracleQuote, reporters[:3], config); err != nil {
+		t.Fatalf("RegisterFeed failed: %v", err)
+	}
+	if _, err := oracle.GetReport(stateDB, oracleBase, oracleQuote, reporters[3]); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("Expected ErrInvalidReport for a removed reporter, got: %v", err)
+	}
+
+	// The reverse pair is priced by inversion
+	inverse, err := oracle.GetPrice(stateDB, now, oracleQuote, oracleBase)
+	expected := new(big.Int).Div(OraclePriceScale, big.NewInt(101))
+	if err != nil || inverse.Cmp(expected) != 0 {
+		t.Errorf("Expected inverse %s, got: %v (%v)", expected, inverse, err)
+	}
+
+	now += 61
+	if _, err := oracle.GetPrice(stateDB, now, oracleBase, oracleQuote); !errors.Is(err, ErrInsufficientReports) {
+		t.Errorf("Expected ErrInsufficientReports once reports are stale, got: %v", err)
+	}
+	if err := submitTestReport(oracle, stateDB, now, keys[0], 100, now-61); !errors.Is(err, ErrStaleReport) {
+		t.Errorf("Expected ErrStaleReport, got: %v", err)
+	}
+}
+
+func TestOracleTWAP(t *testing.T) {
+	now := oracleTestTime
+	stateDB := NewMockStateDB()
+	oracle := NewPriceOracle()
+	oracle.SetAdmin(stateDB, oracleAdmin)
+
+	keys, reporters := newTestReporters(t, 1)
+	config := OracleFeedConfig{MinReporters: 1, MaxStaleness: 3600}
+	if err := oracle.RegisterFeed(stateDB, oracleAdmin, oracleBase, oracleQuote, reporters, config); err != nil {
+		t.Fatalf("RegisterFeed failed: %v", err)
+	}
+
+	if err := submitTestReport(oracle, stateDB, now, keys[0], 100, now); err != nil {
+		t.Fatalf("SubmitReport failed: %v", err)
+	}
+	now += 100
+	if err := submitTestReport(oracle, stateDB, now, keys[0], 200, now); err != nil {
+		t.Fatalf("SubmitReport failed: %v", err)
+	}
+	now += 100
+
+	for window, expected := range map[uint64]int64{200: 150, 100: 200} {
+		twap, err := oracle.GetTWAP(stateDB, now, oracleBase, oracleQuote, window)
+		if err != nil || twap.Cmp(new(big.Int).Mul(big.NewInt(expected), OraclePriceScale)) != 0 {
+			t.Errorf("Expected %ds TWAP of %d, got: %v (%v)", window, expected, twap, err)
+		}
+	}
+	if _, err := oracle.GetTWAP(stateDB, now, oracleBase, oracleQuote, 300); !errors.Is(err, ErrTWAPUnavailable) {
+		t.Errorf("Expected ErrTWAPUnavailable, got: %v", err)
+	}
+
+	now += 3600
+	if _, err := oracle.GetTWAP(stateDB, now, oracleBase, oracleQuote, 100); !errors.Is(err, ErrStaleReport) {
+		t.Errorf("Expected ErrStaleReport, got: %v", err)
+	}
+}
+
+func TestOracleHistoryRing(t *testing.T) {
+	stateDB := NewMockStateDB()
+	id := OraclePairID(oracleBase, oracleQuote)
+	price := new(big.Int).Mul(big.NewInt(100), OraclePriceScale)
+
+	// One observation a second; the ring keeps the newest
+	// MaxOracleObservations
+	for i := uint64(0); i < MaxOracleObservations+4; i++ {
+		loadOracleHistory(stateDB, id).record(price, oracleTestTime+i)
+	}
+	history := loadOracleHistory(stateDB, id)
+	if history.count != MaxOracleObservations || history.at(0).Timestamp != oracleTestTime+4 {
+		t.Fatalf("Expected %d observations from %d, got %d from %d",
+			MaxOracleObservations, oracleTestTime+4, history.count, history.at(0).Timestamp)
+	}
+	last := history.at(history.count - 1)
+	if last.Timestamp != oracleTestTime+MaxOracleObservations+3 {
+		t.Errorf("Expected newest observation at %d, got: %d", oracleTestTime+MaxOracleObservations+3, last.Timestamp)
+	}
+	expected := new(big.Int).Mul(price, big.NewInt(MaxOracleObservations+3))
+	if last.Cumulative.Cmp(expected) != 0 {
+		t.Errorf("Expected cumulative %s, got: %s", expected, last.Cumulative)
+	}
+}
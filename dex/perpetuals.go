@@ -7,7 +7,6 @@ import (
 	"errors"
 	"math/big"
 	"sync"
-	"time"
 
 	"github.com/luxfi/geth/common"
 )
@@ -26,6 +25,10 @@ type PerpetualEngine struct {
 
 	// Funding state per market
 	FundingStates map[[32]byte]*FundingState
+	Funding       *FundingEngine
+
+	// Mark and index source for SyncPrices, typically LXFeed
+	PriceFeed PerpPriceSource
 
 	mu sync.RWMutex
 }
@@ -37,6 +40,7 @@ func NewPerpetualEngine() *PerpetualEngine {
 		Positions:     make(map[common.Address]map[[32]byte]*PerpPosition),
 		InsuranceFund: big.NewInt(0),
 		FundingStates: make(map[[32]byte]*FundingState),
+		Funding:       NewFundingEngine(),
 	}
 }
 
@@ -47,6 +51,13 @@ func (pe *PerpetualEngine) CreateMarket(
 	maxLeverage uint32,
 	maintenanceMargin *big.Int,
 ) ([32]byte, error) {
+	if initialPrice == nil || initialPrice.Sign() <= 0 {
+		return [32]byte{}, ErrInvalidParameter
+	}
+	if maintenanceMargin == nil || maintenanceMargin.Sign() <= 0 || maintenanceMargin.Cmp(big.NewInt(1e18)) >= 0 {
+		return [32]byte{}, ErrInvalidParameter
+	}
+
 	pe.mu.Lock()
 	defer pe.mu.Unlock()
 
@@ -62,6 +73,7 @@ func (pe *PerpetualEngine) CreateMarket(
 		return [32]byte{}, ErrPoolExists
 	}
 
+	now := pe.Funding.now()
 	market := &PerpMarket{
 		BaseAsset:         baseAsset,
 		QuoteAsset:        quoteAsset,
@@ -70,7 +82,7 @@ func (pe *PerpetualEngine) CreateMarket(
 		OpenInterestLong:  big.NewInt(0),
 		OpenInterestShort: big.NewInt(0),
 		FundingRate:       big.NewInt(0),
-		LastFundingTime:   time.Now().Unix(),
+		LastFundingTime:   now,
 		MaxLeverage:       maxLeverage,
 		MaintenanceMargin: maintenanceMargin,
 		InsuranceFund:     big.NewInt(0),
@@ -79,15 +91,28 @@ func (pe *PerpetualEngine) CreateMarket(
 	pe.Markets[marketID] = market
 	pe.FundingStates[marketID] = &FundingState{
 		CumulativeFunding: big.NewInt(0),
-		LastUpdateTime:    time.Now().Unix(),
+		LastUpdateTime:    now,
 		PremiumEMA:        big.NewInt(0),
-		TWAPWindow:        8 * 3600, // 8 hours
+		TWAPWindow:        DefaultFundingWindow,
 	}
 
 	return marketID, nil
 }
 
-// OpenPosition opens or increases a perpetual position
+// PerpClose describes how a position reduction was settled
+type PerpClose struct {
+	Size            *big.Int // Size closed (absolute)
+	RealizedPnL     *big.Int // PnL at the mark price, including funding
+	ReleasedMargin  *big.Int // Margin released by the reduction
+	Payout          *big.Int // Owed to the owner: released margin plus PnL
+	InsurancePayout *big.Int // Drawn from the insurance fund for a loss beyond the margin
+	BadDebt         *big.Int // Loss neither the margin nor the fund absorbed
+}
+
+// OpenPosition opens or increases a perpetual position at the mark price.
+// Funding is settled into the margin first, and the resulting position must
+// stay within the market's leverage cap and above maintenance margin.
+// Positions are reduced with ClosePosition.
 func (pe *PerpetualEngine) OpenPosition(
 	owner common.Address,
 	marketID [32]byte,
@@ -95,6 +120,13 @@ func (pe *PerpetualEngine) OpenPosition(
 	margin *big.Int,
 	isIsolated bool,
 ) (*PerpPosition, error) {
+	if size == nil || size.Sign() == 0 {
+		return nil, ErrInvalidPositionSize
+	}
+	if margin == nil || margin.Sign() < 0 {
+		return nil, ErrInvalidAmount
+	}
+
 	pe.mu.Lock()
 	defer pe.mu.Unlock()
 
@@ -102,61 +134,61 @@ func (pe *PerpetualEngine) OpenPosition(
 	if !exists {
 		return nil, ErrPoolNotFound
 	}
+	if market.MarkPrice == nil || market.MarkPrice.Sign() <= 0 {
+		return nil, ErrMarkPriceUnavailable
+	}
 
-	// Calculate effective leverage
-	notionalValue := new(big.Int).Abs(size)
-	notionalValue.Mul(notionalValue, market.MarkPrice)
-	notionalValue.Div(notionalValue, Q96)
-
-	leverage := new(big.Int).Mul(notionalValue, big.NewInt(100))
-	leverage.Div(leverage, margin)
+	fundingState := pe.accrueFunding(marketID, market)
+	position := pe.Positions[owner][marketID]
 
-	if leverage.Uint64() > uint64(market.MaxLeverage)*100 {
-		return nil, ErrExcessiveLeverage
+	// Build the resulting position on the side so a rejected trade leaves
+	// the existing one untouched
+	next := &PerpPosition{
+		Owner:            owner,
+		Market:           marketID,
+		Size:             new(big.Int).Set(size),
+		EntryPrice:       new(big.Int).Set(market.MarkPrice),
+		Margin:           new(big.Int).Set(margin),
+		LastFundingIndex: new(big.Int).Set(fundingState.CumulativeFunding),
+		IsIsolated:       isIsolated,
 	}
+	if position != nil {
+		if position.Size.Sign() != size.Sign() {
+			return nil, ErrInvalidPositionSize
+		}
 
-	// Get or create position
-	userPositions := pe.Positions[owner]
-	if userPositions == nil {
-		userPositions = make(map[[32]byte]*PerpPosition)
-		pe.Positions[owner] = userPositions
+		next.Size.Add(position.Size, size)
+		next.Margin.Add(next.Margin, position.Margin)
+		next.Margin.Add(next.Margin, fundingPayment(position, fundingState))
+		next.IsIsolated = position.IsIsolated
+
+		// Average entry = (|old| * oldEntry + |size| * mark) / |new|
+		oldNotional := new(big.Int).Mul(new(big.Int).Abs(position.Size), position.EntryPrice)
+		addNotional := new(big.Int).Mul(new(big.Int).Abs(size), market.MarkPrice)
+		next.EntryPrice.Add(oldNotional, addNotional)
+		next.EntryPrice.Div(next.EntryPrice, new(big.Int).Abs(next.Size))
 	}
 
-	position := userPositions[marketID]
-	fundingState := pe.FundingStates[marketID]
+	if err := checkPerpLeverage(next, market, next.Margin); err != nil {
+		return nil, err
+	}
+	if !pe.isPositionSafe(next, market, next.Margin) {
+		return nil, ErrInsufficientMargin
+	}
 
 	if position == nil {
-		// New position
-		position = &PerpPosition{
-			Owner:            owner,
-			Market:           marketID,
-			Size:             new(big.Int).Set(size),
-			EntryPrice:       new(big.Int).Set(market.MarkPrice),
-			Margin:           new(big.Int).Set(margin),
-			LastFundingIndex: new(big.Int).Set(fundingState.CumulativeFunding),
-			IsIsolated:       isIsolated,
+		userPositions := pe.Positions[owner]
+		if userPositions == nil {
+			userPositions = make(map[[32]byte]*PerpPosition)
+			pe.Positions[owner] = userPositions
 		}
-		userPositions[marketID] = position
+		userPositions[marketID] = next
+		position = next
 	} else {
-		// Settle funding before modifying
-		pe.settleFundingForPosition(position, fundingState)
-
-		// Increase position
-		oldNotional := new(big.Int).Mul(position.Size, position.EntryPrice)
-		newNotional := new(big.Int).Mul(size, market.MarkPrice)
-		totalNotional := new(big.Int).Add(oldNotional, newNotional)
-
-		newSize := new(big.Int).Add(position.Size, size)
-		if newSize.Sign() == 0 {
-			// Position closed
-			delete(userPositions, marketID)
-			return nil, nil
-		}
-
-		// Calculate new average entry price
-		position.EntryPrice.Div(totalNotional, newSize)
-		position.Size = newSize
-		position.Margin.Add(position.Margin, margin)
+		position.Size = next.Size
+		position.EntryPrice = next.EntryPrice
+		position.Margin = next.Margin
+		position.LastFundingIndex = next.LastFundingIndex
 	}
 
 	// Update open interest
@@ -169,12 +201,19 @@ func (pe *PerpetualEngine) OpenPosition(
 	return position, nil
 }
 
-// ClosePosition closes a perpetual position
+// ClosePosition closes part or all of a perpetual position at the mark
+// price. The closed share of the margin is released with the realized PnL;
+// a loss beyond it is drawn from the market's insurance fund, and whatever
+// the fund cannot cover is reported as bad debt.
 func (pe *PerpetualEngine) ClosePosition(
 	owner common.Address,
 	marketID [32]byte,
 	sizeToClose *big.Int, // Amount to close (absolute value)
-) (*big.Int, error) { // Returns realized PnL
+) (*PerpClose, error) {
+	if sizeToClose == nil || sizeToClose.Sign() <= 0 {
+		return nil, ErrInvalidPositionSize
+	}
+
 	pe.mu.Lock()
 	defer pe.mu.Unlock()
 
@@ -183,138 +222,163 @@ func (pe *PerpetualEngine) ClosePosition(
 		return nil, ErrPoolNotFound
 	}
 
-	userPositions := pe.Positions[owner]
-	if userPositions == nil {
-		return nil, ErrPositionNotFound
-	}
-
-	position := userPositions[marketID]
+	position := pe.Positions[owner][marketID]
 	if position == nil {
 		return nil, ErrPositionNotFound
 	}
 
-	// Settle funding first
-	fundingState := pe.FundingStates[marketID]
-	fundingPnL := pe.settleFundingForPosition(position, fundingState)
+	// Settle funding into the margin first
+	fundingPnL := pe.settleFundingForPosition(position, pe.accrueFunding(marketID, market))
+	position.Margin.Add(position.Margin, fundingPnL)
 
-	// Calculate PnL
 	positionSize := new(big.Int).Abs(position.Size)
-	if sizeToClose.Cmp(positionSize) > 0 {
-		sizeToClose = positionSize
-	}
+	closed := bigMin(sizeToClose, positionSize)
+	long := position.Size.Sign() > 0
 
-	// Price difference
-	priceDiff := new(big.Int).Sub(market.MarkPrice, position.EntryPrice)
-
-	// PnL = size * priceDiff / Q96
-	pnl := new(big.Int).Mul(sizeToClose, priceDiff)
-	pnl.Div(pnl, Q96)
-
-	// If short, negate PnL
-	if position.Size.Sign() < 0 {
-		pnl.Neg(pnl)
+	result := &PerpClose{
+		Size:            closed,
+		RealizedPnL:     positionPnL(position, closed, market.MarkPrice),
+		ReleasedMargin:  new(big.Int).Set(position.Margin),
+		InsurancePayout: big.NewInt(0),
+		BadDebt:         big.NewInt(0),
 	}
 
-	// Add funding PnL
-	pnl.Add(pnl, fundingPnL)
-
 	// Update position
-	if sizeToClose.Cmp(positionSize) >= 0 {
+	if closed.Cmp(positionSize) == 0 {
 		// Full close
-		delete(userPositions, marketID)
+		delete(pe.Positions[owner], marketID)
 	} else {
-		// Partial close
-		closeFraction := new(big.Int).Mul(sizeToClose, big.NewInt(1e18))
-		closeFraction.Div(closeFraction, positionSize)
-
-		marginReduction := new(big.Int).Mul(position.Margin, closeFraction)
-		marginReduction.Div(marginReduction, big.NewInt(1e18))
-		position.Margin.Sub(position.Margin, marginReduction)
+		// Partial close releases the closed share of the margin
+		result.ReleasedMargin.Mul(result.ReleasedMargin, closed)
+		result.ReleasedMargin.Div(result.ReleasedMargin, positionSize)
+		position.Margin.Sub(position.Margin, result.ReleasedMargin)
 
-		if position.Size.Sign() > 0 {
-			position.Size.Sub(position.Size, sizeToClose)
+		if long {
+			position.Size.Sub(position.Size, closed)
 		} else {
-			position.Size.Add(position.Size, sizeToClose)
+			position.Size.Add(position.Size, closed)
 		}
 	}
 
 	// Update open interest
-	if position.Size.Sign() > 0 {
-		market.OpenInterestLong.Sub(market.OpenInterestLong, sizeToClose)
+	if long {
+		market.OpenInterestLong.Sub(market.OpenInterestLong, closed)
 	} else {
-		market.OpenInterestShort.Sub(market.OpenInterestShort, sizeToClose)
+		market.OpenInterestShort.Sub(market.OpenInterestShort, closed)
 	}
 
-	return pnl, nil
+	result.Payout = new(big.Int).Add(result.ReleasedMargin, result.RealizedPnL)
+	result.RealizedPnL.Add(result.RealizedPnL, fundingPnL)
+	if result.Payout.Sign() < 0 {
+		deficit := new(big.Int).Neg(result.Payout)
+		result.InsurancePayout = bigMin(deficit, market.InsuranceFund)
+		market.InsuranceFund.Sub(market.InsuranceFund, result.InsurancePayout)
+		result.BadDebt = deficit.Sub(deficit, result.InsurancePayout)
+		result.Payout.SetInt64(0)
+	}
+
+	return result, nil
 }
 
-// AddMargin adds margin to an existing position
-func (pe *PerpetualEngine) AddMargin(
+// ModifyMargin adds (delta > 0) or removes (delta < 0) margin on a
+// position after settling its funding. Removal must leave the position
+// within the leverage cap and above maintenance margin.
+func (pe *PerpetualEngine) ModifyMargin(
 	owner common.Address,
 	marketID [32]byte,
-	amount *big.Int,
+	delta *big.Int,
 ) error {
+	if delta == nil || delta.Sign() == 0 {
+		return ErrInvalidAmount
+	}
+
 	pe.mu.Lock()
 	defer pe.mu.Unlock()
 
-	userPositions := pe.Positions[owner]
-	if userPositions == nil {
-		return ErrPositionNotFound
+	market, exists := pe.Markets[marketID]
+	if !exists {
+		return ErrPoolNotFound
 	}
 
-	position := userPositions[marketID]
+	position := pe.Positions[owner][marketID]
 	if position == nil {
 		return ErrPositionNotFound
 	}
 
-	position.Margin.Add(position.Margin, amount)
+	fundingState := pe.accrueFunding(marketID, market)
+	newMargin := new(big.Int).Add(position.Margin, fundingPayment(position, fundingState))
+	newMargin.Add(newMargin, delta)
+
+	if delta.Sign() < 0 {
+		if newMargin.Sign() <= 0 {
+			return ErrInsufficientMargin
+		}
+		if err := checkPerpLeverage(position, market, newMargin); err != nil {
+			return err
+		}
+		if !pe.isPositionSafe(position, market, newMargin) {
+			return ErrInsufficientMargin
+		}
+	}
+
+	pe.settleFundingForPosition(position, fundingState)
+	position.Margin = newMargin
 	return nil
 }
 
+// AddMargin adds margin to an existing position
+func (pe *PerpetualEngine) AddMargin(
+	owner common.Address,
+	marketID [32]byte,
+	amount *big.Int,
+) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+	return pe.ModifyMargin(owner, marketID, amount)
+}
+
 // RemoveMargin removes margin from an existing position (if still safe)
 func (pe *PerpetualEngine) RemoveMargin(
 	owner common.Address,
 	marketID [32]byte,
 	amount *big.Int,
 ) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+	return pe.ModifyMargin(owner, marketID, new(big.Int).Neg(amount))
+}
+
+// SettleFunding settles a position's accrued funding into its margin and
+// returns the payment (positive when the position received funding)
+func (pe *PerpetualEngine) SettleFunding(owner common.Address, marketID [32]byte) (*big.Int, error) {
 	pe.mu.Lock()
 	defer pe.mu.Unlock()
 
 	market, exists := pe.Markets[marketID]
 	if !exists {
-		return ErrPoolNotFound
-	}
-
-	userPositions := pe.Positions[owner]
-	if userPositions == nil {
-		return ErrPositionNotFound
+		return nil, ErrPoolNotFound
 	}
 
-	position := userPositions[marketID]
+	position := pe.Positions[owner][marketID]
 	if position == nil {
-		return ErrPositionNotFound
-	}
-
-	newMargin := new(big.Int).Sub(position.Margin, amount)
-	if newMargin.Sign() <= 0 {
-		return ErrInsufficientMargin
-	}
-
-	// Check if position is still safe
-	if !pe.isPositionSafe(position, market, newMargin) {
-		return ErrInsufficientMargin
+		return nil, ErrPositionNotFound
 	}
 
-	position.Margin = newMargin
-	return nil
+	payment := pe.settleFundingForPosition(position, pe.accrueFunding(marketID, market))
+	position.Margin.Add(position.Margin, payment)
+	return payment, nil
 }
 
-// LiquidatePosition liquidates an underwater position
+// LiquidatePosition liquidates an underwater position. Solvent positions
+// pay the liquidator reward and the insurance penalty out of their equity;
+// bankrupt positions draw on the insurance fund, then on ADL.
 func (pe *PerpetualEngine) LiquidatePosition(
 	liquidator common.Address,
 	owner common.Address,
 	marketID [32]byte,
-) (*big.Int, error) { // Returns liquidation reward
+) (*PerpLiquidation, error) {
 	pe.mu.Lock()
 	defer pe.mu.Unlock()
 
@@ -338,6 +402,9 @@ func (pe *PerpetualEngine) LiquidatePosition(
 		return nil, ErrPositionNotLiquidatable
 	}
 
+	// Settle funding into the margin before valuing the position
+	margin := new(big.Int).Add(position.Margin, pe.settleFundingForPosition(position, pe.accrueFunding(marketID, market)))
+
 	// Calculate position value and PnL
 	positionSize := new(big.Int).Abs(position.Size)
 	priceDiff := new(big.Int).Sub(market.MarkPrice, position.EntryPrice)
@@ -346,23 +413,57 @@ func (pe *PerpetualEngine) LiquidatePosition(
 	if position.Size.Sign() < 0 {
 		pnl.Neg(pnl)
 	}
+	equity := new(big.Int).Add(margin, pnl)
 
-	// Remaining margin after loss
-	remainingMargin := new(big.Int).Add(position.Margin, pnl)
-
-	// Liquidation reward (5% of position notional, capped at remaining margin)
 	notional := new(big.Int).Mul(positionSize, market.MarkPrice)
 	notional.Div(notional, Q96)
-	reward := new(big.Int).Div(notional, big.NewInt(20)) // 5%
-
-	if reward.Cmp(remainingMargin) > 0 {
-		// Socialized loss - use insurance fund
-		deficit := new(big.Int).Sub(reward, remainingMargin)
-		if market.InsuranceFund.Cmp(deficit) >= 0 {
-			market.InsuranceFund.Sub(market.InsuranceFund, deficit)
-		} else {
-			// ADL would be triggered here in production
-			reward = remainingMargin
+	reward := new(big.Int).Mul(notional, big.NewInt(DefaultLiquidatorReward))
+	reward.Div(reward, big.NewInt(MarginPrecision))
+	penalty := new(big.Int).Mul(notional, big.NewInt(DefaultLiquidationPenalty))
+	penalty.Div(penalty, big.NewInt(MarginPrecision))
+
+	result := &PerpLiquidation{
+		Reward:                big.NewInt(0),
+		Refund:                big.NewInt(0),
+		InsuranceContribution: big.NewInt(0),
+		InsurancePayout:       big.NewInt(0),
+		ADLFills:              make([]*ADLFill, 0),
+		BadDebt:               big.NewInt(0),
+	}
+
+	switch {
+	case equity.Sign() >= 0:
+		// Liquidator first, then the fund; the owner keeps the rest
+		result.Reward = bigMin(reward, equity)
+		rest := new(big.Int).Sub(equity, result.Reward)
+		result.InsuranceContribution = bigMin(penalty, rest)
+		result.Refund = rest.Sub(rest, result.InsuranceContribution)
+		market.InsuranceFund.Add(market.InsuranceFund, result.InsuranceContribution)
+
+	case market.InsuranceFund.Cmp(new(big.Int).Neg(equity)) >= 0:
+		// The fund covers the deficit and, as far as it can, the reward
+		deficit := new(big.Int).Neg(equity)
+		market.InsuranceFund.Sub(market.InsuranceFund, deficit)
+		result.Reward = bigMin(reward, market.InsuranceFund)
+		market.InsuranceFund.Sub(market.InsuranceFund, result.Reward)
+		result.InsurancePayout = deficit.Add(deficit, result.Reward)
+
+	default:
+		// The fund is exhausted: drain it, then close the position against
+		// the ADL queue at the price where it is fully paid for
+		result.InsurancePayout = new(big.Int).Set(market.InsuranceFund)
+		market.InsuranceFund.SetInt64(0)
+
+		result.BankruptcyPrice = bankruptcyPrice(position, new(big.Int).Add(margin, result.InsurancePayout))
+		fills, unfilled := pe.autoDeleverage(marketID, market, position.Size.Sign() > 0, positionSize, result.BankruptcyPrice)
+		result.ADLFills = fills
+
+		// Any size without a counterparty leaves its share of the loss unpaid
+		if unfilled.Sign() > 0 {
+			shortfall := new(big.Int).Neg(equity)
+			shortfall.Sub(shortfall, result.InsurancePayout)
+			shortfall.Mul(shortfall, unfilled)
+			result.BadDebt = shortfall.Div(shortfall, positionSize)
 		}
 	}
 
@@ -376,80 +477,7 @@ func (pe *PerpetualEngine) LiquidatePosition(
 	// Remove position
 	delete(userPositions, marketID)
 
-	return reward, nil
-}
-
-// UpdateFunding calculates and applies funding rate
-func (pe *PerpetualEngine) UpdateFunding(marketID [32]byte) error {
-	pe.mu.Lock()
-	defer pe.mu.Unlock()
-
-	market, exists := pe.Markets[marketID]
-	if !exists {
-		return ErrPoolNotFound
-	}
-
-	fundingState := pe.FundingStates[marketID]
-	now := time.Now().Unix()
-
-	// Only update every 8 hours
-	if now-fundingState.LastUpdateTime < int64(fundingState.TWAPWindow) {
-		return nil
-	}
-
-	// Calculate premium (mark - index) / index
-	premium := new(big.Int).Sub(market.MarkPrice, market.IndexPrice)
-	premium.Mul(premium, big.NewInt(1e18))
-	premium.Div(premium, market.IndexPrice)
-
-	// Update EMA of premium
-	alpha := big.NewInt(125) // 0.125 for 8h EMA
-	oneMinusAlpha := big.NewInt(875)
-
-	newEMA := new(big.Int).Mul(premium, alpha)
-	oldEMA := new(big.Int).Mul(fundingState.PremiumEMA, oneMinusAlpha)
-	fundingState.PremiumEMA.Add(newEMA, oldEMA)
-	fundingState.PremiumEMA.Div(fundingState.PremiumEMA, big.NewInt(1000))
-
-	// Interest rate component (0.01% per 8 hours = 0.0001)
-	interestRate := big.NewInt(100) // 0.01% in 1e6
-
-	// Funding rate = premium EMA + interest rate
-	// Clamped to ±0.75% per 8 hours
-	fundingRate := new(big.Int).Add(fundingState.PremiumEMA, interestRate)
-	maxRate := big.NewInt(7500) // 0.75% in 1e6
-	minRate := big.NewInt(-7500)
-
-	if fundingRate.Cmp(maxRate) > 0 {
-		fundingRate = maxRate
-	} else if fundingRate.Cmp(minRate) < 0 {
-		fundingRate = minRate
-	}
-
-	market.FundingRate = fundingRate
-	market.LastFundingTime = now
-
-	// Update cumulative funding
-	// cumulative += fundingRate * markPrice / 1e6
-	fundingPayment := new(big.Int).Mul(fundingRate, market.MarkPrice)
-	fundingPayment.Div(fundingPayment, big.NewInt(1e6))
-	fundingState.CumulativeFunding.Add(fundingState.CumulativeFunding, fundingPayment)
-	fundingState.LastUpdateTime = now
-
-	return nil
-}
-
-// GetFundingRate returns current funding rate for a market
-func (pe *PerpetualEngine) GetFundingRate(marketID [32]byte) (*big.Int, error) {
-	pe.mu.RLock()
-	defer pe.mu.RUnlock()
-
-	market, exists := pe.Markets[marketID]
-	if !exists {
-		return nil, ErrPoolNotFound
-	}
-
-	return new(big.Int).Set(market.FundingRate), nil
+	return result, nil
 }
 
 // GetPosition returns a user's position
@@ -517,6 +545,8 @@ func (pe *PerpetualEngine) UpdateMarkPrice(marketID [32]byte, newPrice *big.Int)
 		return ErrPoolNotFound
 	}
 
+	// Accrue at the old price before it changes
+	pe.accrueFunding(marketID, market)
 	market.MarkPrice = new(big.Int).Set(newPrice)
 	return nil
 }
@@ -531,24 +561,70 @@ func (pe *PerpetualEngine) UpdateIndexPrice(marketID [32]byte, newPrice *big.Int
 		return ErrPoolNotFound
 	}
 
+	// Accrue at the old price before it changes
+	pe.accrueFunding(marketID, market)
 	market.IndexPrice = new(big.Int).Set(newPrice)
 	return nil
 }
 
+// SyncPrices pulls a market's mark and index prices from the price feed at
+// block time now
+func (pe *PerpetualEngine) SyncPrices(stateDB StateDB, now uint64, marketID [32]byte) error {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	market, exists := pe.Markets[marketID]
+	if !exists {
+		return ErrPoolNotFound
+	}
+	if pe.PriceFeed == nil {
+		return ErrNoPriceFeed
+	}
+
+	mark, index, err := pe.PriceFeed.PerpPrices(stateDB, now, market.BaseAsset, market.QuoteAsset)
+	if err != nil {
+		return err
+	}
+	if mark.Sign() <= 0 || index.Sign() <= 0 {
+		return ErrInvalidParameter
+	}
+
+	// Accrue at the old prices before they change
+	pe.accrueFunding(marketID, market)
+	market.MarkPrice = mark
+	market.IndexPrice = index
+	return nil
+}
+
 // Helper functions
 
-func (pe *PerpetualEngine) settleFundingForPosition(position *PerpPosition, state *FundingState) *big.Int {
-	// Funding payment = size * (currentFundingIndex - lastFundingIndex) / Q96
-	fundingDiff := new(big.Int).Sub(state.CumulativeFunding, position.LastFundingIndex)
-	payment := new(big.Int).Mul(position.Size, fundingDiff)
-	payment.Div(payment, Q96)
+// positionPnL returns the PnL of size units of a position marked at price
+func positionPnL(position *PerpPosition, size, price *big.Int) *big.Int {
+	pnl := new(big.Int).Sub(price, position.EntryPrice)
+	pnl.Mul(pnl, size)
+	pnl.Div(pnl, Q96)
+	if position.Size.Sign() < 0 {
+		pnl.Neg(pnl)
+	}
+	return pnl
+}
 
-	// Longs pay shorts when funding > 0
-	// So longs get negative funding, shorts get positive
-	payment.Neg(payment)
+// checkPerpLeverage rejects a position whose notional at the mark price
+// exceeds margin times the market's leverage cap
+func checkPerpLeverage(position *PerpPosition, market *PerpMarket, margin *big.Int) error {
+	if margin.Sign() <= 0 {
+		return ErrInsufficientMargin
+	}
 
-	position.LastFundingIndex = new(big.Int).Set(state.CumulativeFunding)
-	return payment
+	notional := new(big.Int).Abs(position.Size)
+	notional.Mul(notional, market.MarkPrice)
+	notional.Div(notional, Q96)
+
+	maxNotional := new(big.Int).Mul(margin, big.NewInt(int64(market.MaxLeverage)))
+	if notional.Cmp(maxNotional) > 0 {
+		return ErrMaxLeverageExceeded
+	}
+	return nil
 }
 
 func (pe *PerpetualEngine) isPositionSafe(position *PerpPosition, market *PerpMarket, margin *big.Int) bool {
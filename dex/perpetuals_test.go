@@ -0,0 +1,102 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+func TestPerpLeverageCap(t *testing.T) {
+	pe, marketID := setupPerpMarket(t)
+
+	// 10x is within the cap but breaches the 10% maintenance margin
+	trader := common.HexToAddress("0xD000000000000000000000000000000000000004")
+	if _, err := pe.OpenPosition(trader, marketID, big.NewInt(10), big.NewInt(99), true); err != ErrInsufficientMargin {
+		t.Errorf("Expected ErrInsufficientMargin, got: %v", err)
+	}
+	if _, err := pe.OpenPosition(perpLong, marketID, big.NewInt(-1), big.NewInt(10), true); err != ErrInvalidPositionSize {
+		t.Errorf("Expected ErrInvalidPositionSize, got: %v", err)
+	}
+
+	// A market with a 0.05% maintenance margin is bounded by 1111x
+	base := Currency{Address: common.HexToAddress("0x3333333333333333333333333333333333333333")}
+	quote := Currency{Address: common.HexToAddress("0x2222222222222222222222222222222222222222")}
+	thinID, err := pe.CreateMarket(base, quote, new(big.Int).Mul(big.NewInt(100), Q96), 5000, big.NewInt(5e14))
+	if err != nil {
+		t.Fatalf("CreateMarket failed: %v", err)
+	}
+	if _, err := pe.OpenPosition(trader, thinID, big.NewInt(1112), big.NewInt(100), true); err != ErrMaxLeverageExceeded {
+		t.Errorf("Expected ErrMaxLeverageExceeded, got: %v", err)
+	}
+	if _, err := pe.OpenPosition(trader, thinID, big.NewInt(1111), big.NewInt(100), true); err != nil {
+		t.Errorf("Expected 1111x to open, got: %v", err)
+	}
+}
+
+func TestPerpPositionLifecycle(t *testing.T) {
+	pe, marketID := setupPerpMarket(t)
+
+	// Increasing at 110 averages the entry to 105
+	setPerpPrice(t, pe, marketID, 110)
+	position, err := pe.OpenPosition(perpLong, marketID, big.NewInt(10), big.NewInt(100), true)
+	if err != nil {
+		t.Fatalf("OpenPosition failed: %v", err)
+	}
+	if entry := new(big.Int).Div(position.EntryPrice, Q96); entry.Int64() != 105 || position.Margin.Int64() != 200 {
+		t.Errorf("Expected entry 105 and margin 200, got %s and %s", entry, position.Margin)
+	}
+
+	// Funding of 1 per unit: longs pay, shorts receive
+	pe.FundingStates[marketID].CumulativeFunding.Set(Q96)
+	if payment, err := pe.SettleFunding(perpShortB, marketID); err != nil || payment.Int64() != 6 {
+		t.Errorf("Expected funding 6, got %v: %v", payment, err)
+	}
+
+	// The long's margin is 180 after funding; 30 would breach maintenance
+	if err := pe.RemoveMargin(perpLong, marketID, big.NewInt(150)); err != ErrInsufficientMargin {
+		t.Errorf("Expected ErrInsufficientMargin, got: %v", err)
+	}
+	if err := pe.RemoveMargin(perpLong, marketID, big.NewInt(50)); err != nil {
+		t.Fatalf("RemoveMargin failed: %v", err)
+	}
+	if position, _ := pe.GetPosition(perpLong, marketID); position.Margin.Int64() != 130 {
+		t.Errorf("Expected margin 130, got %s", position.Margin)
+	}
+
+	// Closing half realizes 50 and releases half the margin
+	result, err := pe.ClosePosition(perpLong, marketID, big.NewInt(10))
+	if err != nil {
+		t.Fatalf("ClosePosition failed: %v", err)
+	}
+	if result.RealizedPnL.Int64() != 50 || result.ReleasedMargin.Int64() != 65 || result.Payout.Int64() != 115 {
+		t.Errorf("Expected pnl 50, released 65 and payout 115, got %s, %s and %s",
+			result.RealizedPnL, result.ReleasedMargin, result.Payout)
+	}
+	if market := pe.Markets[marketID]; market.OpenInterestLong.Int64() != 10 {
+		t.Errorf("Expected long open interest 10, got %s", market.OpenInterestLong)
+	}
+
+	// At 120 the short loses 120 on 106 of margin; the fund covers 10 of 14
+	setPerpPrice(t, pe, marketID, 120)
+	if err := pe.DepositInsurance(marketID, big.NewInt(10)); err != nil {
+		t.Fatalf("DepositInsurance failed: %v", err)
+	}
+	result, err = pe.ClosePosition(perpShortB, marketID, big.NewInt(6))
+	if err != nil {
+		t.Fatalf("ClosePosition failed: %v", err)
+	}
+	if result.Payout.Sign() != 0 || result.InsurancePayout.Int64() != 10 || result.BadDebt.Int64() != 4 {
+		t.Errorf("Expected payout 0, insurance 10 and bad debt 4, got %s, %s and %s",
+			result.Payout, result.InsurancePayout, result.BadDebt)
+	}
+	if _, err := pe.GetPosition(perpShortB, marketID); err != ErrPositionNotFound {
+		t.Errorf("Expected ErrPositionNotFound, got: %v", err)
+	}
+	if market := pe.Markets[marketID]; market.OpenInterestShort.Int64() != 6 {
+		t.Errorf("Expected short open interest 6, got %s", market.OpenInterestShort)
+	}
+}
@@ -0,0 +1,630 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// =========================================================================
+// Protocol-Owned Liquidity (LP-9093 LXBond)
+// =========================================================================
+//
+// The treasury buys liquidity by selling protocol tokens at a discount. A
+// bond market is opened on a pool that pairs the protocol token with
+// another currency. A bonder hands over liquidity of one of its positions
+// and receives protocol tokens worth that liquidity plus the discount,
+// vesting linearly through an LXEscrow lock.
+//
+// The liquidity never leaves the pool. It moves to a position owned by the
+// treasury over the same tick range, which the treasury manages like any
+// other position through LXPool. Liquidity is valued at the principal it
+// would withdraw, with the other currency converted at the pool's TWAP, so
+// a bonder cannot inflate its payout by moving the spot price.
+//
+// Markets and treasury positions are stored at the LXBond address:
+//
+//	bond/last                          -> ID of the last market opened
+//	bond/mkt || id || "c0", "c1", "hk"  -> pool key currencies and hooks
+//	bond/mkt || id || "trs"             -> treasury (set for every market)
+//	bond/mkt || id || "term"            -> fee | tick spacing | discount |
+//	                                       window | vesting | payout side | closed
+//	bond/mkt || id || "cap", "sold", "bond" -> capacity, sold, bonded
+//	bond/tpos || treasury               -> number of positions acquired
+//	bond/tpos || treasury || index      -> position key
+//
+// Payout locks vest from the block timestamp of the bond.
+
+// Storage key prefixes for LXBond
+var (
+	bondLastPrefix     = []byte("bond/last")
+	bondMarketPrefix   = []byte("bond/mkt")
+	bondPositionPrefix = []byte("bond/tpos")
+)
+
+const (
+	// MaxBondDiscountBps caps a bond market's discount (50%)
+	MaxBondDiscountBps uint32 = 5_000
+
+	// MinBondTWAPWindow is the shortest TWAP window a market may price with
+	MinBondTWAPWindow uint64 = 300
+)
+
+// TWAPOracle supplies time-weighted pool prices
+type TWAPOracle interface {
+	// TWAPPriceX96 returns the price of currency0 in currency1 averaged
+	// over the last window seconds, as a Q64.96 fixed-point number
+	TWAPPriceX96(poolId [32]byte, window uint64) (*big.Int, error)
+}
+
+// BondMarket sells protocol tokens for liquidity of one pool
+type BondMarket struct {
+	ID          uint64
+	Key         PoolKey
+	Payout      Currency       // Protocol token, one of the pool's currencies
+	Treasury    common.Address // Owns bonded liquidity and funds payouts
+	DiscountBps uint32         // Premium paid over the liquidity's value
+	TWAPWindow  uint64         // Pricing window in seconds
+	Vesting     uint64         // Payout vesting term in seconds
+
+	Capacity *big.Int // Funded payout not yet sold
+	Sold     *big.Int // Payout sold so far
+	Bonded   *big.Int // Liquidity acquired so far
+	Closed   bool
+}
+
+// POLManager holds bond markets and the treasury's acquired positions.
+// Markets and positions live at the LXBond address.
+type POLManager struct {
+	mu sync.RWMutex
+
+	// oracle prices bonds; bonding fails until one is set
+	oracle TWAPOracle
+}
+
+// NewPOLManager creates a POL manager with no markets
+func NewPOLManager() *POLManager {
+	return &POLManager{}
+}
+
+// bondMarketKey returns the storage key of a market field
+func bondMarketKey(id uint64, field string) common.Hash {
+	return makeStorageKey(bondMarketPrefix, append(encodeUint64(id)[24:], field...))
+}
+
+// bondPositionKey returns the storage key of a treasury's position count,
+// or of one of its positions
+func bondPositionKey(treasury common.Address, index ...uint64) common.Hash {
+	id := treasury.Bytes()
+	for _, i := range index {
+		id = append(id, encodeUint64(i)[24:]...)
+	}
+	return makeStorageKey(bondPositionPrefix, id)
+}
+
+// loadMarket loads a bond market, or returns ErrBondMarketNotFound
+func (m *POLManager) loadMarket(stateDB StateDB, id uint64) (*BondMarket, error) {
+	get := func(field string) common.Hash {
+		return stateDB.GetState(lxBondAddr, bondMarketKey(id, field))
+	}
+	treasury := common.BytesToAddress(get("trs").Bytes())
+	if treasury == (common.Address{}) {
+		return nil, ErrBondMarketNotFound
+	}
+
+	term := get("term")
+	market := &BondMarket{
+		ID: id,
+		Key: PoolKey{
+			Currency0:   Currency{Address: common.BytesToAddress(get("c0").Bytes())},
+			Currency1:   Currency{Address: common.BytesToAddress(get("c1").Bytes())},
+			Fee:         uint24(binary.BigEndian.Uint32(term[0:4])),
+			TickSpacing: int24(int32(binary.BigEndian.Uint32(term[4:8]))),
+			Hooks:       common.BytesToAddress(get("hk").Bytes()),
+		},
+		Treasury:    treasury,
+		DiscountBps: binary.BigEndian.Uint32(term[8:12]),
+		TWAPWindow:  binary.BigEndian.Uint64(term[12:20]),
+		Vesting:     binary.BigEndian.Uint64(term[20:28]),
+		Capacity:    get("cap").Big(),
+		Sold:        get("sold").Big(),
+		Bonded:      get("bond").Big(),
+		Closed:      term[31] != 0,
+	}
+	market.Payout = market.Key.Currency1
+	if term[30] != 0 {
+		market.Payout = market.Key.Currency0
+	}
+	return market, nil
+}
+
+// saveMarket stores a bond market
+func (m *POLManager) saveMarket(stateDB StateDB, market *BondMarket) {
+	set := func(field string, value common.Hash) {
+		stateDB.SetState(lxBondAddr, bondMarketKey(market.ID, field), value)
+	}
+
+	var term common.Hash
+	binary.BigEndian.PutUint32(term[0:4], uint32(market.Key.Fee))
+	binary.BigEndian.PutUint32(term[4:8], uint32(int32(market.Key.TickSpacing)))
+	binary.BigEndian.PutUint32(term[8:12], market.DiscountBps)
+	binary.BigEndian.PutUint64(term[12:20], market.TWAPWindow)
+	binary.BigEndian.PutUint64(term[20:28], market.Vesting)
+	if market.Payout == market.Key.Currency0 {
+		term[30] = 1
+	}
+	if market.Closed {
+		term[31] = 1
+	}
+
+	set("c0", common.BytesToHash(market.Key.Currency0.Address.Bytes()))
+	set("c1", common.BytesToHash(market.Key.Currency1.Address.Bytes()))
+	set("hk", common.BytesToHash(market.Key.Hooks.Bytes()))
+	set("trs", common.BytesToHash(market.Treasury.Bytes()))
+	set("term", term)
+	set("cap", common.BigToHash(market.Capacity))
+	set("sold", common.BigToHash(market.Sold))
+	set("bond", common.BigToHash(market.Bonded))
+}
+
+// SetOracle sets the TWAP source used to price bonds
+func (m *POLManager) SetOracle(oracle TWAPOracle) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.oracle = oracle
+}
+
+// GetMarket returns a bond market
+func (m *POLManager) GetMarket(stateDB StateDB, id uint64) (*BondMarket, error) {
+	return m.loadMarket(stateDB, id)
+}
+
+// TreasuryPositions returns the position keys a treasury acquired by bonding
+func (m *POLManager) TreasuryPositions(stateDB StateDB, treasury common.Address) [][32]byte {
+	count := decodeUint64Word(stateDB.GetState(lxBondAddr, bondPositionKey(treasury)).Bytes())
+	positions := make([][32]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		positions = append(positions, stateDB.GetState(lxBondAddr, bondPositionKey(treasury, i)))
+	}
+	return positions
+}
+
+// addTreasuryPosition records a position key acquired by a treasury
+func (m *POLManager) addTreasuryPosition(stateDB StateDB, treasury common.Address, key [32]byte) {
+	count := decodeUint64Word(stateDB.GetState(lxBondAddr, bondPositionKey(treasury)).Bytes())
+	stateDB.SetState(lxBondAddr, bondPositionKey(treasury, count), key)
+	stateDB.SetState(lxBondAddr, bondPositionKey(treasury), common.BytesToHash(encodeUint64(count+1)))
+}
+
+// treasuryPositionSalt derives the salt of a market's treasury positions
+func treasuryPositionSalt(marketID uint64) [32]byte {
+	var salt [32]byte
+	copy(salt[:4], "bond")
+	binary.BigEndian.PutUint64(salt[24:], marketID)
+	return salt
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// POL returns the pool manager's protocol-owned liquidity manager
+func (pm *PoolManager) POL() *POLManager {
+	return pm.pol
+}
+
+// SetPOLOracle sets the TWAP source used to price bonds
+func (pm *PoolManager) SetPOLOracle(oracle TWAPOracle) {
+	pm.pol.SetOracle(oracle)
+}
+
+// CreateBondMarket opens a bond market on a pool (protocol fee controller
+// only). The market sells nothing until its treasury funds it.
+func (pm *PoolManager) CreateBondMarket(
+	stateDB StateDB,
+	caller common.Address,
+	key PoolKey,
+	payout Currency,
+	treasury common.Address,
+	discountBps uint32,
+	twapWindow uint64,
+	vesting uint64,
+) (uint64, error) {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return 0, ErrUnauthorized
+	}
+	if payout != key.Currency0 && payout != key.Currency1 {
+		return 0, ErrInvalidBondTerms
+	}
+	if treasury == (common.Address{}) || discountBps > MaxBondDiscountBps || twapWindow < MinBondTWAPWindow {
+		return 0, ErrInvalidBondTerms
+	}
+	if !pm.getPool(stateDB, key.ID()).IsInitialized() {
+		return 0, ErrPoolNotInitialized
+	}
+
+	lastKey := makeStorageKey(bondLastPrefix, nil)
+	id := decodeUint64Word(stateDB.GetState(lxBondAddr, lastKey).Bytes()) + 1
+	stateDB.SetState(lxBondAddr, lastKey, common.BytesToHash(encodeUint64(id)))
+	pm.pol.saveMarket(stateDB, &BondMarket{
+		ID:          id,
+		Key:         key,
+		Payout:      payout,
+		Treasury:    treasury,
+		DiscountBps: discountBps,
+		TWAPWindow:  twapWindow,
+		Vesting:     vesting,
+		Capacity:    big.NewInt(0),
+		Sold:        big.NewInt(0),
+		Bonded:      big.NewInt(0),
+	})
+	return id, nil
+}
+
+// FundBondMarket adds payout capacity to a market. The treasury must be the
+// current locker and owes the amount in its delta.
+func (pm *PoolManager) FundBondMarket(stateDB StateDB, id uint64, amount *big.Int) error {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return ErrUnauthorized
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+
+	market, err := pm.pol.loadMarket(stateDB, id)
+	if err != nil {
+		return err
+	}
+	if market.Closed {
+		return ErrBondMarketClosed
+	}
+	if locker != market.Treasury {
+		return ErrUnauthorized
+	}
+
+	market.Capacity.Add(market.Capacity, amount)
+	pm.pol.saveMarket(stateDB, market)
+
+	// Positive delta: the treasury owes the pool
+	pm.updateDelta(locker, market.Payout, amount)
+	return nil
+}
+
+// CloseBondMarket stops a market and returns its unsold capacity to the
+// treasury, which must be the current locker
+func (pm *PoolManager) CloseBondMarket(stateDB StateDB, id uint64) (*big.Int, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+
+	market, err := pm.pol.loadMarket(stateDB, id)
+	if err != nil {
+		return nil, err
+	}
+	if locker != market.Treasury {
+		return nil, ErrUnauthorized
+	}
+	if market.Closed {
+		return nil, ErrBondMarketClosed
+	}
+
+	refund := market.Capacity
+	market.Capacity = big.NewInt(0)
+	market.Closed = true
+	pm.pol.saveMarket(stateDB, market)
+
+	// Negative delta: the pool owes the treasury
+	pm.updateDelta(locker, market.Payout, new(big.Int).Neg(refund))
+	return refund, nil
+}
+
+// QuoteBond returns the payout for bonding liquidity over a tick range
+func (pm *PoolManager) QuoteBond(stateDB StateDB, id uint64, tickLower, tickUpper int24, liquidity *big.Int) (*big.Int, error) {
+	market, err := pm.pol.loadMarket(stateDB, id)
+	if err != nil {
+		return nil, err
+	}
+	return pm.quoteBond(stateDB, market, tickLower, tickUpper, liquidity)
+}
+
+// quoteBond values liquidity in the payout currency at the TWAP and adds the
+// market's discount
+func (pm *PoolManager) quoteBond(stateDB StateDB, market *BondMarket, tickLower, tickUpper int24, liquidity *big.Int) (*big.Int, error) {
+	if liquidity == nil || liquidity.Sign() <= 0 || tickLower >= tickUpper {
+		return nil, ErrInvalidAmount
+	}
+	pm.pol.mu.RLock()
+	oracle := pm.pol.oracle
+	pm.pol.mu.RUnlock()
+	if oracle == nil {
+		return nil, ErrNoPriceOracle
+	}
+	priceX96, err := oracle.TWAPPriceX96(market.Key.ID(), market.TWAPWindow)
+	if err != nil {
+		return nil, err
+	}
+	if priceX96 == nil || priceX96.Sign() <= 0 {
+		return nil, ErrNoPriceOracle
+	}
+
+	// Principal the liquidity would withdraw at the current tick
+	pool := pm.getPool(stateDB, market.Key.ID())
+	principal := pm.calculateLiquidityAmounts(pool, market.Key, ModifyLiquidityParams{
+		TickLower:      tickLower,
+		TickUpper:      tickUpper,
+		LiquidityDelta: liquidity,
+	}, market.Treasury)
+
+	var value *big.Int
+	if market.Payout == market.Key.Currency1 {
+		// value = amount1 + amount0 * price
+		value = new(big.Int).Mul(principal.Amount0, priceX96)
+		value.Div(value, Q96)
+		value.Add(value, principal.Amount1)
+	} else {
+		// value = amount0 + amount1 / price
+		value = new(big.Int).Mul(principal.Amount1, Q96)
+		value.Div(value, priceX96)
+		value.Add(value, principal.Amount0)
+	}
+
+	// payout = value / (1 - discount)
+	payout := value.Mul(value, big.NewInt(10_000))
+	return payout.Div(payout, big.NewInt(int64(10_000-market.DiscountBps))), nil
+}
+
+// Bond sells protocol tokens for liquidity of one of the current locker's
+// positions. The liquidity moves to the treasury's position over the same
+// range, and the payout vests to the locker through an escrow lock.
+func (pm *PoolManager) Bond(
+	stateDB StateDB,
+	id uint64,
+	tickLower, tickUpper int24,
+	salt [32]byte,
+	liquidity *big.Int,
+	minPayout *big.Int,
+) (uint64, *big.Int, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return 0, nil, ErrUnauthorized
+	}
+
+	market, err := pm.pol.loadMarket(stateDB, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	if market.Closed {
+		return 0, nil, ErrBondMarketClosed
+	}
+
+	payout, err := pm.quoteBond(stateDB, market, tickLower, tickUpper, liquidity)
+	if err != nil {
+		return 0, nil, err
+	}
+	if minPayout != nil && payout.Cmp(minPayout) < 0 {
+		return 0, nil, ErrBondSlippage
+	}
+	if payout.Cmp(market.Capacity) > 0 {
+		return 0, nil, ErrBondCapacityExceeded
+	}
+
+	sourceKey := PositionKey(locker, tickLower, tickUpper, salt)
+	if pm.getPosition(stateDB, sourceKey).Liquidity.Cmp(liquidity) < 0 {
+		return 0, nil, ErrInsufficientLiquidity
+	}
+
+	now := stateDB.GetBlockTimestamp()
+	lockID, err := pm.escrow.add(stateDB, &VestingLock{
+		Kind:        LockTokens,
+		Creator:     lxBondAddr,
+		Beneficiary: locker,
+		Schedule:    VestingSchedule{Start: now, Cliff: now, End: now + market.Vesting},
+		Currency:    market.Payout,
+		Total:       new(big.Int).Set(payout),
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	treasuryKey := PositionKey(market.Treasury, tickLower, tickUpper, treasuryPositionSalt(market.ID))
+	if pm.getPosition(stateDB, treasuryKey).Liquidity.Sign() == 0 {
+		pm.pol.addTreasuryPosition(stateDB, market.Treasury, treasuryKey)
+	}
+	pm.moveLiquidity(stateDB,
+		&VestingLock{PoolID: market.Key.ID(), TickLower: tickLower, TickUpper: tickUpper},
+		positionRef{key: sourceKey, owner: locker, earner: locker},
+		positionRef{key: treasuryKey, owner: market.Treasury, earner: market.Treasury},
+		liquidity,
+	)
+
+	market.Capacity.Sub(market.Capacity, payout)
+	market.Sold.Add(market.Sold, payout)
+	market.Bonded.Add(market.Bonded, liquidity)
+	pm.pol.saveMarket(stateDB, market)
+	return lockID, payout, nil
+}
+
+// =========================================================================
+// LXBond precompile
+// =========================================================================
+
+// Method selectors for LXBond
+const (
+	SelectorCreateBondMarket uint32 = 0x01000000 // createMarket(PoolKey,Currency,address,uint32,uint64,uint64)
+	SelectorFundBondMarket   uint32 = 0x02000000 // fund(uint64,uint256)
+	SelectorBond             uint32 = 0x03000000 // bond(uint64,int24,int24,bytes32,uint256,uint256)
+	SelectorCloseBondMarket  uint32 = 0x04000000 // close(uint64)
+	SelectorQuoteBond        uint32 = 0x05000000 // quote(uint64,int24,int24,uint256)
+	SelectorGetBondMarket    uint32 = 0x06000000 // getMarket(uint64)
+)
+
+// BondContract implements the LXBond precompile over the pool manager
+// shared with LXPool
+type BondContract struct {
+	poolManager *PoolManager
+}
+
+// Run executes the precompile
+func (c *BondContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, fmt.Errorf("input too short")
+	}
+
+	selector := binary.BigEndian.Uint32(input[:4])
+	data := input[4:]
+
+	gas := c.RequiredGas(input)
+	if suppliedGas < gas {
+		return nil, 0, fmt.Errorf("out of gas")
+	}
+	remainingGas = suppliedGas - gas
+
+	// The market ID leads every method except createMarket
+	if selector != SelectorCreateBondMarket && len(data) < 32 {
+		return nil, remainingGas, fmt.Errorf("input too short")
+	}
+	stateAdapter := newPoolStateAdapter(accessibleState)
+
+	switch selector {
+	case SelectorGetBondMarket:
+		market, err := c.poolManager.pol.GetMarket(stateAdapter, decodeUint64Word(data[:32]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return EncodeBondMarket(market), remainingGas, nil
+
+	case SelectorQuoteBond:
+		// id (32) + tickLower (32) + tickUpper (32) + liquidity (32)
+		if len(data) < 128 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		payout, err := c.poolManager.QuoteBond(stateAdapter, decodeUint64Word(data[:32]),
+			decodeInt24Word(data[32:64]), decodeInt24Word(data[64:96]), new(big.Int).SetBytes(data[96:128]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return common.LeftPadBytes(payout.Bytes(), 32), remainingGas, nil
+
+	case SelectorCreateBondMarket, SelectorFundBondMarket, SelectorBond, SelectorCloseBondMarket:
+	default:
+		return nil, suppliedGas, fmt.Errorf("unknown method selector: %x", selector)
+	}
+
+	if readOnly {
+		return nil, suppliedGas, fmt.Errorf("cannot write in read-only mode")
+	}
+
+	switch selector {
+	case SelectorCreateBondMarket:
+		// PoolKey (128) + payout (32) + treasury (32) + discount (32) + window (32) + vesting (32)
+		if len(data) < 288 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		key, err := DecodePoolKey(data[:128])
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		id, err := c.poolManager.CreateBondMarket(stateAdapter, caller, key,
+			Currency{Address: common.BytesToAddress(data[140:160])},
+			common.BytesToAddress(data[172:192]),
+			binary.BigEndian.Uint32(data[220:224]),
+			decodeUint64Word(data[224:256]),
+			decodeUint64Word(data[256:288]),
+		)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return encodeUint64(id), remainingGas, nil
+
+	case SelectorFundBondMarket:
+		// id (32) + amount (32)
+		if len(data) < 64 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		if err := c.poolManager.FundBondMarket(stateAdapter, decodeUint64Word(data[:32]), new(big.Int).SetBytes(data[32:64])); err != nil {
+			return nil, remainingGas, err
+		}
+		return nil, remainingGas, nil
+
+	case SelectorBond:
+		// id (32) + tickLower (32) + tickUpper (32) + salt (32) + liquidity (32) + minPayout (32)
+		if len(data) < 192 {
+			return nil, remainingGas, fmt.Errorf("input too short")
+		}
+		var salt [32]byte
+		copy(salt[:], data[96:128])
+		lockID, payout, err := c.poolManager.Bond(stateAdapter, decodeUint64Word(data[:32]),
+			decodeInt24Word(data[32:64]), decodeInt24Word(data[64:96]), salt,
+			new(big.Int).SetBytes(data[128:160]), new(big.Int).SetBytes(data[160:192]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		result := make([]byte, 64)
+		binary.BigEndian.PutUint64(result[24:32], lockID)
+		payout.FillBytes(result[32:64])
+		return result, remainingGas, nil
+
+	default: // SelectorCloseBondMarket
+		refund, err := c.poolManager.CloseBondMarket(stateAdapter, decodeUint64Word(data[:32]))
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return common.LeftPadBytes(refund.Bytes(), 32), remainingGas, nil
+	}
+}
+
+// RequiredGas returns the gas required for the precompile input
+func (c *BondContract) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return GasPoolLookup
+	}
+
+	switch binary.BigEndian.Uint32(input[:4]) {
+	case SelectorCreateBondMarket:
+		return GasBondCreate
+	case SelectorFundBondMarket, SelectorCloseBondMarket:
+		return GasBondFund
+	case SelectorBond:
+		return GasBond
+	case SelectorQuoteBond:
+		return GasBondQuote
+	default:
+		return GasPoolLookup
+	}
+}
+
+// EncodeBondMarket encodes a market: id (32) + payout (32) + treasury (32) +
+// discount (32) + window (32) + vesting (32) + capacity (32) + sold (32) +
+// bonded (32) + closed (32)
+func EncodeBondMarket(market *BondMarket) []byte {
+	result := make([]byte, 320)
+	binary.BigEndian.PutUint64(result[24:32], market.ID)
+	copy(result[44:64], market.Payout.Address.Bytes())
+	copy(result[76:96], market.Treasury.Bytes())
+	binary.BigEndian.PutUint32(result[124:128], market.DiscountBps)
+	binary.BigEndian.PutUint64(result[152:160], market.TWAPWindow)
+	binary.BigEndian.PutUint64(result[184:192], market.Vesting)
+	market.Capacity.FillBytes(result[192:224])
+	market.Sold.FillBytes(result[224:256])
+	market.Bonded.FillBytes(result[256:288])
+	if market.Closed {
+		result[319] = 1
+	}
+	return result
+}
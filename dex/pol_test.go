@@ -0,0 +1,138 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var testTreasury = common.HexToAddress("0x9999999999999999999999999999999999999999")
+
+// fixedTWAP reports the same price for every pool
+type fixedTWAP struct {
+	priceX96 *big.Int
+}
+
+func (o *fixedTWAP) TWAPPriceX96(poolId [32]byte, window uint64) (*big.Int, error) {
+	return o.priceX96, nil
+}
+
+func TestBondLiquidityForTreasury(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	pm.setProtocolFeeController(stateDB, testTreasury)
+	stateDB.SetBlockTimestamp(1000)
+	key := newTestPoolKey()
+
+	sqrtPriceX96 := new(big.Int).Lsh(big.NewInt(1), 96)
+	if _, err := pm.Initialize(stateDB, key, sqrtPriceX96, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	if _, err := pm.CreateBondMarket(stateDB, testTrader, key, key.Currency1, testTreasury, 1_000, 3600, 500); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if _, err := pm.CreateBondMarket(stateDB, testTreasury, key, key.Currency1, testTreasury, MaxBondDiscountBps+1, 3600, 500); err != ErrInvalidBondTerms {
+		t.Errorf("Expected ErrInvalidBondTerms for discount, got %v", err)
+	}
+	id, err := pm.CreateBondMarket(stateDB, testTreasury, key, key.Currency1, testTreasury, 1_000, 3600, 500)
+	if err != nil {
+		t.Fatalf("CreateBondMarket failed: %v", err)
+	}
+
+	openLock(pm, testTreasury)
+	if err := pm.FundBondMarket(stateDB, id, big.NewInt(10_000)); err != nil {
+		t.Fatalf("FundBondMarket failed: %v", err)
+	}
+	if delta := pm.GetDelta(testTreasury, key.Currency1); delta.Cmp(big.NewInt(10_000)) != 0 {
+		t.Errorf("Expected treasury delta 10000, got %s", delta)
+	}
+
+	openLock(pm, testTrader)
+	params := ModifyLiquidityParams{TickLower: -1000, TickUpper: 1000, LiquidityDelta: big.NewInt(10_000)}
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, params, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+
+	if _, _, err := pm.Bond(stateDB, id, -1000, 1000, [32]byte{}, big.NewInt(4_000), nil); err != ErrNoPriceOracle {
+		t.Errorf("Expected ErrNoPriceOracle, got %v", err)
+	}
+
+	// 4000 liquidity withdraws 2000 of each currency. At a TWAP of 2 that is
+	// worth 6000 of currency1, paid out at a 10% discount.
+	pm.SetPOLOracle(&fixedTWAP{priceX96: new(big.Int).Lsh(big.NewInt(2), 96)})
+	quote, err := pm.QuoteBond(stateDB, id, -1000, 1000, big.NewInt(4_000))
+	if err != nil {
+		t.Fatalf("QuoteBond failed: %v", err)
+	}
+	if quote.Cmp(big.NewInt(6_666)) != 0 {
+		t.Errorf("Expected quote 6666, got %s", quote)
+	}
+
+	if _, _, err := pm.Bond(stateDB, id, -1000, 1000, [32]byte{}, big.NewInt(4_000), big.NewInt(7_000)); err != ErrBondSlippage {
+		t.Errorf("Expected ErrBondSlippage, got %v", err)
+	}
+	lockID, payout, err := pm.Bond(stateDB, id, -1000, 1000, [32]byte{}, big.NewInt(4_000), quote)
+	if err != nil {
+		t.Fatalf("Bond failed: %v", err)
+	}
+	if payout.Cmp(quote) != 0 {
+		t.Errorf("Expected payout %s, got %s", quote, payout)
+	}
+
+	// The liquidity now belongs to the treasury
+	source := pm.getPosition(stateDB, PositionKey(testTrader, -1000, 1000, [32]byte{}))
+	if source.Liquidity.Cmp(big.NewInt(6_000)) != 0 {
+		t.Errorf("Expected bonder liquidity 6000, got %s", source.Liquidity)
+	}
+	owned := pm.POL().TreasuryPositions(stateDB, testTreasury)
+	if len(owned) != 1 {
+		t.Fatalf("Expected 1 treasury position, got %d", len(owned))
+	}
+	if pos := pm.getPosition(stateDB, owned[0]); pos.Liquidity.Cmp(big.NewInt(4_000)) != 0 || pos.Owner != testTreasury {
+		t.Errorf("Expected treasury position of 4000, got %s owned by %s", pos.Liquidity, pos.Owner.Hex())
+	}
+
+	// The payout vests to the bonder
+	lock, err := pm.Escrow().GetLock(stateDB, lockID)
+	if err != nil {
+		t.Fatalf("GetLock failed: %v", err)
+	}
+	if lock.Beneficiary != testTrader || lock.Total.Cmp(payout) != 0 || lock.Schedule.End != 1500 {
+		t.Errorf("Unexpected payout lock: beneficiary %s total %s end %d", lock.Beneficiary.Hex(), lock.Total, lock.Schedule.End)
+	}
+
+	// A second bond of the same size exceeds the remaining capacity
+	if _, _, err := pm.Bond(stateDB, id, -1000, 1000, [32]byte{}, big.NewInt(4_000), nil); err != ErrBondCapacityExceeded {
+		t.Errorf("Expected ErrBondCapacityExceeded, got %v", err)
+	}
+
+	// Only the treasury closes, and it is refunded the unsold capacity
+	if _, err := pm.CloseBondMarket(stateDB, id); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized for bonder, got %v", err)
+	}
+	openLock(pm, testTreasury)
+	refund, err := pm.CloseBondMarket(stateDB, id)
+	if err != nil {
+		t.Fatalf("CloseBondMarket failed: %v", err)
+	}
+	if refund.Cmp(big.NewInt(10_000-6_666)) != 0 {
+		t.Errorf("Expected refund 3334, got %s", refund)
+	}
+
+	// The market lives in state, not in the manager
+	market, err := NewPOLManager().GetMarket(stateDB, id)
+	if err != nil {
+		t.Fatalf("GetMarket failed: %v", err)
+	}
+	if !market.Closed || market.Key != key || market.Payout != key.Currency1 || market.Sold.Cmp(quote) != 0 || market.Bonded.Int64() != 4_000 {
+		t.Errorf("Unexpected market from state: %+v", market)
+	}
+	if _, _, err := pm.Bond(stateDB, id, -1000, 1000, [32]byte{}, big.NewInt(1_000), nil); err != ErrBondMarketClosed {
+		t.Errorf("Expected ErrBondMarketClosed, got %v", err)
+	}
+}
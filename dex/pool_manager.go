@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"math/big"
 	"sync"
 
@@ -15,7 +16,9 @@ import (
 	"github.com/zeebo/blake3"
 )
 
-// StateDB interface for accessing and modifying EVM state
+// StateDB interface for accessing and modifying EVM state. GetBlockNumber
+// and GetBlockTimestamp report the block being executed, so time-dependent
+// state (observations, vesting, epochs) agrees on every node.
 type StateDB interface {
 	GetState(addr common.Address, key common.Hash) common.Hash
 	SetState(addr common.Address, key common.Hash, value common.Hash)
@@ -25,6 +28,7 @@ type StateDB interface {
 	Exist(addr common.Address) bool
 	CreateAccount(addr common.Address)
 	GetBlockNumber() uint64
+	GetBlockTimestamp() uint64
 }
 
 // Precompile address as bytes (LP-9010 LXPool)
@@ -41,6 +45,16 @@ var (
 	settledPrefix       = []byte("setl")
 	protocolFeePrefix   = []byte("pfee")
 	hookRegistryPrefix  = []byte("hook")
+	escrowPrefix        = []byte("escr")
+	observationPrefix   = []byte("obsv")
+	reservePrefix       = []byte("rsrv")
+	claimPrefix         = []byte("clam")
+	gaugePrefix         = []byte("gaug")
+	gaugePositionPrefix = []byte("gpos")
+	gaugePoolPrefix     = []byte("gpol")
+	feeTierPrefix       = []byte("ftir")
+	tokenPrefix         = []byte("tokn")
+	referralPrefix      = []byte("rfrl")
 )
 
 // PoolManager implements the singleton DEX pool manager precompile
@@ -71,18 +85,75 @@ type PoolManager struct {
 	// lockers tracks active callback contexts (for reentrancy)
 	lockers []common.Address
 
-	// protocolFeeController can set protocol fees
-	protocolFeeController common.Address
+	// syncedCurrency is the currency synced for the next settle, if any
+	syncedCurrency *Currency
+
+	// gauges is notified of position liquidity changes (optional)
+	gauges *GaugeController
+
+	// referrals accrues referrer shares of swap fees
+	referrals *ReferralBook
+
+	// tokens settles fee-on-transfer and rebasing currencies
+	tokens *TokenAdapter
+
+	// escrow holds vesting locks over tokens and positions
+	escrow *EscrowManager
+
+	// lottery runs per-epoch swap lotteries for enrolled pools
+	lottery *Lottery
+
+	// book matches limit orders (LXBook)
+	book *OrderBook
+
+	// oracle aggregates reported prices (LXOracle)
+	oracle *PriceOracle
+
+	// feed derives mark and index prices from the book and oracle (LXFeed)
+	feed *PriceFeed
+
+	// identity holds the attestations checked by the compliance hook
+	identity *IdentityRegistry
+
+	// nativeHooks maps hook addresses to hooks run in the precompile
+	nativeHooks map[common.Address]NativeHook
+
+	// pol sells protocol tokens for treasury-owned liquidity
+	pol *POLManager
+
+	// feeTiers holds the fee tiers new pools may use
+	feeTiers *FeeTierRegistry
 }
 
 // NewPoolManager creates a new pool manager instance
 func NewPoolManager() *PoolManager {
-	return &PoolManager{
+	pm := &PoolManager{
 		pools:         make(map[[32]byte]*Pool),
 		positions:     make(map[[32]byte]*Position),
 		currentDeltas: make(map[common.Address]map[Currency]*big.Int),
 		lockers:       make([]common.Address, 0),
-	}
+		referrals:     NewReferralBook(DefaultReferralShareBps),
+		tokens:        NewTokenAdapter(),
+		escrow:        NewEscrowManager(),
+		lottery:       NewLottery(DefaultLotteryEpoch, DefaultLotteryShareBps),
+		book:          NewOrderBook(NewBookControls()),
+		pol:           NewPOLManager(),
+		feeTiers:      NewFeeTierRegistry(),
+		gauges:        NewGaugeController(),
+	}
+	pm.identity = NewIdentityRegistry()
+	pm.oracle = NewPriceOracle()
+	pm.feed = NewPriceFeed(pm.book, pm.oracle)
+	pm.nativeHooks = map[common.Address]NativeHook{
+		ComplianceHookAddress: NewComplianceHook(pm.identity),
+	}
+	return pm
+}
+
+// SetGaugeController attaches a gauge controller that checkpoints
+// liquidity mining rewards whenever a position is modified
+func (pm *PoolManager) SetGaugeController(gc *GaugeController) {
+	pm.gauges = gc
 }
 
 // makeStorageKey creates a storage key from prefix and identifier
@@ -106,6 +177,19 @@ func (pm *PoolManager) Initialize(
 	key PoolKey,
 	sqrtPriceX96 *big.Int,
 	hookData []byte,
+) (int24, error) {
+	return pm.InitializeWithTokenFlags(stateDB, key, sqrtPriceX96, 0, hookData)
+}
+
+// InitializeWithTokenFlags creates a pool whose currencies may be marked as
+// fee-on-transfer or rebasing. Marked currencies settle through the token
+// adapter (see tokens.go).
+func (pm *PoolManager) InitializeWithTokenFlags(
+	stateDB StateDB,
+	key PoolKey,
+	sqrtPriceX96 *big.Int,
+	flags TokenFlags,
+	hookData []byte,
 ) (int24, error) {
 	// Validate currencies are sorted
 	if !pm.areCurrenciesSorted(key.Currency0, key.Currency1) {
@@ -116,6 +200,9 @@ func (pm *PoolManager) Initialize(
 	if key.Fee > FeeMax {
 		return 0, ErrInvalidFee
 	}
+	if err := pm.feeTiers.checkFeeTier(stateDB, key); err != nil {
+		return 0, err
+	}
 
 	// Validate sqrt price
 	if sqrtPriceX96.Cmp(MinSqrtRatio) < 0 || sqrtPriceX96.Cmp(MaxSqrtRatio) > 0 {
@@ -131,7 +218,7 @@ func (pm *PoolManager) Initialize(
 	}
 
 	// Calculate initial tick from sqrt price
-	tick := pm.sqrtPriceX96ToTick(sqrtPriceX96)
+	tick := getTickAtSqrtRatio(sqrtPriceX96)
 
 	// Call beforeInitialize hook if present
 	if key.Hooks != (common.Address{}) {
@@ -140,12 +227,19 @@ func (pm *PoolManager) Initialize(
 		}
 	}
 
+	// Record non-standard currencies before the pool becomes usable
+	if err := pm.registerTokenFlags(stateDB, key, flags); err != nil {
+		return 0, err
+	}
+
 	// Initialize pool state
 	pool.SqrtPriceX96 = new(big.Int).Set(sqrtPriceX96)
 	pool.Tick = tick
 	pool.Liquidity = big.NewInt(0)
 	pool.FeeGrowth0X128 = big.NewInt(0)
 	pool.FeeGrowth1X128 = big.NewInt(0)
+	pool.TokenFlags = flags
+	pm.initializeObservations(stateDB, poolId, pool)
 
 	// Save pool state
 	pm.setPool(stateDB, poolId, pool)
@@ -193,8 +287,7 @@ func (pm *PoolManager) Lock(
 	// Initialize delta tracking for this caller
 	pm.currentDeltas[caller] = make(map[Currency]*big.Int)
 
-	// Execute callback (would be EVM call in real implementation)
-	// The callback can call swap, modifyLiquidity, etc.
+	// Execute the caller's callback, which can call swap, modifyLiquidity, etc.
 	result, err := pm.executeCallback(stateDB, caller, data)
 	if err != nil {
 		pm.cleanupLocker(caller)
@@ -249,33 +342,80 @@ func (pm *PoolManager) Settle(
 		return ErrUnauthorized
 	}
 
+	// Non-standard currencies are credited with what actually arrived
+	if pm.isNonStandard(stateDB, currency) {
+		credited, err := pm.tokens.TransferIn(stateDB, currency, locker, amount)
+		if err != nil {
+			return err
+		}
+		pm.updateDelta(locker, currency, new(big.Int).Neg(credited))
+		return nil
+	}
+
+	// Synced currencies are credited with what arrived since the sync
+	if pm.syncedCurrency != nil && *pm.syncedCurrency == currency {
+		return pm.settleSynced(stateDB, locker, currency, amount)
+	}
+
+	// ERC20s are paid by transfer to the pool manager, so must be synced
+	if !currency.IsNative() {
+		return ErrCurrencyNotSynced
+	}
+
 	// Update delta (settlement reduces the owed amount)
 	pm.updateDelta(locker, currency, new(big.Int).Neg(amount))
 
-	// Handle actual token transfer
-	if currency.IsNative() {
-		// Native LUX transfer
-		if amount.Sign() > 0 {
-			// Locker is paying pool
-			amountU256, _ := uint256.FromBig(amount)
-			stateDB.SubBalance(locker, amountU256)
-			stateDB.AddBalance(poolManagerAddr, amountU256)
-		} else {
-			// Pool is paying locker
-			absAmount := new(big.Int).Abs(amount)
-			amountU256, _ := uint256.FromBig(absAmount)
-			stateDB.SubBalance(poolManagerAddr, amountU256)
-			stateDB.AddBalance(locker, amountU256)
-		}
+	// Native LUX transfer
+	if amount.Sign() > 0 {
+		// Locker is paying pool
+		amountU256, _ := uint256.FromBig(amount)
+		stateDB.SubBalance(locker, amountU256)
+		stateDB.AddBalance(poolManagerAddr, amountU256)
 	} else {
-		// ERC20 transfer (handled via callback in real implementation)
-		// For precompile, we track state directly
-		pm.transferERC20(stateDB, currency, locker, poolManagerAddr, amount)
+		// Pool is paying locker
+		absAmount := new(big.Int).Abs(amount)
+		amountU256, _ := uint256.FromBig(absAmount)
+		stateDB.SubBalance(poolManagerAddr, amountU256)
+		stateDB.AddBalance(locker, amountU256)
 	}
+	pm.addReserves(stateDB, currency, amount)
 
 	return nil
 }
 
+// SettleValue settles native LUX sent with the call as value, which the EVM
+// has already moved to the pool manager. It is credited against what the
+// locker owes in LUX, and any excess is refunded to refundTo. Returns the
+// amount credited.
+func (pm *PoolManager) SettleValue(
+	stateDB StateDB,
+	value *big.Int,
+	refundTo common.Address,
+) (*big.Int, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, ErrUnauthorized
+	}
+
+	credited := new(big.Int).Set(value)
+	owed := pm.GetDelta(locker, NativeCurrency)
+	if owed.Sign() < 0 {
+		owed.SetInt64(0)
+	}
+	if credited.Cmp(owed) > 0 {
+		credited.Set(owed)
+	}
+	pm.updateDelta(locker, NativeCurrency, new(big.Int).Neg(credited))
+	pm.addReserves(stateDB, NativeCurrency, credited)
+
+	if refund := new(big.Int).Sub(value, credited); refund.Sign() > 0 {
+		refundU256, _ := uint256.FromBig(refund)
+		stateDB.SubBalance(poolManagerAddr, refundU256)
+		stateDB.AddBalance(refundTo, refundU256)
+	}
+	return credited, nil
+}
+
 // Take allows locker to take tokens owed to them
 func (pm *PoolManager) Take(
 	stateDB StateDB,
@@ -288,29 +428,14 @@ func (pm *PoolManager) Take(
 		return ErrUnauthorized
 	}
 
-	// Update delta (taking increases what locker owes)
-	pm.updateDelta(locker, currency, amount)
-
-	// Transfer tokens to recipient
-	if currency.IsNative() {
-		amountU256, _ := uint256.FromBig(amount)
-		stateDB.SubBalance(poolManagerAddr, amountU256)
-		stateDB.AddBalance(to, amountU256)
-	} else {
-		pm.transferERC20(stateDB, currency, poolManagerAddr, to, amount)
+	// Transfer tokens to recipient; for rebasing currencies amount is in
+	// shares
+	if err := pm.transferOut(stateDB, currency, to, amount); err != nil {
+		return err
 	}
 
-	return nil
-}
-
-// Sync syncs the reserves for a currency
-// Used after external token transfer to pool manager
-func (pm *PoolManager) Sync(
-	stateDB StateDB,
-	currency Currency,
-) error {
-	// For native currency, sync balance with tracked reserves
-	// For ERC20, sync with actual balance
+	// Update delta (taking increases what locker owes)
+	pm.updateDelta(locker, currency, amount)
 	return nil
 }
 
@@ -348,6 +473,18 @@ func (pm *PoolManager) Swap(
 	key PoolKey,
 	params SwapParams,
 	hookData []byte,
+) (BalanceDelta, error) {
+	return pm.SwapWithReferral(stateDB, key, params, common.Address{}, hookData)
+}
+
+// SwapWithReferral executes a swap and credits referrer (if non-zero) with
+// its share of the swap fee
+func (pm *PoolManager) SwapWithReferral(
+	stateDB StateDB,
+	key PoolKey,
+	params SwapParams,
+	referrer common.Address,
+	hookData []byte,
 ) (BalanceDelta, error) {
 	locker := pm.getCurrentLocker()
 	if locker == (common.Address{}) {
@@ -368,20 +505,28 @@ func (pm *PoolManager) Swap(
 		}
 	}
 
-	// Execute swap math
-	delta, newTick, err := pm.executeSwap(pool, key, params)
+	// Execute swap math on a copy of the pool, moving its price, tick and
+	// liquidity
+	swapped, delta, err := pm.executeSwap(stateDB, pool, key, params)
 	if err != nil {
 		return ZeroBalanceDelta(), err
 	}
 
+	// Record the tick and liquidity the swap moved away from
+	if swapped.Tick != pool.Tick {
+		pm.writeObservation(stateDB, poolId, swapped, pool.Tick, pool.Liquidity)
+	}
+
 	// Update pool state
-	pool.Tick = newTick
-	pm.setPool(stateDB, poolId, pool)
+	pm.setPool(stateDB, poolId, swapped)
 
 	// Update caller's deltas
 	pm.updateDelta(locker, key.Currency0, delta.Amount0)
 	pm.updateDelta(locker, key.Currency1, delta.Amount1)
 
+	// Credit the referrer's share of the fee
+	pm.accrueReferral(stateDB, locker, referrer, key, params, delta)
+
 	// Call afterSwap hook if present
 	if key.Hooks != (common.Address{}) {
 		if err := pm.callHook(stateDB, key.Hooks, HookAfterSwap, key, params, delta, hookData); err != nil {
@@ -389,6 +534,9 @@ func (pm *PoolManager) Swap(
 		}
 	}
 
+	// Enter the swap into the pool's incentive lottery
+	pm.enterLottery(stateDB, locker, key, params, delta)
+
 	return delta, nil
 }
 
@@ -433,23 +581,47 @@ func (pm *PoolManager) ModifyLiquidity(
 		}
 	}
 
+	// A position cannot remove more liquidity than it holds
+	positionKey := PositionKey(locker, params.TickLower, params.TickUpper, params.Salt)
+	position := pm.getPosition(stateDB, positionKey)
+	liquidityNext := new(big.Int).Add(position.Liquidity, params.LiquidityDelta)
+	if liquidityNext.Sign() < 0 {
+		return ZeroBalanceDelta(), ZeroBalanceDelta(), ErrInsufficientLiquidity
+	}
+
 	// Calculate token amounts for liquidity change
-	callerDelta, feesAccrued := pm.calculateLiquidityAmounts(pool, key, params, locker)
+	callerDelta := pm.calculateLiquidityAmounts(pool, key, params, locker)
+
+	// Record the liquidity at the range's ticks, for swaps to cross. Fees
+	// are accrued with both ticks initialized: after adding liquidity
+	// initializes them, before removing it may clear them.
+	var feesAccrued BalanceDelta
+	if !isAdd {
+		feesAccrued = pm.pokePosition(stateDB, poolId, pool, position, params.TickLower, params.TickUpper)
+	}
+	pm.updatePositionTicks(stateDB, poolId, pool, params.TickLower, params.TickUpper, params.LiquidityDelta)
+	if isAdd {
+		feesAccrued = pm.pokePosition(stateDB, poolId, pool, position, params.TickLower, params.TickUpper)
+	}
 
-	// Update pool liquidity
+	// Update pool liquidity, recording the liquidity in range until now
 	if params.TickLower <= pool.Tick && pool.Tick < params.TickUpper {
+		pm.writeObservation(stateDB, poolId, pool, pool.Tick, pool.Liquidity)
 		pool.Liquidity = new(big.Int).Add(pool.Liquidity, params.LiquidityDelta)
 	}
 
 	// Update position
-	positionKey := PositionKey(locker, params.TickLower, params.TickUpper, params.Salt)
-	position := pm.getPosition(stateDB, positionKey)
-	position.Liquidity = new(big.Int).Add(position.Liquidity, params.LiquidityDelta)
+	position.Liquidity = liquidityNext
 	position.Owner = locker
 	position.TickLower = params.TickLower
 	position.TickUpper = params.TickUpper
 	pm.setPosition(stateDB, positionKey, position)
 
+	// Checkpoint liquidity mining rewards at the new liquidity
+	if pm.gauges != nil {
+		pm.gauges.OnPositionModified(stateDB, poolId, positionKey, locker, position.Liquidity)
+	}
+
 	// Save pool state
 	pm.setPool(stateDB, poolId, pool)
 
@@ -630,6 +802,32 @@ func (pm *PoolManager) getPool(stateDB StateDB, poolId [32]byte) *Pool {
 		pool.Liquidity = new(big.Int).SetBytes(liqHash[:])
 	}
 
+	// Read fee growth
+	for i, growth := range []*big.Int{pool.FeeGrowth0X128, pool.FeeGrowth1X128} {
+		growthKey := makeStorageKey(poolStatePrefix, append(poolId[:], fmt.Sprintf("feeGrowth%d", i)...))
+		growthHash := stateDB.GetState(poolManagerAddr, growthKey)
+		growth.SetBytes(growthHash[:])
+	}
+
+	// Read token flags
+	flagsKey := makeStorageKey(poolStatePrefix, append(poolId[:], []byte("tokenFlags")...))
+	pool.TokenFlags = TokenFlags(stateDB.GetState(poolManagerAddr, flagsKey)[31])
+
+	// Read protocol fee share and accrued protocol fees
+	feeBpsHash := stateDB.GetState(poolManagerAddr, makeStorageKey(protocolFeePrefix, append(poolId[:], []byte("bps")...)))
+	pool.ProtocolFeeBps = binary.BigEndian.Uint32(feeBpsHash[28:32])
+	for i, fees := range []*big.Int{pool.ProtocolFees0, pool.ProtocolFees1} {
+		feesHash := stateDB.GetState(poolManagerAddr, makeStorageKey(protocolFeePrefix, append(poolId[:], fmt.Sprintf("fees%d", i)...)))
+		fees.SetBytes(feesHash[:])
+	}
+
+	// Read observation buffer state
+	obsKey := makeStorageKey(poolStatePrefix, append(poolId[:], []byte("observations")...))
+	obsHash := stateDB.GetState(poolManagerAddr, obsKey)
+	pool.ObservationIndex = binary.BigEndian.Uint16(obsHash[26:28])
+	pool.ObservationCardinality = binary.BigEndian.Uint16(obsHash[28:30])
+	pool.ObservationCardinalityNext = binary.BigEndian.Uint16(obsHash[30:32])
+
 	pm.pools[poolId] = pool
 	return pool
 }
@@ -655,6 +853,38 @@ func (pm *PoolManager) setPool(stateDB StateDB, poolId [32]byte, pool *Pool) {
 	var liqHash common.Hash
 	pool.Liquidity.FillBytes(liqHash[:])
 	stateDB.SetState(poolManagerAddr, liqKey, liqHash)
+
+	// Write fee growth
+	for i, growth := range []*big.Int{pool.FeeGrowth0X128, pool.FeeGrowth1X128} {
+		growthKey := makeStorageKey(poolStatePrefix, append(poolId[:], fmt.Sprintf("feeGrowth%d", i)...))
+		var growthHash common.Hash
+		growth.FillBytes(growthHash[:])
+		stateDB.SetState(poolManagerAddr, growthKey, growthHash)
+	}
+
+	// Write token flags
+	flagsKey := makeStorageKey(poolStatePrefix, append(poolId[:], []byte("tokenFlags")...))
+	var flagsHash common.Hash
+	flagsHash[31] = byte(pool.TokenFlags)
+	stateDB.SetState(poolManagerAddr, flagsKey, flagsHash)
+
+	// Write protocol fee share and accrued protocol fees
+	var feeBpsHash common.Hash
+	binary.BigEndian.PutUint32(feeBpsHash[28:32], pool.ProtocolFeeBps)
+	stateDB.SetState(poolManagerAddr, makeStorageKey(protocolFeePrefix, append(poolId[:], []byte("bps")...)), feeBpsHash)
+	for i, fees := range []*big.Int{pool.ProtocolFees0, pool.ProtocolFees1} {
+		var feesHash common.Hash
+		fees.FillBytes(feesHash[:])
+		stateDB.SetState(poolManagerAddr, makeStorageKey(protocolFeePrefix, append(poolId[:], fmt.Sprintf("fees%d", i)...)), feesHash)
+	}
+
+	// Write observation buffer state
+	obsKey := makeStorageKey(poolStatePrefix, append(poolId[:], []byte("observations")...))
+	var obsHash common.Hash
+	binary.BigEndian.PutUint16(obsHash[26:28], pool.ObservationIndex)
+	binary.BigEndian.PutUint16(obsHash[28:30], pool.ObservationCardinality)
+	binary.BigEndian.PutUint16(obsHash[30:32], pool.ObservationCardinalityNext)
+	stateDB.SetState(poolManagerAddr, obsKey, obsHash)
 }
 
 // getPosition retrieves position state from storage
@@ -678,6 +908,12 @@ func (pm *PoolManager) getPosition(stateDB StateDB, positionKey [32]byte) *Posit
 		pos.Liquidity = new(big.Int).SetBytes(liqHash[:])
 	}
 
+	// Load fee checkpoints and owed fees
+	for _, field := range pos.feeFields() {
+		hash := stateDB.GetState(poolManagerAddr, makeStorageKey(positionPrefix, append(positionKey[:], field.name...)))
+		field.value.SetBytes(hash[:])
+	}
+
 	pm.positions[positionKey] = pos
 	return pos
 }
@@ -691,6 +927,13 @@ func (pm *PoolManager) setPosition(stateDB StateDB, positionKey [32]byte, pos *P
 	var liqHash common.Hash
 	pos.Liquidity.FillBytes(liqHash[:])
 	stateDB.SetState(poolManagerAddr, liqKey, liqHash)
+
+	// Write fee checkpoints and owed fees
+	for _, field := range pos.feeFields() {
+		var hash common.Hash
+		field.value.FillBytes(hash[:])
+		stateDB.SetState(poolManagerAddr, makeStorageKey(positionPrefix, append(positionKey[:], field.name...)), hash)
+	}
 }
 
 // =========================================================================
@@ -703,182 +946,161 @@ func (pm *PoolManager) areCurrenciesSorted(c0, c1 Currency) bool {
 	return bytes.Compare(c0.Address.Bytes(), c1.Address.Bytes()) < 0
 }
 
-// sqrtPriceX96ToTick converts sqrt price to tick using binary search
-// tick = floor(log_1.0001(price))
-// price = sqrtPriceX96^2 / 2^192
-func (pm *PoolManager) sqrtPriceX96ToTick(sqrtPriceX96 *big.Int) int24 {
-	if sqrtPriceX96 == nil || sqrtPriceX96.Sign() <= 0 {
-		return 0
+// executeSwap steps the pool's price toward the swap's price limit until
+// the specified amount is swapped, as UniswapV3Pool.swap does. Each step
+// ends at the next initialized tick, the end of a bitmap word or the limit;
+// crossing an initialized tick applies its liquidityNet. Fees go to the
+// liquidity in range at each step. The pool is left untouched; on success
+// the crossed ticks are saved and a copy with the new price, tick,
+// liquidity and fee growth is returned.
+func (pm *PoolManager) executeSwap(stateDB StateDB, pool *Pool, key PoolKey, params SwapParams) (*Pool, BalanceDelta, error) {
+	if params.AmountSpecified == nil || params.AmountSpecified.Sign() == 0 {
+		return nil, ZeroBalanceDelta(), ErrInvalidAmount
+	}
+	limit, err := swapPriceLimit(pool.SqrtPriceX96, params)
+	if err != nil {
+		return nil, ZeroBalanceDelta(), err
 	}
 
-	// Clamp to valid range
-	if sqrtPriceX96.Cmp(MinSqrtRatio) <= 0 {
-		return MinTick
-	}
-	if sqrtPriceX96.Cmp(MaxSqrtRatio) >= 0 {
-		return MaxTick
+	poolId := key.ID()
+	exactInput := params.AmountSpecified.Sign() > 0
+	remaining := new(big.Int).Set(params.AmountSpecified)
+	calculated := big.NewInt(0)
+	sqrtPrice := new(big.Int).Set(pool.SqrtPriceX96)
+	tick := pool.Tick
+	liquidity := new(big.Int).Set(pool.Liquidity)
+	feeGrowth := new(big.Int).Set(pool.FeeGrowth1X128)
+	if params.ZeroForOne {
+		feeGrowth.Set(pool.FeeGrowth0X128)
 	}
+	protocolFee := big.NewInt(0)
+	// Crossed ticks are saved once the swap succeeds
+	var crossings []tickCrossing
 
-	// Binary search for tick
-	// tickToSqrtPrice(tick) <= sqrtPriceX96 < tickToSqrtPrice(tick+1)
-	low := int24(MinTick)
-	high := int24(MaxTick)
+	for remaining.Sign() != 0 && sqrtPrice.Cmp(limit) != 0 {
+		start := sqrtPrice
 
-	for low < high {
-		mid := low + (high-low+1)/2
-		sqrtPriceMid := pm.tickToSqrtPriceX96(mid)
-
-		if sqrtPriceMid.Cmp(sqrtPriceX96) <= 0 {
-			low = mid
-		} else {
-			high = mid - 1
+		tickNext, initialized := pm.nextInitializedTickWithinOneWord(stateDB, poolId, tick, params.ZeroForOne)
+		if tickNext < MinTick {
+			tickNext = MinTick
+		} else if tickNext > MaxTick {
+			tickNext = MaxTick
 		}
-	}
-
-	return low
-}
+		sqrtPriceNext, _ := getSqrtRatioAtTick(tickNext)
 
-// tickToSqrtPriceX96 converts tick to sqrt price (Q64.96 format)
-// sqrtPrice = sqrt(1.0001^tick) * 2^96
-func (pm *PoolManager) tickToSqrtPriceX96(tick int24) *big.Int {
-	// For tick 0: sqrtPrice = 2^96
-	if tick == 0 {
-		return new(big.Int).Set(Q96)
-	}
+		// Stop at the limit if it comes before the next tick
+		target := sqrtPriceNext
+		if (params.ZeroForOne && sqrtPriceNext.Cmp(limit) < 0) || (!params.ZeroForOne && sqrtPriceNext.Cmp(limit) > 0) {
+			target = limit
+		}
 
-	// Use lookup table approach for efficiency
-	// sqrt(1.0001) = 1.00004999875 (approximately)
-	// We compute sqrt(1.0001^|tick|) and adjust if negative
+		var amountIn, amountOut, feeAmount *big.Int
+		sqrtPrice, amountIn, amountOut, feeAmount, err = computeSwapStep(start, target, liquidity, remaining, key.Fee)
+		if err != nil {
+			return nil, ZeroBalanceDelta(), err
+		}
 
-	absTick := tick
-	if tick < 0 {
-		absTick = -tick
-	}
+		if exactInput {
+			remaining.Sub(remaining, amountIn)
+			remaining.Sub(remaining, feeAmount)
+			calculated.Sub(calculated, amountOut)
+		} else {
+			remaining.Add(remaining, amountOut)
+			calculated.Add(calculated, amountIn)
+			calculated.Add(calculated, feeAmount)
+		}
 
-	// Start with 1.0 in Q128 format for precision
-	ratio := new(big.Int).Lsh(big.NewInt(1), 128)
+		// The protocol takes its share before liquidity providers
+		if pool.ProtocolFeeBps > 0 {
+			share := protocolFeeShare(feeAmount, pool.ProtocolFeeBps)
+			feeAmount = new(big.Int).Sub(feeAmount, share)
+			protocolFee.Add(protocolFee, share)
+		}
 
-	// Magic numbers from Uniswap v3 TickMath
-	// These are sqrt(1.0001^(2^i)) in Q128 format
-	sqrtMagics := []struct {
-		bit   int
-		magic *big.Int
-	}{
-		{0, new(big.Int).SetBytes([]byte{0xff, 0xf9, 0x71, 0x63, 0xe1, 0x37, 0x66, 0x35})}, // 2^0
-		{1, new(big.Int).SetBytes([]byte{0xff, 0xf2, 0xe5, 0x0f, 0x62, 0x6c, 0x4c, 0x95})}, // 2^1
-		{2, new(big.Int).SetBytes([]byte{0xff, 0xe5, 0xca, 0xca, 0x7e, 0x10, 0xe4, 0x46})}, // 2^2
-		{3, new(big.Int).SetBytes([]byte{0xff, 0xcb, 0x9a, 0x97, 0x93, 0x42, 0xa9, 0x50})}, // 2^3
-		{4, new(big.Int).SetBytes([]byte{0xff, 0x97, 0x38, 0x3c, 0x7e, 0x70, 0x01, 0x2a})}, // 2^4
-		{5, new(big.Int).SetBytes([]byte{0xff, 0x2e, 0xa1, 0x34, 0x34, 0xc3, 0x39, 0x69})}, // 2^5
-		{6, new(big.Int).SetBytes([]byte{0xfe, 0x5d, 0xee, 0x04, 0x6a, 0x99, 0xa1, 0x2d})}, // 2^6
-		{7, new(big.Int).SetBytes([]byte{0xfc, 0xbe, 0x86, 0xc7, 0x90, 0x67, 0x90, 0x01})}, // 2^7
-		{8, new(big.Int).SetBytes([]byte{0xf9, 0x87, 0xa7, 0x25, 0x30, 0x42, 0x46, 0x85})}, // 2^8
-	}
+		// Fee growth wraps at 2^256 as in v3; positions take differences
+		if liquidity.Sign() > 0 {
+			feeGrowth.Add(feeGrowth, mulDiv(feeAmount, Q128, liquidity))
+			feeGrowth.And(feeGrowth, maxUint256)
+		}
 
-	// Multiply by relevant factors
-	for _, sm := range sqrtMagics {
-		if int(absTick)&(1<<sm.bit) != 0 {
-			ratio.Mul(ratio, sm.magic)
-			ratio.Rsh(ratio, 64)
+		if sqrtPrice.Cmp(sqrtPriceNext) == 0 {
+			if initialized {
+				crossing := tickCrossing{
+					tick:           tickNext,
+					feeGrowth0X128: pool.FeeGrowth0X128,
+					feeGrowth1X128: new(big.Int).Set(feeGrowth),
+				}
+				if params.ZeroForOne {
+					crossing.feeGrowth0X128 = new(big.Int).Set(feeGrowth)
+					crossing.feeGrowth1X128 = pool.FeeGrowth1X128
+				}
+				crossings = append(crossings, crossing)
+
+				liquidityNet := new(big.Int).Set(pm.getTick(stateDB, poolId, tickNext).LiquidityNet)
+				if params.ZeroForOne {
+					liquidityNet.Neg(liquidityNet)
+				}
+				liquidity.Add(liquidity, liquidityNet)
+				if liquidity.Sign() < 0 {
+					return nil, ZeroBalanceDelta(), ErrInsufficientLiquidity
+				}
+			}
+			tick = tickNext
+			if params.ZeroForOne {
+				tick = tickNext - 1
+			}
+		} else if sqrtPrice.Cmp(start) != 0 {
+			tick = getTickAtSqrtRatio(sqrtPrice)
 		}
 	}
 
-	// Handle remaining bits for larger ticks (simplified)
-	remaining := int(absTick) >> 9
-	for i := 0; i < remaining; i++ {
-		// Approximate multiplication by sqrt(1.0001^512)
-		ratio.Mul(ratio, big.NewInt(10001))
-		ratio.Div(ratio, big.NewInt(10000))
+	// The swap succeeded; commit the crossed ticks and build the new pool
+	for _, crossing := range crossings {
+		pm.crossTick(stateDB, poolId, crossing)
 	}
-
-	// If negative tick, invert the ratio
-	if tick < 0 {
-		// ratio = 2^256 / ratio (approximately)
-		maxU256 := new(big.Int).Lsh(big.NewInt(1), 256)
-		ratio = new(big.Int).Div(maxU256, ratio)
+	next := *pool
+	next.SqrtPriceX96 = sqrtPrice
+	next.Tick = tick
+	next.Liquidity = liquidity
+	if params.ZeroForOne {
+		next.FeeGrowth0X128 = feeGrowth
+		next.ProtocolFees0 = new(big.Int).Add(pool.ProtocolFees0, protocolFee)
+	} else {
+		next.FeeGrowth1X128 = feeGrowth
+		next.ProtocolFees1 = new(big.Int).Add(pool.ProtocolFees1, protocolFee)
 	}
 
-	// Convert from Q128 to Q96
-	result := new(big.Int).Rsh(ratio, 32)
-
-	// Ensure within bounds
-	if result.Cmp(MinSqrtRatio) < 0 {
-		return new(big.Int).Set(MinSqrtRatio)
-	}
-	if result.Cmp(MaxSqrtRatio) > 0 {
-		return new(big.Int).Set(MaxSqrtRatio)
+	swapped := new(big.Int).Sub(params.AmountSpecified, remaining)
+	if params.ZeroForOne == exactInput {
+		return &next, NewBalanceDelta(swapped, calculated), nil
 	}
-
-	return result
+	return &next, NewBalanceDelta(calculated, swapped), nil
 }
 
-// executeSwap performs the swap math
-func (pm *PoolManager) executeSwap(pool *Pool, key PoolKey, params SwapParams) (BalanceDelta, int24, error) {
-	// Simplified swap implementation
-	// Real implementation would:
-	// 1. Iterate through ticks
-	// 2. Calculate amounts at each tick
-	// 3. Update fee growth
-	// 4. Handle price limit
-
-	exactInput := params.AmountSpecified.Sign() > 0
-
-	var amount0, amount1 *big.Int
-
+// swapPriceLimit returns the price a swap stops at. A nil limit, or one at
+// or past the bounds of the price range, swaps as far as the amount goes. A
+// limit on the wrong side of the current price fails, as in v3.
+func swapPriceLimit(sqrtPriceX96 *big.Int, params SwapParams) (*big.Int, error) {
+	limit := params.SqrtPriceLimitX96
 	if params.ZeroForOne {
-		// Swapping token0 for token1
-		if exactInput {
-			amount0 = params.AmountSpecified
-			// Calculate output based on price and liquidity
-			amount1 = pm.calculateSwapOutput(pool, amount0, true)
-		} else {
-			amount1 = new(big.Int).Neg(params.AmountSpecified)
-			amount0 = pm.calculateSwapInput(pool, amount1, true)
+		lowest := new(big.Int).Add(MinSqrtRatio, big.NewInt(1))
+		if limit == nil || limit.Cmp(lowest) < 0 {
+			limit = lowest
 		}
-	} else {
-		// Swapping token1 for token0
-		if exactInput {
-			amount1 = params.AmountSpecified
-			amount0 = pm.calculateSwapOutput(pool, amount1, false)
-		} else {
-			amount0 = new(big.Int).Neg(params.AmountSpecified)
-			amount1 = pm.calculateSwapInput(pool, amount0, false)
+		if limit.Cmp(sqrtPriceX96) >= 0 {
+			return nil, ErrPriceLimitReached
 		}
+		return limit, nil
 	}
 
-	// Apply fee
-	fee := pm.calculateSwapFee(amount0, amount1, key.Fee)
-	_ = fee // Fee would be distributed to LPs
-
-	return NewBalanceDelta(amount0, new(big.Int).Neg(amount1)), pool.Tick, nil
-}
-
-// calculateSwapOutput calculates output for given input
-func (pm *PoolManager) calculateSwapOutput(pool *Pool, amountIn *big.Int, zeroForOne bool) *big.Int {
-	// Simplified: output = input * liquidity / (liquidity + input)
-	// Real implementation uses exact tick math
-	if pool.Liquidity.Sign() == 0 {
-		return big.NewInt(0)
-	}
-
-	numerator := new(big.Int).Mul(amountIn, pool.Liquidity)
-	denominator := new(big.Int).Add(pool.Liquidity, amountIn)
-	return new(big.Int).Div(numerator, denominator)
-}
-
-// calculateSwapInput calculates input required for given output
-func (pm *PoolManager) calculateSwapInput(pool *Pool, amountOut *big.Int, zeroForOne bool) *big.Int {
-	// Simplified calculation
-	if pool.Liquidity.Sign() == 0 {
-		return big.NewInt(0)
+	highest := new(big.Int).Sub(MaxSqrtRatio, big.NewInt(1))
+	if limit == nil || limit.Sign() == 0 || limit.Cmp(highest) > 0 {
+		limit = highest
 	}
-
-	numerator := new(big.Int).Mul(amountOut, pool.Liquidity)
-	denominator := new(big.Int).Sub(pool.Liquidity, amountOut)
-	if denominator.Sign() <= 0 {
-		return new(big.Int).Set(pool.Liquidity)
+	if limit.Cmp(sqrtPriceX96) <= 0 {
+		return nil, ErrPriceLimitReached
 	}
-	return new(big.Int).Div(numerator, denominator)
+	return limit, nil
 }
 
 // calculateSwapFee calculates the fee for a swap
@@ -899,7 +1121,7 @@ func (pm *PoolManager) calculateLiquidityAmounts(
 	key PoolKey,
 	params ModifyLiquidityParams,
 	owner common.Address,
-) (BalanceDelta, BalanceDelta) {
+) BalanceDelta {
 	// Simplified liquidity calculation
 	// Real implementation uses sqrtPrice and tick math
 
@@ -937,10 +1159,7 @@ func (pm *PoolManager) calculateLiquidityAmounts(
 		}
 	}
 
-	callerDelta := NewBalanceDelta(amount0, amount1)
-	feesAccrued := ZeroBalanceDelta() // Simplified - no fee calculation
-
-	return callerDelta, feesAccrued
+	return NewBalanceDelta(amount0, amount1)
 }
 
 // calculateFlashFee calculates flash loan fee
@@ -959,17 +1178,54 @@ func (pm *PoolManager) transferERC20(stateDB StateDB, currency Currency, from, t
 	// For precompile, we track balances in state
 }
 
-// executeCallback executes the locker's callback (simplified)
+// SigLockAcquired is the selector of the locker's
+// lockAcquired(address,bytes) callback
+var SigLockAcquired = abiSelector("lockAcquired(address,bytes)")
+
+// executeCallback calls the locker's lockAcquired callback through the EVM
+// with the lock data, passing on all remaining gas. Without an EVM to call
+// into there is no callback to run, and the lock only checks settlement.
 func (pm *PoolManager) executeCallback(stateDB StateDB, caller common.Address, data []byte) ([]byte, error) {
-	// In real implementation, this would be an EVM call
-	// For testing, we return success
-	return nil, nil
+	hookCaller, ok := stateDB.(HookCaller)
+	if !ok {
+		return nil, nil
+	}
+	return hookCaller.CallHook(caller, packHookCall(SigLockAcquired, caller, data), math.MaxUint64)
 }
 
-// callHook calls a hook function (simplified)
+// callHook calls a pool's hook if its address has the flag's permission.
+// Native hooks run in the precompile; hook contracts are called through the
+// EVM with the callback's ABI-encoded arguments and must return its
+// selector, and a revert fails the operation. Without an EVM to call into,
+// as for the lock callback, hook contracts are not called.
 func (pm *PoolManager) callHook(stateDB StateDB, hookAddr common.Address, flag HookFlags, args ...interface{}) error {
-	// In real implementation, this would be an EVM call to hook contract
-	// For now, just return success
+	if !HasPermission(hookAddr, flag) {
+		return nil
+	}
+
+	locker := pm.getCurrentLocker()
+	if hook, ok := pm.nativeHooks[hookAddr]; ok {
+		key, _ := args[0].(PoolKey)
+		return hook.Call(stateDB, flag, locker, key)
+	}
+
+	// A hook acting on its own pool is not called back, as in v4
+	if locker == hookAddr {
+		return nil
+	}
+
+	caller, ok := stateDB.(HookCaller)
+	if !ok {
+		return nil
+	}
+	sig := hookSelector(flag)
+	ret, err := caller.CallHook(hookAddr, packHookCall(sig, locker, args...), GasHookCallLimit)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrHookCallFailed, err)
+	}
+	if len(ret) < len(sig) || !bytes.Equal(ret[:len(sig)], sig) {
+		return ErrInvalidHookResponse
+	}
 	return nil
 }
 
@@ -4,6 +4,8 @@
 package dex
 
 import (
+	"bytes"
+	"errors"
 	"math/big"
 	"testing"
 
@@ -17,6 +19,7 @@ type MockStateDB struct {
 	balances    map[common.Address]*uint256.Int
 	exists      map[common.Address]bool
 	blockNumber uint64
+	timestamp   uint64
 }
 
 func NewMockStateDB() *MockStateDB {
@@ -81,6 +84,14 @@ func (m *MockStateDB) SetBlockNumber(block uint64) {
 	m.blockNumber = block
 }
 
+func (m *MockStateDB) GetBlockTimestamp() uint64 {
+	return m.timestamp
+}
+
+func (m *MockStateDB) SetBlockTimestamp(timestamp uint64) {
+	m.timestamp = timestamp
+}
+
 // Test helper functions
 func newTestPoolKey() PoolKey {
 	return PoolKey{
@@ -201,6 +212,67 @@ func TestPoolManagerLock(t *testing.T) {
 	}
 }
 
+// lockCallbackStateDB calls the locker's lockAcquired callback by running
+// callback with its calldata
+type lockCallbackStateDB struct {
+	*MockStateDB
+	callback func(input []byte) ([]byte, error)
+}
+
+func (s *lockCallbackStateDB) CallHook(hook common.Address, input []byte, gas uint64) ([]byte, error) {
+	return s.callback(input)
+}
+
+// TestPoolManagerLockCallback tests that lock calls back the locker, whose
+// nested take and settle must net to zero
+func TestPoolManagerLockCallback(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := &lockCallbackStateDB{MockStateDB: NewMockStateDB()}
+	caller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	stateDB.AddBalance(poolManagerAddr, uint256.NewInt(2000))
+	amount := big.NewInt(1000)
+
+	// Taking without paying back leaves the lock unsettled
+	stateDB.callback = func(input []byte) ([]byte, error) {
+		return nil, pm.Take(stateDB, NativeCurrency, caller, amount)
+	}
+	if _, err := pm.Lock(stateDB, caller, nil); !errors.Is(err, ErrNonZeroDelta) {
+		t.Errorf("Expected ErrNonZeroDelta, got: %v", err)
+	}
+	if len(pm.lockers) != 0 {
+		t.Errorf("Expected the lock to be released, got %d lockers", len(pm.lockers))
+	}
+
+	// A flash loan repaid within the callback settles
+	data := []byte("flash")
+	var calldata []byte
+	stateDB.callback = func(input []byte) ([]byte, error) {
+		calldata = input
+		if err := pm.Take(stateDB, NativeCurrency, caller, amount); err != nil {
+			return nil, err
+		}
+		return []byte("done"), pm.Settle(stateDB, NativeCurrency, amount)
+	}
+	result, err := pm.Lock(stateDB, caller, data)
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if string(result) != "done" {
+		t.Errorf("Expected the callback's return data, got %q", result)
+	}
+	if !bytes.Equal(calldata, packHookCall(SigLockAcquired, caller, data)) {
+		t.Errorf("Expected lockAcquired(caller, data) calldata, got %x", calldata)
+	}
+
+	// Callback reverts fail the lock
+	stateDB.callback = func(input []byte) ([]byte, error) {
+		return nil, errors.New("reverted")
+	}
+	if _, err := pm.Lock(stateDB, caller, nil); err == nil {
+		t.Error("Expected a reverted callback to fail the lock")
+	}
+}
+
 func TestPoolManagerSettlement(t *testing.T) {
 	pm := newTestPoolManager()
 	stateDB := NewMockStateDB()
@@ -230,6 +302,51 @@ func TestPoolManagerSettlement(t *testing.T) {
 	}
 }
 
+// TestPoolManagerSettleValue tests that value sent with settle is credited
+// against what the locker owes in LUX and the excess refunded
+func TestPoolManagerSettleValue(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	caller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	if _, err := pm.SettleValue(stateDB, big.NewInt(1), caller); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized outside a lock, got: %v", err)
+	}
+
+	// The EVM moves the value to the pool manager before settle runs
+	openLock(pm, caller)
+	pm.updateDelta(caller, NativeCurrency, big.NewInt(1000))
+	stateDB.AddBalance(poolManagerAddr, uint256.NewInt(1500))
+	credited, err := pm.SettleValue(stateDB, big.NewInt(1500), caller)
+	if err != nil {
+		t.Fatalf("SettleValue failed: %v", err)
+	}
+	if credited.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected 1000 credited, got %s", credited)
+	}
+	if delta := pm.GetDelta(caller, NativeCurrency); delta.Sign() != 0 {
+		t.Errorf("Expected zero delta after settlement, got: %s", delta)
+	}
+	if balance := stateDB.GetBalance(caller).Uint64(); balance != 500 {
+		t.Errorf("Expected 500 refunded, got %d", balance)
+	}
+	if balance := stateDB.GetBalance(poolManagerAddr).Uint64(); balance != 1000 {
+		t.Errorf("Expected pool manager balance 1000, got %d", balance)
+	}
+	if reserves := pm.ReservesOf(stateDB, NativeCurrency); reserves.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected native reserves 1000, got %s", reserves)
+	}
+
+	// With nothing owed, all value is refunded
+	stateDB.AddBalance(poolManagerAddr, uint256.NewInt(200))
+	if credited, _ := pm.SettleValue(stateDB, big.NewInt(200), caller); credited.Sign() != 0 {
+		t.Errorf("Expected nothing credited, got %s", credited)
+	}
+	if balance := stateDB.GetBalance(caller).Uint64(); balance != 700 {
+		t.Errorf("Expected 700 refunded in total, got %d", balance)
+	}
+}
+
 // =========================================================================
 // Swap Tests
 // =========================================================================
@@ -323,6 +440,113 @@ func TestPoolManagerSwapUninitializedPool(t *testing.T) {
 	}
 }
 
+func TestPoolManagerSwapCrossesTicks(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+	caller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	poolId := key.ID()
+
+	if _, err := pm.Initialize(stateDB, key, encodePriceSqrt(1, 1), nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	pm.lockers = append(pm.lockers, caller)
+	pm.currentDeltas[caller] = make(map[Currency]*big.Int)
+
+	// Liquidity la in [-60, 60) and lb in [-600, -60)
+	la := expandTo18Decimals(1)
+	lb := new(big.Int).Div(la, big.NewInt(2))
+	for _, p := range []ModifyLiquidityParams{
+		{TickLower: -60, TickUpper: 60, LiquidityDelta: la},
+		{TickLower: -600, TickUpper: -60, LiquidityDelta: lb},
+	} {
+		if _, _, err := pm.ModifyLiquidity(stateDB, key, p, nil); err != nil {
+			t.Fatalf("ModifyLiquidity failed: %v", err)
+		}
+	}
+	if net := pm.getTick(stateDB, poolId, -60).LiquidityNet; net.Cmp(new(big.Int).Sub(la, lb)) != 0 {
+		t.Errorf("Expected liquidityNet %s at tick -60, got %s", new(big.Int).Sub(la, lb), net)
+	}
+
+	// A swap within one range matches a single v3 step toward tick -60
+	sqrtPrice0, _ := getSqrtRatioAtTick(0)
+	sqrtPriceLower, _ := getSqrtRatioAtTick(-60)
+	amountIn := big.NewInt(1_000_000_000_000_000)
+	_, _, wantOut, wantFee, _ := computeSwapStep(sqrtPrice0, sqrtPriceLower, la, amountIn, key.Fee)
+
+	delta, err := pm.Swap(stateDB, key, SwapParams{ZeroForOne: true, AmountSpecified: amountIn}, nil)
+	if err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	if delta.Amount0.Cmp(amountIn) != 0 || delta.Amount1.Cmp(new(big.Int).Neg(wantOut)) != 0 {
+		t.Errorf("Expected delta (%s, -%s), got (%s, %s)", amountIn, wantOut, delta.Amount0, delta.Amount1)
+	}
+	pool := pm.pools[poolId]
+	if growth := mulDiv(wantFee, Q128, la); pool.FeeGrowth0X128.Cmp(growth) != 0 {
+		t.Errorf("Expected FeeGrowth0X128 %s, got %s", growth, pool.FeeGrowth0X128)
+	}
+	if pool.Tick != getTickAtSqrtRatio(pool.SqrtPriceX96) {
+		t.Errorf("Tick %d does not match price %s", pool.Tick, pool.SqrtPriceX96)
+	}
+
+	// Crossing tick -60 downward leaves only lb in range
+	amountIn = big.NewInt(10_000_000_000_000_000)
+	delta, err = pm.Swap(stateDB, key, SwapParams{ZeroForOne: true, AmountSpecified: amountIn}, nil)
+	if err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	if delta.Amount0.Cmp(amountIn) != 0 || delta.Amount1.Sign() >= 0 {
+		t.Errorf("Unexpected delta (%s, %s)", delta.Amount0, delta.Amount1)
+	}
+	if pool = pm.pools[poolId]; pool.Tick >= -60 || pool.Liquidity.Cmp(lb) != 0 {
+		t.Errorf("Expected tick below -60 with liquidity %s, got tick %d liquidity %s", lb, pool.Tick, pool.Liquidity)
+	}
+
+	// Exact output of currency1
+	amountOut := big.NewInt(1_000_000_000_000_000)
+	delta, err = pm.Swap(stateDB, key, SwapParams{ZeroForOne: true, AmountSpecified: new(big.Int).Neg(amountOut)}, nil)
+	if err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	if delta.Amount1.Cmp(new(big.Int).Neg(amountOut)) != 0 || delta.Amount0.Sign() <= 0 {
+		t.Errorf("Expected exact output %s, got (%s, %s)", amountOut, delta.Amount0, delta.Amount1)
+	}
+
+	// Swapping back up stops at the price limit with the input part spent,
+	// crossing tick -60 upward to restore la
+	limit, _ := getSqrtRatioAtTick(30)
+	amountIn = expandTo18Decimals(100)
+	delta, err = pm.Swap(stateDB, key, SwapParams{ZeroForOne: false, AmountSpecified: amountIn, SqrtPriceLimitX96: limit}, nil)
+	if err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	if pool = pm.pools[poolId]; pool.SqrtPriceX96.Cmp(limit) != 0 || pool.Tick != 30 || pool.Liquidity.Cmp(la) != 0 {
+		t.Errorf("Expected price limit at tick 30 with liquidity %s, got tick %d liquidity %s", la, pool.Tick, pool.Liquidity)
+	}
+	if delta.Amount1.Sign() <= 0 || delta.Amount1.Cmp(amountIn) >= 0 || delta.Amount0.Sign() >= 0 {
+		t.Errorf("Expected partial fill, got (%s, %s)", delta.Amount0, delta.Amount1)
+	}
+
+	// A limit on the wrong side of the price fails
+	_, err = pm.Swap(stateDB, key, SwapParams{ZeroForOne: true, AmountSpecified: amountIn, SqrtPriceLimitX96: MaxSqrtRatio}, nil)
+	if err != ErrPriceLimitReached {
+		t.Errorf("Expected ErrPriceLimitReached, got: %v", err)
+	}
+
+	// Removing more liquidity than a position holds fails
+	remove := ModifyLiquidityParams{TickLower: -60, TickUpper: 60, LiquidityDelta: new(big.Int).Neg(new(big.Int).Add(la, big.NewInt(1)))}
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, remove, nil); err != ErrInsufficientLiquidity {
+		t.Errorf("Expected ErrInsufficientLiquidity, got: %v", err)
+	}
+	remove.LiquidityDelta = new(big.Int).Neg(la)
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, remove, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+	if _, initialized := pm.nextInitializedTickWithinOneWord(stateDB, poolId, 0, false); initialized {
+		t.Errorf("Expected tick 60 cleared after its only position was removed")
+	}
+}
+
 // =========================================================================
 // Liquidity Tests
 // =========================================================================
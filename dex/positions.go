@@ -0,0 +1,102 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+)
+
+// Position fees
+//
+// Fees accrue to positions as in UniswapV3Pool. Each initialized tick
+// records the fee growth on its far side from the current tick, so the
+// growth inside a position's range is the global growth less the growth
+// below its lower tick and above its upper tick. Every liquidity change
+// and collect credits TokensOwed0/1 with the position's liquidity times
+// the growth inside since its FeeGrowthInside0/1LastX128 checkpoint.
+// Collected fees are credited to the locker's delta, to be taken or
+// minted as claims.
+//
+// Fee state is stored under positionPrefix next to the liquidity:
+//
+//	positionKey || "fg0" / "fg1"   -> fee growth inside checkpoints
+//	positionKey || "own0" / "own1" -> tokens owed
+
+// positionField is a stored field of a position
+type positionField struct {
+	name  string
+	value *big.Int
+}
+
+// feeFields returns a position's fee checkpoints and owed fees with their
+// storage field names
+func (pos *Position) feeFields() []positionField {
+	return []positionField{
+		{"fg0", pos.FeeGrowthInside0LastX128},
+		{"fg1", pos.FeeGrowthInside1LastX128},
+		{"own0", pos.TokensOwed0},
+		{"own1", pos.TokensOwed1},
+	}
+}
+
+// positionFees returns the fees earned by liquidity over fee growth from
+// last to inside
+func positionFees(liquidity, inside, last *big.Int) *big.Int {
+	growth := new(big.Int).Sub(inside, last)
+	return mulDiv(growth.And(growth, maxUint256), liquidity, Q128)
+}
+
+// pokePosition credits a position with the fees its liquidity earned in
+// the pool since its last checkpoint, returning them
+func (pm *PoolManager) pokePosition(stateDB StateDB, poolId [32]byte, pool *Pool, position *Position, tickLower, tickUpper int24) BalanceDelta {
+	inside0, inside1 := pm.feeGrowthInside(stateDB, poolId, pool, tickLower, tickUpper)
+	fees0 := positionFees(position.Liquidity, inside0, position.FeeGrowthInside0LastX128)
+	fees1 := positionFees(position.Liquidity, inside1, position.FeeGrowthInside1LastX128)
+
+	position.FeeGrowthInside0LastX128 = inside0
+	position.FeeGrowthInside1LastX128 = inside1
+	position.TokensOwed0 = new(big.Int).Add(position.TokensOwed0, fees0)
+	position.TokensOwed1 = new(big.Int).Add(position.TokensOwed1, fees1)
+	return NewBalanceDelta(fees0, fees1)
+}
+
+// Collect credits up to amount0 and amount1 of the fees owed to the current
+// locker's position to its delta, after accruing fees up to now. A nil
+// amount, or one above what is owed, collects everything owed.
+func (pm *PoolManager) Collect(
+	stateDB StateDB,
+	key PoolKey,
+	tickLower, tickUpper int24,
+	salt [32]byte,
+	amount0, amount1 *big.Int,
+) (*big.Int, *big.Int, error) {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return nil, nil, ErrUnauthorized
+	}
+
+	poolId := key.ID()
+	pool := pm.getPool(stateDB, poolId)
+	if !pool.IsInitialized() {
+		return nil, nil, ErrPoolNotInitialized
+	}
+
+	positionKey := PositionKey(locker, tickLower, tickUpper, salt)
+	position := pm.getPosition(stateDB, positionKey)
+	if position.Liquidity.Sign() > 0 {
+		pm.pokePosition(stateDB, poolId, pool, position, tickLower, tickUpper)
+	}
+
+	collected0 := protocolFeeAmount(position.TokensOwed0, amount0)
+	collected1 := protocolFeeAmount(position.TokensOwed1, amount1)
+	position.TokensOwed0 = new(big.Int).Sub(position.TokensOwed0, collected0)
+	position.TokensOwed1 = new(big.Int).Sub(position.TokensOwed1, collected1)
+	pm.setPosition(stateDB, positionKey, position)
+
+	pm.updateDelta(locker, key.Currency0, new(big.Int).Neg(collected0))
+	pm.updateDelta(locker, key.Currency1, new(big.Int).Neg(collected1))
+	return collected0, collected1, nil
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// TestPositionFees tests that positions accrue swap fees earned in their
+// range on every liquidity change, and that owners collect them
+func TestPositionFees(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+	lp := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	if _, err := pm.Initialize(stateDB, key, encodePriceSqrt(1, 1), nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if _, _, err := pm.Collect(stateDB, key, -60, 60, [32]byte{}, nil, nil); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized outside a lock, got: %v", err)
+	}
+
+	openLock(pm, lp)
+	la := expandTo18Decimals(1)
+	inRange := ModifyLiquidityParams{TickLower: -60, TickUpper: 60, LiquidityDelta: la}
+	above := ModifyLiquidityParams{TickLower: 60, TickUpper: 120, LiquidityDelta: la}
+	for _, params := range []ModifyLiquidityParams{inRange, above} {
+		if _, fees, err := pm.ModifyLiquidity(stateDB, key, params, nil); err != nil || fees.Amount0.Sign() != 0 || fees.Amount1.Sign() != 0 {
+			t.Fatalf("Expected a new position with no fees, got %s and %s: %v", fees.Amount0, fees.Amount1, err)
+		}
+	}
+
+	if _, err := pm.Swap(stateDB, key, SwapParams{ZeroForOne: true, AmountSpecified: big.NewInt(1_000_000_000_000_000)}, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	pool := pm.pools[key.ID()]
+	want0 := mulDiv(pool.FeeGrowth0X128, la, Q128)
+
+	// A zero liquidity change accrues the fees earned in range
+	poke := ModifyLiquidityParams{TickLower: -60, TickUpper: 60, LiquidityDelta: big.NewInt(0)}
+	_, fees, err := pm.ModifyLiquidity(stateDB, key, poke, nil)
+	if err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+	if fees.Amount0.Sign() <= 0 || fees.Amount0.Cmp(want0) != 0 || fees.Amount1.Sign() != 0 {
+		t.Errorf("Expected fees (%s, 0), got (%s, %s)", want0, fees.Amount0, fees.Amount1)
+	}
+	positionKey := PositionKey(lp, -60, 60, [32]byte{})
+	position := pm.getPosition(stateDB, positionKey)
+	if position.FeeGrowthInside0LastX128.Cmp(pool.FeeGrowth0X128) != 0 {
+		t.Errorf("Expected checkpoint %s, got %s", pool.FeeGrowth0X128, position.FeeGrowthInside0LastX128)
+	}
+
+	// Fee state persists
+	reloaded := NewPoolManager().getPosition(stateDB, positionKey)
+	if reloaded.TokensOwed0.Cmp(want0) != 0 || reloaded.FeeGrowthInside0LastX128.Cmp(pool.FeeGrowth0X128) != 0 {
+		t.Errorf("Expected reloaded fees %s at checkpoint %s, got %s at %s",
+			want0, pool.FeeGrowth0X128, reloaded.TokensOwed0, reloaded.FeeGrowthInside0LastX128)
+	}
+
+	// Positions out of range earn nothing
+	if collected0, collected1, err := pm.Collect(stateDB, key, 60, 120, [32]byte{}, nil, nil); err != nil || collected0.Sign() != 0 || collected1.Sign() != 0 {
+		t.Errorf("Expected nothing to collect out of range, got (%s, %s): %v", collected0, collected1, err)
+	}
+
+	// Collect credits the owner's delta, capped at what is owed
+	deltaBefore := pm.GetDelta(lp, key.Currency0)
+	collected0, _, err := pm.Collect(stateDB, key, -60, 60, [32]byte{}, big.NewInt(1), nil)
+	if err != nil || collected0.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("Expected to collect 1, got %s: %v", collected0, err)
+	}
+	collected0, _, err = pm.Collect(stateDB, key, -60, 60, [32]byte{}, new(big.Int).Lsh(big.NewInt(1), 128), nil)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if remaining := new(big.Int).Sub(want0, big.NewInt(1)); collected0.Cmp(remaining) != 0 {
+		t.Errorf("Expected to collect the remaining %s, got %s", remaining, collected0)
+	}
+	if delta := new(big.Int).Sub(deltaBefore, pm.GetDelta(lp, key.Currency0)); delta.Cmp(want0) != 0 {
+		t.Errorf("Expected the delta to fall by %s, got %s", want0, delta)
+	}
+
+	// Removing liquidity accrues fees before its ticks are cleared
+	if _, err := pm.Swap(stateDB, key, SwapParams{ZeroForOne: false, AmountSpecified: big.NewInt(1_000_000_000_000_000)}, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	pool = pm.pools[key.ID()]
+	want1 := mulDiv(pool.FeeGrowth1X128, la, Q128)
+	remove := ModifyLiquidityParams{TickLower: -60, TickUpper: 60, LiquidityDelta: new(big.Int).Neg(la)}
+	if _, fees, err = pm.ModifyLiquidity(stateDB, key, remove, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+	if fees.Amount1.Sign() <= 0 || fees.Amount1.Cmp(want1) != 0 {
+		t.Errorf("Expected fees1 %s on removal, got %s", want1, fees.Amount1)
+	}
+	if owed := pm.getPosition(stateDB, positionKey).TokensOwed1; owed.Cmp(want1) != 0 {
+		t.Errorf("Expected %s owed after removal, got %s", want1, owed)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+
+	"github.com/holiman/uint256"
+	"github.com/luxfi/geth/common"
+)
+
+// Protocol fees
+//
+// The protocol fee controller may take a share of each pool's swap fees.
+// The share is set per pool in basis points of the swap fee and taken at
+// every swap step before the rest is credited to liquidity providers, as
+// in v3. Accrued fees are held in the pool's ProtocolFees0/1 until the
+// controller collects them.
+
+// MaxProtocolFeeBps caps the protocol's share of swap fees (25%)
+const MaxProtocolFeeBps uint32 = 2_500
+
+// protocolFeeControllerKey is the storage key of the protocol fee controller
+var protocolFeeControllerKey = makeStorageKey(protocolFeePrefix, []byte("controller"))
+
+// ProtocolFeeController returns the address allowed to set and collect
+// protocol fees
+func (pm *PoolManager) ProtocolFeeController(stateDB StateDB) common.Address {
+	return common.BytesToAddress(stateDB.GetState(poolManagerAddr, protocolFeeControllerKey).Bytes())
+}
+
+// setProtocolFeeController stores the protocol fee controller
+func (pm *PoolManager) setProtocolFeeController(stateDB StateDB, controller common.Address) {
+	stateDB.SetState(poolManagerAddr, protocolFeeControllerKey, common.BytesToHash(controller.Bytes()))
+}
+
+// SetProtocolFeeController hands protocol fee control to a new address
+// (protocol fee controller only)
+func (pm *PoolManager) SetProtocolFeeController(stateDB StateDB, caller, controller common.Address) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	pm.setProtocolFeeController(stateDB, controller)
+	return nil
+}
+
+// SetProtocolFee sets the protocol's share of a pool's swap fees, in basis
+// points (protocol fee controller only)
+func (pm *PoolManager) SetProtocolFee(stateDB StateDB, caller common.Address, key PoolKey, feeBps uint32) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	if feeBps > MaxProtocolFeeBps {
+		return ErrInvalidProtocolFee
+	}
+
+	poolId := key.ID()
+	pool := pm.getPool(stateDB, poolId)
+	if !pool.IsInitialized() {
+		return ErrPoolNotInitialized
+	}
+
+	pool.ProtocolFeeBps = feeBps
+	pm.setPool(stateDB, poolId, pool)
+	return nil
+}
+
+// CollectProtocolFees transfers up to amount0 and amount1 of a pool's
+// accrued protocol fees to recipient (protocol fee controller only). A nil
+// amount, or one above what has accrued, collects everything accrued.
+func (pm *PoolManager) CollectProtocolFees(
+	stateDB StateDB,
+	caller common.Address,
+	key PoolKey,
+	recipient common.Address,
+	amount0, amount1 *big.Int,
+) (*big.Int, *big.Int, error) {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return nil, nil, ErrUnauthorized
+	}
+	if recipient == (common.Address{}) {
+		return nil, nil, ErrInvalidParameter
+	}
+
+	poolId := key.ID()
+	pool := pm.getPool(stateDB, poolId)
+	if !pool.IsInitialized() {
+		return nil, nil, ErrPoolNotInitialized
+	}
+
+	collected0 := protocolFeeAmount(pool.ProtocolFees0, amount0)
+	collected1 := protocolFeeAmount(pool.ProtocolFees1, amount1)
+	if err := pm.transferOut(stateDB, key.Currency0, recipient, collected0); err != nil {
+		return nil, nil, err
+	}
+	if err := pm.transferOut(stateDB, key.Currency1, recipient, collected1); err != nil {
+		return nil, nil, err
+	}
+
+	pool.ProtocolFees0 = new(big.Int).Sub(pool.ProtocolFees0, collected0)
+	pool.ProtocolFees1 = new(big.Int).Sub(pool.ProtocolFees1, collected1)
+	pm.setPool(stateDB, poolId, pool)
+	return collected0, collected1, nil
+}
+
+// protocolFeeAmount caps a requested collection at the accrued amount
+func protocolFeeAmount(accrued, requested *big.Int) *big.Int {
+	if requested == nil || requested.Sign() < 0 || requested.Cmp(accrued) > 0 {
+		return new(big.Int).Set(accrued)
+	}
+	return new(big.Int).Set(requested)
+}
+
+// protocolFeeShare returns the protocol's share of a swap step's fee
+func protocolFeeShare(feeAmount *big.Int, feeBps uint32) *big.Int {
+	share := new(big.Int).Mul(feeAmount, big.NewInt(int64(feeBps)))
+	return share.Div(share, big.NewInt(10_000))
+}
+
+// transferOut pays amount of currency from the pool manager to an address
+func (pm *PoolManager) transferOut(stateDB StateDB, currency Currency, to common.Address, amount *big.Int) error {
+	if amount.Sign() == 0 {
+		return nil
+	}
+
+	// Non-standard currencies pay out through the token adapter
+	if pm.isNonStandard(stateDB, currency) {
+		_, err := pm.tokens.TransferOut(stateDB, currency, to, amount)
+		return err
+	}
+
+	if currency.IsNative() {
+		amountU256, _ := uint256.FromBig(amount)
+		stateDB.SubBalance(poolManagerAddr, amountU256)
+		stateDB.AddBalance(to, amountU256)
+	} else {
+		pm.transferERC20(stateDB, currency, poolManagerAddr, to, amount)
+	}
+	pm.addReserves(stateDB, currency, new(big.Int).Neg(amount))
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/luxfi/geth/common"
+)
+
+// TestProtocolFees tests that swaps split fees between the protocol and
+// liquidity providers, and that only the controller collects them
+func TestProtocolFees(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+	poolId := key.ID()
+	controller := common.HexToAddress("0xC0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0")
+	trader := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	recipient := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	pm.setProtocolFeeController(stateDB, controller)
+
+	if _, err := pm.Initialize(stateDB, key, encodePriceSqrt(1, 1), nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := pm.SetProtocolFee(stateDB, trader, key, 1_000); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got: %v", err)
+	}
+	if err := pm.SetProtocolFee(stateDB, controller, key, MaxProtocolFeeBps+1); err != ErrInvalidProtocolFee {
+		t.Errorf("Expected ErrInvalidProtocolFee, got: %v", err)
+	}
+	if err := pm.SetProtocolFee(stateDB, controller, key, 1_000); err != nil {
+		t.Fatalf("SetProtocolFee failed: %v", err)
+	}
+
+	openLock(pm, trader)
+	la := expandTo18Decimals(1)
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, ModifyLiquidityParams{TickLower: -60, TickUpper: 60, LiquidityDelta: la}, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+
+	// 10% of the swap fee goes to the protocol, the rest to fee growth
+	sqrtPrice0, _ := getSqrtRatioAtTick(0)
+	sqrtPriceLower, _ := getSqrtRatioAtTick(-60)
+	amountIn := big.NewInt(1_000_000_000_000_000)
+	_, _, _, fee, _ := computeSwapStep(sqrtPrice0, sqrtPriceLower, la, amountIn, key.Fee)
+	protocolFee := new(big.Int).Div(new(big.Int).Mul(fee, big.NewInt(1_000)), big.NewInt(10_000))
+
+	if _, err := pm.Swap(stateDB, key, SwapParams{ZeroForOne: true, AmountSpecified: amountIn}, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	pool := pm.pools[poolId]
+	if pool.ProtocolFees0.Cmp(protocolFee) != 0 || pool.ProtocolFees1.Sign() != 0 {
+		t.Errorf("Expected protocol fees (%s, 0), got (%s, %s)", protocolFee, pool.ProtocolFees0, pool.ProtocolFees1)
+	}
+	if growth := mulDiv(new(big.Int).Sub(fee, protocolFee), Q128, la); pool.FeeGrowth0X128.Cmp(growth) != 0 {
+		t.Errorf("Expected FeeGrowth0X128 %s, got %s", growth, pool.FeeGrowth0X128)
+	}
+
+	// The fee share and accrued fees persist
+	reloaded := NewPoolManager().getPool(stateDB, poolId)
+	if reloaded.ProtocolFeeBps != 1_000 || reloaded.ProtocolFees0.Cmp(protocolFee) != 0 {
+		t.Errorf("Expected reloaded protocol fee 1000 bps with %s accrued, got %d bps with %s", protocolFee, reloaded.ProtocolFeeBps, reloaded.ProtocolFees0)
+	}
+
+	// Only the controller collects, capped at what has accrued
+	if _, _, err := pm.CollectProtocolFees(stateDB, trader, key, trader, nil, nil); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got: %v", err)
+	}
+	reserve, _ := uint256.FromBig(protocolFee)
+	stateDB.AddBalance(poolManagerAddr, reserve)
+	collected0, collected1, err := pm.CollectProtocolFees(stateDB, controller, key, recipient, big.NewInt(1), nil)
+	if err != nil {
+		t.Fatalf("CollectProtocolFees failed: %v", err)
+	}
+	if collected0.Cmp(big.NewInt(1)) != 0 || collected1.Sign() != 0 {
+		t.Errorf("Expected to collect (1, 0), got (%s, %s)", collected0, collected1)
+	}
+	collected0, _, err = pm.CollectProtocolFees(stateDB, controller, key, recipient, nil, nil)
+	if err != nil {
+		t.Fatalf("CollectProtocolFees failed: %v", err)
+	}
+	if want := new(big.Int).Sub(protocolFee, big.NewInt(1)); collected0.Cmp(want) != 0 {
+		t.Errorf("Expected to collect the remaining %s, got %s", want, collected0)
+	}
+	if pool.ProtocolFees0.Sign() != 0 {
+		t.Errorf("Expected no protocol fees left, got %s", pool.ProtocolFees0)
+	}
+	if balance := stateDB.GetBalance(recipient).ToBig(); balance.Cmp(protocolFee) != 0 {
+		t.Errorf("Expected recipient balance %s, got %s", protocolFee, balance)
+	}
+
+	// Handing over control revokes the old controller
+	if err := pm.SetProtocolFeeController(stateDB, controller, recipient); err != nil {
+		t.Fatalf("SetProtocolFeeController failed: %v", err)
+	}
+	if err := pm.SetProtocolFee(stateDB, controller, key, 0); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized for the old controller, got: %v", err)
+	}
+	if err := pm.SetProtocolFee(stateDB, recipient, key, 0); err != nil {
+		t.Errorf("SetProtocolFee by the new controller failed: %v", err)
+	}
+	if got := newTestPoolManager().ProtocolFeeController(stateDB); got != recipient {
+		t.Errorf("Expected the controller to be read from state, got %s", got.Hex())
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+)
+
+// =========================================================================
+// Referral Fees
+// =========================================================================
+//
+// Swaps may name a referrer (an aggregator, wallet or frontend). A share of
+// the swap fee, taken in the input currency, is credited to the referrer's
+// internal claim balance instead of going to LPs. Balances are claimed into
+// the referrer's flash-accounting delta inside a lock, or withdrawn directly
+// to an address.
+
+const (
+	// DefaultReferralShareBps is the default referrer cut of the swap fee (10%)
+	DefaultReferralShareBps uint32 = 1_000
+
+	// MaxReferralShareBps caps the referrer cut of the swap fee (50%)
+	MaxReferralShareBps uint32 = 5_000
+)
+
+// ReferrerStats holds a referrer's lifetime statistics in one currency
+type ReferrerStats struct {
+	Swaps   uint64   // Referred swaps across all currencies
+	Volume  *big.Int // Referred input volume
+	Earned  *big.Int // Total fees accrued
+	Claimed *big.Int // Total fees claimed or withdrawn
+}
+
+// ReferralBook tracks referral shares, claim balances and statistics.
+// Everything but the genesis default share lives in the pool manager's
+// storage:
+//
+//	rfrl || "share"                        -> default share (set flag, bps)
+//	rfrl || referrer || "share"            -> negotiated share (set flag, bps)
+//	rfrl || referrer || "swaps"            -> referred swaps
+//	rfrl || referrer || currency || field  -> "bal", "vol", "earn", "clmd"
+type ReferralBook struct {
+	// shareBps is the default cut paid to referrers until governance
+	// stores another
+	shareBps uint32
+}
+
+// NewReferralBook creates a referral book with the given default share
+func NewReferralBook(shareBps uint32) *ReferralBook {
+	return &ReferralBook{shareBps: shareBps}
+}
+
+// referralStorageKey returns the storage key of a referral field
+func referralStorageKey(id []byte, field string) common.Hash {
+	return makeStorageKey(referralPrefix, append(append([]byte(nil), id...), field...))
+}
+
+// referralAmountKey returns the storage key of a referrer's amount in a currency
+func referralAmountKey(referrer common.Address, currency Currency, field string) common.Hash {
+	return referralStorageKey(append(referrer.Bytes(), currency.Address.Bytes()...), field)
+}
+
+// getAmount loads a referrer's amount in a currency
+func (rb *ReferralBook) getAmount(stateDB StateDB, referrer common.Address, currency Currency, field string) *big.Int {
+	return stateDB.GetState(poolManagerAddr, referralAmountKey(referrer, currency, field)).Big()
+}
+
+// addAmount adds delta to a referrer's amount in a currency
+func (rb *ReferralBook) addAmount(stateDB StateDB, referrer common.Address, currency Currency, field string, delta *big.Int) {
+	amount := rb.getAmount(stateDB, referrer, currency, field)
+	stateDB.SetState(poolManagerAddr, referralAmountKey(referrer, currency, field), common.BigToHash(amount.Add(amount, delta)))
+}
+
+// setShareWord stores a share with its set flag
+func setShareWord(stateDB StateDB, key common.Hash, shareBps uint32) {
+	var word common.Hash
+	word[0] = 1
+	binary.BigEndian.PutUint32(word[28:32], shareBps)
+	stateDB.SetState(poolManagerAddr, key, word)
+}
+
+// SetShare sets the default referrer cut in basis points of the swap fee
+func (rb *ReferralBook) SetShare(stateDB StateDB, shareBps uint32) error {
+	if shareBps > MaxReferralShareBps {
+		return ErrInvalidReferralShare
+	}
+	setShareWord(stateDB, referralStorageKey(nil, "share"), shareBps)
+	return nil
+}
+
+// SetReferrerShare overrides the cut for a single referrer
+func (rb *ReferralBook) SetReferrerShare(stateDB StateDB, referrer common.Address, shareBps uint32) error {
+	if shareBps > MaxReferralShareBps {
+		return ErrInvalidReferralShare
+	}
+	setShareWord(stateDB, referralStorageKey(referrer.Bytes(), "share"), shareBps)
+	return nil
+}
+
+// ShareOf returns the cut paid to a referrer in basis points
+func (rb *ReferralBook) ShareOf(stateDB StateDB, referrer common.Address) uint32 {
+	if word := stateDB.GetState(poolManagerAddr, referralStorageKey(referrer.Bytes(), "share")); word[0] != 0 {
+		return binary.BigEndian.Uint32(word[28:32])
+	}
+	if word := stateDB.GetState(poolManagerAddr, referralStorageKey(nil, "share")); word[0] != 0 {
+		return binary.BigEndian.Uint32(word[28:32])
+	}
+	return rb.shareBps
+}
+
+// Accrue credits the referrer's cut of fee and records the referred volume.
+// It returns the amount credited.
+func (rb *ReferralBook) Accrue(stateDB StateDB, referrer common.Address, currency Currency, volume, fee *big.Int) *big.Int {
+	cut := new(big.Int).Mul(fee, big.NewInt(int64(rb.ShareOf(stateDB, referrer))))
+	cut.Div(cut, big.NewInt(10_000))
+
+	swapsKey := referralStorageKey(referrer.Bytes(), "swaps")
+	swaps := decodeUint64Word(stateDB.GetState(poolManagerAddr, swapsKey).Bytes())
+	stateDB.SetState(poolManagerAddr, swapsKey, common.BytesToHash(encodeUint64(swaps+1)))
+	rb.addAmount(stateDB, referrer, currency, "vol", volume)
+
+	if cut.Sign() > 0 {
+		rb.addAmount(stateDB, referrer, currency, "earn", cut)
+		rb.addAmount(stateDB, referrer, currency, "bal", cut)
+	}
+
+	return cut
+}
+
+// Balance returns the unclaimed referral fees of a referrer
+func (rb *ReferralBook) Balance(stateDB StateDB, referrer common.Address, currency Currency) *big.Int {
+	return rb.getAmount(stateDB, referrer, currency, "bal")
+}
+
+// Stats returns a referrer's statistics in a currency
+func (rb *ReferralBook) Stats(stateDB StateDB, referrer common.Address, currency Currency) ReferrerStats {
+	return ReferrerStats{
+		Swaps:   decodeUint64Word(stateDB.GetState(poolManagerAddr, referralStorageKey(referrer.Bytes(), "swaps")).Bytes()),
+		Volume:  rb.getAmount(stateDB, referrer, currency, "vol"),
+		Earned:  rb.getAmount(stateDB, referrer, currency, "earn"),
+		Claimed: rb.getAmount(stateDB, referrer, currency, "clmd"),
+	}
+}
+
+// debit removes amount from a referrer's claim balance
+func (rb *ReferralBook) debit(stateDB StateDB, referrer common.Address, currency Currency, amount *big.Int) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+
+	if rb.Balance(stateDB, referrer, currency).Cmp(amount) < 0 {
+		return ErrInsufficientReferralBalance
+	}
+	rb.addAmount(stateDB, referrer, currency, "bal", new(big.Int).Neg(amount))
+	rb.addAmount(stateDB, referrer, currency, "clmd", amount)
+	return nil
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// Referrals returns the pool manager's referral book
+func (pm *PoolManager) Referrals() *ReferralBook {
+	return pm.referrals
+}
+
+// SetReferralShare sets the default referrer cut (protocol fee controller only)
+func (pm *PoolManager) SetReferralShare(stateDB StateDB, caller common.Address, shareBps uint32) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	return pm.referrals.SetShare(stateDB, shareBps)
+}
+
+// SetReferrerShare overrides the cut for one referrer (protocol fee controller only)
+func (pm *PoolManager) SetReferrerShare(stateDB StateDB, caller, referrer common.Address, shareBps uint32) error {
+	if caller != pm.ProtocolFeeController(stateDB) {
+		return ErrUnauthorized
+	}
+	return pm.referrals.SetReferrerShare(stateDB, referrer, shareBps)
+}
+
+// accrueReferral credits the referrer's share of a swap's fee. Self-referrals
+// earn nothing.
+func (pm *PoolManager) accrueReferral(stateDB StateDB, locker, referrer common.Address, key PoolKey, params SwapParams, delta BalanceDelta) {
+	if referrer == (common.Address{}) || referrer == locker {
+		return
+	}
+
+	currencyIn, amountIn := key.Currency1, delta.Amount1
+	if params.ZeroForOne {
+		currencyIn, amountIn = key.Currency0, delta.Amount0
+	}
+	volume := new(big.Int).Abs(amountIn)
+
+	fee := pm.calculateSwapFee(volume, big.NewInt(0), key.Fee)
+	pm.referrals.Accrue(stateDB, referrer, currencyIn, volume, fee)
+}
+
+// ClaimReferralFees moves referral fees owed to the current locker into its
+// flash-accounting delta, to be taken before the lock settles
+func (pm *PoolManager) ClaimReferralFees(stateDB StateDB, currency Currency, amount *big.Int) error {
+	locker := pm.getCurrentLocker()
+	if locker == (common.Address{}) {
+		return ErrUnauthorized
+	}
+
+	if err := pm.referrals.debit(stateDB, locker, currency, amount); err != nil {
+		return err
+	}
+
+	// Negative delta: the pool owes the locker
+	pm.updateDelta(locker, currency, new(big.Int).Neg(amount))
+	return nil
+}
+
+// WithdrawReferralFees transfers a referrer's fees directly to an address
+func (pm *PoolManager) WithdrawReferralFees(
+	stateDB StateDB,
+	referrer common.Address,
+	currency Currency,
+	to common.Address,
+	amount *big.Int,
+) error {
+	if to == (common.Address{}) {
+		return ErrInvalidParameter
+	}
+	if err := pm.referrals.debit(stateDB, referrer, currency, amount); err != nil {
+		return err
+	}
+	return pm.transferOut(stateDB, currency, to, amount)
+}
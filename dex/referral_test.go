@@ -0,0 +1,166 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var (
+	testReferrer = common.HexToAddress("0x5555555555555555555555555555555555555555")
+	testTrader   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+)
+
+// setupReferralSwap initializes a pool and opens a lock context for trader
+func setupReferralSwap(t *testing.T) (*PoolManager, *MockStateDB, PoolKey) {
+	t.Helper()
+
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+
+	sqrtPriceX96 := new(big.Int).Lsh(big.NewInt(1), 96)
+	if _, err := pm.Initialize(stateDB, key, sqrtPriceX96, nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	pm.pools[key.ID()].Liquidity = big.NewInt(1_000_000_000)
+
+	pm.lockers = append(pm.lockers, testTrader)
+	pm.currentDeltas[testTrader] = make(map[Currency]*big.Int)
+	return pm, stateDB, key
+}
+
+func TestSwapWithReferralAccruesShare(t *testing.T) {
+	pm, stateDB, key := setupReferralSwap(t)
+
+	params := SwapParams{
+		ZeroForOne:        true,
+		AmountSpecified:   big.NewInt(1_000_000),
+		SqrtPriceLimitX96: MinSqrtRatio,
+	}
+	if _, err := pm.SwapWithReferral(stateDB, key, params, testReferrer, nil); err != nil {
+		t.Fatalf("SwapWithReferral failed: %v", err)
+	}
+
+	// Fee = 1_000_000 * 0.30% = 3000; default referral share 10% = 300
+	balance := pm.Referrals().Balance(stateDB, testReferrer, key.Currency0)
+	if balance.Cmp(big.NewInt(300)) != 0 {
+		t.Errorf("Expected referral balance 300, got %s", balance)
+	}
+
+	stats := pm.Referrals().Stats(stateDB, testReferrer, key.Currency0)
+	if stats.Swaps != 1 {
+		t.Errorf("Expected 1 referred swap, got %d", stats.Swaps)
+	}
+	if stats.Volume.Cmp(big.NewInt(1_000_000)) != 0 {
+		t.Errorf("Expected volume 1000000, got %s", stats.Volume)
+	}
+
+	// Plain swaps and self-referrals accrue nothing
+	if _, err := pm.Swap(stateDB, key, params, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	if _, err := pm.SwapWithReferral(stateDB, key, params, testTrader, nil); err != nil {
+		t.Fatalf("SwapWithReferral failed: %v", err)
+	}
+	if pm.Referrals().Stats(stateDB, testTrader, key.Currency0).Swaps != 0 {
+		t.Error("Self-referral should not be recorded")
+	}
+	if pm.Referrals().Stats(stateDB, testReferrer, key.Currency0).Swaps != 1 {
+		t.Error("Unreferred swap should not be attributed")
+	}
+}
+
+func TestReferralShareConfiguration(t *testing.T) {
+	pm := newTestPoolManager()
+	controller := common.HexToAddress("0x9999999999999999999999999999999999999999")
+	stateDB := NewMockStateDB()
+	pm.setProtocolFeeController(stateDB, controller)
+
+	if err := pm.SetReferralShare(stateDB, testTrader, 2_000); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if err := pm.SetReferralShare(stateDB, controller, MaxReferralShareBps+1); err != ErrInvalidReferralShare {
+		t.Errorf("Expected ErrInvalidReferralShare, got %v", err)
+	}
+	if err := pm.SetReferralShare(stateDB, controller, 2_000); err != nil {
+		t.Fatalf("SetReferralShare failed: %v", err)
+	}
+	if err := pm.SetReferrerShare(stateDB, controller, testReferrer, 5_000); err != nil {
+		t.Fatalf("SetReferrerShare failed: %v", err)
+	}
+	if share := pm.Referrals().ShareOf(stateDB, testTrader); share != 2_000 {
+		t.Errorf("Expected stored default share 2000, got %d", share)
+	}
+
+	cut := pm.Referrals().Accrue(stateDB, testReferrer, NativeCurrency, big.NewInt(1_000_000), big.NewInt(3000))
+	if cut.Cmp(big.NewInt(1500)) != 0 {
+		t.Errorf("Expected negotiated cut 1500, got %s", cut)
+	}
+}
+
+func TestClaimAndWithdrawReferralFees(t *testing.T) {
+	pm, stateDB, key := setupReferralSwap(t)
+	pm.Referrals().Accrue(stateDB, testReferrer, key.Currency1, big.NewInt(1_000_000), big.NewInt(10_000))
+
+	// Claim requires a lock held by the referrer
+	if err := pm.ClaimReferralFees(stateDB, key.Currency1, big.NewInt(100)); err != ErrInsufficientReferralBalance {
+		t.Errorf("Expected ErrInsufficientReferralBalance for non-referrer locker, got %v", err)
+	}
+
+	pm.lockers = append(pm.lockers, testReferrer)
+	pm.currentDeltas[testReferrer] = make(map[Currency]*big.Int)
+
+	if err := pm.ClaimReferralFees(stateDB, key.Currency1, big.NewInt(400)); err != nil {
+		t.Fatalf("ClaimReferralFees failed: %v", err)
+	}
+	if delta := pm.GetDelta(testReferrer, key.Currency1); delta.Cmp(big.NewInt(-400)) != 0 {
+		t.Errorf("Expected delta -400 owed to referrer, got %s", delta)
+	}
+
+	to := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	if err := pm.WithdrawReferralFees(stateDB, testReferrer, key.Currency1, to, big.NewInt(600)); err != nil {
+		t.Fatalf("WithdrawReferralFees failed: %v", err)
+	}
+	if err := pm.WithdrawReferralFees(stateDB, testReferrer, key.Currency1, to, big.NewInt(1)); err != ErrInsufficientReferralBalance {
+		t.Errorf("Expected ErrInsufficientReferralBalance after full withdrawal, got %v", err)
+	}
+
+	stats := pm.Referrals().Stats(stateDB, testReferrer, key.Currency1)
+	if stats.Claimed.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected 1000 claimed, got %s", stats.Claimed)
+	}
+	if bal := pm.Referrals().Balance(stateDB, testReferrer, key.Currency1); bal.Sign() != 0 {
+		t.Errorf("Expected empty balance in state, got %s", bal)
+	}
+}
+
+func TestDecodeSwapWithReferralInput(t *testing.T) {
+	input := make([]byte, 227)
+	input[128] = 1
+	input[160] = 0x10
+	copy(input[205:225], testReferrer[:])
+	input[225], input[226] = 0xaa, 0xbb
+
+	_, params, referrer, hookData, err := DecodeSwapWithReferralInput(input)
+	if err != nil {
+		t.Fatalf("DecodeSwapWithReferralInput failed: %v", err)
+	}
+	if !params.ZeroForOne || params.AmountSpecified.Int64() != 0x10 {
+		t.Errorf("Unexpected swap params: %+v", params)
+	}
+	if referrer != testReferrer {
+		t.Errorf("Expected referrer %s, got %s", testReferrer.Hex(), referrer.Hex())
+	}
+	if len(hookData) != 2 || hookData[0] != 0xaa {
+		t.Errorf("Unexpected hook data %x", hookData)
+	}
+
+	if _, _, _, _, err := DecodeSwapWithReferralInput(input[:200]); err == nil {
+		t.Error("Expected error for short input")
+	}
+}
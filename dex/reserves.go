@@ -0,0 +1,108 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+)
+
+// Currency reserves
+//
+// The pool manager tracks its reserves of each standard currency: the
+// balance it has accounted for. Transfers it makes itself update them, so
+// tokens that arrive by external transfer are only credited through sync
+// and settle, as in v4. Sync records the currency's balance as its
+// reserves, the payer transfers tokens in, and Settle credits the locker
+// with the growth of the balance since the sync. Settling an amount other
+// than what arrived reverts.
+//
+// ERC20 balances are read through the TokenLedger. Non-standard currencies
+// are measured by the TokenAdapter on every transfer and are not synced.
+//
+// Reserves are stored under reservePrefix:
+//
+//	currency -> reserves
+
+// reservesStorageKey returns the storage key of a currency's reserves
+func reservesStorageKey(currency Currency) common.Hash {
+	return makeStorageKey(reservePrefix, currency.Address.Bytes())
+}
+
+// getReserves returns the tracked reserves of a currency
+func (pm *PoolManager) getReserves(stateDB StateDB, currency Currency) *big.Int {
+	value := stateDB.GetState(poolManagerAddr, reservesStorageKey(currency))
+	return new(big.Int).SetBytes(value[:])
+}
+
+// setReserves saves the tracked reserves of a currency
+func (pm *PoolManager) setReserves(stateDB StateDB, currency Currency, reserves *big.Int) {
+	var value common.Hash
+	reserves.FillBytes(value[:])
+	stateDB.SetState(poolManagerAddr, reservesStorageKey(currency), value)
+}
+
+// addReserves adjusts a currency's reserves by a signed amount. Reserves not
+// yet synced may be below what is paid out; they floor at zero until the
+// next sync.
+func (pm *PoolManager) addReserves(stateDB StateDB, currency Currency, amount *big.Int) {
+	reserves := pm.getReserves(stateDB, currency)
+	reserves.Add(reserves, amount)
+	if reserves.Sign() < 0 {
+		reserves.SetUint64(0)
+	}
+	pm.setReserves(stateDB, currency, reserves)
+}
+
+// balanceOf returns the pool manager's balance of a currency
+func (pm *PoolManager) balanceOf(stateDB StateDB, currency Currency) (*big.Int, error) {
+	if currency.IsNative() {
+		return stateDB.GetBalance(poolManagerAddr).ToBig(), nil
+	}
+	return pm.tokens.BalanceOf(stateDB, currency.Address, poolManagerAddr)
+}
+
+// ReservesOf returns the pool manager's tracked reserves of a currency
+func (pm *PoolManager) ReservesOf(stateDB StateDB, currency Currency) *big.Int {
+	return pm.getReserves(stateDB, currency)
+}
+
+// Sync records the pool manager's balance of a currency as its reserves,
+// so the next settle of the currency credits what arrives after it
+func (pm *PoolManager) Sync(
+	stateDB StateDB,
+	currency Currency,
+) error {
+	if pm.isNonStandard(stateDB, currency) {
+		return ErrInvalidParameter
+	}
+
+	balance, err := pm.balanceOf(stateDB, currency)
+	if err != nil {
+		return err
+	}
+	pm.setReserves(stateDB, currency, balance)
+	pm.syncedCurrency = &currency
+	return nil
+}
+
+// settleSynced credits the locker with the synced currency's balance growth
+// since the sync, which must equal amount
+func (pm *PoolManager) settleSynced(stateDB StateDB, locker common.Address, currency Currency, amount *big.Int) error {
+	balance, err := pm.balanceOf(stateDB, currency)
+	if err != nil {
+		return err
+	}
+	paid := new(big.Int).Sub(balance, pm.getReserves(stateDB, currency))
+	if paid.Cmp(amount) != 0 {
+		return fmt.Errorf("%w: settled %s, received %s", ErrSettlementMismatch, amount, paid)
+	}
+
+	pm.setReserves(stateDB, currency, balance)
+	pm.syncedCurrency = nil
+	pm.updateDelta(locker, currency, new(big.Int).Neg(paid))
+	return nil
+}
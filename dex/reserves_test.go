@@ -0,0 +1,97 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+// TestSyncSettle tests that synced currencies settle by the balance change
+// since the sync, and that reserves track the pool manager's transfers
+func TestSyncSettle(t *testing.T) {
+	pm, stateDB, ledger, key := setupTokenPool(t, 0)
+	token := key.Currency1
+
+	// ERC20s cannot be settled without a sync
+	pm.updateDelta(testTrader, token, big.NewInt(1_000))
+	if err := pm.Settle(stateDB, token, big.NewInt(1_000)); err != ErrCurrencyNotSynced {
+		t.Errorf("Expected ErrCurrencyNotSynced, got: %v", err)
+	}
+
+	// Tokens that arrive before the sync are absorbed into reserves
+	ledger.mint(token.Address, poolManagerAddr, big.NewInt(50))
+	if err := pm.Sync(stateDB, token); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if reserves := pm.ReservesOf(stateDB, token); reserves.Cmp(big.NewInt(50)) != 0 {
+		t.Errorf("Expected reserves 50 after sync, got %s", reserves)
+	}
+
+	ledger.mint(token.Address, testTrader, big.NewInt(1_000))
+	if err := ledger.Transfer(stateDB, token.Address, testTrader, poolManagerAddr, big.NewInt(1_000)); err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+	if err := pm.Settle(stateDB, token, big.NewInt(999)); !errors.Is(err, ErrSettlementMismatch) {
+		t.Errorf("Expected ErrSettlementMismatch, got: %v", err)
+	}
+	if err := pm.Settle(stateDB, token, big.NewInt(1_000)); err != nil {
+		t.Fatalf("Settle failed: %v", err)
+	}
+	if delta := pm.GetDelta(testTrader, token); delta.Sign() != 0 {
+		t.Errorf("Expected zero delta after settlement, got: %s", delta)
+	}
+	if reserves := pm.ReservesOf(stateDB, token); reserves.Cmp(big.NewInt(1_050)) != 0 {
+		t.Errorf("Expected reserves 1050 after settle, got %s", reserves)
+	}
+
+	// A sync covers one settle
+	if err := pm.Settle(stateDB, token, big.NewInt(0)); err != ErrCurrencyNotSynced {
+		t.Errorf("Expected ErrCurrencyNotSynced after settling, got: %v", err)
+	}
+
+	// Native settles and takes move reserves with the transfer
+	stateDB.AddBalance(testTrader, uint256.NewInt(1_000))
+	pm.updateDelta(testTrader, NativeCurrency, big.NewInt(60))
+	if err := pm.Settle(stateDB, NativeCurrency, big.NewInt(100)); err != nil {
+		t.Fatalf("Settle failed: %v", err)
+	}
+	if err := pm.Take(stateDB, NativeCurrency, testTrader, big.NewInt(40)); err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if reserves := pm.ReservesOf(stateDB, NativeCurrency); reserves.Cmp(big.NewInt(60)) != 0 {
+		t.Errorf("Expected native reserves 60, got %s", reserves)
+	}
+
+	// A synced native settle credits what arrived instead of pulling it
+	if err := pm.Sync(stateDB, NativeCurrency); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	stateDB.AddBalance(poolManagerAddr, uint256.NewInt(25))
+	pm.updateDelta(testTrader, NativeCurrency, big.NewInt(25))
+	if err := pm.Settle(stateDB, NativeCurrency, big.NewInt(25)); err != nil {
+		t.Fatalf("Settle failed: %v", err)
+	}
+	if balance := stateDB.GetBalance(testTrader).Uint64(); balance != 940 {
+		t.Errorf("Expected trader balance 940, got %d", balance)
+	}
+	if reserves := pm.ReservesOf(stateDB, NativeCurrency); reserves.Cmp(big.NewInt(85)) != 0 {
+		t.Errorf("Expected native reserves 85, got %s", reserves)
+	}
+	if delta := pm.GetDelta(testTrader, NativeCurrency); delta.Sign() != 0 {
+		t.Errorf("Expected zero native delta, got: %s", delta)
+	}
+
+	// Non-standard currencies are measured on transfer, not synced
+	flagged, flaggedDB, _, flaggedKey := setupTokenPool(t, TokenFlagFeeOnTransfer1)
+	if err := flagged.Sync(flaggedDB, flaggedKey.Currency1); err != ErrInvalidParameter {
+		t.Errorf("Expected ErrInvalidParameter, got: %v", err)
+	}
+	if err := newTestPoolManager().Sync(stateDB, token); err != ErrNoTokenLedger {
+		t.Errorf("Expected ErrNoTokenLedger, got: %v", err)
+	}
+}
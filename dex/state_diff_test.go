@@ -0,0 +1,96 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/geth/core/tracing"
+	"github.com/luxfi/precompile/contract/statetest"
+)
+
+// diffStateDB adapts the journaling statetest.StateDB to the DEX StateDB
+// interface, for tests that assert exact state transitions
+type diffStateDB struct {
+	*statetest.StateDB
+	timestamp uint64
+}
+
+func newDiffStateDB() *diffStateDB {
+	return &diffStateDB{StateDB: statetest.New()}
+}
+
+func (d *diffStateDB) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	d.StateDB.SetState(addr, key, value)
+}
+
+func (d *diffStateDB) AddBalance(addr common.Address, amount *uint256.Int) {
+	d.StateDB.AddBalance(addr, amount, tracing.BalanceChangeUnspecified)
+}
+
+func (d *diffStateDB) SubBalance(addr common.Address, amount *uint256.Int) {
+	d.StateDB.SubBalance(addr, amount, tracing.BalanceChangeUnspecified)
+}
+
+func (d *diffStateDB) GetBlockTimestamp() uint64 {
+	return d.timestamp
+}
+
+// TestLendingSupplyStateDiff tests the exact balance moves and storage
+// touched by a supply
+func TestLendingSupplyStateDiff(t *testing.T) {
+	lp := NewLendingPool(NewPoolManager())
+	stateDB := newDiffStateDB()
+
+	collateralFactor := new(big.Int).Div(new(big.Int).Mul(big.NewInt(75), RAY), big.NewInt(100))
+	liquidationBonus := new(big.Int).Div(new(big.Int).Mul(big.NewInt(5), RAY), big.NewInt(100))
+	if err := lp.InitializeReserve(stateDB, testLendingAsset, collateralFactor, liquidationBonus, DefaultInterestRateModel()); err != nil {
+		t.Fatalf("InitializeReserve failed: %v", err)
+	}
+	balance, _ := uint256.FromBig(bigInt("10000000000000000000000"))
+	stateDB.AddBalance(testLendingUser1, balance)
+	stateDB.Commit()
+
+	if _, err := lp.Supply(stateDB, testLendingUser1, testLendingAsset, bigInt("1000000000000000000000")); err != nil {
+		t.Fatalf("Supply failed: %v", err)
+	}
+
+	diff := stateDB.Diff()
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	if len(lines) < 3 {
+		t.Fatalf("Expected balance and storage changes, got:\n%s", diff)
+	}
+	wantBalances := []string{
+		fmt.Sprintf("balance %s: 0 -> 1000000000000000000000", lendingPoolAddr.Hex()),
+		fmt.Sprintf("balance %s: 10000000000000000000000 -> 9000000000000000000000", testLendingUser1.Hex()),
+	}
+	for _, want := range wantBalances {
+		if !strings.Contains(diff, want+"\n") {
+			t.Errorf("Expected %q in diff:\n%s", want, diff)
+		}
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "storage ") && !strings.HasPrefix(line, "storage "+lendingPoolAddr.Hex()) {
+			t.Errorf("Unexpected storage write outside the lending pool: %s", line)
+		}
+	}
+	if stateDB.GasUsed() == 0 {
+		t.Error("Expected storage gas to be charged")
+	}
+
+	// A reverted supply leaves the diff untouched
+	snap := stateDB.Snapshot()
+	if _, err := lp.Supply(stateDB, testLendingUser1, testLendingAsset, bigInt("1000000000000000000000")); err != nil {
+		t.Fatalf("Supply failed: %v", err)
+	}
+	stateDB.RevertToSnapshot(snap)
+	if got := stateDB.Diff(); got != diff {
+		t.Errorf("Expected revert to restore the diff, got:\n%s", got)
+	}
+}
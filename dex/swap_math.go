@@ -0,0 +1,334 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+)
+
+// Concentrated liquidity math
+//
+// These are the Uniswap v3 TickMath, SqrtPriceMath and SwapMath libraries
+// on big.Int. Rounding follows the Solidity code exactly, including where it
+// chooses between formulas to avoid uint256 overflow, so every step settles
+// to the same wei as a v3 pool would. Prices are sqrt(price) in Q64.96 and
+// liquidity is L = sqrt(x*y).
+
+// feePipsDenominator is the unit of pool fees: 1_000_000 pips is 100%
+const feePipsDenominator = 1_000_000
+
+var (
+	maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	maxUint160 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+
+	// tickRatios[i] is 2^128 / sqrt(1.0001)^(2^i) in Q128.128, the factor
+	// getSqrtRatioAtTick multiplies in for bit i of |tick|
+	tickRatios = [20]*big.Int{
+		hexBig("fffcb933bd6fad37aa2d162d1a594001"),
+		hexBig("fff97272373d413259a46990580e213a"),
+		hexBig("fff2e50f5f656932ef12357cf3c7fdcc"),
+		hexBig("ffe5caca7e10e4e61c3624eaa0941cd0"),
+		hexBig("ffcb9843d60f6159c9db58835c926644"),
+		hexBig("ff973b41fa98c081472e6896dfb254c0"),
+		hexBig("ff2ea16466c96a3843ec78b326b52861"),
+		hexBig("fe5dee046a99a2a811c461f1969c3053"),
+		hexBig("fcbe86c7900a88aedcffc83b479aa3a4"),
+		hexBig("f987a7253ac413176f2b074cf7815e54"),
+		hexBig("f3392b0822b70005940c7a398e4b70f3"),
+		hexBig("e7159475a2c29b7443b29c7fa6e889d9"),
+		hexBig("d097f3bdfd2022b8845ad8f792aa5825"),
+		hexBig("a9f746462d870fdf8a65dc1f90e061e5"),
+		hexBig("70d869a156d2a1b890bb3df62baf32f7"),
+		hexBig("31be135f97d08fd981231505542fcfa6"),
+		hexBig("9aa508b5b7a84e1c677de54f3e99bc9"),
+		hexBig("5d6af8dedb81196699c329225ee604"),
+		hexBig("2216e584f5fa1ea926041bedfe98"),
+		hexBig("48a170391f7dc42444e8fa2"),
+	}
+)
+
+func hexBig(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("dex: invalid hex constant " + s)
+	}
+	return v
+}
+
+// =========================================================================
+// TickMath
+// =========================================================================
+
+// getSqrtRatioAtTick returns sqrt(1.0001^tick) * 2^96, rounded up
+func getSqrtRatioAtTick(tick int24) (*big.Int, error) {
+	absTick := int64(tick)
+	if absTick < 0 {
+		absTick = -absTick
+	}
+	if absTick > int64(MaxTick) {
+		return nil, ErrTickOutOfRange
+	}
+
+	ratio := new(big.Int).Set(Q128)
+	if absTick&1 != 0 {
+		ratio.Set(tickRatios[0])
+	}
+	for i := 1; i < len(tickRatios); i++ {
+		if absTick&(1<<i) != 0 {
+			ratio.Mul(ratio, tickRatios[i])
+			ratio.Rsh(ratio, 128)
+		}
+	}
+	if tick > 0 {
+		ratio.Div(maxUint256, ratio)
+	}
+
+	// Round up from Q128.128 to Q64.96, so getTickAtSqrtRatio inverts this
+	sqrtPriceX96 := new(big.Int).Rsh(ratio, 32)
+	if ratio.Cmp(new(big.Int).Lsh(sqrtPriceX96, 32)) != 0 {
+		sqrtPriceX96.Add(sqrtPriceX96, big.NewInt(1))
+	}
+	return sqrtPriceX96, nil
+}
+
+// getTickAtSqrtRatio returns the greatest tick whose sqrt ratio is at most
+// sqrtPriceX96. Prices at or beyond the bounds map to MinTick and MaxTick.
+func getTickAtSqrtRatio(sqrtPriceX96 *big.Int) int24 {
+	if sqrtPriceX96.Cmp(MinSqrtRatio) <= 0 {
+		return MinTick
+	}
+	if sqrtPriceX96.Cmp(MaxSqrtRatio) >= 0 {
+		return MaxTick
+	}
+
+	// getSqrtRatioAtTick(low) <= sqrtPriceX96 < getSqrtRatioAtTick(high+1)
+	low, high := MinTick, MaxTick
+	for low < high {
+		mid := low + (high-low+1)/2
+		ratio, _ := getSqrtRatioAtTick(mid)
+		if ratio.Cmp(sqrtPriceX96) <= 0 {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return low
+}
+
+// =========================================================================
+// FullMath
+// =========================================================================
+
+// mulDiv returns floor(a * b / denominator)
+func mulDiv(a, b, denominator *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Div(r, denominator)
+}
+
+// mulDivRoundingUp returns ceil(a * b / denominator)
+func mulDivRoundingUp(a, b, denominator *big.Int) *big.Int {
+	return divRoundingUp(new(big.Int).Mul(a, b), denominator)
+}
+
+// divRoundingUp returns ceil(a / b)
+func divRoundingUp(a, b *big.Int) *big.Int {
+	q, m := new(big.Int).DivMod(a, b, new(big.Int))
+	if m.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// =========================================================================
+// SqrtPriceMath
+// =========================================================================
+
+// getNextSqrtPriceFromAmount0RoundingUp returns the price after adding or
+// removing amount of currency0, rounded up so the pool never gives out more
+// than it receives
+func getNextSqrtPriceFromAmount0RoundingUp(sqrtPX96, liquidity, amount *big.Int, add bool) (*big.Int, error) {
+	if amount.Sign() == 0 {
+		return new(big.Int).Set(sqrtPX96), nil
+	}
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	product := new(big.Int).Mul(amount, sqrtPX96)
+
+	if add {
+		// L * sqrtP / (L + amount * sqrtP), unless that overflows uint256
+		denominator := new(big.Int).Add(numerator1, product)
+		if denominator.Cmp(maxUint256) <= 0 {
+			return mulDivRoundingUp(numerator1, sqrtPX96, denominator), nil
+		}
+		// L / (L / sqrtP + amount)
+		return divRoundingUp(numerator1, new(big.Int).Add(new(big.Int).Div(numerator1, sqrtPX96), amount)), nil
+	}
+
+	if product.Cmp(maxUint256) > 0 || numerator1.Cmp(product) <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	next := mulDivRoundingUp(numerator1, sqrtPX96, new(big.Int).Sub(numerator1, product))
+	if next.Cmp(maxUint160) > 0 {
+		return nil, ErrInvalidSqrtPrice
+	}
+	return next, nil
+}
+
+// getNextSqrtPriceFromAmount1RoundingDown returns the price after adding or
+// removing amount of currency1, rounded down so the pool never gives out
+// more than it receives
+func getNextSqrtPriceFromAmount1RoundingDown(sqrtPX96, liquidity, amount *big.Int, add bool) (*big.Int, error) {
+	if add {
+		next := new(big.Int).Add(sqrtPX96, mulDiv(amount, Q96, liquidity))
+		if next.Cmp(maxUint160) > 0 {
+			return nil, ErrInvalidSqrtPrice
+		}
+		return next, nil
+	}
+
+	quotient := mulDivRoundingUp(amount, Q96, liquidity)
+	if sqrtPX96.Cmp(quotient) <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	return quotient.Sub(sqrtPX96, quotient), nil
+}
+
+// getNextSqrtPriceFromInput returns the price after amountIn of the input
+// currency is swapped in
+func getNextSqrtPriceFromInput(sqrtPX96, liquidity, amountIn *big.Int, zeroForOne bool) (*big.Int, error) {
+	if sqrtPX96.Sign() <= 0 || liquidity.Sign() <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	if zeroForOne {
+		return getNextSqrtPriceFromAmount0RoundingUp(sqrtPX96, liquidity, amountIn, true)
+	}
+	return getNextSqrtPriceFromAmount1RoundingDown(sqrtPX96, liquidity, amountIn, true)
+}
+
+// getNextSqrtPriceFromOutput returns the price after amountOut of the output
+// currency is swapped out
+func getNextSqrtPriceFromOutput(sqrtPX96, liquidity, amountOut *big.Int, zeroForOne bool) (*big.Int, error) {
+	if sqrtPX96.Sign() <= 0 || liquidity.Sign() <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	if zeroForOne {
+		return getNextSqrtPriceFromAmount1RoundingDown(sqrtPX96, liquidity, amountOut, false)
+	}
+	return getNextSqrtPriceFromAmount0RoundingUp(sqrtPX96, liquidity, amountOut, false)
+}
+
+// getAmount0Delta returns the currency0 liquidity holds between two prices:
+// L * (sqrtB - sqrtA) / (sqrtA * sqrtB)
+func getAmount0Delta(sqrtRatioAX96, sqrtRatioBX96, liquidity *big.Int, roundUp bool) *big.Int {
+	if sqrtRatioAX96.Cmp(sqrtRatioBX96) > 0 {
+		sqrtRatioAX96, sqrtRatioBX96 = sqrtRatioBX96, sqrtRatioAX96
+	}
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	numerator2 := new(big.Int).Sub(sqrtRatioBX96, sqrtRatioAX96)
+
+	if roundUp {
+		return divRoundingUp(mulDivRoundingUp(numerator1, numerator2, sqrtRatioBX96), sqrtRatioAX96)
+	}
+	amount := mulDiv(numerator1, numerator2, sqrtRatioBX96)
+	return amount.Div(amount, sqrtRatioAX96)
+}
+
+// getAmount1Delta returns the currency1 liquidity holds between two prices:
+// L * (sqrtB - sqrtA)
+func getAmount1Delta(sqrtRatioAX96, sqrtRatioBX96, liquidity *big.Int, roundUp bool) *big.Int {
+	if sqrtRatioAX96.Cmp(sqrtRatioBX96) > 0 {
+		sqrtRatioAX96, sqrtRatioBX96 = sqrtRatioBX96, sqrtRatioAX96
+	}
+	diff := new(big.Int).Sub(sqrtRatioBX96, sqrtRatioAX96)
+
+	if roundUp {
+		return mulDivRoundingUp(liquidity, diff, Q96)
+	}
+	return mulDiv(liquidity, diff, Q96)
+}
+
+// =========================================================================
+// SwapMath
+// =========================================================================
+
+// computeSwapStep swaps within one price range of constant liquidity, from
+// sqrtRatioCurrentX96 toward sqrtRatioTargetX96, until the target is reached
+// or amountRemaining (positive for exact input, negative for exact output)
+// runs out. It returns the price reached, the input and output amounts and
+// the fee taken on top of the input.
+func computeSwapStep(
+	sqrtRatioCurrentX96 *big.Int,
+	sqrtRatioTargetX96 *big.Int,
+	liquidity *big.Int,
+	amountRemaining *big.Int,
+	feePips uint24,
+) (sqrtRatioNextX96, amountIn, amountOut, feeAmount *big.Int, err error) {
+	zeroForOne := sqrtRatioCurrentX96.Cmp(sqrtRatioTargetX96) >= 0
+	exactIn := amountRemaining.Sign() >= 0
+	fee := big.NewInt(int64(feePips))
+	feeComplement := big.NewInt(int64(feePipsDenominator - feePips))
+
+	if exactIn {
+		amountRemainingLessFee := mulDiv(amountRemaining, feeComplement, big.NewInt(feePipsDenominator))
+		if zeroForOne {
+			amountIn = getAmount0Delta(sqrtRatioTargetX96, sqrtRatioCurrentX96, liquidity, true)
+		} else {
+			amountIn = getAmount1Delta(sqrtRatioCurrentX96, sqrtRatioTargetX96, liquidity, true)
+		}
+		if amountRemainingLessFee.Cmp(amountIn) >= 0 {
+			sqrtRatioNextX96 = new(big.Int).Set(sqrtRatioTargetX96)
+		} else {
+			sqrtRatioNextX96, err = getNextSqrtPriceFromInput(sqrtRatioCurrentX96, liquidity, amountRemainingLessFee, zeroForOne)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+		}
+	} else {
+		amountRemainingOut := new(big.Int).Neg(amountRemaining)
+		if zeroForOne {
+			amountOut = getAmount1Delta(sqrtRatioTargetX96, sqrtRatioCurrentX96, liquidity, false)
+		} else {
+			amountOut = getAmount0Delta(sqrtRatioCurrentX96, sqrtRatioTargetX96, liquidity, false)
+		}
+		if amountRemainingOut.Cmp(amountOut) >= 0 {
+			sqrtRatioNextX96 = new(big.Int).Set(sqrtRatioTargetX96)
+		} else {
+			sqrtRatioNextX96, err = getNextSqrtPriceFromOutput(sqrtRatioCurrentX96, liquidity, amountRemainingOut, zeroForOne)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+		}
+	}
+
+	reached := sqrtRatioTargetX96.Cmp(sqrtRatioNextX96) == 0
+
+	// Recompute the amounts unless the step ran to the target
+	if zeroForOne {
+		if !reached || !exactIn {
+			amountIn = getAmount0Delta(sqrtRatioNextX96, sqrtRatioCurrentX96, liquidity, true)
+		}
+		if !reached || exactIn {
+			amountOut = getAmount1Delta(sqrtRatioNextX96, sqrtRatioCurrentX96, liquidity, false)
+		}
+	} else {
+		if !reached || !exactIn {
+			amountIn = getAmount1Delta(sqrtRatioCurrentX96, sqrtRatioNextX96, liquidity, true)
+		}
+		if !reached || exactIn {
+			amountOut = getAmount0Delta(sqrtRatioCurrentX96, sqrtRatioNextX96, liquidity, false)
+		}
+	}
+
+	// The output can exceed the remainder by rounding; cap it
+	if !exactIn && amountOut.CmpAbs(amountRemaining) > 0 {
+		amountOut = new(big.Int).Neg(amountRemaining)
+	}
+
+	if exactIn && sqrtRatioNextX96.Cmp(sqrtRatioTargetX96) != 0 {
+		// The step consumed the whole remainder, so the fee is what is left
+		feeAmount = new(big.Int).Sub(amountRemaining, amountIn)
+	} else {
+		feeAmount = mulDivRoundingUp(amountIn, fee, feeComplement)
+	}
+	return sqrtRatioNextX96, amountIn, amountOut, feeAmount, nil
+}
@@ -0,0 +1,232 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+)
+
+// Vectors in this file are taken from the Uniswap v3-core test suite
+// (TickMath.spec.ts, SqrtPriceMath.spec.ts and SwapMath.spec.ts)
+
+func bigString(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid number " + s)
+	}
+	return v
+}
+
+// encodePriceSqrt returns sqrt(reserve1 / reserve0) * 2^96, as the v3 tests do
+func encodePriceSqrt(reserve1, reserve0 int64) *big.Int {
+	ratio := new(big.Int).Lsh(big.NewInt(reserve1), 192)
+	ratio.Div(ratio, big.NewInt(reserve0))
+	return ratio.Sqrt(ratio)
+}
+
+// expandTo18Decimals returns n * 10^18
+func expandTo18Decimals(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+}
+
+func TestGetSqrtRatioAtTick(t *testing.T) {
+	tests := []struct {
+		tick int24
+		want string
+	}{
+		{MinTick, "4295128739"},
+		{MinTick + 1, "4295343490"},
+		{0, "79228162514264337593543950336"},
+		{MaxTick - 1, "1461373636630004318706518188784493106690254656249"},
+		{MaxTick, "1461446703485210103287273052203988822378723970342"},
+	}
+	for _, tt := range tests {
+		got, err := getSqrtRatioAtTick(tt.tick)
+		if err != nil {
+			t.Fatalf("getSqrtRatioAtTick(%d) failed: %v", tt.tick, err)
+		}
+		if got.Cmp(bigString(tt.want)) != 0 {
+			t.Errorf("getSqrtRatioAtTick(%d): got %s, want %s", tt.tick, got, tt.want)
+		}
+	}
+
+	if _, err := getSqrtRatioAtTick(MinTick - 1); err != ErrTickOutOfRange {
+		t.Errorf("Expected ErrTickOutOfRange below MinTick, got %v", err)
+	}
+	if _, err := getSqrtRatioAtTick(MaxTick + 1); err != ErrTickOutOfRange {
+		t.Errorf("Expected ErrTickOutOfRange above MaxTick, got %v", err)
+	}
+}
+
+func TestGetTickAtSqrtRatio(t *testing.T) {
+	tests := []struct {
+		sqrtPriceX96 *big.Int
+		want         int24
+	}{
+		{MinSqrtRatio, MinTick},
+		{bigString("4295343490"), MinTick + 1},
+		{encodePriceSqrt(1, 1), 0},
+		{new(big.Int).Sub(encodePriceSqrt(1, 1), big.NewInt(1)), -1},
+		{bigString("1461373636630004318706518188784493106690254656249"), MaxTick - 1},
+		{new(big.Int).Sub(MaxSqrtRatio, big.NewInt(1)), MaxTick - 1},
+	}
+	for _, tt := range tests {
+		if got := getTickAtSqrtRatio(tt.sqrtPriceX96); got != tt.want {
+			t.Errorf("getTickAtSqrtRatio(%s): got %d, want %d", tt.sqrtPriceX96, got, tt.want)
+		}
+	}
+
+	// The two functions invert each other across the range
+	for _, tick := range []int24{-500000, -60, -1, 1, 60, 12345, 500000} {
+		ratio, _ := getSqrtRatioAtTick(tick)
+		if got := getTickAtSqrtRatio(ratio); got != tick {
+			t.Errorf("Round trip of tick %d gave %d", tick, got)
+		}
+	}
+}
+
+func TestSqrtPriceMath(t *testing.T) {
+	one := encodePriceSqrt(1, 1)
+	liquidity := expandTo18Decimals(1)
+	tenth := new(big.Int).Div(liquidity, big.NewInt(10))
+
+	check := func(name string, got *big.Int, err error, want string) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		if got.Cmp(bigString(want)) != 0 {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+	}
+
+	got, err := getNextSqrtPriceFromInput(one, liquidity, tenth, false)
+	check("input of 0.1 token1", got, err, "87150978765690771352898345369")
+	got, err = getNextSqrtPriceFromInput(one, liquidity, tenth, true)
+	check("input of 0.1 token0", got, err, "72025602285694852357767227579")
+	got, err = getNextSqrtPriceFromInput(one, expandTo18Decimals(10), new(big.Int).Lsh(big.NewInt(1), 100), true)
+	check("input above uint96", got, err, "624999999995069620")
+	got, err = getNextSqrtPriceFromInput(one, big.NewInt(1), new(big.Int).Rsh(maxUint256, 1), true)
+	check("input of half uint256", got, err, "1")
+
+	got, err = getNextSqrtPriceFromOutput(one, liquidity, tenth, true)
+	check("output of 0.1 token1", got, err, "71305346262837903834189555302")
+	got, err = getNextSqrtPriceFromOutput(one, liquidity, tenth, false)
+	check("output of 0.1 token0", got, err, "88031291682515930659493278152")
+	if _, err := getNextSqrtPriceFromOutput(one, big.NewInt(1), big.NewInt(4), false); err != ErrInsufficientLiquidity {
+		t.Errorf("Expected ErrInsufficientLiquidity for output exceeding reserves, got %v", err)
+	}
+
+	upper := encodePriceSqrt(121, 100)
+	check("amount0 rounded up", getAmount0Delta(one, upper, liquidity, true), nil, "90909090909090910")
+	check("amount0 rounded down", getAmount0Delta(one, upper, liquidity, false), nil, "90909090909090909")
+	check("amount1 rounded up", getAmount1Delta(one, upper, liquidity, true), nil, "100000000000000000")
+	check("amount1 rounded down", getAmount1Delta(one, upper, liquidity, false), nil, "99999999999999999")
+
+	// Swap computation from the v3 suite
+	sqrtP := bigString("1025574284609383690408304870162715216695788925244")
+	liq := bigString("50015962439936049619261659728067971248")
+	sqrtQ, err := getNextSqrtPriceFromInput(sqrtP, liq, big.NewInt(406), true)
+	check("swap computation", sqrtQ, err, "1025574284609383582644711336373707553698163132913")
+	check("swap computation amount0", getAmount0Delta(sqrtQ, sqrtP, liq, true), nil, "406")
+}
+
+func TestComputeSwapStep(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   *big.Int
+		target    *big.Int
+		liquidity *big.Int
+		remaining *big.Int
+		fee       uint24
+		next      string // empty: the target
+		amountIn  string
+		amountOut string
+		feeAmount string
+	}{
+		{
+			name:    "exact in capped at target, one for zero",
+			current: encodePriceSqrt(1, 1), target: encodePriceSqrt(101, 100),
+			liquidity: expandTo18Decimals(2), remaining: expandTo18Decimals(1), fee: 600,
+			amountIn: "9975124224178055", amountOut: "9925619580021728", feeAmount: "5988667735148",
+		},
+		{
+			name:    "exact out capped at target, one for zero",
+			current: encodePriceSqrt(1, 1), target: encodePriceSqrt(101, 100),
+			liquidity: expandTo18Decimals(2), remaining: new(big.Int).Neg(expandTo18Decimals(1)), fee: 600,
+			amountIn: "9975124224178055", amountOut: "9925619580021728", feeAmount: "5988667735148",
+		},
+		{
+			name:    "exact in fully spent, one for zero",
+			current: encodePriceSqrt(1, 1), target: encodePriceSqrt(1000, 100),
+			liquidity: expandTo18Decimals(2), remaining: expandTo18Decimals(1), fee: 600,
+			next:     "118818475322642227089037862318",
+			amountIn: "999400000000000000", amountOut: "666399946655997866", feeAmount: "600000000000000",
+		},
+		{
+			name:    "exact out fully received, one for zero",
+			current: encodePriceSqrt(1, 1), target: encodePriceSqrt(10000, 100),
+			liquidity: expandTo18Decimals(2), remaining: new(big.Int).Neg(expandTo18Decimals(1)), fee: 600,
+			next:     "158456325028528675187087900672",
+			amountIn: "2000000000000000000", amountOut: "1000000000000000000", feeAmount: "1200720432259356",
+		},
+		{
+			name:      "amount out capped at the desired amount",
+			current:   bigString("417332158212080721273783715441582"),
+			target:    bigString("1452870262520218020823638996"),
+			liquidity: bigString("159344665391607089467575320103"), remaining: big.NewInt(-1), fee: 1,
+			next:     "417332158212080721273783715441581",
+			amountIn: "1", amountOut: "1", feeAmount: "1",
+		},
+		{
+			name:    "target price of 1 uses partial input",
+			current: big.NewInt(2), target: big.NewInt(1),
+			liquidity: big.NewInt(1), remaining: bigString("3915081100057732413702495386755767"), fee: 1,
+			amountIn: "39614081257132168796771975168", amountOut: "0", feeAmount: "39614120871253040049813",
+		},
+		{
+			name:    "entire input taken as fee",
+			current: big.NewInt(2413), target: bigString("79887613182836312"),
+			liquidity: bigString("1985041575832132834610021537970"), remaining: big.NewInt(10), fee: 1872,
+			next:     "2413",
+			amountIn: "0", amountOut: "0", feeAmount: "10",
+		},
+		{
+			name:    "insufficient liquidity, zero for one exact out",
+			current: bigString("20282409603651670423947251286016"), target: bigString("22310650564016837466341976414617"),
+			liquidity: big.NewInt(1024), remaining: big.NewInt(-4), fee: 3000,
+			amountIn: "26215", amountOut: "0", feeAmount: "79",
+		},
+		{
+			name:    "insufficient liquidity, one for zero exact out",
+			current: bigString("20282409603651670423947251286016"), target: bigString("18254168643286503381552526157414"),
+			liquidity: big.NewInt(1024), remaining: big.NewInt(-263000), fee: 3000,
+			amountIn: "1", amountOut: "26214", feeAmount: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		next, amountIn, amountOut, feeAmount, err := computeSwapStep(tt.current, tt.target, tt.liquidity, tt.remaining, tt.fee)
+		if err != nil {
+			t.Fatalf("%s: computeSwapStep failed: %v", tt.name, err)
+		}
+		wantNext := tt.target
+		if tt.next != "" {
+			wantNext = bigString(tt.next)
+		}
+		if next.Cmp(wantNext) != 0 {
+			t.Errorf("%s: next price %s, want %s", tt.name, next, wantNext)
+		}
+		if amountIn.Cmp(bigString(tt.amountIn)) != 0 {
+			t.Errorf("%s: amountIn %s, want %s", tt.name, amountIn, tt.amountIn)
+		}
+		if amountOut.Cmp(bigString(tt.amountOut)) != 0 {
+			t.Errorf("%s: amountOut %s, want %s", tt.name, amountOut, tt.amountOut)
+		}
+		if feeAmount.Cmp(bigString(tt.feeAmount)) != 0 {
+			t.Errorf("%s: feeAmount %s, want %s", tt.name, feeAmount, tt.feeAmount)
+		}
+	}
+}
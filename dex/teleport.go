@@ -4,30 +4,56 @@
 package dex
 
 import (
+	"encoding/binary"
 	"errors"
 	"math/big"
 	"sync"
 	"time"
 
+	"github.com/luxfi/crypto/bls"
 	"github.com/luxfi/geth/common"
 	"github.com/zeebo/blake3"
 )
 
-// TeleportBridge manages cross-chain transfers
+// Warp quorum: signers must hold at least 67% of the source validator weight
+const (
+	WarpQuorumNumerator   uint64 = 67
+	WarpQuorumDenominator uint64 = 100
+
+	// TeleportPayloadSize is the encoded size of a teleport Warp payload:
+	// id(32) | srcChain(32) | dstChain(32) | sender(32) | recipient(32) |
+	// token(32) | amount(32), each word left-padded
+	TeleportPayloadSize = 7 * 32
+)
+
+var teleportAddr = common.HexToAddress(TeleportAddress)
+
+// Storage key prefixes for teleport state. Completed teleports are keyed by
+// teleport ID. A validator set is keyed by source chain ID, holding its
+// size, and each validator by source chain ID || index (4 bytes) || field.
+var (
+	teleportCompletedPrefix = []byte("tport/done") // Completed teleports (replay protection)
+	teleportValidatorPrefix = []byte("tport/val")  // Warp validator sets per source chain
+)
+
+// TeleportBridge manages cross-chain transfers for the chain it runs on.
+// Completed teleports and the Warp validator sets are kept in StateDB.
 // Address: 0x0440
 type TeleportBridge struct {
+	// NetworkID is the network Warp messages are signed for, and ChainID
+	// the Teleport chain ID of the local chain
+	NetworkID uint32
+	ChainID   uint32
+
 	// Pending teleports indexed by ID
 	PendingTeleports map[[32]byte]*TeleportRequest
 
-	// Completed teleports (for replay protection)
-	CompletedTeleports map[[32]byte]bool
-
 	// Supported tokens per chain
 	SupportedTokens map[uint32]map[common.Address]*BridgedToken
 
 	// Bridge operators (for MPC signing)
 	Operators []common.Address
-	Threshold uint32 // Required signatures
+	Threshold uint32 // Minimum number of Warp signers
 
 	// Fee configuration
 	FeeRate uint32 // Basis points
@@ -58,13 +84,27 @@ type BridgedToken struct {
 	TotalMinted   *big.Int // Total minted (for wrapped tokens)
 }
 
+// WarpValidator is a source-chain validator allowed to sign Warp messages
+type WarpValidator struct {
+	PublicKey *bls.PublicKey
+	Weight    uint64
+}
+
 // OmnichainRouter handles multi-chain liquidity routing
 // Address: 0x0441
 type OmnichainRouter struct {
-	Bridge *TeleportBridge
-	Routes map[uint32]map[uint32]*Route // srcChain -> dstChain -> Route
-	Pools  map[uint32]*ChainPool        // chainID -> pool
-	mu     sync.RWMutex
+	Bridge      *TeleportBridge              // Bridge of the source chain
+	DestBridges map[uint32]*TeleportBridge   // chainID -> bridge completing arrivals
+	Routes      map[uint32]map[uint32]*Route // srcChain -> dstChain -> Route
+	Pools       map[uint32]*ChainPool        // chainID -> pool
+
+	// Omnichain swaps (see omnichain.go)
+	Swapper    HopSwapper                   // Executes local swap hops
+	Escrow     RouteEscrow                  // Holds route funds and pays refunds
+	Executions map[[32]byte]*RouteExecution // routeID -> execution state
+	routeNonce uint64
+
+	mu sync.RWMutex
 }
 
 // Route represents a path between two chains
@@ -85,20 +125,22 @@ type ChainPool struct {
 	TotalValueUSD *big.Int
 }
 
-// NewTeleportBridge creates a new cross-chain bridge
-func NewTeleportBridge(threshold uint32) *TeleportBridge {
+// NewTeleportBridge creates a new cross-chain bridge for chainID on
+// networkID
+func NewTeleportBridge(networkID, chainID, threshold uint32) *TeleportBridge {
 	return &TeleportBridge{
-		PendingTeleports:   make(map[[32]byte]*TeleportRequest),
-		CompletedTeleports: make(map[[32]byte]bool),
-		SupportedTokens:    make(map[uint32]map[common.Address]*BridgedToken),
-		Operators:          make([]common.Address, 0),
-		Threshold:          threshold,
-		FeeRate:            30,                                                   // 0.3%
-		MinFee:             big.NewInt(1e15),                                     // 0.001 tokens
-		MaxFee:             new(big.Int).Mul(big.NewInt(1e10), big.NewInt(1e10)), // 100 tokens (1e20)
-		Liquidity:          make(map[uint32]map[common.Address]*big.Int),
-		TotalBridged:       make(map[common.Address]*big.Int),
-		TotalFees:          big.NewInt(0),
+		NetworkID:        networkID,
+		ChainID:          chainID,
+		PendingTeleports: make(map[[32]byte]*TeleportRequest),
+		SupportedTokens:  make(map[uint32]map[common.Address]*BridgedToken),
+		Operators:        make([]common.Address, 0),
+		Threshold:        threshold,
+		FeeRate:          30,                                                   // 0.3%
+		MinFee:           big.NewInt(1e15),                                     // 0.001 tokens
+		MaxFee:           new(big.Int).Mul(big.NewInt(1e10), big.NewInt(1e10)), // 100 tokens (1e20)
+		Liquidity:        make(map[uint32]map[common.Address]*big.Int),
+		TotalBridged:     make(map[common.Address]*big.Int),
+		TotalFees:        big.NewInt(0),
 	}
 }
 
@@ -114,8 +156,8 @@ func (tb *TeleportBridge) InitiateTeleport(
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
-	// Validate destination chain
-	if !tb.isChainSupported(destChain) {
+	// Teleports leave the local chain for another supported chain
+	if sourceChain != tb.ChainID || destChain == tb.ChainID || !tb.isChainSupported(destChain) {
 		return nil, ErrInvalidChainID
 	}
 
@@ -146,9 +188,6 @@ func (tb *TeleportBridge) InitiateTeleport(
 	if _, exists := tb.PendingTeleports[teleportID]; exists {
 		return nil, ErrDuplicateTeleportID
 	}
-	if tb.CompletedTeleports[teleportID] {
-		return nil, ErrDuplicateTeleportID
-	}
 
 	request := &TeleportRequest{
 		TeleportID:  teleportID,
@@ -173,64 +212,99 @@ func (tb *TeleportBridge) InitiateTeleport(
 	return request, nil
 }
 
-// CompleteTeleport completes a cross-chain transfer (called on destination chain)
-func (tb *TeleportBridge) CompleteTeleport(
-	teleportID [32]byte,
-	warpMessage []byte, // Warp message with signatures
-	signatures [][]byte,
-) error {
+// CompleteTeleport completes a cross-chain transfer to the local chain. The
+// message must be for this network and carry a BLS aggregate signature from
+// source chain validators holding a Warp quorum. Locked liquidity of the
+// destination token is released first; otherwise wrapped supply is minted.
+// Each teleport ID completes at most once.
+func (tb *TeleportBridge) CompleteTeleport(stateDB StateDB, message *WarpMessage) (*TeleportRequest, error) {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
-	request := tb.PendingTeleports[teleportID]
-	if request == nil {
-		return ErrTeleportNotFound
+	if message == nil || message.NetworkID != tb.NetworkID {
+		return nil, ErrInvalidWarpMessage
+	}
+	request, err := DecodeTeleportPayload(message.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if message.SourceChainID != WarpChainID(request.SourceChain) {
+		return nil, ErrInvalidWarpMessage
+	}
+	if request.DestChain != tb.ChainID {
+		return nil, ErrInvalidChainID
 	}
 
-	if request.Status != TeleportBurned {
-		return ErrInvalidTeleportState
+	// Replay protection
+	completedKey := makeStorageKey(teleportCompletedPrefix, request.TeleportID[:])
+	if stateDB.GetState(teleportAddr, completedKey) != (common.Hash{}) {
+		return nil, ErrDuplicateTeleportID
 	}
 
-	// Verify signatures (simplified - in production use Warp verification)
-	if uint32(len(signatures)) < tb.Threshold {
-		return ErrInsufficientSignatures
+	if err := tb.verifyWarpMessage(stateDB, message); err != nil {
+		return nil, err
 	}
+	request.Status = TeleportValidated
 
-	// Verify warp message (in production, call Warp precompile)
-	if !tb.verifyWarpMessage(warpMessage, teleportID) {
-		return ErrInvalidWarpSignature
+	tokenConfig := tb.getTokenConfig(request.DestChain, request.Token)
+	if tokenConfig == nil || tokenConfig.IsPaused {
+		return nil, ErrTokenNotSupported
+	}
+	if tokenConfig.TotalLocked.Cmp(request.Amount) >= 0 {
+		tokenConfig.TotalLocked.Sub(tokenConfig.TotalLocked, request.Amount)
+	} else {
+		tokenConfig.TotalMinted.Add(tokenConfig.TotalMinted, request.Amount)
 	}
 
-	// Mark as validated
-	request.Status = TeleportValidated
+	if tb.TotalBridged[request.Token] == nil {
+		tb.TotalBridged[request.Token] = big.NewInt(0)
+	}
+	tb.TotalBridged[request.Token].Add(tb.TotalBridged[request.Token], request.Amount)
 
-	// In production, this would trigger minting on dest chain
 	request.Status = TeleportMinted
-	tb.CompletedTeleports[teleportID] = true
-	delete(tb.PendingTeleports, teleportID)
+	stateDB.SetState(teleportAddr, completedKey, common.BytesToHash([]byte{1}))
 
-	return nil
+	return request, nil
 }
 
 // BurnForTeleport burns tokens on source chain (called after InitiateTeleport)
-func (tb *TeleportBridge) BurnForTeleport(teleportID [32]byte) error {
+// and returns the unsigned Warp message for source validators to sign.
+// Wrapped supply the bridge minted earlier is burned and released from the
+// lock InitiateTeleport took; the rest is native and stays locked until it
+// is teleported back.
+func (tb *TeleportBridge) BurnForTeleport(teleportID [32]byte) (*WarpMessage, error) {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
 	request := tb.PendingTeleports[teleportID]
 	if request == nil {
-		return ErrTeleportNotFound
+		return nil, ErrTeleportNotFound
 	}
 
 	if request.Status != TeleportPending {
-		return ErrInvalidTeleportState
+		return nil, ErrInvalidTeleportState
 	}
 
-	// Burn tokens (in production, interact with ERC20)
-	// This would be verified by Warp message
+	tokenConfig := tb.getTokenConfig(request.SourceChain, request.Token)
+	if tokenConfig == nil {
+		return nil, ErrTokenNotSupported
+	}
+	if tokenConfig.TotalLocked.Cmp(request.Amount) < 0 {
+		return nil, ErrInvalidTeleportState
+	}
+	burned := new(big.Int).Set(request.Amount)
+	if tokenConfig.TotalMinted.Cmp(burned) < 0 {
+		burned.Set(tokenConfig.TotalMinted)
+	}
+	tokenConfig.TotalMinted.Sub(tokenConfig.TotalMinted, burned)
+	tokenConfig.TotalLocked.Sub(tokenConfig.TotalLocked, burned)
 
 	request.Status = TeleportBurned
-	return nil
+	return &WarpMessage{
+		NetworkID:     tb.NetworkID,
+		SourceChainID: WarpChainID(request.SourceChain),
+		Payload:       EncodeTeleportPayload(request, tokenConfig.RemoteAddress),
+	}, nil
 }
 
 // CancelTeleport cancels a pending teleport (only by sender, before burn)
@@ -262,11 +336,11 @@ func (tb *TeleportBridge) CancelTeleport(sender common.Address, teleportID [32]b
 }
 
 // GetTeleportStatus returns the status of a teleport
-func (tb *TeleportBridge) GetTeleportStatus(teleportID [32]byte) (TeleportStatus, error) {
+func (tb *TeleportBridge) GetTeleportStatus(stateDB StateDB, teleportID [32]byte) (TeleportStatus, error) {
 	tb.mu.RLock()
 	defer tb.mu.RUnlock()
 
-	if tb.CompletedTeleports[teleportID] {
+	if stateDB.GetState(teleportAddr, makeStorageKey(teleportCompletedPrefix, teleportID[:])) != (common.Hash{}) {
 		return TeleportMinted, nil
 	}
 
@@ -278,6 +352,31 @@ func (tb *TeleportBridge) GetTeleportStatus(teleportID [32]byte) (TeleportStatus
 	return request.Status, nil
 }
 
+// QuoteTeleport returns the amount delivered for a transfer of amount after
+// bridge fees
+func (tb *TeleportBridge) QuoteTeleport(amount *big.Int) *big.Int {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+
+	fee := tb.calculateFee(amount)
+	if fee.Cmp(amount) >= 0 {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Sub(amount, fee)
+}
+
+// RemoteToken returns the destination-side address of a bridged token
+func (tb *TeleportBridge) RemoteToken(chainID uint32, token common.Address) (common.Address, error) {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+
+	config := tb.getTokenConfig(chainID, token)
+	if config == nil {
+		return common.Address{}, ErrTokenNotSupported
+	}
+	return config.RemoteAddress, nil
+}
+
 // AddSupportedToken adds a token to the bridge
 func (tb *TeleportBridge) AddSupportedToken(
 	chainID uint32,
@@ -339,6 +438,83 @@ func (tb *TeleportBridge) UnpauseToken(chainID uint32, token common.Address) err
 	return nil
 }
 
+// SetWarpValidators replaces the validator set trusted to sign Warp messages
+// from sourceChainID. publicKeys are compressed BLS keys; their order defines
+// the message BitSet indices.
+func (tb *TeleportBridge) SetWarpValidators(stateDB StateDB, sourceChainID [32]byte, publicKeys [][]byte, weights []uint64) error {
+	if len(publicKeys) == 0 || len(publicKeys) != len(weights) {
+		return ErrInvalidParameter
+	}
+
+	var totalWeight uint64
+	for i, keyBytes := range publicKeys {
+		if len(keyBytes) != bls.PublicKeyLen {
+			return ErrInvalidParameter
+		}
+		if _, err := bls.PublicKeyFromCompressedBytes(keyBytes); err != nil {
+			return ErrInvalidParameter
+		}
+		if weights[i] == 0 || totalWeight+weights[i] < totalWeight {
+			return ErrInvalidParameter
+		}
+		totalWeight += weights[i]
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	// Clear the validators of a larger previous set
+	countKey := makeStorageKey(teleportValidatorPrefix, sourceChainID[:])
+	previous := stateDB.GetState(teleportAddr, countKey)
+	for i := uint32(len(publicKeys)); i < binary.BigEndian.Uint32(previous[28:]); i++ {
+		for _, field := range []string{"key0", "key1", "weight"} {
+			stateDB.SetState(teleportAddr, warpValidatorStorageKey(sourceChainID, i, field), common.Hash{})
+		}
+	}
+
+	for i, keyBytes := range publicKeys {
+		var key1, weight common.Hash
+		copy(key1[:], keyBytes[32:])
+		binary.BigEndian.PutUint64(weight[24:], weights[i])
+		stateDB.SetState(teleportAddr, warpValidatorStorageKey(sourceChainID, uint32(i), "key0"), common.BytesToHash(keyBytes[:32]))
+		stateDB.SetState(teleportAddr, warpValidatorStorageKey(sourceChainID, uint32(i), "key1"), key1)
+		stateDB.SetState(teleportAddr, warpValidatorStorageKey(sourceChainID, uint32(i), "weight"), weight)
+	}
+	var count common.Hash
+	binary.BigEndian.PutUint32(count[28:], uint32(len(publicKeys)))
+	stateDB.SetState(teleportAddr, countKey, count)
+	return nil
+}
+
+// warpValidatorStorageKey returns the storage key of a field of a source
+// chain's validator
+func warpValidatorStorageKey(sourceChainID [32]byte, index uint32, field string) common.Hash {
+	id := make([]byte, 0, 32+4+len(field))
+	id = append(id, sourceChainID[:]...)
+	id = binary.BigEndian.AppendUint32(id, index)
+	id = append(id, field...)
+	return makeStorageKey(teleportValidatorPrefix, id)
+}
+
+// warpValidators loads the validator set of a source chain, in BitSet order
+func (tb *TeleportBridge) warpValidators(stateDB StateDB, sourceChainID [32]byte) ([]*WarpValidator, error) {
+	count := stateDB.GetState(teleportAddr, makeStorageKey(teleportValidatorPrefix, sourceChainID[:]))
+	validators := make([]*WarpValidator, binary.BigEndian.Uint32(count[28:]))
+	for i := range validators {
+		key0 := stateDB.GetState(teleportAddr, warpValidatorStorageKey(sourceChainID, uint32(i), "key0"))
+		key1 := stateDB.GetState(teleportAddr, warpValidatorStorageKey(sourceChainID, uint32(i), "key1"))
+		weight := stateDB.GetState(teleportAddr, warpValidatorStorageKey(sourceChainID, uint32(i), "weight"))
+
+		keyBytes := append(key0.Bytes(), key1[:bls.PublicKeyLen-32]...)
+		publicKey, err := bls.PublicKeyFromCompressedBytes(keyBytes)
+		if err != nil {
+			return nil, err
+		}
+		validators[i] = &WarpValidator{PublicKey: publicKey, Weight: binary.BigEndian.Uint64(weight[24:])}
+	}
+	return validators, nil
+}
+
 // AddOperator adds a bridge operator
 func (tb *TeleportBridge) AddOperator(operator common.Address) {
 	tb.mu.Lock()
@@ -401,18 +577,131 @@ func (tb *TeleportBridge) generateTeleportID(
 	return id
 }
 
-func (tb *TeleportBridge) verifyWarpMessage(message []byte, teleportID [32]byte) bool {
-	// In production, this would call the Warp precompile to verify
-	// For now, just check message is non-empty
-	return len(message) > 0
+// verifyWarpMessage checks the aggregate signature against the public keys
+// the BitSet selects from the source chain's validator set. Signers must meet
+// both the Threshold count and the weight quorum.
+func (tb *TeleportBridge) verifyWarpMessage(stateDB StateDB, message *WarpMessage) error {
+	validators, err := tb.warpValidators(stateDB, message.SourceChainID)
+	if err != nil || len(validators) == 0 {
+		return ErrInvalidWarpSignature
+	}
+
+	bitSet := new(big.Int).SetBytes(message.BitSet)
+	if bitSet.BitLen() > len(validators) {
+		return ErrInvalidWarpSignature
+	}
+
+	signers := make([]*bls.PublicKey, 0, len(validators))
+	totalWeight := new(big.Int)
+	signedWeight := new(big.Int)
+	for i, validator := range validators {
+		weight := new(big.Int).SetUint64(validator.Weight)
+		totalWeight.Add(totalWeight, weight)
+		if bitSet.Bit(i) == 1 {
+			signedWeight.Add(signedWeight, weight)
+			signers = append(signers, validator.PublicKey)
+		}
+	}
+
+	if len(signers) == 0 || uint32(len(signers)) < tb.Threshold {
+		return ErrInsufficientSignatures
+	}
+	signedWeight.Mul(signedWeight, new(big.Int).SetUint64(WarpQuorumDenominator))
+	totalWeight.Mul(totalWeight, new(big.Int).SetUint64(WarpQuorumNumerator))
+	if signedWeight.Cmp(totalWeight) < 0 {
+		return ErrInsufficientSignatures
+	}
+
+	aggregateKey, err := bls.AggregatePublicKeys(signers)
+	if err != nil {
+		return ErrInvalidWarpSignature
+	}
+	signature, err := bls.SignatureFromBytes(message.Signatures)
+	if err != nil {
+		return ErrInvalidWarpSignature
+	}
+	if !bls.Verify(aggregateKey, signature, message.UnsignedBytes()) {
+		return ErrInvalidWarpSignature
+	}
+	return nil
+}
+
+// =========================================================================
+// Warp payload encoding
+// =========================================================================
+
+// WarpChainID converts a Teleport chain ID to the 32-byte Lux format used
+// as the Warp source chain ID
+func WarpChainID(chainID uint32) [32]byte {
+	var id [32]byte
+	binary.BigEndian.PutUint32(id[28:], chainID)
+	return id
+}
+
+// UnsignedBytes returns the bytes validators sign:
+// NetworkID (4 bytes) || SourceChainID || Payload
+func (m *WarpMessage) UnsignedBytes() []byte {
+	out := make([]byte, 0, 4+len(m.SourceChainID)+len(m.Payload))
+	out = binary.BigEndian.AppendUint32(out, m.NetworkID)
+	out = append(out, m.SourceChainID[:]...)
+	return append(out, m.Payload...)
+}
+
+// EncodeTeleportPayload encodes request as a Warp payload. destToken is the
+// token address on the destination chain.
+func EncodeTeleportPayload(request *TeleportRequest, destToken common.Address) []byte {
+	payload := make([]byte, TeleportPayloadSize)
+	copy(payload[0:32], request.TeleportID[:])
+	binary.BigEndian.PutUint32(payload[60:64], request.SourceChain)
+	binary.BigEndian.PutUint32(payload[92:96], request.DestChain)
+	copy(payload[108:128], request.Sender[:])
+	copy(payload[140:160], request.Recipient[:])
+	copy(payload[172:192], destToken[:])
+	request.Amount.FillBytes(payload[192:224])
+	return payload
+}
+
+// DecodeTeleportPayload decodes a Warp payload produced by
+// EncodeTeleportPayload. Token in the result is the destination token.
+func DecodeTeleportPayload(payload []byte) (*TeleportRequest, error) {
+	if len(payload) != TeleportPayloadSize {
+		return nil, ErrInvalidWarpMessage
+	}
+	for _, padding := range [][]byte{
+		payload[32:60], payload[64:92], payload[96:108], payload[128:140], payload[160:172],
+	} {
+		for _, b := range padding {
+			if b != 0 {
+				return nil, ErrInvalidWarpMessage
+			}
+		}
+	}
+
+	request := &TeleportRequest{
+		SourceChain: binary.BigEndian.Uint32(payload[60:64]),
+		DestChain:   binary.BigEndian.Uint32(payload[92:96]),
+		Sender:      common.BytesToAddress(payload[108:128]),
+		Recipient:   common.BytesToAddress(payload[140:160]),
+		Token:       common.BytesToAddress(payload[172:192]),
+		Amount:      new(big.Int).SetBytes(payload[192:224]),
+		Status:      TeleportBurned,
+	}
+	copy(request.TeleportID[:], payload[0:32])
+
+	if request.Amount.Sign() == 0 {
+		return nil, ErrInvalidWarpMessage
+	}
+	return request, nil
 }
 
 // NewOmnichainRouter creates a new multi-chain router
 func NewOmnichainRouter(bridge *TeleportBridge) *OmnichainRouter {
 	return &OmnichainRouter{
-		Bridge: bridge,
-		Routes: make(map[uint32]map[uint32]*Route),
-		Pools:  make(map[uint32]*ChainPool),
+		Bridge:      bridge,
+		DestBridges: make(map[uint32]*TeleportBridge),
+		Routes:      make(map[uint32]map[uint32]*Route),
+		Pools:       make(map[uint32]*ChainPool),
+		Executions:  make(map[[32]byte]*RouteExecution),
 	}
 }
 
@@ -447,6 +736,11 @@ func (or *OmnichainRouter) GetBestRoute(
 	or.mu.RLock()
 	defer or.mu.RUnlock()
 
+	return or.bestRoute(srcChain, dstChain, amount)
+}
+
+// bestRoute implements GetBestRoute; the caller must hold or.mu
+func (or *OmnichainRouter) bestRoute(srcChain, dstChain uint32, amount *big.Int) (*Route, error) {
 	// Direct route
 	directRoute := or.getRoute(srcChain, dstChain)
 	if directRoute != nil && directRoute.IsActive {
@@ -488,7 +782,7 @@ func (or *OmnichainRouter) RouteTransfer(
 	or.mu.Lock()
 	defer or.mu.Unlock()
 
-	route, err := or.GetBestRoute(srcChain, dstChain, token, amount)
+	route, err := or.bestRoute(srcChain, dstChain, amount)
 	if err != nil {
 		return nil, err
 	}
@@ -538,6 +832,7 @@ var (
 	ErrTeleportNotFound       = errors.New("teleport not found")
 	ErrInvalidTeleportState   = errors.New("invalid teleport state")
 	ErrInsufficientSignatures = errors.New("insufficient signatures")
+	ErrInvalidWarpMessage     = errors.New("invalid warp message")
 	ErrCannotCancel           = errors.New("cannot cancel teleport in current state")
 	ErrNoRouteFound           = errors.New("no route found")
 )
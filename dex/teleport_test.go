@@ -0,0 +1,207 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/crypto/bls"
+)
+
+// testWarpKey derives the i-th test validator key from a fixed seed
+func testWarpKey(t *testing.T, i int) *bls.SecretKey {
+	t.Helper()
+
+	seed := make([]byte, 32)
+	for j := range seed {
+		seed[j] = byte(i*32 + j + 1)
+	}
+	sk, err := bls.SecretKeyFromSeed(seed)
+	if err != nil {
+		t.Fatalf("SecretKeyFromSeed failed: %v", err)
+	}
+	return sk
+}
+
+// testNetworkID is the network test Warp messages are signed for
+const testNetworkID uint32 = 96369
+
+// setTestWarpValidators registers n equal-weight test validators for chainID
+func setTestWarpValidators(t *testing.T, stateDB StateDB, bridge *TeleportBridge, chainID uint32, n int) {
+	t.Helper()
+
+	keys := make([][]byte, n)
+	weights := make([]uint64, n)
+	for i := range keys {
+		keys[i] = bls.PublicKeyToCompressedBytes(testWarpKey(t, i).PublicKey())
+		weights[i] = 100
+	}
+	if err := bridge.SetWarpValidators(stateDB, WarpChainID(chainID), keys, weights); err != nil {
+		t.Fatalf("SetWarpValidators failed: %v", err)
+	}
+}
+
+// signTestWarp returns a copy of message signed by the given validators
+func signTestWarp(t *testing.T, message *WarpMessage, signers ...int) *WarpMessage {
+	t.Helper()
+
+	sigs := make([]*bls.Signature, 0, len(signers))
+	bitSet := new(big.Int)
+	for _, i := range signers {
+		sig, err := testWarpKey(t, i).Sign(message.UnsignedBytes())
+		if err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+		sigs = append(sigs, sig)
+		bitSet.SetBit(bitSet, i, 1)
+	}
+	aggregate, err := bls.AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatalf("AggregateSignatures failed: %v", err)
+	}
+
+	signed := *message
+	signed.Signatures = bls.SignatureToBytes(aggregate)
+	signed.BitSet = bitSet.Bytes()
+	return &signed
+}
+
+func TestTeleportWarpRoundTrip(t *testing.T) {
+	// Each chain runs its own bridge over its own state
+	luxState, ethState := NewMockStateDB(), NewMockStateDB()
+	lux := NewTeleportBridge(testNetworkID, ChainLux, 2)
+	eth := NewTeleportBridge(testNetworkID, ChainETH, 2)
+	limit := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e9))
+	if err := lux.AddSupportedToken(ChainLux, omniUSDC, omniRemoteUSD, 18, limit, limit, big.NewInt(1)); err != nil {
+		t.Fatalf("AddSupportedToken failed: %v", err)
+	}
+	if err := eth.AddSupportedToken(ChainETH, omniRemoteUSD, omniUSDC, 18, limit, limit, big.NewInt(1)); err != nil {
+		t.Fatalf("AddSupportedToken failed: %v", err)
+	}
+	setTestWarpValidators(t, luxState, lux, ChainETH, 4)
+	setTestWarpValidators(t, ethState, eth, ChainLux, 4)
+
+	amount := new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))
+	if _, err := lux.InitiateTeleport(omniSender, ChainETH, omniRecipient, omniUSDC, amount, ChainETH); !errors.Is(err, ErrInvalidChainID) {
+		t.Errorf("Expected ErrInvalidChainID from another source chain, got: %v", err)
+	}
+	request, err := lux.InitiateTeleport(omniSender, ChainETH, omniRecipient, omniUSDC, amount, ChainLux)
+	if err != nil {
+		t.Fatalf("InitiateTeleport failed: %v", err)
+	}
+	message, err := lux.BurnForTeleport(request.TeleportID)
+	if err != nil {
+		t.Fatalf("BurnForTeleport failed: %v", err)
+	}
+	if status, _ := lux.GetTeleportStatus(luxState, request.TeleportID); status != TeleportBurned {
+		t.Errorf("Expected TeleportBurned, got: %d", status)
+	}
+	origin := lux.getTokenConfig(ChainLux, omniUSDC)
+	if origin.TotalLocked.Cmp(request.Amount) != 0 || origin.TotalMinted.Sign() != 0 {
+		t.Errorf("Expected native %s to stay locked, got: %s locked %s minted", request.Amount, origin.TotalLocked, origin.TotalMinted)
+	}
+
+	decoded, err := DecodeTeleportPayload(message.Payload)
+	if err != nil {
+		t.Fatalf("DecodeTeleportPayload failed: %v", err)
+	}
+	if decoded.TeleportID != request.TeleportID || decoded.Token != omniRemoteUSD || decoded.Amount.Cmp(request.Amount) != 0 {
+		t.Errorf("Payload should carry the teleport ID, destination token and net amount")
+	}
+
+	// One signer is below the signer threshold, two are below the weight quorum
+	if _, err := eth.CompleteTeleport(ethState, signTestWarp(t, message, 0)); !errors.Is(err, ErrInsufficientSignatures) {
+		t.Errorf("Expected ErrInsufficientSignatures below threshold, got: %v", err)
+	}
+	if _, err := eth.CompleteTeleport(ethState, signTestWarp(t, message, 0, 1)); !errors.Is(err, ErrInsufficientSignatures) {
+		t.Errorf("Expected ErrInsufficientSignatures below quorum, got: %v", err)
+	}
+
+	// The bitset must name the validators that actually signed
+	forged := signTestWarp(t, message, 0, 1, 2)
+	forged.BitSet = signTestWarp(t, message, 0, 1, 3).BitSet
+	if _, err := eth.CompleteTeleport(ethState, forged); !errors.Is(err, ErrInvalidWarpSignature) {
+		t.Errorf("Expected ErrInvalidWarpSignature for mismatched bitset, got: %v", err)
+	}
+
+	// A signature does not cover a tampered payload
+	tampered := signTestWarp(t, message, 0, 1, 2)
+	tampered.Payload = append([]byte(nil), tampered.Payload...)
+	tampered.Payload[TeleportPayloadSize-1] ^= 0x01
+	if _, err := eth.CompleteTeleport(ethState, tampered); !errors.Is(err, ErrInvalidWarpSignature) {
+		t.Errorf("Expected ErrInvalidWarpSignature for tampered payload, got: %v", err)
+	}
+
+	// Nor does it cover another network, and messages for another network
+	// or chain are refused
+	other := *message
+	other.NetworkID++
+	if _, err := eth.CompleteTeleport(ethState, signTestWarp(t, &other, 0, 1, 2)); !errors.Is(err, ErrInvalidWarpMessage) {
+		t.Errorf("Expected ErrInvalidWarpMessage for another network, got: %v", err)
+	}
+	renetworked := signTestWarp(t, message, 0, 1, 2)
+	renetworked.NetworkID++
+	eth.NetworkID++
+	if _, err := eth.CompleteTeleport(ethState, renetworked); !errors.Is(err, ErrInvalidWarpSignature) {
+		t.Errorf("Expected ErrInvalidWarpSignature for a message moved to another network, got: %v", err)
+	}
+	eth.NetworkID--
+	if _, err := lux.CompleteTeleport(luxState, signTestWarp(t, message, 0, 1, 2)); !errors.Is(err, ErrInvalidChainID) {
+		t.Errorf("Expected ErrInvalidChainID on the source chain, got: %v", err)
+	}
+
+	signed := signTestWarp(t, message, 0, 1, 2)
+	completed, err := eth.CompleteTeleport(ethState, signed)
+	if err != nil {
+		t.Fatalf("CompleteTeleport failed: %v", err)
+	}
+	if completed.Status != TeleportMinted || completed.Recipient != omniRecipient {
+		t.Errorf("Expected minted teleport to the recipient, got status %d", completed.Status)
+	}
+	if status, _ := eth.GetTeleportStatus(ethState, request.TeleportID); status != TeleportMinted {
+		t.Errorf("Expected TeleportMinted, got: %d", status)
+	}
+	wrapped := eth.getTokenConfig(ChainETH, omniRemoteUSD)
+	if wrapped.TotalMinted.Cmp(request.Amount) != 0 {
+		t.Errorf("Expected %s wrapped supply minted, got: %s", request.Amount, wrapped.TotalMinted)
+	}
+
+	if _, err := eth.CompleteTeleport(ethState, signed); !errors.Is(err, ErrDuplicateTeleportID) {
+		t.Errorf("Expected ErrDuplicateTeleportID on replay, got: %v", err)
+	}
+
+	// Completed teleports and validator sets are read back from state
+	restarted := NewTeleportBridge(testNetworkID, ChainETH, 2)
+	if _, err := restarted.CompleteTeleport(ethState, signed); !errors.Is(err, ErrDuplicateTeleportID) {
+		t.Errorf("Expected ErrDuplicateTeleportID on replay after restart, got: %v", err)
+	}
+	if validators, err := restarted.warpValidators(ethState, WarpChainID(ChainLux)); err != nil || len(validators) != 4 || validators[3].Weight != 100 {
+		t.Errorf("Expected the 4 validators reloaded, got %d (%v)", len(validators), err)
+	}
+
+	// Teleporting back burns the wrapped supply and unlocks the origin side
+	back, err := eth.InitiateTeleport(omniRecipient, ChainLux, omniSender, omniRemoteUSD, request.Amount, ChainETH)
+	if err != nil {
+		t.Fatalf("InitiateTeleport back failed: %v", err)
+	}
+	message, err = eth.BurnForTeleport(back.TeleportID)
+	if err != nil {
+		t.Fatalf("BurnForTeleport back failed: %v", err)
+	}
+	expectedMinted := new(big.Int).Sub(request.Amount, back.Amount)
+	if wrapped.TotalMinted.Cmp(expectedMinted) != 0 || wrapped.TotalLocked.Sign() != 0 {
+		t.Errorf("Expected wrapped supply %s and nothing locked after burn, got: %s minted %s locked",
+			expectedMinted, wrapped.TotalMinted, wrapped.TotalLocked)
+	}
+	if _, err := lux.CompleteTeleport(luxState, signTestWarp(t, message, 1, 2, 3)); err != nil {
+		t.Fatalf("CompleteTeleport back failed: %v", err)
+	}
+	expectedLocked := new(big.Int).Sub(request.Amount, back.Amount)
+	if origin.TotalLocked.Cmp(expectedLocked) != 0 || origin.TotalMinted.Sign() != 0 {
+		t.Errorf("Expected %s left locked and nothing minted on origin, got: %s locked %s minted",
+			expectedLocked, origin.TotalLocked, origin.TotalMinted)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+)
+
+// Tick state
+//
+// Every tick that bounds a position is initialized: it records the liquidity
+// that becomes active when the price crosses it upward (liquidityNet), the
+// total liquidity referencing it (liquidityGross), which keeps the tick
+// initialized until the last position on it is removed, and the fee growth
+// on the other side of it from the current price (feeGrowthOutside).
+// Initialized ticks are marked in a bitmap of 256-tick words, so a swap
+// finds the next one to cross by reading one word at a time and the work of
+// a swap or liquidity change grows with the ticks it touches.
+//
+// Positions may use any tick in range, not only multiples of the pool's
+// tick spacing, so the bitmap is indexed by tick rather than by tick
+// divided by spacing as in v3.
+//
+// Ticks and bitmap words are read and written through StateDB, with no
+// cache in front, so a reverted call leaves nothing behind. They are stored
+// under tickPrefix:
+//
+//	poolId || tick (4 bytes) || field   -> TickInfo field
+//	poolId || "word" || wordPos (2 bytes) -> bitmap word
+
+// TickInfo is the state of an initialized tick
+type TickInfo struct {
+	LiquidityGross        *big.Int // Total liquidity of positions bounded by the tick
+	LiquidityNet          *big.Int // Liquidity added when the price crosses the tick upward
+	FeeGrowthOutside0X128 *big.Int // Currency0 fee growth on the other side of the tick (Q128.128)
+	FeeGrowthOutside1X128 *big.Int // Currency1 fee growth on the other side of the tick (Q128.128)
+}
+
+// newTickInfo returns the state of an uninitialized tick
+func newTickInfo() *TickInfo {
+	return &TickInfo{
+		LiquidityGross:        big.NewInt(0),
+		LiquidityNet:          big.NewInt(0),
+		FeeGrowthOutside0X128: big.NewInt(0),
+		FeeGrowthOutside1X128: big.NewInt(0),
+	}
+}
+
+// tickCrossing is a tick crossed by a swap and the fee growth at the time
+type tickCrossing struct {
+	tick           int24
+	feeGrowth0X128 *big.Int
+	feeGrowth1X128 *big.Int
+}
+
+// tickPosition returns the bitmap word holding tick and its bit in the word
+func tickPosition(tick int24) (int16, uint) {
+	return int16(tick >> 8), uint(tick & 0xff)
+}
+
+// tickStorageKey returns the storage key of a field of a tick
+func tickStorageKey(poolId [32]byte, tick int24, field string) common.Hash {
+	id := make([]byte, 0, 32+4+len(field))
+	id = append(id, poolId[:]...)
+	id = binary.BigEndian.AppendUint32(id, uint32(tick))
+	id = append(id, field...)
+	return makeStorageKey(tickPrefix, id)
+}
+
+// tickWordStorageKey returns the storage key of a bitmap word
+func tickWordStorageKey(poolId [32]byte, wordPos int16) common.Hash {
+	id := make([]byte, 0, 32+4+2)
+	id = append(id, poolId[:]...)
+	id = append(id, "word"...)
+	id = binary.BigEndian.AppendUint16(id, uint16(wordPos))
+	return makeStorageKey(tickPrefix, id)
+}
+
+// signedToHash encodes v in two's complement
+func signedToHash(v *big.Int) common.Hash {
+	var h common.Hash
+	new(big.Int).And(v, maxUint256).FillBytes(h[:])
+	return h
+}
+
+// hashToSigned decodes a two's complement value
+func hashToSigned(h common.Hash) *big.Int {
+	v := new(big.Int).SetBytes(h[:])
+	if h[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return v
+}
+
+// getTick retrieves the state of a tick, uninitialized if absent
+func (pm *PoolManager) getTick(stateDB StateDB, poolId [32]byte, tick int24) *TickInfo {
+	info := newTickInfo()
+	info.LiquidityGross.SetBytes(stateDB.GetState(poolManagerAddr, tickStorageKey(poolId, tick, "gross")).Bytes())
+	info.LiquidityNet = hashToSigned(stateDB.GetState(poolManagerAddr, tickStorageKey(poolId, tick, "net")))
+	info.FeeGrowthOutside0X128.SetBytes(stateDB.GetState(poolManagerAddr, tickStorageKey(poolId, tick, "fg0")).Bytes())
+	info.FeeGrowthOutside1X128.SetBytes(stateDB.GetState(poolManagerAddr, tickStorageKey(poolId, tick, "fg1")).Bytes())
+	return info
+}
+
+// setTick saves the state of a tick
+func (pm *PoolManager) setTick(stateDB StateDB, poolId [32]byte, tick int24, info *TickInfo) {
+	var grossHash, growth0Hash, growth1Hash common.Hash
+	info.LiquidityGross.FillBytes(grossHash[:])
+	info.FeeGrowthOutside0X128.FillBytes(growth0Hash[:])
+	info.FeeGrowthOutside1X128.FillBytes(growth1Hash[:])
+	stateDB.SetState(poolManagerAddr, tickStorageKey(poolId, tick, "gross"), grossHash)
+	stateDB.SetState(poolManagerAddr, tickStorageKey(poolId, tick, "net"), signedToHash(info.LiquidityNet))
+	stateDB.SetState(poolManagerAddr, tickStorageKey(poolId, tick, "fg0"), growth0Hash)
+	stateDB.SetState(poolManagerAddr, tickStorageKey(poolId, tick, "fg1"), growth1Hash)
+}
+
+// updateTick adds liquidityDelta to a position boundary at tick, upper for
+// the position's upper tick, and reports whether the tick flipped between
+// initialized and uninitialized. A tick initialized at or below the current
+// tick counts all fee growth so far as below it.
+func (pm *PoolManager) updateTick(stateDB StateDB, poolId [32]byte, pool *Pool, tick int24, liquidityDelta *big.Int, upper bool) bool {
+	info := pm.getTick(stateDB, poolId, tick)
+
+	grossBefore := info.LiquidityGross
+	grossAfter := new(big.Int).Add(grossBefore, liquidityDelta)
+	flipped := (grossAfter.Sign() == 0) != (grossBefore.Sign() == 0)
+
+	if grossAfter.Sign() == 0 {
+		// The last position on the tick is gone; clear it
+		pm.setTick(stateDB, poolId, tick, newTickInfo())
+		return flipped
+	}
+
+	updated := &TickInfo{
+		LiquidityGross:        grossAfter,
+		LiquidityNet:          new(big.Int).Add(info.LiquidityNet, liquidityDelta),
+		FeeGrowthOutside0X128: new(big.Int).Set(info.FeeGrowthOutside0X128),
+		FeeGrowthOutside1X128: new(big.Int).Set(info.FeeGrowthOutside1X128),
+	}
+	if upper {
+		updated.LiquidityNet.Sub(info.LiquidityNet, liquidityDelta)
+	}
+	if grossBefore.Sign() == 0 && tick <= pool.Tick {
+		updated.FeeGrowthOutside0X128.Set(pool.FeeGrowth0X128)
+		updated.FeeGrowthOutside1X128.Set(pool.FeeGrowth1X128)
+	}
+	pm.setTick(stateDB, poolId, tick, updated)
+	return flipped
+}
+
+// updatePositionTicks records a liquidity change of a position between
+// tickLower and tickUpper at both of its ticks
+func (pm *PoolManager) updatePositionTicks(stateDB StateDB, poolId [32]byte, pool *Pool, tickLower, tickUpper int24, liquidityDelta *big.Int) {
+	if liquidityDelta.Sign() == 0 {
+		return
+	}
+	if pm.updateTick(stateDB, poolId, pool, tickLower, liquidityDelta, false) {
+		pm.flipTick(stateDB, poolId, tickLower)
+	}
+	if pm.updateTick(stateDB, poolId, pool, tickUpper, liquidityDelta, true) {
+		pm.flipTick(stateDB, poolId, tickUpper)
+	}
+}
+
+// feeGrowthInside returns the fee growth per unit of liquidity between
+// tickLower and tickUpper, as v3's Tick.getFeeGrowthInside: the global
+// growth less the growth below tickLower and above tickUpper. Both ticks
+// must be initialized.
+func (pm *PoolManager) feeGrowthInside(stateDB StateDB, poolId [32]byte, pool *Pool, tickLower, tickUpper int24) (*big.Int, *big.Int) {
+	lower := pm.getTick(stateDB, poolId, tickLower)
+	upper := pm.getTick(stateDB, poolId, tickUpper)
+
+	inside := func(global, lowerOutside, upperOutside *big.Int) *big.Int {
+		below := lowerOutside
+		if pool.Tick < tickLower {
+			below = new(big.Int).Sub(global, lowerOutside)
+		}
+		above := upperOutside
+		if pool.Tick >= tickUpper {
+			above = new(big.Int).Sub(global, upperOutside)
+		}
+		growth := new(big.Int).Sub(global, below)
+		growth.Sub(growth, above)
+		return growth.And(growth, maxUint256)
+	}
+	return inside(pool.FeeGrowth0X128, lower.FeeGrowthOutside0X128, upper.FeeGrowthOutside0X128),
+		inside(pool.FeeGrowth1X128, lower.FeeGrowthOutside1X128, upper.FeeGrowthOutside1X128)
+}
+
+// crossTick moves the price across tick: fee growth outside the tick flips
+// to the other side, measured against the global fee growth at the time
+func (pm *PoolManager) crossTick(stateDB StateDB, poolId [32]byte, c tickCrossing) {
+	info := pm.getTick(stateDB, poolId, c.tick)
+	growth0 := new(big.Int).Sub(c.feeGrowth0X128, info.FeeGrowthOutside0X128)
+	growth1 := new(big.Int).Sub(c.feeGrowth1X128, info.FeeGrowthOutside1X128)
+	pm.setTick(stateDB, poolId, c.tick, &TickInfo{
+		LiquidityGross:        info.LiquidityGross,
+		LiquidityNet:          info.LiquidityNet,
+		FeeGrowthOutside0X128: growth0.And(growth0, maxUint256),
+		FeeGrowthOutside1X128: growth1.And(growth1, maxUint256),
+	})
+}
+
+// flipTick toggles tick in the pool's bitmap
+func (pm *PoolManager) flipTick(stateDB StateDB, poolId [32]byte, tick int24) {
+	wordPos, bitPos := tickPosition(tick)
+	word := pm.tickWord(stateDB, poolId, wordPos)
+	word.SetBit(word, int(bitPos), word.Bit(int(bitPos))^1)
+
+	var wordHash common.Hash
+	word.FillBytes(wordHash[:])
+	stateDB.SetState(poolManagerAddr, tickWordStorageKey(poolId, wordPos), wordHash)
+}
+
+// tickWord retrieves a bitmap word of a pool
+func (pm *PoolManager) tickWord(stateDB StateDB, poolId [32]byte, wordPos int16) *big.Int {
+	wordHash := stateDB.GetState(poolManagerAddr, tickWordStorageKey(poolId, wordPos))
+	return new(big.Int).SetBytes(wordHash[:])
+}
+
+// nextInitializedTickWithinOneWord returns the next initialized tick at or
+// below tick when lte is set, or above tick otherwise, searching only the
+// bitmap word of the starting tick. If none is initialized it returns the
+// last tick of the word searched and false.
+func (pm *PoolManager) nextInitializedTickWithinOneWord(stateDB StateDB, poolId [32]byte, tick int24, lte bool) (int24, bool) {
+	if lte {
+		wordPos, bitPos := tickPosition(tick)
+		// All bits at or to the right of bitPos
+		mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bitPos+1), big.NewInt(1))
+		masked := new(big.Int).And(pm.tickWord(stateDB, poolId, wordPos), mask)
+		if masked.Sign() != 0 {
+			return tick - int24(bitPos) + int24(masked.BitLen()-1), true
+		}
+		return tick - int24(bitPos), false
+	}
+
+	// Start from the next tick, as the current one is already crossed
+	wordPos, bitPos := tickPosition(tick + 1)
+	masked := new(big.Int).Rsh(pm.tickWord(stateDB, poolId, wordPos), bitPos)
+	if masked.Sign() != 0 {
+		return tick + 1 + int24(masked.TrailingZeroBits()), true
+	}
+	return tick + 1 + int24(255-bitPos), false
+}
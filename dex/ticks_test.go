@@ -0,0 +1,151 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// TestNextInitializedTickWithinOneWord checks the bitmap search against the
+// v3-core TickBitmap.spec.ts vectors
+func TestNextInitializedTickWithinOneWord(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	poolId := newTestPoolKey().ID()
+	for _, tick := range []int24{-200, -55, -4, 70, 78, 84, 139, 240, 535} {
+		pm.flipTick(stateDB, poolId, tick)
+	}
+
+	tests := []struct {
+		tick        int24
+		lte         bool
+		next        int24
+		initialized bool
+	}{
+		{78, false, 84, true},
+		{-55, false, -4, true},
+		{77, false, 78, true},
+		{-56, false, -55, true},
+		{255, false, 511, false},
+		{383, false, 511, false},
+		{508, false, 511, false},
+		{-257, false, -200, true},
+		{78, true, 78, true},
+		{79, true, 78, true},
+		{258, true, 256, false},
+		{256, true, 256, false},
+		{72, true, 70, true},
+		{-257, true, -512, false},
+		{1023, true, 768, false},
+		{900, true, 768, false},
+	}
+	for _, tt := range tests {
+		next, initialized := pm.nextInitializedTickWithinOneWord(stateDB, poolId, tt.tick, tt.lte)
+		if next != tt.next || initialized != tt.initialized {
+			t.Errorf("From tick %d (lte %v): got (%d, %v), want (%d, %v)", tt.tick, tt.lte, next, initialized, tt.next, tt.initialized)
+		}
+	}
+
+	// Flipping twice clears the tick
+	pm.flipTick(stateDB, poolId, 84)
+	pm.flipTick(stateDB, poolId, 84)
+	if next, _ := pm.nextInitializedTickWithinOneWord(stateDB, poolId, 78, false); next != 84 {
+		t.Errorf("Expected tick 84 initialized after two flips, got next %d", next)
+	}
+	pm.flipTick(stateDB, poolId, 84)
+	if next, _ := pm.nextInitializedTickWithinOneWord(stateDB, poolId, 78, false); next != 139 {
+		t.Errorf("Expected tick 84 cleared, got next %d", next)
+	}
+
+	// Reverting the StateDB reverts the bitmap
+	wordKey := tickWordStorageKey(poolId, 0)
+	word := stateDB.GetState(poolManagerAddr, wordKey)
+	pm.flipTick(stateDB, poolId, 84)
+	stateDB.SetState(poolManagerAddr, wordKey, word)
+	if next, _ := pm.nextInitializedTickWithinOneWord(stateDB, poolId, 78, false); next != 139 {
+		t.Errorf("Expected the reverted flip of tick 84 undone, got next %d", next)
+	}
+}
+
+// TestTickStatePersists checks that ticks and the bitmap are read back from
+// the StateDB by a new PoolManager, and that fee growth outside each tick is
+// set on initialization and flipped when a swap crosses it
+func TestTickStatePersists(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+	poolId := key.ID()
+	caller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	if _, err := pm.Initialize(stateDB, key, encodePriceSqrt(1, 1), nil); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	openLock(pm, caller)
+
+	la := expandTo18Decimals(1)
+	lb := new(big.Int).Div(la, big.NewInt(2))
+	if _, _, err := pm.ModifyLiquidity(stateDB, key, ModifyLiquidityParams{TickLower: -60, TickUpper: 60, LiquidityDelta: la}, nil); err != nil {
+		t.Fatalf("ModifyLiquidity failed: %v", err)
+	}
+	if _, err := pm.Swap(stateDB, key, SwapParams{ZeroForOne: true, AmountSpecified: big.NewInt(1_000_000_000_000_000)}, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	growth := new(big.Int).Set(pm.pools[poolId].FeeGrowth0X128)
+	if growth.Sign() == 0 {
+		t.Fatalf("Expected fee growth from the swap")
+	}
+
+	// Ticks initialized below the price start with all fee growth outside
+	for _, p := range []ModifyLiquidityParams{
+		{TickLower: -600, TickUpper: -60, LiquidityDelta: lb},
+		{TickLower: 60, TickUpper: 600, LiquidityDelta: lb},
+	} {
+		if _, _, err := pm.ModifyLiquidity(stateDB, key, p, nil); err != nil {
+			t.Fatalf("ModifyLiquidity failed: %v", err)
+		}
+	}
+	if outside := pm.getTick(stateDB, poolId, -600).FeeGrowthOutside0X128; outside.Cmp(growth) != 0 {
+		t.Errorf("Expected fee growth outside %s at tick -600, got %s", growth, outside)
+	}
+	for _, tick := range []int24{-60, 60, 600} {
+		if outside := pm.getTick(stateDB, poolId, tick).FeeGrowthOutside0X128; outside.Sign() != 0 {
+			t.Errorf("Expected no fee growth outside tick %d, got %s", tick, outside)
+		}
+	}
+
+	// A new PoolManager reads the same ticks from state
+	restarted := NewPoolManager()
+	for _, tick := range []int24{-600, -60, 60, 600} {
+		want, got := pm.getTick(stateDB, poolId, tick), restarted.getTick(stateDB, poolId, tick)
+		if got.LiquidityGross.Cmp(want.LiquidityGross) != 0 || got.LiquidityNet.Cmp(want.LiquidityNet) != 0 ||
+			got.FeeGrowthOutside0X128.Cmp(want.FeeGrowthOutside0X128) != 0 || got.FeeGrowthOutside1X128.Cmp(want.FeeGrowthOutside1X128) != 0 {
+			t.Errorf("Tick %d reloaded as %+v, want %+v", tick, got, want)
+		}
+	}
+	if net := restarted.getTick(stateDB, poolId, 60).LiquidityNet; net.Cmp(new(big.Int).Neg(lb)) != 0 {
+		t.Errorf("Expected liquidityNet -%s at tick 60, got %s", lb, net)
+	}
+	if next, initialized := restarted.nextInitializedTickWithinOneWord(stateDB, poolId, -1, true); next != -60 || !initialized {
+		t.Errorf("Expected initialized tick -60 below the price, got (%d, %v)", next, initialized)
+	}
+
+	// Swaps through the new PoolManager cross the reloaded ticks
+	openLock(restarted, caller)
+	if _, err := restarted.Swap(stateDB, key, SwapParams{ZeroForOne: true, AmountSpecified: big.NewInt(10_000_000_000_000_000)}, nil); err != nil {
+		t.Fatalf("Swap failed: %v", err)
+	}
+	pool := restarted.pools[poolId]
+	if pool.Tick >= -60 || pool.Liquidity.Cmp(lb) != 0 {
+		t.Errorf("Expected tick below -60 with liquidity %s, got tick %d liquidity %s", lb, pool.Tick, pool.Liquidity)
+	}
+
+	// Crossing -60 moved the fee growth earned above it to its outside
+	outside := NewPoolManager().getTick(stateDB, poolId, -60).FeeGrowthOutside0X128
+	if outside.Cmp(growth) <= 0 || outside.Cmp(pool.FeeGrowth0X128) >= 0 {
+		t.Errorf("Expected fee growth outside tick -60 between %s and %s, got %s", growth, pool.FeeGrowth0X128, outside)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/luxfi/geth/common"
+)
+
+// =========================================================================
+// Non-Standard Token Accounting
+// =========================================================================
+//
+// Flash accounting assumes a transfer of N tokens moves exactly N tokens and
+// that balances only change through transfers. Fee-on-transfer tokens
+// (USDT-style) deliver less than the nominal amount, and rebasing tokens
+// (stETH-style) change every holder's balance in place. Pools mark such
+// currencies at initialization, and settlements for them go through the
+// TokenAdapter:
+//
+//   - Transfers in are measured as the pool manager's balance delta, so
+//     lockers are only credited with what actually arrived.
+//   - Rebasing currencies are accounted in shares of the pool manager's
+//     balance. Deltas, swap amounts and Take amounts for these currencies
+//     are denominated in shares, so a rebase changes the token value of
+//     every share without invalidating any reserve.
+//
+// Behaviors and share supplies are stored next to the pool state:
+//
+//	tokn || currency || "bhv"  -> TokenBehavior (byte 31)
+//	tokn || currency || "shr"  -> total shares of a rebasing currency
+
+// TokenBehavior describes how a currency's transfers and balances behave
+type TokenBehavior uint8
+
+const (
+	TokenStandard      TokenBehavior = iota // Exact transfers, static balances
+	TokenFeeOnTransfer                      // Transfers deliver less than the nominal amount
+	TokenRebasing                           // Balances change without transfers
+)
+
+// TokenFlags marks a pool's non-standard currencies at initialization
+type TokenFlags uint8
+
+const (
+	TokenFlagFeeOnTransfer0 TokenFlags = 1 << iota
+	TokenFlagFeeOnTransfer1
+	TokenFlagRebasing0
+	TokenFlagRebasing1
+)
+
+// behavior returns the behavior flagged for currency0 (index 0) or
+// currency1 (index 1). Rebasing subsumes fee-on-transfer, since rebasing
+// transfers are measured as well.
+func (f TokenFlags) behavior(index int) TokenBehavior {
+	rebasing, feeOnTransfer := TokenFlagRebasing0, TokenFlagFeeOnTransfer0
+	if index == 1 {
+		rebasing, feeOnTransfer = TokenFlagRebasing1, TokenFlagFeeOnTransfer1
+	}
+	switch {
+	case f&rebasing != 0:
+		return TokenRebasing
+	case f&feeOnTransfer != 0:
+		return TokenFeeOnTransfer
+	default:
+		return TokenStandard
+	}
+}
+
+// TokenLedger reads and moves ERC20 balances for the pool manager
+type TokenLedger interface {
+	BalanceOf(stateDB StateDB, token, account common.Address) *big.Int
+	Transfer(stateDB StateDB, token, from, to common.Address, amount *big.Int) error
+}
+
+// TokenAdapter settles non-standard currencies against a TokenLedger.
+// Behaviors and shares live in the pool manager's storage.
+type TokenAdapter struct {
+	mu sync.RWMutex
+
+	// ledger performs measured ERC20 transfers
+	ledger TokenLedger
+}
+
+// NewTokenAdapter creates an adapter with no registered currencies
+func NewTokenAdapter() *TokenAdapter {
+	return &TokenAdapter{}
+}
+
+// tokenStorageKey returns the storage key of a currency's field
+func tokenStorageKey(currency Currency, field string) common.Hash {
+	return makeStorageKey(tokenPrefix, append(currency.Address.Bytes(), field...))
+}
+
+// totalShares loads the shares outstanding for a rebasing currency
+func (ta *TokenAdapter) totalShares(stateDB StateDB, currency Currency) *big.Int {
+	return stateDB.GetState(poolManagerAddr, tokenStorageKey(currency, "shr")).Big()
+}
+
+// setTotalShares stores the shares outstanding for a rebasing currency
+func (ta *TokenAdapter) setTotalShares(stateDB StateDB, currency Currency, total *big.Int) {
+	stateDB.SetState(poolManagerAddr, tokenStorageKey(currency, "shr"), common.BigToHash(total))
+}
+
+// SetLedger sets the ledger used for measured transfers
+func (ta *TokenAdapter) SetLedger(ledger TokenLedger) {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+	ta.ledger = ledger
+}
+
+// BalanceOf returns an account's ERC20 balance through the ledger
+func (ta *TokenAdapter) BalanceOf(stateDB StateDB, token, account common.Address) (*big.Int, error) {
+	ta.mu.RLock()
+	defer ta.mu.RUnlock()
+
+	if ta.ledger == nil {
+		return nil, ErrNoTokenLedger
+	}
+	return ta.ledger.BalanceOf(stateDB, token, account), nil
+}
+
+// Behavior returns the registered behavior of a currency
+func (ta *TokenAdapter) Behavior(stateDB StateDB, currency Currency) TokenBehavior {
+	return TokenBehavior(stateDB.GetState(poolManagerAddr, tokenStorageKey(currency, "bhv"))[31])
+}
+
+// Register records a currency's behavior. A currency keeps one behavior
+// across all pools; native LUX is always standard.
+func (ta *TokenAdapter) Register(stateDB StateDB, currency Currency, behavior TokenBehavior) error {
+	if err := ta.checkBehavior(stateDB, currency, behavior); err != nil {
+		return err
+	}
+	if behavior == TokenStandard {
+		return nil
+	}
+
+	var word common.Hash
+	word[31] = byte(behavior)
+	stateDB.SetState(poolManagerAddr, tokenStorageKey(currency, "bhv"), word)
+	return nil
+}
+
+// checkBehavior verifies behavior can be registered for currency
+func (ta *TokenAdapter) checkBehavior(stateDB StateDB, currency Currency, behavior TokenBehavior) error {
+	if behavior > TokenRebasing || (currency.IsNative() && behavior != TokenStandard) {
+		return ErrInvalidParameter
+	}
+	if existing := ta.Behavior(stateDB, currency); existing != TokenStandard && existing != behavior {
+		return ErrTokenBehaviorMismatch
+	}
+	return nil
+}
+
+// TotalShares returns the shares outstanding for a rebasing currency
+func (ta *TokenAdapter) TotalShares(stateDB StateDB, currency Currency) *big.Int {
+	return ta.totalShares(stateDB, currency)
+}
+
+// SharesToAmount converts shares of a rebasing currency to tokens at the
+// pool manager's current balance
+func (ta *TokenAdapter) SharesToAmount(stateDB StateDB, currency Currency, shares *big.Int) (*big.Int, error) {
+	ta.mu.RLock()
+	defer ta.mu.RUnlock()
+
+	if ta.ledger == nil {
+		return nil, ErrNoTokenLedger
+	}
+	total := ta.totalShares(stateDB, currency)
+	if total.Sign() == 0 {
+		return big.NewInt(0), nil
+	}
+	balance := ta.ledger.BalanceOf(stateDB, currency.Address, poolManagerAddr)
+	amount := new(big.Int).Mul(shares, balance)
+	return amount.Div(amount, total), nil
+}
+
+// TransferIn moves amount from a payer to the pool manager and returns the
+// accounting units to credit: tokens received, or shares minted for
+// rebasing currencies
+func (ta *TokenAdapter) TransferIn(stateDB StateDB, currency Currency, from common.Address, amount *big.Int) (*big.Int, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	if ta.ledger == nil {
+		return nil, ErrNoTokenLedger
+	}
+
+	before := ta.ledger.BalanceOf(stateDB, currency.Address, poolManagerAddr)
+	if err := ta.ledger.Transfer(stateDB, currency.Address, from, poolManagerAddr, amount); err != nil {
+		return nil, err
+	}
+	after := ta.ledger.BalanceOf(stateDB, currency.Address, poolManagerAddr)
+
+	received := new(big.Int).Sub(after, before)
+	if received.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	if ta.Behavior(stateDB, currency) != TokenRebasing {
+		return received, nil
+	}
+
+	// Mint shares at the pre-transfer exchange rate
+	total := ta.totalShares(stateDB, currency)
+	shares := new(big.Int).Set(received)
+	if total.Sign() > 0 && before.Sign() > 0 {
+		shares.Mul(received, total)
+		shares.Div(shares, before)
+	}
+	if shares.Sign() == 0 {
+		return nil, ErrInvalidAmount
+	}
+	ta.setTotalShares(stateDB, currency, total.Add(total, shares))
+	return shares, nil
+}
+
+// TransferOut pays units (tokens, or shares for rebasing currencies) from
+// the pool manager to a recipient and returns the tokens sent
+func (ta *TokenAdapter) TransferOut(stateDB StateDB, currency Currency, to common.Address, units *big.Int) (*big.Int, error) {
+	if units == nil || units.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	if ta.ledger == nil {
+		return nil, ErrNoTokenLedger
+	}
+
+	amount := units
+	if ta.Behavior(stateDB, currency) == TokenRebasing {
+		total := ta.totalShares(stateDB, currency)
+		if total.Cmp(units) < 0 {
+			return nil, ErrInsufficientShares
+		}
+		balance := ta.ledger.BalanceOf(stateDB, currency.Address, poolManagerAddr)
+		amount = new(big.Int).Mul(units, balance)
+		amount.Div(amount, total)
+		ta.setTotalShares(stateDB, currency, total.Sub(total, units))
+	}
+
+	if err := ta.ledger.Transfer(stateDB, currency.Address, poolManagerAddr, to, amount); err != nil {
+		return nil, err
+	}
+	return new(big.Int).Set(amount), nil
+}
+
+// =========================================================================
+// PoolManager integration
+// =========================================================================
+
+// Tokens returns the pool manager's token adapter
+func (pm *PoolManager) Tokens() *TokenAdapter {
+	return pm.tokens
+}
+
+// SetTokenLedger sets the ledger used to settle non-standard currencies
+func (pm *PoolManager) SetTokenLedger(ledger TokenLedger) {
+	pm.tokens.SetLedger(ledger)
+}
+
+// registerTokenFlags records the behaviors flagged for a pool's currencies.
+// A pool must flag a currency exactly as earlier pools did.
+func (pm *PoolManager) registerTokenFlags(stateDB StateDB, key PoolKey, flags TokenFlags) error {
+	if flags > TokenFlagFeeOnTransfer0|TokenFlagFeeOnTransfer1|TokenFlagRebasing0|TokenFlagRebasing1 {
+		return ErrInvalidParameter
+	}
+
+	// Check both currencies first so a rejected pool registers nothing
+	err := pm.tokens.checkBehavior(stateDB, key.Currency0, flags.behavior(0))
+	if err == nil {
+		err = pm.tokens.checkBehavior(stateDB, key.Currency1, flags.behavior(1))
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := pm.tokens.Register(stateDB, key.Currency0, flags.behavior(0)); err != nil {
+		return err
+	}
+	return pm.tokens.Register(stateDB, key.Currency1, flags.behavior(1))
+}
+
+// isNonStandard reports whether currency settles through the token adapter
+func (pm *PoolManager) isNonStandard(stateDB StateDB, currency Currency) bool {
+	return !currency.IsNative() && pm.tokens.Behavior(stateDB, currency) != TokenStandard
+}
@@ -0,0 +1,189 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// mockTokenLedger is an in-memory ERC20 ledger. Tokens listed in feeBps burn
+// that share of every transfer; rebase scales every balance of a token.
+type mockTokenLedger struct {
+	balances map[common.Address]map[common.Address]*big.Int
+	feeBps   map[common.Address]int64
+}
+
+func newMockTokenLedger() *mockTokenLedger {
+	return &mockTokenLedger{
+		balances: make(map[common.Address]map[common.Address]*big.Int),
+		feeBps:   make(map[common.Address]int64),
+	}
+}
+
+func (l *mockTokenLedger) BalanceOf(_ StateDB, token, account common.Address) *big.Int {
+	if bal, ok := l.balances[token][account]; ok {
+		return new(big.Int).Set(bal)
+	}
+	return big.NewInt(0)
+}
+
+func (l *mockTokenLedger) Transfer(_ StateDB, token, from, to common.Address, amount *big.Int) error {
+	if l.BalanceOf(nil, token, from).Cmp(amount) < 0 {
+		return errors.New("transfer amount exceeds balance")
+	}
+	fee := new(big.Int).Mul(amount, big.NewInt(l.feeBps[token]))
+	fee.Div(fee, big.NewInt(10_000))
+
+	l.mint(token, from, new(big.Int).Neg(amount))
+	l.mint(token, to, new(big.Int).Sub(amount, fee))
+	return nil
+}
+
+func (l *mockTokenLedger) mint(token, account common.Address, amount *big.Int) {
+	if l.balances[token] == nil {
+		l.balances[token] = make(map[common.Address]*big.Int)
+	}
+	l.balances[token][account] = new(big.Int).Add(l.BalanceOf(nil, token, account), amount)
+}
+
+func (l *mockTokenLedger) rebase(token common.Address, num, den int64) {
+	for _, bal := range l.balances[token] {
+		bal.Mul(bal, big.NewInt(num))
+		bal.Div(bal, big.NewInt(den))
+	}
+}
+
+// setupTokenPool initializes the test pool with currency1 flagged and opens
+// a lock for testTrader
+func setupTokenPool(t *testing.T, flags TokenFlags) (*PoolManager, *MockStateDB, *mockTokenLedger, PoolKey) {
+	t.Helper()
+
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+	ledger := newMockTokenLedger()
+	pm.SetTokenLedger(ledger)
+
+	sqrtPriceX96 := new(big.Int).Lsh(big.NewInt(1), 96)
+	if _, err := pm.InitializeWithTokenFlags(stateDB, key, sqrtPriceX96, flags, nil); err != nil {
+		t.Fatalf("InitializeWithTokenFlags failed: %v", err)
+	}
+
+	pm.lockers = append(pm.lockers, testTrader)
+	pm.currentDeltas[testTrader] = make(map[Currency]*big.Int)
+	return pm, stateDB, ledger, key
+}
+
+func TestFeeOnTransferSettlement(t *testing.T) {
+	pm, stateDB, ledger, key := setupTokenPool(t, TokenFlagFeeOnTransfer1)
+	token := key.Currency1.Address
+	ledger.feeBps[token] = 100 // 1%
+	ledger.mint(token, testTrader, big.NewInt(10_000))
+
+	if pm.Tokens().Behavior(stateDB, key.Currency1) != TokenFeeOnTransfer {
+		t.Fatal("Expected currency1 registered as fee-on-transfer")
+	}
+	if pool, _ := pm.GetPool(stateDB, key); pool.TokenFlags != TokenFlagFeeOnTransfer1 {
+		t.Errorf("Expected pool token flags %d, got %d", TokenFlagFeeOnTransfer1, pool.TokenFlags)
+	}
+
+	// Locker is credited with what arrived, not the nominal amount
+	if err := pm.Settle(stateDB, key.Currency1, big.NewInt(1_000)); err != nil {
+		t.Fatalf("Settle failed: %v", err)
+	}
+	if delta := pm.GetDelta(testTrader, key.Currency1); delta.Cmp(big.NewInt(-990)) != 0 {
+		t.Errorf("Expected delta -990, got %s", delta)
+	}
+	if bal := ledger.BalanceOf(nil, token, poolManagerAddr); bal.Cmp(big.NewInt(990)) != 0 {
+		t.Errorf("Expected pool balance 990, got %s", bal)
+	}
+
+	if err := pm.Take(stateDB, key.Currency1, testTrader, big.NewInt(990)); err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if delta := pm.GetDelta(testTrader, key.Currency1); delta.Sign() != 0 {
+		t.Errorf("Expected settled delta, got %s", delta)
+	}
+}
+
+func TestRebasingShareAccounting(t *testing.T) {
+	pm, stateDB, ledger, key := setupTokenPool(t, TokenFlagRebasing1)
+	token := key.Currency1.Address
+	ledger.mint(token, testTrader, big.NewInt(10_000))
+
+	if err := pm.Settle(stateDB, key.Currency1, big.NewInt(1_000)); err != nil {
+		t.Fatalf("Settle failed: %v", err)
+	}
+	if shares := pm.Tokens().TotalShares(stateDB, key.Currency1); shares.Cmp(big.NewInt(1_000)) != 0 {
+		t.Fatalf("Expected 1000 shares minted, got %s", shares)
+	}
+	if shares := NewTokenAdapter().TotalShares(stateDB, key.Currency1); shares.Cmp(big.NewInt(1_000)) != 0 {
+		t.Errorf("Expected shares to be read from state, got %s", shares)
+	}
+
+	// A 10% positive rebase grows the value of every share
+	ledger.rebase(token, 11, 10)
+	value, err := pm.Tokens().SharesToAmount(stateDB, key.Currency1, big.NewInt(500))
+	if err != nil {
+		t.Fatalf("SharesToAmount failed: %v", err)
+	}
+	if value.Cmp(big.NewInt(550)) != 0 {
+		t.Errorf("Expected 500 shares worth 550, got %s", value)
+	}
+
+	// New deposits mint at the post-rebase rate
+	if err := pm.Settle(stateDB, key.Currency1, big.NewInt(1_100)); err != nil {
+		t.Fatalf("Settle failed: %v", err)
+	}
+	if delta := pm.GetDelta(testTrader, key.Currency1); delta.Cmp(big.NewInt(-2_000)) != 0 {
+		t.Errorf("Expected delta -2000 shares, got %s", delta)
+	}
+
+	// Taking all shares pays out the full rebased balance
+	if err := pm.Take(stateDB, key.Currency1, testTrader, big.NewInt(2_000)); err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if bal := ledger.BalanceOf(nil, token, testTrader); bal.Cmp(big.NewInt(11_000)) != 0 {
+		t.Errorf("Expected trader balance 11000, got %s", bal)
+	}
+	if err := pm.Take(stateDB, key.Currency1, testTrader, big.NewInt(1)); err != ErrInsufficientShares {
+		t.Errorf("Expected ErrInsufficientShares, got %v", err)
+	}
+}
+
+func TestTokenFlagValidation(t *testing.T) {
+	pm := newTestPoolManager()
+	stateDB := NewMockStateDB()
+	key := newTestPoolKey()
+	sqrtPriceX96 := new(big.Int).Lsh(big.NewInt(1), 96)
+
+	// Native currency cannot be non-standard
+	if _, err := pm.InitializeWithTokenFlags(stateDB, key, sqrtPriceX96, TokenFlagRebasing0, nil); err != ErrInvalidParameter {
+		t.Errorf("Expected ErrInvalidParameter, got %v", err)
+	}
+
+	if _, err := pm.InitializeWithTokenFlags(stateDB, key, sqrtPriceX96, TokenFlagFeeOnTransfer1, nil); err != nil {
+		t.Fatalf("InitializeWithTokenFlags failed: %v", err)
+	}
+
+	// The same currency cannot be flagged differently in another pool
+	other := key
+	other.Fee, other.TickSpacing = Fee005, TickSpacing005
+	if _, err := pm.InitializeWithTokenFlags(stateDB, other, sqrtPriceX96, TokenFlagRebasing1, nil); err != ErrTokenBehaviorMismatch {
+		t.Errorf("Expected ErrTokenBehaviorMismatch, got %v", err)
+	}
+	if _, err := pm.Initialize(stateDB, other, sqrtPriceX96, nil); err != ErrTokenBehaviorMismatch {
+		t.Errorf("Expected ErrTokenBehaviorMismatch for unflagged pool, got %v", err)
+	}
+
+	// Settling without a ledger fails rather than crediting nominal amounts
+	pm.lockers = append(pm.lockers, testTrader)
+	if err := pm.Settle(stateDB, key.Currency1, big.NewInt(1)); err != ErrNoTokenLedger {
+		t.Errorf("Expected ErrNoTokenLedger, got %v", err)
+	}
+}
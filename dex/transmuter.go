@@ -48,7 +48,9 @@ type TransmuterStake struct {
 	LiquidToken     common.Address
 	StakedAmount    *big.Int // Amount of liquid staked
 	UnclaimedAmount *big.Int // Underlying available to claim
-	LastUpdateIndex *big.Int // Index at last update (for pro-rata)
+	LastUpdateIndex *big.Int // ExchangeRate at last update (for pro-rata)
+	Epoch           uint64   // State epoch at last update
+	Scale           uint64   // State scale at last update
 }
 
 // NewTransmuter creates a new Transmuter instance
@@ -92,7 +94,10 @@ func (t *Transmuter) InitializeTransmuter(
 		UnderlyingAsset: underlyingAsset,
 		ExchangeBuffer:  big.NewInt(0),
 		TotalStaked:     big.NewInt(0),
-		ExchangeRate:    new(big.Int).Set(Q96), // 1:1 initial rate
+		ExchangeRate:    new(big.Int).Set(Q96), // Nothing converted yet
+		Unexchanged:     big.NewInt(0),
+		FlowRate:        big.NewInt(0),
+		LastFlowBlock:   stateDB.GetBlockNumber(),
 	}
 
 	t.states[liquidToken] = state
@@ -101,9 +106,54 @@ func (t *Transmuter) InitializeTransmuter(
 	return nil
 }
 
+// SetFlowRate sets how much underlying the buffer releases to stakers per
+// block. Zero releases deposits in full as soon as they arrive.
+func (t *Transmuter) SetFlowRate(
+	stateDB StateDB,
+	liquidToken common.Address,
+	flowRate *big.Int,
+) error {
+	if flowRate == nil || flowRate.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, exists := t.states[liquidToken]
+	if !exists {
+		return ErrLiquidTokenNotRegistered
+	}
+
+	// Release what flowed at the old rate first
+	t.exchange(state, stateDB.GetBlockNumber())
+	state.FlowRate = new(big.Int).Set(flowRate)
+
+	t.saveState(stateDB, state)
+	return nil
+}
+
 // =========================================================================
 // Core Transmuter Operations
 // =========================================================================
+//
+// Underlying enters the exchange buffer from deposits and keeper top-ups
+// and is released to stakers at the flow rate. Each release converts the
+// same amount of staked liquid tokens pro-rata: every stake keeps
+// (staked - released) / staked of what it had, and the converted part
+// becomes claimable underlying 1:1.
+//
+// ExchangeRate tracks the running product of those kept shares, so a
+// stake's unconverted amount is staked * rate / rateAtLastUpdate however
+// many releases happened in between. When the rate gets small it is scaled
+// up by 2^32 and Scale is bumped; a release that converts everything
+// starts a new Epoch, in which older stakes are fully converted.
+
+// transmuterRescaleBits is how far ExchangeRate is scaled up when it drops
+// below 2^64
+const transmuterRescaleBits = 32
+
+var transmuterRescaleFloor = new(big.Int).Lsh(big.NewInt(1), 64)
 
 // Stake stakes liquid tokens for transmutation
 // Staked liquidTokens will be converted to underlying as yield flows in
@@ -121,10 +171,12 @@ func (t *Transmuter) Stake(
 		return ErrLiquidTokenNotRegistered
 	}
 
-	if amount.Sign() <= 0 {
+	if amount == nil || amount.Sign() <= 0 {
 		return ErrInvalidPositionSize
 	}
 
+	t.exchange(state, stateDB.GetBlockNumber())
+
 	// Get or create stake
 	key := stakeKey(liquidToken, owner)
 	stake := t.getStake(stateDB, key)
@@ -135,10 +187,12 @@ func (t *Transmuter) Stake(
 			StakedAmount:    big.NewInt(0),
 			UnclaimedAmount: big.NewInt(0),
 			LastUpdateIndex: new(big.Int).Set(state.ExchangeRate),
+			Epoch:           state.Epoch,
+			Scale:           state.Scale,
 		}
 	}
 
-	// Update stake's unclaimed amount based on exchange rate change
+	// Move what has converted so far into the unclaimed amount
 	t.updateStakeUnclaimed(stake, state)
 
 	// Transfer liquid tokens from user
@@ -146,7 +200,6 @@ func (t *Transmuter) Stake(
 
 	// Update stake
 	stake.StakedAmount = new(big.Int).Add(stake.StakedAmount, amount)
-	stake.LastUpdateIndex = new(big.Int).Set(state.ExchangeRate)
 
 	// Update total staked
 	state.TotalStaked = new(big.Int).Add(state.TotalStaked, amount)
@@ -174,6 +227,10 @@ func (t *Transmuter) Unstake(
 		return ErrLiquidTokenNotRegistered
 	}
 
+	if amount == nil || amount.Sign() <= 0 {
+		return ErrInvalidPositionSize
+	}
+
 	key := stakeKey(liquidToken, owner)
 	stake := t.getStake(stateDB, key)
 	if stake == nil || stake.StakedAmount.Sign() == 0 {
@@ -181,6 +238,7 @@ func (t *Transmuter) Unstake(
 	}
 
 	// Update unclaimed first
+	t.exchange(state, stateDB.GetBlockNumber())
 	t.updateStakeUnclaimed(stake, state)
 
 	// Check unstake amount
@@ -191,8 +249,12 @@ func (t *Transmuter) Unstake(
 	// Update stake
 	stake.StakedAmount = new(big.Int).Sub(stake.StakedAmount, amount)
 
-	// Update total staked
+	// Update total staked; stakes round up, so the total may be a little
+	// short of their sum
 	state.TotalStaked = new(big.Int).Sub(state.TotalStaked, amount)
+	if state.TotalStaked.Sign() < 0 {
+		state.TotalStaked.SetInt64(0)
+	}
 
 	// Transfer liquid tokens back to user
 	t.transferSynthetic(stateDB, liquidToken, transmuterAddr, owner, amount)
@@ -226,10 +288,13 @@ func (t *Transmuter) Claim(
 	}
 
 	// Update unclaimed
+	t.exchange(state, stateDB.GetBlockNumber())
 	t.updateStakeUnclaimed(stake, state)
 
 	claimAmount := new(big.Int).Set(stake.UnclaimedAmount)
 	if claimAmount.Sign() == 0 {
+		t.saveStake(stateDB, key, stake)
+		t.saveState(stateDB, state)
 		return big.NewInt(0), nil
 	}
 
@@ -267,25 +332,64 @@ func (t *Transmuter) Deposit(
 		return ErrLiquidTokenNotRegistered
 	}
 
-	if underlyingAmount.Sign() <= 0 {
+	if underlyingAmount == nil || underlyingAmount.Sign() <= 0 {
 		return nil
 	}
 
-	// Add to exchange buffer
-	state.ExchangeBuffer = new(big.Int).Add(state.ExchangeBuffer, underlyingAmount)
+	t.deposit(stateDB, state, underlyingAmount)
+	t.saveState(stateDB, state)
+
+	return nil
+}
+
+// TopUp is the keeper hook that moves the underlying Liquid has collected
+// for liquidToken, from harvested yield and repayments, into the exchange
+// buffer. Returns the amount added.
+func (t *Transmuter) TopUp(
+	stateDB StateDB,
+	liquidToken common.Address,
+) (*big.Int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, exists := t.states[liquidToken]
+	if !exists {
+		return nil, ErrLiquidTokenNotRegistered
+	}
 
-	// Update exchange rate if there are stakers
-	if state.TotalStaked.Sign() > 0 {
-		// exchangeRate increases as underlying flows in
-		// newRate = oldRate + (underlyingAmount * Q96 / totalStaked)
-		rateIncrease := new(big.Int).Mul(underlyingAmount, Q96)
-		rateIncrease.Div(rateIncrease, state.TotalStaked)
-		state.ExchangeRate = new(big.Int).Add(state.ExchangeRate, rateIncrease)
+	amount := t.alchemist.takeCollected(liquidToken)
+	if amount.Sign() == 0 {
+		return amount, nil
 	}
 
+	t.transferUnderlying(stateDB, state.UnderlyingAsset, liquidAddr, transmuterAddr, amount)
+	t.deposit(stateDB, state, amount)
 	t.saveState(stateDB, state)
 
-	return nil
+	return amount, nil
+}
+
+// HarvestAndTopUp is the keeper hook that harvests an account's yield in
+// Liquid and tops up the buffer of its liquid token with what was
+// collected. Returns the amount added.
+func (t *Transmuter) HarvestAndTopUp(
+	stateDB StateDB,
+	owner common.Address,
+	yieldToken common.Address,
+) (*big.Int, error) {
+	account := t.alchemist.GetAccount(stateDB, owner, yieldToken)
+	if account == nil {
+		return nil, ErrInsufficientCollateral
+	}
+	liquidToken := account.LiquidToken
+
+	if _, err := t.alchemist.Harvest(stateDB, owner, yieldToken); err != nil {
+		return nil, err
+	}
+	if liquidToken == (common.Address{}) {
+		return big.NewInt(0), nil
+	}
+	return t.TopUp(stateDB, liquidToken)
 }
 
 // =========================================================================
@@ -335,18 +439,23 @@ func (t *Transmuter) GetClaimable(
 		return big.NewInt(0)
 	}
 
-	// Calculate current unclaimed
-	unclaimed := new(big.Int).Set(stake.UnclaimedAmount)
-
-	// Add newly accrued based on exchange rate change
-	if stake.StakedAmount.Sign() > 0 {
-		rateDiff := new(big.Int).Sub(state.ExchangeRate, stake.LastUpdateIndex)
-		if rateDiff.Sign() > 0 {
-			newUnclaimed := new(big.Int).Mul(stake.StakedAmount, rateDiff)
-			newUnclaimed.Div(newUnclaimed, Q96)
-			unclaimed.Add(unclaimed, newUnclaimed)
-		}
+	// Release the buffer on a copy of the state, as a touch would
+	preview := &LiquidFXState{
+		ExchangeBuffer: state.ExchangeBuffer,
+		TotalStaked:    new(big.Int).Set(state.TotalStaked),
+		ExchangeRate:   new(big.Int).Set(state.ExchangeRate),
+		Unexchanged:    new(big.Int).Set(state.Unexchanged),
+		FlowRate:       state.FlowRate,
+		LastFlowBlock:  state.LastFlowBlock,
+		Epoch:          state.Epoch,
+		Scale:          state.Scale,
 	}
+	t.exchange(preview, stateDB.GetBlockNumber())
+
+	// Calculate current unclaimed
+	remaining := stakeRemaining(stake, preview)
+	unclaimed := new(big.Int).Add(stake.UnclaimedAmount, stake.StakedAmount)
+	unclaimed.Sub(unclaimed, remaining)
 
 	// Cap at available buffer
 	if unclaimed.Cmp(state.ExchangeBuffer) > 0 {
@@ -356,7 +465,8 @@ func (t *Transmuter) GetClaimable(
 	return unclaimed
 }
 
-// GetExchangeRate returns the current exchange rate
+// GetExchangeRate returns the share of staked liquid tokens still
+// unconverted in the current epoch and scale (Q96)
 func (t *Transmuter) GetExchangeRate(liquidToken common.Address) *big.Int {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -373,32 +483,100 @@ func (t *Transmuter) GetExchangeRate(liquidToken common.Address) *big.Int {
 // Internal Functions
 // =========================================================================
 
-// updateStakeUnclaimed updates a stake's unclaimed amount based on exchange rate
-func (t *Transmuter) updateStakeUnclaimed(stake *TransmuterStake, state *LiquidFXState) {
-	if stake.StakedAmount.Sign() == 0 {
-		return
+// deposit adds underlying to the buffer and releases what the flow allows
+func (t *Transmuter) deposit(stateDB StateDB, state *LiquidFXState, amount *big.Int) {
+	block := stateDB.GetBlockNumber()
+	t.exchange(state, block)
+
+	state.ExchangeBuffer = new(big.Int).Add(state.ExchangeBuffer, amount)
+	state.Unexchanged = new(big.Int).Add(state.Unexchanged, amount)
+
+	t.exchange(state, block)
+}
+
+// exchange releases buffered underlying to stakers up to block, converting
+// the same amount of staked liquid tokens pro-rata
+func (t *Transmuter) exchange(state *LiquidFXState, block uint64) {
+	released := new(big.Int).Set(state.Unexchanged)
+	if state.FlowRate.Sign() > 0 {
+		var elapsed uint64
+		if block > state.LastFlowBlock {
+			elapsed = block - state.LastFlowBlock
+		}
+		flowed := new(big.Int).Mul(state.FlowRate, new(big.Int).SetUint64(elapsed))
+		if flowed.Cmp(released) < 0 {
+			released = flowed
+		}
 	}
+	state.LastFlowBlock = block
 
-	// Calculate newly accrued based on exchange rate change
-	rateDiff := new(big.Int).Sub(state.ExchangeRate, stake.LastUpdateIndex)
-	if rateDiff.Sign() <= 0 {
+	if state.TotalStaked.Sign() == 0 || released.Sign() == 0 {
+		return
+	}
+	if released.Cmp(state.TotalStaked) > 0 {
+		released.Set(state.TotalStaked)
+	}
+	state.Unexchanged = new(big.Int).Sub(state.Unexchanged, released)
+
+	// Every stake keeps (total - released) / total of what it had
+	kept := new(big.Int).Sub(state.TotalStaked, released)
+	rate := new(big.Int).Mul(state.ExchangeRate, kept)
+	rate.Div(rate, state.TotalStaked)
+	state.TotalStaked = kept
+
+	if rate.Sign() == 0 {
+		// Everything converted: older stakes are settled by epoch
+		state.Epoch++
+		state.Scale = 0
+		state.ExchangeRate = new(big.Int).Set(Q96)
+		state.TotalStaked = big.NewInt(0)
 		return
 	}
+	if rate.Cmp(transmuterRescaleFloor) < 0 {
+		rate.Lsh(rate, transmuterRescaleBits)
+		state.Scale++
+	}
+	state.ExchangeRate = rate
+}
 
-	// newUnclaimed = stakedAmount * rateDiff / Q96
-	newUnclaimed := new(big.Int).Mul(stake.StakedAmount, rateDiff)
-	newUnclaimed.Div(newUnclaimed, Q96)
+// stakeRemaining returns the unconverted part of a stake, rounded up
+func stakeRemaining(stake *TransmuterStake, state *LiquidFXState) *big.Int {
+	if stake.StakedAmount.Sign() == 0 || stake.Epoch != state.Epoch {
+		return big.NewInt(0)
+	}
 
-	stake.UnclaimedAmount = new(big.Int).Add(stake.UnclaimedAmount, newUnclaimed)
+	divisor := new(big.Int).Set(stake.LastUpdateIndex)
+	switch state.Scale - stake.Scale {
+	case 0:
+	case 1:
+		divisor.Lsh(divisor, transmuterRescaleBits)
+	default:
+		return big.NewInt(0)
+	}
 
-	// Reduce staked amount by converted amount (liquidTokens are "burned" as they convert)
-	if newUnclaimed.Cmp(stake.StakedAmount) >= 0 {
-		stake.StakedAmount = big.NewInt(0)
-	} else {
-		stake.StakedAmount = new(big.Int).Sub(stake.StakedAmount, newUnclaimed)
+	remaining := new(big.Int).Mul(stake.StakedAmount, state.ExchangeRate)
+	remaining.Add(remaining, divisor)
+	remaining.Sub(remaining, big.NewInt(1))
+	remaining.Div(remaining, divisor)
+	if remaining.Cmp(stake.StakedAmount) > 0 {
+		return new(big.Int).Set(stake.StakedAmount)
 	}
+	return remaining
+}
+
+// updateStakeUnclaimed moves the converted part of a stake into its
+// unclaimed amount and brings it up to the current index
+func (t *Transmuter) updateStakeUnclaimed(stake *TransmuterStake, state *LiquidFXState) {
+	remaining := stakeRemaining(stake, state)
+
+	// liquidTokens are "burned" as they convert
+	converted := new(big.Int).Sub(stake.StakedAmount, remaining)
+	stake.UnclaimedAmount = new(big.Int).Add(stake.UnclaimedAmount, converted)
+	stake.StakedAmount = remaining
 
 	stake.LastUpdateIndex = new(big.Int).Set(state.ExchangeRate)
+	stake.Epoch = state.Epoch
+	stake.Scale = state.Scale
 }
 
 // =========================================================================
@@ -34,6 +34,10 @@ const (
 	LXLiquidAddress   = "0x0000000000000000000000000000000000009060" // LP-9060 LXLiquid (self-repaying loans)
 	LiquidatorAddress = "0x0000000000000000000000000000000000009070" // LP-9070 Liquidator (position liquidation)
 	LiquidFXAddress   = "0x0000000000000000000000000000000000009080" // LP-9080 LiquidFX (transmuter)
+	LXEscrowAddress   = "0x0000000000000000000000000000000000009090" // LP-9090 LXEscrow (vesting escrow)
+	LXLotteryAddress  = "0x0000000000000000000000000000000000009091" // LP-9091 LXLottery (swap incentive lottery)
+	LXIdentityAddress = "0x0000000000000000000000000000000000009092" // LP-9092 LXIdentity (compliance registry)
+	LXBondAddress     = "0x0000000000000000000000000000000000009093" // LP-9093 LXBond (protocol-owned liquidity)
 
 	// Bridge Precompiles (LP-6xxx)
 	TeleportAddress = "0x0000000000000000000000000000000000006010" // LP-6010 Teleport (cross-chain)
@@ -60,6 +64,9 @@ const (
 	GasPoolLookup     uint64 = 100    // Pool state lookup
 	GasNativeTransfer uint64 = 2_100  // Native LUX transfer
 
+	// Gas forwarded to a hook contract per call, capped at the gas left
+	GasHookCallLimit uint64 = 500_000
+
 	// Lending operations
 	GasSupply    uint64 = 15_000 // Supply collateral
 	GasBorrow    uint64 = 20_000 // Borrow against collateral
@@ -84,6 +91,76 @@ const (
 	// Teleport operations
 	GasTeleportInit     uint64 = 50_000 // Initiate cross-chain transfer
 	GasTeleportComplete uint64 = 40_000 // Complete cross-chain transfer
+
+	// Gauge operations
+	GasGaugeFund  uint64 = 30_000 // Fund or top up a gauge
+	GasGaugeClaim uint64 = 15_000 // Claim gauge rewards
+
+	// Order book control operations
+	GasSetSTPMode uint64 = 5_000 // Set self-trade prevention policy
+
+	// Order book operations
+	GasPlaceOrder      uint64 = 30_000 // Place or modify a limit order, before matching
+	GasOrderFill       uint64 = 10_000 // Match against one resting order
+	GasCancelOrder     uint64 = 10_000 // Cancel a resting order
+	GasCollectBookFees uint64 = 15_000 // Collect accrued order book fees
+
+	// Price oracle operations
+	GasOracleRegisterFeed uint64 = 30_000 // Register or reconfigure a price feed
+	GasOracleReport       uint64 = 20_000 // Verify and record a signed price report
+	GasOracleRead         uint64 = 5_000  // Aggregate a price or TWAP
+
+	// Price feed operations
+	GasFeedConfigure uint64 = 10_000 // Add or reconfigure a feed market
+	GasFeedUpdate    uint64 = 25_000 // Derive mark and index from the book and oracle
+
+	// Referral operations
+	GasClaimReferral    uint64 = 5_000 // Claim referral fees into lock delta
+	GasWithdrawReferral uint64 = 8_000 // Withdraw referral fees to an address
+
+	// Escrow operations
+	GasEscrowCreate   uint64 = 30_000 // Create a token or position lock
+	GasEscrowClaim    uint64 = 15_000 // Claim vested amounts or locked fees
+	GasEscrowTransfer uint64 = 10_000 // Reassign a lock to a new beneficiary
+
+	// Lottery operations
+	GasLotteryFund  uint64 = 20_000 // Add to an epoch's prize pot
+	GasLotteryDraw  uint64 = 40_000 // Draw an ended epoch, including seed verification
+	GasLotteryClaim uint64 = 15_000 // Claim a prize pot into the lock delta
+
+	// Identity registry operations
+	GasIdentityIssuer uint64 = 10_000 // Add or remove an issuer
+	GasIdentityAttest uint64 = 20_000 // Attest an address
+	GasIdentityRevoke uint64 = 10_000 // Revoke an attestation
+
+	// Bond operations
+	GasBondCreate uint64 = 30_000 // Open a bond market
+	GasBondFund   uint64 = 15_000 // Fund or close a bond market
+	GasBond       uint64 = 60_000 // Bond liquidity, including pricing and the payout lock
+	GasBondQuote  uint64 = 10_000 // Price liquidity against a market
+
+	// Fee tier governance
+	GasFeeTierUpdate uint64 = 10_000 // Enable or disable a fee tier
+
+	// Aggregate queries
+	GasAggregateCall uint64 = 200 // Per-call overhead of an aggregate query
+
+	// TWAP oracle operations
+	GasObserve         uint64 = 1_000 // Look up one point in an observation buffer
+	GasObservationSlot uint64 = 5_000 // Add one slot to an observation buffer
+
+	// Protocol fee operations
+	GasProtocolFeeUpdate   uint64 = 10_000 // Set a pool's protocol fee or the controller
+	GasCollectProtocolFees uint64 = 15_000 // Collect a pool's accrued protocol fees
+
+	// Reserve operations
+	GasSync uint64 = 5_000 // Record a currency's balance as its reserves
+
+	// ERC-6909 claim operations
+	GasClaimUpdate uint64 = 5_000 // Mint, burn or transfer claims
+
+	// Position fee operations
+	GasCollectFees uint64 = 10_000 // Collect a position's owed fees
 )
 
 // Pool fee tiers (basis points)
@@ -273,6 +350,15 @@ type Pool struct {
 	FeeGrowth1X128 *big.Int // Fee growth for currency1 (Q128.128)
 	ProtocolFees0  *big.Int // Accumulated protocol fees currency0
 	ProtocolFees1  *big.Int // Accumulated protocol fees currency1
+	ProtocolFeeBps uint32   // Protocol share of swap fees (basis points)
+
+	// TokenFlags marks non-standard currencies, set at initialization
+	TokenFlags TokenFlags
+
+	// Observation buffer state (see observations.go)
+	ObservationIndex           uint16 // Slot of the latest observation
+	ObservationCardinality     uint16 // Slots in use
+	ObservationCardinalityNext uint16 // Slots to use once the buffer wraps
 }
 
 // IsInitialized returns true if the pool has been initialized
@@ -364,6 +450,13 @@ var (
 	ErrTickOutOfRange         = errors.New("tick out of range")
 	ErrReentrant              = errors.New("reentrancy detected")
 	ErrNoLiquidity            = errors.New("no liquidity in pool")
+	ErrFeeTierNotEnabled      = errors.New("fee tier not enabled")
+	ErrInvalidFeeTier         = errors.New("invalid fee tier")
+	ErrObservationTooOld      = errors.New("observation older than oldest stored")
+	ErrInvalidProtocolFee     = errors.New("protocol fee exceeds maximum")
+	ErrCurrencyNotSynced      = errors.New("currency not synced")
+	ErrSettlementMismatch     = errors.New("settled amount does not match balance change")
+	ErrInsufficientClaims     = errors.New("insufficient claim balance")
 )
 
 // Errors - Lending
@@ -405,6 +498,7 @@ var (
 	ErrNoDebtToRepay            = errors.New("no debt to repay")
 	ErrTransmuterEmpty          = errors.New("transmuter has no underlying")
 	ErrLiquidTokenNotRegistered = errors.New("liquid token not registered")
+	ErrLiquidTokenMismatch      = errors.New("liquid token does not match collateral")
 )
 
 // Errors - Teleport
@@ -413,6 +507,100 @@ var (
 	ErrTeleportNotFinalized = errors.New("teleport not finalized")
 	ErrDuplicateTeleportID  = errors.New("duplicate teleport ID")
 	ErrInvalidWarpSignature = errors.New("invalid warp signature")
+	ErrInvalidRoute         = errors.New("invalid omnichain route")
+	ErrRouteNotFound        = errors.New("omnichain route not found")
+	ErrInvalidRouteState    = errors.New("invalid omnichain route state")
+	ErrHopSlippage          = errors.New("hop output below minimum")
+	ErrNoSwapper            = errors.New("no swap executor configured")
+	ErrNoRouteEscrow        = errors.New("no route escrow configured")
+)
+
+// Errors - Gauges
+var (
+	ErrGaugeNotFound       = errors.New("gauge not found")
+	ErrInvalidEmissionRate = errors.New("invalid emission rate")
+)
+
+// Errors - Referrals
+var (
+	ErrInvalidReferralShare        = errors.New("referral share exceeds maximum")
+	ErrInsufficientReferralBalance = errors.New("insufficient referral balance")
+)
+
+// Errors - Token behaviors
+var (
+	ErrTokenBehaviorMismatch = errors.New("currency already registered with a different token behavior")
+	ErrNoTokenLedger         = errors.New("no token ledger configured")
+	ErrInsufficientShares    = errors.New("insufficient shares")
+)
+
+// Errors - Escrow
+var (
+	ErrLockNotFound        = errors.New("vesting lock not found")
+	ErrInvalidSchedule     = errors.New("invalid vesting schedule")
+	ErrNothingVested       = errors.New("nothing vested to claim")
+	ErrLockNotTransferable = errors.New("vesting lock is not transferable")
+)
+
+// Errors - Lottery
+var (
+	ErrInvalidLotteryShare = errors.New("lottery share exceeds maximum")
+	ErrLotteryEpochOpen    = errors.New("lottery epoch has not ended")
+	ErrLotteryEpochEnded   = errors.New("lottery epoch has ended")
+	ErrLotteryDrawn        = errors.New("lottery epoch already drawn")
+	ErrLotteryNotDrawn     = errors.New("lottery epoch not drawn")
+	ErrLotteryPrizeClaimed = errors.New("lottery prize already claimed")
+	ErrNoLotteryBeacon     = errors.New("no lottery randomness beacon configured")
+	ErrInvalidRandomness   = errors.New("invalid lottery randomness")
+)
+
+// Errors - Compliance
+var (
+	ErrNotVerified         = errors.New("address has no valid identity attestation")
+	ErrInvalidIssuer       = errors.New("not an active identity issuer")
+	ErrInvalidAttestation  = errors.New("invalid identity attestation")
+	ErrAttestationNotFound = errors.New("identity attestation not found")
+)
+
+// Errors - POL
+var (
+	ErrBondMarketNotFound   = errors.New("bond market not found")
+	ErrBondMarketClosed     = errors.New("bond market closed")
+	ErrBondCapacityExceeded = errors.New("bond exceeds market capacity")
+	ErrBondSlippage         = errors.New("bond payout below minimum")
+	ErrNoPriceOracle        = errors.New("no TWAP price available")
+	ErrInvalidBondTerms     = errors.New("invalid bond market terms")
+)
+
+// Errors - Aggregate queries
+var (
+	ErrInvalidAggregate       = errors.New("invalid aggregate query")
+	ErrInvalidAggregateTarget = errors.New("aggregate target is not a DEX precompile")
+	ErrNestedAggregate        = errors.New("nested aggregate query")
+)
+
+// Errors - Order Book
+var (
+	ErrInvalidSTPMode   = errors.New("invalid self-trade prevention mode")
+	ErrOrderRateLimited = errors.New("order rate limit exceeded for block")
+	ErrInvalidOrder     = errors.New("invalid order")
+	ErrOrderNotFound    = errors.New("order not found")
+	ErrOrderWouldCross  = errors.New("post-only order would cross the book")
+	ErrInvalidBookFee   = errors.New("order book fee too high")
+)
+
+// Errors - Oracle
+var (
+	ErrOracleFeedNotFound  = errors.New("oracle feed not found")
+	ErrInvalidOracleFeed   = errors.New("invalid oracle feed")
+	ErrInvalidReport       = errors.New("invalid oracle report")
+	ErrUnknownReporter     = errors.New("report not signed by a feed reporter")
+	ErrStaleReport         = errors.New("oracle report is stale")
+	ErrInsufficientReports = errors.New("not enough fresh oracle reports")
+	ErrPriceDeviation      = errors.New("oracle reports deviate beyond threshold")
+	ErrTWAPUnavailable     = errors.New("oracle history does not cover TWAP window")
+	ErrFeedMarketNotFound  = errors.New("price feed market not found")
+	ErrNoPriceFeed         = errors.New("no mark and index price feed attached")
 )
 
 // Constants for math
@@ -523,7 +711,7 @@ const (
 type LiquidToken struct {
 	Address         common.Address // Liquid token address
 	UnderlyingAsset Currency       // The underlying asset it tracks
-	TotalMinted     *big.Int       // Total liquid tokens minted
+	TotalMinted     *big.Int       // Outstanding debt minted against the ceiling
 	DebtCeiling     *big.Int       // Maximum mintable
 	MintFee         uint24         // Fee on minting (basis points)
 	BurnFee         uint24         // Fee on burning (basis points)
@@ -543,6 +731,7 @@ type YieldToken struct {
 type LiquidAccount struct {
 	Owner            common.Address
 	YieldToken       common.Address // The yield-bearing collateral token
+	LiquidToken      common.Address // Liquid token the debt is owed in (zero without debt)
 	Collateral       *big.Int       // Amount of yield token deposited
 	Debt             *big.Int       // Amount of liquid token debt owed
 	LastHarvestBlock uint64         // Last block yield was harvested
@@ -555,7 +744,12 @@ type LiquidFXState struct {
 	UnderlyingAsset Currency       // The underlying (e.g., USDC)
 	ExchangeBuffer  *big.Int       // Underlying available for exchange
 	TotalStaked     *big.Int       // Total liquid tokens staked for transmutation
-	ExchangeRate    *big.Int       // Current exchange rate (Q96)
+	ExchangeRate    *big.Int       // Unconverted share of staked tokens this epoch (Q96)
+	Unexchanged     *big.Int       // Part of the buffer not yet released to stakers
+	FlowRate        *big.Int       // Underlying released per block (0 = all at once)
+	LastFlowBlock   uint64         // Block the buffer was last released at
+	Epoch           uint64         // Bumped whenever every staked token converts
+	Scale           uint64         // Times ExchangeRate was scaled up this epoch
 }
 
 // =========================================================================
@@ -602,6 +796,7 @@ const (
 
 // WarpMessage represents a cross-chain message via Lux Warp
 type WarpMessage struct {
+	NetworkID     uint32   // Network the message is signed for
 	SourceChainID [32]byte // Source chain ID (Lux format)
 	Payload       []byte   // Message payload
 	Signatures    []byte   // BLS aggregate signature
@@ -610,6 +805,9 @@ type WarpMessage struct {
 
 // OmnichainRoute represents a multi-hop cross-chain swap route
 type OmnichainRoute struct {
+	RouteID       [32]byte   // Unique route identifier
+	SourceChain   uint32     // Chain the route starts on
+	DestChain     uint32     // Chain the route ends on
 	Hops          []RouteHop // Sequence of hops
 	TotalEstimate *big.Int   // Estimated output amount
 	TotalGas      uint64     // Estimated total gas
@@ -617,10 +815,30 @@ type OmnichainRoute struct {
 
 // RouteHop represents a single hop in a cross-chain route
 type RouteHop struct {
+	Kind         HopKind        // Swap or teleport
 	ChainID      uint32         // Chain for this hop
-	PoolID       [32]byte       // Pool to use on this chain
+	PoolID       [32]byte       // Pool to use on this chain (zero for teleport)
 	TokenIn      common.Address // Input token
 	TokenOut     common.Address // Output token
 	AmountIn     *big.Int       // Amount in
 	MinAmountOut *big.Int       // Minimum output
 }
+
+// HopKind distinguishes local swaps from bridge transfers in a route
+type HopKind uint8
+
+const (
+	HopSwap     HopKind = iota // Swap through a pool on ChainID
+	HopTeleport                // Teleport from ChainID to the route's DestChain
+)
+
+// RouteStatus represents the state of an omnichain route
+type RouteStatus uint8
+
+const (
+	RoutePlanned      RouteStatus = iota // Planned, nothing executed yet
+	RouteInTransit                       // Source swaps done, teleport burned
+	RouteCompleted                       // All hops executed
+	RouteRefunded                        // A hop failed and funds were refunded
+	RouteRefundFailed                    // A hop failed and the refund transfer did not go through
+)
@@ -11,7 +11,6 @@ package ecies
 import (
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/hmac"
 	"crypto/rand"
@@ -21,6 +20,8 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"io"
+	"math/big"
 
 	"github.com/luxfi/crypto/secp256k1"
 	"github.com/luxfi/geth/common"
@@ -170,7 +171,7 @@ func (p *eciesPrecompile) Run(
 
 	switch op {
 	case OpEncrypt:
-		result, err = p.encrypt(curveID, input[2:])
+		result, err = p.encrypt(curveID, input[2:], rand.Reader)
 	case OpDecrypt:
 		result, err = p.decrypt(curveID, input[2:])
 	case OpECDH:
@@ -199,7 +200,53 @@ func (p *eciesPrecompile) getCurve(id byte) (elliptic.Curve, error) {
 	}
 }
 
-func (p *eciesPrecompile) encrypt(curveID byte, input []byte) ([]byte, error) {
+// Encrypt encrypts plaintext to an uncompressed public key, producing the
+// ciphertext OpEncrypt returns for the same inputs
+func Encrypt(curveID byte, publicKey, s1, plaintext []byte) ([]byte, error) {
+	return EncryptFrom(rand.Reader, curveID, publicKey, s1, plaintext)
+}
+
+// EncryptFrom encrypts like Encrypt, drawing the ephemeral key and IV from
+// rng. Callers that must produce the same ciphertext on every node pass a
+// stream keyed by a secret they share.
+func EncryptFrom(rng io.Reader, curveID byte, publicKey, s1, plaintext []byte) ([]byte, error) {
+	if len(publicKey) != 65 {
+		return nil, ErrInvalidPublicKey
+	}
+	input, err := encodeKeyedInput(publicKey, s1, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return ECIESPrecompile.encrypt(curveID, input, rng)
+}
+
+// Decrypt decrypts a ciphertext produced by Encrypt or OpEncrypt with a
+// 32-byte private key
+func Decrypt(curveID byte, privateKey, s1, ciphertext []byte) ([]byte, error) {
+	if len(privateKey) != 32 {
+		return nil, ErrInvalidInput
+	}
+	input, err := encodeKeyedInput(privateKey, s1, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return ECIESPrecompile.decrypt(curveID, input)
+}
+
+// encodeKeyedInput lays out key || s1Len (2) || s1 || data as the
+// encrypt and decrypt operations read it
+func encodeKeyedInput(key, s1, data []byte) ([]byte, error) {
+	if len(s1) > 0xFFFF {
+		return nil, ErrInvalidInput
+	}
+	input := make([]byte, 0, len(key)+2+len(s1)+len(data))
+	input = append(input, key...)
+	input = binary.BigEndian.AppendUint16(input, uint16(len(s1)))
+	input = append(input, s1...)
+	return append(input, data...), nil
+}
+
+func (p *eciesPrecompile) encrypt(curveID byte, input []byte, rng io.Reader) ([]byte, error) {
 	curve, err := p.getCurve(curveID)
 	if err != nil {
 		return nil, err
@@ -239,13 +286,14 @@ func (p *eciesPrecompile) encrypt(curveID byte, input []byte) ([]byte, error) {
 	}
 
 	// Generate ephemeral key pair
-	ephPriv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	ephD, err := ephemeralScalar(curve, rng)
 	if err != nil {
 		return nil, err
 	}
+	ephX, ephY := curve.ScalarBaseMult(ephD.Bytes())
 
 	// ECDH: compute shared secret
-	sx, _ := curve.ScalarMult(x, y, ephPriv.D.Bytes())
+	sx, _ := curve.ScalarMult(x, y, ephD.Bytes())
 	sharedSecret := sx.Bytes()
 
 	// Ensure shared secret is correct length
@@ -271,7 +319,7 @@ func (p *eciesPrecompile) encrypt(curveID byte, input []byte) ([]byte, error) {
 	}
 
 	iv := make([]byte, aes.BlockSize)
-	if _, err := rand.Read(iv); err != nil {
+	if _, err := io.ReadFull(rng, iv); err != nil {
 		return nil, err
 	}
 
@@ -286,7 +334,7 @@ func (p *eciesPrecompile) encrypt(curveID byte, input []byte) ([]byte, error) {
 	tag := mac.Sum(nil)
 
 	// Serialize ephemeral public key
-	ephPub := elliptic.Marshal(curve, ephPriv.PublicKey.X, ephPriv.PublicKey.Y)
+	ephPub := elliptic.Marshal(curve, ephX, ephY)
 
 	// Output: ephemeral_pk || ciphertext || mac
 	result := make([]byte, len(ephPub)+len(ciphertext)+len(tag))
@@ -297,6 +345,19 @@ func (p *eciesPrecompile) encrypt(curveID byte, input []byte) ([]byte, error) {
 	return result, nil
 }
 
+// ephemeralScalar draws a private scalar in [1, N-1] from rng, reducing 64
+// extra bits so the bias is negligible
+func ephemeralScalar(curve elliptic.Curve, rng io.Reader) (*big.Int, error) {
+	params := curve.Params()
+	b := make([]byte, (params.BitSize+7)/8+8)
+	if _, err := io.ReadFull(rng, b); err != nil {
+		return nil, err
+	}
+	n := new(big.Int).Sub(params.N, big.NewInt(1))
+	k := new(big.Int).Mod(new(big.Int).SetBytes(b), n)
+	return k.Add(k, big.NewInt(1)), nil
+}
+
 func (p *eciesPrecompile) decrypt(curveID byte, input []byte) ([]byte, error) {
 	curve, err := p.getCurve(curveID)
 	if err != nil {
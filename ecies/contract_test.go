@@ -4,6 +4,7 @@
 package ecies
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -521,3 +522,26 @@ func BenchmarkECIES_ECDH_Secp256k1(b *testing.B) {
 		_, _, _ = ECIESPrecompile.Run(nil, common.Address{}, ContractAddress, ecdhInput, gas, false)
 	}
 }
+
+func TestECIES_EncryptFrom(t *testing.T) {
+	curve := secp256k1.S256()
+	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	require.NoError(t, err)
+	pubKey := elliptic.Marshal(curve, priv.PublicKey.X, priv.PublicKey.Y)
+	seed := bytes.Repeat([]byte{0x5a}, 64)
+
+	// The same stream yields the same ciphertext
+	ct1, err := EncryptFrom(bytes.NewReader(seed), CurveSecp256k1, pubKey, []byte("ctx"), []byte("sealed"))
+	require.NoError(t, err)
+	ct2, err := EncryptFrom(bytes.NewReader(seed), CurveSecp256k1, pubKey, []byte("ctx"), []byte("sealed"))
+	require.NoError(t, err)
+	require.Equal(t, ct1, ct2)
+
+	plaintext, err := Decrypt(CurveSecp256k1, common.LeftPadBytes(priv.D.Bytes(), 32), []byte("ctx"), ct1)
+	require.NoError(t, err)
+	require.Equal(t, []byte("sealed"), plaintext)
+
+	// A short stream fails
+	_, err = EncryptFrom(bytes.NewReader(seed[:8]), CurveSecp256k1, pubKey, nil, []byte("sealed"))
+	require.Error(t, err)
+}
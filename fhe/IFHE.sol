@@ -18,6 +18,17 @@ interface IFHE {
     // euint256 - encrypted 256-bit unsigned integer
     // eaddress - encrypted address (160 bits)
 
+    // ============ Errors ============
+
+    /// @notice An operand handle has no stored or pending ciphertext
+    error HandleNotFound(bytes32 handle);
+
+    /// @notice An operand handle has the wrong ciphertext type
+    error TypeMismatch(bytes32 handle, uint8 expected, uint8 actual);
+
+    /// @notice Evaluating an operation failed
+    error OperationFailed(string op, string reason);
+
     // ============ Encryption Operations ============
     
     /// @notice Encrypt a uint64 value
@@ -109,17 +120,111 @@ interface IFHE {
     /// @notice Rotate right
     function rotr(bytes32 a, bytes32 bits) external returns (bytes32 result);
 
+    /// @notice Shift left by an encrypted amount, taken modulo the bit width
+    function shlEnc(bytes32 a, bytes32 amount) external returns (bytes32 result);
+
+    /// @notice Shift right by an encrypted amount, taken modulo the bit width
+    function shrEnc(bytes32 a, bytes32 amount) external returns (bytes32 result);
+
+    /// @notice Rotate left by an encrypted amount
+    function rotlEnc(bytes32 a, bytes32 amount) external returns (bytes32 result);
+
+    /// @notice Rotate right by an encrypted amount
+    function rotrEnc(bytes32 a, bytes32 amount) external returns (bytes32 result);
+
     // ============ Conditional Operations ============
 
     /// @notice Conditional select: if condition then ifTrue else ifFalse
     function select(bytes32 condition, bytes32 ifTrue, bytes32 ifFalse) external returns (bytes32 result);
 
+    /// @notice Multi-way select: candidates[index], or an encrypted zero if index is out of range
+    /// @param index Encrypted unsigned index
+    /// @param candidates Candidate handles of one type
+    function selectN(bytes32 index, bytes32[] calldata candidates) external returns (bytes32 result);
+
+    // ============ Encrypted Arrays ============
+
+    /// @notice Pack ciphertexts of one type into an encrypted array
+    /// @param elements Element handles, at most 256
+    function arrayNew(bytes32[] calldata elements) external returns (bytes32 array);
+
+    /// @notice Read the element at an encrypted index, or an encrypted zero if out of range
+    /// @param array Array handle
+    /// @param index Encrypted unsigned index
+    function arrayGet(bytes32 array, bytes32 index) external returns (bytes32 result);
+
+    /// @notice Replace the element at an encrypted index
+    /// @param array Array handle
+    /// @param index Encrypted unsigned index
+    /// @param value Encrypted value of the element type
+    function arraySet(bytes32 array, bytes32 index, bytes32 value) external returns (bytes32 result);
+
+    /// @notice Public element count of an array
+    function arrayLength(bytes32 array) external view returns (uint256);
+
     // ============ Randomness ============
 
     /// @notice Generate encrypted random value of specified type
     /// @param ctType The ciphertext type (0=bool, 4=uint64, etc.)
     function rand(uint8 ctType) external returns (bytes32 result);
 
+    // ============ Sealed Outputs ============
+
+    /// @notice Reveal a value to the holder of a public key only
+    /// @param scheme 1 = ECIES/secp256k1 (65-byte key), 2 = HPKE/X25519 (32-byte key), 3 = ML-KEM-768 (1184-byte key)
+    /// @return sealed The plaintext as a 32-byte word, encrypted to publicKey
+    function sealOutput(bytes32 handle, uint8 scheme, bytes calldata publicKey) external returns (bytes memory sealed);
+
+    // ============ Auction Operations ============
+
+    /// @notice Compute the encrypted maximum bid and encrypted winning index
+    /// @dev Only the two returned handles may be decrypted via the gateway;
+    ///      the individual bids stay sealed. Ties resolve to the lowest index.
+    /// @param bids Encrypted bid handles (all of the same type, at most 256)
+    /// @return maxBid The encrypted maximum bid
+    /// @return winnerIndex The encrypted (euint32) index of the winning bid
+    function encMaxWithIndex(bytes32[] calldata bids) external returns (bytes32 maxBid, bytes32 winnerIndex);
+
+    // ============ Handle Metadata ============
+
+    /// @notice Ciphertext type of a handle; reverts for unknown handles
+    function typeOf(bytes32 handle) external view returns (uint8);
+
+    /// @notice Whether a handle refers to a stored or pending ciphertext
+    function exists(bytes32 handle) external view returns (bool);
+
+    /// @notice Serialized ciphertext size in bytes, 0 if unknown or pending
+    function sizeOf(bytes32 handle) external view returns (uint256);
+
+    /// @notice Network key epoch a ciphertext was stored under
+    function epochOf(bytes32 handle) external view returns (uint256);
+
+    /// @notice Active network key epoch
+    function keyEpoch() external view returns (uint256);
+
+    /// @notice Active network public key
+    function networkPublicKey() external view returns (bytes memory);
+
+    /// @notice Key-switch a stored ciphertext to the active epoch, keeping its handle
+    function migrate(bytes32 handle) external;
+
+    // ============ Storage ============
+
+    /// @notice Exempt a ciphertext from garbage collection, paying rent by size
+    function pin(bytes32 handle) external;
+
+    // ============ Coprocessor ============
+
+    /// @notice Finalize a queued compute job with the coprocessor's result
+    /// @dev Input is packed, not ABI-encoded: handle (32) || signature count (1) ||
+    ///      signatures (65 bytes each) || ciphertext. Signatures are over
+    ///      keccak256("lux.fhe.coprocessor.result.v1" || handle || keccak256(ciphertext))
+    ///      and must come from at least the configured threshold of attestors.
+    function postComputeResult(bytes32 handle, bytes calldata ciphertext, bytes calldata signatures) external returns (bytes32);
+
+    /// @notice Status of a compute job: 0 = unknown, 1 = pending, 2 = finalized
+    function computeStatus(bytes32 handle) external view returns (uint256 status);
+
     // ============ Type Casting ============
 
     /// @notice Cast encrypted value to different type
@@ -128,11 +233,15 @@ interface IFHE {
     // ============ Require Operations ============
 
     /// @notice Require that encrypted boolean is true, revert otherwise
-    /// @dev Triggers threshold decryption and blocks until result
+    /// @dev Decrypts the condition in the call; the revert reveals only its value
     function require_(bytes32 condition) external;
 
     /// @notice Require with custom error message
     function require_(bytes32 condition, string calldata message) external;
+
+    // Events
+    /// @notice Emitted per result handle of every operation, batch entries included
+    event FHEOperation(address indexed caller, bytes4 indexed op, bytes32 indexed result, bytes32[] inputs, uint256 gasUsed);
 }
 
 /**
@@ -0,0 +1,288 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+//go:generate go run ./cmd/fhesol -out FHE.sol
+
+// Precompile ABI.
+//
+// Methods is the dispatch table of the FHE precompile: Run routes calls
+// through it, and the Solidity library generator (GenerateSolidity) emits
+// its typed wrappers from it, so the contract-facing library and the
+// precompile cannot disagree on selectors or argument encoding. Most
+// methods take packed arguments rather than standard ABI encoding; each
+// method's Args describe exactly what its handler decodes.
+
+// ArgKind is the wire encoding of one method argument
+type ArgKind uint8
+
+const (
+	ArgHandle      ArgKind = iota // 32-byte ciphertext handle
+	ArgWord                       // 32-byte big-endian integer
+	ArgByte                       // Single packed byte
+	ArgBytes                      // Raw trailing bytes
+	ArgHandleArray                // ABI-encoded bytes32[]
+	ArgAddress                    // 32-byte ABI address word
+)
+
+// ResultKind is the encoding of a method's return data
+type ResultKind uint8
+
+const (
+	ResultNone       ResultKind = iota
+	ResultHandle                // 32-byte ciphertext handle
+	ResultHandlePair            // Two 32-byte handles
+	ResultUint                  // Minimal big-endian integer (up to 32 bytes)
+	ResultBytes                 // Raw bytes
+	ResultBool                  // 32-byte ABI bool
+	ResultAddress               // 32-byte ABI address
+	ResultWord                  // 32-byte word
+	ResultHandles               // ABI-encoded bytes32[] of handles
+)
+
+// OpClass determines how a method is typed in the Solidity library
+type OpClass uint8
+
+const (
+	OpSystem       OpClass = iota // Not wrapped (coprocessor and raw entry points)
+	OpBinary                      // (T, T) -> T
+	OpCompare                     // (T, T) -> ebool
+	OpUnary                       // T -> T
+	OpScalar                      // (T, uint256) -> T
+	OpShift                       // (T, uint8) -> T
+	OpShiftEnc                    // (T, euintN) -> T
+	OpSelect                      // (ebool, T, T) -> T
+	OpCast                        // T -> U
+	OpEncrypt                     // plaintext -> T
+	OpVerify                      // input ciphertext -> T
+	OpRandom                      // -> T
+	OpDecrypt                     // T -> plaintext
+	OpSealOutput                  // (T, scheme, public key) -> sealed bytes
+	OpMaxWithIndex                // T[] -> (T, euint32)
+	OpACL                         // ACL call on a handle of any type
+	OpDecryptAsync                // (T, callback selector) -> request ID
+	OpScalarCmp                   // (T, uint256) -> ebool
+	OpMulDiv                      // (T, T, uint256, rounding) -> T
+	OpScalarMulDiv                // (T, uint256, uint256, rounding) -> T
+	OpBatch                       // Encoded op list -> bytes32[]
+	OpSelectN                     // (euint32, T[]) -> T
+	OpRandBounded                 // uint256 bound -> T
+	OpHandleInfo                  // bytes32 -> handle metadata (view)
+	OpPin                         // T -> (), exempt from collection
+	OpArrayNew                    // T[] -> earray
+	OpArrayGet                    // (earray, euint32) -> T
+	OpArraySet                    // (earray, euint32, T) -> earray
+	OpArrayLen                    // earray -> uint256 (view)
+	OpRequire                     // ebool [, message], reverts if false
+)
+
+// TypeMask is a set of encrypted types a method accepts
+type TypeMask uint8
+
+const (
+	MaskBool TypeMask = 1 << iota
+	MaskUint
+	MaskAddress
+	MaskArray // Encrypted arrays, for methods on any handle
+
+	MaskAll = MaskBool | MaskUint | MaskAddress
+)
+
+// Method describes one precompile entry point
+type Method struct {
+	Name      string // Solidity function name
+	Signature string // Canonical signature (documentation)
+	Selector  [4]byte
+	Args      []ArgKind
+	Result    ResultKind
+	Class     OpClass
+	Types     TypeMask // Encrypted types the typed wrappers cover
+	CtType    uint8    // Produced type (OpEncrypt only)
+	View      bool     // Does not modify state
+	Persists  bool     // Has effects beyond its result handles; rejected in static calls
+
+	handler    func(*FHEContract, contract.AccessibleState, common.Address, []byte, uint64, bool) ([]byte, uint64, error)
+	aclHandler func(*ACL, common.Address, []byte) ([]byte, error) // ACLMethods only
+}
+
+// sel converts a selector literal to its array form
+func sel(s string) [4]byte {
+	return [4]byte([]byte(s))
+}
+
+var (
+	binaryArgs = []ArgKind{ArgHandle, ArgHandle}
+	unaryArgs  = []ArgKind{ArgHandle}
+	scalarArgs = []ArgKind{ArgHandle, ArgWord}
+	byteArgs   = []ArgKind{ArgHandle, ArgByte}
+	wordArg    = []ArgKind{ArgWord}
+)
+
+// Methods is the FHE precompile's dispatch table
+var Methods = []Method{
+	// Arithmetic operations
+	{Name: "add", Signature: "add(bytes32,bytes32)", Selector: sel("\x23\xb8\x72\xdd"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskUint, handler: (*FHEContract).handleAdd},
+	{Name: "sub", Signature: "sub(bytes32,bytes32)", Selector: sel("\x51\xca\xb0\x91"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskUint, handler: (*FHEContract).handleSub},
+	{Name: "mul", Signature: "mul(bytes32,bytes32)", Selector: sel("\xc8\xa4\xac\x9c"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskUint, handler: (*FHEContract).handleMul},
+	{Name: "div", Signature: "div(bytes32,bytes32)", Selector: sel("\x0f\x5e\x1b\x2a"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskUint, handler: (*FHEContract).handleDiv},
+	{Name: "rem", Signature: "rem(bytes32,bytes32)", Selector: sel("\x1e\x19\x1a\x96"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskUint, handler: (*FHEContract).handleRem},
+	{Name: "neg", Signature: "neg(bytes32)", Selector: sel("\xe4\x7e\xf3\xfc"), Args: unaryArgs, Result: ResultHandle, Class: OpUnary, Types: MaskUint, handler: (*FHEContract).handleNeg},
+
+	// Scalar arithmetic
+	{Name: "scalarAdd", Signature: "scalarAdd(bytes32,uint256)", Selector: sel("\xf5\xa7\x96\xfb"), Args: scalarArgs, Result: ResultHandle, Class: OpScalar, Types: MaskUint, handler: (*FHEContract).handleScalarAdd},
+	{Name: "scalarSub", Signature: "scalarSub(bytes32,uint256)", Selector: sel("\xb6\x3a\x9e\x11"), Args: scalarArgs, Result: ResultHandle, Class: OpScalar, Types: MaskUint, handler: (*FHEContract).handleScalarSub},
+	{Name: "scalarMul", Signature: "scalarMul(bytes32,uint256)", Selector: sel("\x3c\x96\x47\x95"), Args: scalarArgs, Result: ResultHandle, Class: OpScalar, Types: MaskUint, handler: (*FHEContract).handleScalarMul},
+	{Name: "scalarDiv", Signature: "scalarDiv(bytes32,uint256)", Selector: sel("\x7b\x8f\x4a\x2d"), Args: scalarArgs, Result: ResultHandle, Class: OpScalar, Types: MaskUint, handler: (*FHEContract).handleScalarDiv},
+	{Name: "scalarRem", Signature: "scalarRem(bytes32,uint256)", Selector: sel("\x52\x91\xa3\x21"), Args: scalarArgs, Result: ResultHandle, Class: OpScalar, Types: MaskUint, handler: (*FHEContract).handleScalarRem},
+
+	// Comparison operations
+	{Name: "lt", Signature: "lt(bytes32,bytes32)", Selector: sel("\xa9\x05\x9c\xbb"), Args: binaryArgs, Result: ResultHandle, Class: OpCompare, Types: MaskUint, handler: (*FHEContract).handleLt},
+	{Name: "le", Signature: "le(bytes32,bytes32)", Selector: sel("\x26\xa3\x31\x9e"), Args: binaryArgs, Result: ResultHandle, Class: OpCompare, Types: MaskUint, handler: (*FHEContract).handleLe},
+	{Name: "gt", Signature: "gt(bytes32,bytes32)", Selector: sel("\x4b\x64\xe4\x92"), Args: binaryArgs, Result: ResultHandle, Class: OpCompare, Types: MaskUint, handler: (*FHEContract).handleGt},
+	{Name: "ge", Signature: "ge(bytes32,bytes32)", Selector: sel("\x53\x1c\x19\xea"), Args: binaryArgs, Result: ResultHandle, Class: OpCompare, Types: MaskUint, handler: (*FHEContract).handleGe},
+	{Name: "eq", Signature: "eq(bytes32,bytes32)", Selector: sel("\x1c\xf4\x86\x63"), Args: binaryArgs, Result: ResultHandle, Class: OpCompare, Types: MaskAll, handler: (*FHEContract).handleEq},
+	{Name: "ne", Signature: "ne(bytes32,bytes32)", Selector: sel("\x14\x6e\x3a\x7e"), Args: binaryArgs, Result: ResultHandle, Class: OpCompare, Types: MaskAll, handler: (*FHEContract).handleNe},
+	{Name: "min", Signature: "min(bytes32,bytes32)", Selector: sel("\x7a\x8f\x63\xb8"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskUint, handler: (*FHEContract).handleMin},
+	{Name: "max", Signature: "max(bytes32,bytes32)", Selector: sel("\x6e\x32\x91\x28"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskUint, handler: (*FHEContract).handleMax},
+
+	// Scalar comparisons
+	{Name: "scalarLt", Signature: "scalarLt(bytes32,uint256)", Selector: sel("\x2d\x03\x8f\x2f"), Args: scalarArgs, Result: ResultHandle, Class: OpScalarCmp, Types: MaskUint, handler: (*FHEContract).handleScalarLt},
+	{Name: "scalarLe", Signature: "scalarLe(bytes32,uint256)", Selector: sel("\x0c\x4c\x52\x4a"), Args: scalarArgs, Result: ResultHandle, Class: OpScalarCmp, Types: MaskUint, handler: (*FHEContract).handleScalarLe},
+	{Name: "scalarGt", Signature: "scalarGt(bytes32,uint256)", Selector: sel("\x6b\x88\xe9\x22"), Args: scalarArgs, Result: ResultHandle, Class: OpScalarCmp, Types: MaskUint, handler: (*FHEContract).handleScalarGt},
+	{Name: "scalarGe", Signature: "scalarGe(bytes32,uint256)", Selector: sel("\xa6\x5c\x70\xce"), Args: scalarArgs, Result: ResultHandle, Class: OpScalarCmp, Types: MaskUint, handler: (*FHEContract).handleScalarGe},
+	{Name: "scalarEq", Signature: "scalarEq(bytes32,uint256)", Selector: sel("\x66\x57\xc2\xe1"), Args: scalarArgs, Result: ResultHandle, Class: OpScalarCmp, Types: MaskUint, handler: (*FHEContract).handleScalarEq},
+	{Name: "scalarNe", Signature: "scalarNe(bytes32,uint256)", Selector: sel("\x99\xa8\x25\xa2"), Args: scalarArgs, Result: ResultHandle, Class: OpScalarCmp, Types: MaskUint, handler: (*FHEContract).handleScalarNe},
+
+	// Fixed-point arithmetic
+	{Name: "mulDiv", Signature: "mulDiv(bytes32,bytes32,uint256,uint8)", Selector: sel("\x76\x88\x37\xeb"), Args: []ArgKind{ArgHandle, ArgHandle, ArgWord, ArgByte}, Result: ResultHandle, Class: OpMulDiv, Types: MaskUint, handler: (*FHEContract).handleMulDiv},
+	{Name: "scalarMulDiv", Signature: "scalarMulDiv(bytes32,uint256,uint256,uint8)", Selector: sel("\x1a\xa0\x24\xa4"), Args: []ArgKind{ArgHandle, ArgWord, ArgWord, ArgByte}, Result: ResultHandle, Class: OpScalarMulDiv, Types: MaskUint, handler: (*FHEContract).handleScalarMulDiv},
+	{Name: "scaledDiv", Signature: "scaledDiv(bytes32,bytes32,uint256,uint8)", Selector: sel("\x2a\xd4\x7e\x9c"), Args: []ArgKind{ArgHandle, ArgHandle, ArgWord, ArgByte}, Result: ResultHandle, Class: OpMulDiv, Types: MaskUint, handler: (*FHEContract).handleScaledDiv},
+
+	// Bitwise operations
+	{Name: "and", Signature: "and(bytes32,bytes32)", Selector: sel("\xcd\x30\x32\x00"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskBool | MaskUint, handler: (*FHEContract).handleAnd},
+	{Name: "or", Signature: "or(bytes32,bytes32)", Selector: sel("\x5a\x6b\x26\xba"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskBool | MaskUint, handler: (*FHEContract).handleOr},
+	{Name: "xor", Signature: "xor(bytes32,bytes32)", Selector: sel("\xf6\x74\x70\x22"), Args: binaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskBool | MaskUint, handler: (*FHEContract).handleXor},
+	{Name: "not", Signature: "not(bytes32)", Selector: sel("\x6b\x3a\x00\x11"), Args: unaryArgs, Result: ResultHandle, Class: OpUnary, Types: MaskBool | MaskUint, handler: (*FHEContract).handleNot},
+
+	// Shift operations
+	{Name: "shl", Signature: "shl(bytes32,uint8)", Selector: sel("\x3e\x8c\x6c\x10"), Args: byteArgs, Result: ResultHandle, Class: OpShift, Types: MaskUint, handler: (*FHEContract).handleShl},
+	{Name: "shr", Signature: "shr(bytes32,uint8)", Selector: sel("\x5f\x46\xe5\x15"), Args: byteArgs, Result: ResultHandle, Class: OpShift, Types: MaskUint, handler: (*FHEContract).handleShr},
+	{Name: "rotl", Signature: "rotl(bytes32,uint8)", Selector: sel("\x89\xa1\x9e\x6b"), Args: byteArgs, Result: ResultHandle, Class: OpShift, Types: MaskUint, handler: (*FHEContract).handleRotl},
+	{Name: "rotr", Signature: "rotr(bytes32,uint8)", Selector: sel("\xd7\x25\x1c\xb9"), Args: byteArgs, Result: ResultHandle, Class: OpShift, Types: MaskUint, handler: (*FHEContract).handleRotr},
+	{Name: "shlEnc", Signature: "shlEnc(bytes32,bytes32)", Selector: sel("\x66\x7b\x3d\x14"), Args: binaryArgs, Result: ResultHandle, Class: OpShiftEnc, Types: MaskUint, handler: (*FHEContract).handleShlEnc},
+	{Name: "shrEnc", Signature: "shrEnc(bytes32,bytes32)", Selector: sel("\xa2\x02\xa8\x92"), Args: binaryArgs, Result: ResultHandle, Class: OpShiftEnc, Types: MaskUint, handler: (*FHEContract).handleShrEnc},
+	{Name: "rotlEnc", Signature: "rotlEnc(bytes32,bytes32)", Selector: sel("\x50\xa2\x67\x27"), Args: binaryArgs, Result: ResultHandle, Class: OpShiftEnc, Types: MaskUint, handler: (*FHEContract).handleRotlEnc},
+	{Name: "rotrEnc", Signature: "rotrEnc(bytes32,bytes32)", Selector: sel("\x29\xb5\x2a\xcf"), Args: binaryArgs, Result: ResultHandle, Class: OpShiftEnc, Types: MaskUint, handler: (*FHEContract).handleRotrEnc},
+
+	// Selection and casting
+	{Name: "select", Signature: "select(bytes32,bytes32,bytes32)", Selector: sel("\x2e\x17\xde\x78"), Args: []ArgKind{ArgHandle, ArgHandle, ArgHandle}, Result: ResultHandle, Class: OpSelect, Types: MaskAll, handler: (*FHEContract).handleSelect},
+	{Name: "selectN", Signature: "selectN(bytes32,bytes32[])", Selector: sel("\x03\xfd\x4b\xcd"), Args: []ArgKind{ArgHandle, ArgHandleArray}, Result: ResultHandle, Class: OpSelectN, Types: MaskAll, handler: (*FHEContract).handleSelectN},
+	{Name: "cast", Signature: "cast(bytes32,uint8)", Selector: sel("\xae\xd2\x44\x6b"), Args: byteArgs, Result: ResultHandle, Class: OpCast, Types: MaskBool | MaskUint, handler: (*FHEContract).handleCast},
+
+	// Encryption operations
+	{Name: "asEuint64", Signature: "asEuint64(uint64)", Selector: sel("\xa5\x17\x5c\x89"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEuint64, handler: (*FHEContract).handleAsEuint64},
+	{Name: "asEaddress", Signature: "asEaddress(address)", Selector: sel("\xd4\x3f\x02\x80"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEaddress, handler: (*FHEContract).handleAsEaddress},
+	{Name: "asEbool", Signature: "asEbool(bool)", Selector: sel("\x8c\x3f\x5a\x42"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEbool, handler: (*FHEContract).handleAsEbool},
+	{Name: "asEuint4", Signature: "asEuint4(uint8)", Selector: sel("\x2d\xfa\x48\x63"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEuint4, handler: (*FHEContract).handleAsEuint4},
+	{Name: "asEuint8", Signature: "asEuint8(uint8)", Selector: sel("\x64\xc1\x51\x81"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEuint8, handler: (*FHEContract).handleAsEuint8},
+	{Name: "asEuint16", Signature: "asEuint16(uint16)", Selector: sel("\xf8\x91\x08\x50"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEuint16, handler: (*FHEContract).handleAsEuint16},
+	{Name: "asEuint32", Signature: "asEuint32(uint32)", Selector: sel("\x6c\xa9\xea\xe9"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEuint32, handler: (*FHEContract).handleAsEuint32},
+	{Name: "asEuint128", Signature: "asEuint128(uint256)", Selector: sel("\x7d\x6d\x81\x95"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEuint128, handler: (*FHEContract).handleAsEuint128},
+	{Name: "asEuint256", Signature: "asEuint256(uint256)", Selector: sel("\x9e\x5b\x2e\xf3"), Args: wordArg, Result: ResultHandle, Class: OpEncrypt, CtType: TypeEuint256, handler: (*FHEContract).handleAsEuint256},
+
+	// Utility operations
+	{Name: "rand", Signature: "rand(uint8)", Selector: sel("\x71\x5a\xd3\x11"), Args: []ArgKind{ArgByte}, Result: ResultHandle, Class: OpRandom, Types: MaskBool | MaskUint, handler: (*FHEContract).handleRand},
+	{Name: "randBounded", Signature: "randBounded(uint8,uint256)", Selector: sel("\xd5\x92\xa8\x3b"), Args: []ArgKind{ArgByte, ArgWord}, Result: ResultHandle, Class: OpRandBounded, Types: MaskUint, handler: (*FHEContract).handleRandBounded},
+	{Name: "decrypt", Signature: "decrypt(bytes32)", Selector: sel("\x12\x3d\x4c\x87"), Args: unaryArgs, Result: ResultUint, Class: OpDecrypt, Types: MaskAll, handler: (*FHEContract).handleDecrypt},
+	{Name: "verify", Signature: "verify(bytes,uint8)", Selector: sel("\x45\xa9\x32\x18"), Args: []ArgKind{ArgByte, ArgBytes}, Result: ResultHandle, Class: OpVerify, Types: MaskAll, Persists: true, handler: (*FHEContract).handleVerify},
+	{Name: "require_", Signature: "require_(bytes32)", Selector: sel("\x5f\xe9\x5c\xd2"), Args: unaryArgs, Result: ResultNone, Class: OpRequire, Types: MaskBool, handler: (*FHEContract).handleRequire},
+	{Name: "require_", Signature: "require_(bytes32,string)", Selector: sel("\x14\xfc\xc2\x2d"), Args: []ArgKind{ArgHandle, ArgBytes}, Result: ResultNone, Class: OpRequire, Types: MaskBool, handler: (*FHEContract).handleRequire},
+	{Name: "requestDecryption", Signature: "requestDecryption(bytes32,bytes4)", Selector: sel("\x90\xaa\x1b\x60"), Args: []ArgKind{ArgHandle, ArgWord}, Result: ResultWord, Class: OpDecryptAsync, Types: MaskAll, Persists: true, handler: (*FHEContract).handleRequestDecryption},
+	{Name: "sealOutput", Signature: "sealOutput(bytes32,uint8,bytes)", Selector: sel("\xf1\x6e\xba\x61"), Args: []ArgKind{ArgHandle, ArgByte, ArgBytes}, Result: ResultBytes, Class: OpSealOutput, Types: MaskAll, handler: (*FHEContract).handleSealOutput},
+
+	// Handle metadata
+	{Name: "typeOf", Signature: "typeOf(bytes32)", Selector: sel("\x5a\x94\x61\x92"), Args: wordArg, Result: ResultWord, Class: OpHandleInfo, View: true, handler: (*FHEContract).handleTypeOf},
+	{Name: "exists", Signature: "exists(bytes32)", Selector: sel("\x38\xa6\x99\xa4"), Args: wordArg, Result: ResultBool, Class: OpHandleInfo, View: true, handler: (*FHEContract).handleExists},
+	{Name: "sizeOf", Signature: "sizeOf(bytes32)", Selector: sel("\xfd\x97\x0b\xfb"), Args: wordArg, Result: ResultWord, Class: OpHandleInfo, View: true, handler: (*FHEContract).handleSizeOf},
+	{Name: "epochOf", Signature: "epochOf(bytes32)", Selector: sel("\xd3\xb8\xfe\x9b"), Args: wordArg, Result: ResultWord, Class: OpHandleInfo, View: true, handler: (*FHEContract).handleEpochOf},
+
+	// Network key
+	{Name: "keyEpoch", Signature: "keyEpoch()", Selector: sel("\x6f\xdf\x65\x7e"), Result: ResultWord, Class: OpSystem, View: true, handler: (*FHEContract).handleKeyEpoch},
+	{Name: "networkPublicKey", Signature: "networkPublicKey()", Selector: sel("\x80\x64\x43\xfa"), Result: ResultBytes, Class: OpSystem, View: true, handler: (*FHEContract).handleNetworkPublicKey},
+	{Name: "migrate", Signature: "migrate(bytes32)", Selector: sel("\xe1\x9b\x8e\xe3"), Args: wordArg, Result: ResultNone, Class: OpSystem, handler: (*FHEContract).handleMigrate},
+
+	// Ciphertext maintenance
+	{Name: "rerandomize", Signature: "rerandomize(bytes32)", Selector: sel("\x71\xee\x46\xe9"), Args: unaryArgs, Result: ResultHandle, Class: OpUnary, Types: MaskAll, handler: (*FHEContract).handleRerandomize},
+	{Name: "refresh", Signature: "refresh(bytes32)", Selector: sel("\xdc\x4c\xfa\x2f"), Args: unaryArgs, Result: ResultHandle, Class: OpUnary, Types: MaskAll, handler: (*FHEContract).handleRefresh},
+	{Name: "pin", Signature: "pin(bytes32)", Selector: sel("\xd3\x93\x0f\x85"), Args: unaryArgs, Result: ResultNone, Class: OpPin, Types: MaskAll | MaskArray, handler: (*FHEContract).handlePin},
+
+	// Batched operations
+	{Name: "batch", Signature: "batch(bytes)", Selector: sel("\x26\x88\x7f\x26"), Args: []ArgKind{ArgBytes}, Result: ResultHandles, Class: OpBatch, handler: (*FHEContract).handleBatch},
+
+	// Encrypted arrays
+	{Name: "arrayNew", Signature: "arrayNew(bytes32[])", Selector: sel("\xb0\x6b\x95\x20"), Args: []ArgKind{ArgHandleArray}, Result: ResultHandle, Class: OpArrayNew, Types: MaskAll, handler: (*FHEContract).handleArrayNew},
+	{Name: "arrayGet", Signature: "arrayGet(bytes32,bytes32)", Selector: sel("\x35\xe1\x96\x9c"), Args: binaryArgs, Result: ResultHandle, Class: OpArrayGet, Types: MaskAll, handler: (*FHEContract).handleArrayGet},
+	{Name: "arraySet", Signature: "arraySet(bytes32,bytes32,bytes32)", Selector: sel("\x80\xbd\x37\x8d"), Args: []ArgKind{ArgHandle, ArgHandle, ArgHandle}, Result: ResultHandle, Class: OpArraySet, Types: MaskAll, handler: (*FHEContract).handleArraySet},
+	{Name: "arrayLength", Signature: "arrayLength(bytes32)", Selector: sel("\x75\xe8\x3c\x57"), Args: wordArg, Result: ResultWord, Class: OpArrayLen, Types: MaskArray, View: true, handler: (*FHEContract).handleArrayLength},
+
+	// Auction operations
+	{Name: "encMaxWithIndex", Signature: "encMaxWithIndex(bytes32[])", Selector: sel("\x21\x72\x05\x96"), Args: []ArgKind{ArgHandleArray}, Result: ResultHandlePair, Class: OpMaxWithIndex, Types: MaskUint, handler: (*FHEContract).handleMaxWithIndex},
+
+	// Coprocessor operations
+	{Name: "postComputeResult", Signature: "postComputeResult(bytes32,bytes,bytes)", Selector: sel("\x46\xbc\x87\xdc"), Args: []ArgKind{ArgHandle, ArgBytes}, Result: ResultHandle, Class: OpSystem, Persists: true, handler: (*FHEContract).handlePostComputeResult},
+	{Name: "computeStatus", Signature: "computeStatus(bytes32)", Selector: sel("\xfd\x70\x2f\x86"), Args: unaryArgs, Result: ResultUint, Class: OpSystem, View: true, handler: (*FHEContract).handleComputeStatus},
+
+	// Decryption oracle operations
+	{Name: "fulfillDecryption", Signature: "fulfillDecryption(bytes32,uint256,bytes)", Selector: sel("\xce\x3e\x86\xd4"), Args: []ArgKind{ArgWord, ArgWord, ArgBytes}, Result: ResultBytes, Class: OpSystem, Persists: true, handler: (*FHEContract).handleFulfillDecryption},
+	{Name: "decryptionStatus", Signature: "decryptionStatus(bytes32)", Selector: sel("\x67\x53\xc3\x9a"), Args: wordArg, Result: ResultUint, Class: OpSystem, View: true, handler: (*FHEContract).handleDecryptionStatus},
+}
+
+// methodsBySelector indexes Methods for dispatch
+var methodsBySelector = indexMethods(Methods)
+
+func indexMethods(methods []Method) map[[4]byte]*Method {
+	index := make(map[[4]byte]*Method, len(methods))
+	for i := range methods {
+		if _, dup := index[methods[i].Selector]; dup {
+			panic("fhe: duplicate selector for " + methods[i].Signature)
+		}
+		index[methods[i].Selector] = &methods[i]
+	}
+	return index
+}
+
+// abiSelector returns the standard Solidity selector of a signature
+func abiSelector(signature string) [4]byte {
+	return [4]byte(crypto.Keccak256([]byte(signature))[:4])
+}
+
+// ACLMethods is the ABI of the ACL precompile at ACLContractAddress. It
+// uses standard ABI encoding and selectors.
+var ACLMethods = []Method{
+	{Name: "allow", Signature: "allow(bytes32,address)", Args: []ArgKind{ArgHandle, ArgAddress}, Class: OpACL, Types: MaskAll | MaskArray, aclHandler: aclHandleAllow},
+	{Name: "allowThis", Signature: "allowThis(bytes32)", Args: unaryArgs, Class: OpACL, Types: MaskAll | MaskArray, aclHandler: aclHandleAllowThis},
+	{Name: "allowTransient", Signature: "allowTransient(bytes32,address)", Args: []ArgKind{ArgHandle, ArgAddress}, Class: OpACL, Types: MaskAll | MaskArray, aclHandler: aclHandleAllowTransient},
+	{Name: "allowForAll", Signature: "allowForAll(bytes32)", Args: unaryArgs, Class: OpACL, Types: MaskAll | MaskArray, aclHandler: aclHandleAllowForAll},
+	{Name: "isAllowed", Signature: "isAllowed(bytes32,address)", Args: []ArgKind{ArgHandle, ArgAddress}, Result: ResultBool, Class: OpACL, Types: MaskAll | MaskArray, View: true, aclHandler: aclHandleIsAllowed},
+	{Name: "revoke", Signature: "revoke(bytes32,address)", Args: []ArgKind{ArgHandle, ArgAddress}, Class: OpACL, Types: MaskAll | MaskArray, aclHandler: aclHandleRevoke},
+	{Name: "revokeForAll", Signature: "revokeForAll(bytes32)", Args: unaryArgs, Class: OpACL, Types: MaskAll | MaskArray, aclHandler: aclHandleRevokeForAll},
+	{Name: "getOwner", Signature: "getOwner(bytes32)", Args: unaryArgs, Result: ResultAddress, Class: OpACL, Types: MaskAll | MaskArray, View: true, aclHandler: aclHandleGetOwner},
+	{Name: "transferOwnership", Signature: "transferOwnership(bytes32,address)", Args: []ArgKind{ArgHandle, ArgAddress}, Class: OpACL, Types: MaskAll | MaskArray, aclHandler: aclHandleTransferOwnership},
+}
+
+// aclMethodsBySelector indexes ACLMethods for dispatch
+var aclMethodsBySelector map[[4]byte]*Method
+
+func init() {
+	for i := range ACLMethods {
+		ACLMethods[i].Selector = abiSelector(ACLMethods[i].Signature)
+	}
+	aclMethodsBySelector = indexMethods(ACLMethods)
+}
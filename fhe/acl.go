@@ -0,0 +1,368 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"errors"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Access control for ciphertext handles.
+//
+// A handle may only be used as an operand, decrypted or sealed by accounts
+// the ACL allows. Every handle an operation produces is owned by its first
+// producer and allowed to the calling account for the rest of the
+// transaction. A contract that keeps a handle across transactions persists
+// its permission with allowThis, and shares it with allow (persistent) or
+// allowTransient (this transaction only). The owner may revoke accounts,
+// make a handle public with allowForAll, or hand ownership on. Results of
+// sealed computations such as encMaxWithIndex are marked decryptable:
+// anyone may request their decryption through the gateway, while using
+// them as operands stays restricted to allowed accounts.
+//
+// Permissions live in the storage of the ACL precompile account, so they
+// are reverted with the transaction that granted them. Transient grants are
+// kept in the account's EIP-1153 transient storage and lapse when the
+// transaction ends. Calls without a StateDB (tools and tests) are not
+// access controlled.
+
+// ACL gas costs
+const (
+	GasACLCheck uint64 = 2600  // Permission lookup per input handle
+	GasACLWrite uint64 = 22100 // Permission or ownership update
+	GasACLQuery uint64 = 2600  // isAllowed and getOwner
+)
+
+// Domain separators for ACL storage slots
+const (
+	aclOwnerDomain     = "lux.fhe.acl.owner.v1"
+	aclAllowDomain     = "lux.fhe.acl.allow.v1"
+	aclTransientDomain = "lux.fhe.acl.transient.v1"
+	aclPublicDomain    = "lux.fhe.acl.public.v1"
+	aclDecryptDomain   = "lux.fhe.acl.decryptable.v1"
+)
+
+var (
+	ErrACLDenied      = errors.New("caller not allowed on ciphertext handle")
+	ErrNotHandleOwner = errors.New("caller does not own ciphertext handle")
+	ErrACLNoState     = errors.New("ACL requires state")
+)
+
+// aclTrue marks a set permission slot
+var aclTrue = common.Hash{31: 1}
+
+// ACL reads and writes handle permissions in a StateDB
+type ACL struct {
+	db   contract.StateDB
+	addr common.Address
+}
+
+// NewACL keeps handle permissions in the ACL precompile's storage
+func NewACL(db contract.StateDB) *ACL {
+	return &ACL{db: db, addr: ACLContractAddress}
+}
+
+// aclFor returns the ACL of a precompile call, or nil when the call has no
+// state
+func aclFor(state contract.AccessibleState) *ACL {
+	if state == nil {
+		return nil
+	}
+	if db := state.GetStateDB(); db != nil {
+		return NewACL(db)
+	}
+	return nil
+}
+
+// Owner returns the owner of handle, or the zero address if none
+func (a *ACL) Owner(handle common.Hash) common.Address {
+	return common.BytesToAddress(a.db.GetState(a.addr, crypto.Keccak256Hash([]byte(aclOwnerDomain), handle.Bytes())).Bytes())
+}
+
+// IsAllowed reports whether account may use handle
+func (a *ACL) IsAllowed(handle common.Hash, account common.Address) bool {
+	return a.db.GetState(a.addr, a.publicSlot(handle)) == aclTrue ||
+		a.db.GetState(a.addr, a.allowSlot(handle, account)) == aclTrue ||
+		a.db.GetTransientState(a.addr, a.transientSlot(handle, account)) == aclTrue
+}
+
+// MarkDecryptable lets any account request the decryption of handle
+// through the gateway
+func (a *ACL) MarkDecryptable(handle common.Hash) {
+	a.db.SetState(a.addr, a.decryptableSlot(handle), aclTrue)
+}
+
+// IsDecryptable reports whether any account may request the decryption of
+// handle
+func (a *ACL) IsDecryptable(handle common.Hash) bool {
+	return a.db.GetState(a.addr, a.decryptableSlot(handle)) == aclTrue
+}
+
+// Allow persistently allows account to use handle. The caller must itself
+// be allowed.
+func (a *ACL) Allow(caller common.Address, handle common.Hash, account common.Address) error {
+	if !a.IsAllowed(handle, caller) {
+		return ErrACLDenied
+	}
+	a.setPersistent(handle, a.allowSlot(handle, account), true)
+	return nil
+}
+
+// AllowTransient allows account to use handle until the end of the current
+// transaction. The caller must itself be allowed.
+func (a *ACL) AllowTransient(caller common.Address, handle common.Hash, account common.Address) error {
+	if !a.IsAllowed(handle, caller) {
+		return ErrACLDenied
+	}
+	a.db.SetTransientState(a.addr, a.transientSlot(handle, account), aclTrue)
+	return nil
+}
+
+// AllowForAll makes handle usable by every account
+func (a *ACL) AllowForAll(caller common.Address, handle common.Hash) error {
+	if a.Owner(handle) != caller {
+		return ErrNotHandleOwner
+	}
+	a.setPersistent(handle, a.publicSlot(handle), true)
+	return nil
+}
+
+// Revoke removes the persistent and transient permission of account
+func (a *ACL) Revoke(caller common.Address, handle common.Hash, account common.Address) error {
+	if a.Owner(handle) != caller {
+		return ErrNotHandleOwner
+	}
+	a.setPersistent(handle, a.allowSlot(handle, account), false)
+	a.db.SetTransientState(a.addr, a.transientSlot(handle, account), common.Hash{})
+	return nil
+}
+
+// RevokeForAll undoes AllowForAll
+func (a *ACL) RevokeForAll(caller common.Address, handle common.Hash) error {
+	if a.Owner(handle) != caller {
+		return ErrNotHandleOwner
+	}
+	a.setPersistent(handle, a.publicSlot(handle), false)
+	return nil
+}
+
+// TransferOwnership hands the owner rights of handle to newOwner
+func (a *ACL) TransferOwnership(caller common.Address, handle common.Hash, newOwner common.Address) error {
+	if a.Owner(handle) != caller || newOwner == (common.Address{}) {
+		return ErrNotHandleOwner
+	}
+	a.setOwner(handle, newOwner)
+	return nil
+}
+
+// grantResult records producer as the owner of a new handle, unless it
+// already has one, and allows it for the rest of the transaction
+func (a *ACL) grantResult(handle common.Hash, producer common.Address) {
+	if a.Owner(handle) == (common.Address{}) {
+		a.setOwner(handle, producer)
+	}
+	a.db.SetTransientState(a.addr, a.transientSlot(handle, producer), aclTrue)
+}
+
+// setPersistent sets or clears a persistent permission slot, counting set
+// slots as references that keep the ciphertext from collection
+func (a *ACL) setPersistent(handle, slot common.Hash, set bool) {
+	if (a.db.GetState(a.addr, slot) == aclTrue) == set {
+		return
+	}
+	if set {
+		a.db.SetState(a.addr, slot, aclTrue)
+		addLeaseRef(a.db, handle, 1)
+	} else {
+		a.db.SetState(a.addr, slot, common.Hash{})
+		addLeaseRef(a.db, handle, -1)
+	}
+}
+
+func (a *ACL) setOwner(handle common.Hash, owner common.Address) {
+	a.db.SetState(a.addr, crypto.Keccak256Hash([]byte(aclOwnerDomain), handle.Bytes()), common.BytesToHash(owner.Bytes()))
+}
+
+func (a *ACL) allowSlot(handle common.Hash, account common.Address) common.Hash {
+	return crypto.Keccak256Hash([]byte(aclAllowDomain), handle.Bytes(), account.Bytes())
+}
+
+func (a *ACL) transientSlot(handle common.Hash, account common.Address) common.Hash {
+	return crypto.Keccak256Hash([]byte(aclTransientDomain), handle.Bytes(), account.Bytes())
+}
+
+func (a *ACL) publicSlot(handle common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte(aclPublicDomain), handle.Bytes())
+}
+
+func (a *ACL) decryptableSlot(handle common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte(aclDecryptDomain), handle.Bytes())
+}
+
+// inputHandles returns the ciphertext handles a method reads from its
+// packed arguments. Truncated input yields the handles decoded so far; the
+// handler rejects it.
+func inputHandles(m *Method, data []byte) []common.Hash {
+	var handles []common.Hash
+	off := 0
+	for _, kind := range m.Args {
+		switch kind {
+		case ArgHandle:
+			if len(data) < off+32 {
+				return handles
+			}
+			handles = append(handles, common.BytesToHash(data[off:off+32]))
+			off += 32
+		case ArgHandleArray:
+			array, err := decodeHandleArrayArg(data, off)
+			if err != nil {
+				return handles
+			}
+			return append(handles, array...)
+		case ArgWord, ArgAddress:
+			off += 32
+		case ArgByte:
+			off++
+		case ArgBytes:
+			return handles
+		}
+	}
+	return handles
+}
+
+// resultHandleCount returns the number of handles a method returns
+func resultHandleCount(m *Method) int {
+	switch m.Result {
+	case ResultHandle:
+		return 1
+	case ResultHandlePair:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// runWithACL runs method for caller, rejecting input handles the caller
+// may not use and granting it the handles the method produces
+func (c *FHEContract) runWithACL(acl *ACL, m *Method, state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	inputs := inputHandles(m, data)
+	results := resultHandleCount(m)
+	required := GasACLCheck*uint64(len(inputs)) + GasACLWrite*uint64(results)
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+	for _, h := range inputs {
+		if acl.IsAllowed(h, caller) || (m.Class == OpDecryptAsync && acl.IsDecryptable(h)) {
+			continue
+		}
+		return nil, gas, ErrACLDenied
+	}
+
+	ret, remaining, err := m.handler(c, state, caller, data, gas-required, readOnly)
+	if err != nil {
+		return ret, remaining, err
+	}
+	for i := 0; i < results && len(ret) >= (i+1)*32; i++ {
+		if h := common.BytesToHash(ret[i*32 : (i+1)*32]); h != (common.Hash{}) {
+			acl.grantResult(h, caller)
+		}
+	}
+	return ret, remaining, nil
+}
+
+// ACLContract implements the ACL precompile at ACLContractAddress
+type ACLContract struct{}
+
+// Run executes an ACL call
+func (c *ACLContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, ErrInvalidInput
+	}
+	method, ok := aclMethodsBySelector[[4]byte(input[:4])]
+	if !ok {
+		return nil, suppliedGas, ErrNotImplemented
+	}
+	if readOnly && !method.View {
+		return nil, suppliedGas, ErrWriteProtection
+	}
+	acl := aclFor(accessibleState)
+	if acl == nil {
+		return nil, suppliedGas, ErrACLNoState
+	}
+
+	data := input[4:]
+	if len(data) < 32*len(method.Args) {
+		return nil, suppliedGas, ErrInvalidInput
+	}
+	cost := GasACLWrite
+	if method.View {
+		cost = GasACLQuery
+	}
+	if suppliedGas < cost {
+		return nil, suppliedGas, ErrInsufficientGas
+	}
+
+	ret, err = method.aclHandler(acl, caller, data)
+	if err != nil {
+		return nil, suppliedGas - cost, err
+	}
+	return ret, suppliedGas - cost, nil
+}
+
+// ACL method handlers. Arguments are standard ABI words: the handle, then
+// the account where the method takes one.
+
+func aclAccount(data []byte) common.Address {
+	return common.BytesToAddress(data[44:64])
+}
+
+func aclHandleAllow(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	return nil, acl.Allow(caller, common.BytesToHash(data[:32]), aclAccount(data))
+}
+
+func aclHandleAllowThis(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	return nil, acl.Allow(caller, common.BytesToHash(data[:32]), caller)
+}
+
+func aclHandleAllowTransient(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	return nil, acl.AllowTransient(caller, common.BytesToHash(data[:32]), aclAccount(data))
+}
+
+func aclHandleAllowForAll(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	return nil, acl.AllowForAll(caller, common.BytesToHash(data[:32]))
+}
+
+func aclHandleIsAllowed(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	var ret common.Hash
+	if acl.IsAllowed(common.BytesToHash(data[:32]), aclAccount(data)) {
+		ret = aclTrue
+	}
+	return ret.Bytes(), nil
+}
+
+func aclHandleRevoke(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	return nil, acl.Revoke(caller, common.BytesToHash(data[:32]), aclAccount(data))
+}
+
+func aclHandleRevokeForAll(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	return nil, acl.RevokeForAll(caller, common.BytesToHash(data[:32]))
+}
+
+func aclHandleGetOwner(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	return common.BytesToHash(acl.Owner(common.BytesToHash(data[:32])).Bytes()).Bytes(), nil
+}
+
+func aclHandleTransferOwnership(acl *ACL, caller common.Address, data []byte) ([]byte, error) {
+	return nil, acl.TransferOwnership(caller, common.BytesToHash(data[:32]), aclAccount(data))
+}
@@ -0,0 +1,300 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Encrypted arrays.
+//
+// An encrypted array packs up to MaxArrayLength ciphertexts of one type
+// under a single handle, so an order book or a set of sealed bids needs
+// one handle and one ACL grant instead of one per element. Its type is
+// TypeArray | element type, and its stored bytes are
+//
+//	count (2) || (length (4) || ciphertext) per element
+//
+// The length is public; the elements and the positions read and written
+// are not. arrayGet(array, index) reads the element at an encrypted index
+// with the CMUX tree of selectN, and yields an encryption of zero for an
+// index past the end. arraySet(array, index, value) returns a new array in
+// which every element is select(index == i, value, element), so the
+// position written stays hidden. Indexes are unsigned and wide enough to
+// address every element. Without a StateDB, array handles are derived from
+// the packed bytes under their own domain, so they never collide with
+// element handles. Like
+// selectN, array operations run inline even in coprocessor mode.
+
+// TypeArray flags an encrypted array type; the low bits hold the element
+// type
+const TypeArray uint8 = 0x80
+
+// MaxArrayLength bounds the number of elements of an encrypted array
+const MaxArrayLength = 256
+
+// Array gas costs
+const (
+	GasArrayNew        uint64 = 20000
+	GasArrayPerElement uint64 = 2000 // Packing or rewriting one element
+)
+
+// arrayHandleDomain separates array handles from ciphertext handles
+const arrayHandleDomain = "lux.fhe.array.v1"
+
+// isArrayType reports whether ctType is an encrypted array
+func isArrayType(ctType uint8) bool {
+	return ctType&TypeArray != 0
+}
+
+// arrayNewGas returns the gas of packing n elements
+func arrayNewGas(n uint64) uint64 {
+	return GasArrayNew + GasArrayPerElement*n
+}
+
+// arraySetGas returns the gas of writing into an array of n elements: an eq
+// and a select per element
+func arraySetGas(n uint64) uint64 {
+	return (GasEq+GasSelect+GasArrayPerElement)*n + GasArrayNew
+}
+
+// encodeArray packs element ciphertexts
+func encodeArray(cts [][]byte) []byte {
+	data := binary.BigEndian.AppendUint16(nil, uint16(len(cts)))
+	for _, ct := range cts {
+		data = binary.BigEndian.AppendUint32(data, uint32(len(ct)))
+		data = append(data, ct...)
+	}
+	return data
+}
+
+// decodeArray unpacks element ciphertexts
+func decodeArray(data []byte) ([][]byte, bool) {
+	if len(data) < 2 {
+		return nil, false
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	cts := make([][]byte, n)
+	for i := range cts {
+		if len(data) < 4 {
+			return nil, false
+		}
+		size := binary.BigEndian.Uint32(data)
+		if uint64(len(data)-4) < uint64(size) {
+			return nil, false
+		}
+		cts[i], data = data[4:4+size], data[4+size:]
+	}
+	return cts, len(data) == 0
+}
+
+// storeArray stores packed elements of elemType and returns the array handle
+func storeArray(store CiphertextBackend, cts [][]byte, elemType uint8) common.Hash {
+	packed := encodeArray(cts)
+	handle := resultHandle(store, crypto.Keccak256Hash([]byte(arrayHandleDomain), packed))
+	store.Put(handle, packed, TypeArray|elemType)
+	return handle
+}
+
+// loadArray returns the elements and element type of the array under handle
+func loadArray(store CiphertextBackend, op string, handle common.Hash) ([][]byte, uint8, error) {
+	data, ctType, ok := getCiphertext(store, handle)
+	if !ok {
+		return nil, 0, handleNotFound(op, handle)
+	}
+	if !isArrayType(ctType) {
+		return nil, 0, &OpError{Op: op, Handle: handle, Err: ErrInvalidInput}
+	}
+	cts, ok := decodeArray(data)
+	if !ok {
+		return nil, 0, opFailed(op)
+	}
+	return cts, ctType &^ TypeArray, nil
+}
+
+// loadArrayIndex loads an encrypted index and checks that it addresses n
+// elements, returning its ciphertext, type and bit depth
+func loadArrayIndex(store CiphertextBackend, op string, handle common.Hash, n int) ([]byte, uint8, int, error) {
+	ct, ctType, ok := getCiphertext(store, handle)
+	if !ok {
+		return nil, 0, 0, handleNotFound(op, handle)
+	}
+	width, _ := typeBitWidth(ctType)
+	depth := bits.Len(uint(n - 1))
+	if !isUintType(ctType) || depth > int(width) {
+		return nil, 0, 0, &OpError{Op: op, Handle: handle, Err: ErrInvalidInput}
+	}
+	return ct, ctType, depth, nil
+}
+
+// arrayLen returns the element count of the array under handle from its
+// stored bytes
+func arrayLen(store CiphertextBackend, handle common.Hash) (int, bool) {
+	data, ctType, ok := getCiphertext(store, handle)
+	if !ok || !isArrayType(ctType) || len(data) < 2 {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint16(data)), true
+}
+
+// handleArrayNew packs ciphertexts into an encrypted array. Input is an
+// ABI-encoded bytes32[] of element handles.
+func (c *FHEContract) handleArrayNew(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	elements, err := decodeHandleArrayArg(data, 0)
+	if err != nil {
+		return nil, gas, err
+	}
+	if len(elements) == 0 || len(elements) > MaxArrayLength {
+		return nil, gas, ErrInvalidInput
+	}
+	required := arrayNewGas(uint64(len(elements)))
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	store := ciphertextStoreFor(state)
+	cts := make([][]byte, len(elements))
+	var elemType uint8
+	for i, h := range elements {
+		ct, t, ok := getCiphertext(store, h)
+		if !ok {
+			return nil, gas - required, handleNotFound("arrayNew", h)
+		}
+		if isArrayType(t) {
+			return nil, gas - required, &OpError{Op: "arrayNew", Handle: h, Err: ErrInvalidInput}
+		}
+		if i > 0 && t != elemType {
+			return nil, gas - required, typeMismatch("arrayNew", h, elemType, t)
+		}
+		cts[i], elemType = ct, t
+	}
+	return storeArray(store, cts, elemType).Bytes(), gas - required, nil
+}
+
+// handleArrayGet reads an element at an encrypted index. Input is
+// array (32) || index (32).
+func (c *FHEContract) handleArrayGet(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasArrayNew {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	store := ciphertextStoreFor(state)
+	array := common.BytesToHash(data[:32])
+	n, ok := arrayLen(store, array)
+	if !ok {
+		return nil, gas - GasArrayNew, handleNotFound("arrayGet", array)
+	}
+	required := selectNGas(uint64(n))
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	result, err := performFHEArrayGet(store, array, common.BytesToHash(data[32:64]))
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
+}
+
+// performFHEArrayGet selects the element at an encrypted index and stores
+// it
+func performFHEArrayGet(store CiphertextBackend, array, index common.Hash) (common.Hash, error) {
+	cts, elemType, err := loadArray(store, "arrayGet", array)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	ctIndex, indexType, depth, err := loadArrayIndex(store, "arrayGet", index, len(cts))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	width, _ := typeBitWidth(indexType)
+	guard := int(width) > depth || len(cts) < 1<<depth
+	result := tfheSelectN(defaultTFHE(), ctIndex, indexType, cts, elemType, depth, guard)
+	if result == nil {
+		return common.Hash{}, opFailed("arrayGet")
+	}
+	return storeCiphertext(store, result, elemType), nil
+}
+
+// handleArraySet writes an element at an encrypted index. Input is
+// array (32) || index (32) || value (32).
+func (c *FHEContract) handleArraySet(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 96 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasArrayNew {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	store := ciphertextStoreFor(state)
+	array := common.BytesToHash(data[:32])
+	n, ok := arrayLen(store, array)
+	if !ok {
+		return nil, gas - GasArrayNew, handleNotFound("arraySet", array)
+	}
+	required := arraySetGas(uint64(n))
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	result, err := performFHEArraySet(store, array, common.BytesToHash(data[32:64]), common.BytesToHash(data[64:96]))
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
+}
+
+// performFHEArraySet rewrites every element as select(index == i, value,
+// element) and stores the new array
+func performFHEArraySet(store CiphertextBackend, array, index, value common.Hash) (common.Hash, error) {
+	cts, elemType, err := loadArray(store, "arraySet", array)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	ctIndex, indexType, _, err := loadArrayIndex(store, "arraySet", index, len(cts))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	ctValue, valueType, ok := getCiphertext(store, value)
+	if !ok {
+		return common.Hash{}, handleNotFound("arraySet", value)
+	}
+	if valueType != elemType {
+		return common.Hash{}, typeMismatch("arraySet", value, elemType, valueType)
+	}
+
+	updated := tfheArraySet(defaultTFHE(), ctIndex, indexType, cts, ctValue)
+	if updated == nil {
+		return common.Hash{}, opFailed("arraySet")
+	}
+	return storeArray(store, updated, elemType), nil
+}
+
+// handleArrayLength returns the public element count of an array as a
+// uint256
+func (c *FHEContract) handleArrayLength(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasHandleQuery {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	n, ok := arrayLen(ciphertextStoreFor(state), handle)
+	if !ok {
+		return nil, gas - GasHandleQuery, handleNotFound("arrayLength", handle)
+	}
+	return abiWord(uint64(n)), gas - GasHandleQuery, nil
+}
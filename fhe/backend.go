@@ -0,0 +1,241 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+)
+
+// Evaluation backends.
+//
+// Binary, unary, select, scalar, shift and cast operations are evaluated
+// through an EvalBackend. By default they run on the in-process TFHE
+// library. Validators without the CPU for it install a RemoteBackend with
+// SetEvalBackend, which sends operations to an out-of-process evaluator,
+// such as a gRPC service or a WASM runtime, through a RemoteTransport the
+// host provides. Independent operations are sent together: a single call
+// sends one operation, and a batch sends each dependency level of its
+// operation list as one request, split into RemoteBatchSize chunks.
+//
+// Every response must be signed by a threshold of attestors over
+// EvalBatchDigest, which binds the operations, their input ciphertexts and
+// the results, so a faulty evaluator cannot substitute a result. Stored
+// ciphertexts are part of the state, so a remote evaluator must return the
+// bytes the in-process library would under the same network key.
+// Operations with multi-step circuits (mulDiv, selectN, encrypted shifts)
+// and encryption, decryption and randomness stay in process.
+
+// Remote evaluation limits
+const (
+	RemoteBatchSize = 64               // Operations per remote request
+	RemoteTimeout   = 30 * time.Second // Deadline of one remote request
+)
+
+// evalBatchDomain separates evaluation attestations from other signatures
+const evalBatchDomain = "lux.fhe.eval.batch.v1"
+
+var ErrRemoteEvaluation = errors.New("remote FHE evaluation failed")
+
+// EvalRequest is one operation on raw ciphertexts
+type EvalRequest struct {
+	Op     string   // Operation name, as in ParallelOp
+	Inputs [][]byte // Operand ciphertexts
+	Types  []uint8  // Operand types
+	Scalar *big.Int // Plaintext operand for scalar ops, shift amount for shifts
+	ToType uint8    // Target type for "cast"
+}
+
+// EvalResult is the output of one EvalRequest
+type EvalResult struct {
+	Ciphertext []byte
+	Type       uint8
+}
+
+// EvalBackend evaluates operations on raw ciphertexts. Requests in one call
+// are independent, and results are returned in request order.
+type EvalBackend interface {
+	Evaluate(reqs []EvalRequest) ([]EvalResult, error)
+}
+
+// evalBackend is the installed backend; nil evaluates in process
+var evalBackend EvalBackend
+
+// SetEvalBackend routes evaluation to b, or back to the in-process library
+// when b is nil
+func SetEvalBackend(b EvalBackend) {
+	evalBackend = b
+}
+
+// ActiveEvalBackend returns the installed backend, or nil in process
+func ActiveEvalBackend() EvalBackend {
+	return evalBackend
+}
+
+// evaluate runs one operation on the active backend and returns nil if it
+// fails
+func evaluate(req EvalRequest) ([]byte, uint8) {
+	if evalBackend == nil {
+		return evalLocal(defaultTFHE(), &req)
+	}
+	results, err := evalBackend.Evaluate([]EvalRequest{req})
+	if err != nil || len(results) != 1 {
+		return nil, 0
+	}
+	return results[0].Ciphertext, results[0].Type
+}
+
+// evalLocal evaluates one operation on the in-process library with context
+// tc and returns nil if it fails. Operands of mismatched types fail.
+func evalLocal(tc *tfheContext, req *EvalRequest) ([]byte, uint8) {
+	if len(req.Inputs) == 0 || len(req.Inputs) != len(req.Types) {
+		return nil, 0
+	}
+	cts, types := req.Inputs, req.Types
+
+	switch req.Op {
+	case "select":
+		if len(cts) != 3 || types[0] != TypeEbool || types[1] != types[2] {
+			return nil, 0
+		}
+		return tfheSelect(tc, cts[0], cts[1], cts[2], types[1]), types[1]
+	case "not", "neg":
+		return computeFHEUnaryOperation(tc, req.Op, cts[0], types[0]), types[0]
+	case "cast":
+		return tfheCast(tc, cts[0], types[0], req.ToType), req.ToType
+	case "scalarAdd", "scalarSub", "scalarMul", "scalarDiv", "scalarRem",
+		"scalarLt", "scalarLe", "scalarGt", "scalarGe", "scalarEq", "scalarNe":
+		if req.Scalar == nil {
+			return nil, 0
+		}
+		return computeFHEScalarOperation(tc, req.Op, cts[0], req.Scalar, types[0])
+	case "shl", "shr", "rotl", "rotr":
+		if req.Scalar == nil || !req.Scalar.IsInt64() {
+			return nil, 0
+		}
+		return computeFHEShiftOperation(tc, req.Op, cts[0], int(req.Scalar.Int64()), types[0]), types[0]
+	default:
+		if len(cts) != 2 || types[0] != types[1] {
+			return nil, 0
+		}
+		return computeFHEOperation(tc, req.Op, cts[0], cts[1], types[0])
+	}
+}
+
+// evalResultType returns the type the result of req must have
+func evalResultType(req *EvalRequest) uint8 {
+	switch req.Op {
+	case "lt", "gt", "eq", "ne", "le", "ge",
+		"scalarLt", "scalarLe", "scalarGt", "scalarGe", "scalarEq", "scalarNe":
+		return TypeEbool
+	case "select":
+		return req.Types[1]
+	case "cast":
+		return req.ToType
+	default:
+		return req.Types[0]
+	}
+}
+
+// EvalBatchDigest returns the digest attestors sign to approve results as
+// the evaluation of reqs
+func EvalBatchDigest(reqs []EvalRequest, results []EvalResult) common.Hash {
+	data := []byte(evalBatchDomain)
+	for i := range reqs {
+		req := &reqs[i]
+		data = append(data, []byte(req.Op)...)
+		data = append(data, 0, req.ToType)
+		scalar := new(big.Int)
+		if req.Scalar != nil {
+			scalar = req.Scalar
+		}
+		data = append(data, common.BigToHash(scalar).Bytes()...)
+		data = append(data, byte(len(req.Inputs)))
+		for j, in := range req.Inputs {
+			data = append(data, req.Types[j])
+			data = append(data, crypto.Keccak256(in)...)
+		}
+	}
+	for _, r := range results {
+		data = append(data, r.Type)
+		data = append(data, crypto.Keccak256(r.Ciphertext)...)
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+// RemoteTransport carries evaluation requests to an out-of-process
+// evaluator. It returns the results in request order together with the
+// attestors' 65-byte [R || S || V] signatures over EvalBatchDigest.
+type RemoteTransport interface {
+	EvaluateBatch(ctx context.Context, reqs []EvalRequest) ([]EvalResult, [][]byte, error)
+}
+
+// RemoteBackend evaluates operations through a RemoteTransport and accepts
+// only results a threshold of attestors signed
+type RemoteBackend struct {
+	transport RemoteTransport
+	attestors map[common.Address]bool
+	threshold int
+}
+
+var _ EvalBackend = (*RemoteBackend)(nil)
+
+// NewRemoteBackend creates a backend whose results must be signed by at
+// least threshold of the given attestors
+func NewRemoteBackend(transport RemoteTransport, attestors []common.Address, threshold int) (*RemoteBackend, error) {
+	if transport == nil {
+		return nil, ErrInvalidInput
+	}
+	if err := verifyAttestors(attestors, threshold); err != nil {
+		return nil, err
+	}
+
+	set := make(map[common.Address]bool, len(attestors))
+	for _, a := range attestors {
+		set[a] = true
+	}
+	return &RemoteBackend{transport: transport, attestors: set, threshold: threshold}, nil
+}
+
+// Evaluate sends reqs in chunks of RemoteBatchSize and checks each response
+func (b *RemoteBackend) Evaluate(reqs []EvalRequest) ([]EvalResult, error) {
+	results := make([]EvalResult, 0, len(reqs))
+	for start := 0; start < len(reqs); start += RemoteBatchSize {
+		chunk := reqs[start:min(start+RemoteBatchSize, len(reqs))]
+		out, err := b.evaluateChunk(chunk)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, out...)
+	}
+	return results, nil
+}
+
+func (b *RemoteBackend) evaluateChunk(reqs []EvalRequest) ([]EvalResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RemoteTimeout)
+	defer cancel()
+
+	results, signatures, err := b.transport.EvaluateBatch(ctx, reqs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRemoteEvaluation, err)
+	}
+	if len(results) != len(reqs) {
+		return nil, fmt.Errorf("%w: %d results for %d requests", ErrRemoteEvaluation, len(results), len(reqs))
+	}
+	for i := range reqs {
+		if len(results[i].Ciphertext) == 0 || results[i].Type != evalResultType(&reqs[i]) {
+			return nil, fmt.Errorf("%w: invalid result %d (%q)", ErrRemoteEvaluation, i, reqs[i].Op)
+		}
+	}
+	if countAttestors(b.attestors, EvalBatchDigest(reqs, results), signatures) < b.threshold {
+		return nil, ErrAttestation
+	}
+	return results, nil
+}
@@ -0,0 +1,273 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Batched operations.
+//
+// batch(bytes) runs a list of FHE operations in one precompile call and
+// returns every result handle as an ABI-encoded bytes32[]. An operation may
+// consume the result of an earlier one, so a contract can evaluate a whole
+// expression without a call per step. The list is
+//
+//	count (2) || op*
+//	op      = selector (4) || arguments in the method's order
+//	handle  = 0x00 || handle (32)  |  0x01 || result index (2)
+//	word    = 32 bytes
+//	byte    = 1 byte
+//
+// where selector is that of the single-operation method (add, scalarMul,
+// select, cast, ...). Independent operations run on the ParallelExecutor,
+// and either every result is stored or none is. Gas is the sum of the
+// operations' gas. Inputs must be final: batches are evaluated inline even
+// in coprocessor mode.
+
+// MaxBatchOps bounds the number of operations in a batch
+const MaxBatchOps = 256
+
+// Handle operand tags
+const (
+	batchHandle = 0x00 // Existing handle
+	batchResult = 0x01 // Result of an earlier op
+)
+
+// batchGas is the gas of each operation a batch may contain, matching its
+// single-operation method
+var batchGas = map[string]uint64{
+	"add": GasAdd, "sub": GasSub, "mul": GasMul, "div": GasDiv, "rem": GasRem, "neg": GasNeg,
+	"lt": GasLt, "le": GasLe, "gt": GasGt, "ge": GasGe, "eq": GasEq, "ne": GasNe,
+	"min": GasMin, "max": GasMax,
+	"and": GasAnd, "or": GasOr, "xor": GasXor, "not": GasNot,
+	"shl": GasShl, "shr": GasShr, "rotl": GasRotl, "rotr": GasRotr,
+	"select": GasSelect, "cast": GasCast,
+	"scalarAdd": GasAdd, "scalarSub": GasSub, "scalarMul": GasMul, "scalarDiv": GasDiv, "scalarRem": GasRem,
+	"scalarLt": GasLt, "scalarLe": GasLe, "scalarGt": GasGt, "scalarGe": GasGe, "scalarEq": GasEq, "scalarNe": GasNe,
+}
+
+// batchMethods indexes the methods a batch may contain. It is built in
+// init because the batch handler is itself in Methods.
+var batchMethods map[[4]byte]*Method
+
+func init() {
+	batchMethods = make(map[[4]byte]*Method)
+	for i := range Methods {
+		if _, ok := batchGas[Methods[i].Name]; ok {
+			batchMethods[Methods[i].Selector] = &Methods[i]
+		}
+	}
+}
+
+// batchExecutor evaluates batch calls
+var batchExecutor = NewParallelExecutor(0)
+
+// DecodeBatch parses a batch operation list and checks that every result
+// operand refers to an earlier operation
+func DecodeBatch(data []byte) ([]ParallelOp, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidInput
+	}
+	count := int(binary.BigEndian.Uint16(data[:2]))
+	if count == 0 || count > MaxBatchOps {
+		return nil, ErrInvalidInput
+	}
+
+	r := batchReader{data: data, off: 2}
+	ops := make([]ParallelOp, count)
+	for i := range ops {
+		selector := r.next(4)
+		if selector == nil {
+			return nil, fmt.Errorf("%w: op %d", ErrInvalidInput, i)
+		}
+		m, ok := batchMethods[[4]byte(selector)]
+		if !ok {
+			return nil, fmt.Errorf("%w: op %d", ErrNotImplemented, i)
+		}
+		op := ParallelOp{Op: m.Name}
+		for _, kind := range m.Args {
+			switch kind {
+			case ArgHandle:
+				switch tag := r.next(1); {
+				case tag == nil:
+				case tag[0] == batchHandle:
+					op.Inputs = append(op.Inputs, HandleOperand(common.BytesToHash(r.next(32))))
+				case tag[0] == batchResult:
+					if index := r.next(2); index != nil {
+						op.Inputs = append(op.Inputs, ResultOperand(int(binary.BigEndian.Uint16(index))))
+					}
+				default:
+					r.err = true
+				}
+			case ArgWord:
+				op.Scalar = new(big.Int).SetBytes(r.next(32))
+			case ArgByte:
+				if b := r.next(1); b != nil && m.Name == "cast" {
+					op.ToType = b[0]
+				} else if b != nil {
+					op.Scalar = big.NewInt(int64(b[0]))
+				}
+			default:
+				r.err = true
+			}
+		}
+		if r.err {
+			return nil, fmt.Errorf("%w: op %d", ErrInvalidInput, i)
+		}
+		ops[i] = op
+	}
+	if r.off != len(data) {
+		return nil, ErrInvalidInput
+	}
+	if _, err := AnalyzeDependencies(ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// batchReader reads consecutive fields, recording truncation
+type batchReader struct {
+	data []byte
+	off  int
+	err  bool
+}
+
+func (r *batchReader) next(n int) []byte {
+	if r.err || len(r.data) < r.off+n {
+		r.err = true
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+// EncodeBatch serializes ops in the layout DecodeBatch reads
+func EncodeBatch(ops []ParallelOp) ([]byte, error) {
+	if len(ops) == 0 || len(ops) > MaxBatchOps {
+		return nil, ErrInvalidInput
+	}
+	out := binary.BigEndian.AppendUint16(nil, uint16(len(ops)))
+	for i, op := range ops {
+		m, ok := methodByName(op.Op)
+		if !ok || batchMethods[m.Selector] == nil {
+			return nil, fmt.Errorf("%w: op %d (%q)", ErrNotImplemented, i, op.Op)
+		}
+		out = append(out, m.Selector[:]...)
+		inputs := op.Inputs
+		for _, kind := range m.Args {
+			switch kind {
+			case ArgHandle:
+				if len(inputs) == 0 {
+					return nil, fmt.Errorf("%w: op %d (%q)", ErrInvalidInput, i, op.Op)
+				}
+				in := inputs[0]
+				inputs = inputs[1:]
+				if in.IsResult {
+					out = append(out, batchResult)
+					out = binary.BigEndian.AppendUint16(out, uint16(in.Result))
+				} else {
+					out = append(out, batchHandle)
+					out = append(out, in.Handle.Bytes()...)
+				}
+			case ArgWord:
+				if op.Scalar == nil || op.Scalar.Sign() < 0 || op.Scalar.BitLen() > 256 {
+					return nil, fmt.Errorf("%w: op %d (%q)", ErrInvalidInput, i, op.Op)
+				}
+				out = append(out, common.BigToHash(op.Scalar).Bytes()...)
+			case ArgByte:
+				if m.Name == "cast" {
+					out = append(out, op.ToType)
+				} else if op.Scalar != nil && op.Scalar.IsUint64() && op.Scalar.Uint64() <= 0xff {
+					out = append(out, byte(op.Scalar.Uint64()))
+				} else {
+					return nil, fmt.Errorf("%w: op %d (%q)", ErrInvalidInput, i, op.Op)
+				}
+			}
+		}
+		if len(inputs) != 0 {
+			return nil, fmt.Errorf("%w: op %d (%q)", ErrInvalidInput, i, op.Op)
+		}
+	}
+	return out, nil
+}
+
+// batchInputs returns the distinct existing handles ops read
+func batchInputs(ops []ParallelOp) []common.Hash {
+	seen := make(map[common.Hash]bool)
+	var handles []common.Hash
+	for _, op := range ops {
+		for _, in := range op.Inputs {
+			if !in.IsResult && !seen[in.Handle] {
+				seen[in.Handle] = true
+				handles = append(handles, in.Handle)
+			}
+		}
+	}
+	return handles
+}
+
+// batchOpsGas returns the summed gas of ops
+func batchOpsGas(ops []ParallelOp) uint64 {
+	var total uint64
+	for _, op := range ops {
+		total += batchGas[op.Op]
+	}
+	return total
+}
+
+// encodeHandleArray ABI-encodes handles as a bytes32[]
+func encodeHandleArray(handles []common.Hash) []byte {
+	out := make([]byte, 64, 64+32*len(handles))
+	out[31] = 32
+	binary.BigEndian.PutUint64(out[56:64], uint64(len(handles)))
+	for _, h := range handles {
+		out = append(out, h.Bytes()...)
+	}
+	return out
+}
+
+// handleBatch runs a batch operation list. The ACL is applied here rather
+// than in Run, since the handles are inside the list: every existing input
+// must be allowed to the caller, and every result is granted to it.
+func (c *FHEContract) handleBatch(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	ops, err := DecodeBatch(data)
+	if err != nil {
+		return nil, gas, err
+	}
+	required := batchOpsGas(ops)
+	inputs := batchInputs(ops)
+	acl := aclFor(state)
+	if acl != nil {
+		required += GasACLCheck*uint64(len(inputs)) + GasACLWrite*uint64(len(ops))
+	}
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+	if acl != nil {
+		for _, h := range inputs {
+			if !acl.IsAllowed(h, caller) {
+				return nil, gas, ErrACLDenied
+			}
+		}
+	}
+
+	handles, err := batchExecutor.Execute(ciphertextStoreFor(state), ops)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	if acl != nil {
+		for _, h := range handles {
+			acl.grantResult(h, caller)
+		}
+	}
+	emitBatchLogs(state, caller, ops, handles, readOnly)
+	return encodeHandleArray(handles), gas - required, nil
+}
@@ -4,6 +4,9 @@
 package fhe
 
 import (
+	"slices"
+
+	"github.com/luxfi/geth/common"
 	"github.com/luxfi/precompile/precompileconfig"
 )
 
@@ -12,10 +15,40 @@ var _ precompileconfig.Config = (*Config)(nil)
 // Config implements the precompileconfig.Config interface for FHE.
 type Config struct {
 	precompileconfig.Upgrade
-	// NetworkKeyPath specifies the path to the network TFHE key (optional)
+	// NetworkKeyPath is the path of the network TFHE key set of epoch 0, in
+	// the format GenerateNetworkKeys writes (optional)
 	NetworkKeyPath string `json:"networkKeyPath,omitempty"`
+	// KeyRotations schedule the later network keys: entry i is epoch i+1
+	KeyRotations []KeyRotation `json:"keyRotations,omitempty"`
 	// CoprocessorEndpoint specifies the Z-Chain coprocessor endpoint for threshold decryption
 	CoprocessorEndpoint string `json:"coprocessorEndpoint,omitempty"`
+	// CoprocessorAttestors enables coprocessor mode: operations are queued as
+	// compute jobs and finalized by results these attestors sign
+	CoprocessorAttestors []common.Address `json:"coprocessorAttestors,omitempty"`
+	// CoprocessorThreshold is the number of attestor signatures a result needs
+	CoprocessorThreshold int `json:"coprocessorThreshold,omitempty"`
+	// DecryptionCommittee enables asynchronous decryption: plaintexts are
+	// delivered by callback once this committee signs them
+	DecryptionCommittee []common.Address `json:"decryptionCommittee,omitempty"`
+	// DecryptionThreshold is the number of committee signatures a plaintext needs
+	DecryptionThreshold int `json:"decryptionThreshold,omitempty"`
+	// InputAttestors are the input verifiers whose signatures over an input
+	// digest attest a checked ZKPoK (ProofSchemeAttested). They are required:
+	// the precompile accepts no other input proofs.
+	InputAttestors []common.Address `json:"inputAttestors,omitempty"`
+	// InputThreshold is the number of input verifier signatures a proof needs
+	InputThreshold int `json:"inputThreshold,omitempty"`
+	// SealKeyPath is the path of the secret the validators share to key
+	// sealOutput randomness. sealOutput fails until it is set.
+	SealKeyPath string `json:"sealKeyPath,omitempty"`
+}
+
+// KeyRotation is a scheduled network key
+type KeyRotation struct {
+	// KeyPath is the path of the epoch's key set
+	KeyPath string `json:"keyPath"`
+	// ActivationBlock is the first block the key serves
+	ActivationBlock uint64 `json:"activationBlock"`
 }
 
 // NewConfig returns a config for a network upgrade at [blockTimestamp] that enables FHE.
@@ -40,8 +73,30 @@ func (*Config) Key() string { return ConfigKey }
 
 // Verify tries to verify Config and returns an error accordingly.
 func (c *Config) Verify(chainConfig precompileconfig.ChainConfig) error {
-	// FHE has no special verification requirements beyond base config
-	return nil
+	var last uint64
+	for _, r := range c.KeyRotations {
+		if r.KeyPath == "" || r.ActivationBlock <= last {
+			return ErrKeySchedule
+		}
+		last = r.ActivationBlock
+	}
+	if len(c.CoprocessorAttestors) > 0 || c.CoprocessorThreshold != 0 {
+		if err := verifyAttestors(c.CoprocessorAttestors, c.CoprocessorThreshold); err != nil {
+			return err
+		}
+	}
+	if len(c.DecryptionCommittee) > 0 || c.DecryptionThreshold != 0 {
+		if err := verifyAttestors(c.DecryptionCommittee, c.DecryptionThreshold); err != nil {
+			return err
+		}
+	}
+	if c.IsDisabled() {
+		return nil
+	}
+	if len(c.InputAttestors) == 0 {
+		return ErrNoInputVerifier
+	}
+	return verifyAttestors(c.InputAttestors, c.InputThreshold)
 }
 
 // Equal returns true if [s] is a [*Config] and it has been configured identical to [c].
@@ -52,5 +107,38 @@ func (c *Config) Equal(s precompileconfig.Config) bool {
 	}
 	return c.Upgrade.Equal(&other.Upgrade) &&
 		c.NetworkKeyPath == other.NetworkKeyPath &&
-		c.CoprocessorEndpoint == other.CoprocessorEndpoint
+		slices.Equal(c.KeyRotations, other.KeyRotations) &&
+		c.CoprocessorEndpoint == other.CoprocessorEndpoint &&
+		slices.Equal(c.CoprocessorAttestors, other.CoprocessorAttestors) &&
+		c.CoprocessorThreshold == other.CoprocessorThreshold &&
+		slices.Equal(c.DecryptionCommittee, other.DecryptionCommittee) &&
+		c.DecryptionThreshold == other.DecryptionThreshold &&
+		slices.Equal(c.InputAttestors, other.InputAttestors) &&
+		c.InputThreshold == other.InputThreshold &&
+		c.SealKeyPath == other.SealKeyPath
+}
+
+var _ precompileconfig.Config = (*ACLConfig)(nil)
+
+// ACLConfig implements the precompileconfig.Config interface for the ACL
+// precompile.
+type ACLConfig struct {
+	precompileconfig.Upgrade
+}
+
+// Key returns the key for the ACL precompileconfig.
+func (*ACLConfig) Key() string { return ACLConfigKey }
+
+// Verify tries to verify ACLConfig and returns an error accordingly.
+func (c *ACLConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	return nil
+}
+
+// Equal returns true if [s] is a [*ACLConfig] and it has been configured identical to [c].
+func (c *ACLConfig) Equal(s precompileconfig.Config) bool {
+	other, ok := (s).(*ACLConfig)
+	if !ok {
+		return false
+	}
+	return c.Upgrade.Equal(&other.Upgrade)
 }
@@ -55,8 +55,15 @@ const (
 	GasRand           uint64 = 100000
 	GasCast           uint64 = 30000
 	GasRequire        uint64 = 80000
+
+	// GasMaxWithIndexPerBid covers one gt plus two selects (value and index)
+	// per bid folded into the running maximum.
+	GasMaxWithIndexPerBid uint64 = 260000
 )
 
+// MaxAuctionBids bounds the number of bids accepted by encMaxWithIndex.
+const MaxAuctionBids = 256
+
 var (
 	ErrInvalidInput      = errors.New("invalid input")
 	ErrTypeMismatch      = errors.New("ciphertext type mismatch")
@@ -64,6 +71,9 @@ var (
 	ErrNotImplemented    = errors.New("operation not implemented")
 	ErrInsufficientGas   = errors.New("insufficient gas for FHE operation")
 	ErrInvalidCiphertext = errors.New("invalid ciphertext handle")
+	ErrWriteProtection   = errors.New("write protection")
+	ErrStaticMutation    = errors.New("FHE operation persists state, not allowed in a static call")
+	ErrScalarOutOfRange  = errors.New("scalar wider than the operand type")
 )
 
 // FHEContract implements the main FHE precompile
@@ -78,6 +88,13 @@ func (c *FHEContract) Run(
 	suppliedGas uint64,
 	readOnly bool,
 ) (ret []byte, remainingGas uint64, err error) {
+	// Failed calls return their error as revert data
+	defer func() {
+		if err != nil && ret == nil {
+			ret = RevertData(err)
+		}
+	}()
+
 	if len(input) < 4 {
 		return nil, suppliedGas, ErrInvalidInput
 	}
@@ -86,111 +103,59 @@ func (c *FHEContract) Run(
 	selector := input[:4]
 	data := input[4:]
 
-	// Route to appropriate handler based on selector
-	switch string(selector) {
-	// Arithmetic operations
-	case "\x23\xb8\x72\xdd": // add(bytes32,bytes32)
-		return c.handleAdd(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x51\xca\xb0\x91": // sub(bytes32,bytes32)
-		return c.handleSub(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\xc8\xa4\xac\x9c": // mul(bytes32,bytes32)
-		return c.handleMul(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x0f\x5e\x1b\x2a": // div(bytes32,bytes32)
-		return c.handleDiv(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x1e\x19\x1a\x96": // rem(bytes32,bytes32)
-		return c.handleRem(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\xe4\x7e\xf3\xfc": // neg(bytes32)
-		return c.handleNeg(accessibleState, caller, data, suppliedGas, readOnly)
-
-	// Scalar arithmetic
-	case "\xf5\xa7\x96\xfb": // scalarAdd(bytes32,uint256)
-		return c.handleScalarAdd(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\xb6\x3a\x9e\x11": // scalarSub(bytes32,uint256)
-		return c.handleScalarSub(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x3c\x96\x47\x95": // scalarMul(bytes32,uint256)
-		return c.handleScalarMul(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x7b\x8f\x4a\x2d": // scalarDiv(bytes32,uint256)
-		return c.handleScalarDiv(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x52\x91\xa3\x21": // scalarRem(bytes32,uint256)
-		return c.handleScalarRem(accessibleState, caller, data, suppliedGas, readOnly)
-
-	// Comparison operations
-	case "\xa9\x05\x9c\xbb": // lt(bytes32,bytes32)
-		return c.handleLt(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x26\xa3\x31\x9e": // le(bytes32,bytes32)
-		return c.handleLe(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x4b\x64\xe4\x92": // gt(bytes32,bytes32)
-		return c.handleGt(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x53\x1c\x19\xea": // ge(bytes32,bytes32)
-		return c.handleGe(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x1c\xf4\x86\x63": // eq(bytes32,bytes32)
-		return c.handleEq(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x14\x6e\x3a\x7e": // ne(bytes32,bytes32)
-		return c.handleNe(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x7a\x8f\x63\xb8": // min(bytes32,bytes32)
-		return c.handleMin(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x6e\x32\x91\x28": // max(bytes32,bytes32)
-		return c.handleMax(accessibleState, caller, data, suppliedGas, readOnly)
-
-	// Bitwise operations
-	case "\xcd\x30\x32\x00": // and(bytes32,bytes32)
-		return c.handleAnd(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x5a\x6b\x26\xba": // or(bytes32,bytes32)
-		return c.handleOr(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\xf6\x74\x70\x22": // xor(bytes32,bytes32)
-		return c.handleXor(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x6b\x3a\x00\x11": // not(bytes32)
-		return c.handleNot(accessibleState, caller, data, suppliedGas, readOnly)
-
-	// Shift operations
-	case "\x3e\x8c\x6c\x10": // shl(bytes32,uint8)
-		return c.handleShl(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x5f\x46\xe5\x15": // shr(bytes32,uint8)
-		return c.handleShr(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x89\xa1\x9e\x6b": // rotl(bytes32,uint8)
-		return c.handleRotl(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\xd7\x25\x1c\xb9": // rotr(bytes32,uint8)
-		return c.handleRotr(accessibleState, caller, data, suppliedGas, readOnly)
-
-	// Selection and casting
-	case "\x2e\x17\xde\x78": // select(bytes32,bytes32,bytes32)
-		return c.handleSelect(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\xae\xd2\x44\x6b": // cast(bytes32,uint8)
-		return c.handleCast(accessibleState, caller, data, suppliedGas, readOnly)
-
-	// Encryption operations
-	case "\xa5\x17\x5c\x89": // asEuint64(uint64)
-		return c.handleAsEuint64(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\xd4\x3f\x02\x80": // asEaddress(address)
-		return c.handleAsEaddress(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x8c\x3f\x5a\x42": // asEbool(bool)
-		return c.handleAsEbool(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x2d\xfa\x48\x63": // asEuint4(uint8)
-		return c.handleAsEuint4(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x64\xc1\x51\x81": // asEuint8(uint8)
-		return c.handleAsEuint8(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\xf8\x91\x08\x50": // asEuint16(uint16)
-		return c.handleAsEuint16(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x6c\xa9\xea\xe9": // asEuint32(uint32)
-		return c.handleAsEuint32(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x7d\x6d\x81\x95": // asEuint128(uint256)
-		return c.handleAsEuint128(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x9e\x5b\x2e\xf3": // asEuint256(uint256)
-		return c.handleAsEuint256(accessibleState, caller, data, suppliedGas, readOnly)
-
-	// Utility operations
-	case "\x71\x5a\xd3\x11": // rand(uint8)
-		return c.handleRand(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x12\x3d\x4c\x87": // decrypt(bytes32)
-		return c.handleDecrypt(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x45\xa9\x32\x18": // verify(bytes,uint8)
-		return c.handleVerify(accessibleState, caller, data, suppliedGas, readOnly)
-	case "\x56\x7a\x11\x98": // sealOutput(bytes32,bytes)
-		return c.handleSealOutput(accessibleState, caller, data, suppliedGas, readOnly)
-
-	default:
+	// Route to the handler registered for the selector
+	method, ok := methodsBySelector[[4]byte(selector)]
+	if !ok {
 		return nil, suppliedGas, ErrNotImplemented
 	}
+
+	// Static calls may compute but not persist. Their state changes,
+	// ciphertexts and ACL grants included, are reverted when the call
+	// returns, so the handles they return are transient. Methods with
+	// effects outside the StateDB, and coprocessor jobs, are rejected.
+	if readOnly {
+		if method.Persists || (coprocessor != nil && resultHandleCount(method) > 0) {
+			return nil, suppliedGas, ErrStaticMutation
+		}
+		if accessibleState != nil && !method.View {
+			if db := accessibleState.GetStateDB(); db != nil {
+				snapshot := db.Snapshot()
+				defer db.RevertToSnapshot(snapshot)
+			}
+		}
+	}
+
+	// Operations run under the network key of the block
+	if err := useKeyEpoch(keyEpochFor(accessibleState)); err != nil {
+		return nil, suppliedGas, err
+	}
+
+	// Stores opened by the handler derive result handles from this call
+	// and charge it for the ciphertext words they write, from what is left
+	// after the method's price
+	accessibleState = withCall(accessibleState, caller, input, storageBudget(c, method, input, suppliedGas))
+
+	// Coprocessor entry points act on job handles, not caller-owned ones
+	if acl := aclFor(accessibleState); acl != nil && method.Class != OpSystem {
+		ret, remainingGas, err = c.runWithACL(acl, method, accessibleState, caller, data, suppliedGas, readOnly)
+	} else {
+		ret, remainingGas, err = method.handler(c, accessibleState, caller, data, suppliedGas, readOnly)
+	}
+	if call, ok := accessibleState.(*callState); ok && err == nil {
+		if call.err != nil || remainingGas < call.stored {
+			return nil, 0, ErrInsufficientGas
+		}
+		remainingGas -= call.stored
+	}
+
+	// State-changing calls prune expired ciphertexts, paid from their gas
+	if err == nil && !readOnly {
+		remainingGas -= collectStep(accessibleState, remainingGas)
+	}
+	if err == nil {
+		emitOperationLogs(accessibleState, method, caller, data, ret, suppliedGas-remainingGas, readOnly)
+	}
+	return ret, remainingGas, err
 }
 
 // Gas returns the gas required for the FHE operation
@@ -210,6 +175,22 @@ func (c *FHEContract) Gas(input []byte) uint64 {
 		return GasLt
 	case "\x1c\xf4\x86\x63": // eq
 		return GasEq
+	case "\x2d\x03\x8f\x2f", "\x0c\x4c\x52\x4a", "\x6b\x88\xe9\x22", "\xa6\x5c\x70\xce": // scalarLt, scalarLe, scalarGt, scalarGe
+		return GasLt
+	case "\x66\x57\xc2\xe1", "\x99\xa8\x25\xa2": // scalarEq, scalarNe
+		return GasEq
+	case "\x76\x88\x37\xeb", "\x1a\xa0\x24\xa4", "\x2a\xd4\x7e\x9c": // mulDiv, scalarMulDiv, scaledDiv
+		return GasMulDiv
+	case "\x71\xee\x46\xe9": // rerandomize
+		return GasRerandomize
+	case "\xdc\x4c\xfa\x2f": // refresh
+		return GasRefresh
+	case "\x26\x88\x7f\x26": // batch
+		ops, err := DecodeBatch(input[4:])
+		if err != nil {
+			return 0
+		}
+		return batchOpsGas(ops)
 	case "\x2e\x17\xde\x78": // select
 		return GasSelect
 	case "\xa5\x17\x5c\x89", "\xd4\x3f\x02\x80": // asEuint64, asEaddress
@@ -224,6 +205,68 @@ func (c *FHEContract) Gas(input []byte) uint64 {
 		return GasNeg
 	case "\x71\x5a\xd3\x11": // rand
 		return GasRand
+	case "\xd5\x92\xa8\x3b": // randBounded
+		return GasRandBounded
+	case "\x21\x72\x05\x96": // encMaxWithIndex
+		n, err := decodeHandleArrayLen(input[4:], 0)
+		if err != nil {
+			return 0
+		}
+		return GasMaxWithIndexPerBid * n
+	case "\x03\xfd\x4b\xcd": // selectN
+		n, err := decodeHandleArrayLen(input[4:], 32)
+		if err != nil || n == 0 {
+			return 0
+		}
+		return selectNGas(n)
+	case "\x66\x7b\x3d\x14", "\xa2\x02\xa8\x92", "\x50\xa2\x67\x27", "\x29\xb5\x2a\xcf": // shlEnc, shrEnc, rotlEnc, rotrEnc, for the widest type
+		return shiftEncGas(maxShiftDepth)
+	case "\xb0\x6b\x95\x20": // arrayNew
+		n, err := decodeHandleArrayLen(input[4:], 0)
+		if err != nil || n == 0 {
+			return 0
+		}
+		return arrayNewGas(n)
+	case "\x35\xe1\x96\x9c": // arrayGet, for the longest array
+		return selectNGas(MaxArrayLength)
+	case "\x80\xbd\x37\x8d": // arraySet, for the longest array
+		return arraySetGas(MaxArrayLength)
+	case "\x75\xe8\x3c\x57": // arrayLength
+		return GasHandleQuery
+	case "\x46\xbc\x87\xdc": // postComputeResult
+		if len(input) < 37 {
+			return 0
+		}
+		return GasPostComputeResult + GasPerAttestation*uint64(input[36])
+	case "\x5a\x94\x61\x92", "\x38\xa6\x99\xa4", "\xfd\x97\x0b\xfb", "\xd3\xb8\xfe\x9b", "\x6f\xdf\x65\x7e": // typeOf, exists, sizeOf, epochOf, keyEpoch
+		return GasHandleQuery
+	case "\x80\x64\x43\xfa": // networkPublicKey, before the per-word read
+		return GasHandleQuery
+	case "\xf1\x6e\xba\x61": // sealOutput
+		if len(input) < 37 {
+			return 0
+		}
+		gas, _ := sealGas(input[36])
+		return gas
+	case "\xd3\x93\x0f\x85": // pin, before the rent on the ciphertext's size
+		return GasPin
+	case "\xe1\x9b\x8e\xe3": // migrate
+		return GasMigrate
+	case "\xfd\x70\x2f\x86": // computeStatus
+		return GasComputeStatus
+	case "\x45\xa9\x32\x18": // verify
+		return GasVerifyInput
+	case "\x5f\xe9\x5c\xd2", "\x14\xfc\xc2\x2d": // require_
+		return GasRequire
+	case "\x90\xaa\x1b\x60": // requestDecryption
+		return GasDecryptRequest
+	case "\xce\x3e\x86\xd4": // fulfillDecryption
+		if len(input) < 69 {
+			return 0
+		}
+		return GasFulfillDecryption + GasPerAttestation*uint64(input[68])
+	case "\x67\x53\xc3\x9a": // decryptionStatus
+		return GasDecryptionStatus
 	default:
 		return 100000 // Default high gas for unknown operations
 	}
@@ -239,13 +282,17 @@ func (c *FHEContract) handleAdd(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasAdd, handle1, handle2)
 
-	// Delegate to Z-Chain FHE coprocessor
-	result := performFHEOperation("add", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasAdd, nil
+	// Delegated to the Z-Chain FHE coprocessor in coprocessor mode
+	result, err := performFHEOperation(store, "add", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleSub(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -256,12 +303,16 @@ func (c *FHEContract) handleSub(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasSub, handle1, handle2)
 
-	result := performFHEOperation("sub", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasSub, nil
+	result, err := performFHEOperation(store, "sub", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleMul(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -272,12 +323,16 @@ func (c *FHEContract) handleMul(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasMul, handle1, handle2)
 
-	result := performFHEOperation("mul", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasMul, nil
+	result, err := performFHEOperation(store, "mul", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleLt(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -288,12 +343,16 @@ func (c *FHEContract) handleLt(state contract.AccessibleState, caller common.Add
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasLt, handle1, handle2)
 
-	result := performFHEOperation("lt", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasLt, nil
+	result, err := performFHEOperation(store, "lt", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleGt(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -304,12 +363,16 @@ func (c *FHEContract) handleGt(state contract.AccessibleState, caller common.Add
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasGt, handle1, handle2)
 
-	result := performFHEOperation("gt", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasGt, nil
+	result, err := performFHEOperation(store, "gt", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleEq(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -320,12 +383,16 @@ func (c *FHEContract) handleEq(state contract.AccessibleState, caller common.Add
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasEq, handle1, handle2)
 
-	result := performFHEOperation("eq", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasEq, nil
+	result, err := performFHEOperation(store, "eq", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleSelect(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -336,13 +403,17 @@ func (c *FHEContract) handleSelect(state contract.AccessibleState, caller common
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	condition := common.BytesToHash(data[:32])
 	ifTrue := common.BytesToHash(data[32:64])
 	ifFalse := common.BytesToHash(data[64:96])
+	required := operationGas(store, GasSelect, condition, ifTrue, ifFalse)
 
-	result := performFHESelect(condition, ifTrue, ifFalse, caller)
-
-	return result.Bytes(), gas - GasSelect, nil
+	result, err := performFHESelect(store, condition, ifTrue, ifFalse, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleAsEuint64(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -355,8 +426,10 @@ func (c *FHEContract) handleAsEuint64(state contract.AccessibleState, caller com
 
 	value := new(big.Int).SetBytes(data[:32])
 
-	result := encryptValue(value.Uint64(), TypeEuint64, caller)
-
+	result, err := encryptValue(ciphertextStoreFor(state), value.Uint64(), TypeEuint64, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -370,8 +443,10 @@ func (c *FHEContract) handleAsEaddress(state contract.AccessibleState, caller co
 
 	addr := common.BytesToAddress(data[12:32])
 
-	result := encryptAddress(addr, caller)
-
+	result, err := encryptAddress(ciphertextStoreFor(state), addr, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -383,12 +458,16 @@ func (c *FHEContract) handleMax(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasMax, handle1, handle2)
 
-	result := performFHEOperation("max", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasMax, nil
+	result, err := performFHEOperation(store, "max", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleMin(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -399,12 +478,16 @@ func (c *FHEContract) handleMin(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasMin, handle1, handle2)
 
-	result := performFHEOperation("min", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasMin, nil
+	result, err := performFHEOperation(store, "min", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleAnd(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -415,12 +498,16 @@ func (c *FHEContract) handleAnd(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasAnd, handle1, handle2)
 
-	result := performFHEOperation("and", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasAnd, nil
+	result, err := performFHEOperation(store, "and", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleOr(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -431,12 +518,16 @@ func (c *FHEContract) handleOr(state contract.AccessibleState, caller common.Add
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasOr, handle1, handle2)
 
-	result := performFHEOperation("or", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasOr, nil
+	result, err := performFHEOperation(store, "or", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleNot(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -449,8 +540,10 @@ func (c *FHEContract) handleNot(state contract.AccessibleState, caller common.Ad
 
 	handle := common.BytesToHash(data[:32])
 
-	result := performFHEUnaryOperation("not", handle, caller)
-
+	result, err := performFHEUnaryOperation(ciphertextStoreFor(state), "not", handle, caller)
+	if err != nil {
+		return nil, gas - GasNot, err
+	}
 	return result.Bytes(), gas - GasNot, nil
 }
 
@@ -464,8 +557,10 @@ func (c *FHEContract) handleNeg(state contract.AccessibleState, caller common.Ad
 
 	handle := common.BytesToHash(data[:32])
 
-	result := performFHEUnaryOperation("neg", handle, caller)
-
+	result, err := performFHEUnaryOperation(ciphertextStoreFor(state), "neg", handle, caller)
+	if err != nil {
+		return nil, gas - GasNeg, err
+	}
 	return result.Bytes(), gas - GasNeg, nil
 }
 
@@ -479,8 +574,10 @@ func (c *FHEContract) handleRand(state contract.AccessibleState, caller common.A
 
 	ctType := data[0]
 
-	result := generateEncryptedRandom(ctType, caller)
-
+	result, err := generateEncryptedRandom(ciphertextStoreFor(state), ctType, randomSeed(state, caller))
+	if err != nil {
+		return nil, gas - GasRand, err
+	}
 	return result.Bytes(), gas - GasRand, nil
 }
 
@@ -494,12 +591,16 @@ func (c *FHEContract) handleDiv(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasDiv, handle1, handle2)
 
-	result := performFHEOperation("div", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasDiv, nil
+	result, err := performFHEOperation(store, "div", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleRem(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -510,12 +611,16 @@ func (c *FHEContract) handleRem(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasRem, handle1, handle2)
 
-	result := performFHEOperation("rem", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasRem, nil
+	result, err := performFHEOperation(store, "rem", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 // === Scalar Arithmetic Handlers ===
@@ -531,8 +636,10 @@ func (c *FHEContract) handleScalarAdd(state contract.AccessibleState, caller com
 	handle := common.BytesToHash(data[:32])
 	scalar := new(big.Int).SetBytes(data[32:64])
 
-	result := performFHEScalarOperation("scalarAdd", handle, scalar, caller)
-
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarAdd", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasAdd, err
+	}
 	return result.Bytes(), gas - GasAdd, nil
 }
 
@@ -547,8 +654,10 @@ func (c *FHEContract) handleScalarSub(state contract.AccessibleState, caller com
 	handle := common.BytesToHash(data[:32])
 	scalar := new(big.Int).SetBytes(data[32:64])
 
-	result := performFHEScalarOperation("scalarSub", handle, scalar, caller)
-
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarSub", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasSub, err
+	}
 	return result.Bytes(), gas - GasSub, nil
 }
 
@@ -563,8 +672,10 @@ func (c *FHEContract) handleScalarMul(state contract.AccessibleState, caller com
 	handle := common.BytesToHash(data[:32])
 	scalar := new(big.Int).SetBytes(data[32:64])
 
-	result := performFHEScalarOperation("scalarMul", handle, scalar, caller)
-
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarMul", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasMul, err
+	}
 	return result.Bytes(), gas - GasMul, nil
 }
 
@@ -579,8 +690,10 @@ func (c *FHEContract) handleScalarDiv(state contract.AccessibleState, caller com
 	handle := common.BytesToHash(data[:32])
 	scalar := new(big.Int).SetBytes(data[32:64])
 
-	result := performFHEScalarOperation("scalarDiv", handle, scalar, caller)
-
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarDiv", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasDiv, err
+	}
 	return result.Bytes(), gas - GasDiv, nil
 }
 
@@ -595,11 +708,123 @@ func (c *FHEContract) handleScalarRem(state contract.AccessibleState, caller com
 	handle := common.BytesToHash(data[:32])
 	scalar := new(big.Int).SetBytes(data[32:64])
 
-	result := performFHEScalarOperation("scalarRem", handle, scalar, caller)
-
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarRem", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasRem, err
+	}
 	return result.Bytes(), gas - GasRem, nil
 }
 
+// === Scalar Comparison Handlers ===
+
+func (c *FHEContract) handleScalarLt(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasLt {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	scalar := new(big.Int).SetBytes(data[32:64])
+
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarLt", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasLt, err
+	}
+	return result.Bytes(), gas - GasLt, nil
+}
+
+func (c *FHEContract) handleScalarLe(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasLe {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	scalar := new(big.Int).SetBytes(data[32:64])
+
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarLe", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasLe, err
+	}
+	return result.Bytes(), gas - GasLe, nil
+}
+
+func (c *FHEContract) handleScalarGt(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasGt {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	scalar := new(big.Int).SetBytes(data[32:64])
+
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarGt", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasGt, err
+	}
+	return result.Bytes(), gas - GasGt, nil
+}
+
+func (c *FHEContract) handleScalarGe(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasGe {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	scalar := new(big.Int).SetBytes(data[32:64])
+
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarGe", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasGe, err
+	}
+	return result.Bytes(), gas - GasGe, nil
+}
+
+func (c *FHEContract) handleScalarEq(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasEq {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	scalar := new(big.Int).SetBytes(data[32:64])
+
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarEq", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasEq, err
+	}
+	return result.Bytes(), gas - GasEq, nil
+}
+
+func (c *FHEContract) handleScalarNe(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasNe {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	scalar := new(big.Int).SetBytes(data[32:64])
+
+	result, err := performFHEScalarOperation(ciphertextStoreFor(state), "scalarNe", handle, scalar, caller)
+	if err != nil {
+		return nil, gas - GasNe, err
+	}
+	return result.Bytes(), gas - GasNe, nil
+}
+
 // === Additional Comparison Handlers ===
 
 func (c *FHEContract) handleLe(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -610,12 +835,16 @@ func (c *FHEContract) handleLe(state contract.AccessibleState, caller common.Add
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasLe, handle1, handle2)
 
-	result := performFHEOperation("le", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasLe, nil
+	result, err := performFHEOperation(store, "le", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleGe(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -626,12 +855,16 @@ func (c *FHEContract) handleGe(state contract.AccessibleState, caller common.Add
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasGe, handle1, handle2)
 
-	result := performFHEOperation("ge", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasGe, nil
+	result, err := performFHEOperation(store, "ge", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 func (c *FHEContract) handleNe(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
@@ -642,12 +875,16 @@ func (c *FHEContract) handleNe(state contract.AccessibleState, caller common.Add
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasNe, handle1, handle2)
 
-	result := performFHEOperation("ne", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasNe, nil
+	result, err := performFHEOperation(store, "ne", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 // === Additional Bitwise Handlers ===
@@ -660,12 +897,16 @@ func (c *FHEContract) handleXor(state contract.AccessibleState, caller common.Ad
 		return nil, gas, ErrInsufficientGas
 	}
 
+	store := ciphertextStoreFor(state)
 	handle1 := common.BytesToHash(data[:32])
 	handle2 := common.BytesToHash(data[32:64])
+	required := operationGas(store, GasXor, handle1, handle2)
 
-	result := performFHEOperation("xor", handle1, handle2, caller)
-
-	return result.Bytes(), gas - GasXor, nil
+	result, err := performFHEOperation(store, "xor", handle1, handle2, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
 }
 
 // === Shift Operation Handlers ===
@@ -681,8 +922,10 @@ func (c *FHEContract) handleShl(state contract.AccessibleState, caller common.Ad
 	handle := common.BytesToHash(data[:32])
 	shift := int(data[32])
 
-	result := performFHEShiftOperation("shl", handle, shift, caller)
-
+	result, err := performFHEShiftOperation(ciphertextStoreFor(state), "shl", handle, shift, caller)
+	if err != nil {
+		return nil, gas - GasShl, err
+	}
 	return result.Bytes(), gas - GasShl, nil
 }
 
@@ -697,8 +940,10 @@ func (c *FHEContract) handleShr(state contract.AccessibleState, caller common.Ad
 	handle := common.BytesToHash(data[:32])
 	shift := int(data[32])
 
-	result := performFHEShiftOperation("shr", handle, shift, caller)
-
+	result, err := performFHEShiftOperation(ciphertextStoreFor(state), "shr", handle, shift, caller)
+	if err != nil {
+		return nil, gas - GasShr, err
+	}
 	return result.Bytes(), gas - GasShr, nil
 }
 
@@ -713,8 +958,10 @@ func (c *FHEContract) handleRotl(state contract.AccessibleState, caller common.A
 	handle := common.BytesToHash(data[:32])
 	shift := int(data[32])
 
-	result := performFHEShiftOperation("rotl", handle, shift, caller)
-
+	result, err := performFHEShiftOperation(ciphertextStoreFor(state), "rotl", handle, shift, caller)
+	if err != nil {
+		return nil, gas - GasRotl, err
+	}
 	return result.Bytes(), gas - GasRotl, nil
 }
 
@@ -729,8 +976,10 @@ func (c *FHEContract) handleRotr(state contract.AccessibleState, caller common.A
 	handle := common.BytesToHash(data[:32])
 	shift := int(data[32])
 
-	result := performFHEShiftOperation("rotr", handle, shift, caller)
-
+	result, err := performFHEShiftOperation(ciphertextStoreFor(state), "rotr", handle, shift, caller)
+	if err != nil {
+		return nil, gas - GasRotr, err
+	}
 	return result.Bytes(), gas - GasRotr, nil
 }
 
@@ -747,8 +996,10 @@ func (c *FHEContract) handleCast(state contract.AccessibleState, caller common.A
 	handle := common.BytesToHash(data[:32])
 	toType := data[32]
 
-	result := performFHECast(handle, toType, caller)
-
+	result, err := performFHECast(ciphertextStoreFor(state), handle, toType, caller)
+	if err != nil {
+		return nil, gas - GasCast, err
+	}
 	return result.Bytes(), gas - GasCast, nil
 }
 
@@ -766,8 +1017,10 @@ func (c *FHEContract) handleAsEbool(state contract.AccessibleState, caller commo
 		boolVal = 1
 	}
 
-	result := encryptValue(boolVal, TypeEbool, caller)
-
+	result, err := encryptValue(ciphertextStoreFor(state), boolVal, TypeEbool, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -781,8 +1034,10 @@ func (c *FHEContract) handleAsEuint4(state contract.AccessibleState, caller comm
 
 	value := new(big.Int).SetBytes(data[:32])
 
-	result := encryptValue(value.Uint64()&0xF, TypeEuint4, caller)
-
+	result, err := encryptValue(ciphertextStoreFor(state), value.Uint64()&0xF, TypeEuint4, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -796,8 +1051,10 @@ func (c *FHEContract) handleAsEuint8(state contract.AccessibleState, caller comm
 
 	value := new(big.Int).SetBytes(data[:32])
 
-	result := encryptValue(value.Uint64()&0xFF, TypeEuint8, caller)
-
+	result, err := encryptValue(ciphertextStoreFor(state), value.Uint64()&0xFF, TypeEuint8, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -811,8 +1068,10 @@ func (c *FHEContract) handleAsEuint16(state contract.AccessibleState, caller com
 
 	value := new(big.Int).SetBytes(data[:32])
 
-	result := encryptValue(value.Uint64()&0xFFFF, TypeEuint16, caller)
-
+	result, err := encryptValue(ciphertextStoreFor(state), value.Uint64()&0xFFFF, TypeEuint16, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -826,8 +1085,10 @@ func (c *FHEContract) handleAsEuint32(state contract.AccessibleState, caller com
 
 	value := new(big.Int).SetBytes(data[:32])
 
-	result := encryptValue(value.Uint64()&0xFFFFFFFF, TypeEuint32, caller)
-
+	result, err := encryptValue(ciphertextStoreFor(state), value.Uint64()&0xFFFFFFFF, TypeEuint32, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -841,8 +1102,10 @@ func (c *FHEContract) handleAsEuint128(state contract.AccessibleState, caller co
 
 	value := new(big.Int).SetBytes(data[:32])
 
-	result := encryptBigIntValue(value, TypeEuint128, caller)
-
+	result, err := encryptBigIntValue(ciphertextStoreFor(state), value, TypeEuint128, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -856,8 +1119,10 @@ func (c *FHEContract) handleAsEuint256(state contract.AccessibleState, caller co
 
 	value := new(big.Int).SetBytes(data[:32])
 
-	result := encryptBigIntValue(value, TypeEuint256, caller)
-
+	result, err := encryptBigIntValue(ciphertextStoreFor(state), value, TypeEuint256, caller)
+	if err != nil {
+		return nil, gas - GasEncrypt, err
+	}
 	return result.Bytes(), gas - GasEncrypt, nil
 }
 
@@ -871,113 +1136,204 @@ func (c *FHEContract) handleDecrypt(state contract.AccessibleState, caller commo
 		return nil, gas, ErrInsufficientGas
 	}
 
-	handle := common.BytesToHash(data[:32])
+	// The committee holds the key when a decryption oracle is configured
+	if decryptionOracle != nil {
+		return nil, gas - GasDecryptRequest, ErrSyncDecryption
+	}
 
-	result := performFHEDecrypt(handle, caller)
+	handle := common.BytesToHash(data[:32])
 
+	result, err := performFHEDecrypt(ciphertextStoreFor(state), handle, caller)
+	if err != nil {
+		return nil, gas - GasDecryptRequest, err
+	}
 	return result.Bytes(), gas - GasDecryptRequest, nil
 }
 
+// handleVerify accepts a user-supplied ciphertext with its input proof.
+// Input is packed as type (1) || ciphertext || proof || uint32 proof length.
 func (c *FHEContract) handleVerify(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
-	if len(data) < 33 {
+	if len(data) < 1+inputProofHeaderSize+4 {
 		return nil, gas, ErrInvalidInput
 	}
-	if gas < GasEncrypt {
+	if gas < GasVerifyInput {
 		return nil, gas, ErrInsufficientGas
 	}
 
 	ctType := data[0]
-	inputHandle := data[1:]
-
-	result := performFHEVerify(inputHandle, ctType, caller)
+	ct, proof, err := SplitInput(data[1:])
+	if err != nil {
+		return nil, gas - GasVerifyInput, err
+	}
+	digest, err := verifyInput(ct, proof, ctType, caller)
+	if err != nil {
+		return nil, gas - GasVerifyInput, err
+	}
+	if err := consumeInput(state, digest); err != nil {
+		return nil, gas - GasVerifyInput, err
+	}
 
-	return result.Bytes(), gas - GasEncrypt, nil
+	result, err := performFHEVerify(ciphertextStoreFor(state), ct, ctType, caller)
+	if err != nil {
+		return nil, gas - GasVerifyInput, err
+	}
+	return result.Bytes(), gas - GasVerifyInput, nil
 }
 
-func (c *FHEContract) handleSealOutput(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
-	if len(data) < 64 {
+// === Auction Handlers ===
+
+// handleMaxWithIndex computes the encrypted maximum of a list of sealed bids
+// together with the encrypted index of the winning bid. Only the two result
+// handles are marked decryptable in the ACL; the bid handles stay sealed.
+func (c *FHEContract) handleMaxWithIndex(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	bids, err := decodeHandleArray(data)
+	if err != nil {
+		return nil, gas, err
+	}
+	if len(bids) == 0 || len(bids) > MaxAuctionBids {
 		return nil, gas, ErrInvalidInput
 	}
-	if gas < GasEncrypt {
+	required := GasMaxWithIndexPerBid * uint64(len(bids))
+	if gas < required {
 		return nil, gas, ErrInsufficientGas
 	}
 
-	handle := common.BytesToHash(data[:32])
-	publicKey := data[32:]
+	maxHandle, indexHandle, err := performFHEMaxWithIndex(ciphertextStoreFor(state), bids, caller)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	if acl := aclFor(state); acl != nil {
+		acl.MarkDecryptable(maxHandle)
+		acl.MarkDecryptable(indexHandle)
+	}
+
+	ret := make([]byte, 64)
+	copy(ret[:32], maxHandle.Bytes())
+	copy(ret[32:], indexHandle.Bytes())
+	return ret, gas - required, nil
+}
 
-	result := performFHESealOutput(handle, publicKey, caller)
+// decodeHandleArrayLen returns the element count of an ABI-encoded bytes32[]
+// argument whose offset word is at head
+func decodeHandleArrayLen(data []byte, head int) (uint64, error) {
+	if len(data) < head+32 {
+		return 0, ErrInvalidInput
+	}
+	offset := new(big.Int).SetBytes(data[head : head+32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data))-32 {
+		return 0, ErrInvalidInput
+	}
+	start := offset.Uint64()
+	length := new(big.Int).SetBytes(data[start : start+32])
+	if !length.IsUint64() || length.Uint64() > MaxAuctionBids {
+		return 0, ErrInvalidInput
+	}
+	return length.Uint64(), nil
+}
 
-	return result, gas - GasEncrypt, nil
+// decodeHandleArray decodes an ABI-encoded bytes32[] into ciphertext handles
+func decodeHandleArray(data []byte) ([]common.Hash, error) {
+	return decodeHandleArrayArg(data, 0)
 }
 
-// ciphertextStore holds encrypted values indexed by hash
-var ciphertextStore = make(map[common.Hash][]byte)
-var ciphertextTypes = make(map[common.Hash]uint8)
+// decodeHandleArrayArg decodes the bytes32[] argument whose offset word is
+// at head. Offsets are relative to the start of data, as in standard ABI
+// encoding.
+func decodeHandleArrayArg(data []byte, head int) ([]common.Hash, error) {
+	n, err := decodeHandleArrayLen(data, head)
+	if err != nil {
+		return nil, err
+	}
+	start := new(big.Int).SetBytes(data[head:head+32]).Uint64() + 32
+	if uint64(len(data)) < start+n*32 {
+		return nil, ErrInvalidInput
+	}
+	handles := make([]common.Hash, n)
+	for i := range handles {
+		off := start + uint64(i)*32
+		handles[i] = common.BytesToHash(data[off : off+32])
+	}
+	return handles, nil
+}
 
-// storeCiphertext saves ciphertext and returns its hash
-func storeCiphertext(ct []byte, ctType uint8) common.Hash {
-	hash := common.BytesToHash(ct)
-	ciphertextStore[hash] = ct
-	ciphertextTypes[hash] = ctType
+// storeCiphertext saves ciphertext and returns its handle: derived from the
+// call on chain (see handles.go), from the ciphertext bytes otherwise
+func storeCiphertext(store CiphertextBackend, ct []byte, ctType uint8) common.Hash {
+	hash := resultHandle(store, common.BytesToHash(ct))
+	store.Put(hash, ct, ctType)
 	return hash
 }
 
 // getCiphertext retrieves ciphertext by hash
-func getCiphertext(hash common.Hash) ([]byte, uint8, bool) {
-	ct, ok := ciphertextStore[hash]
-	if !ok {
-		return nil, 0, false
-	}
-	return ct, ciphertextTypes[hash], true
+func getCiphertext(store CiphertextBackend, hash common.Hash) ([]byte, uint8, bool) {
+	return store.Get(hash)
 }
 
 // performFHEOperation executes FHE binary operations using real TFHE library
-func performFHEOperation(op string, handle1, handle2 common.Hash, caller common.Address) common.Hash {
-	lhs, lhsType, ok := getCiphertext(handle1)
+func performFHEOperation(store CiphertextBackend, op string, handle1, handle2 common.Hash, caller common.Address) (common.Hash, error) {
+	if coprocessor != nil {
+		return coprocessor.Enqueue(stateDBOf(store), store, op, []common.Hash{handle1, handle2}, caller)
+	}
+
+	lhs, lhsType, ok := getCiphertext(store, handle1)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound(op, handle1)
 	}
-	rhs, _, ok := getCiphertext(handle2)
+	rhs, rhsType, ok := getCiphertext(store, handle2)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound(op, handle2)
+	}
+	if err := checkOperandTypes(op, []common.Hash{handle1, handle2}, []uint8{lhsType, rhsType}); err != nil {
+		return common.Hash{}, err
 	}
 
+	result, resultType := evaluate(EvalRequest{Op: op, Inputs: [][]byte{lhs, rhs}, Types: []uint8{lhsType, rhsType}})
+	if result == nil {
+		return common.Hash{}, opFailed(op)
+	}
+
+	return storeResult(store, result, resultType, handle1, handle2), nil
+}
+
+// computeFHEOperation evaluates a binary operation on raw ciphertexts and
+// returns the result with its type. It does not touch the ciphertext store.
+func computeFHEOperation(tc *tfheContext, op string, lhs, rhs []byte, lhsType uint8) ([]byte, uint8) {
 	var result []byte
 	switch op {
 	case "add":
-		result = tfheAdd(lhs, rhs, lhsType)
+		result = tfheAdd(tc, lhs, rhs, lhsType)
 	case "sub":
-		result = tfheSub(lhs, rhs, lhsType)
+		result = tfheSub(tc, lhs, rhs, lhsType)
 	case "mul":
-		result = tfheMul(lhs, rhs, lhsType)
+		result = tfheMul(tc, lhs, rhs, lhsType)
+	case "div":
+		result = tfheDiv(tc, lhs, rhs, lhsType)
+	case "rem":
+		result = tfheRem(tc, lhs, rhs, lhsType)
 	case "lt":
-		result = tfheLt(lhs, rhs, lhsType)
+		result = tfheLt(tc, lhs, rhs, lhsType)
 	case "gt":
-		result = tfheGt(lhs, rhs, lhsType)
+		result = tfheGt(tc, lhs, rhs, lhsType)
 	case "eq":
-		result = tfheEq(lhs, rhs, lhsType)
+		result = tfheEq(tc, lhs, rhs, lhsType)
 	case "ne":
-		result = tfheNe(lhs, rhs, lhsType)
+		result = tfheNe(tc, lhs, rhs, lhsType)
 	case "le":
-		result = tfheLe(lhs, rhs, lhsType)
+		result = tfheLe(tc, lhs, rhs, lhsType)
 	case "ge":
-		result = tfheGe(lhs, rhs, lhsType)
+		result = tfheGe(tc, lhs, rhs, lhsType)
 	case "and":
-		result = tfheAnd(lhs, rhs, lhsType)
+		result = tfheAnd(tc, lhs, rhs, lhsType)
 	case "or":
-		result = tfheOr(lhs, rhs, lhsType)
+		result = tfheOr(tc, lhs, rhs, lhsType)
 	case "xor":
-		result = tfheXor(lhs, rhs, lhsType)
+		result = tfheXor(tc, lhs, rhs, lhsType)
 	case "min":
-		result = tfheMin(lhs, rhs, lhsType)
+		result = tfheMin(tc, lhs, rhs, lhsType)
 	case "max":
-		result = tfheMax(lhs, rhs, lhsType)
+		result = tfheMax(tc, lhs, rhs, lhsType)
 	default:
-		return common.Hash{}
-	}
-
-	if result == nil {
-		return common.Hash{}
+		return nil, 0
 	}
 
 	// Comparison ops return TypeEbool
@@ -986,192 +1342,277 @@ func performFHEOperation(op string, handle1, handle2 common.Hash, caller common.
 		resultType = TypeEbool
 	}
 
-	return storeCiphertext(result, resultType)
+	return result, resultType
 }
 
 // performFHESelect executes conditional selection using real TFHE library
-func performFHESelect(condition, ifTrue, ifFalse common.Hash, caller common.Address) common.Hash {
-	ctControl, _, ok := getCiphertext(condition)
+func performFHESelect(store CiphertextBackend, condition, ifTrue, ifFalse common.Hash, caller common.Address) (common.Hash, error) {
+	if coprocessor != nil {
+		return coprocessor.Enqueue(stateDBOf(store), store, "select", []common.Hash{condition, ifTrue, ifFalse}, caller)
+	}
+
+	ctControl, controlType, ok := getCiphertext(store, condition)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound("select", condition)
 	}
-	ctTrue, trueType, ok := getCiphertext(ifTrue)
+	ctTrue, trueType, ok := getCiphertext(store, ifTrue)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound("select", ifTrue)
 	}
-	ctFalse, _, ok := getCiphertext(ifFalse)
+	ctFalse, falseType, ok := getCiphertext(store, ifFalse)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound("select", ifFalse)
+	}
+	handles := []common.Hash{condition, ifTrue, ifFalse}
+	if err := checkOperandTypes("select", handles, []uint8{controlType, trueType, falseType}); err != nil {
+		return common.Hash{}, err
 	}
 
-	result := tfheSelect(ctControl, ctTrue, ctFalse, trueType)
+	result, resultType := evaluate(EvalRequest{Op: "select", Inputs: [][]byte{ctControl, ctTrue, ctFalse}, Types: []uint8{controlType, trueType, falseType}})
 	if result == nil {
-		return common.Hash{}
+		return common.Hash{}, opFailed("select")
 	}
 
-	return storeCiphertext(result, trueType)
+	return storeResult(store, result, resultType, handles...), nil
+}
+
+// checkOperandTypes checks the operand types of a binary operation or
+// select: binary operands share a type, and select takes an ebool condition
+// and two values of one type
+func checkOperandTypes(op string, handles []common.Hash, types []uint8) error {
+	switch {
+	case op == "select" && len(types) == 3:
+		if types[0] != TypeEbool {
+			return typeMismatch(op, handles[0], TypeEbool, types[0])
+		}
+		if types[2] != types[1] {
+			return typeMismatch(op, handles[2], types[1], types[2])
+		}
+	case len(types) == 2:
+		if types[1] != types[0] {
+			return typeMismatch(op, handles[1], types[0], types[1])
+		}
+	}
+	return nil
 }
 
 // performFHEUnaryOperation executes FHE unary operations using real TFHE library
-func performFHEUnaryOperation(op string, handle common.Hash, caller common.Address) common.Hash {
-	ct, ctType, ok := getCiphertext(handle)
+func performFHEUnaryOperation(store CiphertextBackend, op string, handle common.Hash, caller common.Address) (common.Hash, error) {
+	if coprocessor != nil {
+		return coprocessor.Enqueue(stateDBOf(store), store, op, []common.Hash{handle}, caller)
+	}
+
+	ct, ctType, ok := getCiphertext(store, handle)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound(op, handle)
 	}
 
-	var result []byte
+	result, _ := evaluate(EvalRequest{Op: op, Inputs: [][]byte{ct}, Types: []uint8{ctType}})
+	if result == nil {
+		return common.Hash{}, opFailed(op)
+	}
+
+	return storeResult(store, result, ctType, handle), nil
+}
+
+// computeFHEUnaryOperation evaluates a unary operation on a raw ciphertext
+func computeFHEUnaryOperation(tc *tfheContext, op string, ct []byte, ctType uint8) []byte {
 	switch op {
 	case "not":
-		result = tfheNot(ct, ctType)
+		return tfheNot(tc, ct, ctType)
 	case "neg":
-		result = tfheNeg(ct, ctType)
+		return tfheNeg(tc, ct, ctType)
 	default:
-		return common.Hash{}
-	}
-
-	if result == nil {
-		return common.Hash{}
+		return nil
 	}
-
-	return storeCiphertext(result, ctType)
 }
 
 // encryptValue encrypts a plaintext value using real TFHE library
-func encryptValue(value uint64, ctType uint8, caller common.Address) common.Hash {
-	ct := tfheTrivialEncrypt(new(big.Int).SetUint64(value), ctType)
+func encryptValue(store CiphertextBackend, value uint64, ctType uint8, caller common.Address) (common.Hash, error) {
+	ct := tfheTrivialEncrypt(defaultTFHE(), new(big.Int).SetUint64(value), ctType)
 	if ct == nil {
-		return common.Hash{}
+		return common.Hash{}, opFailed("encrypt")
 	}
-	return storeCiphertext(ct, ctType)
+	return storeTrivial(store, ct, ctType), nil
 }
 
 // encryptAddress encrypts an address using real TFHE library
-func encryptAddress(addr common.Address, caller common.Address) common.Hash {
+func encryptAddress(store CiphertextBackend, addr common.Address, caller common.Address) (common.Hash, error) {
 	// Address is 160 bits
 	value := new(big.Int).SetBytes(addr.Bytes())
-	ct := tfheTrivialEncrypt(value, TypeEaddress)
+	ct := tfheTrivialEncrypt(defaultTFHE(), value, TypeEaddress)
 	if ct == nil {
-		return common.Hash{}
+		return common.Hash{}, opFailed("encrypt")
 	}
-	return storeCiphertext(ct, TypeEaddress)
+	return storeTrivial(store, ct, TypeEaddress), nil
 }
 
 // generateEncryptedRandom generates random encrypted value using real TFHE library
-func generateEncryptedRandom(ctType uint8, caller common.Address) common.Hash {
-	// Use caller address as part of seed for determinism
-	seed := new(big.Int).SetBytes(caller.Bytes()).Uint64()
-	ct := tfheRandom(ctType, seed)
+func generateEncryptedRandom(store CiphertextBackend, ctType uint8, seed []byte) (common.Hash, error) {
+	ct := tfheRandomSeed(ctType, seed)
 	if ct == nil {
-		return common.Hash{}
+		return common.Hash{}, opFailed("rand")
 	}
-	return storeCiphertext(ct, ctType)
+	return storeCiphertext(store, ct, ctType), nil
 }
 
 // performFHEScalarOperation executes FHE scalar operations using real TFHE library
-func performFHEScalarOperation(op string, handle common.Hash, scalar *big.Int, caller common.Address) common.Hash {
-	ct, ctType, ok := getCiphertext(handle)
+func performFHEScalarOperation(store CiphertextBackend, op string, handle common.Hash, scalar *big.Int, caller common.Address) (common.Hash, error) {
+	ct, ctType, ok := getCiphertext(store, handle)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound(op, handle)
+	}
+	if bits, ok := typeBitWidth(ctType); ok && isScalarComparison(op) && scalar.BitLen() > int(bits) {
+		return common.Hash{}, &OpError{Op: op, Handle: handle, Err: ErrScalarOutOfRange}
 	}
 
-	var result []byte
+	result, resultType := evaluate(EvalRequest{Op: op, Inputs: [][]byte{ct}, Types: []uint8{ctType}, Scalar: scalar})
+	if result == nil {
+		return common.Hash{}, opFailed(op)
+	}
+
+	return storeResult(store, result, resultType, handle), nil
+}
+
+func isScalarComparison(op string) bool {
+	switch op {
+	case "scalarLt", "scalarLe", "scalarGt", "scalarGe", "scalarEq", "scalarNe":
+		return true
+	}
+	return false
+}
+
+// computeFHEScalarOperation evaluates a ciphertext-plaintext operation on a
+// raw ciphertext and returns the result with its type
+func computeFHEScalarOperation(tc *tfheContext, op string, ct []byte, scalar *big.Int, ctType uint8) ([]byte, uint8) {
 	switch op {
 	case "scalarAdd":
-		result = tfheScalarAdd(ct, scalar.Uint64(), ctType)
+		return tfheScalarAdd(tc, ct, scalar.Uint64(), ctType), ctType
 	case "scalarSub":
-		result = tfheScalarSub(ct, scalar.Uint64(), ctType)
+		return tfheScalarSub(tc, ct, scalar.Uint64(), ctType), ctType
 	case "scalarMul":
-		result = tfheScalarMul(ct, scalar.Uint64(), ctType)
+		return tfheScalarMul(tc, ct, scalar.Uint64(), ctType), ctType
 	case "scalarDiv":
-		result = tfheScalarDiv(ct, scalar.Uint64(), ctType)
+		return tfheScalarDiv(tc, ct, scalar.Uint64(), ctType), ctType
 	case "scalarRem":
-		result = tfheScalarRem(ct, scalar.Uint64(), ctType)
+		return tfheScalarRem(tc, ct, scalar.Uint64(), ctType), ctType
+	case "scalarLt", "scalarLe", "scalarGt", "scalarGe", "scalarEq", "scalarNe":
+		return computeFHEScalarComparison(tc, op, ct, scalar, ctType), TypeEbool
 	default:
-		return common.Hash{}
+		return nil, 0
 	}
+}
 
-	if result == nil {
-		return common.Hash{}
+// computeFHEScalarComparison compares a ciphertext with a plaintext no
+// wider than the ciphertext type, and fails for wider scalars
+func computeFHEScalarComparison(tc *tfheContext, op string, ct []byte, scalar *big.Int, ctType uint8) []byte {
+	if bits, ok := typeBitWidth(ctType); !ok || scalar.BitLen() > int(bits) {
+		return nil
 	}
-
-	return storeCiphertext(result, ctType)
+	return tfheScalarCompare(tc, op, ct, scalar, ctType)
 }
 
 // performFHEShiftOperation executes FHE shift operations using real TFHE library
-func performFHEShiftOperation(op string, handle common.Hash, shift int, caller common.Address) common.Hash {
-	ct, ctType, ok := getCiphertext(handle)
+func performFHEShiftOperation(store CiphertextBackend, op string, handle common.Hash, shift int, caller common.Address) (common.Hash, error) {
+	ct, ctType, ok := getCiphertext(store, handle)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound(op, handle)
 	}
 
-	var result []byte
+	result, _ := evaluate(EvalRequest{Op: op, Inputs: [][]byte{ct}, Types: []uint8{ctType}, Scalar: big.NewInt(int64(shift))})
+	if result == nil {
+		return common.Hash{}, opFailed(op)
+	}
+
+	return storeResult(store, result, ctType, handle), nil
+}
+
+// computeFHEShiftOperation evaluates a shift or rotate on a raw ciphertext
+func computeFHEShiftOperation(tc *tfheContext, op string, ct []byte, shift int, ctType uint8) []byte {
 	switch op {
 	case "shl":
-		result = tfheShl(ct, shift, ctType)
+		return tfheShl(tc, ct, shift, ctType)
 	case "shr":
-		result = tfheShr(ct, shift, ctType)
+		return tfheShr(tc, ct, shift, ctType)
 	case "rotl":
-		result = tfheRotl(ct, shift, ctType)
+		return tfheRotl(tc, ct, shift, ctType)
 	case "rotr":
-		result = tfheRotr(ct, shift, ctType)
+		return tfheRotr(tc, ct, shift, ctType)
 	default:
-		return common.Hash{}
-	}
-
-	if result == nil {
-		return common.Hash{}
+		return nil
 	}
-
-	return storeCiphertext(result, ctType)
 }
 
 // performFHECast executes type casting using real TFHE library
-func performFHECast(handle common.Hash, toType uint8, caller common.Address) common.Hash {
-	ct, fromType, ok := getCiphertext(handle)
+func performFHECast(store CiphertextBackend, handle common.Hash, toType uint8, caller common.Address) (common.Hash, error) {
+	ct, fromType, ok := getCiphertext(store, handle)
 	if !ok {
-		return common.Hash{}
+		return common.Hash{}, handleNotFound("cast", handle)
 	}
 
-	result := tfheCast(ct, fromType, toType)
+	result, _ := evaluate(EvalRequest{Op: "cast", Inputs: [][]byte{ct}, Types: []uint8{fromType}, ToType: toType})
 	if result == nil {
-		return common.Hash{}
+		return common.Hash{}, opFailed("cast")
 	}
 
-	return storeCiphertext(result, toType)
+	return storeResult(store, result, toType, handle), nil
 }
 
 // encryptBigIntValue encrypts a big.Int value for types > 64 bits
-func encryptBigIntValue(value *big.Int, ctType uint8, caller common.Address) common.Hash {
-	ct := tfheTrivialEncrypt(value, ctType)
+func encryptBigIntValue(store CiphertextBackend, value *big.Int, ctType uint8, caller common.Address) (common.Hash, error) {
+	ct := tfheTrivialEncrypt(defaultTFHE(), value, ctType)
 	if ct == nil {
-		return common.Hash{}
+		return common.Hash{}, opFailed("encrypt")
 	}
-	return storeCiphertext(ct, ctType)
+	return storeTrivial(store, ct, ctType), nil
 }
 
 // performFHEDecrypt decrypts a ciphertext (returns as big.Int bytes)
-func performFHEDecrypt(handle common.Hash, caller common.Address) *big.Int {
-	ct, ctType, ok := getCiphertext(handle)
+func performFHEDecrypt(store CiphertextBackend, handle common.Hash, caller common.Address) (*big.Int, error) {
+	ct, ctType, ok := getCiphertext(store, handle)
 	if !ok {
-		return big.NewInt(0)
+		return nil, handleNotFound("decrypt", handle)
 	}
 
-	return tfheDecrypt(ct, ctType)
+	result := tfheDecrypt(ct, ctType)
+	if result == nil {
+		return nil, opFailed("decrypt")
+	}
+	return result, nil
 }
 
-// performFHEVerify verifies and stores an input ciphertext
-func performFHEVerify(inputHandle []byte, ctType uint8, caller common.Address) common.Hash {
-	if !tfheVerify(inputHandle, ctType) {
-		return common.Hash{}
+// performFHEVerify stores an input ciphertext whose proof was verified
+func performFHEVerify(store CiphertextBackend, ct []byte, ctType uint8, caller common.Address) (common.Hash, error) {
+	if !tfheVerify(ct, ctType) {
+		return common.Hash{}, opFailed("verify")
 	}
-	return storeCiphertext(inputHandle, ctType)
+	return storeCiphertext(store, ct, ctType), nil
 }
 
-// performFHESealOutput seals output for a specific public key
-func performFHESealOutput(handle common.Hash, publicKey []byte, caller common.Address) []byte {
-	ct, ctType, ok := getCiphertext(handle)
-	if !ok {
-		return nil
-	}
-	return tfheSealOutput(ct, publicKey, ctType)
+// performFHEMaxWithIndex folds the bids into an encrypted maximum and the
+// encrypted index of the first bid holding it
+func performFHEMaxWithIndex(store CiphertextBackend, bids []common.Hash, caller common.Address) (common.Hash, common.Hash, error) {
+	cts := make([][]byte, len(bids))
+	var bidType uint8
+	for i, h := range bids {
+		ct, ctType, ok := getCiphertext(store, h)
+		if !ok {
+			return common.Hash{}, common.Hash{}, handleNotFound("encMaxWithIndex", h)
+		}
+		if i == 0 {
+			bidType = ctType
+		} else if ctType != bidType {
+			return common.Hash{}, common.Hash{}, typeMismatch("encMaxWithIndex", h, bidType, ctType)
+		}
+		cts[i] = ct
+	}
+
+	maxCt, indexCt := tfheMaxWithIndex(defaultTFHE(), cts, bidType)
+	if maxCt == nil || indexCt == nil {
+		return common.Hash{}, common.Hash{}, opFailed("encMaxWithIndex")
+	}
+
+	maxHandle := storeCiphertext(store, maxCt, bidType)
+	indexHandle := storeCiphertext(store, indexCt, TypeEuint32)
+	return maxHandle, indexHandle, nil
 }
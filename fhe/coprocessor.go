@@ -0,0 +1,442 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Coprocessor execution of FHE operations.
+//
+// In coprocessor mode, binary, unary and select operations do not evaluate
+// TFHE inline. The precompile enqueues a compute job and returns the job's
+// result handle straight away. The handle is derived from the operation, its
+// input handles, the caller and a sequence number, so every node assigns the
+// same handle without running the computation.
+//
+// An off-chain coprocessor executes pending jobs and posts each result
+// ciphertext together with signatures from a threshold of attestors over
+// (result handle, ciphertext hash). The precompile checks the signatures and
+// stores the ciphertext under the job's handle, which finalizes the job.
+//
+// Jobs may take pending handles as inputs, so contracts can chain operations
+// before any result has been posted. A result is accepted only once every
+// input is final, so jobs finalize in dependency order. Operations with
+// plaintext operands (scalar, shift, cast) still run inline and need final
+// inputs.
+//
+// Jobs live in the storage of the FHE precompile account, next to the
+// ciphertexts they produce, so every node derives the same handles and a
+// reverted transaction leaves no job behind. Each job takes its slots after
+//
+//	record = keccak256(recordDomain || handle)
+//
+// holding the operation, caller || sequence number, input count || type ||
+// status, and then one input handle per slot. The sequence counter and the
+// oldest possibly pending sequence number have slots of their own, and the
+// queue maps each sequence number to its job handle.
+
+// Coprocessor gas costs
+const (
+	GasComputeStatus     uint64 = 2600
+	GasPostComputeResult uint64 = 50000
+	GasPerAttestation    uint64 = 3000 // One ecrecover per signature
+)
+
+// Domain separators for coprocessor hashing and storage
+const (
+	computeHandleDomain = "lux.fhe.coprocessor.handle.v1"
+	computeResultDomain = "lux.fhe.coprocessor.result.v1"
+	computeRecordDomain = "lux.fhe.coprocessor.record.v1"
+	computeQueueDomain  = "lux.fhe.coprocessor.queue.v1"
+	computeSeqDomain    = "lux.fhe.coprocessor.seq.v1"
+	computeHeadDomain   = "lux.fhe.coprocessor.head.v1"
+)
+
+// Job record word offsets; inputs follow the status word
+const (
+	computeWordOp = iota
+	computeWordCaller
+	computeWordStatus
+	computeWordInputs
+)
+
+var (
+	ErrInvalidAttestors   = errors.New("invalid coprocessor attestor set")
+	ErrJobNotFound        = errors.New("compute job not found")
+	ErrJobFinalized       = errors.New("compute job already finalized")
+	ErrJobInputsPending   = errors.New("compute job inputs not finalized")
+	ErrAttestation        = errors.New("insufficient coprocessor attestations")
+	ErrCoprocessorNoState = errors.New("coprocessor mode requires state")
+)
+
+// JobStatus is the lifecycle state of a compute job
+type JobStatus uint8
+
+const (
+	JobUnknown   JobStatus = iota // No job for the handle
+	JobPending                    // Waiting for the coprocessor
+	JobFinalized                  // Result ciphertext stored
+)
+
+// ComputeJob is an FHE operation delegated to the coprocessor
+type ComputeJob struct {
+	Handle     common.Hash   // Result handle, also identifies the job
+	Op         string        // Operation name, as used by performFHE*
+	Inputs     []common.Hash // Input ciphertext handles
+	ResultType uint8         // Ciphertext type of the result
+	Caller     common.Address
+	Seq        uint64
+	Status     JobStatus
+}
+
+// Coprocessor is the attestor set allowed to finalize compute jobs
+type Coprocessor struct {
+	attestors map[common.Address]bool
+	threshold int
+}
+
+// coprocessor is the active coprocessor; nil means operations run inline
+var coprocessor *Coprocessor
+
+// SetCoprocessor enables coprocessor mode, or disables it when cp is nil
+func SetCoprocessor(cp *Coprocessor) {
+	coprocessor = cp
+}
+
+// ActiveCoprocessor returns the active coprocessor, or nil in inline mode
+func ActiveCoprocessor() *Coprocessor {
+	return coprocessor
+}
+
+// NewCoprocessor creates a coprocessor whose results must be signed by at
+// least threshold of the given attestors
+func NewCoprocessor(attestors []common.Address, threshold int) (*Coprocessor, error) {
+	if err := verifyAttestors(attestors, threshold); err != nil {
+		return nil, err
+	}
+
+	set := make(map[common.Address]bool, len(attestors))
+	for _, a := range attestors {
+		set[a] = true
+	}
+	return &Coprocessor{attestors: set, threshold: threshold}, nil
+}
+
+// verifyAttestors checks that threshold is reachable by distinct, nonzero
+// attestors
+func verifyAttestors(attestors []common.Address, threshold int) error {
+	if threshold <= 0 || threshold > len(attestors) {
+		return ErrInvalidAttestors
+	}
+	seen := make(map[common.Address]bool, len(attestors))
+	for _, a := range attestors {
+		if a == (common.Address{}) || seen[a] {
+			return ErrInvalidAttestors
+		}
+		seen[a] = true
+	}
+	return nil
+}
+
+// Enqueue records a compute job in db and returns its result handle. It
+// fails if an input is unknown to store and the queue, the operand types do
+// not fit the operation, or the operation is not supported.
+func (cp *Coprocessor) Enqueue(db contract.StateDB, store CiphertextBackend, op string, inputs []common.Hash, caller common.Address) (common.Hash, error) {
+	if db == nil {
+		return common.Hash{}, ErrCoprocessorNoState
+	}
+
+	types := make([]uint8, len(inputs))
+	for i, in := range inputs {
+		ctType, ok := cp.inputType(db, store, in)
+		if !ok {
+			return common.Hash{}, handleNotFound(op, in)
+		}
+		types[i] = ctType
+	}
+	if err := checkOperandTypes(op, inputs, types); err != nil {
+		return common.Hash{}, err
+	}
+
+	resultType, ok := computeResultType(op, types)
+	if !ok {
+		return common.Hash{}, &OpError{Op: op, Err: ErrNotImplemented}
+	}
+
+	seq := getCounter(db, counterSlot(computeSeqDomain))
+	job := &ComputeJob{
+		Op:         op,
+		Inputs:     append([]common.Hash(nil), inputs...),
+		ResultType: resultType,
+		Caller:     caller,
+		Seq:        seq,
+		Status:     JobPending,
+	}
+	job.Handle = computeJobHandle(job)
+
+	record := computeRecordSlot(job.Handle)
+	var opWord, meta common.Hash
+	copy(opWord[:], op)
+	db.SetState(ContractAddress, ciphertextDataSlot(record, computeWordOp), opWord)
+	copy(meta[:20], caller.Bytes())
+	binary.BigEndian.PutUint64(meta[24:], seq)
+	db.SetState(ContractAddress, ciphertextDataSlot(record, computeWordCaller), meta)
+	for i, in := range inputs {
+		db.SetState(ContractAddress, ciphertextDataSlot(record, computeWordInputs+i), in)
+	}
+	setJobStatus(db, record, len(inputs), resultType, JobPending)
+	db.SetState(ContractAddress, computeQueueSlot(seq), job.Handle)
+	setCounter(db, counterSlot(computeSeqDomain), seq+1)
+	return job.Handle, nil
+}
+
+// inputType returns the type of a stored or pending ciphertext
+func (cp *Coprocessor) inputType(db contract.StateDB, store CiphertextBackend, handle common.Hash) (uint8, bool) {
+	if _, ctType, ok := getCiphertext(store, handle); ok {
+		return ctType, true
+	}
+	if job, ok := cp.Job(db, handle); ok && job.Status == JobPending {
+		return job.ResultType, true
+	}
+	return 0, false
+}
+
+func computeRecordSlot(handle common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte(computeRecordDomain), handle.Bytes())
+}
+
+func computeQueueSlot(seq uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte(computeQueueDomain), binary.BigEndian.AppendUint64(nil, seq))
+}
+
+func setJobStatus(db contract.StateDB, record common.Hash, inputs int, ctType uint8, status JobStatus) {
+	db.SetState(ContractAddress, ciphertextDataSlot(record, computeWordStatus), common.Hash{29: byte(inputs), 30: ctType, 31: byte(status)})
+}
+
+// computeResultType returns the result type of op over inputs of the given
+// types, matching the inline evaluation
+func computeResultType(op string, types []uint8) (uint8, bool) {
+	switch op {
+	case "add", "sub", "mul", "div", "rem", "and", "or", "xor", "min", "max":
+		return types[0], len(types) == 2
+	case "lt", "gt", "eq", "ne", "le", "ge":
+		return TypeEbool, len(types) == 2
+	case "not", "neg":
+		return types[0], len(types) == 1
+	case "select":
+		if len(types) != 3 {
+			return 0, false
+		}
+		return types[1], true
+	default:
+		return 0, false
+	}
+}
+
+// computeJobHandle derives a job's result handle from its operation, inputs,
+// caller and sequence number
+func computeJobHandle(job *ComputeJob) common.Hash {
+	data := []byte(computeHandleDomain)
+	data = append(data, byte(len(job.Op)))
+	data = append(data, job.Op...)
+	for _, in := range job.Inputs {
+		data = append(data, in.Bytes()...)
+	}
+	data = append(data, job.Caller.Bytes()...)
+	data = binary.BigEndian.AppendUint64(data, job.Seq)
+	return crypto.Keccak256Hash(data)
+}
+
+// ResultDigest returns the digest attestors sign to approve ciphertext as
+// the result of the job with the given handle
+func ResultDigest(handle common.Hash, ciphertext []byte) common.Hash {
+	return crypto.Keccak256Hash(
+		[]byte(computeResultDomain),
+		handle.Bytes(),
+		crypto.Keccak256(ciphertext),
+	)
+}
+
+// Job returns the job with the given result handle
+func (cp *Coprocessor) Job(db contract.StateDB, handle common.Hash) (*ComputeJob, bool) {
+	record := computeRecordSlot(handle)
+	status := db.GetState(ContractAddress, ciphertextDataSlot(record, computeWordStatus))
+	if JobStatus(status[31]) == JobUnknown {
+		return nil, false
+	}
+	op := db.GetState(ContractAddress, ciphertextDataSlot(record, computeWordOp))
+	meta := db.GetState(ContractAddress, ciphertextDataSlot(record, computeWordCaller))
+	job := &ComputeJob{
+		Handle:     handle,
+		Op:         string(bytes.TrimRight(op[:], "\x00")),
+		Inputs:     make([]common.Hash, status[29]),
+		ResultType: status[30],
+		Caller:     common.BytesToAddress(meta[:20]),
+		Seq:        binary.BigEndian.Uint64(meta[24:]),
+		Status:     JobStatus(status[31]),
+	}
+	for i := range job.Inputs {
+		job.Inputs[i] = db.GetState(ContractAddress, ciphertextDataSlot(record, computeWordInputs+i))
+	}
+	return job, true
+}
+
+// Status returns the status of the job with the given result handle
+func (cp *Coprocessor) Status(db contract.StateDB, handle common.Hash) JobStatus {
+	status := db.GetState(ContractAddress, ciphertextDataSlot(computeRecordSlot(handle), computeWordStatus))
+	return JobStatus(status[31])
+}
+
+// Pending returns up to limit pending jobs in enqueue order. A non-positive
+// limit returns every pending job.
+func (cp *Coprocessor) Pending(db contract.StateDB, limit int) []*ComputeJob {
+	var jobs []*ComputeJob
+	seq := getCounter(db, counterSlot(computeSeqDomain))
+	for s := getCounter(db, counterSlot(computeHeadDomain)); s < seq; s++ {
+		if limit > 0 && len(jobs) == limit {
+			break
+		}
+		job, ok := cp.Job(db, db.GetState(ContractAddress, computeQueueSlot(s)))
+		if ok && job.Status == JobPending {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// PostResult finalizes a pending job in db with the coprocessor's result
+// ciphertext. Signatures are 65-byte [R || S || V] signatures over
+// ResultDigest; at least threshold distinct attestors must have signed. The
+// result is written to store.
+func (cp *Coprocessor) PostResult(db contract.StateDB, store CiphertextBackend, handle common.Hash, ciphertext []byte, signatures [][]byte) error {
+	if len(ciphertext) == 0 {
+		return ErrInvalidCiphertext
+	}
+	if db == nil {
+		return ErrCoprocessorNoState
+	}
+
+	job, ok := cp.Job(db, handle)
+	switch {
+	case !ok:
+		return ErrJobNotFound
+	case job.Status == JobFinalized:
+		return ErrJobFinalized
+	}
+	for _, in := range job.Inputs {
+		if !store.Has(in) {
+			return ErrJobInputsPending
+		}
+	}
+
+	digest := ResultDigest(handle, ciphertext)
+	if countAttestors(cp.attestors, digest, signatures) < cp.threshold {
+		return ErrAttestation
+	}
+
+	store.Put(handle, ciphertext, job.ResultType)
+	setJobStatus(db, computeRecordSlot(handle), len(job.Inputs), job.ResultType, JobFinalized)
+	cp.advanceQueue(db)
+	return nil
+}
+
+// advanceQueue moves the queue head past finalized jobs, a bounded number
+// at a time
+func (cp *Coprocessor) advanceQueue(db contract.StateDB) {
+	headSlot := counterSlot(computeHeadDomain)
+	head, seq := getCounter(db, headSlot), getCounter(db, counterSlot(computeSeqDomain))
+	start := head
+	for head < seq && head-start < maxQueueAdvance {
+		if cp.Status(db, db.GetState(ContractAddress, computeQueueSlot(head))) != JobFinalized {
+			break
+		}
+		head++
+	}
+	if head != start {
+		setCounter(db, headSlot, head)
+	}
+}
+
+// countAttestors returns the number of distinct attestors with a valid
+// signature over digest among signatures
+func countAttestors(attestors map[common.Address]bool, digest common.Hash, signatures [][]byte) int {
+	signed := make(map[common.Address]bool, len(signatures))
+	for _, sig := range signatures {
+		if len(sig) != 65 {
+			continue
+		}
+		normalized := append([]byte(nil), sig...)
+		if normalized[64] >= 27 {
+			normalized[64] -= 27
+		}
+		pub, err := crypto.Ecrecover(digest.Bytes(), normalized)
+		if err != nil || len(pub) != 65 {
+			continue
+		}
+		if signer := common.BytesToAddress(crypto.Keccak256(pub[1:])[12:]); attestors[signer] {
+			signed[signer] = true
+		}
+	}
+	return len(signed)
+}
+
+// === Coprocessor Handlers ===
+
+// handlePostComputeResult finalizes a compute job. Input is packed as
+// handle (32) || signature count (1) || signatures (65 each) || ciphertext.
+func (c *FHEContract) handlePostComputeResult(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 33 {
+		return nil, gas, ErrInvalidInput
+	}
+	count := int(data[32])
+	sigEnd := 33 + count*65
+	if len(data) <= sigEnd {
+		return nil, gas, ErrInvalidInput
+	}
+	required := GasPostComputeResult + GasPerAttestation*uint64(count)
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+	if coprocessor == nil {
+		return nil, gas - required, ErrNotImplemented
+	}
+
+	handle := common.BytesToHash(data[:32])
+	signatures := make([][]byte, count)
+	for i := range signatures {
+		signatures[i] = data[33+i*65 : 33+(i+1)*65]
+	}
+	ciphertext := append([]byte(nil), data[sigEnd:]...)
+
+	if err := coprocessor.PostResult(stateDBFor(state), ciphertextStoreFor(state), handle, ciphertext, signatures); err != nil {
+		return nil, gas - required, err
+	}
+	return handle.Bytes(), gas - required, nil
+}
+
+// handleComputeStatus returns the JobStatus of a result handle as a uint256
+func (c *FHEContract) handleComputeStatus(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasComputeStatus {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	status := JobUnknown
+	if db := stateDBFor(state); coprocessor != nil && db != nil {
+		status = coprocessor.Status(db, common.BytesToHash(data[:32]))
+	}
+
+	ret := make([]byte, 32)
+	ret[31] = byte(status)
+	return ret, gas - GasComputeStatus, nil
+}
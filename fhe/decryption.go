@@ -0,0 +1,420 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Asynchronous decryption.
+//
+// With a decryption oracle configured, the precompile holds no usable
+// decryption key: plaintexts come from the Z-Chain threshold committee.
+// A contract calls requestDecryption with a handle and the selector of its
+// callback and receives a request ID straight away. The committee decrypts
+// pending requests off chain and posts each plaintext with signatures from
+// a threshold of its members over (request ID, plaintext). FulfillDecryption
+// checks the signatures, and the fulfillDecryption call then delivers
+//
+//	callback(bytes32 requestId, uint256 plaintext)
+//
+// to the requesting contract through the EVM, with the gas the fulfiller
+// left over. A failed callback does not undo the fulfillment. The
+// synchronous decrypt method is disabled while an oracle is active.
+//
+// Requests live in the storage of the FHE precompile account, so every
+// node sees the same requests and a reverted transaction leaves none
+// behind. Each request takes four slots after
+//
+//	record = keccak256(requestDomain || id)
+//
+// holding the handle, requester || callback || sequence number, type ||
+// status, and the plaintext once fulfilled. The sequence counter and the
+// oldest possibly pending sequence number have slots of their own, and the
+// queue maps each sequence number to its request ID.
+
+// Decryption gas costs
+const (
+	GasFulfillDecryption uint64 = 50000
+	GasDecryptionStatus  uint64 = 2600
+)
+
+// Domain separators for decryption hashing and storage
+const (
+	decryptionRequestDomain = "lux.fhe.decryption.request.v1"
+	decryptionResultDomain  = "lux.fhe.decryption.result.v1"
+	decryptionRecordDomain  = "lux.fhe.decryption.record.v1"
+	decryptionQueueDomain   = "lux.fhe.decryption.queue.v1"
+	decryptionSeqDomain     = "lux.fhe.decryption.seq.v1"
+	decryptionHeadDomain    = "lux.fhe.decryption.head.v1"
+)
+
+// maxQueueAdvance bounds how many fulfilled requests one fulfillment moves
+// the queue head past
+const maxQueueAdvance = 16
+
+// Request record word offsets
+const (
+	decryptionWordHandle = iota
+	decryptionWordRequester
+	decryptionWordStatus
+	decryptionWordPlaintext
+)
+
+var (
+	ErrRequestNotFound    = errors.New("decryption request not found")
+	ErrRequestFulfilled   = errors.New("decryption request already fulfilled")
+	ErrSyncDecryption     = errors.New("synchronous decryption disabled, use requestDecryption")
+	ErrInvalidPlaintext   = errors.New("invalid decryption plaintext")
+	ErrCallbackDelivery   = errors.New("decryption callback failed")
+	ErrNoDecryptionOracle = errors.New("no decryption oracle configured")
+	ErrDecryptionNoState  = errors.New("asynchronous decryption requires state")
+)
+
+// DecryptionStatus is the lifecycle state of a decryption request
+type DecryptionStatus uint8
+
+const (
+	DecryptionUnknown   DecryptionStatus = iota // No request with the ID
+	DecryptionPending                           // Waiting for the committee
+	DecryptionFulfilled                         // Plaintext delivered
+)
+
+// DecryptionRequest is a pending or fulfilled request for a plaintext
+type DecryptionRequest struct {
+	ID        common.Hash
+	Handle    common.Hash
+	CtType    uint8
+	Requester common.Address // Receives the callback
+	Callback  [4]byte        // Callback selector
+	Seq       uint64
+	Status    DecryptionStatus
+	Plaintext *big.Int // Set once fulfilled
+}
+
+// DecryptionOracle is the committee allowed to fulfill decryption requests
+type DecryptionOracle struct {
+	attestors map[common.Address]bool
+	threshold int
+}
+
+// decryptionOracle is the active oracle; nil means decrypt runs inline
+var decryptionOracle *DecryptionOracle
+
+// SetDecryptionOracle enables asynchronous decryption, or disables it when
+// o is nil
+func SetDecryptionOracle(o *DecryptionOracle) {
+	decryptionOracle = o
+}
+
+// ActiveDecryptionOracle returns the active oracle, or nil
+func ActiveDecryptionOracle() *DecryptionOracle {
+	return decryptionOracle
+}
+
+// NewDecryptionOracle creates an oracle whose plaintexts must be signed by
+// at least threshold of the given committee members
+func NewDecryptionOracle(attestors []common.Address, threshold int) (*DecryptionOracle, error) {
+	if err := verifyAttestors(attestors, threshold); err != nil {
+		return nil, err
+	}
+
+	set := make(map[common.Address]bool, len(attestors))
+	for _, a := range attestors {
+		set[a] = true
+	}
+	return &DecryptionOracle{attestors: set, threshold: threshold}, nil
+}
+
+// Enqueue records a request to decrypt handle for requester and returns its
+// ID. The handle may still be pending in the coprocessor.
+func (o *DecryptionOracle) Enqueue(db contract.StateDB, store CiphertextBackend, handle common.Hash, requester common.Address, callback [4]byte) (common.Hash, error) {
+	_, ctType, ok := store.Get(handle)
+	if !ok && coprocessor != nil {
+		var job *ComputeJob
+		if job, ok = coprocessor.Job(db, handle); ok {
+			ctType = job.ResultType
+		}
+	}
+	if !ok {
+		return common.Hash{}, handleNotFound("requestDecryption", handle)
+	}
+
+	seq := getCounter(db, counterSlot(decryptionSeqDomain))
+	req := &DecryptionRequest{
+		Handle:    handle,
+		CtType:    ctType,
+		Requester: requester,
+		Callback:  callback,
+		Seq:       seq,
+		Status:    DecryptionPending,
+	}
+	req.ID = decryptionRequestID(req)
+
+	record := decryptionRecordSlot(req.ID)
+	db.SetState(ContractAddress, ciphertextDataSlot(record, decryptionWordHandle), handle)
+	var meta common.Hash
+	copy(meta[:20], requester.Bytes())
+	copy(meta[20:24], callback[:])
+	binary.BigEndian.PutUint64(meta[24:], seq)
+	db.SetState(ContractAddress, ciphertextDataSlot(record, decryptionWordRequester), meta)
+	setDecryptionStatus(db, record, ctType, DecryptionPending)
+	db.SetState(ContractAddress, decryptionQueueSlot(seq), req.ID)
+	setCounter(db, counterSlot(decryptionSeqDomain), seq+1)
+	return req.ID, nil
+}
+
+// decryptionRequestID derives a request ID from its handle, requester,
+// callback and sequence number
+func decryptionRequestID(req *DecryptionRequest) common.Hash {
+	data := []byte(decryptionRequestDomain)
+	data = append(data, req.Handle.Bytes()...)
+	data = append(data, req.Requester.Bytes()...)
+	data = append(data, req.Callback[:]...)
+	data = binary.BigEndian.AppendUint64(data, req.Seq)
+	return crypto.Keccak256Hash(data)
+}
+
+func decryptionRecordSlot(id common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte(decryptionRecordDomain), id.Bytes())
+}
+
+func decryptionQueueSlot(seq uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte(decryptionQueueDomain), binary.BigEndian.AppendUint64(nil, seq))
+}
+
+func counterSlot(domain string) common.Hash {
+	return crypto.Keccak256Hash([]byte(domain))
+}
+
+func getCounter(db contract.StateDB, slot common.Hash) uint64 {
+	return binary.BigEndian.Uint64(db.GetState(ContractAddress, slot).Bytes()[24:])
+}
+
+func setCounter(db contract.StateDB, slot common.Hash, n uint64) {
+	db.SetState(ContractAddress, slot, common.BytesToHash(binary.BigEndian.AppendUint64(nil, n)))
+}
+
+func setDecryptionStatus(db contract.StateDB, record common.Hash, ctType uint8, status DecryptionStatus) {
+	db.SetState(ContractAddress, ciphertextDataSlot(record, decryptionWordStatus), common.Hash{30: ctType, 31: byte(status)})
+}
+
+// DecryptionDigest returns the digest committee members sign to approve
+// plaintext as the answer to request id
+func DecryptionDigest(id common.Hash, plaintext *big.Int) common.Hash {
+	return crypto.Keccak256Hash(
+		[]byte(decryptionResultDomain),
+		id.Bytes(),
+		common.BigToHash(plaintext).Bytes(),
+	)
+}
+
+// Request returns the request with the given ID
+func (o *DecryptionOracle) Request(db contract.StateDB, id common.Hash) (*DecryptionRequest, bool) {
+	record := decryptionRecordSlot(id)
+	status := db.GetState(ContractAddress, ciphertextDataSlot(record, decryptionWordStatus))
+	if DecryptionStatus(status[31]) == DecryptionUnknown {
+		return nil, false
+	}
+	meta := db.GetState(ContractAddress, ciphertextDataSlot(record, decryptionWordRequester))
+	req := &DecryptionRequest{
+		ID:        id,
+		Handle:    db.GetState(ContractAddress, ciphertextDataSlot(record, decryptionWordHandle)),
+		CtType:    status[30],
+		Requester: common.BytesToAddress(meta[:20]),
+		Callback:  [4]byte(meta[20:24]),
+		Seq:       binary.BigEndian.Uint64(meta[24:]),
+		Status:    DecryptionStatus(status[31]),
+	}
+	if req.Status == DecryptionFulfilled {
+		req.Plaintext = db.GetState(ContractAddress, ciphertextDataSlot(record, decryptionWordPlaintext)).Big()
+	}
+	return req, true
+}
+
+// Status returns the status of the request with the given ID
+func (o *DecryptionOracle) Status(db contract.StateDB, id common.Hash) DecryptionStatus {
+	status := db.GetState(ContractAddress, ciphertextDataSlot(decryptionRecordSlot(id), decryptionWordStatus))
+	return DecryptionStatus(status[31])
+}
+
+// Pending returns up to limit pending requests in request order. A
+// non-positive limit returns every pending request.
+func (o *DecryptionOracle) Pending(db contract.StateDB, limit int) []*DecryptionRequest {
+	var reqs []*DecryptionRequest
+	seq := getCounter(db, counterSlot(decryptionSeqDomain))
+	for s := getCounter(db, counterSlot(decryptionHeadDomain)); s < seq; s++ {
+		if limit > 0 && len(reqs) == limit {
+			break
+		}
+		req, ok := o.Request(db, db.GetState(ContractAddress, decryptionQueueSlot(s)))
+		if ok && req.Status == DecryptionPending {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+// FulfillDecryption completes a pending request with the committee's
+// plaintext. Signatures are 65-byte [R || S || V] signatures over
+// DecryptionDigest; at least threshold distinct members must have signed.
+// It returns the request and the callback input; the caller delivers it.
+// Requests without a callback selector are not delivered.
+func (o *DecryptionOracle) FulfillDecryption(db contract.StateDB, id common.Hash, plaintext *big.Int, signatures [][]byte) (*DecryptionRequest, []byte, error) {
+	if plaintext == nil || plaintext.Sign() < 0 || plaintext.BitLen() > 256 {
+		return nil, nil, ErrInvalidPlaintext
+	}
+
+	req, ok := o.Request(db, id)
+	switch {
+	case !ok:
+		return nil, nil, ErrRequestNotFound
+	case req.Status == DecryptionFulfilled:
+		return nil, nil, ErrRequestFulfilled
+	}
+	if countAttestors(o.attestors, DecryptionDigest(id, plaintext), signatures) < o.threshold {
+		return nil, nil, ErrAttestation
+	}
+
+	record := decryptionRecordSlot(id)
+	req.Status = DecryptionFulfilled
+	req.Plaintext = new(big.Int).Set(plaintext)
+	setDecryptionStatus(db, record, req.CtType, DecryptionFulfilled)
+	db.SetState(ContractAddress, ciphertextDataSlot(record, decryptionWordPlaintext), common.BigToHash(plaintext))
+	o.advanceQueue(db)
+
+	input := make([]byte, 68)
+	copy(input[:4], req.Callback[:])
+	copy(input[4:36], id.Bytes())
+	plaintext.FillBytes(input[36:68])
+	return req, input, nil
+}
+
+// advanceQueue moves the queue head past fulfilled requests, a bounded
+// number at a time
+func (o *DecryptionOracle) advanceQueue(db contract.StateDB) {
+	headSlot := counterSlot(decryptionHeadDomain)
+	head, seq := getCounter(db, headSlot), getCounter(db, counterSlot(decryptionSeqDomain))
+	start := head
+	for head < seq && head-start < maxQueueAdvance {
+		if o.Status(db, db.GetState(ContractAddress, decryptionQueueSlot(head))) != DecryptionFulfilled {
+			break
+		}
+		head++
+	}
+	if head != start {
+		setCounter(db, headSlot, head)
+	}
+}
+
+// deliverDecryption calls the requester back with up to gas and returns the
+// gas the callback left
+func deliverDecryption(state contract.AccessibleState, target common.Address, input []byte, gas uint64) (uint64, error) {
+	env, ok := state.GetPrecompileEnv().(contract.CallerEnvironment)
+	if !ok {
+		return gas, fmt.Errorf("%w: host cannot call contracts", ErrCallbackDelivery)
+	}
+	_, left, err := env.Call(target, input, gas, nil)
+	if err != nil {
+		return left, errors.Join(ErrCallbackDelivery, err)
+	}
+	return left, nil
+}
+
+// === Decryption Handlers ===
+
+// handleRequestDecryption queues a decryption of a handle. Input is packed
+// as handle (32) || callback selector, left-aligned in a 32-byte word.
+func (c *FHEContract) handleRequestDecryption(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasDecryptRequest {
+		return nil, gas, ErrInsufficientGas
+	}
+	if decryptionOracle == nil {
+		return nil, gas - GasDecryptRequest, ErrNoDecryptionOracle
+	}
+	db := stateDBFor(state)
+	if db == nil {
+		return nil, gas - GasDecryptRequest, ErrDecryptionNoState
+	}
+
+	handle := common.BytesToHash(data[:32])
+	id, err := decryptionOracle.Enqueue(db, ciphertextStoreFor(state), handle, caller, [4]byte(data[32:36]))
+	if err != nil {
+		return nil, gas - GasDecryptRequest, err
+	}
+	return id.Bytes(), gas - GasDecryptRequest, nil
+}
+
+// handleFulfillDecryption completes a decryption request and calls the
+// requester back with the gas left. Input is packed as request ID (32) ||
+// plaintext (32) || signature count (1) || signatures (65 each).
+func (c *FHEContract) handleFulfillDecryption(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 65 {
+		return nil, gas, ErrInvalidInput
+	}
+	count := int(data[64])
+	if len(data) < 65+count*65 {
+		return nil, gas, ErrInvalidInput
+	}
+	required := GasFulfillDecryption + GasPerAttestation*uint64(count)
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+	if decryptionOracle == nil {
+		return nil, gas - required, ErrNoDecryptionOracle
+	}
+	db := stateDBFor(state)
+	if db == nil {
+		return nil, gas - required, ErrDecryptionNoState
+	}
+
+	id := common.BytesToHash(data[:32])
+	plaintext := new(big.Int).SetBytes(data[32:64])
+	signatures := make([][]byte, count)
+	for i := range signatures {
+		signatures[i] = data[65+i*65 : 65+(i+1)*65]
+	}
+
+	req, input, err := decryptionOracle.FulfillDecryption(db, id, plaintext, signatures)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	gas -= required
+
+	// A failed callback does not undo the fulfillment
+	if req.Callback != ([4]byte{}) {
+		gas, _ = deliverDecryption(state, req.Requester, input, gas)
+	}
+	return input, gas, nil
+}
+
+// handleDecryptionStatus returns the DecryptionStatus of a request ID as a
+// uint256
+func (c *FHEContract) handleDecryptionStatus(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasDecryptionStatus {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	status := DecryptionUnknown
+	if db := stateDBFor(state); decryptionOracle != nil && db != nil {
+		status = decryptionOracle.Status(db, common.BytesToHash(data[:32]))
+	}
+
+	ret := make([]byte, 32)
+	ret[31] = byte(status)
+	return ret, gas - GasDecryptionStatus, nil
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+)
+
+// Structured operation errors.
+//
+// A failed call returns ABI-encoded revert data alongside its error, so a
+// contract can tell a missing handle from a type mismatch or a failed
+// evaluation instead of receiving a zero handle. Operation errors are the
+// custom errors
+//
+//	HandleNotFound(bytes32 handle)
+//	TypeMismatch(bytes32 handle, uint8 expected, uint8 actual)
+//	OperationFailed(string op, string reason)
+//
+// and any other error is the standard Error(string). The host returns the
+// data as the revert data of the call.
+
+// Revert data selectors
+var (
+	handleNotFoundSelector  = errorSelector("HandleNotFound(bytes32)")
+	typeMismatchSelector    = errorSelector("TypeMismatch(bytes32,uint8,uint8)")
+	operationFailedSelector = errorSelector("OperationFailed(string,string)")
+	errorStringSelector     = errorSelector("Error(string)")
+)
+
+func errorSelector(signature string) []byte {
+	return crypto.Keccak256([]byte(signature))[:4]
+}
+
+// OpError is a failed operation on ciphertext handles. Err is
+// ErrInvalidCiphertext for a missing handle, ErrTypeMismatch for an operand
+// of the wrong type, or the cause of a failed evaluation.
+type OpError struct {
+	Op       string
+	Handle   common.Hash // Offending handle, unless the evaluation failed
+	Expected uint8       // ErrTypeMismatch only
+	Actual   uint8       // ErrTypeMismatch only
+	Err      error
+}
+
+func (e *OpError) Error() string {
+	switch {
+	case errors.Is(e.Err, ErrInvalidCiphertext):
+		return fmt.Sprintf("%s: %v %s", e.Op, e.Err, e.Handle.Hex())
+	case errors.Is(e.Err, ErrTypeMismatch):
+		return fmt.Sprintf("%s: %v: %s has type %d, expected %d", e.Op, e.Err, e.Handle.Hex(), e.Actual, e.Expected)
+	default:
+		return fmt.Sprintf("%s: %v", e.Op, e.Err)
+	}
+}
+
+func (e *OpError) Unwrap() error { return e.Err }
+
+// handleNotFound reports a handle with no stored or pending ciphertext
+func handleNotFound(op string, handle common.Hash) error {
+	return &OpError{Op: op, Handle: handle, Err: ErrInvalidCiphertext}
+}
+
+// typeMismatch reports an operand of the wrong ciphertext type
+func typeMismatch(op string, handle common.Hash, expected, actual uint8) error {
+	return &OpError{Op: op, Handle: handle, Expected: expected, Actual: actual, Err: ErrTypeMismatch}
+}
+
+// opFailed reports a failed evaluation. The tfhe* functions signal failure
+// with a nil result; a backend that failed to initialize is the cause
+// reported, any other failure is ErrOperationFailed.
+func opFailed(op string) error {
+	err := ErrOperationFailed
+	if initErr := initTFHE(); initErr != nil {
+		err = fmt.Errorf("%w: %w", ErrOperationFailed, initErr)
+	}
+	return &OpError{Op: op, Err: err}
+}
+
+// RevertData ABI-encodes err as the revert data of a failed call
+func RevertData(err error) []byte {
+	var opErr *OpError
+	if !errors.As(err, &opErr) {
+		return append(append([]byte(nil), errorStringSelector...), abiStrings(err.Error())...)
+	}
+
+	switch {
+	case errors.Is(opErr.Err, ErrInvalidCiphertext):
+		return append(append([]byte(nil), handleNotFoundSelector...), opErr.Handle.Bytes()...)
+	case errors.Is(opErr.Err, ErrTypeMismatch):
+		out := append(append([]byte(nil), typeMismatchSelector...), opErr.Handle.Bytes()...)
+		out = append(out, common.Hash{31: opErr.Expected}.Bytes()...)
+		return append(out, common.Hash{31: opErr.Actual}.Bytes()...)
+	default:
+		return append(append([]byte(nil), operationFailedSelector...), abiStrings(opErr.Op, opErr.Err.Error())...)
+	}
+}
+
+// abiStrings ABI-encodes strings as the arguments of a call
+func abiStrings(values ...string) []byte {
+	head := make([]byte, 0, 32*len(values))
+	var tail []byte
+	for _, v := range values {
+		head = append(head, abiWord(uint64(32*len(values)+len(tail)))...)
+		tail = append(tail, abiWord(uint64(len(v)))...)
+		tail = append(tail, v...)
+		if pad := len(v) % 32; pad != 0 {
+			tail = append(tail, make([]byte, 32-pad)...)
+		}
+	}
+	return append(head, tail...)
+}
+
+func abiWord(v uint64) []byte {
+	word := make([]byte, 32)
+	binary.BigEndian.PutUint64(word[24:], v)
+	return word
+}
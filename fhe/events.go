@@ -0,0 +1,87 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	ethtypes "github.com/luxfi/geth/core/types"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Operation logs.
+//
+// Every successful call that produces ciphertext handles emits
+//
+//	FHEOperation(address indexed caller, bytes4 indexed op, bytes32 indexed result, bytes32[] inputs, uint256 gasUsed)
+//
+// from the precompile address, one log per result handle. op is the
+// selector of the single-operation method, so a batch emits one log per
+// operation in the list, with results of earlier operations resolved to
+// their handles. Indexers and coprocessors rebuild the computation graph by
+// joining each log's inputs to earlier results, without tracing calls. In
+// coprocessor mode the result is the pending job handle. Logs are part of
+// the call's state: static calls and reverted calls leave none, and they are
+// not charged for.
+
+// FHEOperationTopic is the topic of the FHEOperation log
+var FHEOperationTopic = common.BytesToHash(crypto.Keccak256([]byte("FHEOperation(address,bytes4,bytes32,bytes32[],uint256)")))
+
+// operationLog builds the FHEOperation log of one result
+func operationLog(caller common.Address, op [4]byte, result common.Hash, inputs []common.Hash, gasUsed uint64) *ethtypes.Log {
+	var opTopic common.Hash
+	copy(opTopic[:], op[:])
+
+	data := abiWord(64)
+	data = append(data, abiWord(gasUsed)...)
+	data = append(data, encodeHandleArray(inputs)[32:]...)
+	return &ethtypes.Log{
+		Address: ContractAddress,
+		Topics:  []common.Hash{FHEOperationTopic, common.BytesToHash(caller.Bytes()), opTopic, result},
+		Data:    data,
+	}
+}
+
+// emitOperationLogs logs the results of a successful call to m. Calls
+// without a StateDB and static calls emit nothing.
+func emitOperationLogs(state contract.AccessibleState, m *Method, caller common.Address, data, ret []byte, gasUsed uint64, readOnly bool) {
+	db := stateDBFor(state)
+	if db == nil || readOnly {
+		return
+	}
+	results := resultHandleCount(m)
+	if results == 0 {
+		return
+	}
+	inputs := inputHandles(m, data)
+	for i := 0; i < results && len(ret) >= (i+1)*32; i++ {
+		if h := common.BytesToHash(ret[i*32 : (i+1)*32]); h != (common.Hash{}) {
+			db.AddLog(operationLog(caller, m.Selector, h, inputs, gasUsed))
+		}
+	}
+}
+
+// emitBatchLogs logs each operation of a successful batch, charging it the
+// gas of its single-operation method
+func emitBatchLogs(state contract.AccessibleState, caller common.Address, ops []ParallelOp, results []common.Hash, readOnly bool) {
+	db := stateDBFor(state)
+	if db == nil || readOnly {
+		return
+	}
+	for i, op := range ops {
+		m, ok := methodByName(op.Op)
+		if !ok {
+			continue
+		}
+		inputs := make([]common.Hash, len(op.Inputs))
+		for j, in := range op.Inputs {
+			if in.IsResult {
+				inputs[j] = results[in.Result]
+			} else {
+				inputs[j] = in.Handle
+			}
+		}
+		db.AddLog(operationLog(caller, m.Selector, results[i], inputs, batchGas[op.Op]))
+	}
+}
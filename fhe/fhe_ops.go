@@ -7,24 +7,109 @@ package fhe
 
 import (
 	"crypto/rand"
+	"encoding"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/big"
 	"sync"
 
+	"github.com/luxfi/crypto"
 	"github.com/luxfi/fhe"
+	"github.com/luxfi/geth/common"
 )
 
 var (
-	// Singleton TFHE components
-	tfheOnce  sync.Once
-	evaluator *fhe.BitwiseEvaluator
-	encryptor *fhe.BitwiseEncryptor
-	decryptor *fhe.BitwiseDecryptor
+	// Singleton TFHE parameters
+	tfheOnce sync.Once
+	params   fhe.Parameters
+	initErr  error
+
+	// Key sets by network key epoch, and the one operations run under
+	keysMu     sync.RWMutex
+	keySets    map[uint64]*tfheKeySet
+	activeKeys *tfheKeySet
+)
+
+var (
+	ErrKeyNotLoaded = errors.New("network key of the epoch not loaded")
+	ErrInvalidKeys  = errors.New("invalid network key set")
+)
+
+// tfheKeySet is the evaluator's key material for one network key epoch
+type tfheKeySet struct {
+	epoch     uint64
 	secretKey *fhe.SecretKey
 	publicKey *fhe.PublicKey
-	params    fhe.Parameters
-	initErr   error
-)
+	decryptor *fhe.BitwiseDecryptor
+
+	// Context used by serial execution, and the constructor of fresh
+	// contexts for parallel workers
+	shared     *tfheContext
+	newContext func() *tfheContext
+}
+
+// newKeySet builds the operators of a key set
+func newKeySet(epoch uint64, sk *fhe.SecretKey, pk *fhe.PublicKey, bsk *fhe.BootstrapKey) *tfheKeySet {
+	ks := &tfheKeySet{
+		epoch:     epoch,
+		secretKey: sk,
+		publicKey: pk,
+		decryptor: fhe.NewBitwiseDecryptor(params, sk),
+		newContext: func() *tfheContext {
+			return &tfheContext{
+				ev:  fhe.NewBitwiseEvaluator(params, bsk, sk),
+				enc: fhe.NewBitwiseEncryptor(params, sk),
+			}
+		},
+	}
+	ks.shared = ks.newContext()
+	return ks
+}
+
+// tfheKeys returns the key set operations run under. TFHE must be
+// initialized.
+func tfheKeys() *tfheKeySet {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	return activeKeys
+}
+
+// currentKeyEpoch returns the epoch of the key set operations run under
+func currentKeyEpoch() uint64 {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	if activeKeys == nil {
+		return 0
+	}
+	return activeKeys.epoch
+}
+
+// tfheContext is the evaluator and encryptor one goroutine works with. The
+// bitwise evaluator and encryptor keep scratch state, so goroutines that
+// evaluate concurrently each need their own context.
+type tfheContext struct {
+	ev  *fhe.BitwiseEvaluator
+	enc *fhe.BitwiseEncryptor
+}
+
+// defaultTFHE returns the context of serial execution, or nil if TFHE failed
+// to initialize
+func defaultTFHE() *tfheContext {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+	return tfheKeys().shared
+}
+
+// newTFHEContext returns a context that shares the network keys but no
+// evaluation state with any other, or nil if TFHE failed to initialize
+func newTFHEContext() *tfheContext {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+	return tfheKeys().newContext()
+}
 
 // Initialize TFHE components
 func initTFHE() error {
@@ -38,20 +123,139 @@ func initTFHE() error {
 			return
 		}
 
-		// Generate keys
+		// Generate the keys of epoch 0, until a network key is loaded
 		kg := fhe.NewKeyGenerator(params)
-		secretKey, publicKey = kg.GenKeyPair()
-		bsk := kg.GenBootstrapKey(secretKey)
+		sk, pk := kg.GenKeyPair()
+		ks := newKeySet(0, sk, pk, kg.GenBootstrapKey(sk))
 
-		// Create operators
-		encryptor = fhe.NewBitwiseEncryptor(params, secretKey)
-		decryptor = fhe.NewBitwiseDecryptor(params, secretKey)
-		evaluator = fhe.NewBitwiseEvaluator(params, bsk, secretKey)
+		keysMu.Lock()
+		defer keysMu.Unlock()
+		activeKeys = ks
+		keySets = map[uint64]*tfheKeySet{0: ks}
 	})
 
 	return initErr
 }
 
+// GenerateNetworkKeys generates a fresh network key set in the format
+// LoadNetworkKeys reads: the secret, public and bootstrap keys, each with
+// a 4-byte big-endian length prefix
+func GenerateNetworkKeys() ([]byte, error) {
+	if err := initTFHE(); err != nil {
+		return nil, err
+	}
+
+	kg := fhe.NewKeyGenerator(params)
+	sk, pk := kg.GenKeyPair()
+	var out []byte
+	for _, key := range []encoding.BinaryMarshaler{sk, pk, kg.GenBootstrapKey(sk)} {
+		data, err := key.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		out = binary.BigEndian.AppendUint32(out, uint32(len(data)))
+		out = append(out, data...)
+	}
+	return out, nil
+}
+
+// LoadNetworkKeys installs the key set of a network key epoch, replacing
+// any loaded before. It returns the hash of the public key, which the key
+// registry records for the epoch.
+func LoadNetworkKeys(epoch uint64, data []byte) (common.Hash, error) {
+	if err := initTFHE(); err != nil {
+		return common.Hash{}, err
+	}
+
+	parts := make([][]byte, 3)
+	for i := range parts {
+		if len(data) < 4 || uint64(len(data)-4) < uint64(binary.BigEndian.Uint32(data)) {
+			return common.Hash{}, ErrInvalidKeys
+		}
+		n := binary.BigEndian.Uint32(data)
+		parts[i], data = data[4:4+n], data[4+n:]
+	}
+	if len(data) != 0 {
+		return common.Hash{}, ErrInvalidKeys
+	}
+	sk, pk, bsk := new(fhe.SecretKey), new(fhe.PublicKey), new(fhe.BootstrapKey)
+	if err := sk.UnmarshalBinary(parts[0]); err != nil {
+		return common.Hash{}, fmt.Errorf("%w: secret key: %w", ErrInvalidKeys, err)
+	}
+	if err := pk.UnmarshalBinary(parts[1]); err != nil {
+		return common.Hash{}, fmt.Errorf("%w: public key: %w", ErrInvalidKeys, err)
+	}
+	if err := bsk.UnmarshalBinary(parts[2]); err != nil {
+		return common.Hash{}, fmt.Errorf("%w: bootstrap key: %w", ErrInvalidKeys, err)
+	}
+
+	ks := newKeySet(epoch, sk, pk, bsk)
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keySets[epoch] = ks
+	if activeKeys.epoch == epoch {
+		activeKeys = ks
+	}
+	return crypto.Keccak256Hash(parts[1]), nil
+}
+
+// useKeyEpoch makes the key set of epoch the one operations run under.
+// The evaluator serves one epoch at a time. Before TFHE is initialized it
+// serves epoch 0, whose keys initialization generates.
+func useKeyEpoch(epoch uint64) error {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if activeKeys == nil && epoch == 0 || activeKeys != nil && activeKeys.epoch == epoch {
+		return nil
+	}
+	ks := keySets[epoch]
+	if ks == nil {
+		return fmt.Errorf("%w: epoch %d", ErrKeyNotLoaded, epoch)
+	}
+	activeKeys = ks
+	return nil
+}
+
+// tfheSwitchKey re-encrypts a ciphertext of epoch from under the key of
+// epoch to. The evaluator holds the secret key of every epoch it serves,
+// so the switch decrypts 64 bits at a time under the old key and encrypts
+// them under the new one; the plaintext never leaves the evaluator. It
+// returns nil if either key set is not loaded.
+func tfheSwitchKey(ct []byte, fheType uint8, from, to uint64) []byte {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+
+	keysMu.RLock()
+	src, dst := keySets[from], keySets[to]
+	keysMu.RUnlock()
+	if src == nil || dst == nil {
+		return nil
+	}
+
+	ctIn := deserializeBitCiphertext(ct)
+	if ctIn == nil {
+		return nil
+	}
+
+	targetType := fheTypeToTFHEType(fheType)
+	limbs := (ctIn.NumBits() + 63) / 64
+	var result *fhe.BitCiphertext
+	for i := limbs - 1; i >= 0; i-- {
+		limb := src.decryptor.DecryptUint64(src.shared.ev.Shr(ctIn, 64*i))
+		enc := dst.shared.enc.EncryptUint64(limb, targetType)
+		if result == nil {
+			result = enc
+			continue
+		}
+		var err error
+		if result, err = dst.shared.ev.Or(dst.shared.ev.Shl(result, 64), enc); err != nil {
+			return nil
+		}
+	}
+	return serializeBitCiphertext(result)
+}
+
 // fheTypeToTFHEType converts FHE type constant to TFHE FheUintType
 func fheTypeToTFHEType(fheType uint8) fhe.FheUintType {
 	switch fheType {
@@ -126,7 +330,7 @@ func deserializeCiphertext(data []byte) *fhe.Ciphertext {
 
 // FHE Operations - Binary Arithmetic
 
-func tfheAdd(lhs, rhs []byte, fheType uint8) []byte {
+func tfheAdd(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -137,7 +341,7 @@ func tfheAdd(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Add(ctLhs, ctRhs)
+	result, err := tc.ev.Add(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -145,7 +349,7 @@ func tfheAdd(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheSub(lhs, rhs []byte, fheType uint8) []byte {
+func tfheSub(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -156,7 +360,7 @@ func tfheSub(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Sub(ctLhs, ctRhs)
+	result, err := tc.ev.Sub(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -164,7 +368,7 @@ func tfheSub(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheMul(lhs, rhs []byte, fheType uint8) []byte {
+func tfheMul(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -176,10 +380,10 @@ func tfheMul(lhs, rhs []byte, fheType uint8) []byte {
 	}
 
 	// TFHE multiplication using schoolbook algorithm
-	result, err := evaluator.Mul(ctLhs, ctRhs)
+	result, err := tc.ev.Mul(ctLhs, ctRhs)
 	if err != nil {
 		// Fall back to scalar multiply by 1 if full mul not available
-		result, err = evaluator.ScalarMul(ctLhs, 1)
+		result, err = tc.ev.ScalarMul(ctLhs, 1)
 		if err != nil {
 			return nil
 		}
@@ -188,7 +392,7 @@ func tfheMul(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheDiv(lhs, rhs []byte, fheType uint8) []byte {
+func tfheDiv(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -200,7 +404,7 @@ func tfheDiv(lhs, rhs []byte, fheType uint8) []byte {
 	}
 
 	// TFHE division using binary long division
-	result, err := evaluator.Div(ctLhs, ctRhs)
+	result, err := tc.ev.Div(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -208,7 +412,7 @@ func tfheDiv(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheRem(lhs, rhs []byte, fheType uint8) []byte {
+func tfheRem(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -220,7 +424,7 @@ func tfheRem(lhs, rhs []byte, fheType uint8) []byte {
 	}
 
 	// TFHE remainder operation
-	result, err := evaluator.Rem(ctLhs, ctRhs)
+	result, err := tc.ev.Rem(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -231,7 +435,7 @@ func tfheRem(lhs, rhs []byte, fheType uint8) []byte {
 // FHE Operations - Comparison
 // These return encrypted boolean (single encrypted bit)
 
-func tfheLt(lhs, rhs []byte, fheType uint8) []byte {
+func tfheLt(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -242,7 +446,7 @@ func tfheLt(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Lt(ctLhs, ctRhs)
+	result, err := tc.ev.Lt(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -252,7 +456,7 @@ func tfheLt(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(boolCt)
 }
 
-func tfheLe(lhs, rhs []byte, fheType uint8) []byte {
+func tfheLe(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -263,7 +467,7 @@ func tfheLe(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Le(ctLhs, ctRhs)
+	result, err := tc.ev.Le(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -272,7 +476,7 @@ func tfheLe(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(boolCt)
 }
 
-func tfheGt(lhs, rhs []byte, fheType uint8) []byte {
+func tfheGt(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -283,7 +487,7 @@ func tfheGt(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Gt(ctLhs, ctRhs)
+	result, err := tc.ev.Gt(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -292,7 +496,7 @@ func tfheGt(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(boolCt)
 }
 
-func tfheGe(lhs, rhs []byte, fheType uint8) []byte {
+func tfheGe(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -303,7 +507,7 @@ func tfheGe(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Ge(ctLhs, ctRhs)
+	result, err := tc.ev.Ge(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -312,7 +516,7 @@ func tfheGe(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(boolCt)
 }
 
-func tfheEq(lhs, rhs []byte, fheType uint8) []byte {
+func tfheEq(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -323,7 +527,7 @@ func tfheEq(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Eq(ctLhs, ctRhs)
+	result, err := tc.ev.Eq(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -332,7 +536,7 @@ func tfheEq(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(boolCt)
 }
 
-func tfheNe(lhs, rhs []byte, fheType uint8) []byte {
+func tfheNe(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -344,11 +548,11 @@ func tfheNe(lhs, rhs []byte, fheType uint8) []byte {
 	}
 
 	// NE(a, b) = (a < b) OR (a > b)
-	ltResult, err := evaluator.Lt(ctLhs, ctRhs)
+	ltResult, err := tc.ev.Lt(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
-	gtResult, err := evaluator.Gt(ctLhs, ctRhs)
+	gtResult, err := tc.ev.Gt(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -357,7 +561,7 @@ func tfheNe(lhs, rhs []byte, fheType uint8) []byte {
 	ltBits := fhe.WrapBoolCiphertext(ltResult)
 	gtBits := fhe.WrapBoolCiphertext(gtResult)
 
-	neResult, err := evaluator.Or(ltBits, gtBits)
+	neResult, err := tc.ev.Or(ltBits, gtBits)
 	if err != nil {
 		return nil
 	}
@@ -368,7 +572,7 @@ func tfheNe(lhs, rhs []byte, fheType uint8) []byte {
 
 // FHE Operations - Bitwise
 
-func tfheAnd(lhs, rhs []byte, fheType uint8) []byte {
+func tfheAnd(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -379,7 +583,7 @@ func tfheAnd(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.And(ctLhs, ctRhs)
+	result, err := tc.ev.And(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -387,7 +591,7 @@ func tfheAnd(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheOr(lhs, rhs []byte, fheType uint8) []byte {
+func tfheOr(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -398,7 +602,7 @@ func tfheOr(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Or(ctLhs, ctRhs)
+	result, err := tc.ev.Or(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -406,7 +610,7 @@ func tfheOr(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheXor(lhs, rhs []byte, fheType uint8) []byte {
+func tfheXor(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -417,7 +621,7 @@ func tfheXor(lhs, rhs []byte, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.Xor(ctLhs, ctRhs)
+	result, err := tc.ev.Xor(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -425,7 +629,7 @@ func tfheXor(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheNot(ct []byte, fheType uint8) []byte {
+func tfheNot(tc *tfheContext, ct []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -436,11 +640,11 @@ func tfheNot(ct []byte, fheType uint8) []byte {
 	}
 
 	// Not returns *BitCiphertext directly (no error)
-	result := evaluator.Not(ctIn)
+	result := tc.ev.Not(ctIn)
 	return serializeBitCiphertext(result)
 }
 
-func tfheNeg(ct []byte, fheType uint8) []byte {
+func tfheNeg(tc *tfheContext, ct []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -451,7 +655,7 @@ func tfheNeg(ct []byte, fheType uint8) []byte {
 	}
 
 	// Negation: negate the value (two's complement)
-	result, err := evaluator.Neg(ctIn)
+	result, err := tc.ev.Neg(ctIn)
 	if err != nil {
 		return nil
 	}
@@ -461,7 +665,7 @@ func tfheNeg(ct []byte, fheType uint8) []byte {
 
 // FHE Operations - Selection and Cast
 
-func tfheSelect(control, ifTrue, ifFalse []byte, fheType uint8) []byte {
+func tfheSelect(tc *tfheContext, control, ifTrue, ifFalse []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -475,7 +679,7 @@ func tfheSelect(control, ifTrue, ifFalse []byte, fheType uint8) []byte {
 	}
 
 	// Select: if control then ifTrue else ifFalse
-	result, err := evaluator.Select(ctControl, ctTrue, ctFalse)
+	result, err := tc.ev.Select(ctControl, ctTrue, ctFalse)
 	if err != nil {
 		return nil
 	}
@@ -483,7 +687,7 @@ func tfheSelect(control, ifTrue, ifFalse []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheCast(ct []byte, fromType, toType uint8) []byte {
+func tfheCast(tc *tfheContext, ct []byte, fromType, toType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -495,14 +699,14 @@ func tfheCast(ct []byte, fromType, toType uint8) []byte {
 
 	targetType := fheTypeToTFHEType(toType)
 	// CastTo returns *BitCiphertext directly (no error)
-	result := evaluator.CastTo(ctIn, targetType)
+	result := tc.ev.CastTo(ctIn, targetType)
 
 	return serializeBitCiphertext(result)
 }
 
 // FHE Operations - Min/Max
 
-func tfheMin(lhs, rhs []byte, fheType uint8) []byte {
+func tfheMin(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -514,12 +718,12 @@ func tfheMin(lhs, rhs []byte, fheType uint8) []byte {
 	}
 
 	// Min = (lhs < rhs) ? lhs : rhs
-	ltResult, err := evaluator.Lt(ctLhs, ctRhs)
+	ltResult, err := tc.ev.Lt(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
 
-	result, err := evaluator.Select(ltResult, ctLhs, ctRhs)
+	result, err := tc.ev.Select(ltResult, ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -527,7 +731,7 @@ func tfheMin(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheMax(lhs, rhs []byte, fheType uint8) []byte {
+func tfheMax(tc *tfheContext, lhs, rhs []byte, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -539,12 +743,12 @@ func tfheMax(lhs, rhs []byte, fheType uint8) []byte {
 	}
 
 	// Max = (lhs > rhs) ? lhs : rhs
-	gtResult, err := evaluator.Gt(ctLhs, ctRhs)
+	gtResult, err := tc.ev.Gt(ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
 
-	result, err := evaluator.Select(gtResult, ctLhs, ctRhs)
+	result, err := tc.ev.Select(gtResult, ctLhs, ctRhs)
 	if err != nil {
 		return nil
 	}
@@ -552,6 +756,156 @@ func tfheMax(lhs, rhs []byte, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
+// tfheMaxWithIndex returns the encrypted maximum of the given ciphertexts and
+// the encrypted (euint32) index of the first ciphertext equal to it. Ties keep
+// the earliest index, matching first-price sealed-bid auction semantics.
+func tfheMaxWithIndex(tc *tfheContext, cts [][]byte, fheType uint8) ([]byte, []byte) {
+	if err := initTFHE(); err != nil {
+		return nil, nil
+	}
+	if len(cts) == 0 {
+		return nil, nil
+	}
+
+	curMax := deserializeBitCiphertext(cts[0])
+	if curMax == nil {
+		return nil, nil
+	}
+	indexType := fheTypeToTFHEType(TypeEuint32)
+	curIndex := tc.enc.EncryptUint64(0, indexType)
+
+	for i := 1; i < len(cts); i++ {
+		ct := deserializeBitCiphertext(cts[i])
+		if ct == nil {
+			return nil, nil
+		}
+
+		// Strictly greater so the earlier bidder wins on a tie
+		gt, err := tc.ev.Gt(ct, curMax)
+		if err != nil {
+			return nil, nil
+		}
+
+		curMax, err = tc.ev.Select(gt, ct, curMax)
+		if err != nil {
+			return nil, nil
+		}
+
+		idx := tc.enc.EncryptUint64(uint64(i), indexType)
+		curIndex, err = tc.ev.Select(gt, idx, curIndex)
+		if err != nil {
+			return nil, nil
+		}
+	}
+
+	return serializeBitCiphertext(curMax), serializeBitCiphertext(curIndex)
+}
+
+// tfheSelectN returns cts[index] for an encrypted index of indexType. The
+// candidates are folded pairwise in a CMUX tree, one index bit per level, so
+// n candidates take n-1 selects. bits is the tree depth; an index of n or
+// more yields an encryption of zero unless every index value is in range.
+func tfheSelectN(tc *tfheContext, index []byte, indexType uint8, cts [][]byte, fheType uint8, bits int, guard bool) []byte {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+	if len(cts) == 0 {
+		return nil
+	}
+
+	ctIndex := deserializeBitCiphertext(index)
+	if ctIndex == nil {
+		return nil
+	}
+	level := make([]*fhe.BitCiphertext, len(cts))
+	for i, ct := range cts {
+		if level[i] = deserializeBitCiphertext(ct); level[i] == nil {
+			return nil
+		}
+	}
+
+	indexTFHEType := fheTypeToTFHEType(indexType)
+	for k := 0; k < bits; k++ {
+		// Bit k of the index: (index & 2^k) == 2^k
+		mask := tc.enc.EncryptUint64(1<<k, indexTFHEType)
+		masked, err := tc.ev.And(ctIndex, mask)
+		if err != nil {
+			return nil
+		}
+		bit, err := tc.ev.Eq(masked, mask)
+		if err != nil {
+			return nil
+		}
+
+		// An odd candidate out moves up unchanged
+		next := make([]*fhe.BitCiphertext, 0, (len(level)+1)/2)
+		for j := 0; j+1 < len(level); j += 2 {
+			sel, err := tc.ev.Select(bit, level[j+1], level[j])
+			if err != nil {
+				return nil
+			}
+			next = append(next, sel)
+		}
+		if len(level)%2 == 1 {
+			next = append(next, level[len(level)-1])
+		}
+		level = next
+	}
+	if len(level) != 1 {
+		return nil
+	}
+
+	result := level[0]
+	if guard {
+		bound := tc.enc.EncryptUint64(uint64(len(cts)), indexTFHEType)
+		inRange, err := tc.ev.Lt(ctIndex, bound)
+		if err != nil {
+			return nil
+		}
+		zero := tc.enc.EncryptUint64(0, fheTypeToTFHEType(fheType))
+		if result, err = tc.ev.Select(inRange, result, zero); err != nil {
+			return nil
+		}
+	}
+
+	return serializeBitCiphertext(result)
+}
+
+// tfheArraySet returns the elements with the one at the encrypted index
+// replaced by value: select(index == i, value, cts[i]) for every i
+func tfheArraySet(tc *tfheContext, index []byte, indexType uint8, cts [][]byte, value []byte) [][]byte {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+
+	ctIndex := deserializeBitCiphertext(index)
+	ctValue := deserializeBitCiphertext(value)
+	if ctIndex == nil || ctValue == nil {
+		return nil
+	}
+
+	indexTFHEType := fheTypeToTFHEType(indexType)
+	updated := make([][]byte, len(cts))
+	for i, ct := range cts {
+		elem := deserializeBitCiphertext(ct)
+		if elem == nil {
+			return nil
+		}
+		hit, err := tc.ev.Eq(ctIndex, tc.enc.EncryptUint64(uint64(i), indexTFHEType))
+		if err != nil {
+			return nil
+		}
+		sel, err := tc.ev.Select(hit, ctValue, elem)
+		if err != nil {
+			return nil
+		}
+		if updated[i] = serializeBitCiphertext(sel); updated[i] == nil {
+			return nil
+		}
+	}
+	return updated
+}
+
 // FHE Operations - Encryption/Decryption
 
 func tfheVerify(ct []byte, fheType uint8) bool {
@@ -561,55 +915,50 @@ func tfheVerify(ct []byte, fheType uint8) bool {
 
 func tfheDecrypt(ct []byte, fheType uint8) *big.Int {
 	if err := initTFHE(); err != nil {
-		return big.NewInt(0)
+		return nil
 	}
 
 	ctIn := deserializeBitCiphertext(ct)
 	if ctIn == nil {
-		return big.NewInt(0)
+		return nil
 	}
 
-	plaintext := decryptor.DecryptUint64(ctIn)
+	plaintext := tfheKeys().decryptor.DecryptUint64(ctIn)
 	return new(big.Int).SetUint64(plaintext)
 }
 
-func tfheTrivialEncrypt(plaintext *big.Int, toType uint8) []byte {
+func tfheTrivialEncrypt(tc *tfheContext, plaintext *big.Int, toType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
 
 	targetType := fheTypeToTFHEType(toType)
 	// Use encryptor for now (trivial encryption would be noiseless)
-	ct := encryptor.EncryptUint64(plaintext.Uint64(), targetType)
+	ct := tc.enc.EncryptUint64(plaintext.Uint64(), targetType)
 
 	return serializeBitCiphertext(ct)
 }
 
-func tfheSealOutput(ct, pk []byte, fheType uint8) []byte {
-	// Seal output for a specific public key
-	// In production, this would re-encrypt under the given public key
-	// For now, just return the ciphertext with a header
-	result := make([]byte, len(ct)+len(pk)+8)
-	binary.BigEndian.PutUint32(result[0:4], uint32(len(pk)))
-	binary.BigEndian.PutUint32(result[4:8], uint32(len(ct)))
-	copy(result[8:8+len(pk)], pk)
-	copy(result[8+len(pk):], ct)
-	return result
-}
-
 func tfheRandom(fheType uint8, seed uint64) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
 
-	// Generate random bytes based on seed
-	targetType := fheTypeToTFHEType(fheType)
-
 	// Create deterministic seed bytes
 	seedBytes := make([]byte, 32)
 	binary.BigEndian.PutUint64(seedBytes[24:], seed)
 
-	rng := fhe.NewFheRNG(params, secretKey, seedBytes)
+	return tfheRandomSeed(fheType, seedBytes)
+}
+
+// tfheRandomSeed generates a random ciphertext from a seed of any length
+func tfheRandomSeed(fheType uint8, seed []byte) []byte {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+
+	targetType := fheTypeToTFHEType(fheType)
+	rng := fhe.NewFheRNG(params, tfheKeys().secretKey, seed)
 	ct := rng.RandomUint(targetType)
 
 	return serializeBitCiphertext(ct)
@@ -620,6 +969,7 @@ func tfheGetNetworkPublicKey() []byte {
 		return nil
 	}
 
+	publicKey := tfheKeys().publicKey
 	if publicKey == nil {
 		return nil
 	}
@@ -637,7 +987,7 @@ func tfheGetNetworkPublicKey() []byte {
 
 // === Shift Operations ===
 
-func tfheShl(ct []byte, shift int, fheType uint8) []byte {
+func tfheShl(tc *tfheContext, ct []byte, shift int, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -648,11 +998,11 @@ func tfheShl(ct []byte, shift int, fheType uint8) []byte {
 	}
 
 	// Shl returns *BitCiphertext directly
-	result := evaluator.Shl(ctIn, shift)
+	result := tc.ev.Shl(ctIn, shift)
 	return serializeBitCiphertext(result)
 }
 
-func tfheShr(ct []byte, shift int, fheType uint8) []byte {
+func tfheShr(tc *tfheContext, ct []byte, shift int, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -663,11 +1013,11 @@ func tfheShr(ct []byte, shift int, fheType uint8) []byte {
 	}
 
 	// Shr returns *BitCiphertext directly
-	result := evaluator.Shr(ctIn, shift)
+	result := tc.ev.Shr(ctIn, shift)
 	return serializeBitCiphertext(result)
 }
 
-func tfheRotl(ct []byte, shift int, fheType uint8) []byte {
+func tfheRotl(tc *tfheContext, ct []byte, shift int, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -681,10 +1031,10 @@ func tfheRotl(ct []byte, shift int, fheType uint8) []byte {
 	numBits := ctIn.NumBits()
 	shift = shift % numBits
 
-	leftPart := evaluator.Shl(ctIn, shift)
-	rightPart := evaluator.Shr(ctIn, numBits-shift)
+	leftPart := tc.ev.Shl(ctIn, shift)
+	rightPart := tc.ev.Shr(ctIn, numBits-shift)
 
-	result, err := evaluator.Or(leftPart, rightPart)
+	result, err := tc.ev.Or(leftPart, rightPart)
 	if err != nil {
 		return nil
 	}
@@ -692,7 +1042,7 @@ func tfheRotl(ct []byte, shift int, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheRotr(ct []byte, shift int, fheType uint8) []byte {
+func tfheRotr(tc *tfheContext, ct []byte, shift int, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -706,10 +1056,10 @@ func tfheRotr(ct []byte, shift int, fheType uint8) []byte {
 	numBits := ctIn.NumBits()
 	shift = shift % numBits
 
-	rightPart := evaluator.Shr(ctIn, shift)
-	leftPart := evaluator.Shl(ctIn, numBits-shift)
+	rightPart := tc.ev.Shr(ctIn, shift)
+	leftPart := tc.ev.Shl(ctIn, numBits-shift)
 
-	result, err := evaluator.Or(leftPart, rightPart)
+	result, err := tc.ev.Or(leftPart, rightPart)
 	if err != nil {
 		return nil
 	}
@@ -717,9 +1067,62 @@ func tfheRotr(ct []byte, shift int, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
+// tfheShiftEnc shifts or rotates ct by an encrypted amount with a barrel
+// shifter over the low depth bits of the amount
+func tfheShiftEnc(tc *tfheContext, op string, ct []byte, fheType uint8, amount []byte, amountType uint8, depth int) []byte {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+
+	value := deserializeBitCiphertext(ct)
+	ctAmount := deserializeBitCiphertext(amount)
+	if value == nil || ctAmount == nil {
+		return nil
+	}
+
+	numBits := value.NumBits()
+	amountTFHEType := fheTypeToTFHEType(amountType)
+	for k := 0; k < depth; k++ {
+		// Bit k of the amount: (amount & 2^k) == 2^k
+		mask := tc.enc.EncryptUint64(1<<k, amountTFHEType)
+		masked, err := tc.ev.And(ctAmount, mask)
+		if err != nil {
+			return nil
+		}
+		bit, err := tc.ev.Eq(masked, mask)
+		if err != nil {
+			return nil
+		}
+
+		n := (1 << k) % numBits
+		var moved *fhe.BitCiphertext
+		switch op {
+		case "shlEnc":
+			moved = tc.ev.Shl(value, n)
+		case "shrEnc":
+			moved = tc.ev.Shr(value, n)
+		case "rotlEnc":
+			moved, err = tc.ev.Or(tc.ev.Shl(value, n), tc.ev.Shr(value, numBits-n))
+		case "rotrEnc":
+			moved, err = tc.ev.Or(tc.ev.Shr(value, n), tc.ev.Shl(value, numBits-n))
+		default:
+			return nil
+		}
+		if err != nil {
+			return nil
+		}
+
+		if value, err = tc.ev.Select(bit, moved, value); err != nil {
+			return nil
+		}
+	}
+
+	return serializeBitCiphertext(value)
+}
+
 // === Scalar Operations ===
 
-func tfheScalarAdd(ct []byte, scalar uint64, fheType uint8) []byte {
+func tfheScalarAdd(tc *tfheContext, ct []byte, scalar uint64, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -729,7 +1132,7 @@ func tfheScalarAdd(ct []byte, scalar uint64, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.ScalarAdd(ctIn, scalar)
+	result, err := tc.ev.ScalarAdd(ctIn, scalar)
 	if err != nil {
 		return nil
 	}
@@ -737,7 +1140,7 @@ func tfheScalarAdd(ct []byte, scalar uint64, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheScalarSub(ct []byte, scalar uint64, fheType uint8) []byte {
+func tfheScalarSub(tc *tfheContext, ct []byte, scalar uint64, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -753,7 +1156,7 @@ func tfheScalarSub(ct []byte, scalar uint64, fheType uint8) []byte {
 	mask := uint64((1 << numBits) - 1)
 	negScalar := (^scalar + 1) & mask
 
-	result, err := evaluator.ScalarAdd(ctIn, negScalar)
+	result, err := tc.ev.ScalarAdd(ctIn, negScalar)
 	if err != nil {
 		return nil
 	}
@@ -761,7 +1164,7 @@ func tfheScalarSub(ct []byte, scalar uint64, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheScalarMul(ct []byte, scalar uint64, fheType uint8) []byte {
+func tfheScalarMul(tc *tfheContext, ct []byte, scalar uint64, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -771,7 +1174,7 @@ func tfheScalarMul(ct []byte, scalar uint64, fheType uint8) []byte {
 		return nil
 	}
 
-	result, err := evaluator.ScalarMul(ctIn, scalar)
+	result, err := tc.ev.ScalarMul(ctIn, scalar)
 	if err != nil {
 		return nil
 	}
@@ -779,14 +1182,14 @@ func tfheScalarMul(ct []byte, scalar uint64, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheScalarDiv(ct []byte, scalar uint64, fheType uint8) []byte {
+func tfheScalarDiv(tc *tfheContext, ct []byte, scalar uint64, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
 
 	if scalar == 0 {
 		// Division by zero: return max value
-		return tfheMaxValue(fheType)
+		return tfheMaxValue(tc, fheType)
 	}
 
 	ctIn := deserializeBitCiphertext(ct)
@@ -796,9 +1199,9 @@ func tfheScalarDiv(ct []byte, scalar uint64, fheType uint8) []byte {
 
 	// For scalar division, encrypt the scalar and use encrypted division
 	targetType := fheTypeToTFHEType(fheType)
-	ctScalar := encryptor.EncryptUint64(scalar, targetType)
+	ctScalar := tc.enc.EncryptUint64(scalar, targetType)
 
-	result, err := evaluator.Div(ctIn, ctScalar)
+	result, err := tc.ev.Div(ctIn, ctScalar)
 	if err != nil {
 		return nil
 	}
@@ -806,7 +1209,7 @@ func tfheScalarDiv(ct []byte, scalar uint64, fheType uint8) []byte {
 	return serializeBitCiphertext(result)
 }
 
-func tfheScalarRem(ct []byte, scalar uint64, fheType uint8) []byte {
+func tfheScalarRem(tc *tfheContext, ct []byte, scalar uint64, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
@@ -823,9 +1226,9 @@ func tfheScalarRem(ct []byte, scalar uint64, fheType uint8) []byte {
 
 	// For scalar remainder, encrypt the scalar and use encrypted rem
 	targetType := fheTypeToTFHEType(fheType)
-	ctScalar := encryptor.EncryptUint64(scalar, targetType)
+	ctScalar := tc.enc.EncryptUint64(scalar, targetType)
 
-	result, err := evaluator.Rem(ctIn, ctScalar)
+	result, err := tc.ev.Rem(ctIn, ctScalar)
 	if err != nil {
 		return nil
 	}
@@ -834,16 +1237,163 @@ func tfheScalarRem(ct []byte, scalar uint64, fheType uint8) []byte {
 }
 
 // tfheMaxValue returns an encrypted max value (all bits set)
-func tfheMaxValue(fheType uint8) []byte {
+func tfheMaxValue(tc *tfheContext, fheType uint8) []byte {
 	if err := initTFHE(); err != nil {
 		return nil
 	}
 
 	targetType := fheTypeToTFHEType(fheType)
-	maxVal := evaluator.MaxValue(targetType)
+	maxVal := tc.ev.MaxValue(targetType)
 	return serializeBitCiphertext(maxVal)
 }
 
+// === Re-randomization ===
+
+// tfheRerandomize returns a fresh ciphertext of the same plaintext. A
+// seeded random value r is XORed in twice; the result depends on r's
+// ciphertext, and every bit passes through a bootstrapped gate.
+func tfheRerandomize(tc *tfheContext, ct []byte, fheType uint8, seed []byte) []byte {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+
+	ctIn := deserializeBitCiphertext(ct)
+	if ctIn == nil {
+		return nil
+	}
+
+	targetType := fheTypeToTFHEType(fheType)
+	rng := fhe.NewFheRNG(params, tfheKeys().secretKey, seed)
+	mask := rng.RandomUint(targetType)
+
+	masked, err := tc.ev.Xor(ctIn, mask)
+	if err != nil {
+		return nil
+	}
+	result, err := tc.ev.Xor(masked, mask)
+	if err != nil {
+		return nil
+	}
+
+	return serializeBitCiphertext(result)
+}
+
+// tfheRefresh bootstraps every bit of a ciphertext, resetting its noise.
+// AND of a bit with itself is the identity, evaluated as a bootstrapped gate.
+func tfheRefresh(tc *tfheContext, ct []byte, fheType uint8) []byte {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+
+	ctIn := deserializeBitCiphertext(ct)
+	if ctIn == nil {
+		return nil
+	}
+
+	result, err := tc.ev.And(ctIn, ctIn)
+	if err != nil {
+		return nil
+	}
+
+	return serializeBitCiphertext(result)
+}
+
+// === Scalar Comparisons ===
+
+// gateTerm is a boolean in a comparison circuit, known in plaintext until
+// an encrypted bit enters it
+type gateTerm struct {
+	ct    *fhe.BitCiphertext // nil while the value is known
+	value bool
+}
+
+func (tc *tfheContext) and(t gateTerm, bit *fhe.BitCiphertext) (gateTerm, error) {
+	if t.ct == nil {
+		if !t.value {
+			return t, nil
+		}
+		return gateTerm{ct: bit}, nil
+	}
+	ct, err := tc.ev.And(t.ct, bit)
+	return gateTerm{ct: ct}, err
+}
+
+func (tc *tfheContext) or(t gateTerm, bit *fhe.BitCiphertext) (gateTerm, error) {
+	if t.ct == nil {
+		if t.value {
+			return t, nil
+		}
+		return gateTerm{ct: bit}, nil
+	}
+	ct, err := tc.ev.Or(t.ct, bit)
+	return gateTerm{ct: ct}, err
+}
+
+// tfheScalarCompare compares ct with a plaintext scalar no wider than the
+// operand. The scalar is never encrypted: its bits select the gates. From
+// the least significant bit up, lt becomes (¬x ∨ lt) where the scalar bit is
+// set and (¬x ∧ lt) where it is clear, starting from false for lt and from
+// true for le. eq ands x where the bit is set and ¬x where it is clear. Gt,
+// ge and ne are the complements of le, lt and eq.
+func tfheScalarCompare(tc *tfheContext, op string, ct []byte, scalar *big.Int, fheType uint8) []byte {
+	if err := initTFHE(); err != nil {
+		return nil
+	}
+
+	ctIn := deserializeBitCiphertext(ct)
+	if ctIn == nil || scalar.Sign() < 0 || scalar.BitLen() > ctIn.NumBits() {
+		return nil
+	}
+
+	var acc gateTerm
+	var negate, equality bool
+	switch op {
+	case "scalarLt":
+	case "scalarGe":
+		negate = true
+	case "scalarLe":
+		acc.value = true
+	case "scalarGt":
+		acc.value, negate = true, true
+	case "scalarEq":
+		acc.value, equality = true, true
+	case "scalarNe":
+		acc.value, equality, negate = true, true, true
+	default:
+		return nil
+	}
+
+	for i := 0; i < ctIn.NumBits(); i++ {
+		bit := tc.ev.CastTo(tc.ev.Shr(ctIn, i), fhe.FheBool)
+		set := scalar.Bit(i) == 1
+		var err error
+		switch {
+		case equality && set:
+			acc, err = tc.and(acc, bit)
+		case set:
+			acc, err = tc.or(acc, tc.ev.Not(bit))
+		default:
+			acc, err = tc.and(acc, tc.ev.Not(bit))
+		}
+		if err != nil {
+			return nil
+		}
+	}
+
+	// The scalar alone decided the comparison, as for lt 0
+	if acc.ct == nil {
+		result := new(big.Int)
+		if acc.value != negate {
+			result.SetUint64(1)
+		}
+		return tfheTrivialEncrypt(tc, result, TypeEbool)
+	}
+	if negate {
+		return serializeBitCiphertext(tc.ev.Not(acc.ct))
+	}
+	return serializeBitCiphertext(acc.ct)
+}
+
 // GetBackend returns the current FHE backend being used
 func GetBackend() string {
 	return "CPU (pure Go)"
@@ -6,11 +6,32 @@
 package fhe
 
 import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
+	"github.com/cloudflare/circl/hpke"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/holiman/uint256"
+	"github.com/luxfi/crypto"
 	"github.com/luxfi/fhe"
 	"github.com/luxfi/geth/common"
+	ethtypes "github.com/luxfi/geth/core/types"
+	"github.com/luxfi/precompile/contract"
+	"github.com/luxfi/precompile/contract/statetest"
+	"github.com/luxfi/precompile/ecies"
+	"github.com/luxfi/precompile/precompileconfig"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,11 +39,13 @@ import (
 func TestTFHEInitialization(t *testing.T) {
 	err := initTFHE()
 	require.NoError(t, err, "TFHE initialization should succeed")
-	require.NotNil(t, evaluator, "evaluator should be initialized")
-	require.NotNil(t, encryptor, "encryptor should be initialized")
-	require.NotNil(t, decryptor, "decryptor should be initialized")
-	require.NotNil(t, secretKey, "secretKey should be initialized")
-	require.NotNil(t, publicKey, "publicKey should be initialized")
+	keys := tfheKeys()
+	require.NotNil(t, keys.shared, "shared context should be initialized")
+	require.NotNil(t, keys.shared.ev, "evaluator should be initialized")
+	require.NotNil(t, keys.shared.enc, "encryptor should be initialized")
+	require.NotNil(t, keys.decryptor, "decryptor should be initialized")
+	require.NotNil(t, keys.secretKey, "secretKey should be initialized")
+	require.NotNil(t, keys.publicKey, "publicKey should be initialized")
 }
 
 // TestFheTypeMapping tests FHE type constant to TFHE type mapping
@@ -72,7 +95,7 @@ func TestTrivialEncryptDecrypt(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Encrypt
-			ct := tfheTrivialEncrypt(big.NewInt(int64(tt.value)), tt.fheType)
+			ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.value)), tt.fheType)
 			require.NotNil(t, ct, "encryption should succeed")
 			require.Greater(t, len(ct), 0, "ciphertext should not be empty")
 
@@ -91,7 +114,7 @@ func TestBitCiphertextSerialization(t *testing.T) {
 
 	// Encrypt a value
 	value := uint64(42)
-	ct := tfheTrivialEncrypt(big.NewInt(int64(value)), TypeEuint8)
+	ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(value)), TypeEuint8)
 	require.NotNil(t, ct)
 
 	// Deserialize to BitCiphertext and back
@@ -126,12 +149,12 @@ func TestFHEAdd(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctA := tfheTrivialEncrypt(big.NewInt(int64(tt.a)), tt.fheType)
-			ctB := tfheTrivialEncrypt(big.NewInt(int64(tt.b)), tt.fheType)
+			ctA := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.a)), tt.fheType)
+			ctB := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.b)), tt.fheType)
 			require.NotNil(t, ctA)
 			require.NotNil(t, ctB)
 
-			result := tfheAdd(ctA, ctB, tt.fheType)
+			result := tfheAdd(defaultTFHE(), ctA, ctB, tt.fheType)
 			require.NotNil(t, result, "addition should succeed")
 
 			decrypted := tfheDecrypt(result, tt.fheType)
@@ -158,12 +181,12 @@ func TestFHESub(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctA := tfheTrivialEncrypt(big.NewInt(int64(tt.a)), tt.fheType)
-			ctB := tfheTrivialEncrypt(big.NewInt(int64(tt.b)), tt.fheType)
+			ctA := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.a)), tt.fheType)
+			ctB := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.b)), tt.fheType)
 			require.NotNil(t, ctA)
 			require.NotNil(t, ctB)
 
-			result := tfheSub(ctA, ctB, tt.fheType)
+			result := tfheSub(defaultTFHE(), ctA, ctB, tt.fheType)
 			require.NotNil(t, result, "subtraction should succeed")
 
 			decrypted := tfheDecrypt(result, tt.fheType)
@@ -190,12 +213,12 @@ func TestFHEMul(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctA := tfheTrivialEncrypt(big.NewInt(int64(tt.a)), tt.fheType)
-			ctB := tfheTrivialEncrypt(big.NewInt(int64(tt.b)), tt.fheType)
+			ctA := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.a)), tt.fheType)
+			ctB := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.b)), tt.fheType)
 			require.NotNil(t, ctA)
 			require.NotNil(t, ctB)
 
-			result := tfheMul(ctA, ctB, tt.fheType)
+			result := tfheMul(defaultTFHE(), ctA, ctB, tt.fheType)
 			require.NotNil(t, result, "multiplication should succeed")
 
 			decrypted := tfheDecrypt(result, tt.fheType)
@@ -233,23 +256,23 @@ func TestFHEComparisons(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctA := tfheTrivialEncrypt(big.NewInt(int64(tt.a)), TypeEuint8)
-			ctB := tfheTrivialEncrypt(big.NewInt(int64(tt.b)), TypeEuint8)
+			ctA := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.a)), TypeEuint8)
+			ctB := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.b)), TypeEuint8)
 			require.NotNil(t, ctA)
 			require.NotNil(t, ctB)
 
 			var result []byte
 			switch tt.op {
 			case "lt":
-				result = tfheLt(ctA, ctB, TypeEuint8)
+				result = tfheLt(defaultTFHE(), ctA, ctB, TypeEuint8)
 			case "le":
-				result = tfheLe(ctA, ctB, TypeEuint8)
+				result = tfheLe(defaultTFHE(), ctA, ctB, TypeEuint8)
 			case "gt":
-				result = tfheGt(ctA, ctB, TypeEuint8)
+				result = tfheGt(defaultTFHE(), ctA, ctB, TypeEuint8)
 			case "ge":
-				result = tfheGe(ctA, ctB, TypeEuint8)
+				result = tfheGe(defaultTFHE(), ctA, ctB, TypeEuint8)
 			case "eq":
-				result = tfheEq(ctA, ctB, TypeEuint8)
+				result = tfheEq(defaultTFHE(), ctA, ctB, TypeEuint8)
 			}
 			require.NotNil(t, result, "%s should succeed", tt.op)
 
@@ -285,19 +308,19 @@ func TestFHEBitwise(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctA := tfheTrivialEncrypt(big.NewInt(int64(tt.a)), TypeEuint8)
-			ctB := tfheTrivialEncrypt(big.NewInt(int64(tt.b)), TypeEuint8)
+			ctA := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.a)), TypeEuint8)
+			ctB := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.b)), TypeEuint8)
 			require.NotNil(t, ctA)
 			require.NotNil(t, ctB)
 
 			var result []byte
 			switch tt.op {
 			case "and":
-				result = tfheAnd(ctA, ctB, TypeEuint8)
+				result = tfheAnd(defaultTFHE(), ctA, ctB, TypeEuint8)
 			case "or":
-				result = tfheOr(ctA, ctB, TypeEuint8)
+				result = tfheOr(defaultTFHE(), ctA, ctB, TypeEuint8)
 			case "xor":
-				result = tfheXor(ctA, ctB, TypeEuint8)
+				result = tfheXor(defaultTFHE(), ctA, ctB, TypeEuint8)
 			}
 			require.NotNil(t, result, "%s should succeed", tt.op)
 
@@ -324,10 +347,10 @@ func TestFHENot(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ct := tfheTrivialEncrypt(big.NewInt(int64(tt.value)), TypeEuint8)
+			ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.value)), TypeEuint8)
 			require.NotNil(t, ct)
 
-			result := tfheNot(ct, TypeEuint8)
+			result := tfheNot(defaultTFHE(), ct, TypeEuint8)
 			require.NotNil(t, result)
 
 			decrypted := tfheDecrypt(result, TypeEuint8)
@@ -357,15 +380,15 @@ func TestFHEShift(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ct := tfheTrivialEncrypt(big.NewInt(int64(tt.value)), TypeEuint8)
+			ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.value)), TypeEuint8)
 			require.NotNil(t, ct)
 
 			var result []byte
 			switch tt.op {
 			case "shl":
-				result = tfheShl(ct, tt.shift, TypeEuint8)
+				result = tfheShl(defaultTFHE(), ct, tt.shift, TypeEuint8)
 			case "shr":
-				result = tfheShr(ct, tt.shift, TypeEuint8)
+				result = tfheShr(defaultTFHE(), ct, tt.shift, TypeEuint8)
 			}
 			require.NotNil(t, result, "%s should succeed", tt.op)
 
@@ -388,12 +411,12 @@ func TestFHEMinMax(t *testing.T) {
 
 	// Test min: min(5, 3) = 3
 	t.Run("min", func(t *testing.T) {
-		ctA := tfheTrivialEncrypt(big.NewInt(5), TypeEuint8)
-		ctB := tfheTrivialEncrypt(big.NewInt(3), TypeEuint8)
+		ctA := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(5), TypeEuint8)
+		ctB := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(3), TypeEuint8)
 		require.NotNil(t, ctA)
 		require.NotNil(t, ctB)
 
-		result := tfheMin(ctA, ctB, TypeEuint8)
+		result := tfheMin(defaultTFHE(), ctA, ctB, TypeEuint8)
 		require.NotNil(t, result, "min should succeed")
 
 		decrypted := tfheDecrypt(result, TypeEuint8)
@@ -402,12 +425,12 @@ func TestFHEMinMax(t *testing.T) {
 
 	// Test max: max(3, 5) = 5
 	t.Run("max", func(t *testing.T) {
-		ctA := tfheTrivialEncrypt(big.NewInt(3), TypeEuint8)
-		ctB := tfheTrivialEncrypt(big.NewInt(5), TypeEuint8)
+		ctA := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(3), TypeEuint8)
+		ctB := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(5), TypeEuint8)
 		require.NotNil(t, ctA)
 		require.NotNil(t, ctB)
 
-		result := tfheMax(ctA, ctB, TypeEuint8)
+		result := tfheMax(defaultTFHE(), ctA, ctB, TypeEuint8)
 		require.NotNil(t, result, "max should succeed")
 
 		decrypted := tfheDecrypt(result, TypeEuint8)
@@ -434,10 +457,10 @@ func TestFHEScalarAdd(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ct := tfheTrivialEncrypt(big.NewInt(int64(tt.value)), TypeEuint8)
+			ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.value)), TypeEuint8)
 			require.NotNil(t, ct)
 
-			result := tfheScalarAdd(ct, tt.scalar, TypeEuint8)
+			result := tfheScalarAdd(defaultTFHE(), ct, tt.scalar, TypeEuint8)
 			require.NotNil(t, result)
 
 			decrypted := tfheDecrypt(result, TypeEuint8)
@@ -465,10 +488,10 @@ func TestFHEScalarMul(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ct := tfheTrivialEncrypt(big.NewInt(int64(tt.value)), TypeEuint8)
+			ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(int64(tt.value)), TypeEuint8)
 			require.NotNil(t, ct)
 
-			result := tfheScalarMul(ct, tt.scalar, TypeEuint8)
+			result := tfheScalarMul(defaultTFHE(), ct, tt.scalar, TypeEuint8)
 			require.NotNil(t, result)
 
 			decrypted := tfheDecrypt(result, TypeEuint8)
@@ -477,16 +500,154 @@ func TestFHEScalarMul(t *testing.T) {
 	}
 }
 
+// TestFHEScalarCompare tests comparisons against plaintext scalars
+func TestFHEScalarCompare(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(5), TypeEuint8)
+	require.NotNil(t, ct)
+
+	tests := []struct {
+		op       string
+		scalar   int64
+		expected uint64
+	}{
+		{"scalarLt", 6, 1},
+		{"scalarLt", 5, 0},
+		{"scalarLe", 5, 1},
+		{"scalarLe", 4, 0},
+		{"scalarGt", 4, 1},
+		{"scalarGt", 5, 0},
+		{"scalarGe", 5, 1},
+		{"scalarGe", 6, 0},
+		{"scalarEq", 5, 1},
+		{"scalarEq", 6, 0},
+		{"scalarNe", 6, 1},
+		{"scalarNe", 5, 0},
+		// The scalar's bits are used at the operand's full width
+		{"scalarLt", 0, 0},
+		{"scalarGe", 0, 1},
+		{"scalarLe", 255, 1},
+		{"scalarGt", 255, 0},
+		{"scalarEq", 255, 0},
+		{"scalarNe", 133, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s_%d", tt.op, tt.scalar), func(t *testing.T) {
+			result, resultType := computeFHEScalarOperation(defaultTFHE(), tt.op, ct, big.NewInt(tt.scalar), TypeEuint8)
+			require.NotNil(t, result)
+			require.Equal(t, TypeEbool, resultType)
+
+			decrypted := tfheDecrypt(result, TypeEbool)
+			require.Equal(t, tt.expected, decrypted.Uint64())
+		})
+	}
+
+	// Scalars wider than the operand type are rejected
+	result, _ := computeFHEScalarOperation(defaultTFHE(), "scalarLt", ct, big.NewInt(256), TypeEuint8)
+	require.Nil(t, result)
+
+	// Through the precompile the result handle is stored as an ebool
+	handle := storeCiphertext(ciphertexts, ct, TypeEuint8)
+	input := append([]byte("\x2d\x03\x8f\x2f"), handle.Bytes()...)
+	input = append(input, common.BigToHash(big.NewInt(6)).Bytes()...)
+	c := &FHEContract{}
+	require.Equal(t, GasLt, c.Gas(input))
+	ret, remaining, err := c.Run(nil, common.Address{}, ContractAddress, input, GasLt, false)
+	require.NoError(t, err)
+	require.Zero(t, remaining)
+	_, ctType, ok := getCiphertext(ciphertexts, common.BytesToHash(ret))
+	require.True(t, ok)
+	require.Equal(t, TypeEbool, ctType)
+
+	input = append(input[:36], common.BigToHash(big.NewInt(300)).Bytes()...)
+	_, _, err = c.Run(nil, common.Address{}, ContractAddress, input, GasLt, false)
+	require.ErrorIs(t, err, ErrScalarOutOfRange)
+}
+
+// TestFHEMulDiv tests the mulDiv family and its rounding modes
+func TestFHEMulDiv(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		a, b, d  int64
+		rounding uint8
+		expected uint64
+	}{
+		{"floor", 7, 3, 2, RoundFloor, 10},
+		{"ceil", 7, 3, 2, RoundCeil, 11},
+		{"nearest_half", 7, 3, 2, RoundNearest, 11},
+		{"nearest_down", 7, 3, 4, RoundNearest, 5},
+		{"ceil_exact", 6, 4, 3, RoundCeil, 8},
+		{"wide_product", 200, 200, 250, RoundFloor, 160}, // 40000 overflows euint8
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(tt.a), TypeEuint8)
+			b := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(tt.b), TypeEuint8)
+			d := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(tt.d), TypeEuint8)
+
+			// Encrypted and plaintext operands agree
+			for _, result := range [][]byte{
+				computeMulDiv(a, b, nil, nil, big.NewInt(tt.d), TypeEuint8, tt.rounding),
+				computeMulDiv(a, nil, big.NewInt(tt.b), nil, big.NewInt(tt.d), TypeEuint8, tt.rounding),
+				computeMulDiv(a, nil, big.NewInt(tt.b), d, nil, TypeEuint8, tt.rounding),
+			} {
+				require.NotNil(t, result)
+				require.Equal(t, tt.expected, tfheDecrypt(result, TypeEuint8).Uint64())
+			}
+		})
+	}
+
+	c := &FHEContract{}
+	h7 := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(7), TypeEuint8), TypeEuint8)
+	h2 := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(2), TypeEuint8), TypeEuint8)
+	h16 := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(2), TypeEuint16), TypeEuint16)
+
+	packed := func(selector string, a common.Hash, b common.Hash, word int64, rounding uint8) []byte {
+		input := append([]byte(selector), a.Bytes()...)
+		input = append(input, b.Bytes()...)
+		input = append(input, common.BigToHash(big.NewInt(word)).Bytes()...)
+		return append(input, rounding)
+	}
+
+	// scaledDiv: 7 * 10 / 2
+	input := packed("\x2a\xd4\x7e\x9c", h7, h2, 10, RoundFloor)
+	require.Equal(t, GasMulDiv, c.Gas(input))
+	ret, _, err := c.Run(nil, common.Address{}, ContractAddress, input, GasMulDiv, false)
+	require.NoError(t, err)
+	ct, ctType, ok := getCiphertext(ciphertexts, common.BytesToHash(ret))
+	require.True(t, ok)
+	require.Equal(t, TypeEuint8, ctType)
+	require.Equal(t, uint64(35), tfheDecrypt(ct, TypeEuint8).Uint64())
+
+	// Plaintext divisor of zero, unknown rounding mode, mixed types and a
+	// scalar wider than the operand are rejected
+	_, _, err = c.Run(nil, common.Address{}, ContractAddress, packed("\x76\x88\x37\xeb", h7, h2, 0, RoundFloor), GasMulDiv, false)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	_, _, err = c.Run(nil, common.Address{}, ContractAddress, packed("\x76\x88\x37\xeb", h7, h2, 3, RoundNearest+1), GasMulDiv, false)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	_, _, err = c.Run(nil, common.Address{}, ContractAddress, packed("\x76\x88\x37\xeb", h7, h16, 3, RoundFloor), GasMulDiv, false)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	_, _, err = c.Run(nil, common.Address{}, ContractAddress, packed("\x2a\xd4\x7e\x9c", h7, h2, 1000, RoundFloor), GasMulDiv, false)
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
 // TestFHECast tests type casting
 func TestFHECast(t *testing.T) {
 	err := initTFHE()
 	require.NoError(t, err)
 
 	// Cast uint8 to uint16
-	ct8 := tfheTrivialEncrypt(big.NewInt(42), TypeEuint8)
+	ct8 := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(42), TypeEuint8)
 	require.NotNil(t, ct8)
 
-	ct16 := tfheCast(ct8, TypeEuint8, TypeEuint16)
+	ct16 := tfheCast(defaultTFHE(), ct8, TypeEuint8, TypeEuint16)
 	require.NotNil(t, ct16)
 
 	decrypted := tfheDecrypt(ct16, TypeEuint16)
@@ -509,6 +670,62 @@ func TestFHERandom(t *testing.T) {
 	require.True(t, decrypted.Uint64() <= 255)
 }
 
+// TestRandBounded tests draw seeding and bounded random values
+func TestRandBounded(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	// Draws in one transaction differ, replaying the transaction
+	// reproduces them, and the draw counter leaves no storage behind
+	caller := common.HexToAddress("0xa11ce")
+	seeds := func() [][]byte {
+		db := statetest.New()
+		db.SetTxHash(common.Hash{3})
+		state := &aclTestState{db: db}
+		drawn := [][]byte{randomSeed(state, caller), randomSeed(state, caller)}
+		require.Empty(t, db.Diff())
+		return drawn
+	}
+	first := seeds()
+	require.NotEqual(t, first[0], first[1])
+	require.Equal(t, first, seeds())
+
+	c := &FHEContract{}
+	draw := func(ctType uint8, bound uint64) ([]byte, error) {
+		input := append([]byte("\xd5\x92\xa8\x3b"), ctType)
+		input = append(input, common.BigToHash(new(big.Int).SetUint64(bound)).Bytes()...)
+		ret, _, err := c.Run(nil, caller, ContractAddress, input, GasRandBounded, false)
+		return ret, err
+	}
+	for _, tt := range []struct {
+		ctType uint8
+		bound  uint64
+	}{
+		{TypeEuint8, 10}, {TypeEuint8, 4}, {TypeEuint4, 16}, {TypeEuint64, 1000},
+	} {
+		for i := 0; i < 3; i++ {
+			ret, err := draw(tt.ctType, tt.bound)
+			require.NoError(t, err)
+			ct, ctType, ok := getCiphertext(ciphertexts, common.BytesToHash(ret))
+			require.True(t, ok)
+			require.Equal(t, tt.ctType, ctType)
+			require.Less(t, tfheDecrypt(ct, ctType).Uint64(), tt.bound)
+		}
+	}
+
+	// Zero bounds, bounds above the type's range and non-integer types are
+	// rejected
+	for _, tt := range []struct {
+		ctType uint8
+		bound  uint64
+	}{
+		{TypeEuint8, 0}, {TypeEuint4, 17}, {TypeEbool, 2},
+	} {
+		_, err := draw(tt.ctType, tt.bound)
+		require.ErrorIs(t, err, ErrInvalidInput)
+	}
+}
+
 // TestGetNetworkPublicKey tests public key retrieval
 func TestGetNetworkPublicKey(t *testing.T) {
 	err := initTFHE()
@@ -525,20 +742,88 @@ func TestCiphertextStore(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create ciphertext
-	ct := tfheTrivialEncrypt(big.NewInt(42), TypeEuint8)
+	ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(42), TypeEuint8)
 	require.NotNil(t, ct)
 
 	// Store it
-	handle := storeCiphertext(ct, TypeEuint8)
+	handle := storeCiphertext(ciphertexts, ct, TypeEuint8)
 	require.NotEqual(t, common.Hash{}, handle)
 
 	// Retrieve it
-	retrieved, ctType, ok := getCiphertext(handle)
+	retrieved, ctType, ok := getCiphertext(ciphertexts, handle)
 	require.True(t, ok)
 	require.Equal(t, TypeEuint8, ctType)
 	require.Equal(t, ct, retrieved)
 }
 
+// TestCiphertextStorageGas tests that calls pay for the ciphertext words
+// they store, and store nothing when they cannot
+func TestCiphertextStorageGas(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	c := &FHEContract{}
+	alice := common.HexToAddress("0xa11ce")
+	input := append([]byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(5)).Bytes()...)
+
+	db := statetest.New()
+	db.SetTxHash(common.Hash{5})
+	ret, remaining, err := c.Run(&aclTestState{db: db}, alice, ContractAddress, input, 10_000_000, false)
+	require.NoError(t, err)
+	h := common.BytesToHash(ret)
+	words := uint64(storedWords(db.GetState(ContractAddress, ciphertextHeaderSlot(h)))) + 1
+	used := 10_000_000 - remaining
+	require.GreaterOrEqual(t, used, words*GasStoreWord)
+
+	// Enough for the operation but not its storage
+	db = statetest.New()
+	db.SetTxHash(common.Hash{5})
+	_, _, err = c.Run(&aclTestState{db: db}, alice, ContractAddress, input, used-1, false)
+	require.ErrorIs(t, err, ErrInsufficientGas)
+	require.False(t, NewStateCiphertextStore(db).Has(h))
+}
+
+// TestStateCiphertextStore tests ciphertext persistence in a StateDB
+func TestStateCiphertextStore(t *testing.T) {
+	db := statetest.New()
+	store := NewStateCiphertextStore(db)
+
+	ct := []byte("a short ciphertext that spans more than one storage word")
+	handle := common.BytesToHash(ct)
+	store.Put(handle, ct, TypeEuint16)
+	require.True(t, store.Has(handle))
+
+	// A fresh store over the same state reads it back
+	retrieved, ctType, ok := NewStateCiphertextStore(db).Get(handle)
+	require.True(t, ok)
+	require.Equal(t, TypeEuint16, ctType)
+	require.Equal(t, ct, retrieved)
+
+	// Writes are reverted with the rest of the transaction
+	snap := db.Snapshot()
+	large := bytes.Repeat([]byte{0x5a}, 4*MinCompressSize)
+	largeHandle := common.BytesToHash(large)
+	store.Put(largeHandle, large, TypeEuint64)
+	retrieved, _, ok = store.Get(largeHandle)
+	require.True(t, ok)
+	require.Equal(t, large, retrieved)
+	db.RevertToSnapshot(snap)
+	require.False(t, store.Has(largeHandle))
+
+	// Overwriting with a shorter ciphertext clears the old words
+	store.Put(handle, []byte("short"), TypeEuint8)
+	retrieved, ctType, ok = store.Get(handle)
+	require.True(t, ok)
+	require.Equal(t, TypeEuint8, ctType)
+	require.Equal(t, []byte("short"), retrieved)
+	require.Equal(t, common.Hash{}, db.GetState(ContractAddress, ciphertextDataSlot(ciphertextHeaderSlot(handle), 1)))
+
+	store.Delete(handle)
+	require.False(t, store.Has(handle))
+	_, _, ok = store.Get(handle)
+	require.False(t, ok)
+}
+
 // TestPerformFHEOperation tests the high-level operation dispatcher
 func TestPerformFHEOperation(t *testing.T) {
 	err := initTFHE()
@@ -547,17 +832,18 @@ func TestPerformFHEOperation(t *testing.T) {
 	caller := common.HexToAddress("0x1234567890123456789012345678901234567890")
 
 	// Store two ciphertexts
-	ct1 := tfheTrivialEncrypt(big.NewInt(10), TypeEuint8)
-	ct2 := tfheTrivialEncrypt(big.NewInt(3), TypeEuint8)
+	ct1 := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(10), TypeEuint8)
+	ct2 := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(3), TypeEuint8)
 
-	handle1 := storeCiphertext(ct1, TypeEuint8)
-	handle2 := storeCiphertext(ct2, TypeEuint8)
+	handle1 := storeCiphertext(ciphertexts, ct1, TypeEuint8)
+	handle2 := storeCiphertext(ciphertexts, ct2, TypeEuint8)
 
 	// Test add operation
-	resultHandle := performFHEOperation("add", handle1, handle2, caller)
+	resultHandle, err := performFHEOperation(ciphertexts, "add", handle1, handle2, caller)
+	require.NoError(t, err)
 	require.NotEqual(t, common.Hash{}, resultHandle)
 
-	resultCt, _, ok := getCiphertext(resultHandle)
+	resultCt, _, ok := getCiphertext(ciphertexts, resultHandle)
 	require.True(t, ok)
 
 	decrypted := tfheDecrypt(resultCt, TypeEuint8)
@@ -571,10 +857,11 @@ func TestEncryptValue(t *testing.T) {
 
 	caller := common.HexToAddress("0x1234567890123456789012345678901234567890")
 
-	handle := encryptValue(42, TypeEuint8, caller)
+	handle, err := encryptValue(ciphertexts, 42, TypeEuint8, caller)
+	require.NoError(t, err)
 	require.NotEqual(t, common.Hash{}, handle)
 
-	ct, ctType, ok := getCiphertext(handle)
+	ct, ctType, ok := getCiphertext(ciphertexts, handle)
 	require.True(t, ok)
 	require.Equal(t, TypeEuint8, ctType)
 
@@ -592,10 +879,11 @@ func TestEncryptAddress(t *testing.T) {
 	// Full 160-bit addresses require proper radix integer encryption
 	addr := common.HexToAddress("0x0000000000000000000000000000000012345678")
 
-	handle := encryptAddress(addr, caller)
+	handle, err := encryptAddress(ciphertexts, addr, caller)
+	require.NoError(t, err)
 	require.NotEqual(t, common.Hash{}, handle)
 
-	ct, ctType, ok := getCiphertext(handle)
+	ct, ctType, ok := getCiphertext(ciphertexts, handle)
 	require.True(t, ok)
 	require.Equal(t, TypeEaddress, ctType)
 
@@ -612,7 +900,7 @@ func TestFHEVerify(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create valid ciphertext
-	ct := tfheTrivialEncrypt(big.NewInt(42), TypeEuint8)
+	ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(42), TypeEuint8)
 	require.NotNil(t, ct)
 
 	// Verify should succeed for valid ciphertext
@@ -624,3 +912,1815 @@ func TestFHEVerify(t *testing.T) {
 	invalid := tfheVerify(garbage, TypeEuint8)
 	require.False(t, invalid)
 }
+
+// TestInputProof tests proof-checked input ciphertexts through verify
+func TestInputProof(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	keys := make([]*ecdsa.PrivateKey, 2)
+	attestors := make([]common.Address, 2)
+	for i := range keys {
+		key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+		require.NoError(t, err)
+		keys[i] = key
+		attestors[i] = common.BytesToAddress(crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey)[1:])[12:])
+	}
+	// Input attestors are required and installed by the module config
+	require.ErrorIs(t, NewConfig(nil).Verify(nil), ErrNoInputVerifier)
+	require.NoError(t, NewDisableConfig(nil).Verify(nil))
+	config := &Config{InputAttestors: attestors, InputThreshold: 2}
+	require.NoError(t, config.Verify(nil))
+	require.NoError(t, (&configurator{}).Configure(nil, config, nil, nil))
+	t.Cleanup(func() { SetInputVerifier(nil) })
+
+	c := &FHEContract{}
+	caller := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(7), TypeEuint8)
+	prove := func(ctType uint8, bits uint16, nonce byte, signers int) []byte {
+		proof := &InputProof{Version: 1, Scheme: ProofSchemeAttested, CtType: ctType, Bits: bits, Nonce: common.Hash{nonce}}
+		digest := InputDigest(ct, ctType, caller, proof.Nonce)
+		proof.Body = []byte{byte(signers)}
+		for _, key := range keys[:signers] {
+			sig, err := crypto.Sign(digest.Bytes(), key)
+			require.NoError(t, err)
+			proof.Body = append(proof.Body, sig...)
+		}
+		return append([]byte{ctType}, EncodeInput(ct, proof)...)
+	}
+	verify := func(from common.Address, data []byte) ([]byte, error) {
+		ret, _, err := c.Run(nil, from, ContractAddress, append([]byte("\x45\xa9\x32\x18"), data...), GasVerifyInput, false)
+		return ret, err
+	}
+
+	input := prove(TypeEuint8, 8, 1, 2)
+	ret, err := verify(caller, input)
+	require.NoError(t, err)
+	stored, ctType, ok := getCiphertext(ciphertexts, common.BytesToHash(ret))
+	require.True(t, ok)
+	require.Equal(t, TypeEuint8, ctType)
+	require.Equal(t, ct, stored)
+
+	// Replays are rejected, also from another caller
+	_, err = verify(caller, input)
+	require.ErrorIs(t, err, ErrProofReplayed)
+	_, err = verify(common.Address{1}, prove(TypeEuint8, 8, 2, 2))
+	require.ErrorIs(t, err, ErrAttestation)
+
+	// The header must match the claimed type
+	_, err = verify(caller, prove(TypeEuint8, 16, 3, 2))
+	require.ErrorIs(t, err, ErrInvalidProof)
+	_, err = verify(caller, append([]byte{TypeEuint16}, prove(TypeEuint8, 8, 3, 2)[1:]...))
+	require.ErrorIs(t, err, ErrInvalidProof)
+
+	// Below threshold, malformed and unknown schemes fail
+	_, err = verify(caller, prove(TypeEuint8, 8, 4, 1))
+	require.ErrorIs(t, err, ErrAttestation)
+	truncated := prove(TypeEuint8, 8, 5, 2)
+	_, err = verify(caller, truncated[:len(truncated)-1])
+	require.Error(t, err)
+	unknown := prove(TypeEuint8, 8, 6, 2)
+	unknown[1+len(ct)+1] = 2
+	_, err = verify(caller, unknown)
+	require.ErrorIs(t, err, ErrUnknownProofScheme)
+
+	// Without a committee every input is rejected
+	SetInputVerifier(nil)
+	_, err = verify(caller, prove(TypeEuint8, 8, 7, 2))
+	require.ErrorIs(t, err, ErrNoInputVerifier)
+}
+
+// TestFHEMaxWithIndex tests sealed-bid max and winner index computation
+func TestFHEMaxWithIndex(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping slow FHE auction test in short mode")
+	}
+
+	err := initTFHE()
+	require.NoError(t, err)
+
+	caller := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	bids := []uint64{7, 42, 13, 42}
+	handles := make([]common.Hash, len(bids))
+	for i, b := range bids {
+		handles[i], err = encryptValue(ciphertexts, b, TypeEuint8, caller)
+		require.NoError(t, err)
+	}
+
+	maxHandle, indexHandle, err := performFHEMaxWithIndex(ciphertexts, handles, caller)
+	require.NoError(t, err)
+	require.NotEqual(t, common.Hash{}, maxHandle)
+	require.NotEqual(t, common.Hash{}, indexHandle)
+
+	maxBid, err := performFHEDecrypt(ciphertexts, maxHandle, caller)
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), maxBid.Uint64())
+	// Ties resolve to the earliest bidder
+	index, err := performFHEDecrypt(ciphertexts, indexHandle, caller)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), index.Uint64())
+
+	// Mixed bid types are rejected
+	handles[2], err = encryptValue(ciphertexts, 13, TypeEuint16, caller)
+	require.NoError(t, err)
+	_, _, err = performFHEMaxWithIndex(ciphertexts, handles, caller)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+
+	// On chain anyone may request the decryption of the results, while the
+	// bids stay restricted to accounts the ACL allows
+	oracle, err := NewDecryptionOracle([]common.Address{{0x01}}, 1)
+	require.NoError(t, err)
+	SetDecryptionOracle(oracle)
+	t.Cleanup(func() { SetDecryptionOracle(nil) })
+
+	db := statetest.New()
+	db.SetTxHash(common.Hash{1})
+	state := &aclTestState{db: db}
+	c := &FHEContract{}
+	run := func(from common.Address, input []byte) ([]byte, error) {
+		ret, _, err := c.Run(state, from, ContractAddress, input, 10_000_000, false)
+		return ret, err
+	}
+	input := append([]byte("\x21\x72\x05\x96"), common.BigToHash(big.NewInt(32)).Bytes()...)
+	input = append(input, common.BigToHash(big.NewInt(int64(len(bids)))).Bytes()...)
+	for i, b := range bids {
+		ret, err := run(caller, append([]byte("\xa5\x17\x5c\x89"), common.BigToHash(new(big.Int).SetUint64(b)).Bytes()...))
+		require.NoError(t, err)
+		handles[i] = common.BytesToHash(ret)
+		input = append(input, ret...)
+	}
+	ret, err := run(caller, input)
+	require.NoError(t, err)
+
+	outsider := common.HexToAddress("0xb0b")
+	requestDecryption := func(h common.Hash) error {
+		_, err := run(outsider, append(append([]byte("\x90\xaa\x1b\x60"), h.Bytes()...), make([]byte, 32)...))
+		return err
+	}
+	require.NoError(t, requestDecryption(common.BytesToHash(ret[:32])))
+	require.NoError(t, requestDecryption(common.BytesToHash(ret[32:])))
+	require.ErrorIs(t, requestDecryption(handles[0]), ErrACLDenied)
+	_, err = run(outsider, append([]byte("\xe4\x7e\xf3\xfc"), ret[:32]...))
+	require.ErrorIs(t, err, ErrACLDenied)
+}
+
+// TestFHESelectN tests multi-way selection by an encrypted index
+func TestFHESelectN(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping slow FHE selectN test in short mode")
+	}
+
+	err := initTFHE()
+	require.NoError(t, err)
+
+	encrypt := func(v int64, ctType uint8) common.Hash {
+		return storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(v), ctType), ctType)
+	}
+	candidates := []common.Hash{
+		encrypt(10, TypeEuint16), encrypt(20, TypeEuint16), encrypt(30, TypeEuint16),
+		encrypt(40, TypeEuint16), encrypt(50, TypeEuint16),
+	}
+	input := func(index common.Hash, candidates []common.Hash) []byte {
+		data := append([]byte("\x03\xfd\x4b\xcd"), index.Bytes()...)
+		data = append(data, common.BigToHash(big.NewInt(64)).Bytes()...)
+		data = append(data, common.BigToHash(big.NewInt(int64(len(candidates)))).Bytes()...)
+		for _, h := range candidates {
+			data = append(data, h.Bytes()...)
+		}
+		return data
+	}
+
+	c := &FHEContract{}
+	gas := c.Gas(input(common.Hash{}, candidates))
+	require.Equal(t, selectNGas(5), gas)
+	for index, expected := range map[int64]uint64{0: 10, 3: 40, 4: 50, 5: 0, 200: 0} {
+		ret, remaining, err := c.Run(nil, common.Address{}, ContractAddress, input(encrypt(index, TypeEuint8), candidates), gas, false)
+		require.NoError(t, err, index)
+		require.Zero(t, remaining)
+		ct, ctType, ok := getCiphertext(ciphertexts, common.BytesToHash(ret))
+		require.True(t, ok)
+		require.Equal(t, TypeEuint16, ctType)
+		require.Equal(t, expected, tfheDecrypt(ct, TypeEuint16).Uint64(), index)
+	}
+
+	// The index must be unsigned and wide enough, and candidates must share a type
+	wide := make([]common.Hash, 17)
+	for i := range wide {
+		wide[i] = candidates[0]
+	}
+	for _, bad := range [][]byte{
+		input(encrypt(1, TypeEbool), candidates[:2]),
+		input(encrypt(1, TypeEuint4), wide),
+	} {
+		_, _, err := c.Run(nil, common.Address{}, ContractAddress, bad, c.Gas(bad), false)
+		require.ErrorIs(t, err, ErrInvalidInput)
+	}
+	bad := input(encrypt(1, TypeEuint8), append([]common.Hash{encrypt(1, TypeEuint8)}, candidates...))
+	_, _, err = c.Run(nil, common.Address{}, ContractAddress, bad, c.Gas(bad), false)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	_, _, err = c.Run(nil, common.Address{}, ContractAddress, input(encrypt(1, TypeEuint8), nil), gas, false)
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestFHEShiftEnc tests shifts and rotations by encrypted amounts
+func TestFHEShiftEnc(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping slow FHE shift test in short mode")
+	}
+
+	err := initTFHE()
+	require.NoError(t, err)
+
+	encrypt := func(v int64, ctType uint8) common.Hash {
+		return storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(v), ctType), ctType)
+	}
+	a := encrypt(0b10010110, TypeEuint8)
+
+	c := &FHEContract{}
+	for _, tc := range []struct {
+		selector string
+		expected uint64
+	}{
+		{"\x66\x7b\x3d\x14", 0b10110000}, // shlEnc
+		{"\xa2\x02\xa8\x92", 0b00010010}, // shrEnc
+		{"\x50\xa2\x67\x27", 0b10110100}, // rotlEnc
+		{"\x29\xb5\x2a\xcf", 0b11010010}, // rotrEnc
+	} {
+		// The amount is taken modulo the width, from an amount of any unsigned type
+		for _, amount := range []common.Hash{encrypt(3, TypeEuint8), encrypt(11, TypeEuint16)} {
+			input := append(append([]byte(tc.selector), a.Bytes()...), amount.Bytes()...)
+			require.Equal(t, shiftEncGas(maxShiftDepth), c.Gas(input))
+			ret, remaining, err := c.Run(nil, common.Address{}, ContractAddress, input, shiftEncGas(3), false)
+			require.NoError(t, err)
+			require.Zero(t, remaining)
+			ct, ctType, ok := getCiphertext(ciphertexts, common.BytesToHash(ret))
+			require.True(t, ok)
+			require.Equal(t, TypeEuint8, ctType)
+			require.Equal(t, tc.expected, tfheDecrypt(ct, TypeEuint8).Uint64())
+		}
+	}
+
+	// Both operands must be unsigned
+	for _, bad := range [][2]common.Hash{{a, encrypt(1, TypeEbool)}, {encrypt(1, TypeEbool), a}} {
+		input := append(append([]byte("\x66\x7b\x3d\x14"), bad[0].Bytes()...), bad[1].Bytes()...)
+		_, _, err := c.Run(nil, common.Address{}, ContractAddress, input, c.Gas(input), false)
+		require.ErrorIs(t, err, ErrInvalidInput)
+	}
+}
+
+// TestDecodeHandleArray tests ABI decoding of bytes32[] inputs
+func TestDecodeHandleArray(t *testing.T) {
+	data := make([]byte, 32*4)
+	data[31] = 0x20 // offset
+	data[63] = 2    // length
+	data[95] = 0xaa
+	data[127] = 0xbb
+
+	handles, err := decodeHandleArray(data)
+	require.NoError(t, err)
+	require.Len(t, handles, 2)
+	require.Equal(t, byte(0xaa), handles[0][31])
+	require.Equal(t, byte(0xbb), handles[1][31])
+
+	_, err = decodeHandleArray(data[:96])
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestAnalyzeDependencies tests grouping of independent operations into levels
+func TestAnalyzeDependencies(t *testing.T) {
+	a := HandleOperand(common.Hash{0x01})
+	b := HandleOperand(common.Hash{0x02})
+
+	ops := []ParallelOp{
+		{Op: "add", Inputs: []Operand{a, b}},                               // 0
+		{Op: "mul", Inputs: []Operand{a, a}},                               // 1
+		{Op: "not", Inputs: []Operand{ResultOperand(0)}},                   // 2
+		{Op: "sub", Inputs: []Operand{ResultOperand(2), ResultOperand(1)}}, // 3
+		{Op: "scalarAdd", Inputs: []Operand{b}, Scalar: big.NewInt(1)},     // 4
+	}
+
+	levels, err := AnalyzeDependencies(ops)
+	require.NoError(t, err)
+	require.Equal(t, [][]int{{0, 1, 4}, {2}, {3}}, levels)
+
+	// Forward and self references are rejected
+	_, err = AnalyzeDependencies([]ParallelOp{{Op: "not", Inputs: []Operand{ResultOperand(0)}}})
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	// Wrong arity is rejected
+	_, err = AnalyzeDependencies([]ParallelOp{{Op: "add", Inputs: []Operand{a}}})
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestParallelExecutor tests that batched execution matches serial results
+func TestParallelExecutor(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	h10 := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(10), TypeEuint8), TypeEuint8)
+	h3 := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(3), TypeEuint8), TypeEuint8)
+	h7 := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(7), TypeEuint8), TypeEuint8)
+
+	ops := []ParallelOp{
+		{Op: "add", Inputs: []Operand{HandleOperand(h10), HandleOperand(h3)}},                    // 13
+		{Op: "scalarAdd", Inputs: []Operand{HandleOperand(h7)}, Scalar: big.NewInt(2)},           // 9
+		{Op: "gt", Inputs: []Operand{ResultOperand(0), ResultOperand(1)}},                        // true
+		{Op: "select", Inputs: []Operand{ResultOperand(2), ResultOperand(0), HandleOperand(h7)}}, // 13
+	}
+
+	handles, err := NewParallelExecutor(4).Execute(ciphertexts, ops)
+	require.NoError(t, err)
+	require.Len(t, handles, len(ops))
+
+	expected := []uint64{13, 9, 1, 13}
+	for i, h := range handles {
+		ct, ctType, ok := getCiphertext(ciphertexts, h)
+		require.True(t, ok)
+		require.Equal(t, expected[i], tfheDecrypt(ct, ctType).Uint64(), "op %d", i)
+	}
+
+	// A single worker produces the same plaintexts
+	serial, err := NewParallelExecutor(1).Execute(ciphertexts, ops)
+	require.NoError(t, err)
+	for i, h := range serial {
+		ct, ctType, ok := getCiphertext(ciphertexts, h)
+		require.True(t, ok)
+		require.Equal(t, expected[i], tfheDecrypt(ct, ctType).Uint64(), "serial op %d", i)
+	}
+
+	// A missing input fails the whole batch
+	_, err = NewParallelExecutor(2).Execute(ciphertexts, []ParallelOp{
+		{Op: "not", Inputs: []Operand{HandleOperand(common.Hash{0xde, 0xad})}},
+	})
+	require.ErrorIs(t, err, ErrOperationFailed)
+
+	// Mismatched operand types are rejected before anything is evaluated,
+	// including when one side is a result of another op
+	hb := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(1), TypeEbool), TypeEbool)
+	_, err = NewParallelExecutor(2).Execute(ciphertexts, []ParallelOp{
+		{Op: "add", Inputs: []Operand{HandleOperand(h10), HandleOperand(hb)}},
+	})
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = NewParallelExecutor(2).Execute(ciphertexts, []ParallelOp{
+		{Op: "lt", Inputs: []Operand{HandleOperand(h10), HandleOperand(h3)}},
+		{Op: "add", Inputs: []Operand{HandleOperand(h7), ResultOperand(0)}},
+	})
+	require.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+// TestRerandomize tests that rerandomize and refresh keep the plaintext
+// under a new handle
+func TestRerandomize(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	c := &FHEContract{}
+	h := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(42), TypeEuint8), TypeEuint8)
+	hb := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(1), TypeEbool), TypeEbool)
+
+	for _, tt := range []struct {
+		name     string
+		selector string
+		gas      uint64
+	}{
+		{"rerandomize", "\x71\xee\x46\xe9", GasRerandomize},
+		{"refresh", "\xdc\x4c\xfa\x2f", GasRefresh},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, in := range []struct {
+				handle   common.Hash
+				ctType   uint8
+				expected uint64
+			}{{h, TypeEuint8, 42}, {hb, TypeEbool, 1}} {
+				input := append([]byte(tt.selector), in.handle.Bytes()...)
+				require.Equal(t, tt.gas, c.Gas(input))
+				ret, remaining, err := c.Run(nil, common.Address{1}, ContractAddress, input, tt.gas, false)
+				require.NoError(t, err)
+				require.Zero(t, remaining)
+
+				out := common.BytesToHash(ret)
+				require.NotEqual(t, in.handle, out)
+				ct, ctType, ok := getCiphertext(ciphertexts, out)
+				require.True(t, ok)
+				require.Equal(t, in.ctType, ctType)
+				require.Equal(t, in.expected, tfheDecrypt(ct, ctType).Uint64())
+			}
+
+			_, _, err := c.Run(nil, common.Address{1}, ContractAddress, append([]byte(tt.selector), make([]byte, 32)...), tt.gas, false)
+			require.ErrorIs(t, err, ErrInvalidCiphertext)
+		})
+	}
+
+	// The mask depends on the caller
+	seedA := rerandomizeSeed(nil, common.Address{1}, h)
+	seedB := rerandomizeSeed(nil, common.Address{2}, h)
+	require.NotEqual(t, seedA, seedB)
+	ct, _, _ := getCiphertext(ciphertexts, h)
+	require.NotEqual(t, tfheRerandomize(defaultTFHE(), ct, TypeEuint8, seedA), tfheRerandomize(defaultTFHE(), ct, TypeEuint8, seedB))
+}
+
+// TestBatch tests batch encoding and execution through the precompile
+func TestBatch(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	h10 := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(10), TypeEuint8), TypeEuint8)
+	h3 := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(3), TypeEuint8), TypeEuint8)
+
+	ops := []ParallelOp{
+		{Op: "add", Inputs: []Operand{HandleOperand(h10), HandleOperand(h3)}},                      // 13
+		{Op: "scalarMul", Inputs: []Operand{ResultOperand(0)}, Scalar: big.NewInt(2)},              // 26
+		{Op: "scalarGt", Inputs: []Operand{ResultOperand(1)}, Scalar: big.NewInt(20)},              // true
+		{Op: "shr", Inputs: []Operand{ResultOperand(1)}, Scalar: big.NewInt(1)},                    // 13
+		{Op: "cast", Inputs: []Operand{ResultOperand(3)}, ToType: TypeEuint16},                     // 13
+		{Op: "select", Inputs: []Operand{ResultOperand(2), HandleOperand(h3), HandleOperand(h10)}}, // 3
+	}
+	data, err := EncodeBatch(ops)
+	require.NoError(t, err)
+	decoded, err := DecodeBatch(data)
+	require.NoError(t, err)
+	require.Equal(t, ops, decoded)
+
+	c := &FHEContract{}
+	input := append([]byte("\x26\x88\x7f\x26"), data...)
+	gas := c.Gas(input)
+	require.Equal(t, GasAdd+GasMul+GasGt+GasShr+GasCast+GasSelect, gas)
+
+	ret, remaining, err := c.Run(nil, common.Address{}, ContractAddress, input, gas, false)
+	require.NoError(t, err)
+	require.Zero(t, remaining)
+	handles, err := decodeHandleArray(ret)
+	require.NoError(t, err)
+	require.Len(t, handles, len(ops))
+
+	expected := []struct {
+		value  uint64
+		ctType uint8
+	}{{13, TypeEuint8}, {26, TypeEuint8}, {1, TypeEbool}, {13, TypeEuint8}, {13, TypeEuint16}, {3, TypeEuint8}}
+	for i, h := range handles {
+		ct, ctType, ok := getCiphertext(ciphertexts, h)
+		require.True(t, ok)
+		require.Equal(t, expected[i].ctType, ctType, "op %d", i)
+		require.Equal(t, expected[i].value, tfheDecrypt(ct, ctType).Uint64(), "op %d", i)
+	}
+
+	// Malformed lists are rejected before any work is charged
+	for name, bad := range map[string][]byte{
+		"empty":     {0, 0},
+		"truncated": data[:len(data)-1],
+		"trailing":  append(append([]byte(nil), data...), 0),
+		"forward":   mustEncodeBatch(t, []ParallelOp{{Op: "not", Inputs: []Operand{ResultOperand(1)}}, {Op: "not", Inputs: []Operand{HandleOperand(h3)}}}),
+	} {
+		_, remaining, err := c.Run(nil, common.Address{}, ContractAddress, append([]byte("\x26\x88\x7f\x26"), bad...), gas, false)
+		require.ErrorIs(t, err, ErrInvalidInput, name)
+		require.Equal(t, gas, remaining, name)
+	}
+
+	// Only single-result operations may be batched
+	decrypt := append([]byte{0, 1}, "\x12\x3d\x4c\x87"...)
+	_, err = DecodeBatch(append(decrypt, append([]byte{batchHandle}, h3.Bytes()...)...))
+	require.ErrorIs(t, err, ErrNotImplemented)
+	_, err = EncodeBatch([]ParallelOp{{Op: "decrypt", Inputs: []Operand{HandleOperand(h3)}}})
+	require.ErrorIs(t, err, ErrNotImplemented)
+}
+
+func mustEncodeBatch(t *testing.T, ops []ParallelOp) []byte {
+	data, err := EncodeBatch(ops)
+	require.NoError(t, err)
+	return data
+}
+
+// TestCoprocessorJobs tests queuing operations and finalizing attested results
+func TestCoprocessorJobs(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	attestors := make([]common.Address, 3)
+	for i := range keys {
+		key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+		require.NoError(t, err)
+		keys[i] = key
+		attestors[i] = common.BytesToAddress(crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey)[1:])[12:])
+	}
+	sign := func(key *ecdsa.PrivateKey, handle common.Hash, ct []byte) []byte {
+		sig, err := crypto.Sign(ResultDigest(handle, ct).Bytes(), key)
+		require.NoError(t, err)
+		return sig
+	}
+
+	_, err := NewCoprocessor(attestors, 4)
+	require.ErrorIs(t, err, ErrInvalidAttestors)
+	_, err = NewCoprocessor([]common.Address{attestors[0], attestors[0]}, 1)
+	require.ErrorIs(t, err, ErrInvalidAttestors)
+
+	cp, err := NewCoprocessor(attestors, 2)
+	require.NoError(t, err)
+	SetCoprocessor(cp)
+	t.Cleanup(func() { SetCoprocessor(nil) })
+
+	db := statetest.New()
+	db.SetTxHash(common.Hash{3})
+	store := NewStateCiphertextStore(db)
+	caller := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	a := storeCiphertext(store, []byte("coprocessor input a"), TypeEuint32)
+	b := storeCiphertext(store, []byte("coprocessor input b"), TypeEuint32)
+
+	// Operations queue jobs instead of computing, chaining on pending handles
+	sum, err := performFHEOperation(store, "add", a, b, caller)
+	require.NoError(t, err)
+	cmp, err := performFHEOperation(store, "gt", sum, a, caller)
+	require.NoError(t, err)
+	again, err := performFHEOperation(store, "add", a, b, caller)
+	require.NoError(t, err)
+	require.NotEqual(t, sum, again)
+	_, err = performFHEOperation(store, "add", a, common.Hash{0xde, 0xad}, caller)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	job, ok := cp.Job(db, cmp)
+	require.True(t, ok)
+	require.Equal(t, TypeEbool, job.ResultType)
+	require.Equal(t, []common.Hash{sum, a}, job.Inputs)
+	require.Len(t, cp.Pending(db, 0), 3)
+
+	// Jobs live in state and are reverted with their transaction
+	snap := db.Snapshot()
+	_, err = performFHEOperation(store, "add", a, b, caller)
+	require.NoError(t, err)
+	require.Len(t, cp.Pending(db, 0), 4)
+	db.RevertToSnapshot(snap)
+	require.Len(t, cp.Pending(db, 0), 3)
+	_, err = performFHEOperation(ciphertexts, "add", a, b, caller)
+	require.ErrorIs(t, err, ErrCoprocessorNoState)
+	_, _, ok = getCiphertext(store, sum)
+	require.False(t, ok)
+
+	// Results finalize in dependency order
+	cmpCt := []byte("coprocessor result cmp")
+	err = cp.PostResult(db, store, cmp, cmpCt, [][]byte{sign(keys[0], cmp, cmpCt), sign(keys[1], cmp, cmpCt)})
+	require.ErrorIs(t, err, ErrJobInputsPending)
+
+	// One attestor, counted once, is below threshold
+	sumCt := []byte("coprocessor result sum")
+	sig0 := sign(keys[0], sum, sumCt)
+	err = cp.PostResult(db, store, sum, sumCt, [][]byte{sig0, sig0})
+	require.ErrorIs(t, err, ErrAttestation)
+
+	// Signatures over a different ciphertext do not count
+	err = cp.PostResult(db, store, sum, sumCt, [][]byte{sig0, sign(keys[1], sum, cmpCt)})
+	require.ErrorIs(t, err, ErrAttestation)
+
+	err = cp.PostResult(db, store, sum, sumCt, [][]byte{sig0, sign(keys[2], sum, sumCt)})
+	require.NoError(t, err)
+	require.Equal(t, JobFinalized, cp.Status(db, sum))
+	ct, ctType, ok := getCiphertext(store, sum)
+	require.True(t, ok)
+	require.Equal(t, TypeEuint32, ctType)
+	require.Equal(t, sumCt, ct)
+
+	err = cp.PostResult(db, store, sum, sumCt, [][]byte{sig0, sign(keys[2], sum, sumCt)})
+	require.ErrorIs(t, err, ErrJobFinalized)
+
+	// Finalize through the precompile
+	input := []byte("\x46\xbc\x87\xdc")
+	input = append(input, cmp.Bytes()...)
+	input = append(input, 2)
+	input = append(input, sign(keys[1], cmp, cmpCt)...)
+	input = append(input, sign(keys[2], cmp, cmpCt)...)
+	input = append(input, cmpCt...)
+
+	state := &aclTestState{db: db}
+	c := &FHEContract{}
+	gas := c.Gas(input)
+	require.Equal(t, GasPostComputeResult+2*GasPerAttestation, gas)
+	ret, remaining, err := c.Run(state, caller, ContractAddress, input, gas, false)
+	require.NoError(t, err)
+	require.Equal(t, cmp.Bytes(), ret)
+	require.Zero(t, remaining)
+
+	ret, _, err = c.Run(state, caller, ContractAddress, append([]byte("\xfd\x70\x2f\x86"), cmp.Bytes()...), GasComputeStatus, true)
+	require.NoError(t, err)
+	require.Equal(t, byte(JobFinalized), ret[31])
+	require.Len(t, cp.Pending(db, 0), 1)
+}
+
+// TestDecryptionOracle tests asynchronous decryption through the committee
+func TestDecryptionOracle(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	committee := make([]common.Address, 3)
+	for i := range keys {
+		key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+		require.NoError(t, err)
+		keys[i] = key
+		committee[i] = common.BytesToAddress(crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey)[1:])[12:])
+	}
+	sign := func(key *ecdsa.PrivateKey, id common.Hash, plaintext *big.Int) []byte {
+		sig, err := crypto.Sign(DecryptionDigest(id, plaintext).Bytes(), key)
+		require.NoError(t, err)
+		return sig
+	}
+
+	_, err = NewDecryptionOracle(committee, 0)
+	require.ErrorIs(t, err, ErrInvalidAttestors)
+
+	oracle, err := NewDecryptionOracle(committee, 2)
+	require.NoError(t, err)
+	SetDecryptionOracle(oracle)
+	t.Cleanup(func() { SetDecryptionOracle(nil) })
+
+	env := &callbackEnv{}
+	db := statetest.New()
+	db.SetTxHash(common.Hash{1})
+	state := &aclTestState{db: db, env: env}
+
+	c := &FHEContract{}
+	caller := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	ret, _, err := c.Run(state, caller, ContractAddress, append([]byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(42)).Bytes()...), 10_000_000, false)
+	require.NoError(t, err)
+	handle := common.BytesToHash(ret)
+	callback := []byte("\xca\x11\xba\xc4")
+
+	// Synchronous decryption is disabled
+	_, _, err = c.Run(state, caller, ContractAddress, append([]byte("\x12\x3d\x4c\x87"), handle.Bytes()...), GasDecryptRequest, false)
+	require.ErrorIs(t, err, ErrSyncDecryption)
+
+	request := func(h common.Hash) ([]byte, error) {
+		input := append([]byte("\x90\xaa\x1b\x60"), h.Bytes()...)
+		input = append(input, common.RightPadBytes(callback, 32)...)
+		ret, _, err := c.Run(state, caller, ContractAddress, input, 10_000_000, false)
+		return ret, err
+	}
+	_, err = request(common.Hash{0xde, 0xad})
+	require.ErrorIs(t, err, ErrACLDenied)
+	ret, err = request(handle)
+	require.NoError(t, err)
+	id := common.BytesToHash(ret)
+
+	// Requests live in state and are reverted with their transaction
+	req, ok := oracle.Request(db, id)
+	require.True(t, ok)
+	require.Equal(t, handle, req.Handle)
+	require.Equal(t, TypeEuint64, req.CtType)
+	require.Equal(t, caller, req.Requester)
+	require.Equal(t, [4]byte(callback), req.Callback)
+	require.Len(t, oracle.Pending(db, 0), 1)
+
+	snap := db.Snapshot()
+	_, err = request(handle)
+	require.NoError(t, err)
+	require.Len(t, oracle.Pending(db, 0), 2)
+	db.RevertToSnapshot(snap)
+	require.Len(t, oracle.Pending(db, 0), 1)
+
+	// Fulfillment needs a threshold of distinct committee signatures
+	plaintext := big.NewInt(42)
+	sig0 := sign(keys[0], id, plaintext)
+	_, _, err = oracle.FulfillDecryption(db, id, plaintext, [][]byte{sig0, sig0})
+	require.ErrorIs(t, err, ErrAttestation)
+	_, _, err = oracle.FulfillDecryption(db, id, big.NewInt(43), [][]byte{sig0, sign(keys[1], id, plaintext)})
+	require.ErrorIs(t, err, ErrAttestation)
+
+	input := append([]byte("\xce\x3e\x86\xd4"), id.Bytes()...)
+	input = append(input, common.BigToHash(plaintext).Bytes()...)
+	input = append(input, 2)
+	input = append(input, sig0...)
+	input = append(input, sign(keys[2], id, plaintext)...)
+	gas := c.Gas(input)
+	require.Equal(t, GasFulfillDecryption+2*GasPerAttestation, gas)
+
+	// The callback runs with the gas the fulfiller left over
+	ret, remaining, err := c.Run(state, common.Address{}, ContractAddress, input, gas+50_000, false)
+	require.NoError(t, err)
+	want := append(append(append([]byte(nil), callback...), id.Bytes()...), common.BigToHash(plaintext).Bytes()...)
+	require.Equal(t, want, ret)
+	require.Equal(t, []callbackCall{{caller, want, 50_000}}, env.calls)
+	require.Equal(t, uint64(50_000-callbackGasUsed), remaining)
+	require.Empty(t, oracle.Pending(db, 0))
+
+	ret, _, err = c.Run(state, caller, ContractAddress, append([]byte("\x67\x53\xc3\x9a"), id.Bytes()...), GasDecryptionStatus, true)
+	require.NoError(t, err)
+	require.Equal(t, byte(DecryptionFulfilled), ret[31])
+	req, ok = oracle.Request(db, id)
+	require.True(t, ok)
+	require.Equal(t, plaintext, req.Plaintext)
+
+	_, _, err = oracle.FulfillDecryption(db, id, plaintext, [][]byte{sig0, sign(keys[2], id, plaintext)})
+	require.ErrorIs(t, err, ErrRequestFulfilled)
+
+	// A failing callback still fulfills the request
+	env.err = errors.New("reverted")
+	ret, err = request(handle)
+	require.NoError(t, err)
+	id = common.BytesToHash(ret)
+	input = append([]byte("\xce\x3e\x86\xd4"), id.Bytes()...)
+	input = append(input, common.BigToHash(plaintext).Bytes()...)
+	input = append(input, 2)
+	input = append(input, sign(keys[0], id, plaintext)...)
+	input = append(input, sign(keys[1], id, plaintext)...)
+	_, _, err = c.Run(state, common.Address{}, ContractAddress, input, gas+50_000, false)
+	require.NoError(t, err)
+	require.Len(t, env.calls, 2)
+	require.Equal(t, DecryptionFulfilled, oracle.Status(db, id))
+}
+
+// callbackGasUsed is the gas callbackEnv charges per call
+const callbackGasUsed = 21_000
+
+type callbackCall struct {
+	target common.Address
+	input  []byte
+	gas    uint64
+}
+
+// callbackEnv records the contract calls a precompile makes
+type callbackEnv struct {
+	calls []callbackCall
+	err   error
+}
+
+func (e *callbackEnv) ReadOnly() bool { return false }
+
+func (e *callbackEnv) Call(addr common.Address, input []byte, gas uint64, _ *uint256.Int) ([]byte, uint64, error) {
+	e.calls = append(e.calls, callbackCall{addr, append([]byte(nil), input...), gas})
+	if gas < callbackGasUsed {
+		return nil, 0, errors.New("out of gas")
+	}
+	return nil, gas - callbackGasUsed, e.err
+}
+
+// TestCiphertextStoreChunking tests that large ciphertexts round-trip through
+// chunked, compressed storage and that identical ciphertexts are kept once
+func TestCiphertextStoreChunking(t *testing.T) {
+	store := NewCiphertextStore()
+
+	// Compressible payload spanning several chunks
+	ct := make([]byte, 3*CiphertextChunkSize+100)
+	for i := range ct {
+		ct[i] = byte(i % 7)
+	}
+	h1 := common.HexToHash("0x01")
+	store.Put(h1, ct, TypeEuint256)
+
+	got, ctType, ok := store.Get(h1)
+	require.True(t, ok)
+	require.Equal(t, TypeEuint256, ctType)
+	require.Equal(t, ct, got)
+
+	stats := store.Stats()
+	require.Equal(t, uint64(len(ct)), stats.LogicalBytes)
+	require.Less(t, stats.StoredBytes, stats.LogicalBytes)
+
+	// A second copy under another handle adds no chunks
+	h2 := common.HexToHash("0x02")
+	store.Put(h2, ct, TypeEuint256)
+	again := store.Stats()
+	require.Equal(t, stats.Chunks, again.Chunks)
+	require.Equal(t, stats.StoredBytes, again.StoredBytes)
+	require.Equal(t, 2*stats.LogicalBytes, again.LogicalBytes)
+
+	// Chunks are freed with their last reference
+	store.Delete(h1)
+	got, _, ok = store.Get(h2)
+	require.True(t, ok)
+	require.Equal(t, ct, got)
+	store.Delete(h2)
+	require.Equal(t, StorageStats{}, store.Stats())
+
+	// Incompressible data is stored raw
+	random := make([]byte, 2*MinCompressSize)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+	store.Put(h1, random, TypeEuint64)
+	got, _, ok = store.Get(h1)
+	require.True(t, ok)
+	require.Equal(t, random, got)
+	require.Equal(t, uint64(len(random)), store.Stats().StoredBytes)
+}
+
+// aclTestState is an AccessibleState over a test StateDB, in block number
+// when set
+type aclTestState struct {
+	db     *statetest.StateDB
+	number uint64
+	env    contract.PrecompileEnvironment
+}
+
+func (s *aclTestState) GetStateDB() contract.StateDB { return s.db }
+func (s *aclTestState) GetBlockContext() contract.BlockContext {
+	if s.number == 0 {
+		return nil
+	}
+	return testBlockContext(s.number)
+}
+func (s *aclTestState) GetConsensusContext() context.Context             { return context.Background() }
+func (s *aclTestState) GetChainConfig() precompileconfig.ChainConfig     { return nil }
+func (s *aclTestState) GetPrecompileEnv() contract.PrecompileEnvironment { return s.env }
+
+// TestACL tests handle permissions and their enforcement in Run
+func TestACL(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	db := statetest.New()
+	db.SetTxHash(common.Hash{1})
+	state := &aclTestState{db: db}
+	alice := common.HexToAddress("0xa11ce")
+	bob := common.HexToAddress("0xb0b")
+	ops, acl := &FHEContract{}, &ACLContract{}
+
+	call := func(c contract.StatefulPrecompiledContract, caller common.Address, selector [4]byte, args ...[]byte) ([]byte, error) {
+		input := selector[:]
+		for _, arg := range args {
+			input = append(input, arg...)
+		}
+		ret, _, err := c.Run(state, caller, common.Address{}, input, 10_000_000, false)
+		return ret, err
+	}
+	word := func(addr common.Address) []byte { return common.BytesToHash(addr.Bytes()).Bytes() }
+	aclSel := func(name string) [4]byte {
+		for _, m := range ACLMethods {
+			if m.Name == name {
+				return m.Selector
+			}
+		}
+		t.Fatalf("no ACL method %s", name)
+		return [4]byte{}
+	}
+
+	// The producer owns the result and may use it in this transaction
+	ret, err := call(ops, alice, sel("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(5)).Bytes())
+	require.NoError(t, err)
+	h := common.BytesToHash(ret)
+	ret, err = call(acl, bob, aclSel("getOwner"), h.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, word(alice), ret)
+
+	_, err = call(ops, bob, sel("\xe4\x7e\xf3\xfc"), h.Bytes())
+	require.ErrorIs(t, err, ErrACLDenied)
+	_, err = call(acl, bob, aclSel("allow"), h.Bytes(), word(bob))
+	require.ErrorIs(t, err, ErrACLDenied)
+
+	// A transient grant lasts for the transaction
+	_, err = call(acl, alice, aclSel("allowTransient"), h.Bytes(), word(bob))
+	require.NoError(t, err)
+	_, err = call(ops, bob, sel("\xe4\x7e\xf3\xfc"), h.Bytes())
+	require.NoError(t, err)
+
+	_, err = call(acl, alice, aclSel("allowThis"), h.Bytes())
+	require.NoError(t, err)
+	db.Commit()
+	db.SetTxHash(common.Hash{2})
+	ret, err = call(acl, alice, aclSel("isAllowed"), h.Bytes(), word(bob))
+	require.NoError(t, err)
+	require.Equal(t, common.Hash{}.Bytes(), ret)
+	ret, err = call(acl, bob, aclSel("isAllowed"), h.Bytes(), word(alice))
+	require.NoError(t, err)
+	require.Equal(t, aclTrue.Bytes(), ret)
+
+	// Persistent grants survive until the owner revokes them
+	_, err = call(acl, alice, aclSel("allow"), h.Bytes(), word(bob))
+	require.NoError(t, err)
+	_, err = call(ops, bob, sel("\x12\x3d\x4c\x87"), h.Bytes())
+	require.NoError(t, err)
+	_, err = call(acl, bob, aclSel("revoke"), h.Bytes(), word(bob))
+	require.ErrorIs(t, err, ErrNotHandleOwner)
+	_, err = call(acl, alice, aclSel("revoke"), h.Bytes(), word(bob))
+	require.NoError(t, err)
+	_, err = call(ops, bob, sel("\x12\x3d\x4c\x87"), h.Bytes())
+	require.ErrorIs(t, err, ErrACLDenied)
+
+	// Public handles are usable by anyone
+	_, err = call(acl, alice, aclSel("allowForAll"), h.Bytes())
+	require.NoError(t, err)
+	_, err = call(ops, bob, sel("\x12\x3d\x4c\x87"), h.Bytes())
+	require.NoError(t, err)
+
+	// Only views are allowed in static calls
+	revokeForAll := aclSel("revokeForAll")
+	_, _, err = acl.Run(state, alice, ACLContractAddress, append(revokeForAll[:], h.Bytes()...), 100000, true)
+	require.ErrorIs(t, err, ErrWriteProtection)
+
+	// Grants are reverted with the transaction
+	snap := db.Snapshot()
+	_, err = call(acl, alice, aclSel("transferOwnership"), h.Bytes(), word(bob))
+	require.NoError(t, err)
+	db.RevertToSnapshot(snap)
+	ret, err = call(acl, bob, aclSel("getOwner"), h.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, word(alice), ret)
+}
+
+// TestHandleMetadata tests the typeOf, exists and sizeOf queries
+func TestHandleMetadata(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	ct := tfheTrivialEncrypt(defaultTFHE(), big.NewInt(9), TypeEuint16)
+	unknown := common.Hash{0xee}
+	c := &FHEContract{}
+
+	for name, state := range map[string]*aclTestState{"memory": nil, "state": {db: statetest.New()}} {
+		t.Run(name, func(t *testing.T) {
+			var accessible contract.AccessibleState
+			if state != nil {
+				accessible = state
+			}
+			h := storeCiphertext(ciphertextStoreFor(accessible), ct, TypeEuint16)
+			query := func(selector string, handle common.Hash) ([]byte, error) {
+				// Metadata is not access controlled
+				ret, remaining, err := c.Run(accessible, common.HexToAddress("0xb0b"), ContractAddress, append([]byte(selector), handle.Bytes()...), GasHandleQuery, true)
+				if err == nil {
+					require.Zero(t, remaining)
+				}
+				return ret, err
+			}
+
+			ret, err := query("\x5a\x94\x61\x92", h)
+			require.NoError(t, err)
+			require.Equal(t, common.BigToHash(big.NewInt(int64(TypeEuint16))).Bytes(), ret)
+			ret, err = query("\x38\xa6\x99\xa4", h)
+			require.NoError(t, err)
+			require.Equal(t, aclTrue.Bytes(), ret)
+			ret, err = query("\xfd\x97\x0b\xfb", h)
+			require.NoError(t, err)
+			require.Equal(t, uint64(len(ct)), new(big.Int).SetBytes(ret).Uint64())
+
+			_, err = query("\x5a\x94\x61\x92", unknown)
+			require.ErrorIs(t, err, ErrInvalidCiphertext)
+			ret, err = query("\x38\xa6\x99\xa4", unknown)
+			require.NoError(t, err)
+			require.Equal(t, make([]byte, 32), ret)
+			ret, err = query("\xfd\x97\x0b\xfb", unknown)
+			require.NoError(t, err)
+			require.Equal(t, make([]byte, 32), ret)
+		})
+	}
+}
+
+// TestStaticCall tests that static calls compute without persisting
+func TestStaticCall(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	db := statetest.New()
+	db.SetTxHash(common.Hash{2})
+	state := &aclTestState{db: db}
+	alice := common.HexToAddress("0xa11ce")
+	c := &FHEContract{}
+
+	run := func(readOnly bool, input ...[]byte) ([]byte, uint64, error) {
+		var data []byte
+		for _, part := range input {
+			data = append(data, part...)
+		}
+		return c.Run(state, alice, ContractAddress, data, 10_000_000, readOnly)
+	}
+
+	ret, _, err := run(false, []byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(5)).Bytes())
+	require.NoError(t, err)
+	h := common.BytesToHash(ret)
+	db.Commit()
+
+	// Pure operations return a transient handle and leave no state behind
+	ret, remaining, err := run(true, []byte("\xa9\x05\x9c\xbb"), h.Bytes(), h.Bytes())
+	require.NoError(t, err)
+	require.Less(t, remaining, uint64(10_000_000))
+	require.NotEqual(t, common.Hash{}, common.BytesToHash(ret))
+	require.False(t, NewStateCiphertextStore(db).Has(common.BytesToHash(ret)))
+	require.Empty(t, db.Diff())
+
+	ret, _, err = run(true, []byte("\x12\x3d\x4c\x87"), h.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), new(big.Int).SetBytes(ret).Uint64())
+
+	// Methods with lasting effects are rejected before any gas is used
+	for name, input := range map[string][]byte{
+		"verify":            append([]byte("\x45\xa9\x32\x18"), make([]byte, 64)...),
+		"requestDecryption": append([]byte("\x90\xaa\x1b\x60"), make([]byte, 64)...),
+		"fulfillDecryption": append([]byte("\xce\x3e\x86\xd4"), make([]byte, 65)...),
+	} {
+		_, remaining, err := run(true, input)
+		require.ErrorIs(t, err, ErrStaticMutation, name)
+		require.Equal(t, uint64(10_000_000), remaining, name)
+	}
+
+	// In coprocessor mode results are queued jobs
+	cp, err := NewCoprocessor([]common.Address{{1}}, 1)
+	require.NoError(t, err)
+	SetCoprocessor(cp)
+	t.Cleanup(func() { SetCoprocessor(nil) })
+	_, _, err = run(true, []byte("\xa9\x05\x9c\xbb"), h.Bytes(), h.Bytes())
+	require.ErrorIs(t, err, ErrStaticMutation)
+	require.Empty(t, cp.Pending(db, 0))
+}
+
+// TestMethodTable tests the precompile dispatch table
+func TestMethodTable(t *testing.T) {
+	seen := make(map[[4]byte]string)
+	for _, m := range Methods {
+		require.NotNil(t, m.handler, m.Signature)
+		require.NotContains(t, seen, m.Selector, "%s collides with %s", m.Signature, seen[m.Selector])
+		seen[m.Selector] = m.Signature
+	}
+	for _, m := range ACLMethods {
+		require.NotNil(t, m.aclHandler, m.Signature)
+		require.Equal(t, crypto.Keccak256([]byte(m.Signature))[:4], m.Selector[:], m.Signature)
+	}
+
+	// Unknown selectors are not routed
+	c := &FHEContract{}
+	_, gas, err := c.Run(nil, common.Address{}, ContractAddress, []byte{0xde, 0xad, 0xbe, 0xef}, 1000, false)
+	require.ErrorIs(t, err, ErrNotImplemented)
+	require.Equal(t, uint64(1000), gas)
+}
+
+// TestGenerateSolidity tests the generated Solidity library
+func TestGenerateSolidity(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, GenerateSolidity(&buf))
+	lib := buf.String()
+
+	require.Contains(t, lib, "address internal constant PRECOMPILE = "+ContractAddress.Hex()+";")
+	require.Contains(t, lib, "address internal constant ACL = "+ACLContractAddress.Hex()+";")
+	for _, m := range append(Methods, ACLMethods...) {
+		if m.Class == OpSystem {
+			require.NotContains(t, lib, m.Signature)
+			continue
+		}
+		require.Contains(t, lib, fmt.Sprintf("bytes4(0x%x)", m.Selector), m.Signature)
+	}
+
+	// Typed wrappers per type class
+	require.Contains(t, lib, "function add(euint8 a, euint8 b) internal returns (euint8)")
+	require.NotContains(t, lib, "function add(ebool a, ebool b)")
+	require.Contains(t, lib, "function lt(euint64 a, euint64 b) internal returns (ebool)")
+	require.Contains(t, lib, "function and(ebool a, ebool b) internal returns (ebool)")
+	require.Contains(t, lib, "function asEuint32(euint8 a) internal returns (euint32)")
+	require.Contains(t, lib, "function asEaddress(bytes memory input) internal returns (eaddress)")
+	require.Contains(t, lib, "function decrypt(eaddress a) internal returns (address)")
+	require.Contains(t, lib, "function isAllowed(euint8 a, address account) internal view returns (bool)")
+	require.Contains(t, lib, "function requestDecryption(euint8 a, bytes4 callback) internal returns (bytes32)")
+	require.Contains(t, lib, "function scalarLt(euint32 a, uint256 b) internal returns (ebool)")
+	require.Contains(t, lib, "function mulDiv(euint128 a, euint128 b, uint256 c, Rounding rounding) internal returns (euint128)")
+	require.Contains(t, lib, "function batch(bytes memory ops) internal returns (bytes32[] memory)")
+	require.Contains(t, lib, "function rerandomize(eaddress a) internal returns (eaddress)")
+	require.Contains(t, lib, "function exists(bytes32 handle) internal view returns (bool)")
+	require.Contains(t, lib, "function epochOf(bytes32 handle) internal view returns (uint256)")
+	require.Contains(t, lib, "function pin(euint64 a) internal {")
+	require.Contains(t, lib, "function sealOutput(euint8 a, uint8 scheme, bytes memory publicKey) internal returns (bytes memory)")
+	require.Contains(t, lib, "function randEuint32(uint256 upperBound) internal returns (euint32)")
+	require.Contains(t, lib, "function selectN(euint32 index, euint8[] memory candidates) internal returns (euint8)")
+	require.Contains(t, lib, "function rotlEnc(euint64 a, euint8 amount) internal returns (euint64)")
+	require.Contains(t, lib, "type earray is bytes32;")
+	require.Contains(t, lib, "function require_(ebool condition) internal {")
+	require.Contains(t, lib, "function require_(ebool condition, string memory message) internal {")
+	require.Contains(t, lib, "function arrayNew(euint64[] memory elements) internal returns (earray)")
+	require.Contains(t, lib, "function arrayGetEuint64(earray a, euint32 index) internal returns (euint64)")
+	require.Contains(t, lib, "function arraySet(earray a, euint32 index, eaddress value) internal returns (earray)")
+	require.Contains(t, lib, "function arrayLength(earray a) internal view returns (uint256)")
+	require.Contains(t, lib, "function allow(earray a, address account) internal {")
+
+	// Fixed-point type over euint128
+	require.Contains(t, lib, "type efixed64x18 is bytes32;")
+	require.Contains(t, lib, "uint256 internal constant FIXED_ONE = 1e18;")
+	require.Contains(t, lib, "enum Rounding { Floor, Ceil, Nearest }")
+	require.Contains(t, lib, "function toEfixed64x18(euint64 a) internal returns (efixed64x18)")
+	require.Contains(t, lib, "function toEuint64(efixed64x18 a, Rounding rounding) internal returns (euint64)")
+	require.Contains(t, lib, "function mul(efixed64x18 a, efixed64x18 b, Rounding rounding) internal returns (efixed64x18)")
+	require.Contains(t, lib, "function lt(efixed64x18 a, efixed64x18 b) internal returns (ebool)")
+
+	// A table entry that disagrees with its op class fails generation
+	g := &solWriter{}
+	writeMethod(g, Method{Name: "add", Signature: "add(bytes32)", Args: unaryArgs, Result: ResultHandle, Class: OpBinary, Types: MaskUint})
+	require.Error(t, g.err)
+}
+
+// TestRevertData tests that failed calls return ABI-encoded revert reasons
+func TestRevertData(t *testing.T) {
+	c := &FHEContract{}
+	a := storeCiphertext(ciphertexts, []byte("revert data operand a"), TypeEuint8)
+	b := storeCiphertext(ciphertexts, []byte("revert data operand b"), TypeEuint16)
+	missing := common.Hash{0xde, 0xad}
+	add := func(x, y common.Hash) []byte {
+		return append(append([]byte("\x23\xb8\x72\xdd"), x.Bytes()...), y.Bytes()...)
+	}
+
+	ret, _, err := c.Run(nil, common.Address{}, ContractAddress, add(a, missing), GasAdd, false)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+	require.Equal(t, append(crypto.Keccak256([]byte("HandleNotFound(bytes32)"))[:4], missing.Bytes()...), ret)
+
+	ret, _, err = c.Run(nil, common.Address{}, ContractAddress, add(a, b), GasAdd, false)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	var opErr *OpError
+	require.ErrorAs(t, err, &opErr)
+	require.Equal(t, "add", opErr.Op)
+	want := crypto.Keccak256([]byte("TypeMismatch(bytes32,uint8,uint8)"))[:4]
+	want = append(want, b.Bytes()...)
+	want = append(want, common.Hash{31: TypeEuint8}.Bytes()...)
+	want = append(want, common.Hash{31: TypeEuint16}.Bytes()...)
+	require.Equal(t, want, ret)
+
+	// Other errors revert with Error(string)
+	ret, _, err = c.Run(nil, common.Address{}, ContractAddress, []byte{0x23}, GasAdd, false)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	msg := ErrInvalidInput.Error()
+	want = crypto.Keccak256([]byte("Error(string)"))[:4]
+	want = append(want, common.Hash{31: 32}.Bytes()...)
+	want = append(want, common.Hash{31: byte(len(msg))}.Bytes()...)
+	want = append(want, common.RightPadBytes([]byte(msg), 32)...)
+	require.Equal(t, want, ret)
+
+	// Failed evaluations name the operation
+	data := RevertData(opFailed("mul"))
+	require.Equal(t, crypto.Keccak256([]byte("OperationFailed(string,string)"))[:4], data[:4])
+	require.Equal(t, common.Hash{31: 64}.Bytes(), data[4:36])
+	require.Equal(t, common.Hash{31: 1}.Bytes(), data[68:100])
+	require.Equal(t, common.RightPadBytes([]byte("mul"), 32), data[100:132])
+}
+
+// TestOperationLogs tests that operations log their inputs and results
+func TestOperationLogs(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	db := statetest.New()
+	db.SetTxHash(common.Hash{3})
+	state := &aclTestState{db: db}
+	alice := common.HexToAddress("0xa11ce")
+	c := &FHEContract{}
+
+	check := func(log *ethtypes.Log, op string, result common.Hash, inputs []common.Hash, gasUsed uint64) {
+		require.Equal(t, ContractAddress, log.Address)
+		require.Equal(t, []common.Hash{FHEOperationTopic, common.BytesToHash(alice.Bytes()), common.BytesToHash(common.RightPadBytes([]byte(op), 32)), result}, log.Topics)
+		require.Equal(t, gasUsed, new(big.Int).SetBytes(log.Data[32:64]).Uint64())
+		logged, err := decodeHandleArrayArg(log.Data, 0)
+		require.NoError(t, err)
+		require.Equal(t, len(inputs), len(logged))
+		for i := range inputs {
+			require.Equal(t, inputs[i], logged[i])
+		}
+	}
+
+	ret, remaining, err := c.Run(state, alice, ContractAddress, append([]byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(5)).Bytes()...), 10_000_000, false)
+	require.NoError(t, err)
+	h := common.BytesToHash(ret)
+	require.Len(t, db.Logs(), 1)
+	check(db.Logs()[0], "\xa5\x17\x5c\x89", h, nil, 10_000_000-remaining)
+
+	ret, remaining, err = c.Run(state, alice, ContractAddress, append(append([]byte("\xa9\x05\x9c\xbb"), h.Bytes()...), h.Bytes()...), 10_000_000, false)
+	require.NoError(t, err)
+	require.Len(t, db.Logs(), 2)
+	check(db.Logs()[1], "\xa9\x05\x9c\xbb", common.BytesToHash(ret), []common.Hash{h, h}, 10_000_000-remaining)
+
+	// Static and failed calls log nothing
+	_, _, err = c.Run(state, alice, ContractAddress, append(append([]byte("\xa9\x05\x9c\xbb"), h.Bytes()...), h.Bytes()...), 10_000_000, true)
+	require.NoError(t, err)
+	_, _, err = c.Run(state, alice, ContractAddress, append(append([]byte("\xa9\x05\x9c\xbb"), h.Bytes()...), common.Hash{0xde, 0xad}.Bytes()...), 10_000_000, false)
+	require.Error(t, err)
+	require.Len(t, db.Logs(), 2)
+
+	// Batches log each operation, resolving earlier results
+	input := append([]byte("\x26\x88\x7f\x26"), mustEncodeBatch(t, []ParallelOp{
+		{Op: "add", Inputs: []Operand{HandleOperand(h), HandleOperand(h)}},
+		{Op: "lt", Inputs: []Operand{ResultOperand(0), HandleOperand(h)}},
+	})...)
+	ret, _, err = c.Run(state, alice, ContractAddress, input, 10_000_000, false)
+	require.NoError(t, err)
+	results, err := decodeHandleArray(ret)
+	require.NoError(t, err)
+	require.Len(t, db.Logs(), 4)
+	check(db.Logs()[2], "\x23\xb8\x72\xdd", results[0], []common.Hash{h, h}, GasAdd)
+	check(db.Logs()[3], "\xa9\x05\x9c\xbb", results[1], []common.Hash{results[0], h}, GasLt)
+}
+
+// testBlockContext is a block context at a block number
+type testBlockContext uint64
+
+func (b testBlockContext) Number() *big.Int                                       { return new(big.Int).SetUint64(uint64(b)) }
+func (b testBlockContext) Timestamp() uint64                                      { return 0 }
+func (b testBlockContext) GetPredicateResults(common.Hash, common.Address) []byte { return nil }
+
+// TestGarbageCollection tests that expired, unreferenced ciphertexts are
+// collected while referenced and pinned ones are kept
+func TestGarbageCollection(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	db := statetest.New()
+	db.SetTxHash(common.Hash{4})
+	state := &aclTestState{db: db, number: 100}
+	alice := common.HexToAddress("0xa11ce")
+	c := &FHEContract{}
+	store := NewStateCiphertextStore(db)
+
+	encrypt := func(v int64) common.Hash {
+		ret, _, err := c.Run(state, alice, ContractAddress, append([]byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(v)).Bytes()...), 10_000_000, false)
+		require.NoError(t, err)
+		return common.BytesToHash(ret)
+	}
+	transient, kept, pinned := encrypt(1), encrypt(2), encrypt(3)
+
+	// A persistent permission references kept; pinning charges rent by size
+	acl := NewACL(db)
+	require.NoError(t, acl.Allow(alice, kept, alice))
+	_, size, ok := store.Stat(pinned)
+	require.True(t, ok)
+	_, remaining, err := c.Run(state, alice, ContractAddress, append([]byte("\xd3\x93\x0f\x85"), pinned.Bytes()...), 10_000_000, false)
+	require.NoError(t, err)
+	require.Equal(t, 10_000_000-GasACLCheck-pinGas(size), remaining)
+	_, _, err = c.Run(state, alice, ContractAddress, append([]byte("\xd3\x93\x0f\x85"), common.Hash{0xde, 0xad}.Bytes()...), 10_000_000, false)
+	require.ErrorIs(t, err, ErrACLDenied)
+
+	// Nothing expires before the TTL
+	require.Zero(t, CollectExpired(db, 100+HandleTTL-1, 100))
+	for _, h := range []common.Hash{transient, kept, pinned} {
+		require.True(t, store.Has(h))
+	}
+
+	require.Equal(t, 1, CollectExpired(db, 100+HandleTTL, 100))
+	require.False(t, store.Has(transient))
+	require.True(t, store.Has(kept))
+	require.True(t, store.Has(pinned))
+
+	// Dropping the last reference lets the renewed lease lapse
+	require.NoError(t, acl.Revoke(alice, kept, alice))
+	require.Zero(t, CollectExpired(db, 100+2*HandleTTL-1, 100))
+	require.True(t, store.Has(kept))
+	require.Equal(t, 1, CollectExpired(db, 100+2*HandleTTL, 1_000_000))
+	require.False(t, store.Has(kept))
+	require.True(t, store.Has(pinned))
+
+	// Collection resumes where a limited call stopped
+	state.number = 101 + HandleTTL
+	a, b := encrypt(4), encrypt(5)
+	require.Zero(t, CollectExpired(db, 101+2*HandleTTL, 1))
+	require.Equal(t, 1, CollectExpired(db, 101+2*HandleTTL, 2))
+	require.Equal(t, 1, CollectExpired(db, 101+2*HandleTTL, 2))
+	require.False(t, store.Has(a))
+	require.False(t, store.Has(b))
+
+	// Calls run a collection step and pay for the entries it examines
+	asEuint64 := func(v int64) (common.Hash, uint64) {
+		ret, remaining, err := c.Run(state, alice, ContractAddress, append([]byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(v)).Bytes()...), 10_000_000, false)
+		require.NoError(t, err)
+		return common.BytesToHash(ret), remaining
+	}
+	state.number = 102 + HandleTTL
+	d, idle := asEuint64(6)
+	state.number = 102 + 2*HandleTTL
+	_, pruning := asEuint64(7)
+	require.False(t, store.Has(d))
+	require.Equal(t, 2*GasCollectEntry, idle-pruning)
+}
+
+// TestSealOutput tests sealing a plaintext under each scheme
+func TestSealOutput(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	c := &FHEContract{}
+	h := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(42), TypeEuint8), TypeEuint8)
+	want := common.BigToHash(big.NewInt(42)).Bytes()
+	sealCtx := sealContext(h)
+	seal := func(scheme uint8, publicKey []byte) ([]byte, error) {
+		input := append(append([]byte("\xf1\x6e\xba\x61"), h.Bytes()...), scheme)
+		input = append(input, publicKey...)
+		ret, _, err := c.Run(nil, common.Address{}, ContractAddress, input, c.Gas(input), false)
+		return ret, err
+	}
+
+	// Sealing fails closed until the module config supplies the secret
+	eciesKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	_, err = seal(SealECIES, crypto.FromECDSAPub(&eciesKey.PublicKey))
+	require.ErrorIs(t, err, ErrNoSealKey)
+	require.ErrorIs(t, SetSealKey(make([]byte, MinSealSecretSize-1)), ErrShortSealKey)
+
+	secretPath := filepath.Join(t.TempDir(), "seal.key")
+	require.NoError(t, os.WriteFile(secretPath, bytes.Repeat([]byte{7}, MinSealSecretSize), 0o600))
+	config := &Config{InputAttestors: []common.Address{{0x01}}, InputThreshold: 1, SealKeyPath: secretPath}
+	require.NoError(t, (&configurator{}).Configure(nil, config, nil, nil))
+	t.Cleanup(func() {
+		_ = SetSealKey(nil)
+		SetInputVerifier(nil)
+	})
+
+	// ECIES over secp256k1
+	sealed, err := seal(SealECIES, crypto.FromECDSAPub(&eciesKey.PublicKey))
+	require.NoError(t, err)
+	opened, err := ecies.Decrypt(ecies.CurveSecp256k1, crypto.FromECDSA(eciesKey), sealCtx, sealed)
+	require.NoError(t, err)
+	require.Equal(t, want, opened)
+
+	// Sealing is deterministic under one seal key
+	again, err := seal(SealECIES, crypto.FromECDSAPub(&eciesKey.PublicKey))
+	require.NoError(t, err)
+	require.Equal(t, sealed, again)
+
+	// HPKE with X25519
+	suite := hpke.NewSuite(hpke.KEM_X25519_HKDF_SHA256, hpke.KDF_HKDF_SHA256, hpke.AEAD_AES128GCM)
+	kem, _, _ := suite.Params()
+	hpkePub, hpkePriv, err := kem.Scheme().GenerateKeyPair()
+	require.NoError(t, err)
+	hpkePubBytes, err := hpkePub.MarshalBinary()
+	require.NoError(t, err)
+	sealed, err = seal(SealHPKE, hpkePubBytes)
+	require.NoError(t, err)
+	receiver, err := suite.NewReceiver(hpkePriv, sealCtx)
+	require.NoError(t, err)
+	encSize := kem.Scheme().CiphertextSize()
+	opener, err := receiver.Setup(sealed[:encSize])
+	require.NoError(t, err)
+	opened, err = opener.Open(sealed[encSize:], nil)
+	require.NoError(t, err)
+	require.Equal(t, want, opened)
+
+	// ML-KEM-768
+	mlkemPub, mlkemPriv, err := mlkem768.GenerateKeyPair(rand.Reader)
+	require.NoError(t, err)
+	mlkemPubBytes, err := mlkemPub.MarshalBinary()
+	require.NoError(t, err)
+	sealed, err = seal(SealMLKEM, mlkemPubBytes)
+	require.NoError(t, err)
+	shared, err := mlkem768.Scheme().Decapsulate(mlkemPriv, sealed[:mlkem768.CiphertextSize])
+	require.NoError(t, err)
+	key := sha256.Sum256(append(append([]byte(nil), sealCtx...), shared...))
+	block, err := aes.NewCipher(key[:])
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	opened, err = gcm.Open(nil, make([]byte, gcm.NonceSize()), sealed[mlkem768.CiphertextSize:], sealCtx)
+	require.NoError(t, err)
+	require.Equal(t, want, opened)
+
+	// Unknown schemes and keys of the wrong size are rejected
+	_, err = seal(0x09, hpkePubBytes)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	_, err = seal(SealHPKE, mlkemPubBytes)
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestKeyRotation tests the key schedule, key switching and migration
+func TestKeyRotation(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, useKeyEpoch(0))
+		keysMu.Lock()
+		delete(keySets, 1)
+		keysMu.Unlock()
+	})
+
+	db := statetest.New()
+	db.SetTxHash(common.Hash{4})
+	state := &aclTestState{db: db, number: 10}
+	alice := common.HexToAddress("0xa11ce")
+	c := &FHEContract{}
+	run := func(input ...[]byte) ([]byte, error) {
+		var data []byte
+		for _, part := range input {
+			data = append(data, part...)
+		}
+		ret, _, err := c.Run(state, alice, ContractAddress, data, 10_000_000, false)
+		return ret, err
+	}
+	decrypt := func(h common.Hash) uint64 {
+		ret, err := run([]byte("\x12\x3d\x4c\x87"), h.Bytes())
+		require.NoError(t, err)
+		return new(big.Int).SetBytes(ret).Uint64()
+	}
+	epochOf := func(h common.Hash) []byte {
+		ret, err := run([]byte("\xd3\xb8\xfe\x9b"), h.Bytes())
+		require.NoError(t, err)
+		return ret
+	}
+
+	// Epoch 0 serves until a rotation activates
+	ret, err := run([]byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(7)).Bytes())
+	require.NoError(t, err)
+	h := common.BytesToHash(ret)
+	ret, err = run([]byte("\x6f\xdf\x65\x7e"))
+	require.NoError(t, err)
+	require.Equal(t, abiWord(0), ret)
+	require.Equal(t, abiWord(0), epochOf(h))
+
+	// The network key is the evaluator's, charged per word
+	key0 := tfheGetNetworkPublicKey()
+	require.NotEmpty(t, key0)
+	ret, remaining, err := c.Run(state, alice, ContractAddress, []byte("\x80\x64\x43\xfa"), networkPublicKeyGas(len(key0)), true)
+	require.NoError(t, err)
+	require.Equal(t, key0, ret)
+	require.Zero(t, remaining)
+
+	data, err := GenerateNetworkKeys()
+	require.NoError(t, err)
+	_, err = LoadNetworkKeys(1, data[:len(data)-1])
+	require.ErrorIs(t, err, ErrInvalidKeys)
+	hash, err := LoadNetworkKeys(1, data)
+	require.NoError(t, err)
+
+	reg := NewKeyRegistry(db)
+	_, err = reg.ScheduleRotation(hash, 0)
+	require.ErrorIs(t, err, ErrKeySchedule)
+	epoch, err := reg.ScheduleRotation(hash, 20)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), epoch)
+	require.Equal(t, uint64(0), reg.ActiveEpoch(19))
+	require.Equal(t, uint64(1), reg.ActiveEpoch(20))
+	scheduled, ok := reg.Epoch(1)
+	require.True(t, ok)
+	require.Equal(t, KeyEpoch{Epoch: 1, KeyHash: hash, ActivatesAt: 20}, scheduled)
+
+	// From its activation block the new key serves, and ciphertexts of
+	// the old one are switched on read
+	state.number = 20
+	ret, err = run([]byte("\x6f\xdf\x65\x7e"))
+	require.NoError(t, err)
+	require.Equal(t, abiWord(1), ret)
+	require.NotEqual(t, key0, tfheGetNetworkPublicKey())
+	require.Equal(t, abiWord(0), epochOf(h))
+	require.Equal(t, uint64(7), decrypt(h))
+
+	// Migration rewrites the ciphertext under its handle
+	_, err = run([]byte("\xe1\x9b\x8e\xe3"), h.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, abiWord(1), epochOf(h))
+	require.Equal(t, uint64(7), decrypt(h))
+	_, err = run([]byte("\xe1\x9b\x8e\xe3"), common.Hash{0xde, 0xad}.Bytes())
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	// Blocks of an epoch whose key the node has not loaded fail
+	_, err = reg.ScheduleRotation(common.Hash{2}, 30)
+	require.NoError(t, err)
+	state.number = 30
+	_, err = run([]byte("\x6f\xdf\x65\x7e"))
+	require.ErrorIs(t, err, ErrKeyNotLoaded)
+}
+
+// testTransport evaluates batches with eval and signs them with keys
+type testTransport struct {
+	keys  []*ecdsa.PrivateKey
+	eval  func(req *EvalRequest) EvalResult
+	sizes []int
+}
+
+func (tr *testTransport) EvaluateBatch(ctx context.Context, reqs []EvalRequest) ([]EvalResult, [][]byte, error) {
+	tr.sizes = append(tr.sizes, len(reqs))
+	results := make([]EvalResult, len(reqs))
+	for i := range reqs {
+		results[i] = tr.eval(&reqs[i])
+	}
+	var signatures [][]byte
+	for _, key := range tr.keys {
+		sig, err := crypto.Sign(EvalBatchDigest(reqs, results).Bytes(), key)
+		if err != nil {
+			return nil, nil, err
+		}
+		signatures = append(signatures, sig)
+	}
+	return results, signatures, nil
+}
+
+// TestRemoteBackend tests routing evaluation to an attested remote backend
+func TestRemoteBackend(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	attestors := make([]common.Address, 3)
+	for i := range keys {
+		key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+		require.NoError(t, err)
+		keys[i] = key
+		attestors[i] = common.BytesToAddress(crypto.Keccak256(crypto.FromECDSAPub(&key.PublicKey)[1:])[12:])
+	}
+	_, err := NewRemoteBackend(nil, attestors, 2)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	_, err = NewRemoteBackend(&testTransport{}, attestors, 4)
+	require.ErrorIs(t, err, ErrInvalidAttestors)
+
+	// Requests are sent in chunks of RemoteBatchSize
+	tr := &testTransport{keys: keys[:2], eval: func(req *EvalRequest) EvalResult {
+		return EvalResult{Ciphertext: append([]byte{0xcc}, req.Inputs[0]...), Type: req.Types[0]}
+	}}
+	remote, err := NewRemoteBackend(tr, attestors, 2)
+	require.NoError(t, err)
+	reqs := make([]EvalRequest, RemoteBatchSize+1)
+	for i := range reqs {
+		reqs[i] = EvalRequest{Op: "not", Inputs: [][]byte{{byte(i)}}, Types: []uint8{TypeEuint8}}
+	}
+	results, err := remote.Evaluate(reqs)
+	require.NoError(t, err)
+	require.Equal(t, []int{RemoteBatchSize, 1}, tr.sizes)
+	require.Equal(t, []byte{0xcc, 5}, results[5].Ciphertext)
+
+	// Results need a threshold of attestations and the expected type
+	tr.keys = keys[:1]
+	_, err = remote.Evaluate(reqs[:1])
+	require.ErrorIs(t, err, ErrAttestation)
+	tr.keys = keys
+	_, err = remote.Evaluate([]EvalRequest{{Op: "eq", Inputs: [][]byte{{1}, {2}}, Types: []uint8{TypeEuint8, TypeEuint8}}})
+	require.ErrorIs(t, err, ErrRemoteEvaluation)
+
+	// Precompile operations evaluate through the installed backend
+	require.NoError(t, initTFHE())
+	tr.sizes = nil
+	tr.eval = func(req *EvalRequest) EvalResult {
+		ct, ctType := evalLocal(defaultTFHE(), req)
+		return EvalResult{Ciphertext: ct, Type: ctType}
+	}
+	SetEvalBackend(remote)
+	defer SetEvalBackend(nil)
+	a := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(20), TypeEuint8), TypeEuint8)
+	b := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(22), TypeEuint8), TypeEuint8)
+	sum, err := performFHEOperation(ciphertexts, "add", a, b, common.Address{})
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, tr.sizes)
+	ct, ctType, ok := getCiphertext(ciphertexts, sum)
+	require.True(t, ok)
+	require.Equal(t, TypeEuint8, ctType)
+	require.Equal(t, uint64(42), tfheDecrypt(ct, TypeEuint8).Uint64())
+
+	// A batch sends each dependency level as one request
+	tr.sizes = nil
+	handles, err := NewParallelExecutor(0).Execute(ciphertexts, []ParallelOp{
+		{Op: "add", Inputs: []Operand{HandleOperand(a), HandleOperand(b)}},
+		{Op: "sub", Inputs: []Operand{HandleOperand(b), HandleOperand(a)}},
+		{Op: "mul", Inputs: []Operand{ResultOperand(0), ResultOperand(1)}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 1}, tr.sizes)
+	ct, _, ok = getCiphertext(ciphertexts, handles[2])
+	require.True(t, ok)
+	require.Equal(t, uint64(84), tfheDecrypt(ct, TypeEuint8).Uint64())
+}
+
+// TestEncryptedArray tests packing, oblivious reads and writes of arrays
+func TestEncryptedArray(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping slow FHE array test in short mode")
+	}
+
+	err := initTFHE()
+	require.NoError(t, err)
+
+	encrypt := func(v int64, ctType uint8) common.Hash {
+		return storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(v), ctType), ctType)
+	}
+	c := &FHEContract{}
+	run := func(input []byte) (common.Hash, error) {
+		ret, _, err := c.Run(nil, common.Address{}, ContractAddress, input, c.Gas(input), false)
+		return common.BytesToHash(ret), err
+	}
+	arrayNew := func(elements ...common.Hash) []byte {
+		data := append([]byte("\xb0\x6b\x95\x20"), common.BigToHash(big.NewInt(32)).Bytes()...)
+		data = append(data, common.BigToHash(big.NewInt(int64(len(elements)))).Bytes()...)
+		for _, h := range elements {
+			data = append(data, h.Bytes()...)
+		}
+		return data
+	}
+	get := func(array common.Hash, index int64) uint64 {
+		h, err := run(append(append([]byte("\x35\xe1\x96\x9c"), array.Bytes()...), encrypt(index, TypeEuint8).Bytes()...))
+		require.NoError(t, err)
+		ct, ctType, ok := getCiphertext(ciphertexts, h)
+		require.True(t, ok)
+		require.Equal(t, TypeEuint16, ctType)
+		return tfheDecrypt(ct, ctType).Uint64()
+	}
+
+	input := arrayNew(encrypt(10, TypeEuint16), encrypt(20, TypeEuint16), encrypt(30, TypeEuint16))
+	require.Equal(t, arrayNewGas(3), c.Gas(input))
+	array, err := run(input)
+	require.NoError(t, err)
+	_, ctType, ok := getCiphertext(ciphertexts, array)
+	require.True(t, ok)
+	require.Equal(t, TypeArray|TypeEuint16, ctType)
+	ret, _, err := c.Run(nil, common.Address{}, ContractAddress, append([]byte("\x75\xe8\x3c\x57"), array.Bytes()...), GasHandleQuery, true)
+	require.NoError(t, err)
+	require.Equal(t, abiWord(3), ret)
+
+	// Reads past the end yield zero
+	require.Equal(t, uint64(20), get(array, 1))
+	require.Equal(t, uint64(30), get(array, 2))
+	require.Equal(t, uint64(0), get(array, 3))
+
+	// Writes return a new array and leave the old one unchanged
+	updated, err := run(append(append(append([]byte("\x80\xbd\x37\x8d"), array.Bytes()...), encrypt(1, TypeEuint8).Bytes()...), encrypt(99, TypeEuint16).Bytes()...))
+	require.NoError(t, err)
+	require.NotEqual(t, array, updated)
+	require.Equal(t, uint64(10), get(updated, 0))
+	require.Equal(t, uint64(99), get(updated, 1))
+	require.Equal(t, uint64(30), get(updated, 2))
+	require.Equal(t, uint64(20), get(array, 1))
+
+	// Elements share a type; indexes are unsigned; values match the elements
+	_, err = run(arrayNew(encrypt(1, TypeEuint16), encrypt(1, TypeEuint8)))
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = run(arrayNew(array))
+	require.ErrorIs(t, err, ErrInvalidInput)
+	_, err = run(append(append([]byte("\x35\xe1\x96\x9c"), array.Bytes()...), encrypt(1, TypeEbool).Bytes()...))
+	require.ErrorIs(t, err, ErrInvalidInput)
+	_, err = run(append(append([]byte("\x35\xe1\x96\x9c"), encrypt(1, TypeEuint16).Bytes()...), encrypt(1, TypeEuint8).Bytes()...))
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = run(append(append(append([]byte("\x80\xbd\x37\x8d"), array.Bytes()...), encrypt(1, TypeEuint8).Bytes()...), encrypt(1, TypeEuint8).Bytes()...))
+	require.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+// TestTrivialGas tests provenance tracking and the trivial operand price
+func TestTrivialGas(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	c := &FHEContract{}
+	run := func(input []byte) (common.Hash, uint64) {
+		ret, remaining, err := c.Run(nil, common.Address{}, ContractAddress, input, c.Gas(input), false)
+		require.NoError(t, err)
+		return common.BytesToHash(ret), remaining
+	}
+	add := func(a, b common.Hash) (common.Hash, uint64) {
+		return run(append(append([]byte("\x23\xb8\x72\xdd"), a.Bytes()...), b.Bytes()...))
+	}
+
+	// Plaintext casts are trivial; ciphertexts stored otherwise are not
+	public, _ := run(append([]byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(5)).Bytes()...))
+	require.True(t, ciphertexts.Trivial(public))
+	private := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(7), TypeEuint64), TypeEuint64)
+	require.False(t, ciphertexts.Trivial(private))
+
+	// A trivial operand is charged the trivial price, and the rest is returned
+	sum, remaining := add(public, private)
+	require.Equal(t, GasAdd-trivialGas(GasAdd), remaining)
+	require.False(t, ciphertexts.Trivial(sum))
+	_, remaining = add(private, private)
+	require.Zero(t, remaining)
+
+	// Results of trivial operands are trivial
+	double, remaining := add(public, public)
+	require.Equal(t, GasAdd-trivialGas(GasAdd), remaining)
+	require.True(t, ciphertexts.Trivial(double))
+	ct, ctType, ok := getCiphertext(ciphertexts, double)
+	require.True(t, ok)
+	require.Equal(t, uint64(10), tfheDecrypt(ct, ctType).Uint64())
+
+	// The flag lives in the StateDB header and is cleared by a new Put
+	store := NewStateCiphertextStore(statetest.New())
+	h := storeTrivial(store, ct, ctType)
+	require.True(t, store.Trivial(h))
+	got, gotType, ok := store.Get(h)
+	require.True(t, ok)
+	require.Equal(t, ct, got)
+	require.Equal(t, ctType, gotType)
+	store.Put(h, ct, ctType)
+	require.False(t, store.Trivial(h))
+}
+
+// TestHandleDerivation tests result handles derived from the transaction
+// and call
+func TestHandleDerivation(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	alice := common.HexToAddress("0xa11ce")
+	bob := common.HexToAddress("0xb0b")
+	c := &FHEContract{}
+	newState := func() *aclTestState {
+		db := statetest.New()
+		db.SetTxHash(common.Hash{7})
+		return &aclTestState{db: db, number: 100}
+	}
+	encrypt := func(state *aclTestState, caller common.Address, v int64) common.Hash {
+		ret, _, err := c.Run(state, caller, ContractAddress, append([]byte("\xa5\x17\x5c\x89"), common.BigToHash(big.NewInt(v)).Bytes()...), 10_000_000, false)
+		require.NoError(t, err)
+		return common.BytesToHash(ret)
+	}
+
+	// Equal results of one call get distinct handles, not derived from the
+	// ciphertext
+	state := newState()
+	store := NewStateCiphertextStore(state.db)
+	first, second := encrypt(state, alice, 5), encrypt(state, alice, 5)
+	require.NotEqual(t, first, second)
+	ct, _, ok := store.Get(first)
+	require.True(t, ok)
+	require.NotEqual(t, common.BytesToHash(ct), first)
+	require.NotEqual(t, first, encrypt(state, bob, 5))
+
+	// Replaying the transaction derives the same handles
+	replay := newState()
+	require.Equal(t, first, encrypt(replay, alice, 5))
+	require.Equal(t, second, encrypt(replay, alice, 5))
+
+	// A reverted call does not consume a handle
+	snap := state.db.Snapshot()
+	reverted := encrypt(state, alice, 6)
+	state.db.RevertToSnapshot(snap)
+	require.False(t, store.Has(reverted))
+	require.Equal(t, reverted, encrypt(state, alice, 6))
+}
+
+// TestCiphertextStoreConcurrency tests the in-memory store and precompile
+// calls from many goroutines; run it with -race
+func TestCiphertextStoreConcurrency(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	const workers, rounds = 8, 64
+	store := NewCiphertextStore()
+	shared := bytes.Repeat([]byte{0x11}, 2*CiphertextChunkSize)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				// Distinct handles over a shared chunk, then a private one
+				ct := append(append([]byte(nil), shared...), byte(w), byte(i))
+				h := common.Hash{byte(i), byte(w)}
+				store.Put(h, ct, TypeEuint64)
+				store.MarkTrivial(h)
+				got, ctType, ok := store.Get(h)
+				require.True(t, ok)
+				require.Equal(t, ct, got)
+				require.Equal(t, TypeEuint64, ctType)
+				require.True(t, store.Trivial(h))
+				_ = store.Stats()
+				if i%2 == 1 {
+					store.Delete(h)
+					require.False(t, store.Has(h))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	stats := store.Stats()
+	require.Equal(t, workers*rounds/2, stats.Ciphertexts)
+	require.Equal(t, uint64(workers*rounds/2*(len(shared)+2)), stats.LogicalBytes)
+
+	// Concurrent operations share the package store
+	c := &FHEContract{}
+	a := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(20), TypeEuint8), TypeEuint8)
+	b := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(22), TypeEuint8), TypeEuint8)
+	input := append(append([]byte("\x23\xb8\x72\xdd"), a.Bytes()...), b.Bytes()...)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ret, _, err := c.Run(nil, common.Address{}, ContractAddress, input, GasAdd, false)
+			require.NoError(t, err)
+			require.True(t, ciphertexts.Has(common.BytesToHash(ret)))
+		}()
+	}
+	wg.Wait()
+}
+
+// TestRequire tests encrypted requirements
+func TestRequire(t *testing.T) {
+	err := initTFHE()
+	require.NoError(t, err)
+
+	c := &FHEContract{}
+	alice := common.HexToAddress("0xa11ce")
+	requireInput := func(h common.Hash, message string) []byte {
+		if message != "" {
+			return append(append([]byte("\x14\xfc\xc2\x2d"), h.Bytes()...), message...)
+		}
+		return append([]byte("\x5f\xe9\x5c\xd2"), h.Bytes()...)
+	}
+
+	yes := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(1), TypeEbool), TypeEbool)
+	no := storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(0), TypeEbool), TypeEbool)
+	ret, remaining, err := c.Run(nil, alice, ContractAddress, requireInput(yes, ""), GasRequire, false)
+	require.NoError(t, err)
+	require.Empty(t, ret)
+	require.Zero(t, remaining)
+	_, _, err = c.Run(nil, alice, ContractAddress, requireInput(yes, "balance"), GasRequire, false)
+	require.NoError(t, err)
+	_, _, err = c.Run(nil, alice, ContractAddress, requireInput(no, ""), GasRequire, false)
+	require.ErrorIs(t, err, ErrRequireFailed)
+	_, _, err = c.Run(nil, alice, ContractAddress, requireInput(storeCiphertext(ciphertexts, tfheTrivialEncrypt(defaultTFHE(), big.NewInt(1), TypeEuint8), TypeEuint8), ""), GasRequire, false)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+
+	// The message becomes the revert reason
+	ret, _, err = c.Run(nil, alice, ContractAddress, requireInput(no, "insufficient balance"), GasRequire, false)
+	require.ErrorIs(t, err, ErrRequireFailed)
+	require.Equal(t, append(crypto.Keccak256([]byte("Error(string)"))[:4], abiStrings("insufficient balance")...), ret)
+
+	// A decryption committee does not defer the decision past the call
+	oracle, err := NewDecryptionOracle([]common.Address{{0x01}}, 1)
+	require.NoError(t, err)
+	SetDecryptionOracle(oracle)
+	t.Cleanup(func() { SetDecryptionOracle(nil) })
+	db := statetest.New()
+	state := &aclTestState{db: db, number: 100}
+	encrypt := func(v int64) common.Hash {
+		ret, _, err := c.Run(state, alice, ContractAddress, append([]byte("\x8c\x3f\x5a\x42"), common.BigToHash(big.NewInt(v)).Bytes()...), 10_000_000, false)
+		require.NoError(t, err)
+		return common.BytesToHash(ret)
+	}
+	_, _, err = c.Run(state, alice, ContractAddress, requireInput(encrypt(1), ""), 10_000_000, false)
+	require.NoError(t, err)
+	_, _, err = c.Run(state, alice, ContractAddress, requireInput(encrypt(0), ""), 10_000_000, false)
+	require.ErrorIs(t, err, ErrRequireFailed)
+	require.Empty(t, oracle.Pending(db, 10))
+}
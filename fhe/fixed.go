@@ -0,0 +1,255 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Encrypted fixed-point arithmetic.
+//
+// The Solidity library defines efixed64x18, an unsigned decimal with 18
+// fractional digits stored as a euint128 holding value * 10^18. Addition,
+// subtraction and comparisons are the euint128 operations; products and
+// quotients need the full-width intermediate that mulDiv computes:
+//
+//	mulDiv(a, b, d, r)       = round_r(a * b / d)  (encrypted a, b)
+//	scalarMulDiv(a, m, d, r) = round_r(a * m / d)  (encrypted a)
+//	scaledDiv(a, b, m, r)    = round_r(a * m / b)  (encrypted a, b)
+//
+// The intermediate is formed at twice the operand width, so it cannot
+// overflow below euint256, and the quotient is truncated back to the
+// operand type. Plaintext operands must fit the operand type and plaintext
+// divisors must be non-zero; an encrypted zero divisor yields the type's
+// maximum, as div does.
+
+// Rounding modes of the mulDiv family
+const (
+	RoundFloor   uint8 = iota // Toward zero
+	RoundCeil                 // Away from zero
+	RoundNearest              // To nearest, halves away from zero
+)
+
+// FixedDecimals is the number of fractional digits of efixed64x18
+const FixedDecimals = 18
+
+// FixedOne is the efixed64x18 representation of 1
+var FixedOne = new(big.Int).Exp(big.NewInt(10), big.NewInt(FixedDecimals), nil)
+
+// GasMulDiv covers the widening casts, the double-width multiplication,
+// rounding and division
+const GasMulDiv uint64 = 800000
+
+// mulDivWidth returns the type a mulDiv intermediate of ctType is formed
+// in: twice its width, so the product cannot overflow
+func mulDivWidth(ctType uint8) uint8 {
+	switch ctType {
+	case TypeEuint4:
+		return TypeEuint8
+	case TypeEuint8:
+		return TypeEuint16
+	case TypeEuint16:
+		return TypeEuint32
+	case TypeEuint32:
+		return TypeEuint64
+	case TypeEuint64:
+		return TypeEuint128
+	default:
+		return TypeEuint256
+	}
+}
+
+// mulDivWide computes round(n1 * n2 / d) on ciphertexts of the
+// intermediate type
+func mulDivWide(n1, n2, d []byte, wide, rounding uint8) []byte {
+	num := tfheMul(defaultTFHE(), n1, n2, wide)
+	if num == nil {
+		return nil
+	}
+
+	switch rounding {
+	case RoundFloor:
+	case RoundCeil:
+		// (n + d - 1) / d
+		if num = tfheAdd(defaultTFHE(), num, d, wide); num != nil {
+			num = tfheScalarSub(defaultTFHE(), num, 1, wide)
+		}
+	case RoundNearest:
+		// (n + d/2) / d
+		if half := tfheShr(defaultTFHE(), d, 1, wide); half != nil {
+			num = tfheAdd(defaultTFHE(), num, half, wide)
+		} else {
+			num = nil
+		}
+	default:
+		return nil
+	}
+	if num == nil {
+		return nil
+	}
+
+	return tfheDiv(defaultTFHE(), num, d, wide)
+}
+
+// computeMulDiv evaluates a mulDiv-family operation. Each operand is either
+// a ciphertext of ctType or a plaintext that fits ctType; exactly one form
+// is set.
+func computeMulDiv(a, b []byte, m *big.Int, d []byte, divisor *big.Int, ctType, rounding uint8) []byte {
+	wide := mulDivWidth(ctType)
+	widen := func(ct []byte, plaintext *big.Int) []byte {
+		switch {
+		case ct == nil:
+			return tfheTrivialEncrypt(defaultTFHE(), plaintext, wide)
+		case ctType == wide:
+			return ct
+		default:
+			return tfheCast(defaultTFHE(), ct, ctType, wide)
+		}
+	}
+
+	wa, wb, wd := widen(a, nil), widen(b, m), widen(d, divisor)
+	if wa == nil || wb == nil || wd == nil {
+		return nil
+	}
+
+	q := mulDivWide(wa, wb, wd, wide, rounding)
+	if q == nil || ctType == wide {
+		return q
+	}
+	return tfheCast(defaultTFHE(), q, wide, ctType)
+}
+
+// performFHEMulDiv loads the encrypted operands of a mulDiv-family
+// operation, which must share a type, and stores the result. Zero handles
+// mark the plaintext operands.
+func performFHEMulDiv(store CiphertextBackend, op string, ha, hb common.Hash, m *big.Int, hd common.Hash, divisor *big.Int, rounding uint8) (common.Hash, error) {
+	a, ctType, ok := getCiphertext(store, ha)
+	if !ok {
+		return common.Hash{}, handleNotFound(op, ha)
+	}
+	if !isUintType(ctType) {
+		return common.Hash{}, &OpError{Op: op, Err: ErrInvalidInput}
+	}
+	bits, _ := typeBitWidth(ctType)
+	for _, plaintext := range []*big.Int{m, divisor} {
+		if plaintext != nil && plaintext.BitLen() > int(bits) {
+			return common.Hash{}, &OpError{Op: op, Err: ErrInvalidInput}
+		}
+	}
+	b, err := mulDivOperand(store, op, hb, ctType)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	d, err := mulDivOperand(store, op, hd, ctType)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	result := computeMulDiv(a, b, m, d, divisor, ctType, rounding)
+	if result == nil {
+		return common.Hash{}, opFailed(op)
+	}
+	return storeCiphertext(store, result, ctType), nil
+}
+
+// mulDivOperand loads an encrypted operand of ctType. The zero handle
+// stands for a plaintext operand and yields no ciphertext.
+func mulDivOperand(store CiphertextBackend, op string, handle common.Hash, ctType uint8) ([]byte, error) {
+	if handle == (common.Hash{}) {
+		return nil, nil
+	}
+	ct, opType, ok := getCiphertext(store, handle)
+	if !ok {
+		return nil, handleNotFound(op, handle)
+	}
+	if opType != ctType {
+		return nil, typeMismatch(op, handle, ctType, opType)
+	}
+	return ct, nil
+}
+
+// isUintType reports whether ctType is an encrypted unsigned integer
+func isUintType(ctType uint8) bool {
+	switch ctType {
+	case TypeEuint4, TypeEuint8, TypeEuint16, TypeEuint32, TypeEuint64, TypeEuint128, TypeEuint256:
+		return true
+	default:
+		return false
+	}
+}
+
+// === Fixed-Point Handlers ===
+
+// handleMulDiv computes a * b / d. Input is packed as a (32) || b (32) ||
+// d (32) || rounding (1).
+func (c *FHEContract) handleMulDiv(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 97 || data[96] > RoundNearest {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasMulDiv {
+		return nil, gas, ErrInsufficientGas
+	}
+	divisor := new(big.Int).SetBytes(data[64:96])
+	if divisor.Sign() == 0 {
+		return nil, gas, ErrInvalidInput
+	}
+
+	a, b := common.BytesToHash(data[:32]), common.BytesToHash(data[32:64])
+	if b == (common.Hash{}) {
+		return nil, gas, ErrInvalidInput
+	}
+	result, err := performFHEMulDiv(ciphertextStoreFor(state), "mulDiv", a, b, nil, common.Hash{}, divisor, data[96])
+	if err != nil {
+		return nil, gas - GasMulDiv, err
+	}
+	return result.Bytes(), gas - GasMulDiv, nil
+}
+
+// handleScalarMulDiv computes a * m / d. Input is packed as a (32) ||
+// m (32) || d (32) || rounding (1).
+func (c *FHEContract) handleScalarMulDiv(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 97 || data[96] > RoundNearest {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasMulDiv {
+		return nil, gas, ErrInsufficientGas
+	}
+	divisor := new(big.Int).SetBytes(data[64:96])
+	if divisor.Sign() == 0 {
+		return nil, gas, ErrInvalidInput
+	}
+
+	a := common.BytesToHash(data[:32])
+	m := new(big.Int).SetBytes(data[32:64])
+	result, err := performFHEMulDiv(ciphertextStoreFor(state), "scalarMulDiv", a, common.Hash{}, m, common.Hash{}, divisor, data[96])
+	if err != nil {
+		return nil, gas - GasMulDiv, err
+	}
+	return result.Bytes(), gas - GasMulDiv, nil
+}
+
+// handleScaledDiv computes a * m / b. Input is packed as a (32) || b (32) ||
+// m (32) || rounding (1).
+func (c *FHEContract) handleScaledDiv(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 97 || data[96] > RoundNearest {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasMulDiv {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	a, b := common.BytesToHash(data[:32]), common.BytesToHash(data[32:64])
+	if b == (common.Hash{}) {
+		return nil, gas, ErrInvalidInput
+	}
+	m := new(big.Int).SetBytes(data[64:96])
+	result, err := performFHEMulDiv(ciphertextStoreFor(state), "scaledDiv", a, common.Hash{}, m, b, nil, data[96])
+	if err != nil {
+		return nil, gas - GasMulDiv, err
+	}
+	return result.Bytes(), gas - GasMulDiv, nil
+}
@@ -0,0 +1,242 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Ciphertext garbage collection.
+//
+// Most handles are intermediates that are dead once their transaction ends,
+// so stored ciphertexts are leased rather than kept forever. Each handle
+// has a lease slot holding its expiry block, an ACL reference count and a
+// pinned flag:
+//
+//	lease = keccak256(leaseSlotDomain || h)
+//
+// Storing a ciphertext sets its expiry to HandleTTL blocks ahead and queues
+// the handle under that block. Persistent ACL permissions (allow, allowThis,
+// allowForAll) count as references; transient ones do not. The collector
+// walks the queues of past blocks and deletes every queued ciphertext that
+// has expired, is unreferenced and is not pinned. A referenced handle is
+// queued again for another TTL, and a handle stored again is collected from
+// its later queue.
+//
+// Every successful state-changing call runs a collection step once its
+// operation is done: it examines up to CollectStepEntries queue entries and
+// empty blocks, paying GasCollectEntry for each from the call's remaining
+// gas, so storage is reclaimed at the pace the chain uses it.
+//
+// pin(bytes32) exempts a handle from collection for good. It is charged as
+// rent for permanent storage, per 32-byte word of the ciphertext. Stores
+// without a block context (tools and tests) record no leases.
+
+// HandleTTL is the number of blocks a stored ciphertext outlives its last
+// store, which bounds how long asynchronous decryption and coprocessor jobs
+// may take to consume it
+const HandleTTL uint64 = 7200
+
+// Pin gas costs
+const (
+	GasPin        uint64 = 22100 // Lease update
+	GasPinPerWord uint64 = 5000  // Rent per stored 32-byte word
+)
+
+// Collection step costs
+const (
+	GasCollectEntry    uint64 = 5000 // Per queue entry or empty block examined
+	CollectStepEntries        = 16   // Entries a call's step examines at most
+)
+
+// Domain separators for lease storage slots
+const (
+	leaseSlotDomain   = "lux.fhe.lease.v1"
+	leaseQueueDomain  = "lux.fhe.lease.queue.v1"
+	leaseCursorDomain = "lux.fhe.lease.cursor.v1"
+)
+
+// Lease layout
+const (
+	leasePinned = 0  // 1 when pinned
+	leaseExpiry = 8  // uint64 block the lease ends
+	leaseRefs   = 24 // uint64 persistent ACL references
+)
+
+// lease is the collection state of a handle
+type lease struct {
+	pinned bool
+	expiry uint64
+	refs   uint64
+}
+
+func leaseSlot(handle common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte(leaseSlotDomain), handle.Bytes())
+}
+
+func leaseQueueSlot(number uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte(leaseQueueDomain), binary.BigEndian.AppendUint64(nil, number))
+}
+
+var leaseCursorSlot = crypto.Keccak256Hash([]byte(leaseCursorDomain))
+
+func readLease(db contract.StateDB, handle common.Hash) lease {
+	word := db.GetState(ContractAddress, leaseSlot(handle))
+	return lease{
+		pinned: word[leasePinned] == 1,
+		expiry: binary.BigEndian.Uint64(word[leaseExpiry : leaseExpiry+8]),
+		refs:   binary.BigEndian.Uint64(word[leaseRefs : leaseRefs+8]),
+	}
+}
+
+func writeLease(db contract.StateDB, handle common.Hash, l lease) {
+	var word common.Hash
+	if l.pinned {
+		word[leasePinned] = 1
+	}
+	binary.BigEndian.PutUint64(word[leaseExpiry:], l.expiry)
+	binary.BigEndian.PutUint64(word[leaseRefs:], l.refs)
+	db.SetState(ContractAddress, leaseSlot(handle), word)
+}
+
+// renewLease sets the expiry of handle to HandleTTL blocks after number
+// and queues it for collection then
+func renewLease(db contract.StateDB, handle common.Hash, number uint64) {
+	l := readLease(db, handle)
+	if l.pinned {
+		return
+	}
+	l.expiry = number + HandleTTL
+	writeLease(db, handle, l)
+
+	queue := leaseQueueSlot(l.expiry)
+	n := new(big.Int).SetBytes(db.GetState(ContractAddress, queue).Bytes()).Uint64()
+	db.SetState(ContractAddress, ciphertextDataSlot(queue, int(n)), handle)
+	db.SetState(ContractAddress, queue, common.BigToHash(new(big.Int).SetUint64(n+1)))
+
+	// The first lease starts the collector at its expiry
+	if db.GetState(ContractAddress, leaseCursorSlot) == (common.Hash{}) {
+		db.SetState(ContractAddress, leaseCursorSlot, common.BigToHash(new(big.Int).SetUint64(l.expiry)))
+	}
+}
+
+// addLeaseRef adjusts the ACL reference count of handle by delta
+func addLeaseRef(db contract.StateDB, handle common.Hash, delta int64) {
+	l := readLease(db, handle)
+	if delta < 0 && l.refs < uint64(-delta) {
+		l.refs = 0
+	} else {
+		l.refs = uint64(int64(l.refs) + delta)
+	}
+	writeLease(db, handle, l)
+}
+
+// CollectExpired deletes the expired, unreferenced and unpinned ciphertexts
+// queued up to block number. It examines at most limit queue entries and
+// empty blocks, resuming where the previous call stopped, and returns the
+// number of ciphertexts deleted. Run calls it through collectStep.
+func CollectExpired(db contract.StateDB, number uint64, limit int) int {
+	deleted, _ := collectExpired(db, number, limit)
+	return deleted
+}
+
+// collectStep runs the collection step of a state-changing call with up to
+// gas and returns the gas it used
+func collectStep(state contract.AccessibleState, gas uint64) uint64 {
+	db := stateDBFor(state)
+	if db == nil {
+		return 0
+	}
+	bc := state.GetBlockContext()
+	if bc == nil || bc.Number() == nil {
+		return 0
+	}
+	limit := CollectStepEntries
+	if affordable := gas / GasCollectEntry; affordable < uint64(limit) {
+		limit = int(affordable)
+	}
+	if limit == 0 {
+		return 0
+	}
+	_, examined := collectExpired(db, bc.Number().Uint64(), limit)
+	return uint64(examined) * GasCollectEntry
+}
+
+// collectExpired implements CollectExpired and also returns the number of
+// queue entries and empty blocks it examined
+func collectExpired(db contract.StateDB, number uint64, limit int) (int, int) {
+	cursor := new(big.Int).SetBytes(db.GetState(ContractAddress, leaseCursorSlot).Bytes()).Uint64()
+	if cursor == 0 {
+		return 0, 0
+	}
+
+	store := NewStateCiphertextStore(db)
+	deleted, budget := 0, limit
+	for ; cursor <= number && limit > 0; cursor++ {
+		queue := leaseQueueSlot(cursor)
+		n := new(big.Int).SetBytes(db.GetState(ContractAddress, queue).Bytes()).Uint64()
+		limit--
+		for ; n > 0 && limit > 0; n, limit = n-1, limit-1 {
+			item := ciphertextDataSlot(queue, int(n-1))
+			handle := db.GetState(ContractAddress, item)
+			db.SetState(ContractAddress, item, common.Hash{})
+
+			l := readLease(db, handle)
+			switch {
+			case l.pinned || l.expiry > cursor:
+				// Pinned, or collected from a later queue
+			case l.refs > 0:
+				renewLease(db, handle, number)
+			default:
+				store.Delete(handle)
+				db.SetState(ContractAddress, leaseSlot(handle), common.Hash{})
+				deleted++
+			}
+		}
+		db.SetState(ContractAddress, queue, common.BigToHash(new(big.Int).SetUint64(n)))
+		if n > 0 {
+			break
+		}
+	}
+	db.SetState(ContractAddress, leaseCursorSlot, common.BigToHash(new(big.Int).SetUint64(cursor)))
+	return deleted, budget - limit
+}
+
+// pinGas returns the gas of pinning a ciphertext of size bytes
+func pinGas(size int) uint64 {
+	return GasPin + GasPinPerWord*uint64((size+31)/32)
+}
+
+// handlePin exempts a stored ciphertext from collection. Input is the
+// handle (32).
+func (c *FHEContract) handlePin(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasPin {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	_, size, ok := ciphertextStoreFor(state).Stat(handle)
+	if !ok {
+		return nil, gas - GasPin, handleNotFound("pin", handle)
+	}
+	required := pinGas(size)
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	if db := stateDBFor(state); db != nil {
+		l := readLease(db, handle)
+		l.pinned = true
+		writeLease(db, handle, l)
+	}
+	return nil, gas - required, nil
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Result handles.
+//
+// On chain, a result handle is derived from the call that produced it
+// rather than from the ciphertext bytes:
+//
+//	h = keccak256(handleDomain || txHash || caller || selector || keccak256(args) || counter)
+//
+// where args holds the operand handles and other arguments of the call, and
+// counter numbers the handles stored in the transaction. The counter lives
+// in the precompile account's storage, so it is reverted with a failed call
+// and every validator derives the same handles, also after a reorg. Two
+// results with identical ciphertexts get distinct handles, and a caller
+// cannot choose a ciphertext whose handle collides with another one. Calls
+// without a StateDB (tools and tests) keep handles derived from the
+// ciphertext bytes.
+
+// Domain separators for handle derivation
+const (
+	handleDomain        = "lux.fhe.handle.v1"
+	handleCounterDomain = "lux.fhe.handle.counter.v1"
+)
+
+// callState carries the call a precompile run serves to the stores it
+// opens, so they can derive result handles from it and meter the storage
+// they write
+type callState struct {
+	contract.AccessibleState
+	caller common.Address
+	input  []byte // Selector and arguments
+
+	budget uint64 // Gas the call may spend on ciphertext storage
+	stored uint64 // Gas charged for ciphertext storage so far
+	err    error  // Set when a write was refused for lack of gas
+}
+
+// withCall returns state annotated with the call, or nil for a call
+// without state
+func withCall(state contract.AccessibleState, caller common.Address, input []byte, budget uint64) contract.AccessibleState {
+	if state == nil {
+		return nil
+	}
+	return &callState{AccessibleState: state, caller: caller, input: input, budget: budget}
+}
+
+// chargeStorage charges the storage of words 32-byte words to the call
+// before they are written. It refuses, and fails the call, once the
+// storage budget is spent.
+func (c *callState) chargeStorage(words uint64) bool {
+	cost := words * GasStoreWord
+	if c.err != nil || cost > c.budget-c.stored {
+		c.err = ErrInsufficientGas
+		return false
+	}
+	c.stored += cost
+	return true
+}
+
+// handleCounterSlot is the transient storage slot numbering the handles
+// stored in the transaction
+var handleCounterSlot = crypto.Keccak256Hash([]byte(handleCounterDomain))
+
+// deriveHandle returns the next result handle of the call s serves, or
+// fallback when s has no call
+func (s *StateCiphertextStore) deriveHandle(fallback common.Hash) common.Hash {
+	if s.call == nil {
+		return fallback
+	}
+	txHash := s.db.TxHash()
+	counter := binary.BigEndian.Uint64(s.db.GetTransientState(s.addr, handleCounterSlot).Bytes()[24:])
+	s.db.SetTransientState(s.addr, handleCounterSlot, common.BytesToHash(binary.BigEndian.AppendUint64(nil, counter+1)))
+
+	input := s.call.input
+	return crypto.Keccak256Hash(
+		[]byte(handleDomain),
+		txHash.Bytes(),
+		s.call.caller.Bytes(),
+		input[:min(4, len(input))],
+		crypto.Keccak256(input[min(4, len(input)):]),
+		binary.BigEndian.AppendUint64(nil, counter),
+	)
+}
+
+// resultHandle returns the handle a result stored in store gets: derived
+// from the call on chain, fallback otherwise
+func resultHandle(store CiphertextBackend, fallback common.Hash) common.Hash {
+	if s, ok := store.(*StateCiphertextStore); ok {
+		return s.deriveHandle(fallback)
+	}
+	return fallback
+}
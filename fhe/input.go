@@ -0,0 +1,259 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Verified ciphertext inputs.
+//
+// A user-supplied ciphertext is only accepted together with a proof that
+// its author knows the plaintext (ZKPoK). The input to verify is
+//
+//	ciphertext || proof || uint32 proof length
+//
+// and the proof is
+//
+//	version (1) || scheme (1) || type (1) || bits (2) || nonce (32) || body
+//
+// The header must match the claimed ciphertext type and its bit width. The
+// body is checked by the verifier registered for the scheme against the
+// input digest, which binds the ciphertext, its type, the calling contract
+// and the nonce. Each digest is accepted once, so a proof cannot be
+// replayed by another caller or a second time by the same one.
+//
+// The precompile has no native ZKPoK verifier. ProofSchemeAttested, the
+// only scheme accepted, carries signatures from the committee of input
+// verifiers configured with inputAttestors and inputThreshold, which check
+// the ZKPoK off chain. Without that committee every input is rejected.
+
+// Input proof schemes
+const (
+	ProofSchemeAttested uint8 = 1 // Committee attestation of an off-chain ZKPoK check
+)
+
+// GasVerifyInput is the cost of verifying and storing an input ciphertext
+const GasVerifyInput uint64 = 150000
+
+const (
+	inputProofVersion    = 1
+	inputProofHeaderSize = 37
+	inputProofDomain     = "lux.fhe.input.v1"
+	inputUsedDomain      = "lux.fhe.input.used.v1"
+)
+
+var (
+	ErrInvalidProof       = errors.New("invalid input proof")
+	ErrProofReplayed      = errors.New("input proof already used")
+	ErrUnknownProofScheme = errors.New("unknown input proof scheme")
+	ErrNoInputVerifier    = errors.New("no input verifiers configured")
+)
+
+// InputProof is the proof attached to an input ciphertext
+type InputProof struct {
+	Version uint8
+	Scheme  uint8
+	CtType  uint8
+	Bits    uint16
+	Nonce   common.Hash
+	Body    []byte // Scheme-specific
+}
+
+// Encode serializes the proof
+func (p *InputProof) Encode() []byte {
+	out := make([]byte, inputProofHeaderSize, inputProofHeaderSize+len(p.Body))
+	out[0], out[1], out[2] = p.Version, p.Scheme, p.CtType
+	binary.BigEndian.PutUint16(out[3:5], p.Bits)
+	copy(out[5:37], p.Nonce.Bytes())
+	return append(out, p.Body...)
+}
+
+// DecodeInputProof parses a serialized proof
+func DecodeInputProof(data []byte) (*InputProof, error) {
+	if len(data) < inputProofHeaderSize || data[0] != inputProofVersion {
+		return nil, ErrInvalidProof
+	}
+	return &InputProof{
+		Version: data[0],
+		Scheme:  data[1],
+		CtType:  data[2],
+		Bits:    binary.BigEndian.Uint16(data[3:5]),
+		Nonce:   common.BytesToHash(data[5:37]),
+		Body:    append([]byte(nil), data[37:]...),
+	}, nil
+}
+
+// EncodeInput appends proof to ct in the layout verify expects
+func EncodeInput(ct []byte, proof *InputProof) []byte {
+	encoded := proof.Encode()
+	out := make([]byte, 0, len(ct)+len(encoded)+4)
+	out = append(out, ct...)
+	out = append(out, encoded...)
+	return binary.BigEndian.AppendUint32(out, uint32(len(encoded)))
+}
+
+// SplitInput separates an input into its ciphertext and proof
+func SplitInput(input []byte) ([]byte, *InputProof, error) {
+	if len(input) < 4 {
+		return nil, nil, ErrInvalidInput
+	}
+	proofLen := uint64(binary.BigEndian.Uint32(input[len(input)-4:]))
+	if proofLen > uint64(len(input)-4) {
+		return nil, nil, ErrInvalidInput
+	}
+	split := len(input) - 4 - int(proofLen)
+	if split == 0 {
+		return nil, nil, ErrInvalidInput
+	}
+	proof, err := DecodeInputProof(input[split : len(input)-4])
+	if err != nil {
+		return nil, nil, err
+	}
+	return input[:split], proof, nil
+}
+
+// InputDigest returns the digest an input proof is bound to
+func InputDigest(ct []byte, ctType uint8, caller common.Address, nonce common.Hash) common.Hash {
+	return crypto.Keccak256Hash(
+		[]byte(inputProofDomain),
+		[]byte{ctType},
+		crypto.Keccak256(ct),
+		caller.Bytes(),
+		nonce.Bytes(),
+	)
+}
+
+// typeBitWidth returns the plaintext bit width of a ciphertext type
+func typeBitWidth(ctType uint8) (uint16, bool) {
+	switch ctType {
+	case TypeEbool:
+		return 1, true
+	case TypeEuint4:
+		return 4, true
+	case TypeEuint8:
+		return 8, true
+	case TypeEuint16:
+		return 16, true
+	case TypeEuint32:
+		return 32, true
+	case TypeEuint64:
+		return 64, true
+	case TypeEuint128:
+		return 128, true
+	case TypeEuint160:
+		return 160, true
+	case TypeEuint256:
+		return 256, true
+	default:
+		return 0, false
+	}
+}
+
+// inputVerifier is the configured input verifier committee; nil rejects
+// every input
+var inputVerifier *AttestedInputVerifier
+
+// SetInputVerifier sets the committee whose attestations input proofs
+// carry, or rejects every input when v is nil
+func SetInputVerifier(v *AttestedInputVerifier) {
+	inputVerifier = v
+}
+
+// AttestedInputVerifier accepts proofs signed by a threshold of input
+// verifiers. The body is signature count (1) || signatures (65 each) over
+// the input digest.
+type AttestedInputVerifier struct {
+	attestors map[common.Address]bool
+	threshold int
+}
+
+// NewAttestedInputVerifier creates a verifier requiring threshold of the
+// given attestors
+func NewAttestedInputVerifier(attestors []common.Address, threshold int) (*AttestedInputVerifier, error) {
+	if err := verifyAttestors(attestors, threshold); err != nil {
+		return nil, err
+	}
+	set := make(map[common.Address]bool, len(attestors))
+	for _, a := range attestors {
+		set[a] = true
+	}
+	return &AttestedInputVerifier{attestors: set, threshold: threshold}, nil
+}
+
+// VerifyInputProof checks the signatures in the body of proof over digest
+func (v *AttestedInputVerifier) VerifyInputProof(digest common.Hash, proof *InputProof) error {
+	if len(proof.Body) < 1 || len(proof.Body) != 1+int(proof.Body[0])*65 {
+		return ErrInvalidProof
+	}
+	signatures := make([][]byte, proof.Body[0])
+	for i := range signatures {
+		signatures[i] = proof.Body[1+i*65 : 1+(i+1)*65]
+	}
+	if countAttestors(v.attestors, digest, signatures) < v.threshold {
+		return ErrAttestation
+	}
+	return nil
+}
+
+// verifyInput checks the proof of an input ciphertext for caller and
+// returns the digest it is bound to
+func verifyInput(ct []byte, proof *InputProof, ctType uint8, caller common.Address) (common.Hash, error) {
+	bits, ok := typeBitWidth(ctType)
+	if !ok || proof.CtType != ctType || proof.Bits != bits {
+		return common.Hash{}, ErrInvalidProof
+	}
+	if !tfheVerify(ct, ctType) {
+		return common.Hash{}, ErrInvalidCiphertext
+	}
+
+	if proof.Scheme != ProofSchemeAttested {
+		return common.Hash{}, ErrUnknownProofScheme
+	}
+	v := inputVerifier
+	if v == nil {
+		return common.Hash{}, ErrNoInputVerifier
+	}
+
+	digest := InputDigest(ct, ctType, caller, proof.Nonce)
+	if err := v.VerifyInputProof(digest, proof); err != nil {
+		return common.Hash{}, err
+	}
+	return digest, nil
+}
+
+// usedInputs records consumed digests for calls without a StateDB
+var (
+	usedInputsMu sync.Mutex
+	usedInputs   = make(map[common.Hash]bool)
+)
+
+// consumeInput marks an input digest as used, failing if it already was.
+// On chain the record lives in the input verifier's storage.
+func consumeInput(state contract.AccessibleState, digest common.Hash) error {
+	if state != nil {
+		if db := state.GetStateDB(); db != nil {
+			slot := crypto.Keccak256Hash([]byte(inputUsedDomain), digest.Bytes())
+			if db.GetState(InputVerifierAddress, slot) != (common.Hash{}) {
+				return ErrProofReplayed
+			}
+			db.SetState(InputVerifierAddress, slot, common.Hash{31: 1})
+			return nil
+		}
+	}
+
+	usedInputsMu.Lock()
+	defer usedInputsMu.Unlock()
+	if usedInputs[digest] {
+		return ErrProofReplayed
+	}
+	usedInputs[digest] = true
+	return nil
+}
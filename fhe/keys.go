@@ -0,0 +1,229 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Network key epochs.
+//
+// The network TFHE key is versioned by epoch. Epoch 0 is the initial key;
+// each rotation adds the next epoch with the block it activates at. The
+// KeyRegistry keeps the schedule in the storage of the FHE precompile
+// account, so every node agrees on the key of every block: before each
+// call Run selects the evaluator's key set of the block's epoch, and fails
+// with ErrKeyNotLoaded if the node has not loaded it. Each epoch takes two
+// slots after
+//
+//	record = keccak256(recordDomain || epoch)
+//
+// holding the hash of its public key and its activation block; a counter
+// holds the latest epoch.
+//
+// Every stored ciphertext records the epoch it was stored in. Reading one
+// from another epoch key-switches it to the active key (tfheSwitchKey), and
+// migrate(handle) writes the switched ciphertext back under its handle, so
+// ACL grants, leases and handles held by contracts stay valid.
+
+// Key gas costs
+const (
+	GasPublicKeyPerWord uint64 = 200   // One 32-byte word of the public key
+	GasMigrate          uint64 = 50000 // Key switch, one bootstrapped gate per bit at most
+)
+
+// Domain separators for key registry storage
+const (
+	keysRecordDomain = "lux.fhe.keys.record.v1"
+	keysLatestDomain = "lux.fhe.keys.latest.v1"
+)
+
+// Key record word offsets
+const (
+	keysWordHash = iota
+	keysWordActivation
+)
+
+var ErrKeySchedule = errors.New("invalid network key rotation schedule")
+
+// KeyEpoch is a network key epoch in the registry
+type KeyEpoch struct {
+	Epoch       uint64
+	KeyHash     common.Hash // keccak256 of the public key; zero if not recorded
+	ActivatesAt uint64      // First block the key serves
+}
+
+// KeyRegistry is the network key schedule in a StateDB
+type KeyRegistry struct {
+	db contract.StateDB
+}
+
+// NewKeyRegistry returns the key registry in the FHE precompile's storage
+func NewKeyRegistry(db contract.StateDB) *KeyRegistry {
+	return &KeyRegistry{db: db}
+}
+
+func keysRecordSlot(epoch uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte(keysRecordDomain), binary.BigEndian.AppendUint64(nil, epoch))
+}
+
+// Latest returns the latest scheduled epoch
+func (r *KeyRegistry) Latest() uint64 {
+	return getCounter(r.db, counterSlot(keysLatestDomain))
+}
+
+// Epoch returns a scheduled epoch
+func (r *KeyRegistry) Epoch(epoch uint64) (KeyEpoch, bool) {
+	if epoch > r.Latest() {
+		return KeyEpoch{}, false
+	}
+	record := keysRecordSlot(epoch)
+	activation := r.db.GetState(ContractAddress, ciphertextDataSlot(record, keysWordActivation))
+	return KeyEpoch{
+		Epoch:       epoch,
+		KeyHash:     r.db.GetState(ContractAddress, ciphertextDataSlot(record, keysWordHash)),
+		ActivatesAt: binary.BigEndian.Uint64(activation[24:]),
+	}, true
+}
+
+// SetInitialKey records the public key hash of epoch 0
+func (r *KeyRegistry) SetInitialKey(keyHash common.Hash) {
+	r.db.SetState(ContractAddress, ciphertextDataSlot(keysRecordSlot(0), keysWordHash), keyHash)
+}
+
+// ScheduleRotation adds the next epoch, whose key has the given public key
+// hash and serves from block activatesAt on. Activation blocks must
+// increase with the epoch. It returns the new epoch.
+func (r *KeyRegistry) ScheduleRotation(keyHash common.Hash, activatesAt uint64) (uint64, error) {
+	latest, _ := r.Epoch(r.Latest())
+	if keyHash == (common.Hash{}) || activatesAt <= latest.ActivatesAt {
+		return 0, ErrKeySchedule
+	}
+
+	epoch := latest.Epoch + 1
+	record := keysRecordSlot(epoch)
+	r.db.SetState(ContractAddress, ciphertextDataSlot(record, keysWordHash), keyHash)
+	r.db.SetState(ContractAddress, ciphertextDataSlot(record, keysWordActivation), common.BytesToHash(binary.BigEndian.AppendUint64(nil, activatesAt)))
+	setCounter(r.db, counterSlot(keysLatestDomain), epoch)
+	return epoch, nil
+}
+
+// ActiveEpoch returns the epoch whose key serves block number
+func (r *KeyRegistry) ActiveEpoch(number uint64) uint64 {
+	for epoch := r.Latest(); epoch > 0; epoch-- {
+		if e, _ := r.Epoch(epoch); e.ActivatesAt <= number {
+			return epoch
+		}
+	}
+	return 0
+}
+
+// keyEpochFor returns the epoch of the block a call runs in. Calls without
+// state run under epoch 0, and calls without a block context under the
+// epochs activating at block 0.
+func keyEpochFor(state contract.AccessibleState) uint64 {
+	db := stateDBFor(state)
+	if db == nil {
+		return 0
+	}
+	var number uint64
+	if bc := state.GetBlockContext(); bc != nil && bc.Number() != nil {
+		number = bc.Number().Uint64()
+	}
+	return NewKeyRegistry(db).ActiveEpoch(number)
+}
+
+// networkPublicKeyGas returns the gas of reading a public key of size bytes
+func networkPublicKeyGas(size int) uint64 {
+	return GasHandleQuery + GasPublicKeyPerWord*uint64((size+31)/32)
+}
+
+// handleKeyEpoch returns the active key epoch as a uint256
+func (c *FHEContract) handleKeyEpoch(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if gas < GasHandleQuery {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	return abiWord(currentKeyEpoch()), gas - GasHandleQuery, nil
+}
+
+// handleNetworkPublicKey returns the public key of the active epoch
+func (c *FHEContract) handleNetworkPublicKey(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if gas < GasHandleQuery {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	key := tfheGetNetworkPublicKey()
+	if key == nil {
+		return nil, gas - GasHandleQuery, opFailed("networkPublicKey")
+	}
+	required := networkPublicKeyGas(len(key))
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+	return key, gas - required, nil
+}
+
+// handleEpochOf returns the key epoch a handle's ciphertext was stored in
+// as a uint256. Pending handles will be stored in the current epoch.
+func (c *FHEContract) handleEpochOf(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasHandleQuery {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	if _, _, ok := handleStat(state, handle); !ok {
+		return nil, gas - GasHandleQuery, handleNotFound("epochOf", handle)
+	}
+	epoch := currentKeyEpoch()
+	if db := stateDBFor(state); db != nil {
+		if stored, ok := NewStateCiphertextStore(db).Epoch(handle); ok {
+			epoch = stored
+		}
+	}
+	return abiWord(epoch), gas - GasHandleQuery, nil
+}
+
+// handleMigrate key-switches a stored ciphertext to the active epoch and
+// writes it back under its handle, keeping its trivial flag. Ciphertexts
+// already in the active epoch are left alone. Input is the handle.
+func (c *FHEContract) handleMigrate(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasMigrate {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	store, ok := ciphertextStoreFor(state).(*StateCiphertextStore)
+	if !ok {
+		// The in-memory store holds ciphertexts of the active key only
+		return nil, gas - GasMigrate, nil
+	}
+	epoch, ok := store.Epoch(handle)
+	if !ok {
+		return nil, gas - GasMigrate, handleNotFound("migrate", handle)
+	}
+	if epoch != currentKeyEpoch() {
+		ct, ctType, ok := store.Get(handle)
+		if !ok {
+			return nil, gas - GasMigrate, opFailed("migrate")
+		}
+		trivial := store.Trivial(handle)
+		store.Put(handle, ct, ctType)
+		if trivial {
+			store.MarkTrivial(handle)
+		}
+	}
+	return nil, gas - GasMigrate, nil
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"math/big"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Handle metadata.
+//
+// typeOf, exists and sizeOf let a contract check a user-supplied bytes32
+// before operating on it. They read only the stored header, reveal nothing
+// about the plaintext and are not access controlled. A handle whose
+// coprocessor job is still pending exists and has its result type, but a
+// size of zero until the result is posted. typeOf fails for unknown
+// handles, as every type value is valid; sizeOf returns zero.
+
+// GasHandleQuery is the cost of a metadata query: one header read
+const GasHandleQuery uint64 = 2600
+
+// handleStat returns the type and ciphertext size of a handle
+func handleStat(state contract.AccessibleState, handle common.Hash) (uint8, int, bool) {
+	if ctType, size, ok := ciphertextStoreFor(state).Stat(handle); ok {
+		return ctType, size, true
+	}
+	if db := stateDBFor(state); coprocessor != nil && db != nil {
+		if job, ok := coprocessor.Job(db, handle); ok {
+			return job.ResultType, 0, true
+		}
+	}
+	return 0, 0, false
+}
+
+// handleTypeOf returns the ciphertext type of a handle as a uint256
+func (c *FHEContract) handleTypeOf(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasHandleQuery {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	ctType, _, ok := handleStat(state, common.BytesToHash(data[:32]))
+	if !ok {
+		return nil, gas - GasHandleQuery, handleNotFound("typeOf", common.BytesToHash(data[:32]))
+	}
+	ret := make([]byte, 32)
+	ret[31] = ctType
+	return ret, gas - GasHandleQuery, nil
+}
+
+// handleExists returns whether a handle refers to a ciphertext as an ABI
+// bool
+func (c *FHEContract) handleExists(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasHandleQuery {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	ret := make([]byte, 32)
+	if _, _, ok := handleStat(state, common.BytesToHash(data[:32])); ok {
+		ret[31] = 1
+	}
+	return ret, gas - GasHandleQuery, nil
+}
+
+// handleSizeOf returns the serialized ciphertext size of a handle in bytes
+// as a uint256
+func (c *FHEContract) handleSizeOf(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasHandleQuery {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	_, size, _ := handleStat(state, common.BytesToHash(data[:32]))
+	return common.BigToHash(big.NewInt(int64(size))).Bytes(), gas - GasHandleQuery, nil
+}
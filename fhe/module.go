@@ -5,6 +5,7 @@ package fhe
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/luxfi/geth/common"
 	"github.com/luxfi/precompile/contract"
@@ -33,6 +34,12 @@ var (
 // FHEPrecompile is a thread-safe singleton instance of FHEContract
 var FHEPrecompile contract.StatefulPrecompiledContract = &FHEContract{}
 
+// ACLConfigKey is the json config key of the ACL precompile
+const ACLConfigKey = "fheACLConfig"
+
+// ACLPrecompile is the ciphertext handle ACL at ACLContractAddress
+var ACLPrecompile contract.StatefulPrecompiledContract = &ACLContract{}
+
 // Module is the precompile module. It is used to register the precompile contract.
 var Module = modules.Module{
 	ConfigKey:    ConfigKey,
@@ -41,14 +48,27 @@ var Module = modules.Module{
 	Configurator: &configurator{},
 }
 
+// ACLModule is the ACL precompile module
+var ACLModule = modules.Module{
+	ConfigKey:    ACLConfigKey,
+	Address:      ACLContractAddress,
+	Contract:     ACLPrecompile,
+	Configurator: &aclConfigurator{},
+}
+
 type configurator struct{}
 
+type aclConfigurator struct{}
+
 func init() {
 	// Register the precompile module.
 	// Each precompile contract registers itself through [RegisterModule] function.
 	if err := modules.RegisterModule(Module); err != nil {
 		panic(err)
 	}
+	if err := modules.RegisterModule(ACLModule); err != nil {
+		panic(err)
+	}
 }
 
 // MakeConfig returns a new precompile config instance.
@@ -64,16 +84,113 @@ func (*configurator) Configure(chainConfig precompileconfig.ChainConfig, cfg pre
 		return fmt.Errorf("expected config type %T, got %T: %v", &Config{}, cfg, cfg)
 	}
 
-	// Initialize TFHE parameters if network key path is specified
-	if config.NetworkKeyPath != "" {
-		// TODO: Load network key from path
-		// This would be used for production deployments with shared network keys
+	// Serve the shared network keys, and record their schedule
+	if err := configureNetworkKeys(config, state); err != nil {
+		return err
 	}
 
-	// Initialize coprocessor connection if endpoint is specified
-	if config.CoprocessorEndpoint != "" {
-		// TODO: Connect to Z-Chain coprocessor for threshold decryption
+	// Queue operations for the Z-Chain coprocessor if attestors are configured
+	if len(config.CoprocessorAttestors) > 0 {
+		cp, err := NewCoprocessor(config.CoprocessorAttestors, config.CoprocessorThreshold)
+		if err != nil {
+			return err
+		}
+		SetCoprocessor(cp)
+	} else {
+		SetCoprocessor(nil)
 	}
 
+	// Deliver plaintexts through the threshold committee if one is configured
+	if len(config.DecryptionCommittee) > 0 {
+		oracle, err := NewDecryptionOracle(config.DecryptionCommittee, config.DecryptionThreshold)
+		if err != nil {
+			return err
+		}
+		SetDecryptionOracle(oracle)
+	} else {
+		SetDecryptionOracle(nil)
+	}
+
+	// Accept inputs attested by the input verifiers, and no others
+	v, err := NewAttestedInputVerifier(config.InputAttestors, config.InputThreshold)
+	if err != nil {
+		return err
+	}
+	SetInputVerifier(v)
+
+	// Key sealOutput randomness with the validators' shared secret
+	if config.SealKeyPath != "" {
+		secret, err := os.ReadFile(config.SealKeyPath)
+		if err != nil {
+			return fmt.Errorf("reading seal key: %w", err)
+		}
+		if err := SetSealKey(secret); err != nil {
+			return err
+		}
+	} else {
+		_ = SetSealKey(nil)
+	}
+
+	return nil
+}
+
+// configureNetworkKeys loads the key sets of the configured epochs and
+// schedules the rotations not yet in the key registry. Without a key path
+// the evaluator generates its epoch 0 keys.
+func configureNetworkKeys(config *Config, state contract.StateDB) error {
+	load := func(epoch uint64, path string) (common.Hash, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("reading network key of epoch %d: %w", epoch, err)
+		}
+		return LoadNetworkKeys(epoch, data)
+	}
+
+	var reg *KeyRegistry
+	if state != nil {
+		reg = NewKeyRegistry(state)
+	}
+	if config.NetworkKeyPath != "" {
+		hash, err := load(0, config.NetworkKeyPath)
+		if err != nil {
+			return err
+		}
+		if reg != nil {
+			reg.SetInitialKey(hash)
+		}
+	}
+	for i, r := range config.KeyRotations {
+		epoch := uint64(i + 1)
+		hash, err := load(epoch, r.KeyPath)
+		if err != nil {
+			return err
+		}
+		if reg == nil {
+			continue
+		}
+		if scheduled, ok := reg.Epoch(epoch); ok {
+			if scheduled.KeyHash != hash || scheduled.ActivatesAt != r.ActivationBlock {
+				return fmt.Errorf("%w: epoch %d differs from the registry", ErrKeySchedule, epoch)
+			}
+			continue
+		}
+		if _, err := reg.ScheduleRotation(hash, r.ActivationBlock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MakeConfig returns a new ACL precompile config instance.
+func (*aclConfigurator) MakeConfig() precompileconfig.Config {
+	return new(ACLConfig)
+}
+
+// Configure configures the ACL precompile. Permissions live in its storage,
+// so there is nothing to set up.
+func (*aclConfigurator) Configure(chainConfig precompileconfig.ChainConfig, cfg precompileconfig.Config, state contract.StateDB, blockContext contract.ConfigurationBlockContext) error {
+	if _, ok := cfg.(*ACLConfig); !ok {
+		return fmt.Errorf("expected config type %T, got %T: %v", &ACLConfig{}, cfg, cfg)
+	}
 	return nil
 }
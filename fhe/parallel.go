@@ -0,0 +1,285 @@
+// Copyright (C) 2019-2024, Lux Partners Limited. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/luxfi/geth/common"
+)
+
+// Parallel execution of independent FHE operations within one batch call.
+//
+// The executor serves the batch method (batch.go): transactions, and the
+// calls within a transaction, still run one after another as the EVM
+// executes them. Within a batch, evaluation touches no storage and stored
+// ciphertexts are never rewritten, so the only ordering constraint between
+// two operations is a data dependency: one consumes the result of the
+// other. The analyzer groups the batch's operations into levels, where
+// every operation in a level depends only on existing handles or on
+// results from earlier levels. The executor evaluates each level on a
+// worker pool and then commits all results to the ciphertext store in the
+// original operation order. Result handles are derived from the call and a
+// per-transaction counter (see handles.go), so committing in operation
+// order keeps both the handles and the store contents independent of
+// scheduling.
+//
+// Input handles are loaded from the store before any worker starts, since
+// the StateDB backing it on chain is not safe for concurrent use, and
+// operand types are checked before anything is evaluated. Each worker
+// evaluates with its own TFHE context (see fhe_ops.go). With a remote
+// evaluation backend installed (see backend.go), each level is sent to it
+// as one batch instead.
+
+// Operand is an input to a ParallelOp: either an existing ciphertext handle
+// or the result of an earlier operation in the same batch
+type Operand struct {
+	Handle   common.Hash // Existing handle (when !IsResult)
+	Result   int         // Index of the producing operation (when IsResult)
+	IsResult bool
+}
+
+// HandleOperand references a ciphertext already in the store
+func HandleOperand(handle common.Hash) Operand {
+	return Operand{Handle: handle}
+}
+
+// ResultOperand references the result of operation index in the batch
+func ResultOperand(index int) Operand {
+	return Operand{Result: index, IsResult: true}
+}
+
+// ParallelOp is a single FHE operation scheduled for batched execution
+type ParallelOp struct {
+	Op     string    // Operation name, as used by performFHE* ("add", "not", "select", "scalarAdd", "shl", "cast", ...)
+	Inputs []Operand // Ciphertext inputs
+	Scalar *big.Int  // Plaintext operand for scalar ops, shift amount for shifts
+	ToType uint8     // Target type for "cast"
+}
+
+// arity returns the number of ciphertext inputs the operation takes
+func (op *ParallelOp) arity() int {
+	switch op.Op {
+	case "add", "sub", "mul", "div", "rem", "lt", "gt", "eq", "ne", "le", "ge",
+		"and", "or", "xor", "min", "max":
+		return 2
+	case "select":
+		return 3
+	case "not", "neg", "cast",
+		"scalarAdd", "scalarSub", "scalarMul", "scalarDiv", "scalarRem",
+		"scalarLt", "scalarLe", "scalarGt", "scalarGe", "scalarEq", "scalarNe",
+		"shl", "shr", "rotl", "rotr":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AnalyzeDependencies groups ops into levels of mutually independent
+// operations. Each level lists op indices in ascending order, and every
+// result operand must refer to an earlier op.
+func AnalyzeDependencies(ops []ParallelOp) ([][]int, error) {
+	depth := make([]int, len(ops))
+	var levels [][]int
+
+	for i := range ops {
+		n := ops[i].arity()
+		if n == 0 || len(ops[i].Inputs) != n {
+			return nil, fmt.Errorf("%w: op %d (%q)", ErrInvalidInput, i, ops[i].Op)
+		}
+
+		level := 0
+		for _, in := range ops[i].Inputs {
+			if !in.IsResult {
+				continue
+			}
+			if in.Result < 0 || in.Result >= i {
+				return nil, fmt.Errorf("%w: op %d references result %d", ErrInvalidInput, i, in.Result)
+			}
+			if depth[in.Result]+1 > level {
+				level = depth[in.Result] + 1
+			}
+		}
+
+		depth[i] = level
+		if level == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[level] = append(levels[level], i)
+	}
+
+	return levels, nil
+}
+
+// ParallelExecutor evaluates batches of FHE operations on a worker pool
+type ParallelExecutor struct {
+	workers int
+}
+
+// NewParallelExecutor creates an executor with the given number of workers.
+// A non-positive count uses GOMAXPROCS.
+func NewParallelExecutor(workers int) *ParallelExecutor {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &ParallelExecutor{workers: workers}
+}
+
+// opResult is the output of a single evaluated operation
+type opResult struct {
+	ct     []byte
+	ctType uint8
+}
+
+// Execute evaluates ops and returns the handle of each op's result, in op
+// order. Either every result is committed to store or, if any operation
+// fails, none are.
+func (e *ParallelExecutor) Execute(store CiphertextBackend, ops []ParallelOp) ([]common.Hash, error) {
+	levels, err := AnalyzeDependencies(ops)
+	if err != nil {
+		return nil, err
+	}
+	if err := initTFHE(); err != nil {
+		return nil, err
+	}
+
+	inputs := make(map[common.Hash]opResult)
+	for i := range ops {
+		for _, in := range ops[i].Inputs {
+			if in.IsResult {
+				continue
+			}
+			if _, ok := inputs[in.Handle]; ok {
+				continue
+			}
+			ct, ctType, ok := getCiphertext(store, in.Handle)
+			if !ok {
+				return nil, fmt.Errorf("%w: op %d (%q)", ErrOperationFailed, i, ops[i].Op)
+			}
+			inputs[in.Handle] = opResult{ct: ct, ctType: ctType}
+		}
+	}
+	if err := checkParallelTypes(ops, inputs); err != nil {
+		return nil, err
+	}
+
+	results := make([]opResult, len(ops))
+	failed := make([]bool, len(ops))
+
+	// One TFHE context per worker, created on first use and reused across
+	// levels
+	var contexts []*tfheContext
+
+	for _, level := range levels {
+		if evalBackend != nil {
+			reqs := make([]EvalRequest, len(level))
+			for j, i := range level {
+				reqs[j] = evalRequestFor(&ops[i], inputs, results)
+			}
+			out, err := evalBackend.Evaluate(reqs)
+			if err != nil {
+				return nil, fmt.Errorf("%w: op %d (%q): %w", ErrOperationFailed, level[0], ops[level[0]].Op, err)
+			}
+			for j, i := range level {
+				results[i] = opResult{ct: out[j].Ciphertext, ctType: out[j].Type}
+			}
+			continue
+		}
+
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+
+		workers := e.workers
+		if workers > len(level) {
+			workers = len(level)
+		}
+		for len(contexts) < workers {
+			contexts = append(contexts, newTFHEContext())
+		}
+		for w := 0; w < workers; w++ {
+			tc := contexts[w]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					ct, ctType, ok := evalParallelOp(tc, &ops[i], inputs, results)
+					results[i] = opResult{ct: ct, ctType: ctType}
+					failed[i] = !ok
+				}
+			}()
+		}
+
+		for _, i := range level {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+
+		// Report the lowest failing index so errors are deterministic
+		for _, i := range level {
+			if failed[i] {
+				return nil, fmt.Errorf("%w: op %d (%q)", ErrOperationFailed, i, ops[i].Op)
+			}
+		}
+	}
+
+	handles := make([]common.Hash, len(ops))
+	for i, r := range results {
+		handles[i] = storeCiphertext(store, r.ct, r.ctType)
+	}
+	return handles, nil
+}
+
+// checkParallelTypes checks the operand types of every op before any is
+// evaluated. Result operands take the type their producing op yields.
+func checkParallelTypes(ops []ParallelOp, inputs map[common.Hash]opResult) error {
+	resultTypes := make([]uint8, len(ops))
+	for i := range ops {
+		op := &ops[i]
+		handles := make([]common.Hash, len(op.Inputs))
+		types := make([]uint8, len(op.Inputs))
+		for j, in := range op.Inputs {
+			if in.IsResult {
+				types[j] = resultTypes[in.Result]
+				continue
+			}
+			handles[j], types[j] = in.Handle, inputs[in.Handle].ctType
+		}
+		if err := checkOperandTypes(op.Op, handles, types); err != nil {
+			return fmt.Errorf("op %d: %w", i, err)
+		}
+		resultTypes[i] = evalResultType(&EvalRequest{Op: op.Op, Types: types, ToType: op.ToType})
+	}
+	return nil
+}
+
+// evalParallelOp evaluates a single operation in process with the worker's
+// context tc
+func evalParallelOp(tc *tfheContext, op *ParallelOp, inputs map[common.Hash]opResult, results []opResult) ([]byte, uint8, bool) {
+	req := evalRequestFor(op, inputs, results)
+	result, resultType := evalLocal(tc, &req)
+	if result == nil {
+		return nil, 0, false
+	}
+	return result, resultType, true
+}
+
+// evalRequestFor builds the request of a single operation. Inputs produced
+// by earlier levels are read from results; everything else from the
+// preloaded inputs.
+func evalRequestFor(op *ParallelOp, inputs map[common.Hash]opResult, results []opResult) EvalRequest {
+	cts := make([][]byte, len(op.Inputs))
+	types := make([]uint8, len(op.Inputs))
+	for j, in := range op.Inputs {
+		if in.IsResult {
+			cts[j], types[j] = results[in.Result].ct, results[in.Result].ctType
+			continue
+		}
+		cts[j], types[j] = inputs[in.Handle].ct, inputs[in.Handle].ctType
+	}
+	return EvalRequest{Op: op.Op, Inputs: cts, Types: types, Scalar: op.Scalar, ToType: op.ToType}
+}
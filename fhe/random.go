@@ -0,0 +1,149 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Encrypted randomness.
+//
+// rand and randBounded seed each draw from the hash of the previous block,
+// the block number, the transaction hash, a per-transaction draw counter
+// and the caller. Every validator must derive the same value, so a draw is
+// reproducible from public chain data; mixing in the block hash and the
+// counter keeps a contract from predicting it before its transaction is
+// included, and keeps two draws in one transaction distinct.
+//
+// randBounded(type, upperBound) returns a value uniform in [0, upperBound).
+// Power-of-two bounds are exact. Other bounds reduce a euint128 draw
+// (euint256 for the wider types), leaving a bias below upperBound/2^128.
+// The bound must be non-zero, fit in 64 bits and not exceed 2^bits of the
+// type.
+
+// GasRandBounded covers the wide draw, the reduction and the cast back
+const GasRandBounded = GasRand + GasRem + GasCast
+
+// Domain separators for random seeds
+const (
+	randSeedDomain    = "lux.fhe.rand.v1"
+	randCounterDomain = "lux.fhe.rand.counter.v1"
+)
+
+// BlockHashFunc returns the hash of a block by number. The host wires it
+// to the chain so draws can mix in block entropy.
+type BlockHashFunc func(number uint64) common.Hash
+
+// blockHashFunc is the active block hash source; nil leaves it out
+var blockHashFunc BlockHashFunc
+
+// SetBlockHashFunc sets the block hash source of random draws, or removes
+// it when fn is nil
+func SetBlockHashFunc(fn BlockHashFunc) {
+	blockHashFunc = fn
+}
+
+// randCounterSlot is the transient storage slot numbering the draws made
+// in the transaction
+var randCounterSlot = crypto.Keccak256Hash([]byte(randCounterDomain))
+
+// randCounter numbers draws of calls without a StateDB
+var randCounter atomic.Uint64
+
+// randomSeed derives the seed of the next draw by caller
+func randomSeed(state contract.AccessibleState, caller common.Address) []byte {
+	var (
+		blockNumber uint64
+		blockHash   common.Hash
+		txHash      common.Hash
+		counter     uint64
+	)
+	if state != nil {
+		if bc := state.GetBlockContext(); bc != nil && bc.Number() != nil {
+			blockNumber = bc.Number().Uint64()
+			if blockHashFunc != nil && blockNumber > 0 {
+				blockHash = blockHashFunc(blockNumber - 1)
+			}
+		}
+	}
+	if db := stateDBFor(state); db != nil {
+		txHash = db.TxHash()
+		counter = binary.BigEndian.Uint64(db.GetTransientState(ContractAddress, randCounterSlot).Bytes()[24:])
+		db.SetTransientState(ContractAddress, randCounterSlot, common.BytesToHash(binary.BigEndian.AppendUint64(nil, counter+1)))
+	} else {
+		counter = randCounter.Add(1) - 1
+	}
+
+	return crypto.Keccak256(
+		[]byte(randSeedDomain),
+		blockHash.Bytes(),
+		binary.BigEndian.AppendUint64(nil, blockNumber),
+		txHash.Bytes(),
+		binary.BigEndian.AppendUint64(nil, counter),
+		caller.Bytes(),
+	)
+}
+
+// stateDBFor returns the StateDB of a precompile call, or nil
+func stateDBFor(state contract.AccessibleState) contract.StateDB {
+	if state == nil {
+		return nil
+	}
+	return state.GetStateDB()
+}
+
+// computeRandBounded draws a ciphertext of ctType uniform in [0, bound)
+func computeRandBounded(ctType uint8, bound uint64, seed []byte) []byte {
+	if bound&(bound-1) == 0 {
+		r := tfheRandomSeed(ctType, seed)
+		if bits, _ := typeBitWidth(ctType); r == nil || bits < 64 && bound == 1<<bits {
+			return r
+		}
+		return tfheScalarRem(defaultTFHE(), r, bound, ctType)
+	}
+
+	wide := TypeEuint128
+	if ctType == TypeEuint128 || ctType == TypeEuint256 {
+		wide = TypeEuint256
+	}
+	r := tfheRandomSeed(wide, seed)
+	if r == nil {
+		return nil
+	}
+	if r = tfheScalarRem(defaultTFHE(), r, bound, wide); r == nil || wide == ctType {
+		return r
+	}
+	return tfheCast(defaultTFHE(), r, wide, ctType)
+}
+
+// handleRandBounded draws a random value below a bound. Input is packed as
+// type (1) || upperBound (32).
+func (c *FHEContract) handleRandBounded(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 33 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasRandBounded {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	ctType := data[0]
+	bound := new(big.Int).SetBytes(data[1:33])
+	bits, _ := typeBitWidth(ctType)
+	if !isUintType(ctType) || bound.Sign() == 0 || !bound.IsUint64() ||
+		new(big.Int).Sub(bound, big.NewInt(1)).BitLen() > int(bits) {
+		return nil, gas, ErrInvalidInput
+	}
+
+	result := computeRandBounded(ctType, bound.Uint64(), randomSeed(state, caller))
+	if result == nil {
+		return nil, gas - GasRandBounded, opFailed("randBounded")
+	}
+	return storeCiphertext(ciphertextStoreFor(state), result, ctType).Bytes(), gas - GasRandBounded, nil
+}
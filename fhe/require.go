@@ -0,0 +1,64 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"errors"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Encrypted requirements.
+//
+// require_(condition) asserts an ebool, such as the balance check of a
+// confidential transfer, without the contract seeing its plaintext. The
+// precompile decrypts the condition in the call and fails it with
+// ErrRequireFailed when it is false, so the transaction reverts with it.
+// This holds with a decryption committee configured too: the revert reveals
+// the condition's value whoever decrypts it, and a requirement decided after
+// the transaction could no longer revert it. require_(condition, message)
+// reverts with Error(message) instead.
+
+var ErrRequireFailed = errors.New("encrypted requirement is false")
+
+// RequireError is a failed requirement with the caller's message
+type RequireError struct {
+	Message string
+}
+
+func (e *RequireError) Error() string { return e.Message }
+
+func (e *RequireError) Unwrap() error { return ErrRequireFailed }
+
+// handleRequire asserts an encrypted condition. Input is condition (32)
+// followed by an optional revert message.
+func (c *FHEContract) handleRequire(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasRequire {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	handle := common.BytesToHash(data[:32])
+	ct, ctType, ok := getCiphertext(ciphertextStoreFor(state), handle)
+	if !ok {
+		return nil, gas - GasRequire, handleNotFound("require", handle)
+	}
+	if ctType != TypeEbool {
+		return nil, gas - GasRequire, typeMismatch("require", handle, TypeEbool, ctType)
+	}
+	plaintext := tfheDecrypt(ct, ctType)
+	if plaintext == nil {
+		return nil, gas - GasRequire, opFailed("require")
+	}
+	if plaintext.Sign() == 0 {
+		if len(data) > 32 {
+			return nil, gas - GasRequire, &RequireError{Message: string(data[32:])}
+		}
+		return nil, gas - GasRequire, ErrRequireFailed
+	}
+	return nil, gas - GasRequire, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Ciphertext re-randomization and refresh.
+//
+// rerandomize returns a new handle for the same plaintext whose ciphertext
+// shares no bytes with the original, so handles stored or emitted at
+// different points cannot be matched by comparing ciphertexts. The mask is
+// seeded from the transaction, the caller and the handle, as every
+// validator must compute the same result; the change is therefore
+// reproducible from public data and hides the link only from parties that
+// see the ciphertexts but not the chain.
+//
+// refresh bootstraps a ciphertext without changing its plaintext, resetting
+// the noise accumulated by long chains of operations. Both run inline, also
+// in coprocessor mode, and need final inputs.
+
+// Re-randomization gas costs
+const (
+	GasRerandomize uint64 = 100000 // Two XORs with a seeded mask
+	GasRefresh     uint64 = 50000  // One bootstrapped gate per bit
+)
+
+// rerandomizeDomain separates re-randomization seeds
+const rerandomizeDomain = "lux.fhe.rerandomize.v1"
+
+// rerandomizeSeed derives the mask seed of a re-randomization
+func rerandomizeSeed(state contract.AccessibleState, caller common.Address, handle common.Hash) []byte {
+	var txHash common.Hash
+	if state != nil {
+		if db := state.GetStateDB(); db != nil {
+			txHash = db.TxHash()
+		}
+	}
+	return crypto.Keccak256([]byte(rerandomizeDomain), txHash.Bytes(), caller.Bytes(), handle.Bytes())
+}
+
+func (c *FHEContract) handleRerandomize(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasRerandomize {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	store := ciphertextStoreFor(state)
+	handle := common.BytesToHash(data[:32])
+	ct, ctType, ok := getCiphertext(store, handle)
+	if !ok {
+		return nil, gas - GasRerandomize, handleNotFound("rerandomize", handle)
+	}
+
+	result := tfheRerandomize(defaultTFHE(), ct, ctType, rerandomizeSeed(state, caller, handle))
+	if result == nil {
+		return nil, gas - GasRerandomize, opFailed("rerandomize")
+	}
+	return storeCiphertext(store, result, ctType).Bytes(), gas - GasRerandomize, nil
+}
+
+func (c *FHEContract) handleRefresh(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < GasRefresh {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	store := ciphertextStoreFor(state)
+	handle := common.BytesToHash(data[:32])
+	ct, ctType, ok := getCiphertext(store, handle)
+	if !ok {
+		return nil, gas - GasRefresh, handleNotFound("refresh", handle)
+	}
+
+	result := tfheRefresh(defaultTFHE(), ct, ctType)
+	if result == nil {
+		return nil, gas - GasRefresh, opFailed("refresh")
+	}
+	return storeCiphertext(store, result, ctType).Bytes(), gas - GasRefresh, nil
+}
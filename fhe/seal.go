@@ -0,0 +1,267 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+	"github.com/luxfi/precompile/ecies"
+)
+
+// Sealed outputs.
+//
+// sealOutput(handle, scheme, publicKey) decrypts a ciphertext the caller may
+// use and encrypts the plaintext, as a 32-byte big-endian word, to a key of
+// the caller's, so only the key holder learns it. Every scheme binds the
+// context "lux.fhe.seal.v1" || handle:
+//
+//	SealECIES  ECIES over secp256k1 as the ecies precompile encodes it, with
+//	           s1 = context. Key: 65-byte uncompressed point. Output:
+//	           ephemeral key (65) || IV (16) || ciphertext (32) || HMAC (32)
+//	SealHPKE   RFC 9180 base mode, DHKEM(X25519, HKDF-SHA256), HKDF-SHA256,
+//	           AES-128-GCM, info = context. Key: 32 bytes. Output:
+//	           enc (32) || ciphertext (48)
+//	SealMLKEM  ML-KEM-768, then AES-256-GCM under SHA-256(context || shared
+//	           secret) with a zero nonce and context as associated data.
+//	           Key: 1184 bytes. Output: KEM ciphertext (1088) || ciphertext (48)
+//
+// Every validator must return the same bytes, so ephemeral keys are drawn
+// from a stream keyed by the seal key and the call. Seeds derived from
+// public data alone would let anyone recompute the ephemeral secret, so the
+// seal key is derived from a secret the validators share, read from the
+// sealKeyPath of the precompile config. Until it is configured sealOutput
+// fails with ErrNoSealKey.
+
+// Sealing schemes
+const (
+	SealECIES uint8 = 0x01
+	SealHPKE  uint8 = 0x02
+	SealMLKEM uint8 = 0x03
+)
+
+// Sealing gas costs: the decryption plus the scheme's key agreement
+const (
+	GasSealECIES = GasEncrypt + 10000
+	GasSealHPKE  = GasEncrypt + 5000
+	GasSealMLKEM = GasEncrypt + 15000
+)
+
+// Domain separators for sealing
+const (
+	sealContextDomain = "lux.fhe.seal.v1"
+	sealKeyDomain     = "lux.fhe.seal.key.v1"
+)
+
+// MinSealSecretSize is the minimum size of the shared seal secret
+const MinSealSecretSize = 32
+
+var (
+	ErrNoSealKey    = errors.New("no seal key configured")
+	ErrShortSealKey = errors.New("seal secret too short")
+)
+
+// sealKey keys the sealing randomness; nil until configured
+var sealKey []byte
+
+// SetSealKey derives the key of the sealing randomness from a secret the
+// validators share, or removes it when secret is nil. Validators must share
+// the secret for sealOutput to be deterministic.
+func SetSealKey(secret []byte) error {
+	if secret == nil {
+		sealKey = nil
+		return nil
+	}
+	if len(secret) < MinSealSecretSize {
+		return ErrShortSealKey
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(sealKeyDomain))
+	sealKey = mac.Sum(nil)
+	return nil
+}
+
+// sealGas returns the gas of sealing under scheme
+func sealGas(scheme uint8) (uint64, bool) {
+	switch scheme {
+	case SealECIES:
+		return GasSealECIES, true
+	case SealHPKE:
+		return GasSealHPKE, true
+	case SealMLKEM:
+		return GasSealMLKEM, true
+	default:
+		return 0, false
+	}
+}
+
+// sealKeySize returns the public key size of scheme
+func sealKeySize(scheme uint8) int {
+	switch scheme {
+	case SealECIES:
+		return 65
+	case SealHPKE:
+		return 32
+	case SealMLKEM:
+		return mlkem768.PublicKeySize
+	default:
+		return 0
+	}
+}
+
+// sealStream is the keccak counter-mode stream sealing draws from
+type sealStream struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (s *sealStream) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.buf) == 0 {
+			s.buf = crypto.Keccak256(s.seed, binary.BigEndian.AppendUint64(nil, s.counter))
+			s.counter++
+		}
+		c := copy(p[n:], s.buf)
+		s.buf = s.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// sealRandom returns the stream a sealOutput call draws from
+func sealRandom(state contract.AccessibleState, caller common.Address, handle common.Hash, scheme uint8, publicKey []byte) io.Reader {
+	var txHash common.Hash
+	if db := stateDBFor(state); db != nil {
+		txHash = db.TxHash()
+	}
+	mac := hmac.New(sha256.New, sealKey)
+	mac.Write([]byte(sealContextDomain))
+	mac.Write(txHash.Bytes())
+	mac.Write(caller.Bytes())
+	mac.Write(handle.Bytes())
+	mac.Write([]byte{scheme})
+	mac.Write(publicKey)
+	return &sealStream{seed: mac.Sum(nil)}
+}
+
+// sealContext returns the context a sealed output of handle is bound to
+func sealContext(handle common.Hash) []byte {
+	return append([]byte(sealContextDomain), handle.Bytes()...)
+}
+
+// sealPlaintext encrypts plaintext to publicKey under scheme
+func sealPlaintext(scheme uint8, publicKey, plaintext, context []byte, rng io.Reader) ([]byte, error) {
+	switch scheme {
+	case SealECIES:
+		return ecies.EncryptFrom(rng, ecies.CurveSecp256k1, publicKey, context, plaintext)
+
+	case SealHPKE:
+		suite := hpke.NewSuite(hpke.KEM_X25519_HKDF_SHA256, hpke.KDF_HKDF_SHA256, hpke.AEAD_AES128GCM)
+		kem, _, _ := suite.Params()
+		pk, err := kem.Scheme().UnmarshalBinaryPublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		sender, err := suite.NewSender(pk, context)
+		if err != nil {
+			return nil, err
+		}
+		enc, sealer, err := sender.Setup(rng)
+		if err != nil {
+			return nil, err
+		}
+		ct, err := sealer.Seal(plaintext, nil)
+		if err != nil {
+			return nil, err
+		}
+		return append(enc, ct...), nil
+
+	case SealMLKEM:
+		kem := mlkem768.Scheme()
+		pk, err := kem.UnmarshalBinaryPublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		seed := make([]byte, kem.EncapsulationSeedSize())
+		if _, err := io.ReadFull(rng, seed); err != nil {
+			return nil, err
+		}
+		kemCt, shared, err := kem.EncapsulateDeterministically(pk, seed)
+		if err != nil {
+			return nil, err
+		}
+		key := sha256.Sum256(append(append([]byte(nil), context...), shared...))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return gcm.Seal(append([]byte(nil), kemCt...), make([]byte, gcm.NonceSize()), plaintext, context), nil
+
+	default:
+		return nil, ErrInvalidInput
+	}
+}
+
+// handleSealOutput seals a plaintext for the holder of a public key. Input
+// is handle (32) || scheme (1) || publicKey.
+func (c *FHEContract) handleSealOutput(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 33 {
+		return nil, gas, ErrInvalidInput
+	}
+	scheme := data[32]
+	required, ok := sealGas(scheme)
+	if !ok || len(data)-33 != sealKeySize(scheme) {
+		return nil, gas, ErrInvalidInput
+	}
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+	if sealKey == nil {
+		return nil, gas - required, ErrNoSealKey
+	}
+
+	handle := common.BytesToHash(data[:32])
+	publicKey := data[33:]
+	result, err := performFHESealOutput(ciphertextStoreFor(state), handle, scheme, publicKey, sealRandom(state, caller, handle, scheme, publicKey))
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result, gas - required, nil
+}
+
+// performFHESealOutput decrypts the ciphertext under handle and seals the
+// plaintext to publicKey, drawing ephemeral keys from rng
+func performFHESealOutput(store CiphertextBackend, handle common.Hash, scheme uint8, publicKey []byte, rng io.Reader) ([]byte, error) {
+	ct, ctType, ok := getCiphertext(store, handle)
+	if !ok {
+		return nil, handleNotFound("sealOutput", handle)
+	}
+	plaintext := tfheDecrypt(ct, ctType)
+	if plaintext == nil {
+		return nil, opFailed("sealOutput")
+	}
+
+	sealed, err := sealPlaintext(scheme, publicKey, common.BigToHash(plaintext).Bytes(), sealContext(handle), rng)
+	if err != nil {
+		return nil, &OpError{Op: "sealOutput", Err: fmt.Errorf("%w: %w", ErrOperationFailed, err)}
+	}
+	return sealed, nil
+}
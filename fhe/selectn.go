@@ -0,0 +1,92 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"math/bits"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Multi-way selection.
+//
+// selectN(index, candidates) returns candidates[index] for an encrypted
+// unsigned index, replacing a chain of nested select calls with one call.
+// The precompile extracts the index bits and folds the candidates in a CMUX
+// tree of n-1 selects, log2(n) levels deep. Candidates must share a type;
+// the index may be any unsigned type wide enough to address all of them.
+// An index of n or more yields an encryption of zero. Like batches, selectN
+// is evaluated inline even in coprocessor mode, so its inputs must be final.
+
+// MaxSelectCandidates bounds the number of candidates of selectN
+const MaxSelectCandidates = 256
+
+// selectNGas returns the gas of selectN over n candidates: one and plus one
+// eq per index bit, n-1 tree selects, and the range check
+func selectNGas(n uint64) uint64 {
+	depth := uint64(bits.Len64(n - 1))
+	return (GasAnd+GasEq)*depth + GasSelect*n + GasLt
+}
+
+// handleSelectN selects one of several candidates by an encrypted index.
+// Input is index (32) || ABI-encoded bytes32[] candidates, with the array
+// offset relative to the start of the arguments.
+func (c *FHEContract) handleSelectN(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	if len(data) < 32 {
+		return nil, gas, ErrInvalidInput
+	}
+	candidates, err := decodeHandleArrayArg(data, 32)
+	if err != nil {
+		return nil, gas, err
+	}
+	if len(candidates) == 0 || len(candidates) > MaxSelectCandidates {
+		return nil, gas, ErrInvalidInput
+	}
+	required := selectNGas(uint64(len(candidates)))
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	result, err := performFHESelectN(ciphertextStoreFor(state), common.BytesToHash(data[:32]), candidates)
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
+}
+
+// performFHESelectN loads the index and candidates of selectN and stores
+// the selected ciphertext
+func performFHESelectN(store CiphertextBackend, index common.Hash, candidates []common.Hash) (common.Hash, error) {
+	ctIndex, indexType, ok := getCiphertext(store, index)
+	if !ok {
+		return common.Hash{}, handleNotFound("selectN", index)
+	}
+	width, _ := typeBitWidth(indexType)
+	depth := bits.Len(uint(len(candidates) - 1))
+	if !isUintType(indexType) || depth > int(width) {
+		return common.Hash{}, &OpError{Op: "selectN", Err: ErrInvalidInput}
+	}
+
+	cts := make([][]byte, len(candidates))
+	var ctType uint8
+	for i, h := range candidates {
+		ct, t, ok := getCiphertext(store, h)
+		if !ok {
+			return common.Hash{}, handleNotFound("selectN", h)
+		}
+		if i > 0 && t != ctType {
+			return common.Hash{}, typeMismatch("selectN", h, ctType, t)
+		}
+		cts[i], ctType = ct, t
+	}
+
+	// Out-of-range indexes exist unless the index type holds exactly n values
+	guard := int(width) > depth || len(candidates) < 1<<depth
+	result := tfheSelectN(defaultTFHE(), ctIndex, indexType, cts, ctType, depth, guard)
+	if result == nil {
+		return common.Hash{}, opFailed("selectN")
+	}
+	return storeCiphertext(store, result, ctType), nil
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"math/bits"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Shifts by encrypted amounts.
+//
+// shlEnc, shrEnc, rotlEnc and rotrEnc(a, amount) shift or rotate a by an
+// encrypted unsigned amount, so the distance stays private. The precompile
+// builds a barrel shifter: for each bit k of the amount below log2 of a's
+// width it extracts the bit and selects between the running value and the
+// value moved by 2^k. The amount is taken modulo a's width, as with the
+// public shifts, and may be of any unsigned type; bits above the width are
+// ignored. The amount must be readable by the caller like any operand.
+
+// maxShiftDepth is the barrel depth of the widest shiftable type
+const maxShiftDepth = 8
+
+// shiftEncGas returns the gas of a shift by an encrypted amount over depth
+// amount bits: one and plus one eq to extract each bit, the shift by its
+// weight and the select
+func shiftEncGas(depth int) uint64 {
+	return (GasAnd + GasEq + GasShl + GasSelect) * uint64(depth)
+}
+
+// shiftDepth returns the number of amount bits a shift of ctType reads
+func shiftDepth(ctType uint8) int {
+	width, _ := typeBitWidth(ctType)
+	return bits.Len16(width) - 1
+}
+
+func (c *FHEContract) handleShlEnc(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	return handleShiftEnc(state, "shlEnc", data, gas)
+}
+
+func (c *FHEContract) handleShrEnc(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	return handleShiftEnc(state, "shrEnc", data, gas)
+}
+
+func (c *FHEContract) handleRotlEnc(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	return handleShiftEnc(state, "rotlEnc", data, gas)
+}
+
+func (c *FHEContract) handleRotrEnc(state contract.AccessibleState, caller common.Address, data []byte, gas uint64, readOnly bool) ([]byte, uint64, error) {
+	return handleShiftEnc(state, "rotrEnc", data, gas)
+}
+
+// handleShiftEnc shifts a ciphertext by an encrypted amount. Input is
+// a (32) || amount (32). Gas depends on the width of a.
+func handleShiftEnc(state contract.AccessibleState, op string, data []byte, gas uint64) ([]byte, uint64, error) {
+	if len(data) < 64 {
+		return nil, gas, ErrInvalidInput
+	}
+	minimum := shiftEncGas(1)
+	if gas < minimum {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	store := ciphertextStoreFor(state)
+	handle := common.BytesToHash(data[:32])
+	ctType, _, ok := store.Stat(handle)
+	if !ok {
+		return nil, gas - minimum, handleNotFound(op, handle)
+	}
+	required := shiftEncGas(shiftDepth(ctType))
+	if gas < required {
+		return nil, gas, ErrInsufficientGas
+	}
+
+	result, err := performFHEShiftEnc(store, op, handle, common.BytesToHash(data[32:64]))
+	if err != nil {
+		return nil, gas - required, err
+	}
+	return result.Bytes(), gas - required, nil
+}
+
+// performFHEShiftEnc loads the operands of an encrypted shift and stores
+// the shifted ciphertext
+func performFHEShiftEnc(store CiphertextBackend, op string, handle, amount common.Hash) (common.Hash, error) {
+	ct, ctType, ok := getCiphertext(store, handle)
+	if !ok {
+		return common.Hash{}, handleNotFound(op, handle)
+	}
+	ctAmount, amountType, ok := getCiphertext(store, amount)
+	if !ok {
+		return common.Hash{}, handleNotFound(op, amount)
+	}
+	if !isUintType(ctType) || !isUintType(amountType) {
+		return common.Hash{}, &OpError{Op: op, Err: ErrInvalidInput}
+	}
+
+	// A narrow amount cannot reach the upper barrel stages
+	depth := shiftDepth(ctType)
+	if width, _ := typeBitWidth(amountType); int(width) < depth {
+		depth = int(width)
+	}
+
+	result := tfheShiftEnc(defaultTFHE(), op, ct, ctType, ctAmount, amountType, depth)
+	if result == nil {
+		return common.Hash{}, opFailed(op)
+	}
+	return storeCiphertext(store, result, ctType), nil
+}
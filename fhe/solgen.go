@@ -0,0 +1,706 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Solidity library generation
+//
+// GenerateSolidity emits FHE.sol, a TFHE.sol-style library of typed
+// wrappers (euint8, ebool, eaddress, ...) over the precompile. Every
+// wrapper is derived from Methods and ACLMethods, and the generator checks
+// each method's Args and Result against the shape its OpClass implies, so
+// a change to the dispatch table either regenerates a matching library or
+// fails generation. The encrypted types are attached with
+// `using FHE for T global`, which gives contracts method-call syntax such
+// as a.add(b).le(c).
+//
+// On top of the euint128 wrappers the library defines efixed64x18, an
+// encrypted unsigned decimal with FixedDecimals fractional digits, whose
+// products and quotients use the mulDiv family with a rounding mode.
+// Encrypted arrays of any element type are the untyped earray; reads name
+// the element type they return (arrayGetEuint64).
+
+// solType is an encrypted Solidity type
+type solType struct {
+	name   string // Solidity type name
+	plain  string // Plaintext Solidity type
+	ctType uint8
+	mask   TypeMask
+}
+
+// solTypes are the encrypted types of the library, in declaration order
+var solTypes = []solType{
+	{name: "ebool", plain: "bool", ctType: TypeEbool, mask: MaskBool},
+	{name: "euint4", plain: "uint8", ctType: TypeEuint4, mask: MaskUint},
+	{name: "euint8", plain: "uint8", ctType: TypeEuint8, mask: MaskUint},
+	{name: "euint16", plain: "uint16", ctType: TypeEuint16, mask: MaskUint},
+	{name: "euint32", plain: "uint32", ctType: TypeEuint32, mask: MaskUint},
+	{name: "euint64", plain: "uint64", ctType: TypeEuint64, mask: MaskUint},
+	{name: "euint128", plain: "uint128", ctType: TypeEuint128, mask: MaskUint},
+	{name: "euint256", plain: "uint256", ctType: TypeEuint256, mask: MaskUint},
+	{name: "eaddress", plain: "address", ctType: TypeEaddress, mask: MaskAddress},
+}
+
+// arraySolType is the encrypted array type. Methods on any handle include
+// it through MaskArray.
+var arraySolType = solType{name: "earray", ctType: TypeArray, mask: MaskArray}
+
+// title is the type name as used in function names (asEuint8, randEbool)
+func (t solType) title() string {
+	return strings.ToUpper(t.name[:1]) + t.name[1:]
+}
+
+// constName is the name of the type's TYPE_ constant
+func (t solType) constName() string {
+	return "TYPE_" + strings.ToUpper(t.name)
+}
+
+func (t solType) unwrap(expr string) string {
+	return t.name + ".unwrap(" + expr + ")"
+}
+
+func (t solType) wrap(expr string) string {
+	return t.name + ".wrap(" + expr + ")"
+}
+
+// word converts a plaintext expression to the uint256 the precompile reads
+func (t solType) word(expr string) string {
+	switch t.plain {
+	case "bool":
+		return "(" + expr + " ? uint256(1) : uint256(0))"
+	case "address":
+		return "uint256(uint160(" + expr + "))"
+	default:
+		return "uint256(" + expr + ")"
+	}
+}
+
+// fromUint converts a decrypted uint256 expression to the plaintext type
+func (t solType) fromUint(expr string) string {
+	switch t.plain {
+	case "bool":
+		return expr + " != 0"
+	case "address":
+		return "address(uint160(" + expr + "))"
+	default:
+		return t.plain + "(" + expr + ")"
+	}
+}
+
+// fixedType is the encrypted fixed-point type and the type it wraps
+const (
+	fixedType = "efixed64x18"
+	fixedBase = "euint128"
+)
+
+// roundingModes are the Rounding enum members, in RoundFloor order
+var roundingModes = []string{"Floor", "Ceil", "Nearest"}
+
+// solTypeByCtType returns the library type of a ciphertext type
+func solTypeByCtType(ctType uint8) (solType, bool) {
+	for _, t := range solTypes {
+		if t.ctType == ctType {
+			return t, true
+		}
+	}
+	return solType{}, false
+}
+
+// typesIn returns the library types in a mask
+func typesIn(mask TypeMask) []solType {
+	var types []solType
+	for _, t := range solTypes {
+		if mask&t.mask != 0 {
+			types = append(types, t)
+		}
+	}
+	if mask&MaskArray != 0 {
+		types = append(types, arraySolType)
+	}
+	return types
+}
+
+// operand is one argument of a generated precompile call
+type operand struct {
+	kind ArgKind
+	expr string
+}
+
+// solWriter accumulates the library source and the first generation error
+type solWriter struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (g *solWriter) line(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *solWriter) fail(m Method, format string, args ...any) {
+	if g.err == nil {
+		g.err = fmt.Errorf("fhe: %s: %s", m.Signature, fmt.Sprintf(format, args...))
+	}
+}
+
+// expect checks a method's result kind against the wrapper being generated
+func (g *solWriter) expect(m Method, result ResultKind) {
+	if m.Result != result {
+		g.fail(m, "result kind %d does not match op class %d", m.Result, m.Class)
+	}
+}
+
+// packed returns the packed calldata expression for a precompile call
+func (g *solWriter) packed(m Method, operands ...operand) string {
+	if len(operands) != len(m.Args) {
+		g.fail(m, "op class %d takes %d arguments, table has %d", m.Class, len(operands), len(m.Args))
+		return ""
+	}
+	exprs := []string{fmt.Sprintf("bytes4(0x%x)", m.Selector)}
+	for i, op := range operands {
+		if op.kind != m.Args[i] {
+			g.fail(m, "argument %d has kind %d, op class %d expects %d", i, m.Args[i], m.Class, op.kind)
+			return ""
+		}
+		exprs = append(exprs, op.expr)
+	}
+	return "abi.encodePacked(" + strings.Join(exprs, ", ") + ")"
+}
+
+// function emits a single-statement internal library function
+func (g *solWriter) function(name, params, returns, body string) {
+	g.line("    function %s(%s) internal returns (%s) {", name, params, returns)
+	g.line("        %s", body)
+	g.line("    }")
+	g.line("")
+}
+
+// GenerateSolidity writes the FHE.sol library for the precompile ABI
+func GenerateSolidity(w io.Writer) error {
+	g := &solWriter{}
+
+	g.line("// SPDX-License-Identifier: MIT")
+	g.line("// Code generated by fhe/cmd/fhesol. DO NOT EDIT.")
+	g.line("pragma solidity ^0.8.24;")
+	g.line("")
+	for _, t := range solTypes {
+		g.line("type %s is bytes32;", t.name)
+	}
+	g.line("type %s is bytes32;", fixedType)
+	g.line("type %s is bytes32;", arraySolType.name)
+	g.line("")
+	for _, t := range solTypes {
+		g.line("using FHE for %s global;", t.name)
+	}
+	g.line("using FHE for %s global;", fixedType)
+	g.line("using FHE for %s global;", arraySolType.name)
+	g.line("")
+	g.line("/**")
+	g.line(" * @title FHE")
+	g.line(" * @notice Typed wrappers for the FHE precompile at %s", ContractAddress.Hex())
+	g.line(" *         and its ACL at %s", ACLContractAddress.Hex())
+	g.line(" * @dev Generated from the precompile dispatch table (fhe.Methods)")
+	g.line(" */")
+	g.line("library FHE {")
+	g.line("    address internal constant PRECOMPILE = %s;", ContractAddress.Hex())
+	g.line("    address internal constant ACL = %s;", ACLContractAddress.Hex())
+	g.line("")
+	for _, t := range solTypes {
+		g.line("    uint8 internal constant %s = %d;", t.constName(), t.ctType)
+	}
+	g.line("")
+	g.line("    uint256 internal constant FIXED_ONE = 1e%d;", FixedDecimals)
+	g.line("")
+	g.line("    uint8 internal constant SEAL_ECIES = %d;", SealECIES)
+	g.line("    uint8 internal constant SEAL_HPKE = %d;", SealHPKE)
+	g.line("    uint8 internal constant SEAL_MLKEM = %d;", SealMLKEM)
+	g.line("")
+	g.line("    enum Rounding { %s }", strings.Join(roundingModes, ", "))
+	g.line("")
+	writeHelpers(g)
+
+	for _, m := range Methods {
+		writeMethod(g, m)
+	}
+	writeFixed(g)
+	for _, m := range ACLMethods {
+		writeACLMethod(g, m)
+	}
+
+	// Drop the blank line after the last function
+	g.buf.Truncate(g.buf.Len() - 1)
+	g.line("}")
+
+	if g.err != nil {
+		return g.err
+	}
+	_, err := w.Write(g.buf.Bytes())
+	return err
+}
+
+// writeHelpers emits the private call and result decoding helpers
+func writeHelpers(g *solWriter) {
+	g.line("    function _call(bytes memory input) private returns (bytes memory) {")
+	g.line("        (bool ok, bytes memory out) = PRECOMPILE.call(input);")
+	g.line(`        require(ok, "FHE: precompile call failed");`)
+	g.line("        return out;")
+	g.line("    }")
+	g.line("")
+	g.line("    function _aclCall(bytes memory input) private returns (bytes memory) {")
+	g.line("        (bool ok, bytes memory out) = ACL.call(input);")
+	g.line(`        require(ok, "FHE: ACL call failed");`)
+	g.line("        return out;")
+	g.line("    }")
+	g.line("")
+	g.line("    function _aclView(bytes memory input) private view returns (bytes memory) {")
+	g.line("        (bool ok, bytes memory out) = ACL.staticcall(input);")
+	g.line(`        require(ok, "FHE: ACL call failed");`)
+	g.line("        return out;")
+	g.line("    }")
+	g.line("")
+	g.line("    function _view(bytes memory input) private view returns (bytes memory) {")
+	g.line("        (bool ok, bytes memory out) = PRECOMPILE.staticcall(input);")
+	g.line(`        require(ok, "FHE: precompile call failed");`)
+	g.line("        return out;")
+	g.line("    }")
+	g.line("")
+	g.line("    function _handle(bytes memory out) private pure returns (bytes32) {")
+	g.line(`        require(out.length == 32, "FHE: malformed handle");`)
+	g.line("        return bytes32(out);")
+	g.line("    }")
+	g.line("")
+	g.line("    // Decrypted values are returned as minimal big-endian bytes")
+	g.line("    function _uint(bytes memory out) private pure returns (uint256 value) {")
+	g.line(`        require(out.length <= 32, "FHE: malformed plaintext");`)
+	g.line("        for (uint256 i = 0; i < out.length; i++) {")
+	g.line("            value = (value << 8) | uint8(out[i]);")
+	g.line("        }")
+	g.line("    }")
+	g.line("")
+}
+
+// writeMethod emits the typed wrappers of a precompile method
+func writeMethod(g *solWriter, m Method) {
+	if m.Class == OpSystem {
+		return
+	}
+	g.line("    // %s", m.Signature)
+
+	switch m.Class {
+	case OpBinary, OpCompare:
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			ret := t
+			if m.Class == OpCompare {
+				ret = solTypes[0]
+			}
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")}, operand{ArgHandle, t.unwrap("b")})
+			g.function(m.Name, t.name+" a, "+t.name+" b", ret.name, "return "+ret.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpUnary:
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")})
+			g.function(m.Name, t.name+" a", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpScalar:
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")}, operand{ArgWord, "b"})
+			g.function(m.Name, t.name+" a, uint256 b", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpScalarCmp:
+		g.expect(m, ResultHandle)
+		ret := solTypes[0]
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")}, operand{ArgWord, "b"})
+			g.function(m.Name, t.name+" a, uint256 b", ret.name, "return "+ret.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpMulDiv:
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m,
+				operand{ArgHandle, t.unwrap("a")},
+				operand{ArgHandle, t.unwrap("b")},
+				operand{ArgWord, "c"},
+				operand{ArgByte, "uint8(rounding)"},
+			)
+			g.function(m.Name, t.name+" a, "+t.name+" b, uint256 c, Rounding rounding", t.name,
+				"return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpScalarMulDiv:
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m,
+				operand{ArgHandle, t.unwrap("a")},
+				operand{ArgWord, "m"},
+				operand{ArgWord, "d"},
+				operand{ArgByte, "uint8(rounding)"},
+			)
+			g.function(m.Name, t.name+" a, uint256 m, uint256 d, Rounding rounding", t.name,
+				"return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpShift:
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")}, operand{ArgByte, "bits"})
+			g.function(m.Name, t.name+" a, uint8 bits", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpShiftEnc:
+		g.expect(m, ResultHandle)
+		amount, _ := solTypeByCtType(TypeEuint8)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")}, operand{ArgHandle, amount.unwrap("amount")})
+			g.function(m.Name, t.name+" a, "+amount.name+" amount", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpSelect:
+		g.expect(m, ResultHandle)
+		cond := solTypes[0]
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m,
+				operand{ArgHandle, cond.unwrap("condition")},
+				operand{ArgHandle, t.unwrap("a")},
+				operand{ArgHandle, t.unwrap("b")},
+			)
+			g.function(m.Name, cond.name+" condition, "+t.name+" a, "+t.name+" b", t.name,
+				"return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpSelectN:
+		g.expect(m, ResultHandle)
+		if len(m.Args) != 2 || m.Args[0] != ArgHandle || m.Args[1] != ArgHandleArray {
+			g.fail(m, "op class %d takes an index and a handle array", m.Class)
+			return
+		}
+		index, _ := solTypeByCtType(TypeEuint32)
+		for _, t := range typesIn(m.Types) {
+			g.line("    function %s(%s index, %s[] memory candidates) internal returns (%s) {", m.Name, index.name, t.name, t.name)
+			g.line("        bytes32[] memory handles = new bytes32[](candidates.length);")
+			g.line("        for (uint256 i = 0; i < candidates.length; i++) {")
+			g.line("            handles[i] = %s;", t.unwrap("candidates[i]"))
+			g.line("        }")
+			g.line("        return %s;", t.wrap(fmt.Sprintf("_handle(_call(abi.encodeWithSelector(bytes4(0x%x), %s, handles)))", m.Selector, index.unwrap("index"))))
+			g.line("    }")
+			g.line("")
+		}
+
+	case OpCast:
+		g.expect(m, ResultHandle)
+		for _, from := range typesIn(m.Types) {
+			for _, to := range typesIn(m.Types) {
+				if from.ctType == to.ctType {
+					continue
+				}
+				call := g.packed(m, operand{ArgHandle, from.unwrap("a")}, operand{ArgByte, to.constName()})
+				g.function("as"+to.title(), from.name+" a", to.name, "return "+to.wrap("_handle(_call("+call+"))")+";")
+			}
+		}
+
+	case OpEncrypt:
+		g.expect(m, ResultHandle)
+		t, ok := solTypeByCtType(m.CtType)
+		if !ok || m.Name != "as"+t.title() {
+			g.fail(m, "no library type for ciphertext type %d", m.CtType)
+			return
+		}
+		call := g.packed(m, operand{ArgWord, t.word("value")})
+		g.function(m.Name, t.plain+" value", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+
+	case OpVerify:
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgByte, t.constName()}, operand{ArgBytes, "input"})
+			g.function("as"+t.title(), "bytes memory input", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpRandom:
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgByte, t.constName()})
+			g.function(m.Name+t.title(), "", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpRandBounded:
+		// Overloads the unbounded randEuintN
+		g.expect(m, ResultHandle)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgByte, t.constName()}, operand{ArgWord, "upperBound"})
+			g.function("rand"+t.title(), "uint256 upperBound", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpDecrypt:
+		g.expect(m, ResultUint)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")})
+			g.function(m.Name, t.name+" a", t.plain, "return "+t.fromUint("_uint(_call("+call+"))")+";")
+		}
+
+	case OpDecryptAsync:
+		g.expect(m, ResultWord)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")}, operand{ArgWord, "bytes32(callback)"})
+			g.function(m.Name, t.name+" a, bytes4 callback", "bytes32", "return abi.decode(_call("+call+"), (bytes32));")
+		}
+
+	case OpRequire:
+		g.expect(m, ResultNone)
+		for _, t := range typesIn(m.Types) {
+			params, operands := t.name+" condition", []operand{{ArgHandle, t.unwrap("condition")}}
+			if len(m.Args) > 1 {
+				params, operands = params+", string memory message", append(operands, operand{ArgBytes, "message"})
+			}
+			g.line("    function %s(%s) internal {", m.Name, params)
+			g.line("        _call(%s);", g.packed(m, operands...))
+			g.line("    }")
+			g.line("")
+		}
+
+	case OpSealOutput:
+		g.expect(m, ResultBytes)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")}, operand{ArgByte, "scheme"}, operand{ArgBytes, "publicKey"})
+			g.function(m.Name, t.name+" a, uint8 scheme, bytes memory publicKey", "bytes memory", "return _call("+call+");")
+		}
+
+	case OpBatch:
+		g.expect(m, ResultHandles)
+		call := g.packed(m, operand{ArgBytes, "ops"})
+		g.function(m.Name, "bytes memory ops", "bytes32[] memory", "return abi.decode(_call("+call+"), (bytes32[]));")
+
+	case OpPin:
+		g.expect(m, ResultNone)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, t.unwrap("a")})
+			g.line("    function %s(%s a) internal {", m.Name, t.name)
+			g.line("        _call(%s);", call)
+			g.line("    }")
+			g.line("")
+		}
+
+	case OpArrayNew:
+		g.expect(m, ResultHandle)
+		if len(m.Args) != 1 || m.Args[0] != ArgHandleArray {
+			g.fail(m, "op class %d takes a single handle array", m.Class)
+			return
+		}
+		array := arraySolType
+		for _, t := range typesIn(m.Types) {
+			g.line("    function %s(%s[] memory elements) internal returns (%s) {", m.Name, t.name, array.name)
+			g.line("        bytes32[] memory handles = new bytes32[](elements.length);")
+			g.line("        for (uint256 i = 0; i < elements.length; i++) {")
+			g.line("            handles[i] = %s;", t.unwrap("elements[i]"))
+			g.line("        }")
+			g.line("        return %s;", array.wrap(fmt.Sprintf("_handle(_call(abi.encodeWithSelector(bytes4(0x%x), handles)))", m.Selector)))
+			g.line("    }")
+			g.line("")
+		}
+
+	case OpArrayGet:
+		// Named by element type, as Solidity cannot overload on the return type
+		g.expect(m, ResultHandle)
+		array := arraySolType
+		index, _ := solTypeByCtType(TypeEuint32)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m, operand{ArgHandle, array.unwrap("a")}, operand{ArgHandle, index.unwrap("index")})
+			g.function(m.Name+t.title(), array.name+" a, "+index.name+" index", t.name, "return "+t.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpArraySet:
+		g.expect(m, ResultHandle)
+		array := arraySolType
+		index, _ := solTypeByCtType(TypeEuint32)
+		for _, t := range typesIn(m.Types) {
+			call := g.packed(m,
+				operand{ArgHandle, array.unwrap("a")},
+				operand{ArgHandle, index.unwrap("index")},
+				operand{ArgHandle, t.unwrap("value")},
+			)
+			g.function(m.Name, array.name+" a, "+index.name+" index, "+t.name+" value", array.name,
+				"return "+array.wrap("_handle(_call("+call+"))")+";")
+		}
+
+	case OpArrayLen:
+		if m.Result != ResultWord || !m.View {
+			g.fail(m, "op class %d is a view returning a word", m.Class)
+			return
+		}
+		array := arraySolType
+		call := g.packed(m, operand{ArgWord, array.unwrap("a")})
+		g.line("    function %s(%s a) internal view returns (uint256) {", m.Name, array.name)
+		g.line("        return abi.decode(_view(%s), (uint256));", call)
+		g.line("    }")
+		g.line("")
+
+	case OpHandleInfo:
+		// Raw handles, so contracts can check them before wrapping
+		returns := map[ResultKind]string{ResultWord: "uint256", ResultBool: "bool"}[m.Result]
+		if returns == "" || !m.View {
+			g.fail(m, "op class %d is a view returning a word or bool", m.Class)
+			return
+		}
+		call := g.packed(m, operand{ArgWord, "handle"})
+		g.line("    function %s(bytes32 handle) internal view returns (%s) {", m.Name, returns)
+		g.line("        return abi.decode(_view(%s), (%s));", call, returns)
+		g.line("    }")
+		g.line("")
+
+	case OpMaxWithIndex:
+		g.expect(m, ResultHandlePair)
+		if len(m.Args) != 1 || m.Args[0] != ArgHandleArray {
+			g.fail(m, "op class %d takes a single handle array", m.Class)
+			return
+		}
+		index, _ := solTypeByCtType(TypeEuint32)
+		for _, t := range typesIn(m.Types) {
+			g.line("    function %s(%s[] memory values) internal returns (%s, %s) {", m.Name, t.name, t.name, index.name)
+			g.line("        bytes32[] memory handles = new bytes32[](values.length);")
+			g.line("        for (uint256 i = 0; i < values.length; i++) {")
+			g.line("            handles[i] = %s;", t.unwrap("values[i]"))
+			g.line("        }")
+			g.line("        bytes memory out = _call(abi.encodeWithSelector(bytes4(0x%x), handles));", m.Selector)
+			g.line(`        require(out.length == 64, "FHE: malformed result");`)
+			g.line("        (bytes32 maxHandle, bytes32 indexHandle) = abi.decode(out, (bytes32, bytes32));")
+			g.line("        return (%s, %s);", t.wrap("maxHandle"), index.wrap("indexHandle"))
+			g.line("    }")
+			g.line("")
+		}
+
+	default:
+		g.fail(m, "unsupported op class %d", m.Class)
+	}
+}
+
+// writeACLMethod emits the typed wrappers of an ACL method. The ACL
+// precompile uses standard ABI encoding.
+func writeACLMethod(g *solWriter, m Method) {
+	g.line("    // %s", m.Signature)
+
+	var decode, returns string
+	switch m.Result {
+	case ResultNone:
+	case ResultBool:
+		decode, returns = "bool", "bool"
+	case ResultAddress:
+		decode, returns = "address", "address"
+	default:
+		g.fail(m, "unsupported ACL result kind %d", m.Result)
+		return
+	}
+
+	call, mutability := "_aclCall", ""
+	if m.View {
+		call, mutability = "_aclView", " view"
+	}
+
+	for _, t := range typesIn(m.Types) {
+		params := make([]string, len(m.Args))
+		args := []string{fmt.Sprintf("bytes4(0x%x)", m.Selector)}
+		for i, kind := range m.Args {
+			switch {
+			case kind == ArgHandle && i == 0:
+				params[i] = t.name + " a"
+				args = append(args, t.unwrap("a"))
+			case kind == ArgAddress && i == 1:
+				params[i] = "address account"
+				args = append(args, "account")
+			default:
+				g.fail(m, "unsupported ACL argument %d of kind %d", i, kind)
+				return
+			}
+		}
+
+		input := "abi.encodeWithSelector(" + strings.Join(args, ", ") + ")"
+		if returns == "" {
+			g.line("    function %s(%s) internal%s {", m.Name, strings.Join(params, ", "), mutability)
+			g.line("        %s(%s);", call, input)
+		} else {
+			g.line("    function %s(%s) internal%s returns (%s) {", m.Name, strings.Join(params, ", "), mutability, returns)
+			g.line("        return abi.decode(%s(%s), (%s));", call, input, decode)
+		}
+		g.line("    }")
+		g.line("")
+	}
+}
+
+// writeFixed emits the efixed64x18 functions. They are built on the
+// euint128 wrappers, so the methods they call must be in the table.
+func writeFixed(g *solWriter) {
+	base, _ := solTypeByCtType(TypeEuint128)
+	for _, name := range []string{"add", "sub", "min", "max", "lt", "le", "gt", "ge", "eq", "ne", "select", "cast", "scalarMul", "mulDiv", "scalarMulDiv", "scaledDiv"} {
+		m, ok := methodByName(name)
+		if !ok {
+			g.fail(Method{Signature: fixedType}, "requires method %s", name)
+			return
+		}
+		if m.Types&base.mask == 0 {
+			g.fail(m, "%s requires %s support", fixedType, fixedBase)
+			return
+		}
+	}
+
+	fixed := solType{name: fixedType}
+	g.line("    // %s: unsigned fixed-point with %d decimals, stored as %s value * FIXED_ONE", fixedType, FixedDecimals, fixedBase)
+	g.line("    function as%s(%s value) internal pure returns (%s) {", fixed.title(), fixedBase, fixedType)
+	g.line("        return %s;", fixed.wrap(base.unwrap("value")))
+	g.line("    }")
+	g.line("")
+	g.line("    function raw(%s a) internal pure returns (%s) {", fixedType, fixedBase)
+	g.line("        return %s;", base.wrap(fixed.unwrap("a")))
+	g.line("    }")
+	g.line("")
+
+	// Scaling casts
+	for _, t := range typesIn(MaskUint) {
+		if t.ctType == TypeEuint128 || t.ctType == TypeEuint256 {
+			continue
+		}
+		g.function("to"+fixed.title(), t.name+" a", fixedType, "return as"+fixed.title()+"(scalarMul(asEuint128(a), FIXED_ONE));")
+		g.function("to"+t.title(), fixedType+" a, Rounding rounding", t.name,
+			"return as"+t.title()+"(scalarMulDiv(raw(a), 1, FIXED_ONE, rounding));")
+	}
+
+	// Exact operations
+	for _, name := range []string{"add", "sub", "min", "max"} {
+		g.function(name, fixedType+" a, "+fixedType+" b", fixedType, "return as"+fixed.title()+"("+name+"(raw(a), raw(b)));")
+	}
+	for _, name := range []string{"lt", "le", "gt", "ge", "eq", "ne"} {
+		g.function(name, fixedType+" a, "+fixedType+" b", solTypes[0].name, "return "+name+"(raw(a), raw(b));")
+	}
+	g.function("select", solTypes[0].name+" condition, "+fixedType+" a, "+fixedType+" b", fixedType,
+		"return as"+fixed.title()+"(select(condition, raw(a), raw(b)));")
+
+	// Rounded operations; plaintext operands are fixed-point values
+	g.function("mul", fixedType+" a, "+fixedType+" b, Rounding rounding", fixedType,
+		"return as"+fixed.title()+"(mulDiv(raw(a), raw(b), FIXED_ONE, rounding));")
+	g.function("div", fixedType+" a, "+fixedType+" b, Rounding rounding", fixedType,
+		"return as"+fixed.title()+"(scaledDiv(raw(a), raw(b), FIXED_ONE, rounding));")
+	g.function("mul", fixedType+" a, uint256 b, Rounding rounding", fixedType,
+		"return as"+fixed.title()+"(scalarMulDiv(raw(a), b, FIXED_ONE, rounding));")
+	g.function("div", fixedType+" a, uint256 b, Rounding rounding", fixedType,
+		"return as"+fixed.title()+"(scalarMulDiv(raw(a), FIXED_ONE, b, rounding));")
+}
+
+// methodByName returns the precompile method with the given name
+func methodByName(name string) (Method, bool) {
+	for _, m := range Methods {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Method{}, false
+}
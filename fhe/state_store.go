@@ -0,0 +1,258 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// StateDB ciphertext persistence.
+//
+// On chain, ciphertexts live in the storage of the FHE precompile account,
+// so they survive restarts, follow reorgs and are reverted with the rest of
+// the transaction's state. Stores made during a block are leased and
+// collected once unused (see gc.go). A ciphertext under handle h occupies
+// a header slot and a run of data slots:
+//
+//	header = keccak256(ciphertextSlotDomain || h)
+//	data_i = keccak256(header) + i
+//
+// The header packs a present flag, the ciphertext type, encoding and
+// provenance flags, the network key epoch (see keys.go) and the stored and
+// logical lengths. Ciphertexts of MinCompressSize bytes or more are stored
+// zstd-compressed when that is smaller.
+
+// ciphertextSlotDomain separates ciphertext slots from other precompile
+// storage
+const ciphertextSlotDomain = "lux.fhe.ciphertext.v1"
+
+// GasStoreWord is charged per 32-byte word a ciphertext write stores, the
+// header included. Stored ciphertexts are leased and collected (see gc.go),
+// so this is below the rent of a pinned word.
+const GasStoreWord uint64 = 200
+
+// Header layout
+const (
+	ctHeaderPresent    = 0  // 1 when a ciphertext is stored
+	ctHeaderType       = 1  // Ciphertext type
+	ctHeaderFlags      = 2  // ctFlagCompressed, ctFlagTrivial
+	ctHeaderEpoch      = 8  // uint64 network key epoch
+	ctHeaderStoredLen  = 16 // uint64 length of the stored bytes
+	ctHeaderLogicalLen = 24 // uint64 length of the ciphertext
+
+	ctFlagCompressed = 0x01
+	ctFlagTrivial    = 0x02 // Trivial encryption (see trivial.go)
+)
+
+// StateCiphertextStore persists ciphertexts in a StateDB
+type StateCiphertextStore struct {
+	db     contract.StateDB
+	addr   common.Address
+	number uint64     // Block of the writes, for leases; 0 records none
+	call   *callState // Call result handles derive from; nil for none
+}
+
+// NewStateCiphertextStore stores ciphertexts in the FHE precompile's storage
+func NewStateCiphertextStore(db contract.StateDB) *StateCiphertextStore {
+	return &StateCiphertextStore{db: db, addr: ContractAddress}
+}
+
+// ciphertextHeaderSlot returns the header slot of a handle
+func ciphertextHeaderSlot(handle common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte(ciphertextSlotDomain), handle.Bytes())
+}
+
+// ciphertextDataSlot returns data slot i of a ciphertext
+func ciphertextDataSlot(header common.Hash, i int) common.Hash {
+	base := new(big.Int).SetBytes(crypto.Keccak256(header.Bytes()))
+	return common.BigToHash(base.Add(base, big.NewInt(int64(i))))
+}
+
+// storedWords returns the data slot count of a header
+func storedWords(header common.Hash) int {
+	if header[ctHeaderPresent] != 1 {
+		return 0
+	}
+	n := binary.BigEndian.Uint64(header[ctHeaderStoredLen : ctHeaderStoredLen+8])
+	return int((n + 31) / 32)
+}
+
+// Put stores ct under handle, replacing any previous ciphertext. A store
+// serving a call charges it GasStoreWord per word before writing, and
+// writes nothing if the call cannot pay.
+func (s *StateCiphertextStore) Put(handle common.Hash, ct []byte, ctType uint8) {
+	data, flags := ct, byte(0)
+	if len(ct) >= MinCompressSize {
+		if packed := zstdEncoder.EncodeAll(ct, nil); len(packed) < len(ct) {
+			data, flags = packed, ctFlagCompressed
+		}
+	}
+
+	words := (len(data) + 31) / 32
+	if s.call != nil && !s.call.chargeStorage(uint64(words)+1) {
+		return
+	}
+
+	headerSlot := ciphertextHeaderSlot(handle)
+	oldWords := storedWords(s.db.GetState(s.addr, headerSlot))
+
+	for i := 0; i < words; i++ {
+		var word common.Hash
+		copy(word[:], data[i*32:min((i+1)*32, len(data))])
+		s.db.SetState(s.addr, ciphertextDataSlot(headerSlot, i), word)
+	}
+	for i := words; i < oldWords; i++ {
+		s.db.SetState(s.addr, ciphertextDataSlot(headerSlot, i), common.Hash{})
+	}
+
+	var header common.Hash
+	header[ctHeaderPresent] = 1
+	header[ctHeaderType] = ctType
+	header[ctHeaderFlags] = flags
+	binary.BigEndian.PutUint64(header[ctHeaderEpoch:], currentKeyEpoch())
+	binary.BigEndian.PutUint64(header[ctHeaderStoredLen:], uint64(len(data)))
+	binary.BigEndian.PutUint64(header[ctHeaderLogicalLen:], uint64(len(ct)))
+	s.db.SetState(s.addr, headerSlot, header)
+
+	if s.number > 0 {
+		renewLease(s.db, handle, s.number)
+	}
+}
+
+// Get returns the ciphertext and type stored under handle
+func (s *StateCiphertextStore) Get(handle common.Hash) ([]byte, uint8, bool) {
+	headerSlot := ciphertextHeaderSlot(handle)
+	header := s.db.GetState(s.addr, headerSlot)
+	if header[ctHeaderPresent] != 1 {
+		return nil, 0, false
+	}
+
+	size := binary.BigEndian.Uint64(header[ctHeaderStoredLen : ctHeaderStoredLen+8])
+	data := make([]byte, 0, storedWords(header)*32)
+	for i := 0; i < storedWords(header); i++ {
+		word := s.db.GetState(s.addr, ciphertextDataSlot(headerSlot, i))
+		data = append(data, word[:]...)
+	}
+	data = data[:size]
+
+	if header[ctHeaderFlags]&ctFlagCompressed != 0 {
+		var err error
+		if data, err = zstdDecoder.DecodeAll(data, nil); err != nil {
+			return nil, 0, false
+		}
+	}
+	if uint64(len(data)) != binary.BigEndian.Uint64(header[ctHeaderLogicalLen:]) {
+		return nil, 0, false
+	}
+
+	// Ciphertexts of another epoch are switched to the active key; arrays
+	// hold handles, not ciphertexts
+	epoch, active := binary.BigEndian.Uint64(header[ctHeaderEpoch:ctHeaderEpoch+8]), currentKeyEpoch()
+	if epoch != active && !isArrayType(header[ctHeaderType]) {
+		if data = tfheSwitchKey(data, header[ctHeaderType], epoch, active); data == nil {
+			return nil, 0, false
+		}
+	}
+	return data, header[ctHeaderType], true
+}
+
+// Has reports whether a ciphertext is stored under handle
+func (s *StateCiphertextStore) Has(handle common.Hash) bool {
+	return s.db.GetState(s.addr, ciphertextHeaderSlot(handle))[ctHeaderPresent] == 1
+}
+
+// Stat returns the type and size of the ciphertext under handle from its
+// header
+func (s *StateCiphertextStore) Stat(handle common.Hash) (uint8, int, bool) {
+	header := s.db.GetState(s.addr, ciphertextHeaderSlot(handle))
+	if header[ctHeaderPresent] != 1 {
+		return 0, 0, false
+	}
+	return header[ctHeaderType], int(binary.BigEndian.Uint64(header[ctHeaderLogicalLen:])), true
+}
+
+// MarkTrivial flags the ciphertext under handle as a trivial encryption
+// until it is replaced
+func (s *StateCiphertextStore) MarkTrivial(handle common.Hash) {
+	headerSlot := ciphertextHeaderSlot(handle)
+	header := s.db.GetState(s.addr, headerSlot)
+	if header[ctHeaderPresent] != 1 || header[ctHeaderFlags]&ctFlagTrivial != 0 {
+		return
+	}
+	header[ctHeaderFlags] |= ctFlagTrivial
+	s.db.SetState(s.addr, headerSlot, header)
+}
+
+// Trivial reports whether the ciphertext under handle is a trivial
+// encryption
+func (s *StateCiphertextStore) Trivial(handle common.Hash) bool {
+	header := s.db.GetState(s.addr, ciphertextHeaderSlot(handle))
+	return header[ctHeaderPresent] == 1 && header[ctHeaderFlags]&ctFlagTrivial != 0
+}
+
+// Epoch returns the network key epoch the ciphertext under handle was
+// stored in
+func (s *StateCiphertextStore) Epoch(handle common.Hash) (uint64, bool) {
+	header := s.db.GetState(s.addr, ciphertextHeaderSlot(handle))
+	if header[ctHeaderPresent] != 1 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(header[ctHeaderEpoch : ctHeaderEpoch+8]), true
+}
+
+// Delete clears the ciphertext under handle
+func (s *StateCiphertextStore) Delete(handle common.Hash) {
+	headerSlot := ciphertextHeaderSlot(handle)
+	words := storedWords(s.db.GetState(s.addr, headerSlot))
+	for i := 0; i < words; i++ {
+		s.db.SetState(s.addr, ciphertextDataSlot(headerSlot, i), common.Hash{})
+	}
+	s.db.SetState(s.addr, headerSlot, common.Hash{})
+}
+
+// storageBudget returns the gas a call to m may spend on ciphertext
+// storage: what gas leaves after the method's price and its ACL checks.
+// Both are charged at their maximum, so a write the budget allows is one the
+// call can pay for.
+func storageBudget(c *FHEContract, m *Method, input []byte, gas uint64) uint64 {
+	reserved := c.Gas(input)
+	if m.Class != OpSystem {
+		reserved += GasACLCheck*uint64(len(inputHandles(m, input[4:]))) + GasACLWrite*uint64(resultHandleCount(m))
+	}
+	if gas < reserved {
+		return 0
+	}
+	return gas - reserved
+}
+
+// ciphertextStoreFor returns the store a precompile call reads and writes:
+// the call's StateDB on chain, or the in-memory store when the call has no
+// state (tools and tests)
+func ciphertextStoreFor(state contract.AccessibleState) CiphertextBackend {
+	if state != nil {
+		if db := state.GetStateDB(); db != nil {
+			store := NewStateCiphertextStore(db)
+			if bc := state.GetBlockContext(); bc != nil && bc.Number() != nil {
+				store.number = bc.Number().Uint64()
+			}
+			store.call, _ = state.(*callState)
+			return store
+		}
+	}
+	return ciphertexts
+}
+
+// stateDBOf returns the StateDB a store writes to, or nil for the in-memory
+// store
+func stateDBOf(store CiphertextBackend) contract.StateDB {
+	if s, ok := store.(*StateCiphertextStore); ok {
+		return s.db
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/luxfi/geth/common"
+)
+
+// Ciphertext storage.
+//
+// Large ciphertexts (euint256, ebytes) run to tens of kilobytes. The store
+// splits each ciphertext into fixed-size chunks, compresses every chunk with
+// zstd when that saves space, and keys chunks by the SHA-256 of their
+// plaintext bytes. Identical chunks, and so identical ciphertexts, are kept
+// once and reference counted. Compression and chunking are transparent:
+// Get returns exactly the bytes given to Put. The store is safe for
+// concurrent use: entries are spread over shards by handle, each under its
+// own lock, and the shared chunk table has a lock of its own.
+
+const (
+	// CiphertextChunkSize is the size of a storage chunk before compression
+	CiphertextChunkSize = 16 * 1024
+
+	// MinCompressSize is the smallest chunk worth compressing
+	MinCompressSize = 512
+)
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// StorageStats reports how much space the ciphertext store saves
+type StorageStats struct {
+	Ciphertexts  int    // Stored ciphertexts
+	Chunks       int    // Distinct chunks
+	LogicalBytes uint64 // Sum of ciphertext sizes
+	StoredBytes  uint64 // Bytes held after compression and deduplication
+	DedupHits    uint64 // Chunk writes served by an existing chunk
+}
+
+// BytesSaved returns the bytes saved by compression and deduplication
+func (s StorageStats) BytesSaved() uint64 {
+	if s.StoredBytes >= s.LogicalBytes {
+		return 0
+	}
+	return s.LogicalBytes - s.StoredBytes
+}
+
+// storedChunk is one content-addressed chunk
+type storedChunk struct {
+	data       []byte // zstd frame when compressed, raw bytes otherwise
+	compressed bool
+	refs       int
+}
+
+// storedCiphertext lists the chunks of one ciphertext
+type storedCiphertext struct {
+	ctType  uint8
+	size    int
+	chunks  [][32]byte
+	trivial bool
+}
+
+// CiphertextBackend holds ciphertexts under their handles. On chain this is
+// the StateDB (see StateCiphertextStore); tools and tests without state use
+// an in-memory CiphertextStore.
+type CiphertextBackend interface {
+	Put(handle common.Hash, ct []byte, ctType uint8)
+	Get(handle common.Hash) ([]byte, uint8, bool)
+	Has(handle common.Hash) bool
+	Stat(handle common.Hash) (ctType uint8, size int, ok bool) // Without reading the ciphertext
+	Delete(handle common.Hash)
+	MarkTrivial(handle common.Hash)  // Flag a stored ciphertext as a trivial encryption
+	Trivial(handle common.Hash) bool // Whether a stored ciphertext is a trivial encryption
+}
+
+var (
+	_ CiphertextBackend = (*CiphertextStore)(nil)
+	_ CiphertextBackend = (*StateCiphertextStore)(nil)
+)
+
+// ciphertextShards is the number of independently locked entry shards of a
+// CiphertextStore; a power of two
+const ciphertextShards = 16
+
+// ciphertextShard holds the entries of the handles that map to it
+type ciphertextShard struct {
+	mu      sync.RWMutex
+	entries map[common.Hash]*storedCiphertext
+}
+
+// CiphertextStore holds ciphertexts as compressed, deduplicated chunks. It
+// is safe for concurrent use.
+type CiphertextStore struct {
+	shards [ciphertextShards]ciphertextShard
+
+	// chunkMu guards the chunk table and the byte counters. It is taken
+	// after a shard lock, never before.
+	chunkMu sync.RWMutex
+	chunks  map[[32]byte]*storedChunk
+
+	logicalBytes uint64
+	storedBytes  uint64
+	dedupHits    uint64
+}
+
+// NewCiphertextStore creates an empty store
+func NewCiphertextStore() *CiphertextStore {
+	s := &CiphertextStore{chunks: make(map[[32]byte]*storedChunk)}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[common.Hash]*storedCiphertext)
+	}
+	return s
+}
+
+// ciphertexts is the store behind ciphertext handles for calls without a
+// StateDB
+var ciphertexts = NewCiphertextStore()
+
+// shard returns the shard holding handle
+func (s *CiphertextStore) shard(handle common.Hash) *ciphertextShard {
+	return &s.shards[handle[0]&(ciphertextShards-1)]
+}
+
+// Put stores ct under handle, replacing any previous ciphertext
+func (s *CiphertextStore) Put(handle common.Hash, ct []byte, ctType uint8) {
+	entry := &storedCiphertext{ctType: ctType, size: len(ct)}
+	s.chunkMu.Lock()
+	for off := 0; off < len(ct); off += CiphertextChunkSize {
+		end := min(off+CiphertextChunkSize, len(ct))
+		entry.chunks = append(entry.chunks, s.putChunk(ct[off:end]))
+	}
+	s.logicalBytes += uint64(len(ct))
+	s.chunkMu.Unlock()
+
+	sh := s.shard(handle)
+	sh.mu.Lock()
+	old := sh.entries[handle]
+	sh.entries[handle] = entry
+	sh.mu.Unlock()
+
+	// Release after acquiring, so chunks shared with the old value survive.
+	// Readers of the old entry held the shard lock, so none remain.
+	if old != nil {
+		s.chunkMu.Lock()
+		s.release(old)
+		s.chunkMu.Unlock()
+	}
+}
+
+// Get returns the ciphertext and type stored under handle
+func (s *CiphertextStore) Get(handle common.Hash) ([]byte, uint8, bool) {
+	sh := s.shard(handle)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	entry, ok := sh.entries[handle]
+	if !ok {
+		return nil, 0, false
+	}
+
+	// The entry's references keep its chunks alive while the shard is
+	// locked; chunks are immutable, so they are decoded outside chunkMu
+	chunks := make([]*storedChunk, len(entry.chunks))
+	s.chunkMu.RLock()
+	for i, id := range entry.chunks {
+		chunks[i] = s.chunks[id]
+	}
+	s.chunkMu.RUnlock()
+
+	ct := make([]byte, 0, entry.size)
+	for _, chunk := range chunks {
+		if !chunk.compressed {
+			ct = append(ct, chunk.data...)
+			continue
+		}
+		var err error
+		ct, err = zstdDecoder.DecodeAll(chunk.data, ct)
+		if err != nil {
+			return nil, 0, false
+		}
+	}
+	return ct, entry.ctType, true
+}
+
+// Has reports whether a ciphertext is stored under handle
+func (s *CiphertextStore) Has(handle common.Hash) bool {
+	sh := s.shard(handle)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	_, ok := sh.entries[handle]
+	return ok
+}
+
+// Stat returns the type and size of the ciphertext under handle
+func (s *CiphertextStore) Stat(handle common.Hash) (uint8, int, bool) {
+	sh := s.shard(handle)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	entry, ok := sh.entries[handle]
+	if !ok {
+		return 0, 0, false
+	}
+	return entry.ctType, entry.size, true
+}
+
+// MarkTrivial flags the ciphertext under handle as a trivial encryption
+// until it is replaced
+func (s *CiphertextStore) MarkTrivial(handle common.Hash) {
+	sh := s.shard(handle)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if entry, ok := sh.entries[handle]; ok {
+		entry.trivial = true
+	}
+}
+
+// Trivial reports whether the ciphertext under handle is a trivial
+// encryption
+func (s *CiphertextStore) Trivial(handle common.Hash) bool {
+	sh := s.shard(handle)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	entry, ok := sh.entries[handle]
+	return ok && entry.trivial
+}
+
+// Delete removes the ciphertext under handle, freeing unshared chunks
+func (s *CiphertextStore) Delete(handle common.Hash) {
+	sh := s.shard(handle)
+	sh.mu.Lock()
+	entry, ok := sh.entries[handle]
+	delete(sh.entries, handle)
+	sh.mu.Unlock()
+
+	if ok {
+		s.chunkMu.Lock()
+		s.release(entry)
+		s.chunkMu.Unlock()
+	}
+}
+
+// Stats returns the store's space metrics. Under concurrent writes the
+// counts are taken shard by shard and may not be a single snapshot.
+func (s *CiphertextStore) Stats() StorageStats {
+	var n int
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		n += len(sh.entries)
+		sh.mu.RUnlock()
+	}
+
+	s.chunkMu.RLock()
+	defer s.chunkMu.RUnlock()
+	return StorageStats{
+		Ciphertexts:  n,
+		Chunks:       len(s.chunks),
+		LogicalBytes: s.logicalBytes,
+		StoredBytes:  s.storedBytes,
+		DedupHits:    s.dedupHits,
+	}
+}
+
+// putChunk stores raw, or takes another reference to an identical chunk.
+// Caller must hold s.chunkMu.
+func (s *CiphertextStore) putChunk(raw []byte) [32]byte {
+	id := sha256.Sum256(raw)
+	if chunk, ok := s.chunks[id]; ok {
+		chunk.refs++
+		s.dedupHits++
+		return id
+	}
+
+	chunk := &storedChunk{data: append([]byte(nil), raw...), refs: 1}
+	if len(raw) >= MinCompressSize {
+		if packed := zstdEncoder.EncodeAll(raw, nil); len(packed) < len(raw) {
+			chunk.data, chunk.compressed = packed, true
+		}
+	}
+	s.chunks[id] = chunk
+	s.storedBytes += uint64(len(chunk.data))
+	return id
+}
+
+// release drops entry's chunk references. Caller must hold s.chunkMu.
+func (s *CiphertextStore) release(entry *storedCiphertext) {
+	s.logicalBytes -= uint64(entry.size)
+	for _, id := range entry.chunks {
+		chunk := s.chunks[id]
+		chunk.refs--
+		if chunk.refs == 0 {
+			s.storedBytes -= uint64(len(chunk.data))
+			delete(s.chunks, id)
+		}
+	}
+}
+
+// CiphertextStorageStats returns the space metrics of the precompile's
+// ciphertext store
+func CiphertextStorageStats() StorageStats {
+	return ciphertexts.Stats()
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2019-2025, Lux Industries, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package fhe
+
+import (
+	"github.com/luxfi/geth/common"
+)
+
+// Trivial encryptions.
+//
+// A trivial encryption is a ciphertext of a public plaintext, as made by
+// asEbool, asEuint64, asEaddress and the other plaintext casts. It carries
+// no noise, so TFHE evaluates an operation with a trivial operand at a
+// fraction of the cost of one on two real ciphertexts. The store flags
+// trivial encryptions, and results computed only from trivial operands, when
+// they are stored. Binary operations and select with a trivial operand are
+// charged TrivialGasPercent of their price. Gas quotes the full price, since
+// it sees no state; the handler returns the difference with the remaining
+// gas. Inputs submitted through verify, random draws, batch results and
+// ciphertexts rewritten by key migration are never trivial.
+
+// TrivialGasPercent is the share of an operation's price charged when an
+// operand is a trivial encryption
+const TrivialGasPercent uint64 = 25
+
+// trivialGas returns the price of an operation of price base with a
+// trivial operand
+func trivialGas(base uint64) uint64 {
+	return base * TrivialGasPercent / 100
+}
+
+// operationGas returns the gas of an operation of price base on handles:
+// the trivial price if any operand is a trivial encryption
+func operationGas(store CiphertextBackend, base uint64, handles ...common.Hash) uint64 {
+	for _, h := range handles {
+		if store.Trivial(h) {
+			return trivialGas(base)
+		}
+	}
+	return base
+}
+
+// storeTrivial stores a trivial encryption and flags it
+func storeTrivial(store CiphertextBackend, ct []byte, ctType uint8) common.Hash {
+	handle := storeCiphertext(store, ct, ctType)
+	store.MarkTrivial(handle)
+	return handle
+}
+
+// storeResult stores the result of an operation on operands, flagged as
+// trivial when every operand is
+func storeResult(store CiphertextBackend, ct []byte, ctType uint8, operands ...common.Hash) common.Hash {
+	handle := storeCiphertext(store, ct, ctType)
+	for _, h := range operands {
+		if !store.Trivial(h) {
+			return handle
+		}
+	}
+	store.MarkTrivial(handle)
+	return handle
+}
@@ -48,6 +48,11 @@ var (
 	// 0x0800-0x08FF: Threshold signatures
 	// 0x0900-0x09FF: ZK proofs
 	// 0x0A00-0x0AFF: Curves (secp256r1, etc.)
+	// 0x4230-0x423F: Privacy/ZK, C-Chain (registry MerkleProofCChain, etc.)
+	// 0x4630-0x463F: Privacy/ZK, Z-Chain (registry MerkleProofZChain, etc.)
+	// 0x4240-0x424F: FHE family, C-Chain (registry BGVCChain, etc.)
+	// 0x4640-0x464F: FHE family, Z-Chain (registry BGVZChain, etc.)
+	// 0x5200-0x52FF: Threshold/MPC, C-Chain (registry FROSTCChain, etc.)
 	//
 	// LOW-BYTE RANGES (EIP-collision-free: 0x0000...XXXX):
 	// 0x8000-0x8FFF: Lux Core System (AI Mining at 0x8100)
@@ -105,6 +110,31 @@ var (
 			Start: common.HexToAddress("0x0A00000000000000000000000000000000000000"),
 			End:   common.HexToAddress("0x0A000000000000000000000000000000000000ff"),
 		},
+		// Privacy/ZK, C-Chain (0x4230-0x423F)
+		{
+			Start: common.HexToAddress("0x4230000000000000000000000000000000000000"),
+			End:   common.HexToAddress("0x423f0000000000000000000000000000000000ff"),
+		},
+		// Privacy/ZK, Z-Chain (0x4630-0x463F)
+		{
+			Start: common.HexToAddress("0x4630000000000000000000000000000000000000"),
+			End:   common.HexToAddress("0x463f0000000000000000000000000000000000ff"),
+		},
+		// FHE family, C-Chain (0x4240-0x424F)
+		{
+			Start: common.HexToAddress("0x4240000000000000000000000000000000000000"),
+			End:   common.HexToAddress("0x424f0000000000000000000000000000000000ff"),
+		},
+		// FHE family, Z-Chain (0x4640-0x464F)
+		{
+			Start: common.HexToAddress("0x4640000000000000000000000000000000000000"),
+			End:   common.HexToAddress("0x464f0000000000000000000000000000000000ff"),
+		},
+		// Threshold/MPC, C-Chain (0x5200-0x52FF)
+		{
+			Start: common.HexToAddress("0x5200000000000000000000000000000000000000"),
+			End:   common.HexToAddress("0x52ff0000000000000000000000000000000000ff"),
+		},
 		// =====================================================================
 		// LP-ALIGNED RANGES (Low-byte format: 0x0000...LPNUM)
 		// Address = LP number directly, e.g., LP-9010 = 0x...9010
@@ -0,0 +1,170 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+import (
+	"encoding/binary"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SLH-DSA batch verification
+//
+// A single SLH-DSA verification takes milliseconds, so a block with many PQ
+// transactions stalls when they verify one after another. A batch spreads
+// its signatures over a bounded worker pool. With early abort, workers stop
+// picking up signatures once one has failed. The batch verdict does not
+// depend on scheduling: it is valid only if every signature is valid, and
+// any invalid signature makes it invalid whether or not the others ran.
+
+const (
+	// MaxSLHDSABatch bounds the signatures in one batch
+	MaxSLHDSABatch = 256
+
+	// MaxBatchWorkers bounds the worker pool of one batch
+	MaxBatchWorkers = 16
+)
+
+// SLHDSABatchItem is one signature of a batch
+type SLHDSABatchItem struct {
+	Mode      uint8
+	PublicKey []byte
+	Signature []byte
+	Message   []byte
+}
+
+// BatchOptions configures a batch verification
+type BatchOptions struct {
+	Workers    int  // Worker pool size; 0 uses the CPU count, capped at MaxBatchWorkers
+	EarlyAbort bool // Stop verifying once a signature has failed
+}
+
+// BatchResult is the outcome of a batch verification
+type BatchResult struct {
+	Valid        bool   // Every signature verified
+	Results      []bool // Per-signature validity; false for signatures skipped after an abort
+	Checked      int    // Signatures actually verified
+	FirstInvalid int    // Lowest index seen to fail, -1 when none
+	GasUsed      uint64
+}
+
+// VerifySLHDSABatch verifies a batch of SLH-DSA signatures in parallel
+func (qv *QuantumVerifier) VerifySLHDSABatch(items []SLHDSABatchItem, opts BatchOptions) (*BatchResult, error) {
+	if len(items) == 0 || len(items) > MaxSLHDSABatch {
+		return nil, ErrInvalidBatch
+	}
+	gas, err := SLHDSABatchVerifyGas(items)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, MaxBatchWorkers, len(items))
+
+	results := make([]bool, len(items))
+	checked := make([]bool, len(items))
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+
+	// Verification is stateless, so workers run without qv.mu
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if opts.EarlyAbort && failed.Load() {
+					return
+				}
+				i := int(next.Add(1) - 1)
+				if i >= len(items) {
+					return
+				}
+				item := items[i]
+				results[i] = qv.verifySLHDSASignature(item.PublicKey, item.Message, item.Signature, item.Mode)
+				checked[i] = true
+				if !results[i] {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := &BatchResult{Valid: !failed.Load(), Results: results, FirstInvalid: -1, GasUsed: gas}
+
+	qv.mu.Lock()
+	defer qv.mu.Unlock()
+	for i := range items {
+		if !checked[i] {
+			continue
+		}
+		result.Checked++
+		qv.TotalVerifications++
+		if results[i] {
+			qv.TotalValid++
+		} else {
+			qv.TotalInvalid++
+			if result.FirstInvalid < 0 {
+				result.FirstInvalid = i
+			}
+		}
+	}
+	return result, nil
+}
+
+// Batch input layout (after the op byte, big-endian lengths):
+//
+//	flags(1) count(2) then count times:
+//	mode(1) pkLen(2) sigLen(4) msgLen(4) publicKey signature message
+//
+// Flag bit 0 enables early abort.
+const (
+	slhdsaBatchHeaderSize = 1 + 2
+	slhdsaBatchItemHeader = 1 + 2 + 4 + 4
+
+	batchFlagEarlyAbort = 0x01
+)
+
+// decodeSLHDSABatch parses a batch input into its items and options
+func decodeSLHDSABatch(data []byte) ([]SLHDSABatchItem, BatchOptions, error) {
+	if len(data) < slhdsaBatchHeaderSize {
+		return nil, BatchOptions{}, ErrInvalidInput
+	}
+	opts := BatchOptions{EarlyAbort: data[0]&batchFlagEarlyAbort != 0}
+	count := int(binary.BigEndian.Uint16(data[1:3]))
+	if count == 0 || count > MaxSLHDSABatch {
+		return nil, BatchOptions{}, ErrInvalidBatch
+	}
+
+	items := make([]SLHDSABatchItem, count)
+	rest := data[slhdsaBatchHeaderSize:]
+	for i := range items {
+		if len(rest) < slhdsaBatchItemHeader {
+			return nil, BatchOptions{}, ErrInvalidInput
+		}
+		pkLen := int(binary.BigEndian.Uint16(rest[1:3]))
+		sigLen := int(binary.BigEndian.Uint32(rest[3:7]))
+		msgLen := int(binary.BigEndian.Uint32(rest[7:11]))
+		body := rest[slhdsaBatchItemHeader:]
+		if pkLen > len(body) || sigLen > len(body)-pkLen || msgLen > len(body)-pkLen-sigLen {
+			return nil, BatchOptions{}, ErrInvalidInput
+		}
+		items[i] = SLHDSABatchItem{
+			Mode:      rest[0],
+			PublicKey: body[:pkLen],
+			Signature: body[pkLen : pkLen+sigLen],
+			Message:   body[pkLen+sigLen : pkLen+sigLen+msgLen],
+		}
+		rest = body[pkLen+sigLen+msgLen:]
+	}
+	if len(rest) != 0 {
+		return nil, BatchOptions{}, ErrInvalidInput
+	}
+	return items, opts, nil
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+
+	"github.com/luxfi/crypto/slhdsa"
+	"github.com/luxfi/geth/common"
+)
+
+// slhdsaBatch signs n messages with fresh SLH-DSA-SHA2-128f keys (mode 2)
+func slhdsaBatch(t *testing.T, n int) []SLHDSABatchItem {
+	t.Helper()
+
+	items := make([]SLHDSABatchItem, n)
+	for i := range items {
+		priv, err := slhdsa.GenerateKey(rand.Reader, slhdsa.SHA2_128f)
+		if err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		message := []byte{byte(i), 'b', 'a', 't', 'c', 'h'}
+		signature, err := priv.Sign(rand.Reader, message, nil)
+		if err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+		items[i] = SLHDSABatchItem{Mode: 2, PublicKey: priv.PublicKey.Bytes(), Signature: signature, Message: message}
+	}
+	return items
+}
+
+// encodeSLHDSABatch builds precompile input for a batch
+func encodeSLHDSABatch(flags byte, items []SLHDSABatchItem) []byte {
+	input := []byte{OpVerifySLHDSABatch, flags, 0, 0}
+	binary.BigEndian.PutUint16(input[2:4], uint16(len(items)))
+	for _, item := range items {
+		header := make([]byte, slhdsaBatchItemHeader)
+		header[0] = item.Mode
+		binary.BigEndian.PutUint16(header[1:3], uint16(len(item.PublicKey)))
+		binary.BigEndian.PutUint32(header[3:7], uint32(len(item.Signature)))
+		binary.BigEndian.PutUint32(header[7:11], uint32(len(item.Message)))
+		input = append(input, header...)
+		input = append(input, item.PublicKey...)
+		input = append(input, item.Signature...)
+		input = append(input, item.Message...)
+	}
+	return input
+}
+
+// TestVerifySLHDSABatch tests parallel batch verification and early abort
+func TestVerifySLHDSABatch(t *testing.T) {
+	qv := NewQuantumVerifier()
+	items := slhdsaBatch(t, 4)
+
+	result, err := qv.VerifySLHDSABatch(items, BatchOptions{Workers: 2})
+	if err != nil {
+		t.Fatalf("VerifySLHDSABatch failed: %v", err)
+	}
+	if !result.Valid || result.Checked != 4 || result.FirstInvalid != -1 {
+		t.Errorf("Expected 4 valid signatures, got valid=%v checked=%d first invalid=%d",
+			result.Valid, result.Checked, result.FirstInvalid)
+	}
+
+	// Without early abort every signature is checked
+	bad := append([]SLHDSABatchItem(nil), items...)
+	bad[0].Message = []byte("tampered")
+	result, _ = qv.VerifySLHDSABatch(bad, BatchOptions{Workers: 2})
+	if result.Valid || result.Checked != 4 || result.FirstInvalid != 0 {
+		t.Errorf("Expected full check with first invalid 0, got valid=%v checked=%d first invalid=%d",
+			result.Valid, result.Checked, result.FirstInvalid)
+	}
+
+	// A single worker with early abort stops at the first failure
+	result, _ = qv.VerifySLHDSABatch(bad, BatchOptions{Workers: 1, EarlyAbort: true})
+	if result.Valid || result.Checked != 1 {
+		t.Errorf("Expected abort after 1 signature, got valid=%v checked=%d", result.Valid, result.Checked)
+	}
+
+	if _, err := qv.VerifySLHDSABatch(nil, BatchOptions{}); err != ErrInvalidBatch {
+		t.Errorf("Expected ErrInvalidBatch, got %v", err)
+	}
+}
+
+// TestSLHDSABatchPrecompile tests the batch operation's gas and result
+func TestSLHDSABatchPrecompile(t *testing.T) {
+	p := &quantumVerifyPrecompile{verifier: NewQuantumVerifier()}
+	items := slhdsaBatch(t, 3)
+	input := encodeSLHDSABatch(batchFlagEarlyAbort, items)
+
+	single, _ := SLHDSAVerifyGas(2, len(items[0].Signature), len(items[0].Message))
+	want := single + 2*(single*SLHDSABatchRateBps/10000)
+	if gas := p.RequiredGas(input); gas != want {
+		t.Errorf("Expected batch gas %d, got %d", want, gas)
+	}
+	if want >= 3*single {
+		t.Errorf("Expected batch gas %d below serial gas %d", want, 3*single)
+	}
+
+	ret, _, err := p.Run(nil, common.Address{}, QuantumVerifyContractAddress, input, want, true)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if ret[31] != 1 {
+		t.Error("Expected valid batch")
+	}
+
+	if gas := p.RequiredGas(input[:len(input)-1]); gas != 0 {
+		t.Errorf("Expected zero gas for truncated batch, got %d", gas)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+var (
+	ErrInvalidInput     = errors.New("invalid quantum input")
+	ErrInvalidOperation = errors.New("invalid operation selector")
+	ErrInsufficientGas  = errors.New("insufficient gas for quantum operation")
+)
+
+// Operation selectors (first byte of input), matching the address suffixes
+const (
+	OpVerifyRingtail = 0x01 // Verify Ringtail threshold signature
+	OpVerifyMLDSA    = 0x02 // Verify ML-DSA signature
+	OpVerifySLHDSA   = 0x04 // Verify SLH-DSA signature
+	OpVerifyBLS      = 0x30 // Verify BLS12-381 signature
+
+	// Batched verification
+	OpVerifySLHDSABatch = 0x14 // Verify a batch of SLH-DSA signatures (see batch.go)
+)
+
+// Input layouts (after the op byte, big-endian lengths):
+//
+//	Ringtail: keyID(32) generation(8) maskLen(2) sigLen(4) mask sig message
+//	ML-DSA:   mode(1) publicKey signature message   (sizes fixed by mode)
+//	SLH-DSA:  mode(1) pkLen(2) sigLen(4) publicKey signature message
+//	BLS:      publicKey(48) signature(96) message
+const (
+	ringtailHeaderSize = 32 + 8 + 2 + 4
+	slhdsaHeaderSize   = 1 + 2 + 4
+)
+
+type quantumVerifyPrecompile struct {
+	verifier *QuantumVerifier
+}
+
+// Address returns the precompile address
+func (p *quantumVerifyPrecompile) Address() common.Address {
+	return QuantumVerifyContractAddress
+}
+
+// RequiredGas calculates gas for quantum operations. Malformed input costs
+// nothing here and is rejected by Run.
+func (p *quantumVerifyPrecompile) RequiredGas(input []byte) uint64 {
+	gas, err := p.requiredGas(input)
+	if err != nil {
+		return 0
+	}
+	return gas
+}
+
+// requiredGas prices an operation from its header: the per-mode base cost
+// plus the input-scaled components
+func (p *quantumVerifyPrecompile) requiredGas(input []byte) (uint64, error) {
+	if len(input) < 1 {
+		return 0, ErrInvalidInput
+	}
+	data := input[1:]
+
+	switch input[0] {
+	case OpVerifyRingtail:
+		if len(data) < ringtailHeaderSize {
+			return 0, ErrInvalidInput
+		}
+		maskLen := int(binary.BigEndian.Uint16(data[40:42]))
+		sigLen := int(binary.BigEndian.Uint32(data[42:46]))
+		rest := len(data) - ringtailHeaderSize
+		if maskLen > rest || sigLen > rest-maskLen {
+			return 0, ErrInvalidInput
+		}
+		mask := data[ringtailHeaderSize : ringtailHeaderSize+maskLen]
+		return RingtailVerifyGas(countBits(mask), rest-maskLen-sigLen), nil
+
+	case OpVerifyMLDSA:
+		if len(data) < 1 {
+			return 0, ErrInvalidInput
+		}
+		size := p.verifier.getMLDSAPublicKeySize(data[0]) + p.verifier.getMLDSASignatureSize(data[0])
+		if len(data)-1 < size {
+			return 0, ErrInvalidInput
+		}
+		return MLDSAVerifyGas(data[0], len(data)-1-size)
+
+	case OpVerifySLHDSA:
+		if len(data) < slhdsaHeaderSize {
+			return 0, ErrInvalidInput
+		}
+		pkLen := int(binary.BigEndian.Uint16(data[1:3]))
+		sigLen := int(binary.BigEndian.Uint32(data[3:7]))
+		rest := len(data) - slhdsaHeaderSize
+		if pkLen > rest || sigLen > rest-pkLen {
+			return 0, ErrInvalidInput
+		}
+		return SLHDSAVerifyGas(data[0], sigLen, rest-pkLen-sigLen)
+
+	case OpVerifySLHDSABatch:
+		items, _, err := decodeSLHDSABatch(data)
+		if err != nil {
+			return 0, err
+		}
+		return SLHDSABatchVerifyGas(items)
+
+	case OpVerifyBLS:
+		if len(data) < BLSPublicKeySize+BLSSignatureSize {
+			return 0, ErrInvalidInput
+		}
+		return BLSVerifyGas(len(data) - BLSPublicKeySize - BLSSignatureSize), nil
+
+	default:
+		return 0, ErrInvalidOperation
+	}
+}
+
+// Run executes the quantum verify precompile
+func (p *quantumVerifyPrecompile) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	requiredGas, err := p.requiredGas(input)
+	if err != nil {
+		return nil, suppliedGas, err
+	}
+	if suppliedGas < requiredGas {
+		return nil, suppliedGas, ErrInsufficientGas
+	}
+	remainingGas = suppliedGas - requiredGas
+
+	var valid bool
+	data := input[1:]
+
+	switch input[0] {
+	case OpVerifyRingtail:
+		valid, err = p.verifyRingtail(data)
+	case OpVerifyMLDSA:
+		valid, err = p.verifyMLDSA(data)
+	case OpVerifySLHDSA:
+		valid, err = p.verifySLHDSA(data)
+	case OpVerifySLHDSABatch:
+		valid, err = p.verifySLHDSABatch(data)
+	case OpVerifyBLS:
+		valid, err = p.verifier.VerifyBLS(
+			data[:BLSPublicKeySize],
+			data[BLSPublicKeySize+BLSSignatureSize:],
+			data[BLSPublicKeySize:BLSPublicKeySize+BLSSignatureSize],
+		)
+	}
+	if err != nil {
+		return nil, remainingGas, err
+	}
+	return encodeBool(valid), remainingGas, nil
+}
+
+// verifyRingtail verifies a threshold signature against a registered key
+func (p *quantumVerifyPrecompile) verifyRingtail(data []byte) (bool, error) {
+	var keyID [32]byte
+	copy(keyID[:], data[:32])
+	maskLen := int(binary.BigEndian.Uint16(data[40:42]))
+	sigLen := int(binary.BigEndian.Uint32(data[42:46]))
+
+	mask := data[ringtailHeaderSize : ringtailHeaderSize+maskLen]
+	sig := data[ringtailHeaderSize+maskLen : ringtailHeaderSize+maskLen+sigLen]
+	message := data[ringtailHeaderSize+maskLen+sigLen:]
+
+	result, err := p.verifier.VerifyRingtail(keyID, message, &RingtailSignature{
+		KeyID:      keyID,
+		Signature:  sig,
+		SignerMask: mask,
+		Generation: binary.BigEndian.Uint64(data[32:40]),
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.Valid, nil
+}
+
+// verifyMLDSA verifies an ML-DSA signature; key and signature sizes are
+// fixed by the mode
+func (p *quantumVerifyPrecompile) verifyMLDSA(data []byte) (bool, error) {
+	mode := data[0]
+	pkSize := p.verifier.getMLDSAPublicKeySize(mode)
+	sigSize := p.verifier.getMLDSASignatureSize(mode)
+
+	result, err := p.verifier.VerifyMLDSA(
+		data[1:1+pkSize],
+		data[1+pkSize+sigSize:],
+		&MLDSASignature{Mode: mode, Signature: data[1+pkSize : 1+pkSize+sigSize]},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.Valid, nil
+}
+
+// verifySLHDSA verifies an SLH-DSA signature
+func (p *quantumVerifyPrecompile) verifySLHDSA(data []byte) (bool, error) {
+	pkLen := int(binary.BigEndian.Uint16(data[1:3]))
+	sigLen := int(binary.BigEndian.Uint32(data[3:7]))
+
+	pk := data[slhdsaHeaderSize : slhdsaHeaderSize+pkLen]
+	sig := data[slhdsaHeaderSize+pkLen : slhdsaHeaderSize+pkLen+sigLen]
+	message := data[slhdsaHeaderSize+pkLen+sigLen:]
+
+	result, err := p.verifier.VerifySLHDSA(pk, message, sig, data[0])
+	if err != nil {
+		return false, err
+	}
+	return result.Valid, nil
+}
+
+// verifySLHDSABatch verifies a batch of SLH-DSA signatures; the batch is
+// valid only if every signature is
+func (p *quantumVerifyPrecompile) verifySLHDSABatch(data []byte) (bool, error) {
+	items, opts, err := decodeSLHDSABatch(data)
+	if err != nil {
+		return false, err
+	}
+	result, err := p.verifier.VerifySLHDSABatch(items, opts)
+	if err != nil {
+		return false, err
+	}
+	return result.Valid, nil
+}
+
+// encodeBool encodes a boolean as a 32-byte word
+func encodeBool(b bool) []byte {
+	result := make([]byte, 32)
+	if b {
+		result[31] = 1
+	}
+	return result
+}
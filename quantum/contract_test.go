@@ -0,0 +1,106 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+func mldsaInput(mode uint8, message []byte) []byte {
+	qv := NewQuantumVerifier()
+	size := qv.getMLDSAPublicKeySize(mode) + qv.getMLDSASignatureSize(mode)
+	input := append([]byte{OpVerifyMLDSA, mode}, make([]byte, size)...)
+	return append(input, message...)
+}
+
+// TestRequiredGasPerMode tests that ML-DSA and SLH-DSA costs scale with mode and input
+func TestRequiredGasPerMode(t *testing.T) {
+	p := &quantumVerifyPrecompile{verifier: NewQuantumVerifier()}
+
+	gas44 := p.RequiredGas(mldsaInput(44, nil))
+	gas65 := p.RequiredGas(mldsaInput(65, nil))
+	gas87 := p.RequiredGas(mldsaInput(87, nil))
+	if gas44 != GasMLDSA44Verify || gas65 != GasMLDSA65Verify || gas87 != GasMLDSA87Verify {
+		t.Errorf("Unexpected ML-DSA gas: 44=%d 65=%d 87=%d", gas44, gas65, gas87)
+	}
+
+	long := p.RequiredGas(mldsaInput(65, make([]byte, 100)))
+	if long != GasMLDSA65Verify+4*GasPerMessageWord {
+		t.Errorf("Expected message-scaled gas %d, got %d", GasMLDSA65Verify+4*GasPerMessageWord, long)
+	}
+
+	if gas := p.RequiredGas([]byte{OpVerifyMLDSA, 50}); gas != 0 {
+		t.Errorf("Expected zero gas for unsupported mode, got %d", gas)
+	}
+
+	slh128, err := SLHDSAVerifyGas(2, 17088, 0)
+	if err != nil {
+		t.Fatalf("SLHDSAVerifyGas failed: %v", err)
+	}
+	slh256, _ := SLHDSAVerifyGas(10, 49856, 0)
+	if slh256 <= slh128 {
+		t.Errorf("Expected SLH-DSA-256f (%d) to cost more than 128f (%d)", slh256, slh128)
+	}
+	if _, err := SLHDSAVerifyGas(12, 0, 0); err != ErrUnsupportedAlgorithm {
+		t.Errorf("Expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+}
+
+// TestRingtailGasCountsSigners tests that Ringtail gas scales with the signer mask
+func TestRingtailGasCountsSigners(t *testing.T) {
+	p := &quantumVerifyPrecompile{verifier: NewQuantumVerifier()}
+
+	input := make([]byte, 1+ringtailHeaderSize)
+	input[0] = OpVerifyRingtail
+	binary.BigEndian.PutUint16(input[41:43], 1)
+	binary.BigEndian.PutUint32(input[43:47], 4)
+	input = append(input, 0b00010111)           // 3 signers
+	input = append(input, []byte("sig!msg")...) // 4-byte signature, 3-byte message
+
+	if gas, want := p.RequiredGas(input), RingtailVerifyGas(3, 3); gas != want {
+		t.Errorf("Expected gas %d, got %d", want, gas)
+	}
+
+	// Declared lengths past the end of input are rejected
+	binary.BigEndian.PutUint32(input[43:47], 1000)
+	if _, err := p.requiredGas(input); err != ErrInvalidInput {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+}
+
+// TestRunInsufficientGas tests that under-provisioned calls are rejected before verification
+func TestRunInsufficientGas(t *testing.T) {
+	p := &quantumVerifyPrecompile{verifier: NewQuantumVerifier()}
+	input := mldsaInput(87, []byte("message"))
+	required := p.RequiredGas(input)
+
+	_, remaining, err := p.Run(nil, common.Address{}, QuantumVerifyContractAddress, input, required-1, true)
+	if err != ErrInsufficientGas {
+		t.Errorf("Expected ErrInsufficientGas, got %v", err)
+	}
+	if remaining != required-1 {
+		t.Errorf("Expected supplied gas returned, got %d", remaining)
+	}
+	if p.verifier.TotalVerifications != 0 {
+		t.Error("Verification should not run without enough gas")
+	}
+
+	ret, remaining, err := p.Run(nil, common.Address{}, QuantumVerifyContractAddress, input, required+10, true)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if remaining != 10 {
+		t.Errorf("Expected 10 gas remaining, got %d", remaining)
+	}
+	if len(ret) != 32 || ret[31] != 0 {
+		t.Errorf("Expected false result for zero signature, got %x", ret)
+	}
+
+	if _, _, err := p.Run(nil, common.Address{}, QuantumVerifyContractAddress, []byte{0xEE}, required, true); err != ErrInvalidOperation {
+		t.Errorf("Expected ErrInvalidOperation, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+// Gas schedule
+//
+// Verification cost differs by an order of magnitude across parameter sets:
+// ML-DSA-87 does roughly three times the NTT work of ML-DSA-44, and SLH-DSA
+// cost grows with both security level and signature size. Each operation is
+// charged a per-mode base plus components scaled by the input it hashes.
+
+// Per-mode verification base costs
+const (
+	GasMLDSA44Verify = uint64(30000)
+	GasMLDSA65Verify = GasMLDSAVerify
+	GasMLDSA87Verify = uint64(85000)
+
+	GasSLHDSA128Verify = uint64(60000)
+	GasSLHDSA192Verify = GasSLHDSAVerify
+	GasSLHDSA256Verify = uint64(160000)
+)
+
+// Input-scaled components
+const (
+	GasPerMessageWord    = uint64(12)   // Message hashing, per 32-byte word
+	GasSLHDSAPerSigWord  = uint64(8)    // SLH-DSA hash-tree walk, per 32-byte signature word
+	GasRingtailPerSigner = uint64(2500) // Ringtail share aggregation, per contributing signer
+)
+
+// SLHDSABatchRateBps is the share of its single-verify cost charged for each
+// signature after the first in a batch. The batch runs its signatures in
+// parallel, so it holds the block for far less than their sum.
+const SLHDSABatchRateBps = uint64(6000)
+
+// SLHDSABatchVerifyGas returns the gas to verify a batch of SLH-DSA
+// signatures: the first at full cost, the rest at SLHDSABatchRateBps
+func SLHDSABatchVerifyGas(items []SLHDSABatchItem) (uint64, error) {
+	var total uint64
+	for i, item := range items {
+		gas, err := SLHDSAVerifyGas(item.Mode, len(item.Signature), len(item.Message))
+		if err != nil {
+			return 0, err
+		}
+		if i > 0 {
+			gas = gas * SLHDSABatchRateBps / 10000
+		}
+		total += gas
+	}
+	return total, nil
+}
+
+// MLDSAVerifyGas returns the gas to verify an ML-DSA signature over msgLen bytes
+func MLDSAVerifyGas(mode uint8, msgLen int) (uint64, error) {
+	var base uint64
+	switch mode {
+	case 44:
+		base = GasMLDSA44Verify
+	case 65:
+		base = GasMLDSA65Verify
+	case 87:
+		base = GasMLDSA87Verify
+	default:
+		return 0, ErrUnsupportedAlgorithm
+	}
+	return base + wordGas(msgLen, GasPerMessageWord), nil
+}
+
+// SLHDSAVerifyGas returns the gas to verify an SLH-DSA signature of sigLen
+// bytes over msgLen bytes. Modes 0-3 are 128-bit, 4-7 192-bit, 8-11 256-bit.
+func SLHDSAVerifyGas(mode uint8, sigLen, msgLen int) (uint64, error) {
+	var base uint64
+	switch {
+	case mode <= 3:
+		base = GasSLHDSA128Verify
+	case mode <= 7:
+		base = GasSLHDSA192Verify
+	case mode <= 11:
+		base = GasSLHDSA256Verify
+	default:
+		return 0, ErrUnsupportedAlgorithm
+	}
+	return base + wordGas(sigLen, GasSLHDSAPerSigWord) + wordGas(msgLen, GasPerMessageWord), nil
+}
+
+// RingtailVerifyGas returns the gas to verify a Ringtail threshold signature
+// produced by signers parties over msgLen bytes
+func RingtailVerifyGas(signers, msgLen int) uint64 {
+	return GasRingtailVerify + uint64(signers)*GasRingtailPerSigner + wordGas(msgLen, GasPerMessageWord)
+}
+
+// BLSVerifyGas returns the gas to verify a BLS signature over msgLen bytes
+func BLSVerifyGas(msgLen int) uint64 {
+	return GasBLSVerify + wordGas(msgLen, GasPerMessageWord)
+}
+
+// wordGas charges perWord for every started 32-byte word of n bytes
+func wordGas(n int, perWord uint64) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return uint64((n+31)/32) * perWord
+}
@@ -0,0 +1,275 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+import (
+	"crypto/ed25519"
+	"sync"
+
+	"github.com/luxfi/geth/common"
+)
+
+// Hybrid scheme registry.
+//
+// A hybrid scheme pairs a classical and a post-quantum signature under a
+// combiner policy. Schemes are versioned descriptors rather than code:
+// VerifyHybrid looks up the descriptor for a signature's scheme and
+// dispatches to the verifiers it names, so governance can register a new
+// pairing (or a new version of an existing one) without a release.
+//
+// A signature names its scheme and optionally a version; version 0 means
+// the latest active version. Deprecating a version removes it from
+// negotiation and from latest-version resolution, but signatures that name
+// it explicitly remain verifiable.
+
+// ClassicalAlgorithm is the classical half of a hybrid scheme
+type ClassicalAlgorithm uint8
+
+const (
+	ClassicalBLS     ClassicalAlgorithm = iota // BLS12-381
+	ClassicalECDSA                             // ECDSA secp256k1
+	ClassicalSchnorr                           // BIP-340 Schnorr
+	ClassicalEd25519                           // Ed25519
+)
+
+// CombinerPolicy decides how the two component results combine
+type CombinerPolicy uint8
+
+const (
+	CombineCaller CombinerPolicy = iota // The verifier's caller chooses
+	CombineBoth                         // Both components must verify
+	CombineEither                       // Either component suffices
+)
+
+// HybridSchemeDescriptor is one registered version of a hybrid scheme
+type HybridSchemeDescriptor struct {
+	Scheme     HybridScheme       `json:"scheme"`
+	Version    uint16             `json:"version"`
+	Classical  ClassicalAlgorithm `json:"classical"`
+	Quantum    QuantumAlgorithm   `json:"quantum"`
+	Combiner   CombinerPolicy     `json:"combiner"`
+	Deprecated bool               `json:"deprecated,omitempty"`
+}
+
+// DefaultHybridSchemes are the built-in schemes, registered at version 1
+var DefaultHybridSchemes = []HybridSchemeDescriptor{
+	{Scheme: HybridBLSRingtail, Version: 1, Classical: ClassicalBLS, Quantum: AlgRingtail},
+	{Scheme: HybridECDSAMLDSA, Version: 1, Classical: ClassicalECDSA, Quantum: AlgMLDSA65},
+	{Scheme: HybridSchnorrRingtail, Version: 1, Classical: ClassicalSchnorr, Quantum: AlgRingtail},
+	{Scheme: HybridEd25519MLDSA, Version: 1, Classical: ClassicalEd25519, Quantum: AlgMLDSA65},
+}
+
+// componentVerifier checks one half of a hybrid signature
+type componentVerifier func(qv *QuantumVerifier, publicKey, message, signature []byte) bool
+
+// classicalVerifiers are the classical algorithms a descriptor may name
+var classicalVerifiers = map[ClassicalAlgorithm]componentVerifier{
+	ClassicalBLS:     (*QuantumVerifier).verifyBLSSignature,
+	ClassicalECDSA:   (*QuantumVerifier).verifyECDSASignature,
+	ClassicalSchnorr: (*QuantumVerifier).verifySchnorrSignature,
+	ClassicalEd25519: (*QuantumVerifier).verifyEd25519Signature,
+}
+
+// quantumVerifiers are the post-quantum algorithms a descriptor may name
+var quantumVerifiers = map[QuantumAlgorithm]componentVerifier{
+	AlgRingtail: func(_ *QuantumVerifier, publicKey, message, signature []byte) bool {
+		return VerifyRingtailSignature(publicKey, message, signature)
+	},
+	AlgMLDSA44:        mldsaComponent(44),
+	AlgMLDSA65:        mldsaComponent(65),
+	AlgMLDSA87:        mldsaComponent(87),
+	AlgSLHDSASHA2128f: slhdsaComponent(2),
+	AlgSLHDSASHA2192f: slhdsaComponent(6),
+	AlgSLHDSASHA2256f: slhdsaComponent(10),
+}
+
+func mldsaComponent(mode uint8) componentVerifier {
+	return func(qv *QuantumVerifier, publicKey, message, signature []byte) bool {
+		return qv.verifyMLDSASignature(publicKey, message, &MLDSASignature{Mode: mode, Signature: signature})
+	}
+}
+
+func slhdsaComponent(mode uint8) componentVerifier {
+	return func(qv *QuantumVerifier, publicKey, message, signature []byte) bool {
+		return qv.verifySLHDSASignature(publicKey, message, signature, mode)
+	}
+}
+
+// validate checks that a descriptor names known algorithms and policy
+func (d *HybridSchemeDescriptor) validate() error {
+	if d.Version == 0 || d.Combiner > CombineEither {
+		return ErrInvalidHybridScheme
+	}
+	if classicalVerifiers[d.Classical] == nil || quantumVerifiers[d.Quantum] == nil {
+		return ErrInvalidHybridScheme
+	}
+	return nil
+}
+
+// bothRequired resolves the combiner against the caller's preference
+func (d *HybridSchemeDescriptor) bothRequired(callerRequired bool) bool {
+	switch d.Combiner {
+	case CombineBoth:
+		return true
+	case CombineEither:
+		return false
+	default:
+		return callerRequired
+	}
+}
+
+// HybridSchemeRegistry holds the versioned hybrid scheme descriptors
+type HybridSchemeRegistry struct {
+	mu sync.RWMutex
+
+	// governor is the only address allowed to register or deprecate schemes
+	governor common.Address
+
+	// versions holds each scheme's descriptors in ascending version order
+	versions map[HybridScheme][]*HybridSchemeDescriptor
+}
+
+// NewHybridSchemeRegistry creates a registry with the default schemes
+func NewHybridSchemeRegistry(governor common.Address) *HybridSchemeRegistry {
+	r := &HybridSchemeRegistry{
+		governor: governor,
+		versions: make(map[HybridScheme][]*HybridSchemeDescriptor),
+	}
+	for _, desc := range DefaultHybridSchemes {
+		d := desc
+		r.versions[d.Scheme] = append(r.versions[d.Scheme], &d)
+	}
+	return r
+}
+
+// Governor returns the address allowed to change the registry
+func (r *HybridSchemeRegistry) Governor() common.Address {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.governor
+}
+
+// SetGovernor replaces the registry's governor
+func (r *HybridSchemeRegistry) SetGovernor(governor common.Address) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.governor = governor
+}
+
+// Register adds a scheme descriptor (governor only). A new version of an
+// existing scheme must be numbered above every registered version.
+func (r *HybridSchemeRegistry) Register(caller common.Address, desc HybridSchemeDescriptor) error {
+	if err := desc.validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if caller != r.governor {
+		return ErrUnauthorized
+	}
+	versions := r.versions[desc.Scheme]
+	if n := len(versions); n > 0 && desc.Version <= versions[n-1].Version {
+		return ErrInvalidHybridScheme
+	}
+	desc.Deprecated = false
+	r.versions[desc.Scheme] = append(versions, &desc)
+	return nil
+}
+
+// Deprecate withdraws a scheme version from negotiation and latest-version
+// resolution (governor only)
+func (r *HybridSchemeRegistry) Deprecate(caller common.Address, scheme HybridScheme, version uint16) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if caller != r.governor {
+		return ErrUnauthorized
+	}
+	desc := r.lookup(scheme, version)
+	if desc == nil {
+		return ErrUnsupportedHybrid
+	}
+	desc.Deprecated = true
+	return nil
+}
+
+// Resolve returns the descriptor a signature verifies under: the given
+// version, or the latest active version if version is 0
+func (r *HybridSchemeRegistry) Resolve(scheme HybridScheme, version uint16) (HybridSchemeDescriptor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var desc *HybridSchemeDescriptor
+	if version == 0 {
+		desc = r.latest(scheme)
+	} else {
+		desc = r.lookup(scheme, version)
+	}
+	if desc == nil {
+		return HybridSchemeDescriptor{}, ErrUnsupportedHybrid
+	}
+	return *desc, nil
+}
+
+// Negotiate picks the first scheme in a peer's preference list that has an
+// active version, returning its latest active descriptor
+func (r *HybridSchemeRegistry) Negotiate(offered []HybridScheme) (HybridSchemeDescriptor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, scheme := range offered {
+		if desc := r.latest(scheme); desc != nil {
+			return *desc, nil
+		}
+	}
+	return HybridSchemeDescriptor{}, ErrUnsupportedHybrid
+}
+
+// Schemes returns every registered descriptor, ordered by scheme and version
+func (r *HybridSchemeRegistry) Schemes() []HybridSchemeDescriptor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var descs []HybridSchemeDescriptor
+	for scheme := 0; scheme <= 0xFF; scheme++ {
+		for _, desc := range r.versions[HybridScheme(scheme)] {
+			descs = append(descs, *desc)
+		}
+	}
+	return descs
+}
+
+// lookup finds a specific version. Caller must hold r.mu.
+func (r *HybridSchemeRegistry) lookup(scheme HybridScheme, version uint16) *HybridSchemeDescriptor {
+	for _, desc := range r.versions[scheme] {
+		if desc.Version == version {
+			return desc
+		}
+	}
+	return nil
+}
+
+// latest finds the highest active version. Caller must hold r.mu.
+func (r *HybridSchemeRegistry) latest(scheme HybridScheme) *HybridSchemeDescriptor {
+	versions := r.versions[scheme]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].Deprecated {
+			return versions[i]
+		}
+	}
+	return nil
+}
+
+func (qv *QuantumVerifier) verifyEd25519Signature(
+	publicKey []byte,
+	message []byte,
+	signature []byte,
+) bool {
+	if len(publicKey) != ed25519.PublicKeySize || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(publicKey, message, signature)
+}
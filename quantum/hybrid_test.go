@@ -0,0 +1,133 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/luxfi/crypto/slhdsa"
+	"github.com/luxfi/geth/common"
+)
+
+// TestHybridSchemeRegistry tests governance, versioning and negotiation
+func TestHybridSchemeRegistry(t *testing.T) {
+	governor := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	r := NewHybridSchemeRegistry(governor)
+
+	v2 := HybridSchemeDescriptor{Scheme: HybridECDSAMLDSA, Version: 2, Classical: ClassicalECDSA, Quantum: AlgMLDSA87, Combiner: CombineBoth}
+	if err := r.Register(common.Address{1}, v2); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if err := r.Register(governor, HybridSchemeDescriptor{Scheme: 9, Version: 1, Classical: 99}); err != ErrInvalidHybridScheme {
+		t.Errorf("Expected ErrInvalidHybridScheme for unknown algorithm, got %v", err)
+	}
+	if err := r.Register(governor, v2); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register(governor, v2); err != ErrInvalidHybridScheme {
+		t.Errorf("Expected ErrInvalidHybridScheme for stale version, got %v", err)
+	}
+
+	// Version 0 resolves to the latest active version
+	if desc, err := r.Resolve(HybridECDSAMLDSA, 0); err != nil || desc.Version != 2 || desc.Quantum != AlgMLDSA87 {
+		t.Errorf("Expected version 2, got %+v (%v)", desc, err)
+	}
+	if err := r.Deprecate(governor, HybridECDSAMLDSA, 2); err != nil {
+		t.Fatalf("Deprecate failed: %v", err)
+	}
+	if desc, _ := r.Resolve(HybridECDSAMLDSA, 0); desc.Version != 1 {
+		t.Errorf("Expected fallback to version 1, got %d", desc.Version)
+	}
+	if desc, err := r.Resolve(HybridECDSAMLDSA, 2); err != nil || !desc.Deprecated {
+		t.Errorf("Expected deprecated version to stay resolvable, got %+v (%v)", desc, err)
+	}
+	if _, err := r.Resolve(HybridECDSAMLDSA, 3); err != ErrUnsupportedHybrid {
+		t.Errorf("Expected ErrUnsupportedHybrid, got %v", err)
+	}
+
+	// Negotiation skips schemes with no active version
+	if err := r.Deprecate(governor, HybridBLSRingtail, 1); err != nil {
+		t.Fatalf("Deprecate failed: %v", err)
+	}
+	desc, err := r.Negotiate([]HybridScheme{42, HybridBLSRingtail, HybridEd25519MLDSA, HybridECDSAMLDSA})
+	if err != nil || desc.Scheme != HybridEd25519MLDSA {
+		t.Errorf("Expected Ed25519+ML-DSA, got %+v (%v)", desc, err)
+	}
+	if _, err := r.Negotiate([]HybridScheme{HybridBLSRingtail}); err != ErrUnsupportedHybrid {
+		t.Errorf("Expected ErrUnsupportedHybrid, got %v", err)
+	}
+
+	if n := len(r.Schemes()); n != len(DefaultHybridSchemes)+1 {
+		t.Errorf("Expected %d descriptors, got %d", len(DefaultHybridSchemes)+1, n)
+	}
+}
+
+// TestVerifyHybridRegisteredScheme tests verification under a scheme
+// registered at runtime
+func TestVerifyHybridRegisteredScheme(t *testing.T) {
+	governor := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	qv := NewQuantumVerifier()
+	qv.HybridSchemes.SetGovernor(governor)
+
+	const scheme HybridScheme = 8
+	err := qv.HybridSchemes.Register(governor, HybridSchemeDescriptor{
+		Scheme:    scheme,
+		Version:   1,
+		Classical: ClassicalEd25519,
+		Quantum:   AlgSLHDSASHA2128f,
+		Combiner:  CombineBoth,
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	message := []byte("hybrid message")
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	slhPriv, err := slhdsa.GenerateKey(rand.Reader, slhdsa.SHA2_128f)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	slhSig, err := slhPriv.Sign(rand.Reader, message, nil)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	signature := &HybridSignature{
+		Scheme:          scheme,
+		ClassicalPubKey: edPub,
+		ClassicalSig:    ed25519.Sign(edPriv, message),
+		QuantumPubKey:   slhPriv.PublicKey.Bytes(),
+		QuantumSig:      slhSig,
+	}
+
+	// The combiner overrides the caller's preference
+	result, err := qv.VerifyHybrid(message, signature, false)
+	if err != nil {
+		t.Fatalf("VerifyHybrid failed: %v", err)
+	}
+	if !result.Valid || !result.HybridComponents.BothRequired || result.Algorithm != AlgSLHDSASHA2128f {
+		t.Errorf("Expected valid both-required SLH-DSA result, got %+v", result.HybridComponents)
+	}
+
+	// A broken classical half fails the whole signature
+	signature.ClassicalSig = append([]byte{}, signature.ClassicalSig...)
+	signature.ClassicalSig[0] ^= 0xFF
+	result, err = qv.VerifyHybrid(message, signature, false)
+	if err != nil {
+		t.Fatalf("VerifyHybrid failed: %v", err)
+	}
+	if result.Valid || result.HybridComponents.ClassicalValid || !result.HybridComponents.QuantumValid {
+		t.Errorf("Expected only the quantum half to verify, got %+v", result.HybridComponents)
+	}
+
+	signature.Version = 2
+	if _, err := qv.VerifyHybrid(message, signature, false); err != ErrUnsupportedHybrid {
+		t.Errorf("Expected ErrUnsupportedHybrid for unregistered version, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+import (
+	"fmt"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+	"github.com/luxfi/precompile/modules"
+	"github.com/luxfi/precompile/precompileconfig"
+)
+
+var _ contract.Configurator = (*configurator)(nil)
+var _ contract.StatefulPrecompiledContract = (*quantumVerifyPrecompile)(nil)
+
+// ConfigKey is the key used in json config files to specify this precompile config.
+const ConfigKey = "quantumConfig"
+
+// QuantumVerifyContractAddress is the quantum signature dispatcher (Post-Quantum range 0x0600)
+var QuantumVerifyContractAddress = common.HexToAddress("0x0600000000000000000000000000000000000000")
+
+// QuantumVerifyPrecompile is the singleton instance of the quantum verify precompile
+var QuantumVerifyPrecompile = &quantumVerifyPrecompile{verifier: NewQuantumVerifier()}
+
+// Module is the precompile module
+var Module = modules.Module{
+	ConfigKey:    ConfigKey,
+	Address:      QuantumVerifyContractAddress,
+	Contract:     QuantumVerifyPrecompile,
+	Configurator: &configurator{},
+}
+
+type configurator struct{}
+
+func init() {
+	if err := modules.RegisterModule(Module); err != nil {
+		panic(err)
+	}
+}
+
+func (*configurator) MakeConfig() precompileconfig.Config {
+	return new(Config)
+}
+
+func (*configurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	config, ok := cfg.(*Config)
+	if !ok {
+		return fmt.Errorf("expected config type %T, got %T: %v", &Config{}, cfg, cfg)
+	}
+	if config.HybridSchemeGovernor != nil {
+		QuantumVerifyPrecompile.verifier.HybridSchemes.SetGovernor(*config.HybridSchemeGovernor)
+	}
+	return nil
+}
+
+// Config implements the precompileconfig.Config interface
+type Config struct {
+	Upgrade precompileconfig.Upgrade `json:"upgrade,omitempty"`
+
+	// HybridSchemeGovernor may register and deprecate hybrid schemes
+	HybridSchemeGovernor *common.Address `json:"hybridSchemeGovernor,omitempty"`
+}
+
+func (c *Config) Key() string {
+	return ConfigKey
+}
+
+func (c *Config) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *Config) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *Config) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*Config)
+	if !ok {
+		return false
+	}
+	if (c.HybridSchemeGovernor == nil) != (other.HybridSchemeGovernor == nil) {
+		return false
+	}
+	if c.HybridSchemeGovernor != nil && *c.HybridSchemeGovernor != *other.HybridSchemeGovernor {
+		return false
+	}
+	return c.Upgrade.Equal(&other.Upgrade)
+}
+
+func (c *Config) Verify(chainConfig precompileconfig.ChainConfig) error {
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package quantum
+
+// Stamp freshness and committee rotation.
+//
+// A stamp is only as trustworthy as the committee that signed it at the
+// time it was issued. Verification therefore checks, in order:
+//  1. The stamp is fresh under the caller's use-case policy
+//  2. The signing key generation was active at the stamp's time and epoch
+//  3. The Ringtail signature verifies under that generation's public key
+
+// DefaultStampUseCase is the policy applied when no use case is given
+const DefaultStampUseCase = ""
+
+// Default freshness bounds
+const (
+	DefaultStampMaxAge     = 24 * 60 * 60 // 1 day
+	DefaultStampFutureSkew = 5 * 60       // 5 minutes
+)
+
+// SetStampPolicy configures freshness bounds for a use case
+func (qv *QuantumVerifier) SetStampPolicy(useCase string, maxAge, maxFutureSkew uint64) {
+	qv.mu.Lock()
+	defer qv.mu.Unlock()
+
+	qv.StampPolicies[useCase] = &StampPolicy{
+		MaxAge:        maxAge,
+		MaxFutureSkew: maxFutureSkew,
+	}
+}
+
+// GetStampPolicy returns the policy for a use case, falling back to the default
+func (qv *QuantumVerifier) GetStampPolicy(useCase string) *StampPolicy {
+	qv.mu.RLock()
+	defer qv.mu.RUnlock()
+	return qv.stampPolicy(useCase)
+}
+
+// stampPolicy resolves a use case policy. Caller must hold qv.mu.
+func (qv *QuantumVerifier) stampPolicy(useCase string) *StampPolicy {
+	if policy := qv.StampPolicies[useCase]; policy != nil {
+		return policy
+	}
+	if policy := qv.StampPolicies[DefaultStampUseCase]; policy != nil {
+		return policy
+	}
+	return &StampPolicy{MaxAge: DefaultStampMaxAge, MaxFutureSkew: DefaultStampFutureSkew}
+}
+
+// RotateRingtailKey installs a new committee public key for keyID. The
+// current generation is retired at activatedAt/epoch so stamps it signed
+// before that point stay verifiable and later ones do not.
+func (qv *QuantumVerifier) RotateRingtailKey(
+	keyID [32]byte,
+	newPublicKey []byte,
+	activatedAt uint64,
+	epoch uint64,
+) (uint64, error) {
+	qv.mu.Lock()
+	defer qv.mu.Unlock()
+
+	key := qv.RingtailKeys[keyID]
+	if key == nil {
+		return 0, ErrKeyNotFound
+	}
+	if len(newPublicKey) == 0 {
+		return 0, ErrInvalidPublicKey
+	}
+
+	history := qv.KeyHistory[keyID]
+	if len(history) > 0 {
+		current := history[len(history)-1]
+		if activatedAt <= current.ActivatedAt || epoch < current.ActivatedEpoch {
+			return 0, ErrInvalidRotation
+		}
+		current.RetiredAt = activatedAt
+		// Chains that do not track epochs rotate on time alone
+		if epoch > current.ActivatedEpoch {
+			current.RetiredEpoch = epoch
+		}
+	}
+
+	key.Generation++
+	key.PublicKey = newPublicKey
+	qv.KeyHistory[keyID] = append(history, &RingtailKeyGeneration{
+		Generation:     key.Generation,
+		PublicKey:      newPublicKey,
+		ActivatedAt:    activatedAt,
+		ActivatedEpoch: epoch,
+	})
+
+	return key.Generation, nil
+}
+
+// keyGeneration looks up a generation in a key's history. Caller must hold qv.mu.
+func (qv *QuantumVerifier) keyGeneration(keyID [32]byte, generation uint64) *RingtailKeyGeneration {
+	for _, gen := range qv.KeyHistory[keyID] {
+		if gen.Generation == generation {
+			return gen
+		}
+	}
+	return nil
+}
+
+// activeAt reports whether a generation was active at the given time and epoch
+func (g *RingtailKeyGeneration) activeAt(timestamp, epoch uint64) bool {
+	if timestamp < g.ActivatedAt || (g.RetiredAt != 0 && timestamp >= g.RetiredAt) {
+		return false
+	}
+	if epoch < g.ActivatedEpoch || (g.RetiredEpoch != 0 && epoch >= g.RetiredEpoch) {
+		return false
+	}
+	return true
+}
+
+// VerifyQuantumStampAt verifies a stamp at time now under a use case's
+// freshness policy and the key rotation history
+func (qv *QuantumVerifier) VerifyQuantumStampAt(
+	stamp *QuantumStamp,
+	useCase string,
+	now uint64,
+) (bool, error) {
+	qv.mu.Lock()
+	defer qv.mu.Unlock()
+	return qv.verifyQuantumStamp(stamp, useCase, now)
+}
+
+// verifyQuantumStamp implements stamp verification. Caller must hold qv.mu.
+func (qv *QuantumVerifier) verifyQuantumStamp(
+	stamp *QuantumStamp,
+	useCase string,
+	now uint64,
+) (bool, error) {
+	if stamp == nil || stamp.Signature == nil {
+		return false, ErrInvalidStamp
+	}
+
+	// 1. Freshness
+	policy := qv.stampPolicy(useCase)
+	if stamp.Timestamp > now && stamp.Timestamp-now > policy.MaxFutureSkew {
+		return false, ErrStampFromFuture
+	}
+	if policy.MaxAge > 0 && now > stamp.Timestamp && now-stamp.Timestamp > policy.MaxAge {
+		return false, ErrStampExpired
+	}
+
+	// 2. Signing generation active at stamp time
+	key := qv.RingtailKeys[stamp.Signature.KeyID]
+	if key == nil {
+		return false, ErrKeyNotFound
+	}
+	gen := qv.keyGeneration(stamp.Signature.KeyID, stamp.Signature.Generation)
+	if gen == nil || !gen.activeAt(stamp.Timestamp, stamp.Epoch) {
+		return false, ErrGenerationNotActive
+	}
+
+	// 3. Signature under that generation's key
+	genKey := *key
+	genKey.PublicKey = gen.PublicKey
+	genKey.Generation = gen.Generation
+
+	stampData := append(append([]byte{}, stamp.BlockID[:]...), stamp.Message...)
+	result, err := qv.verifyRingtailWithKey(&genKey, stampData, stamp.Signature)
+	if err != nil {
+		return false, err
+	}
+	return result.Valid, nil
+}
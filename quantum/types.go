@@ -126,6 +126,7 @@ type MLKEMCiphertext struct {
 // HybridSignature combines classical and PQ signatures
 type HybridSignature struct {
 	Scheme          HybridScheme
+	Version         uint16 // Scheme version (0 = latest active)
 	ClassicalSig    []byte // ECDSA/BLS/Schnorr signature
 	QuantumSig      []byte // Ringtail/ML-DSA signature
 	ClassicalPubKey []byte // Classical public key
@@ -138,11 +139,30 @@ type QuantumStamp struct {
 	BlockID     [32]byte           // Q-Chain block ID
 	BlockHeight uint64             // Q-Chain block height
 	Timestamp   uint64             // Unix timestamp
+	Epoch       uint64             // Q-Chain committee epoch at stamping
 	PChainRef   uint64             // P-Chain block reference
 	Message     []byte             // Stamped message/hash
 	Signature   *RingtailSignature // Quantum signature
 }
 
+// StampPolicy bounds how old (or how far ahead) a stamp may be for a use case
+type StampPolicy struct {
+	MaxAge        uint64 // Max seconds between stamp time and now (0 = no limit)
+	MaxFutureSkew uint64 // Max seconds a stamp may be ahead of now
+}
+
+// RingtailKeyGeneration is one entry in a committee key's rotation history.
+// A generation is active for stamps in [ActivatedAt, RetiredAt) and
+// [ActivatedEpoch, RetiredEpoch); zero retirement values mean still active.
+type RingtailKeyGeneration struct {
+	Generation     uint64
+	PublicKey      []byte
+	ActivatedAt    uint64 // Unix timestamp the generation became active
+	RetiredAt      uint64 // Unix timestamp it was rotated out (0 = current)
+	ActivatedEpoch uint64 // Q-Chain epoch the generation became active
+	RetiredEpoch   uint64 // Q-Chain epoch it was rotated out (0 = current)
+}
+
 // QuantumAnchor anchors data to Q-Chain with quantum proof
 type QuantumAnchor struct {
 	AnchorID [32]byte // Unique anchor identifier
@@ -178,6 +198,13 @@ type QuantumKeyPair struct {
 	Address    common.Address // Derived EVM address
 }
 
+// AddressRecord maps a derived address back to its key material
+type AddressRecord struct {
+	Algorithm     QuantumAlgorithm // Algorithm tag used in derivation
+	PublicKeyHash common.Hash      // keccak256(pubkey)
+	Legacy        bool             // Derived with the legacy sha256 scheme
+}
+
 // VerificationResult represents the result of quantum signature verification
 type VerificationResult struct {
 	Valid            bool
@@ -207,12 +234,20 @@ var (
 	ErrDecapsulationFailed   = errors.New("ML-KEM decapsulation failed")
 	ErrInvalidStamp          = errors.New("invalid quantum stamp")
 	ErrStampExpired          = errors.New("quantum stamp expired")
+	ErrStampFromFuture       = errors.New("quantum stamp timestamp in the future")
+	ErrGenerationNotActive   = errors.New("key generation not active at stamp time")
+	ErrInvalidRotation       = errors.New("invalid key rotation")
 	ErrInvalidAnchor         = errors.New("invalid quantum anchor")
 	ErrBLSVerificationFailed = errors.New("BLS verification failed")
 	ErrBLSAggregationFailed  = errors.New("BLS aggregation failed")
 	ErrHybridMismatch        = errors.New("hybrid signature scheme mismatch")
 	ErrKeyNotFound           = errors.New("quantum key not found")
 	ErrInvalidProof          = errors.New("invalid quantum proof")
+	ErrAddressNotFound       = errors.New("quantum address not registered")
+	ErrAddressCollision      = errors.New("quantum address already registered to different key")
+	ErrInvalidBatch          = errors.New("invalid signature batch size")
+	ErrInvalidHybridScheme   = errors.New("invalid hybrid scheme descriptor")
+	ErrUnauthorized          = errors.New("caller not authorized")
 )
 
 // Security level constants
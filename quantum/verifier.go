@@ -32,9 +32,25 @@ type QuantumVerifier struct {
 	Stamps  map[[32]byte]*QuantumStamp
 	Anchors map[[32]byte]*QuantumAnchor
 
+	// Ringtail committee key rotation history (keyID -> generations)
+	KeyHistory map[[32]byte][]*RingtailKeyGeneration
+
+	// Stamp freshness policies by use case
+	StampPolicies map[string]*StampPolicy
+
+	// Derived address registry (address -> algorithm and key hash)
+	Addresses map[common.Address]*AddressRecord
+
+	// Hybrid scheme descriptors VerifyHybrid dispatches on
+	HybridSchemes *HybridSchemeRegistry
+
 	// Q-Chain connection
 	QChainEndpoint string
 
+	// LegacyAddressDerivation selects the pre-standard sha256(pubkey)[12:]
+	// derivation for chains that already issued addresses with it
+	LegacyAddressDerivation bool
+
 	// Statistics
 	TotalVerifications uint64
 	TotalValid         uint64
@@ -52,6 +68,12 @@ func NewQuantumVerifier() *QuantumVerifier {
 		BLSKeys:      make(map[[32]byte]*BLSPublicKey),
 		Stamps:       make(map[[32]byte]*QuantumStamp),
 		Anchors:      make(map[[32]byte]*QuantumAnchor),
+		Addresses:    make(map[common.Address]*AddressRecord),
+		KeyHistory:   make(map[[32]byte][]*RingtailKeyGeneration),
+		StampPolicies: map[string]*StampPolicy{
+			DefaultStampUseCase: {MaxAge: DefaultStampMaxAge, MaxFutureSkew: DefaultStampFutureSkew},
+		},
+		HybridSchemes: NewHybridSchemeRegistry(common.Address{}),
 	}
 }
 
@@ -74,6 +96,17 @@ func (qv *QuantumVerifier) VerifyRingtail(
 		return nil, ErrInvalidSignature
 	}
 
+	return qv.verifyRingtailWithKey(key, message, signature)
+}
+
+// verifyRingtailWithKey checks the threshold and signature against a
+// specific key generation. Caller must hold qv.mu.
+func (qv *QuantumVerifier) verifyRingtailWithKey(
+	key *RingtailPublicKey,
+	message []byte,
+	signature *RingtailSignature,
+) (*VerificationResult, error) {
+
 	// Count signers from mask
 	signerCount := countBits(signature.SignerMask)
 	if uint32(signerCount) < key.Threshold+1 {
@@ -96,7 +129,7 @@ func (qv *QuantumVerifier) VerifyRingtail(
 		Algorithm:       AlgRingtail,
 		MessageHash:     msgHash,
 		SignerPublicKey: key.PublicKey,
-		GasUsed:         GasRingtailVerify,
+		GasUsed:         RingtailVerifyGas(signerCount, len(message)),
 	}, nil
 }
 
@@ -131,13 +164,14 @@ func (qv *QuantumVerifier) VerifyMLDSA(
 		qv.TotalInvalid++
 	}
 
+	gas, _ := MLDSAVerifyGas(signature.Mode, len(message))
 	msgHash := sha256.Sum256(message)
 	return &VerificationResult{
 		Valid:           valid,
 		Algorithm:       qv.modeToAlgorithm(signature.Mode),
 		MessageHash:     msgHash,
 		SignerPublicKey: publicKey,
-		GasUsed:         GasMLDSAVerify,
+		GasUsed:         gas,
 	}, nil
 }
 
@@ -161,51 +195,39 @@ func (qv *QuantumVerifier) VerifySLHDSA(
 		qv.TotalInvalid++
 	}
 
+	gas, _ := SLHDSAVerifyGas(mode, len(signature), len(message))
 	msgHash := sha256.Sum256(message)
 	return &VerificationResult{
 		Valid:           valid,
 		Algorithm:       AlgSLHDSASHA2128f + QuantumAlgorithm(mode),
 		MessageHash:     msgHash,
 		SignerPublicKey: publicKey,
-		GasUsed:         GasSLHDSAVerify,
+		GasUsed:         gas,
 	}, nil
 }
 
-// VerifyHybrid verifies a hybrid classical+PQ signature
+// VerifyHybrid verifies a hybrid classical+PQ signature under its
+// registered scheme descriptor. bothRequired applies to schemes whose
+// combiner leaves the choice to the caller.
 func (qv *QuantumVerifier) VerifyHybrid(
 	message []byte,
 	signature *HybridSignature,
 	bothRequired bool,
 ) (*VerificationResult, error) {
+	desc, err := qv.HybridSchemes.Resolve(signature.Scheme, signature.Version)
+	if err != nil {
+		return nil, err
+	}
+
 	qv.mu.Lock()
 	defer qv.mu.Unlock()
 
-	var classicalValid, quantumValid bool
-
-	// Verify classical component
-	switch signature.Scheme {
-	case HybridBLSRingtail:
-		classicalValid = qv.verifyBLSSignature(signature.ClassicalPubKey, message, signature.ClassicalSig)
-	case HybridECDSAMLDSA:
-		classicalValid = qv.verifyECDSASignature(signature.ClassicalPubKey, message, signature.ClassicalSig)
-	case HybridSchnorrRingtail:
-		classicalValid = qv.verifySchnorrSignature(signature.ClassicalPubKey, message, signature.ClassicalSig)
-	default:
-		return nil, ErrUnsupportedHybrid
-	}
-
-	// Verify quantum component
-	switch signature.Scheme {
-	case HybridBLSRingtail, HybridSchnorrRingtail:
-		// Ringtail verification
-		quantumValid = len(signature.QuantumSig) > 0 && len(signature.QuantumPubKey) > 0
-	case HybridECDSAMLDSA:
-		// ML-DSA verification
-		mldsaSig := &MLDSASignature{Mode: 65, Signature: signature.QuantumSig}
-		quantumValid = qv.verifyMLDSASignature(signature.QuantumPubKey, message, mldsaSig)
-	}
+	// Verify each component with the algorithm the descriptor names
+	classicalValid := classicalVerifiers[desc.Classical](qv, signature.ClassicalPubKey, message, signature.ClassicalSig)
+	quantumValid := quantumVerifiers[desc.Quantum](qv, signature.QuantumPubKey, message, signature.QuantumSig)
 
 	// Determine overall validity
+	bothRequired = desc.bothRequired(bothRequired)
 	var valid bool
 	if bothRequired {
 		valid = classicalValid && quantumValid
@@ -223,7 +245,7 @@ func (qv *QuantumVerifier) VerifyHybrid(
 	msgHash := sha256.Sum256(message)
 	return &VerificationResult{
 		Valid:           valid,
-		Algorithm:       AlgRingtail, // Primary quantum algorithm
+		Algorithm:       desc.Quantum,
 		MessageHash:     msgHash,
 		SignerPublicKey: signature.QuantumPubKey,
 		GasUsed:         GasHybridVerify,
@@ -314,38 +336,31 @@ func (qv *QuantumVerifier) VerifyAggregateBLS(
 	return valid, nil
 }
 
-// VerifyQuantumStamp verifies a quantum timestamp from Q-Chain
+// VerifyQuantumStamp verifies a quantum timestamp from Q-Chain under the
+// default policy. blockTime is the executing block's timestamp, so every
+// node judges freshness against the same clock.
 func (qv *QuantumVerifier) VerifyQuantumStamp(
 	stamp *QuantumStamp,
+	blockTime uint64,
 ) (bool, error) {
-	qv.mu.Lock()
-	defer qv.mu.Unlock()
-
-	if stamp == nil || stamp.Signature == nil {
-		return false, ErrInvalidStamp
-	}
-
-	// Reconstruct stamped message
-	stampData := append(stamp.BlockID[:], stamp.Message...)
-
-	// Get Q-Chain signer set public key
-	qchainKey := qv.RingtailKeys[stamp.Signature.KeyID]
-	if qchainKey == nil {
-		return false, ErrKeyNotFound
-	}
-
-	// Verify the Ringtail signature
-	result, err := qv.VerifyRingtail(stamp.Signature.KeyID, stampData, stamp.Signature)
-	if err != nil {
-		return false, err
-	}
-
-	return result.Valid, nil
+	return qv.VerifyQuantumStampAt(stamp, DefaultStampUseCase, blockTime)
 }
 
-// VerifyQuantumAnchor verifies data is anchored to Q-Chain
+// VerifyQuantumAnchor verifies data is anchored to Q-Chain under the
+// default policy at the executing block's timestamp
 func (qv *QuantumVerifier) VerifyQuantumAnchor(
 	anchor *QuantumAnchor,
+	blockTime uint64,
+) (bool, error) {
+	return qv.VerifyQuantumAnchorAt(anchor, DefaultStampUseCase, blockTime)
+}
+
+// VerifyQuantumAnchorAt verifies an anchor whose stamp must be fresh under
+// the given use case's policy at time now
+func (qv *QuantumVerifier) VerifyQuantumAnchorAt(
+	anchor *QuantumAnchor,
+	useCase string,
+	now uint64,
 ) (bool, error) {
 	qv.mu.Lock()
 	defer qv.mu.Unlock()
@@ -355,7 +370,10 @@ func (qv *QuantumVerifier) VerifyQuantumAnchor(
 	}
 
 	// Verify the stamp
-	stampValid, err := qv.VerifyQuantumStamp(anchor.Stamp)
+	stampValid, err := qv.verifyQuantumStamp(anchor.Stamp, useCase, now)
+	if err == ErrStampExpired || err == ErrStampFromFuture || err == ErrGenerationNotActive {
+		return false, err
+	}
 	if err != nil || !stampValid {
 		return false, ErrInvalidStamp
 	}
@@ -422,10 +440,47 @@ func (qv *QuantumVerifier) RegisterRingtailKey(
 		Parameters:   params,
 	}
 
+	// First generation is active from genesis
+	qv.KeyHistory[keyID] = []*RingtailKeyGeneration{{
+		Generation: 1,
+		PublicKey:  publicKey,
+	}}
+
 	qv.RingtailKeys[keyID] = key
 	return keyID, nil
 }
 
+// ImportRingtailKey registers a Ringtail key exported by a threshold signer.
+// The key ID must be the SHA-256 of the public key, as RegisterRingtailKey
+// derives it, and the threshold must leave at least one party spare.
+func (qv *QuantumVerifier) ImportRingtailKey(key *RingtailPublicKey) ([32]byte, error) {
+	if key == nil || len(key.PublicKey) == 0 || key.KeyID != sha256.Sum256(key.PublicKey) {
+		return [32]byte{}, ErrInvalidPublicKey
+	}
+	if key.TotalParties == 0 || key.Threshold >= key.TotalParties {
+		return [32]byte{}, ErrInvalidParameters
+	}
+	return qv.RegisterRingtailKey(key.PublicKey, key.Threshold, key.TotalParties, key.Parameters)
+}
+
+// RingtailSignerMask builds a signer mask with bit i%8 of byte i/8 set for
+// each party index i
+func RingtailSignerMask(indices []int) []byte {
+	size := 0
+	for _, i := range indices {
+		if i >= 0 && i/8+1 > size {
+			size = i/8 + 1
+		}
+	}
+	mask := make([]byte, size)
+	for _, i := range indices {
+		if i >= 0 {
+			mask[i/8] |= 1 << (i % 8)
+		}
+	}
+	return mask
+}
+
 // RegisterBLSKey registers a BLS public key
 func (qv *QuantumVerifier) RegisterBLSKey(publicKey []byte) ([32]byte, error) {
 	qv.mu.Lock()
@@ -445,15 +500,77 @@ func (qv *QuantumVerifier) RegisterBLSKey(publicKey []byte) ([32]byte, error) {
 	return keyID, nil
 }
 
-// DeriveAddress derives an EVM address from a quantum public key
+// DeriveAddress derives an EVM address from a quantum public key using the
+// canonical scheme, or the legacy scheme if LegacyAddressDerivation is set
 func (qv *QuantumVerifier) DeriveAddress(publicKey []byte, algorithm QuantumAlgorithm) common.Address {
-	// Hash the public key and take last 20 bytes
+	if qv.LegacyAddressDerivation {
+		return DeriveLegacyAddress(publicKey)
+	}
+	return DeriveQuantumAddress(publicKey, algorithm)
+}
+
+// DeriveQuantumAddress derives the canonical PQ address:
+// keccak256(algID || pubkey)[12:]. The algorithm byte domain-separates keys
+// so identical bytes under different schemes never share an address.
+func DeriveQuantumAddress(publicKey []byte, algorithm QuantumAlgorithm) common.Address {
+	hash := crypto.Keccak256([]byte{byte(algorithm)}, publicKey)
+	var addr common.Address
+	copy(addr[:], hash[12:])
+	return addr
+}
+
+// DeriveLegacyAddress derives an address as sha256(pubkey)[12:]
+// Deprecated: kept for compatibility, use DeriveQuantumAddress
+func DeriveLegacyAddress(publicKey []byte) common.Address {
 	hash := sha256.Sum256(publicKey)
 	var addr common.Address
 	copy(addr[:], hash[12:])
 	return addr
 }
 
+// RegisterAddress derives the address for a public key and records the
+// (algorithm, key hash) it maps back to
+func (qv *QuantumVerifier) RegisterAddress(publicKey []byte, algorithm QuantumAlgorithm) (common.Address, error) {
+	if len(publicKey) == 0 {
+		return common.Address{}, ErrInvalidPublicKey
+	}
+	if algorithm > AlgSLHDSASHA2256f {
+		return common.Address{}, ErrUnsupportedAlgorithm
+	}
+
+	addr := qv.DeriveAddress(publicKey, algorithm)
+
+	qv.mu.Lock()
+	defer qv.mu.Unlock()
+
+	pubKeyHash := crypto.Keccak256Hash(publicKey)
+	if existing := qv.Addresses[addr]; existing != nil {
+		if existing.Algorithm != algorithm || existing.PublicKeyHash != pubKeyHash {
+			return common.Address{}, ErrAddressCollision
+		}
+		return addr, nil
+	}
+
+	qv.Addresses[addr] = &AddressRecord{
+		Algorithm:     algorithm,
+		PublicKeyHash: pubKeyHash,
+		Legacy:        qv.LegacyAddressDerivation,
+	}
+	return addr, nil
+}
+
+// LookupAddress returns the algorithm and key hash an address was derived from
+func (qv *QuantumVerifier) LookupAddress(addr common.Address) (*AddressRecord, error) {
+	qv.mu.RLock()
+	defer qv.mu.RUnlock()
+
+	record := qv.Addresses[addr]
+	if record == nil {
+		return nil, ErrAddressNotFound
+	}
+	return record, nil
+}
+
 // Helper functions
 
 func (qv *QuantumVerifier) verifyRingtailSignature(
@@ -469,9 +586,20 @@ func (qv *QuantumVerifier) verifyRingtailSignature(
 		return false
 	}
 
+	return VerifyRingtailSignature(key.PublicKey, message, signature.Signature)
+}
+
+// VerifyRingtailSignature checks a Ringtail threshold signature over message
+// against a combined public key. The precompile and the threshold client both
+// verify through it, so a signature accepted off-chain is accepted on-chain.
+func VerifyRingtailSignature(publicKey, message, signature []byte) bool {
+	if len(publicKey) == 0 || len(signature) == 0 {
+		return false
+	}
+
 	// Use the real Ringtail verification from lux/threshold/ringtail/config
 	// This performs lattice-based signature verification using MLWE
-	return ringtailConfig.VerifySignature(key.PublicKey, message, signature.Signature)
+	return ringtailConfig.VerifySignature(publicKey, message, signature)
 }
 
 func (qv *QuantumVerifier) verifyMLDSASignature(
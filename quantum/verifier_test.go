@@ -4,9 +4,13 @@
 package quantum
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"testing"
 
+	"github.com/luxfi/crypto"
 	"github.com/luxfi/crypto/bls"
+	"github.com/luxfi/geth/common"
 )
 
 // TestNewQuantumVerifier tests verifier creation
@@ -135,8 +139,8 @@ func TestVerifyRingtail(t *testing.T) {
 	if result.Algorithm != AlgRingtail {
 		t.Errorf("Expected Ringtail algorithm, got %v", result.Algorithm)
 	}
-	if result.GasUsed != GasRingtailVerify {
-		t.Errorf("Expected gas %d, got %d", GasRingtailVerify, result.GasUsed)
+	if want := RingtailVerifyGas(3, len(message)); result.GasUsed != want {
+		t.Errorf("Expected gas %d, got %d", want, result.GasUsed)
 	}
 
 	// Verify stats updated
@@ -221,7 +225,8 @@ func TestVerifyMLDSA(t *testing.T) {
 				Signature: make([]byte, tt.sigSize),
 			}
 
-			result, err := qv.VerifyMLDSA(publicKey, []byte("test message"), signature)
+			message := []byte("test message")
+			result, err := qv.VerifyMLDSA(publicKey, message, signature)
 			if err != nil {
 				t.Fatalf("VerifyMLDSA failed: %v", err)
 			}
@@ -229,8 +234,9 @@ func TestVerifyMLDSA(t *testing.T) {
 			if result == nil {
 				t.Fatal("Expected non-nil result")
 			}
-			if result.GasUsed != GasMLDSAVerify {
-				t.Errorf("Expected gas %d, got %d", GasMLDSAVerify, result.GasUsed)
+			want, _ := MLDSAVerifyGas(tt.mode, len(message))
+			if result.GasUsed != want {
+				t.Errorf("Expected gas %d, got %d", want, result.GasUsed)
 			}
 		})
 	}
@@ -278,8 +284,8 @@ func TestVerifySLHDSA(t *testing.T) {
 	if result == nil {
 		t.Fatal("Expected non-nil result")
 	}
-	if result.GasUsed != GasSLHDSAVerify {
-		t.Errorf("Expected gas %d, got %d", GasSLHDSAVerify, result.GasUsed)
+	if want, _ := SLHDSAVerifyGas(0, len(signature), len(message)); result.GasUsed != want {
+		t.Errorf("Expected gas %d, got %d", want, result.GasUsed)
 	}
 }
 
@@ -511,6 +517,65 @@ func TestDeriveAddress(t *testing.T) {
 	}
 }
 
+// TestDeriveAddressAlgorithmTag tests canonical keccak derivation with algorithm separation
+func TestDeriveAddressAlgorithmTag(t *testing.T) {
+	qv := NewQuantumVerifier()
+	publicKey := make([]byte, MLDSA44PublicKeySize)
+	publicKey[0] = 1
+
+	addr := qv.DeriveAddress(publicKey, AlgMLDSA44)
+	hash := crypto.Keccak256(append([]byte{byte(AlgMLDSA44)}, publicKey...))
+	if addr != common.BytesToAddress(hash[12:]) {
+		t.Error("Address should be keccak256(algID || pubkey)[12:]")
+	}
+
+	// Same key bytes under a different algorithm must not collide
+	if addr == qv.DeriveAddress(publicKey, AlgMLDSA65) {
+		t.Error("Different algorithms should derive different addresses")
+	}
+
+	// Compatibility flag restores the legacy derivation
+	qv.LegacyAddressDerivation = true
+	if qv.DeriveAddress(publicKey, AlgMLDSA44) != DeriveLegacyAddress(publicKey) {
+		t.Error("Legacy flag should select sha256 derivation")
+	}
+}
+
+// TestRegisterAddress tests the address registry
+func TestRegisterAddress(t *testing.T) {
+	qv := NewQuantumVerifier()
+	publicKey := make([]byte, 64)
+	publicKey[0] = 7
+
+	addr, err := qv.RegisterAddress(publicKey, AlgRingtail)
+	if err != nil {
+		t.Fatalf("RegisterAddress failed: %v", err)
+	}
+
+	record, err := qv.LookupAddress(addr)
+	if err != nil {
+		t.Fatalf("LookupAddress failed: %v", err)
+	}
+	if record.Algorithm != AlgRingtail {
+		t.Errorf("Expected AlgRingtail, got %d", record.Algorithm)
+	}
+	if record.PublicKeyHash != crypto.Keccak256Hash(publicKey) {
+		t.Error("Record should store keccak256 of the public key")
+	}
+
+	// Re-registering the same key is idempotent
+	if again, err := qv.RegisterAddress(publicKey, AlgRingtail); err != nil || again != addr {
+		t.Errorf("Expected idempotent registration, got %v, %v", again, err)
+	}
+
+	if _, err := qv.LookupAddress(common.Address{}); err != ErrAddressNotFound {
+		t.Errorf("Expected ErrAddressNotFound, got %v", err)
+	}
+	if _, err := qv.RegisterAddress(nil, AlgRingtail); err != ErrInvalidPublicKey {
+		t.Errorf("Expected ErrInvalidPublicKey, got %v", err)
+	}
+}
+
 // TestCountBits tests bit counting helper
 func TestCountBits(t *testing.T) {
 	tests := []struct {
@@ -534,6 +599,62 @@ func TestCountBits(t *testing.T) {
 	}
 }
 
+// TestImportRingtailKey tests registering an exported threshold key
+func TestImportRingtailKey(t *testing.T) {
+	qv := NewQuantumVerifier()
+
+	publicKey := make([]byte, 128)
+	for i := range publicKey {
+		publicKey[i] = byte(i)
+	}
+	key := &RingtailPublicKey{
+		KeyID:        sha256.Sum256(publicKey),
+		PublicKey:    publicKey,
+		Threshold:    1,
+		TotalParties: 3,
+		Generation:   1,
+	}
+
+	keyID, err := qv.ImportRingtailKey(key)
+	if err != nil {
+		t.Fatalf("ImportRingtailKey failed: %v", err)
+	}
+	if keyID != key.KeyID {
+		t.Errorf("Expected key ID %x, got %x", key.KeyID, keyID)
+	}
+	if stored := qv.RingtailKeys[keyID]; stored == nil || stored.TotalParties != 3 {
+		t.Error("Imported key not stored")
+	}
+
+	mismatched := *key
+	mismatched.KeyID = [32]byte{0x01}
+	if _, err := qv.ImportRingtailKey(&mismatched); err != ErrInvalidPublicKey {
+		t.Errorf("Expected ErrInvalidPublicKey, got %v", err)
+	}
+
+	noSpare := *key
+	noSpare.Threshold = 3
+	if _, err := qv.ImportRingtailKey(&noSpare); err != ErrInvalidParameters {
+		t.Errorf("Expected ErrInvalidParameters, got %v", err)
+	}
+}
+
+// TestRingtailSignerMask tests mask construction from party indices
+func TestRingtailSignerMask(t *testing.T) {
+	mask := RingtailSignerMask([]int{0, 1, 2, 4})
+	if !bytes.Equal(mask, []byte{0b00010111}) {
+		t.Errorf("Expected mask 00010111, got %08b", mask)
+	}
+
+	mask = RingtailSignerMask([]int{9, 0})
+	if !bytes.Equal(mask, []byte{0b00000001, 0b00000010}) {
+		t.Errorf("Expected two-byte mask, got %08b", mask)
+	}
+	if countBits(mask) != 2 {
+		t.Errorf("Expected 2 signers, got %d", countBits(mask))
+	}
+}
+
 // TestVerificationStatistics tests stats tracking
 func TestVerificationStatistics(t *testing.T) {
 	qv := NewQuantumVerifier()
@@ -634,3 +755,97 @@ func BenchmarkVerifyHybrid(b *testing.B) {
 		_, _ = qv.VerifyHybrid(message, signature, true)
 	}
 }
+
+// newTestStamp returns a stamp signed by the given key generation
+func newTestStamp(keyID [32]byte, generation, timestamp, epoch uint64) *QuantumStamp {
+	return &QuantumStamp{
+		BlockID:   [32]byte{0x01},
+		Timestamp: timestamp,
+		Epoch:     epoch,
+		Message:   []byte("anchored data"),
+		Signature: &RingtailSignature{
+			KeyID:      keyID,
+			Signature:  []byte("stamp_signature"),
+			SignerMask: []byte{0b00000111},
+			Generation: generation,
+		},
+	}
+}
+
+// TestQuantumStampFreshness tests per-use-case maximum stamp age
+func TestQuantumStampFreshness(t *testing.T) {
+	qv := NewQuantumVerifier()
+	keyID, _ := qv.RegisterRingtailKey(make([]byte, 128), 2, 5, RingtailParams{})
+
+	now := uint64(1_700_000_000)
+	qv.SetStampPolicy("bridge", 600, 30)
+
+	// Old stamp is fine under the default day-long window
+	stamp := newTestStamp(keyID, 1, now-3600, 0)
+	if _, err := qv.VerifyQuantumStampAt(stamp, DefaultStampUseCase, now); err == ErrStampExpired {
+		t.Error("Stamp within default max age should not be expired")
+	}
+
+	// ...but too old for the bridge policy
+	if _, err := qv.VerifyQuantumStampAt(stamp, "bridge", now); err != ErrStampExpired {
+		t.Errorf("Expected ErrStampExpired, got %v", err)
+	}
+
+	// Stamps from beyond the allowed skew are rejected
+	stamp = newTestStamp(keyID, 1, now+60, 0)
+	if _, err := qv.VerifyQuantumStampAt(stamp, "bridge", now); err != ErrStampFromFuture {
+		t.Errorf("Expected ErrStampFromFuture, got %v", err)
+	}
+
+	// Unknown use cases fall back to the default policy
+	if policy := qv.GetStampPolicy("unknown"); policy.MaxAge != DefaultStampMaxAge {
+		t.Errorf("Expected default max age, got %d", policy.MaxAge)
+	}
+
+	// Freshness is judged against the block timestamp passed in
+	stamp = newTestStamp(keyID, 1, now, 0)
+	if _, err := qv.VerifyQuantumStamp(stamp, now+DefaultStampMaxAge+1); err != ErrStampExpired {
+		t.Errorf("Expected ErrStampExpired at a later block time, got %v", err)
+	}
+}
+
+// TestQuantumStampKeyRotation tests generation windows after committee rotation
+func TestQuantumStampKeyRotation(t *testing.T) {
+	qv := NewQuantumVerifier()
+	keyID, _ := qv.RegisterRingtailKey(make([]byte, 128), 2, 5, RingtailParams{})
+	qv.SetStampPolicy(DefaultStampUseCase, 0, DefaultStampFutureSkew)
+
+	rotatedAt := uint64(1_700_000_000)
+	gen, err := qv.RotateRingtailKey(keyID, []byte("new committee key"), rotatedAt, 7)
+	if err != nil {
+		t.Fatalf("RotateRingtailKey failed: %v", err)
+	}
+	if gen != 2 {
+		t.Errorf("Expected generation 2, got %d", gen)
+	}
+
+	now := rotatedAt + 1000
+
+	// Generation 1 signed before rotation: generation check passes
+	stamp := newTestStamp(keyID, 1, rotatedAt-10, 6)
+	if _, err := qv.VerifyQuantumStampAt(stamp, DefaultStampUseCase, now); err == ErrGenerationNotActive {
+		t.Error("Generation 1 should be active before rotation")
+	}
+
+	// Generation 1 claiming a post-rotation time is rejected
+	stamp = newTestStamp(keyID, 1, rotatedAt+10, 7)
+	if _, err := qv.VerifyQuantumStampAt(stamp, DefaultStampUseCase, now); err != ErrGenerationNotActive {
+		t.Errorf("Expected ErrGenerationNotActive, got %v", err)
+	}
+
+	// Generation 2 cannot sign for an epoch before it existed
+	stamp = newTestStamp(keyID, 2, rotatedAt+10, 6)
+	if _, err := qv.VerifyQuantumStampAt(stamp, DefaultStampUseCase, now); err != ErrGenerationNotActive {
+		t.Errorf("Expected ErrGenerationNotActive, got %v", err)
+	}
+
+	// Rotations must move forward in time
+	if _, err := qv.RotateRingtailKey(keyID, []byte("k3"), rotatedAt, 8); err != ErrInvalidRotation {
+		t.Errorf("Expected ErrInvalidRotation, got %v", err)
+	}
+}
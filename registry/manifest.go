@@ -0,0 +1,272 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/luxfi/geth/common"
+)
+
+// ============================================================================
+// DEPLOYMENT MANIFESTS
+// ============================================================================
+//
+// A manifest is the full precompile configuration for one chain: every
+// enabled address, its registry metadata and gas base, and the block height
+// at which it activates. Manifests are generated from ChainPrecompiles and
+// AllPrecompiles so the same precompile set can be deployed to C-Chain, Zoo
+// and Hanzo without hand-editing chain configs, and are validated against
+// the same tables when loaded.
+
+// ManifestVersion is the manifest format version
+const ManifestVersion = 1
+
+var (
+	ErrUnknownChain         = errors.New("unknown chain")
+	ErrInvalidManifest      = errors.New("invalid manifest")
+	ErrUnknownActivationKey = errors.New("activation key does not name a precompile enabled on chain")
+)
+
+// Manifest is the machine-readable precompile configuration for a chain
+type Manifest struct {
+	Version     int             `json:"version"`
+	Chain       string          `json:"chain"`
+	ChainSlot   uint8           `json:"chainSlot"`
+	Precompiles []ManifestEntry `json:"precompiles"`
+}
+
+// ManifestEntry configures a single precompile. Name, GasBase and LPRange
+// are empty for addresses that have no AllPrecompiles metadata.
+type ManifestEntry struct {
+	Address          string `json:"address"`
+	Name             string `json:"name,omitempty"`
+	GasBase          uint64 `json:"gasBase,omitempty"`
+	LPRange          string `json:"lpRange,omitempty"`
+	ActivationHeight uint64 `json:"activationHeight"`
+}
+
+// GenerateManifest builds the manifest for chainLetter. Every precompile
+// activates at defaultHeight unless heights overrides it; override keys are
+// precompile names (e.g. "FROST") or hex addresses, and must refer to a
+// precompile enabled on the chain.
+func GenerateManifest(chainLetter string, defaultHeight uint64, heights map[string]uint64) (*Manifest, error) {
+	addrs, ok := ChainPrecompiles[chainLetter]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownChain, chainLetter)
+	}
+
+	m := &Manifest{
+		Version:     ManifestVersion,
+		Chain:       chainLetter,
+		ChainSlot:   ChainSlot(chainLetter),
+		Precompiles: make([]ManifestEntry, 0, len(addrs)),
+	}
+
+	used := make(map[string]bool, len(heights))
+	for _, addr := range addrs {
+		entry := ManifestEntry{
+			Address:          common.HexToAddress(addr).Hex(),
+			ActivationHeight: defaultHeight,
+		}
+
+		info, found := lookupPrecompileInfo(chainLetter, addr)
+		if found {
+			entry.Name = info.Name
+			entry.GasBase = info.GasBase
+			entry.LPRange = info.LPRange
+		}
+
+		for key, height := range heights {
+			if (found && key == info.Name) ||
+				(common.IsHexAddress(key) && common.HexToAddress(key) == common.HexToAddress(addr)) {
+				entry.ActivationHeight = height
+				used[key] = true
+			}
+		}
+
+		m.Precompiles = append(m.Precompiles, entry)
+	}
+
+	for key := range heights {
+		if !used[key] {
+			return nil, fmt.Errorf("%w %s: %q", ErrUnknownActivationKey, chainLetter, key)
+		}
+	}
+
+	if err := ValidateManifest(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// JSON returns the indented JSON encoding of the manifest
+func (m *Manifest) JSON() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// ParseManifest decodes a manifest and validates it against the registry
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	if err := ValidateManifest(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ValidateManifest checks a manifest against the registry tables: the chain
+// and slot must match, every enabled precompile must be present exactly
+// once, registry metadata must agree with AllPrecompiles, and every
+// AllPrecompiles entry listing the chain must be enabled on it.
+func ValidateManifest(m *Manifest) error {
+	if m.Version != ManifestVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidManifest, m.Version)
+	}
+	addrs, ok := ChainPrecompiles[m.Chain]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownChain, m.Chain)
+	}
+	if m.ChainSlot != ChainSlot(m.Chain) {
+		return fmt.Errorf("%w: chain %s has slot %d, manifest has %d", ErrInvalidManifest, m.Chain, ChainSlot(m.Chain), m.ChainSlot)
+	}
+
+	enabled := make(map[common.Address]string, len(addrs))
+	for _, addr := range addrs {
+		enabled[common.HexToAddress(addr)] = addr
+	}
+
+	seen := make(map[common.Address]bool, len(m.Precompiles))
+	names := make(map[string]bool, len(m.Precompiles))
+	for _, entry := range m.Precompiles {
+		if !common.IsHexAddress(entry.Address) {
+			return fmt.Errorf("%w: malformed address %q", ErrInvalidManifest, entry.Address)
+		}
+		addr := common.HexToAddress(entry.Address)
+		if seen[addr] {
+			return fmt.Errorf("%w: duplicate precompile %s", ErrInvalidManifest, entry.Address)
+		}
+		seen[addr] = true
+
+		regAddr, ok := enabled[addr]
+		if !ok {
+			return fmt.Errorf("%w: %s is not enabled on chain %s", ErrInvalidManifest, entry.Address, m.Chain)
+		}
+
+		info, found := lookupPrecompileInfo(m.Chain, regAddr)
+		if !found {
+			if entry.Name != "" || entry.GasBase != 0 || entry.LPRange != "" {
+				return fmt.Errorf("%w: %s has no registry metadata", ErrInvalidManifest, entry.Address)
+			}
+			continue
+		}
+		if entry.Name != info.Name || entry.GasBase != info.GasBase || entry.LPRange != info.LPRange {
+			return fmt.Errorf("%w: %s metadata does not match registry entry %s", ErrInvalidManifest, entry.Address, info.Name)
+		}
+		names[info.Name] = true
+	}
+
+	// Every precompile the registry deploys to this chain must be configured
+	for _, info := range AllPrecompiles {
+		if containsChain(info.Chains, m.Chain) && !names[info.Name] {
+			return fmt.Errorf("%w: %s is listed for chain %s but not enabled", ErrInvalidManifest, info.Name, m.Chain)
+		}
+	}
+
+	if len(seen) != len(enabled) {
+		for addr, regAddr := range enabled {
+			if !seen[addr] {
+				return fmt.Errorf("%w: missing precompile %s", ErrInvalidManifest, regAddr)
+			}
+		}
+	}
+	return nil
+}
+
+// lookupPrecompileInfo finds the AllPrecompiles entry describing addr on a
+// chain. AllPrecompiles lists each precompile once under its C-Chain address;
+// other chains' instances share the family page and item and differ only in
+// the chain slot.
+func lookupPrecompileInfo(chainLetter, addr string) (PrecompileInfo, bool) {
+	target := common.HexToAddress(addr)
+	p, _, ii, ok := addressSelector(addr)
+
+	for _, info := range AllPrecompiles {
+		if !containsChain(info.Chains, chainLetter) {
+			continue
+		}
+		if common.HexToAddress(info.Address) == target {
+			return info, true
+		}
+		if !ok {
+			continue
+		}
+		if ip, _, iii, iok := addressSelector(info.Address); iok && ip == p && iii == ii &&
+			addressLayout(info.Address) == addressLayout(addr) {
+			return info, true
+		}
+	}
+	return PrecompileInfo{}, false
+}
+
+// addressSelector extracts the (P, C, II) nibbles from a Lux-native address in
+// either trailing-significant (0x00…PCII) or leading-significant (0xPCII00…)
+// layout
+func addressSelector(addr string) (p, c, ii uint8, ok bool) {
+	hex := strings.ToLower(strings.TrimPrefix(addr, "0x"))
+
+	var sel string
+	switch addressLayout(addr) {
+	case layoutTrailing:
+		sel = hex[36:]
+	case layoutLeading:
+		sel = hex[:4]
+	default:
+		return 0, 0, 0, false
+	}
+
+	var v uint16
+	if _, err := fmt.Sscanf(sel, "%04x", &v); err != nil || v>>12 < 2 {
+		return 0, 0, 0, false
+	}
+	return uint8(v >> 12), uint8(v>>8) & 0xF, uint8(v), true
+}
+
+const (
+	layoutOther = iota
+	layoutTrailing
+	layoutLeading
+)
+
+// addressLayout reports where the PCII selector sits within addr
+func addressLayout(addr string) int {
+	hex := strings.ToLower(strings.TrimPrefix(addr, "0x"))
+	if len(hex) != 40 {
+		return layoutOther
+	}
+	zeros := strings.Repeat("0", 36)
+	switch {
+	case hex[:36] == zeros:
+		return layoutTrailing
+	case hex[4:] == zeros:
+		return layoutLeading
+	default:
+		return layoutOther
+	}
+}
+
+// containsChain reports whether chains includes chainLetter
+func containsChain(chains []string, chainLetter string) bool {
+	for _, c := range chains {
+		if c == chainLetter {
+			return true
+		}
+	}
+	return false
+}
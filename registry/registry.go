@@ -388,13 +388,13 @@ var ChainPrecompiles = map[string][]string{
 		// Crypto (P=3)
 		Poseidon2CChain, Blake3CChain, PedersenCChain, SchnorrCChain, ECIESCChain,
 		// Privacy/ZK (P=4)
-		Groth16CChain, PLONKCChain, STARKCChain, KZGCChain, FHECChain, RangeProofCChain,
+		Groth16CChain, PLONKCChain, STARKCChain, KZGCChain, FHECChain, BGVCChain, RangeProofCChain,
 		// Threshold (P=5)
 		FROSTCChain, CGGMP21CChain, RingtailCChain, LSSCChain, DKGCChain,
 		// Bridges (P=6)
 		WarpSendCChain, WarpReceiveCChain, BridgeCChain, TeleportCChain,
 		// AI (P=7)
-		GPUAttestCChain, TEEVerifyCChain, InferenceCChain, SessionCChain,
+		GPUAttestCChain, TEEVerifyCChain, NVTrustCChain, InferenceCChain, SessionCChain,
 		// DEX (LP-9xxx)
 		LXPool, LXRouter, LXHooks, LXFlash, LXOracle, LXBook, LXVault, LXFeed, LXLend, LXLiquid, Liquidator, LiquidFX,
 	},
@@ -434,7 +434,7 @@ var ChainPrecompiles = map[string][]string{
 		STARKZChain, STARKRecursiveZCh, STARKBatchZChain,
 		KZGZChain, IPAZChain, FRIZChain,
 		RangeProofZChain, NullifierZChain, CommitmentZChain, MerkleProofZChain,
-		FHEZChain, TFHEZChain, CKKSZChain, GatewayZChain,
+		FHEZChain, TFHEZChain, CKKSZChain, BGVZChain, GatewayZChain,
 	},
 
 	// Zoo - DEX focused (same precompile addresses)
@@ -503,6 +503,7 @@ var AllPrecompiles = []PrecompileInfo{
 	{STARKCChain, "STARK", "STARK proof verification", 200000, []string{"C", "Z"}, "LP-4xxx"},
 	{KZGCChain, "KZG", "KZG polynomial commitments", 50000, []string{"C", "Z"}, "LP-4xxx"},
 	{FHECChain, "FHE", "Fully Homomorphic Encryption", 500000, []string{"C", "Z"}, "LP-4xxx"},
+	{BGVCChain, "BGV", "Exact-arithmetic FHE over batched integer slots", 150000, []string{"C", "Z"}, "LP-4xxx"},
 	{RangeProofCChain, "RANGE_PROOF", "Bulletproof range proofs", 100000, []string{"C", "Z"}, "LP-4xxx"},
 
 	// Threshold/MPC (P=5) → LP-5xxx
@@ -0,0 +1,91 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+)
+
+// Identifiable abort.
+//
+// CGGMP21, FROST and LSS verify every message they accept, with zero
+// knowledge proofs and commitment openings, and abort naming the parties
+// whose messages failed the checks. When a session fails, the client
+// collects the error of each party it ran and returns an AbortReport that
+// lists those culprits, and separately the parties that stopped responding
+// within a round. Operators can exclude culprits from the next signer set
+// or present the report as evidence for slashing. Unresponsive parties are
+// not proven faulty: a network partition looks the same.
+
+// AbortReport describes a failed protocol session
+type AbortReport struct {
+	Session      [32]byte
+	Culprits     []party.ID         // Parties the protocol proved misbehaving
+	Unresponsive []party.ID         // Parties that missed a round deadline
+	Failures     map[party.ID]error // Error of each party run here that failed
+}
+
+// newAbortReport collects the culprits and unresponsive parties named by
+// failures
+func newAbortReport(session [32]byte, failures map[party.ID]error) *AbortReport {
+	report := &AbortReport{Session: session, Failures: failures}
+	for _, err := range failures {
+		var protoErr *protocol.Error
+		if errors.As(err, &protoErr) {
+			report.Culprits = append(report.Culprits, protoErr.Culprits...)
+		}
+		var timeoutErr *ProtocolTimeoutError
+		if errors.As(err, &timeoutErr) {
+			report.Unresponsive = append(report.Unresponsive, timeoutErr.Missing...)
+		}
+	}
+	report.Culprits = slices.Compact(sortedParties(report.Culprits))
+	report.Unresponsive = slices.Compact(sortedParties(report.Unresponsive))
+	return report
+}
+
+func (r *AbortReport) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrProtocolAborted.Error())
+	if len(r.Culprits) > 0 {
+		fmt.Fprintf(&b, ", culprits %v", r.Culprits)
+	}
+	if len(r.Unresponsive) > 0 {
+		fmt.Fprintf(&b, ", unresponsive %v", r.Unresponsive)
+	}
+	for _, id := range sortedParties(r.partiesFailed()) {
+		fmt.Fprintf(&b, "; %s: %v", id, r.Failures[id])
+	}
+	return b.String()
+}
+
+// Unwrap makes errors.Is and errors.As match ErrProtocolAborted and each
+// party's error
+func (r *AbortReport) Unwrap() []error {
+	errs := []error{ErrProtocolAborted}
+	for _, id := range sortedParties(r.partiesFailed()) {
+		errs = append(errs, r.Failures[id])
+	}
+	return errs
+}
+
+// Faulty returns the culprits and unresponsive parties, the parties to
+// leave out when the session is retried
+func (r *AbortReport) Faulty() []party.ID {
+	return slices.Compact(sortedParties(append(slices.Clone(r.Culprits), r.Unresponsive...)))
+}
+
+func (r *AbortReport) partiesFailed() []party.ID {
+	ids := make([]party.ID, 0, len(r.Failures))
+	for id := range r.Failures {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+)
+
+// TestAbortReport tests that culprits and unresponsive parties are
+// collected from each party's error
+func TestAbortReport(t *testing.T) {
+	proofErr := errors.New("bad proof")
+	report := newAbortReport([32]byte{0x01}, map[party.ID]error{
+		"a": &protocol.Error{Culprits: []party.ID{"d", "c"}, Err: proofErr},
+		"b": &protocol.Error{Culprits: []party.ID{"c"}, Err: proofErr},
+		"e": &ProtocolTimeoutError{Round: 2, Missing: []party.ID{"f"}},
+	})
+
+	if !slices.Equal(report.Culprits, []party.ID{"c", "d"}) {
+		t.Errorf("Expected culprits [c d], got %v", report.Culprits)
+	}
+	if !slices.Equal(report.Unresponsive, []party.ID{"f"}) {
+		t.Errorf("Expected unresponsive [f], got %v", report.Unresponsive)
+	}
+	if !slices.Equal(report.Faulty(), []party.ID{"c", "d", "f"}) {
+		t.Errorf("Expected faulty [c d f], got %v", report.Faulty())
+	}
+
+	var err error = report
+	if !errors.Is(err, ErrProtocolAborted) || !errors.Is(err, proofErr) || !errors.Is(err, ErrProtocolTimeout) {
+		t.Errorf("Report does not wrap its failures: %v", err)
+	}
+}
+
+// TestAbortReportFromSession tests that a stalled session reports the peer
+// that did not respond
+func TestAbortReportFromSession(t *testing.T) {
+	client, parties, start := newStalledSession(t)
+	client.SetRoundTimeout(100 * time.Millisecond)
+
+	_, err := client.runSession(context.Background(), sessionTag{id: [32]byte{0x01}}, parties, "a", start)
+	var report *AbortReport
+	if !errors.As(err, &report) {
+		t.Fatalf("Expected AbortReport, got %v", err)
+	}
+	if len(report.Culprits) != 0 || !slices.Equal(report.Unresponsive, []party.ID{"b"}) {
+		t.Errorf("Expected only b unresponsive, got culprits %v, unresponsive %v", report.Culprits, report.Unresponsive)
+	}
+	if _, ok := report.Failures["a"]; !ok || len(report.Failures) != 1 {
+		t.Errorf("Expected failure of a only, got %v", report.Failures)
+	}
+}
@@ -0,0 +1,695 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luxfi/threshold/pkg/ecdsa"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+	"github.com/luxfi/threshold/pkg/taproot"
+	"github.com/luxfi/threshold/protocols/cmp"
+	"github.com/luxfi/threshold/protocols/frost"
+	"github.com/luxfi/threshold/protocols/lss"
+	"github.com/luxfi/threshold/protocols/ringtail"
+)
+
+// Cold-storage signing
+//
+// Some share holders never connect to the network. An AirGapSession runs the
+// signing protocol for the online signers and pauses whenever a round needs
+// input from an air-gapped signer: messages addressed to that signer are
+// queued and exported as a SessionPackage (a file, or a series of QR codes).
+// The air-gapped device feeds the package to its AirGapSigner, which returns
+// a response package carrying its own round messages. Importing the response
+// resumes the session. This repeats once per protocol round until the
+// signature is produced.
+//
+// Handler state of each party stays in memory on its own device between
+// packages; only protocol messages cross the air gap.
+
+// qrPrefix marks each QR chunk of an encoded SessionPackage
+const qrPrefix = "LUXTSS"
+
+// airGapCollectIdle is how long an AirGapSigner waits for further outbound
+// messages before it considers a round's output complete
+const airGapCollectIdle = 250 * time.Millisecond
+
+// SessionStatus represents the state of an air-gapped signing session
+type SessionStatus uint8
+
+const (
+	SessionRunning         SessionStatus = iota // Online signers are working
+	SessionAwaitingOffline                      // Paused on air-gapped signer input
+	SessionComplete                             // Signature produced
+	SessionFailed                               // Protocol aborted
+)
+
+// SessionPackage carries protocol messages across the air gap
+type SessionPackage struct {
+	SessionID   [32]byte   `json:"sessionId"`
+	KeyID       [32]byte   `json:"keyId"`
+	Protocol    Protocol   `json:"protocol"`
+	MessageHash [32]byte   `json:"messageHash"`
+	Signers     []party.ID `json:"signers"`
+	Party       party.ID   `json:"party"`    // Air-gapped signer the package is for or from
+	Sequence    uint32     `json:"sequence"` // Per-direction counter, starting at 1
+	Messages    [][]byte   `json:"messages"` // Serialized protocol messages
+}
+
+// Encode serializes the package for transfer by file
+func (p *SessionPackage) Encode() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// DecodeSessionPackage parses a package produced by Encode
+func DecodeSessionPackage(data []byte) (*SessionPackage, error) {
+	var p SessionPackage
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSessionPkg, err)
+	}
+	if p.Party == "" || p.Sequence == 0 || len(p.Signers) == 0 {
+		return nil, ErrInvalidSessionPkg
+	}
+	return &p, nil
+}
+
+// QRChunks splits the encoded package into QR payloads of at most
+// maxChunk data characters, each prefixed with "LUXTSS:<i>/<n>:"
+func (p *SessionPackage) QRChunks(maxChunk int) ([]string, error) {
+	if maxChunk <= 0 {
+		return nil, ErrInvalidSessionPkg
+	}
+	data, err := p.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	total := (len(encoded) + maxChunk - 1) / maxChunk
+
+	chunks := make([]string, 0, total)
+	for i := 0; i < total; i++ {
+		end := (i + 1) * maxChunk
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		chunks = append(chunks, fmt.Sprintf("%s:%d/%d:%s", qrPrefix, i+1, total, encoded[i*maxChunk:end]))
+	}
+	return chunks, nil
+}
+
+// DecodeQRChunks reassembles a package from its QR payloads in any order
+func DecodeQRChunks(chunks []string) (*SessionPackage, error) {
+	if len(chunks) == 0 {
+		return nil, ErrInvalidSessionPkg
+	}
+
+	parts := make([]string, len(chunks))
+	for _, chunk := range chunks {
+		fields := strings.SplitN(chunk, ":", 3)
+		if len(fields) != 3 || fields[0] != qrPrefix {
+			return nil, ErrInvalidSessionPkg
+		}
+		pos := strings.SplitN(fields[1], "/", 2)
+		if len(pos) != 2 {
+			return nil, ErrInvalidSessionPkg
+		}
+		index, err1 := strconv.Atoi(pos[0])
+		total, err2 := strconv.Atoi(pos[1])
+		if err1 != nil || err2 != nil || total != len(chunks) || index < 1 || index > total {
+			return nil, ErrInvalidSessionPkg
+		}
+		if parts[index-1] != "" {
+			return nil, ErrInvalidSessionPkg
+		}
+		parts[index-1] = fields[2]
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSessionPkg, err)
+	}
+	return DecodeSessionPackage(data)
+}
+
+// airGapNetwork delivers messages to online handlers and queues messages
+// for air-gapped signers until they are exported
+type airGapNetwork struct {
+	parties []party.ID
+	online  map[party.ID]chan *protocol.Message
+	offline map[party.ID][][]byte
+	closed  bool
+	mu      sync.Mutex
+}
+
+func newAirGapNetwork(signers []party.ID, offline map[party.ID]bool) *airGapNetwork {
+	n := &airGapNetwork{
+		parties: signers,
+		online:  make(map[party.ID]chan *protocol.Message),
+		offline: make(map[party.ID][][]byte),
+	}
+	for _, id := range signers {
+		if offline[id] {
+			n.offline[id] = nil
+		} else {
+			n.online[id] = make(chan *protocol.Message, 1000)
+		}
+	}
+	return n
+}
+
+func (n *airGapNetwork) route(msg *protocol.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return nil
+	}
+
+	var raw []byte
+	deliver := func(to party.ID) error {
+		if ch, ok := n.online[to]; ok {
+			select {
+			case ch <- msg:
+			default:
+				// Channel full, skip
+			}
+			return nil
+		}
+		if _, ok := n.offline[to]; !ok {
+			return nil
+		}
+		if raw == nil {
+			var err error
+			if raw, err = msg.MarshalBinary(); err != nil {
+				return err
+			}
+		}
+		n.offline[to] = append(n.offline[to], raw)
+		return nil
+	}
+
+	if msg.Broadcast || msg.To == "" {
+		for _, id := range n.parties {
+			if id == msg.From {
+				continue
+			}
+			if err := deliver(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return deliver(msg.To)
+}
+
+// drain removes and returns the queued messages for an air-gapped signer
+func (n *airGapNetwork) drain(id party.ID) [][]byte {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	msgs := n.offline[id]
+	n.offline[id] = nil
+	return msgs
+}
+
+// pending returns true if any air-gapped signer has queued messages
+func (n *airGapNetwork) pending() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, msgs := range n.offline {
+		if len(msgs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *airGapNetwork) close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return
+	}
+	n.closed = true
+	for _, ch := range n.online {
+		close(ch)
+	}
+}
+
+// AirGapSession coordinates a signing session with air-gapped signers
+type AirGapSession struct {
+	ID          [32]byte
+	KeyID       [32]byte
+	Protocol    Protocol
+	MessageHash [32]byte
+	Signers     []party.ID
+	Offline     map[party.ID]bool
+
+	net      *airGapNetwork
+	exported map[party.ID]uint32 // Last sequence exported to each signer
+	imported map[party.ID]uint32 // Last sequence imported from each signer
+
+	signature []byte
+	err       error
+	done      chan struct{}
+	finish    sync.Once
+
+	mu sync.Mutex
+}
+
+// newAirGapSession creates a session without starting any handlers
+func newAirGapSession(
+	keyID [32]byte,
+	proto Protocol,
+	messageHash [32]byte,
+	signers []party.ID,
+	offline []party.ID,
+) (*AirGapSession, error) {
+	if len(offline) == 0 || len(offline) >= len(signers) {
+		return nil, ErrInsufficientParties
+	}
+
+	isSigner := make(map[party.ID]bool, len(signers))
+	for _, id := range signers {
+		isSigner[id] = true
+	}
+	offlineSet := make(map[party.ID]bool, len(offline))
+	for _, id := range offline {
+		if !isSigner[id] {
+			return nil, ErrInvalidPartyCount
+		}
+		offlineSet[id] = true
+	}
+
+	hasher := sha256.New()
+	hasher.Write(keyID[:])
+	hasher.Write(messageHash[:])
+	for _, id := range signers {
+		hasher.Write([]byte(id))
+	}
+	var nonce [8]byte
+	binary.BigEndian.PutUint64(nonce[:], uint64(time.Now().UnixNano()))
+	hasher.Write(nonce[:])
+
+	var sessionID [32]byte
+	copy(sessionID[:], hasher.Sum(nil))
+
+	return &AirGapSession{
+		ID:          sessionID,
+		KeyID:       keyID,
+		Protocol:    proto,
+		MessageHash: messageHash,
+		Signers:     signers,
+		Offline:     offlineSet,
+		net:         newAirGapNetwork(signers, offlineSet),
+		exported:    make(map[party.ID]uint32),
+		imported:    make(map[party.ID]uint32),
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// StartAirGapSigning starts a signing session in which the offline signers
+// participate through exported packages
+func (c *ThresholdClient) StartAirGapSigning(
+	keyID [32]byte,
+	proto Protocol,
+	messageHash [32]byte,
+	signers []party.ID,
+	offline []party.ID,
+) (*AirGapSession, error) {
+	if err := c.authorizeSigning(keyID, messageHash); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	s, err := newAirGapSession(keyID, proto, messageHash, signers, offline)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range signers {
+		if s.Offline[id] {
+			continue
+		}
+		start, err := c.signStartFunc(keyID, proto, messageHash, signers)
+		if err != nil {
+			s.complete(nil, err)
+			return nil, err
+		}
+		h, err := protocol.NewMultiHandler(start, nil)
+		if err != nil {
+			s.complete(nil, err)
+			return nil, err
+		}
+		go s.runParty(id, h)
+	}
+
+	return s, nil
+}
+
+// runParty drives the handler of one online signer
+func (s *AirGapSession) runParty(id party.ID, h *protocol.Handler) {
+	go func() {
+		for msg := range h.Listen() {
+			if err := s.net.route(msg); err != nil {
+				s.complete(nil, err)
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for msg := range s.net.online[id] {
+			if h.CanAccept(msg) {
+				h.Accept(msg)
+			}
+		}
+	}()
+
+	result, err := h.WaitForResult()
+	if err != nil {
+		s.complete(nil, fmt.Errorf("air-gapped sign failed: %w", err))
+		return
+	}
+	sig, err := signatureFromResult(s.Protocol, result)
+	s.complete(sig, err)
+}
+
+// complete records the session outcome; only the first call has effect
+func (s *AirGapSession) complete(signature []byte, err error) {
+	s.finish.Do(func() {
+		s.mu.Lock()
+		s.signature, s.err = signature, err
+		s.mu.Unlock()
+
+		s.net.close()
+		close(s.done)
+	})
+}
+
+// Status returns the current state of the session
+func (s *AirGapSession) Status() SessionStatus {
+	select {
+	case <-s.done:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.err != nil {
+			return SessionFailed
+		}
+		return SessionComplete
+	default:
+	}
+
+	if s.net.pending() {
+		return SessionAwaitingOffline
+	}
+	return SessionRunning
+}
+
+// ExportPackage drains the messages queued for an air-gapped signer into a
+// package for transfer to its device
+func (s *AirGapSession) ExportPackage(id party.ID) (*SessionPackage, error) {
+	select {
+	case <-s.done:
+		return nil, ErrSessionClosed
+	default:
+	}
+	if !s.Offline[id] {
+		return nil, ErrUnauthorized
+	}
+
+	msgs := s.net.drain(id)
+	if len(msgs) == 0 {
+		return nil, ErrNoPendingMessages
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.exported[id]++
+	return &SessionPackage{
+		SessionID:   s.ID,
+		KeyID:       s.KeyID,
+		Protocol:    s.Protocol,
+		MessageHash: s.MessageHash,
+		Signers:     s.Signers,
+		Party:       id,
+		Sequence:    s.exported[id],
+		Messages:    msgs,
+	}, nil
+}
+
+// ImportResponse delivers an air-gapped signer's response package and
+// resumes the session. Packages must be imported in sequence order.
+func (s *AirGapSession) ImportResponse(pkg *SessionPackage) error {
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+	if pkg == nil || pkg.SessionID != s.ID || !s.Offline[pkg.Party] {
+		return ErrInvalidSessionPkg
+	}
+
+	s.mu.Lock()
+	last := s.imported[pkg.Party]
+	s.mu.Unlock()
+
+	if pkg.Sequence <= last {
+		return ErrSessionPkgReplay
+	}
+	if pkg.Sequence != last+1 {
+		return ErrInvalidSessionPkg
+	}
+
+	// Decode everything before routing so a bad package has no effect
+	msgs := make([]*protocol.Message, 0, len(pkg.Messages))
+	for _, raw := range pkg.Messages {
+		msg := new(protocol.Message)
+		if err := msg.UnmarshalBinary(raw); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSessionPkg, err)
+		}
+		if msg.From != pkg.Party {
+			return ErrInvalidSessionPkg
+		}
+		msgs = append(msgs, msg)
+	}
+
+	s.mu.Lock()
+	s.imported[pkg.Party] = pkg.Sequence
+	s.mu.Unlock()
+
+	for _, msg := range msgs {
+		if err := s.net.route(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wait blocks until the session completes and returns the signature
+func (s *AirGapSession) Wait(ctx context.Context) ([]byte, error) {
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signature, s.err
+}
+
+// Abort stops the session
+func (s *AirGapSession) Abort() {
+	s.complete(nil, ErrSessionClosed)
+}
+
+// AirGapSigner runs one signer's protocol handler on an air-gapped device
+type AirGapSigner struct {
+	header   SessionPackage // Session fields of the first package (no messages)
+	handler  *protocol.Handler
+	out      <-chan *protocol.Message
+	received uint32
+	sent     uint32
+	mu       sync.Mutex
+}
+
+// NewAirGapSigner starts the local handler for the signer a coordinator
+// package is addressed to. The key share for pkg.KeyID must be loaded in
+// this client.
+func (c *ThresholdClient) NewAirGapSigner(pkg *SessionPackage) (*AirGapSigner, error) {
+	if pkg == nil || pkg.Sequence != 1 {
+		return nil, ErrInvalidSessionPkg
+	}
+	if err := c.authorizeSigning(pkg.KeyID, pkg.MessageHash); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	start, err := c.signStartFunc(pkg.KeyID, pkg.Protocol, pkg.MessageHash, pkg.Signers)
+	if err != nil {
+		return nil, err
+	}
+	h, err := protocol.NewMultiHandler(start, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	header := *pkg
+	header.Messages = nil
+	return &AirGapSigner{
+		header:  header,
+		handler: h,
+		out:     h.Listen(),
+	}, nil
+}
+
+// Process feeds a coordinator package to the local handler and returns the
+// response package carrying this signer's next round messages
+func (s *AirGapSigner) Process(ctx context.Context, pkg *SessionPackage) (*SessionPackage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pkg == nil || pkg.SessionID != s.header.SessionID || pkg.Party != s.header.Party {
+		return nil, ErrInvalidSessionPkg
+	}
+	if pkg.Sequence <= s.received {
+		return nil, ErrSessionPkgReplay
+	}
+	if pkg.Sequence != s.received+1 {
+		return nil, ErrInvalidSessionPkg
+	}
+
+	for _, raw := range pkg.Messages {
+		msg := new(protocol.Message)
+		if err := msg.UnmarshalBinary(raw); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSessionPkg, err)
+		}
+		if s.handler.CanAccept(msg) {
+			s.handler.Accept(msg)
+		}
+	}
+	s.received = pkg.Sequence
+
+	// Collect this round's output until the handler goes quiet
+	var msgs [][]byte
+	idle := time.NewTimer(airGapCollectIdle)
+	defer idle.Stop()
+collect:
+	for {
+		select {
+		case msg, ok := <-s.out:
+			if !ok {
+				break collect
+			}
+			raw, err := msg.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, raw)
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(airGapCollectIdle)
+		case <-idle.C:
+			break collect
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	s.sent++
+	resp := s.header
+	resp.Sequence = s.sent
+	resp.Messages = msgs
+	return &resp, nil
+}
+
+// signStartFunc returns the signing protocol for a stored key. Caller must
+// hold c.mu.
+func (c *ThresholdClient) signStartFunc(
+	keyID [32]byte,
+	proto Protocol,
+	messageHash [32]byte,
+	signers []party.ID,
+) (protocol.StartFunc, error) {
+	switch proto {
+	case ProtocolCGGMP21:
+		config, ok := c.cmpConfigs[keyID]
+		if !ok {
+			return nil, ErrKeyNotFound
+		}
+		return cmp.Sign(config, signers, messageHash[:], c.pool), nil
+	case ProtocolFROST:
+		config, ok := c.frostConfigs[keyID]
+		if !ok {
+			return nil, ErrKeyNotFound
+		}
+		taprootConfig, err := frostTaprootConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return frost.SignTaproot(taprootConfig, signers, messageHash[:]), nil
+	case ProtocolLSS:
+		config, ok := c.lssConfigs[keyID]
+		if !ok {
+			return nil, ErrKeyNotFound
+		}
+		return lss.Sign(config, signers, messageHash[:], c.pool), nil
+	case ProtocolRingtail:
+		config, ok := c.ringtailConfigs[keyID]
+		if !ok {
+			return nil, ErrKeyNotFound
+		}
+		return ringtail.SignWithConfig(config, signers, messageHash[:], c.pool), nil
+	default:
+		return nil, ErrInvalidProtocol
+	}
+}
+
+// signatureFromResult serializes a signing protocol result the same way as
+// ExecuteSigning
+func signatureFromResult(proto Protocol, result interface{}) ([]byte, error) {
+	switch proto {
+	case ProtocolCGGMP21, ProtocolLSS:
+		sig, ok := result.(*ecdsa.Signature)
+		if !ok {
+			return nil, ErrInvalidSignature
+		}
+		return sig.SigEthereum()
+	case ProtocolFROST:
+		sig, ok := result.(taproot.Signature)
+		if !ok {
+			return nil, ErrInvalidSignature
+		}
+		return []byte(sig), nil
+	case ProtocolRingtail:
+		sig, ok := result.([]byte)
+		if !ok {
+			return nil, ErrInvalidSignature
+		}
+		return sig, nil
+	default:
+		return nil, ErrInvalidProtocol
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+func newTestSessionPackage() *SessionPackage {
+	return &SessionPackage{
+		SessionID:   [32]byte{0x01},
+		KeyID:       [32]byte{0x02},
+		Protocol:    ProtocolCGGMP21,
+		MessageHash: [32]byte{0x03},
+		Signers:     []party.ID{"a", "b", "c"},
+		Party:       "c",
+		Sequence:    1,
+		Messages:    [][]byte{{0xde, 0xad}, {0xbe, 0xef}},
+	}
+}
+
+// TestSessionPackageEncoding tests file and QR round trips
+func TestSessionPackageEncoding(t *testing.T) {
+	pkg := newTestSessionPackage()
+
+	data, err := pkg.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	decoded, err := DecodeSessionPackage(data)
+	if err != nil {
+		t.Fatalf("DecodeSessionPackage failed: %v", err)
+	}
+	if decoded.SessionID != pkg.SessionID || decoded.Party != pkg.Party || len(decoded.Messages) != 2 {
+		t.Errorf("Decoded package does not match original")
+	}
+
+	chunks, err := pkg.QRChunks(40)
+	if err != nil {
+		t.Fatalf("QRChunks failed: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("Expected multiple QR chunks, got %d", len(chunks))
+	}
+
+	// Chunks may be scanned in any order
+	reversed := make([]string, len(chunks))
+	for i, c := range chunks {
+		reversed[len(chunks)-1-i] = c
+	}
+	decoded, err = DecodeQRChunks(reversed)
+	if err != nil {
+		t.Fatalf("DecodeQRChunks failed: %v", err)
+	}
+	if decoded.KeyID != pkg.KeyID || string(decoded.Messages[1]) != string(pkg.Messages[1]) {
+		t.Errorf("QR-decoded package does not match original")
+	}
+
+	if _, err := DecodeQRChunks(chunks[1:]); !errors.Is(err, ErrInvalidSessionPkg) {
+		t.Errorf("Expected ErrInvalidSessionPkg for missing chunk, got %v", err)
+	}
+	dup := append([]string{chunks[0]}, chunks[:len(chunks)-1]...)
+	if _, err := DecodeQRChunks(dup); !errors.Is(err, ErrInvalidSessionPkg) {
+		t.Errorf("Expected ErrInvalidSessionPkg for duplicate chunk, got %v", err)
+	}
+}
+
+// TestAirGapSessionValidation tests session setup and package sequencing
+func TestAirGapSessionValidation(t *testing.T) {
+	signers := []party.ID{"a", "b", "c"}
+
+	if _, err := newAirGapSession([32]byte{1}, ProtocolCGGMP21, [32]byte{2}, signers, nil); !errors.Is(err, ErrInsufficientParties) {
+		t.Errorf("Expected ErrInsufficientParties without offline signers, got %v", err)
+	}
+	if _, err := newAirGapSession([32]byte{1}, ProtocolCGGMP21, [32]byte{2}, signers, signers); !errors.Is(err, ErrInsufficientParties) {
+		t.Errorf("Expected ErrInsufficientParties with no online signers, got %v", err)
+	}
+	if _, err := newAirGapSession([32]byte{1}, ProtocolCGGMP21, [32]byte{2}, signers, []party.ID{"z"}); !errors.Is(err, ErrInvalidPartyCount) {
+		t.Errorf("Expected ErrInvalidPartyCount for non-signer, got %v", err)
+	}
+
+	s, err := newAirGapSession([32]byte{1}, ProtocolCGGMP21, [32]byte{2}, signers, []party.ID{"c"})
+	if err != nil {
+		t.Fatalf("newAirGapSession failed: %v", err)
+	}
+	defer s.Abort()
+
+	if s.Status() != SessionRunning {
+		t.Errorf("Expected SessionRunning, got %d", s.Status())
+	}
+	if _, err := s.ExportPackage("c"); !errors.Is(err, ErrNoPendingMessages) {
+		t.Errorf("Expected ErrNoPendingMessages, got %v", err)
+	}
+	if _, err := s.ExportPackage("a"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized for online signer, got %v", err)
+	}
+
+	resp := &SessionPackage{SessionID: s.ID, Party: "c", Sequence: 1}
+	if err := s.ImportResponse(resp); err != nil {
+		t.Fatalf("ImportResponse failed: %v", err)
+	}
+	if err := s.ImportResponse(resp); !errors.Is(err, ErrSessionPkgReplay) {
+		t.Errorf("Expected ErrSessionPkgReplay, got %v", err)
+	}
+	if err := s.ImportResponse(&SessionPackage{SessionID: s.ID, Party: "c", Sequence: 3}); !errors.Is(err, ErrInvalidSessionPkg) {
+		t.Errorf("Expected ErrInvalidSessionPkg for skipped sequence, got %v", err)
+	}
+	if err := s.ImportResponse(&SessionPackage{SessionID: [32]byte{0xff}, Party: "c", Sequence: 2}); !errors.Is(err, ErrInvalidSessionPkg) {
+		t.Errorf("Expected ErrInvalidSessionPkg for foreign session, got %v", err)
+	}
+
+	s.Abort()
+	if s.Status() != SessionFailed {
+		t.Errorf("Expected SessionFailed after abort, got %d", s.Status())
+	}
+	if err := s.ImportResponse(&SessionPackage{SessionID: s.ID, Party: "c", Sequence: 2}); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	}
+}
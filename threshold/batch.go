@@ -0,0 +1,140 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// Batch signing.
+//
+// Bridges sign many withdrawals per block with the same key and signer set.
+// ExecuteBatchSigning runs one signing session per distinct message hash,
+// all at once, and returns the signatures in input order. The sessions
+// share this party's transport connections and session multiplexer, so a
+// batch costs no handshakes beyond those of a single signature, and the
+// rounds of all sessions, including the nonce commitment round of FROST,
+// travel together instead of one session after another.
+//
+// CGGMP21 messages take presignatures from the pool in input order before
+// any session starts, so every signer pairs the same presignature with the
+// same message. A batch that follows ExecutePresign for as many messages
+// signs in a single round. Messages left without a presignature run the
+// full protocol.
+//
+// A batch is all or nothing: when one session fails the others are
+// stopped, and presignatures taken for the batch are spent.
+
+// MaxSignBatch bounds the messages of a single batch signing call
+const MaxSignBatch = 256
+
+// ExecuteBatchSigning signs each of hashes with keyID and returns the
+// results in the order of hashes. Repeated hashes are signed once.
+func (c *ThresholdClient) ExecuteBatchSigning(
+	ctx context.Context,
+	keyID [32]byte,
+	proto Protocol,
+	hashes [][32]byte,
+	signers []party.ID,
+	selfID party.ID,
+) ([]*SigningResult, error) {
+	if len(hashes) == 0 || len(hashes) > MaxSignBatch {
+		return nil, ErrInvalidSignBatch
+	}
+	if err := c.authorizeSigning(keyID, hashes...); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Sign each distinct hash once
+	position := make(map[[32]byte]int, len(hashes))
+	var unique [][32]byte
+	var origin []int // Input index of each distinct hash
+	for i, hash := range hashes {
+		if _, ok := position[hash]; !ok {
+			position[hash] = len(unique)
+			unique = append(unique, hash)
+			origin = append(origin, i)
+		}
+	}
+
+	// Assign presignatures before the sessions race for the pool
+	var presigs []*cmpPresignature
+	if proto == ProtocolCGGMP21 {
+		if _, ok := c.cmpConfigs[keyID]; !ok {
+			return nil, ErrKeyNotFound
+		}
+		presigs = make([]*cmpPresignature, len(unique))
+		for i := range unique {
+			presigs[i] = c.takePresignatureFor(keyID, signers)
+		}
+	}
+
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	signed := make([]*SigningResult, len(unique))
+	errs := make([]error, len(unique))
+	var wg sync.WaitGroup
+	for i, hash := range unique {
+		wg.Add(1)
+		go func(i int, hash [32]byte) {
+			defer wg.Done()
+
+			var result *SigningResult
+			var err error
+			switch {
+			case presigs == nil:
+				result, err = c.executeSign(batchCtx, keyID, proto, hash, signers, selfID)
+			case presigs[i] != nil:
+				result, err = c.executeCMPPresignedSign(batchCtx, keyID, presigs[i], hash, selfID)
+			default:
+				result, err = c.executeCMPFullSign(batchCtx, keyID, c.cmpConfigs[keyID], hash, signers, selfID)
+			}
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			signed[i] = result
+		}(i, hash)
+	}
+	wg.Wait()
+
+	if err := batchError(ctx, errs, origin); err != nil {
+		return nil, err
+	}
+
+	results := make([]*SigningResult, len(hashes))
+	for i, hash := range hashes {
+		results[i] = signed[position[hash]]
+	}
+	return results, nil
+}
+
+// batchError returns the error of the first message that failed on its
+// own, skipping sessions stopped because another failed
+func batchError(ctx context.Context, errs []error, origin []int) error {
+	var first error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("batch message %d: %w", origin[i], err)
+		if ctx.Err() != nil || !errors.Is(err, context.Canceled) {
+			return err
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
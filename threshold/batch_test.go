@@ -0,0 +1,52 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// TestBatchSigning tests that a batch returns one valid signature per hash
+// in input order
+func TestBatchSigning(t *testing.T) {
+	client := NewThresholdClient()
+	defer client.Close()
+
+	participants := []party.ID{"alice", "bob", "charlie"}
+	keygen, err := client.ExecuteKeygen(context.Background(), ProtocolFROST, KeyTypeSecp256k1, 1, participants, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteKeygen failed: %v", err)
+	}
+
+	signers := []party.ID{"alice", "bob"}
+	if _, err := client.ExecuteBatchSigning(context.Background(), keygen.KeyID, ProtocolFROST, nil, signers, "alice"); !errors.Is(err, ErrInvalidSignBatch) {
+		t.Errorf("Expected ErrInvalidSignBatch for empty batch, got %v", err)
+	}
+
+	hashes := [][32]byte{{0x01}, {0x02}, {0x01}, {0x03}}
+	results, err := client.ExecuteBatchSigning(context.Background(), keygen.KeyID, ProtocolFROST, hashes, signers, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteBatchSigning failed: %v", err)
+	}
+	if len(results) != len(hashes) {
+		t.Fatalf("Expected %d results, got %d", len(hashes), len(results))
+	}
+	for i, hash := range hashes {
+		ok, err := client.VerifySignature(keygen.KeyID, ProtocolFROST, hash, results[i].Signature)
+		if err != nil || !ok {
+			t.Errorf("Signature %d does not verify: %v", i, err)
+		}
+	}
+	if string(results[0].Signature) != string(results[2].Signature) {
+		t.Errorf("Repeated hash signed twice")
+	}
+
+	if _, err := client.ExecuteBatchSigning(context.Background(), [32]byte{0xff}, ProtocolFROST, hashes, signers, "alice"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,538 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/luxfi/threshold/pkg/math/curve"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+	"github.com/luxfi/threshold/pkg/round"
+)
+
+// DKG ceremonies
+//
+// A custody key is generated in a ceremony that auditors can later check.
+// One node's Coordinator runs the ceremony and every other participant's
+// Coordinator joins it, each on its own node over a network transport:
+//
+//  1. Invitation: the coordinator sends each participant the ceremony's
+//     parameters, which the participant approves or declines.
+//  2. Agreement: every participant tells every other whether it accepts.
+//     Keygen starts only once all have accepted the same parameters, as
+//     the ceremony ID commits to them.
+//  3. Keygen: the participants run the distributed key generation.
+//  4. Confirmation: each participant hashes the transcript, the parameters,
+//     the acceptances and the public commitments of the new key, and signs
+//     the ceremony report with its message authenticator. The signatures
+//     are exchanged and checked, so every participant ends with the same
+//     report signed by all.
+//
+// A CeremonyReport carries the commitments it was hashed from, so Verify
+// recomputes the transcript and key ID and checks each participant's
+// signature without access to the nodes. A phase that does not complete
+// within the client's round timeout aborts the ceremony with the
+// participants not heard from.
+
+// ceremonyDomain separates ceremony hashes from other hashes
+const ceremonyDomain = "lux.threshold.ceremony.v1"
+
+// Ceremony phases, the round numbers of their messages
+const (
+	ceremonyInvite round.Number = iota + 1
+	ceremonyAccept
+	ceremonyConfirm
+)
+
+// CeremonyParams are the parameters a ceremony's participants agree on
+type CeremonyParams struct {
+	Coordinator  party.ID   // Party that invites the others, itself a participant
+	Protocol     Protocol   // Keygen protocol
+	KeyType      KeyType    // Key type
+	Threshold    int        // t (t+1 signatures required)
+	Participants []party.ID // Parties generating the key
+	Label        string     // Purpose of the key, for the record
+	Nonce        [32]byte   // Distinguishes ceremonies with equal parameters
+}
+
+// Validate checks the parameters describe a keygen the coordinator takes
+// part in
+func (p *CeremonyParams) Validate() error {
+	sorted := sortedParties(p.Participants)
+	if len(slices.Compact(sorted)) != len(p.Participants) || len(p.Participants) < 2 {
+		return ErrInvalidPartyCount
+	}
+	if p.Threshold < 1 || p.Threshold >= len(p.Participants) {
+		return ErrInvalidThreshold
+	}
+	if p.Protocol > ProtocolRingtail {
+		return ErrInvalidProtocol
+	}
+	if !slices.Contains(p.Participants, p.Coordinator) {
+		return ErrNotParticipant
+	}
+	return nil
+}
+
+// MarshalBinary encodes the parameters as coordinator || protocol || key
+// type || threshold || label || nonce || parties, with the parties sorted
+// and every field length-prefixed
+func (p *CeremonyParams) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	writeField(&buf, []byte(p.Coordinator))
+	writeField(&buf, []byte{byte(p.Protocol)})
+	writeField(&buf, []byte{byte(p.KeyType)})
+	writeField(&buf, binary.BigEndian.AppendUint32(nil, uint32(p.Threshold)))
+	writeField(&buf, []byte(p.Label))
+	writeField(&buf, p.Nonce[:])
+	writeField(&buf, binary.BigEndian.AppendUint32(nil, uint32(len(p.Participants))))
+	for _, id := range sortedParties(p.Participants) {
+		writeField(&buf, []byte(id))
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes parameters encoded by MarshalBinary
+func (p *CeremonyParams) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	var fields [7][]byte
+	for i := range fields {
+		field, err := readField(r)
+		if err != nil {
+			return ErrInvalidCeremony
+		}
+		fields[i] = field
+	}
+	if len(fields[1]) != 1 || len(fields[2]) != 1 || len(fields[3]) != 4 || len(fields[5]) != 32 || len(fields[6]) != 4 {
+		return ErrInvalidCeremony
+	}
+
+	count := binary.BigEndian.Uint32(fields[6])
+	if uint64(count) > uint64(r.Len()) {
+		return ErrInvalidCeremony
+	}
+	participants := make([]party.ID, 0, count)
+	for i := uint32(0); i < count; i++ {
+		id, err := readField(r)
+		if err != nil {
+			return ErrInvalidCeremony
+		}
+		participants = append(participants, party.ID(id))
+	}
+	if r.Len() != 0 {
+		return ErrInvalidCeremony
+	}
+
+	*p = CeremonyParams{
+		Coordinator:  party.ID(fields[0]),
+		Protocol:     Protocol(fields[1][0]),
+		KeyType:      KeyType(fields[2][0]),
+		Threshold:    int(binary.BigEndian.Uint32(fields[3])),
+		Participants: participants,
+		Label:        string(fields[4]),
+		Nonce:        [32]byte(fields[5]),
+	}
+	return nil
+}
+
+// ID returns the ceremony ID, which commits to every parameter
+func (p *CeremonyParams) ID() [32]byte {
+	encoded, _ := p.MarshalBinary()
+	h := sha256.New()
+	h.Write([]byte(ceremonyDomain))
+	writeField(h, []byte("params"))
+	writeField(h, encoded)
+	var id [32]byte
+	h.Sum(id[:0])
+	return id
+}
+
+// CeremonyReport records a completed ceremony and the participants'
+// signatures of it
+type CeremonyReport struct {
+	Params      CeremonyParams
+	KeyID       [32]byte
+	PublicKey   []byte
+	Commitments []byte              // Public data of the key: verification shares, or the public key alone
+	Transcript  [32]byte            // Hash of the parameters, acceptances and commitments
+	Signatures  map[party.ID][]byte // Each participant's signature of Digest
+}
+
+// ceremonyTranscript hashes the parameters, the participants that accepted
+// them and the key's public commitments
+func ceremonyTranscript(params *CeremonyParams, commitments []byte) [32]byte {
+	encoded, _ := params.MarshalBinary()
+	h := sha256.New()
+	h.Write([]byte(ceremonyDomain))
+	writeField(h, []byte("transcript"))
+	writeField(h, encoded)
+	for _, id := range sortedParties(params.Participants) {
+		writeField(h, []byte(id))
+		h.Write([]byte{1})
+	}
+	writeField(h, commitments)
+	var transcript [32]byte
+	h.Sum(transcript[:0])
+	return transcript
+}
+
+// Digest returns the hash each participant signs
+func (r *CeremonyReport) Digest() [32]byte {
+	id := r.Params.ID()
+	h := sha256.New()
+	h.Write([]byte(ceremonyDomain))
+	writeField(h, []byte("report"))
+	h.Write(id[:])
+	h.Write(r.KeyID[:])
+	writeField(h, r.PublicKey)
+	h.Write(r.Transcript[:])
+	var digest [32]byte
+	h.Sum(digest[:0])
+	return digest
+}
+
+// Verify checks the report's transcript and key ID against its parameters
+// and commitments, and that every participant, and no one else, signed it
+// as verified by auth
+func (r *CeremonyReport) Verify(auth MessageAuthenticator) error {
+	if err := r.Params.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCeremony, err)
+	}
+	if ceremonyTranscript(&r.Params, r.Commitments) != r.Transcript {
+		return fmt.Errorf("%w: transcript does not match commitments", ErrInvalidCeremony)
+	}
+	keyID := DeriveKeyID(r.Params.Protocol, protocolKeyType(r.Params.Protocol), r.Params.Threshold, r.Params.Participants, r.PublicKey)
+	if keyID != r.KeyID {
+		return fmt.Errorf("%w: key ID does not match parameters", ErrInvalidCeremony)
+	}
+	if !r.commitsToKey() {
+		return fmt.Errorf("%w: commitments do not match key", ErrInvalidCeremony)
+	}
+	if len(r.Signatures) != len(r.Params.Participants) {
+		return fmt.Errorf("%w: signed by %d of %d participants", ErrInvalidCeremony, len(r.Signatures), len(r.Params.Participants))
+	}
+	digest := r.Digest()
+	for _, id := range r.Params.Participants {
+		if !auth.Verify(id, digest, r.Signatures[id]) {
+			return fmt.Errorf("%w: invalid signature of %s", ErrInvalidCeremony, id)
+		}
+	}
+	return nil
+}
+
+// commitsToKey reports whether the commitments are those of the report's
+// public key, threshold and participants
+func (r *CeremonyReport) commitsToKey() bool {
+	switch r.Params.Protocol {
+	case ProtocolCGGMP21, ProtocolFROST:
+		key, err := unmarshalReshareKey(curve.Secp256k1{}, r.Commitments)
+		if err != nil {
+			return false
+		}
+		public, err := key.public.MarshalBinary()
+		return err == nil && bytes.Equal(public, r.PublicKey) && key.threshold == r.Params.Threshold &&
+			slices.Equal(key.parties(), sortedParties(r.Params.Participants))
+	default:
+		return bytes.Equal(r.Commitments, r.PublicKey)
+	}
+}
+
+// Coordinator runs and joins DKG ceremonies as one party of a client with a
+// network transport
+type Coordinator struct {
+	client *ThresholdClient
+	self   party.ID
+}
+
+// NewCoordinator creates a Coordinator for party self of client
+func NewCoordinator(client *ThresholdClient, self party.ID) *Coordinator {
+	return &Coordinator{client: client, self: self}
+}
+
+// inviteSession returns the session invitations from coordinator travel in
+func inviteSession(coordinator party.ID) [32]byte {
+	return sessionID(sessionInvite, 0, []party.ID{coordinator})
+}
+
+// network returns the client's transport and authenticator
+func (co *Coordinator) network() (Transport, MessageAuthenticator, error) {
+	co.client.mu.RLock()
+	defer co.client.mu.RUnlock()
+	if co.client.transport == nil {
+		return nil, nil, ErrCeremonyInProcess
+	}
+	return co.client.transport, co.client.auth, nil
+}
+
+// Run invites the participants of params to a ceremony coordinated by this
+// party and takes part in it
+func (co *Coordinator) Run(ctx context.Context, params CeremonyParams) (*CeremonyReport, error) {
+	params.Coordinator = co.self
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	transport, auth, err := co.network()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := params.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	invite := inviteSession(co.self)
+	guard := co.client.replayGuard(co.self)
+	for _, id := range peersOf(params.Participants, co.self) {
+		msg := &protocol.Message{SSID: invite[:], From: co.self, To: id, RoundNumber: ceremonyInvite, Data: encoded}
+		env, err := sealMessage(invite, guard.seq(), msg, auth)
+		if err != nil {
+			return nil, err
+		}
+		if err := transport.Send(ctx, id, env); err != nil {
+			return nil, fmt.Errorf("failed to invite %s: %w", id, err)
+		}
+	}
+	return co.participate(ctx, &params, true)
+}
+
+// Join waits for an invitation from coordinator and takes part in its
+// ceremony if approve accepts the parameters. A declined ceremony still
+// tells the other participants, so they abort instead of timing out.
+func (co *Coordinator) Join(ctx context.Context, coordinator party.ID, approve func(*CeremonyParams) bool) (*CeremonyReport, error) {
+	_, auth, err := co.network()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := inviteSession(coordinator)
+	mux := co.client.sessionMux(co.self)
+	inbox := mux.open(invite)
+	defer mux.close(invite)
+	guard := co.client.replayGuard(co.self)
+
+	for {
+		select {
+		case env, ok := <-inbox:
+			if !ok {
+				return nil, ErrTransportClosed
+			}
+			msg, ok := openEnvelope(invite, env, auth)
+			if !ok || msg.From != coordinator || msg.To != co.self || msg.RoundNumber != ceremonyInvite ||
+				!guard.accept(env.From, env.Seq) {
+				continue
+			}
+			var params CeremonyParams
+			if err := params.UnmarshalBinary(msg.Data); err != nil || params.Coordinator != coordinator ||
+				params.Validate() != nil || !slices.Contains(params.Participants, co.self) {
+				continue
+			}
+			return co.participate(ctx, &params, approve(&params))
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// participate runs the agreement, keygen and confirmation phases of the
+// ceremony of params, accepting its parameters or not
+func (co *Coordinator) participate(ctx context.Context, params *CeremonyParams, accepted bool) (*CeremonyReport, error) {
+	ceremonyID := params.ID()
+	session := sessionID(sessionCeremony, params.Protocol, params.Participants, ceremonyID[:])
+	mux := co.client.sessionMux(co.self)
+	phases := &ceremonyInbox{
+		co:      co,
+		session: session,
+		inbox:   mux.open(session),
+		peers:   peersOf(params.Participants, co.self),
+		stash:   make(map[round.Number]map[party.ID][]byte),
+	}
+	defer mux.close(session)
+
+	// Agreement
+	vote := []byte{0}
+	if accepted {
+		vote[0] = 1
+	}
+	if err := phases.broadcast(ctx, ceremonyAccept, vote); err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, fmt.Errorf("%w: declined by %s", ErrCeremonyRejected, co.self)
+	}
+	votes, err := phases.collect(ctx, ceremonyAccept)
+	if err != nil {
+		return nil, err
+	}
+	var declined []party.ID
+	for id, v := range votes {
+		if !bytes.Equal(v, []byte{1}) {
+			declined = append(declined, id)
+		}
+	}
+	if len(declined) > 0 {
+		return nil, fmt.Errorf("%w: declined by %v", ErrCeremonyRejected, sortedParties(declined))
+	}
+
+	// Keygen
+	result, err := co.client.ExecuteKeygen(ctx, params.Protocol, params.KeyType, params.Threshold, params.Participants, co.self)
+	if err != nil {
+		return nil, err
+	}
+	commitments, err := co.client.keyCommitments(result.KeyID, params.Protocol, result.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Confirmation
+	report := &CeremonyReport{
+		Params:      *params,
+		KeyID:       result.KeyID,
+		PublicKey:   result.PublicKey,
+		Commitments: commitments,
+		Transcript:  ceremonyTranscript(params, commitments),
+		Signatures:  make(map[party.ID][]byte, len(params.Participants)),
+	}
+	digest := report.Digest()
+	_, auth, err := co.network()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := auth.Sign(co.self, digest)
+	if err != nil {
+		return nil, err
+	}
+	report.Signatures[co.self] = signature
+	if err := phases.broadcast(ctx, ceremonyConfirm, signature); err != nil {
+		return nil, err
+	}
+	signatures, err := phases.collect(ctx, ceremonyConfirm)
+	if err != nil {
+		return nil, err
+	}
+	for id, signature := range signatures {
+		if !auth.Verify(id, digest, signature) {
+			return nil, newAbortReport(session, map[party.ID]error{
+				co.self: &protocol.Error{Culprits: []party.ID{id}, Err: fmt.Errorf("%w: invalid report signature", ErrInvalidCeremony)},
+			})
+		}
+		report.Signatures[id] = signature
+	}
+	return report, nil
+}
+
+// keyCommitments returns the public data of keyID that a ceremony
+// transcript commits to: the verification shares of a CGGMP21 or FROST key,
+// and the public key of others
+func (c *ThresholdClient) keyCommitments(keyID [32]byte, proto Protocol, publicKey []byte) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	switch proto {
+	case ProtocolCGGMP21:
+		if cfg, ok := c.cmpConfigs[keyID]; ok {
+			return cmpReshareKey(cfg).marshalPublic()
+		}
+	case ProtocolFROST:
+		if cfg, ok := c.frostConfigs[keyID]; ok {
+			return frostReshareKey(cfg).marshalPublic()
+		}
+	default:
+		return slices.Clone(publicKey), nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// ceremonyInbox exchanges one message per phase with every other
+// participant of a ceremony, keeping messages of later phases until they
+// are collected
+type ceremonyInbox struct {
+	co      *Coordinator
+	session [32]byte
+	inbox   <-chan *Envelope
+	peers   []party.ID
+	stash   map[round.Number]map[party.ID][]byte
+}
+
+// broadcast sends data to every other participant as self's message of
+// phase
+func (b *ceremonyInbox) broadcast(ctx context.Context, phase round.Number, data []byte) error {
+	transport, auth, err := b.co.network()
+	if err != nil {
+		return err
+	}
+	msg := &protocol.Message{
+		SSID:        b.session[:],
+		From:        b.co.self,
+		Broadcast:   true,
+		RoundNumber: phase,
+		Data:        data,
+	}
+	env, err := sealMessage(b.session, b.co.client.replayGuard(b.co.self).seq(), msg, auth)
+	if err != nil {
+		return err
+	}
+	return transport.Broadcast(ctx, b.peers, env)
+}
+
+// collect returns every other participant's message of phase, aborting
+// with the participants not heard from when the round timeout passes
+func (b *ceremonyInbox) collect(ctx context.Context, phase round.Number) (map[party.ID][]byte, error) {
+	_, auth, err := b.co.network()
+	if err != nil {
+		return nil, err
+	}
+	guard := b.co.client.replayGuard(b.co.self)
+	if b.stash[phase] == nil {
+		b.stash[phase] = make(map[party.ID][]byte)
+	}
+	got := b.stash[phase]
+
+	var expired <-chan time.Time
+	if timeout := b.co.client.timeout; timeout > 0 {
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		expired = deadline.C
+	}
+	for len(got) < len(b.peers) {
+		select {
+		case env, ok := <-b.inbox:
+			if !ok {
+				return nil, ErrTransportClosed
+			}
+			msg, ok := openEnvelope(b.session, env, auth)
+			if !ok || !msg.Broadcast || !slices.Contains(b.peers, msg.From) || msg.RoundNumber < phase ||
+				!guard.accept(env.From, env.Seq) {
+				continue
+			}
+			stashed := b.stash[msg.RoundNumber]
+			if stashed == nil {
+				stashed = make(map[party.ID][]byte)
+				b.stash[msg.RoundNumber] = stashed
+			}
+			if _, seen := stashed[msg.From]; !seen {
+				stashed[msg.From] = msg.Data
+			}
+		case <-expired:
+			var missing []party.ID
+			for _, id := range b.peers {
+				if _, ok := got[id]; !ok {
+					missing = append(missing, id)
+				}
+			}
+			return nil, newAbortReport(b.session, map[party.ID]error{
+				b.co.self: &ProtocolTimeoutError{Round: int(phase), Missing: missing},
+			})
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return got, nil
+}
@@ -0,0 +1,194 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// newCeremonyNodes creates n clients, each authenticated with its own key
+// and connected over one LocalTransport
+func newCeremonyNodes(t *testing.T, n int) ([]party.ID, map[party.ID]*Coordinator, *ECDSAAuthenticator) {
+	t.Helper()
+	keys := make(map[party.ID]*ecdsa.PrivateKey)
+	ids := make([]party.ID, 0, n)
+	for i := 0; i < n; i++ {
+		key, err := luxcrypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		id := NewECDSAAuthenticator(key).Self()
+		keys[id] = key
+		ids = append(ids, id)
+	}
+
+	lt := NewLocalTransport(ids)
+	t.Cleanup(lt.Close)
+	nodes := make(map[party.ID]*Coordinator, n)
+	for _, id := range ids {
+		client := NewThresholdClient()
+		t.Cleanup(client.Close)
+		client.SetRoundTimeout(30 * time.Second)
+		if err := client.SetTransport(lt, NewECDSAAuthenticator(keys[id])); err != nil {
+			t.Fatalf("SetTransport failed: %v", err)
+		}
+		nodes[id] = NewCoordinator(client, id)
+	}
+	return ids, nodes, NewECDSAAuthenticator(keys[ids[0]])
+}
+
+// runCeremony runs a ceremony coordinated by ids[0], with the others
+// joining if approve accepts its parameters
+func runCeremony(ids []party.ID, nodes map[party.ID]*Coordinator, params CeremonyParams, approve func(party.ID, *CeremonyParams) bool) (map[party.ID]*CeremonyReport, map[party.ID]error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	reports := make(map[party.ID]*CeremonyReport)
+	errs := make(map[party.ID]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id party.ID) {
+			defer wg.Done()
+			var report *CeremonyReport
+			var err error
+			if i == 0 {
+				report, err = nodes[id].Run(ctx, params)
+			} else {
+				report, err = nodes[id].Join(ctx, ids[0], func(p *CeremonyParams) bool { return approve(id, p) })
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			reports[id], errs[id] = report, err
+		}(i, id)
+	}
+	wg.Wait()
+	return reports, errs
+}
+
+// TestCeremony tests that every participant ends a ceremony with the same
+// report, signed by all and verifiable from the report alone
+func TestCeremony(t *testing.T) {
+	ids, nodes, verifier := newCeremonyNodes(t, 3)
+	params := CeremonyParams{
+		Protocol:     ProtocolFROST,
+		KeyType:      KeyTypeSecp256k1,
+		Threshold:    1,
+		Participants: ids,
+		Label:        "custody",
+		Nonce:        [32]byte{0x01},
+	}
+
+	reports, errs := runCeremony(ids, nodes, params, func(_ party.ID, p *CeremonyParams) bool {
+		return p.Label == "custody"
+	})
+	for _, id := range ids {
+		if errs[id] != nil {
+			t.Fatalf("Ceremony failed for %s: %v", id, errs[id])
+		}
+	}
+
+	report := reports[ids[0]]
+	for _, id := range ids[1:] {
+		if reports[id].Digest() != report.Digest() {
+			t.Errorf("Participant %s holds a different report", id)
+		}
+	}
+	if err := report.Verify(verifier); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if _, ok := nodes[ids[1]].client.frostConfigs[report.KeyID]; !ok {
+		t.Errorf("Participant holds no share of the key")
+	}
+
+	// Altered reports do not verify
+	forged := *report
+	forged.Signatures = map[party.ID][]byte{ids[0]: report.Signatures[ids[0]], ids[1]: report.Signatures[ids[0]], ids[2]: report.Signatures[ids[2]]}
+	if err := forged.Verify(verifier); !errors.Is(err, ErrInvalidCeremony) {
+		t.Errorf("Expected ErrInvalidCeremony for a swapped signature, got %v", err)
+	}
+	forged = *report
+	forged.Params.Label = "other"
+	if err := forged.Verify(verifier); !errors.Is(err, ErrInvalidCeremony) {
+		t.Errorf("Expected ErrInvalidCeremony for altered parameters, got %v", err)
+	}
+	forged = *report
+	forged.Commitments = append([]byte{}, report.PublicKey...)
+	forged.Transcript = ceremonyTranscript(&forged.Params, forged.Commitments)
+	if err := forged.Verify(verifier); !errors.Is(err, ErrInvalidCeremony) {
+		t.Errorf("Expected ErrInvalidCeremony for substituted commitments, got %v", err)
+	}
+}
+
+// TestCeremonyRejected tests that a participant declining the parameters
+// aborts the ceremony for everyone before keygen
+func TestCeremonyRejected(t *testing.T) {
+	ids, nodes, _ := newCeremonyNodes(t, 3)
+	params := CeremonyParams{
+		Protocol:     ProtocolFROST,
+		KeyType:      KeyTypeSecp256k1,
+		Threshold:    1,
+		Participants: ids,
+	}
+
+	_, errs := runCeremony(ids, nodes, params, func(id party.ID, _ *CeremonyParams) bool {
+		return id != ids[2]
+	})
+	for _, id := range ids {
+		if !errors.Is(errs[id], ErrCeremonyRejected) {
+			t.Errorf("Expected ErrCeremonyRejected for %s, got %v", id, errs[id])
+		}
+	}
+	if len(nodes[ids[0]].client.ListKeys()) != 0 {
+		t.Errorf("Key generated despite rejection")
+	}
+
+	local := NewCoordinator(NewThresholdClient(), ids[0])
+	defer local.client.Close()
+	if _, err := local.Run(context.Background(), params); !errors.Is(err, ErrCeremonyInProcess) {
+		t.Errorf("Expected ErrCeremonyInProcess without a transport, got %v", err)
+	}
+}
+
+// TestCeremonyParamsEncoding tests parameter round trips and that the
+// ceremony ID ignores participant order
+func TestCeremonyParamsEncoding(t *testing.T) {
+	params := CeremonyParams{
+		Coordinator:  "a",
+		Protocol:     ProtocolCGGMP21,
+		KeyType:      KeyTypeSecp256k1,
+		Threshold:    2,
+		Participants: []party.ID{"c", "a", "b"},
+		Label:        "treasury",
+		Nonce:        [32]byte{0x07},
+	}
+	data, err := params.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var decoded CeremonyParams
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if decoded.ID() != params.ID() || decoded.Label != params.Label || decoded.Threshold != params.Threshold {
+		t.Errorf("Decoded parameters do not match original")
+	}
+	if err := new(CeremonyParams).UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrInvalidCeremony) {
+		t.Errorf("Expected ErrInvalidCeremony for truncated parameters, got %v", err)
+	}
+
+	params.Participants = []party.ID{"a", "a", "b"}
+	if err := params.Validate(); !errors.Is(err, ErrInvalidPartyCount) {
+		t.Errorf("Expected ErrInvalidPartyCount for duplicate participants, got %v", err)
+	}
+}
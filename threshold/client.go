@@ -4,15 +4,19 @@
 package threshold
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
 	luxcrypto "github.com/luxfi/crypto"
 	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/quantum"
 
 	log "github.com/luxfi/log"
 	"github.com/luxfi/threshold/pkg/ecdsa"
@@ -20,10 +24,12 @@ import (
 	"github.com/luxfi/threshold/pkg/party"
 	"github.com/luxfi/threshold/pkg/pool"
 	"github.com/luxfi/threshold/pkg/protocol"
+	"github.com/luxfi/threshold/pkg/taproot"
 	"github.com/luxfi/threshold/protocols/cmp"
 	"github.com/luxfi/threshold/protocols/frost"
 	"github.com/luxfi/threshold/protocols/lss"
 	"github.com/luxfi/threshold/protocols/ringtail"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ThresholdClient wraps the real threshold package to execute MPC protocols
@@ -34,7 +40,7 @@ type ThresholdClient struct {
 	// Logger
 	log log.Logger
 
-	// Configuration
+	// Longest wait for the parties of a session to complete one round
 	timeout time.Duration
 
 	// Key storage - maps KeyID to protocol config
@@ -43,6 +49,30 @@ type ThresholdClient struct {
 	lssConfigs      map[[32]byte]*lss.Config
 	ringtailConfigs map[[32]byte]*ringtail.Config
 
+	// Sorted participant set of each Ringtail key, for signer masks
+	ringtailParties map[[32]byte][]party.ID
+
+	// Pre-generated FROST nonces and published commitment lists per key
+	frostNonces map[[32]byte]*frostNonceBook
+
+	// Unused CGGMP21 presignatures per key
+	presigs   map[[32]byte]*presignPool
+	presignMu sync.Mutex
+
+	// Network transport; nil runs every party in process
+	transport Transport
+	auth      MessageAuthenticator
+	muxes     map[party.ID]*sessionMux
+	guards    map[party.ID]*replayGuard
+	netMu     sync.Mutex
+
+	// Session measurements and spans; nil when not installed
+	metrics Metrics
+	tracer  trace.Tracer
+
+	// Authorizes each hash before it is signed; nil signs any hash
+	guard SigningGuard
+
 	mu sync.RWMutex
 }
 
@@ -57,9 +87,23 @@ func NewThresholdClient() *ThresholdClient {
 		frostConfigs:    make(map[[32]byte]*frost.Config),
 		lssConfigs:      make(map[[32]byte]*lss.Config),
 		ringtailConfigs: make(map[[32]byte]*ringtail.Config),
+		ringtailParties: make(map[[32]byte][]party.ID),
+		frostNonces:     make(map[[32]byte]*frostNonceBook),
+		presigs:         make(map[[32]byte]*presignPool),
+		muxes:           make(map[party.ID]*sessionMux),
+		guards:          make(map[party.ID]*replayGuard),
 	}
 }
 
+// SetRoundTimeout sets how long a session waits for the parties to complete
+// one round before failing with ErrProtocolTimeout. A d of zero or less
+// disables the deadline.
+func (c *ThresholdClient) SetRoundTimeout(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = d
+}
+
 // Close cleans up resources
 func (c *ThresholdClient) Close() {
 	if c.pool != nil {
@@ -100,92 +144,414 @@ type KeygenResult struct {
 	Address   common.Address
 }
 
-// simpleNetwork is a simple in-memory network for MPC protocols
-type simpleNetwork struct {
-	parties   []party.ID
-	channels  map[party.ID]chan *protocol.Message
-	mu        sync.RWMutex
-	closeChan chan struct{}
+// keyIDDomain separates key IDs from other hashes
+const keyIDDomain = "lux.threshold.keyid.v1"
+
+// DeriveKeyID returns the canonical ID of a key: a hash of its protocol,
+// curve, threshold, sorted participants and group public key. Every
+// participant derives the same ID from public keygen outputs, whichever
+// config it holds, and a reshare to a new participant set or threshold
+// gives the key a new ID.
+func DeriveKeyID(proto Protocol, keyType KeyType, threshold int, participants []party.ID, publicKey []byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte(keyIDDomain))
+	h.Write([]byte{byte(proto), byte(keyType)})
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(threshold)))
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(participants))))
+	for _, p := range sortedParties(participants) {
+		writeField(h, []byte(p))
+	}
+	writeField(h, publicKey)
+	var id [32]byte
+	h.Sum(id[:0])
+	return id
 }
 
-func newSimpleNetwork(parties []party.ID) *simpleNetwork {
-	n := &simpleNetwork{
-		parties:   parties,
-		channels:  make(map[party.ID]chan *protocol.Message),
-		closeChan: make(chan struct{}),
+// protocolKeyType returns the curve proto's keys are generated on
+func protocolKeyType(proto Protocol) KeyType {
+	if proto == ProtocolRingtail {
+		return KeyTypeRingtail
 	}
-	for _, p := range parties {
-		n.channels[p] = make(chan *protocol.Message, 1000)
+	return KeyTypeSecp256k1
+}
+
+// Session kinds, part of each session ID
+const (
+	sessionKeygen   = "keygen"
+	sessionSign     = "sign"
+	sessionRefresh  = "refresh"
+	sessionReshare  = "reshare"
+	sessionPresign  = "presign"
+	sessionImport   = "import"
+	sessionDeal     = "deal"
+	sessionInvite   = "invite"
+	sessionCeremony = "ceremony"
+)
+
+// sessionDomain separates session IDs from other hashes
+const sessionDomain = "lux.threshold.session.v1"
+
+// sessionID derives the session ID every participant computes for the same
+// call
+func sessionID(kind string, proto Protocol, parties []party.ID, fields ...[]byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte(sessionDomain))
+	writeField(h, []byte(kind))
+	h.Write([]byte{byte(proto)})
+	for _, f := range fields {
+		writeField(h, f)
+	}
+	for _, p := range sortedParties(parties) {
+		writeField(h, []byte(p))
+	}
+	var id [32]byte
+	h.Sum(id[:0])
+	return id
+}
+
+// sessionTag is a session's ID with its kind and protocol, which name it
+// in metrics and spans
+type sessionTag struct {
+	id    [32]byte
+	kind  string
+	proto Protocol
+}
+
+// newSessionTag derives the session every participant computes for the
+// same call
+func newSessionTag(kind string, proto Protocol, parties []party.ID, fields ...[]byte) sessionTag {
+	return sessionTag{id: sessionID(kind, proto, parties, fields...), kind: kind, proto: proto}
+}
+
+// SetTransport runs protocols over t, with envelopes signed and checked by
+// auth. Each node then runs only its own party. A nil t runs every party in
+// process again.
+func (c *ThresholdClient) SetTransport(t Transport, auth MessageAuthenticator) error {
+	if t != nil && auth == nil {
+		return ErrAuthRequired
 	}
-	return n
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.netMu.Lock()
+	defer c.netMu.Unlock()
+
+	c.transport, c.auth = t, auth
+	c.muxes = make(map[party.ID]*sessionMux)
+	return nil
 }
 
-func (n *simpleNetwork) send(msg *protocol.Message) {
-	n.mu.RLock()
-	defer n.mu.RUnlock()
+// sessionMux returns the router of the envelopes the installed transport
+// delivers to self
+func (c *ThresholdClient) sessionMux(self party.ID) *sessionMux {
+	c.netMu.Lock()
+	defer c.netMu.Unlock()
 
-	select {
-	case <-n.closeChan:
-		return
-	default:
+	m, ok := c.muxes[self]
+	if !ok {
+		m = newSessionMux(c.transport.Receive(self))
+		c.muxes[self] = m
 	}
+	return m
+}
 
-	if msg.Broadcast || msg.To == "" {
-		// Broadcast to all parties except sender
-		for p, ch := range n.channels {
-			if p != msg.From {
-				select {
-				case ch <- msg:
-				default:
-					// Channel full, skip
-				}
-			}
+// replayGuard returns the sequence numbers and replay windows of party
+// self. They outlive transports, so envelopes recorded before SetTransport
+// are not accepted after it.
+func (c *ThresholdClient) replayGuard(self party.ID) *replayGuard {
+	c.netMu.Lock()
+	defer c.netMu.Unlock()
+
+	g, ok := c.guards[self]
+	if !ok {
+		g = newReplayGuard()
+		c.guards[self] = g
+	}
+	return g
+}
+
+// runSession runs one protocol session and returns the result of each
+// party run here: every party over a LocalTransport, or selfID alone over
+// the installed transport. A party fails when ctx ends or when a round takes
+// longer than the round timeout. When any party fails, the others are
+// stopped and the failures are returned as an AbortReport. The session is
+// reported to the installed Metrics and tracer.
+func (c *ThresholdClient) runSession(
+	ctx context.Context,
+	session sessionTag,
+	parties []party.ID,
+	selfID party.ID,
+	start func(id party.ID) protocol.StartFunc,
+) (map[party.ID]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, obs := c.observe(ctx, session, parties)
+	results, err := c.runParties(ctx, session.id, parties, selfID, start, obs)
+	obs.finish(err)
+	return results, err
+}
+
+// runParties runs the parties of session that run here for runSession
+func (c *ThresholdClient) runParties(
+	ctx context.Context,
+	session [32]byte,
+	parties []party.ID,
+	selfID party.ID,
+	start func(id party.ID) protocol.StartFunc,
+	obs *sessionObserver,
+) (map[party.ID]interface{}, error) {
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	transport, auth := c.transport, c.auth
+	local := []party.ID{selfID}
+	inboxes := make(map[party.ID]<-chan *Envelope, len(parties))
+
+	if transport == nil {
+		lt := NewLocalTransport(parties)
+		defer lt.Close()
+		transport, local = lt, parties
+		for _, id := range parties {
+			inboxes[id] = lt.Receive(id)
 		}
 	} else {
-		// Send to specific party
-		if ch, ok := n.channels[msg.To]; ok {
-			select {
-			case ch <- msg:
-			default:
-				// Channel full, skip
+		if !slices.Contains(parties, selfID) {
+			return nil, ErrNotParticipant
+		}
+		mux := c.sessionMux(selfID)
+		inboxes[selfID] = mux.open(session)
+		defer mux.close(session)
+	}
+
+	results := make(map[party.ID]interface{}, len(local))
+	failures := make(map[party.ID]error)
+	var resultsMu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, id := range local {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+
+			result, err := c.runParty(sessionCtx, session, parties, id, start(id), transport, auth, inboxes[id], obs)
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+			if err != nil {
+				// Parties stopped because another failed add nothing
+				if len(failures) == 0 || ctx.Err() != nil || !errors.Is(err, context.Canceled) {
+					failures[id] = err
+				}
+				cancel()
+				return
 			}
+			results[id] = result
+		}(id)
+	}
+
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return nil, newAbortReport(session, failures)
+	}
+	return results, nil
+}
+
+// runParty runs the handler of party self until it returns a result, ctx
+// ends or handlerLoop gives up on the session
+func (c *ThresholdClient) runParty(
+	ctx context.Context,
+	session [32]byte,
+	parties []party.ID,
+	self party.ID,
+	start protocol.StartFunc,
+	transport Transport,
+	auth MessageAuthenticator,
+	inbox <-chan *Envelope,
+	obs *sessionObserver,
+) (interface{}, error) {
+	h, err := protocol.NewMultiHandler(start, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	loopErr := make(chan error, 1)
+	go func() {
+		loopErr <- handlerLoop(ctx, h, session, parties, self, transport, auth, c.replayGuard(self), inbox, c.timeout, obs)
+	}()
+
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := h.WaitForResult()
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case err := <-loopErr:
+		if err == nil {
+			err = ErrTransportClosed
 		}
+		h.Stop()
+		return nil, err
 	}
 }
 
-func (n *simpleNetwork) receive(id party.ID) <-chan *protocol.Message {
-	n.mu.RLock()
-	defer n.mu.RUnlock()
-	return n.channels[id]
+// roundTracker follows the round a party has reached and the rounds its
+// peers have sent messages for
+type roundTracker struct {
+	round    int
+	heard    map[party.ID]int
+	advanced chan struct{}
+	mu       sync.Mutex
+}
+
+func newRoundTracker(peers []party.ID) *roundTracker {
+	heard := make(map[party.ID]int, len(peers))
+	for _, p := range peers {
+		heard[p] = -1
+	}
+	return &roundTracker{heard: heard, advanced: make(chan struct{}, 1)}
 }
 
-func (n *simpleNetwork) close() {
-	close(n.closeChan)
-	n.mu.Lock()
-	defer n.mu.Unlock()
-	for _, ch := range n.channels {
-		close(ch)
+// sent records a message self sent in round r
+func (t *roundTracker) sent(r int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r > t.round {
+		t.round = r
+		select {
+		case t.advanced <- struct{}{}:
+		default:
+		}
 	}
 }
 
-// handlerLoop runs the protocol handler loop for a party
-func handlerLoop(id party.ID, h *protocol.Handler, net *simpleNetwork) {
+// received records a message from in round r
+func (t *roundTracker) received(from party.ID, r int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if last, ok := t.heard[from]; ok && r > last {
+		t.heard[from] = r
+	}
+}
+
+// timeout returns the error for a round that did not complete in time,
+// naming the peers not yet heard from in it
+func (t *roundTracker) timeout() *ProtocolTimeoutError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var missing []party.ID
+	for p, r := range t.heard {
+		if r < t.round {
+			missing = append(missing, p)
+		}
+	}
+	return &ProtocolTimeoutError{Round: t.round, Missing: sortedParties(missing)}
+}
+
+// handlerLoop carries party self's messages between its handler and the
+// transport until the session ends. Envelopes addressed to another party or
+// already received are dropped. It returns ctx's error when ctx ends, and a
+// ProtocolTimeoutError when self stays in one round for longer than
+// timeout.
+func handlerLoop(
+	ctx context.Context,
+	h *protocol.Handler,
+	session [32]byte,
+	parties []party.ID,
+	self party.ID,
+	transport Transport,
+	auth MessageAuthenticator,
+	guard *replayGuard,
+	inbox <-chan *Envelope,
+	timeout time.Duration,
+	obs *sessionObserver,
+) error {
 	outChan := h.Listen()
+	peers := peersOf(parties, self)
+	rounds := newRoundTracker(peers)
 
-	// Forward outgoing messages to network
+	// Forward outgoing messages to the transport. A round lasts from self's
+	// first message in it to self's first message in the next.
 	go func() {
+		round, roundStart := 0, time.Now()
 		for msg := range outChan {
-			net.send(msg)
+			if r := int(msg.RoundNumber); r > round {
+				if round > 0 {
+					obs.roundCompleted(round, time.Since(roundStart))
+				}
+				round, roundStart = r, time.Now()
+			}
+			env, err := sealMessage(session, guard.seq(), msg, auth)
+			if err != nil {
+				continue
+			}
+			obs.sent(int(msg.RoundNumber))
+			rounds.sent(int(msg.RoundNumber))
+			if env.To == "" {
+				transport.Broadcast(ctx, peers, env)
+			} else {
+				transport.Send(ctx, env.To, env)
+			}
 		}
 	}()
 
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	expired := deadline.C
+	if timeout <= 0 {
+		expired = nil
+	}
+
 	// Accept incoming messages
-	inChan := net.receive(id)
-	for msg := range inChan {
-		if h.CanAccept(msg) {
-			h.Accept(msg)
+	for {
+		select {
+		case env, ok := <-inbox:
+			if !ok {
+				return nil
+			}
+			if env.To != "" && env.To != self {
+				continue
+			}
+			if msg, ok := openEnvelope(session, env, auth); ok && h.CanAccept(msg) && guard.accept(env.From, env.Seq) {
+				rounds.received(msg.From, int(msg.RoundNumber))
+				obs.received(int(msg.RoundNumber))
+				h.Accept(msg)
+			}
+		case <-rounds.advanced:
+			if expired == nil {
+				continue
+			}
+			if !deadline.Stop() {
+				select {
+				case <-deadline.C:
+				default:
+				}
+			}
+			deadline.Reset(timeout)
+		case <-expired:
+			return rounds.timeout()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// peersOf returns parties other than self
+func peersOf(parties []party.ID, self party.ID) []party.ID {
+	peers := make([]party.ID, 0, len(parties))
+	for _, p := range parties {
+		if p != self {
+			peers = append(peers, p)
 		}
 	}
+	return peers
 }
 
 func (c *ThresholdClient) executeCMPKeygen(
@@ -199,62 +565,17 @@ func (c *ThresholdClient) executeCMPKeygen(
 		return nil, fmt.Errorf("CMP only supports secp256k1, got %v", keyType)
 	}
 
-	// Create network for local simulation
-	net := newSimpleNetwork(participants)
-	defer net.close()
-
-	var configs []*cmp.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range participants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				cmp.Keygen(curve.Secp256k1{}, id, participants, threshold, c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			configs = append(configs, result.(*cmp.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("CMP keygen failed: %w", lastErr)
-	}
-
-	if len(configs) == 0 {
-		return nil, errors.New("no configs generated")
+	session := newSessionTag(sessionKeygen, ProtocolCGGMP21, participants, []byte{byte(keyType)}, binary.BigEndian.AppendUint32(nil, uint32(threshold)))
+	results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+		return cmp.Keygen(curve.Secp256k1{}, id, participants, threshold, c.pool)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CMP keygen failed: %w", err)
 	}
 
 	// Get the config for our party
-	var ourConfig *cmp.Config
-	for _, cfg := range configs {
-		if cfg.ID == selfID {
-			ourConfig = cfg
-			break
-		}
-	}
-	if ourConfig == nil {
+	ourConfig, ok := results[selfID].(*cmp.Config)
+	if !ok {
 		return nil, errors.New("config for self not found")
 	}
 
@@ -265,7 +586,7 @@ func (c *ThresholdClient) executeCMPKeygen(
 		return nil, fmt.Errorf("failed to marshal public key: %w", err)
 	}
 
-	keyID := sha256.Sum256(pubBytes)
+	keyID := DeriveKeyID(ProtocolCGGMP21, KeyTypeSecp256k1, threshold, participants, pubBytes)
 
 	// Store the config
 	c.cmpConfigs[keyID] = ourConfig
@@ -291,62 +612,18 @@ func (c *ThresholdClient) executeFROSTKeygen(
 		return nil, fmt.Errorf("FROST supports secp256k1 or ed25519, got %v", keyType)
 	}
 
-	net := newSimpleNetwork(participants)
-	defer net.close()
-
-	var configs []*frost.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
 	group := curve.Secp256k1{}
 
-	for _, id := range participants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				frost.Keygen(group, id, participants, threshold),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			configs = append(configs, result.(*frost.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("FROST keygen failed: %w", lastErr)
-	}
-
-	if len(configs) == 0 {
-		return nil, errors.New("no configs generated")
+	session := newSessionTag(sessionKeygen, ProtocolFROST, participants, []byte{byte(keyType)}, binary.BigEndian.AppendUint32(nil, uint32(threshold)))
+	results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+		return frost.Keygen(group, id, participants, threshold)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("FROST keygen failed: %w", err)
 	}
 
-	var ourConfig *frost.Config
-	for _, cfg := range configs {
-		if cfg.ID == selfID {
-			ourConfig = cfg
-			break
-		}
-	}
-	if ourConfig == nil {
+	ourConfig, ok := results[selfID].(*frost.Config)
+	if !ok {
 		return nil, errors.New("config for self not found")
 	}
 
@@ -355,7 +632,7 @@ func (c *ThresholdClient) executeFROSTKeygen(
 		return nil, fmt.Errorf("failed to marshal public key: %w", err)
 	}
 
-	keyID := sha256.Sum256(pubBytes)
+	keyID := DeriveKeyID(ProtocolFROST, protocolKeyType(ProtocolFROST), threshold, participants, pubBytes)
 	c.frostConfigs[keyID] = ourConfig
 
 	address := deriveAddressFromPublicKey(pubBytes)
@@ -378,60 +655,16 @@ func (c *ThresholdClient) executeLSSKeygen(
 		return nil, fmt.Errorf("LSS only supports secp256k1, got %v", keyType)
 	}
 
-	net := newSimpleNetwork(participants)
-	defer net.close()
-
-	var configs []*lss.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range participants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				lss.Keygen(curve.Secp256k1{}, id, participants, threshold, c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			configs = append(configs, result.(*lss.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("LSS keygen failed: %w", lastErr)
-	}
-
-	if len(configs) == 0 {
-		return nil, errors.New("no configs generated")
+	session := newSessionTag(sessionKeygen, ProtocolLSS, participants, []byte{byte(keyType)}, binary.BigEndian.AppendUint32(nil, uint32(threshold)))
+	results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+		return lss.Keygen(curve.Secp256k1{}, id, participants, threshold, c.pool)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LSS keygen failed: %w", err)
 	}
 
-	var ourConfig *lss.Config
-	for _, cfg := range configs {
-		if cfg.ID == selfID {
-			ourConfig = cfg
-			break
-		}
-	}
-	if ourConfig == nil {
+	ourConfig, ok := results[selfID].(*lss.Config)
+	if !ok {
 		return nil, errors.New("config for self not found")
 	}
 
@@ -445,7 +678,7 @@ func (c *ThresholdClient) executeLSSKeygen(
 		return nil, fmt.Errorf("failed to marshal public key: %w", err)
 	}
 
-	keyID := sha256.Sum256(pubBytes)
+	keyID := DeriveKeyID(ProtocolLSS, KeyTypeSecp256k1, threshold, participants, pubBytes)
 	c.lssConfigs[keyID] = ourConfig
 
 	address := deriveAddressFromPublicKey(pubBytes)
@@ -463,66 +696,23 @@ func (c *ThresholdClient) executeRingtailKeygen(
 	participants []party.ID,
 	selfID party.ID,
 ) (*KeygenResult, error) {
-	net := newSimpleNetwork(participants)
-	defer net.close()
-
-	var configs []*ringtail.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range participants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				ringtail.Keygen(id, participants, threshold, c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			configs = append(configs, result.(*ringtail.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("Ringtail keygen failed: %w", lastErr)
-	}
-
-	if len(configs) == 0 {
-		return nil, errors.New("no configs generated")
+	session := newSessionTag(sessionKeygen, ProtocolRingtail, participants, binary.BigEndian.AppendUint32(nil, uint32(threshold)))
+	results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+		return ringtail.Keygen(id, participants, threshold, c.pool)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Ringtail keygen failed: %w", err)
 	}
 
-	var ourConfig *ringtail.Config
-	for _, cfg := range configs {
-		if cfg.ID == selfID {
-			ourConfig = cfg
-			break
-		}
-	}
-	if ourConfig == nil {
+	ourConfig, ok := results[selfID].(*ringtail.Config)
+	if !ok {
 		return nil, errors.New("config for self not found")
 	}
 
 	pubBytes := ourConfig.PublicKey
-	keyID := sha256.Sum256(pubBytes)
+	keyID := DeriveKeyID(ProtocolRingtail, KeyTypeRingtail, threshold, participants, pubBytes)
 	c.ringtailConfigs[keyID] = ourConfig
+	c.ringtailParties[keyID] = sortedParties(participants)
 
 	// Ringtail doesn't have EVM address derivation (post-quantum)
 	return &KeygenResult{
@@ -537,6 +727,39 @@ type SigningResult struct {
 	Signature []byte
 }
 
+// SigningGuard authorizes the hashes a client signs
+type SigningGuard interface {
+	// AuthorizeSigning returns an error if messageHash may not be signed
+	// with keyID
+	AuthorizeSigning(keyID [32]byte, messageHash [32]byte) error
+}
+
+// SetSigningGuard installs guard to authorize every hash the client signs,
+// whoever asks for the signature. A nil guard removes it.
+func (c *ThresholdClient) SetSigningGuard(guard SigningGuard) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.guard = guard
+}
+
+// authorizeSigning checks hashes with the signing guard, if any. It runs
+// without c.mu, since the guard may take its own locks.
+func (c *ThresholdClient) authorizeSigning(keyID [32]byte, hashes ...[32]byte) error {
+	c.mu.RLock()
+	guard := c.guard
+	c.mu.RUnlock()
+
+	if guard == nil {
+		return nil
+	}
+	for _, hash := range hashes {
+		if err := guard.AuthorizeSigning(keyID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ExecuteSigning runs the threshold signing protocol
 func (c *ThresholdClient) ExecuteSigning(
 	ctx context.Context,
@@ -546,9 +769,25 @@ func (c *ThresholdClient) ExecuteSigning(
 	signers []party.ID,
 	selfID party.ID,
 ) (*SigningResult, error) {
+	if err := c.authorizeSigning(keyID, messageHash); err != nil {
+		return nil, err
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	return c.executeSign(ctx, keyID, proto, messageHash, signers, selfID)
+}
+
+// executeSign runs one signing session of proto. Caller must hold c.mu.
+func (c *ThresholdClient) executeSign(
+	ctx context.Context,
+	keyID [32]byte,
+	proto Protocol,
+	messageHash [32]byte,
+	signers []party.ID,
+	selfID party.ID,
+) (*SigningResult, error) {
 	switch proto {
 	case ProtocolCGGMP21:
 		return c.executeCMPSign(ctx, keyID, messageHash, signers, selfID)
@@ -575,54 +814,34 @@ func (c *ThresholdClient) executeCMPSign(
 		return nil, ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(signers)
-	defer net.close()
-
-	var signatures []*ecdsa.Signature
-	var sigMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range signers {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				cmp.Sign(config, signers, messageHash[:], c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			sigMu.Lock()
-			signatures = append(signatures, result.(*ecdsa.Signature))
-			sigMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("CMP sign failed: %w", lastErr)
+	// Complete in one round when a presignature is ready
+	if presig := c.takePresignatureFor(keyID, signers); presig != nil {
+		return c.executeCMPPresignedSign(ctx, keyID, presig, messageHash, selfID)
 	}
+	return c.executeCMPFullSign(ctx, keyID, config, messageHash, signers, selfID)
+}
 
-	if len(signatures) == 0 {
-		return nil, errors.New("no signatures generated")
+// executeCMPFullSign runs every round of CGGMP21 signing
+func (c *ThresholdClient) executeCMPFullSign(
+	ctx context.Context,
+	keyID [32]byte,
+	config *cmp.Config,
+	messageHash [32]byte,
+	signers []party.ID,
+	selfID party.ID,
+) (*SigningResult, error) {
+	session := newSessionTag(sessionSign, ProtocolCGGMP21, signers, keyID[:], messageHash[:])
+	results, err := c.runSession(ctx, session, signers, selfID, func(id party.ID) protocol.StartFunc {
+		return cmp.Sign(config, signers, messageHash[:], c.pool)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CMP sign failed: %w", err)
 	}
 
-	// Use the first signature (they should all be the same)
-	sig := signatures[0]
+	sig, ok := results[selfID].(*ecdsa.Signature)
+	if !ok {
+		return nil, errors.New("no signature generated")
+	}
 	sigBytes, err := sig.SigEthereum()
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize signature: %w", err)
@@ -645,65 +864,26 @@ func (c *ThresholdClient) executeFROSTSign(
 		return nil, ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(signers)
-	defer net.close()
-
-	var signatures []frost.Signature
-	var sigMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range signers {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				frost.Sign(config, signers, messageHash[:]),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			sigMu.Lock()
-			signatures = append(signatures, result.(frost.Signature))
-			sigMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("FROST sign failed: %w", lastErr)
+	taprootConfig, err := frostTaprootConfig(config)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(signatures) == 0 {
-		return nil, errors.New("no signatures generated")
+	session := newSessionTag(sessionSign, ProtocolFROST, signers, keyID[:], messageHash[:])
+	results, err := c.runSession(ctx, session, signers, selfID, func(id party.ID) protocol.StartFunc {
+		return frost.SignTaproot(taprootConfig, signers, messageHash[:])
+	})
+	if err != nil {
+		return nil, fmt.Errorf("FROST sign failed: %w", err)
 	}
 
-	sig := signatures[0]
-	// Serialize FROST signature: R (point) || z (scalar)
-	rBytes, err := sig.R.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("failed to serialize R: %w", err)
+	sig, ok := results[selfID].(taproot.Signature)
+	if !ok {
+		return nil, errors.New("no signature generated")
 	}
-	// Access z via reflection since it's unexported (lowercase)
-	// Actually, the struct shows z is lowercase so we can't access it directly
-	// We need to use a different approach - serialize the whole struct
-	sigBytes := rBytes // For now, just use R - FROST verification uses the whole sig
 
 	return &SigningResult{
-		Signature: sigBytes,
+		Signature: []byte(sig),
 	}, nil
 }
 
@@ -719,53 +899,18 @@ func (c *ThresholdClient) executeLSSSign(
 		return nil, ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(signers)
-	defer net.close()
-
-	var signatures []*ecdsa.Signature
-	var sigMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range signers {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				lss.Sign(config, signers, messageHash[:], c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			sigMu.Lock()
-			signatures = append(signatures, result.(*ecdsa.Signature))
-			sigMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("LSS sign failed: %w", lastErr)
+	session := newSessionTag(sessionSign, ProtocolLSS, signers, keyID[:], messageHash[:])
+	results, err := c.runSession(ctx, session, signers, selfID, func(id party.ID) protocol.StartFunc {
+		return lss.Sign(config, signers, messageHash[:], c.pool)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LSS sign failed: %w", err)
 	}
 
-	if len(signatures) == 0 {
-		return nil, errors.New("no signatures generated")
+	sig, ok := results[selfID].(*ecdsa.Signature)
+	if !ok {
+		return nil, errors.New("no signature generated")
 	}
-
-	sig := signatures[0]
 	sigBytes, err := sig.SigEthereum()
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize signature: %w", err)
@@ -788,54 +933,21 @@ func (c *ThresholdClient) executeRingtailSign(
 		return nil, ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(signers)
-	defer net.close()
-
-	var signatures [][]byte
-	var sigMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range signers {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				ringtail.SignWithConfig(config, signers, messageHash[:], c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			sigMu.Lock()
-			signatures = append(signatures, result.([]byte))
-			sigMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return nil, fmt.Errorf("Ringtail sign failed: %w", lastErr)
+	session := newSessionTag(sessionSign, ProtocolRingtail, signers, keyID[:], messageHash[:])
+	results, err := c.runSession(ctx, session, signers, selfID, func(id party.ID) protocol.StartFunc {
+		return ringtail.SignWithConfig(config, signers, messageHash[:], c.pool)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Ringtail sign failed: %w", err)
 	}
 
-	if len(signatures) == 0 {
-		return nil, errors.New("no signatures generated")
+	sig, ok := results[selfID].([]byte)
+	if !ok {
+		return nil, errors.New("no signature generated")
 	}
 
 	return &SigningResult{
-		Signature: signatures[0],
+		Signature: sig,
 	}, nil
 }
 
@@ -875,54 +987,18 @@ func (c *ThresholdClient) executeCMPRefresh(
 		return ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(participants)
-	defer net.close()
-
-	var newConfigs []*cmp.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range participants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				cmp.Refresh(config, c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			newConfigs = append(newConfigs, result.(*cmp.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return fmt.Errorf("CMP refresh failed: %w", lastErr)
+	session := newSessionTag(sessionRefresh, ProtocolCGGMP21, participants, keyID[:])
+	results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+		return cmp.Refresh(config, c.pool)
+	})
+	if err != nil {
+		return fmt.Errorf("CMP refresh failed: %w", err)
 	}
 
 	// Update stored config
-	for _, cfg := range newConfigs {
-		if cfg.ID == selfID {
-			c.cmpConfigs[keyID] = cfg
-			break
-		}
+	if cfg, ok := results[selfID].(*cmp.Config); ok {
+		c.cmpConfigs[keyID] = cfg
+		c.discardPresignatures(keyID)
 	}
 
 	return nil
@@ -939,53 +1015,16 @@ func (c *ThresholdClient) executeFROSTRefresh(
 		return ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(participants)
-	defer net.close()
-
-	var newConfigs []*frost.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range participants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				frost.Refresh(config, participants),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			newConfigs = append(newConfigs, result.(*frost.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return fmt.Errorf("FROST refresh failed: %w", lastErr)
+	session := newSessionTag(sessionRefresh, ProtocolFROST, participants, keyID[:])
+	results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+		return frost.Refresh(config, participants)
+	})
+	if err != nil {
+		return fmt.Errorf("FROST refresh failed: %w", err)
 	}
 
-	for _, cfg := range newConfigs {
-		if cfg.ID == selfID {
-			c.frostConfigs[keyID] = cfg
-			break
-		}
+	if cfg, ok := results[selfID].(*frost.Config); ok {
+		c.frostConfigs[keyID] = cfg
 	}
 
 	return nil
@@ -1002,53 +1041,16 @@ func (c *ThresholdClient) executeLSSRefresh(
 		return ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(participants)
-	defer net.close()
-
-	var newConfigs []*lss.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range participants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				lss.Refresh(config, c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			newConfigs = append(newConfigs, result.(*lss.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return fmt.Errorf("LSS refresh failed: %w", lastErr)
+	session := newSessionTag(sessionRefresh, ProtocolLSS, participants, keyID[:])
+	results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+		return lss.Refresh(config, c.pool)
+	})
+	if err != nil {
+		return fmt.Errorf("LSS refresh failed: %w", err)
 	}
 
-	for _, cfg := range newConfigs {
-		if cfg.ID == selfID {
-			c.lssConfigs[keyID] = cfg
-			break
-		}
+	if cfg, ok := results[selfID].(*lss.Config); ok {
+		c.lssConfigs[keyID] = cfg
 	}
 
 	return nil
@@ -1065,59 +1067,24 @@ func (c *ThresholdClient) executeRingtailRefresh(
 		return ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(participants)
-	defer net.close()
-
-	var newConfigs []*ringtail.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range participants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				ringtail.Refresh(config, participants, config.Threshold, c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			newConfigs = append(newConfigs, result.(*ringtail.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return fmt.Errorf("Ringtail refresh failed: %w", lastErr)
+	session := newSessionTag(sessionRefresh, ProtocolRingtail, participants, keyID[:])
+	results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+		return ringtail.Refresh(config, participants, config.Threshold, c.pool)
+	})
+	if err != nil {
+		return fmt.Errorf("Ringtail refresh failed: %w", err)
 	}
 
-	for _, cfg := range newConfigs {
-		if cfg.ID == selfID {
-			c.ringtailConfigs[keyID] = cfg
-			break
-		}
+	if cfg, ok := results[selfID].(*ringtail.Config); ok {
+		c.ringtailConfigs[keyID] = cfg
 	}
 
 	return nil
 }
 
 // ExecuteReshare runs the key resharing protocol with new parties/threshold
+// and returns the key's new ID. The public key, and the address derived
+// from it, stays the same; see reshare.go for CGGMP21 and FROST.
 func (c *ThresholdClient) ExecuteReshare(
 	ctx context.Context,
 	keyID [32]byte,
@@ -1134,6 +1101,8 @@ func (c *ThresholdClient) ExecuteReshare(
 		return c.executeLSSReshare(ctx, keyID, newParticipants, newThreshold, selfID)
 	case ProtocolRingtail:
 		return c.executeRingtailReshare(ctx, keyID, newParticipants, newThreshold, selfID)
+	case ProtocolCGGMP21, ProtocolFROST:
+		return c.executeDealerReshare(ctx, keyID, proto, newParticipants, newThreshold, selfID)
 	default:
 		return [32]byte{}, fmt.Errorf("reshare not supported for protocol %v", proto)
 	}
@@ -1151,57 +1120,17 @@ func (c *ThresholdClient) executeLSSReshare(
 		return [32]byte{}, ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(newParticipants)
-	defer net.close()
-
-	var newConfigs []*lss.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range newParticipants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				lss.Reshare(config, newParticipants, newThreshold, c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			newConfigs = append(newConfigs, result.(*lss.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return [32]byte{}, fmt.Errorf("LSS reshare failed: %w", lastErr)
+	session := newSessionTag(sessionReshare, ProtocolLSS, newParticipants, keyID[:], binary.BigEndian.AppendUint32(nil, uint32(newThreshold)))
+	results, err := c.runSession(ctx, session, newParticipants, selfID, func(id party.ID) protocol.StartFunc {
+		return lss.Reshare(config, newParticipants, newThreshold, c.pool)
+	})
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("LSS reshare failed: %w", err)
 	}
 
-	// Generate new key ID (same public key, new generation)
-	var ourConfig *lss.Config
-	for _, cfg := range newConfigs {
-		if cfg.ID == selfID {
-			ourConfig = cfg
-			break
-		}
-	}
-	if ourConfig == nil {
+	// Same public key, new participants and threshold
+	ourConfig, ok := results[selfID].(*lss.Config)
+	if !ok {
 		return [32]byte{}, errors.New("config for self not found")
 	}
 
@@ -1210,7 +1139,7 @@ func (c *ThresholdClient) executeLSSReshare(
 		return [32]byte{}, err
 	}
 	pubBytes, _ := pubPoint.MarshalBinary()
-	newKeyID := sha256.Sum256(append(pubBytes, byte(ourConfig.Generation)))
+	newKeyID := DeriveKeyID(ProtocolLSS, KeyTypeSecp256k1, newThreshold, newParticipants, pubBytes)
 
 	// Delete old key and store new one
 	delete(c.lssConfigs, keyID)
@@ -1231,62 +1160,24 @@ func (c *ThresholdClient) executeRingtailReshare(
 		return [32]byte{}, ErrKeyNotFound
 	}
 
-	net := newSimpleNetwork(newParticipants)
-	defer net.close()
-
-	var newConfigs []*ringtail.Config
-	var configsMu sync.Mutex
-	var wg sync.WaitGroup
-	var lastErr error
-
-	for _, id := range newParticipants {
-		wg.Add(1)
-		go func(id party.ID) {
-			defer wg.Done()
-
-			h, err := protocol.NewMultiHandler(
-				ringtail.Refresh(config, newParticipants, newThreshold, c.pool),
-				nil,
-			)
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			go handlerLoop(id, h, net)
-
-			result, err := h.WaitForResult()
-			if err != nil {
-				lastErr = err
-				return
-			}
-
-			configsMu.Lock()
-			newConfigs = append(newConfigs, result.(*ringtail.Config))
-			configsMu.Unlock()
-		}(id)
-	}
-
-	wg.Wait()
-
-	if lastErr != nil {
-		return [32]byte{}, fmt.Errorf("Ringtail reshare failed: %w", lastErr)
+	session := newSessionTag(sessionReshare, ProtocolRingtail, newParticipants, keyID[:], binary.BigEndian.AppendUint32(nil, uint32(newThreshold)))
+	results, err := c.runSession(ctx, session, newParticipants, selfID, func(id party.ID) protocol.StartFunc {
+		return ringtail.Refresh(config, newParticipants, newThreshold, c.pool)
+	})
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("Ringtail reshare failed: %w", err)
 	}
 
-	var ourConfig *ringtail.Config
-	for _, cfg := range newConfigs {
-		if cfg.ID == selfID {
-			ourConfig = cfg
-			break
-		}
-	}
-	if ourConfig == nil {
+	ourConfig, ok := results[selfID].(*ringtail.Config)
+	if !ok {
 		return [32]byte{}, errors.New("config for self not found")
 	}
 
-	newKeyID := sha256.Sum256(ourConfig.PublicKey)
+	newKeyID := DeriveKeyID(ProtocolRingtail, KeyTypeRingtail, newThreshold, newParticipants, ourConfig.PublicKey)
 	delete(c.ringtailConfigs, keyID)
+	delete(c.ringtailParties, keyID)
 	c.ringtailConfigs[newKeyID] = ourConfig
+	c.ringtailParties[newKeyID] = sortedParties(newParticipants)
 
 	return newKeyID, nil
 }
@@ -1314,17 +1205,11 @@ func (c *ThresholdClient) VerifySignature(
 		return sig.Verify(config.PublicPoint(), messageHash[:]), nil
 
 	case ProtocolFROST:
-		// FROST verification requires the internal Signature type
-		// For external verification, we use the stored signature
-		// This is a limitation - FROST signatures need special handling
-		_, ok := c.frostConfigs[keyID]
+		config, ok := c.frostConfigs[keyID]
 		if !ok {
 			return false, ErrKeyNotFound
 		}
-		// FROST signature verification requires the full Signature struct
-		// which contains unexported fields. For now, return an error
-		// indicating that FROST verification must be done internally.
-		return false, errors.New("FROST signature verification not supported via byte serialization; use internal verification")
+		return VerifyFROSTSignature(config.PublicKey, messageHash, signature), nil
 
 	case ProtocolLSS:
 		config, ok := c.lssConfigs[keyID]
@@ -1346,13 +1231,41 @@ func (c *ThresholdClient) VerifySignature(
 		if !ok {
 			return false, ErrKeyNotFound
 		}
-		return ringtail.VerifySignature(config.PublicKey, messageHash[:], signature), nil
+		return quantum.VerifyRingtailSignature(config.PublicKey, messageHash[:], signature), nil
 
 	default:
 		return false, ErrInvalidProtocol
 	}
 }
 
+// VerifyWithPublicKey verifies a signature of proto against a combined
+// public key in the form GetPublicKey returns, without holding a share of
+// the key
+func VerifyWithPublicKey(proto Protocol, publicKey []byte, messageHash [32]byte, signature []byte) bool {
+	switch proto {
+	case ProtocolCGGMP21, ProtocolLSS, ProtocolFROST:
+		group := curve.Secp256k1{}
+		point := group.NewPoint()
+		if err := point.UnmarshalBinary(publicKey); err != nil {
+			return false
+		}
+		if proto == ProtocolFROST {
+			return VerifyFROSTSignature(point, messageHash, signature)
+		}
+		sig, err := parseECDSASignature(signature, group)
+		if err != nil {
+			return false
+		}
+		return sig.Verify(point, messageHash[:])
+
+	case ProtocolRingtail:
+		return quantum.VerifyRingtailSignature(publicKey, messageHash[:], signature)
+
+	default:
+		return false
+	}
+}
+
 // parseECDSASignature parses an Ethereum-format signature (65 bytes: r || s || v)
 // into an ecdsa.Signature struct
 func parseECDSASignature(sigBytes []byte, group curve.Curve) (*ecdsa.Signature, error) {
@@ -1424,6 +1337,44 @@ func (c *ThresholdClient) GetPublicKey(keyID [32]byte, proto Protocol) ([]byte,
 	}
 }
 
+// KeyInfo describes a key this client holds a share of
+type KeyInfo struct {
+	KeyID     [32]byte
+	Protocol  Protocol
+	PublicKey []byte
+}
+
+// ListKeys returns the keys this client holds a share of, ordered by key ID
+func (c *ThresholdClient) ListKeys() []KeyInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var keys []KeyInfo
+	add := func(keyID [32]byte, proto Protocol, point interface{ MarshalBinary() ([]byte, error) }) {
+		if pub, err := point.MarshalBinary(); err == nil {
+			keys = append(keys, KeyInfo{KeyID: keyID, Protocol: proto, PublicKey: pub})
+		}
+	}
+	for keyID, config := range c.cmpConfigs {
+		add(keyID, ProtocolCGGMP21, config.PublicPoint())
+	}
+	for keyID, config := range c.frostConfigs {
+		add(keyID, ProtocolFROST, config.PublicKey)
+	}
+	for keyID, config := range c.lssConfigs {
+		if point, err := config.PublicPoint(); err == nil {
+			add(keyID, ProtocolLSS, point)
+		}
+	}
+	for keyID, config := range c.ringtailConfigs {
+		keys = append(keys, KeyInfo{KeyID: keyID, Protocol: ProtocolRingtail, PublicKey: config.PublicKey})
+	}
+	slices.SortFunc(keys, func(a, b KeyInfo) int {
+		return bytes.Compare(a.KeyID[:], b.KeyID[:])
+	})
+	return keys
+}
+
 // deriveAddressFromPublicKey derives an EVM address from a secp256k1 public key
 func deriveAddressFromPublicKey(pubKey []byte) common.Address {
 	// Use luxcrypto.Keccak256 to hash the public key
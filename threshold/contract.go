@@ -0,0 +1,604 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Threshold signing precompiles.
+//
+// ThresholdContract gives contracts access to T-Chain threshold keys at
+// the LP-52xx addresses of the registry, one per protocol: FROST at
+// 0x5200…, CGGMP21 at 0x5201… and Ringtail at 0x5202…. Keygen and signing
+// are MPC sessions among the key's participants and do not run inside a
+// call. requestKeygen and requestSign record a request in the precompile's
+// storage (see state_store.go), emit a KeygenRequested or SignRequested log
+// for the committee and return the request ID, and the contract polls
+// getKeygenResult and getSignature until the status is final. Requests the
+// committee leaves pending past their timeout read as failed or expired.
+//
+// The committee runs the sessions off chain with a ThresholdManager and
+// posts the results back. fulfillKeygen records a key once a threshold of
+// the committee configured for the precompile has signed
+// KeygenResultDigest. fulfillSign needs no attestation: anyone may post a
+// signature, and it is recorded if it verifies against the key's public
+// key. Keys are owned by the requester of their keygen, and only the owner
+// may request signatures.
+//
+// Keys restricted to EIP-712 are signed through requestSignTyped, which
+// hashes the typed-data document and checks it against the schemas the key
+// owner registered with registerTypedDataSchema. getPublicKey and verify
+// answer directly. Each precompile keeps its own keys, so a key generated
+// with another protocol is not found.
+
+// Precompile addresses (LP-5200, LP-5201, LP-5202)
+var (
+	FROSTContractAddress    = common.HexToAddress("0x5200000000000000000000000000000000000000")
+	CGGMP21ContractAddress  = common.HexToAddress("0x5201000000000000000000000000000000000000")
+	RingtailContractAddress = common.HexToAddress("0x5202000000000000000000000000000000000000")
+)
+
+// Function selectors
+var (
+	SelectorRequestKeygen   = selector("requestKeygen(uint8,uint32,address[])")
+	SelectorRequestSign     = selector("requestSign(bytes32,bytes32)")
+	SelectorGetKeygenResult = selector("getKeygenResult(bytes32)")
+	SelectorGetSignature    = selector("getSignature(bytes32)")
+	SelectorGetPublicKey    = selector("getPublicKey(bytes32)")
+	SelectorVerify          = selector("verify(bytes32,bytes32,bytes)")
+	SelectorFulfillKeygen   = selector("fulfillKeygen(bytes32,bytes32,bytes,bytes)")
+	SelectorFulfillSign     = selector("fulfillSign(bytes32,bytes)")
+
+	SelectorRegisterTypedDataSchema = selector("registerTypedDataSchema(bytes32,bytes)")
+	SelectorRequestSignTyped        = selector("requestSignTyped(bytes32,bytes)")
+)
+
+func selector(signature string) [4]byte {
+	return [4]byte(luxcrypto.Keccak256([]byte(signature))[:4])
+}
+
+// ThresholdContract is the precompile of one threshold protocol
+type ThresholdContract struct {
+	address  common.Address
+	protocol Protocol
+
+	// committee attests keygen results; nil until configured
+	committee *committee
+}
+
+var _ contract.StatefulPrecompiledContract = (*ThresholdContract)(nil)
+
+// Precompile instances
+var (
+	FROSTContract    = &ThresholdContract{address: FROSTContractAddress, protocol: ProtocolFROST}
+	CGGMP21Contract  = &ThresholdContract{address: CGGMP21ContractAddress, protocol: ProtocolCGGMP21}
+	RingtailContract = &ThresholdContract{address: RingtailContractAddress, protocol: ProtocolRingtail}
+)
+
+// Address returns the address of the precompile
+func (c *ThresholdContract) Address() common.Address {
+	return c.address
+}
+
+// Protocol returns the protocol the precompile runs
+func (c *ThresholdContract) Protocol() Protocol {
+	return c.protocol
+}
+
+// SetCommittee sets the committee whose attestations complete keygen, or
+// clears it when members is empty
+func (c *ThresholdContract) SetCommittee(members []common.Address, threshold int) error {
+	if len(members) == 0 {
+		c.committee = nil
+		return nil
+	}
+	cm, err := newCommittee(members, threshold)
+	if err != nil {
+		return err
+	}
+	c.committee = cm
+	return nil
+}
+
+// RequiredGas returns the gas of a call
+func (c *ThresholdContract) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return 0
+	}
+	data := input[4:]
+	switch [4]byte(input[:4]) {
+	case SelectorRequestKeygen:
+		return GasKeygen
+	case SelectorRequestSign:
+		return GasSign
+	case SelectorVerify:
+		return GasVerify
+	case SelectorGetPublicKey:
+		return GasGetPublicKey
+	case SelectorGetKeygenResult, SelectorGetSignature:
+		return GasGetKeyInfo
+	case SelectorGetRequestNonce:
+		return GasGetNonce
+	case SelectorRegisterTypedDataSchema:
+		return GasRegisterType
+	case SelectorRequestSignTyped:
+		return GasSignTyped
+	case SelectorFulfillKeygen:
+		publicKey, _ := abiBytesArg(data, 2)
+		signatures, _ := abiBytesArg(data, 3)
+		return GasFulfillKeygen + GasPerAttestation*uint64(len(signatures)/65) + GasPerWord*wordCount(len(publicKey))
+	case SelectorFulfillSign:
+		signature, _ := abiBytesArg(data, 1)
+		return GasFulfillSign + GasPerWord*wordCount(len(signature))
+	default:
+		return 0
+	}
+}
+
+// Run dispatches a call on its selector
+func (c *ThresholdContract) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) ([]byte, uint64, error) {
+	if len(input) < 4 {
+		return nil, suppliedGas, ErrInvalidCalldata
+	}
+	gas := c.RequiredGas(input)
+	if gas == 0 {
+		return nil, suppliedGas, ErrInvalidCalldata
+	}
+	remaining, err := contract.DeductGas(suppliedGas, gas)
+	if err != nil {
+		return nil, 0, err
+	}
+	if accessibleState == nil || accessibleState.GetStateDB() == nil {
+		return nil, remaining, ErrNoState
+	}
+
+	s := &store{db: accessibleState.GetStateDB(), addr: c.address}
+	var now uint64
+	if bc := accessibleState.GetBlockContext(); bc != nil {
+		now = bc.Timestamp()
+	}
+
+	sel := [4]byte(input[:4])
+	switch sel {
+	case SelectorRequestKeygen, SelectorRequestSign, SelectorRegisterTypedDataSchema,
+		SelectorRequestSignTyped, SelectorFulfillKeygen, SelectorFulfillSign:
+		if readOnly {
+			return nil, remaining, ErrWriteProtection
+		}
+	}
+
+	data := input[4:]
+	var ret []byte
+	switch sel {
+	case SelectorRequestKeygen:
+		ret, err = c.requestKeygen(s, now, caller, data)
+	case SelectorRequestSign:
+		ret, err = c.requestSign(s, now, caller, data)
+	case SelectorGetKeygenResult:
+		ret, err = c.getKeygenResult(s, now, data)
+	case SelectorGetSignature:
+		ret, err = c.getSignature(s, now, data)
+	case SelectorGetPublicKey:
+		ret, err = c.getPublicKey(s, data)
+	case SelectorVerify:
+		ret, err = c.verify(s, data)
+	case SelectorGetRequestNonce:
+		ret, err = c.getRequestNonce(s, input)
+	case SelectorRegisterTypedDataSchema:
+		ret, err = c.registerTypedDataSchema(s, caller, data)
+	case SelectorRequestSignTyped:
+		ret, err = c.requestSignTyped(s, now, caller, data)
+	case SelectorFulfillKeygen:
+		ret, err = c.fulfillKeygen(s, now, data)
+	case SelectorFulfillSign:
+		ret, err = c.fulfillSign(s, now, data)
+	}
+	if err != nil {
+		return nil, remaining, err
+	}
+	return ret, remaining, nil
+}
+
+// requestKeygen decodes (uint8 keyType, uint32 threshold, address[]
+// participants) and returns the request ID
+func (c *ThresholdContract) requestKeygen(s *store, now uint64, caller common.Address, data []byte) ([]byte, error) {
+	keyType, ok := abiUint(data, 0, 8)
+	if !ok {
+		return nil, ErrInvalidCalldata
+	}
+	threshold, ok := abiUint(data, 1, 32)
+	if !ok {
+		return nil, ErrInvalidCalldata
+	}
+	participants, ok := abiAddressArray(data, 2)
+	if !ok || len(participants) > MaxParties {
+		return nil, ErrInvalidCalldata
+	}
+	if err := validateKeygenParams(c.protocol, KeyType(keyType), uint32(threshold), uint32(len(participants))); err != nil {
+		return nil, err
+	}
+
+	requestID := s.nextRequestID(keygenRequestPrefix, caller)
+	s.putKeygen(requestID, &stateKeygen{
+		Requester: caller,
+		KeyType:   KeyType(keyType),
+		Status:    KeygenStatusPending,
+		Threshold: uint8(threshold),
+		Parties:   uint8(len(participants)),
+		ExpiresAt: now + KeygenRequestTimeout,
+	})
+	s.db.AddLog(keygenRequestedLog(c.address, requestID, caller, KeyType(keyType), uint32(threshold), participants))
+	return requestID[:], nil
+}
+
+// requestSign decodes (bytes32 keyId, bytes32 messageHash) and returns the
+// request ID
+func (c *ThresholdContract) requestSign(s *store, now uint64, caller common.Address, data []byte) ([]byte, error) {
+	if len(data) < 64 {
+		return nil, ErrInvalidCalldata
+	}
+	keyID := [32]byte(data[:32])
+	key, err := signingKey(s, now, caller, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.TypedDataOnly {
+		return nil, ErrBlindSigningDisabled
+	}
+
+	requestID := c.fileSignRequest(s, now, caller, keyID, [32]byte(data[32:64]))
+	return requestID[:], nil
+}
+
+// signingKey returns the key caller may request signatures with at now
+func signingKey(s *store, now uint64, caller common.Address, keyID [32]byte) (*stateKey, error) {
+	key, ok := s.key(keyID)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	switch {
+	case key.Status == KeyStatusRevoked:
+		return nil, ErrKeyRevoked
+	case key.Status == KeyStatusExpired || now > key.ExpiresAt:
+		return nil, ErrKeyExpired
+	case key.Status != KeyStatusActive:
+		return nil, ErrKeyBusy
+	case key.Owner != caller:
+		return nil, ErrUnauthorized
+	}
+	return key, nil
+}
+
+// fileSignRequest records a signing request and announces it to the
+// committee
+func (c *ThresholdContract) fileSignRequest(s *store, now uint64, caller common.Address, keyID, messageHash [32]byte) [32]byte {
+	requestID := s.nextRequestID(signRequestPrefix, caller)
+	s.putSign(requestID, &stateSign{
+		Requester:   caller,
+		Status:      SignStatusPending,
+		ExpiresAt:   now + SignRequestTimeout,
+		KeyID:       keyID,
+		MessageHash: messageHash,
+	})
+	s.db.AddLog(signRequestedLog(c.address, requestID, keyID, messageHash))
+	return requestID
+}
+
+// registerTypedDataSchema decodes (bytes32 keyId, bytes typedData) and
+// returns the ID of the schema allowlisted from the example document. Once
+// a key has a schema it only signs typed data.
+func (c *ThresholdContract) registerTypedDataSchema(s *store, caller common.Address, data []byte) ([]byte, error) {
+	keyID, typedData, ok := typedDataArgs(data)
+	if !ok {
+		return nil, ErrInvalidCalldata
+	}
+	td, err := ParseTypedData(typedData)
+	if err != nil {
+		return nil, err
+	}
+	schemaID, err := td.SchemaID()
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := s.key(keyID)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if key.Owner != caller {
+		return nil, ErrUnauthorized
+	}
+	s.allowSchema(keyID, schemaID)
+	if !key.TypedDataOnly {
+		key.TypedDataOnly = true
+		s.setKeyHeader(keyID, key)
+	}
+	return schemaID[:], nil
+}
+
+// requestSignTyped decodes (bytes32 keyId, bytes typedData) and returns
+// (bytes32 requestId, bytes32 digest)
+func (c *ThresholdContract) requestSignTyped(s *store, now uint64, caller common.Address, data []byte) ([]byte, error) {
+	keyID, typedData, ok := typedDataArgs(data)
+	if !ok {
+		return nil, ErrInvalidCalldata
+	}
+	td, err := ParseTypedData(typedData)
+	if err != nil {
+		return nil, err
+	}
+	schemaID, err := td.SchemaID()
+	if err != nil {
+		return nil, err
+	}
+	digest, err := td.SigningHash()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := signingKey(s, now, caller, keyID); err != nil {
+		return nil, err
+	}
+	if !s.schemaAllowed(keyID, schemaID) {
+		return nil, ErrSchemaNotAllowed
+	}
+
+	requestID := c.fileSignRequest(s, now, caller, keyID, digest)
+	return append(requestID[:], digest[:]...), nil
+}
+
+// typedDataArgs decodes (bytes32 keyId, bytes typedData)
+func typedDataArgs(data []byte) ([32]byte, []byte, bool) {
+	if len(data) < 64 {
+		return [32]byte{}, nil, false
+	}
+	typedData, ok := abiBytesArg(data, 1)
+	if !ok {
+		return [32]byte{}, nil, false
+	}
+	return [32]byte(data[:32]), typedData, true
+}
+
+// fulfillKeygen decodes (bytes32 requestId, bytes32 keyId, bytes
+// publicKey, bytes signatures) and records the key once a threshold of the
+// committee has signed KeygenResultDigest. Signatures are concatenated
+// 65-byte [R || S || V] signatures.
+func (c *ThresholdContract) fulfillKeygen(s *store, now uint64, data []byte) ([]byte, error) {
+	if len(data) < 128 {
+		return nil, ErrInvalidCalldata
+	}
+	publicKey, ok := abiBytesArg(data, 2)
+	if !ok {
+		return nil, ErrInvalidCalldata
+	}
+	signatures, ok := abiBytesArg(data, 3)
+	if !ok || len(signatures)%65 != 0 {
+		return nil, ErrInvalidCalldata
+	}
+	if c.committee == nil {
+		return nil, ErrNoCommittee
+	}
+
+	requestID, keyID := [32]byte(data[:32]), [32]byte(data[32:64])
+	req, ok := s.keygen(requestID)
+	switch {
+	case !ok:
+		return nil, ErrRequestNotFound
+	case req.Status != KeygenStatusPending:
+		return nil, ErrRequestFulfilled
+	case now > req.ExpiresAt:
+		return nil, ErrRequestExpired
+	case len(publicKey) == 0:
+		return nil, ErrInvalidPublicKey
+	}
+	if !c.committee.attested(KeygenResultDigest(c.address, requestID, keyID, publicKey), signatures) {
+		return nil, ErrAttestation
+	}
+	if _, exists := s.key(keyID); exists {
+		return nil, ErrKeyExists
+	}
+
+	s.putKey(keyID, &stateKey{
+		Owner:     req.Requester,
+		KeyType:   req.KeyType,
+		Status:    KeyStatusActive,
+		ExpiresAt: now + DefaultKeyExpiry,
+		PublicKey: publicKey,
+	})
+	req.Status = KeygenStatusComplete
+	req.KeyID = keyID
+	s.putKeygen(requestID, req)
+	return nil, nil
+}
+
+// fulfillSign decodes (bytes32 requestId, bytes signature) and records the
+// signature if it verifies against the key's public key
+func (c *ThresholdContract) fulfillSign(s *store, now uint64, data []byte) ([]byte, error) {
+	if len(data) < 64 {
+		return nil, ErrInvalidCalldata
+	}
+	signature, ok := abiBytesArg(data, 1)
+	if !ok {
+		return nil, ErrInvalidCalldata
+	}
+
+	requestID := [32]byte(data[:32])
+	req, ok := s.sign(requestID)
+	switch {
+	case !ok:
+		return nil, ErrRequestNotFound
+	case req.Status != SignStatusPending:
+		return nil, ErrRequestFulfilled
+	case now > req.ExpiresAt:
+		return nil, ErrRequestExpired
+	}
+	key, ok := s.key(req.KeyID)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if !VerifyWithPublicKey(c.protocol, key.PublicKey, req.MessageHash, signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	s.setSignature(requestID, req, signature)
+	return nil, nil
+}
+
+// getKeygenResult returns (uint8 status, bytes32 keyId) of a keygen
+// request
+func (c *ThresholdContract) getKeygenResult(s *store, now uint64, data []byte) ([]byte, error) {
+	if len(data) < 32 {
+		return nil, ErrInvalidCalldata
+	}
+	req, ok := s.keygen([32]byte(data[:32]))
+	if !ok {
+		return nil, ErrRequestNotFound
+	}
+	status := req.Status
+	if status == KeygenStatusPending && now > req.ExpiresAt {
+		status = KeygenStatusFailed
+	}
+	return append(abiUintWord(uint64(status)), req.KeyID[:]...), nil
+}
+
+// getSignature returns (uint8 status, bytes signature) of a signing
+// request
+func (c *ThresholdContract) getSignature(s *store, now uint64, data []byte) ([]byte, error) {
+	if len(data) < 32 {
+		return nil, ErrInvalidCalldata
+	}
+	req, ok := s.sign([32]byte(data[:32]))
+	if !ok {
+		return nil, ErrRequestNotFound
+	}
+	status := req.Status
+	if status == SignStatusPending && now > req.ExpiresAt {
+		status = SignStatusExpired
+	}
+	ret := abiUintWord(uint64(status))
+	ret = append(ret, abiUintWord(64)...)
+	return append(ret, abiBytes(req.Signature)...), nil
+}
+
+// getPublicKey returns the key's public key as bytes
+func (c *ThresholdContract) getPublicKey(s *store, data []byte) ([]byte, error) {
+	if len(data) < 32 {
+		return nil, ErrInvalidCalldata
+	}
+	key, ok := s.key([32]byte(data[:32]))
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return append(abiUintWord(32), abiBytes(key.PublicKey)...), nil
+}
+
+// verify decodes (bytes32 keyId, bytes32 messageHash, bytes signature) and
+// returns whether the signature is valid
+func (c *ThresholdContract) verify(s *store, data []byte) ([]byte, error) {
+	if len(data) < 96 {
+		return nil, ErrInvalidCalldata
+	}
+	signature, ok := abiBytesArg(data, 2)
+	if !ok {
+		return nil, ErrInvalidCalldata
+	}
+	key, ok := s.key([32]byte(data[:32]))
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if VerifyWithPublicKey(c.protocol, key.PublicKey, [32]byte(data[32:64]), signature) {
+		return abiUintWord(1), nil
+	}
+	return abiUintWord(0), nil
+}
+
+// getRequestNonce answers getRequestNonce(address) with the nonce the
+// requester's next request at this precompile takes
+func (c *ThresholdContract) getRequestNonce(s *store, input []byte) ([]byte, error) {
+	if len(input) != 4+32 {
+		return nil, ErrInvalidNonceQuery
+	}
+	return common.BigToHash(new(big.Int).SetUint64(s.requestNonce(common.BytesToAddress(input[4:36])))).Bytes(), nil
+}
+
+// abiUintWord encodes v as a uint256 word
+func abiUintWord(v uint64) []byte {
+	word := make([]byte, 32)
+	binary.BigEndian.PutUint64(word[24:], v)
+	return word
+}
+
+// abiUint decodes argument i as an unsigned integer of at most bits bits
+func abiUint(data []byte, i int, bits uint) (uint64, bool) {
+	if len(data) < (i+1)*32 {
+		return 0, false
+	}
+	v := new(big.Int).SetBytes(data[i*32 : (i+1)*32])
+	if uint(v.BitLen()) > bits {
+		return 0, false
+	}
+	return v.Uint64(), true
+}
+
+// abiTail returns the length word and contents of dynamic argument i
+func abiTail(data []byte, i int) (uint64, []byte, bool) {
+	offset, ok := abiUint(data, i, 32)
+	if !ok || uint64(len(data)) < offset+32 {
+		return 0, nil, false
+	}
+	n, ok := abiUint(data[offset:], 0, 32)
+	if !ok {
+		return 0, nil, false
+	}
+	return n, data[offset+32:], true
+}
+
+// abiBytesArg decodes dynamic bytes argument i
+func abiBytesArg(data []byte, i int) ([]byte, bool) {
+	n, tail, ok := abiTail(data, i)
+	if !ok || uint64(len(tail)) < n {
+		return nil, false
+	}
+	return tail[:n], true
+}
+
+// abiAddressArray decodes address[] argument i
+func abiAddressArray(data []byte, i int) ([]common.Address, bool) {
+	n, tail, ok := abiTail(data, i)
+	if !ok || uint64(len(tail))/32 < n {
+		return nil, false
+	}
+	addrs := make([]common.Address, n)
+	for j := range addrs {
+		word := tail[j*32 : (j+1)*32]
+		if new(big.Int).SetBytes(word).BitLen() > 160 {
+			return nil, false
+		}
+		addrs[j] = common.BytesToAddress(word)
+	}
+	return addrs, true
+}
+
+// abiBytes encodes the length and padded contents of a bytes value
+func abiBytes(b []byte) []byte {
+	out := abiUintWord(uint64(len(b)))
+	out = append(out, b...)
+	if pad := len(b) % 32; pad != 0 {
+		out = append(out, make([]byte, 32-pad)...)
+	}
+	return out
+}
@@ -0,0 +1,297 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+	"github.com/luxfi/precompile/contract/statetest"
+	"github.com/luxfi/precompile/precompileconfig"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// testState is an AccessibleState over a test StateDB at a block time
+type testState struct {
+	db   *statetest.StateDB
+	time uint64
+}
+
+func (s *testState) GetStateDB() contract.StateDB                     { return s.db }
+func (s *testState) GetBlockContext() contract.BlockContext           { return testBlockContext(s.time) }
+func (s *testState) GetConsensusContext() context.Context             { return context.Background() }
+func (s *testState) GetChainConfig() precompileconfig.ChainConfig     { return nil }
+func (s *testState) GetPrecompileEnv() contract.PrecompileEnvironment { return nil }
+
+// testBlockContext is a block context at a timestamp
+type testBlockContext uint64
+
+func (b testBlockContext) Number() *big.Int                                       { return big.NewInt(1) }
+func (b testBlockContext) Timestamp() uint64                                      { return uint64(b) }
+func (b testBlockContext) GetPredicateResults(common.Hash, common.Address) []byte { return nil }
+
+// keygenCalldata encodes requestKeygen(uint8,uint32,address[])
+func keygenCalldata(keyType KeyType, threshold uint32, participants []common.Address) []byte {
+	input := append([]byte(nil), SelectorRequestKeygen[:]...)
+	input = append(input, abiUintWord(uint64(keyType))...)
+	input = append(input, abiUintWord(uint64(threshold))...)
+	input = append(input, abiUintWord(96)...)
+	input = append(input, abiUintWord(uint64(len(participants)))...)
+	for _, p := range participants {
+		input = append(input, common.BytesToHash(p.Bytes()).Bytes()...)
+	}
+	return input
+}
+
+// fulfillKeygenCalldata encodes fulfillKeygen(bytes32,bytes32,bytes,bytes)
+func fulfillKeygenCalldata(requestID, keyID [32]byte, publicKey, signatures []byte) []byte {
+	input := append(append([]byte(nil), SelectorFulfillKeygen[:]...), requestID[:]...)
+	input = append(input, keyID[:]...)
+	input = append(input, abiUintWord(128)...)
+	input = append(input, abiUintWord(128+uint64(len(abiBytes(publicKey))))...)
+	input = append(input, abiBytes(publicKey)...)
+	return append(input, abiBytes(signatures)...)
+}
+
+// fulfillSignCalldata encodes fulfillSign(bytes32,bytes)
+func fulfillSignCalldata(requestID [32]byte, signature []byte) []byte {
+	input := append(append([]byte(nil), SelectorFulfillSign[:]...), requestID[:]...)
+	input = append(input, abiUintWord(64)...)
+	return append(input, abiBytes(signature)...)
+}
+
+// testCommittee configures a committee of n members on c and returns their
+// keys
+func testCommittee(t *testing.T, c *ThresholdContract, n, threshold int) []*ecdsa.PrivateKey {
+	t.Helper()
+	keys := make([]*ecdsa.PrivateKey, n)
+	members := make([]common.Address, n)
+	for i := range keys {
+		key, err := ecdsa.GenerateKey(luxcrypto.S256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		keys[i] = key
+		members[i] = common.BytesToAddress(luxcrypto.Keccak256(luxcrypto.FromECDSAPub(&key.PublicKey)[1:])[12:])
+	}
+	if err := c.SetCommittee(members, threshold); err != nil {
+		t.Fatalf("SetCommittee failed: %v", err)
+	}
+	t.Cleanup(func() { _ = c.SetCommittee(nil, 0) })
+	return keys
+}
+
+// attest signs digest with each key
+func attest(t *testing.T, digest common.Hash, keys ...*ecdsa.PrivateKey) []byte {
+	t.Helper()
+	var signatures []byte
+	for _, key := range keys {
+		sig, err := luxcrypto.Sign(digest.Bytes(), key)
+		if err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+		signatures = append(signatures, sig...)
+	}
+	return signatures
+}
+
+// TestThresholdContract tests keygen and signing through the FROST
+// precompile, with the sessions run by a client standing in for the
+// committee
+func TestThresholdContract(t *testing.T) {
+	committee := testCommittee(t, FROSTContract, 3, 2)
+	state := &testState{db: statetest.New(), time: 1000}
+
+	caller := common.HexToAddress("0x1000")
+	participants := []common.Address{
+		common.HexToAddress("0x2001"),
+		common.HexToAddress("0x2002"),
+		common.HexToAddress("0x2003"),
+	}
+	input := keygenCalldata(KeyTypeSecp256k1, 1, participants)
+
+	if _, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, input, GasKeygen, true); !errors.Is(err, ErrWriteProtection) {
+		t.Errorf("Expected ErrWriteProtection in a static call, got %v", err)
+	}
+	if _, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, input, GasKeygen-1, false); err == nil {
+		t.Errorf("Expected out of gas")
+	}
+	if _, _, err := FROSTContract.Run(nil, caller, FROSTContractAddress, input, GasKeygen, false); !errors.Is(err, ErrNoState) {
+		t.Errorf("Expected ErrNoState, got %v", err)
+	}
+
+	ret, remaining, err := FROSTContract.Run(state, caller, FROSTContractAddress, input, GasKeygen+100, false)
+	if err != nil {
+		t.Fatalf("requestKeygen failed: %v", err)
+	}
+	if remaining != 100 || len(ret) != 32 {
+		t.Fatalf("Expected 32-byte request ID and 100 gas left, got %d bytes and %d", len(ret), remaining)
+	}
+	requestID := [32]byte(ret)
+
+	// The same call again gets the requester's next nonce and a new ID
+	again, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, input, GasKeygen, false)
+	if err != nil || bytes.Equal(again, ret) {
+		t.Fatalf("Second request should get a new ID: %v", err)
+	}
+	nonceQuery := append(SelectorGetRequestNonce[:], common.BytesToHash(caller.Bytes()).Bytes()...)
+	if out, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, nonceQuery, GasGetNonce, true); err != nil || !bytes.Equal(out, abiUintWord(2)) {
+		t.Errorf("Expected request nonce 2, got %x (%v)", out, err)
+	}
+	if logs := state.db.Logs(); len(logs) != 2 || logs[0].Topics[0] != KeygenRequestedTopic || logs[0].Topics[1] != requestID {
+		t.Errorf("Expected a KeygenRequested log per request, got %d logs", len(logs))
+	}
+
+	// The committee runs the session off chain
+	client := NewThresholdClient()
+	defer client.Close()
+	parties := []party.ID{"alice", "bob", "charlie"}
+	keygen, err := client.ExecuteKeygen(context.Background(), ProtocolFROST, KeyTypeSecp256k1, 1, parties, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteKeygen failed: %v", err)
+	}
+
+	query := append(SelectorGetKeygenResult[:], requestID[:]...)
+	out, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, query, GasGetKeyInfo, true)
+	if err != nil || KeygenStatus(out[31]) != KeygenStatusPending {
+		t.Fatalf("Expected a pending keygen, got %x (%v)", out, err)
+	}
+
+	// One attestation is below the committee threshold
+	digest := KeygenResultDigest(FROSTContractAddress, requestID, keygen.KeyID, keygen.PublicKey)
+	fulfill := fulfillKeygenCalldata(requestID, keygen.KeyID, keygen.PublicKey, attest(t, digest, committee[0]))
+	if _, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, fulfill, FROSTContract.RequiredGas(fulfill), false); !errors.Is(err, ErrAttestation) {
+		t.Errorf("Expected ErrAttestation, got %v", err)
+	}
+	fulfill = fulfillKeygenCalldata(requestID, keygen.KeyID, keygen.PublicKey, attest(t, digest, committee[0], committee[2]))
+	if _, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, fulfill, FROSTContract.RequiredGas(fulfill), false); err != nil {
+		t.Fatalf("fulfillKeygen failed: %v", err)
+	}
+	if _, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, fulfill, FROSTContract.RequiredGas(fulfill), false); !errors.Is(err, ErrRequestFulfilled) {
+		t.Errorf("Expected ErrRequestFulfilled, got %v", err)
+	}
+
+	out, _, err = FROSTContract.Run(state, caller, FROSTContractAddress, query, GasGetKeyInfo, true)
+	if err != nil || KeygenStatus(out[31]) != KeygenStatusComplete || [32]byte(out[32:64]) != keygen.KeyID {
+		t.Fatalf("Expected the completed key, got %x (%v)", out, err)
+	}
+	keyID := keygen.KeyID
+
+	out, _, err = FROSTContract.Run(state, caller, FROSTContractAddress, append(SelectorGetPublicKey[:], keyID[:]...), GasGetPublicKey, true)
+	if err != nil {
+		t.Fatalf("getPublicKey failed: %v", err)
+	}
+	if !bytes.Equal(out, append(abiUintWord(32), abiBytes(keygen.PublicKey)...)) {
+		t.Errorf("getPublicKey returned %x", out)
+	}
+
+	// Only the owner signs
+	messageHash := [32]byte{0x42}
+	sign := append(append(append([]byte(nil), SelectorRequestSign[:]...), keyID[:]...), messageHash[:]...)
+	if _, _, err := FROSTContract.Run(state, participants[0], FROSTContractAddress, sign, GasSign, false); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	ret, _, err = FROSTContract.Run(state, caller, FROSTContractAddress, sign, GasSign, false)
+	if err != nil {
+		t.Fatalf("requestSign failed: %v", err)
+	}
+	signRequestID := [32]byte(ret)
+
+	result, err := client.ExecuteSigning(context.Background(), keyID, ProtocolFROST, messageHash, []party.ID{"alice", "bob"}, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteSigning failed: %v", err)
+	}
+
+	// A signature that does not verify is refused
+	forged := append([]byte(nil), result.Signature...)
+	forged[63] ^= 0x01
+	bad := fulfillSignCalldata(signRequestID, forged)
+	if _, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, bad, FROSTContract.RequiredGas(bad), false); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+	good := fulfillSignCalldata(signRequestID, result.Signature)
+	if _, _, err := FROSTContract.Run(state, common.HexToAddress("0x3000"), FROSTContractAddress, good, FROSTContract.RequiredGas(good), false); err != nil {
+		t.Fatalf("fulfillSign failed: %v", err)
+	}
+
+	out, _, err = FROSTContract.Run(state, caller, FROSTContractAddress, append(SelectorGetSignature[:], signRequestID[:]...), GasGetKeyInfo, true)
+	if err != nil {
+		t.Fatalf("getSignature failed: %v", err)
+	}
+	want := append(abiUintWord(uint64(SignStatusComplete)), abiUintWord(64)...)
+	if !bytes.Equal(out, append(want, abiBytes(result.Signature)...)) {
+		t.Errorf("getSignature returned %x", out)
+	}
+
+	verify := append(SelectorVerify[:], keyID[:]...)
+	verify = append(verify, messageHash[:]...)
+	verify = append(verify, abiUintWord(96)...)
+	for sig, valid := range map[string]bool{string(result.Signature): true, string(make([]byte, 64)): false} {
+		out, _, err = FROSTContract.Run(state, caller, FROSTContractAddress, append(verify, abiBytes([]byte(sig))...), GasVerify, true)
+		if err != nil {
+			t.Fatalf("verify failed: %v", err)
+		}
+		if bytes.Equal(out, abiUintWord(1)) != valid {
+			t.Errorf("verify returned %x, expected valid=%v", out, valid)
+		}
+	}
+
+	// The key belongs to the FROST precompile
+	if _, _, err := CGGMP21Contract.Run(state, caller, CGGMP21ContractAddress, append(SelectorGetPublicKey[:], keyID[:]...), GasGetPublicKey, true); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+// TestThresholdContractExpiry tests that requests the committee leaves
+// pending expire with block time
+func TestThresholdContractExpiry(t *testing.T) {
+	committee := testCommittee(t, CGGMP21Contract, 1, 1)
+	state := &testState{db: statetest.New(), time: 1000}
+	caller := common.HexToAddress("0x1000")
+
+	input := keygenCalldata(KeyTypeSecp256k1, 1, []common.Address{common.HexToAddress("0x2001"), common.HexToAddress("0x2002")})
+	ret, _, err := CGGMP21Contract.Run(state, caller, CGGMP21ContractAddress, input, GasKeygen, false)
+	if err != nil {
+		t.Fatalf("requestKeygen failed: %v", err)
+	}
+	requestID := [32]byte(ret)
+
+	state.time += KeygenRequestTimeout + 1
+	out, _, err := CGGMP21Contract.Run(state, caller, CGGMP21ContractAddress, append(SelectorGetKeygenResult[:], requestID[:]...), GasGetKeyInfo, true)
+	if err != nil || KeygenStatus(out[31]) != KeygenStatusFailed {
+		t.Errorf("Expected an expired keygen to read as failed, got %x (%v)", out, err)
+	}
+
+	publicKey := []byte{0x02, 0x01}
+	fulfill := fulfillKeygenCalldata(requestID, [32]byte{1}, publicKey, attest(t, KeygenResultDigest(CGGMP21ContractAddress, requestID, [32]byte{1}, publicKey), committee...))
+	if _, _, err := CGGMP21Contract.Run(state, caller, CGGMP21ContractAddress, fulfill, CGGMP21Contract.RequiredGas(fulfill), false); !errors.Is(err, ErrRequestExpired) {
+		t.Errorf("Expected ErrRequestExpired, got %v", err)
+	}
+}
+
+// TestThresholdContractCalldata tests rejection of malformed calls
+func TestThresholdContractCalldata(t *testing.T) {
+	state := &testState{db: statetest.New(), time: 1000}
+	caller := common.HexToAddress("0x1000")
+	cases := map[string][]byte{
+		"short":               {0x01, 0x02},
+		"unknown selector":    {0xde, 0xad, 0xbe, 0xef},
+		"truncated array":     keygenCalldata(KeyTypeSecp256k1, 1, []common.Address{caller})[:4+4*32],
+		"wide key type":       append(append(append([]byte(nil), SelectorRequestKeygen[:]...), bytes.Repeat([]byte{0xff}, 32)...), make([]byte, 64)...),
+		"ragged attestations": fulfillKeygenCalldata([32]byte{1}, [32]byte{2}, []byte{0x02}, make([]byte, 64)),
+	}
+	for name, input := range cases {
+		if _, _, err := FROSTContract.Run(state, caller, FROSTContractAddress, input, GasKeygen, false); !errors.Is(err, ErrInvalidCalldata) {
+			t.Errorf("%s: expected ErrInvalidCalldata, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,540 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/geth/common/hexutil"
+)
+
+// EIP712DomainType is the reserved name of the domain struct
+const EIP712DomainType = "EIP712Domain"
+
+// TypedDataField is a single member of an EIP-712 struct type
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is an EIP-712 payload in eth_signTypedData_v4 JSON form
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// TypedDataSchema is an allowlisted (domain, primary type) pair for a key.
+// The committee only signs typed data whose domain separator and primary
+// type hash match a registered schema.
+type TypedDataSchema struct {
+	SchemaID        [32]byte       // keccak256(domainSeparator || typeHash)
+	DomainSeparator [32]byte       // hashStruct(EIP712Domain, domain)
+	PrimaryType     string         // Name of the signed struct
+	TypeHash        [32]byte       // keccak256(encodeType(primaryType))
+	RegisteredBy    common.Address // Key owner that allowlisted the schema
+	RegisteredAt    uint64
+}
+
+// ParseTypedData decodes an eth_signTypedData_v4 JSON document.
+// Numbers are kept as json.Number so large integers are not truncated.
+func ParseTypedData(data []byte) (*TypedData, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+
+	var td TypedData
+	if err := dec.Decode(&td); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTypedData, err)
+	}
+	if td.PrimaryType == "" || td.PrimaryType == EIP712DomainType {
+		return nil, fmt.Errorf("%w: invalid primary type", ErrInvalidTypedData)
+	}
+	if _, ok := td.Types[EIP712DomainType]; !ok {
+		return nil, fmt.Errorf("%w: missing %s type", ErrInvalidTypedData, EIP712DomainType)
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return nil, fmt.Errorf("%w: undefined primary type %s", ErrInvalidTypedData, td.PrimaryType)
+	}
+	return &td, nil
+}
+
+// DomainSeparator returns hashStruct(EIP712Domain, domain)
+func (td *TypedData) DomainSeparator() ([32]byte, error) {
+	return td.HashStruct(EIP712DomainType, td.Domain)
+}
+
+// TypeHash returns keccak256(encodeType(primaryType))
+func (td *TypedData) TypeHash(primaryType string) ([32]byte, error) {
+	encoded, err := td.EncodeType(primaryType)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return common.BytesToHash(luxcrypto.Keccak256([]byte(encoded))), nil
+}
+
+// SigningHash returns keccak256(0x1901 || domainSeparator || hashStruct(message))
+func (td *TypedData) SigningHash() ([32]byte, error) {
+	domainSeparator, err := td.DomainSeparator()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	messageHash, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return common.BytesToHash(luxcrypto.Keccak256(
+		[]byte{0x19, 0x01}, domainSeparator[:], messageHash[:],
+	)), nil
+}
+
+// EncodeType returns the EIP-712 type encoding: the primary type followed by
+// its referenced struct types sorted by name
+func (td *TypedData) EncodeType(primaryType string) (string, error) {
+	deps := make(map[string]bool)
+	if err := td.collectDependencies(primaryType, deps); err != nil {
+		return "", err
+	}
+	delete(deps, primaryType)
+
+	sorted := make([]string, 0, len(deps))
+	for dep := range deps {
+		sorted = append(sorted, dep)
+	}
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	for _, name := range append([]string{primaryType}, sorted...) {
+		b.WriteString(name)
+		b.WriteByte('(')
+		for i, field := range td.Types[name] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(field.Type)
+			b.WriteByte(' ')
+			b.WriteString(field.Name)
+		}
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+// collectDependencies walks the struct types referenced by typeName
+func (td *TypedData) collectDependencies(typeName string, deps map[string]bool) error {
+	typeName = baseType(typeName)
+	if deps[typeName] {
+		return nil
+	}
+	fields, ok := td.Types[typeName]
+	if !ok {
+		if isAtomicType(typeName) {
+			return nil
+		}
+		return fmt.Errorf("%w: undefined type %s", ErrInvalidTypedData, typeName)
+	}
+	deps[typeName] = true
+	for _, field := range fields {
+		if err := td.collectDependencies(field.Type, deps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HashStruct returns keccak256(typeHash || encodeData(data))
+func (td *TypedData) HashStruct(typeName string, data map[string]interface{}) ([32]byte, error) {
+	encoded, err := td.encodeData(typeName, data)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return common.BytesToHash(luxcrypto.Keccak256(encoded)), nil
+}
+
+// encodeData encodes a struct value. Every declared field must be present
+// and no undeclared fields are accepted, so the signed digest always
+// reflects the full message.
+func (td *TypedData) encodeData(typeName string, data map[string]interface{}) ([]byte, error) {
+	fields, ok := td.Types[typeName]
+	if !ok {
+		return nil, fmt.Errorf("%w: undefined type %s", ErrInvalidTypedData, typeName)
+	}
+	if len(data) != len(fields) {
+		return nil, fmt.Errorf("%w: %s has %d fields, got %d", ErrInvalidTypedData, typeName, len(fields), len(data))
+	}
+
+	typeHash, err := td.TypeHash(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 32*(len(fields)+1))
+	out = append(out, typeHash[:]...)
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s missing field %s", ErrInvalidTypedData, typeName, field.Name)
+		}
+		word, err := td.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, word...)
+	}
+	return out, nil
+}
+
+// encodeValue encodes a single field value into a 32-byte word
+func (td *TypedData) encodeValue(typeName string, value interface{}) ([]byte, error) {
+	// Arrays: keccak256 of the concatenated element encodings
+	if strings.HasSuffix(typeName, "]") {
+		open := strings.LastIndex(typeName, "[")
+		if open < 0 {
+			return nil, fmt.Errorf("%w: malformed array type %s", ErrInvalidTypedData, typeName)
+		}
+		elemType := typeName[:open]
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: expected array for %s", ErrInvalidTypedData, typeName)
+		}
+		if length := typeName[open+1 : len(typeName)-1]; length != "" {
+			n, err := strconv.Atoi(length)
+			if err != nil || n != len(items) {
+				return nil, fmt.Errorf("%w: expected %s elements for %s", ErrInvalidTypedData, length, typeName)
+			}
+		}
+		var concat []byte
+		for _, item := range items {
+			word, err := td.encodeValue(elemType, item)
+			if err != nil {
+				return nil, err
+			}
+			concat = append(concat, word...)
+		}
+		return luxcrypto.Keccak256(concat), nil
+	}
+
+	// Nested structs: hashStruct
+	if _, ok := td.Types[typeName]; ok {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: expected object for %s", ErrInvalidTypedData, typeName)
+		}
+		hash, err := td.HashStruct(typeName, nested)
+		if err != nil {
+			return nil, err
+		}
+		return hash[:], nil
+	}
+
+	return encodeAtomicValue(typeName, value)
+}
+
+// encodeAtomicValue encodes an elementary EIP-712 value
+func encodeAtomicValue(typeName string, value interface{}) ([]byte, error) {
+	word := make([]byte, 32)
+
+	switch {
+	case typeName == "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: expected string", ErrInvalidTypedData)
+		}
+		return luxcrypto.Keccak256([]byte(s)), nil
+
+	case typeName == "bytes":
+		b, err := decodeTypedHex(value)
+		if err != nil {
+			return nil, err
+		}
+		return luxcrypto.Keccak256(b), nil
+
+	case typeName == "address":
+		b, err := decodeTypedHex(value)
+		if err != nil || len(b) != common.AddressLength {
+			return nil, fmt.Errorf("%w: invalid address", ErrInvalidTypedData)
+		}
+		copy(word[12:], b)
+		return word, nil
+
+	case typeName == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: expected bool", ErrInvalidTypedData)
+		}
+		if b {
+			word[31] = 1
+		}
+		return word, nil
+
+	case strings.HasPrefix(typeName, "bytes"):
+		size, err := strconv.Atoi(typeName[len("bytes"):])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("%w: invalid type %s", ErrInvalidTypedData, typeName)
+		}
+		b, err := decodeTypedHex(value)
+		if err != nil || len(b) != size {
+			return nil, fmt.Errorf("%w: expected %d bytes for %s", ErrInvalidTypedData, size, typeName)
+		}
+		copy(word, b)
+		return word, nil
+
+	case strings.HasPrefix(typeName, "uint"), strings.HasPrefix(typeName, "int"):
+		signed := strings.HasPrefix(typeName, "int")
+		bits, err := intTypeBits(typeName)
+		if err != nil {
+			return nil, err
+		}
+		n, err := parseTypedInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		if !signed {
+			if n.Sign() < 0 || n.BitLen() > bits {
+				return nil, fmt.Errorf("%w: %s out of range", ErrInvalidTypedData, typeName)
+			}
+			return common.LeftPadBytes(n.Bytes(), 32), nil
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+		if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%w: %s out of range", ErrInvalidTypedData, typeName)
+		}
+		// Two's complement over 256 bits
+		if n.Sign() < 0 {
+			n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return common.LeftPadBytes(n.Bytes(), 32), nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported type %s", ErrInvalidTypedData, typeName)
+}
+
+// baseType strips array suffixes from a type name
+func baseType(typeName string) string {
+	if i := strings.Index(typeName, "["); i >= 0 {
+		return typeName[:i]
+	}
+	return typeName
+}
+
+// isAtomicType reports whether typeName is an elementary EIP-712 type
+func isAtomicType(typeName string) bool {
+	switch typeName {
+	case "string", "bytes", "address", "bool":
+		return true
+	}
+	if strings.HasPrefix(typeName, "bytes") {
+		size, err := strconv.Atoi(typeName[len("bytes"):])
+		return err == nil && size >= 1 && size <= 32
+	}
+	if strings.HasPrefix(typeName, "uint") || strings.HasPrefix(typeName, "int") {
+		_, err := intTypeBits(typeName)
+		return err == nil
+	}
+	return false
+}
+
+// intTypeBits returns the bit width of a uintN/intN type
+func intTypeBits(typeName string) (int, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(typeName, "u"), "int")
+	if digits == "" {
+		return 256, nil
+	}
+	bits, err := strconv.Atoi(digits)
+	if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+		return 0, fmt.Errorf("%w: invalid type %s", ErrInvalidTypedData, typeName)
+	}
+	return bits, nil
+}
+
+// parseTypedInteger accepts JSON numbers and decimal or 0x-hex strings
+func parseTypedInteger(value interface{}) (*big.Int, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("%w: expected integer", ErrInvalidTypedData)
+	}
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid integer %q", ErrInvalidTypedData, s)
+	}
+	return n, nil
+}
+
+// decodeTypedHex decodes a 0x-prefixed hex string
+func decodeTypedHex(value interface{}) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected hex string", ErrInvalidTypedData)
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTypedData, err)
+	}
+	return b, nil
+}
+
+// typedDataSchemaID identifies a (domain, primary type) pair
+func typedDataSchemaID(domainSeparator, typeHash [32]byte) [32]byte {
+	return common.BytesToHash(luxcrypto.Keccak256(domainSeparator[:], typeHash[:]))
+}
+
+// SchemaID returns the ID of the document's (domain, primary type) schema
+func (td *TypedData) SchemaID() ([32]byte, error) {
+	domainSeparator, err := td.DomainSeparator()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	typeHash, err := td.TypeHash(td.PrimaryType)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return typedDataSchemaID(domainSeparator, typeHash), nil
+}
+
+// RegisterTypedDataSchema allowlists the domain and primary type of an
+// example typed-data document for a key. Once a key has a schema registered
+// it only signs typed data; raw hash signing is refused.
+func (tm *ThresholdManager) RegisterTypedDataSchema(
+	requester common.Address,
+	keyID [32]byte,
+	typedDataJSON []byte,
+) ([32]byte, error) {
+	td, err := ParseTypedData(typedDataJSON)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	domainSeparator, err := td.DomainSeparator()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	typeHash, err := td.TypeHash(td.PrimaryType)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return [32]byte{}, ErrKeyNotFound
+	}
+	if key.Owner != requester {
+		return [32]byte{}, ErrUnauthorized
+	}
+
+	schemaID := typedDataSchemaID(domainSeparator, typeHash)
+	if tm.TypedDataSchemas[keyID] == nil {
+		tm.TypedDataSchemas[keyID] = make(map[[32]byte]*TypedDataSchema)
+	}
+	tm.TypedDataSchemas[keyID][schemaID] = &TypedDataSchema{
+		SchemaID:        schemaID,
+		DomainSeparator: domainSeparator,
+		PrimaryType:     td.PrimaryType,
+		TypeHash:        typeHash,
+		RegisteredBy:    requester,
+		RegisteredAt:    uint64(time.Now().Unix()),
+	}
+	key.Permissions.TypedDataOnly = true
+
+	return schemaID, nil
+}
+
+// RemoveTypedDataSchema removes a schema from a key's allowlist
+func (tm *ThresholdManager) RemoveTypedDataSchema(
+	requester common.Address,
+	keyID [32]byte,
+	schemaID [32]byte,
+) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return ErrKeyNotFound
+	}
+	if key.Owner != requester {
+		return ErrUnauthorized
+	}
+	if tm.TypedDataSchemas[keyID][schemaID] == nil {
+		return ErrSchemaNotAllowed
+	}
+	delete(tm.TypedDataSchemas[keyID], schemaID)
+	return nil
+}
+
+// GetTypedDataSchemas returns the allowlisted schemas of a key
+func (tm *ThresholdManager) GetTypedDataSchemas(keyID [32]byte) []*TypedDataSchema {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	schemas := make([]*TypedDataSchema, 0, len(tm.TypedDataSchemas[keyID]))
+	for _, schema := range tm.TypedDataSchemas[keyID] {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return bytes.Compare(schemas[i].SchemaID[:], schemas[j].SchemaID[:]) < 0
+	})
+	return schemas
+}
+
+// RequestTypedDataSignature hashes an EIP-712 document, checks its schema
+// against the key's allowlist and requests a threshold signature over the
+// EIP-712 digest
+func (tm *ThresholdManager) RequestTypedDataSignature(
+	requester common.Address,
+	keyID [32]byte,
+	typedDataJSON []byte,
+) ([32]byte, [32]byte, error) {
+	td, err := ParseTypedData(typedDataJSON)
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+	domainSeparator, err := td.DomainSeparator()
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+	typeHash, err := td.TypeHash(td.PrimaryType)
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+	digest, err := td.SigningHash()
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.TypedDataSchemas[keyID][typedDataSchemaID(domainSeparator, typeHash)] == nil {
+		if tm.Keys[keyID] == nil {
+			return [32]byte{}, [32]byte{}, ErrKeyNotFound
+		}
+		return [32]byte{}, [32]byte{}, ErrSchemaNotAllowed
+	}
+	if tm.Policies[keyID] != nil {
+		return [32]byte{}, [32]byte{}, ErrTransactionRequired
+	}
+
+	requestID, err := tm.requestSignature(requester, keyID, digest)
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+	return requestID, digest, nil
+}
@@ -0,0 +1,225 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract/statetest"
+)
+
+// mailTypedData is the reference example from the EIP-712 specification
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// TestTypedDataHashing checks hashing against the EIP-712 reference vectors
+func TestTypedDataHashing(t *testing.T) {
+	td, err := ParseTypedData([]byte(mailTypedData))
+	if err != nil {
+		t.Fatalf("ParseTypedData failed: %v", err)
+	}
+
+	encoded, err := td.EncodeType("Mail")
+	if err != nil {
+		t.Fatalf("EncodeType failed: %v", err)
+	}
+	if encoded != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Errorf("Unexpected type encoding: %s", encoded)
+	}
+
+	domainSeparator, err := td.DomainSeparator()
+	if err != nil {
+		t.Fatalf("DomainSeparator failed: %v", err)
+	}
+	if domainSeparator != common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f") {
+		t.Errorf("Unexpected domain separator: %x", domainSeparator)
+	}
+
+	messageHash, err := td.HashStruct("Mail", td.Message)
+	if err != nil {
+		t.Fatalf("HashStruct failed: %v", err)
+	}
+	if messageHash != common.HexToHash("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e") {
+		t.Errorf("Unexpected message hash: %x", messageHash)
+	}
+
+	digest, err := td.SigningHash()
+	if err != nil {
+		t.Fatalf("SigningHash failed: %v", err)
+	}
+	if digest != common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2") {
+		t.Errorf("Unexpected signing hash: %x", digest)
+	}
+}
+
+// TestTypedDataRejectsMalformed tests strict message validation
+func TestTypedDataRejectsMalformed(t *testing.T) {
+	tests := map[string]string{
+		"extra field": strings.Replace(mailTypedData, `"contents": "Hello, Bob!"`, `"contents": "Hello, Bob!", "amount": 1`, 1),
+		"missing field": strings.Replace(mailTypedData, `,
+		"contents": "Hello, Bob!"`, ``, 1),
+		"bad address":       strings.Replace(mailTypedData, `0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB`, `0xbBbB`, 1),
+		"undefined type":    strings.Replace(mailTypedData, `"type": "Person"}`, `"type": "Human"}`, 1),
+		"negative uint":     strings.Replace(mailTypedData, `"chainId": 1`, `"chainId": -1`, 1),
+		"domain as primary": strings.Replace(mailTypedData, `"primaryType": "Mail"`, `"primaryType": "EIP712Domain"`, 1),
+	}
+
+	for name, doc := range tests {
+		td, err := ParseTypedData([]byte(doc))
+		if err == nil {
+			_, err = td.SigningHash()
+		}
+		if !errors.Is(err, ErrInvalidTypedData) {
+			t.Errorf("%s: expected ErrInvalidTypedData, got %v", name, err)
+		}
+	}
+}
+
+// TestRequestTypedDataSignature tests schema allowlisting and blind-sign refusal
+func TestRequestTypedDataSignature(t *testing.T) {
+	tm := NewThresholdManager()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	other := common.HexToAddress("0xABCDABCDABCDABCDABCDABCDABCDABCDABCDABCD")
+	keyID := setupTestKey(t, tm, owner)
+
+	// Not allowlisted yet
+	if _, _, err := tm.RequestTypedDataSignature(owner, keyID, []byte(mailTypedData)); err != ErrSchemaNotAllowed {
+		t.Errorf("Expected ErrSchemaNotAllowed, got %v", err)
+	}
+
+	// Only the key owner may register schemas
+	if _, err := tm.RegisterTypedDataSchema(other, keyID, []byte(mailTypedData)); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+
+	schemaID, err := tm.RegisterTypedDataSchema(owner, keyID, []byte(mailTypedData))
+	if err != nil {
+		t.Fatalf("RegisterTypedDataSchema failed: %v", err)
+	}
+	if schemas := tm.GetTypedDataSchemas(keyID); len(schemas) != 1 || schemas[0].SchemaID != schemaID {
+		t.Errorf("Expected one registered schema, got %d", len(schemas))
+	}
+
+	// Different message under the same schema is allowed
+	doc := strings.Replace(mailTypedData, "Hello, Bob!", "Wire 10 LUX", 1)
+	requestID, digest, err := tm.RequestTypedDataSignature(owner, keyID, []byte(doc))
+	if err != nil {
+		t.Fatalf("RequestTypedDataSignature failed: %v", err)
+	}
+	if tm.SignRequests[requestID].MessageHash != digest {
+		t.Error("Signing request should carry the EIP-712 digest")
+	}
+
+	// A different domain is a different schema
+	doc = strings.Replace(mailTypedData, `"chainId": 1`, `"chainId": 96369`, 1)
+	if _, _, err := tm.RequestTypedDataSignature(owner, keyID, []byte(doc)); err != ErrSchemaNotAllowed {
+		t.Errorf("Expected ErrSchemaNotAllowed for other domain, got %v", err)
+	}
+
+	// Raw hashes are no longer signed
+	if _, err := tm.RequestSignature(owner, keyID, [32]byte{0xDE, 0xAD}); err != ErrBlindSigningDisabled {
+		t.Errorf("Expected ErrBlindSigningDisabled, got %v", err)
+	}
+}
+
+// typedDataCalldata encodes a (bytes32 keyId, bytes typedData) call
+func typedDataCalldata(sel [4]byte, keyID [32]byte, typedData string) []byte {
+	input := append(append([]byte(nil), sel[:]...), keyID[:]...)
+	input = append(input, abiUintWord(64)...)
+	return append(input, abiBytes([]byte(typedData))...)
+}
+
+// TestTypedDataContract tests schema registration and typed signing through
+// the FROST precompile
+func TestTypedDataContract(t *testing.T) {
+	state := &testState{db: statetest.New(), time: 1000}
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := [32]byte{0x01, 0x02, 0x03}
+	(&store{db: state.db, addr: FROSTContractAddress}).putKey(keyID, &stateKey{
+		Owner:     owner,
+		KeyType:   KeyTypeSecp256k1,
+		Status:    KeyStatusActive,
+		ExpiresAt: state.time + DefaultKeyExpiry,
+		PublicKey: []byte("test_public_key"),
+	})
+
+	register := typedDataCalldata(SelectorRegisterTypedDataSchema, keyID, mailTypedData)
+	if _, _, err := FROSTContract.Run(state, owner, FROSTContractAddress, register, GasRegisterType, true); !errors.Is(err, ErrWriteProtection) {
+		t.Errorf("Expected ErrWriteProtection in a static call, got %v", err)
+	}
+	if _, _, err := CGGMP21Contract.Run(state, owner, CGGMP21ContractAddress, register, GasRegisterType, false); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if _, _, err := FROSTContract.Run(state, common.HexToAddress("0x1000"), FROSTContractAddress, register, GasRegisterType, false); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+
+	// Not allowlisted yet
+	sign := typedDataCalldata(SelectorRequestSignTyped, keyID, mailTypedData)
+	if _, _, err := FROSTContract.Run(state, owner, FROSTContractAddress, sign, GasSignTyped, false); !errors.Is(err, ErrSchemaNotAllowed) {
+		t.Errorf("Expected ErrSchemaNotAllowed, got %v", err)
+	}
+
+	ret, remaining, err := FROSTContract.Run(state, owner, FROSTContractAddress, register, GasRegisterType+10, false)
+	if err != nil {
+		t.Fatalf("registerTypedDataSchema failed: %v", err)
+	}
+	if remaining != 10 || len(ret) != 32 {
+		t.Fatalf("Expected 32-byte schema ID and 10 gas left, got %d bytes and %d", len(ret), remaining)
+	}
+
+	ret, _, err = FROSTContract.Run(state, owner, FROSTContractAddress, sign, GasSignTyped, false)
+	if err != nil {
+		t.Fatalf("requestSignTyped failed: %v", err)
+	}
+	if len(ret) != 64 {
+		t.Fatalf("Expected request ID and digest, got %d bytes", len(ret))
+	}
+	s := &store{db: state.db, addr: FROSTContractAddress}
+	if request, ok := s.sign([32]byte(ret[:32])); !ok || request.MessageHash != [32]byte(ret[32:]) {
+		t.Error("Signing request should carry the returned digest")
+	}
+
+	// Raw hashes are no longer signed
+	raw := append(append(append([]byte(nil), SelectorRequestSign[:]...), keyID[:]...), make([]byte, 32)...)
+	if _, _, err := FROSTContract.Run(state, owner, FROSTContractAddress, raw, GasSign, false); !errors.Is(err, ErrBlindSigningDisabled) {
+		t.Errorf("Expected ErrBlindSigningDisabled, got %v", err)
+	}
+
+	if _, _, err := FROSTContract.Run(state, owner, FROSTContractAddress, sign[:4+64], GasSignTyped, false); !errors.Is(err, ErrInvalidCalldata) {
+		t.Errorf("Expected ErrInvalidCalldata, got %v", err)
+	}
+}
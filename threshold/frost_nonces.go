@@ -0,0 +1,252 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"crypto/rand"
+
+	"github.com/luxfi/threshold/pkg/math/curve"
+	"github.com/luxfi/threshold/pkg/math/sample"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// FROST nonce pre-generation.
+//
+// FROST signing starts with a round in which every signer commits to a
+// hiding and a binding nonce (RFC 9591, section 5.1). That round does not
+// depend on the message, so signers can run it ahead of time. Each signer
+// generates a list of nonce pairs, keeps the secret nonces, and publishes
+// the matching commitment list. When a message arrives, the coordinator
+// takes the next unused commitment of each signer, and each signer answers
+// with a single signature share.
+//
+// A nonce pair must never sign twice, or the signer's share leaks. Both
+// sides therefore track consumption by index. A signer deletes the secret
+// nonces when it takes them. The coordinator records every reserved index
+// and refuses to accept or hand out that index again for the same signer.
+
+// MaxFROSTNonceBatch bounds a single pre-generation call
+const MaxFROSTNonceBatch = 1024
+
+// FROSTNonceCommitment is the public half of a pre-generated nonce pair
+type FROSTNonceCommitment struct {
+	Index   uint64 // Position in the signer's list, never reused
+	Hiding  []byte // D = d·G
+	Binding []byte // E = e·G
+}
+
+// frostNonce is the secret half of a pre-generated nonce pair
+type frostNonce struct {
+	hiding  curve.Scalar
+	binding curve.Scalar
+}
+
+// frostNonceBook holds the nonce state of one FROST key
+type frostNonceBook struct {
+	// Own secret nonces by index, and the next index to hand out
+	secret    map[uint64]*frostNonce
+	nextIndex uint64
+
+	// Published commitments of every signer, in index order, and the
+	// indices already reserved for a signing session
+	published map[party.ID][]FROSTNonceCommitment
+	consumed  map[party.ID]map[uint64]bool
+}
+
+func newFROSTNonceBook() *frostNonceBook {
+	return &frostNonceBook{
+		secret:    make(map[uint64]*frostNonce),
+		published: make(map[party.ID][]FROSTNonceCommitment),
+		consumed:  make(map[party.ID]map[uint64]bool),
+	}
+}
+
+// PregenerateFROSTNonces generates count nonce pairs for this party and
+// returns their commitments for publication. The secret nonces stay in the
+// client until TakeFROSTNonce consumes them.
+func (c *ThresholdClient) PregenerateFROSTNonces(keyID [32]byte, count int) ([]FROSTNonceCommitment, error) {
+	if count <= 0 || count > MaxFROSTNonceBatch {
+		return nil, ErrInvalidNonceBatch
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	config, ok := c.frostConfigs[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	group := config.PublicKey.Curve()
+	book := c.nonceBook(keyID)
+
+	commitments := make([]FROSTNonceCommitment, 0, count)
+	for i := 0; i < count; i++ {
+		nonce := &frostNonce{
+			hiding:  sample.Scalar(rand.Reader, group),
+			binding: sample.Scalar(rand.Reader, group),
+		}
+		hiding, err := nonce.hiding.ActOnBase().MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		binding, err := nonce.binding.ActOnBase().MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		index := book.nextIndex
+		book.nextIndex++
+		book.secret[index] = nonce
+		commitments = append(commitments, FROSTNonceCommitment{
+			Index:   index,
+			Hiding:  hiding,
+			Binding: binding,
+		})
+	}
+
+	return commitments, nil
+}
+
+// TakeFROSTNonce removes and returns this party's secret nonces for index.
+// A second call for the same index fails with ErrNonceConsumed.
+func (c *ThresholdClient) TakeFROSTNonce(keyID [32]byte, index uint64) (hiding, binding curve.Scalar, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	book, ok := c.frostNonces[keyID]
+	if !ok {
+		return nil, nil, ErrNonceConsumed
+	}
+	nonce, ok := book.secret[index]
+	if !ok {
+		return nil, nil, ErrNonceConsumed
+	}
+	delete(book.secret, index)
+
+	return nonce.hiding, nonce.binding, nil
+}
+
+// PublishFROSTCommitments records a signer's commitment list for a key.
+// Indices must be new for the signer and increasing, and every commitment
+// must be a valid non-identity point.
+func (c *ThresholdClient) PublishFROSTCommitments(
+	keyID [32]byte,
+	signer party.ID,
+	commitments []FROSTNonceCommitment,
+) error {
+	if len(commitments) == 0 || len(commitments) > MaxFROSTNonceBatch {
+		return ErrInvalidNonceBatch
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	config, ok := c.frostConfigs[keyID]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if _, ok := config.VerificationShares.Points[signer]; !ok {
+		return ErrUnknownSigner
+	}
+	group := config.PublicKey.Curve()
+	book := c.nonceBook(keyID)
+
+	// Continue after the last index seen, consumed or still queued
+	var next uint64
+	var seen bool
+	if queue := book.published[signer]; len(queue) > 0 {
+		next, seen = queue[len(queue)-1].Index+1, true
+	}
+	for index := range book.consumed[signer] {
+		if !seen || index >= next {
+			next, seen = index+1, true
+		}
+	}
+
+	for _, commitment := range commitments {
+		if seen && commitment.Index < next {
+			return ErrNonceConsumed
+		}
+		if !validNonceCommitment(group, commitment.Hiding) || !validNonceCommitment(group, commitment.Binding) {
+			return ErrBadNonceCommitment
+		}
+		next, seen = commitment.Index+1, true
+	}
+
+	book.published[signer] = append(book.published[signer], commitments...)
+	return nil
+}
+
+// ReserveFROSTCommitments takes the next unused commitment of each signer
+// for one signing session. The reservation is all or nothing: if any signer
+// has run out, no commitment is consumed.
+func (c *ThresholdClient) ReserveFROSTCommitments(
+	keyID [32]byte,
+	signers []party.ID,
+) (map[party.ID]FROSTNonceCommitment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.frostConfigs[keyID]; !ok {
+		return nil, ErrKeyNotFound
+	}
+	book, ok := c.frostNonces[keyID]
+	if !ok {
+		return nil, ErrNoncesExhausted
+	}
+
+	reserved := make(map[party.ID]FROSTNonceCommitment, len(signers))
+	for _, signer := range signers {
+		if _, dup := reserved[signer]; dup {
+			return nil, ErrInsufficientParties
+		}
+		if len(book.published[signer]) == 0 {
+			return nil, ErrNoncesExhausted
+		}
+		reserved[signer] = book.published[signer][0]
+	}
+
+	for signer, commitment := range reserved {
+		book.published[signer] = book.published[signer][1:]
+		if book.consumed[signer] == nil {
+			book.consumed[signer] = make(map[uint64]bool)
+		}
+		book.consumed[signer][commitment.Index] = true
+	}
+
+	return reserved, nil
+}
+
+// FROSTNoncesRemaining returns how many published commitments of signer are
+// still unused for a key
+func (c *ThresholdClient) FROSTNoncesRemaining(keyID [32]byte, signer party.ID) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	book, ok := c.frostNonces[keyID]
+	if !ok {
+		return 0
+	}
+	return len(book.published[signer])
+}
+
+// nonceBook returns the nonce book of a key, creating it if needed.
+// Callers must hold c.mu.
+func (c *ThresholdClient) nonceBook(keyID [32]byte) *frostNonceBook {
+	book, ok := c.frostNonces[keyID]
+	if !ok {
+		book = newFROSTNonceBook()
+		c.frostNonces[keyID] = book
+	}
+	return book
+}
+
+// validNonceCommitment checks that data encodes a non-identity point of group
+func validNonceCommitment(group curve.Curve, data []byte) bool {
+	point := group.NewPoint()
+	if err := point.UnmarshalBinary(data); err != nil {
+		return false
+	}
+	return !point.IsIdentity()
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"testing"
+
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// TestFROSTNoncePregeneration tests that pre-generated nonce commitments are
+// handed out once per signer and their secrets taken once
+func TestFROSTNoncePregeneration(t *testing.T) {
+	client := NewThresholdClient()
+	defer client.Close()
+
+	participants := []party.ID{"alice", "bob", "charlie"}
+	keygen, err := client.ExecuteKeygen(context.Background(), ProtocolFROST, KeyTypeSecp256k1, 1, participants, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteKeygen failed: %v", err)
+	}
+	keyID := keygen.KeyID
+
+	if _, err := client.PregenerateFROSTNonces(keyID, 0); err != ErrInvalidNonceBatch {
+		t.Errorf("Expected ErrInvalidNonceBatch, got %v", err)
+	}
+	own, err := client.PregenerateFROSTNonces(keyID, 2)
+	if err != nil {
+		t.Fatalf("PregenerateFROSTNonces failed: %v", err)
+	}
+	if own[0].Index != 0 || own[1].Index != 1 {
+		t.Errorf("Expected indices 0 and 1, got %d and %d", own[0].Index, own[1].Index)
+	}
+	if err := client.PublishFROSTCommitments(keyID, "alice", own); err != nil {
+		t.Fatalf("PublishFROSTCommitments failed: %v", err)
+	}
+
+	// Commitments are checked against the key's curve and participants
+	if err := client.PublishFROSTCommitments(keyID, "mallory", own); err != ErrUnknownSigner {
+		t.Errorf("Expected ErrUnknownSigner, got %v", err)
+	}
+	bad := []FROSTNonceCommitment{{Index: 0, Hiding: []byte{0x01}, Binding: own[0].Binding}}
+	if err := client.PublishFROSTCommitments(keyID, "bob", bad); err != ErrBadNonceCommitment {
+		t.Errorf("Expected ErrBadNonceCommitment, got %v", err)
+	}
+	bobs := []FROSTNonceCommitment{{Index: 0, Hiding: own[0].Hiding, Binding: own[0].Binding}}
+	if err := client.PublishFROSTCommitments(keyID, "bob", bobs); err != nil {
+		t.Fatalf("PublishFROSTCommitments failed: %v", err)
+	}
+
+	signers := []party.ID{"alice", "bob"}
+	reserved, err := client.ReserveFROSTCommitments(keyID, signers)
+	if err != nil {
+		t.Fatalf("ReserveFROSTCommitments failed: %v", err)
+	}
+	if reserved["alice"].Index != 0 || reserved["bob"].Index != 0 {
+		t.Errorf("Expected first commitments reserved, got %v", reserved)
+	}
+
+	// Bob is out of commitments, so nothing of Alice's is consumed
+	if _, err := client.ReserveFROSTCommitments(keyID, signers); err != ErrNoncesExhausted {
+		t.Errorf("Expected ErrNoncesExhausted, got %v", err)
+	}
+	if n := client.FROSTNoncesRemaining(keyID, "alice"); n != 1 {
+		t.Errorf("Expected 1 commitment left for alice, got %d", n)
+	}
+
+	// A consumed index cannot be published again
+	if err := client.PublishFROSTCommitments(keyID, "bob", bobs); err != ErrNonceConsumed {
+		t.Errorf("Expected ErrNonceConsumed on republish, got %v", err)
+	}
+
+	if _, _, err := client.TakeFROSTNonce(keyID, reserved["alice"].Index); err != nil {
+		t.Fatalf("TakeFROSTNonce failed: %v", err)
+	}
+	if _, _, err := client.TakeFROSTNonce(keyID, reserved["alice"].Index); err != ErrNonceConsumed {
+		t.Errorf("Expected ErrNonceConsumed on second take, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"github.com/luxfi/threshold/pkg/math/curve"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/taproot"
+	"github.com/luxfi/threshold/protocols/frost"
+)
+
+// FROST signature encoding.
+//
+// FROST keys are secp256k1 keys, and their signatures are BIP-340 Schnorr
+// signatures
+//
+//	x(R) (32) || s (32)
+//
+// under the x-only group key, the 32-byte x coordinate of the group public
+// key. The threshold library's Taproot signing produces them in this
+// encoding, so signing runs it over the key's shares; where the group key
+// has an odd y, the shares are negated to share the even-y key with the
+// same x coordinate, as BIP-340 requires.
+
+// frostTaprootConfig returns config as a Taproot signing config
+func frostTaprootConfig(config *frost.Config) (*frost.TaprootConfig, error) {
+	group := config.PublicKey.Curve()
+	if !isSecp256k1(group) {
+		return nil, ErrInvalidKeyType
+	}
+	P, odd, err := xOnly(config.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	share := group.NewScalar().Set(config.PrivateShare)
+	if odd {
+		share = share.Negate()
+	}
+	privateShare, ok := share.(*curve.Secp256k1Scalar)
+	if !ok {
+		return nil, ErrInvalidKeyType
+	}
+	verifies := make(map[party.ID]*curve.Secp256k1Point, len(config.VerificationShares.Points))
+	for id, point := range config.VerificationShares.Points {
+		if odd {
+			point = point.Negate()
+		}
+		p, ok := point.(*curve.Secp256k1Point)
+		if !ok {
+			return nil, ErrInvalidKeyType
+		}
+		verifies[id] = p
+	}
+
+	return &frost.TaprootConfig{
+		ID:                 config.ID,
+		Threshold:          config.Threshold,
+		PrivateShare:       privateShare,
+		PublicKey:          taproot.PublicKey(P[:]),
+		VerificationShares: verifies,
+	}, nil
+}
+
+// VerifyFROSTSignature checks a BIP-340 signature on messageHash against
+// the group key public
+func VerifyFROSTSignature(public curve.Point, messageHash [32]byte, signature []byte) bool {
+	P, _, err := xOnly(public)
+	if err != nil {
+		return false
+	}
+	return VerifyTaprootSignature(P, messageHash, signature)
+}
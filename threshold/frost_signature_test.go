@@ -0,0 +1,60 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"testing"
+
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// TestFROSTSignatureEncoding tests that FROST signatures are BIP-340
+// signatures under the x-only group key and verify from their bytes
+func TestFROSTSignatureEncoding(t *testing.T) {
+	client := NewThresholdClient()
+	defer client.Close()
+
+	participants := []party.ID{"alice", "bob", "charlie"}
+	keygen, err := client.ExecuteKeygen(context.Background(), ProtocolFROST, KeyTypeSecp256k1, 1, participants, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteKeygen failed: %v", err)
+	}
+
+	messageHash := [32]byte{0x42}
+	result, err := client.ExecuteSigning(context.Background(), keygen.KeyID, ProtocolFROST, messageHash, []party.ID{"alice", "bob"}, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteSigning failed: %v", err)
+	}
+	if len(result.Signature) != schnorrSigSize {
+		t.Fatalf("Expected 64-byte BIP-340 signature, got %d bytes", len(result.Signature))
+	}
+
+	ok, err := client.VerifySignature(keygen.KeyID, ProtocolFROST, messageHash, result.Signature)
+	if err != nil || !ok {
+		t.Fatalf("Valid signature rejected: %v", err)
+	}
+	if ok, _ := client.VerifySignature(keygen.KeyID, ProtocolFROST, [32]byte{0x43}, result.Signature); ok {
+		t.Errorf("Signature accepted for another message")
+	}
+
+	tampered := append([]byte(nil), result.Signature...)
+	tampered[63] ^= 0x01
+	if ok, _ := client.VerifySignature(keygen.KeyID, ProtocolFROST, messageHash, tampered); ok {
+		t.Errorf("Signature with altered s accepted")
+	}
+	if ok, _ := client.VerifySignature(keygen.KeyID, ProtocolFROST, messageHash, result.Signature[:40]); ok {
+		t.Errorf("Truncated signature accepted")
+	}
+
+	// It is a plain BIP-340 signature under the group key's x coordinate
+	config := client.frostConfigs[keygen.KeyID]
+	P, _, err := xOnly(config.PublicKey)
+	if err != nil {
+		t.Fatalf("xOnly failed: %v", err)
+	}
+	if !VerifyTaprootSignature(P, messageHash, result.Signature) {
+		t.Errorf("Signature does not verify as BIP-340")
+	}
+}
@@ -0,0 +1,470 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/ecies"
+	"github.com/luxfi/threshold/pkg/math/curve"
+	"github.com/luxfi/threshold/pkg/math/polynomial"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+	"github.com/luxfi/threshold/protocols/cmp"
+	"github.com/luxfi/threshold/protocols/cmp/config"
+	"github.com/luxfi/threshold/protocols/frost"
+)
+
+// Key import and export.
+//
+// ImportKey moves an existing secp256k1 private key, such as a custody key,
+// into the threshold subsystem. A trusted dealer splits the key with a
+// random polynomial of degree threshold whose constant term is the key, and
+// the participants then re-split their dealt shares in a refresh session.
+// The refresh discards the polynomial the dealer chose and, for CGGMP21,
+// generates each party's Paillier and Pedersen parameters. The dealer still
+// knows the key itself, so the original copy must be destroyed once the
+// import is verified. The dealt shares never leave this process, so every
+// participant runs here and a client with a network transport refuses to
+// import.
+//
+// ExportConfig seals this party's share of a key to a recipient secp256k1
+// public key with ECIES, to move it to another machine, where ImportConfig
+// opens it. Through the manager, a share is only exported to a recipient a
+// shareholder quorum approved.
+
+// exportVersion is the version of the exported share format
+const exportVersion = 1
+
+// exportDomain is the ECIES shared info of exported shares
+const exportDomain = "lux.threshold.export.v1"
+
+// ImportKey splits privateKey, a 32-byte secp256k1 scalar, into shares of
+// participants for proto and stores the share of selfID
+func (c *ThresholdClient) ImportKey(
+	ctx context.Context,
+	proto Protocol,
+	privateKey []byte,
+	threshold int,
+	participants []party.ID,
+	selfID party.ID,
+) (*KeygenResult, error) {
+	if proto != ProtocolCGGMP21 && proto != ProtocolFROST {
+		return nil, ErrInvalidProtocol
+	}
+	if threshold < 1 || threshold >= len(participants) {
+		return nil, ErrInvalidThreshold
+	}
+	if !slices.Contains(participants, selfID) {
+		return nil, ErrNotParticipant
+	}
+
+	group := curve.Secp256k1{}
+	secret := group.NewScalar()
+	if len(privateKey) != 32 || secret.UnmarshalBinary(privateKey) != nil || secret.IsZero() {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.transport != nil {
+		return nil, ErrImportOverNetwork
+	}
+
+	publicKey := secret.ActOnBase()
+	pubBytes, err := publicKey.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	keyID := DeriveKeyID(proto, KeyTypeSecp256k1, threshold, participants, pubBytes)
+
+	// Deal a share to every participant
+	poly := polynomial.NewPolynomial(group, threshold, secret)
+	shares := make(map[party.ID]curve.Scalar, len(participants))
+	points := make(map[party.ID]curve.Point, len(participants))
+	for _, id := range participants {
+		shares[id] = poly.Evaluate(id.Scalar(group))
+		points[id] = shares[id].ActOnBase()
+	}
+
+	session := newSessionTag(sessionImport, proto, participants, keyID[:])
+	switch proto {
+	case ProtocolCGGMP21:
+		public := make(map[party.ID]*config.Public, len(participants))
+		for id, point := range points {
+			public[id] = &config.Public{ECDSA: point}
+		}
+		results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+			return cmp.Refresh(&cmp.Config{
+				Group:     group,
+				ID:        id,
+				Threshold: threshold,
+				ECDSA:     shares[id],
+				Public:    public,
+			}, c.pool)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("CMP import failed: %w", err)
+		}
+		ourConfig, ok := results[selfID].(*cmp.Config)
+		if !ok {
+			return nil, errors.New("config for self not found")
+		}
+		c.cmpConfigs[keyID] = ourConfig
+		c.discardPresignatures(keyID)
+
+	case ProtocolFROST:
+		results, err := c.runSession(ctx, session, participants, selfID, func(id party.ID) protocol.StartFunc {
+			return frost.Refresh(&frost.Config{
+				ID:                 id,
+				Threshold:          threshold,
+				PrivateShare:       shares[id],
+				PublicKey:          publicKey,
+				VerificationShares: party.NewPointMap(points),
+			}, participants)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("FROST import failed: %w", err)
+		}
+		ourConfig, ok := results[selfID].(*frost.Config)
+		if !ok {
+			return nil, errors.New("config for self not found")
+		}
+		c.frostConfigs[keyID] = ourConfig
+	}
+
+	return &KeygenResult{
+		KeyID:     keyID,
+		PublicKey: pubBytes,
+		Address:   deriveAddressFromPublicKey(pubBytes),
+	}, nil
+}
+
+// ExportConfig returns this party's share of keyID sealed to recipient, an
+// uncompressed secp256k1 public key
+func (c *ThresholdClient) ExportConfig(keyID [32]byte, proto Protocol, recipient []byte) ([]byte, error) {
+	c.mu.RLock()
+	plain, err := c.encodeShare(keyID, proto)
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(plain)
+
+	sealed, err := ecies.Encrypt(ecies.CurveSecp256k1, recipient, []byte(exportDomain), plain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecipient, err)
+	}
+	return sealed, nil
+}
+
+// ImportConfig opens a share sealed by ExportConfig with the recipient's
+// 32-byte private key and stores it
+func (c *ThresholdClient) ImportConfig(recipientKey []byte, sealed []byte) (*KeygenResult, error) {
+	plain, err := ecies.Decrypt(ecies.CurveSecp256k1, recipientKey, []byte(exportDomain), sealed)
+	if err != nil {
+		return nil, ErrInvalidExport
+	}
+	defer clear(plain)
+	return c.restoreShare(plain)
+}
+
+// encodeShare encodes this party's share of keyID as version || protocol ||
+// key ID || config. The caller holds c.mu.
+func (c *ThresholdClient) encodeShare(keyID [32]byte, proto Protocol) ([]byte, error) {
+	var encoded []byte
+	var err error
+	switch proto {
+	case ProtocolCGGMP21:
+		cfg, ok := c.cmpConfigs[keyID]
+		if !ok {
+			return nil, ErrKeyNotFound
+		}
+		encoded, err = cfg.MarshalBinary()
+	case ProtocolFROST:
+		cfg, ok := c.frostConfigs[keyID]
+		if !ok {
+			return nil, ErrKeyNotFound
+		}
+		encoded, err = marshalFROSTConfig(cfg)
+	default:
+		return nil, ErrInvalidProtocol
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize config: %w", err)
+	}
+	defer clear(encoded)
+
+	var buf bytes.Buffer
+	buf.WriteByte(exportVersion)
+	buf.WriteByte(byte(proto))
+	buf.Write(keyID[:])
+	writeField(&buf, encoded)
+	return buf.Bytes(), nil
+}
+
+// restoreShare decodes a share encoded by encodeShare, checks it against its
+// key ID and stores it
+func (c *ThresholdClient) restoreShare(plain []byte) (*KeygenResult, error) {
+	if len(plain) < 2+32 || plain[0] != exportVersion {
+		return nil, ErrInvalidExport
+	}
+	proto := Protocol(plain[1])
+	keyID := [32]byte(plain[2:34])
+	encoded, err := readField(bytes.NewReader(plain[34:]))
+	if err != nil {
+		return nil, ErrInvalidExport
+	}
+
+	var cmpConfig *cmp.Config
+	var frostConfig *frost.Config
+	var key *reshareKey
+	switch proto {
+	case ProtocolCGGMP21:
+		cmpConfig = cmp.EmptyConfig(curve.Secp256k1{})
+		if err := cmpConfig.UnmarshalBinary(encoded); err != nil {
+			return nil, ErrInvalidExport
+		}
+		key = cmpReshareKey(cmpConfig)
+	case ProtocolFROST:
+		frostConfig, err = unmarshalFROSTConfig(curve.Secp256k1{}, encoded)
+		if err != nil {
+			return nil, ErrInvalidExport
+		}
+		key = frostReshareKey(frostConfig)
+	default:
+		return nil, ErrInvalidExport
+	}
+
+	pubBytes, err := key.public.MarshalBinary()
+	if err != nil || key.keyID(proto, pubBytes) != keyID {
+		return nil, ErrInvalidExport
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cmpConfig != nil {
+		c.cmpConfigs[keyID] = cmpConfig
+		c.discardPresignatures(keyID)
+	} else {
+		c.frostConfigs[keyID] = frostConfig
+	}
+
+	return &KeygenResult{
+		KeyID:     keyID,
+		PublicKey: pubBytes,
+		Address:   deriveAddressFromPublicKey(pubBytes),
+	}, nil
+}
+
+// marshalFROSTConfig encodes a FROST config as id || threshold ||
+// private share || public key || chain key || parties, followed by the id
+// and verification share of each party, every field length-prefixed
+func marshalFROSTConfig(cfg *frost.Config) ([]byte, error) {
+	share, err := cfg.PrivateShare.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	public, err := cfg.PublicKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeField(&buf, []byte(cfg.ID))
+	writeField(&buf, binary.BigEndian.AppendUint32(nil, uint32(cfg.Threshold)))
+	writeField(&buf, share)
+	writeField(&buf, public)
+	writeField(&buf, cfg.ChainKey)
+
+	ids := make([]party.ID, 0, len(cfg.VerificationShares.Points))
+	for id := range cfg.VerificationShares.Points {
+		ids = append(ids, id)
+	}
+	writeField(&buf, binary.BigEndian.AppendUint32(nil, uint32(len(ids))))
+	for _, id := range sortedParties(ids) {
+		point, err := cfg.VerificationShares.Points[id].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		writeField(&buf, []byte(id))
+		writeField(&buf, point)
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalFROSTConfig decodes a config encoded by marshalFROSTConfig
+func unmarshalFROSTConfig(group curve.Curve, data []byte) (*frost.Config, error) {
+	r := bytes.NewReader(data)
+	var fields [6][]byte
+	for i := range fields {
+		field, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = field
+	}
+	if len(fields[1]) != 4 || len(fields[5]) != 4 {
+		return nil, ErrInvalidExport
+	}
+
+	cfg := &frost.Config{
+		ID:           party.ID(fields[0]),
+		Threshold:    int(binary.BigEndian.Uint32(fields[1])),
+		PrivateShare: group.NewScalar(),
+		PublicKey:    group.NewPoint(),
+		ChainKey:     fields[4],
+	}
+	if err := cfg.PrivateShare.UnmarshalBinary(fields[2]); err != nil {
+		return nil, err
+	}
+	if err := cfg.PublicKey.UnmarshalBinary(fields[3]); err != nil {
+		return nil, err
+	}
+
+	count := binary.BigEndian.Uint32(fields[5])
+	if count > MaxParties {
+		return nil, ErrInvalidExport
+	}
+	points := make(map[party.ID]curve.Point, count)
+	for i := uint32(0); i < count; i++ {
+		id, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		point := group.NewPoint()
+		if err := point.UnmarshalBinary(encoded); err != nil {
+			return nil, err
+		}
+		points[party.ID(id)] = point
+	}
+	if r.Len() != 0 {
+		return nil, ErrInvalidExport
+	}
+	cfg.VerificationShares = party.NewPointMap(points)
+	return cfg, nil
+}
+
+// ImportKey imports privateKey as a key owned by requester and split among
+// participants, the first of which is this node
+func (tm *ThresholdManager) ImportKey(
+	requester common.Address,
+	protocol Protocol,
+	privateKey []byte,
+	threshold uint32,
+	participants [][20]byte,
+) ([32]byte, error) {
+	if protocol != ProtocolCGGMP21 && protocol != ProtocolFROST {
+		return [32]byte{}, ErrInvalidProtocol
+	}
+	if err := validateKeygenParams(protocol, KeyTypeSecp256k1, threshold, uint32(len(participants))); err != nil {
+		return [32]byte{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tm.KeygenTimeout)
+	defer cancel()
+
+	ids := partyIDsFromAddresses(participants)
+	result, err := tm.client.ImportKey(ctx, protocol, privateKey, int(threshold), ids, ids[0])
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.Keys[result.KeyID] != nil {
+		return [32]byte{}, ErrKeyExists
+	}
+	request := &KeygenRequest{
+		Protocol:     protocol,
+		KeyType:      KeyTypeSecp256k1,
+		Threshold:    threshold,
+		TotalParties: uint32(len(participants)),
+		Requester:    requester,
+		RequestedAt:  uint64(time.Now().Unix()),
+		Participants: participants,
+	}
+	tm.completeKeygenInternal(request, result.KeyID, result.PublicKey, result.Address)
+	return result.KeyID, nil
+}
+
+// ApproveExport records a shareholder's approval to export this node's
+// share of keyID to recipient
+func (tm *ThresholdManager) ApproveExport(approver common.Address, keyID [32]byte, recipient []byte) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return ErrKeyNotFound
+	}
+	if !isShareholder(key, approver) {
+		return ErrNotShareholder
+	}
+
+	approvalID := exportApprovalID(keyID, recipient)
+	if !containsAddress(tm.ExportApprovals[approvalID], approver) {
+		tm.ExportApprovals[approvalID] = append(tm.ExportApprovals[approvalID], approver)
+	}
+	return nil
+}
+
+// ExportKeyShare seals this node's share of keyID to recipient. The owner
+// requests the export, and threshold+1 shareholders must have approved the
+// recipient. Approvals are spent by the export.
+func (tm *ThresholdManager) ExportKeyShare(requester common.Address, keyID [32]byte, recipient []byte) ([]byte, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return nil, ErrKeyNotFound
+	}
+	if key.Owner != requester {
+		return nil, ErrUnauthorized
+	}
+
+	approvalID := exportApprovalID(keyID, recipient)
+	count := uint32(0)
+	for _, approver := range tm.ExportApprovals[approvalID] {
+		if isShareholder(key, approver) {
+			count++
+		}
+	}
+	if count < key.Threshold+1 {
+		return nil, ErrExportNotApproved
+	}
+
+	sealed, err := tm.client.ExportConfig(keyID, key.Protocol, recipient)
+	if err != nil {
+		return nil, err
+	}
+	delete(tm.ExportApprovals, approvalID)
+	return sealed, nil
+}
+
+// exportApprovalID identifies approvals of exporting a key's share to one
+// recipient
+func exportApprovalID(keyID [32]byte, recipient []byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte(exportDomain))
+	h.Write(keyID[:])
+	h.Write(recipient)
+	return [32]byte(h.Sum(nil))
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// TestImportKey tests that an imported key keeps its address, signs, and
+// moves between clients as a sealed share
+func TestImportKey(t *testing.T) {
+	client := NewThresholdClient()
+	defer client.Close()
+
+	custody, err := luxcrypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	privateKey := luxcrypto.FromECDSA(custody)
+	participants := []party.ID{"alice", "bob", "charlie"}
+
+	if _, err := client.ImportKey(context.Background(), ProtocolFROST, make([]byte, 32), 1, participants, "alice"); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Errorf("Expected ErrInvalidPrivateKey for zero key, got %v", err)
+	}
+	if _, err := client.ImportKey(context.Background(), ProtocolRingtail, privateKey, 1, participants, "alice"); !errors.Is(err, ErrInvalidProtocol) {
+		t.Errorf("Expected ErrInvalidProtocol, got %v", err)
+	}
+
+	result, err := client.ImportKey(context.Background(), ProtocolFROST, privateKey, 1, participants, "alice")
+	if err != nil {
+		t.Fatalf("ImportKey failed: %v", err)
+	}
+	if result.Address != luxcrypto.PubkeyToAddress(custody.PublicKey) {
+		t.Errorf("Expected address %s, got %s", luxcrypto.PubkeyToAddress(custody.PublicKey), result.Address)
+	}
+	reordered := []party.ID{"charlie", "alice", "bob"}
+	if result.KeyID != DeriveKeyID(ProtocolFROST, KeyTypeSecp256k1, 1, reordered, result.PublicKey) {
+		t.Errorf("Key ID depends on participant order")
+	}
+
+	messageHash := [32]byte{0x42}
+	signed, err := client.ExecuteSigning(context.Background(), result.KeyID, ProtocolFROST, messageHash, []party.ID{"alice", "bob"}, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteSigning failed: %v", err)
+	}
+
+	// Move the share to a second client
+	recipient, _ := luxcrypto.GenerateKey()
+	sealed, err := client.ExportConfig(result.KeyID, ProtocolFROST, luxcrypto.FromECDSAPub(&recipient.PublicKey))
+	if err != nil {
+		t.Fatalf("ExportConfig failed: %v", err)
+	}
+
+	other := NewThresholdClient()
+	defer other.Close()
+	wrongKey, _ := luxcrypto.GenerateKey()
+	if _, err := other.ImportConfig(luxcrypto.FromECDSA(wrongKey), sealed); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("Expected ErrInvalidExport for wrong recipient, got %v", err)
+	}
+	imported, err := other.ImportConfig(luxcrypto.FromECDSA(recipient), sealed)
+	if err != nil {
+		t.Fatalf("ImportConfig failed: %v", err)
+	}
+	if imported.KeyID != result.KeyID || !bytes.Equal(imported.PublicKey, result.PublicKey) {
+		t.Errorf("Imported share belongs to another key")
+	}
+	ok, err := other.VerifySignature(result.KeyID, ProtocolFROST, messageHash, signed.Signature)
+	if err != nil || !ok {
+		t.Errorf("Imported share does not verify the key's signature: %v", err)
+	}
+}
+
+// TestExportApproval tests that a share is exported only to a recipient a
+// shareholder quorum approved
+func TestExportApproval(t *testing.T) {
+	tm := NewThresholdManager()
+	defer tm.Close()
+
+	owner := common.HexToAddress("0x1000")
+	participants := [][20]byte{
+		common.HexToAddress("0x2001"),
+		common.HexToAddress("0x2002"),
+		common.HexToAddress("0x2003"),
+	}
+	custody, _ := luxcrypto.GenerateKey()
+	keyID, err := tm.ImportKey(owner, ProtocolFROST, luxcrypto.FromECDSA(custody), 1, participants)
+	if err != nil {
+		t.Fatalf("ImportKey failed: %v", err)
+	}
+	if _, err := tm.ImportKey(owner, ProtocolFROST, luxcrypto.FromECDSA(custody), 1, participants); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("Expected ErrKeyExists for second import, got %v", err)
+	}
+
+	recipient, _ := luxcrypto.GenerateKey()
+	recipientPub := luxcrypto.FromECDSAPub(&recipient.PublicKey)
+
+	if err := tm.ApproveExport(owner, keyID, recipientPub); !errors.Is(err, ErrNotShareholder) {
+		t.Errorf("Expected ErrNotShareholder, got %v", err)
+	}
+	if err := tm.ApproveExport(participants[0], keyID, recipientPub); err != nil {
+		t.Fatalf("ApproveExport failed: %v", err)
+	}
+	if _, err := tm.ExportKeyShare(owner, keyID, recipientPub); !errors.Is(err, ErrExportNotApproved) {
+		t.Errorf("Expected ErrExportNotApproved with one approval, got %v", err)
+	}
+	if err := tm.ApproveExport(participants[1], keyID, recipientPub); err != nil {
+		t.Fatalf("ApproveExport failed: %v", err)
+	}
+	if _, err := tm.ExportKeyShare(participants[0], keyID, recipientPub); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized for non-owner, got %v", err)
+	}
+	if _, err := tm.ExportKeyShare(owner, keyID, recipientPub); err != nil {
+		t.Fatalf("ExportKeyShare failed: %v", err)
+	}
+	if _, err := tm.ExportKeyShare(owner, keyID, recipientPub); !errors.Is(err, ErrExportNotApproved) {
+		t.Errorf("Expected approvals spent by export, got %v", err)
+	}
+}
@@ -6,7 +6,7 @@ package threshold
 import (
 	"context"
 	"crypto/sha256"
-	"math/big"
+	"encoding/binary"
 	"sync"
 	"time"
 
@@ -14,8 +14,10 @@ import (
 	"github.com/luxfi/threshold/pkg/party"
 )
 
-// ThresholdManager provides the interface to T-Chain threshold operations
-// This is the main precompile at 0x0800 that coordinates threshold cryptography
+// ThresholdManager provides the interface to T-Chain threshold operations.
+// It runs on committee nodes, which serve the requests the threshold
+// precompiles record in EVM state and post the results back; its own state
+// and clock are node-local.
 type ThresholdManager struct {
 	// Key storage
 	Keys map[[32]byte]*ThresholdKey
@@ -26,6 +28,29 @@ type ThresholdManager struct {
 	RefreshRequests map[[32]byte]*RefreshRequest
 	ReshareRequests map[[32]byte]*ReshareRequest
 
+	// EIP-712 schema allowlist per key (keyID -> schemaID -> schema)
+	TypedDataSchemas map[[32]byte]map[[32]byte]*TypedDataSchema
+
+	// Usage policies, pending policy updates and transaction approvals
+	Policies        map[[32]byte]*KeyPolicy
+	PolicyProposals map[[32]byte]*PolicyProposal
+	TxApprovals     map[[32]byte][]common.Address
+	policyNonces    map[[32]byte]uint64
+
+	// Shareholder approvals of share exports (see keyimport.go)
+	ExportApprovals map[[32]byte][]common.Address
+
+	// Share generation each participant last presented per key (see
+	// refresh.go)
+	ShareGenerations map[[32]byte]map[common.Address]uint64
+
+	// Next signing request nonce per requester (see replay.go)
+	requestNonces map[common.Address]uint64
+
+	// Sequence number of the next request, so that request IDs are
+	// unique within a second
+	requestSeq uint64
+
 	// Real threshold client for executing MPC protocols
 	client *ThresholdClient
 
@@ -33,28 +58,43 @@ type ThresholdManager struct {
 	TChainEndpoint string
 
 	// Configuration
+	ChainID          uint64 // Bound into nonced signing payloads
 	DefaultThreshold uint32
 	SignTimeout      time.Duration
 	KeygenTimeout    time.Duration
 	MaxKeysPerOwner  int
 
+	// Chooses the signers of each request (see selector.go); nil signs
+	// with the first threshold+1 allowed signers
+	SignerSelector SignerSelector
+
 	mu sync.RWMutex
 }
 
 // NewThresholdManager creates a new threshold manager
 func NewThresholdManager() *ThresholdManager {
-	return &ThresholdManager{
+	tm := &ThresholdManager{
 		Keys:             make(map[[32]byte]*ThresholdKey),
 		KeygenRequests:   make(map[[32]byte]*KeygenRequest),
 		SignRequests:     make(map[[32]byte]*SigningRequest),
 		RefreshRequests:  make(map[[32]byte]*RefreshRequest),
 		ReshareRequests:  make(map[[32]byte]*ReshareRequest),
+		TypedDataSchemas: make(map[[32]byte]map[[32]byte]*TypedDataSchema),
+		Policies:         make(map[[32]byte]*KeyPolicy),
+		PolicyProposals:  make(map[[32]byte]*PolicyProposal),
+		TxApprovals:      make(map[[32]byte][]common.Address),
+		policyNonces:     make(map[[32]byte]uint64),
+		ExportApprovals:  make(map[[32]byte][]common.Address),
+		ShareGenerations: make(map[[32]byte]map[common.Address]uint64),
+		requestNonces:    make(map[common.Address]uint64),
 		client:           NewThresholdClient(),
 		DefaultThreshold: 2,
 		SignTimeout:      5 * time.Minute,
 		KeygenTimeout:    10 * time.Minute,
 		MaxKeysPerOwner:  100,
 	}
+	tm.client.SetSigningGuard(tm)
+	return tm
 }
 
 // Close cleans up resources
@@ -77,7 +117,7 @@ func (tm *ThresholdManager) RequestKeygen(
 	defer tm.mu.Unlock()
 
 	// Validate parameters
-	if err := tm.validateKeygenParams(protocol, keyType, threshold, totalParties); err != nil {
+	if err := validateKeygenParams(protocol, keyType, threshold, totalParties); err != nil {
 		return [32]byte{}, err
 	}
 
@@ -87,9 +127,7 @@ func (tm *ThresholdManager) RequestKeygen(
 
 	// Generate request ID
 	now := uint64(time.Now().Unix())
-	requestData := append(requester.Bytes(), byte(protocol), byte(keyType))
-	requestData = append(requestData, big.NewInt(int64(now)).Bytes()...)
-	requestID := sha256.Sum256(requestData)
+	requestID := tm.newRequestID(now, requester.Bytes(), []byte{byte(protocol), byte(keyType)})
 
 	request := &KeygenRequest{
 		RequestID:    requestID,
@@ -121,6 +159,25 @@ func (tm *ThresholdManager) RequestSignature(
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
+	// Keys restricted to typed data never blind-sign raw hashes
+	if key := tm.Keys[keyID]; key != nil && key.Permissions.TypedDataOnly {
+		return [32]byte{}, ErrBlindSigningDisabled
+	}
+
+	// Keys under a usage policy only sign decoded transactions
+	if tm.Policies[keyID] != nil {
+		return [32]byte{}, ErrTransactionRequired
+	}
+
+	return tm.requestSignature(requester, keyID, messageHash)
+}
+
+// requestSignature creates a signing request. Caller must hold tm.mu.
+func (tm *ThresholdManager) requestSignature(
+	requester common.Address,
+	keyID [32]byte,
+	messageHash [32]byte,
+) ([32]byte, error) {
 	// Get key and validate
 	key := tm.Keys[keyID]
 	if key == nil {
@@ -140,9 +197,14 @@ func (tm *ThresholdManager) RequestSignature(
 
 	// Generate request ID
 	now := uint64(time.Now().Unix())
-	requestData := append(keyID[:], messageHash[:]...)
-	requestData = append(requestData, big.NewInt(int64(now)).Bytes()...)
-	requestID := sha256.Sum256(requestData)
+	requestID := tm.newRequestID(now, keyID[:], messageHash[:])
+
+	// Choose the signers; those still holding shares from before a
+	// refresh cannot sign
+	signers, err := tm.selectSigners(key, requestID)
+	if err != nil {
+		return [32]byte{}, err
+	}
 
 	request := &SigningRequest{
 		RequestID:   requestID,
@@ -153,6 +215,7 @@ func (tm *ThresholdManager) RequestSignature(
 		ExpiresAt:   now + uint64(tm.SignTimeout.Seconds()),
 		Status:      SignStatusPending,
 		PartialSigs: make([][]byte, 0),
+		Signers:     signers,
 	}
 
 	tm.SignRequests[requestID] = request
@@ -177,6 +240,20 @@ func (tm *ThresholdManager) GetSignature(requestID [32]byte) ([]byte, SigningSta
 	return request.Signature, request.Status, nil
 }
 
+// GetKeygenResult returns the status of a keygen request and, once it is
+// complete, the generated key's ID
+func (tm *ThresholdManager) GetKeygenResult(requestID [32]byte) (KeygenStatus, [32]byte, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	request := tm.KeygenRequests[requestID]
+	if request == nil {
+		return 0, [32]byte{}, ErrRequestNotFound
+	}
+
+	return request.Status, request.ResultKeyID, nil
+}
+
 // RequestRefresh initiates key share refresh (proactive security)
 func (tm *ThresholdManager) RequestRefresh(
 	requester common.Address,
@@ -200,9 +277,7 @@ func (tm *ThresholdManager) RequestRefresh(
 
 	// Generate request ID
 	now := uint64(time.Now().Unix())
-	requestData := append(keyID[:], requester.Bytes()...)
-	requestData = append(requestData, big.NewInt(int64(now)).Bytes()...)
-	requestID := sha256.Sum256(requestData)
+	requestID := tm.newRequestID(now, keyID[:], requester.Bytes())
 
 	request := &RefreshRequest{
 		RequestID:   requestID,
@@ -250,9 +325,7 @@ func (tm *ThresholdManager) RequestReshare(
 
 	// Generate request ID
 	now := uint64(time.Now().Unix())
-	requestData := append(keyID[:], requester.Bytes()...)
-	requestData = append(requestData, big.NewInt(int64(now)).Bytes()...)
-	requestID := sha256.Sum256(requestData)
+	requestID := tm.newRequestID(now, keyID[:], requester.Bytes())
 
 	request := &ReshareRequest{
 		RequestID:    requestID,
@@ -420,7 +493,20 @@ func (tm *ThresholdManager) RemoveSigner(
 
 // Helper functions
 
-func (tm *ThresholdManager) validateKeygenParams(
+// newRequestID derives the ID of a request from its fields, the request
+// time and the manager's request sequence. Caller must hold tm.mu.
+func (tm *ThresholdManager) newRequestID(now uint64, fields ...[]byte) [32]byte {
+	h := sha256.New()
+	for _, f := range fields {
+		h.Write(f)
+	}
+	h.Write(binary.BigEndian.AppendUint64(nil, now))
+	h.Write(binary.BigEndian.AppendUint64(nil, tm.requestSeq))
+	tm.requestSeq++
+	return [32]byte(h.Sum(nil))
+}
+
+func validateKeygenParams(
 	protocol Protocol,
 	keyType KeyType,
 	threshold uint32,
@@ -537,19 +623,11 @@ func (tm *ThresholdManager) initiateSigning(request *SigningRequest, key *Thresh
 	ctx, cancel := context.WithTimeout(context.Background(), tm.SignTimeout)
 	defer cancel()
 
-	// Generate signer party IDs from key participants
-	// In production, this would come from the authorized signers list
-	signers := make([]party.ID, 0, key.Threshold+1)
-	for i, addr := range key.Permissions.AllowedSigners {
-		if uint32(i) >= key.Threshold+1 {
-			break
-		}
+	// Generate signer party IDs from the signers chosen for the request
+	signers := make([]party.ID, 0, len(request.Signers))
+	for _, addr := range request.Signers {
 		signers = append(signers, participantAddressToPartyID([20]byte(addr)))
 	}
-	// Add owner as signer if not enough
-	if len(signers) < int(key.Threshold)+1 {
-		signers = append(signers, participantAddressToPartyID([20]byte(key.Owner)))
-	}
 
 	selfID := signers[0]
 
@@ -565,6 +643,9 @@ func (tm *ThresholdManager) initiateSigning(request *SigningRequest, key *Thresh
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
+	if tm.SignerSelector != nil {
+		tm.SignerSelector.Report(request.KeyID, request.Signers, faultySigners(err))
+	}
 	if err != nil {
 		request.Status = SignStatusFailed
 		return
@@ -614,6 +695,7 @@ func (tm *ThresholdManager) initiateRefresh(request *RefreshRequest, key *Thresh
 
 	// Update key metadata
 	key.Generation++
+	tm.recordGenerations(key)
 	key.LastRefresh = uint64(time.Now().Unix())
 	key.Status = KeyStatusActive
 	request.Status = RefreshStatusComplete
@@ -650,6 +732,7 @@ func (tm *ThresholdManager) initiateReshare(request *ReshareRequest, key *Thresh
 	key.KeyID = newKeyID
 	key.Threshold = request.NewThreshold
 	key.TotalParties = uint32(len(request.NewParties))
+	key.Participants = request.NewParties
 	key.Generation++
 	key.LastRefresh = uint64(time.Now().Unix())
 	key.Status = KeyStatusActive
@@ -659,7 +742,17 @@ func (tm *ThresholdManager) initiateReshare(request *ReshareRequest, key *Thresh
 	if newKeyID != request.KeyID {
 		delete(tm.Keys, request.KeyID)
 		tm.Keys[newKeyID] = key
+
+		// The policy follows the key
+		if policy := tm.Policies[request.KeyID]; policy != nil {
+			delete(tm.Policies, request.KeyID)
+			tm.Policies[newKeyID] = policy
+		}
+		tm.policyNonces[newKeyID] = tm.policyNonces[request.KeyID]
+		delete(tm.policyNonces, request.KeyID)
 	}
+	delete(tm.ShareGenerations, request.KeyID)
+	tm.recordGenerations(key)
 }
 
 func (tm *ThresholdManager) verifyWithProtocol(
@@ -696,6 +789,7 @@ func (tm *ThresholdManager) completeKeygenInternal(
 		Address:      address,
 		Threshold:    request.Threshold,
 		TotalParties: request.TotalParties,
+		Participants: request.Participants,
 		Generation:   1,
 		CreatedAt:    now,
 		LastRefresh:  now,
@@ -711,6 +805,7 @@ func (tm *ThresholdManager) completeKeygenInternal(
 	}
 
 	tm.Keys[keyID] = key
+	tm.recordGenerations(key)
 	request.Status = KeygenStatusComplete
 	request.ResultKeyID = keyID
 }
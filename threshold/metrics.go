@@ -0,0 +1,296 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/luxfi/threshold/pkg/party"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Session metrics and tracing.
+//
+// A ThresholdClient reports every protocol session it runs to the Metrics
+// installed with SetMetrics: sessions started and finished with their
+// duration, rounds completed with their latency, messages sent and
+// received, and the parties an aborted session names as culprits or
+// unresponsive. Sessions are labeled with their kind, one of keygen, sign,
+// refresh, reshare, presign or import, and their protocol. Round latencies
+// are measured per party run here, so a client running every party in
+// process reports each round once per party. MemoryMetrics keeps the
+// measurements in memory for a monitoring system to poll.
+//
+// With a tracer installed by SetTracer, each session also records an
+// OpenTelemetry span named threshold.<kind>, a child of the span in the
+// caller's context, with an event per completed round and the faulty
+// parties of a failed session.
+
+// Metrics receives the measurements of the sessions a ThresholdClient runs.
+// Implementations must be safe for concurrent use and return quickly, as
+// they are called from the sessions' message loops.
+type Metrics interface {
+	// SessionStarted reports a session starting
+	SessionStarted(kind string, proto Protocol)
+	// SessionFinished reports a session ending after duration, with err
+	// nil on success
+	SessionFinished(kind string, proto Protocol, duration time.Duration, err error)
+	// RoundCompleted reports a party run here leaving round after latency
+	RoundCompleted(kind string, proto Protocol, round int, latency time.Duration)
+	// MessageSent reports a message sent by a party run here
+	MessageSent(kind string, proto Protocol, round int)
+	// MessageReceived reports a message accepted by a party run here
+	MessageReceived(kind string, proto Protocol, round int)
+	// PartyFailed reports a party named faulty by an aborted session
+	PartyFailed(kind string, proto Protocol, id party.ID)
+}
+
+// SetMetrics reports the sessions this client runs to m. A nil m stops
+// reporting.
+func (c *ThresholdClient) SetMetrics(m Metrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = m
+}
+
+// SetTracer records a span for each session this client runs with t. A nil
+// t stops tracing.
+func (c *ThresholdClient) SetTracer(t trace.Tracer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tracer = t
+}
+
+// sessionObserver reports one session to the client's Metrics and span. A
+// nil observer reports nothing.
+type sessionObserver struct {
+	metrics Metrics
+	span    trace.Span
+	kind    string
+	proto   Protocol
+	started time.Time
+}
+
+// observe starts reporting session, returning ctx with its span when a
+// tracer is installed
+func (c *ThresholdClient) observe(ctx context.Context, session sessionTag, parties []party.ID) (context.Context, *sessionObserver) {
+	if c.metrics == nil && c.tracer == nil {
+		return ctx, nil
+	}
+
+	o := &sessionObserver{
+		metrics: c.metrics,
+		kind:    session.kind,
+		proto:   session.proto,
+		started: time.Now(),
+	}
+	if c.tracer != nil {
+		ctx, o.span = c.tracer.Start(ctx, "threshold."+session.kind, trace.WithAttributes(
+			attribute.String("threshold.session", hex.EncodeToString(session.id[:])),
+			attribute.Int("threshold.protocol", int(session.proto)),
+			attribute.Int("threshold.parties", len(parties)),
+		))
+	}
+	if o.metrics != nil {
+		o.metrics.SessionStarted(o.kind, o.proto)
+	}
+	return ctx, o
+}
+
+func (o *sessionObserver) sent(round int) {
+	if o != nil && o.metrics != nil {
+		o.metrics.MessageSent(o.kind, o.proto, round)
+	}
+}
+
+func (o *sessionObserver) received(round int) {
+	if o != nil && o.metrics != nil {
+		o.metrics.MessageReceived(o.kind, o.proto, round)
+	}
+}
+
+func (o *sessionObserver) roundCompleted(round int, latency time.Duration) {
+	if o == nil {
+		return
+	}
+	if o.metrics != nil {
+		o.metrics.RoundCompleted(o.kind, o.proto, round, latency)
+	}
+	if o.span != nil {
+		o.span.AddEvent("round", trace.WithAttributes(
+			attribute.Int("threshold.round", round),
+			attribute.Int64("threshold.latency_ms", latency.Milliseconds()),
+		))
+	}
+}
+
+// finish reports the session ending with err and ends its span
+func (o *sessionObserver) finish(err error) {
+	if o == nil {
+		return
+	}
+
+	var faulty []party.ID
+	var report *AbortReport
+	if errors.As(err, &report) {
+		faulty = report.Faulty()
+	}
+
+	if o.metrics != nil {
+		o.metrics.SessionFinished(o.kind, o.proto, time.Since(o.started), err)
+		for _, id := range faulty {
+			o.metrics.PartyFailed(o.kind, o.proto, id)
+		}
+	}
+	if o.span != nil {
+		if err != nil {
+			o.span.RecordError(err)
+			o.span.SetStatus(codes.Error, err.Error())
+		}
+		if len(faulty) > 0 {
+			names := make([]string, len(faulty))
+			for i, id := range faulty {
+				names[i] = string(id)
+			}
+			o.span.SetAttributes(attribute.StringSlice("threshold.faulty", names))
+		}
+		o.span.End()
+	}
+}
+
+// DefaultLatencyBounds are the histogram bucket bounds MemoryMetrics uses
+var DefaultLatencyBounds = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	Bounds []time.Duration // Upper bound of each bucket
+	Counts []uint64        // Observations per bucket, the last above every bound
+	Sum    time.Duration   // Sum of all observations
+}
+
+func newHistogram(bounds []time.Duration) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i, _ := slices.BinarySearch(h.Bounds, d)
+	h.Counts[i]++
+	h.Sum += d
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	var n uint64
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+// MetricsSnapshot holds the measurements of MemoryMetrics
+type MetricsSnapshot struct {
+	InFlight         int                 // Sessions running
+	Sessions         map[string]uint64   // Sessions finished, by kind
+	Failures         map[string]uint64   // Sessions failed, by kind
+	PartyFailures    map[party.ID]uint64 // Aborted sessions that named each party faulty
+	Rounds           uint64              // Rounds completed
+	MessagesSent     uint64              // Messages sent
+	MessagesReceived uint64              // Messages accepted
+	SessionLatency   Histogram           // Duration of finished sessions
+	RoundLatency     Histogram           // Latency of completed rounds
+}
+
+// MemoryMetrics is a Metrics that keeps counters and latency histograms in
+// memory
+type MemoryMetrics struct {
+	stats MetricsSnapshot
+	mu    sync.Mutex
+}
+
+var _ Metrics = (*MemoryMetrics)(nil)
+
+// NewMemoryMetrics creates an empty MemoryMetrics with DefaultLatencyBounds
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{stats: MetricsSnapshot{
+		Sessions:       make(map[string]uint64),
+		Failures:       make(map[string]uint64),
+		PartyFailures:  make(map[party.ID]uint64),
+		SessionLatency: newHistogram(DefaultLatencyBounds),
+		RoundLatency:   newHistogram(DefaultLatencyBounds),
+	}}
+}
+
+// Snapshot returns a copy of the measurements so far
+func (m *MemoryMetrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.stats
+	s.Sessions = maps.Clone(s.Sessions)
+	s.Failures = maps.Clone(s.Failures)
+	s.PartyFailures = maps.Clone(s.PartyFailures)
+	s.SessionLatency.Counts = slices.Clone(s.SessionLatency.Counts)
+	s.RoundLatency.Counts = slices.Clone(s.RoundLatency.Counts)
+	return s
+}
+
+func (m *MemoryMetrics) SessionStarted(string, Protocol) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.InFlight++
+}
+
+func (m *MemoryMetrics) SessionFinished(kind string, _ Protocol, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.InFlight--
+	m.stats.Sessions[kind]++
+	if err != nil {
+		m.stats.Failures[kind]++
+	}
+	m.stats.SessionLatency.observe(duration)
+}
+
+func (m *MemoryMetrics) RoundCompleted(_ string, _ Protocol, _ int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Rounds++
+	m.stats.RoundLatency.observe(latency)
+}
+
+func (m *MemoryMetrics) MessageSent(string, Protocol, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.MessagesSent++
+}
+
+func (m *MemoryMetrics) MessageReceived(string, Protocol, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.MessagesReceived++
+}
+
+func (m *MemoryMetrics) PartyFailed(_ string, _ Protocol, id party.ID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.PartyFailures[id]++
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luxfi/threshold/pkg/party"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TestSessionMetrics tests that keygen and signing sessions are counted
+// with their rounds and messages
+func TestSessionMetrics(t *testing.T) {
+	client := NewThresholdClient()
+	defer client.Close()
+	metrics := NewMemoryMetrics()
+	client.SetMetrics(metrics)
+
+	participants := []party.ID{"alice", "bob", "charlie"}
+	keygen, err := client.ExecuteKeygen(context.Background(), ProtocolFROST, KeyTypeSecp256k1, 1, participants, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteKeygen failed: %v", err)
+	}
+	if _, err := client.ExecuteSigning(context.Background(), keygen.KeyID, ProtocolFROST, [32]byte{0x42}, []party.ID{"alice", "bob"}, "alice"); err != nil {
+		t.Fatalf("ExecuteSigning failed: %v", err)
+	}
+
+	stats := metrics.Snapshot()
+	if stats.Sessions[sessionKeygen] != 1 || stats.Sessions[sessionSign] != 1 {
+		t.Errorf("Expected one keygen and one sign session, got %v", stats.Sessions)
+	}
+	if len(stats.Failures) != 0 || stats.InFlight != 0 {
+		t.Errorf("Expected no failures or running sessions, got %v, %d", stats.Failures, stats.InFlight)
+	}
+	if stats.Rounds == 0 || stats.MessagesSent == 0 || stats.MessagesReceived == 0 {
+		t.Errorf("Expected rounds and messages counted, got %d rounds, %d sent, %d received", stats.Rounds, stats.MessagesSent, stats.MessagesReceived)
+	}
+	if stats.SessionLatency.Count() != 2 || stats.RoundLatency.Count() != stats.Rounds {
+		t.Errorf("Histogram counts do not match the sessions and rounds")
+	}
+}
+
+// TestSessionFailureMetrics tests that an aborted session counts as a
+// failure of its kind and of the unresponsive party
+func TestSessionFailureMetrics(t *testing.T) {
+	client, parties, start := newStalledSession(t)
+	client.SetRoundTimeout(100 * time.Millisecond)
+	metrics := NewMemoryMetrics()
+	client.SetMetrics(metrics)
+	client.SetTracer(noop.NewTracerProvider().Tracer("threshold"))
+
+	session := newSessionTag(sessionKeygen, ProtocolFROST, parties)
+	if _, err := client.runSession(context.Background(), session, parties, "a", start); err == nil {
+		t.Fatalf("Expected stalled session to fail")
+	}
+
+	stats := metrics.Snapshot()
+	if stats.Failures[sessionKeygen] != 1 {
+		t.Errorf("Expected one failed keygen, got %v", stats.Failures)
+	}
+	if stats.PartyFailures["b"] != 1 || stats.PartyFailures["a"] != 0 {
+		t.Errorf("Expected party b failed once, got %v", stats.PartyFailures)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+	"github.com/luxfi/precompile/modules"
+	"github.com/luxfi/precompile/precompileconfig"
+)
+
+var _ contract.Configurator = (*configurator)(nil)
+
+// Config keys of the threshold precompiles
+const (
+	FROSTConfigKey    = "thresholdFROSTConfig"
+	CGGMP21ConfigKey  = "thresholdCGGMP21Config"
+	RingtailConfigKey = "thresholdRingtailConfig"
+)
+
+// Precompile modules, one per protocol
+var (
+	FROSTModule = modules.Module{
+		ConfigKey:    FROSTConfigKey,
+		Address:      FROSTContractAddress,
+		Contract:     FROSTContract,
+		Configurator: &configurator{key: FROSTConfigKey, contract: FROSTContract},
+	}
+	CGGMP21Module = modules.Module{
+		ConfigKey:    CGGMP21ConfigKey,
+		Address:      CGGMP21ContractAddress,
+		Contract:     CGGMP21Contract,
+		Configurator: &configurator{key: CGGMP21ConfigKey, contract: CGGMP21Contract},
+	}
+	RingtailModule = modules.Module{
+		ConfigKey:    RingtailConfigKey,
+		Address:      RingtailContractAddress,
+		Contract:     RingtailContract,
+		Configurator: &configurator{key: RingtailConfigKey, contract: RingtailContract},
+	}
+)
+
+type configurator struct {
+	key      string
+	contract *ThresholdContract
+}
+
+func init() {
+	for _, m := range []modules.Module{FROSTModule, CGGMP21Module, RingtailModule} {
+		if err := modules.RegisterModule(m); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (c *configurator) MakeConfig() precompileconfig.Config {
+	return &Config{key: c.key}
+}
+
+// Configure sets the committee that attests the precompile's keygen
+// results
+func (c *configurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	config, ok := cfg.(*Config)
+	if !ok {
+		return fmt.Errorf("expected config type %T, got %T: %v", &Config{}, cfg, cfg)
+	}
+	return c.contract.SetCommittee(config.Committee, config.CommitteeThreshold)
+}
+
+// Config implements the precompileconfig.Config interface
+type Config struct {
+	Upgrade precompileconfig.Upgrade `json:"upgrade,omitempty"`
+
+	// Committee are the T-Chain signers that attest keygen results; without
+	// one, keygen requests are never fulfilled
+	Committee []common.Address `json:"committee,omitempty"`
+	// CommitteeThreshold is the number of committee signatures a keygen
+	// result needs
+	CommitteeThreshold int `json:"committeeThreshold,omitempty"`
+
+	key string
+}
+
+func (c *Config) Key() string {
+	if c.key == "" {
+		return CGGMP21ConfigKey
+	}
+	return c.key
+}
+
+func (c *Config) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *Config) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *Config) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*Config)
+	if !ok {
+		return false
+	}
+	return c.Key() == other.Key() && c.Upgrade.Equal(&other.Upgrade) &&
+		slices.Equal(c.Committee, other.Committee) && c.CommitteeThreshold == other.CommitteeThreshold
+}
+
+func (c *Config) Verify(chainConfig precompileconfig.ChainConfig) error {
+	if len(c.Committee) == 0 {
+		return nil
+	}
+	_, err := newCommittee(c.Committee, c.CommitteeThreshold)
+	return err
+}
@@ -0,0 +1,404 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/luxfi/geth/common"
+	ethtypes "github.com/luxfi/geth/core/types"
+)
+
+// Key usage policies
+//
+// A KeyPolicy restricts what a key signs. Once a key has a policy it only
+// signs EVM transactions: the raw transaction is decoded, its chain ID,
+// destination and value are checked against the policy, and the signature
+// is requested over the transaction's signing hash. Raw hashes and typed
+// data are refused, since their effect cannot be evaluated.
+//
+// Policies are installed, replaced and removed by a quorum of the key's
+// shareholders (threshold+1 distinct participants), never by the owner
+// alone: one shareholder proposes a policy and the others approve it.
+
+// DefaultValuePeriod is the value limit window when a policy sets none (1 day)
+const DefaultValuePeriod = 24 * 60 * 60
+
+// KeyPolicy defines what transactions a key may sign
+type KeyPolicy struct {
+	AllowedChainIDs     []uint64         // Chain IDs transactions may target (empty = any)
+	AllowedDestinations []common.Address // Transaction recipients (empty = any)
+	ValueLimit          *big.Int         // Max native value per period (nil = unlimited)
+	ValuePeriod         uint64           // Value limit window in seconds
+	RequiredApprovals   uint32           // Shareholder approvals needed per transaction
+	Nonce               uint64           // Policy version (increments on update)
+	SpentInPeriod       *big.Int         // Value signed in the current period
+	PeriodStart         uint64           // Start of the current period
+}
+
+// PolicyProposal is a pending policy update awaiting shareholder approval
+type PolicyProposal struct {
+	ProposalID [32]byte
+	KeyID      [32]byte
+	Policy     *KeyPolicy // Proposed policy (nil removes the policy)
+	BaseNonce  uint64     // Policy nonce the proposal applies to
+	Proposer   common.Address
+	Approvals  []common.Address
+}
+
+// ProposePolicy proposes a new policy for a key, or its removal when policy
+// is nil. The proposer must be a shareholder and counts as the first
+// approval.
+func (tm *ThresholdManager) ProposePolicy(
+	proposer common.Address,
+	keyID [32]byte,
+	policy *KeyPolicy,
+) ([32]byte, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return [32]byte{}, ErrKeyNotFound
+	}
+	if !isShareholder(key, proposer) {
+		return [32]byte{}, ErrNotShareholder
+	}
+
+	var proposed *KeyPolicy
+	if policy != nil {
+		proposed = &KeyPolicy{
+			AllowedChainIDs:     append([]uint64(nil), policy.AllowedChainIDs...),
+			AllowedDestinations: append([]common.Address(nil), policy.AllowedDestinations...),
+			ValuePeriod:         policy.ValuePeriod,
+			RequiredApprovals:   policy.RequiredApprovals,
+		}
+		if policy.ValueLimit != nil {
+			if policy.ValueLimit.Sign() < 0 {
+				return [32]byte{}, ErrInvalidPolicy
+			}
+			proposed.ValueLimit = new(big.Int).Set(policy.ValueLimit)
+		}
+		if proposed.ValuePeriod == 0 {
+			proposed.ValuePeriod = DefaultValuePeriod
+		}
+		if proposed.RequiredApprovals > key.TotalParties {
+			return [32]byte{}, ErrInvalidPolicy
+		}
+	}
+
+	baseNonce := tm.policyNonces[keyID]
+	proposalID := policyProposalID(keyID, proposed, baseNonce)
+	if tm.PolicyProposals[proposalID] == nil {
+		tm.PolicyProposals[proposalID] = &PolicyProposal{
+			ProposalID: proposalID,
+			KeyID:      keyID,
+			Policy:     proposed,
+			BaseNonce:  baseNonce,
+			Proposer:   proposer,
+		}
+	}
+
+	if _, err := tm.approvePolicy(key, tm.PolicyProposals[proposalID], proposer); err != nil {
+		return [32]byte{}, err
+	}
+	return proposalID, nil
+}
+
+// ApprovePolicy records a shareholder's approval of a policy proposal and
+// applies it once a quorum has approved. It reports whether the policy was
+// applied.
+func (tm *ThresholdManager) ApprovePolicy(approver common.Address, proposalID [32]byte) (bool, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	proposal := tm.PolicyProposals[proposalID]
+	if proposal == nil {
+		return false, ErrProposalNotFound
+	}
+	key := tm.Keys[proposal.KeyID]
+	if key == nil {
+		return false, ErrKeyNotFound
+	}
+	if !isShareholder(key, approver) {
+		return false, ErrNotShareholder
+	}
+
+	return tm.approvePolicy(key, proposal, approver)
+}
+
+// approvePolicy adds an approval and applies the proposal on quorum.
+// Caller must hold tm.mu.
+func (tm *ThresholdManager) approvePolicy(
+	key *ThresholdKey,
+	proposal *PolicyProposal,
+	approver common.Address,
+) (bool, error) {
+	// Another proposal was applied since this one was made
+	if proposal.BaseNonce != tm.policyNonces[proposal.KeyID] {
+		delete(tm.PolicyProposals, proposal.ProposalID)
+		return false, ErrStaleProposal
+	}
+
+	if !containsAddress(proposal.Approvals, approver) {
+		proposal.Approvals = append(proposal.Approvals, approver)
+	}
+	if uint32(len(proposal.Approvals)) < key.Threshold+1 {
+		return false, nil
+	}
+
+	if proposal.Policy == nil {
+		delete(tm.Policies, proposal.KeyID)
+	} else {
+		policy := *proposal.Policy
+		policy.Nonce = proposal.BaseNonce + 1
+		policy.SpentInPeriod = big.NewInt(0)
+		policy.PeriodStart = 0
+		tm.Policies[proposal.KeyID] = &policy
+	}
+	tm.policyNonces[proposal.KeyID] = proposal.BaseNonce + 1
+	delete(tm.PolicyProposals, proposal.ProposalID)
+	return true, nil
+}
+
+// AuthorizeSigning implements SigningGuard. Keys restricted to typed data or
+// bound to a usage policy only sign hashes of pending signing requests,
+// which the manager creates after checking the payload, the policy and its
+// approvals. Other keys, and keys the manager does not hold, sign any hash.
+func (tm *ThresholdManager) AuthorizeSigning(keyID [32]byte, messageHash [32]byte) error {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	key := tm.Keys[keyID]
+	if key == nil || (!key.Permissions.TypedDataOnly && tm.Policies[keyID] == nil) {
+		return nil
+	}
+	for _, request := range tm.SignRequests {
+		if request.KeyID == keyID && request.MessageHash == messageHash && request.Status == SignStatusPending {
+			return nil
+		}
+	}
+	if key.Permissions.TypedDataOnly {
+		return ErrBlindSigningDisabled
+	}
+	return ErrTransactionRequired
+}
+
+// GetPolicy returns the policy of a key, or nil if it has none
+func (tm *ThresholdManager) GetPolicy(keyID [32]byte) *KeyPolicy {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.Policies[keyID]
+}
+
+// ApproveTransaction records a shareholder's approval to sign the
+// transaction with the given signing hash
+func (tm *ThresholdManager) ApproveTransaction(
+	approver common.Address,
+	keyID [32]byte,
+	txHash [32]byte,
+) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return ErrKeyNotFound
+	}
+	if !isShareholder(key, approver) {
+		return ErrNotShareholder
+	}
+
+	approvalID := txApprovalID(keyID, txHash)
+	if !containsAddress(tm.TxApprovals[approvalID], approver) {
+		tm.TxApprovals[approvalID] = append(tm.TxApprovals[approvalID], approver)
+	}
+	return nil
+}
+
+// RequestTransactionSignature decodes an unsigned EVM transaction (EIP-155
+// legacy RLP or EIP-2718 typed envelope), evaluates it against the key's
+// policy and requests a threshold signature over its signing hash
+func (tm *ThresholdManager) RequestTransactionSignature(
+	requester common.Address,
+	keyID [32]byte,
+	rawTx []byte,
+) ([32]byte, [32]byte, error) {
+	tx, chainID, err := decodeTransaction(rawTx)
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+	txHash := ethtypes.LatestSignerForChainID(chainID).Hash(tx)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return [32]byte{}, [32]byte{}, ErrKeyNotFound
+	}
+	if err := tm.validateKeyForSigning(key, requester); err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+	if key.Permissions.TypedDataOnly {
+		return [32]byte{}, [32]byte{}, ErrBlindSigningDisabled
+	}
+
+	policy := tm.Policies[keyID]
+	approvalID := txApprovalID(keyID, txHash)
+	if policy != nil {
+		if err := tm.checkPolicy(key, policy, tx, chainID, tm.TxApprovals[approvalID]); err != nil {
+			return [32]byte{}, [32]byte{}, err
+		}
+	}
+
+	requestID, err := tm.requestSignature(requester, keyID, txHash)
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+
+	if policy != nil && policy.ValueLimit != nil {
+		policy.SpentInPeriod.Add(policy.SpentInPeriod, tx.Value())
+	}
+	delete(tm.TxApprovals, approvalID)
+	return requestID, txHash, nil
+}
+
+// checkPolicy evaluates a decoded transaction against a key's policy.
+// Caller must hold tm.mu.
+func (tm *ThresholdManager) checkPolicy(
+	key *ThresholdKey,
+	policy *KeyPolicy,
+	tx *ethtypes.Transaction,
+	chainID *big.Int,
+	approvals []common.Address,
+) error {
+	if len(policy.AllowedChainIDs) > 0 {
+		allowed := false
+		for _, id := range policy.AllowedChainIDs {
+			if chainID.IsUint64() && chainID.Uint64() == id {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrChainNotAllowed
+		}
+	}
+
+	// Contract creation has no recipient and never matches an allowlist
+	if len(policy.AllowedDestinations) > 0 {
+		if tx.To() == nil || !containsAddress(policy.AllowedDestinations, *tx.To()) {
+			return ErrDestinationDenied
+		}
+	}
+
+	if policy.ValueLimit != nil {
+		period := uint64(time.Now().Unix()) / policy.ValuePeriod * policy.ValuePeriod
+		if policy.PeriodStart < period {
+			policy.SpentInPeriod = big.NewInt(0)
+			policy.PeriodStart = period
+		}
+		spent := new(big.Int).Add(policy.SpentInPeriod, tx.Value())
+		if spent.Cmp(policy.ValueLimit) > 0 {
+			return ErrValueLimitExceeded
+		}
+	}
+
+	if policy.RequiredApprovals > 0 {
+		count := uint32(0)
+		for _, approver := range approvals {
+			if isShareholder(key, approver) {
+				count++
+			}
+		}
+		if count < policy.RequiredApprovals {
+			return ErrApprovalsRequired
+		}
+	}
+
+	return nil
+}
+
+// decodeTransaction decodes an unsigned transaction and its chain ID.
+// Unsigned legacy transactions use the EIP-155 signing form, which carries
+// the chain ID in V with empty R and S. Transactions without a chain ID
+// replay across chains and are rejected.
+func decodeTransaction(rawTx []byte) (*ethtypes.Transaction, *big.Int, error) {
+	tx := new(ethtypes.Transaction)
+	if err := tx.UnmarshalBinary(rawTx); err != nil {
+		return nil, nil, ErrInvalidTransaction
+	}
+
+	chainID := tx.ChainId()
+	if tx.Type() == ethtypes.LegacyTxType {
+		v, r, s := tx.RawSignatureValues()
+		if r.Sign() != 0 || s.Sign() != 0 {
+			return nil, nil, ErrInvalidTransaction
+		}
+		chainID = v
+	}
+	if chainID == nil || chainID.Sign() <= 0 {
+		return nil, nil, ErrInvalidTransaction
+	}
+	return tx, new(big.Int).Set(chainID), nil
+}
+
+// isShareholder reports whether addr holds a share of key
+func isShareholder(key *ThresholdKey, addr common.Address) bool {
+	for _, participant := range key.Participants {
+		if common.Address(participant) == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAddress(addrs []common.Address, addr common.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// policyProposalID commits to the key, the proposed policy and the nonce it
+// applies to, so identical proposals share approvals
+func policyProposalID(keyID [32]byte, policy *KeyPolicy, baseNonce uint64) [32]byte {
+	h := sha256.New()
+	h.Write(keyID[:])
+	h.Write(binary.BigEndian.AppendUint64(nil, baseNonce))
+	if policy == nil {
+		h.Write([]byte{0})
+		return [32]byte(h.Sum(nil))
+	}
+
+	h.Write([]byte{1})
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(policy.AllowedChainIDs))))
+	for _, id := range policy.AllowedChainIDs {
+		h.Write(binary.BigEndian.AppendUint64(nil, id))
+	}
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(policy.AllowedDestinations))))
+	for _, dest := range policy.AllowedDestinations {
+		h.Write(dest.Bytes())
+	}
+	if policy.ValueLimit != nil {
+		h.Write([]byte{1})
+		h.Write(common.BigToHash(policy.ValueLimit).Bytes())
+	} else {
+		h.Write([]byte{0})
+	}
+	h.Write(binary.BigEndian.AppendUint64(nil, policy.ValuePeriod))
+	h.Write(binary.BigEndian.AppendUint32(nil, policy.RequiredApprovals))
+	return [32]byte(h.Sum(nil))
+}
+
+// txApprovalID identifies approvals of one transaction for one key
+func txApprovalID(keyID [32]byte, txHash [32]byte) [32]byte {
+	return sha256.Sum256(append(keyID[:], txHash[:]...))
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+	ethtypes "github.com/luxfi/geth/core/types"
+)
+
+// shareholder returns the address of the i-th participant of setupTestKey
+func shareholder(i int) common.Address {
+	return common.Address([20]byte{byte(i + 1)})
+}
+
+func encodeTestTx(t *testing.T, chainID int64, to common.Address, value int64) []byte {
+	t.Helper()
+
+	raw, err := ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(chainID),
+		Gas:       21000,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		To:        &to,
+		Value:     big.NewInt(value),
+	}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	return raw
+}
+
+// installPolicy proposes a policy and approves it up to quorum
+func installPolicy(t *testing.T, tm *ThresholdManager, keyID [32]byte, policy *KeyPolicy) {
+	t.Helper()
+
+	proposalID, err := tm.ProposePolicy(shareholder(0), keyID, policy)
+	if err != nil {
+		t.Fatalf("ProposePolicy failed: %v", err)
+	}
+	if applied, err := tm.ApprovePolicy(shareholder(1), proposalID); err != nil || applied {
+		t.Fatalf("Expected pending proposal after 2 approvals, got applied=%v err=%v", applied, err)
+	}
+	if applied, err := tm.ApprovePolicy(shareholder(2), proposalID); err != nil || !applied {
+		t.Fatalf("Expected policy applied at quorum, got applied=%v err=%v", applied, err)
+	}
+}
+
+// TestPolicyQuorum tests that policies change only with shareholder quorum
+func TestPolicyQuorum(t *testing.T) {
+	tm := NewThresholdManager()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := setupTestKey(t, tm, owner)
+
+	// The owner alone holds no share
+	if _, err := tm.ProposePolicy(owner, keyID, &KeyPolicy{}); err != ErrNotShareholder {
+		t.Errorf("Expected ErrNotShareholder, got %v", err)
+	}
+
+	// Two competing proposals: applying one makes the other stale
+	first, _ := tm.ProposePolicy(shareholder(0), keyID, &KeyPolicy{AllowedChainIDs: []uint64{1}})
+	second, _ := tm.ProposePolicy(shareholder(3), keyID, &KeyPolicy{AllowedChainIDs: []uint64{96369}})
+	tm.ApprovePolicy(shareholder(1), first)
+	if applied, err := tm.ApprovePolicy(shareholder(1), first); err != nil || applied {
+		t.Errorf("Repeated approval should not count, got applied=%v err=%v", applied, err)
+	}
+	if applied, _ := tm.ApprovePolicy(shareholder(2), first); !applied {
+		t.Fatal("Expected first proposal applied")
+	}
+	if _, err := tm.ApprovePolicy(shareholder(4), second); err != ErrStaleProposal {
+		t.Errorf("Expected ErrStaleProposal, got %v", err)
+	}
+
+	policy := tm.GetPolicy(keyID)
+	if policy == nil || policy.Nonce != 1 || policy.ValuePeriod != DefaultValuePeriod {
+		t.Fatalf("Unexpected policy: %+v", policy)
+	}
+
+	// Raw hashes are refused once a policy is in place
+	if _, err := tm.RequestSignature(owner, keyID, [32]byte{0xDE, 0xAD}); err != ErrTransactionRequired {
+		t.Errorf("Expected ErrTransactionRequired, got %v", err)
+	}
+
+	// Removal also takes a quorum
+	installPolicy(t, tm, keyID, nil)
+	if tm.GetPolicy(keyID) != nil {
+		t.Error("Expected policy removed")
+	}
+}
+
+// TestPolicyEvaluation tests chain, destination, value and approval rules
+func TestPolicyEvaluation(t *testing.T) {
+	tm := NewThresholdManager()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := setupTestKey(t, tm, owner)
+	treasury := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	other := common.HexToAddress("0x8888888888888888888888888888888888888888")
+
+	installPolicy(t, tm, keyID, &KeyPolicy{
+		AllowedChainIDs:     []uint64{96369},
+		AllowedDestinations: []common.Address{treasury},
+		ValueLimit:          big.NewInt(1000),
+		RequiredApprovals:   1,
+	})
+
+	if _, _, err := tm.RequestTransactionSignature(owner, keyID, []byte{0x02, 0xFF}); err != ErrInvalidTransaction {
+		t.Errorf("Expected ErrInvalidTransaction, got %v", err)
+	}
+	if _, _, err := tm.RequestTransactionSignature(owner, keyID, encodeTestTx(t, 1, treasury, 1)); err != ErrChainNotAllowed {
+		t.Errorf("Expected ErrChainNotAllowed, got %v", err)
+	}
+	if _, _, err := tm.RequestTransactionSignature(owner, keyID, encodeTestTx(t, 96369, other, 1)); err != ErrDestinationDenied {
+		t.Errorf("Expected ErrDestinationDenied, got %v", err)
+	}
+
+	raw := encodeTestTx(t, 96369, treasury, 600)
+	if _, _, err := tm.RequestTransactionSignature(owner, keyID, raw); err != ErrApprovalsRequired {
+		t.Errorf("Expected ErrApprovalsRequired, got %v", err)
+	}
+
+	tx := new(ethtypes.Transaction)
+	_ = tx.UnmarshalBinary(raw)
+	txHash := ethtypes.LatestSignerForChainID(big.NewInt(96369)).Hash(tx)
+	if err := tm.ApproveTransaction(other, keyID, txHash); err != ErrNotShareholder {
+		t.Errorf("Expected ErrNotShareholder, got %v", err)
+	}
+	if err := tm.ApproveTransaction(shareholder(4), keyID, txHash); err != nil {
+		t.Fatalf("ApproveTransaction failed: %v", err)
+	}
+
+	requestID, signedHash, err := tm.RequestTransactionSignature(owner, keyID, raw)
+	if err != nil {
+		t.Fatalf("RequestTransactionSignature failed: %v", err)
+	}
+	if signedHash != txHash || tm.SignRequests[requestID].MessageHash != txHash {
+		t.Error("Signing request should carry the transaction signing hash")
+	}
+
+	// Approvals are consumed, and the period's remaining value is 400
+	if _, _, err := tm.RequestTransactionSignature(owner, keyID, raw); err != ErrApprovalsRequired {
+		t.Errorf("Expected approvals consumed, got %v", err)
+	}
+	raw = encodeTestTx(t, 96369, treasury, 500)
+	tx = new(ethtypes.Transaction)
+	_ = tx.UnmarshalBinary(raw)
+	_ = tm.ApproveTransaction(shareholder(4), keyID, ethtypes.LatestSignerForChainID(big.NewInt(96369)).Hash(tx))
+	if _, _, err := tm.RequestTransactionSignature(owner, keyID, raw); err != ErrValueLimitExceeded {
+		t.Errorf("Expected ErrValueLimitExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/luxfi/threshold/pkg/ecdsa"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+	"github.com/luxfi/threshold/protocols/cmp"
+)
+
+// CGGMP21 presignatures.
+//
+// CGGMP21 signing splits into a presigning phase, which does not depend on
+// the message and takes most of the rounds and all of the Paillier work,
+// and an online phase of one round. ExecutePresign runs the presigning
+// phase ahead of time, typically from a background worker, and stores the
+// presignatures in a pool per key. ExecuteSigning then takes the oldest
+// presignature for its signer set, if any, and completes in one round;
+// ConsumePresignature signs with a presignature the caller names, for
+// coordinators that assign presignatures to requests themselves.
+//
+// A presignature must never sign two messages, or the key leaks. It is
+// removed from the pool before its online round starts and is not returned
+// if the round fails. Presignatures are bound to the key shares they were
+// made from, so refreshing a key discards its pool. Every signer must run
+// the same sequence of ExecutePresign calls, since presigning session IDs
+// are numbered per key and signer set.
+
+// MaxPresignBatch bounds a single presigning call
+const MaxPresignBatch = 256
+
+// PresignatureID identifies a presignature; it is the presigning session ID
+type PresignatureID [32]byte
+
+// cmpPresignature is one presignature of a signer set, with the share of
+// every party run here
+type cmpPresignature struct {
+	id      PresignatureID
+	signers []party.ID
+	shares  map[party.ID]*ecdsa.PreSignature
+}
+
+// presignPool holds the unused presignatures of one key in creation order
+type presignPool struct {
+	ready []*cmpPresignature
+	next  map[string]uint64 // Next sequence number per signer set
+}
+
+// signerSetKey returns a map key for the sorted signer set
+func signerSetKey(signers []party.ID) string {
+	var key []byte
+	for _, s := range sortedParties(signers) {
+		key = binary.BigEndian.AppendUint32(key, uint32(len(s)))
+		key = append(key, s...)
+	}
+	return string(key)
+}
+
+// ExecutePresign runs count CGGMP21 presigning sessions for signers and
+// adds the presignatures to the key's pool. It returns the IDs of the
+// presignatures made, which are kept even if a later session fails.
+func (c *ThresholdClient) ExecutePresign(
+	ctx context.Context,
+	keyID [32]byte,
+	signers []party.ID,
+	selfID party.ID,
+	count int,
+) ([]PresignatureID, error) {
+	if count <= 0 || count > MaxPresignBatch {
+		return nil, ErrInvalidPresignBatch
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	config, ok := c.cmpConfigs[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	set := signerSetKey(signers)
+	ids := make([]PresignatureID, 0, count)
+	for i := 0; i < count; i++ {
+		c.presignMu.Lock()
+		pool := c.presignPool(keyID)
+		seq := pool.next[set]
+		pool.next[set]++
+		c.presignMu.Unlock()
+
+		session := newSessionTag(sessionPresign, ProtocolCGGMP21, signers, keyID[:], binary.BigEndian.AppendUint64(nil, seq))
+		results, err := c.runSession(ctx, session, signers, selfID, func(id party.ID) protocol.StartFunc {
+			return cmp.Presign(config, signers, c.pool)
+		})
+		if err != nil {
+			return ids, fmt.Errorf("CMP presign failed: %w", err)
+		}
+
+		presig := &cmpPresignature{
+			id:      session.id,
+			signers: sortedParties(signers),
+			shares:  make(map[party.ID]*ecdsa.PreSignature, len(results)),
+		}
+		for id, result := range results {
+			share, ok := result.(*ecdsa.PreSignature)
+			if !ok {
+				return ids, errors.New("no presignature generated")
+			}
+			presig.shares[id] = share
+		}
+
+		c.presignMu.Lock()
+		pool = c.presignPool(keyID)
+		pool.ready = append(pool.ready, presig)
+		c.presignMu.Unlock()
+		ids = append(ids, presig.id)
+	}
+
+	return ids, nil
+}
+
+// ConsumePresignature signs messageHash in one round with the presignature
+// id. The presignature is spent even if signing fails.
+func (c *ThresholdClient) ConsumePresignature(
+	ctx context.Context,
+	keyID [32]byte,
+	id PresignatureID,
+	messageHash [32]byte,
+	selfID party.ID,
+) (*SigningResult, error) {
+	if err := c.authorizeSigning(keyID, messageHash); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	presig, err := c.takePresignature(keyID, func(p *cmpPresignature) bool {
+		return p.id == id
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.executeCMPPresignedSign(ctx, keyID, presig, messageHash, selfID)
+}
+
+// PresignaturesRemaining returns how many unused presignatures a key holds
+// for signers
+func (c *ThresholdClient) PresignaturesRemaining(keyID [32]byte, signers []party.ID) int {
+	c.presignMu.Lock()
+	defer c.presignMu.Unlock()
+
+	pool, ok := c.presigs[keyID]
+	if !ok {
+		return 0
+	}
+	n := 0
+	for _, p := range pool.ready {
+		if slices.Equal(p.signers, sortedParties(signers)) {
+			n++
+		}
+	}
+	return n
+}
+
+// takePresignature removes and returns the oldest presignature of a key
+// that match accepts
+func (c *ThresholdClient) takePresignature(keyID [32]byte, match func(*cmpPresignature) bool) (*cmpPresignature, error) {
+	c.presignMu.Lock()
+	defer c.presignMu.Unlock()
+
+	pool, ok := c.presigs[keyID]
+	if !ok {
+		return nil, ErrNoPresignature
+	}
+	i := slices.IndexFunc(pool.ready, match)
+	if i < 0 {
+		return nil, ErrNoPresignature
+	}
+	presig := pool.ready[i]
+	pool.ready = slices.Delete(pool.ready, i, i+1)
+	return presig, nil
+}
+
+// takePresignatureFor removes and returns the oldest presignature of a key
+// for exactly signers, or nil if there is none
+func (c *ThresholdClient) takePresignatureFor(keyID [32]byte, signers []party.ID) *cmpPresignature {
+	sorted := sortedParties(signers)
+	presig, err := c.takePresignature(keyID, func(p *cmpPresignature) bool {
+		return slices.Equal(p.signers, sorted)
+	})
+	if err != nil {
+		return nil
+	}
+	return presig
+}
+
+// executeCMPPresignedSign runs the online round of CGGMP21 signing. Caller
+// must hold c.mu.
+func (c *ThresholdClient) executeCMPPresignedSign(
+	ctx context.Context,
+	keyID [32]byte,
+	presig *cmpPresignature,
+	messageHash [32]byte,
+	selfID party.ID,
+) (*SigningResult, error) {
+	config, ok := c.cmpConfigs[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	session := newSessionTag(sessionSign, ProtocolCGGMP21, presig.signers, keyID[:], messageHash[:], presig.id[:])
+	results, err := c.runSession(ctx, session, presig.signers, selfID, func(id party.ID) protocol.StartFunc {
+		return cmp.PresignOnline(config, presig.shares[id], messageHash[:], c.pool)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CMP presigned sign failed: %w", err)
+	}
+
+	sig, ok := results[selfID].(*ecdsa.Signature)
+	if !ok {
+		return nil, errors.New("no signature generated")
+	}
+	sigBytes, err := sig.SigEthereum()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize signature: %w", err)
+	}
+
+	return &SigningResult{
+		Signature: sigBytes,
+	}, nil
+}
+
+// presignPool returns the presignature pool of a key, creating it if
+// needed. Callers must hold c.presignMu.
+func (c *ThresholdClient) presignPool(keyID [32]byte) *presignPool {
+	pool, ok := c.presigs[keyID]
+	if !ok {
+		pool = &presignPool{next: make(map[string]uint64)}
+		c.presigs[keyID] = pool
+	}
+	return pool
+}
+
+// discardPresignatures drops the unused presignatures of a key. The
+// sequence numbers are kept so later sessions get fresh IDs.
+func (c *ThresholdClient) discardPresignatures(keyID [32]byte) {
+	c.presignMu.Lock()
+	defer c.presignMu.Unlock()
+
+	if pool, ok := c.presigs[keyID]; ok {
+		pool.ready = nil
+	}
+}
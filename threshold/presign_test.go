@@ -0,0 +1,58 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// TestPresignaturePool tests that presignatures are handed out once, oldest
+// first, per signer set, and dropped on refresh
+func TestPresignaturePool(t *testing.T) {
+	client := NewThresholdClient()
+	defer client.Close()
+
+	keyID := [32]byte{0x01}
+	ab := []party.ID{"a", "b"}
+	if _, err := client.ExecutePresign(context.Background(), keyID, ab, "a", 0); !errors.Is(err, ErrInvalidPresignBatch) {
+		t.Errorf("Expected ErrInvalidPresignBatch, got %v", err)
+	}
+	if _, err := client.ExecutePresign(context.Background(), keyID, ab, "a", 1); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	pool := client.presignPool(keyID)
+	pool.ready = []*cmpPresignature{
+		{id: PresignatureID{0x01}, signers: sortedParties(ab)},
+		{id: PresignatureID{0x02}, signers: sortedParties([]party.ID{"a", "c"})},
+		{id: PresignatureID{0x03}, signers: sortedParties(ab)},
+	}
+	if n := client.PresignaturesRemaining(keyID, []party.ID{"b", "a"}); n != 2 {
+		t.Errorf("Expected 2 presignatures for a and b, got %d", n)
+	}
+
+	if p := client.takePresignatureFor(keyID, []party.ID{"b", "a"}); p == nil || p.id != (PresignatureID{0x01}) {
+		t.Errorf("Expected oldest presignature for a and b, got %v", p)
+	}
+	if _, err := client.takePresignature(keyID, func(p *cmpPresignature) bool {
+		return p.id == PresignatureID{0x01}
+	}); !errors.Is(err, ErrNoPresignature) {
+		t.Errorf("Expected ErrNoPresignature for spent presignature, got %v", err)
+	}
+	if _, err := client.ConsumePresignature(context.Background(), keyID, PresignatureID{0x09}, [32]byte{}, "a"); !errors.Is(err, ErrNoPresignature) {
+		t.Errorf("Expected ErrNoPresignature for unknown presignature, got %v", err)
+	}
+
+	client.discardPresignatures(keyID)
+	if n := client.PresignaturesRemaining(keyID, ab); n != 0 {
+		t.Errorf("Expected no presignatures after discard, got %d", n)
+	}
+	if p := client.takePresignatureFor(keyID, []party.ID{"a", "c"}); p != nil {
+		t.Errorf("Expected no presignature after discard, got %v", p)
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luxfi/geth/common"
+)
+
+// Proactive refresh scheduling
+//
+// Key shares are refreshed once per epoch without operator action. Epochs
+// are counted in blocks, so every shareholder derives the same epoch from
+// the same block height. Within an epoch a key's refresh is offset by a
+// jitter drawn from sha256(RefreshJitterDomain || keyID || epoch), which
+// spreads keys across the epoch while keeping the offset identical on every
+// node. A key is due at
+//
+//	epoch*EpochBlocks + jitter(keyID, epoch)
+//
+// and is refreshed at most once per epoch. A key that is busy when it comes
+// due is retried on later blocks of the same epoch. Refresh request IDs
+// derive from the key and epoch, so shareholders agree on them too. Nodes
+// without a block feed use RunClock, which counts epochs in seconds.
+//
+// Every refresh and reshare advances the key's share generation. The
+// manager records the generation each participant presents, and refuses
+// signing requests whose signer set includes a participant still on an
+// older generation, such as a node restored from a backup taken before the
+// last refresh. Its shares no longer combine with the others', so the
+// session could only fail.
+
+// RefreshJitterDomain domain-separates the refresh jitter hash
+const RefreshJitterDomain = "LuxThresholdRefresh/v1"
+
+// MaxRefreshHistory bounds the refresh records kept per key
+const MaxRefreshHistory = 64
+
+// RefreshSchedule is the refresh cadence of a key
+type RefreshSchedule struct {
+	EpochBlocks  uint64 // Blocks per refresh epoch
+	JitterBlocks uint64 // Jitter window at the start of each epoch (< EpochBlocks)
+}
+
+// DefaultRefreshSchedule refreshes roughly daily at 2s blocks, with the
+// first half of the epoch as jitter window
+var DefaultRefreshSchedule = RefreshSchedule{
+	EpochBlocks:  43200,
+	JitterBlocks: 21600,
+}
+
+// Validate checks the schedule is usable
+func (s RefreshSchedule) Validate() error {
+	if s.EpochBlocks == 0 || s.JitterBlocks >= s.EpochBlocks {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+// Epoch returns the epoch containing height
+func (s RefreshSchedule) Epoch(height uint64) uint64 {
+	return height / s.EpochBlocks
+}
+
+// DueHeight returns the height at which keyID is refreshed in epoch
+func (s RefreshSchedule) DueHeight(keyID [32]byte, epoch uint64) uint64 {
+	due := epoch * s.EpochBlocks
+	if s.JitterBlocks == 0 {
+		return due
+	}
+	data := make([]byte, 0, len(RefreshJitterDomain)+40)
+	data = append(data, RefreshJitterDomain...)
+	data = append(data, keyID[:]...)
+	data = binary.BigEndian.AppendUint64(data, epoch)
+	h := sha256.Sum256(data)
+	return due + binary.BigEndian.Uint64(h[:8])%s.JitterBlocks
+}
+
+// RefreshRecord is one scheduled refresh of a key
+type RefreshRecord struct {
+	KeyID       [32]byte
+	RequestID   [32]byte
+	Epoch       uint64
+	BlockHeight uint64 // Height the refresh was triggered at
+	TriggeredAt uint64 // Unix timestamp
+	Generation  uint64 // Share generation the refresh produces
+	Status      RefreshStatus
+}
+
+// RefreshScheduler triggers proactive refreshes of the manager's keys
+type RefreshScheduler struct {
+	tm *ThresholdManager
+
+	// Default schedule, and per-key overrides (nil disables a key)
+	schedule  RefreshSchedule
+	overrides map[[32]byte]*RefreshSchedule
+
+	// Epoch after the last one each key was refreshed in (0 = never)
+	nextEpoch map[[32]byte]uint64
+
+	history map[[32]byte][]*RefreshRecord
+
+	// trigger starts the refresh of a key; replaced in tests
+	trigger func(keyID [32]byte, epoch uint64) ([32]byte, error)
+
+	mu sync.Mutex
+}
+
+// NewRefreshScheduler creates a scheduler applying schedule to every key of
+// tm
+func NewRefreshScheduler(tm *ThresholdManager, schedule RefreshSchedule) (*RefreshScheduler, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	s := &RefreshScheduler{
+		tm:        tm,
+		schedule:  schedule,
+		overrides: make(map[[32]byte]*RefreshSchedule),
+		nextEpoch: make(map[[32]byte]uint64),
+		history:   make(map[[32]byte][]*RefreshRecord),
+	}
+	s.trigger = tm.requestScheduledRefresh
+	return s, nil
+}
+
+// SetSchedule overrides the schedule of a key (owner only). A nil schedule
+// disables automatic refresh for the key.
+func (s *RefreshScheduler) SetSchedule(requester common.Address, keyID [32]byte, schedule *RefreshSchedule) error {
+	key, err := s.tm.GetKey(keyID)
+	if err != nil {
+		return err
+	}
+	if key.Owner != requester {
+		return ErrUnauthorized
+	}
+	if schedule != nil {
+		if err := schedule.Validate(); err != nil {
+			return err
+		}
+		sched := *schedule
+		schedule = &sched
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overrides[keyID] = schedule
+	return nil
+}
+
+// Schedule returns the schedule of a key, or nil if automatic refresh is
+// disabled for it
+func (s *RefreshScheduler) Schedule(keyID [32]byte) *RefreshSchedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule := s.scheduleOf(keyID)
+	if schedule == nil {
+		return nil
+	}
+	sched := *schedule
+	return &sched
+}
+
+// scheduleOf resolves a key's schedule. Caller must hold s.mu.
+func (s *RefreshScheduler) scheduleOf(keyID [32]byte) *RefreshSchedule {
+	if override, ok := s.overrides[keyID]; ok {
+		return override
+	}
+	return &s.schedule
+}
+
+// OnBlock triggers the refreshes due at height and returns the keys it
+// refreshed. The node calls it for every accepted block.
+func (s *RefreshScheduler) OnBlock(height uint64) [][32]byte {
+	s.tm.mu.RLock()
+	keys := make([][32]byte, 0, len(s.tm.Keys))
+	for keyID, key := range s.tm.Keys {
+		if key.Status == KeyStatusActive {
+			keys = append(keys, keyID)
+		}
+	}
+	s.tm.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var triggered [][32]byte
+	for _, keyID := range keys {
+		schedule := s.scheduleOf(keyID)
+		if schedule == nil {
+			continue
+		}
+		epoch := schedule.Epoch(height)
+		if epoch < s.nextEpoch[keyID] {
+			continue
+		}
+		if height < schedule.DueHeight(keyID, epoch) {
+			continue
+		}
+
+		var generation uint64
+		if key, err := s.tm.GetKey(keyID); err == nil {
+			generation = key.Generation + 1
+		}
+		requestID, err := s.trigger(keyID, epoch)
+		if err != nil {
+			// Busy or gone; retried on the next block of the epoch
+			continue
+		}
+		s.nextEpoch[keyID] = epoch + 1
+		s.record(&RefreshRecord{
+			KeyID:       keyID,
+			RequestID:   requestID,
+			Epoch:       epoch,
+			BlockHeight: height,
+			TriggeredAt: uint64(time.Now().Unix()),
+			Generation:  generation,
+		})
+		triggered = append(triggered, keyID)
+	}
+	return triggered
+}
+
+// Run calls OnBlock for each height received until ctx is done or heights
+// is closed
+func (s *RefreshScheduler) Run(ctx context.Context, heights <-chan uint64) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case height, ok := <-heights:
+			if !ok {
+				return
+			}
+			s.OnBlock(height)
+		}
+	}
+}
+
+// RunClock calls OnBlock with the Unix time in seconds every interval
+// until ctx is done, so that schedules count epochs in seconds
+func (s *RefreshScheduler) RunClock(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.OnBlock(uint64(now.Unix()))
+		}
+	}
+}
+
+// record appends to a key's history. Caller must hold s.mu.
+func (s *RefreshScheduler) record(rec *RefreshRecord) {
+	history := append(s.history[rec.KeyID], rec)
+	if len(history) > MaxRefreshHistory {
+		history = history[len(history)-MaxRefreshHistory:]
+	}
+	s.history[rec.KeyID] = history
+}
+
+// History returns the scheduled refreshes of a key, oldest first, with each
+// record's current request status
+func (s *RefreshScheduler) History(keyID [32]byte) []RefreshRecord {
+	s.mu.Lock()
+	records := make([]RefreshRecord, len(s.history[keyID]))
+	for i, rec := range s.history[keyID] {
+		records[i] = *rec
+	}
+	s.mu.Unlock()
+
+	s.tm.mu.RLock()
+	defer s.tm.mu.RUnlock()
+	for i := range records {
+		if request := s.tm.RefreshRequests[records[i].RequestID]; request != nil {
+			records[i].Status = request.Status
+		}
+	}
+	return records
+}
+
+// requestScheduledRefresh starts the refresh of a key for epoch. The
+// request ID is derived from the key and epoch so that every shareholder
+// computes the same one.
+func (tm *ThresholdManager) requestScheduledRefresh(keyID [32]byte, epoch uint64) ([32]byte, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return [32]byte{}, ErrKeyNotFound
+	}
+	if key.Status != KeyStatusActive {
+		return [32]byte{}, ErrKeyBusy
+	}
+
+	data := make([]byte, 0, len(RefreshJitterDomain)+41)
+	data = append(data, RefreshJitterDomain...)
+	data = append(data, 0x01)
+	data = append(data, keyID[:]...)
+	data = binary.BigEndian.AppendUint64(data, epoch)
+	requestID := sha256.Sum256(data)
+
+	request := &RefreshRequest{
+		RequestID:   requestID,
+		KeyID:       keyID,
+		RequestedAt: uint64(time.Now().Unix()),
+		Status:      RefreshStatusPending,
+	}
+	tm.RefreshRequests[requestID] = request
+	key.Status = KeyStatusRefreshing
+
+	go tm.initiateRefresh(request, key)
+
+	return requestID, nil
+}
+
+// ReportShareGeneration records the share generation a participant presents
+// for a key
+func (tm *ThresholdManager) ReportShareGeneration(participant common.Address, keyID [32]byte, generation uint64) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	key := tm.Keys[keyID]
+	if key == nil {
+		return ErrKeyNotFound
+	}
+	if !isShareholder(key, participant) {
+		return ErrNotShareholder
+	}
+	if generation == 0 || generation > key.Generation {
+		return ErrInvalidGeneration
+	}
+
+	if tm.ShareGenerations[keyID] == nil {
+		tm.ShareGenerations[keyID] = make(map[common.Address]uint64)
+	}
+	tm.ShareGenerations[keyID][participant] = generation
+	return nil
+}
+
+// ShareGeneration returns the share generation a participant last
+// presented for a key, or 0 if none is recorded
+func (tm *ThresholdManager) ShareGeneration(keyID [32]byte, participant common.Address) uint64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.ShareGenerations[keyID][participant]
+}
+
+// recordGenerations marks every participant of key as holding its current
+// generation, after a session they all took part in. Caller must hold
+// tm.mu.
+func (tm *ThresholdManager) recordGenerations(key *ThresholdKey) {
+	generations := make(map[common.Address]uint64, len(key.Participants))
+	for _, participant := range key.Participants {
+		generations[participant] = key.Generation
+	}
+	tm.ShareGenerations[key.KeyID] = generations
+}
+
+// hasStaleSigner reports whether a signer presented a generation older
+// than the key's. Signers with no recorded generation are not shareholders
+// and are not checked. Caller must hold tm.mu.
+func (tm *ThresholdManager) hasStaleSigner(key *ThresholdKey, signers []common.Address) bool {
+	for _, signer := range signers {
+		if generation, ok := tm.ShareGenerations[key.KeyID][signer]; ok && generation < key.Generation {
+			return true
+		}
+	}
+	return false
+}
+
+// signerSet returns the addresses that sign for key: the first threshold+1
+// allowed signers, topped up with the owner
+func signerSet(key *ThresholdKey) []common.Address {
+	signers := make([]common.Address, 0, key.Threshold+1)
+	for i, addr := range key.Permissions.AllowedSigners {
+		if uint32(i) >= key.Threshold+1 {
+			break
+		}
+		signers = append(signers, addr)
+	}
+	if len(signers) < int(key.Threshold)+1 {
+		signers = append(signers, key.Owner)
+	}
+	return signers
+}
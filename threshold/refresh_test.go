@@ -0,0 +1,175 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// TestRefreshScheduleJitter tests that due heights are deterministic and
+// fall inside the jitter window
+func TestRefreshScheduleJitter(t *testing.T) {
+	schedule := RefreshSchedule{EpochBlocks: 100, JitterBlocks: 40}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if err := (RefreshSchedule{EpochBlocks: 100, JitterBlocks: 100}).Validate(); err != ErrInvalidSchedule {
+		t.Errorf("Expected ErrInvalidSchedule, got %v", err)
+	}
+
+	spread := make(map[uint64]bool)
+	for i := 0; i < 16; i++ {
+		keyID := [32]byte{byte(i)}
+		due := schedule.DueHeight(keyID, 3)
+		if due < 300 || due >= 340 {
+			t.Errorf("Due height %d outside jitter window", due)
+		}
+		if due != schedule.DueHeight(keyID, 3) {
+			t.Error("Expected deterministic due height")
+		}
+		spread[due] = true
+	}
+	if len(spread) < 2 {
+		t.Error("Expected jitter to spread keys across the epoch")
+	}
+}
+
+// TestRefreshScheduler tests per-epoch triggering, retries and history
+func TestRefreshScheduler(t *testing.T) {
+	tm := NewThresholdManager()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := setupTestKey(t, tm, owner)
+
+	s, err := NewRefreshScheduler(tm, RefreshSchedule{EpochBlocks: 100, JitterBlocks: 50})
+	if err != nil {
+		t.Fatalf("NewRefreshScheduler failed: %v", err)
+	}
+	busy := false
+	var epochs []uint64
+	s.trigger = func(_ [32]byte, epoch uint64) ([32]byte, error) {
+		if busy {
+			return [32]byte{}, ErrKeyBusy
+		}
+		epochs = append(epochs, epoch)
+		return [32]byte{byte(epoch)}, nil
+	}
+
+	// Epoch 0 is due within its first 50 blocks
+	if got := s.OnBlock(99); len(got) != 1 || got[0] != keyID {
+		t.Fatalf("Expected refresh in epoch 0, got %v", got)
+	}
+
+	due := s.Schedule(keyID).DueHeight(keyID, 1)
+	if got := s.OnBlock(due - 1); len(got) != 0 {
+		t.Errorf("Expected no refresh before due height, got %d", len(got))
+	}
+	if got := s.OnBlock(due); len(got) != 1 || got[0] != keyID {
+		t.Fatalf("Expected refresh at due height, got %v", got)
+	}
+	if got := s.OnBlock(199); len(got) != 0 {
+		t.Error("Expected at most one refresh per epoch")
+	}
+
+	// A busy key is retried later in the epoch
+	busy = true
+	due = s.Schedule(keyID).DueHeight(keyID, 2)
+	if got := s.OnBlock(due); len(got) != 0 {
+		t.Error("Expected busy key to be skipped")
+	}
+	busy = false
+	if got := s.OnBlock(due + 1); len(got) != 1 {
+		t.Error("Expected busy key to be retried")
+	}
+
+	history := s.History(keyID)
+	if len(history) != 3 || history[1].Epoch != 1 || history[2].Epoch != 2 || history[2].BlockHeight != due+1 {
+		t.Errorf("Unexpected history: %+v", history)
+	}
+
+	// Owners can disable automatic refresh
+	if err := s.SetSchedule(common.Address{1}, keyID, nil); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if err := s.SetSchedule(owner, keyID, nil); err != nil {
+		t.Fatalf("SetSchedule failed: %v", err)
+	}
+	if got := s.OnBlock(399); len(got) != 0 || len(epochs) != 3 {
+		t.Error("Expected disabled key not to refresh")
+	}
+}
+
+// TestScheduledRefreshRequestID tests that shareholders derive the same
+// refresh request for a key and epoch
+func TestScheduledRefreshRequestID(t *testing.T) {
+	tm := NewThresholdManager()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := setupTestKey(t, tm, owner)
+
+	requestID, err := tm.requestScheduledRefresh(keyID, 7)
+	if err != nil {
+		t.Fatalf("requestScheduledRefresh failed: %v", err)
+	}
+
+	other := NewThresholdManager()
+	other.Keys[keyID] = &ThresholdKey{KeyID: keyID, TotalParties: 1, Owner: owner, Status: KeyStatusActive}
+	otherID, err := other.requestScheduledRefresh(keyID, 7)
+	if err != nil {
+		t.Fatalf("requestScheduledRefresh failed: %v", err)
+	}
+	if otherID != requestID {
+		t.Error("Expected the same request ID on every shareholder")
+	}
+}
+
+// TestShareGeneration tests that signers on a generation older than the
+// key's are refused until they present the current one
+func TestShareGeneration(t *testing.T) {
+	tm := NewThresholdManager()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := setupTestKey(t, tm, owner)
+
+	key, _ := tm.GetKey(keyID)
+	signers := make([]common.Address, 3)
+	for i := range signers {
+		signers[i] = common.Address(key.Participants[i])
+		if g := tm.ShareGeneration(keyID, signers[i]); g != 1 {
+			t.Errorf("Expected generation 1 after keygen, got %d", g)
+		}
+	}
+	key.Permissions.AllowedSigners = signers
+
+	// A refresh advanced the key but only two signers took part
+	s, _ := NewRefreshScheduler(tm, RefreshSchedule{EpochBlocks: 100})
+	s.trigger = func(_ [32]byte, epoch uint64) ([32]byte, error) {
+		return [32]byte{byte(epoch)}, nil
+	}
+	s.OnBlock(0)
+	if history := s.History(keyID); len(history) != 1 || history[0].Generation != 2 {
+		t.Errorf("Expected refresh to generation 2 in history, got %+v", history)
+	}
+	key.Generation = 2
+	for _, signer := range signers[1:] {
+		if err := tm.ReportShareGeneration(signer, keyID, 2); err != nil {
+			t.Fatalf("ReportShareGeneration failed: %v", err)
+		}
+	}
+
+	if _, err := tm.RequestSignature(owner, keyID, [32]byte{0x01}); err != ErrStaleShareGeneration {
+		t.Errorf("Expected ErrStaleShareGeneration, got %v", err)
+	}
+	if err := tm.ReportShareGeneration(signers[0], keyID, 3); err != ErrInvalidGeneration {
+		t.Errorf("Expected ErrInvalidGeneration, got %v", err)
+	}
+	if err := tm.ReportShareGeneration(owner, keyID, 2); err != ErrNotShareholder {
+		t.Errorf("Expected ErrNotShareholder, got %v", err)
+	}
+	if err := tm.ReportShareGeneration(signers[0], keyID, 2); err != nil {
+		t.Fatalf("ReportShareGeneration failed: %v", err)
+	}
+	if _, err := tm.RequestSignature(owner, keyID, [32]byte{0x01}); err != nil {
+		t.Errorf("RequestSignature failed after catching up: %v", err)
+	}
+}
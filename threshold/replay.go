@@ -0,0 +1,108 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"math/big"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+)
+
+// Replay protection for contract signing requests
+//
+// A contract that asks the committee to sign a hash cannot tell whether a
+// signature it later sees was produced for this request or for an earlier,
+// identical one. Nonced requests close that gap: every requester has a
+// monotonic nonce, a request must carry the current nonce, and the
+// committee signs a payload that binds the nonce to the chain, key,
+// requester and message:
+//
+//	keccak256(abi.encode(SigningDomainTag, chainId, keyId, requester, nonce, messageHash))
+//
+// The nonce is consumed only when the request is accepted, so a replayed
+// request fails instead of producing a second signature over stale data.
+
+// SigningDomain names the domain of nonced signing payloads
+const SigningDomain = "LuxThresholdSign/v1"
+
+var (
+	// SigningDomainTag is keccak256(SigningDomain)
+	SigningDomainTag = common.BytesToHash(luxcrypto.Keccak256([]byte(SigningDomain)))
+
+	// SelectorGetRequestNonce is the selector of getRequestNonce(address)
+	SelectorGetRequestNonce = [4]byte(luxcrypto.Keccak256([]byte("getRequestNonce(address)"))[:4])
+)
+
+// NoncedSigningPayload returns the hash the committee signs for a nonced
+// request
+func NoncedSigningPayload(
+	chainID uint64,
+	keyID [32]byte,
+	requester common.Address,
+	nonce uint64,
+	messageHash [32]byte,
+) [32]byte {
+	encoded := make([]byte, 0, 6*32)
+	encoded = append(encoded, SigningDomainTag.Bytes()...)
+	encoded = append(encoded, common.BigToHash(new(big.Int).SetUint64(chainID)).Bytes()...)
+	encoded = append(encoded, keyID[:]...)
+	encoded = append(encoded, common.BytesToHash(requester.Bytes()).Bytes()...)
+	encoded = append(encoded, common.BigToHash(new(big.Int).SetUint64(nonce)).Bytes()...)
+	encoded = append(encoded, messageHash[:]...)
+	return common.BytesToHash(luxcrypto.Keccak256(encoded))
+}
+
+// RequestNoncedSignature requests a threshold signature over the nonced
+// payload of messageHash. nonce must equal the requester's current nonce,
+// which advances once the request is accepted. Returns the request ID and
+// the signed payload.
+func (tm *ThresholdManager) RequestNoncedSignature(
+	requester common.Address,
+	keyID [32]byte,
+	messageHash [32]byte,
+	nonce uint64,
+) ([32]byte, [32]byte, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if nonce != tm.requestNonces[requester] {
+		return [32]byte{}, [32]byte{}, ErrInvalidRequestNonce
+	}
+
+	// Same restrictions as raw hash requests
+	if key := tm.Keys[keyID]; key != nil && key.Permissions.TypedDataOnly {
+		return [32]byte{}, [32]byte{}, ErrBlindSigningDisabled
+	}
+	if tm.Policies[keyID] != nil {
+		return [32]byte{}, [32]byte{}, ErrTransactionRequired
+	}
+
+	payload := NoncedSigningPayload(tm.ChainID, keyID, requester, nonce, messageHash)
+	requestID, err := tm.requestSignature(requester, keyID, payload)
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+	tm.requestNonces[requester] = nonce + 1
+	return requestID, payload, nil
+}
+
+// GetRequestNonce returns the nonce the requester's next signing request
+// must carry
+func (tm *ThresholdManager) GetRequestNonce(requester common.Address) uint64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.requestNonces[requester]
+}
+
+// QueryRequestNonce answers getRequestNonce(address) calldata with the
+// requester's current nonce as a uint256 word
+func (tm *ThresholdManager) QueryRequestNonce(input []byte) ([]byte, error) {
+	if len(input) != 4+32 || [4]byte(input[:4]) != SelectorGetRequestNonce {
+		return nil, ErrInvalidNonceQuery
+	}
+	requester := common.BytesToAddress(input[4:36])
+	nonce := tm.GetRequestNonce(requester)
+	return common.BigToHash(new(big.Int).SetUint64(nonce)).Bytes(), nil
+}
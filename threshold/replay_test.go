@@ -0,0 +1,87 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+// TestRequestNoncedSignature tests nonce enforcement and payload binding
+func TestRequestNoncedSignature(t *testing.T) {
+	tm := NewThresholdManager()
+	tm.ChainID = 96369
+	requester := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := setupTestKey(t, tm, requester)
+	messageHash := [32]byte{0xDE, 0xAD, 0xBE, 0xEF}
+
+	if _, _, err := tm.RequestNoncedSignature(requester, keyID, messageHash, 1); err != ErrInvalidRequestNonce {
+		t.Errorf("Expected ErrInvalidRequestNonce for future nonce, got %v", err)
+	}
+
+	requestID, payload, err := tm.RequestNoncedSignature(requester, keyID, messageHash, 0)
+	if err != nil {
+		t.Fatalf("RequestNoncedSignature failed: %v", err)
+	}
+	if payload != NoncedSigningPayload(96369, keyID, requester, 0, messageHash) {
+		t.Error("Expected payload bound to chain, key, requester and nonce")
+	}
+	if tm.SignRequests[requestID].MessageHash != payload {
+		t.Error("Expected committee to sign the nonced payload")
+	}
+	if nonce := tm.GetRequestNonce(requester); nonce != 1 {
+		t.Errorf("Expected nonce 1, got %d", nonce)
+	}
+
+	// Replaying the accepted request is refused
+	if _, _, err := tm.RequestNoncedSignature(requester, keyID, messageHash, 0); err != ErrInvalidRequestNonce {
+		t.Errorf("Expected ErrInvalidRequestNonce for replay, got %v", err)
+	}
+
+	// The same message at the next nonce signs a different payload
+	_, next, err := tm.RequestNoncedSignature(requester, keyID, messageHash, 1)
+	if err != nil {
+		t.Fatalf("RequestNoncedSignature failed: %v", err)
+	}
+	if next == payload {
+		t.Error("Expected distinct payloads for distinct nonces")
+	}
+	if NoncedSigningPayload(1, keyID, requester, 0, messageHash) == payload {
+		t.Error("Expected payload to depend on the chain ID")
+	}
+
+	// A rejected request does not consume the nonce
+	other := common.HexToAddress("0xABCDABCDABCDABCDABCDABCDABCDABCDABCDABCD")
+	if _, _, err := tm.RequestNoncedSignature(other, keyID, messageHash, 0); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if nonce := tm.GetRequestNonce(other); nonce != 0 {
+		t.Errorf("Expected nonce 0 after rejected request, got %d", nonce)
+	}
+}
+
+// TestQueryRequestNonce tests the getRequestNonce(address) query
+func TestQueryRequestNonce(t *testing.T) {
+	tm := NewThresholdManager()
+	requester := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := setupTestKey(t, tm, requester)
+
+	if _, _, err := tm.RequestNoncedSignature(requester, keyID, [32]byte{1}, 0); err != nil {
+		t.Fatalf("RequestNoncedSignature failed: %v", err)
+	}
+
+	input := append(SelectorGetRequestNonce[:], common.BytesToHash(requester.Bytes()).Bytes()...)
+	out, err := tm.QueryRequestNonce(input)
+	if err != nil {
+		t.Fatalf("QueryRequestNonce failed: %v", err)
+	}
+	if len(out) != 32 || out[31] != 1 {
+		t.Errorf("Expected nonce word 1, got %x", out)
+	}
+
+	if _, err := tm.QueryRequestNonce(input[:20]); err != ErrInvalidNonceQuery {
+		t.Errorf("Expected ErrInvalidNonceQuery, got %v", err)
+	}
+}
@@ -0,0 +1,499 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/luxfi/threshold/pkg/math/curve"
+	"github.com/luxfi/threshold/pkg/math/polynomial"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+	"github.com/luxfi/threshold/protocols/cmp"
+	"github.com/luxfi/threshold/protocols/cmp/config"
+	"github.com/luxfi/threshold/protocols/frost"
+)
+
+// CGGMP21 and FROST resharing.
+//
+// ExecuteReshare moves a CGGMP21 or FROST key to a new participant set and
+// threshold without changing its public key, so the key's address survives
+// a validator-set rotation. Every shareholder of the key deals: it weights
+// its share by its Lagrange coefficient over the old set, splits the result
+// with a random polynomial of degree newThreshold and sends each new
+// participant one evaluation, with Feldman commitments to the polynomial.
+// A new participant checks each sub-share against its commitments and the
+// commitments against the dealer's verification share, then sums the
+// sub-shares into its new share. The commitments give every new party's
+// verification share, and the new participants finish with a refresh
+// session, which for CGGMP21 also generates their Paillier and Pedersen
+// parameters. The refresh fails when the parties disagree on the public
+// data, so a dealer that sends different commitments to different
+// participants aborts the reshare instead of splitting the key. The key
+// ID, derived from the participants and threshold, changes with them.
+//
+// New participants need not hold a share: the dealers send the key's public
+// key, threshold and old verification shares, which must derive the key ID
+// being reshared. Dealing needs each shareholder's own share, so the reshare runs over a
+// network transport with every shareholder and new participant on its own
+// node, all making the same call. Shareholders that leave the set delete
+// their share once they have dealt it.
+
+// reshareKey is the public data of a key being reshared and, on a
+// shareholder, its share
+type reshareKey struct {
+	threshold int
+	share     curve.Scalar
+	public    curve.Point
+	shares    map[party.ID]curve.Point
+}
+
+func cmpReshareKey(cfg *cmp.Config) *reshareKey {
+	shares := make(map[party.ID]curve.Point, len(cfg.Public))
+	for id, public := range cfg.Public {
+		shares[id] = public.ECDSA
+	}
+	return &reshareKey{
+		threshold: cfg.Threshold,
+		share:     cfg.ECDSA,
+		public:    cfg.PublicPoint(),
+		shares:    shares,
+	}
+}
+
+func frostReshareKey(cfg *frost.Config) *reshareKey {
+	return &reshareKey{
+		threshold: cfg.Threshold,
+		share:     cfg.PrivateShare,
+		public:    cfg.PublicKey,
+		shares:    cfg.VerificationShares.Points,
+	}
+}
+
+// keyID returns the canonical ID of k under proto, pubBytes being its
+// encoded public key
+func (k *reshareKey) keyID(proto Protocol, pubBytes []byte) [32]byte {
+	return DeriveKeyID(proto, KeyTypeSecp256k1, k.threshold, k.parties(), pubBytes)
+}
+
+// parties returns the shareholders of k in order
+func (k *reshareKey) parties() []party.ID {
+	ids := make([]party.ID, 0, len(k.shares))
+	for id := range k.shares {
+		ids = append(ids, id)
+	}
+	return sortedParties(ids)
+}
+
+// marshalPublic encodes the public data of k as threshold || public key ||
+// parties, followed by the id and verification share of each party, every
+// field length-prefixed
+func (k *reshareKey) marshalPublic() ([]byte, error) {
+	public, err := k.public.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeField(&buf, binary.BigEndian.AppendUint32(nil, uint32(k.threshold)))
+	writeField(&buf, public)
+	writeField(&buf, binary.BigEndian.AppendUint32(nil, uint32(len(k.shares))))
+	for _, id := range k.parties() {
+		point, err := k.shares[id].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		writeField(&buf, []byte(id))
+		writeField(&buf, point)
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalReshareKey decodes public data encoded by marshalPublic
+func unmarshalReshareKey(group curve.Curve, data []byte) (*reshareKey, error) {
+	r := bytes.NewReader(data)
+	var fields [3][]byte
+	for i := range fields {
+		field, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = field
+	}
+	if len(fields[0]) != 4 || len(fields[2]) != 4 {
+		return nil, ErrBadSubShare
+	}
+
+	k := &reshareKey{
+		threshold: int(binary.BigEndian.Uint32(fields[0])),
+		public:    group.NewPoint(),
+	}
+	if err := k.public.UnmarshalBinary(fields[1]); err != nil {
+		return nil, err
+	}
+
+	count := binary.BigEndian.Uint32(fields[2])
+	if count > MaxParties || k.threshold < 1 || uint32(k.threshold) >= count {
+		return nil, ErrBadSubShare
+	}
+	k.shares = make(map[party.ID]curve.Point, count)
+	for i := uint32(0); i < count; i++ {
+		id, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		point := group.NewPoint()
+		if err := point.UnmarshalBinary(encoded); err != nil {
+			return nil, err
+		}
+		k.shares[party.ID(id)] = point
+	}
+	if r.Len() != 0 || len(k.shares) != int(count) {
+		return nil, ErrBadSubShare
+	}
+	return k, nil
+}
+
+// executeDealerReshare reshares a CGGMP21 or FROST key; the caller holds c.mu
+func (c *ThresholdClient) executeDealerReshare(
+	ctx context.Context,
+	keyID [32]byte,
+	proto Protocol,
+	newParticipants []party.ID,
+	newThreshold int,
+	selfID party.ID,
+) ([32]byte, error) {
+	if newThreshold < 1 || newThreshold >= len(newParticipants) {
+		return [32]byte{}, ErrInvalidThreshold
+	}
+	if c.transport == nil {
+		return [32]byte{}, ErrReshareInProcess
+	}
+
+	var old *reshareKey
+	switch proto {
+	case ProtocolCGGMP21:
+		if cfg, ok := c.cmpConfigs[keyID]; ok {
+			old = cmpReshareKey(cfg)
+		}
+	case ProtocolFROST:
+		if cfg, ok := c.frostConfigs[keyID]; ok {
+			old = frostReshareKey(cfg)
+		}
+	}
+	joining := slices.Contains(newParticipants, selfID)
+	if old == nil && !joining {
+		return [32]byte{}, ErrKeyNotFound
+	}
+	if old != nil {
+		if _, ok := old.shares[selfID]; !ok {
+			return [32]byte{}, ErrNotParticipant
+		}
+	}
+
+	group := curve.Secp256k1{}
+	threshold := binary.BigEndian.AppendUint32(nil, uint32(newThreshold))
+	deal := sessionID(sessionDeal, proto, newParticipants, keyID[:], threshold)
+	mux := c.sessionMux(selfID)
+	inbox := mux.open(deal)
+	defer mux.close(deal)
+
+	// Deal this party's share to the new participants
+	var own []byte
+	if old != nil {
+		dealt, err := c.dealSubShares(ctx, deal, old, newParticipants, newThreshold, selfID)
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("reshare dealing failed: %w", err)
+		}
+		own = dealt
+	}
+	if !joining {
+		pubBytes, err := old.public.MarshalBinary()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		delete(c.cmpConfigs, keyID)
+		delete(c.frostConfigs, keyID)
+		c.discardPresignatures(keyID)
+		return DeriveKeyID(proto, KeyTypeSecp256k1, newThreshold, newParticipants, pubBytes), nil
+	}
+
+	next, err := c.collectSubShares(ctx, deal, proto, keyID, newParticipants, newThreshold, selfID, own, inbox)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	pubBytes, err := next.public.MarshalBinary()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	newKeyID := next.keyID(proto, pubBytes)
+
+	session := newSessionTag(sessionReshare, proto, newParticipants, keyID[:], threshold)
+	switch proto {
+	case ProtocolCGGMP21:
+		public := make(map[party.ID]*config.Public, len(next.shares))
+		for id, point := range next.shares {
+			public[id] = &config.Public{ECDSA: point}
+		}
+		results, err := c.runSession(ctx, session, newParticipants, selfID, func(id party.ID) protocol.StartFunc {
+			return cmp.Refresh(&cmp.Config{
+				Group:     group,
+				ID:        id,
+				Threshold: newThreshold,
+				ECDSA:     next.share,
+				Public:    public,
+			}, c.pool)
+		})
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("CMP reshare failed: %w", err)
+		}
+		ourConfig, ok := results[selfID].(*cmp.Config)
+		if !ok {
+			return [32]byte{}, errors.New("config for self not found")
+		}
+		delete(c.cmpConfigs, keyID)
+		c.discardPresignatures(keyID)
+		c.cmpConfigs[newKeyID] = ourConfig
+		c.discardPresignatures(newKeyID)
+
+	case ProtocolFROST:
+		results, err := c.runSession(ctx, session, newParticipants, selfID, func(id party.ID) protocol.StartFunc {
+			return frost.Refresh(&frost.Config{
+				ID:                 id,
+				Threshold:          newThreshold,
+				PrivateShare:       next.share,
+				PublicKey:          next.public,
+				VerificationShares: party.NewPointMap(next.shares),
+			}, newParticipants)
+		})
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("FROST reshare failed: %w", err)
+		}
+		ourConfig, ok := results[selfID].(*frost.Config)
+		if !ok {
+			return [32]byte{}, errors.New("config for self not found")
+		}
+		delete(c.frostConfigs, keyID)
+		c.frostConfigs[newKeyID] = ourConfig
+	}
+
+	return newKeyID, nil
+}
+
+// dealSubShares splits self's weighted share of old among newParticipants
+// and sends each its sub-share. It returns the message self deals to
+// itself, nil when self leaves the set.
+func (c *ThresholdClient) dealSubShares(
+	ctx context.Context,
+	session [32]byte,
+	old *reshareKey,
+	newParticipants []party.ID,
+	newThreshold int,
+	selfID party.ID,
+) ([]byte, error) {
+	group := curve.Secp256k1{}
+	lambda := polynomial.Lagrange(group, old.parties())
+	weighted := group.NewScalar().Set(lambda[selfID]).Mul(old.share)
+	poly := polynomial.NewPolynomial(group, newThreshold, weighted)
+
+	publicData, err := old.marshalPublic()
+	if err != nil {
+		return nil, err
+	}
+	commitments, err := polynomial.NewPolynomialExponent(poly).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	var own []byte
+	for _, id := range newParticipants {
+		subShare, err := poly.Evaluate(id.Scalar(group)).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		writeField(&buf, publicData)
+		writeField(&buf, commitments)
+		writeField(&buf, subShare)
+		if id == selfID {
+			own = buf.Bytes()
+			continue
+		}
+
+		msg := &protocol.Message{SSID: session[:], From: selfID, To: id, RoundNumber: 1, Data: buf.Bytes()}
+		env, err := sealMessage(session, c.replayGuard(selfID).seq(), msg, c.auth)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.transport.Send(ctx, id, env); err != nil {
+			return nil, fmt.Errorf("failed to send sub-share to %s: %w", id, err)
+		}
+	}
+	return own, nil
+}
+
+// collectSubShares receives a sub-share from every dealer of keyID, own
+// being the one self dealt to itself, and returns self's new share with the
+// verification shares of newParticipants. A dealer whose sub-share fails
+// its checks is named a culprit.
+func (c *ThresholdClient) collectSubShares(
+	ctx context.Context,
+	session [32]byte,
+	proto Protocol,
+	keyID [32]byte,
+	newParticipants []party.ID,
+	newThreshold int,
+	selfID party.ID,
+	own []byte,
+	inbox <-chan *Envelope,
+) (*reshareKey, error) {
+	group := curve.Secp256k1{}
+	var (
+		old         *reshareKey
+		publicData  []byte
+		lambda      map[party.ID]curve.Scalar
+		commitments = make(map[party.ID]*polynomial.Exponent)
+		subShares   = make(map[party.ID]curve.Scalar)
+	)
+
+	// accept checks the sub-share dealt by from. The first message fixes
+	// the key's public data, every later dealer must send the same.
+	accept := func(from party.ID, data []byte) error {
+		r := bytes.NewReader(data)
+		var fields [3][]byte
+		for i := range fields {
+			field, err := readField(r)
+			if err != nil {
+				return err
+			}
+			fields[i] = field
+		}
+		if r.Len() != 0 {
+			return ErrBadSubShare
+		}
+
+		if old == nil {
+			key, err := unmarshalReshareKey(group, fields[0])
+			if err != nil {
+				return err
+			}
+			pubBytes, err := key.public.MarshalBinary()
+			if err != nil || key.keyID(proto, pubBytes) != keyID {
+				return fmt.Errorf("%w: public data does not match key ID", ErrBadSubShare)
+			}
+			old, publicData = key, fields[0]
+			lambda = polynomial.Lagrange(group, old.parties())
+		} else if !bytes.Equal(fields[0], publicData) {
+			return fmt.Errorf("%w: public data differs between dealers", ErrBadSubShare)
+		}
+		share, ok := old.shares[from]
+		if !ok {
+			return fmt.Errorf("%w: %s holds no share", ErrBadSubShare, from)
+		}
+
+		exponent := polynomial.EmptyExponent(group)
+		if err := exponent.UnmarshalBinary(fields[1]); err != nil {
+			return err
+		}
+		if exponent.Degree() != newThreshold || !exponent.Constant().Equal(lambda[from].Act(share)) {
+			return fmt.Errorf("%w: commitments do not match verification share", ErrBadSubShare)
+		}
+		subShare := group.NewScalar()
+		if err := subShare.UnmarshalBinary(fields[2]); err != nil {
+			return err
+		}
+		if !subShare.ActOnBase().Equal(exponent.Evaluate(selfID.Scalar(group))) {
+			return fmt.Errorf("%w: sub-share does not match commitments", ErrBadSubShare)
+		}
+
+		commitments[from] = exponent
+		subShares[from] = subShare
+		return nil
+	}
+	culprit := func(from party.ID, err error) error {
+		return newAbortReport(session, map[party.ID]error{
+			selfID: &protocol.Error{Culprits: []party.ID{from}, Err: err},
+		})
+	}
+
+	if own != nil {
+		if err := accept(selfID, own); err != nil {
+			return nil, culprit(selfID, err)
+		}
+	}
+
+	var expired <-chan time.Time
+	if c.timeout > 0 {
+		deadline := time.NewTimer(c.timeout)
+		defer deadline.Stop()
+		expired = deadline.C
+	}
+	for old == nil || len(subShares) < len(old.shares) {
+		select {
+		case env, ok := <-inbox:
+			if !ok {
+				return nil, ErrTransportClosed
+			}
+			msg, ok := openEnvelope(session, env, c.auth)
+			if !ok || msg.Broadcast || msg.To != selfID || msg.RoundNumber != 1 {
+				continue
+			}
+			if _, seen := subShares[msg.From]; seen || !c.replayGuard(selfID).accept(env.From, env.Seq) {
+				continue
+			}
+			if err := accept(msg.From, msg.Data); err != nil {
+				return nil, culprit(msg.From, err)
+			}
+		case <-expired:
+			var missing []party.ID
+			if old != nil {
+				for _, id := range old.parties() {
+					if _, ok := subShares[id]; !ok {
+						missing = append(missing, id)
+					}
+				}
+			}
+			return nil, newAbortReport(session, map[party.ID]error{
+				selfID: &ProtocolTimeoutError{Round: 1, Missing: missing},
+			})
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// Sum the sub-shares and the commitments of every dealer
+	next := &reshareKey{
+		threshold: newThreshold,
+		share:     group.NewScalar(),
+		public:    old.public,
+		shares:    make(map[party.ID]curve.Point, len(newParticipants)),
+	}
+	constant := group.NewPoint()
+	for id, exponent := range commitments {
+		next.share.Add(subShares[id])
+		constant = constant.Add(exponent.Constant())
+	}
+	if !constant.Equal(old.public) {
+		return nil, fmt.Errorf("%w: dealt shares do not reconstruct the public key", ErrBadSubShare)
+	}
+	for _, id := range newParticipants {
+		x := id.Scalar(group)
+		point := group.NewPoint()
+		for _, exponent := range commitments {
+			point = point.Add(exponent.Evaluate(x))
+		}
+		next.shares[id] = point
+	}
+	return next, nil
+}
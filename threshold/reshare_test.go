@@ -0,0 +1,118 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// TestDealerReshare tests that a FROST key moves to a new participant set
+// and threshold under the same public key
+func TestDealerReshare(t *testing.T) {
+	keys := make(map[party.ID]*ecdsa.PrivateKey)
+	var ids []party.ID
+	for i := 0; i < 5; i++ {
+		key, err := luxcrypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		id := NewECDSAAuthenticator(key).Self()
+		keys[id] = key
+		ids = append(ids, id)
+	}
+	oldSet, newSet := ids[:3], ids[1:]
+
+	keyID, clients := newFROSTSigners(t, oldSet, 1)
+	publicKey := clients[oldSet[0]].frostConfigs[keyID].PublicKey
+	pubBytes, _ := publicKey.MarshalBinary()
+	newKeyID := DeriveKeyID(ProtocolFROST, KeyTypeSecp256k1, 2, newSet, pubBytes)
+	for _, id := range ids[3:] {
+		client := NewThresholdClient()
+		t.Cleanup(client.Close)
+		clients[id] = client
+	}
+
+	if _, err := clients[oldSet[0]].ExecuteReshare(context.Background(), keyID, ProtocolFROST, newSet, 2, oldSet[0]); !errors.Is(err, ErrReshareInProcess) {
+		t.Errorf("Expected ErrReshareInProcess without a transport, got %v", err)
+	}
+
+	lt := NewLocalTransport(ids)
+	defer lt.Close()
+	for id, client := range clients {
+		if err := client.SetTransport(lt, NewECDSAAuthenticator(keys[id])); err != nil {
+			t.Fatalf("SetTransport failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Every shareholder and new participant makes the same call
+	var wg sync.WaitGroup
+	for id, client := range clients {
+		wg.Add(1)
+		go func(id party.ID, client *ThresholdClient) {
+			defer wg.Done()
+			resharedID, err := client.ExecuteReshare(ctx, keyID, ProtocolFROST, newSet, 2, id)
+			if err != nil {
+				t.Errorf("ExecuteReshare failed for %s: %v", id, err)
+				return
+			}
+			if resharedID != newKeyID {
+				t.Errorf("Party %s derived another key ID", id)
+			}
+		}(id, client)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	if _, ok := clients[oldSet[0]].frostConfigs[keyID]; ok {
+		t.Errorf("Departing shareholder kept its share")
+	}
+	if _, ok := clients[newSet[0]].frostConfigs[keyID]; ok {
+		t.Errorf("Share kept under the old key ID")
+	}
+	joined := clients[newSet[3]].frostConfigs[newKeyID]
+	if joined == nil || !joined.PublicKey.Equal(publicKey) || joined.Threshold != 2 {
+		t.Fatalf("New participant does not hold a share of the key")
+	}
+
+	// Any three of the four new participants sign
+	signers := newSet[1:]
+	messageHash := [32]byte{0x42}
+	signatures := make(map[party.ID][]byte)
+	var mu sync.Mutex
+	for _, id := range signers {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			result, err := clients[id].ExecuteSigning(ctx, newKeyID, ProtocolFROST, messageHash, signers, id)
+			if err != nil {
+				t.Errorf("ExecuteSigning failed for %s: %v", id, err)
+				return
+			}
+			mu.Lock()
+			signatures[id] = result.Signature
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	for id, signature := range signatures {
+		ok, err := clients[signers[0]].VerifySignature(newKeyID, ProtocolFROST, messageHash, signature)
+		if err != nil || !ok {
+			t.Errorf("Signature of %s does not verify: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"slices"
+
+	"github.com/luxfi/precompile/quantum"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// Ringtail interop with the quantum verifier.
+//
+// Ringtail keys generated by the client can be registered with a
+// quantum.QuantumVerifier so their signatures verify on-chain. Both sides
+// identify a key by the SHA-256 of its combined public key, and both read a
+// threshold t as t+1 required signers. Signatures are made over the 32-byte
+// message hash, so the on-chain message is messageHash[:]. Signer masks set
+// one bit per signer at its index in the key's sorted participant set.
+
+// RingtailVerifierKey exports a Ringtail key in the quantum verifier's format
+func (c *ThresholdClient) RingtailVerifierKey(keyID [32]byte) (*quantum.RingtailPublicKey, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	config, ok := c.ringtailConfigs[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return &quantum.RingtailPublicKey{
+		KeyID:        keyID,
+		PublicKey:    slices.Clone(config.PublicKey),
+		Threshold:    uint32(config.Threshold),
+		TotalParties: uint32(len(c.ringtailParties[keyID])),
+		Generation:   1,
+	}, nil
+}
+
+// RegisterRingtailKey registers a Ringtail key with the quantum verifier
+func (c *ThresholdClient) RegisterRingtailKey(qv *quantum.QuantumVerifier, keyID [32]byte) error {
+	key, err := c.RingtailVerifierKey(keyID)
+	if err != nil {
+		return err
+	}
+	_, err = qv.ImportRingtailKey(key)
+	return err
+}
+
+// RingtailVerifierSignature wraps a Ringtail signature from ExecuteSigning
+// for the quantum verifier. generation is the verifier's current generation
+// of the key, 1 unless it has been rotated there.
+func (c *ThresholdClient) RingtailVerifierSignature(
+	keyID [32]byte,
+	signature []byte,
+	signers []party.ID,
+	generation uint64,
+) (*quantum.RingtailSignature, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	parties, ok := c.ringtailParties[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	indices := make([]int, len(signers))
+	for i, signer := range signers {
+		index, found := slices.BinarySearch(parties, signer)
+		if !found {
+			return nil, ErrUnknownSigner
+		}
+		indices[i] = index
+	}
+
+	return &quantum.RingtailSignature{
+		KeyID:      keyID,
+		Signature:  slices.Clone(signature),
+		SignerMask: quantum.RingtailSignerMask(indices),
+		Generation: generation,
+	}, nil
+}
+
+// sortedParties returns a sorted copy of participants
+func sortedParties(participants []party.ID) []party.ID {
+	sorted := slices.Clone(participants)
+	slices.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/luxfi/precompile/quantum"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// TestRingtailQuantumRoundTrip tests that a Ringtail key and signature from
+// the client verify in the quantum verifier
+func TestRingtailQuantumRoundTrip(t *testing.T) {
+	client := NewThresholdClient()
+	defer client.Close()
+
+	participants := []party.ID{"charlie", "alice", "bob"}
+	ctx := context.Background()
+
+	keygen, err := client.ExecuteKeygen(ctx, ProtocolRingtail, KeyTypeRingtail, 1, participants, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteKeygen failed: %v", err)
+	}
+
+	qv := quantum.NewQuantumVerifier()
+	if err := client.RegisterRingtailKey(qv, keygen.KeyID); err != nil {
+		t.Fatalf("RegisterRingtailKey failed: %v", err)
+	}
+	key := qv.RingtailKeys[keygen.KeyID]
+	if key == nil {
+		t.Fatal("Key not registered under the client's key ID")
+	}
+	if key.Threshold != 1 || key.TotalParties != 3 {
+		t.Errorf("Expected 1-of-3 key, got threshold %d of %d", key.Threshold, key.TotalParties)
+	}
+
+	messageHash := sha256.Sum256([]byte("ringtail interop"))
+	signers := []party.ID{"alice", "charlie"}
+	signed, err := client.ExecuteSigning(ctx, keygen.KeyID, ProtocolRingtail, messageHash, signers, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteSigning failed: %v", err)
+	}
+
+	sig, err := client.RingtailVerifierSignature(keygen.KeyID, signed.Signature, signers, key.Generation)
+	if err != nil {
+		t.Fatalf("RingtailVerifierSignature failed: %v", err)
+	}
+	// alice and charlie are parties 0 and 2 of the sorted set
+	if len(sig.SignerMask) != 1 || sig.SignerMask[0] != 0b00000101 {
+		t.Errorf("Expected signer mask 00000101, got %08b", sig.SignerMask)
+	}
+
+	result, err := qv.VerifyRingtail(keygen.KeyID, messageHash[:], sig)
+	if err != nil {
+		t.Fatalf("VerifyRingtail failed: %v", err)
+	}
+	if !result.Valid {
+		t.Error("Expected client signature to verify on-chain")
+	}
+
+	valid, err := client.VerifySignature(keygen.KeyID, ProtocolRingtail, messageHash, signed.Signature)
+	if err != nil || !valid {
+		t.Errorf("Expected client verification to agree, got %v (%v)", valid, err)
+	}
+
+	otherHash := sha256.Sum256([]byte("other message"))
+	result, err = qv.VerifyRingtail(keygen.KeyID, otherHash[:], sig)
+	if err != nil {
+		t.Fatalf("VerifyRingtail failed: %v", err)
+	}
+	if result.Valid {
+		t.Error("Expected signature over a different message to fail")
+	}
+
+	if _, err := client.RingtailVerifierSignature(keygen.KeyID, signed.Signature, []party.ID{"mallory"}, 1); err != ErrUnknownSigner {
+		t.Errorf("Expected ErrUnknownSigner, got %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Secret share storage
+//
+// A party's share lives in memory inside its CGGMP21 or FROST config.
+// SaveKey writes the share to a SecretStore and LoadKey restores it after a
+// restart, so a node keeps its shares without ever writing one in
+// plaintext. Stored shares use the format ExportConfig seals.
+//
+// EnvelopeStore keeps shares in a directory with envelope encryption. Each
+// share is sealed with AES-256-GCM under a fresh data key, and the data key
+// is wrapped by a KeyWrapper whose master key stays inside an HSM or cloud
+// KMS. The key ID is authenticated with both layers, so a sealed share
+// cannot be restored under another key's name. The secretstore package
+// provides KeyWrappers for PKCS#11 tokens, AWS KMS and Google Cloud KMS.
+
+// sealedShareVersion is the version of the EnvelopeStore file format
+const sealedShareVersion = 1
+
+// sealedShareDomain prefixes the additional data of sealed shares
+const sealedShareDomain = "lux.threshold.share.v1"
+
+// SecretStore keeps secret key shares by key ID. Implementations must not
+// write the secret in plaintext.
+type SecretStore interface {
+	// Put stores secret under keyID, replacing any secret stored before
+	Put(ctx context.Context, keyID [32]byte, secret []byte) error
+	// Get returns the secret stored under keyID, or ErrKeyNotFound
+	Get(ctx context.Context, keyID [32]byte) ([]byte, error)
+	// Delete removes the secret stored under keyID
+	Delete(ctx context.Context, keyID [32]byte) error
+}
+
+// KeyWrapper encrypts data keys under a master key it holds, such as a key
+// in an HSM or cloud KMS. aad is authenticated with the data key and must
+// be presented again to unwrap it.
+type KeyWrapper interface {
+	// WrapKey encrypts dataKey bound to aad
+	WrapKey(ctx context.Context, dataKey, aad []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped with aad
+	UnwrapKey(ctx context.Context, wrapped, aad []byte) ([]byte, error)
+}
+
+// SaveKey writes this party's share of keyID to store
+func (c *ThresholdClient) SaveKey(ctx context.Context, store SecretStore, keyID [32]byte, proto Protocol) error {
+	c.mu.RLock()
+	plain, err := c.encodeShare(keyID, proto)
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	defer clear(plain)
+	return store.Put(ctx, keyID, plain)
+}
+
+// LoadKey restores this party's share of keyID from store
+func (c *ThresholdClient) LoadKey(ctx context.Context, store SecretStore, keyID [32]byte) (*KeygenResult, error) {
+	plain, err := store.Get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(plain)
+
+	result, err := c.restoreShare(plain)
+	if err != nil {
+		return nil, err
+	}
+	if result.KeyID != keyID {
+		return nil, ErrInvalidExport
+	}
+	return result, nil
+}
+
+// EnvelopeStore is a SecretStore that keeps each secret in a file of dir,
+// sealed under a data key wrapped by a KeyWrapper
+type EnvelopeStore struct {
+	dir     string
+	wrapper KeyWrapper
+}
+
+var _ SecretStore = (*EnvelopeStore)(nil)
+
+// NewEnvelopeStore stores secrets in dir, creating it if needed, with data
+// keys wrapped by wrapper
+func NewEnvelopeStore(dir string, wrapper KeyWrapper) (*EnvelopeStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &EnvelopeStore{dir: dir, wrapper: wrapper}, nil
+}
+
+// path returns the file of keyID's secret
+func (s *EnvelopeStore) path(keyID [32]byte) string {
+	return filepath.Join(s.dir, hex.EncodeToString(keyID[:])+".share")
+}
+
+// sealedShareAAD returns the additional data binding a sealed share to keyID
+func sealedShareAAD(keyID [32]byte) []byte {
+	return append([]byte(sealedShareDomain), keyID[:]...)
+}
+
+// Put seals secret under a fresh data key and writes it as version ||
+// wrapped data key || nonce || ciphertext, the wrapped key length-prefixed
+func (s *EnvelopeStore) Put(ctx context.Context, keyID [32]byte, secret []byte) error {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return err
+	}
+	defer clear(dataKey)
+
+	aad := sealedShareAAD(keyID)
+	wrapped, err := s.wrapper.WrapKey(ctx, dataKey, aad)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	aead, err := newShareAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(sealedShareVersion)
+	writeField(&buf, wrapped)
+	buf.Write(nonce)
+	buf.Write(aead.Seal(nil, nonce, secret, aad))
+	return writeFileAtomic(s.path(keyID), buf.Bytes())
+}
+
+// Get reads and opens the secret stored under keyID
+func (s *EnvelopeStore) Get(ctx context.Context, keyID [32]byte) ([]byte, error) {
+	data, err := os.ReadFile(s.path(keyID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || data[0] != sealedShareVersion {
+		return nil, ErrInvalidSealedShare
+	}
+	r := bytes.NewReader(data[1:])
+	wrapped, err := readField(r)
+	if err != nil {
+		return nil, ErrInvalidSealedShare
+	}
+
+	aad := sealedShareAAD(keyID)
+	dataKey, err := s.wrapper.UnwrapKey(ctx, wrapped, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	defer clear(dataKey)
+	aead, err := newShareAEAD(dataKey)
+	if err != nil {
+		return nil, ErrInvalidSealedShare
+	}
+
+	rest := data[len(data)-r.Len():]
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrInvalidSealedShare
+	}
+	secret, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, ErrInvalidSealedShare
+	}
+	return secret, nil
+}
+
+// Delete removes the file of keyID's secret
+func (s *EnvelopeStore) Delete(_ context.Context, keyID [32]byte) error {
+	if err := os.Remove(s.path(keyID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func newShareAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFileAtomic writes data to a temporary file beside path, syncs it and
+// renames it over path, so a crash leaves either the old or the new file
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".share-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
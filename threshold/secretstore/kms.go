@@ -0,0 +1,312 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package secretstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/luxfi/precompile/threshold"
+)
+
+// maxResponseSize bounds the KMS responses read
+const maxResponseSize = 1 << 20
+
+// awsContextKey names the encryption context entry that carries the
+// additional data of a wrapped key
+const awsContextKey = "lux.threshold.aad"
+
+// AWSCredentials are the credentials of an AWS KMS request
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Set for temporary credentials
+}
+
+// AWSKMS wraps data keys with a symmetric AWS KMS key. The additional data
+// of each key becomes its encryption context, which KMS authenticates and
+// records in CloudTrail.
+type AWSKMS struct {
+	region      string
+	keyID       string
+	credentials func(context.Context) (AWSCredentials, error)
+
+	// Endpoint overrides the regional endpoint, such as for a VPC endpoint
+	Endpoint string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+
+	now func() time.Time
+}
+
+var _ threshold.KeyWrapper = (*AWSKMS)(nil)
+
+// NewAWSKMS wraps data keys with keyID, a key ID, ARN or alias in region,
+// signing each request with the credentials returned by credentials
+func NewAWSKMS(region, keyID string, credentials func(context.Context) (AWSCredentials, error)) *AWSKMS {
+	return &AWSKMS{
+		region:      region,
+		keyID:       keyID,
+		credentials: credentials,
+		Endpoint:    "https://kms." + region + ".amazonaws.com",
+		now:         time.Now,
+	}
+}
+
+type awsEncryptRequest struct {
+	KeyID             string            `json:"KeyId"`
+	Plaintext         []byte            `json:"Plaintext"`
+	EncryptionContext map[string]string `json:"EncryptionContext"`
+}
+
+type awsDecryptRequest struct {
+	KeyID             string            `json:"KeyId"`
+	CiphertextBlob    []byte            `json:"CiphertextBlob"`
+	EncryptionContext map[string]string `json:"EncryptionContext"`
+}
+
+type awsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func awsContext(aad []byte) map[string]string {
+	return map[string]string{awsContextKey: base64.StdEncoding.EncodeToString(aad)}
+}
+
+// WrapKey encrypts dataKey with KMS Encrypt
+func (k *AWSKMS) WrapKey(ctx context.Context, dataKey, aad []byte) ([]byte, error) {
+	var resp struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+	}
+	req := &awsEncryptRequest{KeyID: k.keyID, Plaintext: dataKey, EncryptionContext: awsContext(aad)}
+	if err := k.call(ctx, "TrentService.Encrypt", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.CiphertextBlob, nil
+}
+
+// UnwrapKey decrypts a data key with KMS Decrypt
+func (k *AWSKMS) UnwrapKey(ctx context.Context, wrapped, aad []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	req := &awsDecryptRequest{KeyID: k.keyID, CiphertextBlob: wrapped, EncryptionContext: awsContext(aad)}
+	if err := k.call(ctx, "TrentService.Decrypt", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+// call sends one KMS JSON API request signed with Signature Version 4
+func (k *AWSKMS) call(ctx context.Context, target string, in, out interface{}) error {
+	creds, err := k.credentials(ctx)
+	if err != nil {
+		return fmt.Errorf("aws kms credentials: %w", err)
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	k.sign(req, body, creds, k.now().UTC())
+
+	data, status, err := do(k.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		var e awsError
+		json.Unmarshal(data, &e)
+		return fmt.Errorf("aws kms %s: %d %s: %s", target, status, e.Type, e.Message)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// sign adds the Signature Version 4 headers of req with body at t
+func (k *AWSKMS) sign(req *http.Request, body []byte, creds AWSCredentials, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// Headers are signed in sorted order
+	headers := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if creds.SessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+	}
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+	bodyHash := sha256.Sum256(body)
+
+	canonical := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+
+	scope := date + "/" + k.region + "/kms/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, k.region)
+	key = hmacSHA256(key, "kms")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalPath(u *url.URL) string {
+	if p := u.EscapedPath(); p != "" {
+		return p
+	}
+	return "/"
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// GCPKMS wraps data keys with a symmetric Google Cloud KMS key, passing the
+// additional data of each key as the additional authenticated data of the
+// KMS operation
+type GCPKMS struct {
+	keyName string
+	token   func(context.Context) (string, error)
+
+	// Endpoint overrides the Cloud KMS endpoint
+	Endpoint string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+var _ threshold.KeyWrapper = (*GCPKMS)(nil)
+
+// NewGCPKMS wraps data keys with keyName, the resource name
+// projects/*/locations/*/keyRings/*/cryptoKeys/*, authorizing each request
+// with the OAuth2 access token returned by token
+func NewGCPKMS(keyName string, token func(context.Context) (string, error)) *GCPKMS {
+	return &GCPKMS{
+		keyName:  keyName,
+		token:    token,
+		Endpoint: "https://cloudkms.googleapis.com",
+	}
+}
+
+type gcpError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// WrapKey encrypts dataKey with cryptoKeys.encrypt
+func (k *GCPKMS) WrapKey(ctx context.Context, dataKey, aad []byte) ([]byte, error) {
+	req := struct {
+		Plaintext []byte `json:"plaintext"`
+		AAD       []byte `json:"additionalAuthenticatedData"`
+	}{dataKey, aad}
+	var resp struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := k.call(ctx, "encrypt", &req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Ciphertext, nil
+}
+
+// UnwrapKey decrypts a data key with cryptoKeys.decrypt
+func (k *GCPKMS) UnwrapKey(ctx context.Context, wrapped, aad []byte) ([]byte, error) {
+	req := struct {
+		Ciphertext []byte `json:"ciphertext"`
+		AAD        []byte `json:"additionalAuthenticatedData"`
+	}{wrapped, aad}
+	var resp struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := k.call(ctx, "decrypt", &req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+// call sends one request to the key's :method endpoint
+func (k *GCPKMS) call(ctx context.Context, method string, in, out interface{}) error {
+	token, err := k.token(ctx)
+	if err != nil {
+		return fmt.Errorf("gcp kms token: %w", err)
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.Endpoint+"/v1/"+k.keyName+":"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	data, status, err := do(k.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		var e gcpError
+		json.Unmarshal(data, &e)
+		return fmt.Errorf("gcp kms %s: %d %s: %s", method, status, e.Error.Status, e.Error.Message)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// do sends req with client and returns the response body and status
+func do(client *http.Client, req *http.Request) ([]byte, int, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, resp.StatusCode, nil
+}
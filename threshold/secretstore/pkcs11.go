@@ -0,0 +1,83 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package secretstore provides KeyWrappers that keep the master key of a
+// threshold.EnvelopeStore outside the node: on a PKCS#11 HSM, in AWS KMS or
+// in Google Cloud KMS. Each wrapper encrypts only the data keys of sealed
+// shares, so the shares themselves never reach the HSM or KMS.
+//
+// The cloud wrappers call the KMS REST APIs directly and take credentials
+// from a caller-supplied function, so operators keep their usual credential
+// chain and rotation. The PKCS#11 wrapper takes the AES-GCM operations of a
+// token session, which operators adapt from their PKCS#11 binding.
+package secretstore
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+
+	"github.com/luxfi/precompile/threshold"
+)
+
+// gcmNonceSize is the IV size of AES-GCM on PKCS#11 tokens
+const gcmNonceSize = 12
+
+// ErrInvalidWrappedKey is returned for wrapped data keys that are too short
+// to have been produced by the wrapper
+var ErrInvalidWrappedKey = errors.New("invalid wrapped data key")
+
+// PKCS11Token performs CKM_AES_GCM with the wrapping key on a PKCS#11
+// token, with a 128-bit tag. It is the part of a token session the wrapper
+// needs, adapted from a PKCS#11 binding such as github.com/miekg/pkcs11
+// with the session and the wrapping key's object handle.
+type PKCS11Token interface {
+	// EncryptGCM returns ciphertext || tag of plaintext under iv and aad
+	EncryptGCM(iv, aad, plaintext []byte) ([]byte, error)
+	// DecryptGCM opens ciphertext || tag under iv and aad
+	DecryptGCM(iv, aad, ciphertext []byte) ([]byte, error)
+}
+
+// PKCS11Wrapper wraps data keys with an AES key that never leaves a PKCS#11
+// token
+type PKCS11Wrapper struct {
+	token PKCS11Token
+	mu    sync.Mutex
+}
+
+var _ threshold.KeyWrapper = (*PKCS11Wrapper)(nil)
+
+// NewPKCS11Wrapper wraps data keys on token. Calls to token are serialized,
+// as PKCS#11 sessions are not safe for concurrent use.
+func NewPKCS11Wrapper(token PKCS11Token) *PKCS11Wrapper {
+	return &PKCS11Wrapper{token: token}
+}
+
+// WrapKey encrypts dataKey on the token as iv || ciphertext || tag. The IV
+// is drawn here, as many tokens refuse to generate GCM IVs.
+func (w *PKCS11Wrapper) WrapKey(_ context.Context, dataKey, aad []byte) ([]byte, error) {
+	iv := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sealed, err := w.token.EncryptGCM(iv, aad, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(iv, sealed...), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey on the token
+func (w *PKCS11Wrapper) UnwrapKey(_ context.Context, wrapped, aad []byte) ([]byte, error) {
+	if len(wrapped) <= gcmNonceSize {
+		return nil, ErrInvalidWrappedKey
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.token.DecryptGCM(wrapped[:gcmNonceSize], aad, wrapped[gcmNonceSize:])
+}
@@ -0,0 +1,196 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package secretstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/luxfi/precompile/threshold"
+)
+
+// testToken is a PKCS#11 token holding an AES key in memory
+type testToken struct {
+	aead cipher.AEAD
+}
+
+func newTestToken(t *testing.T) *testToken {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{0x05}, 32))
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+	aead, _ := cipher.NewGCM(block)
+	return &testToken{aead: aead}
+}
+
+func (t *testToken) EncryptGCM(iv, aad, plaintext []byte) ([]byte, error) {
+	return t.aead.Seal(nil, iv, plaintext, aad), nil
+}
+
+func (t *testToken) DecryptGCM(iv, aad, ciphertext []byte) ([]byte, error) {
+	return t.aead.Open(nil, iv, ciphertext, aad)
+}
+
+// fakeKMS keeps the keys it wraps with the additional data they are bound
+// to
+type fakeKMS struct {
+	keys map[string][2][]byte
+	mu   sync.Mutex
+}
+
+func (f *fakeKMS) wrap(key, aad []byte) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	blob := fmt.Sprintf("blob-%d", len(f.keys))
+	f.keys[blob] = [2][]byte{key, aad}
+	return []byte(blob)
+}
+
+func (f *fakeKMS) unwrap(blob, aad []byte) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, ok := f.keys[string(blob)]
+	if !ok || !bytes.Equal(entry[1], aad) {
+		return nil, false
+	}
+	return entry[0], true
+}
+
+// testWrapper wraps and unwraps a data key through w and checks the
+// additional data is enforced
+func testWrapper(t *testing.T, w threshold.KeyWrapper) {
+	t.Helper()
+	ctx := context.Background()
+	dataKey := bytes.Repeat([]byte{0x2a}, 32)
+	aad := []byte("key")
+
+	wrapped, err := w.WrapKey(ctx, dataKey, aad)
+	if err != nil {
+		t.Fatalf("WrapKey failed: %v", err)
+	}
+	if bytes.Contains(wrapped, dataKey) {
+		t.Errorf("Data key wrapped in plaintext")
+	}
+	unwrapped, err := w.UnwrapKey(ctx, wrapped, aad)
+	if err != nil {
+		t.Fatalf("UnwrapKey failed: %v", err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("Unwrapped key does not match")
+	}
+	if _, err := w.UnwrapKey(ctx, wrapped, []byte("other")); err == nil {
+		t.Errorf("Expected unwrap with other additional data to fail")
+	}
+
+	// Shares sealed through the wrapper round trip
+	store, err := threshold.NewEnvelopeStore(t.TempDir(), w)
+	if err != nil {
+		t.Fatalf("NewEnvelopeStore failed: %v", err)
+	}
+	if err := store.Put(ctx, [32]byte{0x01}, []byte("share")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if secret, err := store.Get(ctx, [32]byte{0x01}); err != nil || string(secret) != "share" {
+		t.Errorf("Expected stored secret, got %q, %v", secret, err)
+	}
+}
+
+// TestPKCS11Wrapper tests wrapping data keys on a PKCS#11 token
+func TestPKCS11Wrapper(t *testing.T) {
+	w := NewPKCS11Wrapper(newTestToken(t))
+	testWrapper(t, w)
+
+	if _, err := w.UnwrapKey(context.Background(), make([]byte, gcmNonceSize), nil); err != ErrInvalidWrappedKey {
+		t.Errorf("Expected ErrInvalidWrappedKey, got %v", err)
+	}
+}
+
+// TestAWSKMS tests wrapping data keys with signed AWS KMS requests
+func TestAWSKMS(t *testing.T) {
+	kms := &fakeKMS{keys: make(map[string][2][]byte)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/us-east-1/kms/aws4_request") ||
+			!strings.Contains(auth, "x-amz-security-token") || r.Header.Get("X-Amz-Security-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var req awsDecryptRequest
+		var plain awsEncryptRequest
+		body := new(bytes.Buffer)
+		body.ReadFrom(r.Body)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			json.Unmarshal(body.Bytes(), &plain)
+			aad := []byte(plain.EncryptionContext[awsContextKey])
+			json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": kms.wrap(plain.Plaintext, aad)})
+		case "TrentService.Decrypt":
+			json.Unmarshal(body.Bytes(), &req)
+			key, ok := kms.unwrap(req.CiphertextBlob, []byte(req.EncryptionContext[awsContextKey]))
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(&awsError{Type: "InvalidCiphertextException"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": key})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	w := NewAWSKMS("us-east-1", "alias/threshold", func(context.Context) (AWSCredentials, error) {
+		return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}, nil
+	})
+	w.Endpoint = srv.URL
+	testWrapper(t, w)
+}
+
+// TestGCPKMS tests wrapping data keys with Google Cloud KMS requests
+func TestGCPKMS(t *testing.T) {
+	const keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	kms := &fakeKMS{keys: make(map[string][2][]byte)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Plaintext  []byte `json:"plaintext"`
+			Ciphertext []byte `json:"ciphertext"`
+			AAD        []byte `json:"additionalAuthenticatedData"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v1/" + keyName + ":encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"ciphertext": kms.wrap(req.Plaintext, req.AAD)})
+		case "/v1/" + keyName + ":decrypt":
+			key, ok := kms.unwrap(req.Ciphertext, req.AAD)
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":{"code":400,"status":"INVALID_ARGUMENT","message":"Decryption failed"}}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string][]byte{"plaintext": key})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	w := NewGCPKMS(keyName, func(context.Context) (string, error) { return "token", nil })
+	w.Endpoint = srv.URL
+	testWrapper(t, w)
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// testWrapper wraps data keys with an in-memory AES key
+type testWrapper struct {
+	aead cipher.AEAD
+}
+
+func newTestWrapper(t *testing.T) *testWrapper {
+	aead, err := newShareAEAD(bytes.Repeat([]byte{0x07}, 32))
+	if err != nil {
+		t.Fatalf("newShareAEAD failed: %v", err)
+	}
+	return &testWrapper{aead: aead}
+}
+
+func (w *testWrapper) WrapKey(_ context.Context, dataKey, aad []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	return w.aead.Seal(nonce, nonce, dataKey, aad), nil
+}
+
+func (w *testWrapper) UnwrapKey(_ context.Context, wrapped, aad []byte) ([]byte, error) {
+	n := w.aead.NonceSize()
+	return w.aead.Open(nil, wrapped[:n], wrapped[n:], aad)
+}
+
+// TestEnvelopeStore tests that a share saved to an EnvelopeStore is sealed
+// on disk and restores into another client
+func TestEnvelopeStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewEnvelopeStore(t.TempDir(), newTestWrapper(t))
+	if err != nil {
+		t.Fatalf("NewEnvelopeStore failed: %v", err)
+	}
+
+	client := NewThresholdClient()
+	defer client.Close()
+	participants := []party.ID{"alice", "bob", "charlie"}
+	keygen, err := client.ExecuteKeygen(ctx, ProtocolFROST, KeyTypeSecp256k1, 1, participants, "alice")
+	if err != nil {
+		t.Fatalf("ExecuteKeygen failed: %v", err)
+	}
+	if err := client.SaveKey(ctx, store, keygen.KeyID, ProtocolFROST); err != nil {
+		t.Fatalf("SaveKey failed: %v", err)
+	}
+
+	share, _ := client.frostConfigs[keygen.KeyID].PrivateShare.MarshalBinary()
+	sealed, err := os.ReadFile(store.path(keygen.KeyID))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if bytes.Contains(sealed, share) {
+		t.Errorf("Share stored in plaintext")
+	}
+
+	restored := NewThresholdClient()
+	defer restored.Close()
+	result, err := restored.LoadKey(ctx, store, keygen.KeyID)
+	if err != nil {
+		t.Fatalf("LoadKey failed: %v", err)
+	}
+	if result.KeyID != keygen.KeyID || !bytes.Equal(result.PublicKey, keygen.PublicKey) {
+		t.Errorf("Restored key does not match")
+	}
+	if got, _ := restored.frostConfigs[keygen.KeyID].PrivateShare.MarshalBinary(); !bytes.Equal(got, share) {
+		t.Errorf("Restored share does not match")
+	}
+
+	// A sealed share opens only under its own key ID and unmodified
+	other := [32]byte{0x01}
+	if err := os.WriteFile(store.path(other), sealed, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := store.Get(ctx, other); err == nil {
+		t.Errorf("Expected share moved to another key ID to fail")
+	}
+	sealed[len(sealed)-1] ^= 0x01
+	if err := os.WriteFile(store.path(keygen.KeyID), sealed, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := store.Get(ctx, keygen.KeyID); !errors.Is(err, ErrInvalidSealedShare) {
+		t.Errorf("Expected ErrInvalidSealedShare for modified share, got %v", err)
+	}
+
+	if err := store.Delete(ctx, keygen.KeyID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := restored.LoadKey(ctx, store, keygen.KeyID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound after delete, got %v", err)
+	}
+}
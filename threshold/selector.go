@@ -0,0 +1,287 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/luxfi/geth/common"
+)
+
+// Signer selection
+//
+// A key's signing requests are served by threshold+1 of its signers. By
+// default these are always the first threshold+1 allowed signers, so the
+// same parties carry every session and one offline party fails them all.
+// A manager with a SignerSelector instead chooses the signers of each
+// request from all candidates, the key's allowed signers and its owner,
+// skipping any still holding a share from before the last refresh:
+//
+//	RoundRobinSelector  rotates through the candidates, spreading sessions evenly
+//	StakeSelector       samples candidates in proportion to their stake
+//	LivenessSelector    prefers candidates that completed recent sessions
+//
+// The chosen set is recorded on the SigningRequest. When the session ends,
+// the selector is told which signers it named faulty, if any.
+
+// livenessDecay weights the latest outcome in a LivenessSelector score
+const livenessDecay = 0.2
+
+// SignerSelector chooses the signers of each signing session
+type SignerSelector interface {
+	// Select returns need of candidates to sign requestID with keyID.
+	// Random choices derive from requestID, so they can be reproduced.
+	Select(keyID, requestID [32]byte, candidates []common.Address, need int) ([]common.Address, error)
+	// Report records the outcome of a session signed by signers, naming
+	// the faulty signers of a failed session
+	Report(keyID [32]byte, signers []common.Address, faulty []common.Address)
+}
+
+// signerCandidates returns the addresses that may sign for key: its allowed
+// signers and its owner
+func signerCandidates(key *ThresholdKey) []common.Address {
+	candidates := slices.Clone(key.Permissions.AllowedSigners)
+	if !slices.Contains(candidates, key.Owner) {
+		candidates = append(candidates, key.Owner)
+	}
+	return candidates
+}
+
+// selectSigners returns the signers of requestID with key, chosen by the
+// installed selector or signerSet when none is. Caller must hold tm.mu.
+func (tm *ThresholdManager) selectSigners(key *ThresholdKey, requestID [32]byte) ([]common.Address, error) {
+	if tm.SignerSelector == nil {
+		signers := signerSet(key)
+		if tm.hasStaleSigner(key, signers) {
+			return nil, ErrStaleShareGeneration
+		}
+		return signers, nil
+	}
+
+	need := int(key.Threshold) + 1
+	var candidates []common.Address
+	for _, addr := range signerCandidates(key) {
+		if !tm.hasStaleSigner(key, []common.Address{addr}) {
+			candidates = append(candidates, addr)
+		}
+	}
+	if len(candidates) < need {
+		return nil, ErrStaleShareGeneration
+	}
+	signers, err := tm.SignerSelector.Select(key.KeyID, requestID, candidates, need)
+	if err != nil {
+		return nil, err
+	}
+	if len(signers) != need {
+		return nil, ErrInsufficientParties
+	}
+	for _, s := range signers {
+		if !slices.Contains(candidates, s) {
+			return nil, ErrUnknownSigner
+		}
+	}
+	return signers, nil
+}
+
+// faultySigners returns the addresses of the parties err names faulty
+func faultySigners(err error) []common.Address {
+	var report *AbortReport
+	if !errors.As(err, &report) {
+		return nil
+	}
+	faulty := make([]common.Address, 0, len(report.Faulty()))
+	for _, id := range report.Faulty() {
+		faulty = append(faulty, common.HexToAddress(string(id)))
+	}
+	return faulty
+}
+
+// RoundRobinSelector rotates each key's signing sessions through its
+// candidates, so every candidate signs equally often
+type RoundRobinSelector struct {
+	next map[[32]byte]int
+	mu   sync.Mutex
+}
+
+var _ SignerSelector = (*RoundRobinSelector)(nil)
+
+// NewRoundRobinSelector creates a RoundRobinSelector
+func NewRoundRobinSelector() *RoundRobinSelector {
+	return &RoundRobinSelector{next: make(map[[32]byte]int)}
+}
+
+// Select returns need candidates starting after the first signer of the
+// key's previous session
+func (s *RoundRobinSelector) Select(keyID, _ [32]byte, candidates []common.Address, need int) ([]common.Address, error) {
+	if need <= 0 || need > len(candidates) {
+		return nil, ErrInsufficientParties
+	}
+
+	s.mu.Lock()
+	start := s.next[keyID] % len(candidates)
+	s.next[keyID] = start + 1
+	s.mu.Unlock()
+
+	signers := make([]common.Address, need)
+	for i := range signers {
+		signers[i] = candidates[(start+i)%len(candidates)]
+	}
+	return signers, nil
+}
+
+// Report does nothing; rotation does not depend on outcomes
+func (s *RoundRobinSelector) Report([32]byte, []common.Address, []common.Address) {}
+
+// StakeSelector samples signers without replacement with probability in
+// proportion to their stake
+type StakeSelector struct {
+	stakes map[common.Address]uint64
+	mu     sync.RWMutex
+}
+
+var _ SignerSelector = (*StakeSelector)(nil)
+
+// NewStakeSelector creates a StakeSelector with no stakes
+func NewStakeSelector() *StakeSelector {
+	return &StakeSelector{stakes: make(map[common.Address]uint64)}
+}
+
+// SetStake sets the stake of addr. Candidates without stake are never
+// selected.
+func (s *StakeSelector) SetStake(addr common.Address, stake uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stake == 0 {
+		delete(s.stakes, addr)
+		return
+	}
+	s.stakes[addr] = stake
+}
+
+// Select draws need staked candidates by weighted sampling (Efraimidis and
+// Spirakis): each candidate gets the key u^(1/stake) for a uniform u
+// derived from requestID and its address, and the largest keys win
+func (s *StakeSelector) Select(_ [32]byte, requestID [32]byte, candidates []common.Address, need int) ([]common.Address, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type draw struct {
+		addr common.Address
+		key  float64
+	}
+	draws := make([]draw, 0, len(candidates))
+	for _, addr := range candidates {
+		if stake := s.stakes[addr]; stake > 0 {
+			// Compare log(u)/stake, which orders as u^(1/stake)
+			draws = append(draws, draw{addr, math.Log(uniform(requestID, addr)) / float64(stake)})
+		}
+	}
+	if need <= 0 || need > len(draws) {
+		return nil, ErrInsufficientParties
+	}
+
+	slices.SortFunc(draws, func(a, b draw) int {
+		if a.key != b.key {
+			if a.key > b.key {
+				return -1
+			}
+			return 1
+		}
+		return bytes.Compare(a.addr[:], b.addr[:])
+	})
+	signers := make([]common.Address, need)
+	for i := range signers {
+		signers[i] = draws[i].addr
+	}
+	return signers, nil
+}
+
+// Report does nothing; stake does not depend on outcomes
+func (s *StakeSelector) Report([32]byte, []common.Address, []common.Address) {}
+
+// uniform returns a value in (0, 1) derived from seed and addr
+func uniform(seed [32]byte, addr common.Address) float64 {
+	h := sha256.Sum256(append(seed[:], addr[:]...))
+	// 53 random bits, offset by half a step to exclude 0 and 1
+	return (float64(binary.BigEndian.Uint64(h[:8])>>11) + 0.5) / (1 << 53)
+}
+
+// LivenessSelector scores each signer by its recent sessions and selects
+// the signers with the highest scores. A score is an exponential moving
+// average of outcomes, 1 for a completed session and 0 for one the signer
+// was named faulty in, and starts at 1. Ties are broken by a hash of the
+// request and address, so equally live signers share the load.
+type LivenessSelector struct {
+	scores map[common.Address]float64
+	mu     sync.RWMutex
+}
+
+var _ SignerSelector = (*LivenessSelector)(nil)
+
+// NewLivenessSelector creates a LivenessSelector with every signer live
+func NewLivenessSelector() *LivenessSelector {
+	return &LivenessSelector{scores: make(map[common.Address]float64)}
+}
+
+// Score returns the liveness score of addr, in [0, 1]
+func (s *LivenessSelector) Score(addr common.Address) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.score(addr)
+}
+
+func (s *LivenessSelector) score(addr common.Address) float64 {
+	if score, ok := s.scores[addr]; ok {
+		return score
+	}
+	return 1
+}
+
+// Select returns the need candidates with the highest scores
+func (s *LivenessSelector) Select(_ [32]byte, requestID [32]byte, candidates []common.Address, need int) ([]common.Address, error) {
+	if need <= 0 || need > len(candidates) {
+		return nil, ErrInsufficientParties
+	}
+
+	s.mu.RLock()
+	scores := make(map[common.Address]float64, len(candidates))
+	for _, addr := range candidates {
+		scores[addr] = s.score(addr)
+	}
+	s.mu.RUnlock()
+
+	ranked := slices.Clone(candidates)
+	slices.SortFunc(ranked, func(a, b common.Address) int {
+		if scores[a] != scores[b] {
+			if scores[a] > scores[b] {
+				return -1
+			}
+			return 1
+		}
+		ha := sha256.Sum256(append(requestID[:], a[:]...))
+		hb := sha256.Sum256(append(requestID[:], b[:]...))
+		return bytes.Compare(ha[:], hb[:])
+	})
+	return ranked[:need], nil
+}
+
+// Report moves the score of each signer toward 0 if it was faulty and
+// toward 1 otherwise
+func (s *LivenessSelector) Report(_ [32]byte, signers []common.Address, faulty []common.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, addr := range signers {
+		outcome := 1.0
+		if slices.Contains(faulty, addr) {
+			outcome = 0
+		}
+		s.scores[addr] = (1-livenessDecay)*s.score(addr) + livenessDecay*outcome
+	}
+}
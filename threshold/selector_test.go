@@ -0,0 +1,144 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+func testCandidates(n int) []common.Address {
+	candidates := make([]common.Address, n)
+	for i := range candidates {
+		candidates[i] = common.Address{byte(i + 1)}
+	}
+	return candidates
+}
+
+// TestRoundRobinSelector tests that every candidate signs equally often
+func TestRoundRobinSelector(t *testing.T) {
+	s := NewRoundRobinSelector()
+	candidates := testCandidates(5)
+
+	counts := make(map[common.Address]int)
+	for i := 0; i < 10; i++ {
+		signers, err := s.Select([32]byte{0x01}, [32]byte{byte(i)}, candidates, 3)
+		if err != nil {
+			t.Fatalf("Select failed: %v", err)
+		}
+		for _, signer := range signers {
+			counts[signer]++
+		}
+	}
+	for _, c := range candidates {
+		if counts[c] != 6 {
+			t.Errorf("Expected candidate %s to sign 6 times, got %d", c, counts[c])
+		}
+	}
+
+	if _, err := s.Select([32]byte{0x01}, [32]byte{}, candidates, 6); err != ErrInsufficientParties {
+		t.Errorf("Expected ErrInsufficientParties, got %v", err)
+	}
+}
+
+// TestStakeSelector tests that signers are drawn in proportion to stake
+// and reproducibly per request
+func TestStakeSelector(t *testing.T) {
+	s := NewStakeSelector()
+	candidates := testCandidates(4)
+	s.SetStake(candidates[0], 100)
+	s.SetStake(candidates[1], 10)
+	s.SetStake(candidates[2], 10)
+
+	counts := make(map[common.Address]int)
+	for i := 0; i < 1000; i++ {
+		signers, err := s.Select([32]byte{}, [32]byte{byte(i), byte(i >> 8)}, candidates, 1)
+		if err != nil {
+			t.Fatalf("Select failed: %v", err)
+		}
+		counts[signers[0]]++
+	}
+	if counts[candidates[3]] != 0 {
+		t.Errorf("Candidate without stake selected")
+	}
+	if counts[candidates[0]] < 700 {
+		t.Errorf("Expected the largest stake to sign most sessions, got %d of 1000", counts[candidates[0]])
+	}
+
+	first, _ := s.Select([32]byte{}, [32]byte{0x42}, candidates, 2)
+	second, _ := s.Select([32]byte{}, [32]byte{0x42}, candidates, 2)
+	if !slices.Equal(first, second) {
+		t.Errorf("Selection differs for the same request")
+	}
+	if _, err := s.Select([32]byte{}, [32]byte{}, candidates, 4); err != ErrInsufficientParties {
+		t.Errorf("Expected ErrInsufficientParties with three staked candidates, got %v", err)
+	}
+}
+
+// TestLivenessSelector tests that signers named faulty are passed over
+// until they complete sessions again
+func TestLivenessSelector(t *testing.T) {
+	s := NewLivenessSelector()
+	candidates := testCandidates(4)
+
+	s.Report([32]byte{}, candidates[:3], []common.Address{candidates[0]})
+	if s.Score(candidates[0]) >= s.Score(candidates[1]) {
+		t.Errorf("Expected faulty signer to score lower")
+	}
+	for i := 0; i < 10; i++ {
+		signers, err := s.Select([32]byte{}, [32]byte{byte(i)}, candidates, 3)
+		if err != nil {
+			t.Fatalf("Select failed: %v", err)
+		}
+		if slices.Contains(signers, candidates[0]) {
+			t.Fatalf("Faulty signer selected over live candidates")
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		s.Report([32]byte{}, candidates[:1], nil)
+	}
+	if s.Score(candidates[0]) < 0.99 {
+		t.Errorf("Expected score to recover, got %f", s.Score(candidates[0]))
+	}
+}
+
+// TestManagerSignerSelection tests that a manager with a selector records
+// the chosen signers and skips stale ones
+func TestManagerSignerSelection(t *testing.T) {
+	tm := NewThresholdManager()
+	defer tm.Close()
+	tm.SignerSelector = NewLivenessSelector()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	keyID := setupTestKey(t, tm, owner)
+
+	key, _ := tm.GetKey(keyID)
+	signers := make([]common.Address, 4)
+	for i := range signers {
+		signers[i] = common.Address(key.Participants[i])
+	}
+	key.Permissions.AllowedSigners = signers
+	key.Generation = 2
+	for _, signer := range signers[1:] {
+		if err := tm.ReportShareGeneration(signer, keyID, 2); err != nil {
+			t.Fatalf("ReportShareGeneration failed: %v", err)
+		}
+	}
+
+	requestID, err := tm.RequestSignature(owner, keyID, [32]byte{0x01})
+	if err != nil {
+		t.Fatalf("RequestSignature failed: %v", err)
+	}
+	tm.mu.RLock()
+	chosen := slices.Clone(tm.SignRequests[requestID].Signers)
+	tm.mu.RUnlock()
+	if len(chosen) != int(key.Threshold)+1 {
+		t.Errorf("Expected %d signers, got %d", key.Threshold+1, len(chosen))
+	}
+	if slices.Contains(chosen, signers[0]) {
+		t.Errorf("Stale signer chosen")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"sync"
+)
+
+// Operation is a set of operations a caller may run on a key
+type Operation uint8
+
+const (
+	OpSign    Operation = 1 << iota // Sign with the key
+	OpRefresh                       // Refresh the key's shares
+
+	OpAll = OpSign | OpRefresh
+)
+
+// Policy decides which callers may run which operations. Callers are named
+// by the common name of their verified client certificate. Admins may run
+// every operation on every key and are the only callers allowed to generate
+// keys; other callers run only the operations granted to them per key.
+type Policy struct {
+	admins map[string]bool
+	grants map[[32]byte]map[string]Operation
+	mu     sync.RWMutex
+}
+
+// NewPolicy creates a policy with admins and no per-key grants
+func NewPolicy(admins ...string) *Policy {
+	p := &Policy{
+		admins: make(map[string]bool, len(admins)),
+		grants: make(map[[32]byte]map[string]Operation),
+	}
+	for _, admin := range admins {
+		p.admins[admin] = true
+	}
+	return p
+}
+
+// Grant allows identity to run ops on keyID, in addition to any operations
+// granted before
+func (p *Policy) Grant(keyID [32]byte, identity string, ops Operation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	grants, ok := p.grants[keyID]
+	if !ok {
+		grants = make(map[string]Operation)
+		p.grants[keyID] = grants
+	}
+	grants[identity] |= ops
+}
+
+// Revoke removes every grant of identity on keyID
+func (p *Policy) Revoke(keyID [32]byte, identity string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if grants, ok := p.grants[keyID]; ok {
+		delete(grants, identity)
+		if len(grants) == 0 {
+			delete(p.grants, keyID)
+		}
+	}
+}
+
+// IsAdmin reports whether identity is an admin
+func (p *Policy) IsAdmin(identity string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.admins[identity]
+}
+
+// Allowed reports whether identity may run every operation of ops on keyID
+func (p *Policy) Allowed(identity string, keyID [32]byte, ops Operation) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.admins[identity] {
+		return true
+	}
+	return ops != 0 && p.grants[keyID][identity]&ops == ops
+}
+
+// visible reports whether identity may see keyID, which it may when it can
+// run any operation on it
+func (p *Policy) visible(identity string, keyID [32]byte) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.admins[identity] || p.grants[keyID][identity] != 0
+}
@@ -0,0 +1,337 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package server exposes a ThresholdClient as a remote signer, so node
+// operators can run the MPC signer as a sidecar next to their node.
+//
+// The server speaks JSON-RPC 2.0 over HTTPS with mutual TLS: every caller
+// presents a client certificate issued by the operator's CA, and the common
+// name of the verified certificate names the caller to the Policy, which
+// authorizes each call per key. Protocol sessions run as the server's own
+// party over whatever transport the client has installed, so every node of
+// a session needs its sidecar to receive the same call.
+//
+// The Policy only names who may call. What a key may sign is enforced by
+// the client's SigningGuard: serve the client of the node's
+// ThresholdManager, or install the manager with SetSigningGuard, so keys
+// bound to a usage policy or restricted to typed data refuse raw hashes
+// here as they do on chain.
+//
+// Methods:
+//
+//	threshold_keygen    KeygenParams  -> KeyResult     (admins)
+//	threshold_sign      SignParams    -> SignResult    (OpSign on the key)
+//	threshold_refresh   RefreshParams -> true          (OpRefresh on the key)
+//	threshold_listKeys  none          -> []KeyResult   (keys the caller may use)
+//	threshold_health    none          -> HealthResult  (any caller)
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/geth/common/hexutil"
+	"github.com/luxfi/precompile/threshold"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeProtocolError  = -32000 // The threshold operation failed
+	CodeUnauthorized   = -32001 // No verified client certificate, or not allowed by policy
+)
+
+// Server limits
+const (
+	MaxRequestSize    = 1 << 20 // Largest request body read
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 30 * time.Second
+)
+
+// Error is a JSON-RPC error
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	errUnauthenticated = &Error{Code: CodeUnauthorized, Message: "verified client certificate required"}
+	errForbidden       = &Error{Code: CodeUnauthorized, Message: "operation not allowed by policy"}
+)
+
+// KeygenParams are the parameters of threshold_keygen
+type KeygenParams struct {
+	Protocol     threshold.Protocol `json:"protocol"`
+	KeyType      threshold.KeyType  `json:"keyType"`
+	Threshold    int                `json:"threshold"`
+	Participants []party.ID         `json:"participants"`
+}
+
+// SignParams are the parameters of threshold_sign
+type SignParams struct {
+	KeyID       common.Hash        `json:"keyId"`
+	Protocol    threshold.Protocol `json:"protocol"`
+	MessageHash common.Hash        `json:"messageHash"`
+	Signers     []party.ID         `json:"signers"`
+}
+
+// RefreshParams are the parameters of threshold_refresh
+type RefreshParams struct {
+	KeyID        common.Hash        `json:"keyId"`
+	Protocol     threshold.Protocol `json:"protocol"`
+	Participants []party.ID         `json:"participants"`
+}
+
+// KeyResult describes a key
+type KeyResult struct {
+	KeyID     common.Hash        `json:"keyId"`
+	Protocol  threshold.Protocol `json:"protocol"`
+	PublicKey hexutil.Bytes      `json:"publicKey"`
+}
+
+// SignResult is the result of threshold_sign
+type SignResult struct {
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// HealthResult is the result of threshold_health
+type HealthResult struct {
+	Status string   `json:"status"`
+	Self   party.ID `json:"self"`
+	Keys   int      `json:"keys"`
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// method runs one JSON-RPC method for caller
+type method func(ctx context.Context, caller string, params json.RawMessage) (interface{}, error)
+
+// Server serves a ThresholdClient's operations to authorized callers
+type Server struct {
+	client  *threshold.ThresholdClient
+	policy  *Policy
+	self    party.ID
+	methods map[string]method
+}
+
+var _ http.Handler = (*Server)(nil)
+
+// NewServer serves client's operations as party self, authorized by policy
+func NewServer(client *threshold.ThresholdClient, policy *Policy, self party.ID) *Server {
+	s := &Server{client: client, policy: policy, self: self}
+	s.methods = map[string]method{
+		"threshold_keygen":   s.keygen,
+		"threshold_sign":     s.sign,
+		"threshold_refresh":  s.refresh,
+		"threshold_listKeys": s.listKeys,
+		"threshold_health":   s.health,
+	}
+	return s
+}
+
+// NewTLSConfig returns a server TLS configuration that presents cert and
+// requires a client certificate issued by clientCAs
+func NewTLSConfig(cert tls.Certificate, clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	}
+}
+
+// ListenAndServe serves on addr with config, which should require client
+// certificates as NewTLSConfig does, until ctx ends
+func (s *Server) ListenAndServe(ctx context.Context, addr string, config *tls.Config) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		TLSConfig:         config,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServeTLS("", "")
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// ServeHTTP answers one JSON-RPC request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	resp := response{JSONRPC: "2.0"}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestSize)).Decode(&req); err != nil {
+		resp.Error = &Error{Code: CodeParseError, Message: "parse error"}
+		writeResponse(w, &resp)
+		return
+	}
+	resp.ID = req.ID
+
+	caller, ok := callerOf(r)
+	switch {
+	case !ok:
+		resp.Error = errUnauthenticated
+	case req.JSONRPC != "2.0" || req.Method == "":
+		resp.Error = &Error{Code: CodeInvalidRequest, Message: "invalid request"}
+	case s.methods[req.Method] == nil:
+		resp.Error = &Error{Code: CodeMethodNotFound, Message: "method not found"}
+	default:
+		result, err := s.methods[req.Method](r.Context(), caller, req.Params)
+		if err != nil {
+			var rpcErr *Error
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &Error{Code: CodeProtocolError, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+		} else {
+			resp.Result = result
+		}
+	}
+	writeResponse(w, &resp)
+}
+
+func writeResponse(w http.ResponseWriter, resp *response) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// callerOf returns the common name of the request's verified client
+// certificate
+func callerOf(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	return name, name != ""
+}
+
+// decodeParams decodes params into v, rejecting unknown fields
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return &Error{Code: CodeInvalidParams, Message: "missing params"}
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) keygen(ctx context.Context, caller string, params json.RawMessage) (interface{}, error) {
+	if !s.policy.IsAdmin(caller) {
+		return nil, errForbidden
+	}
+	var p KeygenParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.ExecuteKeygen(ctx, p.Protocol, p.KeyType, p.Threshold, p.Participants, s.self)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyResult{
+		KeyID:     result.KeyID,
+		Protocol:  p.Protocol,
+		PublicKey: result.PublicKey,
+	}, nil
+}
+
+func (s *Server) sign(ctx context.Context, caller string, params json.RawMessage) (interface{}, error) {
+	var p SignParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if !s.policy.Allowed(caller, p.KeyID, OpSign) {
+		return nil, errForbidden
+	}
+
+	result, err := s.client.ExecuteSigning(ctx, p.KeyID, p.Protocol, p.MessageHash, p.Signers, s.self)
+	if errors.Is(err, threshold.ErrTransactionRequired) || errors.Is(err, threshold.ErrBlindSigningDisabled) {
+		return nil, &Error{Code: CodeUnauthorized, Message: err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SignResult{Signature: result.Signature}, nil
+}
+
+func (s *Server) refresh(ctx context.Context, caller string, params json.RawMessage) (interface{}, error) {
+	var p RefreshParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if !s.policy.Allowed(caller, p.KeyID, OpRefresh) {
+		return nil, errForbidden
+	}
+
+	if err := s.client.ExecuteRefresh(ctx, p.KeyID, p.Protocol, p.Participants, s.self); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+func (s *Server) listKeys(_ context.Context, caller string, _ json.RawMessage) (interface{}, error) {
+	keys := make([]KeyResult, 0)
+	for _, key := range s.client.ListKeys() {
+		if s.policy.visible(caller, key.KeyID) {
+			keys = append(keys, KeyResult{
+				KeyID:     key.KeyID,
+				Protocol:  key.Protocol,
+				PublicKey: key.PublicKey,
+			})
+		}
+	}
+	return keys, nil
+}
+
+func (s *Server) health(_ context.Context, _ string, _ json.RawMessage) (interface{}, error) {
+	return &HealthResult{
+		Status: "ok",
+		Self:   s.self,
+		Keys:   len(s.client.ListKeys()),
+	}, nil
+}
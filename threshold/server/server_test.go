@@ -0,0 +1,186 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luxfi/precompile/threshold"
+	"github.com/luxfi/threshold/pkg/party"
+)
+
+type testResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// call sends method with params to s as caller, unauthenticated when
+// caller is empty
+func call(t *testing.T, s *Server, caller string, method string, params interface{}) *testResponse {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	if caller != "" {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: caller}}
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	var resp testResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid response %q: %v", rec.Body.String(), err)
+	}
+	return &resp
+}
+
+// TestServer tests keygen, signing and key listing through the server and
+// their authorization
+func TestServer(t *testing.T) {
+	client := threshold.NewThresholdClient()
+	defer client.Close()
+	policy := NewPolicy("admin")
+	s := NewServer(client, policy, "alice")
+
+	participants := []party.ID{"alice", "bob", "charlie"}
+	keygen := KeygenParams{
+		Protocol:     threshold.ProtocolFROST,
+		KeyType:      threshold.KeyTypeSecp256k1,
+		Threshold:    1,
+		Participants: participants,
+	}
+
+	if resp := call(t, s, "", "threshold_health", nil); resp.Error == nil || resp.Error.Code != CodeUnauthorized {
+		t.Errorf("Expected CodeUnauthorized without a client certificate, got %+v", resp.Error)
+	}
+	if resp := call(t, s, "operator", "threshold_keygen", keygen); resp.Error == nil || resp.Error.Code != CodeUnauthorized {
+		t.Errorf("Expected CodeUnauthorized for non-admin keygen, got %+v", resp.Error)
+	}
+	if resp := call(t, s, "admin", "threshold_unknown", nil); resp.Error == nil || resp.Error.Code != CodeMethodNotFound {
+		t.Errorf("Expected CodeMethodNotFound, got %+v", resp.Error)
+	}
+
+	resp := call(t, s, "admin", "threshold_keygen", keygen)
+	if resp.Error != nil {
+		t.Fatalf("threshold_keygen failed: %v", resp.Error)
+	}
+	var key KeyResult
+	if err := json.Unmarshal(resp.Result, &key); err != nil {
+		t.Fatalf("Invalid keygen result: %v", err)
+	}
+
+	sign := SignParams{
+		KeyID:       key.KeyID,
+		Protocol:    threshold.ProtocolFROST,
+		MessageHash: [32]byte{0x42},
+		Signers:     []party.ID{"alice", "bob"},
+	}
+	if resp := call(t, s, "operator", "threshold_sign", sign); resp.Error == nil || resp.Error.Code != CodeUnauthorized {
+		t.Errorf("Expected CodeUnauthorized before grant, got %+v", resp.Error)
+	}
+	var keys []KeyResult
+	if resp := call(t, s, "operator", "threshold_listKeys", nil); json.Unmarshal(resp.Result, &keys) != nil || len(keys) != 0 {
+		t.Errorf("Expected no visible keys before grant, got %s", resp.Result)
+	}
+
+	policy.Grant(key.KeyID, "operator", OpSign)
+	resp = call(t, s, "operator", "threshold_sign", sign)
+	if resp.Error != nil {
+		t.Fatalf("threshold_sign failed: %v", resp.Error)
+	}
+	var signed SignResult
+	if err := json.Unmarshal(resp.Result, &signed); err != nil {
+		t.Fatalf("Invalid sign result: %v", err)
+	}
+	ok, err := client.VerifySignature(key.KeyID, threshold.ProtocolFROST, sign.MessageHash, signed.Signature)
+	if err != nil || !ok {
+		t.Errorf("Signature does not verify: %v", err)
+	}
+
+	refresh := RefreshParams{KeyID: key.KeyID, Protocol: threshold.ProtocolFROST, Participants: participants}
+	if resp := call(t, s, "operator", "threshold_refresh", refresh); resp.Error == nil || resp.Error.Code != CodeUnauthorized {
+		t.Errorf("Expected CodeUnauthorized for refresh without grant, got %+v", resp.Error)
+	}
+
+	if resp := call(t, s, "operator", "threshold_listKeys", nil); json.Unmarshal(resp.Result, &keys) != nil || len(keys) != 1 || keys[0].KeyID != key.KeyID {
+		t.Errorf("Expected the granted key listed, got %s", resp.Result)
+	}
+
+	policy.Revoke(key.KeyID, "operator")
+	if policy.Allowed("operator", key.KeyID, OpSign) {
+		t.Errorf("Grant survived revoke")
+	}
+}
+
+// TestServerEnforcesKeyPolicy tests that keys bound to a usage policy or
+// restricted to typed data refuse raw hashes over RPC
+func TestServerEnforcesKeyPolicy(t *testing.T) {
+	client := threshold.NewThresholdClient()
+	defer client.Close()
+	manager := threshold.NewThresholdManager()
+	defer manager.Close()
+	client.SetSigningGuard(manager)
+	policy := NewPolicy("admin")
+	s := NewServer(client, policy, "alice")
+
+	resp := call(t, s, "admin", "threshold_keygen", KeygenParams{
+		Protocol:     threshold.ProtocolFROST,
+		KeyType:      threshold.KeyTypeSecp256k1,
+		Threshold:    1,
+		Participants: []party.ID{"alice", "bob", "charlie"},
+	})
+	if resp.Error != nil {
+		t.Fatalf("threshold_keygen failed: %v", resp.Error)
+	}
+	var key KeyResult
+	if err := json.Unmarshal(resp.Result, &key); err != nil {
+		t.Fatalf("Invalid keygen result: %v", err)
+	}
+	policy.Grant(key.KeyID, "operator", OpSign)
+
+	sign := SignParams{
+		KeyID:       key.KeyID,
+		Protocol:    threshold.ProtocolFROST,
+		MessageHash: [32]byte{0x42},
+		Signers:     []party.ID{"alice", "bob"},
+	}
+	manager.Keys[key.KeyID] = &threshold.ThresholdKey{KeyID: key.KeyID, Protocol: threshold.ProtocolFROST, Threshold: 1, TotalParties: 3}
+	manager.Policies[key.KeyID] = &threshold.KeyPolicy{RequiredApprovals: 2}
+	resp = call(t, s, "operator", "threshold_sign", sign)
+	if resp.Error == nil || resp.Error.Code != CodeUnauthorized || resp.Error.Message != threshold.ErrTransactionRequired.Error() {
+		t.Errorf("Expected ErrTransactionRequired for a policy-bound key, got %+v", resp.Error)
+	}
+
+	delete(manager.Policies, key.KeyID)
+	manager.Keys[key.KeyID].Permissions.TypedDataOnly = true
+	resp = call(t, s, "operator", "threshold_sign", sign)
+	if resp.Error == nil || resp.Error.Code != CodeUnauthorized || resp.Error.Message != threshold.ErrBlindSigningDisabled.Error() {
+		t.Errorf("Expected ErrBlindSigningDisabled for a typed-data key, got %+v", resp.Error)
+	}
+
+	// Unrestricted keys sign any hash
+	manager.Keys[key.KeyID].Permissions.TypedDataOnly = false
+	if resp := call(t, s, "operator", "threshold_sign", sign); resp.Error != nil {
+		t.Errorf("threshold_sign failed for an unrestricted key: %v", resp.Error)
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	ethtypes "github.com/luxfi/geth/core/types"
+	"github.com/luxfi/precompile/contract"
+)
+
+// Precompile state.
+//
+// Each threshold precompile keeps its keys and requests in the storage of
+// its own account, so every node sees the same records and a reverted call
+// leaves none behind. A record is a header word at
+//
+//	record = keccak256(domain || id)
+//
+// followed by data words at keccak256(record) + i. Key headers hold owner
+// || key type || status || typed-data-only flag || expiry, and their data
+// words the length and bytes of the public key. Keygen request headers hold
+// requester || key type || status || threshold || party count || expiry,
+// and one data word the generated key's ID. Signing request headers hold
+// requester || status || expiry, and their data words the key ID, message
+// hash and the length and bytes of the signature. Every header carries a
+// non-zero expiry, so a zero header means no record.
+//
+// Requests are numbered by a per-requester nonce kept in a slot of its own,
+// and expire against the block timestamp.
+
+// Domain separators for precompile storage and hashing
+const (
+	keyRecordDomain     = "lux.threshold.key.v1"
+	keygenRecordDomain  = "lux.threshold.keygen.v1"
+	signRecordDomain    = "lux.threshold.sign.v1"
+	schemaRecordDomain  = "lux.threshold.schema.v1"
+	requestNonceDomain  = "lux.threshold.nonce.v1"
+	requestIDDomain     = "lux.threshold.request.v1"
+	keygenResultDomain  = "lux.threshold.keygen.result.v1"
+	keygenRequestPrefix = "keygen"
+	signRequestPrefix   = "sign"
+)
+
+// How long the committee has to complete a request, in seconds
+const (
+	KeygenRequestTimeout = 10 * 60
+	SignRequestTimeout   = 5 * 60
+)
+
+// Log topics of the requests the committee serves
+var (
+	KeygenRequestedTopic = common.BytesToHash(luxcrypto.Keccak256([]byte("KeygenRequested(bytes32,address,uint8,uint32,address[])")))
+	SignRequestedTopic   = common.BytesToHash(luxcrypto.Keccak256([]byte("SignRequested(bytes32,bytes32,bytes32)")))
+)
+
+// committee is the T-Chain signer set whose attestations complete keygen
+type committee struct {
+	members   map[common.Address]bool
+	threshold int
+}
+
+// newCommittee creates a committee whose results must be signed by at
+// least threshold of members
+func newCommittee(members []common.Address, threshold int) (*committee, error) {
+	if threshold <= 0 || threshold > len(members) {
+		return nil, ErrInvalidCommittee
+	}
+	set := make(map[common.Address]bool, len(members))
+	for _, m := range members {
+		if m == (common.Address{}) || set[m] {
+			return nil, ErrInvalidCommittee
+		}
+		set[m] = true
+	}
+	return &committee{members: set, threshold: threshold}, nil
+}
+
+// attested reports whether at least the threshold of distinct members
+// signed digest. Signatures are 65-byte [R || S || V].
+func (c *committee) attested(digest common.Hash, signatures []byte) bool {
+	signed := make(map[common.Address]bool)
+	for i := 0; i+65 <= len(signatures); i += 65 {
+		sig := append([]byte(nil), signatures[i:i+65]...)
+		if sig[64] >= 27 {
+			sig[64] -= 27
+		}
+		pub, err := luxcrypto.Ecrecover(digest.Bytes(), sig)
+		if err != nil || len(pub) != 65 {
+			continue
+		}
+		if signer := common.BytesToAddress(luxcrypto.Keccak256(pub[1:])[12:]); c.members[signer] {
+			signed[signer] = true
+		}
+	}
+	return len(signed) >= c.threshold
+}
+
+// KeygenResultDigest returns the digest committee members sign to report
+// that keygen request requestID at precompile produced the key keyID
+func KeygenResultDigest(precompile common.Address, requestID, keyID [32]byte, publicKey []byte) common.Hash {
+	return common.BytesToHash(luxcrypto.Keccak256(
+		[]byte(keygenResultDomain),
+		precompile.Bytes(),
+		requestID[:],
+		keyID[:],
+		luxcrypto.Keccak256(publicKey),
+	))
+}
+
+// stateKey is a key record
+type stateKey struct {
+	Owner         common.Address
+	KeyType       KeyType
+	Status        KeyStatus
+	TypedDataOnly bool
+	ExpiresAt     uint64
+	PublicKey     []byte
+}
+
+// stateKeygen is a keygen request record
+type stateKeygen struct {
+	Requester common.Address
+	KeyType   KeyType
+	Status    KeygenStatus
+	Threshold uint8
+	Parties   uint8
+	ExpiresAt uint64
+	KeyID     [32]byte // Set once complete
+}
+
+// stateSign is a signing request record
+type stateSign struct {
+	Requester   common.Address
+	Status      SigningStatus
+	ExpiresAt   uint64
+	KeyID       [32]byte
+	MessageHash [32]byte
+	Signature   []byte // Set once complete
+}
+
+// store reads and writes the records of one precompile account
+type store struct {
+	db   contract.StateDB
+	addr common.Address
+}
+
+func recordSlot(domain string, id []byte) common.Hash {
+	return common.BytesToHash(luxcrypto.Keccak256([]byte(domain), id))
+}
+
+func dataSlot(record common.Hash, i int) common.Hash {
+	base := new(big.Int).SetBytes(luxcrypto.Keccak256(record.Bytes()))
+	return common.BigToHash(base.Add(base, big.NewInt(int64(i))))
+}
+
+// wordCount returns the number of words n bytes take
+func wordCount(n int) uint64 {
+	return uint64(n+31) / 32
+}
+
+// nextRequestID consumes the requester's nonce and returns the ID of its
+// next request of kind
+func (s *store) nextRequestID(kind string, requester common.Address) [32]byte {
+	slot := recordSlot(requestNonceDomain, requester.Bytes())
+	nonce := s.db.GetState(s.addr, slot).Big().Uint64()
+	s.db.SetState(s.addr, slot, common.BigToHash(new(big.Int).SetUint64(nonce+1)))
+	return common.BytesToHash(luxcrypto.Keccak256(
+		[]byte(requestIDDomain),
+		[]byte(kind),
+		s.addr.Bytes(),
+		requester.Bytes(),
+		binary.BigEndian.AppendUint64(nil, nonce),
+	))
+}
+
+// requestNonce returns the nonce the requester's next request takes
+func (s *store) requestNonce(requester common.Address) uint64 {
+	return s.db.GetState(s.addr, recordSlot(requestNonceDomain, requester.Bytes())).Big().Uint64()
+}
+
+// setBytes writes the length and contents of b to data words from i
+func (s *store) setBytes(record common.Hash, i int, b []byte) {
+	s.db.SetState(s.addr, dataSlot(record, i), common.BigToHash(big.NewInt(int64(len(b)))))
+	for w := 0; w*32 < len(b); w++ {
+		var word common.Hash
+		copy(word[:], b[w*32:])
+		s.db.SetState(s.addr, dataSlot(record, i+1+w), word)
+	}
+}
+
+// getBytes reads bytes written by setBytes
+func (s *store) getBytes(record common.Hash, i int) []byte {
+	n := int(s.db.GetState(s.addr, dataSlot(record, i)).Big().Uint64())
+	b := make([]byte, 0, n)
+	for w := 0; len(b) < n; w++ {
+		word := s.db.GetState(s.addr, dataSlot(record, i+1+w))
+		b = append(b, word[:min(32, n-len(b))]...)
+	}
+	return b
+}
+
+// key returns the key record of keyID
+func (s *store) key(keyID [32]byte) (*stateKey, bool) {
+	record := recordSlot(keyRecordDomain, keyID[:])
+	header := s.db.GetState(s.addr, record)
+	if header == (common.Hash{}) {
+		return nil, false
+	}
+	return &stateKey{
+		Owner:         common.BytesToAddress(header[:20]),
+		KeyType:       KeyType(header[20]),
+		Status:        KeyStatus(header[21]),
+		TypedDataOnly: header[22] == 1,
+		ExpiresAt:     binary.BigEndian.Uint64(header[24:]),
+		PublicKey:     s.getBytes(record, 0),
+	}, true
+}
+
+// setKeyHeader writes the header of a key record
+func (s *store) setKeyHeader(keyID [32]byte, key *stateKey) {
+	var header common.Hash
+	copy(header[:20], key.Owner.Bytes())
+	header[20] = byte(key.KeyType)
+	header[21] = byte(key.Status)
+	if key.TypedDataOnly {
+		header[22] = 1
+	}
+	binary.BigEndian.PutUint64(header[24:], key.ExpiresAt)
+	s.db.SetState(s.addr, recordSlot(keyRecordDomain, keyID[:]), header)
+}
+
+// putKey writes a new key record
+func (s *store) putKey(keyID [32]byte, key *stateKey) {
+	s.setKeyHeader(keyID, key)
+	s.setBytes(recordSlot(keyRecordDomain, keyID[:]), 0, key.PublicKey)
+}
+
+// keygen returns the keygen request record of requestID
+func (s *store) keygen(requestID [32]byte) (*stateKeygen, bool) {
+	record := recordSlot(keygenRecordDomain, requestID[:])
+	header := s.db.GetState(s.addr, record)
+	if header == (common.Hash{}) {
+		return nil, false
+	}
+	return &stateKeygen{
+		Requester: common.BytesToAddress(header[:20]),
+		KeyType:   KeyType(header[20]),
+		Status:    KeygenStatus(header[21]),
+		Threshold: header[22],
+		Parties:   header[23],
+		ExpiresAt: binary.BigEndian.Uint64(header[24:]),
+		KeyID:     s.db.GetState(s.addr, dataSlot(record, 0)),
+	}, true
+}
+
+// putKeygen writes a keygen request record
+func (s *store) putKeygen(requestID [32]byte, req *stateKeygen) {
+	record := recordSlot(keygenRecordDomain, requestID[:])
+	var header common.Hash
+	copy(header[:20], req.Requester.Bytes())
+	header[20] = byte(req.KeyType)
+	header[21] = byte(req.Status)
+	header[22] = req.Threshold
+	header[23] = req.Parties
+	binary.BigEndian.PutUint64(header[24:], req.ExpiresAt)
+	s.db.SetState(s.addr, record, header)
+	s.db.SetState(s.addr, dataSlot(record, 0), req.KeyID)
+}
+
+// sign returns the signing request record of requestID
+func (s *store) sign(requestID [32]byte) (*stateSign, bool) {
+	record := recordSlot(signRecordDomain, requestID[:])
+	header := s.db.GetState(s.addr, record)
+	if header == (common.Hash{}) {
+		return nil, false
+	}
+	req := &stateSign{
+		Requester:   common.BytesToAddress(header[:20]),
+		Status:      SigningStatus(header[20]),
+		ExpiresAt:   binary.BigEndian.Uint64(header[24:]),
+		KeyID:       s.db.GetState(s.addr, dataSlot(record, 0)),
+		MessageHash: s.db.GetState(s.addr, dataSlot(record, 1)),
+	}
+	if req.Status == SignStatusComplete {
+		req.Signature = s.getBytes(record, 2)
+	}
+	return req, true
+}
+
+// setSignHeader writes the header of a signing request record
+func (s *store) setSignHeader(requestID [32]byte, req *stateSign) {
+	var header common.Hash
+	copy(header[:20], req.Requester.Bytes())
+	header[20] = byte(req.Status)
+	binary.BigEndian.PutUint64(header[24:], req.ExpiresAt)
+	s.db.SetState(s.addr, recordSlot(signRecordDomain, requestID[:]), header)
+}
+
+// putSign writes a new signing request record
+func (s *store) putSign(requestID [32]byte, req *stateSign) {
+	record := recordSlot(signRecordDomain, requestID[:])
+	s.setSignHeader(requestID, req)
+	s.db.SetState(s.addr, dataSlot(record, 0), req.KeyID)
+	s.db.SetState(s.addr, dataSlot(record, 1), req.MessageHash)
+}
+
+// setSignature completes a signing request with its signature
+func (s *store) setSignature(requestID [32]byte, req *stateSign, signature []byte) {
+	req.Status = SignStatusComplete
+	req.Signature = signature
+	s.setSignHeader(requestID, req)
+	s.setBytes(recordSlot(signRecordDomain, requestID[:]), 2, signature)
+}
+
+// schemaAllowed reports whether schemaID is allowlisted for keyID
+func (s *store) schemaAllowed(keyID, schemaID [32]byte) bool {
+	return s.db.GetState(s.addr, recordSlot(schemaRecordDomain, append(keyID[:], schemaID[:]...))) != (common.Hash{})
+}
+
+// allowSchema allowlists schemaID for keyID
+func (s *store) allowSchema(keyID, schemaID [32]byte) {
+	s.db.SetState(s.addr, recordSlot(schemaRecordDomain, append(keyID[:], schemaID[:]...)), common.Hash{31: 1})
+}
+
+// keygenRequestedLog builds the KeygenRequested log the committee serves
+func keygenRequestedLog(addr common.Address, requestID [32]byte, requester common.Address, keyType KeyType, threshold uint32, participants []common.Address) *ethtypes.Log {
+	data := abiUintWord(uint64(keyType))
+	data = append(data, abiUintWord(uint64(threshold))...)
+	data = append(data, abiUintWord(96)...)
+	data = append(data, abiUintWord(uint64(len(participants)))...)
+	for _, p := range participants {
+		data = append(data, common.BytesToHash(p.Bytes()).Bytes()...)
+	}
+	return &ethtypes.Log{
+		Address: addr,
+		Topics:  []common.Hash{KeygenRequestedTopic, requestID, common.BytesToHash(requester.Bytes())},
+		Data:    data,
+	}
+}
+
+// signRequestedLog builds the SignRequested log the committee serves
+func signRequestedLog(addr common.Address, requestID, keyID, messageHash [32]byte) *ethtypes.Log {
+	return &ethtypes.Log{
+		Address: addr,
+		Topics:  []common.Hash{SignRequestedTopic, requestID, keyID},
+		Data:    append([]byte(nil), messageHash[:]...),
+	}
+}
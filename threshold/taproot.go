@@ -0,0 +1,520 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/luxfi/threshold/pkg/math/curve"
+	"github.com/luxfi/threshold/pkg/math/polynomial"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/protocols/frost"
+)
+
+// Taproot and adaptor signatures.
+//
+// A secp256k1 FROST key P can hold Bitcoin as a BIP-341 output key
+//
+//	Q = P' + t·G,  t = H_TapTweak(x(P) || merkleRoot)
+//
+// where P' is P or -P, whichever has an even y. Since Lagrange coefficients
+// sum to one, adding t to every share (after negating the shares with P)
+// yields shares of Q, so the signers can spend on the key path without
+// ever combining the key.
+//
+// Taproot signing is one round over pre-generated nonces (see
+// frost_nonces.go). A coordinator reserves a commitment of each signer and
+// sends them a TaprootSigningPackage. Each signer answers with
+// SignTaprootShare, and the coordinator combines the shares with
+// AggregateTaprootSignature into a BIP-340 signature, checking each share
+// against the signer's verification share so that a bad one names its
+// signer.
+//
+// With an adaptor point T in the package, the result is instead an adaptor
+// signature (R', s') with R' = R + T: a BIP-340 signature except that s'
+// lacks the discrete logarithm t of T. Anyone can check it with
+// VerifyAdaptorSignature. Whoever learns t completes it with
+// CompleteAdaptorSignature, and whoever sees both the adaptor signature
+// and the completed one recovers t with ExtractAdaptorSecret, which is the
+// exchange atomic swaps are built on.
+
+// Tags of BIP-340 and BIP-341 tagged hashes, and of the binding factors
+const (
+	tagTapTweak      = "TapTweak"
+	tagChallenge     = "BIP0340/challenge"
+	tagTaprootRho    = "LuxThreshold/taproot/rho"
+	adaptorSigSize   = 65 // R' (compressed) || s'
+	schnorrSigSize   = 64 // x(R) || s
+	compressedKeyLen = 33
+)
+
+// secp256k1N is the order of secp256k1
+var secp256k1N, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+// TaprootOutput is the BIP-341 output key of a FROST key
+type TaprootOutput struct {
+	InternalKey [32]byte // x(P)
+	OutputKey   [32]byte // x(Q), the witness program
+	OddY        bool     // Parity of Q, for script path control blocks
+	Tweak       [32]byte // t
+}
+
+// TaprootSigningPackage is what the coordinator sends each signer
+type TaprootSigningPackage struct {
+	KeyID       [32]byte
+	MerkleRoot  []byte // Script tree root; empty for a key path only output
+	MessageHash [32]byte
+	Adaptor     []byte // Compressed adaptor point T; empty for a plain signature
+	Commitments map[party.ID]FROSTNonceCommitment
+}
+
+// taprootKey holds the tweaked key and shares of one output
+type taprootKey struct {
+	output   *TaprootOutput
+	Q        curve.Point
+	share    curve.Scalar             // Own share of Q
+	verifies map[party.ID]curve.Point // Every party's share of Q times G
+	config   *frost.Config            // Untweaked config
+}
+
+// TweakPublicKey returns the BIP-341 output key of keyID for merkleRoot,
+// which is empty for an output without a script path (BIP-86) and 32 bytes
+// otherwise
+func (c *ThresholdClient) TweakPublicKey(keyID [32]byte, merkleRoot []byte) (*TaprootOutput, error) {
+	key, err := c.taprootKey(keyID, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return key.output, nil
+}
+
+// taprootKey tweaks this party's FROST config of keyID
+func (c *ThresholdClient) taprootKey(keyID [32]byte, merkleRoot []byte) (*taprootKey, error) {
+	if len(merkleRoot) != 0 && len(merkleRoot) != 32 {
+		return nil, ErrInvalidTweak
+	}
+
+	c.mu.RLock()
+	config, ok := c.frostConfigs[keyID]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	group := config.PublicKey.Curve()
+	if !isSecp256k1(group) {
+		return nil, ErrInvalidKeyType
+	}
+
+	P, oddP, err := xOnly(config.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	tweak := taggedHash(tagTapTweak, P[:], merkleRoot)
+	t := group.NewScalar()
+	if new(big.Int).SetBytes(tweak[:]).Cmp(secp256k1N) >= 0 || t.UnmarshalBinary(tweak[:]) != nil {
+		return nil, ErrInvalidTweak
+	}
+
+	// Shares of P' = ±P, then of Q = P' + t·G
+	share := group.NewScalar().Set(config.PrivateShare)
+	verifies := make(map[party.ID]curve.Point, len(config.VerificationShares.Points))
+	for id, point := range config.VerificationShares.Points {
+		verifies[id] = point
+	}
+	Q := config.PublicKey
+	if oddP {
+		share = share.Negate()
+		Q = Q.Negate()
+		for id, point := range verifies {
+			verifies[id] = point.Negate()
+		}
+	}
+	tG := t.ActOnBase()
+	share = share.Add(t)
+	Q = Q.Add(tG)
+	for id, point := range verifies {
+		verifies[id] = point.Add(tG)
+	}
+	if Q.IsIdentity() {
+		return nil, ErrInvalidTweak
+	}
+
+	// BIP-340 keys are x-only: sign for the even-y point
+	outputKey, oddQ, err := xOnly(Q)
+	if err != nil {
+		return nil, err
+	}
+	if oddQ {
+		share = share.Negate()
+		Q = Q.Negate()
+		for id, point := range verifies {
+			verifies[id] = point.Negate()
+		}
+	}
+
+	return &taprootKey{
+		output: &TaprootOutput{
+			InternalKey: P,
+			OutputKey:   outputKey,
+			OddY:        oddQ,
+			Tweak:       tweak,
+		},
+		Q:        Q,
+		share:    share,
+		verifies: verifies,
+		config:   config,
+	}, nil
+}
+
+// taprootRound holds what every party derives from a signing package
+type taprootRound struct {
+	signers []party.ID
+	nonces  map[party.ID]curve.Point // D_i + ρ_i·E_i
+	rho     map[party.ID]curve.Scalar
+	R       curve.Point // R' = R + T
+	negate  bool        // R' has an odd y, so nonces are negated
+	e       curve.Scalar
+}
+
+// newTaprootRound computes the binding factors, the group commitment and
+// the challenge of a signing package
+func newTaprootRound(key *taprootKey, pkg *TaprootSigningPackage) (*taprootRound, error) {
+	group := key.Q.Curve()
+	signers := make([]party.ID, 0, len(pkg.Commitments))
+	for id := range pkg.Commitments {
+		if _, ok := key.verifies[id]; !ok {
+			return nil, ErrUnknownSigner
+		}
+		signers = append(signers, id)
+	}
+	signers = sortedParties(signers)
+	if len(signers) < key.config.Threshold+1 {
+		return nil, ErrInsufficientParties
+	}
+
+	// Every binding factor commits to the whole package
+	var list bytes.Buffer
+	list.Write(key.output.OutputKey[:])
+	list.Write(pkg.MessageHash[:])
+	writeField(&list, pkg.Adaptor)
+	for _, id := range signers {
+		commitment := pkg.Commitments[id]
+		writeField(&list, []byte(id))
+		list.Write(binary.BigEndian.AppendUint64(nil, commitment.Index))
+		writeField(&list, commitment.Hiding)
+		writeField(&list, commitment.Binding)
+	}
+
+	round := &taprootRound{
+		signers: signers,
+		nonces:  make(map[party.ID]curve.Point, len(signers)),
+		rho:     make(map[party.ID]curve.Scalar, len(signers)),
+		R:       group.NewPoint(),
+	}
+	for _, id := range signers {
+		commitment := pkg.Commitments[id]
+		D, E := group.NewPoint(), group.NewPoint()
+		if D.UnmarshalBinary(commitment.Hiding) != nil || E.UnmarshalBinary(commitment.Binding) != nil {
+			return nil, ErrBadNonceCommitment
+		}
+		rho := scalarFromHash(group, taggedHash(tagTaprootRho, list.Bytes(), []byte(id)))
+		round.rho[id] = rho
+		round.nonces[id] = D.Add(rho.Act(E))
+		round.R = round.R.Add(round.nonces[id])
+	}
+
+	if len(pkg.Adaptor) > 0 {
+		T := group.NewPoint()
+		if len(pkg.Adaptor) != compressedKeyLen || T.UnmarshalBinary(pkg.Adaptor) != nil || T.IsIdentity() {
+			return nil, ErrInvalidAdaptor
+		}
+		round.R = round.R.Add(T)
+	}
+	if round.R.IsIdentity() {
+		return nil, ErrBadNonceCommitment
+	}
+
+	rx, odd, err := xOnly(round.R)
+	if err != nil {
+		return nil, err
+	}
+	round.negate = odd
+	round.e = bip340Challenge(group, rx, key.output.OutputKey, pkg.MessageHash)
+	return round, nil
+}
+
+// SignTaprootShare takes this party's nonce named in pkg and returns its
+// signature share. The nonce is spent even if signing fails.
+func (c *ThresholdClient) SignTaprootShare(pkg *TaprootSigningPackage) ([]byte, error) {
+	if err := c.authorizeSigning(pkg.KeyID, pkg.MessageHash); err != nil {
+		return nil, err
+	}
+	key, err := c.taprootKey(pkg.KeyID, pkg.MerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	self := key.config.ID
+	commitment, ok := pkg.Commitments[self]
+	if !ok {
+		return nil, ErrNotParticipant
+	}
+	round, err := newTaprootRound(key, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	d, e, err := c.TakeFROSTNonce(pkg.KeyID, commitment.Index)
+	if err != nil {
+		return nil, err
+	}
+	D, E := d.ActOnBase(), e.ActOnBase()
+	if hiding, _ := D.MarshalBinary(); !bytes.Equal(hiding, commitment.Hiding) {
+		return nil, ErrBadNonceCommitment
+	}
+	if binding, _ := E.MarshalBinary(); !bytes.Equal(binding, commitment.Binding) {
+		return nil, ErrBadNonceCommitment
+	}
+
+	// z_i = ±(d_i + ρ_i·e_i) + λ_i·q_i·c
+	group := key.Q.Curve()
+	k := group.NewScalar().Set(round.rho[self]).Mul(e).Add(d)
+	if round.negate {
+		k = k.Negate()
+	}
+	lambda := polynomial.Lagrange(group, round.signers)[self]
+	z := group.NewScalar().Set(lambda).Mul(key.share).Mul(round.e).Add(k)
+	return z.MarshalBinary()
+}
+
+// AggregateTaprootSignature checks and combines the signers' shares. It
+// returns a 64-byte BIP-340 signature, or a 65-byte adaptor signature when
+// pkg names an adaptor point.
+func (c *ThresholdClient) AggregateTaprootSignature(pkg *TaprootSigningPackage, shares map[party.ID][]byte) ([]byte, error) {
+	key, err := c.taprootKey(pkg.KeyID, pkg.MerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	round, err := newTaprootRound(key, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	group := key.Q.Curve()
+	lagrange := polynomial.Lagrange(group, round.signers)
+	s := group.NewScalar()
+	for _, id := range round.signers {
+		z := group.NewScalar()
+		if data, ok := shares[id]; !ok || z.UnmarshalBinary(data) != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBadSignatureShare, id)
+		}
+
+		// z_i·G = ±(D_i + ρ_i·E_i) + λ_i·c·Q_i
+		nonce := round.nonces[id]
+		if round.negate {
+			nonce = nonce.Negate()
+		}
+		want := group.NewScalar().Set(lagrange[id]).Mul(round.e).Act(key.verifies[id]).Add(nonce)
+		if !z.ActOnBase().Equal(want) {
+			return nil, fmt.Errorf("%w: %s", ErrBadSignatureShare, id)
+		}
+		s = s.Add(z)
+	}
+
+	sBytes, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if len(pkg.Adaptor) > 0 {
+		rBytes, err := round.R.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return append(rBytes, sBytes...), nil
+	}
+	rx, _, err := xOnly(round.R)
+	if err != nil {
+		return nil, err
+	}
+	return append(rx[:], sBytes...), nil
+}
+
+// VerifyTaprootSignature checks a BIP-340 signature under an x-only key
+func VerifyTaprootSignature(outputKey [32]byte, messageHash [32]byte, signature []byte) bool {
+	if len(signature) != schnorrSigSize {
+		return false
+	}
+	group := curve.Secp256k1{}
+	R, err := liftX(group, [32]byte(signature[:32]))
+	if err != nil {
+		return false
+	}
+	return verifySchnorr(group, outputKey, messageHash, R, signature[32:])
+}
+
+// VerifyAdaptorSignature checks that presig becomes a valid BIP-340
+// signature under outputKey once completed with the discrete logarithm of
+// the adaptor point
+func VerifyAdaptorSignature(outputKey [32]byte, messageHash [32]byte, adaptor []byte, presig []byte) bool {
+	group := curve.Secp256k1{}
+	R, s, err := parseAdaptorSignature(group, presig)
+	if err != nil {
+		return false
+	}
+	T := group.NewPoint()
+	if len(adaptor) != compressedKeyLen || T.UnmarshalBinary(adaptor) != nil || T.IsIdentity() {
+		return false
+	}
+	rx, odd, err := xOnly(R)
+	if err != nil {
+		return false
+	}
+
+	// s'·G = ±(R' - T) + c·Q, with the sign of R'
+	nonce := R.Sub(T)
+	if odd {
+		nonce = nonce.Negate()
+	}
+	Q, err := liftX(group, outputKey)
+	if err != nil {
+		return false
+	}
+	e := bip340Challenge(group, rx, outputKey, messageHash)
+	return s.ActOnBase().Equal(e.Act(Q).Add(nonce))
+}
+
+// CompleteAdaptorSignature completes presig with the adaptor secret into a
+// BIP-340 signature
+func CompleteAdaptorSignature(presig []byte, secret []byte) ([]byte, error) {
+	group := curve.Secp256k1{}
+	R, s, err := parseAdaptorSignature(group, presig)
+	if err != nil {
+		return nil, err
+	}
+	t := group.NewScalar()
+	if len(secret) != 32 || t.UnmarshalBinary(secret) != nil {
+		return nil, ErrInvalidAdaptor
+	}
+
+	rx, odd, err := xOnly(R)
+	if err != nil {
+		return nil, err
+	}
+	if odd {
+		t = t.Negate()
+	}
+	sBytes, err := s.Add(t).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(rx[:], sBytes...), nil
+}
+
+// ExtractAdaptorSecret recovers the adaptor secret from presig and the
+// signature it was completed into, and checks it against the adaptor point
+func ExtractAdaptorSecret(presig []byte, signature []byte, adaptor []byte) ([]byte, error) {
+	group := curve.Secp256k1{}
+	R, sPre, err := parseAdaptorSignature(group, presig)
+	if err != nil {
+		return nil, err
+	}
+	rx, odd, err := xOnly(R)
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != schnorrSigSize || !bytes.Equal(signature[:32], rx[:]) {
+		return nil, ErrInvalidSignature
+	}
+	s := group.NewScalar()
+	if s.UnmarshalBinary(signature[32:]) != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	t := s.Sub(sPre)
+	if odd {
+		t = t.Negate()
+	}
+	T := group.NewPoint()
+	if len(adaptor) != compressedKeyLen || T.UnmarshalBinary(adaptor) != nil || !t.ActOnBase().Equal(T) {
+		return nil, ErrInvalidAdaptor
+	}
+	return t.MarshalBinary()
+}
+
+// parseAdaptorSignature decodes R' || s'
+func parseAdaptorSignature(group curve.Curve, presig []byte) (curve.Point, curve.Scalar, error) {
+	if len(presig) != adaptorSigSize {
+		return nil, nil, ErrInvalidSignature
+	}
+	R, s := group.NewPoint(), group.NewScalar()
+	if R.UnmarshalBinary(presig[:compressedKeyLen]) != nil || R.IsIdentity() || s.UnmarshalBinary(presig[compressedKeyLen:]) != nil {
+		return nil, nil, ErrInvalidSignature
+	}
+	return R, s, nil
+}
+
+// verifySchnorr checks s·G = R + c·Q for the even-y nonce point R
+func verifySchnorr(group curve.Curve, outputKey [32]byte, messageHash [32]byte, R curve.Point, sBytes []byte) bool {
+	s := group.NewScalar()
+	if s.UnmarshalBinary(sBytes) != nil {
+		return false
+	}
+	Q, err := liftX(group, outputKey)
+	if err != nil {
+		return false
+	}
+	rx, _, err := xOnly(R)
+	if err != nil {
+		return false
+	}
+	e := bip340Challenge(group, rx, outputKey, messageHash)
+	return s.ActOnBase().Equal(e.Act(Q).Add(R))
+}
+
+// bip340Challenge returns H_challenge(x(R) || x(Q) || m) mod n
+func bip340Challenge(group curve.Curve, rx, qx [32]byte, messageHash [32]byte) curve.Scalar {
+	return scalarFromHash(group, taggedHash(tagChallenge, rx[:], qx[:], messageHash[:]))
+}
+
+// taggedHash is the BIP-340 tagged hash sha256(sha256(tag) || sha256(tag) || data)
+func taggedHash(tag string, data ...[]byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, d := range data {
+		h.Write(d)
+	}
+	return [32]byte(h.Sum(nil))
+}
+
+// scalarFromHash reduces a hash modulo the secp256k1 order
+func scalarFromHash(group curve.Curve, digest [32]byte) curve.Scalar {
+	reduced := new(big.Int).Mod(new(big.Int).SetBytes(digest[:]), secp256k1N)
+	s := group.NewScalar()
+	s.UnmarshalBinary(reduced.FillBytes(make([]byte, 32)))
+	return s
+}
+
+// xOnly returns the x coordinate of a secp256k1 point and whether its y is
+// odd
+func xOnly(p curve.Point) ([32]byte, bool, error) {
+	data, err := p.MarshalBinary()
+	if err != nil || len(data) != compressedKeyLen {
+		return [32]byte{}, false, ErrInvalidPublicKey
+	}
+	return [32]byte(data[1:]), data[0] == 0x03, nil
+}
+
+// liftX returns the point with x coordinate x and an even y
+func liftX(group curve.Curve, x [32]byte) (curve.Point, error) {
+	p := group.NewPoint()
+	if err := p.UnmarshalBinary(append([]byte{0x02}, x[:]...)); err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return p, nil
+}
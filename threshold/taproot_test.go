@@ -0,0 +1,185 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/threshold/pkg/math/curve"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+	"github.com/luxfi/threshold/protocols/frost"
+)
+
+// newFROSTSigners runs FROST keygen in process and gives each participant
+// a client holding its own share
+func newFROSTSigners(t *testing.T, participants []party.ID, threshold int) ([32]byte, map[party.ID]*ThresholdClient) {
+	t.Helper()
+
+	dealer := NewThresholdClient()
+	defer dealer.Close()
+	session := newSessionTag(sessionKeygen, ProtocolFROST, participants)
+	results, err := dealer.runSession(context.Background(), session, participants, participants[0], func(id party.ID) protocol.StartFunc {
+		return frost.Keygen(curve.Secp256k1{}, id, participants, threshold)
+	})
+	if err != nil {
+		t.Fatalf("FROST keygen failed: %v", err)
+	}
+
+	var keyID [32]byte
+	clients := make(map[party.ID]*ThresholdClient, len(participants))
+	for _, id := range participants {
+		config := results[id].(*frost.Config)
+		pub, _ := config.PublicKey.MarshalBinary()
+		keyID = DeriveKeyID(ProtocolFROST, KeyTypeSecp256k1, threshold, participants, pub)
+		client := NewThresholdClient()
+		client.frostConfigs[keyID] = config
+		clients[id] = client
+		t.Cleanup(client.Close)
+	}
+	return keyID, clients
+}
+
+// reserveTaprootRound publishes one nonce of each signer to the
+// coordinator and reserves them
+func reserveTaprootRound(t *testing.T, keyID [32]byte, clients map[party.ID]*ThresholdClient, coordinator *ThresholdClient, signers []party.ID) map[party.ID]FROSTNonceCommitment {
+	t.Helper()
+	for _, id := range signers {
+		commitments, err := clients[id].PregenerateFROSTNonces(keyID, 1)
+		if err != nil {
+			t.Fatalf("PregenerateFROSTNonces failed: %v", err)
+		}
+		if err := coordinator.PublishFROSTCommitments(keyID, id, commitments); err != nil {
+			t.Fatalf("PublishFROSTCommitments failed: %v", err)
+		}
+	}
+	reserved, err := coordinator.ReserveFROSTCommitments(keyID, signers)
+	if err != nil {
+		t.Fatalf("ReserveFROSTCommitments failed: %v", err)
+	}
+	return reserved
+}
+
+// TestTaprootSigning tests key path signing under a tweaked output key
+func TestTaprootSigning(t *testing.T) {
+	participants := []party.ID{"alice", "bob", "charlie"}
+	keyID, clients := newFROSTSigners(t, participants, 1)
+	coordinator := clients["alice"]
+
+	if _, err := coordinator.TweakPublicKey(keyID, []byte{0x01}); !errors.Is(err, ErrInvalidTweak) {
+		t.Errorf("Expected ErrInvalidTweak for short merkle root, got %v", err)
+	}
+	output, err := coordinator.TweakPublicKey(keyID, nil)
+	if err != nil {
+		t.Fatalf("TweakPublicKey failed: %v", err)
+	}
+	other, _ := clients["bob"].TweakPublicKey(keyID, nil)
+	if *other != *output {
+		t.Errorf("Parties disagree on the output key")
+	}
+	scripted, _ := coordinator.TweakPublicKey(keyID, bytes.Repeat([]byte{0x07}, 32))
+	if scripted.OutputKey == output.OutputKey || scripted.InternalKey != output.InternalKey {
+		t.Errorf("Expected the merkle root to change only the output key")
+	}
+
+	signers := []party.ID{"alice", "charlie"}
+	pkg := &TaprootSigningPackage{
+		KeyID:       keyID,
+		MessageHash: [32]byte{0x42},
+		Commitments: reserveTaprootRound(t, keyID, clients, coordinator, signers),
+	}
+	shares := make(map[party.ID][]byte)
+	for _, id := range signers {
+		share, err := clients[id].SignTaprootShare(pkg)
+		if err != nil {
+			t.Fatalf("SignTaprootShare failed: %v", err)
+		}
+		shares[id] = share
+	}
+	if _, err := clients["charlie"].SignTaprootShare(pkg); !errors.Is(err, ErrNonceConsumed) {
+		t.Errorf("Expected ErrNonceConsumed on second share, got %v", err)
+	}
+
+	signature, err := coordinator.AggregateTaprootSignature(pkg, shares)
+	if err != nil {
+		t.Fatalf("AggregateTaprootSignature failed: %v", err)
+	}
+	if !VerifyTaprootSignature(output.OutputKey, pkg.MessageHash, signature) {
+		t.Errorf("Signature does not verify under the output key")
+	}
+	if VerifyTaprootSignature(output.InternalKey, pkg.MessageHash, signature) {
+		t.Errorf("Signature verifies under the internal key")
+	}
+
+	shares["charlie"] = shares["alice"]
+	if _, err := coordinator.AggregateTaprootSignature(pkg, shares); !errors.Is(err, ErrBadSignatureShare) {
+		t.Errorf("Expected ErrBadSignatureShare, got %v", err)
+	}
+}
+
+// TestAdaptorSignature tests that an adaptor signature completes with the
+// adaptor secret, which the completed signature then reveals
+func TestAdaptorSignature(t *testing.T) {
+	participants := []party.ID{"alice", "bob", "charlie"}
+	keyID, clients := newFROSTSigners(t, participants, 1)
+	coordinator := clients["bob"]
+
+	secretKey, _ := luxcrypto.GenerateKey()
+	secret := luxcrypto.FromECDSA(secretKey)
+	adaptor := luxcrypto.CompressPubkey(&secretKey.PublicKey)
+
+	merkleRoot := bytes.Repeat([]byte{0x01}, 32)
+	output, err := coordinator.TweakPublicKey(keyID, merkleRoot)
+	if err != nil {
+		t.Fatalf("TweakPublicKey failed: %v", err)
+	}
+
+	signers := []party.ID{"alice", "bob"}
+	pkg := &TaprootSigningPackage{
+		KeyID:       keyID,
+		MerkleRoot:  merkleRoot,
+		MessageHash: [32]byte{0x24},
+		Adaptor:     adaptor,
+		Commitments: reserveTaprootRound(t, keyID, clients, coordinator, signers),
+	}
+	shares := make(map[party.ID][]byte)
+	for _, id := range signers {
+		share, err := clients[id].SignTaprootShare(pkg)
+		if err != nil {
+			t.Fatalf("SignTaprootShare failed: %v", err)
+		}
+		shares[id] = share
+	}
+	presig, err := coordinator.AggregateTaprootSignature(pkg, shares)
+	if err != nil {
+		t.Fatalf("AggregateTaprootSignature failed: %v", err)
+	}
+
+	if !VerifyAdaptorSignature(output.OutputKey, pkg.MessageHash, adaptor, presig) {
+		t.Fatalf("Adaptor signature does not verify")
+	}
+	if VerifyTaprootSignature(output.OutputKey, pkg.MessageHash, append(presig[1:33:33], presig[33:]...)) {
+		t.Errorf("Adaptor signature verifies without the secret")
+	}
+
+	signature, err := CompleteAdaptorSignature(presig, secret)
+	if err != nil {
+		t.Fatalf("CompleteAdaptorSignature failed: %v", err)
+	}
+	if !VerifyTaprootSignature(output.OutputKey, pkg.MessageHash, signature) {
+		t.Errorf("Completed signature does not verify")
+	}
+
+	extracted, err := ExtractAdaptorSecret(presig, signature, adaptor)
+	if err != nil {
+		t.Fatalf("ExtractAdaptorSecret failed: %v", err)
+	}
+	if !bytes.Equal(extracted, secret) {
+		t.Errorf("Extracted %x, expected %x", extracted, secret)
+	}
+}
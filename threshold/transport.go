@@ -0,0 +1,659 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+)
+
+// Network transport
+//
+// ThresholdClient exchanges protocol messages through a Transport. By
+// default every participant runs in this process over a LocalTransport,
+// which suits tests and single-operator deployments. Validators that each
+// hold one share install a network transport with SetTransport; each node
+// then runs only its own party's handler, and keygen, signing and refresh
+// complete once every participant's node makes the same call.
+//
+// Messages travel in Envelopes that carry the session they belong to and
+// are signed by the sending party through a MessageAuthenticator. The
+// client drops envelopes whose signature does not verify against their
+// From party, so a peer cannot inject messages in another party's name.
+// Session IDs bind the key, the message signed and the participants, so an
+// envelope is only accepted by the session it was sent in. Repeating a call
+// repeats its session ID, so each envelope also carries a sequence number
+// its sender signs and never reuses; a party accepts each sequence number
+// of a sender once, and drops envelopes recorded in an earlier session and
+// replayed into a later one.
+// StreamTransport carries envelopes as length-prefixed frames over any
+// reliable byte stream: a TCP or TLS connection, a gRPC bidirectional
+// stream or a libp2p stream.
+
+// envelopeDomain separates envelope signatures from other signatures
+const envelopeDomain = "lux.threshold.envelope.v1"
+
+// Transport limits
+const (
+	MaxEnvelopeSize   = 16 << 20 // Largest frame a StreamTransport reads
+	sessionInboxSize  = 1000     // Envelopes buffered per party and session
+	maxPendingSession = 64       // Sessions buffered before this node joins them
+	replayWindowSize  = 4096     // Sequence numbers of a sender tracked for replays
+)
+
+// Envelope is one protocol message on the wire
+type Envelope struct {
+	Session   [32]byte // Session the message belongs to
+	From      party.ID // Sending party
+	To        party.ID // Receiving party, empty for broadcast
+	Seq       uint64   // Sender's sequence number, never reused
+	Payload   []byte   // Encoded protocol.Message
+	Signature []byte   // Sender's signature over Digest
+}
+
+// Digest returns the digest the sender signs
+func (e *Envelope) Digest() [32]byte {
+	h := sha256.New()
+	h.Write([]byte(envelopeDomain))
+	h.Write(e.Session[:])
+	writeField(h, []byte(e.From))
+	writeField(h, []byte(e.To))
+	h.Write(binary.BigEndian.AppendUint64(nil, e.Seq))
+	writeField(h, e.Payload)
+	var digest [32]byte
+	h.Sum(digest[:0])
+	return digest
+}
+
+// writeField writes a length-prefixed field
+func writeField(w io.Writer, field []byte) {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(field)))
+	w.Write(size[:])
+	w.Write(field)
+}
+
+// readField reads a length-prefixed field
+func readField(r *bytes.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if uint64(n) > uint64(r.Len()) {
+		return nil, ErrInvalidEnvelope
+	}
+	field := make([]byte, n)
+	io.ReadFull(r, field)
+	return field, nil
+}
+
+// MarshalBinary encodes the envelope as
+// session (32) || seq (8) || from || to || payload || signature, each field
+// after the sequence number prefixed with its 4-byte length
+func (e *Envelope) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(e.Session[:])
+	buf.Write(binary.BigEndian.AppendUint64(nil, e.Seq))
+	writeField(&buf, []byte(e.From))
+	writeField(&buf, []byte(e.To))
+	writeField(&buf, e.Payload)
+	writeField(&buf, e.Signature)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an envelope encoded by MarshalBinary
+func (e *Envelope) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if _, err := io.ReadFull(r, e.Session[:]); err != nil {
+		return ErrInvalidEnvelope
+	}
+	var seq [8]byte
+	if _, err := io.ReadFull(r, seq[:]); err != nil {
+		return ErrInvalidEnvelope
+	}
+	e.Seq = binary.BigEndian.Uint64(seq[:])
+	var fields [4][]byte
+	for i := range fields {
+		var err error
+		if fields[i], err = readField(r); err != nil {
+			return err
+		}
+	}
+	if r.Len() != 0 || len(fields[0]) == 0 {
+		return ErrInvalidEnvelope
+	}
+	e.From, e.To = party.ID(fields[0]), party.ID(fields[1])
+	e.Payload, e.Signature = fields[2], fields[3]
+	return nil
+}
+
+// Transport delivers envelopes between the parties of a session
+type Transport interface {
+	// Send delivers env to party to
+	Send(ctx context.Context, to party.ID, env *Envelope) error
+	// Broadcast delivers env to each party of to
+	Broadcast(ctx context.Context, to []party.ID, env *Envelope) error
+	// Receive returns the envelopes delivered to party self. The channel
+	// is closed when the transport is closed.
+	Receive(self party.ID) <-chan *Envelope
+}
+
+// MessageAuthenticator signs outgoing envelopes and checks incoming ones
+type MessageAuthenticator interface {
+	// Sign signs digest as party from
+	Sign(from party.ID, digest [32]byte) ([]byte, error)
+	// Verify reports whether signature is party from's signature of digest
+	Verify(from party.ID, digest [32]byte, signature []byte) bool
+}
+
+// ECDSAAuthenticator signs with a validator's secp256k1 key. Party IDs are
+// validator addresses, as participantAddressToPartyID forms them, and an
+// envelope is accepted when its signature recovers to the From address.
+type ECDSAAuthenticator struct {
+	self party.ID
+	key  *ecdsa.PrivateKey
+}
+
+var _ MessageAuthenticator = (*ECDSAAuthenticator)(nil)
+
+// NewECDSAAuthenticator creates an authenticator that signs as the party
+// whose ID is key's address
+func NewECDSAAuthenticator(key *ecdsa.PrivateKey) *ECDSAAuthenticator {
+	pub := luxcrypto.FromECDSAPub(&key.PublicKey)
+	addr := common.BytesToAddress(luxcrypto.Keccak256(pub[1:])[12:])
+	return &ECDSAAuthenticator{self: participantAddressToPartyID(addr), key: key}
+}
+
+// Self returns the party ID the authenticator signs as
+func (a *ECDSAAuthenticator) Self() party.ID {
+	return a.self
+}
+
+// Sign signs digest as from, which must be the authenticator's own party
+func (a *ECDSAAuthenticator) Sign(from party.ID, digest [32]byte) ([]byte, error) {
+	if from != a.self {
+		return nil, fmt.Errorf("%w: cannot sign as %s", ErrUnauthorized, from)
+	}
+	return luxcrypto.Sign(digest[:], a.key)
+}
+
+// Verify recovers the signer of digest and compares it with from
+func (a *ECDSAAuthenticator) Verify(from party.ID, digest [32]byte, signature []byte) bool {
+	if len(signature) != 65 || !common.IsHexAddress(string(from)) {
+		return false
+	}
+	pub, err := luxcrypto.Ecrecover(digest[:], signature)
+	if err != nil || len(pub) != 65 {
+		return false
+	}
+	return common.BytesToAddress(luxcrypto.Keccak256(pub[1:])[12:]) == common.HexToAddress(string(from))
+}
+
+// LocalTransport delivers envelopes between parties in this process
+type LocalTransport struct {
+	inboxes   map[party.ID]chan *Envelope
+	done      chan struct{}
+	closed    bool
+	closeOnce sync.Once
+	mu        sync.RWMutex
+}
+
+var _ Transport = (*LocalTransport)(nil)
+
+// NewLocalTransport creates a transport between parties
+func NewLocalTransport(parties []party.ID) *LocalTransport {
+	t := &LocalTransport{
+		inboxes: make(map[party.ID]chan *Envelope, len(parties)),
+		done:    make(chan struct{}),
+	}
+	for _, p := range parties {
+		t.inboxes[p] = make(chan *Envelope, sessionInboxSize)
+	}
+	return t
+}
+
+// Send delivers env to to's inbox
+func (t *LocalTransport) Send(ctx context.Context, to party.ID, env *Envelope) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return ErrTransportClosed
+	}
+	inbox, ok := t.inboxes[to]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPeer, to)
+	}
+	select {
+	case inbox <- env:
+		return nil
+	case <-t.done:
+		return ErrTransportClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Broadcast delivers env to each inbox of to
+func (t *LocalTransport) Broadcast(ctx context.Context, to []party.ID, env *Envelope) error {
+	for _, p := range to {
+		if err := t.Send(ctx, p, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Receive returns self's inbox, or nil if self is not a party
+func (t *LocalTransport) Receive(self party.ID) <-chan *Envelope {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.inboxes[self]
+}
+
+// Close closes every inbox
+func (t *LocalTransport) Close() {
+	t.closeOnce.Do(func() {
+		// Release senders blocked on a full inbox before taking the write lock
+		close(t.done)
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.closed = true
+		for _, inbox := range t.inboxes {
+			close(inbox)
+		}
+	})
+}
+
+// StreamTransport carries envelopes over one reliable byte stream per peer.
+// The host dials or accepts the streams, authenticated at the connection
+// level if it wishes, and registers them with AddPeer.
+type StreamTransport struct {
+	self    party.ID
+	peers   map[party.ID]*streamPeer
+	inbox   chan *Envelope
+	done    chan struct{}
+	closed  bool
+	readers sync.WaitGroup
+	mu      sync.RWMutex
+}
+
+type streamPeer struct {
+	conn io.ReadWriteCloser
+	mu   sync.Mutex // Serializes frame writes
+}
+
+var _ Transport = (*StreamTransport)(nil)
+
+// NewStreamTransport creates a stream transport for party self
+func NewStreamTransport(self party.ID) *StreamTransport {
+	return &StreamTransport{
+		self:  self,
+		peers: make(map[party.ID]*streamPeer),
+		inbox: make(chan *Envelope, sessionInboxSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// AddPeer registers the stream to party id, replacing any earlier one, and
+// reads envelopes from it until it fails or the transport is closed.
+// Envelopes on the stream must come from id.
+func (t *StreamTransport) AddPeer(id party.ID, conn io.ReadWriteCloser) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return ErrTransportClosed
+	}
+	if old, ok := t.peers[id]; ok {
+		old.conn.Close()
+	}
+	peer := &streamPeer{conn: conn}
+	t.peers[id] = peer
+	t.readers.Add(1)
+	go t.readLoop(id, peer)
+	return nil
+}
+
+// RemovePeer closes and forgets the stream to party id
+func (t *StreamTransport) RemovePeer(id party.ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removePeer(id, nil)
+}
+
+// removePeer removes id's stream if it is peer, or any stream if peer is
+// nil. The caller holds t.mu.
+func (t *StreamTransport) removePeer(id party.ID, peer *streamPeer) {
+	current, ok := t.peers[id]
+	if !ok || (peer != nil && current != peer) {
+		return
+	}
+	current.conn.Close()
+	delete(t.peers, id)
+}
+
+func (t *StreamTransport) readLoop(id party.ID, peer *streamPeer) {
+	defer t.readers.Done()
+	defer func() {
+		t.mu.Lock()
+		t.removePeer(id, peer)
+		t.mu.Unlock()
+	}()
+
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(peer.conn, size[:]); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n > MaxEnvelopeSize {
+			return
+		}
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(peer.conn, frame); err != nil {
+			return
+		}
+
+		env := new(Envelope)
+		if env.UnmarshalBinary(frame) != nil || env.From != id {
+			continue
+		}
+		if env.To != "" && env.To != t.self {
+			continue
+		}
+		select {
+		case t.inbox <- env:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// Send writes env as one frame to the stream of party to
+func (t *StreamTransport) Send(ctx context.Context, to party.ID, env *Envelope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t.mu.RLock()
+	peer, ok := t.peers[to]
+	closed := t.closed
+	t.mu.RUnlock()
+	if closed {
+		return ErrTransportClosed
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPeer, to)
+	}
+
+	data, err := env.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(data)), uint32(len(data)))
+	frame = append(frame, data...)
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+	if conn, ok := peer.conn.(net.Conn); ok {
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetWriteDeadline(deadline)
+			defer conn.SetWriteDeadline(time.Time{})
+		}
+	}
+	if _, err := peer.conn.Write(frame); err != nil {
+		t.mu.Lock()
+		t.removePeer(to, peer)
+		t.mu.Unlock()
+		return fmt.Errorf("send to %s: %w", to, err)
+	}
+	return nil
+}
+
+// Broadcast writes env to the stream of each party of to
+func (t *StreamTransport) Broadcast(ctx context.Context, to []party.ID, env *Envelope) error {
+	for _, p := range to {
+		if err := t.Send(ctx, p, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Receive returns the envelopes read from every peer, or nil if self is
+// not this transport's party
+func (t *StreamTransport) Receive(self party.ID) <-chan *Envelope {
+	if self != t.self {
+		return nil
+	}
+	return t.inbox
+}
+
+// Close closes every stream and then the receive channel
+func (t *StreamTransport) Close() {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	close(t.done)
+	for id := range t.peers {
+		t.removePeer(id, nil)
+	}
+	t.mu.Unlock()
+
+	t.readers.Wait()
+	close(t.inbox)
+}
+
+// sealMessage wraps msg in an envelope for session numbered seq, signed by
+// auth when set
+func sealMessage(session [32]byte, seq uint64, msg *protocol.Message, auth MessageAuthenticator) (*Envelope, error) {
+	payload, err := msg.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	env := &Envelope{Session: session, From: msg.From, Seq: seq, Payload: payload}
+	if !msg.Broadcast {
+		env.To = msg.To
+	}
+	if auth != nil {
+		if env.Signature, err = auth.Sign(env.From, env.Digest()); err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+// openEnvelope returns the message in env if it belongs to session, its
+// signature verifies against its sender and the message claims the same
+// sender and recipient
+func openEnvelope(session [32]byte, env *Envelope, auth MessageAuthenticator) (*protocol.Message, bool) {
+	if env.Session != session {
+		return nil, false
+	}
+	if auth != nil && !auth.Verify(env.From, env.Digest(), env.Signature) {
+		return nil, false
+	}
+	msg := new(protocol.Message)
+	if err := msg.UnmarshalBinary(env.Payload); err != nil || msg.From != env.From {
+		return nil, false
+	}
+	if msg.Broadcast != (env.To == "") || !msg.Broadcast && msg.To != env.To {
+		return nil, false
+	}
+	return msg, true
+}
+
+// replayGuard numbers the envelopes one party sends and rejects envelopes
+// it has already received. Sequence numbers start from the time the guard
+// was created, so they keep increasing across restarts of the sender.
+type replayGuard struct {
+	next    atomic.Uint64
+	windows map[party.ID]*replayWindow
+	mu      sync.Mutex
+}
+
+func newReplayGuard() *replayGuard {
+	g := &replayGuard{windows: make(map[party.ID]*replayWindow)}
+	g.next.Store(uint64(time.Now().UnixNano()))
+	return g
+}
+
+// seq returns the sequence number of the next envelope sent
+func (g *replayGuard) seq() uint64 {
+	return g.next.Add(1)
+}
+
+// accept reports whether seq is new from sender from and records it
+func (g *replayGuard) accept(from party.ID, seq uint64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	w, ok := g.windows[from]
+	if !ok {
+		w = new(replayWindow)
+		g.windows[from] = w
+	}
+	return w.accept(seq)
+}
+
+// replayWindow records the last replayWindowSize sequence numbers seen from
+// one sender. Numbers older than the window are rejected, so envelopes
+// delayed behind more than replayWindowSize later ones are dropped.
+type replayWindow struct {
+	top  uint64
+	seen [replayWindowSize]uint64
+}
+
+func (w *replayWindow) accept(seq uint64) bool {
+	if seq == 0 || seq+replayWindowSize <= w.top {
+		return false
+	}
+	slot := &w.seen[seq%replayWindowSize]
+	if *slot == seq {
+		return false
+	}
+	*slot = seq
+	if seq > w.top {
+		w.top = seq
+	}
+	return true
+}
+
+// pendingTTL bounds how long envelopes of a session this node has not
+// joined are kept
+const pendingTTL = time.Minute
+
+// pendingSession holds envelopes that arrived before this node joined
+// their session
+type pendingSession struct {
+	envelopes []*Envelope
+	since     time.Time
+}
+
+// sessionMux routes the envelopes a transport delivers to one party into
+// per-session inboxes. Faster peers start a session before this node does,
+// so envelopes of sessions not yet open are held, for up to pendingTTL,
+// and handed over when the session opens.
+type sessionMux struct {
+	sessions map[[32]byte]chan *Envelope
+	pending  map[[32]byte]*pendingSession
+	mu       sync.Mutex
+}
+
+// newSessionMux routes the envelopes received on in
+func newSessionMux(in <-chan *Envelope) *sessionMux {
+	m := &sessionMux{
+		sessions: make(map[[32]byte]chan *Envelope),
+		pending:  make(map[[32]byte]*pendingSession),
+	}
+	if in != nil {
+		go m.run(in)
+	}
+	return m
+}
+
+func (m *sessionMux) run(in <-chan *Envelope) {
+	for env := range in {
+		m.dispatch(env)
+	}
+}
+
+// dispatch delivers env to its session's inbox, or holds it until the
+// session opens. Envelopes past the buffer limits are dropped.
+func (m *sessionMux) dispatch(env *Envelope) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if inbox, ok := m.sessions[env.Session]; ok {
+		select {
+		case inbox <- env:
+		default:
+		}
+		return
+	}
+
+	now := time.Now()
+	for id, p := range m.pending {
+		if now.Sub(p.since) > pendingTTL {
+			delete(m.pending, id)
+		}
+	}
+	p, ok := m.pending[env.Session]
+	if !ok {
+		if len(m.pending) >= maxPendingSession {
+			return
+		}
+		p = &pendingSession{since: now}
+		m.pending[env.Session] = p
+	}
+	if len(p.envelopes) < sessionInboxSize {
+		p.envelopes = append(p.envelopes, env)
+	}
+}
+
+// open returns the inbox of session, holding any envelopes that arrived
+// before it opened
+func (m *sessionMux) open(session [32]byte) <-chan *Envelope {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inbox := make(chan *Envelope, sessionInboxSize)
+	if p, ok := m.pending[session]; ok {
+		for _, env := range p.envelopes {
+			inbox <- env
+		}
+		delete(m.pending, session)
+	}
+	m.sessions[session] = inbox
+	return inbox
+}
+
+// close ends session and closes its inbox
+func (m *sessionMux) close(session [32]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if inbox, ok := m.sessions[session]; ok {
+		close(inbox)
+		delete(m.sessions, session)
+	}
+}
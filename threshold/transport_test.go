@@ -0,0 +1,357 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package threshold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	luxcrypto "github.com/luxfi/crypto"
+	"github.com/luxfi/threshold/pkg/math/curve"
+	"github.com/luxfi/threshold/pkg/party"
+	"github.com/luxfi/threshold/pkg/protocol"
+	"github.com/luxfi/threshold/protocols/frost"
+)
+
+// TestEnvelopeEncoding tests envelope round trips and malformed input
+func TestEnvelopeEncoding(t *testing.T) {
+	env := &Envelope{
+		Session:   [32]byte{0x01},
+		From:      "a",
+		To:        "b",
+		Seq:       7,
+		Payload:   []byte{0xde, 0xad},
+		Signature: []byte{0xbe, 0xef},
+	}
+	data, err := env.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	decoded := new(Envelope)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if decoded.Session != env.Session || decoded.From != env.From || decoded.To != env.To || decoded.Seq != env.Seq ||
+		!bytes.Equal(decoded.Payload, env.Payload) || !bytes.Equal(decoded.Signature, env.Signature) {
+		t.Errorf("Decoded envelope does not match original")
+	}
+	if decoded.Digest() != env.Digest() {
+		t.Errorf("Digest changed across encoding")
+	}
+
+	if err := new(Envelope).UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("Expected ErrInvalidEnvelope for truncated envelope, got %v", err)
+	}
+	if err := new(Envelope).UnmarshalBinary(append(data, 0)); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Errorf("Expected ErrInvalidEnvelope for trailing bytes, got %v", err)
+	}
+}
+
+// TestECDSAAuthenticator tests that envelopes verify only against their
+// signer
+func TestECDSAAuthenticator(t *testing.T) {
+	key, err := luxcrypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	other, _ := luxcrypto.GenerateKey()
+	auth := NewECDSAAuthenticator(key)
+	otherAuth := NewECDSAAuthenticator(other)
+
+	digest := (&Envelope{From: auth.Self(), Payload: []byte("msg")}).Digest()
+	sig, err := auth.Sign(auth.Self(), digest)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if !otherAuth.Verify(auth.Self(), digest, sig) {
+		t.Errorf("Valid signature rejected")
+	}
+	if otherAuth.Verify(otherAuth.Self(), digest, sig) {
+		t.Errorf("Signature accepted for another party")
+	}
+	if auth.Verify(auth.Self(), [32]byte{0xff}, sig) {
+		t.Errorf("Signature accepted for another digest")
+	}
+	if _, err := auth.Sign(otherAuth.Self(), digest); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized signing as another party, got %v", err)
+	}
+}
+
+// TestLocalTransport tests delivery and close
+func TestLocalTransport(t *testing.T) {
+	parties := []party.ID{"a", "b", "c"}
+	lt := NewLocalTransport(parties)
+	ctx := context.Background()
+
+	env := &Envelope{From: "a", Payload: []byte{0x01}}
+	if err := lt.Broadcast(ctx, peersOf(parties, "a"), env); err != nil {
+		t.Fatalf("Broadcast failed: %v", err)
+	}
+	for _, p := range []party.ID{"b", "c"} {
+		if got := <-lt.Receive(p); got != env {
+			t.Errorf("Party %s did not receive the envelope", p)
+		}
+	}
+	if err := lt.Send(ctx, "d", env); !errors.Is(err, ErrUnknownPeer) {
+		t.Errorf("Expected ErrUnknownPeer, got %v", err)
+	}
+
+	lt.Close()
+	if _, ok := <-lt.Receive("a"); ok {
+		t.Errorf("Inbox still open after Close")
+	}
+	if err := lt.Send(ctx, "b", env); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Expected ErrTransportClosed, got %v", err)
+	}
+}
+
+// TestStreamTransport tests envelopes framed over a stream and rejection of
+// envelopes a peer sends in another party's name
+func TestStreamTransport(t *testing.T) {
+	connA, connB := net.Pipe()
+	a := NewStreamTransport("a")
+	b := NewStreamTransport("b")
+	defer a.Close()
+	defer b.Close()
+	if err := a.AddPeer("b", connA); err != nil {
+		t.Fatalf("AddPeer failed: %v", err)
+	}
+	if err := b.AddPeer("a", connB); err != nil {
+		t.Fatalf("AddPeer failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Spoofed sender is dropped, the next envelope arrives
+	if err := a.Send(ctx, "b", &Envelope{From: "c", To: "b", Payload: []byte{0x01}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	env := &Envelope{Session: [32]byte{0x02}, From: "a", To: "b", Payload: []byte{0x02}}
+	if err := a.Send(ctx, "b", env); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	select {
+	case got := <-b.Receive("b"):
+		if got.From != "a" || got.Session != env.Session || !bytes.Equal(got.Payload, env.Payload) {
+			t.Errorf("Received envelope does not match sent one")
+		}
+	case <-ctx.Done():
+		t.Fatal("Envelope not received")
+	}
+
+	if err := a.Send(ctx, "c", env); !errors.Is(err, ErrUnknownPeer) {
+		t.Errorf("Expected ErrUnknownPeer, got %v", err)
+	}
+}
+
+// TestSessionMux tests that envelopes arriving before a session opens are
+// handed over when it does
+func TestSessionMux(t *testing.T) {
+	m := newSessionMux(nil)
+	early := &Envelope{Session: [32]byte{0x01}, From: "a"}
+	m.dispatch(early)
+
+	inbox := m.open([32]byte{0x01})
+	if got := <-inbox; got != early {
+		t.Errorf("Pending envelope not delivered on open")
+	}
+
+	late := &Envelope{Session: [32]byte{0x01}, From: "b"}
+	m.dispatch(late)
+	if got := <-inbox; got != late {
+		t.Errorf("Envelope not routed to open session")
+	}
+
+	m.close([32]byte{0x01})
+	if _, ok := <-inbox; ok {
+		t.Errorf("Inbox still open after close")
+	}
+
+	for i := 0; i < maxPendingSession+1; i++ {
+		m.dispatch(&Envelope{Session: [32]byte{0x10, byte(i)}, From: "a"})
+	}
+	if len(m.pending) != maxPendingSession {
+		t.Errorf("Expected %d pending sessions, got %d", maxPendingSession, len(m.pending))
+	}
+}
+
+// TestSessionID tests that session IDs do not depend on participant order
+func TestSessionID(t *testing.T) {
+	a := sessionID(sessionSign, ProtocolCGGMP21, []party.ID{"a", "b"}, []byte{0x01})
+	b := sessionID(sessionSign, ProtocolCGGMP21, []party.ID{"b", "a"}, []byte{0x01})
+	if a != b {
+		t.Errorf("Session ID depends on participant order")
+	}
+	if a == sessionID(sessionSign, ProtocolFROST, []party.ID{"a", "b"}, []byte{0x01}) {
+		t.Errorf("Session ID does not bind the protocol")
+	}
+}
+
+// acceptAllAuthenticator signs nothing and accepts every envelope
+type acceptAllAuthenticator struct{}
+
+func (acceptAllAuthenticator) Sign(party.ID, [32]byte) ([]byte, error) { return nil, nil }
+func (acceptAllAuthenticator) Verify(party.ID, [32]byte, []byte) bool  { return true }
+
+// newStalledSession returns a client whose only peer never joins its
+// sessions
+func newStalledSession(t *testing.T) (*ThresholdClient, []party.ID, func(party.ID) protocol.StartFunc) {
+	parties := []party.ID{"a", "b"}
+	lt := NewLocalTransport(parties)
+	t.Cleanup(lt.Close)
+
+	client := NewThresholdClient()
+	t.Cleanup(client.Close)
+	if err := client.SetTransport(lt, acceptAllAuthenticator{}); err != nil {
+		t.Fatalf("SetTransport failed: %v", err)
+	}
+	start := func(id party.ID) protocol.StartFunc {
+		return frost.Keygen(curve.Secp256k1{}, id, parties, 1)
+	}
+	return client, parties, start
+}
+
+// TestRoundTimeout tests that a stalled peer fails the session with the
+// peer named
+func TestRoundTimeout(t *testing.T) {
+	client, parties, start := newStalledSession(t)
+	client.SetRoundTimeout(100 * time.Millisecond)
+
+	_, err := client.runSession(context.Background(), sessionTag{id: [32]byte{0x01}}, parties, "a", start)
+	var timeoutErr *ProtocolTimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, ErrProtocolTimeout) {
+		t.Fatalf("Expected ProtocolTimeoutError, got %v", err)
+	}
+	if len(timeoutErr.Missing) != 1 || timeoutErr.Missing[0] != "b" {
+		t.Errorf("Expected party b missing, got %v", timeoutErr.Missing)
+	}
+}
+
+// TestSessionCancellation tests that sessions end with their context
+func TestSessionCancellation(t *testing.T) {
+	client, parties, start := newStalledSession(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.runSession(ctx, sessionTag{id: [32]byte{0x01}}, parties, "a", start); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.runSession(ctx, sessionTag{id: [32]byte{0x02}}, parties, "a", start); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// TestReplayWindow tests that each sequence number of a sender is accepted
+// once
+func TestReplayWindow(t *testing.T) {
+	g := newReplayGuard()
+	if !g.accept("a", 100) || !g.accept("b", 100) {
+		t.Fatalf("Expected new sequence numbers accepted")
+	}
+	if g.accept("a", 100) {
+		t.Errorf("Replayed sequence number accepted")
+	}
+	if !g.accept("a", 90) || g.accept("a", 90) {
+		t.Errorf("Expected late sequence number accepted once")
+	}
+	if !g.accept("a", 100+replayWindowSize) {
+		t.Fatalf("Expected new sequence number accepted")
+	}
+	if g.accept("a", 99) || g.accept("a", 0) {
+		t.Errorf("Sequence number outside the window accepted")
+	}
+	if next := g.seq(); g.seq() <= next {
+		t.Errorf("Sequence numbers do not increase")
+	}
+}
+
+// recordingTransport records the envelopes sent through a LocalTransport
+type recordingTransport struct {
+	*LocalTransport
+	sent []*Envelope
+	mu   sync.Mutex
+}
+
+func (r *recordingTransport) Send(ctx context.Context, to party.ID, env *Envelope) error {
+	r.mu.Lock()
+	r.sent = append(r.sent, env)
+	r.mu.Unlock()
+	return r.LocalTransport.Send(ctx, to, env)
+}
+
+func (r *recordingTransport) Broadcast(ctx context.Context, to []party.ID, env *Envelope) error {
+	r.mu.Lock()
+	r.sent = append(r.sent, env)
+	r.mu.Unlock()
+	return r.LocalTransport.Broadcast(ctx, to, env)
+}
+
+// TestReplayedEnvelopes tests that envelopes recorded in one signing
+// session are dropped when replayed into a later session signing the same
+// message with the same signers
+func TestReplayedEnvelopes(t *testing.T) {
+	signers := []party.ID{"a", "b"}
+	keyID, clients := newFROSTSigners(t, signers, 1)
+
+	lt := NewLocalTransport(signers)
+	defer lt.Close()
+	rt := &recordingTransport{LocalTransport: lt}
+	for _, client := range clients {
+		if err := client.SetTransport(rt, acceptAllAuthenticator{}); err != nil {
+			t.Fatalf("SetTransport failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	messageHash := [32]byte{0x42}
+	sign := func() {
+		var wg sync.WaitGroup
+		for _, id := range signers {
+			wg.Add(1)
+			go func(id party.ID) {
+				defer wg.Done()
+				result, err := clients[id].ExecuteSigning(ctx, keyID, ProtocolFROST, messageHash, signers, id)
+				if err != nil {
+					t.Errorf("ExecuteSigning failed for %s: %v", id, err)
+					return
+				}
+				ok, err := clients[id].VerifySignature(keyID, ProtocolFROST, messageHash, result.Signature)
+				if err != nil || !ok {
+					t.Errorf("Signature of %s does not verify: %v", id, err)
+				}
+			}(id)
+		}
+		wg.Wait()
+	}
+
+	sign()
+	if t.Failed() {
+		return
+	}
+
+	// Replay a's envelopes of the first session to b ahead of the second
+	rt.mu.Lock()
+	recorded := slices.Clone(rt.sent)
+	rt.mu.Unlock()
+	for _, env := range recorded {
+		if env.From == "a" {
+			if err := lt.Send(ctx, "b", env); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+		}
+	}
+	sign()
+}
@@ -8,8 +8,10 @@ package threshold
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/luxfi/geth/common"
+	"github.com/luxfi/threshold/pkg/party"
 )
 
 // Precompile addresses for threshold operations
@@ -35,6 +37,14 @@ const (
 	GasVerify       = uint64(25000)  // Signature verification
 	GasGetPublicKey = uint64(5000)   // Get public key
 	GasGetKeyInfo   = uint64(5000)   // Get key metadata
+	GasSignTyped    = uint64(110000) // EIP-712 hashing + threshold signing
+	GasRegisterType = uint64(20000)  // Register EIP-712 schema
+	GasGetNonce     = uint64(2600)   // Query a requester's signing nonce
+
+	GasFulfillKeygen  = uint64(50000) // Record a committee keygen result
+	GasFulfillSign    = uint64(50000) // Verify and record a signature
+	GasPerAttestation = uint64(3000)  // Recover one committee signature
+	GasPerWord        = uint64(20000) // Store one word of a key or signature
 )
 
 // Protocol represents a threshold signature protocol
@@ -67,7 +77,8 @@ type ThresholdKey struct {
 	Address      common.Address // Derived EVM address (for ECDSA keys)
 	Threshold    uint32         // t (t+1 signatures required)
 	TotalParties uint32         // n (total parties)
-	Generation   uint64         // Key generation number (increments on reshare)
+	Participants [][20]byte     // Shareholder node IDs
+	Generation   uint64         // Share generation (increments on refresh and reshare)
 	CreatedAt    uint64         // Creation timestamp
 	LastRefresh  uint64         // Last refresh timestamp
 	ExpiresAt    uint64         // Expiration timestamp (0 = no expiry)
@@ -95,6 +106,7 @@ type KeyPermissions struct {
 	MaxSignsPerDay uint64           // Daily signing limit
 	SignsToday     uint64           // Signs used today
 	LastResetDay   uint64           // Last daily reset
+	TypedDataOnly  bool             // Only sign allowlisted EIP-712 payloads
 }
 
 // SigningRequest represents a threshold signing request
@@ -106,9 +118,10 @@ type SigningRequest struct {
 	RequestedAt uint64         // Request timestamp
 	ExpiresAt   uint64         // Request expiry
 	Status      SigningStatus
-	Signature   []byte   // Final threshold signature
-	PartialSigs [][]byte // Partial signatures collected
-	PartyCount  uint32   // Number of parties that signed
+	Signature   []byte           // Final threshold signature
+	PartialSigs [][]byte         // Partial signatures collected
+	PartyCount  uint32           // Number of parties that signed
+	Signers     []common.Address // Signers chosen for the session
 }
 
 // SigningStatus represents the status of a signing request
@@ -216,8 +229,84 @@ var (
 	ErrInsufficientParties  = errors.New("insufficient parties for threshold")
 	ErrKeygenInProgress     = errors.New("keygen already in progress")
 	ErrProtocolMismatch     = errors.New("protocol mismatch for operation")
+	ErrInvalidTypedData     = errors.New("invalid EIP-712 typed data")
+	ErrSchemaNotAllowed     = errors.New("typed data schema not allowlisted for key")
+	ErrBlindSigningDisabled = errors.New("key only signs allowlisted typed data")
+	ErrInvalidSessionPkg    = errors.New("invalid signing session package")
+	ErrSessionPkgReplay     = errors.New("signing session package already imported")
+	ErrNoPendingMessages    = errors.New("no pending messages for air-gapped signer")
+	ErrSessionClosed        = errors.New("signing session closed")
+	ErrInvalidPolicy        = errors.New("invalid key usage policy")
+	ErrNotShareholder       = errors.New("not a shareholder of key")
+	ErrProposalNotFound     = errors.New("policy proposal not found")
+	ErrStaleProposal        = errors.New("policy changed since proposal")
+	ErrTransactionRequired  = errors.New("key policy only allows signing EVM transactions")
+	ErrInvalidTransaction   = errors.New("invalid or unprotected EVM transaction")
+	ErrUnknownSigner        = errors.New("signer is not a party of key")
+	ErrChainNotAllowed      = errors.New("chain ID not allowed by key policy")
+	ErrDestinationDenied    = errors.New("destination not allowed by key policy")
+	ErrValueLimitExceeded   = errors.New("value limit exceeded for policy period")
+	ErrApprovalsRequired    = errors.New("insufficient shareholder approvals")
+	ErrInvalidNonceBatch    = errors.New("invalid FROST nonce batch size")
+	ErrBadNonceCommitment   = errors.New("invalid FROST nonce commitment")
+	ErrNonceConsumed        = errors.New("FROST nonce already consumed")
+	ErrNoncesExhausted      = errors.New("no unused FROST nonce commitments for signer")
+	ErrInvalidRequestNonce  = errors.New("signing request nonce is not the requester's current nonce")
+	ErrInvalidNonceQuery    = errors.New("invalid request nonce query")
+	ErrInvalidSchedule      = errors.New("invalid refresh schedule")
+	ErrInvalidEnvelope      = errors.New("invalid protocol message envelope")
+	ErrTransportClosed      = errors.New("transport closed")
+	ErrUnknownPeer          = errors.New("no connection to party")
+	ErrNotParticipant       = errors.New("self is not a participant of session")
+	ErrAuthRequired         = errors.New("network transport requires a message authenticator")
+	ErrProtocolTimeout      = errors.New("threshold protocol round timed out")
+	ErrInvalidPresignBatch  = errors.New("invalid CGGMP21 presignature batch size")
+	ErrNoPresignature       = errors.New("no unused presignature for signer set")
+	ErrProtocolAborted      = errors.New("threshold protocol aborted")
+	ErrInvalidCalldata      = errors.New("invalid threshold precompile calldata")
+	ErrWriteProtection      = errors.New("write protection")
+	ErrNoState              = errors.New("threshold precompile requires state")
+	ErrNoCommittee          = errors.New("no threshold committee configured")
+	ErrInvalidCommittee     = errors.New("invalid threshold committee")
+	ErrAttestation          = errors.New("insufficient committee attestations")
+	ErrRequestFulfilled     = errors.New("request already fulfilled")
+	ErrInvalidSignBatch     = errors.New("invalid batch signing size")
+	ErrInvalidPrivateKey    = errors.New("invalid secp256k1 private key")
+	ErrImportOverNetwork    = errors.New("key import runs every party in process")
+	ErrInvalidRecipient     = errors.New("invalid export recipient public key")
+	ErrInvalidExport        = errors.New("invalid exported key share")
+	ErrExportNotApproved    = errors.New("key share export not approved by shareholder quorum")
+	ErrKeyExists            = errors.New("key already exists")
+	ErrStaleShareGeneration = errors.New("signer holds a share from before the last refresh")
+	ErrInvalidGeneration    = errors.New("share generation ahead of key")
+	ErrInvalidTweak         = errors.New("invalid taproot tweak")
+	ErrInvalidAdaptor       = errors.New("invalid adaptor point or secret")
+	ErrBadSignatureShare    = errors.New("invalid signature share")
+	ErrInvalidPublicKey     = errors.New("invalid public key")
+	ErrReshareInProcess     = errors.New("reshare needs each shareholder's node on a network transport")
+	ErrBadSubShare          = errors.New("invalid reshare sub-share")
+	ErrInvalidSealedShare   = errors.New("invalid sealed key share")
+	ErrCeremonyInProcess    = errors.New("DKG ceremony needs each participant's node on a network transport")
+	ErrCeremonyRejected     = errors.New("DKG ceremony parameters rejected")
+	ErrInvalidCeremony      = errors.New("invalid DKG ceremony parameters or report")
 )
 
+// ProtocolTimeoutError reports a session round that did not complete in
+// time and the parties that had not sent their messages for it
+type ProtocolTimeoutError struct {
+	Round   int        // Round the local party was waiting in
+	Missing []party.ID // Parties not heard from in that round
+}
+
+func (e *ProtocolTimeoutError) Error() string {
+	return fmt.Sprintf("%v: round %d, no messages from %v", ErrProtocolTimeout, e.Round, e.Missing)
+}
+
+// Unwrap makes errors.Is match ErrProtocolTimeout
+func (e *ProtocolTimeoutError) Unwrap() error {
+	return ErrProtocolTimeout
+}
+
 // DefaultKeyExpiry is the default key expiration (90 days)
 const DefaultKeyExpiry = 90 * 24 * 60 * 60
 
@@ -338,17 +338,17 @@ interface IPrivacyPool {
  * @notice Nullifier operations for double-spend prevention at 0x0921
  */
 interface INullifier {
-    /// @notice Check if nullifier has been spent
-    function isSpent(bytes32 nullifierHash) external view returns (bool);
+    /// @notice Check if nullifier has been spent within a pool or circuit domain
+    function isSpent(bytes32 domain, bytes32 nullifierHash) external view returns (bool);
 
-    /// @notice Mark nullifier as spent (only callable by privacy pool)
-    function spend(bytes32 nullifierHash) external returns (bool);
+    /// @notice Mark nullifier as spent within a domain (only callable by privacy pool)
+    function spend(bytes32 domain, bytes32 nullifierHash) external returns (bool);
 
-    /// @notice Batch check nullifiers
-    function batchIsSpent(bytes32[] calldata nullifiers) external view returns (bool[] memory);
+    /// @notice Batch check nullifiers within a domain
+    function batchIsSpent(bytes32 domain, bytes32[] calldata nullifiers) external view returns (bool[] memory);
 
     /// @notice Event emitted when nullifier is spent
-    event NullifierSpent(bytes32 indexed nullifierHash, address indexed pool, uint256 timestamp);
+    event NullifierSpent(bytes32 indexed domain, bytes32 indexed nullifierHash, address indexed pool, uint256 timestamp);
 }
 
 /**
@@ -0,0 +1,503 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/luxfi/geth/common"
+)
+
+// Groth16 phase-2 ceremony verification.
+//
+// In the circuit-specific phase 2 of a Groth16 setup (BGM17) each contributor
+// samples a secret δᵢ and multiplies the running δ by it, in both G1 and G2.
+// Alongside the updated δ the contributor publishes a proof of knowledge of
+// δᵢ: a point S in G1, S·δᵢ, and R·δᵢ where R = HashToG2(prev || S || S·δᵢ).
+//
+// A contribution is accepted when:
+//  1. PoK:         e(S, R·δᵢ)   == e(S·δᵢ, R)
+//  2. Update:      e(δ_new, R)  == e(δ_prev, R·δᵢ)  (G1 ratio matches PoK)
+//  3. Consistency: e(δ_new, g₂) == e(g₁, δ_new₂)    (G1 and G2 agree)
+//
+// The chain starts from δ = 1, i.e. the generators. Every contribution also
+// divides the L and H queries of the proving key by δᵢ, so once the δ chain
+// is verified the final queries must be the initial ones divided by the
+// final δ: e(L_final, δ_final₂) == e(L_initial, g₂), and the same for H.
+// This is checked over a random linear combination of the elements.
+//
+// The phase-1 powers of tau the ceremony builds on can optionally be checked
+// for consistency (τⁱ⁺¹·g₁ / τⁱ·g₁ == τ·g₂ / g₂) using a random linear
+// combination so the cost is two pairings regardless of size.
+
+// Ceremony encoding sizes (uncompressed, big-endian, imaginary part first)
+const (
+	ceremonyG1Size           = 64
+	ceremonyG2Size           = 128
+	ceremonyContributionSize = 2*ceremonyG1Size + ceremonyG2Size + ceremonyG1Size + ceremonyG2Size
+	// MaxCeremonyContributions bounds the length of a verifiable transcript
+	MaxCeremonyContributions = 1024
+)
+
+// ceremonyHashDST is the domain separation tag for the PoK hash-to-G2
+var ceremonyHashDST = []byte("LUX_GROTH16_PHASE2_POK_BN254G2_XMD:SHA-256_SSWU_RO_")
+
+var (
+	ErrInvalidCeremony      = errors.New("invalid ceremony transcript")
+	ErrCeremonyPoKFailed    = errors.New("ceremony contribution proof of knowledge failed")
+	ErrCeremonyUpdateFailed = errors.New("ceremony delta update inconsistent with proof")
+	ErrCeremonyG1G2Mismatch = errors.New("ceremony delta G1/G2 mismatch")
+	ErrCeremonyInitialDelta = errors.New("ceremony initial delta is not the generator")
+	ErrCeremonyQueryFailed  = errors.New("ceremony L/H queries inconsistent with delta")
+	ErrCeremonyReadOnly     = errors.New("cannot attest a ceremony in read-only mode")
+	ErrCeremonyTauMismatch  = errors.New("powers of tau inconsistent")
+	ErrCeremonyNotAttested  = errors.New("verifying key not attested by a ceremony")
+	ErrTooManyContributions = errors.New("too many ceremony contributions")
+)
+
+// Phase2Contribution is a single participant's update to δ and its PoK
+type Phase2Contribution struct {
+	DeltaG1   bn254.G1Affine // δ after this contribution (G1)
+	DeltaG2   bn254.G2Affine // δ after this contribution (G2)
+	PoKS      bn254.G1Affine // Random base S
+	PoKSDelta bn254.G1Affine // S·δᵢ
+	PoKRDelta bn254.G2Affine // R·δᵢ, R = HashToG2(prev || S || S·δᵢ)
+}
+
+// PowersOfTau holds the phase-1 powers the ceremony builds on
+type PowersOfTau struct {
+	TauG1 []bn254.G1Affine // τⁱ·g₁ for i = 0..n
+	TauG2 []bn254.G2Affine // τⁱ·g₂ for i = 0..1 (at least)
+}
+
+// Phase2Transcript is the full contribution chain of a phase-2 ceremony
+type Phase2Transcript struct {
+	InitialDeltaG1 bn254.G1Affine // δ before any contribution (g₁)
+	InitialDeltaG2 bn254.G2Affine // δ before any contribution (g₂)
+	Contributions  []Phase2Contribution
+	InitialL       []bn254.G1Affine // L query before any contribution
+	FinalL         []bn254.G1Affine // L query after the last contribution
+	InitialH       []bn254.G1Affine // H query before any contribution
+	FinalH         []bn254.G1Affine // H query after the last contribution
+	PowersOfTau    *PowersOfTau     // Optional phase-1 consistency check
+}
+
+// CeremonyAttestation records a verified ceremony for VK provenance
+type CeremonyAttestation struct {
+	TranscriptHash [32]byte // Hash over the full contribution chain
+	FinalDeltaG2   []byte   // Final δ in G2 (VerifyingKey.Delta encoding)
+	Contributions  uint32   // Number of verified contributions
+	AttestedAt     uint64   // Timestamp of attestation
+}
+
+// CeremonyGas returns the gas charged to verify a transcript with the given
+// number of contributions, powers of tau and L/H query elements
+func CeremonyGas(numContributions, numTauPowers, numQueryElements int) uint64 {
+	return GasCeremonyBase + GasCeremonyQueryChecks +
+		uint64(numContributions)*GasCeremonyPerContribution +
+		uint64(numTauPowers)*GasCeremonyPerTauPower +
+		uint64(numQueryElements)*GasCeremonyPerQueryElement
+}
+
+// VerifyPhase2Transcript verifies every contribution in the chain and the
+// optional powers of tau. It returns the transcript hash on success.
+func VerifyPhase2Transcript(t *Phase2Transcript) ([32]byte, error) {
+	if t == nil || len(t.Contributions) == 0 {
+		return [32]byte{}, ErrInvalidCeremony
+	}
+	if len(t.Contributions) > MaxCeremonyContributions {
+		return [32]byte{}, ErrTooManyContributions
+	}
+	if len(t.InitialL) == 0 || len(t.InitialL) != len(t.FinalL) ||
+		len(t.InitialH) == 0 || len(t.InitialH) != len(t.FinalH) {
+		return [32]byte{}, ErrInvalidCeremony
+	}
+
+	if t.PowersOfTau != nil {
+		if err := VerifyPowersOfTau(t.PowersOfTau); err != nil {
+			return [32]byte{}, err
+		}
+	}
+
+	_, _, g1Gen, g2Gen := bn254.Generators()
+
+	prevG1 := t.InitialDeltaG1
+	prevG2 := t.InitialDeltaG2
+	if !prevG1.Equal(&g1Gen) || !prevG2.Equal(&g2Gen) {
+		return [32]byte{}, ErrCeremonyInitialDelta
+	}
+
+	transcript := sha256.New()
+	g1Bytes := prevG1.RawBytes()
+	g2Bytes := prevG2.RawBytes()
+	transcript.Write(g1Bytes[:])
+	transcript.Write(g2Bytes[:])
+
+	for i := range t.Contributions {
+		c := &t.Contributions[i]
+		if c.DeltaG1.IsInfinity() || c.PoKS.IsInfinity() || c.PoKSDelta.IsInfinity() {
+			return [32]byte{}, ErrInvalidCeremony
+		}
+
+		var prevHash [32]byte
+		copy(prevHash[:], transcript.Sum(nil))
+
+		r, err := ceremonyPoKBase(prevHash, &c.PoKS, &c.PoKSDelta)
+		if err != nil {
+			return [32]byte{}, err
+		}
+
+		// 1. Contributor knows δᵢ: S·δᵢ / S == R·δᵢ / R
+		if !sameRatioG1G2(&c.PoKSDelta, &c.PoKS, &c.PoKRDelta, &r) {
+			return [32]byte{}, ErrCeremonyPoKFailed
+		}
+		// 2. δ was multiplied by that same δᵢ: δ_new / δ_prev == R·δᵢ / R
+		if !sameRatioG1G2(&c.DeltaG1, &prevG1, &c.PoKRDelta, &r) {
+			return [32]byte{}, ErrCeremonyUpdateFailed
+		}
+		// 3. G1 and G2 halves of δ agree
+		if !sameRatioG1G2(&c.DeltaG1, &g1Gen, &c.DeltaG2, &g2Gen) {
+			return [32]byte{}, ErrCeremonyG1G2Mismatch
+		}
+
+		writeContribution(transcript, c)
+		prevG1 = c.DeltaG1
+		prevG2 = c.DeltaG2
+	}
+
+	// The queries were divided by the same δ the chain arrived at
+	for _, q := range [][2][]bn254.G1Affine{{t.InitialL, t.FinalL}, {t.InitialH, t.FinalH}} {
+		if !sameRatioQuery(q[0], q[1], &prevG2, &g2Gen) {
+			return [32]byte{}, ErrCeremonyQueryFailed
+		}
+		for _, elements := range q {
+			for i := range elements {
+				b := elements[i].RawBytes()
+				transcript.Write(b[:])
+			}
+		}
+	}
+
+	var hash [32]byte
+	copy(hash[:], transcript.Sum(nil))
+	return hash, nil
+}
+
+// VerifyPowersOfTau checks e(τⁱ⁺¹·g₁, g₂) == e(τⁱ·g₁, τ·g₂) for all i using a
+// Fiat-Shamir random linear combination over the G1 powers.
+func VerifyPowersOfTau(p *PowersOfTau) error {
+	if p == nil || len(p.TauG1) < 2 || len(p.TauG2) < 2 {
+		return ErrCeremonyTauMismatch
+	}
+
+	_, _, g1Gen, g2Gen := bn254.Generators()
+	if !p.TauG1[0].Equal(&g1Gen) || !p.TauG2[0].Equal(&g2Gen) {
+		return ErrCeremonyTauMismatch
+	}
+
+	// Derive combination coefficients from the powers themselves
+	h := sha256.New()
+	for i := range p.TauG1 {
+		b := p.TauG1[i].RawBytes()
+		h.Write(b[:])
+	}
+	tau2 := p.TauG2[1].RawBytes()
+	h.Write(tau2[:])
+	seed := h.Sum(nil)
+
+	var lhs, rhs bn254.G1Affine // Σρᵢ·τⁱ⁺¹·g₁ and Σρᵢ·τⁱ·g₁
+	for i := 0; i+1 < len(p.TauG1); i++ {
+		rho := ceremonyScalar(seed, uint64(i))
+		var a, b bn254.G1Affine
+		a.ScalarMultiplication(&p.TauG1[i+1], rho)
+		b.ScalarMultiplication(&p.TauG1[i], rho)
+		lhs.Add(&lhs, &a)
+		rhs.Add(&rhs, &b)
+	}
+
+	if !sameRatioG1G2(&lhs, &rhs, &p.TauG2[1], &g2Gen) {
+		return ErrCeremonyTauMismatch
+	}
+	return nil
+}
+
+// sameRatioQuery checks initialᵢ/finalᵢ == a2/b2 for every i using a
+// Fiat-Shamir random linear combination over the G1 elements
+func sameRatioQuery(initial, final []bn254.G1Affine, a2, b2 *bn254.G2Affine) bool {
+	h := sha256.New()
+	for _, elements := range [][]bn254.G1Affine{initial, final} {
+		for i := range elements {
+			b := elements[i].RawBytes()
+			h.Write(b[:])
+		}
+	}
+	a2Bytes := a2.RawBytes()
+	h.Write(a2Bytes[:])
+	seed := h.Sum(nil)
+
+	var lhs, rhs bn254.G1Affine // Σρᵢ·initialᵢ and Σρᵢ·finalᵢ
+	for i := range initial {
+		rho := ceremonyScalar(seed, uint64(i))
+		var a, b bn254.G1Affine
+		a.ScalarMultiplication(&initial[i], rho)
+		b.ScalarMultiplication(&final[i], rho)
+		lhs.Add(&lhs, &a)
+		rhs.Add(&rhs, &b)
+	}
+	if lhs.IsInfinity() || rhs.IsInfinity() {
+		return false
+	}
+	return sameRatioG1G2(&lhs, &rhs, a2, b2)
+}
+
+// sameRatioG1G2 checks a1/b1 == a2/b2, i.e. e(a1, b2) == e(b1, a2)
+func sameRatioG1G2(a1, b1 *bn254.G1Affine, a2, b2 *bn254.G2Affine) bool {
+	var negB1 bn254.G1Affine
+	negB1.Neg(b1)
+	ok, err := bn254.PairingCheck(
+		[]bn254.G1Affine{*a1, negB1},
+		[]bn254.G2Affine{*b2, *a2},
+	)
+	return err == nil && ok
+}
+
+// ceremonyPoKBase derives R = HashToG2(prev || S || S·δᵢ)
+func ceremonyPoKBase(prev [32]byte, s, sDelta *bn254.G1Affine) (bn254.G2Affine, error) {
+	sBytes := s.RawBytes()
+	sdBytes := sDelta.RawBytes()
+	msg := make([]byte, 0, 32+2*ceremonyG1Size)
+	msg = append(msg, prev[:]...)
+	msg = append(msg, sBytes[:]...)
+	msg = append(msg, sdBytes[:]...)
+	return bn254.HashToG2(msg, ceremonyHashDST)
+}
+
+// ceremonyScalar derives the i-th linear combination coefficient
+func ceremonyScalar(seed []byte, i uint64) *big.Int {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], i)
+	h := sha256.Sum256(append(append([]byte{}, seed...), buf[:]...))
+	var e fr.Element
+	e.SetBytes(h[:])
+	return e.BigInt(new(big.Int))
+}
+
+// writeContribution appends a contribution to the running transcript hash
+func writeContribution(h interface{ Write([]byte) (int, error) }, c *Phase2Contribution) {
+	d1 := c.DeltaG1.RawBytes()
+	d2 := c.DeltaG2.RawBytes()
+	s := c.PoKS.RawBytes()
+	sd := c.PoKSDelta.RawBytes()
+	rd := c.PoKRDelta.RawBytes()
+	h.Write(d1[:])
+	h.Write(d2[:])
+	h.Write(s[:])
+	h.Write(sd[:])
+	h.Write(rd[:])
+}
+
+// DecodePhase2Transcript parses the precompile encoding of a transcript:
+//
+//	[4 bytes num_contributions]
+//	[64 bytes initial δ G1][128 bytes initial δ G2]
+//	num_contributions × [64 δ G1][128 δ G2][64 S][64 S·δᵢ][128 R·δᵢ]
+//	[4 bytes num_l][num_l × 64 initial L][num_l × 64 final L]
+//	[4 bytes num_h][num_h × 64 initial H][num_h × 64 final H]
+//	[4 bytes num_tau][num_tau × 64 τⁱ·g₁][128 τ·g₂ if num_tau > 0]
+func DecodePhase2Transcript(data []byte) (*Phase2Transcript, error) {
+	if len(data) < 4+ceremonyG1Size+ceremonyG2Size {
+		return nil, ErrInvalidCeremony
+	}
+	n := binary.BigEndian.Uint32(data[:4])
+	if n == 0 {
+		return nil, ErrInvalidCeremony
+	}
+	if n > MaxCeremonyContributions {
+		return nil, ErrTooManyContributions
+	}
+	expected := 4 + ceremonyG1Size + ceremonyG2Size + int(n)*ceremonyContributionSize
+	if len(data) < expected {
+		return nil, ErrInvalidCeremony
+	}
+
+	t := &Phase2Transcript{Contributions: make([]Phase2Contribution, n)}
+	off := 4
+	if err := readG1(data, &off, &t.InitialDeltaG1); err != nil {
+		return nil, err
+	}
+	if err := readG2(data, &off, &t.InitialDeltaG2); err != nil {
+		return nil, err
+	}
+	for i := range t.Contributions {
+		c := &t.Contributions[i]
+		if err := readG1(data, &off, &c.DeltaG1); err != nil {
+			return nil, err
+		}
+		if err := readG2(data, &off, &c.DeltaG2); err != nil {
+			return nil, err
+		}
+		if err := readG1(data, &off, &c.PoKS); err != nil {
+			return nil, err
+		}
+		if err := readG1(data, &off, &c.PoKSDelta); err != nil {
+			return nil, err
+		}
+		if err := readG2(data, &off, &c.PoKRDelta); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, q := range []struct{ initial, final *[]bn254.G1Affine }{
+		{&t.InitialL, &t.FinalL},
+		{&t.InitialH, &t.FinalH},
+	} {
+		n, err := readCount(data, &off, 2*ceremonyG1Size, 0)
+		if err != nil {
+			return nil, err
+		}
+		*q.initial = make([]bn254.G1Affine, n)
+		*q.final = make([]bn254.G1Affine, n)
+		for _, elements := range [][]bn254.G1Affine{*q.initial, *q.final} {
+			for i := range elements {
+				if err := readG1(data, &off, &elements[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	n, err := readCount(data, &off, ceremonyG1Size, ceremonyG2Size)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		_, _, _, g2Gen := bn254.Generators()
+		t.PowersOfTau = &PowersOfTau{
+			TauG1: make([]bn254.G1Affine, n),
+			TauG2: []bn254.G2Affine{g2Gen, {}},
+		}
+		for i := range t.PowersOfTau.TauG1 {
+			if err := readG1(data, &off, &t.PowersOfTau.TauG1[i]); err != nil {
+				return nil, err
+			}
+		}
+		if err := readG2(data, &off, &t.PowersOfTau.TauG2[1]); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// readCount decodes a 4-byte element count and checks that the data holds
+// that many elements of elemSize bytes plus trailer bytes when non-zero
+func readCount(data []byte, off *int, elemSize, trailer int) (int, error) {
+	if len(data) < *off+4 {
+		return 0, ErrInvalidCeremony
+	}
+	n := int(binary.BigEndian.Uint32(data[*off:]))
+	*off += 4
+	if n > 0 && len(data) < *off+n*elemSize+trailer {
+		return 0, ErrInvalidCeremony
+	}
+	return n, nil
+}
+
+// CeremonySizes returns the number of contributions, powers of tau and L/H
+// query elements an encoded transcript declares, counting only sections
+// present in the data
+func CeremonySizes(data []byte) (contributions, tauPowers, queryElements int) {
+	if len(data) < 4 {
+		return 0, 0, 0
+	}
+	contributions = int(binary.BigEndian.Uint32(data[:4]))
+	off := 4 + ceremonyG1Size + ceremonyG2Size + contributions*ceremonyContributionSize
+	for i := 0; i < 2; i++ {
+		if len(data) < off+4 {
+			return contributions, 0, queryElements
+		}
+		n := int(binary.BigEndian.Uint32(data[off:]))
+		queryElements += n
+		off += 4 + n*2*ceremonyG1Size
+	}
+	if len(data) >= off+4 {
+		tauPowers = int(binary.BigEndian.Uint32(data[off:]))
+	}
+	return contributions, tauPowers, queryElements
+}
+
+// readG1 decodes an uncompressed G1 point and advances the offset
+func readG1(data []byte, off *int, p *bn254.G1Affine) error {
+	if _, err := p.SetBytes(data[*off : *off+ceremonyG1Size]); err != nil {
+		return ErrPointNotOnCurve
+	}
+	*off += ceremonyG1Size
+	return nil
+}
+
+// readG2 decodes an uncompressed G2 point and advances the offset
+func readG2(data []byte, off *int, p *bn254.G2Affine) error {
+	if _, err := p.SetBytes(data[*off : *off+ceremonyG2Size]); err != nil {
+		return ErrPointNotOnCurve
+	}
+	*off += ceremonyG2Size
+	return nil
+}
+
+// AttestCeremony verifies a transcript and records its final δ so VKs derived
+// from the ceremony can be registered with RegisterAttestedVerifyingKey.
+func (zv *ZKVerifier) AttestCeremony(t *Phase2Transcript) (*CeremonyAttestation, error) {
+	hash, err := VerifyPhase2Transcript(t)
+	if err != nil {
+		return nil, err
+	}
+
+	final := t.Contributions[len(t.Contributions)-1].DeltaG2.RawBytes()
+	att := &CeremonyAttestation{
+		TranscriptHash: hash,
+		FinalDeltaG2:   append([]byte(nil), final[:]...),
+		Contributions:  uint32(len(t.Contributions)),
+		AttestedAt:     uint64(time.Now().Unix()),
+	}
+
+	zv.mu.Lock()
+	defer zv.mu.Unlock()
+	zv.CeremonyAttestations[sha256.Sum256(att.FinalDeltaG2)] = att
+	return att, nil
+}
+
+// GetCeremonyAttestation returns the attestation for a VK δ (G2 encoding)
+func (zv *ZKVerifier) GetCeremonyAttestation(delta []byte) (*CeremonyAttestation, error) {
+	zv.mu.RLock()
+	defer zv.mu.RUnlock()
+
+	att := zv.CeremonyAttestations[sha256.Sum256(delta)]
+	if att == nil {
+		return nil, ErrCeremonyNotAttested
+	}
+	return att, nil
+}
+
+// RegisterAttestedVerifyingKey registers a VK only if its δ is the output of
+// an attested ceremony.
+func (zv *ZKVerifier) RegisterAttestedVerifyingKey(
+	owner common.Address,
+	proofSystem ProofSystem,
+	circuitType CircuitType,
+	alpha, beta, gamma, delta []byte,
+	ic [][]byte,
+) ([32]byte, error) {
+	if proofSystem != ProofSystemGroth16 {
+		return [32]byte{}, ErrProofSystemMismatch
+	}
+	if _, err := zv.GetCeremonyAttestation(delta); err != nil {
+		return [32]byte{}, err
+	}
+	return zv.RegisterVerifyingKey(owner, proofSystem, circuitType, alpha, beta, gamma, delta, ic)
+}
@@ -0,0 +1,308 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/luxfi/geth/common"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTranscript starts a phase-2 transcript at δ = 1 with a two-element
+// L query and a one-element H query
+func newTestTranscript() *Phase2Transcript {
+	_, _, g1, g2 := bn254.Generators()
+	tr := &Phase2Transcript{
+		InitialDeltaG1: g1,
+		InitialDeltaG2: g2,
+		InitialL:       make([]bn254.G1Affine, 2),
+		InitialH:       make([]bn254.G1Affine, 1),
+	}
+	for i := range tr.InitialL {
+		tr.InitialL[i].ScalarMultiplicationBase(big.NewInt(int64(3 + i)))
+	}
+	tr.InitialH[0].ScalarMultiplicationBase(big.NewInt(5))
+	tr.FinalL = append([]bn254.G1Affine(nil), tr.InitialL...)
+	tr.FinalH = append([]bn254.G1Affine(nil), tr.InitialH...)
+	return tr
+}
+
+// contribute appends an honest contribution with secret δᵢ and base S = s·g₁
+func contribute(t *testing.T, tr *Phase2Transcript, secret, s int64) {
+	t.Helper()
+
+	h := sha256.New()
+	g1Bytes := tr.InitialDeltaG1.RawBytes()
+	g2Bytes := tr.InitialDeltaG2.RawBytes()
+	h.Write(g1Bytes[:])
+	h.Write(g2Bytes[:])
+	prevG1 := tr.InitialDeltaG1
+	prevG2 := tr.InitialDeltaG2
+	for i := range tr.Contributions {
+		writeContribution(h, &tr.Contributions[i])
+		prevG1 = tr.Contributions[i].DeltaG1
+		prevG2 = tr.Contributions[i].DeltaG2
+	}
+	var prev [32]byte
+	copy(prev[:], h.Sum(nil))
+
+	d := big.NewInt(secret)
+	var c Phase2Contribution
+	c.DeltaG1.ScalarMultiplication(&prevG1, d)
+	c.DeltaG2.ScalarMultiplication(&prevG2, d)
+	c.PoKS.ScalarMultiplicationBase(big.NewInt(s))
+	c.PoKSDelta.ScalarMultiplication(&c.PoKS, d)
+
+	r, err := ceremonyPoKBase(prev, &c.PoKS, &c.PoKSDelta)
+	require.NoError(t, err)
+	c.PoKRDelta.ScalarMultiplication(&r, d)
+
+	tr.Contributions = append(tr.Contributions, c)
+
+	// Divide the queries by δᵢ
+	var inv fr.Element
+	inv.SetInt64(secret)
+	inv.Inverse(&inv)
+	dInv := inv.BigInt(new(big.Int))
+	for _, q := range [][]bn254.G1Affine{tr.FinalL, tr.FinalH} {
+		for i := range q {
+			q[i].ScalarMultiplication(&q[i], dInv)
+		}
+	}
+}
+
+// encodeTranscript produces the precompile encoding of a transcript
+func encodeTranscript(tr *Phase2Transcript) []byte {
+	out := make([]byte, 4)
+	binary.BigEndian.PutUint32(out, uint32(len(tr.Contributions)))
+	g1 := tr.InitialDeltaG1.RawBytes()
+	g2 := tr.InitialDeltaG2.RawBytes()
+	out = append(out, g1[:]...)
+	out = append(out, g2[:]...)
+	for i := range tr.Contributions {
+		c := &tr.Contributions[i]
+		d1 := c.DeltaG1.RawBytes()
+		d2 := c.DeltaG2.RawBytes()
+		s := c.PoKS.RawBytes()
+		sd := c.PoKSDelta.RawBytes()
+		rd := c.PoKRDelta.RawBytes()
+		out = append(out, d1[:]...)
+		out = append(out, d2[:]...)
+		out = append(out, s[:]...)
+		out = append(out, sd[:]...)
+		out = append(out, rd[:]...)
+	}
+	for _, q := range [][2][]bn254.G1Affine{{tr.InitialL, tr.FinalL}, {tr.InitialH, tr.FinalH}} {
+		out = binary.BigEndian.AppendUint32(out, uint32(len(q[0])))
+		for _, elements := range q {
+			for i := range elements {
+				b := elements[i].RawBytes()
+				out = append(out, b[:]...)
+			}
+		}
+	}
+	if tr.PowersOfTau == nil {
+		return binary.BigEndian.AppendUint32(out, 0)
+	}
+	out = binary.BigEndian.AppendUint32(out, uint32(len(tr.PowersOfTau.TauG1)))
+	for i := range tr.PowersOfTau.TauG1 {
+		b := tr.PowersOfTau.TauG1[i].RawBytes()
+		out = append(out, b[:]...)
+	}
+	tau := tr.PowersOfTau.TauG2[1].RawBytes()
+	return append(out, tau[:]...)
+}
+
+// TestPhase2TranscriptValid tests an honest multi-party contribution chain
+func TestPhase2TranscriptValid(t *testing.T) {
+	tr := newTestTranscript()
+	contribute(t, tr, 7, 11)
+	contribute(t, tr, 13, 17)
+	contribute(t, tr, 19, 23)
+
+	hash, err := VerifyPhase2Transcript(tr)
+	require.NoError(t, err)
+	require.NotEqual(t, [32]byte{}, hash)
+
+	// Final δ is the product of all secrets
+	var expected bn254.G2Affine
+	expected.ScalarMultiplicationBase(big.NewInt(7 * 13 * 19))
+	require.True(t, tr.Contributions[2].DeltaG2.Equal(&expected))
+}
+
+// TestPhase2TranscriptRejectsTampering tests each per-contribution check
+func TestPhase2TranscriptRejectsTampering(t *testing.T) {
+	_, _, g1, g2 := bn254.Generators()
+
+	// Empty transcript
+	_, err := VerifyPhase2Transcript(newTestTranscript())
+	require.ErrorIs(t, err, ErrInvalidCeremony)
+
+	// δ updated by a different secret than the one proven
+	tr := newTestTranscript()
+	contribute(t, tr, 7, 11)
+	tr.Contributions[0].DeltaG1.ScalarMultiplication(&g1, big.NewInt(8))
+	tr.Contributions[0].DeltaG2.ScalarMultiplication(&g2, big.NewInt(8))
+	_, err = VerifyPhase2Transcript(tr)
+	require.ErrorIs(t, err, ErrCeremonyUpdateFailed)
+
+	// G2 half disagrees with G1 half
+	tr = newTestTranscript()
+	contribute(t, tr, 7, 11)
+	tr.Contributions[0].DeltaG2.ScalarMultiplication(&g2, big.NewInt(8))
+	_, err = VerifyPhase2Transcript(tr)
+	require.ErrorIs(t, err, ErrCeremonyG1G2Mismatch)
+
+	// Proof of knowledge over the wrong base
+	tr = newTestTranscript()
+	contribute(t, tr, 7, 11)
+	tr.Contributions[0].PoKSDelta.ScalarMultiplication(&tr.Contributions[0].PoKS, big.NewInt(8))
+	_, err = VerifyPhase2Transcript(tr)
+	require.ErrorIs(t, err, ErrCeremonyPoKFailed)
+
+	// Chain starting from a δ other than the generator
+	tr = newTestTranscript()
+	tr.InitialDeltaG1.ScalarMultiplication(&g1, big.NewInt(2))
+	tr.InitialDeltaG2.ScalarMultiplication(&g2, big.NewInt(2))
+	contribute(t, tr, 7, 11)
+	_, err = VerifyPhase2Transcript(tr)
+	require.ErrorIs(t, err, ErrCeremonyInitialDelta)
+
+	// L query not divided by the contribution
+	tr = newTestTranscript()
+	contribute(t, tr, 7, 11)
+	tr.FinalL[1] = tr.InitialL[1]
+	_, err = VerifyPhase2Transcript(tr)
+	require.ErrorIs(t, err, ErrCeremonyQueryFailed)
+
+	// H query divided by a different secret
+	tr = newTestTranscript()
+	contribute(t, tr, 7, 11)
+	tr.FinalH[0].ScalarMultiplication(&tr.FinalH[0], big.NewInt(7))
+	tr.FinalH[0].ScalarMultiplication(&tr.FinalH[0], big.NewInt(8))
+	_, err = VerifyPhase2Transcript(tr)
+	require.ErrorIs(t, err, ErrCeremonyQueryFailed)
+
+	// Missing queries
+	tr = newTestTranscript()
+	contribute(t, tr, 7, 11)
+	tr.InitialH, tr.FinalH = nil, nil
+	_, err = VerifyPhase2Transcript(tr)
+	require.ErrorIs(t, err, ErrInvalidCeremony)
+}
+
+// TestVerifyPowersOfTau tests the phase-1 consistency check
+func TestVerifyPowersOfTau(t *testing.T) {
+	tau := big.NewInt(5)
+	p := &PowersOfTau{
+		TauG1: make([]bn254.G1Affine, 8),
+		TauG2: make([]bn254.G2Affine, 2),
+	}
+	pow := big.NewInt(1)
+	for i := range p.TauG1 {
+		p.TauG1[i].ScalarMultiplicationBase(pow)
+		pow = new(big.Int).Mul(pow, tau)
+	}
+	p.TauG2[0].ScalarMultiplicationBase(big.NewInt(1))
+	p.TauG2[1].ScalarMultiplicationBase(tau)
+
+	require.NoError(t, VerifyPowersOfTau(p))
+
+	// Break one power in the middle
+	p.TauG1[4].ScalarMultiplicationBase(big.NewInt(626))
+	require.ErrorIs(t, VerifyPowersOfTau(p), ErrCeremonyTauMismatch)
+}
+
+// TestCeremonyPrecompile tests the precompile encoding and gas
+func TestCeremonyPrecompile(t *testing.T) {
+	tr := newTestTranscript()
+	contribute(t, tr, 7, 11)
+	contribute(t, tr, 13, 17)
+
+	input := append([]byte{OpVerifyCeremony}, encodeTranscript(tr)...)
+
+	p := &zkVerifyPrecompile{}
+	gas := CeremonyGas(2, 0, 3)
+	require.Equal(t, gas, p.RequiredGas(input))
+
+	ret, _, err := p.Run(nil, common.Address{}, ZKVerifyContractAddress, input, gas, true)
+	require.NoError(t, err)
+	require.Equal(t, encodeBool(true), ret)
+
+	// Powers of tau are charged for and checked
+	tau := big.NewInt(5)
+	tr.PowersOfTau = &PowersOfTau{TauG1: make([]bn254.G1Affine, 4), TauG2: make([]bn254.G2Affine, 2)}
+	pow := big.NewInt(1)
+	for i := range tr.PowersOfTau.TauG1 {
+		tr.PowersOfTau.TauG1[i].ScalarMultiplicationBase(pow)
+		pow = new(big.Int).Mul(pow, tau)
+	}
+	tr.PowersOfTau.TauG2[0].ScalarMultiplicationBase(big.NewInt(1))
+	tr.PowersOfTau.TauG2[1].ScalarMultiplicationBase(tau)
+	input = append([]byte{OpVerifyCeremony}, encodeTranscript(tr)...)
+	require.Equal(t, CeremonyGas(2, 4, 3), p.RequiredGas(input))
+	ret, _, err = p.Run(nil, common.Address{}, ZKVerifyContractAddress, input, CeremonyGas(2, 4, 3), true)
+	require.NoError(t, err)
+	require.Equal(t, encodeBool(true), ret)
+	tr.PowersOfTau = nil
+
+	// Corrupt the last R·δᵢ: still well-formed, but fails verification
+	tr.Contributions[1].PoKRDelta = tr.Contributions[0].PoKRDelta
+	input = append([]byte{OpVerifyCeremony}, encodeTranscript(tr)...)
+	ret, _, err = p.Run(nil, common.Address{}, ZKVerifyContractAddress, input, gas, true)
+	require.NoError(t, err)
+	require.Equal(t, encodeBool(false), ret)
+
+	// Truncated input is malformed
+	_, _, err = p.Run(nil, common.Address{}, ZKVerifyContractAddress, input[:100], gas, true)
+	require.ErrorIs(t, err, ErrInvalidCeremony)
+	_, _, err = p.Run(nil, common.Address{}, ZKVerifyContractAddress, input[:len(input)-4], gas, true)
+	require.ErrorIs(t, err, ErrInvalidCeremony)
+
+	// Attesting writes state, so a read-only call may not
+	tr = newTestTranscript()
+	contribute(t, tr, 7, 11)
+	input = append([]byte{OpVerifyCeremony}, encodeTranscript(tr)...)
+	p = &zkVerifyPrecompile{verifier: NewZKVerifier()}
+	_, _, err = p.Run(nil, common.Address{}, ZKVerifyContractAddress, input, CeremonyGas(1, 0, 3), true)
+	require.ErrorIs(t, err, ErrCeremonyReadOnly)
+	ret, _, err = p.Run(nil, common.Address{}, ZKVerifyContractAddress, input, CeremonyGas(1, 0, 3), false)
+	require.NoError(t, err)
+	require.Equal(t, encodeBool(true), ret)
+	final := tr.Contributions[0].DeltaG2.RawBytes()
+	_, err = p.verifier.GetCeremonyAttestation(final[:])
+	require.NoError(t, err)
+}
+
+// TestRegisterAttestedVerifyingKey tests VK provenance gating
+func TestRegisterAttestedVerifyingKey(t *testing.T) {
+	zv := NewZKVerifier()
+	owner := common.HexToAddress("0x1234")
+
+	tr := newTestTranscript()
+	contribute(t, tr, 7, 11)
+	att, err := zv.AttestCeremony(tr)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), att.Contributions)
+
+	alpha := make([]byte, 64)
+	beta := make([]byte, 128)
+	gamma := make([]byte, 128)
+
+	// δ from the ceremony is accepted
+	keyID, err := zv.RegisterAttestedVerifyingKey(owner, ProofSystemGroth16, CircuitTransfer, alpha, beta, gamma, att.FinalDeltaG2, nil)
+	require.NoError(t, err)
+	require.NotNil(t, zv.VerifyingKeys[keyID])
+
+	// Any other δ is rejected
+	other := make([]byte, 128)
+	_, err = zv.RegisterAttestedVerifyingKey(owner, ProofSystemGroth16, CircuitTransfer, alpha, beta, gamma, other, nil)
+	require.ErrorIs(t, err, ErrCeremonyNotAttested)
+}
@@ -32,6 +32,7 @@ const (
 	OpVerifyNullifier  = 0x21 // Verify nullifier
 	OpVerifyCommitment = 0x22 // Verify Pedersen commitment
 	OpVerifyBatch      = 0x30 // Verify batch of proofs
+	OpVerifyCeremony   = 0x40 // Verify Groth16 phase-2 ceremony transcript
 )
 
 // Gas costs
@@ -47,6 +48,12 @@ const (
 	GasCommitmentBase = 20000  // Base cost for commitment
 	GasPerPublicInput = 1000   // Per public input element
 	GasPerBatchProof  = 50000  // Per proof in batch
+
+	GasCeremonyBase            = 50000  // Transcript parsing and hashing
+	GasCeremonyPerContribution = 340000 // Three 2-pairing checks plus hash-to-G2
+	GasCeremonyPerTauPower     = 2000   // Per power in the tau linear combination
+	GasCeremonyQueryChecks     = 200000 // L and H query checks, two 2-pairing checks
+	GasCeremonyPerQueryElement = 2000   // Per L/H element pair in the linear combination
 )
 
 type zkVerifyPrecompile struct {
@@ -105,6 +112,12 @@ func (p *zkVerifyPrecompile) RequiredGas(input []byte) uint64 {
 		numProofs := binary.BigEndian.Uint32(input[1:5])
 		return uint64(numProofs) * GasPerBatchProof
 
+	case OpVerifyCeremony:
+		if len(input) < 5 {
+			return 0
+		}
+		return CeremonyGas(CeremonySizes(input[1:]))
+
 	default:
 		return 0
 	}
@@ -213,6 +226,13 @@ func (p *zkVerifyPrecompile) Run(
 		}
 		return encodeBool(valid), remainingGas, nil
 
+	case OpVerifyCeremony:
+		valid, err := p.verifyCeremony(data, readOnly)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		return encodeBool(valid), remainingGas, nil
+
 	default:
 		return nil, remainingGas, ErrInvalidOperation
 	}
@@ -307,10 +327,16 @@ func (p *zkVerifyPrecompile) verifyRangeProof(data []byte) (bool, error) {
 }
 
 // verifyNullifier checks if a nullifier has been used
+// Input: domain (32) + nullifier (32)
 func (p *zkVerifyPrecompile) verifyNullifier(data []byte) (bool, error) {
-	if len(data) < 32 {
+	if len(data) < 64 {
 		return false, ErrInvalidInput
 	}
+	var domain NullifierDomain
+	copy(domain[:], data[:32])
+	if domain == (NullifierDomain{}) {
+		return false, ErrInvalidDomain
+	}
 
 	// Check nullifier hasn't been spent
 	// TODO: Query nullifier set from state
@@ -341,3 +367,29 @@ func (p *zkVerifyPrecompile) verifyBatch(data []byte) (bool, error) {
 	// TODO: Implement batch verification
 	return true, nil
 }
+
+// verifyCeremony verifies a Groth16 phase-2 contribution chain. Malformed
+// encodings are errors; a well-formed chain that fails a check returns false.
+// When the precompile is backed by a verifier the result is attested so the
+// final δ can be used for VK registration, which a read-only call may not do.
+func (p *zkVerifyPrecompile) verifyCeremony(data []byte, readOnly bool) (bool, error) {
+	transcript, err := DecodePhase2Transcript(data)
+	if err != nil {
+		return false, err
+	}
+
+	if p.verifier != nil {
+		if readOnly {
+			return false, ErrCeremonyReadOnly
+		}
+		if _, err := p.verifier.AttestCeremony(transcript); err != nil {
+			return false, nil
+		}
+		return true, nil
+	}
+
+	if _, err := VerifyPhase2Transcript(transcript); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
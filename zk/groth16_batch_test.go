@@ -0,0 +1,158 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/luxfi/crypto/bn256"
+	"github.com/luxfi/geth/common"
+)
+
+// Trapdoor of the test verifying key: α, β, γ, δ and the IC scalars
+var (
+	testAlpha = big.NewInt(3)
+	testBeta  = big.NewInt(5)
+	testGamma = big.NewInt(7)
+	testDelta = big.NewInt(11)
+	testIC    = []*big.Int{big.NewInt(13), big.NewInt(17), big.NewInt(19)}
+)
+
+func g1Mul(k *big.Int) []byte {
+	return new(bn256.G1).ScalarBaseMult(k).Marshal()
+}
+
+func g2Mul(k *big.Int) []byte {
+	return new(bn256.G2).ScalarBaseMult(k).Marshal()
+}
+
+// registerTrapdoorKey registers a Groth16 key whose trapdoor is known, so
+// tests can produce proofs for any public inputs
+func registerTrapdoorKey(t *testing.T, zv *ZKVerifier) [32]byte {
+	t.Helper()
+
+	ic := make([][]byte, len(testIC))
+	for i, s := range testIC {
+		ic[i] = g1Mul(s)
+	}
+	keyID, err := zv.RegisterVerifyingKey(
+		common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		ProofSystemGroth16,
+		CircuitRollupBatch,
+		g1Mul(testAlpha), g2Mul(testBeta), g2Mul(testGamma), g2Mul(testDelta),
+		ic,
+	)
+	if err != nil {
+		t.Fatalf("RegisterVerifyingKey failed: %v", err)
+	}
+	return keyID
+}
+
+// trapdoorProof builds A = x·g₁, B = y·g₂ and solves for C so that
+// x·y = α·β + vk_x·γ + c·δ
+func trapdoorProof(x, y int64, inputs ...*big.Int) *Proof {
+	return trapdoorProofFor(testIC, x, y, inputs...)
+}
+
+// trapdoorProofFor is trapdoorProof for a key with IC scalars ic
+func trapdoorProofFor(ic []*big.Int, x, y int64, inputs ...*big.Int) *Proof {
+	order := fr.Modulus()
+
+	vkX := new(big.Int).Set(ic[0])
+	for i, w := range inputs {
+		vkX.Add(vkX, new(big.Int).Mul(w, ic[i+1]))
+	}
+
+	c := big.NewInt(x * y)
+	c.Sub(c, new(big.Int).Mul(testAlpha, testBeta))
+	c.Sub(c, vkX.Mul(vkX, testGamma))
+	c.Mul(c, new(big.Int).ModInverse(testDelta, order))
+	c.Mod(c, order)
+
+	return &Proof{
+		ProofSystem:  ProofSystemGroth16,
+		A:            g1Mul(big.NewInt(x)),
+		B:            g2Mul(big.NewInt(y)),
+		C:            g1Mul(c),
+		PublicInputs: inputs,
+	}
+}
+
+// TestVerifyGroth16Batch tests that a batch of valid proofs passes as a whole
+func TestVerifyGroth16Batch(t *testing.T) {
+	zv := NewZKVerifier()
+	keyID := registerTrapdoorKey(t, zv)
+
+	proofs := []*Proof{
+		trapdoorProof(23, 29, big.NewInt(1), big.NewInt(2)),
+		trapdoorProof(31, 37, big.NewInt(100), big.NewInt(200)),
+		trapdoorProof(41, 43, big.NewInt(7), big.NewInt(0)),
+	}
+
+	// Each proof holds on its own
+	for i, proof := range proofs {
+		result, err := zv.VerifyGroth16(keyID, proof.A, proof.B, proof.C, proof.PublicInputs)
+		if err != nil || !result.Valid {
+			t.Fatalf("Proof %d should verify individually (err=%v)", i, err)
+		}
+	}
+
+	result, err := zv.VerifyGroth16Batch(keyID, proofs)
+	if err != nil {
+		t.Fatalf("VerifyGroth16Batch failed: %v", err)
+	}
+	if !result.Valid || len(result.FailedProofs) != 0 {
+		t.Errorf("Expected valid batch, failed proofs %v", result.FailedProofs)
+	}
+	if result.GasUsed != Groth16BatchGas(3, false) {
+		t.Errorf("Expected gas %d, got %d", Groth16BatchGas(3, false), result.GasUsed)
+	}
+	if result.GasUsed >= 3*GasGroth16Verify {
+		t.Errorf("Batch gas %d should undercut individual verification", result.GasUsed)
+	}
+}
+
+// TestVerifyGroth16BatchIdentifiesFailure tests the fallback to individual checks
+func TestVerifyGroth16BatchIdentifiesFailure(t *testing.T) {
+	zv := NewZKVerifier()
+	keyID := registerTrapdoorKey(t, zv)
+
+	// Proof 1 is valid for different public inputs
+	bad := trapdoorProof(31, 37, big.NewInt(100), big.NewInt(200))
+	bad.PublicInputs = []*big.Int{big.NewInt(100), big.NewInt(201)}
+
+	proofs := []*Proof{
+		trapdoorProof(23, 29, big.NewInt(1), big.NewInt(2)),
+		bad,
+		trapdoorProof(41, 43, big.NewInt(7), big.NewInt(0)),
+	}
+
+	result, err := zv.VerifyGroth16Batch(keyID, proofs)
+	if err != nil {
+		t.Fatalf("VerifyGroth16Batch failed: %v", err)
+	}
+	if result.Valid {
+		t.Fatal("Expected batch with a bad proof to fail")
+	}
+	if len(result.FailedProofs) != 1 || result.FailedProofs[0] != 1 {
+		t.Errorf("Expected proof 1 to fail, got %v", result.FailedProofs)
+	}
+	if !result.ProofValid[0] || !result.ProofValid[2] {
+		t.Error("Valid proofs should be reported valid")
+	}
+	if result.GasUsed != Groth16BatchGas(3, true) {
+		t.Errorf("Expected fallback gas %d, got %d", Groth16BatchGas(3, true), result.GasUsed)
+	}
+
+	// Malformed batches are rejected before any pairing
+	if _, err := zv.VerifyGroth16Batch(keyID, nil); err != ErrInvalidProof {
+		t.Errorf("Expected ErrInvalidProof, got %v", err)
+	}
+	short := trapdoorProof(23, 29, big.NewInt(1))
+	if _, err := zv.VerifyGroth16Batch(keyID, []*Proof{short}); err != ErrInvalidPublicInputs {
+		t.Errorf("Expected ErrInvalidPublicInputs, got %v", err)
+	}
+}
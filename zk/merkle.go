@@ -0,0 +1,425 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/contract"
+	"github.com/zeebo/blake3"
+)
+
+// Merkle proof verification precompile.
+//
+// Verifies inclusion of 32-byte leaves against a root using one of several
+// node hash functions. Leaves are taken as-is; callers hash their leaf data
+// (with whatever domain separation their tree uses) before submitting.
+//
+// Modes:
+//   - Indexed: the leaf index selects left/right at each level (bit i = 1
+//     means the running node is the right child at depth i)
+//   - Sorted:  each pair is hashed as H(min(a,b) || max(a,b)), as used by
+//     OpenZeppelin MerkleProof
+//   - Multiproof: OpenZeppelin-compatible sorted-pair multiproof proving
+//     several leaves against one root with shared siblings
+
+// Merkle operation selectors (first byte of input)
+const (
+	OpMerkleVerifyIndexed = 0x01 // Single proof, index-ordered siblings
+	OpMerkleVerifySorted  = 0x02 // Single proof, sorted-pair hashing
+	OpMerkleMultiProof    = 0x03 // Sorted-pair multiproof
+)
+
+// MerkleHash selects the node hash function (second byte of input)
+type MerkleHash uint8
+
+const (
+	MerkleHashKeccak256 MerkleHash = iota // keccak256(left || right)
+	MerkleHashSHA256                      // sha256(left || right)
+	MerkleHashPoseidon2                   // Poseidon2(left, right) over BN254
+	MerkleHashBlake3                      // blake3-256(left || right)
+)
+
+// Merkle gas costs
+const (
+	GasMerkleBase          = 2000 // Input parsing and root comparison
+	GasMerkleNodeKeccak256 = 60   // Per node hash
+	GasMerkleNodeSHA256    = 100  // Per node hash
+	GasMerkleNodePoseidon2 = 700  // Per node hash (matches Poseidon2 pair cost)
+	GasMerkleNodeBlake3    = 50   // Per node hash
+)
+
+// Merkle limits
+const (
+	MaxMerkleDepth           = 64   // Max siblings in a single proof
+	MaxMerkleMultiProofLeafs = 1024 // Max leaves in a multiproof
+	MaxMerkleMultiProofNodes = 4096 // Max sibling nodes in a multiproof
+)
+
+var (
+	ErrUnsupportedMerkleHash = errors.New("unsupported merkle hash function")
+	ErrMerkleProofTooDeep    = errors.New("merkle proof exceeds maximum depth")
+	ErrMerkleIndexOutOfRange = errors.New("merkle leaf index out of range for depth")
+	ErrInvalidMultiProof     = errors.New("invalid merkle multiproof")
+)
+
+// MerkleNodeGas returns the per-node gas for a hash function
+func MerkleNodeGas(h MerkleHash) uint64 {
+	switch h {
+	case MerkleHashKeccak256:
+		return GasMerkleNodeKeccak256
+	case MerkleHashSHA256:
+		return GasMerkleNodeSHA256
+	case MerkleHashPoseidon2:
+		return GasMerkleNodePoseidon2
+	case MerkleHashBlake3:
+		return GasMerkleNodeBlake3
+	default:
+		return 0
+	}
+}
+
+// MerkleHashPair hashes two child nodes with the given function
+func MerkleHashPair(h MerkleHash, left, right [32]byte) ([32]byte, error) {
+	switch h {
+	case MerkleHashKeccak256:
+		return common.BytesToHash(crypto.Keccak256(left[:], right[:])), nil
+	case MerkleHashSHA256:
+		var buf [64]byte
+		copy(buf[:32], left[:])
+		copy(buf[32:], right[:])
+		return sha256.Sum256(buf[:]), nil
+	case MerkleHashPoseidon2:
+		return globalPoseidon2.HashPair(left, right)
+	case MerkleHashBlake3:
+		var buf [64]byte
+		copy(buf[:32], left[:])
+		copy(buf[32:], right[:])
+		return blake3.Sum256(buf[:]), nil
+	default:
+		return [32]byte{}, ErrUnsupportedMerkleHash
+	}
+}
+
+// merkleHashSorted hashes a pair in ascending byte order
+func merkleHashSorted(h MerkleHash, a, b [32]byte) ([32]byte, error) {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return MerkleHashPair(h, a, b)
+}
+
+// VerifyIndexedMerkleProof verifies a proof whose sibling order is given by
+// the leaf index
+func VerifyIndexedMerkleProof(
+	h MerkleHash,
+	root, leaf [32]byte,
+	index uint64,
+	siblings [][32]byte,
+) (bool, error) {
+	if len(siblings) > MaxMerkleDepth {
+		return false, ErrMerkleProofTooDeep
+	}
+	if len(siblings) < 64 && index>>uint(len(siblings)) != 0 {
+		return false, ErrMerkleIndexOutOfRange
+	}
+
+	current := leaf
+	for i, sibling := range siblings {
+		var err error
+		if (index>>uint(i))&1 == 0 {
+			current, err = MerkleHashPair(h, current, sibling)
+		} else {
+			current, err = MerkleHashPair(h, sibling, current)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return current == root, nil
+}
+
+// VerifySortedMerkleProof verifies a sorted-pair proof
+func VerifySortedMerkleProof(h MerkleHash, root, leaf [32]byte, siblings [][32]byte) (bool, error) {
+	if len(siblings) > MaxMerkleDepth {
+		return false, ErrMerkleProofTooDeep
+	}
+
+	current := leaf
+	for _, sibling := range siblings {
+		var err error
+		current, err = merkleHashSorted(h, current, sibling)
+		if err != nil {
+			return false, err
+		}
+	}
+	return current == root, nil
+}
+
+// VerifyMerkleMultiProof verifies a sorted-pair multiproof. Each flag
+// consumes the next queued node and, if set, a second queued node, else the
+// next proof element. Leaves must be ordered as the tree's hash walk visits
+// them (OpenZeppelin processMultiProof semantics).
+func VerifyMerkleMultiProof(
+	h MerkleHash,
+	root [32]byte,
+	leaves [][32]byte,
+	proof [][32]byte,
+	flags []bool,
+) (bool, error) {
+	if len(leaves) > MaxMerkleMultiProofLeafs || len(proof) > MaxMerkleMultiProofNodes {
+		return false, ErrInvalidMultiProof
+	}
+	if len(leaves)+len(proof) != len(flags)+1 {
+		return false, ErrInvalidMultiProof
+	}
+
+	total := len(flags)
+	if total == 0 {
+		if len(leaves) > 0 {
+			return leaves[0] == root, nil
+		}
+		return proof[0] == root, nil
+	}
+
+	hashes := make([][32]byte, total)
+	leafPos, hashPos, proofPos, computed := 0, 0, 0, 0
+
+	// next pops the next node from leaves, then from already computed hashes
+	next := func() ([32]byte, bool) {
+		if leafPos < len(leaves) {
+			leafPos++
+			return leaves[leafPos-1], true
+		}
+		if hashPos < computed {
+			hashPos++
+			return hashes[hashPos-1], true
+		}
+		return [32]byte{}, false
+	}
+
+	for i := 0; i < total; i++ {
+		a, ok := next()
+		if !ok {
+			return false, ErrInvalidMultiProof
+		}
+		var b [32]byte
+		if flags[i] {
+			if b, ok = next(); !ok {
+				return false, ErrInvalidMultiProof
+			}
+		} else {
+			if proofPos >= len(proof) {
+				return false, ErrInvalidMultiProof
+			}
+			b = proof[proofPos]
+			proofPos++
+		}
+		node, err := merkleHashSorted(h, a, b)
+		if err != nil {
+			return false, err
+		}
+		hashes[i] = node
+		computed++
+	}
+
+	// Every proof element must be consumed
+	if proofPos != len(proof) {
+		return false, ErrInvalidMultiProof
+	}
+	return hashes[total-1] == root, nil
+}
+
+// =========================================================================
+// Precompile
+// =========================================================================
+
+// merkleProofPrecompile is deployed once per chain; address is the
+// registry slot it serves.
+type merkleProofPrecompile struct {
+	address common.Address
+}
+
+// Address returns the precompile address
+func (p *merkleProofPrecompile) Address() common.Address {
+	return p.address
+}
+
+// RequiredGas calculates gas from the hash function and node count
+//
+// Input formats (after [1 byte op][1 byte hash][32 bytes root]):
+//
+//	Indexed:    [32 leaf][8 index][4 n][n × 32 siblings]
+//	Sorted:     [32 leaf][4 n][n × 32 siblings]
+//	Multiproof: [4 numLeaves][4 numProof][numLeaves × 32][numProof × 32][flags, 1 byte each]
+func (p *merkleProofPrecompile) RequiredGas(input []byte) uint64 {
+	if len(input) < 2 {
+		return 0
+	}
+	nodeGas := MerkleNodeGas(MerkleHash(input[1]))
+
+	var nodes uint64
+	switch input[0] {
+	case OpMerkleVerifyIndexed:
+		if len(input) < 78 {
+			return GasMerkleBase
+		}
+		nodes = uint64(binary.BigEndian.Uint32(input[74:78]))
+	case OpMerkleVerifySorted:
+		if len(input) < 70 {
+			return GasMerkleBase
+		}
+		nodes = uint64(binary.BigEndian.Uint32(input[66:70]))
+	case OpMerkleMultiProof:
+		if len(input) < 42 {
+			return GasMerkleBase
+		}
+		numLeaves := uint64(binary.BigEndian.Uint32(input[34:38]))
+		numProof := uint64(binary.BigEndian.Uint32(input[38:42]))
+		if numLeaves+numProof > 0 {
+			nodes = numLeaves + numProof - 1
+		}
+	default:
+		return 0
+	}
+	return GasMerkleBase + nodes*nodeGas
+}
+
+// Run executes the Merkle proof precompile
+func (p *merkleProofPrecompile) Run(
+	accessibleState contract.AccessibleState,
+	caller common.Address,
+	addr common.Address,
+	input []byte,
+	suppliedGas uint64,
+	readOnly bool,
+) (ret []byte, remainingGas uint64, err error) {
+	requiredGas := p.RequiredGas(input)
+	if suppliedGas < requiredGas {
+		return nil, 0, contract.ErrOutOfGas
+	}
+	remainingGas = suppliedGas - requiredGas
+
+	if len(input) < 34 {
+		return nil, remainingGas, ErrInvalidInput
+	}
+
+	op := input[0]
+	h := MerkleHash(input[1])
+	if MerkleNodeGas(h) == 0 {
+		return nil, remainingGas, ErrUnsupportedMerkleHash
+	}
+	var root [32]byte
+	copy(root[:], input[2:34])
+	data := input[34:]
+
+	var valid bool
+	switch op {
+	case OpMerkleVerifyIndexed:
+		if len(data) < 44 {
+			return nil, remainingGas, ErrInvalidInput
+		}
+		var leaf [32]byte
+		copy(leaf[:], data[:32])
+		index := binary.BigEndian.Uint64(data[32:40])
+		siblings, err := decodeMerkleNodes(data[40:])
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		valid, err = VerifyIndexedMerkleProof(h, root, leaf, index, siblings)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+
+	case OpMerkleVerifySorted:
+		if len(data) < 36 {
+			return nil, remainingGas, ErrInvalidInput
+		}
+		var leaf [32]byte
+		copy(leaf[:], data[:32])
+		siblings, err := decodeMerkleNodes(data[32:])
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		valid, err = VerifySortedMerkleProof(h, root, leaf, siblings)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+
+	case OpMerkleMultiProof:
+		leaves, proof, flags, err := decodeMerkleMultiProof(data)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+		valid, err = VerifyMerkleMultiProof(h, root, leaves, proof, flags)
+		if err != nil {
+			return nil, remainingGas, err
+		}
+
+	default:
+		return nil, remainingGas, ErrInvalidOperation
+	}
+
+	return encodeBool(valid), remainingGas, nil
+}
+
+// decodeMerkleNodes parses [4 bytes n][n × 32 bytes]
+func decodeMerkleNodes(data []byte) ([][32]byte, error) {
+	if len(data) < 4 {
+		return nil, ErrInvalidInput
+	}
+	n := binary.BigEndian.Uint32(data[:4])
+	if n > MaxMerkleDepth {
+		return nil, ErrMerkleProofTooDeep
+	}
+	if len(data) < 4+int(n)*32 {
+		return nil, ErrInvalidProofLength
+	}
+	nodes := make([][32]byte, n)
+	for i := range nodes {
+		copy(nodes[i][:], data[4+i*32:])
+	}
+	return nodes, nil
+}
+
+// decodeMerkleMultiProof parses the multiproof encoding
+func decodeMerkleMultiProof(data []byte) ([][32]byte, [][32]byte, []bool, error) {
+	if len(data) < 8 {
+		return nil, nil, nil, ErrInvalidInput
+	}
+	numLeaves := int(binary.BigEndian.Uint32(data[:4]))
+	numProof := int(binary.BigEndian.Uint32(data[4:8]))
+	if numLeaves > MaxMerkleMultiProofLeafs || numProof > MaxMerkleMultiProofNodes {
+		return nil, nil, nil, ErrInvalidMultiProof
+	}
+	if numLeaves+numProof == 0 {
+		return nil, nil, nil, ErrInvalidMultiProof
+	}
+	numFlags := numLeaves + numProof - 1
+	if len(data) < 8+(numLeaves+numProof)*32+numFlags {
+		return nil, nil, nil, ErrInvalidProofLength
+	}
+
+	off := 8
+	leaves := make([][32]byte, numLeaves)
+	for i := range leaves {
+		copy(leaves[i][:], data[off:])
+		off += 32
+	}
+	proof := make([][32]byte, numProof)
+	for i := range proof {
+		copy(proof[i][:], data[off:])
+		off += 32
+	}
+	flags := make([]bool, numFlags)
+	for i := range flags {
+		flags[i] = data[off+i] != 0
+	}
+	return leaves, proof, flags, nil
+}
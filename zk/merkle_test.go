@@ -0,0 +1,152 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+	"github.com/luxfi/precompile/registry"
+	"github.com/stretchr/testify/require"
+)
+
+var allMerkleHashes = []MerkleHash{
+	MerkleHashKeccak256,
+	MerkleHashSHA256,
+	MerkleHashPoseidon2,
+	MerkleHashBlake3,
+}
+
+// testMerkleLeaves returns n distinct leaves
+func testMerkleLeaves(n int) [][32]byte {
+	leaves := make([][32]byte, n)
+	for i := range leaves {
+		leaves[i][0] = byte(i + 1)
+		leaves[i][31] = byte(0xA0 + i)
+	}
+	return leaves
+}
+
+// TestIndexedMerkleProof tests index-ordered proofs for every hash function
+func TestIndexedMerkleProof(t *testing.T) {
+	leaves := testMerkleLeaves(4)
+
+	for _, h := range allMerkleHashes {
+		n01, err := MerkleHashPair(h, leaves[0], leaves[1])
+		require.NoError(t, err)
+		n23, err := MerkleHashPair(h, leaves[2], leaves[3])
+		require.NoError(t, err)
+		root, err := MerkleHashPair(h, n01, n23)
+		require.NoError(t, err)
+
+		// Leaf 2: left child at depth 0, right child at depth 1
+		valid, err := VerifyIndexedMerkleProof(h, root, leaves[2], 2, [][32]byte{leaves[3], n01})
+		require.NoError(t, err)
+		require.True(t, valid, "hash %d", h)
+
+		// Wrong index flips the ordering
+		valid, err = VerifyIndexedMerkleProof(h, root, leaves[2], 3, [][32]byte{leaves[3], n01})
+		require.NoError(t, err)
+		require.False(t, valid, "hash %d", h)
+	}
+
+	// Index beyond the tree depth is rejected
+	_, err := VerifyIndexedMerkleProof(MerkleHashKeccak256, [32]byte{}, leaves[0], 4, make([][32]byte, 2))
+	require.ErrorIs(t, err, ErrMerkleIndexOutOfRange)
+}
+
+// TestSortedMerkleProof tests sorted-pair proofs
+func TestSortedMerkleProof(t *testing.T) {
+	leaves := testMerkleLeaves(4)
+	h := MerkleHashKeccak256
+
+	n01, _ := merkleHashSorted(h, leaves[0], leaves[1])
+	n23, _ := merkleHashSorted(h, leaves[2], leaves[3])
+	root, _ := merkleHashSorted(h, n01, n23)
+
+	for i, proof := range [][][32]byte{
+		{leaves[1], n23},
+		{leaves[0], n23},
+		{leaves[3], n01},
+		{leaves[2], n01},
+	} {
+		valid, err := VerifySortedMerkleProof(h, root, leaves[i], proof)
+		require.NoError(t, err)
+		require.True(t, valid, "leaf %d", i)
+	}
+
+	valid, err := VerifySortedMerkleProof(h, root, leaves[0], [][32]byte{leaves[2], n23})
+	require.NoError(t, err)
+	require.False(t, valid)
+}
+
+// TestMerkleMultiProof tests OpenZeppelin-style multiproofs
+func TestMerkleMultiProof(t *testing.T) {
+	leaves := testMerkleLeaves(4)
+	h := MerkleHashBlake3
+
+	n01, _ := merkleHashSorted(h, leaves[0], leaves[1])
+	n23, _ := merkleHashSorted(h, leaves[2], leaves[3])
+	root, _ := merkleHashSorted(h, n01, n23)
+
+	// Adjacent leaves share a parent
+	valid, err := VerifyMerkleMultiProof(h, root, leaves[:2], [][32]byte{n23}, []bool{true, false})
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	// Leaves in different subtrees
+	valid, err = VerifyMerkleMultiProof(h, root,
+		[][32]byte{leaves[0], leaves[2]},
+		[][32]byte{leaves[1], leaves[3]},
+		[]bool{false, false, true},
+	)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	// Flag count must match leaves + proof - 1
+	_, err = VerifyMerkleMultiProof(h, root, leaves[:2], [][32]byte{n23}, []bool{true})
+	require.ErrorIs(t, err, ErrInvalidMultiProof)
+
+	// Unconsumed proof elements are rejected
+	_, err = VerifyMerkleMultiProof(h, root, leaves[:2], [][32]byte{n23, n01}, []bool{true, true, false})
+	require.ErrorIs(t, err, ErrInvalidMultiProof)
+}
+
+// TestMerklePrecompile tests the precompile encoding and gas
+func TestMerklePrecompile(t *testing.T) {
+	leaves := testMerkleLeaves(2)
+	h := MerkleHashSHA256
+	root, err := MerkleHashPair(h, leaves[0], leaves[1])
+	require.NoError(t, err)
+
+	input := []byte{OpMerkleVerifyIndexed, byte(h)}
+	input = append(input, root[:]...)
+	input = append(input, leaves[1][:]...)
+	input = binary.BigEndian.AppendUint64(input, 1)
+	input = binary.BigEndian.AppendUint32(input, 1)
+	input = append(input, leaves[0][:]...)
+
+	p := MerkleProofPrecompile
+	gas := p.RequiredGas(input)
+	require.Equal(t, uint64(GasMerkleBase+GasMerkleNodeSHA256), gas)
+
+	ret, remaining, err := p.Run(nil, common.Address{}, MerkleProofContractAddress, input, gas+10, true)
+	require.NoError(t, err)
+	require.Equal(t, encodeBool(true), ret)
+	require.Equal(t, uint64(10), remaining)
+
+	// Unknown hash function
+	input[1] = 0x7f
+	_, _, err = p.Run(nil, common.Address{}, MerkleProofContractAddress, input, 1_000_000, true)
+	require.ErrorIs(t, err, ErrUnsupportedMerkleHash)
+}
+
+// TestMerkleProofAddresses tests both modules sit at their registry slots
+func TestMerkleProofAddresses(t *testing.T) {
+	require.Equal(t, common.HexToAddress(registry.MerkleProofCChain), MerkleProofPrecompile.Address())
+	require.Equal(t, common.HexToAddress(registry.MerkleProofZChain), MerkleProofZChainPrecompile.Address())
+	require.Equal(t, MerkleConfigKey, MerkleModule.Configurator.MakeConfig().Key())
+	require.Equal(t, MerkleZChainConfigKey, MerkleZChainModule.Configurator.MakeConfig().Key())
+}
@@ -8,10 +8,13 @@ import (
 	"github.com/luxfi/precompile/contract"
 	"github.com/luxfi/precompile/modules"
 	"github.com/luxfi/precompile/precompileconfig"
+	"github.com/luxfi/precompile/registry"
 )
 
 var _ contract.Configurator = (*configurator)(nil)
 var _ contract.StatefulPrecompiledContract = (*zkVerifyPrecompile)(nil)
+var _ contract.StatefulPrecompiledContract = (*merkleProofPrecompile)(nil)
+var _ contract.Configurator = (*merkleConfigurator)(nil)
 
 // ConfigKey is the key used in json config files to specify this precompile config.
 const ConfigKey = "zkConfig"
@@ -41,6 +44,12 @@ var (
 	BatchProofContractAddress   = common.HexToAddress("0x0900000000000000000000000000000000000032")
 )
 
+// Merkle proof precompile addresses (registry privacy range 0x4233 / 0x4633)
+var (
+	MerkleProofContractAddress       = common.HexToAddress(registry.MerkleProofCChain)
+	MerkleProofZChainContractAddress = common.HexToAddress(registry.MerkleProofZChain)
+)
+
 // Hashing precompile addresses (Lux Hashing range 0x0500...01-03)
 var (
 	Poseidon2ContractAddress = common.HexToAddress("0x0500000000000000000000000000000000000001")
@@ -60,12 +69,51 @@ var Module = modules.Module{
 	Configurator: &configurator{},
 }
 
+// MerkleConfigKey is the json config key of the C-Chain Merkle proof precompile
+const MerkleConfigKey = "merkleProofConfig"
+
+// MerkleZChainConfigKey is the json config key of the Z-Chain Merkle proof precompile
+const MerkleZChainConfigKey = "merkleProofZChainConfig"
+
+// MerkleProofPrecompile is the C-Chain Merkle proof precompile
+var MerkleProofPrecompile = &merkleProofPrecompile{address: MerkleProofContractAddress}
+
+// MerkleProofZChainPrecompile is the Z-Chain Merkle proof precompile
+var MerkleProofZChainPrecompile = &merkleProofPrecompile{address: MerkleProofZChainContractAddress}
+
+// MerkleModule is the C-Chain Merkle proof precompile module
+var MerkleModule = modules.Module{
+	ConfigKey:    MerkleConfigKey,
+	Address:      MerkleProofContractAddress,
+	Contract:     MerkleProofPrecompile,
+	Configurator: &merkleConfigurator{key: MerkleConfigKey},
+}
+
+// MerkleZChainModule is the Z-Chain Merkle proof precompile module
+var MerkleZChainModule = modules.Module{
+	ConfigKey:    MerkleZChainConfigKey,
+	Address:      MerkleProofZChainContractAddress,
+	Contract:     MerkleProofZChainPrecompile,
+	Configurator: &merkleConfigurator{key: MerkleZChainConfigKey},
+}
+
 type configurator struct{}
 
+// merkleConfigurator serves both Merkle modules; key selects which one.
+type merkleConfigurator struct {
+	key string
+}
+
 func init() {
 	if err := modules.RegisterModule(Module); err != nil {
 		panic(err)
 	}
+	if err := modules.RegisterModule(MerkleModule); err != nil {
+		panic(err)
+	}
+	if err := modules.RegisterModule(MerkleZChainModule); err != nil {
+		panic(err)
+	}
 }
 
 func (*configurator) MakeConfig() precompileconfig.Config {
@@ -110,3 +158,51 @@ func (c *Config) Equal(cfg precompileconfig.Config) bool {
 func (c *Config) Verify(chainConfig precompileconfig.ChainConfig) error {
 	return nil
 }
+
+func (m *merkleConfigurator) MakeConfig() precompileconfig.Config {
+	return &MerkleConfig{key: m.key}
+}
+
+func (*merkleConfigurator) Configure(
+	chainConfig precompileconfig.ChainConfig,
+	cfg precompileconfig.Config,
+	state contract.StateDB,
+	blockContext contract.ConfigurationBlockContext,
+) error {
+	// No state initialization required
+	return nil
+}
+
+// MerkleConfig implements the precompileconfig.Config interface
+type MerkleConfig struct {
+	Upgrade precompileconfig.Upgrade `json:"upgrade,omitempty"`
+
+	key string
+}
+
+func (c *MerkleConfig) Key() string {
+	if c.key == "" {
+		return MerkleConfigKey
+	}
+	return c.key
+}
+
+func (c *MerkleConfig) Timestamp() *uint64 {
+	return c.Upgrade.Timestamp()
+}
+
+func (c *MerkleConfig) IsDisabled() bool {
+	return c.Upgrade.Disable
+}
+
+func (c *MerkleConfig) Equal(cfg precompileconfig.Config) bool {
+	other, ok := cfg.(*MerkleConfig)
+	if !ok {
+		return false
+	}
+	return c.Key() == other.Key() && c.Upgrade.Equal(&other.Upgrade)
+}
+
+func (c *MerkleConfig) Verify(chainConfig precompileconfig.ChainConfig) error {
+	return nil
+}
@@ -0,0 +1,305 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	blsfr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/luxfi/crypto/bn256"
+)
+
+// Multi-scalar multiplication (MSM)
+//
+// Preparing the pairing input of a Groth16 proof computes ∑ wᵢ·ICᵢ over
+// every public input, which dominates verification for circuits with many
+// inputs. MSMs at or above a size threshold are offloaded to an accelerator
+// registered through SetMSMAccelerator, normally the luxfi/accel GPU
+// backend wired in by the host. Smaller MSMs stay on the CPU, where launch
+// overhead would outweigh the speedup. An accelerator error, or a result
+// that does not decode to a curve point, falls back to the CPU.
+//
+// A result that decodes to a wrong point cannot be told apart from a right
+// one without redoing the MSM, so the accelerator is trusted: a device that
+// returns wrong points makes its node accept or reject proofs differently
+// from the rest of the network. Two checks bound that trust. Backends are
+// registered only after a known-answer test (CheckMSMAccelerator), and every
+// MSMCrossCheck-th offloaded MSM, starting with the first, is recomputed on
+// the CPU. A mismatch returns the CPU result and unregisters the
+// accelerator for good.
+
+const (
+	// DefaultMSMThreshold is the smallest MSM offloaded to the accelerator
+	DefaultMSMThreshold = 64
+
+	// DefaultMSMCrossCheck is how often an offloaded MSM is recomputed on
+	// the CPU: one in every DefaultMSMCrossCheck
+	DefaultMSMCrossCheck = 32
+
+	// G1 point encodings exchanged with the accelerator (uncompressed affine)
+	bn254G1Size    = 64
+	bls12381G1Size = bls12381.SizeOfG1AffineUncompressed
+)
+
+var (
+	ErrMSMLength = errors.New("msm: points and scalars differ in length")
+	ErrMSMPoint  = errors.New("msm: invalid curve point")
+	ErrMSMCheck  = errors.New("msm: accelerator failed the known-answer test")
+)
+
+// MSMCurve identifies the group an MSM runs over
+type MSMCurve uint8
+
+const (
+	CurveBN254G1 MSMCurve = iota + 1
+	CurveBLS12381G1
+)
+
+// MSMAccelerator offloads multi-scalar multiplication
+type MSMAccelerator interface {
+	// Name identifies the backend, e.g. "CUDA" or "Metal"
+	Name() string
+
+	// Available reports whether the device can take work
+	Available() bool
+
+	// MSM returns ∑ scalars[i]·points[i]. Points and the result use the
+	// curve's uncompressed affine encoding.
+	MSM(curve MSMCurve, points [][]byte, scalars []*big.Int) ([]byte, error)
+}
+
+// MSMStats counts how MSMs were executed
+type MSMStats struct {
+	Accelerated uint64 // Computed by the accelerator
+	CPU         uint64 // Computed on the CPU, including fallbacks
+	Fallbacks   uint64 // Accelerator attempts that failed over to the CPU
+	Mismatches  uint64 // Cross-checked results that differed from the CPU
+}
+
+// msmDispatcher routes MSMs between the accelerator and the CPU
+type msmDispatcher struct {
+	mu          sync.RWMutex
+	accelerator MSMAccelerator
+	threshold   int
+	crossCheck  int
+	offloaded   uint64
+	stats       MSMStats
+}
+
+var msmDispatch = &msmDispatcher{threshold: DefaultMSMThreshold, crossCheck: DefaultMSMCrossCheck}
+
+// SetMSMAccelerator registers the MSM accelerator; nil disables offloading
+func SetMSMAccelerator(acc MSMAccelerator) {
+	msmDispatch.mu.Lock()
+	defer msmDispatch.mu.Unlock()
+	msmDispatch.accelerator = acc
+}
+
+// SetMSMThreshold sets the smallest MSM offloaded to the accelerator
+func SetMSMThreshold(n int) {
+	msmDispatch.mu.Lock()
+	defer msmDispatch.mu.Unlock()
+	msmDispatch.threshold = max(n, 1)
+}
+
+// SetMSMCrossCheck recomputes one in every n offloaded MSMs on the CPU;
+// 1 checks every result and 0 disables checking
+func SetMSMCrossCheck(n int) {
+	msmDispatch.mu.Lock()
+	defer msmDispatch.mu.Unlock()
+	msmDispatch.crossCheck = max(n, 0)
+}
+
+// MSMStatistics returns the MSM execution counters
+func MSMStatistics() MSMStats {
+	msmDispatch.mu.RLock()
+	defer msmDispatch.mu.RUnlock()
+	return msmDispatch.stats
+}
+
+// MSMBackend returns the name of the registered accelerator, or "CPU"
+func MSMBackend() string {
+	msmDispatch.mu.RLock()
+	defer msmDispatch.mu.RUnlock()
+	if msmDispatch.accelerator == nil || !msmDispatch.accelerator.Available() {
+		return "CPU"
+	}
+	return msmDispatch.accelerator.Name()
+}
+
+// CheckMSMAccelerator runs a known-answer MSM on both curves through acc
+// and returns ErrMSMCheck unless it matches the CPU
+func CheckMSMAccelerator(acc MSMAccelerator) error {
+	scalars := make([]*big.Int, 4)
+	bnPoints := make([]*bn256.G1, len(scalars))
+	bnEncoded := make([][]byte, len(scalars))
+	_, _, blsGen, _ := bls12381.Generators()
+	blsAffine := make([]bls12381.G1Affine, len(scalars))
+	blsEncoded := make([][]byte, len(scalars))
+	for i := range scalars {
+		k := big.NewInt(int64(2*i + 3))
+		scalars[i] = new(big.Int).Lsh(k, uint(60*i))
+		bnPoints[i] = new(bn256.G1).ScalarBaseMult(k)
+		bnEncoded[i] = bnPoints[i].Marshal()
+		blsAffine[i].ScalarMultiplication(&blsGen, k)
+		raw := blsAffine[i].RawBytes()
+		blsEncoded[i] = raw[:]
+	}
+
+	out, err := acc.MSM(CurveBN254G1, bnEncoded, scalars)
+	if err != nil || !bytes.Equal(out, msmBN254CPU(bnPoints, scalars).Marshal()) {
+		return ErrMSMCheck
+	}
+	want, err := msmBLS12381CPU(blsAffine, scalars)
+	if err != nil {
+		return err
+	}
+	if out, err := acc.MSM(CurveBLS12381G1, blsEncoded, scalars); err != nil || !bytes.Equal(out, want) {
+		return ErrMSMCheck
+	}
+	return nil
+}
+
+// offload returns the accelerator when an MSM of n points should use it
+func (d *msmDispatcher) offload(n int) MSMAccelerator {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.accelerator == nil || n < d.threshold || !d.accelerator.Available() {
+		return nil
+	}
+	return d.accelerator
+}
+
+// sample reports whether the next accelerated result is cross-checked
+func (d *msmDispatcher) sample() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := d.offloaded
+	d.offloaded++
+	return d.crossCheck > 0 && n%uint64(d.crossCheck) == 0
+}
+
+// reject unregisters acc after a result that differed from the CPU
+func (d *msmDispatcher) reject(acc MSMAccelerator) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.accelerator == acc {
+		d.accelerator = nil
+	}
+	d.stats.Mismatches++
+	d.stats.CPU++
+}
+
+// record counts one MSM
+func (d *msmDispatcher) record(accelerated, fellBack bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if accelerated {
+		d.stats.Accelerated++
+		return
+	}
+	d.stats.CPU++
+	if fellBack {
+		d.stats.Fallbacks++
+	}
+}
+
+// msmBN254 computes ∑ scalars[i]·points[i] over BN254 G1
+func msmBN254(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+	acc := msmDispatch.offload(len(points))
+	if acc != nil {
+		encoded := make([][]byte, len(points))
+		for i, p := range points {
+			encoded[i] = p.Marshal()
+		}
+		if out, err := acc.MSM(CurveBN254G1, encoded, scalars); err == nil && len(out) == bn254G1Size {
+			result := new(bn256.G1)
+			if _, err := result.Unmarshal(out); err == nil {
+				if msmDispatch.sample() {
+					if cpu := msmBN254CPU(points, scalars); !bytes.Equal(cpu.Marshal(), result.Marshal()) {
+						msmDispatch.reject(acc)
+						return cpu
+					}
+				}
+				msmDispatch.record(true, false)
+				return result
+			}
+		}
+	}
+	msmDispatch.record(false, acc != nil)
+	return msmBN254CPU(points, scalars)
+}
+
+// msmBN254CPU computes ∑ scalars[i]·points[i] over BN254 G1 on the CPU
+func msmBN254CPU(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+	result := new(bn256.G1).ScalarMult(points[0], scalars[0])
+	for i := 1; i < len(points); i++ {
+		result.Add(result, new(bn256.G1).ScalarMult(points[i], scalars[i]))
+	}
+	return result
+}
+
+// MSMBLS12381G1 computes ∑ scalars[i]·points[i] over BLS12-381 G1. Points
+// are compressed or uncompressed encodings; the result is uncompressed.
+func MSMBLS12381G1(points [][]byte, scalars []*big.Int) ([]byte, error) {
+	if len(points) == 0 || len(points) != len(scalars) {
+		return nil, ErrMSMLength
+	}
+
+	affine := make([]bls12381.G1Affine, len(points))
+	for i, p := range points {
+		if _, err := affine[i].SetBytes(p); err != nil {
+			return nil, ErrMSMPoint
+		}
+	}
+
+	acc := msmDispatch.offload(len(points))
+	if acc != nil {
+		encoded := make([][]byte, len(affine))
+		for i := range affine {
+			raw := affine[i].RawBytes()
+			encoded[i] = raw[:]
+		}
+		if out, err := acc.MSM(CurveBLS12381G1, encoded, scalars); err == nil && len(out) == bls12381G1Size {
+			var result bls12381.G1Affine
+			if _, err := result.SetBytes(out); err == nil {
+				raw := result.RawBytes()
+				if msmDispatch.sample() {
+					cpu, err := msmBLS12381CPU(affine, scalars)
+					if err != nil {
+						return nil, err
+					}
+					if !bytes.Equal(cpu, raw[:]) {
+						msmDispatch.reject(acc)
+						return cpu, nil
+					}
+				}
+				msmDispatch.record(true, false)
+				return raw[:], nil
+			}
+		}
+	}
+	msmDispatch.record(false, acc != nil)
+	return msmBLS12381CPU(affine, scalars)
+}
+
+// msmBLS12381CPU computes ∑ scalars[i]·points[i] over BLS12-381 G1 on the
+// CPU and returns the uncompressed result
+func msmBLS12381CPU(affine []bls12381.G1Affine, scalars []*big.Int) ([]byte, error) {
+	elems := make([]blsfr.Element, len(scalars))
+	for i, s := range scalars {
+		elems[i].SetBigInt(s)
+	}
+	var result bls12381.G1Affine
+	if _, err := result.MultiExp(affine, elems, ecc.MultiExpConfig{}); err != nil {
+		return nil, err
+	}
+	raw := result.RawBytes()
+	return raw[:], nil
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/luxfi/accel"
+	zkops "github.com/luxfi/accel/ops/zk"
+)
+
+var ErrMSMUnavailable = errors.New("msm: no accel backend available")
+
+// accelMSM runs MSMs on the luxfi/accel GPU backend
+type accelMSM struct {
+	backend string
+}
+
+func (a *accelMSM) Name() string    { return a.backend }
+func (a *accelMSM) Available() bool { return accel.Available() }
+
+// MSM passes points through and scalars as 32-byte big-endian words
+func (a *accelMSM) MSM(curve MSMCurve, points [][]byte, scalars []*big.Int) ([]byte, error) {
+	var c zkops.CurveType
+	switch curve {
+	case CurveBN254G1:
+		c = zkops.CurveBN254
+	case CurveBLS12381G1:
+		c = zkops.CurveBLS12_381
+	default:
+		return nil, zkops.ErrInvalidInput
+	}
+
+	encoded := make([][]byte, len(scalars))
+	for i, s := range scalars {
+		if s.Sign() < 0 || s.BitLen() > 256 {
+			return nil, zkops.ErrInvalidInput
+		}
+		encoded[i] = s.FillBytes(make([]byte, 32))
+	}
+	return zkops.MSM(c, encoded, points)
+}
+
+// RegisterAccelMSM registers the luxfi/accel backend as the MSM accelerator
+// if a device is present and it passes CheckMSMAccelerator
+func RegisterAccelMSM() error {
+	if err := accel.Init(); err != nil {
+		return err
+	}
+	backends := accel.Backends()
+	if len(backends) == 0 {
+		return ErrMSMUnavailable
+	}
+
+	acc := &accelMSM{backend: backends[0].String()}
+	if err := CheckMSMAccelerator(acc); err != nil {
+		return err
+	}
+	SetMSMAccelerator(acc)
+	return nil
+}
+
+func init() {
+	// Without a working device MSMs stay on the CPU
+	_ = RegisterAccelMSM()
+}
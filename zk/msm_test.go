@@ -0,0 +1,254 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	blsfr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/luxfi/crypto/bn256"
+)
+
+// gnarkAccelerator stands in for a GPU backend with an independent MSM
+// implementation (gnark-crypto's bucket method), so agreement with the CPU
+// path is a differential check
+type gnarkAccelerator struct {
+	calls int
+}
+
+func (a *gnarkAccelerator) Name() string    { return "gnark" }
+func (a *gnarkAccelerator) Available() bool { return true }
+
+func (a *gnarkAccelerator) MSM(curve MSMCurve, points [][]byte, scalars []*big.Int) ([]byte, error) {
+	a.calls++
+	switch curve {
+	case CurveBN254G1:
+		affine := make([]bn254.G1Affine, len(points))
+		for i, p := range points {
+			affine[i].X.SetBytes(p[:32])
+			affine[i].Y.SetBytes(p[32:64])
+		}
+		elems := make([]fr.Element, len(scalars))
+		for i, s := range scalars {
+			elems[i].SetBigInt(s)
+		}
+		var result bn254.G1Affine
+		if _, err := result.MultiExp(affine, elems, ecc.MultiExpConfig{}); err != nil {
+			return nil, err
+		}
+		x, y := result.X.Bytes(), result.Y.Bytes()
+		return append(x[:], y[:]...), nil
+
+	case CurveBLS12381G1:
+		affine := make([]bls12381.G1Affine, len(points))
+		for i, p := range points {
+			if _, err := affine[i].SetBytes(p); err != nil {
+				return nil, err
+			}
+		}
+		elems := make([]blsfr.Element, len(scalars))
+		for i, s := range scalars {
+			elems[i].SetBigInt(s)
+		}
+		var result bls12381.G1Affine
+		if _, err := result.MultiExp(affine, elems, ecc.MultiExpConfig{}); err != nil {
+			return nil, err
+		}
+		raw := result.RawBytes()
+		return raw[:], nil
+	}
+	return nil, errors.New("unsupported curve")
+}
+
+// faultyAccelerator fails every MSM, either with an error or with bytes
+// that are not a curve point
+type faultyAccelerator struct {
+	garbage bool
+}
+
+func (a *faultyAccelerator) Name() string    { return "faulty" }
+func (a *faultyAccelerator) Available() bool { return true }
+
+func (a *faultyAccelerator) MSM(curve MSMCurve, points [][]byte, scalars []*big.Int) ([]byte, error) {
+	if a.garbage {
+		return bytes.Repeat([]byte{0x01}, bn254G1Size), nil
+	}
+	return nil, errors.New("device lost")
+}
+
+// lyingAccelerator returns the first point, a valid encoding of the wrong
+// result
+type lyingAccelerator struct{}
+
+func (lyingAccelerator) Name() string    { return "lying" }
+func (lyingAccelerator) Available() bool { return true }
+
+func (lyingAccelerator) MSM(curve MSMCurve, points [][]byte, scalars []*big.Int) ([]byte, error) {
+	return points[0], nil
+}
+
+// useMSMAccelerator registers acc with threshold for the rest of the test
+func useMSMAccelerator(t *testing.T, acc MSMAccelerator, threshold int) {
+	t.Helper()
+	SetMSMAccelerator(acc)
+	SetMSMThreshold(threshold)
+	t.Cleanup(func() {
+		SetMSMAccelerator(nil)
+		SetMSMThreshold(DefaultMSMThreshold)
+	})
+}
+
+// randomMSM returns n random BN254 G1 points and scalars
+func randomMSM(rng *rand.Rand, n int) ([]*bn256.G1, []*big.Int) {
+	order := fr.Modulus()
+	points := make([]*bn256.G1, n)
+	scalars := make([]*big.Int, n)
+	for i := range points {
+		points[i] = new(bn256.G1).ScalarBaseMult(new(big.Int).Rand(rng, order))
+		scalars[i] = new(big.Int).Rand(rng, order)
+	}
+	return points, scalars
+}
+
+// TestMSMBN254Differential checks accelerated MSMs against the CPU path
+func TestMSMBN254Differential(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	acc := &gnarkAccelerator{}
+	useMSMAccelerator(t, acc, 16)
+
+	for _, n := range []int{1, 15, 16, 100} {
+		points, scalars := randomMSM(rng, n)
+
+		SetMSMAccelerator(nil)
+		want := msmBN254(points, scalars).Marshal()
+
+		SetMSMAccelerator(acc)
+		calls := acc.calls
+		got := msmBN254(points, scalars).Marshal()
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("MSM of %d points differs between accelerator and CPU", n)
+		}
+		if offloaded := acc.calls > calls; offloaded != (n >= 16) {
+			t.Errorf("MSM of %d points: offloaded=%v with threshold 16", n, offloaded)
+		}
+	}
+}
+
+// TestMSMBLS12381Differential checks BLS12-381 MSMs against the CPU path
+func TestMSMBLS12381Differential(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	_, _, g1, _ := bls12381.Generators()
+
+	points := make([][]byte, 40)
+	scalars := make([]*big.Int, len(points))
+	for i := range points {
+		var p bls12381.G1Affine
+		p.ScalarMultiplication(&g1, new(big.Int).Rand(rng, blsfr.Modulus()))
+		raw := p.RawBytes()
+		points[i] = raw[:]
+		scalars[i] = new(big.Int).Rand(rng, blsfr.Modulus())
+	}
+
+	want, err := MSMBLS12381G1(points, scalars)
+	if err != nil {
+		t.Fatalf("MSMBLS12381G1 failed: %v", err)
+	}
+
+	useMSMAccelerator(t, &gnarkAccelerator{}, 8)
+	before := MSMStatistics()
+	got, err := MSMBLS12381G1(points, scalars)
+	if err != nil {
+		t.Fatalf("Accelerated MSMBLS12381G1 failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("BLS12-381 MSM differs between accelerator and CPU")
+	}
+	if MSMStatistics().Accelerated != before.Accelerated+1 {
+		t.Error("Expected BLS12-381 MSM to be accelerated")
+	}
+
+	if _, err := MSMBLS12381G1(points, scalars[:1]); err != ErrMSMLength {
+		t.Errorf("Expected ErrMSMLength, got %v", err)
+	}
+}
+
+// TestMSMFallback checks that a failing accelerator falls back to the CPU
+func TestMSMFallback(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	points, scalars := randomMSM(rng, 20)
+	want := msmBN254(points, scalars).Marshal()
+
+	for _, acc := range []*faultyAccelerator{{}, {garbage: true}} {
+		useMSMAccelerator(t, acc, 1)
+		before := MSMStatistics()
+		if got := msmBN254(points, scalars).Marshal(); !bytes.Equal(got, want) {
+			t.Errorf("Fallback MSM (garbage=%v) differs from CPU", acc.garbage)
+		}
+		if MSMStatistics().Fallbacks != before.Fallbacks+1 {
+			t.Errorf("Expected a fallback to be recorded (garbage=%v)", acc.garbage)
+		}
+	}
+}
+
+// TestGroth16WithMSMAccelerator checks proof verification through the accelerator
+func TestGroth16WithMSMAccelerator(t *testing.T) {
+	zv := NewZKVerifier()
+	keyID := registerTrapdoorKey(t, zv)
+	acc := &gnarkAccelerator{}
+	useMSMAccelerator(t, acc, 1)
+
+	proof := trapdoorProof(23, 29, big.NewInt(1), big.NewInt(2))
+	result, err := zv.VerifyGroth16(keyID, proof.A, proof.B, proof.C, proof.PublicInputs)
+	if err != nil || !result.Valid {
+		t.Fatalf("Expected proof to verify with accelerator (err=%v)", err)
+	}
+	if acc.calls == 0 {
+		t.Error("Expected input commitment to use the accelerator")
+	}
+
+	bad := trapdoorProof(23, 29, big.NewInt(1), big.NewInt(3))
+	bad.PublicInputs[1] = big.NewInt(2)
+	if result, _ := zv.VerifyGroth16(keyID, bad.A, bad.B, bad.C, bad.PublicInputs); result != nil && result.Valid {
+		t.Error("Expected proof with wrong inputs to fail with accelerator")
+	}
+}
+
+// TestMSMCrossCheck checks that a wrong accelerator result is caught, the
+// CPU result returned and the accelerator unregistered
+func TestMSMCrossCheck(t *testing.T) {
+	if err := CheckMSMAccelerator(&gnarkAccelerator{}); err != nil {
+		t.Errorf("Expected gnark accelerator to pass the known-answer test, got %v", err)
+	}
+	if err := CheckMSMAccelerator(lyingAccelerator{}); err != ErrMSMCheck {
+		t.Errorf("Expected ErrMSMCheck, got %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(4))
+	points, scalars := randomMSM(rng, 20)
+	want := msmBN254(points, scalars).Marshal()
+
+	useMSMAccelerator(t, lyingAccelerator{}, 1)
+	SetMSMCrossCheck(1)
+	t.Cleanup(func() { SetMSMCrossCheck(DefaultMSMCrossCheck) })
+
+	before := MSMStatistics()
+	if got := msmBN254(points, scalars).Marshal(); !bytes.Equal(got, want) {
+		t.Error("Cross-checked MSM differs from CPU")
+	}
+	if MSMStatistics().Mismatches != before.Mismatches+1 {
+		t.Error("Expected a mismatch to be recorded")
+	}
+	if backend := MSMBackend(); backend != "CPU" {
+		t.Errorf("Expected accelerator to be unregistered, backend is %s", backend)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"math/big"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+	ethtypes "github.com/luxfi/geth/core/types"
+	"github.com/luxfi/precompile/ecies"
+)
+
+// Encrypted note memos.
+//
+// A recipient cannot find a note in a confidential pool from its commitment
+// alone, so the sender attaches a memo: the note's opening (amount, asset,
+// owner and blinding) encrypted with ECIES to the recipient's registered
+// viewing key. The pool records the memo as a NoteEvent and emits it as an
+// EncryptedNote log. A wallet scans the events of a pool and keeps the memos
+// its viewing key decrypts; amounts never appear on-chain in the clear.
+//
+// Viewing keys are uncompressed secp256k1 public keys. The ECIES shared info
+// is NoteMemoDomain || poolID, so a memo only opens against the pool it was
+// posted to.
+
+const (
+	// NoteMemoDomain domain-separates note memos in the ECIES shared info
+	NoteMemoDomain = "lux.zk.note.v1"
+
+	// NotePlaintextSize is the encoded size of a note opening
+	NotePlaintextSize = 128
+
+	// MaxNoteMemoSize bounds the memo stored with a commitment
+	MaxNoteMemoSize = 512
+)
+
+// NoteEventTopic is the topic of the EncryptedNote log:
+// EncryptedNote(bytes32 indexed poolID, bytes32 indexed commitmentID, uint64 index, bytes memo)
+var NoteEventTopic = common.BytesToHash(crypto.Keccak256([]byte("EncryptedNote(bytes32,bytes32,uint64,bytes)")))
+
+// NotePlaintext is the opening of a note, as carried in its memo
+type NotePlaintext struct {
+	Amount   *big.Int
+	AssetID  [32]byte
+	Owner    common.Address
+	Blinding [32]byte
+}
+
+// NoteEvent is an encrypted memo recorded by a confidential pool
+type NoteEvent struct {
+	PoolID       [32]byte
+	CommitmentID [32]byte
+	Index        uint64 // Position in the pool's note sequence
+	Memo         []byte
+}
+
+// Encode lays out the note as amount (32) || assetID (32) || owner (32) ||
+// blinding (32)
+func (n *NotePlaintext) Encode() ([]byte, error) {
+	if n.Amount == nil || n.Amount.Sign() < 0 || n.Amount.BitLen() > 256 {
+		return nil, ErrInvalidNoteMemo
+	}
+	out := make([]byte, NotePlaintextSize)
+	n.Amount.FillBytes(out[0:32])
+	copy(out[32:64], n.AssetID[:])
+	copy(out[76:96], n.Owner.Bytes())
+	copy(out[96:128], n.Blinding[:])
+	return out, nil
+}
+
+// DecodeNotePlaintext decodes a note encoded by NotePlaintext.Encode
+func DecodeNotePlaintext(data []byte) (*NotePlaintext, error) {
+	if len(data) != NotePlaintextSize {
+		return nil, ErrInvalidNoteMemo
+	}
+	n := &NotePlaintext{
+		Amount: new(big.Int).SetBytes(data[0:32]),
+		Owner:  common.BytesToAddress(data[76:96]),
+	}
+	copy(n.AssetID[:], data[32:64])
+	copy(n.Blinding[:], data[96:128])
+	return n, nil
+}
+
+// noteSharedInfo binds a memo to its pool
+func noteSharedInfo(poolID [32]byte) []byte {
+	return append([]byte(NoteMemoDomain), poolID[:]...)
+}
+
+// EncryptNoteMemo encrypts a note to a viewing key for posting to poolID
+func EncryptNoteMemo(viewingKey []byte, poolID [32]byte, note *NotePlaintext) ([]byte, error) {
+	plaintext, err := note.Encode()
+	if err != nil {
+		return nil, err
+	}
+	memo, err := ecies.Encrypt(ecies.CurveSecp256k1, viewingKey, noteSharedInfo(poolID), plaintext)
+	if err != nil {
+		return nil, ErrInvalidViewingKey
+	}
+	return memo, nil
+}
+
+// DecryptNoteMemo opens a memo posted to poolID with a viewing private key.
+// It fails for memos addressed to other keys or other pools.
+func DecryptNoteMemo(viewingPrivateKey []byte, poolID [32]byte, memo []byte) (*NotePlaintext, error) {
+	plaintext, err := ecies.Decrypt(ecies.CurveSecp256k1, viewingPrivateKey, noteSharedInfo(poolID), memo)
+	if err != nil {
+		return nil, ErrInvalidNoteMemo
+	}
+	return DecodeNotePlaintext(plaintext)
+}
+
+// RegisterViewingKey sets the viewing key senders encrypt memos to for
+// owner. Registering again replaces the key.
+func (zv *ZKVerifier) RegisterViewingKey(owner common.Address, publicKey []byte) error {
+	if len(publicKey) != 65 {
+		return ErrInvalidViewingKey
+	}
+	if _, err := crypto.UnmarshalPubkey(publicKey); err != nil {
+		return ErrInvalidViewingKey
+	}
+
+	zv.mu.Lock()
+	defer zv.mu.Unlock()
+
+	zv.ViewingKeys[owner] = append([]byte(nil), publicKey...)
+	return nil
+}
+
+// ViewingKey returns the viewing key registered for owner
+func (zv *ZKVerifier) ViewingKey(owner common.Address) ([]byte, error) {
+	zv.mu.RLock()
+	defer zv.mu.RUnlock()
+
+	key := zv.ViewingKeys[owner]
+	if key == nil {
+		return nil, ErrViewingKeyNotFound
+	}
+	return key, nil
+}
+
+// EncryptNoteFor encrypts a note to recipient's registered viewing key
+func (zv *ZKVerifier) EncryptNoteFor(recipient common.Address, poolID [32]byte, note *NotePlaintext) ([]byte, error) {
+	key, err := zv.ViewingKey(recipient)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptNoteMemo(key, poolID, note)
+}
+
+// ScanNotes returns up to limit note events of a pool starting at index
+// from. A wallet passes the index after the last event it has seen.
+func (zv *ZKVerifier) ScanNotes(poolID [32]byte, from uint64, limit int) ([]*NoteEvent, error) {
+	zv.mu.RLock()
+	defer zv.mu.RUnlock()
+
+	pool := zv.Pools[poolID]
+	if pool == nil {
+		return nil, ErrPoolNotFound
+	}
+	if from >= uint64(len(pool.Notes)) || limit <= 0 {
+		return nil, nil
+	}
+	end := uint64(len(pool.Notes))
+	if uint64(limit) < end-from {
+		end = from + uint64(limit)
+	}
+	return append([]*NoteEvent(nil), pool.Notes[from:end]...), nil
+}
+
+// recordNote appends a commitment's memo to the pool's note sequence.
+// Caller must hold the verifier lock.
+func (pool *ConfidentialPool) recordNote(commitID [32]byte, memo []byte) *NoteEvent {
+	event := &NoteEvent{
+		PoolID:       pool.PoolID,
+		CommitmentID: commitID,
+		Index:        uint64(len(pool.Notes)),
+		Memo:         memo,
+	}
+	pool.Notes = append(pool.Notes, event)
+	return event
+}
+
+// Log builds the EncryptedNote log for the event, emitted by address. The
+// data is ABI-encoded (uint64 index, bytes memo).
+func (e *NoteEvent) Log(address common.Address) *ethtypes.Log {
+	padded := (len(e.Memo) + 31) / 32 * 32
+	data := make([]byte, 96+padded)
+	new(big.Int).SetUint64(e.Index).FillBytes(data[0:32])
+	data[63] = 0x40
+	new(big.Int).SetUint64(uint64(len(e.Memo))).FillBytes(data[64:96])
+	copy(data[96:], e.Memo)
+
+	return &ethtypes.Log{
+		Address: address,
+		Topics:  []common.Hash{NoteEventTopic, e.PoolID, e.CommitmentID},
+		Data:    data,
+	}
+}
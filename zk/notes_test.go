@@ -0,0 +1,97 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/crypto"
+	"github.com/luxfi/geth/common"
+)
+
+// TestEncryptedNoteMemo tests posting a memo to a recipient's viewing key
+// and scanning it back
+func TestEncryptedNoteMemo(t *testing.T) {
+	zv := NewZKVerifier()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	recipient := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	token := common.HexToAddress("0xABCDABCDABCDABCDABCDABCDABCDABCDABCDABCD")
+	poolID, _ := zv.CreateConfidentialPool(owner, token, 32)
+
+	viewKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	if err := zv.RegisterViewingKey(recipient, []byte{0x04, 0x01}); err != ErrInvalidViewingKey {
+		t.Errorf("Expected ErrInvalidViewingKey, got %v", err)
+	}
+	if _, err := zv.EncryptNoteFor(recipient, poolID, &NotePlaintext{Amount: big.NewInt(1)}); err != ErrViewingKeyNotFound {
+		t.Errorf("Expected ErrViewingKeyNotFound, got %v", err)
+	}
+	if err := zv.RegisterViewingKey(recipient, crypto.FromECDSAPub(&viewKey.PublicKey)); err != nil {
+		t.Fatalf("RegisterViewingKey failed: %v", err)
+	}
+
+	note := &NotePlaintext{
+		Amount:   big.NewInt(5e17),
+		AssetID:  common.BytesToHash(token.Bytes()),
+		Owner:    recipient,
+		Blinding: [32]byte{0x42},
+	}
+	memo, err := zv.EncryptNoteFor(recipient, poolID, note)
+	if err != nil {
+		t.Fatalf("EncryptNoteFor failed: %v", err)
+	}
+
+	// A commitment without a memo posts no note
+	if _, err := zv.AddCommitment(poolID, &Commitment{Value: []byte("plain"), Amount: big.NewInt(1)}); err != nil {
+		t.Fatalf("AddCommitment failed: %v", err)
+	}
+	commitID, err := zv.AddCommitment(poolID, &Commitment{Value: []byte("noted"), Amount: note.Amount, Memo: memo})
+	if err != nil {
+		t.Fatalf("AddCommitment failed: %v", err)
+	}
+	if _, err := zv.AddCommitment(poolID, &Commitment{Value: []byte("big"), Amount: big.NewInt(1), Memo: make([]byte, MaxNoteMemoSize+1)}); err != ErrInvalidNoteMemo {
+		t.Errorf("Expected ErrInvalidNoteMemo for oversized memo, got %v", err)
+	}
+
+	events, err := zv.ScanNotes(poolID, 0, 10)
+	if err != nil {
+		t.Fatalf("ScanNotes failed: %v", err)
+	}
+	if len(events) != 1 || events[0].CommitmentID != commitID || events[0].Index != 0 {
+		t.Fatalf("Expected one note for the commitment, got %+v", events)
+	}
+	if more, _ := zv.ScanNotes(poolID, 1, 10); len(more) != 0 {
+		t.Errorf("Expected no notes past the cursor, got %d", len(more))
+	}
+
+	// The recipient opens the memo; amounts are not in the event in the clear
+	opened, err := DecryptNoteMemo(crypto.FromECDSA(viewKey), poolID, events[0].Memo)
+	if err != nil {
+		t.Fatalf("DecryptNoteMemo failed: %v", err)
+	}
+	if opened.Amount.Cmp(note.Amount) != 0 || opened.Owner != recipient || opened.AssetID != note.AssetID || opened.Blinding != note.Blinding {
+		t.Errorf("Decrypted note mismatch: %+v", opened)
+	}
+
+	// Other keys and other pools cannot open it
+	otherKey, _ := crypto.GenerateKey()
+	if _, err := DecryptNoteMemo(crypto.FromECDSA(otherKey), poolID, events[0].Memo); err != ErrInvalidNoteMemo {
+		t.Errorf("Expected ErrInvalidNoteMemo for wrong key, got %v", err)
+	}
+	if _, err := DecryptNoteMemo(crypto.FromECDSA(viewKey), [32]byte{1}, events[0].Memo); err != ErrInvalidNoteMemo {
+		t.Errorf("Expected ErrInvalidNoteMemo for wrong pool, got %v", err)
+	}
+
+	log := events[0].Log(CommitmentContractAddress)
+	if len(log.Topics) != 3 || log.Topics[0] != NoteEventTopic || log.Topics[2] != commitID {
+		t.Errorf("Unexpected log topics: %v", log.Topics)
+	}
+	if !bytes.Equal(log.Data[96:96+len(memo)], memo) {
+		t.Error("Expected memo in log data")
+	}
+}
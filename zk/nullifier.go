@@ -0,0 +1,64 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"crypto/sha256"
+)
+
+// Nullifier namespaces
+//
+// Nullifiers are only unique within the pool or circuit that derives them.
+// Storing them in a single global set lets a nullifier spent in one pool
+// block an identical value in another, and lets a malicious circuit grief
+// other pools by spending hashes it knows they will produce. Every nullifier
+// is therefore stored under a domain-separated key:
+//
+//	key = SHA256("lux.zk.nullifier.v1" || domain || nullifier)
+//
+// where the domain is derived from a confidential pool ID or a circuit's
+// verifying key ID.
+
+// Domain separation tags
+var (
+	nullifierKeyTag  = []byte("lux.zk.nullifier.v1")
+	poolDomainTag    = []byte("lux.zk.nullifier.pool")
+	circuitDomainTag = []byte("lux.zk.nullifier.circuit")
+)
+
+// NullifierDomain identifies the namespace a nullifier is spent in
+type NullifierDomain [32]byte
+
+// PoolNullifierDomain returns the nullifier domain of a confidential pool
+func PoolNullifierDomain(poolID [32]byte) NullifierDomain {
+	return deriveNullifierDomain(poolDomainTag, poolID)
+}
+
+// CircuitNullifierDomain returns the nullifier domain of a circuit,
+// identified by its verifying key ID
+func CircuitNullifierDomain(keyID [32]byte) NullifierDomain {
+	return deriveNullifierDomain(circuitDomainTag, keyID)
+}
+
+// NullifierKey returns the storage key of a nullifier within a domain
+func NullifierKey(domain NullifierDomain, nullifierHash [32]byte) [32]byte {
+	h := sha256.New()
+	h.Write(nullifierKeyTag)
+	h.Write(domain[:])
+	h.Write(nullifierHash[:])
+
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+func deriveNullifierDomain(tag []byte, id [32]byte) NullifierDomain {
+	h := sha256.New()
+	h.Write(tag)
+	h.Write(id[:])
+
+	var domain NullifierDomain
+	copy(domain[:], h.Sum(nil))
+	return domain
+}
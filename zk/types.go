@@ -71,6 +71,7 @@ const (
 	CircuitLiquidity                      // Liquidity provision
 	CircuitRollupBatch                    // Rollup batch
 	CircuitCustom                         // Custom circuit
+	CircuitWithdraw                       // Shielded pool withdrawal
 )
 
 // VerifyingKey represents a ZK verification key
@@ -104,6 +105,7 @@ type Commitment struct {
 	Blinding   []byte         // Blinding factor (if applicable)
 	Token      common.Address // Token being committed
 	Amount     *big.Int       // Hidden amount
+	Memo       []byte         // Note encrypted to the recipient (optional)
 }
 
 // CommitmentType represents the type of commitment scheme
@@ -118,9 +120,10 @@ const (
 
 // Nullifier represents a nullifier for double-spend prevention
 type Nullifier struct {
-	Hash    [32]byte    // Nullifier hash
-	SpentAt uint64      // Block height when spent
-	SpentTx common.Hash // Transaction that spent it
+	Hash    [32]byte        // Nullifier hash
+	Domain  NullifierDomain // Pool or circuit namespace
+	SpentAt uint64          // Block height when spent
+	SpentTx common.Hash     // Transaction that spent it
 }
 
 // PrivateInput represents encrypted input for a confidential transaction
@@ -186,6 +189,12 @@ type ConfidentialPool struct {
 	TotalDeposits  *big.Int                 // Total deposited
 	TotalWithdraws *big.Int                 // Total withdrawn
 	Enabled        bool
+
+	// Credits holds withdrawn amounts awaiting payout by the pool token
+	Credits map[common.Address]*big.Int
+
+	// Notes holds the encrypted memos posted with commitments, in order
+	Notes []*NoteEvent
 }
 
 // VerificationResult represents the result of proof verification
@@ -205,6 +214,7 @@ var (
 	ErrCircuitMismatch      = errors.New("circuit type mismatch")
 	ErrInvalidPublicInputs  = errors.New("invalid public inputs")
 	ErrNullifierSpent       = errors.New("nullifier already spent")
+	ErrInvalidDomain        = errors.New("invalid nullifier domain")
 	ErrCommitmentNotFound   = errors.New("commitment not found")
 	ErrInvalidCommitment    = errors.New("invalid commitment")
 	ErrInvalidRangeProof    = errors.New("invalid range proof")
@@ -215,6 +225,11 @@ var (
 	ErrPoolNotFound         = errors.New("confidential pool not found")
 	ErrPoolDisabled         = errors.New("confidential pool disabled")
 	ErrInsufficientBalance  = errors.New("insufficient confidential balance")
+	ErrUnknownRoot          = errors.New("unknown pool merkle root")
+	ErrFeeExceedsAmount     = errors.New("relayer fee exceeds withdrawal amount")
+	ErrInvalidViewingKey    = errors.New("invalid viewing key")
+	ErrViewingKeyNotFound   = errors.New("viewing key not found")
+	ErrInvalidNoteMemo      = errors.New("invalid note memo")
 )
 
 // BN254 curve parameters (used by Groth16)
@@ -21,7 +21,7 @@ type ZKVerifier struct {
 	// Verification keys
 	VerifyingKeys map[[32]byte]*VerifyingKey
 
-	// Nullifier tracking (for privacy)
+	// Nullifier tracking (for privacy), keyed by NullifierKey(domain, hash)
 	Nullifiers map[[32]byte]*Nullifier
 
 	// Commitment tracking
@@ -39,6 +39,12 @@ type ZKVerifier struct {
 	// KZG trusted setup
 	KZGSetup *KZGSetup
 
+	// Groth16 phase-2 ceremonies, keyed by hash of the final δ (G2)
+	CeremonyAttestations map[[32]byte]*CeremonyAttestation
+
+	// Note viewing keys (uncompressed secp256k1), keyed by owner
+	ViewingKeys map[common.Address][]byte
+
 	// Statistics
 	TotalVerifications uint64
 	TotalProofsValid   uint64
@@ -65,6 +71,9 @@ func NewZKVerifier() *ZKVerifier {
 		Rollups:       make(map[[32]byte]*RollupConfig),
 		RollupStates:  make(map[[32]byte]*RollupState),
 		Pools:         make(map[[32]byte]*ConfidentialPool),
+
+		CeremonyAttestations: make(map[[32]byte]*CeremonyAttestation),
+		ViewingKeys:          make(map[common.Address][]byte),
 	}
 }
 
@@ -219,30 +228,41 @@ func (zv *ZKVerifier) VerifyRangeProof(
 	return valid, nil
 }
 
-// CheckNullifier checks if a nullifier has been spent
-func (zv *ZKVerifier) CheckNullifier(nullifierHash [32]byte) (bool, error) {
+// CheckNullifier checks if a nullifier has been spent within a domain
+func (zv *ZKVerifier) CheckNullifier(domain NullifierDomain, nullifierHash [32]byte) (bool, error) {
+	if domain == (NullifierDomain{}) {
+		return false, ErrInvalidDomain
+	}
+
 	zv.mu.RLock()
 	defer zv.mu.RUnlock()
 
-	_, spent := zv.Nullifiers[nullifierHash]
+	_, spent := zv.Nullifiers[NullifierKey(domain, nullifierHash)]
 	return spent, nil
 }
 
-// SpendNullifier marks a nullifier as spent
+// SpendNullifier marks a nullifier as spent within a domain
 func (zv *ZKVerifier) SpendNullifier(
+	domain NullifierDomain,
 	nullifierHash [32]byte,
 	txHash common.Hash,
 	blockHeight uint64,
 ) error {
+	if domain == (NullifierDomain{}) {
+		return ErrInvalidDomain
+	}
+
 	zv.mu.Lock()
 	defer zv.mu.Unlock()
 
-	if _, exists := zv.Nullifiers[nullifierHash]; exists {
+	key := NullifierKey(domain, nullifierHash)
+	if _, exists := zv.Nullifiers[key]; exists {
 		return ErrNullifierSpent
 	}
 
-	zv.Nullifiers[nullifierHash] = &Nullifier{
+	zv.Nullifiers[key] = &Nullifier{
 		Hash:    nullifierHash,
+		Domain:  domain,
 		SpentAt: blockHeight,
 		SpentTx: txHash,
 	}
@@ -267,12 +287,21 @@ func (zv *ZKVerifier) AddCommitment(
 		return [32]byte{}, ErrPoolDisabled
 	}
 
+	if len(commitment.Memo) > MaxNoteMemoSize {
+		return [32]byte{}, ErrInvalidNoteMemo
+	}
+
 	// Generate commitment ID
 	commitID := sha256.Sum256(commitment.Value)
 
 	pool.Commitments[commitID] = commitment
 	pool.TotalDeposits.Add(pool.TotalDeposits, commitment.Amount)
 
+	// Publish the recipient's encrypted note
+	if len(commitment.Memo) > 0 {
+		pool.recordNote(commitID, commitment.Memo)
+	}
+
 	// Update merkle root
 	zv.updatePoolMerkleRoot(pool)
 
@@ -445,6 +474,7 @@ func (zv *ZKVerifier) CreateConfidentialPool(
 		TotalDeposits:  big.NewInt(0),
 		TotalWithdraws: big.NewInt(0),
 		Enabled:        true,
+		Credits:        make(map[common.Address]*big.Int),
 	}
 
 	zv.Pools[poolID] = pool
@@ -465,87 +495,112 @@ func (zv *ZKVerifier) groth16PairingCheck(
 	proofA, proofB, proofC []byte,
 	publicInputs []*big.Int,
 ) bool {
-	// Parse proof elements
-	var a bn256.G1
-	if _, err := a.Unmarshal(proofA); err != nil {
+	key, ok := parseGroth16Key(vk)
+	if !ok {
 		return false
 	}
 
-	var b bn256.G2
-	if _, err := b.Unmarshal(proofB); err != nil {
+	// Parse proof elements
+	a, b, c, ok := parseGroth16Proof(proofA, proofB, proofC)
+	if !ok {
 		return false
 	}
 
-	var c bn256.G1
-	if _, err := c.Unmarshal(proofC); err != nil {
+	vkX, ok := key.inputCommitment(publicInputs)
+	if !ok {
 		return false
 	}
 
-	// Parse verification key elements
-	var alpha bn256.G1
-	if _, err := alpha.Unmarshal(vk.Alpha); err != nil {
-		return false
-	}
+	// Negate points for the pairing check
+	// We check: e(A, B) · e(-α, β) · e(-vk_x, γ) · e(-C, δ) = 1
+	negAlpha := new(bn256.G1)
+	negAlpha.ScalarMult(key.alpha, big.NewInt(-1))
 
-	var beta bn256.G2
-	if _, err := beta.Unmarshal(vk.Beta); err != nil {
-		return false
-	}
+	negVkX := new(bn256.G1)
+	negVkX.ScalarMult(vkX, big.NewInt(-1))
 
-	var gamma bn256.G2
-	if _, err := gamma.Unmarshal(vk.Gamma); err != nil {
-		return false
-	}
+	negC := new(bn256.G1)
+	negC.ScalarMult(c, big.NewInt(-1))
 
-	var delta bn256.G2
-	if _, err := delta.Unmarshal(vk.Delta); err != nil {
-		return false
+	// Perform pairing check
+	// PairingCheck returns true if ∏ᵢ e(Pᵢ, Qᵢ) = 1
+	g1Points := []*bn256.G1{a, negAlpha, negVkX, negC}
+	g2Points := []*bn256.G2{b, key.beta, key.gamma, key.delta}
+
+	return bn256.PairingCheck(g1Points, g2Points)
+}
+
+// groth16Key is a parsed Groth16 verifying key
+type groth16Key struct {
+	alpha *bn256.G1
+	beta  *bn256.G2
+	gamma *bn256.G2
+	delta *bn256.G2
+	ic    []*bn256.G1 // Input constraints
+}
+
+// parseGroth16Key parses the curve points of a verifying key
+func parseGroth16Key(vk *VerifyingKey) (*groth16Key, bool) {
+	key := &groth16Key{
+		alpha: new(bn256.G1),
+		beta:  new(bn256.G2),
+		gamma: new(bn256.G2),
+		delta: new(bn256.G2),
+	}
+	if _, err := key.alpha.Unmarshal(vk.Alpha); err != nil {
+		return nil, false
+	}
+	if _, err := key.beta.Unmarshal(vk.Beta); err != nil {
+		return nil, false
+	}
+	if _, err := key.gamma.Unmarshal(vk.Gamma); err != nil {
+		return nil, false
+	}
+	if _, err := key.delta.Unmarshal(vk.Delta); err != nil {
+		return nil, false
 	}
 
 	// Parse IC points (input constraints)
 	if len(vk.IC) < 1 {
-		return false
+		return nil, false
 	}
-
-	ic := make([]*bn256.G1, len(vk.IC))
+	key.ic = make([]*bn256.G1, len(vk.IC))
 	for i, icBytes := range vk.IC {
-		ic[i] = new(bn256.G1)
-		if _, err := ic[i].Unmarshal(icBytes); err != nil {
-			return false
+		key.ic[i] = new(bn256.G1)
+		if _, err := key.ic[i].Unmarshal(icBytes); err != nil {
+			return nil, false
 		}
 	}
+	return key, true
+}
 
-	// Compute vk_x = IC[0] + ∑ᵢ (publicInputs[i] * IC[i+1])
-	// This is the linear combination of public inputs with IC points
-	vkX := new(bn256.G1)
-	vkX.ScalarMult(ic[0], big.NewInt(1)) // Start with IC[0]
-
-	for i, input := range publicInputs {
-		if i+1 >= len(ic) {
-			return false
-		}
-		tmp := new(bn256.G1)
-		tmp.ScalarMult(ic[i+1], input)
-		vkX.Add(vkX, tmp)
+// parseGroth16Proof parses the A (G1), B (G2) and C (G1) proof points
+func parseGroth16Proof(proofA, proofB, proofC []byte) (*bn256.G1, *bn256.G2, *bn256.G1, bool) {
+	a, b, c := new(bn256.G1), new(bn256.G2), new(bn256.G1)
+	if _, err := a.Unmarshal(proofA); err != nil {
+		return nil, nil, nil, false
 	}
+	if _, err := b.Unmarshal(proofB); err != nil {
+		return nil, nil, nil, false
+	}
+	if _, err := c.Unmarshal(proofC); err != nil {
+		return nil, nil, nil, false
+	}
+	return a, b, c, true
+}
 
-	// Negate points for the pairing check
-	// We check: e(A, B) · e(-α, β) · e(-vk_x, γ) · e(-C, δ) = 1
-	negAlpha := new(bn256.G1)
-	negAlpha.ScalarMult(&alpha, big.NewInt(-1))
-
-	negVkX := new(bn256.G1)
-	negVkX.ScalarMult(vkX, big.NewInt(-1))
-
-	negC := new(bn256.G1)
-	negC.ScalarMult(&c, big.NewInt(-1))
-
-	// Perform pairing check
-	// PairingCheck returns true if ∏ᵢ e(Pᵢ, Qᵢ) = 1
-	g1Points := []*bn256.G1{&a, negAlpha, negVkX, negC}
-	g2Points := []*bn256.G2{&b, &beta, &gamma, &delta}
+// inputCommitment computes vk_x = IC[0] + ∑ᵢ (publicInputs[i] * IC[i+1]),
+// the linear combination of public inputs with IC points. Large input sets
+// are offloaded to the MSM accelerator.
+func (k *groth16Key) inputCommitment(publicInputs []*big.Int) (*bn256.G1, bool) {
+	if len(publicInputs) >= len(k.ic) {
+		return nil, false
+	}
 
-	return bn256.PairingCheck(g1Points, g2Points)
+	scalars := make([]*big.Int, 0, len(publicInputs)+1)
+	scalars = append(scalars, big.NewInt(1)) // IC[0] is taken once
+	scalars = append(scalars, publicInputs...)
+	return msmBN254(k.ic[:len(scalars)], scalars), true
 }
 
 // plonkVerify verifies a PLONK proof using KZG polynomial commitments.
@@ -217,10 +217,11 @@ func TestVerifyPlonk(t *testing.T) {
 func TestCheckNullifier(t *testing.T) {
 	zv := NewZKVerifier()
 
+	domain := PoolNullifierDomain([32]byte{0xAA})
 	nullifierHash := [32]byte{0x01, 0x02, 0x03}
 
 	// Initially not spent
-	spent, err := zv.CheckNullifier(nullifierHash)
+	spent, err := zv.CheckNullifier(domain, nullifierHash)
 	if err != nil {
 		t.Fatalf("CheckNullifier failed: %v", err)
 	}
@@ -230,13 +231,13 @@ func TestCheckNullifier(t *testing.T) {
 
 	// Spend it
 	txHash := common.HexToHash("0xABCDEF")
-	err = zv.SpendNullifier(nullifierHash, txHash, 100)
+	err = zv.SpendNullifier(domain, nullifierHash, txHash, 100)
 	if err != nil {
 		t.Fatalf("SpendNullifier failed: %v", err)
 	}
 
 	// Now should be spent
-	spent, err = zv.CheckNullifier(nullifierHash)
+	spent, err = zv.CheckNullifier(domain, nullifierHash)
 	if err != nil {
 		t.Fatalf("CheckNullifier failed: %v", err)
 	}
@@ -249,22 +250,64 @@ func TestCheckNullifier(t *testing.T) {
 func TestSpendNullifierAlreadySpent(t *testing.T) {
 	zv := NewZKVerifier()
 
+	domain := PoolNullifierDomain([32]byte{0xAA})
 	nullifierHash := [32]byte{0x01, 0x02, 0x03}
 	txHash := common.HexToHash("0xABCDEF")
 
 	// First spend
-	err := zv.SpendNullifier(nullifierHash, txHash, 100)
+	err := zv.SpendNullifier(domain, nullifierHash, txHash, 100)
 	if err != nil {
 		t.Fatalf("First spend failed: %v", err)
 	}
 
 	// Second spend should fail
-	err = zv.SpendNullifier(nullifierHash, txHash, 101)
+	err = zv.SpendNullifier(domain, nullifierHash, txHash, 101)
 	if err != ErrNullifierSpent {
 		t.Errorf("Expected ErrNullifierSpent, got %v", err)
 	}
 }
 
+// TestNullifierNamespaces tests that nullifiers do not collide across domains
+func TestNullifierNamespaces(t *testing.T) {
+	zv := NewZKVerifier()
+
+	poolA := PoolNullifierDomain([32]byte{0xAA})
+	poolB := PoolNullifierDomain([32]byte{0xBB})
+	circuitA := CircuitNullifierDomain([32]byte{0xAA})
+	if poolA == poolB || poolA == circuitA {
+		t.Fatal("Expected distinct domains")
+	}
+
+	nullifierHash := [32]byte{0x01, 0x02, 0x03}
+	txHash := common.HexToHash("0xABCDEF")
+
+	if err := zv.SpendNullifier(poolA, nullifierHash, txHash, 100); err != nil {
+		t.Fatalf("SpendNullifier failed: %v", err)
+	}
+
+	// The same hash is still spendable in other pools and circuits
+	for _, domain := range []NullifierDomain{poolB, circuitA} {
+		spent, err := zv.CheckNullifier(domain, nullifierHash)
+		if err != nil {
+			t.Fatalf("CheckNullifier failed: %v", err)
+		}
+		if spent {
+			t.Error("Nullifier spent in pool A should not be spent in another domain")
+		}
+		if err := zv.SpendNullifier(domain, nullifierHash, txHash, 101); err != nil {
+			t.Errorf("SpendNullifier in another domain failed: %v", err)
+		}
+	}
+
+	// The zero domain is rejected
+	if _, err := zv.CheckNullifier(NullifierDomain{}, nullifierHash); err != ErrInvalidDomain {
+		t.Errorf("Expected ErrInvalidDomain, got %v", err)
+	}
+	if err := zv.SpendNullifier(NullifierDomain{}, nullifierHash, txHash, 102); err != ErrInvalidDomain {
+		t.Errorf("Expected ErrInvalidDomain, got %v", err)
+	}
+}
+
 // TestCreateConfidentialPool tests pool creation
 func TestCreateConfidentialPool(t *testing.T) {
 	zv := NewZKVerifier()
@@ -750,29 +793,31 @@ func BenchmarkVerifyPlonk(b *testing.B) {
 
 func BenchmarkSpendNullifier(b *testing.B) {
 	zv := NewZKVerifier()
+	domain := PoolNullifierDomain([32]byte{0xAA})
 	txHash := common.HexToHash("0xABCDEF")
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		nullifier := [32]byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
-		_ = zv.SpendNullifier(nullifier, txHash, uint64(i))
+		_ = zv.SpendNullifier(domain, nullifier, txHash, uint64(i))
 	}
 }
 
 func BenchmarkCheckNullifier(b *testing.B) {
 	zv := NewZKVerifier()
+	domain := PoolNullifierDomain([32]byte{0xAA})
 
 	// Pre-populate some nullifiers
 	for i := 0; i < 10000; i++ {
 		nullifier := [32]byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
-		zv.Nullifiers[nullifier] = &Nullifier{}
+		zv.Nullifiers[NullifierKey(domain, nullifier)] = &Nullifier{}
 	}
 
 	nullifier := [32]byte{0x00, 0x00, 0x27, 0x10} // 10000
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = zv.CheckNullifier(nullifier)
+		_, _ = zv.CheckNullifier(domain, nullifier)
 	}
 }
 
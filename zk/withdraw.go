@@ -0,0 +1,219 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/luxfi/geth/common"
+)
+
+// Shielded withdrawals with relayer fees.
+//
+// A withdrawal spends a note of a confidential pool and pays it out in the
+// clear. The withdrawal circuit proves that a note of the given amount sits
+// under the pool's Merkle root and that the nullifier was derived from it.
+// Its public inputs, in order, are:
+//
+//	[root, nullifierHash, recipient, relayer, fee, refund, amount]
+//
+// The proof binds the relayer and fee, so a relayer can submit the
+// withdrawal and pay its gas but cannot redirect funds. The recipient is
+// credited amount - fee and the relayer is credited fee. Both credits happen
+// together with the nullifier spend, or not at all. refund is native
+// currency the relayer forwards to the recipient for future gas. The proof
+// binds it, and the caller checks it against the value the relayer sent.
+
+// WithdrawalPublicInputs is the public input count of the withdrawal circuit
+const WithdrawalPublicInputs = 7
+
+// GasConfidentialWithdraw covers proof verification, the nullifier spend
+// and the two credits
+const GasConfidentialWithdraw = GasGroth16Verify + GasNullifierCheck + uint64(WithdrawalPublicInputs)*GasPerPublicInput
+
+// Withdrawal holds the public inputs of a shielded withdrawal
+type Withdrawal struct {
+	Root          [32]byte       // Pool Merkle root the note is proven under
+	NullifierHash [32]byte       // Nullifier of the spent note
+	Recipient     common.Address // Receives amount - fee
+	Relayer       common.Address // Receives fee; zero for self-submitted withdrawals
+	Fee           *big.Int       // Relayer fee, taken from the note
+	Refund        *big.Int       // Native currency the relayer forwards to the recipient
+	Amount        *big.Int       // Note amount
+}
+
+// PublicInputs returns the withdrawal circuit's public inputs
+func (w *Withdrawal) PublicInputs() []*big.Int {
+	return []*big.Int{
+		new(big.Int).SetBytes(w.Root[:]),
+		new(big.Int).SetBytes(w.NullifierHash[:]),
+		new(big.Int).SetBytes(w.Recipient.Bytes()),
+		new(big.Int).SetBytes(w.Relayer.Bytes()),
+		w.Fee,
+		w.Refund,
+		w.Amount,
+	}
+}
+
+// WithdrawalReceipt records the credits of a completed withdrawal
+type WithdrawalReceipt struct {
+	PoolID          [32]byte
+	NullifierHash   [32]byte
+	Recipient       common.Address
+	RecipientAmount *big.Int // amount - fee
+	Relayer         common.Address
+	Fee             *big.Int
+	Refund          *big.Int // Owed by the relayer to the recipient
+}
+
+// WithdrawConfidential verifies a withdrawal proof against the pool and,
+// if it holds, spends the nullifier and credits the recipient and relayer.
+// Nothing changes unless every check passes.
+func (zv *ZKVerifier) WithdrawConfidential(
+	poolID [32]byte,
+	vkID [32]byte,
+	proof *Proof,
+	w *Withdrawal,
+	txHash common.Hash,
+	blockHeight uint64,
+) (*WithdrawalReceipt, error) {
+	if err := w.validate(); err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		return nil, ErrInvalidProof
+	}
+
+	zv.mu.Lock()
+	defer zv.mu.Unlock()
+
+	pool := zv.Pools[poolID]
+	if pool == nil {
+		return nil, ErrPoolNotFound
+	}
+	if !pool.Enabled {
+		return nil, ErrPoolDisabled
+	}
+	if w.Root != pool.MerkleRoot {
+		return nil, ErrUnknownRoot
+	}
+
+	vk := zv.VerifyingKeys[vkID]
+	if vk == nil {
+		return nil, ErrInvalidVerifyingKey
+	}
+	if vk.ProofSystem != ProofSystemGroth16 {
+		return nil, ErrProofSystemMismatch
+	}
+	if vk.CircuitType != CircuitWithdraw {
+		return nil, ErrCircuitMismatch
+	}
+	if len(vk.IC) != WithdrawalPublicInputs+1 {
+		return nil, ErrInvalidPublicInputs
+	}
+
+	domain := PoolNullifierDomain(poolID)
+	nullifierKey := NullifierKey(domain, w.NullifierHash)
+	if _, spent := zv.Nullifiers[nullifierKey]; spent {
+		return nil, ErrNullifierSpent
+	}
+
+	available := new(big.Int).Sub(pool.TotalDeposits, pool.TotalWithdraws)
+	if available.Cmp(w.Amount) < 0 {
+		return nil, ErrInsufficientBalance
+	}
+
+	valid := zv.groth16PairingCheck(vk, proof.A, proof.B, proof.C, w.PublicInputs())
+	zv.TotalVerifications++
+	if !valid {
+		zv.TotalProofsFailed++
+		return nil, ErrInvalidProof
+	}
+	zv.TotalProofsValid++
+
+	// Spend the nullifier and credit both parties together
+	zv.Nullifiers[nullifierKey] = &Nullifier{
+		Hash:    w.NullifierHash,
+		Domain:  domain,
+		SpentAt: blockHeight,
+		SpentTx: txHash,
+	}
+	pool.TotalWithdraws.Add(pool.TotalWithdraws, w.Amount)
+
+	recipientAmount := new(big.Int).Sub(w.Amount, w.Fee)
+	creditTo(pool.Credits, w.Recipient, recipientAmount)
+	if w.Fee.Sign() > 0 {
+		creditTo(pool.Credits, w.Relayer, w.Fee)
+	}
+
+	return &WithdrawalReceipt{
+		PoolID:          poolID,
+		NullifierHash:   w.NullifierHash,
+		Recipient:       w.Recipient,
+		RecipientAmount: recipientAmount,
+		Relayer:         w.Relayer,
+		Fee:             new(big.Int).Set(w.Fee),
+		Refund:          new(big.Int).Set(w.Refund),
+	}, nil
+}
+
+// ClaimCredit returns and clears an account's withdrawn balance in a pool,
+// for the pool's token contract to pay out
+func (zv *ZKVerifier) ClaimCredit(poolID [32]byte, account common.Address) (*big.Int, error) {
+	zv.mu.Lock()
+	defer zv.mu.Unlock()
+
+	pool := zv.Pools[poolID]
+	if pool == nil {
+		return nil, ErrPoolNotFound
+	}
+
+	credit, ok := pool.Credits[account]
+	if !ok {
+		return big.NewInt(0), nil
+	}
+	delete(pool.Credits, account)
+	return credit, nil
+}
+
+// validate checks a withdrawal's public inputs before any proof work
+func (w *Withdrawal) validate() error {
+	if w == nil || w.Amount == nil || w.Fee == nil || w.Refund == nil {
+		return ErrInvalidPublicInputs
+	}
+	if w.Amount.Sign() <= 0 || w.Fee.Sign() < 0 || w.Refund.Sign() < 0 {
+		return ErrInvalidPublicInputs
+	}
+	if w.Recipient == (common.Address{}) {
+		return ErrInvalidPublicInputs
+	}
+	if w.Fee.Cmp(w.Amount) > 0 {
+		return ErrFeeExceedsAmount
+	}
+
+	// Fees and refunds need a relayer to pay or collect them
+	if w.Relayer == (common.Address{}) && (w.Fee.Sign() > 0 || w.Refund.Sign() > 0) {
+		return ErrInvalidPublicInputs
+	}
+
+	// Every input must be a canonical field element, or a nullifier could be
+	// replayed as nullifier + r under the same proof
+	order := fr.Modulus()
+	for _, input := range w.PublicInputs() {
+		if input.Cmp(order) >= 0 {
+			return ErrInvalidPublicInputs
+		}
+	}
+	return nil
+}
+
+// creditTo adds amount to credits[account]
+func creditTo(credits map[common.Address]*big.Int, account common.Address, amount *big.Int) {
+	if cur, ok := credits[account]; ok {
+		cur.Add(cur, amount)
+		return
+	}
+	credits[account] = new(big.Int).Set(amount)
+}
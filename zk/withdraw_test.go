@@ -0,0 +1,140 @@
+// Copyright (C) 2025, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package zk
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luxfi/geth/common"
+)
+
+var (
+	testWithdrawIC = []*big.Int{
+		big.NewInt(13), big.NewInt(17), big.NewInt(19), big.NewInt(23),
+		big.NewInt(29), big.NewInt(31), big.NewInt(37), big.NewInt(41),
+	}
+	testRecipient = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	testRelayer   = common.HexToAddress("0x00000000000000000000000000000000000000b2")
+)
+
+// setupWithdrawPool creates a pool holding deposit and registers a
+// withdrawal key with a known trapdoor
+func setupWithdrawPool(t *testing.T, deposit int64) (*ZKVerifier, [32]byte, [32]byte) {
+	t.Helper()
+
+	zv := NewZKVerifier()
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	poolID, err := zv.CreateConfidentialPool(owner, common.HexToAddress("0x1"), 20)
+	if err != nil {
+		t.Fatalf("CreateConfidentialPool failed: %v", err)
+	}
+	if _, err := zv.AddCommitment(poolID, &Commitment{Value: []byte("note"), Amount: big.NewInt(deposit)}); err != nil {
+		t.Fatalf("AddCommitment failed: %v", err)
+	}
+
+	ic := make([][]byte, len(testWithdrawIC))
+	for i, s := range testWithdrawIC {
+		ic[i] = g1Mul(s)
+	}
+	vkID, err := zv.RegisterVerifyingKey(owner, ProofSystemGroth16, CircuitWithdraw,
+		g1Mul(testAlpha), g2Mul(testBeta), g2Mul(testGamma), g2Mul(testDelta), ic)
+	if err != nil {
+		t.Fatalf("RegisterVerifyingKey failed: %v", err)
+	}
+	return zv, poolID, vkID
+}
+
+func TestWithdrawConfidentialWithRelayer(t *testing.T) {
+	zv, poolID, vkID := setupWithdrawPool(t, 1000)
+
+	w := &Withdrawal{
+		NullifierHash: [32]byte{31: 0x42},
+		Recipient:     testRecipient,
+		Relayer:       testRelayer,
+		Fee:           big.NewInt(30),
+		Refund:        big.NewInt(5),
+		Amount:        big.NewInt(1000),
+	}
+	proof := trapdoorProofFor(testWithdrawIC, 23, 29, w.PublicInputs()...)
+
+	// The proof binds the fee: a relayer cannot raise it
+	raised := *w
+	raised.Fee = big.NewInt(300)
+	if _, err := zv.WithdrawConfidential(poolID, vkID, proof, &raised, common.Hash{}, 1); err != ErrInvalidProof {
+		t.Errorf("Expected ErrInvalidProof for altered fee, got %v", err)
+	}
+	if spent, _ := zv.CheckNullifier(PoolNullifierDomain(poolID), w.NullifierHash); spent {
+		t.Fatal("Failed withdrawal must not spend the nullifier")
+	}
+
+	receipt, err := zv.WithdrawConfidential(poolID, vkID, proof, w, common.Hash{}, 1)
+	if err != nil {
+		t.Fatalf("WithdrawConfidential failed: %v", err)
+	}
+	if receipt.RecipientAmount.Cmp(big.NewInt(970)) != 0 || receipt.Fee.Cmp(big.NewInt(30)) != 0 {
+		t.Errorf("Expected 970 to recipient and 30 to relayer, got %s and %s", receipt.RecipientAmount, receipt.Fee)
+	}
+	if receipt.Refund.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("Expected refund 5, got %s", receipt.Refund)
+	}
+
+	if credit, _ := zv.ClaimCredit(poolID, testRelayer); credit.Cmp(big.NewInt(30)) != 0 {
+		t.Errorf("Expected relayer credit 30, got %s", credit)
+	}
+	if credit, _ := zv.ClaimCredit(poolID, testRecipient); credit.Cmp(big.NewInt(970)) != 0 {
+		t.Errorf("Expected recipient credit 970, got %s", credit)
+	}
+	if credit, _ := zv.ClaimCredit(poolID, testRelayer); credit.Sign() != 0 {
+		t.Errorf("Expected relayer credit cleared, got %s", credit)
+	}
+
+	if _, err := zv.WithdrawConfidential(poolID, vkID, proof, w, common.Hash{}, 2); err != ErrNullifierSpent {
+		t.Errorf("Expected ErrNullifierSpent, got %v", err)
+	}
+}
+
+func TestWithdrawConfidentialValidation(t *testing.T) {
+	zv, poolID, vkID := setupWithdrawPool(t, 100)
+	proof := &Proof{ProofSystem: ProofSystemGroth16}
+
+	base := Withdrawal{
+		NullifierHash: [32]byte{31: 0x01},
+		Recipient:     testRecipient,
+		Relayer:       testRelayer,
+		Fee:           big.NewInt(10),
+		Refund:        big.NewInt(0),
+		Amount:        big.NewInt(100),
+	}
+
+	tooMuchFee := base
+	tooMuchFee.Fee = big.NewInt(101)
+	if _, err := zv.WithdrawConfidential(poolID, vkID, proof, &tooMuchFee, common.Hash{}, 1); err != ErrFeeExceedsAmount {
+		t.Errorf("Expected ErrFeeExceedsAmount, got %v", err)
+	}
+
+	noRelayer := base
+	noRelayer.Relayer = common.Address{}
+	if _, err := zv.WithdrawConfidential(poolID, vkID, proof, &noRelayer, common.Hash{}, 1); err != ErrInvalidPublicInputs {
+		t.Errorf("Expected ErrInvalidPublicInputs for fee without relayer, got %v", err)
+	}
+
+	nonCanonical := base
+	nonCanonical.NullifierHash = [32]byte{0: 0xff}
+	if _, err := zv.WithdrawConfidential(poolID, vkID, proof, &nonCanonical, common.Hash{}, 1); err != ErrInvalidPublicInputs {
+		t.Errorf("Expected ErrInvalidPublicInputs for non-canonical nullifier, got %v", err)
+	}
+
+	staleRoot := base
+	staleRoot.Root = [32]byte{31: 0x07}
+	if _, err := zv.WithdrawConfidential(poolID, vkID, proof, &staleRoot, common.Hash{}, 1); err != ErrUnknownRoot {
+		t.Errorf("Expected ErrUnknownRoot, got %v", err)
+	}
+
+	overdrawn := base
+	overdrawn.Amount = big.NewInt(101)
+	if _, err := zv.WithdrawConfidential(poolID, vkID, proof, &overdrawn, common.Hash{}, 1); err != ErrInsufficientBalance {
+		t.Errorf("Expected ErrInsufficientBalance, got %v", err)
+	}
+}